@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/internal/config"
 	chanpkg "github.com/opentalon/opentalon/pkg/channel"
 )
 
@@ -106,3 +107,24 @@ func TestChannelNotifier_AnonymousThreadKey_RoutesWithoutStamp(t *testing.T) {
 		t.Errorf("anonymous threaded frame must not carry the owner stamp, got %+v", got.Metadata)
 	}
 }
+
+func TestBuildNotifyTool_RequiresConfiguredChannels(t *testing.T) {
+	cfg := &config.Config{
+		Channels: map[string]config.ChannelConfig{"slack": {Enabled: true}},
+		Notify: config.NotifyConfig{
+			Enabled: true,
+			Targets: map[string]config.NotifyTarget{"release": {Channel: "slack", Conversation: "C0REL"}},
+		},
+	}
+	if _, err := buildNotifyTool(cfg, &channelNotifier{}); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Notify.Channels = []string{"teams"}
+	if _, err := buildNotifyTool(cfg, &channelNotifier{}); err == nil {
+		t.Error("a channel missing from channels: should be rejected")
+	}
+	cfg.Notify = config.NotifyConfig{Enabled: true}
+	if _, err := buildNotifyTool(cfg, &channelNotifier{}); err == nil {
+		t.Error("notify without destinations should be rejected")
+	}
+}
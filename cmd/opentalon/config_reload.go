@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+// rulesSetter is the orchestrator surface the reloader needs.
+type rulesSetter interface {
+	SetRules(customRules []string)
+}
+
+// staticJobReplacer is the scheduler surface the reloader needs.
+type staticJobReplacer interface {
+	ReplaceStaticJobs(jobs []scheduler.Job) (added, removed, changed []string, err error)
+}
+
+// configReloader applies the live-reloadable subset of a changed config file
+// (reload.enabled): orchestrator rules, routing (provider rebuild, which also
+// picks up catalog weights and provider entries), and static scheduler jobs.
+// Every other section keeps its startup value until restart; the audit entry
+// names those sections so an operator can tell an edit did not take effect.
+type configReloader struct {
+	mu      sync.Mutex // serializes apply; Watch already calls it from one goroutine
+	current *config.Config
+
+	orch  rulesSetter
+	llm   *defaultModelClient
+	sched staticJobReplacer
+
+	// buildProvider rebuilds the LLM provider from a new config. ctx bounds
+	// the health-gated provider's probe loop; provCancel stops the previous one.
+	buildProvider func(ctx context.Context, cfg *config.Config) (provider.Provider, string, error)
+	provCancel    context.CancelFunc
+}
+
+// apply diffs next against the running config and applies what it can. A
+// subset that fails to apply (e.g. a routing ref to an unknown provider) is
+// logged and left at its previous value; the remaining subsets still apply,
+// and the failed one is retried on the next change because the running
+// config keeps the old value for it.
+func (r *configReloader) apply(next *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	diff := config.DiffReload(r.current, next)
+	if diff.Empty() && len(diff.Ignored) == 0 {
+		return
+	}
+	cp := *r.current
+	var done []string
+
+	if diff.Rules {
+		r.orch.SetRules(next.Orchestrator.Rules)
+		cp.Orchestrator.Rules = next.Orchestrator.Rules
+		done = append(done, "orchestrator.rules")
+	}
+	if diff.Routing {
+		ctx, cancel := context.WithCancel(context.Background())
+		prov, model, err := r.buildProvider(ctx, next)
+		if err != nil {
+			cancel()
+			slog.Warn("config reload: routing not applied", "component", "config", "error", err)
+		} else {
+			r.llm.swap(prov, model, providerModelMap(prov))
+			if r.provCancel != nil {
+				r.provCancel()
+			}
+			r.provCancel = cancel
+			cp.Routing, cp.Models = next.Routing, next.Models
+			done = append(done, "routing")
+			slog.Info("config reload: routing applied", "component", "config",
+				"primary", next.Routing.Primary, "fallbacks", next.Routing.Fallbacks, "default_model", model)
+		}
+	}
+	if diff.Scheduler {
+		// Only scheduler.jobs reload; digest reports keep the running config's.
+		want := cp
+		want.Scheduler.Jobs = next.Scheduler.Jobs
+		added, removed, changed, err := r.sched.ReplaceStaticJobs(staticSchedulerJobs(&want))
+		if err != nil {
+			// The running config keeps the old jobs, so the next change
+			// retries the ones that did not apply.
+			slog.Warn("config reload: some scheduler jobs not applied", "component", "config", "error", err,
+				"added", added, "removed", removed, "changed", changed)
+		} else {
+			cp.Scheduler.Jobs = next.Scheduler.Jobs
+			done = append(done, "scheduler.jobs")
+			slog.Info("config reload: scheduler jobs applied", "component", "config",
+				"added", added, "removed", removed, "changed", changed)
+		}
+	}
+	r.current = &cp
+
+	slog.Info("audit", "event", "config_reload", "applied", done, "restart_required", diff.Ignored)
+	if len(diff.Ignored) > 0 {
+		slog.Warn("config reload: some changed sections need a restart to take effect",
+			"component", "config", "sections", diff.Ignored)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+type fakeRulesSetter struct{ rules []string }
+
+func (f *fakeRulesSetter) SetRules(r []string) { f.rules = r }
+
+type fakeJobReplacer struct {
+	jobs []scheduler.Job
+	err  error
+}
+
+func (f *fakeJobReplacer) ReplaceStaticJobs(jobs []scheduler.Job) (added, removed, changed []string, err error) {
+	f.jobs = jobs
+	return nil, nil, nil, f.err
+}
+
+func newTestReloader(buildErr error) (*configReloader, *fakeRulesSetter, *fakeJobReplacer, *fakeProvider) {
+	old := &fakeProvider{models: []provider.ModelInfo{{ID: "m1"}}}
+	next := &fakeProvider{models: []provider.ModelInfo{{ID: "m2", MaxTokens: 99}}}
+	rs, jr := &fakeRulesSetter{}, &fakeJobReplacer{}
+	r := &configReloader{
+		current: &config.Config{Routing: config.RoutingConfig{Primary: "a/m1"}},
+		orch:    rs,
+		llm:     &defaultModelClient{provider: old, model: "m1", models: modelMap(old.models)},
+		sched:   jr,
+		buildProvider: func(_ context.Context, cfg *config.Config) (provider.Provider, string, error) {
+			if buildErr != nil {
+				return nil, "", buildErr
+			}
+			return next, "m2", nil
+		},
+	}
+	return r, rs, jr, next
+}
+
+func TestConfigReloader_AppliesLiveSubsets(t *testing.T) {
+	r, rs, jr, next := newTestReloader(nil)
+	cfg := &config.Config{
+		Routing:      config.RoutingConfig{Primary: "b/m2"},
+		Orchestrator: config.OrchestratorConfig{Rules: []string{"no emojis"}},
+		Scheduler:    config.SchedulerConfig{Jobs: []config.JobConfig{{Name: "ping", Interval: "1h", Action: "p__a"}}},
+	}
+	r.apply(cfg)
+
+	if len(rs.rules) != 1 || rs.rules[0] != "no emojis" {
+		t.Errorf("rules = %v", rs.rules)
+	}
+	if len(jr.jobs) != 1 || jr.jobs[0].Name != "ping" {
+		t.Errorf("static jobs = %+v", jr.jobs)
+	}
+	if _, err := r.llm.Complete(context.Background(), &provider.CompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if next.lastReq == nil || next.lastReq.Model != "m2" || next.lastReq.MaxTokens != 99 {
+		t.Errorf("request not routed to the rebuilt provider with its model defaults: %+v", next.lastReq)
+	}
+	if r.current.Routing.Primary != "b/m2" {
+		t.Errorf("running config not updated: %+v", r.current.Routing)
+	}
+}
+
+func TestConfigReloader_RoutingFailureKeepsProvider(t *testing.T) {
+	r, rs, _, _ := newTestReloader(errors.New("provider \"b\" not found"))
+	r.apply(&config.Config{
+		Routing:      config.RoutingConfig{Primary: "b/m2"},
+		Orchestrator: config.OrchestratorConfig{Rules: []string{"x"}},
+	})
+	if prov, model, _ := r.llm.snapshot(); model != "m1" || prov.Models()[0].ID != "m1" {
+		t.Errorf("provider swapped despite build failure: model=%q", model)
+	}
+	if r.current.Routing.Primary != "a/m1" {
+		t.Errorf("failed routing recorded as applied: %+v", r.current.Routing)
+	}
+	if len(rs.rules) != 1 {
+		t.Error("rules should still apply when routing fails")
+	}
+}
+
+func TestConfigReloader_SchedulerFailureRetries(t *testing.T) {
+	r, _, jr, _ := newTestReloader(nil)
+	jr.err = errors.New("job ping kept: bad interval")
+	cfg := &config.Config{
+		Routing:   r.current.Routing,
+		Scheduler: config.SchedulerConfig{Jobs: []config.JobConfig{{Name: "ping", Interval: "soon", Action: "p__a"}}},
+	}
+	r.apply(cfg)
+	if len(jr.jobs) != 1 {
+		t.Fatalf("static jobs = %+v", jr.jobs)
+	}
+	if len(r.current.Scheduler.Jobs) != 0 {
+		t.Errorf("failed jobs recorded as applied: %+v", r.current.Scheduler.Jobs)
+	}
+
+	jr.err, jr.jobs = nil, nil
+	r.apply(cfg)
+	if len(jr.jobs) != 1 {
+		t.Error("the next reload did not retry the jobs")
+	}
+	if len(r.current.Scheduler.Jobs) != 1 {
+		t.Errorf("applied jobs not recorded: %+v", r.current.Scheduler.Jobs)
+	}
+}
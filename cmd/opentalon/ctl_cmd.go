@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/daemon"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scheduler"
+	"github.com/opentalon/opentalon/internal/sessioncache"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+const ctlUsage = `Usage: opentalon ctl <group> <command> [-config <path> | -socket <path>] [-json] [args]
+  sessions list [-limit N]        most recently active sessions (default 50)
+  sessions show <id>              a session's messages
+  sessions messages [-after N | -before N] [-limit N] <id>
+                                  a page of a session's messages by seq
+  sessions delete <id>            delete a session and its messages
+  jobs list                       scheduler jobs
+  jobs run-now <name>             run a job now and wait for it
+  plugins list                    loaded plugins
+  plugins reload <name>           restart a plugin and refresh its tools
+  memory search <words>           search long-term memories
+  usage report [-since 24h|7d] [-by entity|group|channel|model|kind]
+  providers status                LLM endpoints: health, cooldown, success rates
+  actor export <actor-id>         everything stored about an actor, as JSON
+  actor forget [-dry-run] <actor-id>
+                                  delete it; -dry-run only counts what would go
+The instance must be running; ctl talks to its admin socket (ctl.socket,
+default <state.data_dir>/ctl.sock).`
+
+// ctlSocketPath is where the admin socket lives for cfg; empty when it is
+// disabled or there is no data dir to put it in.
+func ctlSocketPath(cfg *config.Config) string {
+	switch {
+	case cfg.Ctl.Disabled:
+		return ""
+	case cfg.Ctl.Socket != "":
+		return cfg.Ctl.Socket
+	case cfg.State.DataDir != "":
+		return filepath.Join(cfg.State.DataDir, "ctl.sock")
+	}
+	return ""
+}
+
+// adminBackends completes b with the stores the admin API can use.
+// Sessions, memory, usage and actor data are only offered when backed by
+// the state database.
+func adminBackends(b ctl.Backends, sessions orchestrator.SessionStoreInterface, memory orchestrator.MemoryStoreInterface, usage *store.UsageStore, actors *store.ActorDataStore) ctl.Backends {
+	if cache, ok := sessions.(*sessioncache.Store); ok {
+		if sa, ok := cache.Backend().(ctl.SessionAdmin); ok {
+			b.Sessions = cachedSessionAdmin{SessionAdmin: sa, cache: cache}
+		}
+	} else if sa, ok := sessions.(ctl.SessionAdmin); ok {
+		b.Sessions = sa
+	}
+	if ma, ok := memory.(ctl.MemoryAdmin); ok {
+		b.Memory = ma
+	}
+	if usage != nil {
+		b.Usage = usage
+	}
+	if actors != nil {
+		b.Actors = actors
+		if cache, ok := sessions.(*sessioncache.Store); ok {
+			b.Actors = cachedActorAdmin{ActorAdmin: actors, cache: cache}
+		}
+	}
+	return b
+}
+
+// cachedSessionAdmin lists and pages sessions from the state database behind
+// the cluster session cache, and reads and deletes them through the cache so
+// a deleted session is not served from Redis afterwards.
+type cachedSessionAdmin struct {
+	ctl.SessionAdmin
+	cache *sessioncache.Store
+}
+
+func (a cachedSessionAdmin) Get(id string) (*state.Session, error) { return a.cache.Get(id) }
+func (a cachedSessionAdmin) Delete(id string) error                { return a.cache.Delete(id) }
+
+// cachedActorAdmin drops an erased actor's sessions from the cluster session
+// cache, which would otherwise serve them until they expire.
+type cachedActorAdmin struct {
+	ctl.ActorAdmin
+	cache *sessioncache.Store
+}
+
+func (a cachedActorAdmin) Forget(ctx context.Context, actorID string, dryRun bool) (store.ForgetReport, error) {
+	r, err := a.ActorAdmin.Forget(ctx, actorID, dryRun)
+	if err == nil && !dryRun {
+		for _, id := range r.SessionIDs {
+			a.cache.Invalidate(id)
+		}
+	}
+	return r, err
+}
+
+// startCtl serves the admin socket for b (see adminBackends). The returned
+// func stops it.
+func startCtl(cfg *config.Config, b ctl.Backends) func() {
+	path := ctlSocketPath(cfg)
+	if path == "" {
+		return func() {}
+	}
+	srv, err := ctl.Listen(path, b)
+	if err != nil {
+		slog.Warn("ctl socket disabled", "component", "ctl", "error", err)
+		return func() {}
+	}
+	return srv.Close
+}
+
+// runCtl implements `opentalon ctl ...` against a running instance.
+func runCtl(args []string) {
+	if len(args) < 2 {
+		fmt.Fprintln(os.Stderr, ctlUsage)
+		os.Exit(daemon.ExitUsage)
+	}
+	group, cmd := args[0], args[1]
+	fs := flag.NewFlagSet("ctl "+group+" "+cmd, flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file (to find the socket)")
+	socket := fs.String("socket", "", "admin socket path; overrides -config")
+	asJSON := fs.Bool("json", false, "print the raw JSON answer")
+	limit := fs.Int("limit", 50, "sessions list, sessions messages: how many sessions or messages")
+	after := fs.Int64("after", 0, "sessions messages: start after this message seq")
+	before := fs.Int64("before", 0, "sessions messages: end before this message seq, paging backward")
+	since := fs.String("since", "24h", "usage report: look-back window, e.g. 12h or 7d")
+	by := fs.String("by", "entity", "usage report: entity, group, channel, model or kind")
+	dryRun := fs.Bool("dry-run", false, "actor forget: report what would be deleted without deleting it")
+	_ = fs.Parse(args[2:])
+	rest := fs.Args()
+
+	path := *socket
+	if path == "" && *configPath != "" {
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+			os.Exit(daemon.ExitConfig)
+		}
+		abs, err := filepath.Abs(*configPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error resolving config path: %v\n", err)
+			os.Exit(daemon.ExitConfig)
+		}
+		cfg.State.DataDir = config.ResolveStateDataDir(cfg, abs)
+		path = ctlSocketPath(cfg)
+	}
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "Error: pass -config <path> or -socket <path>; the config must not set ctl.disabled.")
+		os.Exit(daemon.ExitUsage)
+	}
+	needArg := func() string {
+		if len(rest) == 0 || strings.TrimSpace(rest[0]) == "" {
+			fmt.Fprintf(os.Stderr, "ctl %s %s needs an argument.\n%s\n", group, cmd, ctlUsage)
+			os.Exit(daemon.ExitUsage)
+		}
+		return rest[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	c := ctl.NewClient(path)
+	var (
+		method = http.MethodGet
+		route  string
+		query  url.Values
+		out    any
+		show   func()
+	)
+	switch group + " " + cmd {
+	case "sessions list":
+		var list []store.SessionSummary
+		route, query, out = "/sessions", url.Values{"limit": {strconv.Itoa(*limit)}}, &list
+		show = func() { printSessions(list) }
+	case "sessions show":
+		var sess state.Session
+		route, out = "/sessions/"+url.PathEscape(needArg()), &sess
+		show = func() { printSession(&sess) }
+	case "sessions messages":
+		var msgs []store.StoredMessage
+		route, out = "/sessions/"+url.PathEscape(needArg())+"/messages", &msgs
+		query = url.Values{"after": {strconv.FormatInt(*after, 10)}, "before": {strconv.FormatInt(*before, 10)}, "limit": {strconv.Itoa(*limit)}}
+		show = func() { printMessages(msgs) }
+	case "sessions delete":
+		id := needArg()
+		method, route = http.MethodDelete, "/sessions/"+url.PathEscape(id)
+		show = func() { fmt.Printf("Deleted session %s.\n", id) }
+	case "jobs list":
+		var jobs []scheduler.Job
+		route, out = "/jobs", &jobs
+		show = func() { printJobs(jobs) }
+	case "jobs run-now":
+		name := needArg()
+		var res map[string]string
+		method, route, out = http.MethodPost, "/jobs/"+url.PathEscape(name)+"/run", &res
+		show = func() { fmt.Printf("Job %s succeeded in %s.\n", name, res["duration"]) }
+	case "plugins list":
+		var names []string
+		route, out = "/plugins", &names
+		show = func() { fmt.Println(strings.Join(names, "\n")) }
+	case "plugins reload":
+		name := needArg()
+		method, route = http.MethodPost, "/plugins/"+url.PathEscape(name)+"/reload"
+		show = func() { fmt.Printf("Reloaded plugin %s.\n", name) }
+	case "memory search":
+		needArg()
+		var mems []state.Memory
+		route, query, out = "/memory", url.Values{"q": {strings.Join(rest, " ")}}, &mems
+		show = func() { printMemories(mems) }
+	case "usage report":
+		var totals []store.UsageTotal
+		route, query, out = "/usage", url.Values{"since": {*since}, "by": {*by}}, &totals
+		show = func() { printUsage(*by, totals) }
+	case "providers status":
+		var endpoints []provider.EndpointStatus
+		route, out = "/providers", &endpoints
+		show = func() { printProviders(endpoints) }
+	case "actor export":
+		// An export is JSON either way.
+		route, *asJSON = "/actors/"+url.PathEscape(needArg())+"/export", true
+	case "actor forget":
+		id := needArg()
+		var res ctl.ActorForget
+		method, route, out = http.MethodPost, "/actors/"+url.PathEscape(id)+"/forget", &res
+		query = url.Values{"dry_run": {strconv.FormatBool(*dryRun)}}
+		show = func() { printForget(id, res) }
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown ctl command %q.\n%s\n", group+" "+cmd, ctlUsage)
+		os.Exit(daemon.ExitUsage)
+	}
+
+	if *asJSON && out == nil {
+		var raw json.RawMessage
+		out = &raw
+	}
+	if err := c.Do(ctx, method, route, query, out); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(daemon.ExitFailure)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+		return
+	}
+	show()
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printSessions(list []store.SessionSummary) {
+	tw := newTable()
+	_, _ = fmt.Fprintln(tw, "ID\tENTITY\tCHANNEL\tMESSAGES\tUPDATED\tTITLE")
+	for _, s := range list {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", s.ID, s.EntityID, s.ChannelID, s.Messages, s.UpdatedAt.Local().Format(time.DateTime), s.Title)
+	}
+	_ = tw.Flush()
+}
+
+func printSession(s *state.Session) {
+	fmt.Printf("Session %s (updated %s)\n", s.ID, s.UpdatedAt.Local().Format(time.DateTime))
+	if s.Title != "" {
+		fmt.Printf("Title: %s\n", s.Title)
+	}
+	if s.Summary != "" {
+		fmt.Printf("Summary: %s\n", s.Summary)
+	}
+	for _, m := range s.Messages {
+		fmt.Printf("\n[%s]\n%s\n", m.Role, m.Content)
+	}
+}
+
+func printMessages(msgs []store.StoredMessage) {
+	for i, m := range msgs {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("#%d [%s] %s\n%s\n", m.Seq, m.Role, m.CreatedAt.Local().Format(time.DateTime), m.Content)
+	}
+}
+
+func printForget(id string, r ctl.ActorForget) {
+	if r.DryRun {
+		fmt.Printf("Dry run: forgetting %s would delete\n", id)
+	} else {
+		fmt.Printf("Forgot %s, deleting\n", id)
+	}
+	tw := newTable()
+	for _, row := range []struct {
+		what string
+		n    int
+	}{
+		{"sessions", r.Sessions}, {"messages", r.Messages}, {"session events", r.Events}, {"debug events", r.DebugEvents},
+		{"scores", r.Scores}, {"checkpoints", r.Checkpoints}, {"memories", r.Memories}, {"usage records", r.Usage},
+		{"profiles", r.Profiles}, {"entities", r.Entities}, {"scheduler jobs", len(r.Jobs)},
+	} {
+		_, _ = fmt.Fprintf(tw, "  %s\t%d\n", row.what, row.n)
+	}
+	_ = tw.Flush()
+}
+
+func printJobs(jobs []scheduler.Job) {
+	tw := newTable()
+	_, _ = fmt.Fprintln(tw, "NAME\tSCHEDULE\tACTION\tSOURCE\tPAUSED")
+	for _, j := range jobs {
+		sched := j.Interval
+		switch {
+		case j.Cron != "":
+			sched = "cron " + j.Cron
+		case j.At != "":
+			sched = "at " + j.At
+		case sched != "":
+			sched = "every " + sched
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\n", j.Name, sched, j.Action, j.Source, j.Paused)
+	}
+	_ = tw.Flush()
+}
+
+func printMemories(mems []state.Memory) {
+	if len(mems) == 0 {
+		fmt.Println("No memories found.")
+		return
+	}
+	for _, m := range mems {
+		tags := ""
+		if len(m.Tags) > 0 {
+			tags = " [" + strings.Join(m.Tags, ", ") + "]"
+		}
+		fmt.Printf("%s  %s%s\n  %s\n", m.ID, m.CreatedAt.Local().Format(time.DateTime), tags, m.Content)
+	}
+}
+
+func printUsage(by string, totals []store.UsageTotal) {
+	tw := newTable()
+	_, _ = fmt.Fprintf(tw, "%s\tRUNS\tINPUT\tOUTPUT\tTOOL CALLS\tCOST\n", strings.ToUpper(by))
+	for _, t := range totals {
+		key := t.Key
+		if key == "" {
+			key = "(none)"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%d\t%.4f\n", key, t.Runs, t.InputTokens, t.OutputTokens, t.ToolCalls, t.Cost)
+	}
+	_ = tw.Flush()
+}
+
+func printProviders(endpoints []provider.EndpointStatus) {
+	if len(endpoints) == 0 {
+		fmt.Println("No failover configured: a single provider serves every request.")
+		return
+	}
+	tw := newTable()
+	_, _ = fmt.Fprintln(tw, "ENDPOINT\tROLE\tSTATE\tREQUESTS\tSUCCESS\tLAST ERROR")
+	for _, e := range endpoints {
+		name := e.Provider
+		if e.Model != "" {
+			name += "/" + e.Model
+		}
+		health := "healthy"
+		if !e.Healthy {
+			health = "unhealthy"
+			if !e.UnhealthySince.IsZero() {
+				health += " since " + e.UnhealthySince.Local().Format(time.DateTime)
+			}
+		}
+		if e.Active {
+			health += ", active"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%.0f%%\t%s\n", name, e.Role, health, e.Requests, e.SuccessRate*100, e.LastError)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/sessioncache"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+func TestCtlSocketPath(t *testing.T) {
+	cfg := &config.Config{}
+	if got := ctlSocketPath(cfg); got != "" {
+		t.Errorf("no data dir: %q; want none", got)
+	}
+	cfg.State.DataDir = "/var/lib/opentalon"
+	if got := ctlSocketPath(cfg); got != filepath.Join("/var/lib/opentalon", "ctl.sock") {
+		t.Errorf("default = %q", got)
+	}
+	cfg.Ctl.Socket = "/run/opentalon/ctl.sock"
+	if got := ctlSocketPath(cfg); got != "/run/opentalon/ctl.sock" {
+		t.Errorf("configured = %q", got)
+	}
+	cfg.Ctl.Disabled = true
+	if got := ctlSocketPath(cfg); got != "" {
+		t.Errorf("disabled = %q", got)
+	}
+}
+
+func TestStartCtl_InMemoryState(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	cfg := &config.Config{}
+	cfg.Ctl.Socket = filepath.Join(dir, "ctl.sock")
+
+	memory := state.NewMemoryStore("")
+	memory.Add("the deploy window is Tuesday")
+	stop := startCtl(cfg, adminBackends(ctl.Backends{}, state.NewSessionStore(""), memory, nil, nil))
+	defer stop()
+
+	c := ctl.NewClient(cfg.Ctl.Socket)
+	ctx := context.Background()
+	var mems []state.Memory
+	if err := c.Do(ctx, http.MethodGet, "/memory", map[string][]string{"q": {"deploy"}}, &mems); err != nil || len(mems) != 1 {
+		t.Errorf("memory search over in-memory state = %+v, %v", mems, err)
+	}
+	// The in-memory session store cannot list summaries, and there is no
+	// usage table without the state database.
+	for _, route := range []string{"/sessions", "/usage"} {
+		if err := c.Do(ctx, http.MethodGet, route, nil, nil); err == nil {
+			t.Errorf("%s should be unavailable without the state database", route)
+		}
+	}
+}
+
+func TestAdminBackends_SessionCache(t *testing.T) {
+	db, err := store.Open(config.DBConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer func() { _ = client.Close() }()
+	sessions := sessioncache.New(store.NewSessionStore(db, 0, 0), client, 0)
+	sessions.Create("web:c1", "e1", "", "chat")
+	if err := sessions.AddMessage("web:c1", provider.Message{Role: provider.RoleUser, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := adminBackends(ctl.Backends{}, sessions, state.NewMemoryStore(""), nil, nil)
+	if b.Sessions == nil {
+		t.Fatal("sessions admin not available with the session cache on")
+	}
+	ctx := context.Background()
+	if list, err := b.Sessions.ListSummaries(ctx, 10); err != nil || len(list) != 1 {
+		t.Errorf("summaries = %+v, %v", list, err)
+	}
+	if msgs, err := b.Sessions.Messages(ctx, "web:c1", store.MessagePage{Limit: 10}); err != nil || len(msgs) != 1 {
+		t.Errorf("messages = %+v, %v", msgs, err)
+	}
+	if _, err := sessions.Get("web:c1"); err != nil { // cache the session
+		t.Fatal(err)
+	}
+	if err := b.Sessions.Delete("web:c1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.Get("web:c1"); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("deleted session still served from the cache: %v", err)
+	}
+
+	// Erasing an actor drops their cached sessions too.
+	sessions.Create("web:c2", "e1", "", "chat")
+	b = adminBackends(ctl.Backends{}, sessions, state.NewMemoryStore(""), nil, store.NewActorDataStore(db))
+	if _, err := sessions.Get("web:c2"); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := b.Actors.Forget(ctx, "e1", false); err != nil || r.Sessions != 1 {
+		t.Fatalf("forget = %+v, %v", r, err)
+	}
+	if _, err := sessions.Get("web:c2"); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("erased session still served from the cache: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/daemon"
+	"github.com/opentalon/opentalon/internal/dashboard"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+// startDashboard serves the web admin dashboard when dashboard.addr is set.
+// The returned func stops it.
+func startDashboard(cfg config.DashboardConfig, admin ctl.Backends, plugins dashboard.PluginHealth, usage *store.UsageStore, events *eventbus.Bus) func() {
+	if cfg.Addr == "" {
+		return func() {}
+	}
+	if cfg.Token == "" {
+		fmt.Fprintf(os.Stderr, "dashboard.addr requires dashboard.token\n")
+		os.Exit(daemon.ExitConfig)
+	}
+	activity := dashboard.NewActivity(0)
+	activity.Attach(events)
+	src := dashboard.Sources{Admin: admin, Plugins: plugins, Activity: activity}
+	if usage != nil {
+		src.Usage = usage
+	}
+	srv := &http.Server{Addr: cfg.Addr, Handler: dashboard.NewHandler(cfg.Token, src), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		slog.Info("dashboard listening", "component", "dashboard", "addr", cfg.Addr)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("dashboard error", "component", "dashboard", "error", err)
+		}
+	}()
+	return func() { _ = srv.Shutdown(context.Background()) }
+}
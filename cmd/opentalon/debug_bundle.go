@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/debugbundle"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// runDebugBundle implements `opentalon debug-bundle`: it writes a tar.gz
+// with the sanitized config, log tails, lock files, scheduler state, data
+// directory listing and provider health for attaching to bug reports.
+func runDebugBundle(args []string) {
+	fs := flag.NewFlagSet("debug-bundle", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	out := fs.String("o", "", "output archive (default opentalon-debug-<time>.tar.gz in the current directory)")
+	logs := fs.String("logs", "", "comma-separated log files to include (their last -log-lines lines)")
+	logLines := fs.Int("log-lines", debugbundle.DefaultLogLines, "lines kept from the end of each log file")
+	noProbe := fs.Bool("no-probe", false, "skip probing provider endpoints")
+	_ = fs.Parse(args)
+
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, "Usage: opentalon debug-bundle -config <path> [-o bundle.tar.gz] [-logs a.log,b.log]")
+		os.Exit(1)
+	}
+	opts := debugbundle.Options{ConfigPath: *configPath, LogLines: *logLines}
+	for _, l := range strings.Split(*logs, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			opts.LogFiles = append(opts.LogFiles, l)
+		}
+	}
+	// A config that no longer loads is a common reason to file a bug: the
+	// bundle still gets the (redacted) file, just no data dir or probes.
+	if cfg, err := config.Load(*configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: config does not load (%v); collecting what is available.\n", err)
+	} else {
+		if abs, err := filepath.Abs(*configPath); err == nil {
+			opts.DataDir = config.ResolveStateDataDir(cfg, abs)
+		}
+		if !*noProbe {
+			opts.Probes = providerProbes(cfg)
+		}
+	}
+
+	path := *out
+	if path == "" {
+		path = "opentalon-debug-" + time.Now().UTC().Format("20060102-150405") + ".tar.gz"
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	names, err := debugbundle.Write(context.Background(), f, opts)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		fmt.Fprintf(os.Stderr, "Error writing debug bundle: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s (%s).\nSecrets are redacted, but review the archive before sharing it.\n", path, strings.Join(names, ", "))
+}
+
+// providerProbes returns one health probe per configured provider, against
+// the same endpoint routing.health uses. Providers without a base_url use a
+// built-in endpoint and are listed unprobed.
+func providerProbes(cfg *config.Config) []debugbundle.Probe {
+	ids := make([]string, 0, len(cfg.Models.Providers))
+	for id := range cfg.Models.Providers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	probes := make([]debugbundle.Probe, 0, len(ids))
+	for _, id := range ids {
+		pc := cfg.Models.Providers[id]
+		p := debugbundle.Probe{Name: id}
+		if pc.BaseURL != "" {
+			p.URL = healthProbeURL(cfg, pc)
+			p.Check = provider.NewHTTPHealthProbe(p.URL, pc.APIKey, nil)
+		}
+		probes = append(probes, p)
+	}
+	return probes
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/digest"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+// validateDigestReports checks digest.reports so a broken template or a
+// report without a destination fails the boot instead of every run.
+func validateDigestReports(reports []config.DigestReportConfig) error {
+	seen := make(map[string]bool, len(reports))
+	for i, r := range reports {
+		if r.Name == "" {
+			return fmt.Errorf("digest.reports[%d]: name is required", i)
+		}
+		if seen[r.Name] {
+			return fmt.Errorf("digest.reports[%d]: duplicate name %q", i, r.Name)
+		}
+		seen[r.Name] = true
+		if (r.Cron == "") == (r.Interval == "") {
+			return fmt.Errorf("digest report %q: set exactly one of cron and interval", r.Name)
+		}
+		if r.Channel == "" || r.ConversationID == "" {
+			return fmt.Errorf("digest report %q: channel and conversation_id are required", r.Name)
+		}
+		if r.Period != "" {
+			if d, err := time.ParseDuration(r.Period); err != nil || d <= 0 {
+				return fmt.Errorf("digest report %q: invalid period %q", r.Name, r.Period)
+			}
+		}
+		if _, err := digest.ParseTemplate(r.Template); err != nil {
+			return fmt.Errorf("digest report %q: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// digestJobs turns digest.reports into scheduler jobs that run the digest
+// tool and post its report; they are static jobs, the only ones the tool
+// answers.
+func digestJobs(reports []config.DigestReportConfig) []scheduler.Job {
+	jobs := make([]scheduler.Job, 0, len(reports))
+	for _, r := range reports {
+		jobs = append(jobs, scheduler.Job{
+			Name:     "digest-" + r.Name,
+			Cron:     r.Cron,
+			Interval: r.Interval,
+			Action:   digest.ToolName + "__" + digest.CompileAction,
+			Args: map[string]string{
+				"title":    r.Title,
+				"period":   r.Period,
+				"template": r.Template,
+			},
+			NotifyChannel:        r.Channel,
+			NotifyConversationID: r.ConversationID,
+		})
+	}
+	return jobs
+}
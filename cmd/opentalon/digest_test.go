@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/config"
+)
+
+func TestValidateDigestReports(t *testing.T) {
+	ok := config.DigestReportConfig{Name: "daily", Cron: "0 9 * * *", Channel: "slack", ConversationID: "C1"}
+	for _, tc := range []struct {
+		edit func(*config.DigestReportConfig)
+		want string
+	}{
+		{func(*config.DigestReportConfig) {}, ""},
+		{func(r *config.DigestReportConfig) { r.Name = "" }, "name is required"},
+		{func(r *config.DigestReportConfig) { r.Interval = "24h" }, "exactly one of cron and interval"},
+		{func(r *config.DigestReportConfig) { r.ConversationID = "" }, "conversation_id are required"},
+		{func(r *config.DigestReportConfig) { r.Period = "week" }, `invalid period "week"`},
+		{func(r *config.DigestReportConfig) { r.Template = "{{.Title" }, "digest template"},
+	} {
+		r := ok
+		tc.edit(&r)
+		err := validateDigestReports([]config.DigestReportConfig{r})
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%+v: err = %v, want %q", r, err, tc.want)
+		}
+	}
+	if err := validateDigestReports([]config.DigestReportConfig{ok, ok}); err == nil || !strings.Contains(err.Error(), "duplicate name") {
+		t.Errorf("duplicate: err = %v", err)
+	}
+}
+
+func TestStaticSchedulerJobs_Digests(t *testing.T) {
+	cfg := &config.Config{
+		Scheduler: config.SchedulerConfig{Jobs: []config.JobConfig{{Name: "ping", Interval: "1h", Action: "test__ping"}}},
+		Digest: config.DigestConfig{Reports: []config.DigestReportConfig{
+			{Name: "weekly", Cron: "0 9 * * 1", Period: "168h", Title: "Week", Channel: "slack", ConversationID: "C1"},
+		}},
+	}
+	jobs := staticSchedulerJobs(cfg)
+	if len(jobs) != 2 {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	j := jobs[1]
+	if j.Name != "digest-weekly" || j.Action != "digest__compile" || j.Cron != "0 9 * * 1" ||
+		j.Args["period"] != "168h" || j.Args["title"] != "Week" || j.NotifyChannel != "slack" || j.NotifyConversationID != "C1" {
+		t.Errorf("digest job = %+v", j)
+	}
+}
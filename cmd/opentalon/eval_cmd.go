@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/daemon"
+	"github.com/opentalon/opentalon/internal/eval"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/scenarios"
+)
+
+const evalUsage = `Usage: opentalon eval -config <path> [-model provider/model|alias] [-json] [-min-pass-rate 1] <scenarios.yaml | dir>...
+  Run YAML scenarios against a configured model with stand-in tools and
+  report pass/fail with tokens and cost. Exits 1 below -min-pass-rate.`
+
+// runEval implements `opentalon eval`: the scenario suite as a regression
+// gate for prompt, model and plugin changes.
+func runEval(args []string) {
+	fs := flag.NewFlagSet("eval", flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file (providers, rules, prompt overrides)")
+	model := fs.String("model", "", "provider/model or catalog alias to run on (default routing.primary)")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	minPassRate := fs.Float64("min-pass-rate", 1, "lowest pass rate (0 to 1) that exits 0")
+	verbose := fs.Bool("v", false, "show the orchestrator's log")
+	_ = fs.Parse(args)
+	if *configPath == "" || fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, evalUsage)
+		os.Exit(daemon.ExitUsage)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(daemon.ExitConfig)
+	}
+	files, err := scenarios.LoadFiles(fs.Args()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading scenarios: %v\n", err)
+		os.Exit(daemon.ExitUsage)
+	}
+	ref := cfg.Models.ResolveAlias(*model)
+	if ref == "" {
+		ref = cfg.Routing.Primary
+	}
+	prov, modelID, _, err := buildProviderRef(cfg, ref, nil, nil, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: model %q: %v\n", ref, err)
+		os.Exit(daemon.ExitConfig)
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	res := eval.Run(ctx, files, eval.Options{
+		LLM: &defaultModelClient{provider: prov, model: modelID, models: providerModelMap(prov)},
+		Opts: orchestrator.OrchestratorOpts{
+			CustomRules:     cfg.Orchestrator.Rules,
+			PromptOverrides: promptOverrides(cfg),
+		},
+	})
+	res.Model = prov.ID() + "/" + modelID
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(res)
+	} else {
+		printEval(res)
+	}
+	if res.Total == 0 || res.PassRate < *minPassRate {
+		os.Exit(daemon.ExitFailure)
+	}
+}
+
+func printEval(res eval.EvalResult) {
+	tw := newTable()
+	_, _ = fmt.Fprintln(tw, "RESULT\tSCENARIO\tLLM CALLS\tINPUT\tOUTPUT\tCOST\tREASON")
+	for _, r := range res.Results {
+		result := "PASS"
+		if !r.Passed {
+			result = "FAIL"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%.4f\t%s\n", result, r.Name, r.LLMCalls, r.InputTokens, r.OutputTokens, r.Cost, r.Reason)
+	}
+	_ = tw.Flush()
+	fmt.Printf("\n%s: %d of %d passed (%.0f%%), %d input and %d output tokens, cost %.4f.\n",
+		res.Model, res.Passed, res.Total, res.PassRate*100, res.InputTokens, res.OutputTokens, res.Cost)
+}
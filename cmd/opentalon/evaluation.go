@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/evaluation"
+	"github.com/opentalon/opentalon/internal/metrics"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/internal/state/store"
+	"github.com/opentalon/opentalon/internal/state/store/events/emit"
+)
+
+// buildJudge builds the session judge from cfg.Evaluation. It returns nil
+// (with a warning) when there is no state database to keep scores in.
+func buildJudge(cfg *config.Config, llm *defaultModelClient, scores *store.SessionScoreStore, sessions orchestrator.SessionStoreInterface,
+	collector *metrics.Collector, debugSink provider.DebugEventSink, debugResolve provider.DebugContextResolver, eventSink emit.Sink) (*evaluation.Judge, error) {
+	ec := cfg.Evaluation
+	if ec.SampleRate < 0 || ec.SampleRate > 1 {
+		return nil, fmt.Errorf("evaluation.sample_rate must be between 0 and 1, got %v", ec.SampleRate)
+	}
+	rubric := make([]evaluation.Criterion, 0, len(ec.Rubric))
+	for i, c := range ec.Rubric {
+		if c.Name == "" || c.Description == "" {
+			return nil, fmt.Errorf("evaluation.rubric[%d]: name and description are required", i)
+		}
+		rubric = append(rubric, evaluation.Criterion{Name: c.Name, Description: c.Description})
+	}
+	if scores == nil {
+		slog.Warn("evaluation is enabled but there is no state database; sessions are not scored", "component", "evaluation")
+		return nil, nil
+	}
+
+	judgeLLM := orchestrator.LLMClient(llm)
+	judgeModel := ec.Model
+	if ec.Model != "" {
+		prov, model, _, err := buildProviderRef(cfg, ec.Model, debugSink, debugResolve, eventSink)
+		if err != nil {
+			return nil, fmt.Errorf("evaluation.model: %w", err)
+		}
+		judgeLLM = &defaultModelClient{provider: prov, model: model, models: providerModelMap(prov)}
+	} else {
+		_, judgeModel, _ = llm.snapshot()
+	}
+
+	var observe func(evaluation.Score)
+	if collector != nil {
+		observe = func(s evaluation.Score) { collector.ObserveSessionScore(s.Criterion, s.ModelID, s.Variant, s.Score) }
+	}
+	return evaluation.New(evaluation.Options{
+		LLM:        judgeLLM,
+		JudgeModel: judgeModel,
+		Rubric:     rubric,
+		SampleRate: ec.SampleRate,
+		Sessions:   sessions,
+		Store:      scores,
+		ModelFor: func(sess *state.Session) string {
+			if sess.ActiveModel != "" {
+				return string(sess.ActiveModel)
+			}
+			_, model, _ := llm.snapshot()
+			return model
+		},
+		Observe: observe,
+	}), nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/state/store/events/emit"
+)
+
+func TestBuildJudgeValidatesConfig(t *testing.T) {
+	llm := &defaultModelClient{model: "m"}
+	for name, ec := range map[string]config.EvaluationConfig{
+		"sample_rate": {Enabled: true, SampleRate: 1.5},
+		"rubric":      {Enabled: true, Rubric: []config.EvaluationCriterion{{Name: "tone"}}},
+	} {
+		cfg := &config.Config{Evaluation: ec}
+		if _, err := buildJudge(cfg, llm, nil, nil, nil, nil, nil, emit.NoOpSink{}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	cfg := &config.Config{Evaluation: config.EvaluationConfig{Enabled: true, SampleRate: 0.25}}
+	judge, err := buildJudge(cfg, llm, nil, nil, nil, nil, nil, emit.NoOpSink{})
+	if err != nil || judge != nil {
+		t.Errorf("without a state database: judge = %v, err = %v; want nil, nil", judge, err)
+	}
+}
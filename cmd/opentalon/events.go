@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/lua"
+)
+
+// actionRunner is the orchestrator surface event subscriptions need.
+type actionRunner interface {
+	RunAction(ctx context.Context, plugin, action string, args map[string]string) (string, error)
+}
+
+// validateEventSubscriptions checks events.subscriptions so a typo in an
+// event type or a missing action fails the boot instead of never firing.
+func validateEventSubscriptions(subs []config.EventSubscription) error {
+	for i, sub := range subs {
+		if sub.Plugin == "" {
+			return fmt.Errorf("events.subscriptions[%d]: plugin is required", i)
+		}
+		if !strings.HasPrefix(sub.Plugin, "lua:") && sub.Action == "" {
+			return fmt.Errorf("events.subscriptions[%d]: action is required for plugin %q", i, sub.Plugin)
+		}
+		if len(sub.Events) == 0 {
+			return fmt.Errorf("events.subscriptions[%d]: events is required", i)
+		}
+		for _, t := range sub.Events {
+			if !eventbus.Known(t) {
+				return fmt.Errorf("events.subscriptions[%d]: unknown event type %q (known: %s)", i, t, strings.Join(eventbus.Types, ", "))
+			}
+		}
+	}
+	return nil
+}
+
+// subscribeEvents registers each subscription on bus. Plugin subscribers get
+// the event as action arguments; Lua subscribers get it as on_event's table.
+func subscribeEvents(bus *eventbus.Bus, subs []config.EventSubscription, runner actionRunner, luaScriptPaths map[string]string, luaOpts lua.Options) {
+	for _, sub := range subs {
+		var name string
+		var h eventbus.Handler
+		if script, ok := strings.CutPrefix(sub.Plugin, "lua:"); ok {
+			name = sub.Plugin
+			h = func(ctx context.Context, e eventbus.Event) error {
+				path, ok := luaScriptPaths[script]
+				if !ok {
+					return fmt.Errorf("lua script %q not found", script)
+				}
+				return lua.RunEvent(ctx, path, luaOpts, eventFields(e))
+			}
+		} else {
+			plugin, action := sub.Plugin, sub.Action
+			name = plugin + "." + action
+			h = func(ctx context.Context, e eventbus.Event) error {
+				_, err := runner.RunAction(ctx, plugin, action, eventFields(e))
+				return err
+			}
+		}
+		for _, t := range sub.Events {
+			bus.Subscribe(t, name, h)
+		}
+	}
+}
+
+// eventFields flattens e into string fields: type, session_id, time and
+// the data keys.
+func eventFields(e eventbus.Event) map[string]string {
+	fields := make(map[string]string, len(e.Data)+3)
+	for k, v := range e.Data {
+		fields[k] = v
+	}
+	fields["type"] = e.Type
+	fields["session_id"] = e.SessionID
+	fields["time"] = e.Time.Format(time.RFC3339)
+	return fields
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/lua"
+)
+
+type recordingRunner struct {
+	calls chan map[string]string
+}
+
+func (r *recordingRunner) RunAction(_ context.Context, plugin, action string, args map[string]string) (string, error) {
+	args["_call"] = plugin + "." + action
+	r.calls <- args
+	return "", nil
+}
+
+func TestValidateEventSubscriptions(t *testing.T) {
+	for _, tc := range []struct {
+		sub  config.EventSubscription
+		want string
+	}{
+		{config.EventSubscription{Events: []string{"session_completed"}, Plugin: "score", Action: "grade"}, ""},
+		{config.EventSubscription{Events: []string{"*"}, Plugin: "lua:stats"}, ""},
+		{config.EventSubscription{Events: []string{"session_archived"}, Plugin: "score", Action: "grade"}, "unknown event type"},
+		{config.EventSubscription{Events: []string{"job_run"}, Plugin: "score"}, "action is required"},
+		{config.EventSubscription{Plugin: "score", Action: "grade"}, "events is required"},
+	} {
+		err := validateEventSubscriptions([]config.EventSubscription{tc.sub})
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Errorf("%+v: err = %v, want %q", tc.sub, err, tc.want)
+		}
+	}
+}
+
+func TestSubscribeEventsRunsPluginAction(t *testing.T) {
+	bus := eventbus.New(10)
+	defer bus.Close(context.Background())
+	runner := &recordingRunner{calls: make(chan map[string]string, 1)}
+	subscribeEvents(bus, []config.EventSubscription{
+		{Events: []string{eventbus.ToolExecuted}, Plugin: "score", Action: "record"},
+	}, runner, nil, lua.Options{})
+
+	bus.Publish(context.Background(), eventbus.Event{
+		Type: eventbus.ToolExecuted, SessionID: "web:c1", Data: map[string]string{"plugin": "gitlab"},
+	})
+	select {
+	case args := <-runner.calls:
+		if args["_call"] != "score.record" || args["type"] != "tool_executed" || args["session_id"] != "web:c1" || args["plugin"] != "gitlab" || args["time"] == "" {
+			t.Errorf("args = %v", args)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("subscriber action not run")
+	}
+}
@@ -1,17 +1,25 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
+	"maps"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
+	"text/template"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -20,32 +28,50 @@ import (
 	"google.golang.org/grpc/status"
 
 	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/actorprofile"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/blob"
 	"github.com/opentalon/opentalon/internal/bootstrap"
 	"github.com/opentalon/opentalon/internal/bundle"
 	"github.com/opentalon/opentalon/internal/channel"
 	"github.com/opentalon/opentalon/internal/commands"
 	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/daemon"
 	"github.com/opentalon/opentalon/internal/dedup"
+	"github.com/opentalon/opentalon/internal/digest"
+	"github.com/opentalon/opentalon/internal/evaluation"
+	"github.com/opentalon/opentalon/internal/eventbus"
 	"github.com/opentalon/opentalon/internal/eventwebhook"
+	"github.com/opentalon/opentalon/internal/failover"
 	"github.com/opentalon/opentalon/internal/health"
 	"github.com/opentalon/opentalon/internal/logger"
 	"github.com/opentalon/opentalon/internal/metrics"
+	"github.com/opentalon/opentalon/internal/notify"
 	"github.com/opentalon/opentalon/internal/orchestrator"
 	"github.com/opentalon/opentalon/internal/pipeline"
 	"github.com/opentalon/opentalon/internal/plugin"
 	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/promotion"
 	"github.com/opentalon/opentalon/internal/prompts"
 	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/rag"
 	"github.com/opentalon/opentalon/internal/redisclient"
 	"github.com/opentalon/opentalon/internal/reminder"
+	"github.com/opentalon/opentalon/internal/replyrelay"
 	"github.com/opentalon/opentalon/internal/requestpkg"
 	"github.com/opentalon/opentalon/internal/scheduler"
+	"github.com/opentalon/opentalon/internal/search"
+	"github.com/opentalon/opentalon/internal/sessioncache"
+	"github.com/opentalon/opentalon/internal/sessionidle"
 	"github.com/opentalon/opentalon/internal/sessionlock"
 	"github.com/opentalon/opentalon/internal/state"
 	"github.com/opentalon/opentalon/internal/state/store"
 	"github.com/opentalon/opentalon/internal/state/store/events/emit"
 	"github.com/opentalon/opentalon/internal/synclock"
+	"github.com/opentalon/opentalon/internal/usagestats"
 	"github.com/opentalon/opentalon/internal/version"
+	"github.com/opentalon/opentalon/internal/workflow"
 	chanpkg "github.com/opentalon/opentalon/pkg/channel"
 )
 
@@ -112,7 +138,8 @@ func refreshOnePlugin(ctx context.Context, name string, pm capabilityRefresher,
 		slog.Warn("refresh poll: refresh failed", "component", "refresh", "plugin", name, "error", err)
 		return
 	}
-	reg.UpdateCapability(name, fresh)
+	// fresh carries the registered name, which an alias makes differ from name.
+	reg.UpdateCapability(cmp.Or(fresh.Name, name), fresh)
 	slog.Info("refresh poll: capabilities refreshed", "component", "refresh", "plugin", name,
 		"actions", len(fresh.Actions), "knowledge", len(fresh.KnowledgeArticles))
 
@@ -135,10 +162,36 @@ func refreshOnePlugin(ctx context.Context, name string, pm capabilityRefresher,
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "debug-bundle" {
+		runDebugBundle(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		runEval(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "skill" {
+		runSkill(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ctl" {
+		runCtl(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "state" {
+		runState(os.Args[2:])
+		return
+	}
 	fmt.Fprintln(os.Stderr, "OpenTalon starting...")
 	configPath := flag.String("config", "", "path to config file")
 	showVersion := flag.Bool("version", false, "print version and exit")
+	noRemoteBuild := flag.Bool("no-remote-build", false, "refuse plugins and channels with github/ref sources (bundles.no_remote_build)")
 	cleanFlag := flag.String("clean", "", "clear cached bundles and exit (all, plugins, channels, skills, lua_plugins); requires -config")
+	daemonFlag := flag.Bool("daemon", false, "run as a service: sd_notify readiness, PID and health files (daemon.*), Windows service support")
 	flag.Parse()
 
 	if *showVersion {
@@ -154,20 +207,35 @@ func main() {
 	if *configPath == "" {
 		fmt.Fprintln(os.Stderr, "Usage: opentalon -config <path>")
 		fmt.Fprintln(os.Stderr, "  Run OpenTalon with the given config. Use config.example.yaml as a template.")
-		os.Exit(1)
+		fmt.Fprintln(os.Stderr, "       opentalon debug-bundle -config <path>")
+		fmt.Fprintln(os.Stderr, "  Collect a redacted support archive for bug reports.")
+		fmt.Fprintln(os.Stderr, "       opentalon skill search|install|update|list|pin -config <path> [args]")
+		fmt.Fprintln(os.Stderr, "  Manage skills from the configured skills index.")
+		fmt.Fprintln(os.Stderr, "       opentalon ctl sessions|jobs|plugins|memory|usage ... -config <path>")
+		fmt.Fprintln(os.Stderr, "  Inspect and manage a running instance over its admin socket.")
+		fmt.Fprintln(os.Stderr, "       opentalon state backup|maintain|rotate-key|keygen -config <path> [args]")
+		fmt.Fprintln(os.Stderr, "  Back up, prune and vacuum the state database.")
+		fmt.Fprintln(os.Stderr, "       opentalon eval -config <path> <scenarios.yaml | dir>...")
+		fmt.Fprintln(os.Stderr, "  Run evaluation scenarios against a configured model.")
+		fmt.Fprintln(os.Stderr, "       opentalon replay <session.json>")
+		fmt.Fprintln(os.Stderr, "  Replay a recorded session against this build to catch regressions.")
+		os.Exit(daemon.ExitUsage)
 	}
 
 	cfg, err := config.Load(*configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(daemon.ExitConfig)
 	}
 	absConfigPath, err := filepath.Abs(*configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error resolving config path: %v\n", err)
-		os.Exit(1)
+		os.Exit(daemon.ExitConfig)
 	}
 	cfg.State.DataDir = config.ResolveStateDataDir(cfg, absConfigPath)
+	if *noRemoteBuild {
+		cfg.Bundles.NoRemoteBuild = true
+	}
 
 	// Configure structured logging (stdout/stderr, level-filtered).
 	logLevel := cfg.Log.Level
@@ -176,6 +244,27 @@ func main() {
 	}
 	logger.Setup(logLevel)
 
+	// Daemon mode: report readiness to the service manager and keep the
+	// PID/health files. sup stays nil otherwise; its methods are no-ops.
+	var sup *daemon.Supervisor
+	if *daemonFlag {
+		sup, err = daemon.Start(daemon.Options{
+			PIDFile:        cfg.Daemon.PIDFile,
+			HealthFile:     cfg.Daemon.HealthFile,
+			HealthInterval: parseDurationOrZero(cfg.Daemon.HealthInterval),
+			ServiceName:    cfg.Daemon.ServiceName,
+		})
+		if errors.Is(err, daemon.ErrAlreadyRunning) {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(daemon.ExitAlreadyRunning)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error starting daemon mode: %v\n", err)
+			os.Exit(daemon.ExitFailure)
+		}
+		defer sup.Close()
+	}
+
 	// Start gRPC health probe server (always on).
 	healthSrv := health.New(cfg.Health.Addr)
 	go func() {
@@ -211,7 +300,7 @@ func main() {
 		if err != nil {
 			if cfg.Bootstrap.Required {
 				fmt.Fprintf(os.Stderr, "Error fetching bootstrap config (required=true): %v\n", err)
-				os.Exit(1)
+				os.Exit(daemon.ExitUnavailable)
 			}
 			slog.Warn("bootstrap fetch failed, proceeding with static config only", "error", err)
 		} else {
@@ -235,6 +324,10 @@ func main() {
 	var sessions orchestrator.SessionStoreInterface
 	var groupPluginStore *store.GroupPluginStore
 	var usageStore *store.UsageStore
+	var activityStore *store.ActivityStore
+	var actorData *store.ActorDataStore // export and erasure over the admin API; nil without a state DB
+	var scoreStore *store.SessionScoreStore
+	var actorProfiles orchestrator.ActorProfileStore = state.NewActorProfileStore()
 	var entityStore *store.EntityStore
 	var debugStore *store.DebugEventStore
 	var debugWriter *store.DebugEventWriter
@@ -242,13 +335,30 @@ func main() {
 	var sessionEventStore *store.SessionEventStore
 	var sessionEventWriter *store.SessionEventWriter
 	var sessionEventsRetentionCancel context.CancelFunc
+	var maintenanceCancel context.CancelFunc
 	var eventWebhookSink *eventwebhook.Sink
+	var blobRefs blob.RefSource // live blob references for GC; nil without a state DB
+	// runCheckpoints backs orchestrator.resume; nil without a state DB.
+	var runCheckpoints orchestrator.RunCheckpointStore
+	// toolCacheStore is the persistent tier of orchestrator.tool_cache.
+	var toolCacheStore orchestrator.ToolCacheStore
 	// injectionStateStore persists load_tools sticky promotion (the
 	// per-session KnownTools set) across turns — the DB-backed SessionStore
 	// satisfies it, the in-memory fallback does not. Stays nil when the
 	// state DB is unavailable; load_tools promotion is then best-effort and
 	// does not survive past the request.
 	var injectionStateStore orchestrator.InjectionStateStore
+	// schedulerJobs shares dynamic scheduler jobs and run claims between
+	// replicas on a Postgres state DB; nil keeps them in dataDir.
+	var schedulerJobs scheduler.JobStore
+	// searchIndex backs the search tool; nil unless search.enabled and the
+	// state DB opened.
+	var searchIndex *store.SearchIndex
+	stateCipher, err := loadStateCipher(cfg.State.Encryption)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid state.encryption: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
 	if dataDir != "" || cfg.State.DB.Driver == "postgres" {
 		db, err := store.Open(cfg.State.DB, dataDir)
 		if err != nil {
@@ -256,6 +366,7 @@ func main() {
 			memory, sessions = newInMemoryState()
 		} else {
 			defer func() { _ = db.Close() }()
+			db.SetCipher(stateCipher)
 			memory = store.NewMemoryStore(db)
 			sessStore := store.NewSessionStore(db, cfg.State.Session.MaxMessages, cfg.State.Session.MaxIdleDays)
 			if err := sessStore.PruneIdleSessions(); err != nil {
@@ -263,9 +374,28 @@ func main() {
 			}
 			sessions = sessStore
 			injectionStateStore = sessStore
+			blobRefs = sessStore.BlobRefs
 			groupPluginStore = store.NewGroupPluginStore(db)
 			usageStore = store.NewUsageStore(db)
+			activityStore = store.NewActivityStore(db)
+			actorData = store.NewActorDataStore(db)
+			scoreStore = store.NewSessionScoreStore(db)
+			actorProfiles = store.NewActorProfileStore(db)
 			entityStore = store.NewEntityStore(db)
+			if cfg.Orchestrator.Resume.Enabled {
+				runCheckpoints = store.NewRunCheckpointStore(db)
+			}
+			if cfg.Orchestrator.ToolCache.Persist {
+				toolCacheStore = store.NewToolCacheStore(db)
+			}
+			if cfg.State.DB.Driver == "postgres" {
+				schedulerJobs = store.NewSchedulerJobStore(db)
+			}
+			if cfg.Search.Enabled {
+				if searchIndex, err = store.NewSearchIndex(context.Background(), db); err != nil {
+					slog.Warn("search index creation failed", "error", err)
+				}
+			}
 			debugStore = store.NewDebugEventStore(db)
 			debugWriter = store.NewDebugEventWriter(debugStore)
 			debugWriter.Start(context.Background())
@@ -304,6 +434,11 @@ func main() {
 			var sessionEventsRetentionCtx context.Context
 			sessionEventsRetentionCtx, sessionEventsRetentionCancel = context.WithCancel(context.Background())
 			go store.RunSessionEventsRetention(sessionEventsRetentionCtx, sessionEventStore, sessionEventsRetention)
+			if interval := parseDurationOrZero(cfg.State.Maintenance.Interval); interval > 0 {
+				var maintenanceCtx context.Context
+				maintenanceCtx, maintenanceCancel = context.WithCancel(context.Background())
+				go store.RunMaintenance(maintenanceCtx, db, interval, maintenancePolicy(cfg.State.Maintenance))
+			}
 			// Seed static group→plugin assignments from config (source="config"; does not overwrite whoami/admin).
 			seedGroupPlugins(context.Background(), groupPluginStore, cfg.Profiles.Groups)
 			// Seed group→plugin assignments from remote bootstrap response (source="bootstrap"; lower priority than "config", does not overwrite whoami/admin).
@@ -313,6 +448,49 @@ func main() {
 		memory, sessions = newInMemoryState()
 	}
 
+	// Build a single shared Redis client when cluster dedup, plugin exec, or both need it.
+	// Sharing one pool halves connection count compared to opening two clients to the same instance.
+	// Hoisted right after the state stores so the session cache can wrap
+	// them before anything holds the session store, and before the
+	// orchestrator so the session-turn lease (and the sync lock) can use it.
+	needsRedis := cfg.Cluster.Enabled || cfg.PluginExec.Enabled
+	var sharedRedis redis.UniversalClient
+	if needsRedis && (cfg.Redis.RedisURL != "" || len(cfg.Redis.Sentinels) > 0) {
+		var err error
+		sharedRedis, err = redisclient.New(
+			cfg.Redis.RedisURL,
+			cfg.Redis.MasterName,
+			cfg.Redis.Sentinels,
+			cfg.Redis.Password,
+			cfg.Redis.SentinelPassword,
+		)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error connecting to Redis: %v\n", err)
+			os.Exit(daemon.ExitUnavailable) //nolint:gocritic
+		}
+		defer func() { _ = sharedRedis.Close() }()
+	}
+	if cfg.Cluster.Enabled && sharedRedis == nil {
+		fmt.Fprintf(os.Stderr, "cluster.enabled requires redis.redis_url or redis.sentinels to be configured\n")
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+
+	// Session read-through cache: with several pods on one state DB, every
+	// turn's session read goes to Redis first. Writes through this wrapper
+	// invalidate the cached copy on all pods.
+	if cfg.Cluster.Enabled && cfg.Cluster.SessionCache && sharedRedis != nil {
+		ttl := sessioncache.DefaultTTL
+		if cfg.Cluster.SessionCacheTTL != "" {
+			if d, err := time.ParseDuration(cfg.Cluster.SessionCacheTTL); err == nil && d > 0 {
+				ttl = d
+			} else {
+				slog.Warn("invalid cluster.session_cache_ttl, using default", "value", cfg.Cluster.SessionCacheTTL, "default", ttl)
+			}
+		}
+		sessions = sessioncache.New(sessions, sharedRedis, ttl)
+		slog.Info("cluster session cache enabled", "ttl", ttl)
+	}
+
 	// Build the resolver for raw-HTTP capture: trace_id is derived from the
 	// session key and stamped onto ctx by orchestrator.Run; logger.IsSession-
 	// Debug reflects whether the session has metadata["debug"]=true. When
@@ -360,7 +538,7 @@ func main() {
 		})
 		if werr != nil {
 			fmt.Fprintf(os.Stderr, "Error building event webhook: %v\n", werr)
-			os.Exit(1) //nolint:gocritic // matches the other main()-level fatal config paths
+			os.Exit(daemon.ExitConfig) //nolint:gocritic // matches the other main()-level fatal config paths
 		}
 		eventWebhookSink = ws
 		// context.Background so a graceful Stop can still flush in-flight
@@ -391,21 +569,38 @@ func main() {
 		}
 	}
 
+	// Lifecycle event bus (events.subscriptions). Built before the provider
+	// so failovers are published; subscribers are attached once the
+	// orchestrator that runs their actions exists.
+	if err := validateEventSubscriptions(cfg.Events.Subscriptions); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid events config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic // matches the other main()-level fatal config paths
+	}
+	events := eventbus.New(cfg.Events.BufferSize)
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		events.Close(closeCtx)
+	}()
+
 	// Build LLM provider and default model from config. The debug sink
 	// + resolver pair feeds per-session /debug capture (either nil
 	// disables it); sessionSink captures the structured event stream
 	// for every LLM call.
-	prov, defaultModel, err := buildProvider(cfg, debugSink, debugResolver, sessionSink)
+	// provCtx bounds the health-gated provider's probe loop; a config reload
+	// that rebuilds the provider cancels it and starts a fresh one.
+	provCtx, provCancel := context.WithCancel(context.Background())
+	defer provCancel()
+	prov, defaultModel, err := buildProvider(provCtx, cfg, debugSink, debugResolver, sessionSink, events)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error building provider: %v\n", err)
-		os.Exit(1) //nolint:gocritic // matches the other main()-level fatal paths; the deferred db.Close is best-effort, the OS reclaims handles on exit
+		os.Exit(daemon.ExitConfig) //nolint:gocritic // matches the other main()-level fatal paths; the deferred db.Close is best-effort, the OS reclaims handles on exit
 	}
 
+	discoverModels(provCtx, cfg.Models.Discovery, prov)
+
 	// Build model lookup map for defaultModelClient.
-	modelMap := make(map[string]provider.ModelInfo)
-	for _, m := range prov.Models() {
-		modelMap[m.ID] = m
-	}
+	modelMap := providerModelMap(prov)
 
 	// Surface a repair.model misconfiguration at startup instead of at the
 	// first repairable failure: the corrector side-call goes through the
@@ -430,6 +625,30 @@ func main() {
 
 	// LLM client that sets default model when orchestrator doesn't
 	llm := &defaultModelClient{provider: prov, model: defaultModel, models: modelMap}
+	if lc := cfg.Orchestrator.LLMCache; lc.Enabled {
+		ttl := 10 * time.Minute
+		if lc.TTL != "" {
+			if d, err := time.ParseDuration(lc.TTL); err == nil && d > 0 {
+				ttl = d
+			} else {
+				slog.Warn("invalid orchestrator.llm_cache.ttl, using default 10m", "value", lc.TTL)
+			}
+		}
+		llm.cache = provider.NewResponseCache(ttl, lc.MaxEntries)
+		if metricsCollector != nil {
+			metricsCollector.MustRegister(
+				prometheus.NewCounterFunc(prometheus.CounterOpts{
+					Name: "opentalon_llm_cache_hits_total",
+					Help: "LLM requests answered from the response cache.",
+				}, func() float64 { return float64(llm.cache.Hits()) }),
+				prometheus.NewCounterFunc(prometheus.CounterOpts{
+					Name: "opentalon_llm_cache_misses_total",
+					Help: "Cacheable LLM requests that went to the model.",
+				}, func() float64 { return float64(llm.cache.Misses()) }),
+			)
+		}
+		slog.Info("LLM response cache enabled", "ttl", ttl)
+	}
 
 	// Look up context window and output budget for the default model. The
 	// output budget (max_tokens) is reserved from the window on every trim so
@@ -450,7 +669,9 @@ func main() {
 	for name, p := range cfg.Plugins {
 		path := p.Plugin
 		if p.GitHub != "" && p.Ref != "" {
-			resolvedPath, err := bundle.EnsurePlugin(ctx, dataDir, name, p.GitHub, p.Ref, p.Cache)
+			resolvedPath, err := bundle.EnsurePlugin(ctx, dataDir, name, p.GitHub, p.Ref, bundle.BuildOptions{
+				Cache: p.Cache, FromSource: p.FromSource, Policy: bundlePolicy(cfg.Bundles), Checksums: bundle.Checksums{Source: p.SHA256, Binary: p.BinarySHA256},
+			})
 			if err != nil {
 				slog.Warn("bundle plugin failed", "plugin", name, "error", err)
 				continue
@@ -481,7 +702,17 @@ func main() {
 			}
 		}
 		entry := plugin.PluginEntry{
-			Name: name, Plugin: path, Enabled: p.Enabled, Config: pluginCfg, ExposeHTTP: p.ExposeHTTP,
+			Name: name, Plugin: path, Enabled: p.Enabled, Config: pluginCfg, ExposeHTTP: p.ExposeHTTP, WorkDir: p.WorkDir,
+			Alias: p.Alias, Version: p.Version,
+		}
+		if p.EnvAllow != nil {
+			entry.Env = plugin.IsolatedEnv(p.EnvAllow)
+			if entry.WorkDir == "" && dataDir != "" {
+				entry.WorkDir = filepath.Join(dataDir, "plugin-work", name)
+			}
+		}
+		for k, v := range p.Env {
+			entry.WithEnvOverride(k, v)
 		}
 		if p.DialTimeout != "" {
 			if d, err := time.ParseDuration(p.DialTimeout); err == nil {
@@ -492,6 +723,9 @@ func main() {
 		}
 		pluginEntries = append(pluginEntries, entry)
 	}
+	// Load in name order so a tool name conflict always rejects the same
+	// plugin, whatever order the config map iterates in.
+	slices.SortFunc(pluginEntries, func(a, b plugin.PluginEntry) int { return strings.Compare(a.Name, b.Name) })
 	// Request packages (skill-style): loaded before plugins so MCP server configs
 	// can be injected into the MCP plugin binary's environment at launch.
 	var requestSets []requestpkg.Set
@@ -545,6 +779,9 @@ func main() {
 			slog.Warn("load skill failed", "skill", skill.Name, "dir", skillDir, "error", err)
 			continue
 		}
+		if skill.Alias != "" {
+			set.Alias = skill.Alias
+		}
 		requestSets = append(requestSets, set)
 	}
 	// Merge installed skills (persisted from /install skill) so they survive restart
@@ -571,18 +808,57 @@ func main() {
 		}
 	}
 	for _, inl := range cfg.RequestPackages.Inline {
-		set := requestpkg.Set{PluginName: inl.Plugin, Description: inl.Description, AllowedGroups: inl.AllowedGroups}
+		set := requestpkg.Set{PluginName: inl.Plugin, Alias: inl.Alias, Version: inl.Version, Description: inl.Description, AllowedGroups: inl.AllowedGroups, Config: inl.Config}
 		set.MCP = mcpConfigFromInline(inl.MCP)
+		set.Auth = requestAuthFromInline(inl.Auth)
+		if err := set.Auth.Validate(); err != nil {
+			slog.Warn("request package set ignored", "plugin", inl.Plugin, "error", err)
+			continue
+		}
 		for _, p := range inl.Packages {
 			params := make([]requestpkg.ParamDefinition, len(p.Parameters))
 			for i, q := range p.Parameters {
-				params[i] = requestpkg.ParamDefinition{Name: q.Name, Description: q.Description, Required: q.Required}
+				params[i] = requestpkg.ParamDefinition{Name: q.Name, Description: q.Description, Required: q.Required, Type: q.Type}
 			}
-			set.Packages = append(set.Packages, requestpkg.Package{
-				Action: p.Action, Description: p.Description, Method: p.Method, URL: p.URL,
-				Body: p.Body, Headers: p.Headers, RequiredEnv: p.RequiredEnv, Parameters: params,
-			})
+			pkg := requestpkg.Package{
+				Action: p.Action, Description: p.Description, Type: p.Type, Method: p.Method, URL: p.URL,
+				Body: p.Body, JSONBody: p.JSONBody, Query: p.Query, Variables: p.Variables, Headers: p.Headers, RequiredEnv: p.RequiredEnv, Parameters: params,
+				Timeout: p.Timeout, Retries: p.Retries, RetryBackoff: p.RetryBackoff, RetryOn: p.RetryOn, RateLimit: p.RateLimit,
+				ReadOnly: p.ReadOnly, CacheTTL: p.CacheTTL, InjectContextArgs: p.InjectContextArgs,
+			}
+			if p.Response != nil {
+				pkg.Response = &requestpkg.Response{Extract: p.Response.Extract, Success: p.Response.Success, Error: p.Response.Error}
+			}
+			for _, st := range p.Steps {
+				pkg.Steps = append(pkg.Steps, requestpkg.Step{
+					Name: st.Name, Method: st.Method, URL: st.URL, Body: st.Body, Headers: st.Headers, Extract: st.Extract,
+				})
+			}
+			pkg.Auth = requestAuthFromInline(p.Auth)
+			if pg := p.Paginate; pg != nil {
+				pkg.Paginate = &requestpkg.Pagination{
+					Param: pg.Param, In: pg.In, Next: pg.Next, Start: pg.Start,
+					Increment: pg.Increment, Items: pg.Items, MaxPages: pg.MaxPages,
+				}
+			}
+			if err := pkg.Validate(); err != nil {
+				slog.Warn("request package ignored", "plugin", inl.Plugin, "action", p.Action, "error", err)
+				continue
+			}
+			set.Packages = append(set.Packages, pkg)
+		}
+		requestSets = append(requestSets, set)
+	}
+	for _, oa := range cfg.RequestPackages.OpenAPI {
+		set, err := requestpkg.LoadOpenAPI(ctx, oa.Spec, requestpkg.OpenAPIOptions{
+			Plugin: oa.Plugin, Description: oa.Description, Operations: oa.Operations,
+			BaseURL: oa.BaseURL, EnvPrefix: oa.EnvPrefix, AllowedGroups: oa.AllowedGroups,
+		})
+		if err != nil {
+			slog.Warn("openapi import failed", "plugin", oa.Plugin, "spec", oa.Spec, "error", err)
+			continue
 		}
+		slog.Info("openapi spec imported", "component", "startup", "plugin", oa.Plugin, "actions", len(set.Packages))
 		requestSets = append(requestSets, set)
 	}
 
@@ -608,8 +884,17 @@ func main() {
 	if err := requestpkg.Register(toolRegistry, requestSets); err != nil {
 		slog.Warn("request_packages registration failed", "error", err)
 	}
+	luaScriptPaths := buildLuaScriptPaths(ctx, dataDir, cfg)
+	jsScriptPaths := buildJSScriptPaths(cfg)
+	luaOpts := luaOptions(cfg.Lua)
+	if cfg.Lua != nil {
+		registerScriptTools(ctx, toolRegistry, luaEngine, luaScriptPaths, cfg.Lua.Tools, luaOpts)
+	}
+	if cfg.JS != nil {
+		registerScriptTools(ctx, toolRegistry, jsEngine, jsScriptPaths, cfg.JS.Tools, luaOpts)
+	}
 
-	// Register built-in opentalon plugin (install_skill, show_config, list_commands, set_prompt, clear_session, reload_mcp)
+	// Register built-in opentalon plugin (install_skill, show_config, list_commands, capabilities, set_prompt, clear_session, reload_mcp)
 	runtimePromptPath := ""
 	if dataDir != "" {
 		runtimePromptPath = filepath.Join(dataDir, "custom_prompt.txt")
@@ -632,6 +917,9 @@ func main() {
 	if debugStore != nil {
 		cmdExecutor.WithDebugEventCounter(debugStore)
 	}
+	if cfg.Orchestrator.HelpPolish {
+		cmdExecutor.WithHelpPolish(llm)
+	}
 	if err := toolRegistry.Register(commands.Capability(), cmdExecutor); err != nil {
 		slog.Warn("register opentalon commands failed", "error", err)
 	}
@@ -647,7 +935,7 @@ func main() {
 			STT:      p.STT,
 			Insecure: true, // default: cannot run invoke
 		}
-		if !strings.HasPrefix(p.Plugin, "lua:") {
+		if !strings.HasPrefix(p.Plugin, "lua:") && !strings.HasPrefix(p.Plugin, "js:") {
 			if plug, ok := cfg.Plugins[p.Plugin]; ok && plug.Insecure != nil && !*plug.Insecure {
 				entry.Insecure = false // trusted: can invoke
 			}
@@ -666,7 +954,14 @@ func main() {
 			FailOpen: failOpen,
 		})
 	}
-	luaScriptPaths := buildLuaScriptPaths(ctx, dataDir, cfg)
+	responseModerators := make([]orchestrator.ResponseModeratorEntry, 0, len(cfg.Orchestrator.ResponseModerators))
+	for _, m := range cfg.Orchestrator.ResponseModerators {
+		responseModerators = append(responseModerators, orchestrator.ResponseModeratorEntry{
+			Plugin:   m.Plugin,
+			Action:   m.Action,
+			FailOpen: m.FailOpen,
+		})
+	}
 	var permChecker orchestrator.PermissionChecker
 	permPluginName := cfg.Orchestrator.PermissionPlugin
 	if permPluginName != "" {
@@ -724,8 +1019,14 @@ func main() {
 	}
 	// Avoid non-nil interface wrapping a nil pointer.
 	var pluginObserver orchestrator.PluginCallObserver
+	var timingObserver orchestrator.TimingObserver
+	var experimentObserver orchestrator.ExperimentObserver
+	var loopObserver orchestrator.LoopObserver
 	if metricsCollector != nil {
 		pluginObserver = metricsCollector
+		timingObserver = metricsCollector
+		experimentObserver = metricsCollector
+		loopObserver = metricsCollector
 	}
 
 	// channelNotifier carries a late-bound *channel.Registry pointer; both
@@ -736,32 +1037,6 @@ func main() {
 	// orch ← ChannelSender ← notifier ← reg ← handler ← orch cycle.
 	notifier := &channelNotifier{reg: nil}
 
-	// Build a single shared Redis client when cluster dedup, plugin exec, or both need it.
-	// Sharing one pool halves connection count compared to opening two clients to the same instance.
-	// Hoisted before the orchestrator so the session-turn lease can use it
-	// (and before sync so the sync lock can, too).
-	needsRedis := cfg.Cluster.Enabled || cfg.PluginExec.Enabled
-	var sharedRedis redis.UniversalClient
-	if needsRedis && (cfg.Redis.RedisURL != "" || len(cfg.Redis.Sentinels) > 0) {
-		var err error
-		sharedRedis, err = redisclient.New(
-			cfg.Redis.RedisURL,
-			cfg.Redis.MasterName,
-			cfg.Redis.Sentinels,
-			cfg.Redis.Password,
-			cfg.Redis.SentinelPassword,
-		)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error connecting to Redis: %v\n", err)
-			os.Exit(1) //nolint:gocritic
-		}
-		defer func() { _ = sharedRedis.Close() }()
-	}
-	if cfg.Cluster.Enabled && sharedRedis == nil {
-		fmt.Fprintf(os.Stderr, "cluster.enabled requires redis.redis_url or redis.sentinels to be configured\n")
-		os.Exit(1) //nolint:gocritic
-	}
-
 	// Cross-pod session-turn lease: in cluster mode, turns for the same
 	// session are serialized across pods via Redis; single-instance mode
 	// relies on the orchestrator's in-memory per-session mutex alone.
@@ -804,14 +1079,232 @@ func main() {
 		escalationLimit = usageStore
 	}
 
+	var systemPromptTemplate *template.Template
+	if tpl := cfg.Orchestrator.SystemPromptTemplate; tpl != "" {
+		t, err := orchestrator.ParseSystemPromptTemplate(tpl)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid orchestrator.system_prompt_template: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		systemPromptTemplate = t
+	}
+
+	agents, err := agentsFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid agents config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	experiments, err := experimentsFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid experiments config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	generation := generationFromConfig(cfg.Orchestrator.Generation)
+	if err := generation.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.generation: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	taskGeneration, err := taskGenerationFromConfig(cfg.Orchestrator.TaskGeneration)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.task_generation: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	loopLimits, err := loopLimitsFromConfig(cfg.Orchestrator.LoopLimits)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.loop_limits: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	channelLoopLimits, err := channelLoopLimitsFromConfig(cfg.Channels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid channels loop_limits: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+
+	var approvals *approval.Queue
+	if cfg.Approvals.Enabled {
+		q, err := approval.NewQueue(notifier, dataDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading approval queue: %v\n", err)
+			os.Exit(daemon.ExitFailure) //nolint:gocritic
+		}
+		approvals = q
+	}
+
+	if l := cfg.Orchestrator.ReplyLanguage; l != "" {
+		if _, ok := orchestrator.ResolveLanguage(l); !ok {
+			fmt.Fprintf(os.Stderr, "Invalid orchestrator.reply_language %q: use a language name or ISO 639-1 code\n", l)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+	}
+	channelLocales, err := localesFromConfig(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid locale: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	messages := orchestrator.MessageCatalog(cfg.Orchestrator.Messages)
+	if err := messages.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.messages: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+
+	blobs, stopBlobGC, err := openBlobStore(cfg.State, dataDir, blobRefs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid blob store config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+
+	docIndex, stopRAGSync, err := openRAGIndex(cfg, dataDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid rag config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	transcriber, speaker, speakAlways, err := speechClients(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid speech config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	var attachments orchestrator.Attachments
+	if cfg.Orchestrator.Attachments.Enabled {
+		if blobs == nil {
+			fmt.Fprintf(os.Stderr, "Invalid orchestrator.attachments config: requires state.blobs.enabled\n")
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		attachments = orchestrator.Attachments{Store: blobs, InlineBytes: cfg.Orchestrator.Attachments.InlineBytes}
+	}
+	promptLayers := orchestrator.PromptLayers{
+		Deployment:  cfg.Orchestrator.PromptLayers.Deployment,
+		Order:       cfg.Orchestrator.PromptLayers.Order,
+		Budgets:     cfg.Orchestrator.PromptLayers.Budgets,
+		TotalBudget: cfg.Orchestrator.PromptLayers.TotalBudget,
+	}
+	if err := promptLayers.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.prompt_layers config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	redaction, err := secretRedaction(cfg.Orchestrator.SecretRedaction)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.secret_redaction config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	toolScopes, err := toolScopes(cfg.Orchestrator.ToolScopes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.tool_scopes config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	toolExposure := orchestrator.ToolExposure(cfg.Orchestrator.ToolExposure)
+	if err := toolExposure.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	piiCfg := cfg.Orchestrator.PIIRedaction
+	if err := orchestrator.ValidatePIIKinds(piiCfg.Types); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.pii_redaction config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	piiRedaction := orchestrator.PIIRedaction{Enabled: piiCfg.Enabled, Kinds: piiCfg.Types, Restore: piiCfg.Restore == nil || *piiCfg.Restore}
+	toolPolicies, err := compileToolPolicies(cfg.Orchestrator.Policies, approvals != nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.policies config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	policy, err := guardPolicy(cfg.Orchestrator.Guard, requestSets, llm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid orchestrator.guard config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	var documents orchestrator.Documents
+	if docIndex != nil {
+		documents = orchestrator.Documents{Index: docIndex, InjectTopK: cfg.RAG.InjectTopK, MinScore: cfg.RAG.MinScore}
+	}
+
+	// Idle-session completion (state.session.completion): the tracker learns
+	// of every turn from the orchestrator and completes the session once it
+	// has been quiet for its timeout.
+	idleTimeoutFor, err := idleTimeouts(cfg.State.Session.Completion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid state.session.completion config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	completer := &sessionCompleter{cfg: cfg.State.Session.Completion, events: events}
+	var activityObserver orchestrator.SessionActivityObserver
+	var trackers activityObservers
+	if idleTimeoutFor != nil {
+		idleTracker := sessionidle.New(idleTimeoutFor, completer.complete)
+		defer idleTracker.Stop()
+		trackers = append(trackers, idleTracker)
+	}
+	// Idle auto-close (channels.<name>.auto_close) runs on its own tracker:
+	// its timeouts are per channel and independent of completion's.
+	autoCloseFor, closeNotices, err := autoCloseTimeouts(cfg.Channels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid channels config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	closer := &sessionCloser{notices: closeNotices, send: notifier.SendToSession, events: events}
+	if autoCloseFor != nil {
+		closeTracker := sessionidle.New(autoCloseFor, closer.close)
+		defer closeTracker.Stop()
+		trackers = append(trackers, closeTracker)
+	}
+	switch len(trackers) {
+	case 0:
+	case 1:
+		activityObserver = trackers[0]
+	default:
+		activityObserver = trackers
+	}
+
+	// Workflow recording (workflows.record): each turn's tool calls are kept
+	// for workflow__replay in the data dir, or in memory without one.
+	var recordings *workflow.Recordings
+	var workflowRecorder orchestrator.WorkflowRecorder
+	if cfg.Workflows.Record {
+		path := ""
+		if dataDir != "" {
+			path = filepath.Join(dataDir, "workflows", "recorded.yaml")
+		}
+		recordings, err = workflow.NewRecordings(path, cfg.Workflows.RecordLimit)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Loading recorded workflows: %v\n", err)
+			os.Exit(daemon.ExitFailure) //nolint:gocritic
+		}
+		workflowRecorder = recordings
+	}
+
 	orch := orchestrator.NewWithRules(llm, orchestrator.DefaultParser, toolRegistry, memory, sessions, orchestrator.OrchestratorOpts{
 		CustomRules:                   cfg.Orchestrator.Rules,
 		ContentPreparers:              contentPreparers,
 		ResponseFormatters:            responseFormatters,
+		ResponseModerators:            responseModerators,
 		LuaScriptPaths:                luaScriptPaths,
+		LuaOptions:                    luaOpts,
+		JSScriptPaths:                 jsScriptPaths,
 		PermissionChecker:             permChecker,
 		PermissionPluginName:          permPluginName,
 		RuntimePromptPath:             runtimePromptPath,
+		PromptOverrides:               promptOverrides(cfg),
+		PromptLayers:                  promptLayers,
+		SystemPromptTemplate:          systemPromptTemplate,
+		ToolApprovals:                 orchestrator.ToolApprovals{Queue: approvals, Tools: cfg.Approvals.Tools},
+		Agents:                        agents,
+		Experiments:                   experiments,
+		ModelAliases:                  cfg.Models.Aliases(),
+		ActorProfiles:                 actorProfiles,
+		ToolOutputBlobs:               orchestrator.ToolOutputBlobs{Store: blobs, Threshold: cfg.State.Blobs.OffloadBytes},
+		ToolExposure:                  toolExposure,
+		ToolScopes:                    toolScopes,
+		ReplyLanguage:                 cfg.Orchestrator.ReplyLanguage,
+		Locale:                        cfg.Orchestrator.Locale,
+		ChannelLocales:                channelLocales,
+		Messages:                      messages,
+		Documents:                     documents,
+		Attachments:                   attachments,
+		Transcriber:                   transcriber,
+		GuardPolicy:                   policy,
+		SecretRedaction:               redaction,
+		PIIRedaction:                  piiRedaction,
+		ToolPolicies:                  toolPolicies,
+		DryRun:                        orchestrator.DryRun{Enabled: cfg.Orchestrator.DryRun.Enabled, Plugins: cfg.Orchestrator.DryRun.Plugins},
 		ContextMessages:               cfg.State.Session.ContextMessages, // 0 = all messages; >0 = send only last N messages to LLM
 		SummarizeAfterMessages:        cfg.State.Session.SummarizeAfter,  // 0 (default) = off; set to e.g. 10 to enable LLM summarization
 		MaxMessagesAfterSummary:       defaultInt(cfg.State.Session.MaxMessagesAfterSummary, 5),
@@ -830,6 +1323,11 @@ func main() {
 		GroupPluginLookup:             groupPluginStore,
 		UsageRecorder:                 usageRecorder,
 		PluginCallObserver:            pluginObserver,
+		TimingObserver:                timingObserver,
+		ExperimentObserver:            experimentObserver,
+		LoopObserver:                  loopObserver,
+		ActivityObserver:              activityObserver,
+		WorkflowRecorder:              workflowRecorder,
 		EventSink:                     sessionSink,       // async-buffered via SessionEventWriter
 		PromptSnapshotStore:           sessionEventStore, // direct/sync store; intentionally not async-buffered so a consumer reading a turn_start event can resolve its sha256 references without racing the writer. nil when state DB is not configured
 		SyncActionsPlugin:             cfg.Orchestrator.Knowledge.SyncPlugin,
@@ -839,7 +1337,9 @@ func main() {
 			Action: cfg.Orchestrator.Knowledge.Action,
 			Dir:    cfg.Orchestrator.Knowledge.Dir,
 		},
-		ShowToolCalls: cfg.Orchestrator.ShowToolCalls,
+		ShowToolCalls:      cfg.Orchestrator.ShowToolCalls,
+		ReportToolFailures: cfg.Orchestrator.ReportToolFailures,
+		Events:             events,
 		// The DB-backed SessionStore satisfies InjectionStateStore via
 		// its GetInjectionState / UpdateInjectionState methods
 		// (migration 010). When state DB is not configured the variable
@@ -877,11 +1377,30 @@ func main() {
 			Enabled: cfg.Orchestrator.Escalation.Enabled,
 		},
 		EscalationLimitChecker: escalationLimit,
-		SessionLocker:          sessionLocker,
+		Resume: orchestrator.ResumeConfig{
+			Store:      runCheckpoints,
+			MaxAge:     parseDurationOrZero(cfg.Orchestrator.Resume.MaxAge),
+			NotifyOnly: cfg.Orchestrator.Resume.NotifyOnly,
+		},
+		Jobs: orchestrator.JobsConfig{
+			Enabled:      cfg.Orchestrator.Jobs.Enabled,
+			PollInterval: parseDurationOrZero(cfg.Orchestrator.Jobs.PollInterval),
+			MaxAge:       parseDurationOrZero(cfg.Orchestrator.Jobs.MaxAge),
+		},
+		AskUser: orchestrator.AskUserConfig{
+			Enabled: cfg.Orchestrator.AskUser.Enabled,
+			Timeout: parseDurationOrZero(cfg.Orchestrator.AskUser.Timeout),
+		},
+		ToolCache:         toolCacheConfig(cfg.Orchestrator.ToolCache, toolCacheStore),
+		Generation:        generation,
+		TaskGeneration:    taskGeneration,
+		LoopLimits:        loopLimits,
+		ChannelLoopLimits: channelLoopLimits,
+		SessionLocker:     sessionLocker,
 	})
 
 	// Wire on-clear actions now that the orchestrator is available.
-	cmdExecutor.WithOnClear(onClearActions, orch.RunAction)
+	cmdExecutor.WithOnClear(onClearActions, orch.RunAction).WithBranchReplay(orch)
 
 	// Build sync locker: cluster mode uses Redis so only one pod runs
 	// SyncActions/IngestKnowledgeDir; single-instance uses noop.
@@ -940,6 +1459,7 @@ func main() {
 		// Update readiness when a late-loaded plugin comes online.
 		if channelManager != nil && pluginManager.Ready() && channelManager.Ready() {
 			healthSrv.SetReady("opentalon", true)
+			sup.Ready()
 		}
 	})
 
@@ -979,32 +1499,127 @@ func main() {
 	// Reuses the channelNotifier built above the orchestrator (shared
 	// late-bound *channel.Registry pointer); the title-push path and the
 	// scheduler's job-result path therefore go through the same adapter.
-	// Scheduler/reminder jobs persist to pod-local disk (dataDir), so in
-	// cluster mode a job created on one pod is delivered and visible only on
-	// that pod (and lost if the pod is replaced) — see docs/cluster.md.
-	if cfg.Cluster.Enabled {
+	// Scheduler/reminder jobs persist to pod-local disk (dataDir) unless the
+	// state DB is Postgres, so in cluster mode on SQLite a job created on one
+	// pod is delivered and visible only on that pod (and lost if the pod is
+	// replaced) — see docs/cluster.md.
+	if cfg.Cluster.Enabled && schedulerJobs == nil {
 		slog.Warn("cluster mode: scheduler and reminder jobs persist to pod-local disk; each job is visible and delivered only on the pod that created it and does not survive pod replacement")
 	}
 	sched := scheduler.NewWithPolicy(orch, notifier, dataDir, cfg.Scheduler.Approvers, cfg.Scheduler.MaxJobsPerUser)
-	staticJobs := make([]scheduler.Job, 0, len(cfg.Scheduler.Jobs))
-	for _, jc := range cfg.Scheduler.Jobs {
-		if jc.Enabled != nil && !*jc.Enabled {
-			continue
+	if schedulerJobs != nil {
+		sched.SetJobStore(schedulerJobs)
+	}
+	sched.SetEventBus(events)
+	sched.SetMaxConcurrent(cfg.Scheduler.MaxConcurrent)
+	sched.SetJitter(parseDurationOrZero(cfg.Scheduler.Jitter))
+	if a := cfg.Scheduler.FailureAlert; a != nil {
+		if a.Channel == "" || a.ConversationID == "" {
+			fmt.Fprintf(os.Stderr, "Invalid scheduler.failure_alert: channel and conversation_id are required\n")
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		sched.SetFailureAlert(scheduler.FailureAlert{Channel: a.Channel, ConversationID: a.ConversationID, After: a.After})
+	}
+	completer.orch = orch
+	closer.orch = orch
+	if cfg.Evaluation.Enabled {
+		judge, err := buildJudge(cfg, llm, scoreStore, sessions, metricsCollector, debugSink, debugResolver, sessionSink)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid evaluation config: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		if judge != nil {
+			if idleTimeoutFor == nil {
+				slog.Warn("evaluation scores completed sessions, but state.session.completion.idle_timeout is not set, so no session completes", "component", "evaluation")
+			}
+			completer.judge = judge
+			evalTool := evaluation.NewTool(scoreStore, cfg.Evaluation.AllowedGroups)
+			if err := toolRegistry.Register(evalTool.Capability(), evalTool); err != nil {
+				slog.Warn("register evaluation tool failed", "error", err)
+			}
+		}
+	}
+	if searchIndex != nil {
+		searchTool := search.NewTool(searchIndex, cfg.Search.AllowedGroups)
+		if err := toolRegistry.Register(searchTool.Capability(), searchTool); err != nil {
+			slog.Warn("register search tool failed", "error", err)
+		}
+	} else if cfg.Search.Enabled {
+		slog.Warn("search is enabled but no search index is available (it needs the state DB); search tool disabled")
+	}
+	if cfg.Memory.Enabled {
+		if len(cfg.Memory.Approvers) == 0 && approvals == nil {
+			fmt.Fprintf(os.Stderr, "Invalid memory config: memory.approvers is empty and approvals are off, so no memory could ever be promoted\n")
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		if ms, ok := memory.(promotion.Store); ok {
+			memoryTool := promotion.NewTool(ms, cfg.Memory.Approvers, cfg.Memory.AllowedGroups)
+			if approvals != nil {
+				memoryTool.SetApprovalQueue(approvals)
+			}
+			if err := toolRegistry.Register(memoryTool.Capability(), memoryTool); err != nil {
+				slog.Warn("register memory tool failed", "error", err)
+			}
+		} else {
+			slog.Warn("memory is enabled but needs the state DB; memory tool disabled")
+		}
+	}
+	if cfg.Stats.Enabled {
+		if usageStore != nil {
+			statsTool := usagestats.NewTool(usageStore, cfg.Stats.AllowedGroups)
+			if err := toolRegistry.Register(statsTool.Capability(), statsTool); err != nil {
+				slog.Warn("register stats tool failed", "error", err)
+			}
+		} else {
+			slog.Warn("stats is enabled but needs the state DB; stats tool disabled")
+		}
+	}
+	if len(cfg.Digest.Reports) > 0 {
+		if err := validateDigestReports(cfg.Digest.Reports); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid digest config: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		if activityStore != nil {
+			digestTool := digest.NewTool(activityStore)
+			if err := toolRegistry.Register(digestTool.Capability(), digestTool); err != nil {
+				slog.Warn("register digest tool failed", "error", err)
+			}
+		} else {
+			slog.Warn("digest reports are configured but need the state DB; digest tool disabled")
 		}
-		staticJobs = append(staticJobs, scheduler.Job{
-			Name:          jc.Name,
-			Interval:      jc.Interval,
-			Cron:          jc.Cron,
-			At:            jc.At,
-			Action:        jc.Action,
-			Args:          jc.Args,
-			NotifyChannel: jc.NotifyChannel,
-		})
 	}
-	if err := sched.Start(staticJobs); err != nil {
+	subscribeEvents(events, cfg.Events.Subscriptions, orch, luaScriptPaths, luaOpts)
+	stopApprovals := func() {}
+	if approvals != nil {
+		sched.SetApprovalQueue(approvals)
+		stopApprovals = startApprovals(cfg.Approvals, approvals)
+	}
+	if err := sched.Start(staticSchedulerJobs(cfg)); err != nil {
 		slog.Warn("scheduler start failed", "error", err)
 	}
 	defer sched.Stop()
+
+	if cfg.Reload.Enabled {
+		reloader := &configReloader{
+			current:    cfg,
+			orch:       orch,
+			llm:        llm,
+			sched:      sched,
+			provCancel: provCancel,
+			buildProvider: func(ctx context.Context, next *config.Config) (provider.Provider, string, error) {
+				prov, model, err := buildProvider(ctx, next, debugSink, debugResolver, sessionSink, events)
+				if err == nil {
+					discoverModels(ctx, next.Models.Discovery, prov)
+				}
+				return prov, model, err
+			},
+		}
+		if err := config.Watch(ctx, absConfigPath, parseDurationOrZero(cfg.Reload.Debounce), reloader.apply); err != nil {
+			slog.Warn("config reload disabled: cannot watch config file", "component", "config", "path", absConfigPath, "error", err)
+		} else {
+			slog.Info("config reload enabled", "component", "config", "path", absConfigPath)
+		}
+	}
 	schedTool := scheduler.NewSchedulerTool(sched)
 	if err := toolRegistry.Register(schedTool.Capability(), schedTool); err != nil {
 		slog.Warn("register scheduler tool failed", "error", err)
@@ -1012,6 +1627,50 @@ func main() {
 	if err := toolRegistry.Register(reminder.Capability(), reminder.NewTool()); err != nil {
 		slog.Warn("register reminder tool failed", "error", err)
 	}
+	if cfg.Notify.Enabled {
+		notifyTool, err := buildNotifyTool(cfg, notifier)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid notify config: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		if err := toolRegistry.Register(notifyTool.Capability(), notifyTool); err != nil {
+			slog.Warn("register notify tool failed", "error", err)
+		}
+	}
+	if len(cfg.Routing.Fallbacks) > 0 || cfg.Routing.Offline.Model != "" {
+		providerTool := failover.NewTool(llm, cfg.Routing.Ops.AllowedGroups)
+		if err := toolRegistry.Register(providerTool.Capability(), providerTool); err != nil {
+			slog.Warn("register provider tool failed", "error", err)
+		}
+	}
+	if ops := cfg.Routing.Ops; ops.Channel != "" || ops.Conversation != "" {
+		if ops.Channel == "" || ops.Conversation == "" {
+			fmt.Fprintf(os.Stderr, "Invalid routing.ops config: alerts need both channel and conversation\n")
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		failover.NewAlerts(notifier, ops.Channel, ops.Conversation, parseDurationOrZero(ops.MinInterval)).Attach(events)
+		slog.Info("llm provider alerts enabled", "channel", ops.Channel, "conversation", ops.Conversation)
+	}
+	profileTool := actorprofile.NewTool(actorProfiles)
+	if err := toolRegistry.Register(profileTool.Capability(), profileTool); err != nil {
+		slog.Warn("register profile tool failed", "error", err)
+	}
+	stopWorkflowAPI := func() {}
+	if len(cfg.Workflows.Definitions) > 0 || recordings != nil {
+		engine, err := workflow.NewEngine(orch, llm, workflowsFromConfig(cfg.Workflows))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid workflows config: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+		workflowTool := workflow.NewTool(engine, recordings, cfg.Workflows.AllowedGroups)
+		if err := toolRegistry.Register(workflowTool.Capability(), workflowTool); err != nil {
+			slog.Warn("register workflow tool failed", "error", err)
+		}
+		stopWorkflowAPI = startWorkflowAPI(cfg.Workflows, engine)
+	}
+	admin := adminBackends(ctl.Backends{Jobs: sched, Plugins: pluginManager, Providers: llm}, sessions, memory, usageStore, actorData)
+	stopCtl := startCtl(cfg, admin)
+	stopDashboard := startDashboard(cfg.Dashboard, admin, pluginManager, usageStore, events)
 
 	// Strict resume: surface "not found" up to the handler so it can emit
 	// session_expired to the client rather than silently auto-creating
@@ -1026,10 +1685,35 @@ func main() {
 	// Fresh mint: delegates to the underlying store, which is idempotent
 	// in both the DB-backed (INSERT-on-conflict returns existing row) and
 	// the in-memory variant (Create returns existing pointer when present).
+	// session_created is published only for a key that did not exist, which
+	// costs a lookup, so it is skipped when nothing subscribes.
 	createSession := func(sessionKey, entityID, groupID, kind string) {
+		isNew := false
+		if events.Subscribed(eventbus.SessionCreated) {
+			_, err := sessions.Get(sessionKey)
+			isNew = errors.Is(err, state.ErrSessionNotFound)
+		}
 		sessions.Create(sessionKey, entityID, groupID, kind)
+		if isNew {
+			events.Publish(context.Background(), eventbus.Event{
+				Type:      eventbus.SessionCreated,
+				SessionID: sessionKey,
+				Data:      map[string]string{"entity_id": entityID, "group_id": groupID, "kind": kind},
+			})
+		}
 	}
 	runner := &channelRunner{orch: orch}
+	var commandRouter *channel.CommandRouter
+	if cfg.Commands.Enabled {
+		slash := &slashCommands{cfg: cfg, runAction: orch.RunAction, registry: toolRegistry, sched: sched}
+		if usageStore != nil {
+			slash.usageStore = usageStore
+		}
+		if commandRouter, err = slash.router(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid commands config: %v\n", err)
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
+		}
+	}
 	handler := channel.NewMessageHandler(channel.HandlerConfig{
 		ResumeSession: resumeSession,
 		CreateSession: createSession,
@@ -1041,10 +1725,25 @@ func main() {
 		// Read-only re-emit of a still-pending tool confirmation on a resume
 		// handshake, so a reconnected client redraws its Approve/Reject buttons.
 		PendingConfirmation: orch.PendingConfirmationFrame,
+		// Threads on channels declaring link_threads start with their parent
+		// conversation's summary and pinned facts.
+		LinkSession: func(child, parent string) error {
+			return orchestrator.LinkSessions(sessions, child, parent, true)
+		},
+		Speaker:       speaker,
+		SpeakAlways:   speakAlways,
+		GroupPolicies: groupPolicies(cfg),
+		Commands:      commandRouter,
 	})
 
 	reg := channel.NewRegistry(handler)
 	notifier.reg = reg
+	delivery, err := deliveryPolicy(cfg.Delivery, dataDir, notifier)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid delivery config: %v\n", err)
+		os.Exit(daemon.ExitConfig) //nolint:gocritic
+	}
+	reg.SetDeliveryPolicy(delivery)
 
 	if dw := cfg.Orchestrator.DebounceWindow; dw != "" {
 		if d, err := time.ParseDuration(dw); err == nil && d > 0 {
@@ -1054,31 +1753,95 @@ func main() {
 			slog.Warn("invalid orchestrator.debounce_window, debounce disabled", "value", dw, "error", err)
 		}
 	}
-
-	if cfg.Cluster.Enabled {
-		dedupTTL := 5 * time.Minute
-		if cfg.Cluster.DedupTTL != "" {
-			if d, err := time.ParseDuration(cfg.Cluster.DedupTTL); err == nil {
-				dedupTTL = d
-			} else {
-				slog.Warn("invalid cluster.dedup_ttl, using default 5m", "value", cfg.Cluster.DedupTTL)
-			}
+	if mw := cfg.Orchestrator.DebounceMaxWait; mw != "" {
+		reg.SetDebounceMaxWait(parseDurationOrZero(mw))
+	}
+	dedupWindow := 10 * time.Minute
+	if dw := cfg.Orchestrator.DedupWindow; dw != "" {
+		if d, err := time.ParseDuration(dw); err == nil {
+			dedupWindow = d
+		} else {
+			slog.Warn("invalid orchestrator.dedup_window, using default 10m", "value", dw, "error", err)
 		}
-		reg.SetDeduplicator(dedup.NewFromClient(sharedRedis), dedupTTL)
-		slog.Info("cluster deduplication enabled", "ttl", dedupTTL, "sentinel", len(cfg.Redis.Sentinels) > 0)
 	}
-	channelManager = channel.NewManager(reg, toolRegistry)
-	// Wire the inbound-enrichment cache. Redis-backed when the deployment
-	// already runs Redis (cache is shared across pods, survives restarts);
-	// in-memory fallback otherwise so single-pod and dev setups keep
-	// working without Redis. Channels without an inbound.enrich block
-	// ignore the cache entirely.
+	reg.SetDedupWindow(dedupWindow)
+	for name, ch := range cfg.Channels {
+		if ch.DebounceWindow == "" {
+			continue
+		}
+		d, err := time.ParseDuration(ch.DebounceWindow)
+		if err != nil {
+			slog.Warn("invalid channels.debounce_window, using the global window", "channel", name, "value", ch.DebounceWindow, "error", err)
+			continue
+		}
+		reg.SetChannelDebounceWindow(name, d)
+		slog.Info("channel debounce window set", "channel", name, "window", d)
+	}
+	for name, ch := range cfg.Channels {
+		if ch.Formatting == nil {
+			continue
+		}
+		f := channel.Formatting{Convert: ch.Formatting.Convert, Overflow: channel.Overflow(ch.Formatting.Overflow), MaxParts: ch.Formatting.MaxParts}
+		switch f.Overflow {
+		case "", channel.OverflowSplit, channel.OverflowFile:
+		default:
+			slog.Warn("invalid channels.formatting.overflow, splitting long replies", "channel", name, "value", f.Overflow)
+			f.Overflow = channel.OverflowSplit
+		}
+		reg.SetChannelFormatting(name, f)
+	}
+	for name, ch := range cfg.Channels {
+		if ch.Progress {
+			reg.SetChannelProgress(name, true)
+		}
+	}
+	for name, ch := range cfg.Channels {
+		if ch.Queue == nil {
+			continue
+		}
+		q := channel.QueuePolicy{Size: ch.Queue.Size, Workers: ch.Queue.Workers, Overflow: channel.QueueOverflow(ch.Queue.Overflow), BusyReply: ch.Queue.BusyReply, Merge: ch.Queue.Merge}
+		switch q.Overflow {
+		case "", channel.QueueWait, channel.QueueReject, channel.QueueCoalesce:
+		default:
+			slog.Warn("invalid channels.queue.overflow, waiting for room", "channel", name, "value", q.Overflow)
+			q.Overflow = channel.QueueWait
+		}
+		reg.SetChannelQueue(name, q)
+	}
+	if metricsCollector != nil {
+		reg.SetQueueObserver(metricsCollector)
+	}
+
+	if cfg.Cluster.Enabled {
+		dedupTTL := 5 * time.Minute
+		if cfg.Cluster.DedupTTL != "" {
+			if d, err := time.ParseDuration(cfg.Cluster.DedupTTL); err == nil {
+				dedupTTL = d
+			} else {
+				slog.Warn("invalid cluster.dedup_ttl, using default 5m", "value", cfg.Cluster.DedupTTL)
+			}
+		}
+		reg.SetDeduplicator(dedup.NewFromClient(sharedRedis), dedupTTL)
+		slog.Info("cluster deduplication enabled", "ttl", dedupTTL, "sentinel", len(cfg.Redis.Sentinels) > 0)
+		if cfg.Cluster.ReplyRelay {
+			reg.SetRelay(replyrelay.NewRedis(sharedRedis))
+			slog.Info("cluster reply relay enabled")
+		}
+	}
+	channelManager = channel.NewManager(reg, toolRegistry)
+	// Wire the inbound-enrichment cache. Redis-backed when the deployment
+	// already runs Redis (cache is shared across pods, survives restarts);
+	// in-memory fallback otherwise so single-pod and dev setups keep
+	// working without Redis. Channels without an inbound.enrich block
+	// ignore the cache entirely.
 	channelManager.SetEnrichCache(channel.NewEnrichCache(sharedRedis))
 	channelEntries := make([]channel.ChannelEntry, 0, len(cfg.Channels))
 	for name, ch := range cfg.Channels {
 		pathRef := ch.Plugin
 		if ch.GitHub != "" && ch.Ref != "" {
-			resolvedPath, err := bundle.EnsureChannel(ctx, dataDir, name, ch.GitHub, ch.Ref, ch.Cache)
+			resolvedPath, err := bundle.EnsureChannel(ctx, dataDir, name, ch.GitHub, ch.Ref, bundle.BuildOptions{
+				Cache: ch.Cache, FromSource: ch.FromSource, Policy: bundlePolicy(cfg.Bundles), Checksums: bundle.Checksums{Source: ch.SHA256, Binary: ch.BinarySHA256},
+			})
 			if err != nil {
 				slog.Warn("bundle channel failed", "channel", name, "error", err)
 				continue
@@ -1099,9 +1862,26 @@ func main() {
 		slog.Info("channels loaded")
 	}
 
+	// Pick up turns the previous process was killed in the middle of; the
+	// channels must be loaded so the replies can be pushed.
+	if cfg.Orchestrator.Resume.Enabled && runCheckpoints == nil {
+		slog.Warn("orchestrator.resume needs the state database; interrupted turns will not be resumed")
+	}
+	if resumed, notified, err := orch.ResumeInterruptedRuns(ctx); err != nil {
+		slog.Warn("loading run checkpoints failed", "error", err)
+	} else if resumed+notified > 0 {
+		slog.Info("interrupted turns picked up", "resumed", resumed, "notified", notified)
+	}
+
+	go orch.RunJobs(ctx)
+
+	outboxCtx, stopOutbox := context.WithCancel(ctx)
+	go reg.RunOutbox(outboxCtx, parseDurationOrZero(cfg.Delivery.RedeliverInterval))
+
 	// Mark readiness once all plugins and channels are loaded.
 	if pluginManager.Ready() && channelManager.Ready() {
 		healthSrv.SetReady("opentalon", true)
+		sup.Ready()
 	}
 
 	// Start plugin exec dispatcher (allows trusted plugins to execute ToolRegistry actions via Redis).
@@ -1111,7 +1891,7 @@ func main() {
 	if cfg.PluginExec.Enabled {
 		if sharedRedis == nil {
 			fmt.Fprintf(os.Stderr, "plugin_exec.enabled requires redis.redis_url or redis.sentinels to be configured\n")
-			os.Exit(1) //nolint:gocritic
+			os.Exit(daemon.ExitConfig) //nolint:gocritic
 		} else {
 			var actionTimeout time.Duration
 			if cfg.PluginExec.ActionTimeout != "" {
@@ -1133,7 +1913,19 @@ func main() {
 	// graceful teardown below never runs and the process is hard-killed,
 	// severing every open WebSocket chat session on every rollout.
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-	<-sigCh
+	if *daemonFlag {
+		// A daemon has no controlling terminal; a stray SIGHUP from the
+		// session that launched it must not kill it.
+		signal.Ignore(syscall.SIGHUP)
+	}
+	select {
+	case <-sigCh:
+	case <-sup.StopRequested():
+	}
+	sup.Stopping()
+	// Turns that fail from here on were cut off by the shutdown; they keep
+	// their checkpoints so the next start can resume them.
+	orch.BeginShutdown()
 
 	// Stop advertising readiness as the first shutdown step so Kubernetes
 	// removes this pod from the Service endpoints before we close connections.
@@ -1154,6 +1946,11 @@ func main() {
 	if sessionEventsRetentionCancel != nil {
 		sessionEventsRetentionCancel()
 	}
+	if maintenanceCancel != nil {
+		maintenanceCancel()
+	}
+	stopBlobGC()
+	stopRAGSync()
 
 	// Stop health server first so K8s stops routing traffic during teardown.
 	healthSrv.Shutdown()
@@ -1162,6 +1959,11 @@ func main() {
 	// be delivered via the channel registry. (deferred sched.Stop() above
 	// runs late — we want explicit ordering here.)
 	sched.Stop()
+	stopApprovals()
+	stopWorkflowAPI()
+	stopCtl()
+	stopDashboard()
+	stopOutbox()
 	if metricsSrv != nil {
 		_ = metricsSrv.Shutdown(context.Background())
 	}
@@ -1271,7 +2073,7 @@ type usageRecorderAdapter struct {
 	collector *metrics.Collector
 }
 
-func (a *usageRecorderAdapter) RecordUsage(ctx context.Context, entityID, groupID, channelID, sessionID, modelID, interactionKind, systemSource string, inputTokens, outputTokens, toolCalls int) {
+func (a *usageRecorderAdapter) RecordUsage(ctx context.Context, entityID, groupID, channelID, sessionID, modelID, interactionKind, systemSource string, inputTokens, outputTokens, toolCalls int, tools []string) {
 	if a.store == nil && a.collector == nil {
 		return
 	}
@@ -1298,6 +2100,7 @@ func (a *usageRecorderAdapter) RecordUsage(ctx context.Context, entityID, groupI
 			InputTokens:     inputTokens,
 			OutputTokens:    outputTokens,
 			ToolCalls:       toolCalls,
+			Tools:           tools,
 			InputCost:       inputCostUSD,
 			OutputCost:      outputCostUSD,
 		}); err != nil {
@@ -1412,51 +2215,112 @@ type channelRunner struct {
 func (r *channelRunner) Run(ctx context.Context, sessionKey, content string, files ...chanpkg.FileAttachment) (string, string, map[string]string, error) {
 	providerFiles := make([]provider.MessageFile, len(files))
 	for i, f := range files {
-		providerFiles[i] = provider.MessageFile{MimeType: f.MimeType, Data: f.Data}
+		providerFiles[i] = provider.MessageFile{MimeType: f.MimeType, Data: f.Data, Name: f.Name, URL: f.URL}
 	}
 	result, err := r.orch.Run(ctx, sessionKey, content, providerFiles...)
 	if err != nil {
 		return "", "", nil, err
 	}
-	return result.Response, result.InputForDisplay, result.Metadata, nil
+	return result.Response, result.InputForDisplay, usageMetadata(result), nil
+}
+
+// usageMetadata returns the result's metadata with what the turn spent on
+// the model (chanpkg.UsageTokensMetadataKey, chanpkg.UsageCostMetadataKey),
+// for channels that show it under the reply.
+func usageMetadata(result *orchestrator.RunResult) map[string]string {
+	if result.Usage == nil || result.Usage.LLMCalls == 0 {
+		return result.Metadata
+	}
+	meta := make(map[string]string, len(result.Metadata)+2)
+	maps.Copy(meta, result.Metadata)
+	meta[chanpkg.UsageTokensMetadataKey] = strconv.Itoa(result.Usage.Tokens())
+	if result.Usage.Cost > 0 {
+		meta[chanpkg.UsageCostMetadataKey] = strconv.FormatFloat(result.Usage.Cost, 'f', 6, 64)
+	}
+	return meta
 }
 
 // defaultModelClient wraps a provider and sets req.Model when empty.
 // It also injects model-level defaults (MaxTokens, ReasoningEffort)
 // from the provider's model config when the request doesn't set them.
+// The provider, default model and model map can be swapped at runtime by a
+// config reload (see configReloader); mu guards all three.
 type defaultModelClient struct {
+	mu       sync.RWMutex
 	provider provider.Provider
 	model    string
 	models   map[string]provider.ModelInfo
+	cache    *provider.ResponseCache // orchestrator.llm_cache; nil = off. Only Complete is cached
+}
+
+// swap replaces the provider, default model and model map. Requests already
+// dispatched keep the provider they started with.
+func (c *defaultModelClient) swap(prov provider.Provider, model string, models map[string]provider.ModelInfo) {
+	c.mu.Lock()
+	c.provider, c.model, c.models = prov, model, models
+	c.mu.Unlock()
+}
+
+func (c *defaultModelClient) snapshot() (provider.Provider, string, map[string]provider.ModelInfo) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.provider, c.model, c.models
+}
+
+// Offline reports whether the current provider is serving from the
+// routing.offline model.
+func (c *defaultModelClient) Offline() bool {
+	prov, _, _ := c.snapshot()
+	o, ok := prov.(*provider.OfflineProvider)
+	return ok && o.Offline()
+}
+
+// EndpointStatus reports the endpoints of the current provider, or none
+// when it is a single provider without failover.
+func (c *defaultModelClient) EndpointStatus() []provider.EndpointStatus {
+	prov, _, _ := c.snapshot()
+	if r, ok := prov.(provider.StatusReporter); ok {
+		return r.EndpointStatus()
+	}
+	return nil
+}
+
+// AcceptsInput reports whether the default model's models.providers entry
+// lists kind among its input types.
+func (c *defaultModelClient) AcceptsInput(kind string) bool {
+	_, model, models := c.snapshot()
+	return slices.Contains(models[model].InputTypes, kind)
 }
 
 func (c *defaultModelClient) Complete(ctx context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	prov, model, models := c.snapshot()
 	if req.Model == "" {
 		cp := *req
-		cp.Model = c.model
+		cp.Model = model
 		req = &cp
 	}
-	c.applyModelDefaults(req)
-	return c.provider.Complete(ctx, req)
+	applyModelDefaults(models, req)
+	return c.cache.Complete(ctx, req, prov.Complete)
 }
 
 // Stream implements orchestrator.StreamingLLMClient by delegating to the
 // underlying provider's Stream method, filling in the default model if needed.
 func (c *defaultModelClient) Stream(ctx context.Context, req *provider.CompletionRequest) (provider.ResponseStream, error) {
+	prov, model, models := c.snapshot()
 	if req.Model == "" {
 		cp := *req
-		cp.Model = c.model
+		cp.Model = model
 		cp.Stream = true
 		req = &cp
 	}
-	c.applyModelDefaults(req)
-	return c.provider.Stream(ctx, req)
+	applyModelDefaults(models, req)
+	return prov.Stream(ctx, req)
 }
 
 // applyModelDefaults injects MaxTokens and ReasoningEffort from the model
 // config when the request doesn't already set them.
-func (c *defaultModelClient) applyModelDefaults(req *provider.CompletionRequest) {
-	m, ok := c.models[req.Model]
+func applyModelDefaults(models map[string]provider.ModelInfo, req *provider.CompletionRequest) {
+	m, ok := models[req.Model]
 	if !ok {
 		return
 	}
@@ -1471,7 +2335,8 @@ func (c *defaultModelClient) applyModelDefaults(req *provider.CompletionRequest)
 // SupportsFeature delegates to the underlying provider so the orchestrator
 // can detect reasoning support via type assertion.
 func (c *defaultModelClient) SupportsFeature(f provider.Feature) bool {
-	return c.provider.SupportsFeature(f)
+	prov, _, _ := c.snapshot()
+	return prov.SupportsFeature(f)
 }
 
 // buildLuaScriptPaths returns a map of Lua plugin name -> path to .lua script,
@@ -1482,27 +2347,7 @@ func buildLuaScriptPaths(ctx context.Context, dataDir string, cfg *config.Config
 		return paths
 	}
 	// Local scripts_dir: each .lua file -> name (without extension) -> path
-	if cfg.Lua.ScriptsDir != "" {
-		dir := cfg.Lua.ScriptsDir
-		if strings.HasPrefix(dir, "~") {
-			home, _ := os.UserHomeDir()
-			rest := strings.TrimPrefix(strings.TrimPrefix(dir, "~"), "/")
-			dir = filepath.Join(home, rest)
-		}
-		entries, err := os.ReadDir(dir)
-		if err == nil {
-			for _, e := range entries {
-				if e.IsDir() {
-					continue
-				}
-				name := e.Name()
-				if strings.HasSuffix(name, ".lua") {
-					pluginName := strings.TrimSuffix(name, ".lua")
-					paths[pluginName] = filepath.Join(dir, name)
-				}
-			}
-		}
-	}
+	scriptsInDir(cfg.Lua.ScriptsDir, ".lua", paths)
 	// Downloaded plugins: default repo (subdir/name.lua) or per-plugin repo (name.lua at root)
 	var defaultRepoPath string
 	if cfg.Lua.DefaultGitHub != "" && cfg.Lua.DefaultRef != "" {
@@ -1531,22 +2376,69 @@ func buildLuaScriptPaths(ctx context.Context, dataDir string, cfg *config.Config
 	return paths
 }
 
+// buildJSScriptPaths returns a map of JS script name -> path to .js script
+// from js.scripts_dir.
+func buildJSScriptPaths(cfg *config.Config) map[string]string {
+	paths := make(map[string]string)
+	if cfg.JS != nil {
+		scriptsInDir(cfg.JS.ScriptsDir, ".js", paths)
+	}
+	return paths
+}
+
+// scriptsInDir adds each file in dir with extension ext to paths, keyed by
+// its name without the extension. A leading ~ in dir is the home directory.
+func scriptsInDir(dir, ext string, paths map[string]string) {
+	if dir == "" {
+		return
+	}
+	if strings.HasPrefix(dir, "~") {
+		home, _ := os.UserHomeDir()
+		rest := strings.TrimPrefix(strings.TrimPrefix(dir, "~"), "/")
+		dir = filepath.Join(home, rest)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		name := e.Name()
+		if strings.HasSuffix(name, ext) {
+			paths[strings.TrimSuffix(name, ext)] = filepath.Join(dir, name)
+		}
+	}
+}
+
+// bundlePolicy maps the bundles config section to a bundle.Policy.
+func bundlePolicy(c config.BundlesConfig) bundle.Policy {
+	return bundle.Policy{
+		NoRemoteBuild:   c.NoRemoteBuild,
+		AllowedOrgs:     c.AllowedOrgs,
+		SignedCommits:   c.SignedCommits,
+		AllowedSigners:  c.AllowedSigners,
+		RequireChecksum: c.RequireChecksum,
+	}
+}
+
 // runClean clears cached bundles under the state data dir and exits.
 func runClean(configPath, category string) {
 	if configPath == "" {
 		fmt.Fprintln(os.Stderr, "Error: -clean requires -config <path> so state.data_dir matches your deployment.")
 		fmt.Fprintln(os.Stderr, "Example: opentalon -config /data/opentalon/config.yaml -clean plugins")
-		os.Exit(1)
+		os.Exit(daemon.ExitUsage)
 	}
 	cfg, err := config.Load(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
-		os.Exit(1)
+		os.Exit(daemon.ExitConfig)
 	}
 	absConfigPath, err := filepath.Abs(configPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error resolving config path: %v\n", err)
-		os.Exit(1)
+		os.Exit(daemon.ExitConfig)
 	}
 	dataDir := config.ResolveStateDataDir(cfg, absConfigPath)
 
@@ -1568,11 +2460,11 @@ func runClean(configPath, category string) {
 		err = bundle.CleanLuaPlugins(dataDir)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown clean category %q. Use: all, plugins, channels, skills, lua_plugins\n", category)
-		os.Exit(1)
+		os.Exit(daemon.ExitUsage)
 	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Clean failed: %v\n", err)
-		os.Exit(1)
+		os.Exit(daemon.ExitFailure)
 	}
 	fmt.Fprintln(os.Stderr, "Done. Next run will re-download from configured refs.")
 }
@@ -1582,8 +2474,35 @@ func runClean(configPath, category string) {
 // empty it returns the single primary provider (unchanged behavior). Otherwise
 // it builds the primary plus each fallback and wraps them in a health-gated
 // provider that prefers the primary while its endpoint is reachable and falls
-// back — with recovery hysteresis — to the fallbacks otherwise.
-func buildProvider(cfg *config.Config, debugSink provider.DebugEventSink, debugResolve provider.DebugContextResolver, eventSink emit.Sink) (provider.Provider, string, error) {
+// back — with recovery hysteresis — to the fallbacks otherwise. With
+// routing.offline set, the result is wrapped once more so the local model
+// answers while no remote endpoint is reachable.
+func buildProvider(ctx context.Context, cfg *config.Config, debugSink provider.DebugEventSink, debugResolve provider.DebugContextResolver, eventSink emit.Sink, events *eventbus.Bus) (provider.Provider, string, error) {
+	prov, modelID, err := buildRemoteProvider(ctx, cfg, debugSink, debugResolve, eventSink, events)
+	if err != nil || cfg.Routing.Offline.Model == "" {
+		return prov, modelID, err
+	}
+	local, localModel, _, err := buildProviderRef(cfg, cfg.Routing.Offline.Model, debugSink, debugResolve, eventSink)
+	if err != nil {
+		return nil, "", fmt.Errorf("routing.offline %q: %w", cfg.Routing.Offline.Model, err)
+	}
+	var probes []provider.HealthProbe
+	for _, ref := range append([]string{cfg.Routing.Primary}, cfg.Routing.Fallbacks...) {
+		if pc, ok := cfg.Models.Providers[strings.SplitN(ref, "/", 2)[0]]; ok && pc.BaseURL != "" {
+			probes = append(probes, provider.NewHTTPHealthProbe(healthProbeURL(cfg, pc), pc.APIKey, nil))
+		}
+	}
+	var probe provider.HealthProbe
+	if len(probes) > 0 {
+		probe = provider.AnyHealthy(probes...)
+	}
+	slog.Info("llm routing: offline fallback enabled", "offline_model", cfg.Routing.Offline.Model)
+	return provider.NewOfflineProvider(ctx, prov, provider.ProviderEntry{Prov: local, Model: localModel}, probe, healthGateConfig(cfg, events), slog.Default()), modelID, nil
+}
+
+// buildRemoteProvider builds the primary provider and, with routing.fallbacks,
+// the health-gated wrapper around it and the fallbacks.
+func buildRemoteProvider(ctx context.Context, cfg *config.Config, debugSink provider.DebugEventSink, debugResolve provider.DebugContextResolver, eventSink emit.Sink, events *eventbus.Bus) (provider.Provider, string, error) {
 	prov, modelID, primaryPC, err := buildProviderRef(cfg, cfg.Routing.Primary, debugSink, debugResolve, eventSink)
 	if err != nil {
 		return nil, "", err
@@ -1592,32 +2511,98 @@ func buildProvider(cfg *config.Config, debugSink provider.DebugEventSink, debugR
 		return prov, modelID, nil
 	}
 
+	hc := cfg.Routing.Health
+	if hc.Probe != "" && hc.Probe != "models" && hc.Probe != "completion" {
+		return nil, "", fmt.Errorf("routing.health.probe %q: want models or completion", hc.Probe)
+	}
+	monitor := provider.NewHealthMonitor(provider.HealthMonitorConfig{
+		Interval:      parseDurationOrZero(hc.Interval),
+		Timeout:       parseDurationOrZero(hc.Timeout),
+		Window:        hc.Window,
+		SlowAfter:     parseDurationOrZero(hc.SlowAfter),
+		DegradedBelow: hc.DegradedBelow,
+	})
 	entries := make([]provider.ProviderEntry, 0, 1+len(cfg.Routing.Fallbacks))
 	entries = append(entries, provider.ProviderEntry{Prov: prov, Model: modelID})
 	for _, ref := range cfg.Routing.Fallbacks {
-		fp, fModel, _, ferr := buildProviderRef(cfg, ref, debugSink, debugResolve, eventSink)
+		fp, fModel, fpc, ferr := buildProviderRef(cfg, ref, debugSink, debugResolve, eventSink)
 		if ferr != nil {
 			return nil, "", fmt.Errorf("routing fallback %q: %w", ref, ferr)
 		}
 		entries = append(entries, provider.ProviderEntry{Prov: fp, Model: fModel})
+		if fprobe := fallbackProbe(cfg, fpc, fp, fModel); fprobe != nil {
+			monitor.Watch(ctx, fp.ID()+"/"+fModel, fprobe)
+		}
+	}
+
+	probeURL := healthProbeURL(cfg, primaryPC)
+	probe := provider.NewHTTPHealthProbe(probeURL, primaryPC.APIKey, nil)
+	if hc.Probe == "completion" {
+		probe, probeURL = provider.NewCompletionProbe(prov, modelID), "completion"
+	}
+	slog.Info("llm routing: health-gated fallback enabled",
+		"primary", cfg.Routing.Primary,
+		"fallbacks", cfg.Routing.Fallbacks,
+		"health_probe", probeURL)
+	gate := healthGateConfig(cfg, events)
+	gate.Monitor = monitor
+	if cfg.Routing.Offline.Model != "" {
+		gate.OnExhausted = nil // the offline model is still to be tried; it reports exhaustion
 	}
+	return provider.NewHealthGatedProvider(ctx, entries, probe, gate, slog.Default()), modelID, nil
+}
+
+// fallbackProbe is how routing.health.probe checks a fallback endpoint: a
+// one-token completion, or its models listing. Without a base_url there is
+// no listing to fetch and the fallback goes unprobed.
+func fallbackProbe(cfg *config.Config, pc config.ProviderConfig, prov provider.Provider, model string) provider.HealthProbe {
+	if cfg.Routing.Health.Probe == "completion" {
+		return provider.NewCompletionProbe(prov, model)
+	}
+	if pc.BaseURL == "" {
+		return nil
+	}
+	return provider.NewHTTPHealthProbe(healthProbeURL(cfg, pc), pc.APIKey, nil)
+}
 
+// healthProbeURL is pc's base_url joined with routing.health.path.
+func healthProbeURL(cfg *config.Config, pc config.ProviderConfig) string {
 	probePath := cfg.Routing.Health.Path
 	if probePath == "" {
 		probePath = "/models"
 	}
-	probeURL := strings.TrimRight(primaryPC.BaseURL, "/") + probePath
-	probe := provider.NewHTTPHealthProbe(probeURL, primaryPC.APIKey, nil)
-	gate := provider.HealthGateConfig{
+	return strings.TrimRight(pc.BaseURL, "/") + probePath
+}
+
+// healthGateConfig reads routing.health; failovers, exhaustion and health
+// changes are published on events.
+func healthGateConfig(cfg *config.Config, events *eventbus.Bus) provider.HealthGateConfig {
+	return provider.HealthGateConfig{
 		Interval:     parseDurationOrZero(cfg.Routing.Health.Interval),
 		Timeout:      parseDurationOrZero(cfg.Routing.Health.Timeout),
 		RecoverAfter: cfg.Routing.Health.RecoverAfter,
+		OnFailover: func(ctx context.Context, from, to string, err error) {
+			events.Publish(ctx, eventbus.Event{
+				Type:      eventbus.ProviderFailover,
+				SessionID: actor.SessionID(ctx),
+				Data:      map[string]string{"from": from, "to": to, "error": err.Error()},
+			})
+		},
+		OnExhausted: func(ctx context.Context, attempted []string, err error) {
+			events.Publish(ctx, eventbus.Event{
+				Type:      eventbus.ProviderExhausted,
+				SessionID: actor.SessionID(ctx),
+				Data:      map[string]string{"attempted": strings.Join(attempted, ","), "error": err.Error()},
+			})
+		},
+		OnHealthChange: func(ctx context.Context, endpoint string, healthy bool, err error) {
+			data := map[string]string{"endpoint": endpoint, "healthy": strconv.FormatBool(healthy)}
+			if err != nil {
+				data["error"] = err.Error()
+			}
+			events.Publish(ctx, eventbus.Event{Type: eventbus.ProviderHealth, Data: data})
+		},
 	}
-	slog.Info("llm routing: health-gated fallback enabled",
-		"primary", cfg.Routing.Primary,
-		"fallbacks", cfg.Routing.Fallbacks,
-		"health_probe", probeURL)
-	return provider.NewHealthGatedProvider(context.Background(), entries, probe, gate, slog.Default()), modelID, nil
 }
 
 // buildProviderRef builds a single provider from a "providerID" or
@@ -1681,7 +2666,7 @@ func buildProviderRef(cfg *config.Config, ref string, debugSink provider.DebugEv
 			InputTypes:      m.InputTypes,
 			ContextWindow:   m.ContextWindow,
 			MaxTokens:       m.MaxTokens,
-			Cost:            provider.ModelCost{Input: m.Cost.Input, Output: m.Cost.Output},
+			Cost:            provider.ModelCost{Input: m.Cost.Input, Output: m.Cost.Output, CacheRead: m.Cost.CacheRead, CacheWrite: m.Cost.CacheWrite},
 			Features:        features,
 		})
 	}
@@ -1701,6 +2686,7 @@ func buildProviderRef(cfg *config.Config, ref string, debugSink provider.DebugEv
 			MaxDelay:     parseDurationOrZero(pc.Retry.MaxDelay),
 			MaxTotalWait: parseDurationOrZero(pc.Retry.MaxTotalWait),
 		},
+		NoPromptCache: pc.PromptCache != nil && !*pc.PromptCache,
 	}
 	prov, err := provider.FromConfig(provCfg)
 	if err != nil {
@@ -1709,6 +2695,621 @@ func buildProviderRef(cfg *config.Config, ref string, debugSink provider.DebugEv
 	return prov, modelID, pc, nil
 }
 
+// discoverModels merges the models the providers' APIs list into prov when
+// models.discovery is enabled: once now, so the startup model map sees them,
+// and every interval after until ctx (the provider's lifetime) ends.
+func discoverModels(ctx context.Context, dc config.ModelDiscoveryConfig, prov provider.Provider) {
+	if !dc.Enabled {
+		return
+	}
+	startCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	_ = provider.DiscoverModels(startCtx, prov, slog.Default())
+	cancel()
+	if interval := parseDurationOrZero(dc.Interval); interval > 0 {
+		go provider.RunModelDiscovery(ctx, prov, interval, slog.Default())
+	}
+}
+
+// providerModelMap indexes the provider's configured models by id for
+// defaultModelClient's per-model defaults.
+func providerModelMap(prov provider.Provider) map[string]provider.ModelInfo {
+	m := make(map[string]provider.ModelInfo)
+	for _, mi := range prov.Models() {
+		m[mi.ID] = mi
+	}
+	return m
+}
+
+// startApprovals serves the approval queue's admin API and sends pending
+// digests as configured. The returned func stops both.
+func startApprovals(cfg config.ApprovalsConfig, q *approval.Queue) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	var srv *http.Server
+	if cfg.AdminAddr != "" {
+		if cfg.AdminToken == "" {
+			fmt.Fprintf(os.Stderr, "approvals.admin_addr requires approvals.admin_token\n")
+			os.Exit(daemon.ExitConfig)
+		}
+		srv = &http.Server{Addr: cfg.AdminAddr, Handler: approval.NewHandler(q, cfg.AdminToken), ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			slog.Info("approvals admin API listening", "component", "approval", "addr", cfg.AdminAddr)
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("approvals admin API error", "component", "approval", "error", err)
+			}
+		}()
+	}
+	if d := parseDurationOrZero(cfg.DigestInterval); d > 0 {
+		if cfg.DigestChannel == "" || cfg.DigestConversationID == "" {
+			slog.Warn("approvals.digest_interval set without digest_channel and digest_conversation_id; digests disabled", "component", "approval")
+		} else {
+			go q.RunDigest(ctx, d, cfg.DigestChannel, cfg.DigestConversationID)
+		}
+	}
+	return func() {
+		cancel()
+		if srv != nil {
+			_ = srv.Shutdown(context.Background())
+		}
+	}
+}
+
+// deliveryPolicy builds the channel delivery policy from the delivery
+// section. Undelivered replies are kept under dataDir and, with an operator
+// conversation configured, reported there.
+func deliveryPolicy(cfg config.DeliveryConfig, dataDir string, notifier *channelNotifier) (channel.DeliveryPolicy, error) {
+	box, err := channel.NewOutbox(dataDir, parseDurationOrZero(cfg.OutboxTTL))
+	if err != nil {
+		return channel.DeliveryPolicy{}, err
+	}
+	p := channel.DeliveryPolicy{Attempts: cfg.Attempts, Backoff: parseDurationOrZero(cfg.Backoff), Outbox: box}
+	if n := box.Len(); n > 0 {
+		slog.Warn("undelivered replies waiting in the outbox", "component", "outbox", "count", n)
+	}
+	if cfg.OperatorChannel == "" || cfg.OperatorConversationID == "" {
+		return p, nil
+	}
+	p.OnUndelivered = func(ctx context.Context, e channel.OutboxEntry) {
+		msg := fmt.Sprintf("A reply to conversation %s on %s could not be delivered after %d attempts (%s). It is kept in the outbox as %s and retried in the background.",
+			e.Message.ConversationID, e.ChannelID, e.Attempts, e.Error, e.ID)
+		if err := notifier.Notify(ctx, cfg.OperatorChannel, cfg.OperatorConversationID, msg); err != nil {
+			slog.Warn("notifying operator of undelivered reply failed", "component", "outbox", "id", e.ID, "error", err)
+		}
+	}
+	return p, nil
+}
+
+// openBlobStore builds the blob store from state.blobs and starts its GC.
+// refs is nil without a state DB; GC then goes by age alone. The returned
+// func stops the GC loop.
+func openBlobStore(cfg config.StateConfig, dataDir string, refs blob.RefSource) (*blob.Store, func(), error) {
+	bc := cfg.Blobs
+	if !bc.Enabled {
+		return nil, func() {}, nil
+	}
+	var backend blob.Backend
+	switch bc.Backend {
+	case "", "local":
+		dir := bc.Dir
+		if dir == "" {
+			if dataDir == "" {
+				return nil, nil, fmt.Errorf("state.blobs.dir is required when state.data_dir is not set")
+			}
+			dir = filepath.Join(dataDir, "blobs")
+		}
+		b, err := blob.NewLocalBackend(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		backend = b
+	case "s3":
+		b, err := blob.NewS3Backend(blob.S3Config{
+			Bucket:          bc.S3.Bucket,
+			Region:          bc.S3.Region,
+			Endpoint:        bc.S3.Endpoint,
+			Prefix:          bc.S3.Prefix,
+			AccessKeyID:     bc.S3.AccessKeyID,
+			SecretAccessKey: bc.S3.SecretAccessKey,
+			PathStyle:       bc.S3.PathStyle,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		backend = b
+	default:
+		return nil, nil, fmt.Errorf("state.blobs.backend %q: use local or s3", bc.Backend)
+	}
+	store := blob.New(backend, bc.MaxBlobBytes)
+
+	// Unreferenced blobs go once no pruned-by-idleness session could still
+	// point at them. Without a ref source an in-use blob cannot be told
+	// apart, so age alone decides and the floor is higher.
+	minAge := time.Duration(cfg.Session.MaxIdleDays) * 24 * time.Hour
+	if minAge <= 0 {
+		minAge = 24 * time.Hour
+		if refs == nil {
+			minAge = 30 * 24 * time.Hour
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	go blob.RunGC(ctx, store, refs, minAge)
+	return store, cancel, nil
+}
+
+// openRAGIndex opens <data_dir>/rag.db when rag is enabled and keeps it in
+// sync with rag.sources in the background. The returned func stops the sync
+// and closes the index.
+func openRAGIndex(cfg *config.Config, dataDir string) (*rag.Index, func(), error) {
+	rc := cfg.RAG
+	if !rc.Enabled {
+		return nil, func() {}, nil
+	}
+	if dataDir == "" {
+		return nil, nil, fmt.Errorf("rag requires state.data_dir")
+	}
+	if len(rc.Sources) == 0 {
+		return nil, nil, fmt.Errorf("rag.sources is empty")
+	}
+	if rc.Embeddings.Model == "" {
+		return nil, nil, fmt.Errorf("rag.embeddings.model is required")
+	}
+	pc, ok := cfg.Models.Providers[rc.Embeddings.Provider]
+	if !ok {
+		return nil, nil, fmt.Errorf("rag.embeddings.provider %q is not under models.providers", rc.Embeddings.Provider)
+	}
+	if pc.API == provider.APIAnthropic {
+		return nil, nil, fmt.Errorf("rag.embeddings.provider %q: Anthropic has no embeddings API; use an OpenAI-compatible provider", rc.Embeddings.Provider)
+	}
+	interval := time.Hour
+	if rc.RefreshInterval != "" {
+		d, err := time.ParseDuration(rc.RefreshInterval)
+		if err != nil {
+			return nil, nil, fmt.Errorf("rag.refresh_interval: %w", err)
+		}
+		interval = d
+	}
+	embedder := provider.NewOpenAIEmbedder(pc.BaseURL, pc.APIKey, rc.Embeddings.Model)
+	idx, err := rag.Open(filepath.Join(dataDir, "rag.db"), embedder, rc.Embeddings.Provider+"/"+rc.Embeddings.Model)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rag.RunSync(ctx, idx, rc.Sources, interval)
+	}()
+	return idx, func() {
+		cancel()
+		<-done
+		_ = idx.Close()
+	}, nil
+}
+
+// speechClients builds the speech.stt transcriber and speech.tts speaker.
+// Either is nil when its provider is unset.
+func speechClients(cfg *config.Config) (orchestrator.Transcriber, channel.Speaker, bool, error) {
+	sc := cfg.Speech
+	resolve := func(key, id, model string) (config.ProviderConfig, error) {
+		if model == "" {
+			return config.ProviderConfig{}, fmt.Errorf("%s.model is required", key)
+		}
+		pc, ok := cfg.Models.Providers[id]
+		if !ok {
+			return pc, fmt.Errorf("%s.provider %q is not under models.providers", key, id)
+		}
+		if pc.API == provider.APIAnthropic {
+			return pc, fmt.Errorf("%s.provider %q: Anthropic has no audio API; use an OpenAI-compatible provider", key, id)
+		}
+		return pc, nil
+	}
+	var transcriber orchestrator.Transcriber
+	if sc.STT.Provider != "" {
+		pc, err := resolve("stt", sc.STT.Provider, sc.STT.Model)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		transcriber = provider.NewOpenAITranscriber(pc.BaseURL, pc.APIKey, sc.STT.Model, sc.STT.Language)
+	}
+	var speaker channel.Speaker
+	speakAlways := false
+	if sc.TTS.Provider != "" {
+		pc, err := resolve("tts", sc.TTS.Provider, sc.TTS.Model)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		if sc.TTS.Voice == "" {
+			return nil, nil, false, fmt.Errorf("tts.voice is required")
+		}
+		switch sc.TTS.Format {
+		case "", "mp3", "opus", "aac", "flac", "wav":
+		default:
+			return nil, nil, false, fmt.Errorf("tts.format %q: want mp3, opus, aac, flac or wav", sc.TTS.Format)
+		}
+		switch sc.TTS.Reply {
+		case "", "voice":
+		case "always":
+			speakAlways = true
+		default:
+			return nil, nil, false, fmt.Errorf("tts.reply %q: want \"voice\" or \"always\"", sc.TTS.Reply)
+		}
+		speaker = provider.NewOpenAISpeaker(pc.BaseURL, pc.APIKey, sc.TTS.Model, sc.TTS.Voice, sc.TTS.Format)
+	}
+	return transcriber, speaker, speakAlways, nil
+}
+
+// agentsFromConfig builds the orchestrator's personas from agents: and
+// channels.<name>.agent.
+func agentsFromConfig(cfg *config.Config) (orchestrator.Agents, error) {
+	var a orchestrator.Agents
+	for _, ac := range cfg.Agents {
+		limits, err := loopLimitsFromConfig(ac.LoopLimits)
+		if err != nil {
+			return a, fmt.Errorf("agent %q loop_limits: %w", ac.Name, err)
+		}
+		a.List = append(a.List, orchestrator.Agent{
+			Name:            ac.Name,
+			Description:     ac.Description,
+			SystemPrompt:    ac.SystemPrompt,
+			Plugins:         ac.Plugins,
+			Model:           ac.Model,
+			HandoffApproval: ac.HandoffApproval,
+			Generation:      generationFromConfig(ac.Generation),
+			LoopLimits:      limits,
+		})
+		if ac.Default {
+			if a.Default != "" {
+				return a, fmt.Errorf("agents %q and %q are both marked default", a.Default, ac.Name)
+			}
+			a.Default = ac.Name
+		}
+	}
+	for name, ch := range cfg.Channels {
+		if ch.Agent == "" {
+			continue
+		}
+		if a.Channels == nil {
+			a.Channels = make(map[string]string)
+		}
+		a.Channels[name] = ch.Agent
+	}
+	return a, a.Validate()
+}
+
+// experimentsFromConfig builds the A/B variants from experiments:, sorted by
+// name so a session hashes to the same variant on every start.
+func experimentsFromConfig(cfg *config.Config) (orchestrator.Experiments, error) {
+	var e orchestrator.Experiments
+	for _, name := range slices.Sorted(maps.Keys(cfg.Experiments)) {
+		v := cfg.Experiments[name]
+		e.Variants = append(e.Variants, orchestrator.ExperimentVariant{
+			Name:         name,
+			Model:        v.Model,
+			PromptSuffix: v.PromptSuffix,
+			Weight:       v.Weight,
+		})
+	}
+	return e, e.Validate()
+}
+
+// localesFromConfig checks orchestrator.locale and collects the
+// channels.<name>.locale overrides.
+func localesFromConfig(cfg *config.Config) (map[string]string, error) {
+	if l := cfg.Orchestrator.Locale; l != "" {
+		if _, ok := orchestrator.ResolveLanguage(l); !ok {
+			return nil, fmt.Errorf("orchestrator.locale %q: use a language name or ISO 639-1 code", l)
+		}
+	}
+	var locales map[string]string
+	for name, ch := range cfg.Channels {
+		if ch.Locale == "" {
+			continue
+		}
+		if _, ok := orchestrator.ResolveLanguage(ch.Locale); !ok {
+			return nil, fmt.Errorf("channels.%s.locale %q: use a language name or ISO 639-1 code", name, ch.Locale)
+		}
+		if locales == nil {
+			locales = make(map[string]string)
+		}
+		locales[name] = ch.Locale
+	}
+	return locales, nil
+}
+
+// groupPolicies collects channels.<name>.group into the handler's
+// per-channel group-conversation policies.
+func groupPolicies(cfg *config.Config) map[string]channel.GroupPolicy {
+	var policies map[string]channel.GroupPolicy
+	for name, ch := range cfg.Channels {
+		if ch.Group == nil {
+			continue
+		}
+		if ch.Group.RequireMention && len(ch.Group.MentionNames) == 0 {
+			slog.Warn("channels.group.require_mention without mention_names: only messages the channel flags as mentions are answered", "channel", name)
+		}
+		if policies == nil {
+			policies = make(map[string]channel.GroupPolicy)
+		}
+		policies[name] = channel.GroupPolicy{
+			RequireMention: ch.Group.RequireMention,
+			MentionNames:   ch.Group.MentionNames,
+			PerSender:      ch.Group.PerSender,
+		}
+	}
+	return policies
+}
+
+// buildNotifyTool builds the notify tool from notify:; every destination
+// must be a configured channel.
+func buildNotifyTool(cfg *config.Config, sender notify.Sender) (*notify.Tool, error) {
+	nc := notify.Config{Channels: cfg.Notify.Channels, AllowedGroups: cfg.Notify.AllowedGroups}
+	for name, t := range cfg.Notify.Targets {
+		if nc.Targets == nil {
+			nc.Targets = make(map[string]notify.Target)
+		}
+		nc.Targets[name] = notify.Target{Channel: t.Channel, Conversation: t.Conversation}
+	}
+	if err := nc.Validate(); err != nil {
+		return nil, err
+	}
+	for _, t := range nc.Targets {
+		if _, ok := cfg.Channels[t.Channel]; !ok {
+			return nil, fmt.Errorf("target channel %q is not configured under channels", t.Channel)
+		}
+	}
+	for _, ch := range nc.Channels {
+		if _, ok := cfg.Channels[ch]; !ok {
+			return nil, fmt.Errorf("channel %q is not configured under channels", ch)
+		}
+	}
+	return notify.NewTool(sender, nc), nil
+}
+
+// promptOverrides collects channels.<name>.system_prompt and
+// orchestrator.group_system_prompts into the orchestrator's override set.
+func promptOverrides(cfg *config.Config) orchestrator.PromptOverrides {
+	var po orchestrator.PromptOverrides
+	for name, ch := range cfg.Channels {
+		if ch.SystemPrompt == nil {
+			continue
+		}
+		if po.Channels == nil {
+			po.Channels = make(map[string]orchestrator.PromptOverride)
+		}
+		po.Channels[name] = orchestrator.PromptOverride{Replace: ch.SystemPrompt.Replace, Append: ch.SystemPrompt.Append}
+	}
+	for group, sp := range cfg.Orchestrator.GroupSystemPrompts {
+		if po.Groups == nil {
+			po.Groups = make(map[string]orchestrator.PromptOverride)
+		}
+		po.Groups[group] = orchestrator.PromptOverride{Replace: sp.Replace, Append: sp.Append}
+	}
+	return po
+}
+
+// secretRedaction compiles orchestrator.secret_redaction.
+func secretRedaction(c config.SecretRedactionConfig) (orchestrator.SecretRedaction, error) {
+	sr := orchestrator.SecretRedaction{Enabled: c.Enabled}
+	for _, pat := range c.Allowlist {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return sr, fmt.Errorf("allowlist %q: %w", pat, err)
+		}
+		sr.Allowlist = append(sr.Allowlist, re)
+	}
+	return sr, nil
+}
+
+// compileToolPolicies compiles orchestrator.policies. require_approval needs
+// the approval queue.
+// toolScopes converts orchestrator.tool_scopes, naming unnamed scopes by
+// their position for logs and errors.
+func toolScopes(cs []config.ToolScopeConfig) ([]orchestrator.ToolScope, error) {
+	out := make([]orchestrator.ToolScope, 0, len(cs))
+	for i, c := range cs {
+		s := orchestrator.ToolScope{
+			Name:     cmp.Or(c.Name, fmt.Sprintf("tool_scopes[%d]", i)),
+			Channels: c.Channels,
+			Groups:   c.Groups,
+			Tasks:    c.Tasks,
+			Plugins:  c.Plugins,
+			Deny:     c.Deny,
+		}
+		if err := s.Validate(); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func compileToolPolicies(cs []config.ToolPolicyConfig, haveApprovals bool) ([]orchestrator.ToolPolicy, error) {
+	var out []orchestrator.ToolPolicy
+	for i, c := range cs {
+		p := orchestrator.ToolPolicy{
+			Name:    cmp.Or(c.Name, fmt.Sprintf("policies[%d]", i)),
+			Tools:   c.Tools,
+			Actors:  c.Actors,
+			Groups:  c.Groups,
+			Effect:  c.Effect,
+			Message: c.Message,
+		}
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+		if p.Effect == orchestrator.PolicyRequireApproval && !haveApprovals {
+			return nil, fmt.Errorf("policy %q: require_approval needs approvals.enabled", p.Name)
+		}
+		if c.Hours != "" {
+			w, err := orchestrator.ParseTimeWindow(c.Hours, c.Days, c.Timezone)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: %w", p.Name, err)
+			}
+			p.Window = w
+		} else if len(c.Days) > 0 || c.Timezone != "" {
+			return nil, fmt.Errorf("policy %q: days and timezone need hours", p.Name)
+		}
+		for name, pat := range c.Args {
+			re, err := regexp.Compile(pat)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: args.%s: %w", p.Name, name, err)
+			}
+			if p.Args == nil {
+				p.Args = make(map[string]*regexp.Regexp)
+			}
+			p.Args[name] = re
+		}
+		for _, w := range c.When {
+			cond, err := orchestrator.ParseArgCondition(w)
+			if err != nil {
+				return nil, fmt.Errorf("policy %q: when: %w", p.Name, err)
+			}
+			p.When = append(p.When, cond)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// guardPolicy compiles orchestrator.guard. HTTP request packages default to
+// untrusted, since their responses come straight from external services.
+func guardPolicy(c config.GuardConfig, requestSets []requestpkg.Set, llm orchestrator.LLMClient) (orchestrator.GuardPolicy, error) {
+	gp := orchestrator.GuardPolicy{Trust: map[string]string{}}
+	for _, pat := range c.DenyPatterns {
+		re, err := regexp.Compile(pat)
+		if err != nil {
+			return gp, fmt.Errorf("deny_patterns %q: %w", pat, err)
+		}
+		gp.DenyPatterns = append(gp.DenyPatterns, re)
+	}
+	for _, set := range requestSets {
+		if set.MCP == nil {
+			gp.Trust[set.Name()] = orchestrator.TrustUntrusted
+		}
+	}
+	for name, level := range c.Trust {
+		gp.Trust[name] = level
+	}
+	if c.Classifier.Enabled {
+		gp.Classifier = orchestrator.InjectionClassifier{LLM: llm, Model: c.Classifier.Model, FailOpen: c.Classifier.FailOpen}
+	}
+	return gp, gp.Validate()
+}
+
+// staticSchedulerJobs converts the enabled scheduler.jobs entries of cfg, and
+// its digest reports, into scheduler jobs. Used at startup and again on
+// config reload.
+func staticSchedulerJobs(cfg *config.Config) []scheduler.Job {
+	jobs := make([]scheduler.Job, 0, len(cfg.Scheduler.Jobs))
+	for _, jc := range cfg.Scheduler.Jobs {
+		if jc.Enabled != nil && !*jc.Enabled {
+			continue
+		}
+		job := scheduler.Job{
+			Name:                 jc.Name,
+			Interval:             jc.Interval,
+			Cron:                 jc.Cron,
+			At:                   jc.At,
+			Action:               jc.Action,
+			Args:                 jc.Args,
+			NotifyChannel:        jc.NotifyChannel,
+			NotifyConversationID: jc.NotifyConversationID,
+			NotifyTemplate:       jc.NotifyTemplate,
+			NoOverlap:            jc.NoOverlap,
+			Jitter:               jc.Jitter,
+		}
+		for _, t := range jc.NotifyTargets {
+			job.NotifyTargets = append(job.NotifyTargets, scheduler.NotifyTarget{Channel: t.Channel, ConversationID: t.ConversationID})
+		}
+		if jc.NotifyIf != nil {
+			job.NotifyIf = &scheduler.NotifyCondition{Match: jc.NotifyIf.Match, JSON: jc.NotifyIf.JSON}
+		}
+		if jc.Retry != nil {
+			job.Retry = &scheduler.RetryPolicy{Attempts: jc.Retry.Attempts, Backoff: jc.Retry.Backoff}
+		}
+		jobs = append(jobs, job)
+	}
+	return append(jobs, digestJobs(cfg.Digest.Reports)...)
+}
+
+// toolCacheConfig converts orchestrator.tool_cache; st is nil without a
+// state database or when persist is off.
+func toolCacheConfig(c config.ToolCacheConfig, st orchestrator.ToolCacheStore) orchestrator.ToolCacheConfig {
+	out := orchestrator.ToolCacheConfig{
+		Enabled:    c.Enabled,
+		DefaultTTL: parseDurationOrZero(c.DefaultTTL),
+		MaxEntries: c.MaxEntries,
+		Store:      st,
+	}
+	if len(c.TTL) > 0 {
+		out.TTLs = make(map[string]time.Duration, len(c.TTL))
+		for action, ttl := range c.TTL {
+			out.TTLs[action] = parseDurationOrZero(ttl)
+		}
+	}
+	if c.Enabled && c.Persist && st == nil {
+		slog.Warn("orchestrator.tool_cache.persist needs the state database; caching in memory only")
+	}
+	return out
+}
+
+// generationFromConfig converts a generation: block.
+func generationFromConfig(g config.GenerationConfig) orchestrator.Generation {
+	return orchestrator.Generation{MaxTokens: g.MaxTokens, Temperature: g.Temperature, TopP: g.TopP, Stop: g.Stop}
+}
+
+// taskGenerationFromConfig converts orchestrator.task_generation, rejecting
+// task names the orchestrator does not have.
+func taskGenerationFromConfig(tasks map[string]config.GenerationConfig) (map[string]orchestrator.Generation, error) {
+	if len(tasks) == 0 {
+		return nil, nil
+	}
+	out := make(map[string]orchestrator.Generation, len(tasks))
+	for name, g := range tasks {
+		switch name {
+		case orchestrator.TaskSummary, orchestrator.TaskTitle, orchestrator.TaskConfirmation, orchestrator.TaskSubagent:
+		default:
+			return nil, fmt.Errorf("unknown task %q (want %s, %s, %s or %s)", name,
+				orchestrator.TaskSummary, orchestrator.TaskTitle, orchestrator.TaskConfirmation, orchestrator.TaskSubagent)
+		}
+		gen := generationFromConfig(g)
+		if err := gen.Validate(); err != nil {
+			return nil, fmt.Errorf("task %q: %w", name, err)
+		}
+		out[name] = gen
+	}
+	return out, nil
+}
+
+// loopLimitsFromConfig converts a loop_limits: block.
+func loopLimitsFromConfig(c config.LoopLimitsConfig) (orchestrator.LoopLimits, error) {
+	l := orchestrator.LoopLimits{MaxIterations: c.MaxIterations}
+	if c.MaxDuration != "" {
+		d, err := time.ParseDuration(c.MaxDuration)
+		if err != nil {
+			return l, fmt.Errorf("max_duration: %w", err)
+		}
+		l.MaxDuration = d
+	}
+	return l, l.Validate()
+}
+
+// channelLoopLimitsFromConfig collects the loop_limits of every channel that
+// sets them, keyed by channel id.
+func channelLoopLimitsFromConfig(channels map[string]config.ChannelConfig) (map[string]orchestrator.LoopLimits, error) {
+	out := make(map[string]orchestrator.LoopLimits)
+	for name, ch := range channels {
+		if ch.LoopLimits == nil {
+			continue
+		}
+		l, err := loopLimitsFromConfig(*ch.LoopLimits)
+		if err != nil {
+			return nil, fmt.Errorf("channel %q: %w", name, err)
+		}
+		out[name] = l
+	}
+	return out, nil
+}
+
 // parseDurationOrZero parses a Go duration string, returning 0 (which the
 // consumer maps to its default) on empty or invalid input.
 func parseDurationOrZero(s string) time.Duration {
@@ -1729,3 +3330,23 @@ func defaultInt(v, fallback int) int {
 	}
 	return fallback
 }
+
+// requestAuthFromInline converts the inline auth block of a request package
+// (or set) to a requestpkg.Auth.
+func requestAuthFromInline(inl *config.RequestAuthInl) *requestpkg.Auth {
+	if inl == nil {
+		return nil
+	}
+	return &requestpkg.Auth{
+		Grant:           inl.Grant,
+		TokenURL:        inl.TokenURL,
+		ClientIDEnv:     inl.ClientIDEnv,
+		ClientSecretEnv: inl.ClientSecretEnv,
+		RefreshTokenEnv: inl.RefreshTokenEnv,
+		Scope:           inl.Scope,
+		ClientAuth:      inl.ClientAuth,
+		CacheTTL:        inl.CacheTTL,
+		Header:          inl.Header,
+		Format:          inl.Format,
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/opentalon/opentalon/internal/daemon"
+	"github.com/opentalon/opentalon/internal/replay"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+const replayUsage = `Usage: opentalon replay [-v] <session.json | ->
+  Replay a recorded session against this build: the LLM answers from the
+  session's own messages and tools with their recorded results. Exits 1 when
+  the tool calls or stored messages differ. Export a session with
+  opentalon ctl sessions show -json <id> > session.json`
+
+// runReplay implements `opentalon replay`: a regression check of parsing,
+// the guard and message construction against a real conversation.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	verbose := fs.Bool("v", false, "show the orchestrator's log")
+	_ = fs.Parse(args)
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, replayUsage)
+		os.Exit(daemon.ExitUsage)
+	}
+	var (
+		data []byte
+		err  error
+	)
+	if path := fs.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(daemon.ExitUsage)
+	}
+	var sess state.Session
+	if err := json.Unmarshal(data, &sess); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: not a session export: %v\n", err)
+		os.Exit(daemon.ExitUsage)
+	}
+	tr, err := replay.FromSession(&sess)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(daemon.ExitFailure)
+	}
+	if !*verbose {
+		log.SetOutput(io.Discard)
+		slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}
+	rep, err := replay.Run(context.Background(), tr, replay.Options{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(daemon.ExitFailure)
+	}
+	for _, d := range rep.Divergences {
+		fmt.Println(d)
+	}
+	if !rep.OK() {
+		fmt.Printf("Replayed %d turns: %d divergences.\n", rep.Turns, len(rep.Divergences))
+		os.Exit(daemon.ExitFailure)
+	}
+	fmt.Printf("Replayed %d turns: no divergences.\n", rep.Turns)
+}
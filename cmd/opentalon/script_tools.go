@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/js"
+	"github.com/opentalon/opentalon/internal/lua"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+// scriptEngine is a runtime that can host tool scripts.
+type scriptEngine struct {
+	name string // "lua" or "js", for logs
+	load func(scriptPath string, opts lua.Options) (*lua.ToolSpec, error)
+	run  func(ctx context.Context, scriptPath string, opts lua.Options, action string, args map[string]string) (lua.ToolOutput, error)
+}
+
+var (
+	luaEngine = scriptEngine{name: "lua", load: lua.LoadTool, run: lua.RunTool}
+	jsEngine  = scriptEngine{name: "js", load: js.LoadTool, run: js.RunTool}
+)
+
+// scriptToolExecutor runs the execute function of a tool script. Each call
+// gets a fresh sandbox, so scripts keep no state between calls.
+type scriptToolExecutor struct {
+	engine     scriptEngine
+	scriptPath string
+	opts       lua.Options
+}
+
+func (e *scriptToolExecutor) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	out, err := e.engine.run(ctx, e.scriptPath, e.opts, call.Action, call.Args)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: out.Content, Error: out.Error}
+}
+
+// luaOptions builds the sandbox options for Lua and JS scripts from the lua
+// config.
+// An invalid duration keeps the default rather than failing startup.
+func luaOptions(c *config.LuaConfig) lua.Options {
+	if c == nil {
+		return lua.Options{}
+	}
+	opts := lua.Options{HTTPAllow: c.HTTPAllow, MaxInstructions: c.MaxInstructions, Config: c.Config}
+	if c.MaxMemoryMB != 0 {
+		opts.MaxMemoryBytes = c.MaxMemoryMB << 20
+	}
+	for _, d := range []struct {
+		key   string
+		value string
+		dst   *time.Duration
+	}{
+		{"lua.http_timeout", c.HTTPTimeout, &opts.HTTPTimeout},
+		{"lua.timeout", c.Timeout, &opts.Timeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			slog.Warn("invalid duration, using default", "component", "lua", "key", d.key, "value", d.value, "error", err)
+			continue
+		}
+		*d.dst = v
+	}
+	return opts
+}
+
+// registerScriptTools registers each named script as a tool plugin run by
+// engine (lua.tools, js.tools). A script that is missing or fails to load is
+// skipped with a warning, like a plugin that fails to start. When a
+// registered script changes on disk its capability is re-read, so new
+// actions and descriptions reach the LLM without a restart.
+func registerScriptTools(ctx context.Context, registry *orchestrator.ToolRegistry, engine scriptEngine, scriptPaths map[string]string, tools []string, opts lua.Options) {
+	if len(tools) == 0 {
+		return
+	}
+	registered := make(map[string]string) // abs script path -> plugin name
+	for _, name := range tools {
+		path := scriptPaths[name]
+		if path == "" {
+			slog.Warn("tool script not found", "component", engine.name, "script", name)
+			continue
+		}
+		capability, err := loadScriptTool(engine, path, opts)
+		if err != nil {
+			slog.Warn("tool script failed to load", "component", engine.name, "script", name, "error", err)
+			continue
+		}
+		if err := registry.Register(capability, &scriptToolExecutor{engine: engine, scriptPath: path, opts: opts}); err != nil {
+			slog.Warn("register tool script failed", "component", engine.name, "script", name, "error", err)
+			continue
+		}
+		if abs, err := filepath.Abs(path); err == nil {
+			registered[abs] = capability.Name
+		}
+		slog.Info("tool script registered", "component", engine.name, "plugin", capability.Name, "actions", len(capability.Actions))
+	}
+
+	paths := make([]string, 0, len(registered))
+	for p := range registered {
+		paths = append(paths, p)
+	}
+	err := lua.Watch(ctx, paths, func(path string) {
+		reloadScriptTool(registry, engine, registered[path], path, opts)
+	})
+	if err != nil {
+		slog.Warn("tool script watcher failed; capabilities reload on restart only", "component", engine.name, "error", err)
+	}
+}
+
+// reloadScriptTool re-reads the capability of the tool script at path, which
+// is registered as plugin name. A script that no longer loads, or now
+// declares another name, keeps its previous capability.
+func reloadScriptTool(registry *orchestrator.ToolRegistry, engine scriptEngine, name, path string, opts lua.Options) {
+	capability, err := loadScriptTool(engine, path, opts)
+	if err != nil {
+		slog.Warn("tool script reload failed, keeping previous capability", "component", engine.name, "plugin", name, "error", err)
+		return
+	}
+	if capability.Name != name {
+		slog.Warn("tool script renamed; restart to apply the new name", "component", engine.name, "plugin", name, "new_name", capability.Name)
+		capability.Name = name
+	}
+	registry.UpdateCapability(name, capability)
+	slog.Info("tool script reloaded", "component", engine.name, "plugin", name, "actions", len(capability.Actions))
+}
+
+func loadScriptTool(engine scriptEngine, path string, opts lua.Options) (orchestrator.PluginCapability, error) {
+	spec, err := engine.load(path, opts)
+	if err != nil {
+		return orchestrator.PluginCapability{}, err
+	}
+	capability := orchestrator.PluginCapability{Name: spec.Name, Description: spec.Description}
+	for _, a := range spec.Actions {
+		action := orchestrator.Action{Name: a.Name, Description: a.Description, ReadOnly: a.ReadOnly, CacheTTL: a.CacheTTL}
+		for _, p := range a.Parameters {
+			action.Parameters = append(action.Parameters, orchestrator.ParameterFromWire(p.Name, p.Description, p.Type, p.Required))
+		}
+		capability.Actions = append(capability.Actions, action)
+	}
+	return capability, nil
+}
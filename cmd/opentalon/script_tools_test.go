@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/lua"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func TestRegisterScriptTools(t *testing.T) {
+	dir := t.TempDir()
+	script := `
+plugin = {
+  name = "greeter",
+  description = "Says hello",
+  actions = { { name = "hello", read_only = true, parameters = { { name = "who", required = true } } } },
+}
+function execute(action, args) return "hello " .. args.who end
+`
+	if err := os.WriteFile(filepath.Join(dir, "greeter.lua"), []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.lua"), []byte("plugin = {"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	paths := map[string]string{"greeter": filepath.Join(dir, "greeter.lua"), "broken": filepath.Join(dir, "broken.lua")}
+
+	registry := orchestrator.NewToolRegistry()
+	registerScriptTools(context.Background(), registry, luaEngine, paths, []string{"greeter", "broken", "missing"}, lua.Options{})
+
+	if !registry.HasAction("greeter", "hello") || !registry.IsActionReadOnly("greeter", "hello") {
+		t.Fatal("greeter.hello should be registered as read-only")
+	}
+	if len(registry.ListCapabilities()) != 1 {
+		t.Errorf("only the loadable script should register, got %d capabilities", len(registry.ListCapabilities()))
+	}
+	exec, _ := registry.GetExecutor("greeter")
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Plugin: "greeter", Action: "hello", Args: map[string]string{"who": "Ada"}})
+	if res.Content != "hello Ada" || res.Error != "" || res.CallID != "1" {
+		t.Errorf("result = %+v", res)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/evaluation"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	chanpkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// completionTimeout bounds the summarization and actions run for one
+// completed session.
+const completionTimeout = 5 * time.Minute
+
+// sessionSummarizer is the orchestrator surface session completion needs
+// besides RunAction.
+type sessionSummarizer interface {
+	actionRunner
+	SummarizeSession(ctx context.Context, sessionID string)
+}
+
+// sessionCompleter runs state.session.completion for a session that went
+// idle. orch is set once the orchestrator exists; the tracker only fires
+// after a turn, so it is never nil by then. judge, when set, scores the
+// session (see package evaluation).
+type sessionCompleter struct {
+	cfg    config.SessionCompletionConfig
+	orch   sessionSummarizer
+	events *eventbus.Bus
+	judge  *evaluation.Judge
+}
+
+func (c *sessionCompleter) complete(sessionID string, idleFor time.Duration) {
+	ctx, cancel := context.WithTimeout(actor.WithSessionID(context.Background(), sessionID), completionTimeout)
+	defer cancel()
+	// Judge before summarizing: summarization drops the older messages.
+	if c.judge != nil {
+		if _, err := c.judge.Evaluate(ctx, sessionID); err != nil {
+			slog.Warn("session evaluation failed", "component", "evaluation", "session", sessionID, "error", err)
+		}
+	}
+	if c.cfg.Summarize {
+		c.orch.SummarizeSession(ctx, sessionID)
+	}
+	for _, a := range c.cfg.Actions {
+		args := map[string]string{"session_id": sessionID, "idle_for": idleFor.String()}
+		if _, err := c.orch.RunAction(ctx, a.Plugin, a.Action, args); err != nil {
+			slog.Warn("session completion action failed", "component", "session", "session", sessionID, "plugin", a.Plugin, "action", a.Action, "error", err)
+		}
+	}
+	c.events.Publish(ctx, eventbus.Event{
+		Type:      eventbus.SessionCompleted,
+		SessionID: sessionID,
+		Data:      map[string]string{"idle_for": idleFor.String()},
+	})
+}
+
+// idleTimeouts parses state.session.completion into a per-session timeout
+// (the channel override, else idle_timeout). It returns nil when no session
+// can ever complete.
+func idleTimeouts(cfg config.SessionCompletionConfig) (func(sessionID string) time.Duration, error) {
+	for i, a := range cfg.Actions {
+		if a.Plugin == "" || a.Action == "" {
+			return nil, fmt.Errorf("state.session.completion.actions[%d]: plugin and action are required", i)
+		}
+	}
+	parse := func(field, s string) (time.Duration, error) {
+		if s == "" {
+			return 0, nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("state.session.completion.%s: %w", field, err)
+		}
+		return d, nil
+	}
+	def, err := parse("idle_timeout", cfg.IdleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	channels := make(map[string]time.Duration, len(cfg.Channels))
+	enabled := def > 0
+	for id, s := range cfg.Channels {
+		d, err := parse("channels."+id, s)
+		if err != nil {
+			return nil, err
+		}
+		channels[id] = d
+		enabled = enabled || d > 0
+	}
+	if !enabled {
+		return nil, nil
+	}
+	return func(sessionID string) time.Duration {
+		ch, _, _ := strings.Cut(sessionID, ":")
+		if d, ok := channels[ch]; ok {
+			return d
+		}
+		return def
+	}, nil
+}
+
+// sessionCloser closes the idle conversations of channels with auto_close
+// (see orchestrator.CloseSession) and posts the channel's notice. orch and
+// send are set once the orchestrator and the channel registry exist.
+type sessionCloser struct {
+	notices map[string]string // by channel id; "" = close silently
+	orch    interface {
+		CloseSession(ctx context.Context, sessionID string) error
+	}
+	send   func(ctx context.Context, sessionID string, msg chanpkg.OutboundMessage) error
+	events *eventbus.Bus
+}
+
+func (c *sessionCloser) close(sessionID string, idleFor time.Duration) {
+	ctx, cancel := context.WithTimeout(actor.WithSessionID(context.Background(), sessionID), completionTimeout)
+	defer cancel()
+	if err := c.orch.CloseSession(ctx, sessionID); err != nil {
+		slog.Warn("closing idle session failed", "component", "session", "session", sessionID, "error", err)
+		return
+	}
+	if notice := c.notices[sessionChannel(sessionID, c.notices)]; notice != "" && c.send != nil {
+		if err := c.send(ctx, sessionID, chanpkg.OutboundMessage{Content: notice}); err != nil {
+			slog.Warn("sending session close notice failed", "component", "session", "session", sessionID, "error", err)
+		}
+	}
+	c.events.Publish(ctx, eventbus.Event{
+		Type:      eventbus.SessionClosed,
+		SessionID: sessionID,
+		Data:      map[string]string{"idle_for": idleFor.String()},
+	})
+}
+
+// autoCloseTimeouts parses channels.<name>.auto_close into a per-session
+// timeout and the notice of each channel. It returns a nil func when no
+// channel closes its sessions.
+func autoCloseTimeouts(channels map[string]config.ChannelConfig) (func(sessionID string) time.Duration, map[string]string, error) {
+	after := make(map[string]time.Duration)
+	notices := make(map[string]string)
+	for name, ch := range channels {
+		if ch.AutoClose == nil {
+			continue
+		}
+		d, err := time.ParseDuration(ch.AutoClose.After)
+		if err != nil {
+			return nil, nil, fmt.Errorf("channels.%s.auto_close.after: %w", name, err)
+		}
+		if d <= 0 {
+			return nil, nil, fmt.Errorf("channels.%s.auto_close.after: must be positive", name)
+		}
+		after[name] = d
+		notices[name] = ch.AutoClose.Notice
+	}
+	if len(after) == 0 {
+		return nil, nil, nil
+	}
+	return func(sessionID string) time.Duration {
+		return after[sessionChannel(sessionID, after)]
+	}, notices, nil
+}
+
+// sessionChannel finds the channel of a session key among the given
+// channels. Keys are "<channel>:<conversation>[:<thread>]", and the channel
+// handler prefixes "<entity>:" once it knows the user, so the channel is not
+// always the first segment.
+func sessionChannel[V any](sessionID string, channels map[string]V) string {
+	for part := range strings.SplitSeq(sessionID, ":") {
+		if _, ok := channels[part]; ok {
+			return part
+		}
+	}
+	return ""
+}
+
+// activityObservers fans turn notifications out to several observers, e.g.
+// the completion and the auto-close trackers.
+type activityObservers []orchestrator.SessionActivityObserver
+
+func (a activityObservers) TurnStarted(sessionID string) {
+	for _, o := range a {
+		o.TurnStarted(sessionID)
+	}
+}
+
+func (a activityObservers) TurnFinished(sessionID string) {
+	for _, o := range a {
+		o.TurnFinished(sessionID)
+	}
+}
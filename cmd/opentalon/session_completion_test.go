@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	chanpkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func TestIdleTimeouts(t *testing.T) {
+	timeoutFor, err := idleTimeouts(config.SessionCompletionConfig{
+		IdleTimeout: "30m",
+		Channels:    map[string]string{"slack": "4h", "cli": "0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]time.Duration{
+		"web:c1":       30 * time.Minute,
+		"slack:C1:t99": 4 * time.Hour,
+		"cli:local":    0,
+	} {
+		if got := timeoutFor(id); got != want {
+			t.Errorf("%s: timeout %v, want %v", id, got, want)
+		}
+	}
+
+	if fn, err := idleTimeouts(config.SessionCompletionConfig{}); fn != nil || err != nil {
+		t.Errorf("unset completion should be off: %v", err)
+	}
+	if _, err := idleTimeouts(config.SessionCompletionConfig{IdleTimeout: "soon"}); err == nil {
+		t.Error("expected error for a bad duration")
+	}
+	if _, err := idleTimeouts(config.SessionCompletionConfig{IdleTimeout: "1m", Actions: []config.SessionCompletionAction{{Plugin: "score"}}}); err == nil {
+		t.Error("expected error for an action without a name")
+	}
+}
+
+type fakeSummarizer struct {
+	recordingRunner
+	summarized []string
+}
+
+func (f *fakeSummarizer) SummarizeSession(_ context.Context, sessionID string) {
+	f.summarized = append(f.summarized, sessionID)
+}
+
+func TestSessionCompleterRunsStepsAndPublishes(t *testing.T) {
+	bus := eventbus.New(4)
+	defer bus.Close(context.Background())
+	got := make(chan eventbus.Event, 1)
+	bus.Subscribe(eventbus.SessionCompleted, "test", func(_ context.Context, e eventbus.Event) error {
+		got <- e
+		return nil
+	})
+	orch := &fakeSummarizer{recordingRunner: recordingRunner{calls: make(chan map[string]string, 1)}}
+	c := &sessionCompleter{
+		cfg: config.SessionCompletionConfig{
+			Summarize: true,
+			Actions:   []config.SessionCompletionAction{{Plugin: "score", Action: "grade"}},
+		},
+		orch:   orch,
+		events: bus,
+	}
+
+	c.complete("web:c1", 30*time.Minute)
+	if len(orch.summarized) != 1 || orch.summarized[0] != "web:c1" {
+		t.Errorf("summarized = %v", orch.summarized)
+	}
+	if args := <-orch.calls; args["_call"] != "score.grade" || args["session_id"] != "web:c1" || args["idle_for"] != "30m0s" {
+		t.Errorf("action args = %v", args)
+	}
+	select {
+	case e := <-got:
+		if e.SessionID != "web:c1" || e.Data["idle_for"] != "30m0s" {
+			t.Errorf("event = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no session_completed event")
+	}
+}
+
+func TestAutoCloseTimeouts(t *testing.T) {
+	timeoutFor, notices, err := autoCloseTimeouts(map[string]config.ChannelConfig{
+		"slack": {AutoClose: &config.AutoCloseConfig{After: "8h", Notice: "archived"}},
+		"web":   {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]time.Duration{
+		"slack:C1":        8 * time.Hour,
+		"slack:C1:t99":    8 * time.Hour,
+		"U42:slack:C1":    8 * time.Hour, // profile-scoped key
+		"web:c1":          0,
+		"console:slack-x": 0,
+	} {
+		if got := timeoutFor(id); got != want {
+			t.Errorf("%s: timeout %v, want %v", id, got, want)
+		}
+	}
+	if notices["slack"] != "archived" {
+		t.Errorf("notices = %v", notices)
+	}
+
+	if fn, _, err := autoCloseTimeouts(map[string]config.ChannelConfig{"web": {}}); fn != nil || err != nil {
+		t.Errorf("no auto_close should be off: %v", err)
+	}
+	for _, after := range []string{"", "soon", "0"} {
+		if _, _, err := autoCloseTimeouts(map[string]config.ChannelConfig{"slack": {AutoClose: &config.AutoCloseConfig{After: after}}}); err == nil {
+			t.Errorf("after %q accepted", after)
+		}
+	}
+}
+
+type fakeCloser struct{ closed []string }
+
+func (f *fakeCloser) CloseSession(_ context.Context, sessionID string) error {
+	f.closed = append(f.closed, sessionID)
+	return nil
+}
+
+func TestSessionCloserClosesNotifiesAndPublishes(t *testing.T) {
+	bus := eventbus.New(4)
+	defer bus.Close(context.Background())
+	got := make(chan eventbus.Event, 1)
+	bus.Subscribe(eventbus.SessionClosed, "test", func(_ context.Context, e eventbus.Event) error {
+		got <- e
+		return nil
+	})
+	orch := &fakeCloser{}
+	var sent []string
+	c := &sessionCloser{
+		notices: map[string]string{"slack": "I've archived this conversation.", "web": ""},
+		orch:    orch,
+		send: func(_ context.Context, sessionID string, msg chanpkg.OutboundMessage) error {
+			sent = append(sent, sessionID+" "+msg.Content)
+			return nil
+		},
+		events: bus,
+	}
+
+	c.close("slack:C1:t9", 8*time.Hour)
+	c.close("web:c1", time.Hour)
+	if len(orch.closed) != 2 {
+		t.Errorf("closed = %v", orch.closed)
+	}
+	if len(sent) != 1 || sent[0] != "slack:C1:t9 I've archived this conversation." {
+		t.Errorf("notices sent = %q", sent)
+	}
+	select {
+	case e := <-got:
+		if e.SessionID != "slack:C1:t9" || e.Data["idle_for"] != "8h0m0s" {
+			t.Errorf("event = %+v", e)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no session_closed event")
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/commands"
+	"github.com/opentalon/opentalon/internal/config"
+)
+
+const skillUsage = `Usage: opentalon skill <command> -config <path> [args]
+  search [words]        search the skills index (request_packages.skills_index)
+  install <name> [ref]  install a skill from the index (checksum verified at the index ref)
+  update [name]         update installed skills; pinned ones are skipped
+  list                  list installed skills and their locked commits
+  pin <name> [ref]      pin an installed skill to a commit (default: the installed one)
+Installed skills are loaded on the next start.`
+
+// runSkill implements `opentalon skill ...`: the same operations as the
+// opentalon skill_* tool actions, against the data dir of a config.
+func runSkill(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, skillUsage)
+		os.Exit(1)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("skill "+cmd, flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	_ = fs.Parse(args[1:])
+	rest := fs.Args()
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, skillUsage)
+		os.Exit(1)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	abs, err := filepath.Abs(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving config path: %v\n", err)
+		os.Exit(1)
+	}
+	market := commands.NewSkillMarket(config.ResolveStateDataDir(cfg, abs), cfg.RequestPackages.SkillsIndex)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	arg := func(i int) string {
+		if i < len(rest) {
+			return rest[i]
+		}
+		return ""
+	}
+	var out string
+	switch cmd {
+	case "search":
+		found, serr := market.Search(ctx, strings.Join(rest, " "))
+		out, err = commands.FormatSkillSearch(found), serr
+	case "install":
+		if arg(0) == "" {
+			fmt.Fprintln(os.Stderr, skillUsage)
+			os.Exit(1)
+		}
+		if _, err = market.Install(ctx, arg(0), arg(1)); err == nil {
+			out = fmt.Sprintf("Installed skill %q.", arg(0))
+		}
+	case "update":
+		updates, uerr := market.Update(ctx, arg(0))
+		err = uerr
+		for _, u := range updates {
+			out += u.String() + "\n"
+		}
+		if err == nil && len(updates) == 0 {
+			out = "No skills installed."
+		}
+	case "list":
+		list, lerr := market.List()
+		out, err = commands.FormatSkillList(list), lerr
+	case "pin":
+		if arg(0) == "" {
+			fmt.Fprintln(os.Stderr, skillUsage)
+			os.Exit(1)
+		}
+		var sha string
+		if sha, err = market.Pin(ctx, arg(0), arg(1)); err == nil {
+			out = fmt.Sprintf("Pinned skill %q at %s.", arg(0), sha)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown skill command %q.\n%s\n", cmd, skillUsage)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(strings.TrimRight(out, "\n"))
+}
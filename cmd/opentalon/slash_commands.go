@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/internal/commands"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/scheduler"
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// slashCommands are the built-in commands of commands.enabled. They act on
+// the caller's own session, tools, usage and jobs only.
+type slashCommands struct {
+	cfg        *config.Config
+	runAction  pkg.RunActionFunc
+	registry   *orchestrator.ToolRegistry
+	usageStore channel.LimitChecker // nil = usage not tracked
+	sched      *scheduler.Scheduler
+}
+
+// router returns the command router for the handler, applying
+// commands.permissions.
+func (s *slashCommands) router() (*channel.CommandRouter, error) {
+	list := []channel.Command{
+		{Name: "reset", Description: "Clear this conversation", Run: s.reset},
+		{Name: "model", Usage: "[alias | provider/model | default]", Description: "Show or switch this conversation's model", Run: s.model},
+		{Name: "tools", Description: "List the tools you can use", Run: s.tools},
+		{Name: "usage", Description: "Show your token usage", Run: s.usage},
+		{Name: "jobs", Description: "List your scheduled jobs", Run: s.jobs},
+	}
+	for name, perm := range s.cfg.Commands.Permissions {
+		i := slices.IndexFunc(list, func(c channel.Command) bool { return c.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("permissions: unknown command %q", name)
+		}
+		list[i].Users, list[i].Groups = perm.Users, perm.Groups
+	}
+	return channel.NewCommandRouter(s.cfg.Commands.Prefix, list), nil
+}
+
+func (s *slashCommands) reset(ctx context.Context, call channel.CommandCall) (string, error) {
+	return s.runAction(ctx, commands.PluginName, commands.ActionClearSession, map[string]string{"session_id": call.SessionKey})
+}
+
+func (s *slashCommands) model(ctx context.Context, call channel.CommandCall) (string, error) {
+	return s.runAction(ctx, commands.PluginName, commands.ActionSetSessionModel, map[string]string{"session_id": call.SessionKey, "model": call.Args})
+}
+
+// tools lists the plugins and actions the caller's profile group may use,
+// leaving out host-internal plugins and the slash-command executor.
+func (s *slashCommands) tools(ctx context.Context, _ channel.CommandCall) (string, error) {
+	var group string
+	if p := profile.FromContext(ctx); p != nil {
+		group = p.Group
+	}
+	caps := s.registry.ListCapabilities()
+	slices.SortFunc(caps, func(a, b orchestrator.PluginCapability) int { return strings.Compare(a.Name, b.Name) })
+	var b strings.Builder
+	for _, c := range caps {
+		if strings.HasPrefix(c.Name, "_") || c.Name == commands.PluginName || len(c.Actions) == 0 {
+			continue
+		}
+		if len(c.AllowedGroups) > 0 && !slices.Contains(c.AllowedGroups, group) {
+			continue
+		}
+		actions := make([]string, len(c.Actions))
+		for i, a := range c.Actions {
+			actions[i] = a.Name
+		}
+		fmt.Fprintf(&b, "\n%s: %s", c.Name, strings.Join(actions, ", "))
+	}
+	if b.Len() == 0 {
+		return "No tools are available.", nil
+	}
+	return "Tools:" + b.String(), nil
+}
+
+// usage reports the caller's chat tokens: against their limit when the
+// profile has one, else for the last day and week. Usage is recorded per
+// profile entity, so callers without a verified profile get no figures.
+func (s *slashCommands) usage(ctx context.Context, _ channel.CommandCall) (string, error) {
+	p := profile.FromContext(ctx)
+	if s.usageStore == nil || p == nil || p.EntityID == "" {
+		return "Usage is not tracked for this conversation.", nil
+	}
+	now := time.Now()
+	if p.Limit > 0 && p.LimitWindow > 0 {
+		used, err := s.usageStore.TotalTokensSince(ctx, p.EntityID, now.Add(-p.LimitWindow))
+		if err != nil {
+			return "", fmt.Errorf("usage lookup: %w", err)
+		}
+		return fmt.Sprintf("You have used %d of %d tokens in the last %s.", used, p.Limit, p.LimitWindow), nil
+	}
+	day, err := s.usageStore.TotalTokensSince(ctx, p.EntityID, now.Add(-24*time.Hour))
+	if err != nil {
+		return "", fmt.Errorf("usage lookup: %w", err)
+	}
+	week, err := s.usageStore.TotalTokensSince(ctx, p.EntityID, now.Add(-7*24*time.Hour))
+	if err != nil {
+		return "", fmt.Errorf("usage lookup: %w", err)
+	}
+	return fmt.Sprintf("Tokens used: %d in the last 24 hours, %d in the last 7 days.", day, week), nil
+}
+
+func (s *slashCommands) jobs(ctx context.Context, _ channel.CommandCall) (string, error) {
+	jobs := s.sched.ListJobsForContext(ctx)
+	if len(jobs) == 0 {
+		return "You have no scheduled jobs.", nil
+	}
+	slices.SortFunc(jobs, func(a, b scheduler.Job) int { return strings.Compare(a.Name, b.Name) })
+	var b strings.Builder
+	b.WriteString("Scheduled jobs:")
+	for _, j := range jobs {
+		when := "every " + j.Interval
+		switch {
+		case j.Cron != "":
+			when = "cron " + j.Cron
+		case j.At != "":
+			when = "at " + j.At
+		}
+		fmt.Fprintf(&b, "\n%s: %s, %s", j.Name, j.Action, when)
+		if j.Paused {
+			b.WriteString(" (paused)")
+		}
+	}
+	return b.String(), nil
+}
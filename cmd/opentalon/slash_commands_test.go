@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+func TestSlashCommandsRouterPermissions(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Commands.Permissions = map[string]config.CommandPermission{"model": {Groups: []string{"admins"}}}
+	if _, err := (&slashCommands{cfg: cfg}).router(); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Commands.Permissions["deploy"] = config.CommandPermission{Users: []string{"e1"}}
+	if _, err := (&slashCommands{cfg: cfg}).router(); err == nil || !strings.Contains(err.Error(), `"deploy"`) {
+		t.Errorf("router() = %v, want an unknown command error", err)
+	}
+}
+
+func TestSlashToolsAndJobs(t *testing.T) {
+	registry := orchestrator.NewToolRegistry()
+	for _, c := range []orchestrator.PluginCapability{
+		{Name: "jira", Actions: []orchestrator.Action{{Name: "create_issue"}, {Name: "search"}}},
+		{Name: "ledger", AllowedGroups: []string{"finance"}, Actions: []orchestrator.Action{{Name: "post"}}},
+		{Name: "_meta", Actions: []orchestrator.Action{{Name: "ping"}}},
+	} {
+		if err := registry.Register(c, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sched := scheduler.New(nil, nil, "")
+	defer sched.Stop()
+	if err := sched.AddJob(scheduler.Job{Name: "standup", Cron: "0 9 * * 1-5", Action: "jira.search"}, "u7"); err != nil {
+		t.Fatal(err)
+	}
+	s := &slashCommands{registry: registry, sched: sched}
+	ctx := actor.WithActor(context.Background(), "slack:u7")
+
+	if got, _ := s.tools(ctx, channel.CommandCall{}); got != "Tools:\njira: create_issue, search" {
+		t.Errorf("/tools = %q", got)
+	}
+	if got, _ := s.jobs(ctx, channel.CommandCall{}); got != "Scheduled jobs:\nstandup: jira.search, cron 0 9 * * 1-5" {
+		t.Errorf("/jobs = %q", got)
+	}
+	if got, _ := s.jobs(actor.WithActor(context.Background(), "slack:u8"), channel.CommandCall{}); got != "You have no scheduled jobs." {
+		t.Errorf("/jobs for another user = %q", got)
+	}
+}
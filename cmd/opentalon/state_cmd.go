@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+const stateUsage = `Usage: opentalon state <command> -config <path> [args]
+  backup <file>         write a consistent snapshot of the SQLite state database to file (safe while running)
+  maintain [-vacuum]    prune to state.maintenance's size caps, ANALYZE, and VACUUM with -vacuum or maintenance.vacuum
+  rotate-key            re-encrypt everything not yet under state.encryption's current key (safe while running)
+  keygen                print a new random key for state.encryption (needs no -config)`
+
+// runState implements `opentalon state ...`: maintenance of the state
+// database of a config, run next to (or instead of) the daemon.
+func runState(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		os.Exit(1)
+	}
+	cmd := args[0]
+	fs := flag.NewFlagSet("state "+cmd, flag.ExitOnError)
+	configPath := fs.String("config", "", "path to config file")
+	vacuum := fs.Bool("vacuum", false, "maintain: VACUUM even when state.maintenance.vacuum is off")
+	_ = fs.Parse(args[1:])
+	rest := fs.Args()
+	if cmd == "keygen" {
+		key, err := store.GenerateKey()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(key)
+		return
+	}
+	if *configPath == "" {
+		fmt.Fprintln(os.Stderr, stateUsage)
+		os.Exit(1)
+	}
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	abs, err := filepath.Abs(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error resolving config path: %v\n", err)
+		os.Exit(1)
+	}
+	db, err := store.Open(cfg.State.DB, config.ResolveStateDataDir(cfg, abs))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() { _ = db.Close() }()
+	c, err := loadStateCipher(cfg.State.Encryption)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid state.encryption: %v\n", err)
+		os.Exit(1) //nolint:gocritic
+	}
+	db.SetCipher(c)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var out string
+	switch cmd {
+	case "backup":
+		if len(rest) != 1 {
+			fmt.Fprintln(os.Stderr, stateUsage)
+			os.Exit(1)
+		}
+		if err = db.Backup(ctx, rest[0]); err == nil {
+			out = "Backed up the state database to " + rest[0] + "."
+		}
+	case "maintain":
+		p := maintenancePolicy(cfg.State.Maintenance)
+		p.Vacuum = p.Vacuum || *vacuum
+		var r store.MaintenanceReport
+		if r, err = db.Maintain(ctx, p); err == nil {
+			out = fmt.Sprintf("Pruned %d sessions.", r.SessionsPruned)
+			if r.SizeBefore > 0 {
+				out += fmt.Sprintf(" Data: %.1f MB -> %.1f MB.", float64(r.SizeBefore)/(1<<20), float64(r.SizeAfter)/(1<<20))
+			}
+			if r.Vacuumed {
+				out += " Vacuumed."
+			}
+		}
+	case "rotate-key":
+		var r store.RotationReport
+		if r, err = db.RotateKey(ctx); err == nil {
+			out = fmt.Sprintf("Re-encrypted %d messages, %d summaries and %d memories.", r.Messages, r.Summaries, r.Memories)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown state command %q.\n%s\n", cmd, stateUsage)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1) //nolint:gocritic
+	}
+	fmt.Println(out)
+}
+
+// maintenancePolicy maps state.maintenance onto a store.MaintenancePolicy.
+func maintenancePolicy(mc config.MaintenanceConfig) store.MaintenancePolicy {
+	return store.MaintenancePolicy{
+		MaxBytes:    int64(mc.MaxSizeMB) << 20,
+		MaxSessions: mc.MaxSessions,
+		Vacuum:      mc.Vacuum,
+	}
+}
+
+// keyCommandTimeout bounds state.encryption.key_command.
+const keyCommandTimeout = 30 * time.Second
+
+// loadStateCipher builds the cipher for state.encryption, running
+// key_command for the current key when set; nil when encryption is off.
+func loadStateCipher(ec config.EncryptionConfig) (*store.Cipher, error) {
+	if !ec.Enabled() {
+		return nil, nil
+	}
+	current := ec.Key
+	if len(ec.KeyCommand) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), keyCommandTimeout)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, ec.KeyCommand[0], ec.KeyCommand[1:]...)
+		cmd.Stderr = &stderr
+		stdout, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("key_command: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		current = string(stdout)
+	}
+	key, err := store.ParseKey(current)
+	if err != nil {
+		return nil, err
+	}
+	var previous [][]byte
+	for i, s := range ec.PreviousKeys {
+		k, err := store.ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("previous_keys[%d]: %w", i, err)
+		}
+		previous = append(previous, k)
+	}
+	return store.NewCipher(key, previous...)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/workflow"
+)
+
+// workflowsFromConfig converts workflows.definitions for the engine.
+func workflowsFromConfig(cfg config.WorkflowsConfig) []workflow.Workflow {
+	out := make([]workflow.Workflow, 0, len(cfg.Definitions))
+	for _, wc := range cfg.Definitions {
+		out = append(out, workflow.Workflow{
+			Name:        wc.Name,
+			Description: wc.Description,
+			Inputs:      wc.Inputs,
+			Steps:       workflowSteps(wc.Steps),
+			Output:      wc.Output,
+		})
+	}
+	return out
+}
+
+func workflowSteps(steps []config.WorkflowStepConfig) []workflow.Step {
+	out := make([]workflow.Step, 0, len(steps))
+	for _, sc := range steps {
+		st := workflow.Step{
+			ID:              sc.ID,
+			Tool:            sc.Tool,
+			Args:            sc.Args,
+			Prompt:          sc.Prompt,
+			System:          sc.System,
+			Model:           sc.Model,
+			If:              sc.If,
+			ContinueOnError: sc.ContinueOnError,
+		}
+		if len(sc.Parallel) > 0 {
+			st.Parallel = workflowSteps(sc.Parallel)
+		}
+		if sc.Retry != nil {
+			st.Retry = workflow.Retry{Attempts: sc.Retry.Attempts, Backoff: parseDurationOrZero(sc.Retry.Backoff)}
+			if st.Retry.Backoff == 0 {
+				st.Retry.Backoff = time.Second
+			}
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// startWorkflowAPI serves the workflow HTTP API when workflows.api_addr is
+// set. The returned func stops it.
+func startWorkflowAPI(cfg config.WorkflowsConfig, e *workflow.Engine) func() {
+	if cfg.APIAddr == "" {
+		return func() {}
+	}
+	if cfg.APIToken == "" {
+		fmt.Fprintf(os.Stderr, "workflows.api_addr requires workflows.api_token\n")
+		os.Exit(1)
+	}
+	srv := &http.Server{Addr: cfg.APIAddr, Handler: workflow.NewHandler(e, cfg.APIToken), ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		slog.Info("workflow API listening", "component", "workflow", "addr", cfg.APIAddr)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("workflow API error", "component", "workflow", "error", err)
+		}
+	}()
+	return func() { _ = srv.Shutdown(context.Background()) }
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/config"
+)
+
+func TestWorkflowsFromConfig(t *testing.T) {
+	wfs := workflowsFromConfig(config.WorkflowsConfig{Definitions: []config.WorkflowConfig{{
+		Name:   "triage",
+		Inputs: []string{"ticket"},
+		Steps: []config.WorkflowStepConfig{
+			{ID: "fetch", Tool: "jira__get", Args: map[string]string{"id": "{{.Input.ticket}}"}, Retry: &config.JobRetryConfig{Attempts: 2}},
+			{ID: "fan", Parallel: []config.WorkflowStepConfig{
+				{ID: "a", Prompt: "classify {{.Steps.fetch.Output}}"},
+				{ID: "b", Tool: "slack__post", ContinueOnError: true, Retry: &config.JobRetryConfig{Attempts: 1, Backoff: "5s"}},
+			}},
+		},
+	}}})
+	if len(wfs) != 1 || len(wfs[0].Steps) != 2 {
+		t.Fatalf("workflows = %+v", wfs)
+	}
+	fetch, fan := wfs[0].Steps[0], wfs[0].Steps[1]
+	if fetch.Retry.Attempts != 2 || fetch.Retry.Backoff != time.Second || fetch.Args["id"] != "{{.Input.ticket}}" {
+		t.Errorf("fetch = %+v", fetch)
+	}
+	if len(fan.Parallel) != 2 || fan.Parallel[0].Prompt == "" || !fan.Parallel[1].ContinueOnError || fan.Parallel[1].Retry.Backoff != 5*time.Second {
+		t.Errorf("parallel = %+v", fan.Parallel)
+	}
+}
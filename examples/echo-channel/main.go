@@ -0,0 +1,135 @@
+// Command echo-channel is the reference channel plugin built on pkg/channel.
+// It reads one message per line from stdin and prints the core's replies to
+// stdout, which is all a terminal "platform" needs, so the parts every
+// channel has stand out: capabilities, config, delivering inbound messages
+// under backpressure, sending replies, and shutting down.
+//
+//	channels:
+//	  echo:
+//	    enabled: true
+//	    plugin: "./examples/echo-channel/echo-channel"
+//	    config:
+//	      prompt: "you> "
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/opentalon/opentalon/pkg/channel"
+)
+
+// echoChannel adapts a pair of streams (stdin/stdout) as a channel.
+type echoChannel struct {
+	in  io.Reader
+	out io.Writer
+
+	mu     sync.Mutex // Send may be called concurrently
+	prompt string
+	sender string
+}
+
+var (
+	_ channel.Channel             = (*echoChannel)(nil)
+	_ channel.ConfigurableChannel = (*echoChannel)(nil)
+)
+
+func (c *echoChannel) ID() string   { return "echo" }
+func (c *echoChannel) Kind() string { return "echo" }
+
+func (c *echoChannel) Capabilities() channel.Capabilities {
+	return channel.Capabilities{
+		ID:             "echo",
+		Name:           "Echo",
+		ResponseFormat: channel.FormatText,
+	}
+}
+
+// Configure receives the entry's config block before Start.
+func (c *echoChannel) Configure(config map[string]interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := config["prompt"].(string); ok {
+		c.prompt = p
+	}
+	if s, ok := config["sender"].(string); ok {
+		c.sender = s
+	}
+	return nil
+}
+
+// Start runs once for the life of the plugin, even if the core reconnects.
+// It must return quickly; messages go into inbox from a goroutine, through
+// channel.Deliver, so a slow core stops the reading rather than piling up
+// lines in memory.
+func (c *echoChannel) Start(ctx context.Context, inbox chan<- channel.InboundMessage) error {
+	c.mu.Lock()
+	sender := c.sender
+	if sender == "" {
+		sender = "local"
+	}
+	c.mu.Unlock()
+	go func() {
+		scanner := bufio.NewScanner(c.in)
+		for n := 1; scanner.Scan(); n++ {
+			msg := channel.InboundMessage{
+				ChannelID:      c.ID(),
+				Kind:           c.Kind(),
+				ConversationID: "stdin",
+				SenderID:       sender,
+				SenderName:     sender,
+				Content:        scanner.Text(),
+				Timestamp:      time.Now(),
+				// A stable id per line lets the core drop a message it has
+				// already seen if one is ever delivered twice.
+				MessageID: strconv.Itoa(os.Getpid()) + "-" + strconv.Itoa(n),
+			}
+			if err := channel.Deliver(ctx, inbox, msg); err != nil {
+				return
+			}
+		}
+	}()
+	c.printPrompt()
+	return nil
+}
+
+func (c *echoChannel) Send(_ context.Context, msg channel.OutboundMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, err := fmt.Fprintln(c.out, msg.Content); err != nil {
+		return err
+	}
+	if c.prompt != "" {
+		_, _ = fmt.Fprint(c.out, c.prompt)
+	}
+	return nil
+}
+
+func (c *echoChannel) printPrompt() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.prompt != "" {
+		_, _ = fmt.Fprint(c.out, c.prompt)
+	}
+}
+
+func (c *echoChannel) Stop() error { return nil }
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ch := &echoChannel{in: os.Stdin, out: os.Stdout}
+	if err := channel.Serve(ctx, ch); err != nil {
+		fmt.Fprintln(os.Stderr, "echo-channel:", err)
+		os.Exit(1)
+	}
+	_ = ch.Stop()
+}
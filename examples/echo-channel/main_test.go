@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	hostchannel "github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/pkg/channel"
+)
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+// TestEchoChannelWithHost runs the channel the way the core launches it and
+// drives it with the core's own channel client.
+func TestEchoChannelWithHost(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(channel.EnvSockDir, dir)
+	out := &syncBuffer{}
+	ch := &echoChannel{in: strings.NewReader("hello\n"), out: out}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() { served <- channel.Serve(ctx, ch) }()
+	defer func() {
+		cancel()
+		<-served
+	}()
+
+	sock := filepath.Join(dir, channel.SocketFileName)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(sock); err == nil {
+			break
+		}
+	}
+	client, err := hostchannel.DialChannel("unix", sock, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = client.Stop() }()
+	if err := client.Configure(map[string]interface{}{"prompt": "> ", "sender": "ada"}); err != nil {
+		t.Fatal(err)
+	}
+	inbox := make(chan channel.InboundMessage, 1)
+	if err := client.Start(ctx, inbox); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-inbox:
+		if msg.Content != "hello" || msg.SenderID != "ada" || msg.MessageID == "" {
+			t.Errorf("inbound = %+v", msg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no inbound message")
+	}
+	if err := client.Send(ctx, channel.OutboundMessage{ConversationID: "stdin", Content: "hi ada"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.String(); got != "> hi ada\n> " {
+		t.Errorf("printed %q", got)
+	}
+}
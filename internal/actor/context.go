@@ -8,6 +8,7 @@ type conversationKey struct{}
 type confirmationKey struct{}
 type groupKey struct{}
 type visibilityKey struct{}
+type messageKey struct{}
 
 // WithActor returns a context that carries the given actor ID (e.g. channel_id:sender_id).
 // Use Actor(ctx) to retrieve it. When the request has no actor, do not call WithActor.
@@ -153,3 +154,28 @@ func Visibility(ctx context.Context) string {
 	s, _ := v.(string)
 	return s
 }
+
+// WithMessageKey attaches the identity of the inbound message being handled
+// (channel, conversation and the platform's message id) so work it causes
+// can be made idempotent: a redelivered message carries the same key. When
+// empty, the original context is returned unchanged.
+func WithMessageKey(ctx context.Context, key string) context.Context {
+	if key == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, messageKey{}, key)
+}
+
+// MessageKey returns the inbound message key from the context, or empty
+// string if not set.
+func MessageKey(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	v := ctx.Value(messageKey{})
+	if v == nil {
+		return ""
+	}
+	s, _ := v.(string)
+	return s
+}
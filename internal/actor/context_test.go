@@ -60,3 +60,18 @@ func TestWithGroupID_GroupID(t *testing.T) {
 		t.Errorf("WithGroupID(_, \"\") should not overwrite; GroupID = %q", GroupID(ctx))
 	}
 }
+
+func TestWithMessageKey_MessageKey(t *testing.T) {
+	ctx := context.Background()
+	if MessageKey(ctx) != "" {
+		t.Errorf("MessageKey(background) = %q; want \"\"", MessageKey(ctx))
+	}
+	ctx = WithMessageKey(ctx, "telegram:42:1001")
+	if got := MessageKey(ctx); got != "telegram:42:1001" {
+		t.Errorf("MessageKey = %q; want telegram:42:1001", got)
+	}
+	ctx = WithMessageKey(ctx, "")
+	if MessageKey(ctx) != "telegram:42:1001" {
+		t.Errorf("WithMessageKey(_, \"\") should not overwrite; MessageKey = %q", MessageKey(ctx))
+	}
+}
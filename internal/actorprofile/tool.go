@@ -0,0 +1,191 @@
+// Package actorprofile provides the built-in profile tool, through which a
+// user reads and changes the preferences the assistant keeps about them
+// (state.ActorProfile): how to address them, which language to answer in,
+// their timezone, the response style they like and standing instructions.
+// A user can only reach their own profile; the actor id comes from the
+// request context, never from the LLM's arguments.
+package actorprofile
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+const ToolName = "profile"
+
+// Field limits, in characters. Every field ends up in each system prompt
+// the user's turns send, and users rather than operators write them.
+const (
+	maxNameChars         = 100
+	maxStyleChars        = 200
+	maxInstructionsChars = 1000
+)
+
+// fields lists the settable fields in the order they are reported.
+var fields = []string{"name", "locale", "timezone", "style", "instructions"}
+
+// Tool is the built-in profile plugin.
+type Tool struct {
+	store orchestrator.ActorProfileStore
+}
+
+func NewTool(store orchestrator.ActorProfileStore) *Tool {
+	return &Tool{store: store}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name: ToolName,
+		Description: "Read and change the current user's own preferences: name, reply language, timezone, response style and standing instructions. " +
+			"They apply to every later conversation of this user. Use it when the user states a lasting preference, e.g. \"always answer in German\" or \"keep it short\".",
+		Actions: []orchestrator.Action{
+			{
+				Name:        "get",
+				Description: "Show the user's saved preferences.",
+			},
+			{
+				Name:        "set",
+				Description: "Save preferences. Only the fields given change; the others are kept.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "name", Description: "How to address the user", Required: false},
+					{Name: "locale", Description: "Reply language, as a name or ISO 639-1 code (German, de)", Required: false},
+					{Name: "timezone", Description: "IANA timezone, e.g. Europe/Berlin", Required: false},
+					{Name: "style", Description: "Response style in a few words, e.g. concise, bullet points", Required: false},
+					{Name: "instructions", Description: "Standing instructions in the user's words; replaces the saved ones", Required: false},
+				},
+			},
+			{
+				Name:        "clear",
+				Description: "Forget preferences.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "fields", Description: "Comma-separated fields to forget (name, locale, timezone, style, instructions); empty = all", Required: false},
+				},
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	actorID := actor.Actor(ctx)
+	if actorID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "profiles need a known user; this request has none"}
+	}
+	p, err := t.store.ActorProfile(ctx, actorID)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if p == nil {
+		p = &state.ActorProfile{ActorID: actorID}
+	}
+	switch call.Action {
+	case "get":
+		if p.Empty() {
+			return orchestrator.ToolResult{CallID: call.ID, Content: "No preferences saved."}
+		}
+		return profileResult(call.ID, p)
+	case "set":
+		changed := false
+		for _, f := range fields {
+			v, ok := call.Args[f]
+			if !ok || strings.TrimSpace(v) == "" {
+				continue
+			}
+			if err := setField(p, f, strings.TrimSpace(v)); err != nil {
+				return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+			}
+			changed = true
+		}
+		if !changed {
+			return orchestrator.ToolResult{CallID: call.ID, Error: "give at least one of: " + strings.Join(fields, ", ")}
+		}
+	case "clear":
+		names := fields
+		if s := strings.TrimSpace(call.Args["fields"]); s != "" {
+			names = strings.Split(s, ",")
+		}
+		for _, f := range names {
+			if err := setField(p, strings.TrimSpace(f), ""); err != nil {
+				return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+			}
+		}
+	default:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown profile action: %s", call.Action)}
+	}
+	p.UpdatedAt = time.Now()
+	if err := t.store.SaveActorProfile(ctx, p); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if p.Empty() {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Preferences cleared."}
+	}
+	return profileResult(call.ID, p)
+}
+
+// setField validates and stores one field; v == "" clears it.
+func setField(p *state.ActorProfile, field, v string) error {
+	switch field {
+	case "name":
+		if err := checkLength(field, v, maxNameChars); err != nil {
+			return err
+		}
+		p.Name = v
+	case "locale":
+		if v != "" {
+			lang, ok := orchestrator.ResolveLanguage(v)
+			if !ok {
+				return fmt.Errorf("unknown language %q: give a language name or ISO 639-1 code", v)
+			}
+			v = strings.ToLower(lang.IsoCode639_1().String())
+		}
+		p.Locale = v
+	case "timezone":
+		if v != "" {
+			if _, err := time.LoadLocation(v); err != nil {
+				return fmt.Errorf("unknown timezone %q: give an IANA name such as Europe/Berlin", v)
+			}
+		}
+		p.Timezone = v
+	case "style":
+		if err := checkLength(field, v, maxStyleChars); err != nil {
+			return err
+		}
+		p.Style = v
+	case "instructions":
+		if err := checkLength(field, v, maxInstructionsChars); err != nil {
+			return err
+		}
+		p.Instructions = v
+	default:
+		return fmt.Errorf("unknown field %q (want one of %s)", field, strings.Join(fields, ", "))
+	}
+	return nil
+}
+
+func checkLength(field, v string, limit int) error {
+	if n := utf8.RuneCountInString(v); n > limit {
+		return fmt.Errorf("%s is %d characters; the limit is %d", field, n, limit)
+	}
+	return nil
+}
+
+func profileResult(callID string, p *state.ActorProfile) orchestrator.ToolResult {
+	data, err := json.Marshal(struct {
+		Name         string `json:"name,omitempty"`
+		Locale       string `json:"locale,omitempty"`
+		Timezone     string `json:"timezone,omitempty"`
+		Style        string `json:"style,omitempty"`
+		Instructions string `json:"instructions,omitempty"`
+	}{p.Name, p.Locale, p.Timezone, p.Style, p.Instructions})
+	if err != nil {
+		return orchestrator.ToolResult{CallID: callID, Error: fmt.Sprintf("marshaling profile: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: callID, Content: string(data)}
+}
@@ -0,0 +1,77 @@
+package actorprofile
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func run(t *testing.T, tool *Tool, ctx context.Context, action string, args map[string]string) orchestrator.ToolResult {
+	t.Helper()
+	return tool.Execute(ctx, orchestrator.ToolCall{ID: "c1", Plugin: ToolName, Action: action, Args: args})
+}
+
+func TestSetGetClear(t *testing.T) {
+	store := state.NewActorProfileStore()
+	tool := NewTool(store)
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+
+	if res := run(t, tool, ctx, "get", nil); res.Content != "No preferences saved." {
+		t.Errorf("empty get = %+v", res)
+	}
+	res := run(t, tool, ctx, "set", map[string]string{"locale": "German", "timezone": "Europe/Berlin", "style": "concise"})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	p, _ := store.ActorProfile(ctx, "slack:U1")
+	if p == nil || p.Locale != "de" || p.Timezone != "Europe/Berlin" || p.Style != "concise" {
+		t.Fatalf("saved profile = %+v", p)
+	}
+
+	// set only touches the fields it is given.
+	if res := run(t, tool, ctx, "set", map[string]string{"name": "Ana"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res := run(t, tool, ctx, "get", nil); !strings.Contains(res.Content, `"name":"Ana"`) || !strings.Contains(res.Content, `"locale":"de"`) {
+		t.Errorf("get = %s", res.Content)
+	}
+
+	if res := run(t, tool, ctx, "clear", map[string]string{"fields": "locale, style"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	p, _ = store.ActorProfile(ctx, "slack:U1")
+	if p.Locale != "" || p.Style != "" || p.Name != "Ana" {
+		t.Errorf("after partial clear = %+v", p)
+	}
+	if res := run(t, tool, ctx, "clear", nil); res.Content != "Preferences cleared." {
+		t.Errorf("clear all = %+v", res)
+	}
+	if p, _ := store.ActorProfile(ctx, "slack:U1"); p != nil {
+		t.Errorf("profile kept after clearing everything: %+v", p)
+	}
+}
+
+func TestSetRejectsBadValues(t *testing.T) {
+	tool := NewTool(state.NewActorProfileStore())
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+	for name, args := range map[string]map[string]string{
+		"locale":       {"locale": "Klingon"},
+		"timezone":     {"timezone": "Mars/Olympus"},
+		"instructions": {"instructions": strings.Repeat("x", maxInstructionsChars+1)},
+		"nothing":      {},
+	} {
+		if res := run(t, tool, ctx, "set", args); res.Error == "" {
+			t.Errorf("%s: expected an error, got %+v", name, res)
+		}
+	}
+	if res := run(t, tool, ctx, "clear", map[string]string{"fields": "password"}); res.Error == "" {
+		t.Error("clearing an unknown field should fail")
+	}
+	if res := run(t, tool, context.Background(), "get", nil); res.Error == "" {
+		t.Error("a request without an actor should fail")
+	}
+}
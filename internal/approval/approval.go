@@ -0,0 +1,316 @@
+// Package approval is the single pending-approvals queue for actions that
+// need an admin's sign-off before they run: LLM tool calls to tools marked as
+// requiring approval, scheduled jobs created by non-approvers, agent
+// handoffs and memories promoted to everyone by non-approvers. Each feature files a Request of its own Kind and registers a
+// Handler that carries the action out once an admin approves it; the queue
+// owns persistence, the decision record (who approved or rejected, when,
+// why), the requester notification and the admin digest.
+package approval
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Kind names the feature that filed a request; it selects the Handler.
+type Kind string
+
+const (
+	KindToolCall Kind = "tool_call" // an LLM tool call to a tool listed under approvals.tools
+	KindJob      Kind = "job"       // a scheduled job created by a non-approver
+	KindHandoff  Kind = "handoff"   // a conversation handoff to another agent
+	KindMemory   Kind = "memory"    // a memory promoted to the general scope by a non-approver
+)
+
+// Status is the lifecycle state of a request.
+type Status string
+
+const (
+	StatusPending  Status = "pending"
+	StatusApproved Status = "approved"
+	StatusRejected Status = "rejected"
+)
+
+// decidedRetention is how long decided requests stay listed before they are
+// pruned from the queue file.
+const decidedRetention = 30 * 24 * time.Hour
+
+var (
+	ErrNotFound       = errors.New("approval request not found")
+	ErrAlreadyDecided = errors.New("approval request already decided")
+	ErrNoApprover     = errors.New("approver is required")
+	ErrNoHandler      = errors.New("no handler registered for this kind of request")
+)
+
+// Request is one action waiting for (or having received) an admin decision.
+// Payload carries whatever the Kind's Handler needs to carry the action out;
+// it is persisted, so a request filed before a restart can still be approved
+// after it.
+type Request struct {
+	ID             string            `yaml:"id" json:"id"`
+	Kind           Kind              `yaml:"kind" json:"kind"`
+	Summary        string            `yaml:"summary" json:"summary"`                                     // one line shown in listings and digests
+	Requester      string            `yaml:"requester,omitempty" json:"requester,omitempty"`             // actor or entity that asked for the action
+	ChannelID      string            `yaml:"channel_id,omitempty" json:"channel_id,omitempty"`           // where the requester is told about the decision
+	ConversationID string            `yaml:"conversation_id,omitempty" json:"conversation_id,omitempty"` // chat/room on ChannelID
+	SessionID      string            `yaml:"session_id,omitempty" json:"session_id,omitempty"`
+	Payload        map[string]string `yaml:"payload,omitempty" json:"payload,omitempty"`
+	Status         Status            `yaml:"status" json:"status"`
+	CreatedAt      time.Time         `yaml:"created_at" json:"created_at"`
+	DecidedBy      string            `yaml:"decided_by,omitempty" json:"decided_by,omitempty"`
+	DecidedAt      time.Time         `yaml:"decided_at,omitempty" json:"decided_at,omitempty"`
+	Reason         string            `yaml:"reason,omitempty" json:"reason,omitempty"` // approver's note; required reading for rejections
+	Result         string            `yaml:"result,omitempty" json:"result,omitempty"` // handler output after approval
+	Error          string            `yaml:"error,omitempty" json:"error,omitempty"`   // handler failure after approval
+}
+
+// Handler carries out an approved request and returns a short result for the
+// requester. It runs outside any request context, so it must not rely on the
+// requester's actor or profile being on ctx.
+type Handler func(ctx context.Context, req Request) (string, error)
+
+// Notifier delivers a message to a conversation on a channel. It matches
+// scheduler.Notifier so the host's channel notifier serves both.
+type Notifier interface {
+	Notify(ctx context.Context, channelID, conversationID, content string) error
+}
+
+// Queue is the pending-approvals store. Requests are kept in memory and
+// persisted to <dataDir>/approvals/requests.yaml after every change.
+type Queue struct {
+	mu       sync.Mutex
+	requests map[string]*Request
+	handlers map[Kind]Handler
+	notifier Notifier
+	dataDir  string
+}
+
+// NewQueue creates a queue and loads persisted requests from dataDir.
+// An empty dataDir keeps the queue in memory only.
+func NewQueue(notifier Notifier, dataDir string) (*Queue, error) {
+	q := &Queue{
+		requests: make(map[string]*Request),
+		handlers: make(map[Kind]Handler),
+		notifier: notifier,
+		dataDir:  dataDir,
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Handle registers the handler that runs approved requests of kind.
+func (q *Queue) Handle(kind Kind, h Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = h
+}
+
+// Submit files req as pending and returns it with its ID and CreatedAt set.
+func (q *Queue) Submit(req Request) (Request, error) {
+	if req.Kind == "" {
+		return Request{}, fmt.Errorf("approval request kind is required")
+	}
+	req.ID = newID()
+	req.Status = StatusPending
+	req.CreatedAt = time.Now().UTC()
+	req.DecidedBy, req.DecidedAt, req.Reason, req.Result, req.Error = "", time.Time{}, "", "", ""
+
+	q.mu.Lock()
+	stored := req
+	q.requests[req.ID] = &stored
+	err := q.persistLocked()
+	q.mu.Unlock()
+	if err != nil {
+		return Request{}, err
+	}
+	slog.Info("audit", "event", "approval_requested", "id", req.ID, "kind", req.Kind, "requester", req.Requester, "summary", req.Summary)
+	return req, nil
+}
+
+// Get returns the request with id.
+func (q *Queue) Get(id string) (Request, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, ok := q.requests[id]
+	if !ok {
+		return Request{}, false
+	}
+	return *r, true
+}
+
+// List returns requests with the given status (all when status is empty),
+// oldest first.
+func (q *Queue) List(status Status) []Request {
+	q.mu.Lock()
+	out := make([]Request, 0, len(q.requests))
+	for _, r := range q.requests {
+		if status == "" || r.Status == status {
+			out = append(out, *r)
+		}
+	}
+	q.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Approve records approver's approval and runs the request's handler. The
+// decision stands even when the handler fails; the failure is recorded on the
+// request and reported to the requester.
+func (q *Queue) Approve(ctx context.Context, id, approver, reason string) (Request, error) {
+	req, h, err := q.decide(id, approver, reason, StatusApproved)
+	if err != nil {
+		return Request{}, err
+	}
+	if h == nil {
+		req.Error = ErrNoHandler.Error()
+	} else if result, herr := h(ctx, req); herr != nil {
+		req.Error = herr.Error()
+	} else {
+		req.Result = result
+	}
+	q.mu.Lock()
+	if r, ok := q.requests[id]; ok {
+		r.Result, r.Error = req.Result, req.Error
+	}
+	if perr := q.persistLocked(); perr != nil {
+		slog.Warn("persisting approval result failed", "component", "approval", "id", id, "error", perr)
+	}
+	q.mu.Unlock()
+
+	msg := fmt.Sprintf("Your request (%s) was approved by %s.", req.Summary, approver)
+	switch {
+	case req.Error != "":
+		msg += "\nIt failed to run: " + req.Error
+	case req.Result != "":
+		msg += "\n\n" + req.Result
+	}
+	q.notifyRequester(ctx, req, msg)
+	return req, nil
+}
+
+// Reject records approver's rejection. The requester is told, with the reason
+// when one is given.
+func (q *Queue) Reject(ctx context.Context, id, approver, reason string) (Request, error) {
+	req, _, err := q.decide(id, approver, reason, StatusRejected)
+	if err != nil {
+		return Request{}, err
+	}
+	msg := fmt.Sprintf("Your request (%s) was rejected by %s.", req.Summary, approver)
+	if reason != "" {
+		msg += " Reason: " + reason
+	}
+	q.notifyRequester(ctx, req, msg)
+	return req, nil
+}
+
+// decide moves a pending request to status under the lock, so two admins
+// deciding at once cannot both run the handler.
+func (q *Queue) decide(id, approver, reason string, status Status) (Request, Handler, error) {
+	approver = strings.TrimSpace(approver)
+	if approver == "" {
+		return Request{}, nil, ErrNoApprover
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	r, ok := q.requests[id]
+	if !ok {
+		return Request{}, nil, ErrNotFound
+	}
+	if r.Status != StatusPending {
+		return Request{}, nil, fmt.Errorf("%w: %s by %s", ErrAlreadyDecided, r.Status, r.DecidedBy)
+	}
+	r.Status = status
+	r.DecidedBy = approver
+	r.DecidedAt = time.Now().UTC()
+	r.Reason = strings.TrimSpace(reason)
+	if err := q.persistLocked(); err != nil {
+		r.Status, r.DecidedBy, r.DecidedAt, r.Reason = StatusPending, "", time.Time{}, ""
+		return Request{}, nil, err
+	}
+	slog.Info("audit", "event", "approval_decided", "id", r.ID, "kind", r.Kind, "decision", status, "approver", approver, "requester", r.Requester, "reason", r.Reason)
+	return *r, q.handlers[r.Kind], nil
+}
+
+func (q *Queue) notifyRequester(ctx context.Context, req Request, content string) {
+	if q.notifier == nil || req.ChannelID == "" || req.ConversationID == "" {
+		return
+	}
+	if err := q.notifier.Notify(ctx, req.ChannelID, req.ConversationID, content); err != nil {
+		slog.Warn("notifying requester of approval decision failed", "component", "approval", "id", req.ID, "channel", req.ChannelID, "error", err)
+	}
+}
+
+func (q *Queue) persistPath() string {
+	return filepath.Join(q.dataDir, "approvals", "requests.yaml")
+}
+
+// persistLocked writes the queue to disk, pruning decided requests older than
+// decidedRetention. Caller holds q.mu.
+func (q *Queue) persistLocked() error {
+	cutoff := time.Now().Add(-decidedRetention)
+	for id, r := range q.requests {
+		if r.Status != StatusPending && r.DecidedAt.Before(cutoff) {
+			delete(q.requests, id)
+		}
+	}
+	if q.dataDir == "" {
+		return nil
+	}
+	list := make([]Request, 0, len(q.requests))
+	for _, r := range q.requests {
+		list = append(list, *r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	if err := os.MkdirAll(filepath.Dir(q.persistPath()), 0700); err != nil {
+		return fmt.Errorf("creating approvals dir: %w", err)
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("marshaling approval requests: %w", err)
+	}
+	return os.WriteFile(q.persistPath(), data, 0600)
+}
+
+func (q *Queue) load() error {
+	if q.dataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(q.persistPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading approvals file: %w", err)
+	}
+	var list []Request
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing approvals file: %w", err)
+	}
+	for i := range list {
+		q.requests[list[i].ID] = &list[i]
+	}
+	return nil
+}
+
+func newID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("150405.000")))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,179 @@
+package approval
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, channelID, conversationID, content string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sent = append(n.sent, channelID+"/"+conversationID+": "+content)
+	return nil
+}
+
+func TestQueue_ApproveRunsHandlerAndNotifies(t *testing.T) {
+	n := &recordingNotifier{}
+	q, err := NewQueue(n, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ran Request
+	q.Handle(KindToolCall, func(_ context.Context, r Request) (string, error) {
+		ran = r
+		return "deployed v2", nil
+	})
+	req, err := q.Submit(Request{Kind: KindToolCall, Summary: "deploy__run(env=prod)", Requester: "slack:U1",
+		ChannelID: "slack", ConversationID: "C1", Payload: map[string]string{"env": "prod"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.ID == "" || req.Status != StatusPending {
+		t.Fatalf("submitted = %+v", req)
+	}
+
+	got, err := q.Approve(context.Background(), req.ID, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ran.Payload["env"] != "prod" {
+		t.Errorf("handler got payload %v", ran.Payload)
+	}
+	if got.Status != StatusApproved || got.DecidedBy != "alice" || got.Result != "deployed v2" {
+		t.Errorf("decided = %+v", got)
+	}
+	if stored, _ := q.Get(req.ID); stored.Result != "deployed v2" {
+		t.Errorf("stored result = %q", stored.Result)
+	}
+	if len(n.sent) != 1 || !strings.Contains(n.sent[0], "slack/C1: ") || !strings.Contains(n.sent[0], "approved by alice") {
+		t.Errorf("notifications = %q", n.sent)
+	}
+
+	if _, err := q.Reject(context.Background(), req.ID, "bob", ""); !errors.Is(err, ErrAlreadyDecided) {
+		t.Errorf("second decision: err = %v; want ErrAlreadyDecided", err)
+	}
+}
+
+func TestQueue_RejectAndHandlerFailure(t *testing.T) {
+	n := &recordingNotifier{}
+	q, _ := NewQueue(n, "")
+	q.Handle(KindJob, func(context.Context, Request) (string, error) { return "", errors.New("limit reached") })
+
+	a, _ := q.Submit(Request{Kind: KindJob, Summary: "job a", ChannelID: "tg", ConversationID: "42"})
+	b, _ := q.Submit(Request{Kind: KindJob, Summary: "job b", ChannelID: "tg", ConversationID: "42"})
+
+	if _, err := q.Reject(context.Background(), a.ID, "", "no"); !errors.Is(err, ErrNoApprover) {
+		t.Errorf("anonymous decision: err = %v; want ErrNoApprover", err)
+	}
+	if _, err := q.Reject(context.Background(), a.ID, "alice", "too frequent"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := q.Approve(context.Background(), b.ID, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusApproved || got.Error != "limit reached" {
+		t.Errorf("failed handler = %+v; the approval stands and the error is recorded", got)
+	}
+	if !strings.Contains(n.sent[0], "Reason: too frequent") || !strings.Contains(n.sent[1], "failed to run: limit reached") {
+		t.Errorf("notifications = %q", n.sent)
+	}
+	if _, err := q.Approve(context.Background(), "missing", "alice", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown id: err = %v", err)
+	}
+}
+
+func TestQueue_PersistsAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+	q, _ := NewQueue(nil, dir)
+	req, err := q.Submit(Request{Kind: KindJob, Summary: "nightly report", Payload: map[string]string{"job": "name: x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q2, err := NewQueue(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pending := q2.List(StatusPending)
+	if len(pending) != 1 || pending[0].ID != req.ID || pending[0].Payload["job"] != "name: x" {
+		t.Fatalf("reloaded = %+v", pending)
+	}
+	var ran bool
+	q2.Handle(KindJob, func(context.Context, Request) (string, error) { ran = true; return "", nil })
+	if _, err := q2.Approve(context.Background(), req.ID, "alice", ""); err != nil || !ran {
+		t.Errorf("approving a reloaded request: err = %v, ran = %v", err, ran)
+	}
+}
+
+func TestQueue_Digest(t *testing.T) {
+	q, _ := NewQueue(nil, "")
+	if got := q.Digest(time.Now()); got != "" {
+		t.Errorf("empty queue digest = %q", got)
+	}
+	r, _ := q.Submit(Request{Kind: KindHandoff, Summary: "handoff to billing", Requester: "web:u7"})
+	d := q.Digest(r.CreatedAt.Add(90 * time.Minute))
+	for _, want := range []string{"1 pending approval(s)", r.ID, "[handoff] handoff to billing", "requested by web:u7", "waiting 1h30m"} {
+		if !strings.Contains(d, want) {
+			t.Errorf("digest missing %q:\n%s", want, d)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	q, _ := NewQueue(nil, "")
+	q.Handle(KindToolCall, func(context.Context, Request) (string, error) { return "ok", nil })
+	req, _ := q.Submit(Request{Kind: KindToolCall, Summary: "x"})
+	srv := httptest.NewServer(NewHandler(q, "s3cret"))
+	defer srv.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		r, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	if resp := do("GET", "/approvals", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad token: status %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/approvals", "s3cret", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("list: status %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/approvals?status=bogus", "s3cret", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad status filter: status %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/approvals/"+req.ID+"/approve", "s3cret", `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("approve without approver: status %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/approvals/"+req.ID+"/approve", "s3cret", `{"approver":"alice"}`); resp.StatusCode != http.StatusOK {
+		t.Errorf("approve: status %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/approvals/"+req.ID+"/reject", "s3cret", `{"approver":"bob"}`); resp.StatusCode != http.StatusConflict {
+		t.Errorf("decide twice: status %d", resp.StatusCode)
+	}
+	if resp := do("GET", "/approvals/nope", "s3cret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown id: status %d", resp.StatusCode)
+	}
+	if got, _ := q.Get(req.ID); got.DecidedBy != "alice" {
+		t.Errorf("decided by %q", got.DecidedBy)
+	}
+}
@@ -0,0 +1,61 @@
+package approval
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// maxDigestItems bounds a digest message; the remainder is summarized as a
+// count so a flooded queue still yields one readable message.
+const maxDigestItems = 20
+
+// Digest renders the pending requests as a message for admins. It returns ""
+// when nothing is pending.
+func (q *Queue) Digest(now time.Time) string {
+	pending := q.List(StatusPending)
+	if len(pending) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d pending approval(s):\n", len(pending))
+	for i, r := range pending {
+		if i == maxDigestItems {
+			fmt.Fprintf(&sb, "…and %d more.\n", len(pending)-maxDigestItems)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s [%s] %s", r.ID, r.Kind, r.Summary)
+		if r.Requester != "" {
+			fmt.Fprintf(&sb, " — requested by %s", r.Requester)
+		}
+		fmt.Fprintf(&sb, ", waiting %s\n", now.Sub(r.CreatedAt).Truncate(time.Minute))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// RunDigest sends Digest to the admin conversation every interval while
+// requests are pending, until ctx is done. Intervals with nothing pending send
+// nothing.
+func (q *Queue) RunDigest(ctx context.Context, interval time.Duration, channelID, conversationID string) {
+	if q.notifier == nil || interval <= 0 || channelID == "" || conversationID == "" {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			msg := q.Digest(now)
+			if msg == "" {
+				continue
+			}
+			if err := q.notifier.Notify(ctx, channelID, conversationID, msg); err != nil {
+				slog.Warn("sending approvals digest failed", "component", "approval", "channel", channelID, "error", err)
+			}
+		}
+	}
+}
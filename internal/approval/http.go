@@ -0,0 +1,104 @@
+package approval
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// decisionBody is the JSON body of POST /approvals/{id}/approve|reject.
+type decisionBody struct {
+	Approver string `json:"approver"`
+	Reason   string `json:"reason"`
+}
+
+// NewHandler returns the admin HTTP API for the queue:
+//
+//	GET  /approvals?status=pending|approved|rejected   list (default: pending)
+//	GET  /approvals/{id}                                one request
+//	POST /approvals/{id}/approve  {"approver", "reason"}
+//	POST /approvals/{id}/reject   {"approver", "reason"}
+//
+// Every route requires "Authorization: Bearer <token>". The approver named in
+// the body is recorded on the request and in the audit log.
+func NewHandler(q *Queue, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /approvals", func(w http.ResponseWriter, r *http.Request) {
+		status := Status(r.URL.Query().Get("status"))
+		switch status {
+		case "":
+			status = StatusPending
+		case "all":
+			status = ""
+		case StatusPending, StatusApproved, StatusRejected:
+		default:
+			writeError(w, http.StatusBadRequest, "status must be pending, approved, rejected or all")
+			return
+		}
+		writeJSON(w, http.StatusOK, q.List(status))
+	})
+	mux.HandleFunc("GET /approvals/{id}", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := q.Get(r.PathValue("id"))
+		if !ok {
+			writeError(w, http.StatusNotFound, ErrNotFound.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, req)
+	})
+	mux.HandleFunc("POST /approvals/{id}/{decision}", func(w http.ResponseWriter, r *http.Request) {
+		var body decisionBody
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		var (
+			req Request
+			err error
+		)
+		switch r.PathValue("decision") {
+		case "approve":
+			req, err = q.Approve(r.Context(), r.PathValue("id"), body.Approver, body.Reason)
+		case "reject":
+			req, err = q.Reject(r.Context(), r.PathValue("id"), body.Approver, body.Reason)
+		default:
+			writeError(w, http.StatusNotFound, "decision must be approve or reject")
+			return
+		}
+		switch {
+		case errors.Is(err, ErrNotFound):
+			writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, ErrAlreadyDecided):
+			writeError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, ErrNoApprover):
+			writeError(w, http.StatusBadRequest, err.Error())
+		case err != nil:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		default:
+			writeJSON(w, http.StatusOK, req)
+		}
+	})
+	return requireToken(token, mux)
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
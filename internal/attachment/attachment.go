@@ -0,0 +1,46 @@
+// Package attachment turns files users send through channels into text the
+// LLM can read: plain text, Markdown, JSON and CSV are decoded as UTF-8 and
+// PDFs go through a small built-in text extractor. Images and other binary
+// formats are not handled here; the orchestrator passes images to models
+// that accept them.
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnsupported is returned for formats Text cannot extract.
+var ErrUnsupported = errors.New("attachment: unsupported format")
+
+// textExtensions identify text files channels send as application/octet-stream.
+var textExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true,
+	".json": true, ".xml": true, ".yaml": true, ".yml": true, ".log": true,
+}
+
+// Text extracts the readable text of a file. name is only used to recognise
+// files sent with a generic MIME type.
+func Text(mimeType, name string, data []byte) (string, error) {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case mimeType == "application/pdf" || ext == ".pdf":
+		return pdfText(data)
+	case strings.HasPrefix(mimeType, "text/"), mimeType == "application/json", mimeType == "application/xml",
+		mimeType == "application/x-yaml", mimeType == "application/csv", textExtensions[ext]:
+		return plainText(data)
+	}
+	return "", ErrUnsupported
+}
+
+func plainText(data []byte) (string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return "", errors.New("attachment: text is not valid UTF-8")
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
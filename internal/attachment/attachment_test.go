@@ -0,0 +1,81 @@
+package attachment
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// buildPDF wraps content streams into a minimal PDF; compressed streams are
+// FlateDecode-encoded.
+func buildPDF(t *testing.T, compress bool, streams ...string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	b.WriteString("1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	for i, s := range streams {
+		data := []byte(s)
+		filter := ""
+		if compress {
+			var z bytes.Buffer
+			w := zlib.NewWriter(&z)
+			_, _ = w.Write(data)
+			_ = w.Close()
+			data = z.Bytes()
+			filter = " /Filter /FlateDecode"
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n<< /Length %d%s >>\nstream\n", i+3, len(data), filter)
+		b.Write(data)
+		b.WriteString("\nendstream\nendobj\n")
+	}
+	// An image stream with a non-Flate filter must be ignored.
+	b.WriteString("9 0 obj\n<< /Type /XObject /Subtype /Image /Filter /DCTDecode /Length 4 >>\nstream\n\xff\xd8BT\nendstream\nendobj\n")
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestText_PDF(t *testing.T) {
+	page := `BT /F1 12 Tf 72 712 Td (Quarterly report) Tj 0 -14 Td [(Revenue ) -250 (grew \(again\)) ] TJ ET
+BT 72 600 Td <FEFF00C400DF> Tj T* (caf\351 \\ done) Tj ET`
+	for _, compress := range []bool{false, true} {
+		got, err := Text("application/pdf", "report.pdf", buildPDF(t, compress, page))
+		if err != nil {
+			t.Fatalf("compress=%v: %v", compress, err)
+		}
+		want := "Quarterly report\nRevenue grew (again)\nÄß\ncafé \\ done"
+		if got != want {
+			t.Errorf("compress=%v: got %q\nwant %q", compress, got, want)
+		}
+	}
+}
+
+func TestText_PDFWithoutText(t *testing.T) {
+	_, err := Text("application/pdf", "scan.pdf", buildPDF(t, true, "q 100 0 0 100 0 0 cm /Im1 Do Q"))
+	if !errors.Is(err, errNoPDFText) {
+		t.Errorf("err = %v; want errNoPDFText", err)
+	}
+	if _, err := Text("application/pdf", "fake.pdf", []byte("hello")); err == nil {
+		t.Error("a non-PDF body should fail")
+	}
+}
+
+func TestText_PlainFormats(t *testing.T) {
+	for _, tc := range []struct{ mime, name, data, want string }{
+		{"text/csv", "a.csv", "\xef\xbb\xbfname,qty\r\nbolt,3\r\n", "name,qty\nbolt,3\n"},
+		{"application/octet-stream", "notes.md", "# Notes", "# Notes"},
+		{"application/json; charset=utf-8", "", `{"a":1}`, `{"a":1}`},
+	} {
+		got, err := Text(tc.mime, tc.name, []byte(tc.data))
+		if err != nil || got != tc.want {
+			t.Errorf("Text(%q, %q) = %q, %v; want %q", tc.mime, tc.name, got, err, tc.want)
+		}
+	}
+	if _, err := Text("image/png", "x.png", []byte{0x89, 'P'}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("image err = %v; want ErrUnsupported", err)
+	}
+	if _, err := Text("text/plain", "bad.txt", []byte{0xff, 0xfe}); err == nil {
+		t.Error("invalid UTF-8 should fail")
+	}
+}
@@ -0,0 +1,382 @@
+package attachment
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxInflatedStream bounds one decompressed PDF stream, so a small
+// attachment cannot expand into gigabytes.
+const maxInflatedStream = 32 << 20
+
+var errNoPDFText = errors.New("attachment: the PDF has no extractable text (it may be scanned, or use embedded font encodings)")
+
+// pdfText extracts the text shown by a PDF's content streams. It is a
+// deliberately small reader: it inflates FlateDecode streams and interprets
+// the text-showing operators, decoding strings as PDFDocEncoding/Latin-1 or
+// UTF-16BE. It does not follow the page tree or font encodings, so text
+// comes out in stream order and fonts with custom CID mappings yield
+// nothing — enough for the reports and letters people forward to a bot.
+func pdfText(data []byte) (string, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return "", errors.New("attachment: not a PDF file")
+	}
+	var sb strings.Builder
+	for _, s := range pdfStreams(data) {
+		if bytes.Contains(s, []byte("BT")) && bytes.Contains(s, []byte("ET")) {
+			showText(s, &sb)
+		}
+	}
+	text := tidyText(sb.String())
+	if text == "" {
+		return "", errNoPDFText
+	}
+	return text, nil
+}
+
+// pdfStreams returns every stream body in data, inflated when the stream is
+// FlateDecode-compressed. Streams with other filters (images, fonts) are
+// skipped.
+func pdfStreams(data []byte) [][]byte {
+	var out [][]byte
+	for i := 0; i < len(data); {
+		j := bytes.Index(data[i:], []byte("stream"))
+		if j < 0 {
+			break
+		}
+		start := i + j
+		i = start + len("stream")
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		body := i
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body >= len(data) || data[body] != '\n' {
+			continue
+		}
+		body++
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := bytes.TrimRight(data[body:body+end], "\r\n")
+		i = body + end + len("endstream")
+
+		dict := data[max(0, start-1024):start]
+		if k := bytes.LastIndex(dict, []byte(" obj")); k >= 0 {
+			dict = dict[k:]
+		}
+		switch {
+		case bytes.Contains(dict, []byte("/FlateDecode")):
+			dec, err := inflate(raw)
+			if err != nil {
+				continue
+			}
+			out = append(out, dec)
+		case !bytes.Contains(dict, []byte("/Filter")):
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+func inflate(raw []byte) ([]byte, error) {
+	var r io.ReadCloser
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		r = flate.NewReader(bytes.NewReader(raw))
+	}
+	defer func() { _ = r.Close() }()
+	out, err := io.ReadAll(io.LimitReader(r, maxInflatedStream))
+	// A truncated stream still yields usable text.
+	if len(out) > 0 {
+		return out, nil
+	}
+	return nil, err
+}
+
+type arrayStart struct{}
+
+// showText interprets a content stream's text operators into sb.
+func showText(b []byte, sb *strings.Builder) {
+	var stack []any
+	newline := func() {
+		if s := sb.String(); s != "" && s[len(s)-1] != '\n' {
+			sb.WriteByte('\n')
+		}
+	}
+	for i := 0; i < len(b); {
+		c := b[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+			continue
+		case c == '%':
+			for i < len(b) && b[i] != '\n' && b[i] != '\r' {
+				i++
+			}
+			continue
+		case c == '(':
+			s, n := literalString(b[i:])
+			stack = append(stack, s)
+			i += n
+			continue
+		case c == '<' && i+1 < len(b) && b[i+1] == '<':
+			i += skipDict(b[i:])
+			continue
+		case c == '<':
+			s, n := hexString(b[i:])
+			stack = append(stack, s)
+			i += n
+			continue
+		case c == '[':
+			stack = append(stack, arrayStart{})
+			i++
+			continue
+		case c == ']':
+			k := len(stack) - 1
+			for k >= 0 {
+				if _, ok := stack[k].(arrayStart); ok {
+					break
+				}
+				k--
+			}
+			if k < 0 {
+				i++
+				continue
+			}
+			arr := append([]any(nil), stack[k+1:]...)
+			stack = append(stack[:k], arr)
+			i++
+			continue
+		case c == '/':
+			i++
+			for i < len(b) && !isPDFSpace(b[i]) && !isPDFDelim(b[i]) {
+				i++
+			}
+			continue
+		}
+		// A number or an operator.
+		j := i
+		for j < len(b) && !isPDFSpace(b[j]) && !isPDFDelim(b[j]) {
+			j++
+		}
+		if j == i {
+			i++ // stray delimiter
+			continue
+		}
+		tok := string(b[i:j])
+		i = j
+		if f, err := strconv.ParseFloat(tok, 64); err == nil {
+			stack = append(stack, f)
+			continue
+		}
+		switch tok {
+		case "Tj":
+			if s, ok := lastString(stack); ok {
+				sb.WriteString(s)
+			}
+		case "'", "\"":
+			newline()
+			if s, ok := lastString(stack); ok {
+				sb.WriteString(s)
+			}
+		case "TJ":
+			if len(stack) > 0 {
+				if arr, ok := stack[len(stack)-1].([]any); ok {
+					for _, el := range arr {
+						switch v := el.(type) {
+						case string:
+							sb.WriteString(v)
+						case float64:
+							// Large negative kerning is how PDFs space words.
+							if v < -200 {
+								sb.WriteByte(' ')
+							}
+						}
+					}
+				}
+			}
+		case "Td", "TD":
+			if len(stack) >= 2 {
+				if ty, ok := stack[len(stack)-1].(float64); ok && ty != 0 {
+					newline()
+				} else {
+					sb.WriteByte(' ')
+				}
+			}
+		case "T*", "Tm", "ET":
+			newline()
+		case "ID":
+			// Inline image data runs to the EI operator.
+			if k := bytes.Index(b[i:], []byte("EI")); k >= 0 {
+				i += k + 2
+			} else {
+				i = len(b)
+			}
+		}
+		stack = stack[:0]
+	}
+}
+
+func lastString(stack []any) (string, bool) {
+	if len(stack) == 0 {
+		return "", false
+	}
+	s, ok := stack[len(stack)-1].(string)
+	return s, ok
+}
+
+// literalString decodes a "(...)" string at the start of b and returns it
+// with the number of bytes consumed.
+func literalString(b []byte) (string, int) {
+	var out []byte
+	depth := 0
+	i := 0
+	for i < len(b) {
+		c := b[i]
+		switch {
+		case c == '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(out), i + 1
+			}
+			out = append(out, c)
+		case c == '\\' && i+1 < len(b):
+			i++
+			switch e := b[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b', 'f':
+			case '\r':
+				if i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					k := 0
+					for k < 3 && i < len(b) && b[i] >= '0' && b[i] <= '7' {
+						v = v*8 + int(b[i]-'0')
+						i++
+						k++
+					}
+					out = append(out, byte(v))
+					continue
+				}
+				out = append(out, e)
+			}
+		default:
+			out = append(out, c)
+		}
+		i++
+	}
+	return decodePDFString(out), len(b)
+}
+
+func hexString(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		end = len(b)
+	}
+	var digits []byte
+	for _, c := range b[1:end] {
+		if (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	out := make([]byte, len(digits)/2)
+	for k := range out {
+		v, _ := strconv.ParseUint(string(digits[2*k:2*k+2]), 16, 8)
+		out[k] = byte(v)
+	}
+	return decodePDFString(out), min(end+1, len(b))
+}
+
+// skipDict returns the length of the "<< ... >>" dictionary at the start of b.
+func skipDict(b []byte) int {
+	depth := 0
+	for i := 0; i+1 < len(b); i++ {
+		switch {
+		case b[i] == '<' && b[i+1] == '<':
+			depth++
+			i++
+		case b[i] == '>' && b[i+1] == '>':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(b)
+}
+
+// decodePDFString decodes UTF-16BE (with BOM) or single-byte text, dropping
+// control characters.
+func decodePDFString(b []byte) string {
+	var runes []rune
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		u := make([]uint16, 0, len(b)/2)
+		for k := 2; k+1 < len(b); k += 2 {
+			u = append(u, uint16(b[k])<<8|uint16(b[k+1]))
+		}
+		runes = utf16.Decode(u)
+	} else {
+		runes = make([]rune, len(b))
+		for k, c := range b {
+			runes[k] = rune(c)
+		}
+	}
+	var sb strings.Builder
+	for _, r := range runes {
+		if r == '\n' || r == '\t' || r >= 0x20 && r != 0x7f && (r < 0x80 || r >= 0xa0) {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelim(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+var (
+	spaceRuns = regexp.MustCompile(`[ \t]+`)
+	blankRuns = regexp.MustCompile(`\n{3,}`)
+)
+
+// tidyText collapses the whitespace the operator walk leaves behind.
+func tidyText(s string) string {
+	lines := strings.Split(s, "\n")
+	for k, l := range lines {
+		lines[k] = strings.TrimSpace(spaceRuns.ReplaceAllString(l, " "))
+	}
+	return strings.TrimSpace(blankRuns.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
@@ -0,0 +1,139 @@
+// Package blob stores large payloads — oversized tool outputs today, channel
+// attachments later — outside session messages. Payloads are
+// content-addressed: the ID is the SHA-256 of the bytes, so storing the same
+// output twice costs nothing and an ID can never point at different data.
+// Messages and audit entries carry a Ref ("blob:sha256:<hex>") instead of the
+// payload; GC removes blobs no live session references once they are older
+// than the session retention horizon.
+//
+// A Store is a thin layer over a Backend (a local directory or an S3 bucket)
+// that owns hashing, the size bound and verification on read.
+package blob
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultMaxBytes bounds a single blob when the config sets no limit.
+const DefaultMaxBytes = 10 << 20
+
+const (
+	idPrefix  = "sha256:"
+	refPrefix = "blob:"
+)
+
+var (
+	ErrNotFound  = errors.New("blob not found")
+	ErrTooLarge  = errors.New("blob exceeds the size limit")
+	ErrInvalidID = errors.New("invalid blob id")
+)
+
+var (
+	idPattern  = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+	refPattern = regexp.MustCompile(`blob:(sha256:[0-9a-f]{64})`)
+)
+
+// Object is one stored blob as reported by Backend.List.
+type Object struct {
+	Key     string // backend key (the hex digest)
+	Size    int64
+	ModTime time.Time // last write; a re-Put of the same content refreshes it
+}
+
+// Backend is the raw key/value storage under a Store. Keys are hex digests;
+// Read returns ErrNotFound for a missing key and Remove ignores one.
+type Backend interface {
+	Write(ctx context.Context, key string, data []byte) error
+	Read(ctx context.Context, key string) ([]byte, error)
+	Remove(ctx context.Context, key string) error
+	List(ctx context.Context) ([]Object, error)
+}
+
+// Store is a content-addressed, size-bounded blob store.
+type Store struct {
+	backend  Backend
+	maxBytes int64
+}
+
+// New returns a Store over backend. maxBytes <= 0 uses DefaultMaxBytes.
+func New(backend Backend, maxBytes int64) *Store {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{backend: backend, maxBytes: maxBytes}
+}
+
+// MaxBytes is the largest payload Put accepts.
+func (s *Store) MaxBytes() int64 { return s.maxBytes }
+
+// Put stores data and returns its ID ("sha256:<hex>").
+func (s *Store) Put(ctx context.Context, data []byte) (string, error) {
+	if int64(len(data)) > s.maxBytes {
+		return "", fmt.Errorf("%w (%d > %d bytes)", ErrTooLarge, len(data), s.maxBytes)
+	}
+	sum := sha256.Sum256(data)
+	key := hex.EncodeToString(sum[:])
+	if err := s.backend.Write(ctx, key, data); err != nil {
+		return "", fmt.Errorf("storing blob: %w", err)
+	}
+	return idPrefix + key, nil
+}
+
+// Get returns the payload stored under id. A payload whose digest no longer
+// matches its id is reported as an error rather than returned.
+func (s *Store) Get(ctx context.Context, id string) ([]byte, error) {
+	key, err := keyOf(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.backend.Read(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != key {
+		return nil, fmt.Errorf("blob %s is corrupt", id)
+	}
+	return data, nil
+}
+
+// Delete removes the blob; a missing blob is not an error.
+func (s *Store) Delete(ctx context.Context, id string) error {
+	key, err := keyOf(id)
+	if err != nil {
+		return err
+	}
+	return s.backend.Remove(ctx, key)
+}
+
+func keyOf(id string) (string, error) {
+	if !idPattern.MatchString(id) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return strings.TrimPrefix(id, idPrefix), nil
+}
+
+// Ref is the form a blob id takes inside message text and audit entries.
+func Ref(id string) string { return refPrefix + id }
+
+// Refs returns the blob ids referenced in text, in order of first appearance.
+func Refs(text string) []string {
+	if !strings.Contains(text, refPrefix+idPrefix) {
+		return nil
+	}
+	var ids []string
+	seen := map[string]bool{}
+	for _, m := range refPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
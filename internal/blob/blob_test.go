@@ -0,0 +1,105 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStore_LocalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	b, err := NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(b, 16)
+
+	id, err := s.Put(ctx, []byte("hello blob"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(id, "sha256:") || len(id) != len("sha256:")+64 {
+		t.Fatalf("id = %q", id)
+	}
+	if again, _ := s.Put(ctx, []byte("hello blob")); again != id {
+		t.Errorf("same content got a different id: %q vs %q", again, id)
+	}
+	got, err := s.Get(ctx, id)
+	if err != nil || string(got) != "hello blob" {
+		t.Fatalf("Get = %q, %v", got, err)
+	}
+
+	if _, err := s.Put(ctx, []byte("this is more than sixteen bytes")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("oversized Put err = %v", err)
+	}
+	if _, err := s.Get(ctx, "../../etc/passwd"); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("bad id err = %v", err)
+	}
+	if _, err := s.Get(ctx, "sha256:"+strings.Repeat("0", 64)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing blob err = %v", err)
+	}
+
+	// Tampered content is refused rather than served under the old id.
+	key := strings.TrimPrefix(id, "sha256:")
+	if err := os.WriteFile(filepath.Join(b.dir, key[:2], key), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, id); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("tampered Get err = %v", err)
+	}
+
+	if err := s.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, id); err != nil {
+		t.Errorf("second Delete = %v; want no-op", err)
+	}
+}
+
+func TestRefs(t *testing.T) {
+	a := "sha256:" + strings.Repeat("a", 64)
+	b := "sha256:" + strings.Repeat("b", 64)
+	text := "see " + Ref(a) + " and " + Ref(b) + ", again " + Ref(a) + " but not blob:sha256:abc"
+	got := Refs(text)
+	if len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("Refs = %v", got)
+	}
+	if Refs("no refs here") != nil {
+		t.Error("want nil for text without refs")
+	}
+}
+
+func TestStore_GC(t *testing.T) {
+	ctx := context.Background()
+	b, err := NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(b, 0)
+	oldKept, _ := s.Put(ctx, []byte("referenced"))
+	oldGone, _ := s.Put(ctx, []byte("orphaned"))
+	fresh, _ := s.Put(ctx, []byte("new"))
+	past := time.Now().Add(-48 * time.Hour)
+	for _, id := range []string{oldKept, oldGone} {
+		key := strings.TrimPrefix(id, "sha256:")
+		if err := os.Chtimes(filepath.Join(b.dir, key[:2], key), past, past); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	refs := func(context.Context) (map[string]bool, error) { return map[string]bool{oldKept: true}, nil }
+	n, err := s.GC(ctx, refs, 24*time.Hour)
+	if err != nil || n != 1 {
+		t.Fatalf("GC = %d, %v; want 1 deletion", n, err)
+	}
+	for id, want := range map[string]bool{oldKept: true, oldGone: false, fresh: true} {
+		_, err := s.Get(ctx, id)
+		if (err == nil) != want {
+			t.Errorf("%s present=%v; want %v", id[:15], err == nil, want)
+		}
+	}
+}
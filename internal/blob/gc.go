@@ -0,0 +1,69 @@
+package blob
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RefSource reports every blob id still referenced by live data (session
+// messages). GC never deletes a referenced blob.
+type RefSource func(ctx context.Context) (map[string]bool, error)
+
+// GC deletes blobs last written before now-minAge that refs does not report.
+// With a nil refs, age alone decides; minAge should then be the session
+// retention so a blob outlives every session that could reference it.
+func (s *Store) GC(ctx context.Context, refs RefSource, minAge time.Duration) (int, error) {
+	var live map[string]bool
+	if refs != nil {
+		var err error
+		if live, err = refs(ctx); err != nil {
+			return 0, err
+		}
+	}
+	objs, err := s.backend.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-minAge)
+	deleted := 0
+	for _, o := range objs {
+		if o.ModTime.After(cutoff) || live[idPrefix+o.Key] {
+			continue
+		}
+		if err := s.backend.Remove(ctx, o.Key); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// RunGC collects garbage now and then daily until ctx is cancelled, in the
+// same shape as the state store's retention workers.
+func RunGC(ctx context.Context, s *Store, refs RefSource, minAge time.Duration) {
+	gc := func() {
+		gcCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		defer cancel()
+		n, err := s.GC(gcCtx, refs, minAge)
+		if err != nil {
+			slog.Warn("blob gc failed", "component", "blob", "error", err)
+			return
+		}
+		if n > 0 {
+			slog.Info("blob gc", "component", "blob", "deleted", n, "min_age", minAge)
+		}
+	}
+
+	gc()
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			gc()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LocalBackend keeps blobs as files under a directory, fanned out by the
+// first two hex digits ("ab/abcdef…") so no single directory grows huge.
+type LocalBackend struct {
+	dir string
+}
+
+// NewLocalBackend creates dir if needed.
+func NewLocalBackend(dir string) (*LocalBackend, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating blob dir: %w", err)
+	}
+	return &LocalBackend{dir: dir}, nil
+}
+
+func (b *LocalBackend) path(key string) string {
+	return filepath.Join(b.dir, key[:2], key)
+}
+
+// Write stores data atomically (temp file + rename). Content-addressed keys
+// make an existing file identical, so only its mtime is refreshed.
+func (b *LocalBackend) Write(_ context.Context, key string, data []byte) error {
+	p := b.path(key)
+	if _, err := os.Stat(p); err == nil {
+		now := time.Now()
+		return os.Chtimes(p, now, now)
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p), key+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
+
+func (b *LocalBackend) Read(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (b *LocalBackend) Remove(_ context.Context, key string) error {
+	if err := os.Remove(b.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List walks the directory; leftover temp files are skipped.
+func (b *LocalBackend) List(ctx context.Context) ([]Object, error) {
+	var out []Object
+	err := filepath.WalkDir(b.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if d.IsDir() || !idPattern.MatchString(idPrefix+d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		out = append(out, Object{Key: d.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return out, err
+}
@@ -0,0 +1,286 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Config addresses an S3 bucket or an S3-compatible service (MinIO, R2,
+// Ceph). Empty credentials fall back to AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type S3Config struct {
+	Bucket          string
+	Region          string // default us-east-1
+	Endpoint        string // e.g. http://minio:9000; empty = AWS
+	Prefix          string // key prefix inside the bucket, e.g. "opentalon/blobs/"
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool // bucket in the path rather than the host; implied by Endpoint
+}
+
+// S3Backend talks to the S3 REST API directly (SigV4-signed requests), so
+// the binary does not carry an SDK for four operations.
+type S3Backend struct {
+	cfg    S3Config
+	base   *url.URL // scheme://host[/bucket]
+	client *http.Client
+}
+
+// NewS3Backend validates cfg and returns a backend. It does not contact S3.
+func NewS3Backend(cfg S3Config) (*S3Backend, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3: bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.AccessKeyID == "" {
+		cfg.AccessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		cfg.SecretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		cfg.SessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.New("s3: access key id and secret access key are required")
+	}
+	raw := cfg.Endpoint
+	switch {
+	case raw != "":
+		cfg.PathStyle = true
+	case cfg.PathStyle:
+		raw = "https://s3." + cfg.Region + ".amazonaws.com"
+	default:
+		raw = "https://" + cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com"
+	}
+	base, err := url.Parse(strings.TrimRight(raw, "/"))
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("s3: invalid endpoint %q", cfg.Endpoint)
+	}
+	if cfg.PathStyle {
+		base.Path += "/" + cfg.Bucket
+	}
+	return &S3Backend{cfg: cfg, base: base, client: &http.Client{Timeout: 60 * time.Second}}, nil
+}
+
+func (b *S3Backend) objectURL(key string) *url.URL {
+	u := *b.base
+	u.Path += "/" + b.cfg.Prefix + key
+	return &u
+}
+
+func (b *S3Backend) Write(ctx context.Context, key string, data []byte) error {
+	resp, err := b.do(ctx, http.MethodPut, b.objectURL(key), data)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	return checkStatus(resp, "put")
+}
+
+func (b *S3Backend) Read(ctx context.Context, key string) ([]byte, error) {
+	resp, err := b.do(ctx, http.MethodGet, b.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if err := checkStatus(resp, "get"); err != nil {
+		return nil, err
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (b *S3Backend) Remove(ctx context.Context, key string) error {
+	resp, err := b.do(ctx, http.MethodDelete, b.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return checkStatus(resp, "delete")
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// List pages through ListObjectsV2 under the prefix.
+func (b *S3Backend) List(ctx context.Context) ([]Object, error) {
+	var out []Object
+	token := ""
+	for {
+		u := *b.base
+		u.Path += "/"
+		q := url.Values{"list-type": {"2"}}
+		if b.cfg.Prefix != "" {
+			q.Set("prefix", b.cfg.Prefix)
+		}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = q.Encode()
+		resp, err := b.do(ctx, http.MethodGet, &u, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = checkStatus(resp, "list")
+		if err == nil {
+			err = xml.NewDecoder(resp.Body).Decode(&page)
+		}
+		_ = resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			key := strings.TrimPrefix(c.Key, b.cfg.Prefix)
+			if idPattern.MatchString(idPrefix + key) {
+				out = append(out, Object{Key: key, Size: c.Size, ModTime: c.LastModified})
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return out, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func checkStatus(resp *http.Response, op string) error {
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s: %s: %s", op, resp.Status, strings.TrimSpace(string(body)))
+}
+
+func (b *S3Backend) do(ctx context.Context, method string, u *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	b.sign(req, body, time.Now().UTC())
+	return b.client.Do(req)
+}
+
+// sign adds AWS Signature Version 4 headers to req.
+func (b *S3Backend) sign(req *http.Request, body []byte, now time.Time) {
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+	if b.cfg.SessionToken != "" {
+		req.Header.Set("x-amz-security-token", b.cfg.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for k := range req.Header {
+		if lk := strings.ToLower(k); strings.HasPrefix(lk, "x-amz-") {
+			headers[lk] = strings.TrimSpace(req.Header.Get(k))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonHeaders strings.Builder
+	for _, k := range names {
+		canonHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signed := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		awsEscapePath(req.URL.Path),
+		canonicalQuery(req.URL.Query()),
+		canonHeaders.String(),
+		signed,
+		payloadHash,
+	}, "\n")
+	scope := day + "/" + b.cfg.Region + "/s3/aws4_request"
+	crSum := sha256.Sum256([]byte(canonical))
+	toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(crSum[:])
+
+	key := hmacSHA256([]byte("AWS4"+b.cfg.SecretAccessKey), day)
+	key = hmacSHA256(key, b.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	sig := hex.EncodeToString(hmacSHA256(key, toSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.cfg.AccessKeyID, scope, signed, sig))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string(nil), q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, awsEscape(k)+"="+awsEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func awsEscapePath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	segs := strings.Split(p, "/")
+	for i, s := range segs {
+		segs[i] = awsEscape(s)
+	}
+	return strings.Join(segs, "/")
+}
+
+// awsEscape percent-encodes everything but the RFC 3986 unreserved set, as
+// SigV4 requires (url.QueryEscape would turn spaces into '+').
+func awsEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
@@ -51,7 +51,7 @@ var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
 
 func repoURL(repo string) string {
 	repo = strings.TrimSpace(repo)
-	if strings.HasPrefix(repo, "https://") || strings.HasPrefix(repo, "git@") {
+	if strings.HasPrefix(repo, "https://") || strings.HasPrefix(repo, "git@") || strings.HasPrefix(repo, "file://") {
 		return repo
 	}
 	return githubPrefix + strings.TrimPrefix(repo, "/") + ".git"
@@ -60,36 +60,14 @@ func repoURL(repo string) string {
 // CloneAndBuild clones the repo at ref into dir and runs `go build -o binaryName .`.
 // resolvedSHA is the commit from ResolveRef; we checkout that commit for reproducibility.
 func CloneAndBuild(ctx context.Context, repo, ref, resolvedSHA, dir, binaryName string) (binaryPath string, err error) {
-	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
-		return "", fmt.Errorf("create parent dir: %w", err)
-	}
-	_ = os.RemoveAll(dir)
-
-	repoURL := repoURL(repo)
-	isCommit := commitSHARegex.MatchString(ref)
-
-	var cloneCmd *exec.Cmd
-	if isCommit {
-		cloneCmd = exec.CommandContext(ctx, gitBin, "clone", "--depth", "1", repoURL, dir)
-	} else {
-		cloneCmd = exec.CommandContext(ctx, gitBin, "clone", "--depth", "1", "--branch", ref, repoURL, dir)
-	}
-	if output, err := cloneCmd.CombinedOutput(); err != nil {
-		return "", fmt.Errorf("git clone: %w (output: %s)", err, string(output))
-	}
-
-	checkoutTarget := resolvedSHA
-	if checkoutTarget == "" {
-		checkoutTarget = ref
-	}
-	if checkoutTarget != "" {
-		checkout := exec.CommandContext(ctx, gitBin, "checkout", checkoutTarget)
-		checkout.Dir = dir
-		if output, err := checkout.CombinedOutput(); err != nil {
-			return "", fmt.Errorf("git checkout %s: %w (output: %s)", checkoutTarget, err, string(output))
-		}
+	if err := CloneOnly(ctx, repo, ref, resolvedSHA, dir); err != nil {
+		return "", err
 	}
+	return build(ctx, dir, binaryName)
+}
 
+// build compiles a checked-out plugin or channel repo in dir.
+func build(ctx context.Context, dir, binaryName string) (binaryPath string, err error) {
 	// If this is a YAML-only repo (no go.mod), skip Go build steps entirely
 	// and return the channel.yaml path directly.
 	if _, err := os.Stat(filepath.Join(dir, "go.mod")); os.IsNotExist(err) {
@@ -174,7 +152,9 @@ func CloneOnly(ctx context.Context, repo, ref, resolvedSHA, dir string) error {
 
 	var cloneCmd *exec.Cmd
 	if isCommit {
-		cloneCmd = exec.CommandContext(ctx, gitBin, "clone", "--depth", "1", repoURL, dir)
+		// A shallow clone only has the default branch's tip; a pinned commit
+		// can be anywhere in the history.
+		cloneCmd = exec.CommandContext(ctx, gitBin, "clone", repoURL, dir)
 	} else {
 		cloneCmd = exec.CommandContext(ctx, gitBin, "clone", "--depth", "1", "--branch", ref, repoURL, dir)
 	}
@@ -389,56 +369,52 @@ func EnsureLuaPluginDir(ctx context.Context, stateDir, name, github, ref string)
 
 // EnsurePlugin ensures the plugin is present under stateDir/plugins/<name>/,
 // resolves ref to a commit, clones and builds if needed, updates plugins.lock, and returns the path to the binary.
-// When cache is true the lock file is consulted and a matching entry is reused; when false the plugin is always rebuilt.
-func EnsurePlugin(ctx context.Context, stateDir, name, github, ref string, cache bool) (path string, err error) {
+// When opts.Cache is true the lock file is consulted and a matching entry is reused; when false the plugin is always rebuilt.
+// opts.Policy and opts.Checksums are enforced before anything is fetched, built or reused.
+func EnsurePlugin(ctx context.Context, stateDir, name, github, ref string, opts BuildOptions) (path string, err error) {
 	if github == "" || ref == "" {
 		return "", fmt.Errorf("github and ref are required")
 	}
+	if err := opts.Policy.allow(github, opts.Checksums); err != nil {
+		return "", err
+	}
 
 	lock, err := LoadPluginsLock(stateDir)
 	if err != nil {
 		return "", err
 	}
 
-	if cache {
+	if opts.Cache {
 		entry, locked := lock.Plugins[name]
 		if locked && entry.GitHub == github && entry.Ref == ref && entry.Resolved != "" && entry.Path != "" {
 			absPath := entry.Path
 			if !filepath.IsAbs(absPath) {
 				absPath = filepath.Join(stateDir, entry.Path)
 			}
-			if _, err := os.Stat(absPath); err == nil {
+			if _, err := os.Stat(absPath); err == nil && opts.cached(ctx, entry, absPath) {
 				return absPath, nil
 			}
 		}
 	}
 
-	resolved, err := ResolveRef(ctx, github, ref)
-	if err != nil {
-		return "", fmt.Errorf("resolve ref %q: %w", ref, err)
-	}
-
 	pluginDir := filepath.Join(stateDir, "plugins", name)
 	binaryName := name
 	if !strings.Contains(binaryName, "-") {
 		binaryName = name + "-plugin"
 	}
 
-	builtPath, err := CloneAndBuild(ctx, github, ref, resolved, pluginDir, binaryName)
+	entry, err := opts.fetch(ctx, github, ref, pluginDir, binaryName)
 	if err != nil {
 		return "", err
 	}
+	builtPath := entry.Path
 
 	relPath, _ := filepath.Rel(stateDir, builtPath)
 	if relPath == "" || strings.HasPrefix(relPath, "..") {
 		relPath = builtPath
 	}
-	lock.Plugins[name] = LockEntry{
-		GitHub:   github,
-		Ref:      ref,
-		Resolved: resolved,
-		Path:     relPath,
-	}
+	entry.Path = relPath
+	lock.Plugins[name] = entry
 	if err := SavePluginsLock(stateDir, lock); err != nil {
 		return "", err
 	}
@@ -447,56 +423,52 @@ func EnsurePlugin(ctx context.Context, stateDir, name, github, ref string, cache
 
 // EnsureChannel ensures the channel is present under stateDir/channels/<name>/,
 // resolves ref, clones and builds, updates channels.lock, and returns the path to the binary.
-// When cache is true the lock file is consulted and a matching entry is reused; when false the channel is always rebuilt.
-func EnsureChannel(ctx context.Context, stateDir, name, github, ref string, cache bool) (path string, err error) {
+// When opts.Cache is true the lock file is consulted and a matching entry is reused; when false the channel is always rebuilt.
+// opts.Policy and opts.Checksums are enforced as in EnsurePlugin.
+func EnsureChannel(ctx context.Context, stateDir, name, github, ref string, opts BuildOptions) (path string, err error) {
 	if github == "" || ref == "" {
 		return "", fmt.Errorf("github and ref are required")
 	}
+	if err := opts.Policy.allow(github, opts.Checksums); err != nil {
+		return "", err
+	}
 
 	lock, err := LoadChannelsLock(stateDir)
 	if err != nil {
 		return "", err
 	}
 
-	if cache {
+	if opts.Cache {
 		entry, locked := lock.Channels[name]
 		if locked && entry.GitHub == github && entry.Ref == ref && entry.Resolved != "" && entry.Path != "" {
 			absPath := entry.Path
 			if !filepath.IsAbs(absPath) {
 				absPath = filepath.Join(stateDir, entry.Path)
 			}
-			if _, err := os.Stat(absPath); err == nil {
+			if _, err := os.Stat(absPath); err == nil && opts.cached(ctx, entry, absPath) {
 				return absPath, nil
 			}
 		}
 	}
 
-	resolved, err := ResolveRef(ctx, github, ref)
-	if err != nil {
-		return "", fmt.Errorf("resolve ref %q: %w", ref, err)
-	}
-
 	channelDir := filepath.Join(stateDir, "channels", name)
 	binaryName := name
 	if !strings.Contains(binaryName, "-") {
 		binaryName = name + "-channel"
 	}
 
-	builtPath, err := CloneAndBuild(ctx, github, ref, resolved, channelDir, binaryName)
+	entry, err := opts.fetch(ctx, github, ref, channelDir, binaryName)
 	if err != nil {
 		return "", err
 	}
+	builtPath := entry.Path
 
 	relPath, _ := filepath.Rel(stateDir, builtPath)
 	if relPath == "" || strings.HasPrefix(relPath, "..") {
 		relPath = builtPath
 	}
-	lock.Channels[name] = LockEntry{
-		GitHub:   github,
-		Ref:      ref,
-		Resolved: resolved,
-		Path:     relPath,
-	}
+	entry.Path = relPath
+	lock.Channels[name] = entry
 	if err := SaveChannelsLock(stateDir, lock); err != nil {
 		return "", err
 	}
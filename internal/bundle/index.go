@@ -0,0 +1,251 @@
+package bundle
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SkillIndexFile is the file at the root of a skills index repo that lists
+// the skills it offers.
+const SkillIndexFile = "index.yaml"
+
+// SkillIndex is the content of a skills index (index.yaml).
+type SkillIndex struct {
+	Skills []IndexedSkill `yaml:"skills"`
+}
+
+// IndexedSkill is one skill listed in a skills index.
+type IndexedSkill struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	GitHub      string   `yaml:"github"` // repo holding the skill (SKILL.md or request.yaml at its root)
+	Ref         string   `yaml:"ref"`    // default main
+	Tags        []string `yaml:"tags,omitempty"`
+	SHA256      string   `yaml:"sha256,omitempty"` // DirChecksum of the skill at Ref; verified on install
+}
+
+// LoadSkillIndex fetches the index repo at ref into stateDir/skills/.index/
+// (re-cloning only when ref has moved), records it in skills.lock and
+// parses its index.yaml. Entries without a name or github are dropped.
+func LoadSkillIndex(ctx context.Context, stateDir, github, ref string) (*SkillIndex, error) {
+	if github == "" || ref == "" {
+		return nil, fmt.Errorf("github and ref are required for the skills index")
+	}
+	lock, err := LoadSkillsLock(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := ResolveRef(ctx, github, ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolve ref %q: %w", ref, err)
+	}
+
+	indexDir := filepath.Join(stateDir, "skills", ".index", sanitizeRepoName(github))
+	entry := lock.Index
+	fresh := entry != nil && entry.GitHub == github && entry.Resolved == resolved
+	if _, err := os.Stat(filepath.Join(indexDir, SkillIndexFile)); err != nil || !fresh {
+		if err := CloneOnly(ctx, github, ref, resolved, indexDir); err != nil {
+			return nil, err
+		}
+		relPath, _ := filepath.Rel(stateDir, indexDir)
+		lock.Index = &LockEntry{GitHub: github, Ref: ref, Resolved: resolved, Path: relPath}
+		if err := SaveSkillsLock(stateDir, lock); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(indexDir, SkillIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("read skills index: %w", err)
+	}
+	return ParseSkillIndex(data)
+}
+
+// ParseSkillIndex parses the content of an index.yaml.
+func ParseSkillIndex(data []byte) (*SkillIndex, error) {
+	var idx SkillIndex
+	if err := yaml.Unmarshal(data, &idx); err != nil {
+		return nil, fmt.Errorf("parse skills index: %w", err)
+	}
+	skills := idx.Skills[:0]
+	for _, s := range idx.Skills {
+		if s.Name == "" || s.GitHub == "" {
+			continue
+		}
+		if s.Ref == "" {
+			s.Ref = "main"
+		}
+		skills = append(skills, s)
+	}
+	idx.Skills = skills
+	return &idx, nil
+}
+
+// Find returns the skill called name.
+func (idx *SkillIndex) Find(name string) (IndexedSkill, bool) {
+	for _, s := range idx.Skills {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return IndexedSkill{}, false
+}
+
+// Search returns the skills whose name, description or tags contain every
+// word of query (case-insensitive), name matches first. An empty query
+// returns every skill.
+func (idx *SkillIndex) Search(query string) []IndexedSkill {
+	words := strings.Fields(strings.ToLower(query))
+	type hit struct {
+		skill IndexedSkill
+		score int
+	}
+	var hits []hit
+	for _, s := range idx.Skills {
+		name := strings.ToLower(s.Name)
+		text := name + " " + strings.ToLower(s.Description) + " " + strings.ToLower(strings.Join(s.Tags, " "))
+		score, ok := 0, true
+		for _, w := range words {
+			if !strings.Contains(text, w) {
+				ok = false
+				break
+			}
+			if strings.Contains(name, w) {
+				score++
+			}
+		}
+		if ok {
+			hits = append(hits, hit{s, score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].skill.Name < hits[j].skill.Name
+	})
+	out := make([]IndexedSkill, len(hits))
+	for i, h := range hits {
+		out[i] = h.skill
+	}
+	return out
+}
+
+// DirChecksum returns the hex SHA-256 of a skill directory: each regular
+// file (outside .git), in path order, contributes its slash-separated
+// relative path and the SHA-256 of its content. It does not depend on file
+// modes or timestamps, so index maintainers can compute it from a checkout.
+func DirChecksum(dir string) (string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if d.Type().IsRegular() {
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("checksum %s: %w", dir, err)
+	}
+	sort.Strings(paths)
+	sum := sha256.New()
+	for _, p := range paths {
+		fh, err := fileSHA256(filepath.Join(dir, filepath.FromSlash(p)))
+		if err != nil {
+			return "", fmt.Errorf("checksum %s: %w", dir, err)
+		}
+		fmt.Fprintf(sum, "%s\x00%s\n", p, fh)
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = f.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// InstallSkillDir fetches a skill like EnsureSkillDir, but always resolves
+// ref again so a moved branch or tag is picked up. When want is set, the
+// checkout must have that DirChecksum; on a mismatch the skill directory and
+// its lock entry are removed. It returns the skill directory and whether the
+// resolved commit changed.
+func InstallSkillDir(ctx context.Context, stateDir, name, github, ref, want string) (string, bool, error) {
+	if github == "" || ref == "" {
+		return "", false, fmt.Errorf("github and ref are required for skill %q", name)
+	}
+	lock, err := LoadSkillsLock(stateDir)
+	if err != nil {
+		return "", false, err
+	}
+	resolved, err := ResolveRef(ctx, github, ref)
+	if err != nil {
+		return "", false, fmt.Errorf("resolve ref %q: %w", ref, err)
+	}
+
+	skillDir := filepath.Join(stateDir, "skills", name)
+	old, locked := lock.Skills[name]
+	changed := !locked || old.GitHub != github || old.Resolved != resolved
+	if _, err := os.Stat(skillDir); err != nil || changed {
+		if err := CloneOnly(ctx, github, ref, resolved, skillDir); err != nil {
+			return "", false, err
+		}
+	}
+	sum, err := DirChecksum(skillDir)
+	if err != nil {
+		return "", false, err
+	}
+	if want != "" && !strings.EqualFold(sum, want) {
+		_ = os.RemoveAll(skillDir)
+		delete(lock.Skills, name)
+		_ = SaveSkillsLock(stateDir, lock)
+		return "", false, fmt.Errorf("checksum mismatch for skill %q: index has %s, got %s", name, want, sum)
+	}
+
+	relPath, _ := filepath.Rel(stateDir, skillDir)
+	if relPath == "" || strings.HasPrefix(relPath, "..") {
+		relPath = skillDir
+	}
+	lock.Skills[name] = LockEntry{
+		GitHub:   github,
+		Ref:      ref,
+		Resolved: resolved,
+		Path:     relPath,
+		Checksum: sum,
+	}
+	if err := SaveSkillsLock(stateDir, lock); err != nil {
+		return "", false, err
+	}
+	return skillDir, changed, nil
+}
+
+// IsCommitSHA reports whether ref is a full commit SHA, i.e. pinned.
+func IsCommitSHA(ref string) bool {
+	return commitSHARegex.MatchString(ref)
+}
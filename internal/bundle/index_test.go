@@ -0,0 +1,187 @@
+package bundle
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo creates a git repo with files committed on main and returns its
+// file:// URL. Skipped when git is not installed.
+func gitRepo(t *testing.T, files map[string]string) (string, func(files map[string]string) string) {
+	t.Helper()
+	if _, err := exec.LookPath(gitBin); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) string {
+		cmd := exec.Command(gitBin, append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	commit := func(files map[string]string) string {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		run("add", "-A")
+		run("commit", "-q", "-m", "update")
+		return run("rev-parse", "HEAD")
+	}
+	run("init", "-q")
+	commit(files)
+	return "file://" + dir, commit
+}
+
+func TestParseSkillIndexAndSearch(t *testing.T) {
+	idx, err := ParseSkillIndex([]byte(`
+skills:
+  - name: jira-create-issue
+    description: Create Jira issues
+    github: openclaw/skill-jira
+    tags: [jira, tickets]
+  - name: slack-send
+    description: Post a message to a Slack channel
+    github: openclaw/skill-slack
+    ref: v2
+  - name: orphan
+    description: no repo, dropped
+  - name: linear-issues
+    description: Search Linear tickets
+    github: openclaw/skill-linear
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Skills) != 3 {
+		t.Fatalf("skills = %+v", idx.Skills)
+	}
+	if s, ok := idx.Find("jira-create-issue"); !ok || s.Ref != "main" {
+		t.Errorf("default ref: %+v", s)
+	}
+	names := func(skills []IndexedSkill) string {
+		var out []string
+		for _, s := range skills {
+			out = append(out, s.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := names(idx.Search("tickets")); got != "jira-create-issue,linear-issues" {
+		t.Errorf("search tickets = %s", got)
+	}
+	if got := names(idx.Search("ISSUE tickets")); got != "jira-create-issue,linear-issues" {
+		t.Errorf("name matches rank first, got %s", got)
+	}
+	if got := names(idx.Search("slack message")); got != "slack-send" {
+		t.Errorf("search slack message = %s", got)
+	}
+	if got := idx.Search(""); len(got) != 3 {
+		t.Errorf("empty query returns everything, got %d", len(got))
+	}
+}
+
+func TestDirChecksum(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	for _, dir := range []string{a, b} {
+		if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		_ = os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("# skill"), 0644)
+		_ = os.WriteFile(filepath.Join(dir, "sub", "x.txt"), []byte("x"), 0600)
+	}
+	_ = os.MkdirAll(filepath.Join(b, ".git"), 0755)
+	_ = os.WriteFile(filepath.Join(b, ".git", "HEAD"), []byte("ref"), 0644)
+	sa, err := DirChecksum(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb, _ := DirChecksum(b)
+	if sa != sb || len(sa) != 64 {
+		t.Errorf("checksums %s %s: .git and modes must not count", sa, sb)
+	}
+	_ = os.WriteFile(filepath.Join(b, "sub", "x.txt"), []byte("y"), 0600)
+	if sb, _ = DirChecksum(b); sb == sa {
+		t.Error("changed content must change the checksum")
+	}
+}
+
+const skillMD = "---\nname: hello\ndescription: Say hello\n---\n# hello\n"
+
+func TestInstallSkillDir(t *testing.T) {
+	repo, commit := gitRepo(t, map[string]string{"SKILL.md": skillMD})
+	state := t.TempDir()
+	ctx := context.Background()
+
+	dir, changed, err := InstallSkillDir(ctx, state, "hello", repo, "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed || dir != filepath.Join(state, "skills", "hello") {
+		t.Errorf("first install: %s changed=%v", dir, changed)
+	}
+	lock, _ := LoadSkillsLock(state)
+	first := lock.Skills["hello"]
+	if want, _ := DirChecksum(dir); first.Checksum != want || first.Resolved == "" {
+		t.Errorf("lock entry = %+v", first)
+	}
+
+	if _, changed, _ = InstallSkillDir(ctx, state, "hello", repo, "main", first.Checksum); changed {
+		t.Error("same commit should not count as changed")
+	}
+
+	second := commit(map[string]string{"SKILL.md": skillMD + "more\n"})
+	if _, changed, err = InstallSkillDir(ctx, state, "hello", repo, "main", ""); err != nil || !changed {
+		t.Fatalf("update: changed=%v err=%v", changed, err)
+	}
+	lock, _ = LoadSkillsLock(state)
+	if lock.Skills["hello"].Resolved != second {
+		t.Errorf("resolved = %s, want %s", lock.Skills["hello"].Resolved, second)
+	}
+
+	// Pinning to the older commit needs more than a shallow clone.
+	if _, _, err = InstallSkillDir(ctx, state, "hello", repo, first.Resolved, ""); err != nil {
+		t.Fatalf("install at a commit: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "SKILL.md")); string(data) != skillMD {
+		t.Errorf("checked out %q", data)
+	}
+
+	_, _, err = InstallSkillDir(ctx, state, "hello", repo, "main", strings.Repeat("0", 64))
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("mismatch: %v", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("a skill failing its checksum must be removed")
+	}
+	if lock, _ = LoadSkillsLock(state); lock.Skills["hello"].Path != "" {
+		t.Error("lock entry of a rejected skill must be removed")
+	}
+}
+
+func TestLoadSkillIndex(t *testing.T) {
+	repo, commit := gitRepo(t, map[string]string{SkillIndexFile: "skills:\n  - {name: a, github: org/a}\n"})
+	state := t.TempDir()
+	idx, err := LoadSkillIndex(context.Background(), state, repo, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Skills) != 1 {
+		t.Fatalf("skills = %+v", idx.Skills)
+	}
+	commit(map[string]string{SkillIndexFile: "skills:\n  - {name: a, github: org/a}\n  - {name: b, github: org/b}\n"})
+	if idx, err = LoadSkillIndex(context.Background(), state, repo, "main"); err != nil || len(idx.Skills) != 2 {
+		t.Errorf("index not refreshed: %v %+v", err, idx)
+	}
+	lock, _ := LoadSkillsLock(state)
+	if lock.Index == nil || lock.Index.GitHub != repo {
+		t.Errorf("lock index = %+v", lock.Index)
+	}
+}
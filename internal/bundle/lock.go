@@ -22,8 +22,11 @@ type ChannelsLock struct {
 type LockEntry struct {
 	GitHub   string `yaml:"github"`
 	Ref      string `yaml:"ref"`
-	Resolved string `yaml:"resolved"` // commit SHA
-	Path     string `yaml:"path"`     // path to binary (relative to state dir or absolute)
+	Resolved string `yaml:"resolved"`                // commit SHA
+	Path     string `yaml:"path"`                    // path to binary (relative to state dir or absolute)
+	Checksum string `yaml:"checksum,omitempty"`      // DirChecksum of the source (skills: of the installed files)
+	Binary   string `yaml:"binary_sha256,omitempty"` // plugins and channels: SHA-256 of the build output
+	Release  string `yaml:"release,omitempty"`       // prebuilt release asset installed instead of a build
 }
 
 func pluginsLockPath(stateDir string) string {
@@ -102,6 +105,7 @@ func SaveChannelsLock(stateDir string, lock *ChannelsLock) error {
 type SkillsLock struct {
 	Repo   *LockEntry           `yaml:"repo,omitempty"`   // default monorepo (one repo, many skill subdirs)
 	Skills map[string]LockEntry `yaml:"skills,omitempty"` // per-skill repos (name -> entry, Path = skill dir)
+	Index  *LockEntry           `yaml:"index,omitempty"`  // skills index repo (see LoadSkillIndex)
 }
 
 func skillsLockPath(stateDir string) string {
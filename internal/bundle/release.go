@@ -0,0 +1,399 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// githubAPI is the GitHub REST endpoint used to look up releases; tests
+// point it at a fake server.
+var githubAPI = "https://api.github.com"
+
+// maxAssetSize caps a downloaded release asset.
+const maxAssetSize = 512 << 20
+
+// errNoRelease means there is no usable prebuilt asset and the source
+// should be built instead. Checksum mismatches are never wrapped in it.
+var errNoRelease = errors.New("no prebuilt release asset")
+
+type githubRelease struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// checksumFiles are the release assets searched, in order, for the
+// SHA-256 of a binary asset (after "<asset>.sha256").
+var checksumFiles = []string{"checksums.txt", "SHA256SUMS", "sha256sums.txt"}
+
+// fetch installs github at ref into dir and returns its lock entry with an
+// absolute Path. A prebuilt binary from the GitHub release tagged ref is
+// used when one matches this OS/arch and its checksum file; otherwise the
+// source is cloned and built.
+func (o BuildOptions) fetch(ctx context.Context, github, ref, dir, binaryName string) (LockEntry, error) {
+	if o.prebuiltAllowed() {
+		entry, err := o.downloadRelease(ctx, github, ref, dir, binaryName)
+		if err == nil {
+			return entry, nil
+		}
+		if !errors.Is(err, errNoRelease) {
+			return LockEntry{}, err
+		}
+		slog.Info("building from source", "component", "bundle", "github", github, "ref", ref, "reason", err)
+	}
+
+	resolved, err := ResolveRef(ctx, github, ref)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("resolve ref %q: %w", ref, err)
+	}
+	builtPath, sums, err := o.fetchAndBuild(ctx, github, ref, resolved, dir, binaryName)
+	if err != nil {
+		return LockEntry{}, err
+	}
+	return LockEntry{
+		GitHub:   github,
+		Ref:      ref,
+		Resolved: resolved,
+		Path:     builtPath,
+		Checksum: sums.Source,
+		Binary:   sums.Binary,
+	}, nil
+}
+
+// prebuiltAllowed reports whether a release asset may stand in for a
+// source build. A prebuilt binary cannot satisfy a source checksum or a
+// commit signature, so those always build from source.
+func (o BuildOptions) prebuiltAllowed() bool {
+	if o.FromSource || o.Policy.SignedCommits {
+		return false
+	}
+	return o.Checksums.Source == "" || o.Checksums.Binary != ""
+}
+
+// downloadRelease installs binaryName for runtime.GOOS/GOARCH from the
+// release tagged ref. The asset must be listed in a checksum file of the
+// same release; a mismatch there or against Checksums.Binary is an error,
+// anything missing is errNoRelease.
+func (o BuildOptions) downloadRelease(ctx context.Context, github, ref, dir, binaryName string) (LockEntry, error) {
+	slug := githubSlug(github)
+	if slug == "" {
+		return LockEntry{}, fmt.Errorf("%w: %s is not a GitHub repo", errNoRelease, github)
+	}
+	var rel githubRelease
+	if err := githubGet(ctx, "/repos/"+slug+"/releases/tags/"+url.PathEscape(ref), "application/vnd.github+json", &rel); err != nil {
+		return LockEntry{}, fmt.Errorf("%w: %v", errNoRelease, err)
+	}
+	asset, ok := pickAsset(rel.Assets, binaryName, runtime.GOOS, runtime.GOARCH)
+	if !ok {
+		return LockEntry{}, fmt.Errorf("%w: release %s has no asset for %s/%s", errNoRelease, ref, runtime.GOOS, runtime.GOARCH)
+	}
+	want, err := releaseChecksum(ctx, rel.Assets, asset.Name)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("%w: %v", errNoRelease, err)
+	}
+	var resolved string
+	if err := githubGet(ctx, "/repos/"+slug+"/commits/"+url.PathEscape(ref), "application/vnd.github.sha", &resolved); err != nil {
+		return LockEntry{}, fmt.Errorf("%w: resolve %s: %v", errNoRelease, ref, err)
+	}
+
+	data, err := download(ctx, asset.URL)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("%w: %v", errNoRelease, err)
+	}
+	sum := sha256.Sum256(data)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return LockEntry{}, fmt.Errorf("release asset %s checksum mismatch: want %s, got %s", asset.Name, want, got)
+	}
+	bin, err := extractBinary(asset.Name, data, binaryName)
+	if err != nil {
+		return LockEntry{}, fmt.Errorf("release asset %s: %w", asset.Name, err)
+	}
+	sum = sha256.Sum256(bin)
+	binarySum := hex.EncodeToString(sum[:])
+	if want := o.Checksums.Binary; want != "" && !strings.EqualFold(want, binarySum) {
+		return LockEntry{}, fmt.Errorf("binary checksum mismatch: want %s, got %s", want, binarySum)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return LockEntry{}, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return LockEntry{}, err
+	}
+	binaryPath := filepath.Join(dir, binaryName)
+	if err := os.WriteFile(binaryPath, bin, 0755); err != nil {
+		return LockEntry{}, err
+	}
+	return LockEntry{
+		GitHub:   github,
+		Ref:      ref,
+		Resolved: strings.TrimSpace(resolved),
+		Path:     binaryPath,
+		Binary:   binarySum,
+		Release:  asset.Name,
+	}, nil
+}
+
+// githubSlug returns "owner/repo" for a github.com repo and "" otherwise.
+func githubSlug(repo string) string {
+	repo = strings.TrimSpace(repo)
+	var p string
+	switch {
+	case strings.HasPrefix(repo, "git@github.com:"):
+		p = strings.TrimPrefix(repo, "git@github.com:")
+	case strings.Contains(repo, "://"):
+		u, err := url.Parse(repo)
+		if err != nil || u.Host != "github.com" {
+			return ""
+		}
+		p = u.Path
+	case strings.HasPrefix(repo, "git@"):
+		return ""
+	default:
+		p = repo
+	}
+	parts := strings.Split(strings.TrimSuffix(strings.Trim(p, "/"), ".git"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + parts[1]
+}
+
+// githubGet fetches an API path into out: JSON-decoded, or the raw body
+// when out is a *string. GITHUB_TOKEN, when set, raises the rate limit.
+func githubGet(ctx context.Context, apiPath, accept string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubAPI+apiPath, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", accept)
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", apiPath, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return err
+	}
+	if s, ok := out.(*string); ok {
+		*s = string(body)
+		return nil
+	}
+	return json.Unmarshal(body, out)
+}
+
+func download(ctx context.Context, assetURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, assetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download %s: %s", assetURL, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("download %s: larger than %d bytes", assetURL, maxAssetSize)
+	}
+	return data, nil
+}
+
+// archTokens maps GOARCH to the spellings found in release asset names.
+var archTokens = map[string][]string{
+	"amd64": {"amd64", "x86_64", "x64"},
+	"arm64": {"arm64", "aarch64"},
+	"386":   {"386", "i386", "x86"},
+}
+
+// pickAsset returns the asset built for goos/goarch: its name, split on
+// "-", "_" and ".", must contain both. Checksum and signature files are
+// skipped, and assets named after binaryName win over the rest.
+func pickAsset(assets []releaseAsset, binaryName, goos, goarch string) (releaseAsset, bool) {
+	osNames := []string{goos}
+	if goos == "darwin" {
+		osNames = append(osNames, "macos")
+	}
+	arches, ok := archTokens[goarch]
+	if !ok {
+		arches = []string{goarch}
+	}
+	var found []releaseAsset
+	for _, a := range assets {
+		name := strings.ToLower(a.Name)
+		if strings.Contains(name, "sha256") || strings.Contains(name, "checksums") ||
+			strings.HasSuffix(name, ".sig") || strings.HasSuffix(name, ".asc") || strings.HasSuffix(name, ".pem") {
+			continue
+		}
+		tokens := map[string]bool{}
+		for _, tok := range strings.FieldsFunc(strings.ReplaceAll(name, "x86_64", "x86-64"), func(r rune) bool {
+			return r == '-' || r == '_' || r == '.'
+		}) {
+			tokens[tok] = true
+		}
+		if tokens["x86"] && tokens["64"] {
+			tokens["x86_64"] = true
+		}
+		if hasAny(tokens, osNames) && hasAny(tokens, arches) {
+			found = append(found, a)
+		}
+	}
+	for _, a := range found {
+		if strings.HasPrefix(strings.ToLower(a.Name), strings.ToLower(binaryName)) {
+			return a, true
+		}
+	}
+	if len(found) > 0 {
+		return found[0], true
+	}
+	return releaseAsset{}, false
+}
+
+func hasAny(tokens map[string]bool, want []string) bool {
+	for _, w := range want {
+		if tokens[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// releaseChecksum finds the SHA-256 of assetName in the release's
+// "<asset>.sha256" or shared checksum file ("<hex>  <name>" per line).
+func releaseChecksum(ctx context.Context, assets []releaseAsset, assetName string) (string, error) {
+	byName := map[string]releaseAsset{}
+	for _, a := range assets {
+		byName[a.Name] = a
+	}
+	for _, file := range append([]string{assetName + ".sha256"}, checksumFiles...) {
+		a, ok := byName[file]
+		if !ok {
+			continue
+		}
+		data, err := download(ctx, a.URL)
+		if err != nil {
+			return "", err
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			fields := strings.Fields(line)
+			switch {
+			case len(fields) == 1 && file == assetName+".sha256":
+				return fields[0], nil
+			case len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == assetName:
+				return fields[0], nil
+			}
+		}
+		return "", fmt.Errorf("%s does not list %s", file, assetName)
+	}
+	return "", fmt.Errorf("release has no checksum file for %s", assetName)
+}
+
+// extractBinary returns binaryName from a .tar.gz/.tgz or .zip asset, or
+// the asset itself when it is not an archive. Inside an archive a file
+// named binaryName (or binaryName.exe) is preferred over the first
+// executable.
+func extractBinary(assetName string, data []byte, binaryName string) ([]byte, error) {
+	name := strings.ToLower(assetName)
+	isBinary := func(p string) bool {
+		base := path.Base(p)
+		return base == binaryName || base == binaryName+".exe"
+	}
+	var fallback []byte
+	switch {
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		tr := tar.NewReader(gz)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			if isBinary(hdr.Name) || (fallback == nil && hdr.Mode&0111 != 0) {
+				content, err := io.ReadAll(io.LimitReader(tr, maxAssetSize))
+				if err != nil {
+					return nil, err
+				}
+				if isBinary(hdr.Name) {
+					return content, nil
+				}
+				fallback = content
+			}
+		}
+	case strings.HasSuffix(name, ".zip"):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			if isBinary(f.Name) || (fallback == nil && (f.Mode()&0111 != 0 || strings.HasSuffix(f.Name, ".exe"))) {
+				rc, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				content, err := io.ReadAll(io.LimitReader(rc, maxAssetSize))
+				if cerr := rc.Close(); err == nil {
+					err = cerr
+				}
+				if err != nil {
+					return nil, err
+				}
+				if isBinary(f.Name) {
+					return content, nil
+				}
+				fallback = content
+			}
+		}
+	default:
+		return data, nil
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("no %s or executable in archive", binaryName)
+	}
+	return fallback, nil
+}
@@ -0,0 +1,172 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestGithubSlug(t *testing.T) {
+	cases := map[string]string{
+		"opentalon/slack-channel":             "opentalon/slack-channel",
+		"https://github.com/opentalon/x.git":  "opentalon/x",
+		"git@github.com:opentalon/x.git":      "opentalon/x",
+		"https://gitlab.com/team/repo":        "",
+		"git@gitlab.example.com:team/repo":    "",
+		"file:///tmp/repo":                    "",
+		"https://github.com/opentalon/x/tree": "",
+	}
+	for repo, want := range cases {
+		if got := githubSlug(repo); got != want {
+			t.Errorf("githubSlug(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestPickAsset(t *testing.T) {
+	assets := []releaseAsset{
+		{Name: "checksums.txt"},
+		{Name: "tool_1.0_linux_amd64.tar.gz.sig"},
+		{Name: "tool_1.0_linux_amd64.tar.gz"},
+		{Name: "hello-plugin_1.0_Linux_x86_64.tar.gz"},
+		{Name: "hello-plugin-darwin-arm64"},
+		{Name: "hello-plugin_linux_arm.tar.gz"},
+	}
+	for _, c := range []struct{ os, arch, want string }{
+		{"linux", "amd64", "hello-plugin_1.0_Linux_x86_64.tar.gz"},
+		{"darwin", "arm64", "hello-plugin-darwin-arm64"},
+		{"linux", "arm", "hello-plugin_linux_arm.tar.gz"},
+		{"windows", "amd64", ""},
+	} {
+		got, ok := pickAsset(assets, "hello-plugin", c.os, c.arch)
+		if got.Name != c.want || ok != (c.want != "") {
+			t.Errorf("%s/%s: got %q", c.os, c.arch, got.Name)
+		}
+	}
+}
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "README.md", Mode: 0644, Size: 2, Typeflag: tar.TypeReg})
+	_, _ = tw.Write([]byte("hi"))
+	_ = tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(content)
+	_ = tw.Close()
+	_ = gz.Close()
+	return buf.Bytes()
+}
+
+// fakeGitHub serves a release tagged v1.0.0 with the given assets and a
+// checksums.txt covering them; sums overrides individual checksums.
+func fakeGitHub(t *testing.T, assets map[string][]byte, sums map[string]string) {
+	t.Helper()
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	old := githubAPI
+	githubAPI = srv.URL
+	t.Cleanup(func() { githubAPI = old })
+
+	rel := githubRelease{TagName: "v1.0.0"}
+	var checksums strings.Builder
+	for name, data := range assets {
+		sum := sha256.Sum256(data)
+		hexSum := hex.EncodeToString(sum[:])
+		if s, ok := sums[name]; ok {
+			hexSum = s
+		}
+		checksums.WriteString(hexSum + "  " + name + "\n")
+		rel.Assets = append(rel.Assets, releaseAsset{Name: name, URL: srv.URL + "/download/" + name})
+		data := data
+		mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write(data) })
+	}
+	rel.Assets = append(rel.Assets, releaseAsset{Name: "checksums.txt", URL: srv.URL + "/download/checksums.txt"})
+	mux.HandleFunc("/download/checksums.txt", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte(checksums.String())) })
+	mux.HandleFunc("/repos/opentalon/hello/releases/tags/v1.0.0", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(rel)
+	})
+	mux.HandleFunc("/repos/opentalon/hello/commits/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "application/vnd.github.sha" {
+			http.Error(w, "bad accept", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(strings.Repeat("a", 40)))
+	})
+}
+
+func TestEnsurePlugin_ReleaseAsset(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho prebuilt\n")
+	asset := "hello-plugin_1.0.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".tar.gz"
+	fakeGitHub(t, map[string][]byte{asset: tarGz(t, "hello-plugin", binary)}, nil)
+	state := t.TempDir()
+
+	path, err := EnsurePlugin(context.Background(), state, "hello", "opentalon/hello", "v1.0.0", BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, binary) {
+		t.Errorf("installed %q", got)
+	}
+	lock, _ := LoadPluginsLock(state)
+	entry := lock.Plugins["hello"]
+	wantSum := sha256.Sum256(binary)
+	if entry.Release != asset || entry.Resolved != strings.Repeat("a", 40) || entry.Binary != hex.EncodeToString(wantSum[:]) {
+		t.Errorf("lock entry = %+v", entry)
+	}
+
+	_, err = EnsurePlugin(context.Background(), state, "hello", "opentalon/hello", "v1.0.0", BuildOptions{Checksums: Checksums{Binary: strings.Repeat("0", 64)}})
+	if err == nil || !strings.Contains(err.Error(), "binary checksum mismatch") {
+		t.Errorf("binary pin: %v", err)
+	}
+}
+
+func TestDownloadRelease_ChecksumMismatch(t *testing.T) {
+	asset := "hello-plugin-" + runtime.GOOS + "-" + runtime.GOARCH
+	fakeGitHub(t, map[string][]byte{asset: []byte("evil")}, map[string]string{asset: strings.Repeat("1", 64)})
+	_, err := (BuildOptions{}).downloadRelease(context.Background(), "opentalon/hello", "v1.0.0", t.TempDir(), "hello-plugin")
+	if err == nil || errors.Is(err, errNoRelease) || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("a tampered asset must fail, not fall back: %v", err)
+	}
+}
+
+func TestDownloadRelease_NoRelease(t *testing.T) {
+	fakeGitHub(t, map[string][]byte{"hello-plugin-plan9-mips": []byte("x")}, nil)
+	ctx := context.Background()
+	for _, ref := range []string{"main", "v1.0.0"} {
+		_, err := (BuildOptions{}).downloadRelease(ctx, "opentalon/hello", ref, t.TempDir(), "hello-plugin")
+		if !errors.Is(err, errNoRelease) {
+			t.Errorf("%s: %v", ref, err)
+		}
+	}
+}
+
+func TestPrebuiltAllowed(t *testing.T) {
+	for _, c := range []struct {
+		opts BuildOptions
+		want bool
+	}{
+		{BuildOptions{}, true},
+		{BuildOptions{FromSource: true}, false},
+		{BuildOptions{Policy: Policy{SignedCommits: true}}, false},
+		{BuildOptions{Checksums: Checksums{Source: "abc"}}, false},
+		{BuildOptions{Checksums: Checksums{Source: "abc", Binary: "def"}}, true},
+	} {
+		if got := c.opts.prebuiltAllowed(); got != c.want {
+			t.Errorf("%+v: got %v", c.opts, got)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Policy limits which github/ref plugins and channels may be fetched and
+// built. The zero value allows everything, as before.
+type Policy struct {
+	NoRemoteBuild   bool     // refuse github/ref sources entirely (local binaries and grpc:// only)
+	AllowedOrgs     []string // repo owners allowed as sources ("opentalon", or "gitlab.com/team" for other hosts); empty = any
+	SignedCommits   bool     // the resolved commit must pass `git verify-commit`
+	AllowedSigners  string   // ssh allowed_signers file used for SignedCommits (gpg signatures use the keyring)
+	RequireChecksum bool     // every github/ref source must pin sha256 or binary_sha256
+}
+
+// Checksums pins what one plugin or channel must build from and into.
+type Checksums struct {
+	Source string // DirChecksum of the checkout, checked before building
+	Binary string // SHA-256 of the build output, checked after building and on cache hits
+}
+
+// BuildOptions controls EnsurePlugin and EnsureChannel.
+type BuildOptions struct {
+	Cache      bool // reuse a matching lock entry instead of rebuilding
+	FromSource bool // always build, even when the release tagged ref has a prebuilt asset
+	Policy     Policy
+	Checksums  Checksums
+}
+
+// allow reports whether policy lets github be fetched and built.
+func (p Policy) allow(github string, sums Checksums) error {
+	if p.NoRemoteBuild {
+		return fmt.Errorf("remote builds are disabled (no_remote_build); use a local plugin path")
+	}
+	if len(p.AllowedOrgs) > 0 {
+		owner := repoOwner(github)
+		allowed := false
+		for _, org := range p.AllowedOrgs {
+			if strings.EqualFold(strings.TrimSuffix(org, "/"), owner) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("repo owner %q is not in allowed_orgs", owner)
+		}
+	}
+	if p.RequireChecksum && sums.Source == "" && sums.Binary == "" {
+		return fmt.Errorf("a sha256 or binary_sha256 is required (require_checksum)")
+	}
+	return nil
+}
+
+// repoOwner returns the owner of a repo: "org" for GitHub (org/repo or a
+// github.com URL), "host/org" for other hosts and "" when there is none.
+func repoOwner(repo string) string {
+	repo = strings.TrimSpace(repo)
+	var host, path string
+	switch {
+	case strings.HasPrefix(repo, "git@"):
+		host, path, _ = strings.Cut(strings.TrimPrefix(repo, "git@"), ":")
+	case strings.Contains(repo, "://"):
+		u, err := url.Parse(repo)
+		if err != nil {
+			return ""
+		}
+		host, path = u.Host, u.Path
+	default:
+		host, path = "github.com", repo
+	}
+	owner, _, ok := strings.Cut(strings.Trim(path, "/"), "/")
+	if !ok || owner == "" {
+		return ""
+	}
+	if host == "github.com" {
+		return owner
+	}
+	return host + "/" + owner
+}
+
+// verifyCommit checks the signature of the commit checked out in dir.
+func (p Policy) verifyCommit(ctx context.Context, dir, sha string) error {
+	if !p.SignedCommits {
+		return nil
+	}
+	args := []string{"verify-commit", sha}
+	if p.AllowedSigners != "" {
+		args = append([]string{"-c", "gpg.ssh.allowedSignersFile=" + p.AllowedSigners}, args...)
+	}
+	cmd := exec.CommandContext(ctx, gitBin, args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("commit %s has no valid signature: %s", sha, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// fetchAndBuild clones github at resolved into dir and builds it, checking
+// the commit signature and the source checksum before anything is compiled
+// and the binary checksum after. It returns the binary and both checksums.
+func (o BuildOptions) fetchAndBuild(ctx context.Context, github, ref, resolved, dir, binaryName string) (string, Checksums, error) {
+	if err := CloneOnly(ctx, github, ref, resolved, dir); err != nil {
+		return "", Checksums{}, err
+	}
+	if err := o.Policy.verifyCommit(ctx, dir, resolved); err != nil {
+		return "", Checksums{}, err
+	}
+	var got Checksums
+	var err error
+	if got.Source, err = DirChecksum(dir); err != nil {
+		return "", Checksums{}, err
+	}
+	if want := o.Checksums.Source; want != "" && !strings.EqualFold(want, got.Source) {
+		return "", Checksums{}, fmt.Errorf("source checksum mismatch: want %s, got %s", want, got.Source)
+	}
+	binaryPath, err := build(ctx, dir, binaryName)
+	if err != nil {
+		return "", Checksums{}, err
+	}
+	if got.Binary, err = fileSHA256(binaryPath); err != nil {
+		return "", Checksums{}, fmt.Errorf("checksum build output: %w", err)
+	}
+	if want := o.Checksums.Binary; want != "" && !strings.EqualFold(want, got.Binary) {
+		return "", Checksums{}, fmt.Errorf("binary checksum mismatch: want %s, got %s", want, got.Binary)
+	}
+	return binaryPath, got, nil
+}
+
+// cached reports whether a locked binary at path can be reused: it must
+// still satisfy the pinned checksums and, with SignedCommits, come from a
+// signed commit.
+func (o BuildOptions) cached(ctx context.Context, entry LockEntry, path string) bool {
+	if o.Policy.verifyCommit(ctx, filepath.Dir(path), entry.Resolved) != nil {
+		return false
+	}
+	if o.Checksums.Source != "" && !strings.EqualFold(o.Checksums.Source, entry.Checksum) {
+		return false
+	}
+	if o.Checksums.Binary != "" {
+		sum, err := fileSHA256(path)
+		return err == nil && strings.EqualFold(o.Checksums.Binary, sum)
+	}
+	return true
+}
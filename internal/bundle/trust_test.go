@@ -0,0 +1,140 @@
+package bundle
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepoOwner(t *testing.T) {
+	cases := map[string]string{
+		"opentalon/slack-channel":                  "opentalon",
+		"https://github.com/OpenTalon/x.git":       "OpenTalon",
+		"git@github.com:myorg/repo.git":            "myorg",
+		"https://gitlab.example.com/team/sub/repo": "gitlab.example.com/team",
+		"git@gitlab.example.com:team/repo.git":     "gitlab.example.com/team",
+		"just-a-name":                              "",
+	}
+	for repo, want := range cases {
+		if got := repoOwner(repo); got != want {
+			t.Errorf("repoOwner(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestPolicyAllow(t *testing.T) {
+	if err := (Policy{}).allow("anyone/anything", Checksums{}); err != nil {
+		t.Errorf("zero policy: %v", err)
+	}
+	if err := (Policy{NoRemoteBuild: true}).allow("opentalon/x", Checksums{Source: "abc"}); err == nil || !strings.Contains(err.Error(), "no_remote_build") {
+		t.Errorf("no remote build: %v", err)
+	}
+	orgs := Policy{AllowedOrgs: []string{"opentalon", "gitlab.example.com/team"}}
+	for repo, ok := range map[string]bool{
+		"opentalon/slack-channel":               true,
+		"https://github.com/opentalon/x":        true,
+		"git@gitlab.example.com:team/repo.git":  true,
+		"evil/opentalon":                        false,
+		"https://github.com.evil.io/opentalon/": false,
+	} {
+		if err := orgs.allow(repo, Checksums{}); (err == nil) != ok {
+			t.Errorf("allowed_orgs %s: %v", repo, err)
+		}
+	}
+	req := Policy{RequireChecksum: true}
+	if err := req.allow("opentalon/x", Checksums{}); err == nil {
+		t.Error("require_checksum without a checksum should fail")
+	}
+	if err := req.allow("opentalon/x", Checksums{Binary: "abc"}); err != nil {
+		t.Errorf("require_checksum with binary_sha256: %v", err)
+	}
+}
+
+// A repo with only a channel.yaml "builds" without a Go toolchain: the
+// build output is the yaml file itself.
+const channelYAML = "name: test\ntransport: webhook\n"
+
+func TestEnsureChannel_Checksums(t *testing.T) {
+	repo, commit := gitRepo(t, map[string]string{"channel.yaml": channelYAML})
+	state := t.TempDir()
+	ctx := context.Background()
+
+	path, err := EnsureChannel(ctx, state, "test", repo, "main", BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, _ := LoadChannelsLock(state)
+	entry := lock.Channels["test"]
+	if entry.Checksum == "" || entry.Binary == "" {
+		t.Fatalf("lock entry without checksums: %+v", entry)
+	}
+	wantBinary, _ := fileSHA256(path)
+	if entry.Binary != wantBinary {
+		t.Errorf("binary checksum = %s, want %s", entry.Binary, wantBinary)
+	}
+
+	pinned := BuildOptions{Cache: true, Checksums: Checksums{Source: entry.Checksum, Binary: entry.Binary}}
+	if _, err := EnsureChannel(ctx, state, "test", repo, "main", pinned); err != nil {
+		t.Errorf("matching checksums: %v", err)
+	}
+
+	commit(map[string]string{"channel.yaml": channelYAML + "evil: true\n"})
+	pinned.Cache = false
+	_, err = EnsureChannel(ctx, state, "test", repo, "main", pinned)
+	if err == nil || !strings.Contains(err.Error(), "source checksum mismatch") {
+		t.Errorf("changed source: %v", err)
+	}
+	_, err = EnsureChannel(ctx, state, "test", repo, "main", BuildOptions{Checksums: Checksums{Binary: entry.Binary}})
+	if err == nil || !strings.Contains(err.Error(), "binary checksum mismatch") {
+		t.Errorf("changed binary: %v", err)
+	}
+
+	// A cached binary that no longer matches its pin is rebuilt, not reused.
+	if err := os.WriteFile(path, []byte("tampered"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if (BuildOptions{Checksums: Checksums{Binary: entry.Binary}}).cached(ctx, entry, path) {
+		t.Error("tampered cache accepted")
+	}
+	if !(BuildOptions{}).cached(ctx, entry, path) {
+		t.Error("without a pin the cache is reused")
+	}
+}
+
+func TestEnsureChannel_SignedCommits(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen not installed")
+	}
+	repo, _ := gitRepo(t, map[string]string{"channel.yaml": channelYAML})
+	dir := strings.TrimPrefix(repo, "file://")
+	keys := t.TempDir()
+	key := filepath.Join(keys, "id")
+	if out, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-C", "test", "-f", key).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen: %v\n%s", err, out)
+	}
+	pub, _ := os.ReadFile(key + ".pub")
+	signers := filepath.Join(keys, "allowed_signers")
+	if err := os.WriteFile(signers, []byte("test@example.com "+string(pub)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	state := t.TempDir()
+	opts := BuildOptions{Policy: Policy{SignedCommits: true, AllowedSigners: signers}}
+	_, err := EnsureChannel(context.Background(), state, "signed", repo, "main", opts)
+	if err == nil || !strings.Contains(err.Error(), "no valid signature") {
+		t.Fatalf("unsigned commit: %v", err)
+	}
+
+	cmd := exec.Command(gitBin, "-c", "user.name=test", "-c", "user.email=test@example.com",
+		"-c", "gpg.format=ssh", "-c", "user.signingkey="+key, "commit", "-q", "-S", "--allow-empty", "-m", "signed")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Skipf("git cannot sign with ssh keys here: %v\n%s", err, out)
+	}
+	if _, err := EnsureChannel(context.Background(), state, "signed", repo, "main", opts); err != nil {
+		t.Errorf("signed commit: %v", err)
+	}
+}
@@ -3,6 +3,8 @@ package channel
 import (
 	"context"
 	"fmt"
+	"io"
+	"log/slog"
 	"strings"
 	"sync"
 	"time"
@@ -125,8 +127,18 @@ func (c *PluginClient) Tools() ([]pkg.ToolDefinition, error) {
 	return toolsFromProto(resp.Tools), nil
 }
 
+// Reconnect backoff for a broken inbound stream: the first retry waits
+// reconnectMinDelay, each further one twice as long, up to reconnectMaxDelay.
+var (
+	reconnectMinDelay = time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
 // Start begins listening for inbound messages from the channel plugin.
-// Messages are pushed into the provided inbox channel.
+// Messages are pushed into the provided inbox channel. When the stream
+// breaks (the plugin restarted, the network dropped) it is reopened with
+// backoff; the plugin keeps the messages that arrived meanwhile. A stream
+// the plugin ends cleanly is not reopened.
 func (c *PluginClient) Start(ctx context.Context, inbox chan<- pkg.InboundMessage) error {
 	c.ctx, c.cancel = context.WithCancel(ctx)
 
@@ -142,11 +154,19 @@ func (c *PluginClient) Start(ctx context.Context, inbox chan<- pkg.InboundMessag
 
 func (c *PluginClient) receiveLoop(stream channelpb.ChannelService_StartClient, inbox chan<- pkg.InboundMessage) {
 	defer c.wg.Done()
+	delay := reconnectMinDelay
 	for {
 		msg, err := stream.Recv()
-		if err != nil {
+		if err == io.EOF {
 			return
 		}
+		if err != nil {
+			if stream = c.reconnect(err, &delay); stream == nil {
+				return
+			}
+			continue
+		}
+		delay = reconnectMinDelay
 		converted := inboundFromProto(msg)
 		// Host-side instance stamp: opentalon assigned this client's
 		// instance id at construction; trust it over whatever the
@@ -164,6 +184,28 @@ func (c *PluginClient) receiveLoop(stream channelpb.ChannelService_StartClient,
 	}
 }
 
+// reconnect reopens the inbound stream after err broke it, waiting *delay
+// before each try and doubling it. It returns nil once the client stops.
+func (c *PluginClient) reconnect(err error, delay *time.Duration) channelpb.ChannelService_StartClient {
+	for {
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("channel stream broken, reconnecting", "channel", c.ID(), "error", err, "retry_in", *delay)
+		select {
+		case <-c.ctx.Done():
+			return nil
+		case <-time.After(*delay):
+		}
+		*delay = min(2**delay, reconnectMaxDelay)
+		var stream channelpb.ChannelService_StartClient
+		if stream, err = c.client.Start(c.ctx, &emptypb.Empty{}); err == nil {
+			slog.Info("channel stream reconnected", "channel", c.ID())
+			return stream
+		}
+	}
+}
+
 // Send dispatches an outbound message to the channel plugin.
 func (c *PluginClient) Send(ctx context.Context, msg pkg.OutboundMessage) error {
 	_, err := c.client.Send(ctx, outboundToProto(msg))
@@ -228,6 +270,10 @@ func inboundFromProto(pb *channelpb.InboundMessage) pkg.InboundMessage {
 	if pb.Timestamp != nil {
 		m.Timestamp = pb.Timestamp.AsTime()
 	}
+	if id, ok := m.Metadata[pkg.MessageIDMetadataKey]; ok {
+		m.MessageID = id
+		delete(m.Metadata, pkg.MessageIDMetadataKey)
+	}
 	for _, f := range pb.Files {
 		if f != nil {
 			m.Files = append(m.Files, pkg.FileAttachment{
@@ -9,7 +9,9 @@ import (
 	pkg "github.com/opentalon/opentalon/pkg/channel"
 	"github.com/opentalon/opentalon/pkg/channel/channelpb"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -24,6 +26,9 @@ type fakeChannelService struct {
 	tools      []*channelpb.ToolDefinition
 	configSeen map[string]interface{}
 	received   []*channelpb.OutboundMessage
+
+	brokenStarts int // Start streams that fail before sending anything
+	starts       int
 }
 
 func (s *fakeChannelService) Capabilities(_ context.Context, _ *emptypb.Empty) (*channelpb.ChannelCapabilities, error) {
@@ -42,6 +47,11 @@ func (s *fakeChannelService) Tools(_ context.Context, _ *emptypb.Empty) (*channe
 }
 
 func (s *fakeChannelService) Start(_ *emptypb.Empty, stream channelpb.ChannelService_StartServer) error {
+	s.starts++
+	if s.brokenStarts > 0 {
+		s.brokenStarts--
+		return status.Error(codes.Unavailable, "plugin restarting")
+	}
 	// Send one test inbound message.
 	msg := &channelpb.InboundMessage{
 		ChannelId:      s.caps.Id,
@@ -49,6 +59,7 @@ func (s *fakeChannelService) Start(_ *emptypb.Empty, stream channelpb.ChannelSer
 		SenderId:       "user-1",
 		SenderName:     "Diana",
 		Content:        "hello from plugin",
+		Metadata:       map[string]string{pkg.MessageIDMetadataKey: "evt-9"},
 		Timestamp:      timestamppb.Now(),
 	}
 	if err := stream.Send(msg); err != nil {
@@ -182,11 +193,42 @@ func TestChannelClientReceive(t *testing.T) {
 		if msg.ChannelID != "recv-ch" {
 			t.Errorf("channel_id = %q", msg.ChannelID)
 		}
+		if msg.MessageID != "evt-9" || len(msg.Metadata) != 0 {
+			t.Errorf("message id = %q, metadata = %v; want the id lifted out of metadata", msg.MessageID, msg.Metadata)
+		}
 	case <-time.After(2 * time.Second):
 		t.Fatal("timed out waiting for inbound message")
 	}
 }
 
+func TestChannelClientReconnects(t *testing.T) {
+	prevMin := reconnectMinDelay
+	reconnectMinDelay = 5 * time.Millisecond
+	defer func() { reconnectMinDelay = prevMin }()
+
+	svc := &fakeChannelService{
+		caps:         &channelpb.ChannelCapabilities{Id: "flaky-ch", Name: "Flaky"},
+		brokenStarts: 2,
+	}
+	client := newTestClient(t, svc)
+	inbox := make(chan pkg.InboundMessage, 10)
+	if err := client.Start(context.Background(), inbox); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-inbox:
+		if msg.Content != "hello from plugin" {
+			t.Errorf("content = %q", msg.Content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the stream was not reopened")
+	}
+	_ = client.Stop()
+	if svc.starts != 3 {
+		t.Errorf("%d Start streams, want 3", svc.starts)
+	}
+}
+
 func TestChannelClientDialFailure(t *testing.T) {
 	_, err := DialChannel("unix", "/nonexistent/channel.sock", defaultDialTimeout)
 	// gRPC NewClient is lazy, so dial failure happens on first RPC (fetchCapabilities).
@@ -0,0 +1,142 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/logger"
+	"github.com/opentalon/opentalon/internal/profile"
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// DefaultCommandPrefix starts a built-in command when none is configured.
+const DefaultCommandPrefix = "/"
+
+// Command is a slash command the handler answers itself: no LLM turn runs
+// and nothing is added to the conversation.
+type Command struct {
+	Name        string // without the prefix, e.g. "reset"; matched case-insensitively
+	Usage       string // argument synopsis shown by help, e.g. "<alias>"
+	Description string // one line for help
+	// Users and Groups restrict who may run the command: profile entity ids
+	// or "channel:sender" actors, and profile groups. Both empty = everyone.
+	Users  []string
+	Groups []string
+	Run    func(ctx context.Context, call CommandCall) (string, error)
+}
+
+// CommandCall is one invocation of a Command.
+type CommandCall struct {
+	SessionKey string // the scoped session the message belongs to
+	Args       string // the text after the command name, trimmed
+	Message    pkg.InboundMessage
+}
+
+// CommandRouter matches inbound messages against the built-in commands.
+// Prefixed messages naming no registered command are left alone, so the
+// content preparers (e.g. the opentalon-commands plugin) still see them.
+// help is built in: it lists the commands the caller may run.
+type CommandRouter struct {
+	prefix   string
+	commands []Command
+}
+
+// NewCommandRouter returns a router for commands starting with prefix
+// ("" = DefaultCommandPrefix). Registering "help" replaces the built-in one.
+func NewCommandRouter(prefix string, commands []Command) *CommandRouter {
+	if prefix == "" {
+		prefix = DefaultCommandPrefix
+	}
+	r := &CommandRouter{prefix: prefix, commands: commands}
+	if _, ok := r.command("help"); !ok {
+		r.commands = append([]Command{{
+			Name:        "help",
+			Description: "List the commands you can use",
+			Run: func(ctx context.Context, _ CommandCall) (string, error) {
+				return r.help(ctx), nil
+			},
+		}}, r.commands...)
+	}
+	return r
+}
+
+// match returns the command content invokes and its arguments.
+func (r *CommandRouter) match(content string) (Command, string, bool) {
+	if r == nil {
+		return Command{}, "", false
+	}
+	rest, ok := strings.CutPrefix(strings.TrimSpace(content), r.prefix)
+	if !ok {
+		return Command{}, "", false
+	}
+	name, args := rest, ""
+	if i := strings.IndexFunc(rest, unicode.IsSpace); i >= 0 {
+		name, args = rest[:i], rest[i:]
+	}
+	cmd, ok := r.command(name)
+	return cmd, strings.TrimSpace(args), ok
+}
+
+func (r *CommandRouter) command(name string) (Command, bool) {
+	for _, c := range r.commands {
+		if name != "" && strings.EqualFold(c.Name, name) {
+			return c, true
+		}
+	}
+	return Command{}, false
+}
+
+// allowed reports whether the caller in ctx may run c: by profile entity
+// id or group when a profile was verified, else by "channel:sender" actor.
+func allowed(ctx context.Context, c Command) bool {
+	if len(c.Users) == 0 && len(c.Groups) == 0 {
+		return true
+	}
+	if p := profile.FromContext(ctx); p != nil && p.EntityID != "" {
+		return slices.Contains(c.Users, p.EntityID) || (p.Group != "" && slices.Contains(c.Groups, p.Group))
+	}
+	return slices.Contains(c.Users, actor.Actor(ctx))
+}
+
+func (r *CommandRouter) help(ctx context.Context) string {
+	var b strings.Builder
+	b.WriteString("Commands:")
+	for _, c := range r.commands {
+		if !allowed(ctx, c) {
+			continue
+		}
+		usage := r.prefix + c.Name
+		if c.Usage != "" {
+			usage += " " + c.Usage
+		}
+		fmt.Fprintf(&b, "\n%s — %s", usage, c.Description)
+	}
+	return b.String()
+}
+
+// run answers a matched command. A caller without permission gets a
+// command_forbidden error frame; a failing command a command_failed one.
+func (r *CommandRouter) run(ctx context.Context, c Command, call CommandCall) pkg.OutboundMessage {
+	msg := call.Message
+	log := logger.FromContext(ctx)
+	if !allowed(ctx, c) {
+		log.Info("command refused", "command", c.Name, "actor", actor.Actor(ctx))
+		return errorFrame(msg, fmt.Sprintf("You are not allowed to use %s%s.", r.prefix, c.Name), "command_forbidden")
+	}
+	text, err := c.Run(ctx, call)
+	if err != nil {
+		log.Warn("command failed", "command", c.Name, "session", call.SessionKey, "error", err)
+		return errorFrame(msg, err.Error(), "command_failed")
+	}
+	log.Debug("command handled", "command", c.Name, "session", call.SessionKey)
+	return pkg.OutboundMessage{
+		ConversationID: msg.ConversationID,
+		ThreadID:       msg.ThreadID,
+		Content:        text,
+		Metadata:       safeMetadata(msg.Metadata),
+	}
+}
@@ -0,0 +1,120 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/profile"
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func commandHandler(t *testing.T, prefix string, commands ...Command) HandlerConfig {
+	cfg := baseHandlerConfig()
+	cfg.Runner = &failRunner{t: t}
+	cfg.Commands = NewCommandRouter(prefix, commands)
+	return cfg
+}
+
+func sendCommand(h pkg.MessageHandler, content string, meta map[string]string) pkg.OutboundMessage {
+	out, _ := h(context.Background(), "slack:conv1", pkg.InboundMessage{
+		ChannelID: "slack", ConversationID: "conv1", SenderID: "u7", Content: content, Metadata: meta,
+	})
+	return out
+}
+
+func TestHandler_CommandRunsWithoutLLM(t *testing.T) {
+	var got CommandCall
+	h := NewMessageHandler(commandHandler(t, "", Command{
+		Name: "model",
+		Run: func(_ context.Context, call CommandCall) (string, error) {
+			got = call
+			return "switched", nil
+		},
+	}))
+	out := sendCommand(h, "  /Model   opus fast ", nil)
+	if out.Content != "switched" || out.ConversationID != "conv1" {
+		t.Fatalf("reply = %+v", out)
+	}
+	if got.SessionKey != "slack:conv1" || got.Args != "opus fast" {
+		t.Errorf("call = %+v", got)
+	}
+}
+
+func TestHandler_CommandPrefixAndFallThrough(t *testing.T) {
+	cfg := commandHandler(t, "!", Command{Name: "reset", Run: func(context.Context, CommandCall) (string, error) {
+		return "cleared", nil
+	}})
+	cfg.Runner = &echoRunner{}
+	h := NewMessageHandler(cfg)
+	if out := sendCommand(h, "!reset", nil); out.Content != "cleared" {
+		t.Errorf("!reset = %q", out.Content)
+	}
+	// Another prefix, or a name the router does not know, goes on to the
+	// preparers and the LLM.
+	for _, content := range []string{"/reset", "!install skill x", "!"} {
+		if out := sendCommand(h, content, nil); out.Content != "echo: "+content {
+			t.Errorf("%q answered %q", content, out.Content)
+		}
+	}
+}
+
+func TestHandler_CommandPermissions(t *testing.T) {
+	cmd := Command{Name: "jobs", Users: []string{"slack:admin"}, Groups: []string{"ops"}, Run: func(context.Context, CommandCall) (string, error) {
+		return "jobs", nil
+	}}
+	h := NewMessageHandler(commandHandler(t, "", cmd))
+	out := sendCommand(h, "/jobs", nil)
+	if out.Metadata["error_code"] != "command_forbidden" {
+		t.Errorf("actor slack:u7 got %+v", out)
+	}
+	if out := sendCommand(h, "/help", nil); strings.Contains(out.Content, "/jobs") {
+		t.Errorf("help lists a forbidden command: %q", out.Content)
+	}
+
+	cfg := commandHandler(t, "", cmd)
+	cfg.Verifier = &stubVerifier{p: &profile.Profile{EntityID: "e1", Group: "ops"}}
+	h = NewMessageHandler(cfg)
+	if out := sendCommand(h, "/jobs", map[string]string{"profile_token": "tok"}); out.Content != "jobs" {
+		t.Errorf("group ops got %+v", out)
+	}
+	if out := sendCommand(h, "/help", map[string]string{"profile_token": "tok"}); !strings.Contains(out.Content, "/jobs") {
+		t.Errorf("help = %q", out.Content)
+	}
+}
+
+func TestHandler_CommandFailureFrame(t *testing.T) {
+	h := NewMessageHandler(commandHandler(t, "", Command{Name: "usage", Run: func(context.Context, CommandCall) (string, error) {
+		return "", errors.New("usage is not tracked")
+	}}))
+	out := sendCommand(h, "/usage", nil)
+	if out.Content != "usage is not tracked" || out.Metadata["error_code"] != "command_failed" {
+		t.Errorf("reply = %+v", out)
+	}
+}
+
+func TestHandler_CommandSkipsTokenLimit(t *testing.T) {
+	cfg := commandHandler(t, "", Command{Name: "usage", Run: func(context.Context, CommandCall) (string, error) {
+		return "1000 of 1000 tokens", nil
+	}})
+	cfg.Verifier = &stubVerifier{p: &profile.Profile{EntityID: "e1", Limit: 1000, LimitWindow: time.Hour}}
+	cfg.LimitChecker = &stubLimitChecker{total: 1000}
+	h := NewMessageHandler(cfg)
+	if out := sendCommand(h, "/usage", map[string]string{"profile_token": "tok"}); out.Content != "1000 of 1000 tokens" {
+		t.Errorf("reply = %+v", out)
+	}
+}
+
+func TestCommandRouterHelp(t *testing.T) {
+	r := NewCommandRouter("", []Command{{Name: "model", Usage: "<alias>", Description: "Switch model"}})
+	cmd, args, ok := r.match("/help")
+	if !ok || args != "" {
+		t.Fatalf("help not matched")
+	}
+	text, _ := cmd.Run(context.Background(), CommandCall{})
+	if want := "Commands:\n/help — List the commands you can use\n/model <alias> — Switch model"; text != want {
+		t.Errorf("help = %q, want %q", text, want)
+	}
+}
@@ -79,9 +79,6 @@ func (c *Connector) connectYAML(ctx context.Context, entry ChannelEntry) (pkg.Ch
 	return ch, nil
 }
 
-// sockFileName is the Unix socket filename used when OPENTALON_CHANNEL_SOCK_DIR is set.
-const sockFileName = "channel.sock"
-
 func (c *Connector) connectBinary(ctx context.Context, id, binaryPath string) (pkg.Channel, error) {
 	absPath, err := filepath.Abs(binaryPath)
 	if err != nil {
@@ -95,10 +92,10 @@ func (c *Connector) connectBinary(ctx context.Context, id, binaryPath string) (p
 	if err != nil {
 		return nil, fmt.Errorf("create socket dir: %w", err)
 	}
-	sockPath := filepath.Join(sockDir, sockFileName)
+	sockPath := filepath.Join(sockDir, pkg.SocketFileName)
 
 	cmd := exec.CommandContext(ctx, absPath)
-	cmd.Env = append(os.Environ(), "OPENTALON_CHANNEL_SOCK_DIR="+sockDir)
+	cmd.Env = append(os.Environ(), pkg.EnvSockDir+"="+sockDir)
 	cmd.Stdin = os.Stdin
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
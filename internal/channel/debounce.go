@@ -12,13 +12,25 @@ import (
 // messages before dispatching. 0 = disabled (opt-in via SetDebounceWindow).
 const defaultDebounceWindow = 0
 
+// defaultDebounceMaxWaitFactor bounds how long a burst can keep extending
+// the window when no explicit max wait is set: a user typing steadily is
+// answered after at most this many windows instead of never.
+const defaultDebounceMaxWaitFactor = 5
+
 // sessionDebouncer collects rapid-fire messages for the same session and
 // merges them into a single InboundMessage before dispatching. This avoids
 // burning an LLM round (~98k tokens) for each line when the user sends
 // "yes\nalso add barcode\nset price to 50" as three separate WebSocket frames.
+//
+// Only consecutive messages from the same sender are merged: in a group
+// conversation a message from someone else closes the current burst (it is
+// dispatched right away) and starts a new one, so one person's text is never
+// attributed to another. Each new message restarts the window, but a burst is
+// never held longer than maxWait after its first message.
 type sessionDebouncer struct {
 	mu       sync.Mutex
 	window   time.Duration
+	maxWait  time.Duration
 	buffers  map[string]*debounceBuf
 	dispatch func(sessionKey string, merged pkg.InboundMessage)
 	stopped  bool
@@ -27,11 +39,22 @@ type sessionDebouncer struct {
 type debounceBuf struct {
 	messages []pkg.InboundMessage
 	timer    *time.Timer
+	first    time.Time // arrival of the first buffered message; anchors maxWait
+	gen      int       // bumped whenever timer is replaced
 }
 
-func newSessionDebouncer(window time.Duration, dispatch func(string, pkg.InboundMessage)) *sessionDebouncer {
+// newSessionDebouncer builds a debouncer. maxWait <= 0 selects
+// defaultDebounceMaxWaitFactor × window.
+func newSessionDebouncer(window, maxWait time.Duration, dispatch func(string, pkg.InboundMessage)) *sessionDebouncer {
+	if maxWait <= 0 {
+		maxWait = defaultDebounceMaxWaitFactor * window
+	}
+	if maxWait < window {
+		maxWait = window
+	}
 	return &sessionDebouncer{
 		window:   window,
+		maxWait:  maxWait,
 		buffers:  make(map[string]*debounceBuf),
 		dispatch: dispatch,
 	}
@@ -60,41 +83,70 @@ func (d *sessionDebouncer) submit(sessionKey string, msg pkg.InboundMessage) boo
 	// Skip debounce for confirmation signals, typing indicators, and control
 	// messages (e.g. a resume handshake) — these must be processed immediately
 	// and never merged with a user's chat text.
-	if msg.Metadata["confirmation"] != "" || msg.Metadata["_typing"] == "true" || msg.Metadata[pkg.ControlMetadataKey] != "" {
+	if isControlMessage(msg) {
 		return false
 	}
 
 	d.mu.Lock()
-	defer d.mu.Unlock()
-
 	if d.stopped {
+		d.mu.Unlock()
 		return false
 	}
 
+	// A different sender closes the pending burst: take it out of the
+	// buffer now and dispatch it (outside the lock) ahead of this message.
+	var closed []pkg.InboundMessage
 	buf, exists := d.buffers[sessionKey]
+	if exists && len(buf.messages) > 0 && buf.messages[len(buf.messages)-1].SenderID != msg.SenderID {
+		if buf.timer != nil {
+			buf.timer.Stop()
+		}
+		closed = buf.messages
+		exists = false
+	}
+	now := time.Now()
 	if !exists {
-		buf = &debounceBuf{}
+		buf = &debounceBuf{first: now}
 		d.buffers[sessionKey] = buf
 	}
 
 	buf.messages = append(buf.messages, msg)
 
-	// Reset the timer on each new message.
+	// Reset the timer on each new message, capped at maxWait since the
+	// first message of the burst.
 	if buf.timer != nil {
 		buf.timer.Stop()
 	}
-	buf.timer = time.AfterFunc(d.window, func() {
-		d.flush(sessionKey)
+	wait := d.window
+	if remaining := buf.first.Add(d.maxWait).Sub(now); remaining < wait {
+		wait = max(remaining, 0)
+	}
+	buf.gen++
+	gen := buf.gen
+	buf.timer = time.AfterFunc(wait, func() {
+		d.flush(sessionKey, buf, gen)
 	})
+	d.mu.Unlock()
 
+	if closed != nil {
+		d.dispatch(sessionKey, mergeMessages(closed))
+	}
 	return true
 }
 
-// flush merges all buffered messages for a session and dispatches them.
-func (d *sessionDebouncer) flush(sessionKey string) {
+// isControlMessage reports whether msg is a confirmation signal, typing
+// indicator or control message rather than chat text.
+func isControlMessage(msg pkg.InboundMessage) bool {
+	return msg.Metadata["confirmation"] != "" || msg.Metadata[pkg.TypingMetadataKey] == "true" || msg.Metadata[pkg.ControlMetadataKey] != ""
+}
+
+// flush merges all buffered messages for a session and dispatches them. buf
+// and gen identify the timer that fired: Stop cannot recall a timer whose
+// func already started and is waiting for d.mu, so a flush whose burst was
+// since closed, or whose timer was since reset, does nothing.
+func (d *sessionDebouncer) flush(sessionKey string, buf *debounceBuf, gen int) {
 	d.mu.Lock()
-	buf, exists := d.buffers[sessionKey]
-	if d.stopped || !exists || len(buf.messages) == 0 {
+	if d.stopped || d.buffers[sessionKey] != buf || buf.gen != gen || len(buf.messages) == 0 {
 		d.mu.Unlock()
 		return
 	}
@@ -143,6 +195,7 @@ func mergeMessages(messages []pkg.InboundMessage) pkg.InboundMessage {
 
 	return pkg.InboundMessage{
 		ChannelID:      last.ChannelID,
+		Kind:           last.Kind,
 		ConversationID: last.ConversationID,
 		ThreadID:       last.ThreadID,
 		SenderID:       last.SenderID,
@@ -151,5 +204,6 @@ func mergeMessages(messages []pkg.InboundMessage) pkg.InboundMessage {
 		Metadata:       meta,
 		Files:          files,
 		Timestamp:      last.Timestamp,
+		MessageID:      last.MessageID,
 	}
 }
@@ -12,7 +12,7 @@ func TestDebouncerMergesRapidMessages(t *testing.T) {
 	var mu sync.Mutex
 	var dispatched []pkg.InboundMessage
 
-	d := newSessionDebouncer(100*time.Millisecond, func(_ string, merged pkg.InboundMessage) {
+	d := newSessionDebouncer(100*time.Millisecond, 0, func(_ string, merged pkg.InboundMessage) {
 		mu.Lock()
 		dispatched = append(dispatched, merged)
 		mu.Unlock()
@@ -39,7 +39,7 @@ func TestDebouncerConfirmationBypassesDebounce(t *testing.T) {
 	var mu sync.Mutex
 	var dispatched []pkg.InboundMessage
 
-	d := newSessionDebouncer(100*time.Millisecond, func(_ string, merged pkg.InboundMessage) {
+	d := newSessionDebouncer(100*time.Millisecond, 0, func(_ string, merged pkg.InboundMessage) {
 		mu.Lock()
 		dispatched = append(dispatched, merged)
 		mu.Unlock()
@@ -67,7 +67,7 @@ func TestDebouncerSeparateSessions(t *testing.T) {
 	var mu sync.Mutex
 	dispatched := make(map[string]pkg.InboundMessage)
 
-	d := newSessionDebouncer(100*time.Millisecond, func(key string, merged pkg.InboundMessage) {
+	d := newSessionDebouncer(100*time.Millisecond, 0, func(key string, merged pkg.InboundMessage) {
 		mu.Lock()
 		dispatched[key] = merged
 		mu.Unlock()
@@ -97,7 +97,7 @@ func TestDebouncerSingleMessageDispatchesAfterWindow(t *testing.T) {
 	var mu sync.Mutex
 	var dispatched []pkg.InboundMessage
 
-	d := newSessionDebouncer(window, func(_ string, merged pkg.InboundMessage) {
+	d := newSessionDebouncer(window, 0, func(_ string, merged pkg.InboundMessage) {
 		mu.Lock()
 		dispatched = append(dispatched, merged)
 		mu.Unlock()
@@ -145,3 +145,104 @@ func TestMergeMessagesFiles(t *testing.T) {
 		t.Fatalf("expected 3 files, got %d", len(merged.Files))
 	}
 }
+
+func TestDebouncerSenderChangeClosesBurst(t *testing.T) {
+	var mu sync.Mutex
+	var dispatched []pkg.InboundMessage
+
+	d := newSessionDebouncer(100*time.Millisecond, 0, func(_ string, merged pkg.InboundMessage) {
+		mu.Lock()
+		dispatched = append(dispatched, merged)
+		mu.Unlock()
+	})
+
+	d.submit("s1", pkg.InboundMessage{Content: "a1", SenderID: "alice"})
+	d.submit("s1", pkg.InboundMessage{Content: "a2", SenderID: "alice"})
+	d.submit("s1", pkg.InboundMessage{Content: "b1", SenderID: "bob"})
+
+	// Alice's burst is dispatched as soon as Bob speaks.
+	mu.Lock()
+	if len(dispatched) != 1 || dispatched[0].Content != "a1\na2" || dispatched[0].SenderID != "alice" {
+		t.Fatalf("after sender change: %+v", dispatched)
+	}
+	mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dispatched) != 2 || dispatched[1].Content != "b1" || dispatched[1].SenderID != "bob" {
+		t.Fatalf("bob's message not dispatched separately: %+v", dispatched)
+	}
+}
+
+func TestDebouncerStaleFlushDoesNothing(t *testing.T) {
+	var mu sync.Mutex
+	var dispatched []pkg.InboundMessage
+
+	d := newSessionDebouncer(100*time.Millisecond, 0, func(_ string, merged pkg.InboundMessage) {
+		mu.Lock()
+		dispatched = append(dispatched, merged)
+		mu.Unlock()
+	})
+
+	d.submit("s1", pkg.InboundMessage{Content: "a1", SenderID: "alice"})
+	d.mu.Lock()
+	aliceBuf, aliceGen := d.buffers["s1"], d.buffers["s1"].gen
+	d.mu.Unlock()
+	d.submit("s1", pkg.InboundMessage{Content: "b1", SenderID: "bob"})
+	d.mu.Lock()
+	bobBuf, bobGen := d.buffers["s1"], d.buffers["s1"].gen
+	d.mu.Unlock()
+	d.submit("s1", pkg.InboundMessage{Content: "b2", SenderID: "bob"})
+
+	// Flushes whose timers fired before the burst closed or the timer was
+	// reset, and then waited for the lock.
+	d.flush("s1", aliceBuf, aliceGen)
+	d.flush("s1", bobBuf, bobGen)
+	mu.Lock()
+	if len(dispatched) != 1 || dispatched[0].Content != "a1" {
+		t.Fatalf("stale flush dispatched: %+v", dispatched)
+	}
+	mu.Unlock()
+
+	time.Sleep(200 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dispatched) != 2 || dispatched[1].Content != "b1\nb2" {
+		t.Fatalf("bob's burst = %+v", dispatched)
+	}
+}
+
+func TestDebouncerMaxWaitCapsBurst(t *testing.T) {
+	var mu sync.Mutex
+	var dispatched []pkg.InboundMessage
+
+	d := newSessionDebouncer(80*time.Millisecond, 150*time.Millisecond, func(_ string, merged pkg.InboundMessage) {
+		mu.Lock()
+		dispatched = append(dispatched, merged)
+		mu.Unlock()
+	})
+
+	// Keep typing every 50ms — each message would extend an uncapped window.
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		d.submit("s1", pkg.InboundMessage{Content: "x", SenderID: "u"})
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dispatched) == 0 {
+		t.Fatalf("burst held for %v with max wait 150ms", time.Since(start))
+	}
+}
+
+func TestMergeMessagesKeepsKind(t *testing.T) {
+	merged := mergeMessages([]pkg.InboundMessage{
+		{Content: "a", Kind: "slack"},
+		{Content: "b", Kind: "slack"},
+	})
+	if merged.Kind != "slack" {
+		t.Errorf("Kind = %q; want slack", merged.Kind)
+	}
+}
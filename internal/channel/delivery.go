@@ -0,0 +1,313 @@
+package channel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/opentalon/opentalon/internal/logger"
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+const (
+	DefaultDeliveryAttempts  = 3
+	DefaultDeliveryBackoff   = 2 * time.Second
+	DefaultRedeliverInterval = time.Minute
+	DefaultOutboxTTL         = 24 * time.Hour
+
+	maxDeliveryBackoff = 30 * time.Second
+)
+
+// DeliveryPolicy controls how final replies reach the channel. A reply is
+// sent up to Attempts times with exponential backoff; one that still fails
+// is parked in Outbox, retried by RunOutbox, and reported to the operator
+// through OnUndelivered.
+type DeliveryPolicy struct {
+	Attempts      int           // <= 0 = DefaultDeliveryAttempts
+	Backoff       time.Duration // wait before the second attempt, doubled after each failure; <= 0 = DefaultDeliveryBackoff
+	Outbox        *Outbox       // nil = undelivered replies are only logged
+	OnUndelivered func(ctx context.Context, e OutboxEntry)
+}
+
+func (p DeliveryPolicy) attempts() int {
+	if p.Attempts <= 0 {
+		return DefaultDeliveryAttempts
+	}
+	return p.Attempts
+}
+
+func (p DeliveryPolicy) backoff() time.Duration {
+	if p.Backoff <= 0 {
+		return DefaultDeliveryBackoff
+	}
+	return p.Backoff
+}
+
+// SetDeliveryPolicy replaces the default delivery policy (three attempts,
+// no outbox). Must be called before any channels are registered.
+func (r *Registry) SetDeliveryPolicy(p DeliveryPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.channels) > 0 {
+		panic("channel: SetDeliveryPolicy called after channels registered")
+	}
+	r.delivery = p
+}
+
+// deliver sends a final reply, retrying transient failures. A reply that
+// cannot be delivered goes to the outbox instead of being dropped.
+func (r *Registry) deliver(ctx context.Context, ch pkg.Channel, msg pkg.OutboundMessage) {
+	r.mu.RLock()
+	p := r.delivery
+	r.mu.RUnlock()
+	log := logger.FromContext(ctx)
+
+	wait := p.backoff()
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		if err = ch.Send(ctx, msg); err == nil {
+			if attempt > 1 {
+				log.Info("response delivered after retry", "channel", ch.ID(), "attempts", attempt)
+			}
+			return
+		}
+		if attempt >= p.attempts() {
+			break
+		}
+		log.Warn("sending response failed, retrying", "channel", ch.ID(), "attempt", attempt, "retry_in", wait, "error", err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(wait):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wait = min(wait*2, maxDeliveryBackoff)
+	}
+	log.Error("sending response failed", "channel", ch.ID(), "attempts", attempt, "error", err)
+	if p.Outbox == nil {
+		return
+	}
+	entry, perr := p.Outbox.Add(ch.ID(), msg, attempt, err)
+	if perr != nil {
+		log.Error("storing undelivered response failed", "channel", ch.ID(), "error", perr)
+		return
+	}
+	slog.Info("audit", "event", "response_undelivered", "id", entry.ID, "channel", entry.ChannelID, "conversation", msg.ConversationID, "attempts", attempt, "error", entry.Error)
+	if p.OnUndelivered != nil {
+		// The turn's context may be the one that was cancelled.
+		p.OnUndelivered(context.WithoutCancel(ctx), entry)
+	}
+}
+
+// RunOutbox retries the outbox every interval until ctx is done. Entries for
+// channels that are not registered wait; entries older than the outbox TTL
+// are dropped.
+func (r *Registry) RunOutbox(ctx context.Context, interval time.Duration) {
+	r.mu.RLock()
+	box := r.delivery.Outbox
+	r.mu.RUnlock()
+	if box == nil {
+		return
+	}
+	if interval <= 0 {
+		interval = DefaultRedeliverInterval
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			r.redeliver(ctx, box)
+		}
+	}
+}
+
+// redeliver makes one pass over the outbox.
+func (r *Registry) redeliver(ctx context.Context, box *Outbox) {
+	for _, e := range box.List() {
+		if box.expired(e) {
+			box.remove(e.ID)
+			slog.Info("audit", "event", "response_dropped", "id", e.ID, "channel", e.ChannelID, "conversation", e.Message.ConversationID, "attempts", e.Attempts, "error", e.Error)
+			continue
+		}
+		ch, ok := r.Get(e.ChannelID)
+		if !ok {
+			continue
+		}
+		if err := ch.Send(ctx, e.Message); err != nil {
+			box.failed(e.ID, err)
+			slog.Debug("redelivering response failed", "component", "outbox", "id", e.ID, "channel", e.ChannelID, "error", err)
+			continue
+		}
+		box.remove(e.ID)
+		slog.Info("audit", "event", "response_redelivered", "id", e.ID, "channel", e.ChannelID, "conversation", e.Message.ConversationID, "attempts", e.Attempts+1)
+	}
+}
+
+// OutboxEntry is a final reply that could not be delivered.
+type OutboxEntry struct {
+	ID          string              `yaml:"id"`
+	ChannelID   string              `yaml:"channel_id"`
+	Message     pkg.OutboundMessage `yaml:"message"`
+	Attempts    int                 `yaml:"attempts"`
+	Error       string              `yaml:"error"` // last send error
+	CreatedAt   time.Time           `yaml:"created_at"`
+	LastAttempt time.Time           `yaml:"last_attempt"`
+}
+
+// Outbox holds undelivered replies. Entries are kept in memory and persisted
+// to <dataDir>/outbox/undelivered.yaml after every change, so replies survive
+// a restart.
+type Outbox struct {
+	mu      sync.Mutex
+	entries map[string]*OutboxEntry
+	dataDir string
+	ttl     time.Duration
+}
+
+// NewOutbox creates an outbox and loads persisted entries from dataDir. An
+// empty dataDir keeps the outbox in memory only; ttl <= 0 selects
+// DefaultOutboxTTL.
+func NewOutbox(dataDir string, ttl time.Duration) (*Outbox, error) {
+	if ttl <= 0 {
+		ttl = DefaultOutboxTTL
+	}
+	o := &Outbox{entries: make(map[string]*OutboxEntry), dataDir: dataDir, ttl: ttl}
+	if err := o.load(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Add stores msg for channelID after attempts failed sends, the last with err.
+func (o *Outbox) Add(channelID string, msg pkg.OutboundMessage, attempts int, err error) (OutboxEntry, error) {
+	now := time.Now().UTC()
+	e := OutboxEntry{ID: newOutboxID(), ChannelID: channelID, Message: msg, Attempts: attempts, CreatedAt: now, LastAttempt: now}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.entries[e.ID] = &e
+	if perr := o.persistLocked(); perr != nil {
+		delete(o.entries, e.ID)
+		return OutboxEntry{}, perr
+	}
+	return e, nil
+}
+
+// List returns the entries, oldest first.
+func (o *Outbox) List() []OutboxEntry {
+	o.mu.Lock()
+	out := make([]OutboxEntry, 0, len(o.entries))
+	for _, e := range o.entries {
+		out = append(out, *e)
+	}
+	o.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Len returns the number of undelivered replies.
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.entries)
+}
+
+func (o *Outbox) expired(e OutboxEntry) bool {
+	return time.Since(e.CreatedAt) > o.ttl
+}
+
+func (o *Outbox) failed(id string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if e, ok := o.entries[id]; ok {
+		e.Attempts++
+		e.Error = err.Error()
+		e.LastAttempt = time.Now().UTC()
+		o.persistOrWarnLocked()
+	}
+}
+
+func (o *Outbox) remove(id string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.entries[id]; ok {
+		delete(o.entries, id)
+		o.persistOrWarnLocked()
+	}
+}
+
+func (o *Outbox) persistPath() string {
+	return filepath.Join(o.dataDir, "outbox", "undelivered.yaml")
+}
+
+func (o *Outbox) persistOrWarnLocked() {
+	if err := o.persistLocked(); err != nil {
+		slog.Warn("persisting outbox failed", "component", "outbox", "error", err)
+	}
+}
+
+// persistLocked writes the outbox to disk. Caller holds o.mu.
+func (o *Outbox) persistLocked() error {
+	if o.dataDir == "" {
+		return nil
+	}
+	list := make([]OutboxEntry, 0, len(o.entries))
+	for _, e := range o.entries {
+		list = append(list, *e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+
+	if err := os.MkdirAll(filepath.Dir(o.persistPath()), 0700); err != nil {
+		return fmt.Errorf("creating outbox dir: %w", err)
+	}
+	data, err := yaml.Marshal(list)
+	if err != nil {
+		return fmt.Errorf("marshaling outbox: %w", err)
+	}
+	return os.WriteFile(o.persistPath(), data, 0600)
+}
+
+func (o *Outbox) load() error {
+	if o.dataDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(o.persistPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading outbox file: %w", err)
+	}
+	var list []OutboxEntry
+	if err := yaml.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("parsing outbox file: %w", err)
+	}
+	for i := range list {
+		o.entries[list[i].ID] = &list[i]
+	}
+	return nil
+}
+
+func newOutboxID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return hex.EncodeToString([]byte(time.Now().Format("150405.000")))
+	}
+	return hex.EncodeToString(b)
+}
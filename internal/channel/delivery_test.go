@@ -0,0 +1,103 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// flakyChannel fails its first `failures` sends.
+type flakyChannel struct {
+	*mockChannel
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (f *flakyChannel) Send(ctx context.Context, msg pkg.OutboundMessage) error {
+	f.mu.Lock()
+	f.calls++
+	fail := f.calls <= f.failures
+	f.mu.Unlock()
+	if fail {
+		return errors.New("upstream 502")
+	}
+	return f.mockChannel.Send(ctx, msg)
+}
+
+func TestDeliver_RetriesTransientFailures(t *testing.T) {
+	reg := NewRegistry(nil)
+	reg.SetDeliveryPolicy(DeliveryPolicy{Attempts: 3, Backoff: time.Millisecond})
+	ch := &flakyChannel{mockChannel: newMockChannel("c"), failures: 2}
+
+	reg.deliver(context.Background(), ch, pkg.OutboundMessage{ConversationID: "conv", Content: "answer"})
+	if got := ch.sentMessages(); len(got) != 1 || got[0].Content != "answer" {
+		t.Fatalf("sent = %+v; want the answer delivered on the third attempt", got)
+	}
+}
+
+func TestDeliver_ParksUndeliveredAndRedelivers(t *testing.T) {
+	dir := t.TempDir()
+	box, err := NewOutbox(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notified []OutboxEntry
+	reg := NewRegistry(nil)
+	reg.SetDeliveryPolicy(DeliveryPolicy{Attempts: 2, Backoff: time.Millisecond, Outbox: box,
+		OnUndelivered: func(_ context.Context, e OutboxEntry) { notified = append(notified, e) }})
+	ch := &flakyChannel{mockChannel: newMockChannel("c"), failures: 3}
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+	defer reg.StopAll()
+
+	reg.deliver(context.Background(), ch, pkg.OutboundMessage{ConversationID: "conv", Content: "answer"})
+	if box.Len() != 1 || len(notified) != 1 || notified[0].Attempts != 2 || notified[0].Error != "upstream 502" {
+		t.Fatalf("outbox %d, notified %+v", box.Len(), notified)
+	}
+
+	// The outbox survives a restart.
+	reloaded, err := NewOutbox(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := reloaded.List(); len(l) != 1 || l[0].Message.Content != "answer" || l[0].ChannelID != "c" {
+		t.Fatalf("reloaded = %+v", l)
+	}
+
+	reg.redeliver(context.Background(), box) // third failure
+	if l := box.List(); len(l) != 1 || l[0].Attempts != 3 {
+		t.Fatalf("after failed redelivery: %+v", l)
+	}
+	reg.redeliver(context.Background(), box)
+	if box.Len() != 0 || len(ch.sentMessages()) != 1 {
+		t.Errorf("after redelivery: outbox %d, sent %d", box.Len(), len(ch.sentMessages()))
+	}
+}
+
+func TestRedeliver_WaitsForChannelAndDropsExpired(t *testing.T) {
+	box, _ := NewOutbox("", time.Hour)
+	reg := NewRegistry(nil)
+	if _, err := box.Add("gone", pkg.OutboundMessage{Content: "waiting"}, 3, errors.New("closed")); err != nil {
+		t.Fatal(err)
+	}
+	reg.redeliver(context.Background(), box)
+	if box.Len() != 1 {
+		t.Fatal("an entry for an unregistered channel should wait")
+	}
+
+	box.mu.Lock()
+	for _, e := range box.entries {
+		e.CreatedAt = time.Now().Add(-2 * time.Hour)
+	}
+	box.mu.Unlock()
+	reg.redeliver(context.Background(), box)
+	if box.Len() != 0 {
+		t.Error("an entry past the TTL should be dropped")
+	}
+}
@@ -0,0 +1,384 @@
+package channel
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// Overflow says what happens to a reply longer than the channel's
+// MaxMessageLength.
+type Overflow string
+
+const (
+	OverflowSplit Overflow = "split" // several messages, one file when there would be too many
+	OverflowFile  Overflow = "file"  // a short preview plus the whole reply as a file
+)
+
+// defaultMaxParts is how many messages a split reply may take before it is
+// sent as a file instead (on channels declaring Files).
+const defaultMaxParts = 4
+
+// Formatting is the per-channel reply formatting policy. The zero value
+// leaves replies as the handler returned them.
+type Formatting struct {
+	// Convert makes the model write standard Markdown (the handler sees the
+	// channel as FormatMarkdown) and renders each reply in the channel's
+	// real ResponseFormat on the way out.
+	Convert  bool
+	Overflow Overflow // "" = OverflowSplit
+	MaxParts int      // <= 0 = defaultMaxParts
+}
+
+// SetChannelFormatting sets the reply formatting policy for one channel
+// instance (its config key). Must be called before that channel is
+// registered.
+func (r *Registry) SetChannelFormatting(channelID string, f Formatting) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[channelID]; exists {
+		panic("channel: SetChannelFormatting called after channel registered")
+	}
+	if r.formatting == nil {
+		r.formatting = make(map[string]Formatting)
+	}
+	r.formatting[channelID] = f
+}
+
+func (r *Registry) formattingFor(channelID string) (Formatting, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.formatting[channelID]
+	return f, ok
+}
+
+// formatReply renders msg for a channel and splits it to fit
+// caps.MaxMessageLength. Every part keeps the conversation, thread and
+// metadata of msg; files msg already carries go with the last part.
+func formatReply(msg pkg.OutboundMessage, caps pkg.Capabilities, f Formatting) []pkg.OutboundMessage {
+	format := pkg.FormatMarkdown // renders as is
+	if f.Convert {
+		format = caps.ResponseFormat
+	}
+	limit := int(caps.MaxMessageLength)
+	source := msg.Content
+	if rendered := renderMarkdown(source, format); limit <= 0 || utf8.RuneCountInString(rendered) <= limit {
+		msg.Content = rendered
+		return []pkg.OutboundMessage{msg}
+	}
+
+	parts := splitRendered(source, format, limit)
+	maxParts := f.MaxParts
+	if maxParts <= 0 {
+		maxParts = defaultMaxParts
+	}
+	if caps.Files && (f.Overflow == OverflowFile || len(parts) > maxParts) {
+		return []pkg.OutboundMessage{replyAsFile(msg, source, format, limit)}
+	}
+
+	out := make([]pkg.OutboundMessage, len(parts))
+	for i, p := range parts {
+		out[i] = pkg.OutboundMessage{
+			ConversationID: msg.ConversationID,
+			ThreadID:       msg.ThreadID,
+			Content:        p,
+			Metadata:       copyMetadata(msg.Metadata),
+		}
+	}
+	out[len(out)-1].Files = msg.Files
+	return out
+}
+
+// replyAsFile sends the start of a long reply as text and the whole reply
+// as an attachment: Markdown source as reply.md, or rendered text as
+// reply.txt on plain-text channels.
+func replyAsFile(msg pkg.OutboundMessage, source string, format pkg.ResponseFormat, limit int) pkg.OutboundMessage {
+	file := pkg.FileAttachment{Name: "reply.md", MimeType: "text/markdown", Data: []byte(source)}
+	if format == pkg.FormatText {
+		file = pkg.FileAttachment{Name: "reply.txt", MimeType: "text/plain", Data: []byte(renderMarkdown(source, format))}
+	}
+	file.Size = int64(len(file.Data))
+
+	note := fmt.Sprintf("\n\n(Full reply attached as %s.)", file.Name)
+	preview := ""
+	if budget := limit - utf8.RuneCountInString(note); budget > 0 {
+		preview = splitRendered(source, format, budget)[0]
+	}
+	msg.Content = strings.TrimLeft(preview+note, "\n")
+	msg.Files = append(append([]pkg.FileAttachment(nil), msg.Files...), file)
+	return msg
+}
+
+// splitRendered splits Markdown source into parts whose rendered form fits
+// limit. Rendering can lengthen text (escaping, HTML tags), so a part that
+// still does not fit is split again with a smaller budget.
+func splitRendered(source string, format pkg.ResponseFormat, limit int) []string {
+	budget := limit
+	for {
+		chunks := splitMarkdown(source, budget)
+		parts := make([]string, len(chunks))
+		fits := true
+		for i, c := range chunks {
+			parts[i] = renderMarkdown(c, format)
+			if utf8.RuneCountInString(parts[i]) > limit {
+				fits = false
+			}
+		}
+		if fits || budget < 64 {
+			return parts
+		}
+		budget = budget * 3 / 4
+	}
+}
+
+// splitMarkdown cuts text into chunks of at most limit runes, preferring
+// paragraph, then line, then word boundaries. A code block cut in two is
+// closed at the end of one chunk and reopened at the start of the next.
+func splitMarkdown(text string, limit int) []string {
+	var chunks []string
+	text = strings.TrimSpace(text)
+	for utf8.RuneCountInString(text) > limit {
+		window := limit
+		if strings.Contains(text, "```") {
+			window -= 4 // room to close a code block
+		}
+		end := runeOffset(text, window)
+		cut := end
+		if i := strings.LastIndex(text[:end], "\n\n"); i > end/2 {
+			cut = i
+		} else if i := strings.LastIndex(text[:end], "\n"); i > end/3 {
+			cut = i
+		} else if i := strings.LastIndex(text[:end], " "); i > end/3 {
+			cut = i
+		}
+		chunk, rest := strings.TrimRight(text[:cut], " \n"), strings.TrimLeft(text[cut:], " \n")
+		if fence, open := openFence(chunk); open {
+			chunk += "\n```"
+			rest = fence + "\n" + rest
+		}
+		chunks = append(chunks, chunk)
+		text = rest
+	}
+	return append(chunks, text)
+}
+
+// openFence reports whether text ends inside a fenced code block, and the
+// fence line (with its language) that opened it.
+func openFence(text string) (string, bool) {
+	fence, open := "", false
+	for _, line := range strings.Split(text, "\n") {
+		if t := strings.TrimSpace(line); strings.HasPrefix(t, "```") {
+			fence, open = t, !open
+		}
+	}
+	return fence, open
+}
+
+func runeOffset(s string, n int) int {
+	for i := range s {
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(s)
+}
+
+func copyMetadata(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+var (
+	mdHeading = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	mdBullet  = regexp.MustCompile(`^(\s*)[-*+]\s+`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	mdBold    = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	mdItalic  = []*regexp.Regexp{
+		regexp.MustCompile(`(^|[^\w*])\*([^*\s](?:[^*]*[^*\s])?)\*([^\w*]|$)`),
+		regexp.MustCompile(`(^|[^\w_])_([^_\s](?:[^_]*[^_\s])?)_([^\w_]|$)`),
+	}
+	mdStrike = regexp.MustCompile(`~~([^~]+)~~`)
+	mdCode   = regexp.MustCompile("`([^`]+)`")
+)
+
+// renderMarkdown converts the common Markdown the model writes (headings,
+// bullets, bold, italic, strikethrough, links, inline code, fenced code,
+// quotes) into a channel dialect: Slack mrkdwn, WhatsApp markup, Telegram
+// or generic HTML, or plain text. Formats that render Markdown themselves
+// (markdown, teams, discord, unset) are returned unchanged.
+func renderMarkdown(text string, format pkg.ResponseFormat) string {
+	switch format {
+	case pkg.FormatSlack, pkg.FormatWhatsApp, pkg.FormatTelegram, pkg.FormatHTML, pkg.FormatText:
+	default:
+		return text
+	}
+	htmlish := format == pkg.FormatTelegram || format == pkg.FormatHTML
+	var out []string
+	var code, quote []string
+	inCode := false
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out = append(out, "<blockquote>"+strings.Join(quote, "\n")+"</blockquote>")
+			quote = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inCode {
+				out = append(out, renderCodeBlock(code, format))
+				code = nil
+			} else {
+				flushQuote()
+			}
+			inCode = !inCode
+			continue
+		}
+		if inCode {
+			code = append(code, line)
+			continue
+		}
+		if htmlish {
+			if rest, ok := strings.CutPrefix(line, ">"); ok {
+				quote = append(quote, renderInline(strings.TrimPrefix(rest, " "), format))
+				continue
+			}
+			flushQuote()
+		}
+		out = append(out, renderLine(line, format))
+	}
+	flushQuote()
+	if inCode { // unterminated block: keep it as code
+		out = append(out, renderCodeBlock(code, format))
+	}
+	s := strings.Join(out, "\n")
+	if format == pkg.FormatHTML {
+		s = strings.ReplaceAll(s, "\n", "<br>\n")
+	}
+	return s
+}
+
+func renderCodeBlock(lines []string, format pkg.ResponseFormat) string {
+	body := strings.Join(lines, "\n")
+	switch format {
+	case pkg.FormatTelegram, pkg.FormatHTML:
+		return "<pre>" + html.EscapeString(body) + "</pre>"
+	case pkg.FormatText:
+		return body
+	case pkg.FormatSlack:
+		return "```\n" + slackEscape(body) + "\n```"
+	}
+	return "```\n" + body + "\n```"
+}
+
+func renderLine(line string, format pkg.ResponseFormat) string {
+	if m := mdHeading.FindStringSubmatch(line); m != nil {
+		title := renderInline(m[1], format)
+		switch format {
+		case pkg.FormatTelegram, pkg.FormatHTML:
+			return "<b>" + title + "</b>"
+		case pkg.FormatText:
+			return title
+		}
+		return "*" + title + "*"
+	}
+	if rest, ok := strings.CutPrefix(line, ">"); ok { // quotes outside HTML keep their marker
+		return ">" + renderInline(rest, format)
+	}
+	if m := mdBullet.FindStringSubmatch(line); m != nil {
+		return m[1] + "• " + renderInline(line[len(m[0]):], format)
+	}
+	return renderInline(line, format)
+}
+
+// renderInline converts inline markup outside code spans; code spans are
+// kept verbatim (escaped for HTML and Slack).
+func renderInline(line string, format pkg.ResponseFormat) string {
+	var sb strings.Builder
+	last := 0
+	for _, m := range mdCode.FindAllStringSubmatchIndex(line, -1) {
+		sb.WriteString(renderSpans(line[last:m[0]], format))
+		code := line[m[2]:m[3]]
+		switch format {
+		case pkg.FormatTelegram, pkg.FormatHTML:
+			sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+		case pkg.FormatText:
+			sb.WriteString(code)
+		case pkg.FormatSlack:
+			sb.WriteString("`" + slackEscape(code) + "`")
+		default:
+			sb.WriteString("`" + code + "`")
+		}
+		last = m[1]
+	}
+	sb.WriteString(renderSpans(line[last:], format))
+	return sb.String()
+}
+
+// Placeholders keep converted markers out of the later passes.
+const (
+	boldOpen, boldClose     = "\x00b\x00", "\x00/b\x00"
+	italicOpen, italicClose = "\x00i\x00", "\x00/i\x00"
+)
+
+func renderSpans(s string, format pkg.ResponseFormat) string {
+	if s == "" {
+		return s
+	}
+	switch format {
+	case pkg.FormatTelegram, pkg.FormatHTML:
+		s = html.EscapeString(s)
+	case pkg.FormatSlack:
+		s = slackEscape(s)
+	}
+	s = mdLink.ReplaceAllStringFunc(s, func(m string) string {
+		p := mdLink.FindStringSubmatch(m)
+		label, url := p[1], p[2]
+		switch format {
+		case pkg.FormatSlack:
+			return "<" + url + "|" + label + ">"
+		case pkg.FormatTelegram, pkg.FormatHTML:
+			return `<a href="` + url + `">` + label + "</a>"
+		}
+		if label == url {
+			return url
+		}
+		return label + " (" + url + ")"
+	})
+	s = mdBold.ReplaceAllStringFunc(s, func(m string) string {
+		p := mdBold.FindStringSubmatch(m)
+		return boldOpen + p[1] + p[2] + boldClose
+	})
+	var bold, italic, strike [2]string
+	switch format {
+	case pkg.FormatTelegram, pkg.FormatHTML:
+		bold, italic, strike = [2]string{"<b>", "</b>"}, [2]string{"<i>", "</i>"}, [2]string{"<s>", "</s>"}
+	case pkg.FormatText:
+	default: // Slack, WhatsApp
+		bold, italic, strike = [2]string{"*", "*"}, [2]string{"_", "_"}, [2]string{"~", "~"}
+	}
+	for _, re := range mdItalic {
+		// Twice: adjacent spans share the boundary character the regexp consumes.
+		for range 2 {
+			s = re.ReplaceAllString(s, "${1}"+italicOpen+"${2}"+italicClose+"${3}")
+		}
+	}
+	s = mdStrike.ReplaceAllString(s, strike[0]+"${1}"+strike[1])
+	return strings.NewReplacer(boldOpen, bold[0], boldClose, bold[1], italicOpen, italic[0], italicClose, italic[1]).Replace(s)
+}
+
+// slackEscape escapes the three characters Slack treats as control
+// sequences in message text.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
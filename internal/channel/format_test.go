@@ -0,0 +1,139 @@
+package channel
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	md := "## Plan\n- **Book** the _flight_ via [portal](https://x.io/a?b=1&c=2)\n- ~~hotel~~ `a<b`\n> note\n```go\nif a < b {}\n```"
+	tests := map[pkg.ResponseFormat]string{
+		pkg.FormatSlack:    "*Plan*\n• *Book* the _flight_ via <https://x.io/a?b=1&amp;c=2|portal>\n• ~hotel~ `a&lt;b`\n> note\n```\nif a &lt; b {}\n```",
+		pkg.FormatTelegram: "<b>Plan</b>\n• <b>Book</b> the <i>flight</i> via <a href=\"https://x.io/a?b=1&amp;c=2\">portal</a>\n• <s>hotel</s> <code>a&lt;b</code>\n<blockquote>note</blockquote>\n<pre>if a &lt; b {}</pre>",
+		pkg.FormatText:     "Plan\n• Book the flight via portal (https://x.io/a?b=1&c=2)\n• hotel a<b\n> note\nif a < b {}",
+		pkg.FormatWhatsApp: "*Plan*\n• *Book* the _flight_ via portal (https://x.io/a?b=1&c=2)\n• ~hotel~ `a<b`\n> note\n```\nif a < b {}\n```",
+		pkg.FormatDiscord:  md,
+		"":                 md,
+	}
+	for format, want := range tests {
+		if got := renderMarkdown(md, format); got != want {
+			t.Errorf("%q:\ngot  %q\nwant %q", format, got, want)
+		}
+	}
+}
+
+func TestRenderMarkdown_LeavesSnakeCaseAlone(t *testing.T) {
+	if got := renderMarkdown("set max_tokens and *a* *b*", pkg.FormatSlack); got != "set max_tokens and _a_ _b_" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestFormatReply_SplitsAtParagraphsAndKeepsThread(t *testing.T) {
+	para := strings.Repeat("word ", 15) // 75 runes
+	msg := pkg.OutboundMessage{
+		ConversationID: "c1", ThreadID: "t1", Content: para + "\n\n" + para + "\n\n" + para,
+		Metadata: map[string]string{"k": "v"},
+		Files:    []pkg.FileAttachment{{Name: "reply.ogg"}},
+	}
+	parts := formatReply(msg, pkg.Capabilities{MaxMessageLength: 160}, Formatting{})
+	if len(parts) != 2 {
+		t.Fatalf("parts = %d; want 2", len(parts))
+	}
+	for i, p := range parts {
+		if utf8.RuneCountInString(p.Content) > 160 || p.ThreadID != "t1" || p.ConversationID != "c1" || p.Metadata["k"] != "v" {
+			t.Errorf("part %d = %+v", i, p)
+		}
+	}
+	if len(parts[0].Files) != 0 || len(parts[1].Files) != 1 {
+		t.Errorf("files should ride on the last part: %+v", parts)
+	}
+}
+
+func TestFormatReply_ReopensCodeBlock(t *testing.T) {
+	code := "```python\n" + strings.Repeat("print(1)\n", 20) + "```"
+	parts := formatReply(pkg.OutboundMessage{Content: code}, pkg.Capabilities{MaxMessageLength: 100}, Formatting{})
+	if len(parts) < 2 {
+		t.Fatalf("parts = %d", len(parts))
+	}
+	for i, p := range parts {
+		if strings.Count(p.Content, "```")%2 != 0 {
+			t.Errorf("part %d has an unbalanced fence:\n%s", i, p.Content)
+		}
+		if i > 0 && !strings.HasPrefix(p.Content, "```python\n") {
+			t.Errorf("part %d does not reopen the block:\n%s", i, p.Content)
+		}
+	}
+}
+
+func TestFormatReply_FileOverflow(t *testing.T) {
+	long := strings.Repeat("**line**\n", 100)
+	caps := pkg.Capabilities{MaxMessageLength: 100, Files: true, ResponseFormat: pkg.FormatText}
+
+	// Too many parts for a split: the whole reply becomes a file.
+	parts := formatReply(pkg.OutboundMessage{Content: long}, caps, Formatting{Convert: true})
+	if len(parts) != 1 || len(parts[0].Files) != 1 {
+		t.Fatalf("parts = %+v; want one message with a file", parts)
+	}
+	f := parts[0].Files[0]
+	if f.Name != "reply.txt" || strings.Contains(string(f.Data), "**") || f.Size != int64(len(f.Data)) {
+		t.Errorf("file = %s %q", f.Name, f.Data)
+	}
+	if c := parts[0].Content; utf8.RuneCountInString(c) > 100 || !strings.HasSuffix(c, "(Full reply attached as reply.txt.)") {
+		t.Errorf("content = %q", c)
+	}
+
+	// Without the files capability it is split however long it gets.
+	caps.Files = false
+	if parts := formatReply(pkg.OutboundMessage{Content: long}, caps, Formatting{Overflow: OverflowFile}); len(parts) <= defaultMaxParts {
+		t.Errorf("parts = %d; want a split", len(parts))
+	}
+}
+
+func TestRegistryFormatsReplies(t *testing.T) {
+	var seen pkg.Capabilities
+	handler := func(ctx context.Context, _ string, msg pkg.InboundMessage) (pkg.OutboundMessage, error) {
+		seen = pkg.CapabilitiesFromContext(ctx)
+		return pkg.OutboundMessage{ConversationID: msg.ConversationID, ThreadID: msg.ThreadID, Content: "**done** and " + strings.Repeat("x", 30)}, nil
+	}
+	reg := NewRegistry(handler)
+	reg.SetDebounceWindow(0)
+	defer reg.StopAll()
+	reg.SetChannelFormatting("tg", Formatting{Convert: true})
+	ch := &mockChannel{id: "tg", caps: pkg.Capabilities{ID: "tg", ResponseFormat: pkg.FormatTelegram, ResponseFormatPrompt: "use HTML", MaxMessageLength: 30}}
+	_ = reg.Register(ch)
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "c1", ThreadID: "t9", Content: "hi"})
+
+	deadline := time.After(2 * time.Second)
+	for len(ch.sentMessages()) < 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("sent = %+v; want the reply in two parts", ch.sentMessages())
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	sent := ch.sentMessages()
+	if sent[0].Content != "<b>done</b> and" || sent[1].ThreadID != "t9" {
+		t.Errorf("sent = %+v", sent)
+	}
+	if seen.ResponseFormat != pkg.FormatMarkdown || seen.ResponseFormatPrompt != "" {
+		t.Errorf("handler saw %q / %q; want plain Markdown requested", seen.ResponseFormat, seen.ResponseFormatPrompt)
+	}
+}
+
+func TestRegistrySetChannelFormattingAfterRegisterPanics(t *testing.T) {
+	reg := NewRegistry(echoHandler)
+	defer reg.StopAll()
+	_ = reg.Register(newMockChannel("slack"))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	reg.SetChannelFormatting("slack", Formatting{Convert: true})
+}
@@ -0,0 +1,90 @@
+package channel
+
+import (
+	"strings"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// GroupPolicy decides how the handler treats messages from group
+// conversations (pkg.ChatTypeMetadataKey = pkg.ChatTypeGroup) on one
+// channel. The zero value answers every message in one shared session per
+// conversation (or thread), as for a direct chat.
+type GroupPolicy struct {
+	// RequireMention drops group messages that do not address the bot.
+	// Control frames and button confirmations always go through.
+	RequireMention bool
+	// MentionNames are the names ("opentalon", "bot") that count as an
+	// @mention in the text when the channel does not set
+	// pkg.MentionedMetadataKey itself. Matched case-insensitively after "@".
+	MentionNames []string
+	// PerSender gives every member of a group conversation their own
+	// session, so one person's history and pending confirmations are never
+	// another's context.
+	PerSender bool
+}
+
+// isGroupMessage reports whether msg comes from a group conversation.
+func isGroupMessage(msg pkg.InboundMessage) bool {
+	return msg.Metadata[pkg.ChatTypeMetadataKey] == pkg.ChatTypeGroup
+}
+
+// mentioned reports whether a group message addresses the bot: the
+// channel says so, or the text @mentions one of the policy's names.
+func (p GroupPolicy) mentioned(msg pkg.InboundMessage) bool {
+	if msg.Metadata[pkg.MentionedMetadataKey] == "true" {
+		return true
+	}
+	text := strings.ToLower(msg.Content)
+	for _, name := range p.MentionNames {
+		if name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@")); name != "" && hasMention(text, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMention reports whether text (lowercased) contains "@name" followed by
+// a non-name character or the end, so "@bot" does not match "@bottom".
+func hasMention(text, name string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], "@"+name)
+		if j < 0 {
+			return false
+		}
+		end := i + j + 1 + len(name)
+		if end == len(text) || !isNameByte(text[end]) {
+			return true
+		}
+		i = end
+	}
+}
+
+func isNameByte(b byte) bool {
+	return b == '_' || b == '-' || b >= 'a' && b <= 'z' || b >= '0' && b <= '9' || b >= 0x80
+}
+
+// ignoreGroupMessage reports whether the policy drops msg unanswered.
+func (p GroupPolicy) ignoreGroupMessage(msg pkg.InboundMessage) bool {
+	if !p.RequireMention || !isGroupMessage(msg) {
+		return false
+	}
+	if msg.Metadata[pkg.ControlMetadataKey] != "" || msg.Metadata["confirmation"] != "" {
+		return false
+	}
+	return !p.mentioned(msg)
+}
+
+// senderSessionKey moves a group message into its sender's own session:
+// "<channel>:<conversation>:@<sender>", with ":<thread>" kept last so a
+// thread still links to the sender's conversation session.
+func (p GroupPolicy) senderSessionKey(sessionKey string, msg pkg.InboundMessage) string {
+	if !p.PerSender || !isGroupMessage(msg) || msg.SenderID == "" {
+		return sessionKey
+	}
+	if msg.ThreadID == "" {
+		return sessionKey + ":@" + msg.SenderID
+	}
+	base := strings.TrimSuffix(sessionKey, ":"+msg.ThreadID)
+	return base + ":@" + msg.SenderID + ":" + msg.ThreadID
+}
@@ -0,0 +1,78 @@
+package channel
+
+import (
+	"context"
+	"testing"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func groupMsg(sender, content string, meta map[string]string) pkg.InboundMessage {
+	m := map[string]string{pkg.ChatTypeMetadataKey: pkg.ChatTypeGroup}
+	for k, v := range meta {
+		m[k] = v
+	}
+	return pkg.InboundMessage{ChannelID: "slack", ConversationID: "C1", SenderID: sender, Content: content, Metadata: m}
+}
+
+func TestGroupPolicy_Mentioned(t *testing.T) {
+	p := GroupPolicy{MentionNames: []string{"@OpenTalon", "bot"}}
+	for content, want := range map[string]bool{
+		"@opentalon what's up?":    true,
+		"hey @Bot, deploy it":      true,
+		"ask @bot.":                true,
+		"look at the @bottom":      false,
+		"opentalon without the at": false,
+		"":                         false,
+	} {
+		if got := p.mentioned(groupMsg("U1", content, nil)); got != want {
+			t.Errorf("mentioned(%q) = %v, want %v", content, got, want)
+		}
+	}
+	if !p.mentioned(groupMsg("U1", "reply in thread", map[string]string{pkg.MentionedMetadataKey: "true"})) {
+		t.Error("the channel's mentioned flag should count")
+	}
+}
+
+func TestGroupPolicy_SenderSessionKey(t *testing.T) {
+	p := GroupPolicy{PerSender: true}
+	if got := p.senderSessionKey("slack:C1", groupMsg("U1", "hi", nil)); got != "slack:C1:@U1" {
+		t.Errorf("key = %q", got)
+	}
+	threaded := groupMsg("U1", "hi", nil)
+	threaded.ThreadID = "170.1"
+	if got := p.senderSessionKey("slack:C1:170.1", threaded); got != "slack:C1:@U1:170.1" {
+		t.Errorf("threaded key = %q", got)
+	}
+	direct := pkg.InboundMessage{ChannelID: "slack", ConversationID: "D1", SenderID: "U1"}
+	if got := p.senderSessionKey("slack:D1", direct); got != "slack:D1" {
+		t.Errorf("direct chats keep their key, got %q", got)
+	}
+}
+
+func TestHandler_GroupPolicy(t *testing.T) {
+	rec := &sessionRecorder{}
+	cfg := baseHandlerConfig()
+	cfg.CreateSession = rec.createFunc()
+	cfg.GroupPolicies = map[string]GroupPolicy{"slack": {RequireMention: true, MentionNames: []string{"bot"}, PerSender: true}}
+	h := NewMessageHandler(cfg)
+
+	out, _ := h(context.Background(), "slack:C1", groupMsg("U1", "lunch anyone?", nil))
+	if out.Content != "" || len(rec.creates) != 0 {
+		t.Fatalf("unmentioned group message answered: %+v (creates %v)", out, rec.creates)
+	}
+	out, _ = h(context.Background(), "slack:C1", groupMsg("U1", "@bot status?", nil))
+	if out.Content != "echo: @bot status?" {
+		t.Errorf("mentioned reply = %q", out.Content)
+	}
+	_, _ = h(context.Background(), "slack:C1", groupMsg("U2", "yes", map[string]string{"confirmation": "approve"}))
+	if want := []string{"slack:C1:@U1", "slack:C1:@U2"}; len(rec.creates) != 2 || rec.creates[0] != want[0] || rec.creates[1] != want[1] {
+		t.Errorf("sessions = %v, want %v", rec.creates, want)
+	}
+
+	// Direct messages and other channels are untouched.
+	out = callHandler(h, nil)
+	if out.Content != "echo: hello" {
+		t.Errorf("direct message reply = %q", out.Content)
+	}
+}
@@ -49,6 +49,29 @@ type HandlerConfig struct {
 	// ok=false means nothing is pending. Read-only — it MUST NOT consume or
 	// mutate pending state. nil disables confirmation re-emit on resume.
 	PendingConfirmation func(sessionKey string) (content string, metadata map[string]string, ok bool)
+	// LinkSession links a freshly created thread session to the session of
+	// the conversation it belongs to, on channels declaring LinkThreads. It
+	// must keep an existing link (a manual /link wins). nil disables
+	// automatic thread linking.
+	LinkSession func(child, parent string) error
+	// Speaker renders replies to audio on channels declaring Voice. nil
+	// disables spoken replies.
+	Speaker Speaker
+	// SpeakAlways speaks every reply on a voice channel; by default only
+	// replies to a voice message are spoken.
+	SpeakAlways bool
+	// GroupPolicies is the group-conversation policy per channel id
+	// (mention gating, per-sender sessions). Channels missing from it treat
+	// group messages like direct ones.
+	GroupPolicies map[string]GroupPolicy
+	// Commands answers built-in slash commands (/help, /reset, ...) before
+	// any content preparer or LLM turn. nil disables them.
+	Commands *CommandRouter
+}
+
+// Speaker is the subset of provider.OpenAISpeaker used by the handler.
+type Speaker interface {
+	Synthesize(ctx context.Context, text string) (audio []byte, mimeType string, err error)
 }
 
 // NewMessageHandler returns a MessageHandler that: ensures session, verifies profile token (if
@@ -72,6 +95,17 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 	return func(ctx context.Context, sessionKey string, msg pkg.InboundMessage) (pkg.OutboundMessage, error) {
 		var entityID, groupID string
 
+		// Group conversations: chatter that does not address the bot gets no
+		// reply (an empty frame the registry drops) and costs no identity
+		// lookup; with per-sender sessions each member talks to the bot in
+		// their own session.
+		group := cfg.GroupPolicies[msg.ChannelID]
+		if group.ignoreGroupMessage(msg) {
+			slog.Debug("group message without mention ignored", "channel", msg.ChannelID, "conversation", msg.ConversationID)
+			return pkg.OutboundMessage{}, nil
+		}
+		sessionKey = group.senderSessionKey(sessionKey, msg)
+
 		// Inbound enrichment is fail-closed: if the channel adapter
 		// couldn't fetch the data the WhoAmI server (or LLM) is going
 		// to need, refuse the request rather than serve a half-known
@@ -89,6 +123,13 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 		// profile may override it below (a system invocation sets "system").
 		interactionKind := profile.KindChat
 
+		// A built-in command runs no LLM turn, so it is exempt from the token
+		// limit below: /usage must still answer once the budget is spent.
+		command, commandArgs, isCommand := cfg.Commands.match(msg.Content)
+		if msg.Metadata["confirmation"] != "" {
+			isCommand = false
+		}
+
 		// Profile verification: required when verifier is configured.
 		if cfg.Verifier != nil {
 			token := msg.Metadata["profile_token"]
@@ -125,7 +166,7 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 			// sums chat runs), so a job-completion note is never blocked by, and
 			// never counts against, the customer's interactive budget.
 			if cfg.LimitChecker != nil && p.Limit > 0 && p.LimitWindow > 0 &&
-				p.Kind != profile.KindSystem && msg.Metadata[pkg.ControlMetadataKey] == "" {
+				p.Kind != profile.KindSystem && msg.Metadata[pkg.ControlMetadataKey] == "" && !isCommand {
 				since := time.Now().Add(-p.LimitWindow)
 				used, lerr := cfg.LimitChecker.TotalTokensSince(ctx, p.EntityID, since)
 				if lerr != nil {
@@ -152,6 +193,12 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 		// else creating deferred work) can deliver results back to this chat.
 		ctx = actor.WithConversationID(ctx, msg.ConversationID)
 
+		// Identify the message itself when the channel can, so tool calls
+		// the turn makes get idempotency keys that survive a redelivery.
+		if msg.MessageID != "" {
+			ctx = actor.WithMessageKey(ctx, msg.ChannelID+":"+msg.ConversationID+":"+msg.MessageID)
+		}
+
 		// Carry the resolved group (account) id so emitted session events can be
 		// scoped per-account by an out-of-process consumer. No-op without a
 		// profile system (groupID stays empty).
@@ -199,6 +246,14 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 			}
 		} else {
 			cfg.CreateSession(sessionKey, entityID, groupID, interactionKind)
+			if msg.ThreadID != "" && cfg.LinkSession != nil && pkg.CapabilitiesFromContext(ctx).LinkThreads {
+				// The thread's key is the conversation's key plus ":<thread>",
+				// entity prefix included, so the parent stays in the same scope.
+				parentKey := strings.TrimSuffix(sessionKey, ":"+msg.ThreadID)
+				if err := cfg.LinkSession(sessionKey, parentKey); err != nil {
+					slog.Debug("thread not linked to its conversation", "session", sessionKey, "parent", parentKey, "error", err)
+				}
+			}
 		}
 
 		// Resume handshake: a reconnecting client sends one control frame right
@@ -225,6 +280,10 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 			return pkg.OutboundMessage{}, nil
 		}
 
+		if isCommand {
+			return cfg.Commands.run(ctx, command, CommandCall{SessionKey: sessionKey, Args: commandArgs, Message: msg}), nil
+		}
+
 		content := msg.Content
 		// Content preparers register by channel KIND ("slack", "console")
 		// not by instance. Two Slack bots in one process share the same
@@ -252,13 +311,46 @@ func NewMessageHandler(cfg HandlerConfig) pkg.MessageHandler {
 			}
 			outMeta[k] = v
 		}
-		return pkg.OutboundMessage{
+		out := pkg.OutboundMessage{
 			ConversationID: msg.ConversationID,
 			ThreadID:       msg.ThreadID,
 			Content:        outContent,
 			Metadata:       outMeta,
-		}, nil
+		}
+		if response != "" && cfg.Speaker != nil && pkg.CapabilitiesFromContext(ctx).Voice &&
+			(cfg.SpeakAlways || hasAudio(msg.Files)) {
+			// A reply that cannot be spoken still goes out as text.
+			if voice, ok := speak(ctx, cfg.Speaker, response); ok {
+				out.Files = append(out.Files, voice)
+			}
+		}
+		return out, nil
+	}
+}
+
+// speak synthesizes text into a voice-note attachment.
+func speak(ctx context.Context, s Speaker, text string) (pkg.FileAttachment, bool) {
+	audio, mimeType, err := s.Synthesize(ctx, text)
+	if err != nil {
+		logger.FromContext(ctx).Warn("reply not spoken; sending text only", "error", err)
+		return pkg.FileAttachment{}, false
+	}
+	return pkg.FileAttachment{
+		Name:     "reply" + provider.AudioExtension(mimeType),
+		MimeType: mimeType,
+		Data:     audio,
+		Size:     int64(len(audio)),
+	}, true
+}
+
+// hasAudio reports whether the inbound message carried a voice note.
+func hasAudio(files []pkg.FileAttachment) bool {
+	for _, f := range files {
+		if strings.HasPrefix(strings.ToLower(f.MimeType), "audio/") {
+			return true
+		}
 	}
+	return false
 }
 
 // kindOf returns the channel TYPE for a message. Prefers msg.Kind (set by
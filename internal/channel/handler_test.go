@@ -402,6 +402,41 @@ func TestHandler_ResumeIntentMissing_CallsCreateNotResume(t *testing.T) {
 	}
 }
 
+func TestHandler_LinksNewThreadToConversation(t *testing.T) {
+	type link struct{ child, parent string }
+	var links []link
+	cfg := baseHandlerConfig()
+	cfg.Verifier = &stubVerifier{p: &profile.Profile{EntityID: "ent1"}}
+	cfg.LinkSession = func(child, parent string) error {
+		links = append(links, link{child, parent})
+		return nil
+	}
+	h := NewMessageHandler(cfg)
+	msg := pkg.InboundMessage{
+		ChannelID: "slack", ConversationID: "C1", ThreadID: "170.1", Content: "hi",
+		Metadata: map[string]string{"profile_token": "tok"},
+	}
+
+	// Without the capability flag nothing is linked.
+	_, _ = h(context.Background(), "slack:C1:170.1", msg)
+	if len(links) != 0 {
+		t.Fatalf("linked without link_threads capability: %v", links)
+	}
+
+	ctx := pkg.WithCapabilities(context.Background(), pkg.Capabilities{Threads: true, LinkThreads: true})
+	_, _ = h(ctx, "slack:C1:170.1", msg)
+	if len(links) != 1 || links[0] != (link{"ent1:slack:C1:170.1", "ent1:slack:C1"}) {
+		t.Errorf("links = %v; want thread linked to its entity-scoped conversation", links)
+	}
+
+	// A top-level message is not a thread and is never linked.
+	msg.ThreadID = ""
+	_, _ = h(ctx, "slack:C1", msg)
+	if len(links) != 1 {
+		t.Errorf("top-level message linked: %v", links)
+	}
+}
+
 func TestHandler_ResumeIntentTrue_ResumeFails_NotFound_EmitsSessionExpired(t *testing.T) {
 	// The whole point of the refactor: stale conv-id must surface as a
 	// typed error frame so the client can clear its storage and reconnect
@@ -656,3 +691,63 @@ func TestHandler_NewMessageHandler_PanicsOnNilRunner(t *testing.T) {
 		Runner:        nil,
 	})
 }
+
+// stubSpeaker returns fixed audio, or err.
+type stubSpeaker struct {
+	err   error
+	texts []string
+}
+
+func (s *stubSpeaker) Synthesize(_ context.Context, text string) ([]byte, string, error) {
+	s.texts = append(s.texts, text)
+	if s.err != nil {
+		return nil, "", s.err
+	}
+	return []byte("ogg"), "audio/ogg", nil
+}
+
+func TestHandler_SpeaksReplyToVoiceNote(t *testing.T) {
+	sp := &stubSpeaker{}
+	cfg := baseHandlerConfig()
+	cfg.Speaker = sp
+	h := NewMessageHandler(cfg)
+	voice := pkg.InboundMessage{
+		ChannelID: "telegram", ConversationID: "c1", Content: "",
+		Files: []pkg.FileAttachment{{Name: "note.ogg", MimeType: "audio/ogg", Data: []byte("x")}},
+	}
+	text := pkg.InboundMessage{ChannelID: "telegram", ConversationID: "c1", Content: "hi"}
+
+	// Channels without the voice capability never get audio.
+	if out, _ := h(context.Background(), "telegram:c1", voice); len(out.Files) != 0 {
+		t.Fatalf("audio sent to a channel without voice: %+v", out.Files)
+	}
+
+	ctx := pkg.WithCapabilities(context.Background(), pkg.Capabilities{Voice: true})
+	out, _ := h(ctx, "telegram:c1", voice)
+	if len(out.Files) != 1 || out.Files[0].Name != "reply.ogg" || out.Files[0].MimeType != "audio/ogg" || out.Files[0].Size != 3 {
+		t.Fatalf("files = %+v; want one spoken reply", out.Files)
+	}
+	if out.Content != "echo: " || len(sp.texts) != 1 || sp.texts[0] != "echo: " {
+		t.Errorf("content %q, spoken %q; the text reply must still be sent and be what is spoken", out.Content, sp.texts)
+	}
+
+	// A typed message gets a typed reply unless SpeakAlways is set.
+	if out, _ := h(ctx, "telegram:c1", text); len(out.Files) != 0 {
+		t.Errorf("typed message got a spoken reply")
+	}
+	cfg.SpeakAlways = true
+	if out, _ := NewMessageHandler(cfg)(ctx, "telegram:c1", text); len(out.Files) != 1 {
+		t.Errorf("SpeakAlways: files = %+v", out.Files)
+	}
+}
+
+func TestHandler_SpeakerFailureSendsText(t *testing.T) {
+	cfg := baseHandlerConfig()
+	cfg.Speaker = &stubSpeaker{err: provider.ErrSpeechTooLong}
+	cfg.SpeakAlways = true
+	ctx := pkg.WithCapabilities(context.Background(), pkg.Capabilities{Voice: true})
+	out, err := NewMessageHandler(cfg)(ctx, "telegram:c1", pkg.InboundMessage{ChannelID: "telegram", ConversationID: "c1", Content: "hi"})
+	if err != nil || out.Content != "echo: hi" || len(out.Files) != 0 {
+		t.Errorf("out = %+v, err = %v; want the text reply alone", out, err)
+	}
+}
@@ -158,6 +158,7 @@ func (m *Manager) registerChannelTools(channelID string, tools []pkg.ToolDefinit
 				Name:        p.Name,
 				Description: p.Description,
 				Required:    p.Required,
+				Type:        p.Type,
 			}
 		}
 		grouped[pluginName] = append(grouped[pluginName], requestpkg.Package{
@@ -0,0 +1,276 @@
+package channel
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/opentalon/opentalon/internal/logger"
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// QueueOverflow is what a channel's full dispatch queue does with one more
+// message.
+type QueueOverflow string
+
+const (
+	// QueueWait holds the message until a slot frees up. Nothing is lost;
+	// the channel's inbox fills behind it, which pushes back on the channel
+	// (plugins built on pkg/channel block in Deliver).
+	QueueWait QueueOverflow = "wait"
+	// QueueReject drops the message and tells the sender to try again.
+	QueueReject QueueOverflow = "reject"
+	// QueueCoalesce folds the message into one already queued for the same
+	// conversation and sender, so a burst becomes one turn; with nothing to
+	// fold into it is rejected.
+	QueueCoalesce QueueOverflow = "coalesce"
+)
+
+// Queue defaults for channels without a policy. The old dispatcher ran one
+// goroutine per message; these bound a burst while leaving normal traffic
+// (which the orchestrator's max_concurrent_sessions throttles anyway) alone.
+const (
+	defaultQueueSize    = 64
+	defaultQueueWorkers = 16
+	defaultBusyReply    = "I'm handling too many messages right now. Please try again in a moment."
+)
+
+// QueuePolicy bounds the turns one channel can have waiting and running.
+type QueuePolicy struct {
+	Size      int           // messages waiting for a worker; 0 = 64
+	Workers   int           // messages handled at once; 0 = 16
+	Overflow  QueueOverflow // "" = QueueWait
+	BusyReply string        // sent for a rejected message; "" = a short English notice
+	// Merge hands a worker every message its sender queued while the
+	// conversation's previous turn ran, merged into one turn, instead of one
+	// turn per message.
+	Merge bool
+}
+
+func (p QueuePolicy) withDefaults() QueuePolicy {
+	if p.Size <= 0 {
+		p.Size = defaultQueueSize
+	}
+	if p.Workers <= 0 {
+		p.Workers = defaultQueueWorkers
+	}
+	if p.Overflow == "" {
+		p.Overflow = QueueWait
+	}
+	if p.BusyReply == "" {
+		p.BusyReply = defaultBusyReply
+	}
+	return p
+}
+
+// QueueObserver receives dispatch queue activity, e.g. for Prometheus.
+// Implementations must be safe for concurrent use.
+type QueueObserver interface {
+	// ObserveQueueDepth reports the number of messages waiting for a
+	// worker on a channel after each change.
+	ObserveQueueDepth(channel string, depth int)
+	// ObserveQueueOverflow counts a message that found the queue full;
+	// action is QueueReject or QueueCoalesce (waits are not counted).
+	ObserveQueueOverflow(channel string, action QueueOverflow)
+}
+
+// SetChannelQueue sets the dispatch queue policy for one channel instance
+// (its config key). Must be called before that channel is registered.
+func (r *Registry) SetChannelQueue(channelID string, p QueuePolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[channelID]; exists {
+		panic("channel: SetChannelQueue called after channel registered")
+	}
+	if r.queues == nil {
+		r.queues = make(map[string]QueuePolicy)
+	}
+	r.queues[channelID] = p
+}
+
+// SetQueueObserver reports queue depth and overflows to o.
+// Must be called before any channels are registered.
+func (r *Registry) SetQueueObserver(o QueueObserver) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.channels) > 0 {
+		panic("channel: SetQueueObserver called after channels registered")
+	}
+	r.queueObserver = o
+}
+
+func (r *Registry) queuePolicy(channelID string) (QueuePolicy, QueueObserver) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.queues[channelID].withDefaults(), r.queueObserver
+}
+
+// queued is a message waiting for a worker, with the conversation it is
+// ordered in.
+type queued struct {
+	conversation string
+	msg          pkg.InboundMessage
+}
+
+// dispatchQueue is the bounded queue between a channel's dispatcher and its
+// workers. It is FIFO per conversation: a worker takes the oldest message
+// of a conversation no other worker is handling, so the turns of one
+// conversation run one after another, in arrival order, while different
+// conversations run in parallel.
+type dispatchQueue struct {
+	channel  string
+	policy   QueuePolicy
+	observer QueueObserver
+
+	mu     sync.Mutex
+	cond   *sync.Cond // signalled when an item is added or removed, a conversation is released, or the queue closes
+	items  []queued
+	active map[string]bool // conversations a worker is handling
+	closed bool
+}
+
+func newDispatchQueue(channel string, p QueuePolicy, o QueueObserver) *dispatchQueue {
+	q := &dispatchQueue{channel: channel, policy: p, observer: o, active: make(map[string]bool)}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push queues a message, applying the overflow policy when the queue is
+// full. It returns false when the message was not queued (rejected, or the
+// queue closed while waiting); a coalesced message counts as queued.
+// Control messages — confirmations, typing, resume handshakes — are always
+// queued, even past the limit: dropping or merging them would strand a turn
+// the user is already in.
+func (q *dispatchQueue) push(msg pkg.InboundMessage) bool {
+	conversation := pkg.SessionKey(q.channel, msg.ConversationID, msg.ThreadID)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.items) >= q.policy.Size && !isControlMessage(msg) {
+		switch q.policy.Overflow {
+		case QueueCoalesce:
+			if q.coalesce(conversation, msg) {
+				q.overflow(QueueCoalesce)
+				return true
+			}
+			q.overflow(QueueReject)
+			return false
+		case QueueReject:
+			q.overflow(QueueReject)
+			return false
+		default:
+			for len(q.items) >= q.policy.Size && !q.closed {
+				q.cond.Wait()
+			}
+		}
+	}
+	if q.closed {
+		return false
+	}
+	q.items = append(q.items, queued{conversation: conversation, msg: msg})
+	q.observeDepth()
+	q.cond.Broadcast()
+	return true
+}
+
+// coalesce merges msg into the newest queued message of the same
+// conversation when that message is from the same sender. Caller holds q.mu.
+func (q *dispatchQueue) coalesce(conversation string, msg pkg.InboundMessage) bool {
+	for i, it := range slices.Backward(q.items) {
+		if it.conversation != conversation {
+			continue
+		}
+		if isControlMessage(it.msg) || it.msg.SenderID != msg.SenderID {
+			return false
+		}
+		q.items[i].msg = mergeMessages([]pkg.InboundMessage{it.msg, msg})
+		return true
+	}
+	return false
+}
+
+// pop blocks until a message of an idle conversation is queued, or the
+// queue is closed; ok is false once closed. The conversation stays taken
+// until release. With Merge, the sender's further chat messages queued
+// right behind it are merged into the one returned.
+func (q *dispatchQueue) pop() (queued, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for {
+		if q.closed {
+			return queued{}, false
+		}
+		i := slices.IndexFunc(q.items, func(it queued) bool { return !q.active[it.conversation] })
+		if i < 0 {
+			q.cond.Wait()
+			continue
+		}
+		it := q.items[i]
+		taken := []int{i}
+		if q.policy.Merge && !isControlMessage(it.msg) {
+			batch := []pkg.InboundMessage{it.msg}
+			for j := i + 1; j < len(q.items); j++ {
+				next := q.items[j]
+				if next.conversation != it.conversation {
+					continue
+				}
+				if isControlMessage(next.msg) || next.msg.SenderID != it.msg.SenderID {
+					break
+				}
+				batch = append(batch, next.msg)
+				taken = append(taken, j)
+			}
+			it.msg = mergeMessages(batch)
+		}
+		for _, j := range slices.Backward(taken) {
+			q.items = slices.Delete(q.items, j, j+1)
+		}
+		q.active[it.conversation] = true
+		q.observeDepth()
+		q.cond.Broadcast()
+		return it, true
+	}
+}
+
+// release lets workers take the conversation's next message.
+func (q *dispatchQueue) release(conversation string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.active, conversation)
+	q.cond.Broadcast()
+}
+
+// close wakes every waiter; queued messages are dropped, as the registry is
+// shutting down.
+func (q *dispatchQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.items = nil
+	q.cond.Broadcast()
+}
+
+func (q *dispatchQueue) observeDepth() {
+	if q.observer != nil {
+		q.observer.ObserveQueueDepth(q.channel, len(q.items))
+	}
+}
+
+func (q *dispatchQueue) overflow(action QueueOverflow) {
+	if q.observer != nil {
+		q.observer.ObserveQueueOverflow(q.channel, action)
+	}
+}
+
+// sendBusy tells the sender of a rejected message to try again later. It is
+// a plain Send, not a delivery: a notice that cannot go out is not worth the
+// outbox.
+func sendBusy(ctx context.Context, ch pkg.Channel, m pkg.InboundMessage, text string) {
+	msg := pkg.OutboundMessage{
+		ConversationID: m.ConversationID,
+		ThreadID:       m.ThreadID,
+		Content:        text,
+	}
+	if err := ch.Send(ctx, msg); err != nil {
+		logger.FromContext(ctx).Debug("busy reply send failed", "channel", ch.ID(), "error", err)
+	}
+}
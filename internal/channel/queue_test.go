@@ -0,0 +1,278 @@
+package channel
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+type fakeQueueObserver struct {
+	mu        sync.Mutex
+	maxDepth  int
+	overflows map[QueueOverflow]int
+}
+
+func (o *fakeQueueObserver) ObserveQueueDepth(_ string, depth int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.maxDepth = max(o.maxDepth, depth)
+}
+
+func (o *fakeQueueObserver) ObserveQueueOverflow(_ string, action QueueOverflow) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.overflows == nil {
+		o.overflows = make(map[QueueOverflow]int)
+	}
+	o.overflows[action]++
+}
+
+// blockingHandler holds every message until release is closed and records
+// what it handled.
+type blockingHandler struct {
+	release  chan struct{}
+	inflight atomic.Int32
+	peak     atomic.Int32
+
+	mu      sync.Mutex
+	handled []string
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{release: make(chan struct{})}
+}
+
+func (h *blockingHandler) handle(ctx context.Context, _ string, msg pkg.InboundMessage) (pkg.OutboundMessage, error) {
+	cur := h.inflight.Add(1)
+	defer h.inflight.Add(-1)
+	for {
+		old := h.peak.Load()
+		if cur <= old || h.peak.CompareAndSwap(old, cur) {
+			break
+		}
+	}
+	select {
+	case <-h.release:
+	case <-ctx.Done():
+		return pkg.OutboundMessage{}, ctx.Err()
+	}
+	h.mu.Lock()
+	h.handled = append(h.handled, msg.Content)
+	h.mu.Unlock()
+	return pkg.OutboundMessage{ConversationID: msg.ConversationID, Content: "re: " + msg.Content}, nil
+}
+
+func (h *blockingHandler) contents() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.handled...)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(2 * time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for " + what)
+		}
+	}
+}
+
+func TestRegistryQueueBoundsWorkers(t *testing.T) {
+	h := newBlockingHandler()
+	reg := NewRegistry(h.handle)
+	defer reg.StopAll()
+	reg.SetChannelQueue("q", QueuePolicy{Workers: 2})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []string{"a", "b", "c", "d", "e"} {
+		ch.pushMessage(pkg.InboundMessage{ConversationID: c, Content: c})
+	}
+	waitFor(t, "two handlers", func() bool { return h.inflight.Load() == 2 })
+	time.Sleep(20 * time.Millisecond)
+	if n := h.peak.Load(); n != 2 {
+		t.Errorf("peak handlers = %d, want 2", n)
+	}
+	close(h.release)
+	waitFor(t, "all replies", func() bool { return len(ch.sentMessages()) == 5 })
+}
+
+func TestRegistryQueueRejectsWhenFull(t *testing.T) {
+	h := newBlockingHandler()
+	obs := &fakeQueueObserver{}
+	reg := NewRegistry(h.handle)
+	defer reg.StopAll()
+	reg.SetQueueObserver(obs)
+	reg.SetChannelQueue("q", QueuePolicy{Size: 1, Workers: 1, Overflow: QueueReject, BusyReply: "busy"})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", Content: "one"})
+	waitFor(t, "the first handler", func() bool { return h.inflight.Load() == 1 })
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "b", Content: "two"})
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "c", Content: "three", ThreadID: "t"})
+	waitFor(t, "the busy reply", func() bool { return len(ch.sentMessages()) == 1 })
+	if got := ch.sentMessages()[0]; got.ConversationID != "c" || got.ThreadID != "t" || got.Content != "busy" {
+		t.Errorf("busy reply = %+v", got)
+	}
+
+	close(h.release)
+	waitFor(t, "the replies", func() bool { return len(ch.sentMessages()) == 3 })
+	if got := h.contents(); len(got) != 2 || got[0] != "one" || got[1] != "two" {
+		t.Errorf("handled %q, want one and two", got)
+	}
+	obs.mu.Lock()
+	defer obs.mu.Unlock()
+	if obs.overflows[QueueReject] != 1 || obs.maxDepth != 1 {
+		t.Errorf("observed overflows %v, max depth %d", obs.overflows, obs.maxDepth)
+	}
+}
+
+func TestRegistryQueueCoalesces(t *testing.T) {
+	h := newBlockingHandler()
+	reg := NewRegistry(h.handle)
+	defer reg.StopAll()
+	reg.SetChannelQueue("q", QueuePolicy{Size: 1, Workers: 1, Overflow: QueueCoalesce})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: "one"})
+	waitFor(t, "the first handler", func() bool { return h.inflight.Load() == 1 })
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: "two"})
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: "three"})
+	// A confirmation is never merged or dropped, even into a full queue.
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: "yes", Metadata: map[string]string{"confirmation": "approve"}})
+	// Nothing queued for this conversation to fold into: rejected.
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "b", SenderID: "v", Content: "four"})
+	waitFor(t, "the busy reply", func() bool { return len(ch.sentMessages()) == 1 })
+	if got := ch.sentMessages()[0]; got.ConversationID != "b" || got.Content != defaultBusyReply {
+		t.Errorf("busy reply = %+v", got)
+	}
+
+	close(h.release)
+	waitFor(t, "the replies", func() bool { return len(ch.sentMessages()) == 4 })
+	got := h.contents()
+	if len(got) != 3 || got[0] != "one" || got[1] != "two\nthree" || got[2] != "yes" {
+		t.Errorf("handled %q", got)
+	}
+}
+
+func TestRegistryQueueWaitStopsCleanly(t *testing.T) {
+	h := newBlockingHandler()
+	reg := NewRegistry(h.handle)
+	reg.SetChannelQueue("q", QueuePolicy{Size: 1, Workers: 1})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", Content: "one"})
+	waitFor(t, "the first handler", func() bool { return h.inflight.Load() == 1 })
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "b", Content: "two"})
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "c", Content: "three"}) // the dispatcher waits for room
+
+	stopped := make(chan struct{})
+	go func() {
+		reg.StopAll()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("StopAll hung on a full queue")
+	}
+	if len(ch.sentMessages()) != 0 {
+		t.Errorf("sent %+v during shutdown", ch.sentMessages())
+	}
+}
+
+func TestRegistryQueueOrdersConversations(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	running := map[string]bool{}
+	var overlap atomic.Bool
+	var parallel atomic.Bool
+	handler := func(_ context.Context, _ string, msg pkg.InboundMessage) (pkg.OutboundMessage, error) {
+		mu.Lock()
+		if running[msg.ConversationID] {
+			overlap.Store(true)
+		}
+		running[msg.ConversationID] = true
+		if len(running) > 1 {
+			parallel.Store(true)
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		delete(running, msg.ConversationID)
+		if msg.ConversationID == "a" {
+			order = append(order, msg.Content)
+		}
+		mu.Unlock()
+		return pkg.OutboundMessage{ConversationID: msg.ConversationID, Content: "ok"}, nil
+	}
+	reg := NewRegistry(handler)
+	defer reg.StopAll()
+	reg.SetChannelQueue("q", QueuePolicy{Workers: 4})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range 5 {
+		ch.pushMessage(pkg.InboundMessage{ConversationID: "a", Content: string(rune('1' + i))})
+		ch.pushMessage(pkg.InboundMessage{ConversationID: "b", Content: "x"})
+	}
+	waitFor(t, "all replies", func() bool { return len(ch.sentMessages()) == 10 })
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(order, ""); got != "12345" {
+		t.Errorf("conversation a handled in order %q", got)
+	}
+	if overlap.Load() {
+		t.Error("two turns of one conversation ran at once")
+	}
+	if !parallel.Load() {
+		t.Error("conversations a and b never ran in parallel")
+	}
+}
+
+func TestRegistryQueueMergesWhileBusy(t *testing.T) {
+	h := newBlockingHandler()
+	reg := NewRegistry(h.handle)
+	defer reg.StopAll()
+	reg.SetChannelQueue("q", QueuePolicy{Workers: 2, Merge: true})
+	ch := newMockChannel("q")
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: "one"})
+	waitFor(t, "the first handler", func() bool { return h.inflight.Load() == 1 })
+	for _, c := range []string{"two", "three", "four"} {
+		ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "u", Content: c})
+	}
+	ch.pushMessage(pkg.InboundMessage{ConversationID: "a", SenderID: "w", Content: "five"})
+	time.Sleep(20 * time.Millisecond)
+	if n := h.inflight.Load(); n != 1 {
+		t.Fatalf("%d turns of one conversation running", n)
+	}
+
+	close(h.release)
+	waitFor(t, "the replies", func() bool { return len(ch.sentMessages()) == 3 })
+	got := h.contents()
+	if len(got) != 3 || got[0] != "one" || got[1] != "two\nthree\nfour" || got[2] != "five" {
+		t.Errorf("handled %q", got)
+	}
+}
@@ -19,19 +19,29 @@ type MessageDeduplicator interface {
 
 // Registry manages channel lifecycle, dispatches inbound messages to
 // the orchestrator, and routes responses back to the originating channel.
-// It owns the first two stages of the inbound pipeline — cross-pod message
-// dedup and per-conversation debounce — then hands off to the orchestrator,
+// It owns the first stages of the inbound pipeline — the redelivery window,
+// cross-pod message dedup, per-conversation debounce and the bounded,
+// per-conversation FIFO dispatch queue — then hands off to the orchestrator,
 // which enforces the global concurrency cap and serializes turns per session
-// (in-pod mutex, then the cross-pod session-turn lease). The full pipeline is
-// documented in docs/concurrency.md.
+// (in-pod mutex, then the cross-pod session-turn lease).
+// The full pipeline is documented in docs/concurrency.md.
 type Registry struct {
 	mu       sync.RWMutex
 	channels map[string]pkg.Channel
 	handler  pkg.MessageHandler
 
-	dedup          MessageDeduplicator
-	dedupTTL       time.Duration
-	debounceWindow time.Duration
+	dedup            MessageDeduplicator
+	dedupTTL         time.Duration
+	seen             *Deduplicator // in-pod redelivery window over message ids; nil = off
+	debounceWindow   time.Duration
+	debounceMaxWait  time.Duration            // 0 = defaultDebounceMaxWaitFactor × window
+	channelDebounces map[string]time.Duration // per-channel window overrides, keyed by channel id
+	delivery         DeliveryPolicy
+	formatting       map[string]Formatting  // per-channel reply formatting, keyed by channel id
+	progress         map[string]bool        // channels whose users see tool progress lines, keyed by channel id
+	queues           map[string]QueuePolicy // per-channel dispatch queue policy, keyed by channel id
+	queueObserver    QueueObserver          // nil = no queue metrics
+	relay            Relay                  // nil = Send reaches local channels only
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -62,6 +72,35 @@ func (r *Registry) SetDebounceWindow(d time.Duration) {
 	r.debounceWindow = d
 }
 
+// SetDebounceMaxWait caps how long a burst of messages can be held: once
+// maxWait has passed since the first buffered message, the burst is dispatched
+// even if the user is still typing. 0 selects a multiple of the window.
+// Must be called before any channels are registered.
+func (r *Registry) SetDebounceMaxWait(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.channels) > 0 {
+		panic("channel: SetDebounceMaxWait called after channels registered")
+	}
+	r.debounceMaxWait = d
+}
+
+// SetChannelDebounceWindow overrides the debounce window for one channel
+// instance (its config key), e.g. a longer window for a chat app whose users
+// send bursts and none for an API channel. 0 disables debouncing for that
+// channel. Must be called before that channel is registered.
+func (r *Registry) SetChannelDebounceWindow(channelID string, d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[channelID]; exists {
+		panic("channel: SetChannelDebounceWindow called after channel registered")
+	}
+	if r.channelDebounces == nil {
+		r.channelDebounces = make(map[string]time.Duration)
+	}
+	r.channelDebounces[channelID] = d
+}
+
 // SetDeduplicator attaches a Redis-backed deduplicator to the registry.
 // Must be called before any channels are registered.
 func (r *Registry) SetDeduplicator(d MessageDeduplicator, ttl time.Duration) {
@@ -74,6 +113,23 @@ func (r *Registry) SetDeduplicator(d MessageDeduplicator, ttl time.Duration) {
 	r.dedupTTL = ttl
 }
 
+// SetDedupWindow drops a message whose MessageID this pod has already seen
+// on the same channel and conversation within d, so a webhook or bot API
+// that redelivers after a timeout does not start a second run. Messages
+// without an id are never dropped here. 0 disables the window.
+// Must be called before any channels are registered.
+func (r *Registry) SetDedupWindow(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.channels) > 0 {
+		panic("channel: SetDedupWindow called after channels registered")
+	}
+	r.seen = nil
+	if d > 0 {
+		r.seen = NewDeduplicator(d)
+	}
+}
+
 func (r *Registry) Register(ch pkg.Channel) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -92,6 +148,7 @@ func (r *Registry) Register(ch pkg.Channel) error {
 
 	r.wg.Add(1)
 	go r.dispatch(ch, inbox)
+	r.holdRelay(id)
 
 	return nil
 }
@@ -106,6 +163,7 @@ func (r *Registry) Deregister(id string) error {
 	delete(r.channels, id)
 	r.mu.Unlock()
 
+	r.releaseRelay(id)
 	return ch.Stop()
 }
 
@@ -127,13 +185,19 @@ func (r *Registry) List() []pkg.Channel {
 	return out
 }
 
-// Send routes an outbound message to a specific channel.
+// Send routes an outbound message to a specific channel. A channel that is
+// not registered here is forwarded through the relay, when one is set, to
+// the replica that holds it.
 func (r *Registry) Send(ctx context.Context, channelID string, msg pkg.OutboundMessage) error {
 	r.mu.RLock()
 	ch, ok := r.channels[channelID]
+	rl := r.relay
 	r.mu.RUnlock()
 
 	if !ok {
+		if rl != nil {
+			return rl.Forward(ctx, channelID, msg)
+		}
 		return fmt.Errorf("channel %q not found", channelID)
 	}
 	return ch.Send(ctx, msg)
@@ -160,30 +224,54 @@ func (r *Registry) StopAll() {
 
 func (r *Registry) dispatch(ch pkg.Channel, inbox <-chan pkg.InboundMessage) {
 	// Shutdown ordering: r.cancel() closes r.ctx, the select below returns,
-	// defer wg.Wait() drains in-flight goroutines, then defer r.wg.Done() signals StopAll.
+	// the debouncer stops, queue.close() releases the workers, defer wg.Wait()
+	// drains in-flight handlers, then defer r.wg.Done() signals StopAll.
 	defer r.wg.Done()
 
+	// Messages wait in a bounded queue for a fixed pool of workers, so a
+	// burst cannot start an unbounded number of handlers. The queue hands
+	// out one message per conversation at a time, in arrival order.
+	policy, observer := r.queuePolicy(ch.ID())
+	queue := newDispatchQueue(ch.ID(), policy, observer)
 	var wg sync.WaitGroup
-	defer wg.Wait() // drain in-flight goroutines before signalling outer WaitGroup
-
-	// processMessage is the callback for both direct dispatch and debounced dispatch.
-	processMessage := func(m pkg.InboundMessage) {
+	defer wg.Wait() // drain in-flight handlers before signalling outer WaitGroup
+	defer queue.close()
+	// A dispatcher waiting for room in a full queue is not at the select
+	// below, so shutdown must also wake it through the queue.
+	defer context.AfterFunc(r.ctx, queue.close)()
+	for range policy.Workers {
 		wg.Add(1)
-		go func(m pkg.InboundMessage) {
+		go func() {
 			defer wg.Done()
-			r.handleMessage(ch, m)
-		}(m)
+			for {
+				it, ok := queue.pop()
+				if !ok {
+					return
+				}
+				r.handleMessage(ch, it.msg)
+				queue.release(it.conversation)
+			}
+		}()
+	}
+
+	// processMessage is the callback for both direct dispatch and debounced dispatch.
+	processMessage := func(sessionKey string, m pkg.InboundMessage) {
+		if !queue.push(m) && r.ctx.Err() == nil {
+			slog.Warn("channel queue full, message rejected", "channel", ch.ID(), "session", sessionKey)
+			sendBusy(r.ctx, ch, m, policy.BusyReply)
+		}
 	}
 
 	// Create debouncer if window > 0.
 	r.mu.RLock()
-	debounceWindow := r.debounceWindow
+	debounceWindow, maxWait := r.debounceWindow, r.debounceMaxWait
+	if d, ok := r.channelDebounces[ch.ID()]; ok {
+		debounceWindow = d
+	}
 	r.mu.RUnlock()
 	var debouncer *sessionDebouncer
 	if debounceWindow > 0 {
-		debouncer = newSessionDebouncer(debounceWindow, func(_ string, merged pkg.InboundMessage) {
-			processMessage(merged)
-		})
+		debouncer = newSessionDebouncer(debounceWindow, maxWait, processMessage)
 	}
 
 	defer func() {
@@ -202,21 +290,30 @@ func (r *Registry) dispatch(ch pkg.Channel, inbox <-chan pkg.InboundMessage) {
 			}
 
 			r.mu.RLock()
-			dedup, dedupTTL := r.dedup, r.dedupTTL
+			dedup, dedupTTL, seen := r.dedup, r.dedupTTL, r.seen
 			r.mu.RUnlock()
 
+			if seen != nil && msg.MessageID != "" && seen.IsDuplicate(msg.ChannelID+"\x00"+msg.ConversationID+"\x00"+msg.MessageID) {
+				slog.Info("dropped redelivered message", "channel", ch.ID(), "message_id", msg.MessageID)
+				continue
+			}
+
 			// Dedup runs before debounce — this ordering is load-bearing.
-			// Each original message is deduped by its own timestamp so that
+			// Each original message is deduped by its own id (or, when the
+			// channel gives none, its timestamp) so that
 			// pod-level duplicates (delivered by at-least-once channels) are
 			// suppressed before they enter the debounce buffer. If dedup ran
 			// after debounce, the merged message would carry only the last
 			// original's timestamp, and earlier duplicates arriving on another
 			// pod could slip through.
 			if dedup != nil {
-				if msg.Timestamp.IsZero() {
-					slog.Warn("dedup skipped: message has no timestamp", "channel", ch.ID())
+				if msg.MessageID == "" && msg.Timestamp.IsZero() {
+					slog.Warn("dedup skipped: message has no id or timestamp", "channel", ch.ID())
 				} else {
 					key := fmt.Sprintf("dedup:%s:%s:%d", msg.ChannelID, msg.ConversationID, msg.Timestamp.UnixNano())
+					if msg.MessageID != "" {
+						key = fmt.Sprintf("dedup:%s:%s:id:%s", msg.ChannelID, msg.ConversationID, msg.MessageID)
+					}
 					won, err := dedup.TryAcquire(r.ctx, key, dedupTTL)
 					if err != nil {
 						slog.Warn("dedup acquire failed, processing anyway", "channel", ch.ID(), "error", err)
@@ -228,6 +325,11 @@ func (r *Registry) dispatch(ch pkg.Channel, inbox <-chan pkg.InboundMessage) {
 			}
 
 			sessionKey := pkg.SessionKey(ch.ID(), msg.ConversationID, msg.ThreadID)
+			if isGroupMessage(msg) {
+				// Rapid messages from different members of a group are
+				// separate requests; only merge each sender's own burst.
+				sessionKey += ":@" + msg.SenderID
+			}
 
 			// Try debouncing. If the message bypasses debounce (confirmation, typing),
 			// or debouncing is disabled, dispatch immediately.
@@ -235,7 +337,7 @@ func (r *Registry) dispatch(ch pkg.Channel, inbox <-chan pkg.InboundMessage) {
 				slog.Debug("message debounced", "channel", ch.ID(), "session", sessionKey)
 				continue
 			}
-			processMessage(msg)
+			processMessage(sessionKey, msg)
 		}
 	}
 }
@@ -248,7 +350,16 @@ func (r *Registry) handleMessage(ch pkg.Channel, m pkg.InboundMessage) {
 	traceID := logger.TraceIDFromSessionKey(sessionKey)
 	ctx := logger.WithTraceID(r.ctx, traceID)
 	caps := ch.Capabilities()
-	ctx = pkg.WithCapabilities(ctx, caps)
+	formatting, format := r.formattingFor(ch.ID())
+	if formatting.Convert {
+		// The model writes plain Markdown; formatReply renders the channel's
+		// dialect, so the handler must not ask for that dialect as well.
+		handlerCaps := caps
+		handlerCaps.ResponseFormat, handlerCaps.ResponseFormatPrompt = pkg.FormatMarkdown, ""
+		ctx = pkg.WithCapabilities(ctx, handlerCaps)
+	} else {
+		ctx = pkg.WithCapabilities(ctx, caps)
+	}
 
 	// When the channel supports edits, attach a StreamWriter so the
 	// orchestrator can progressively deliver LLM output in real-time.
@@ -260,8 +371,14 @@ func (r *Registry) handleMessage(ch pkg.Channel, m pkg.InboundMessage) {
 		}
 	}
 
+	// Progress lines ("Running gitlab → analyze_code…") for channels that
+	// can show them and have them turned on.
+	if caps.Status && r.progressEnabled(ch.ID()) {
+		ctx = pkg.WithStatus(ctx, statusSender(ch, m))
+	}
+
 	// Send periodic typing indicators while the handler is processing.
-	typingStop := startTypingIndicator(ctx, ch, m)
+	typingStop := startTypingIndicator(ctx, ch, m, caps.Typing)
 
 	resp, err := r.handler(ctx, sessionKey, m)
 	typingStop()
@@ -288,8 +405,12 @@ func (r *Registry) handleMessage(ch pkg.Channel, m pkg.InboundMessage) {
 		logger.FromContext(ctx).Debug("registry: streaming path",
 			"channel", ch.ID(), "has_metadata", hasMeta, "metadata", resp.Metadata)
 		sw.MergeMetadata(resp.Metadata)
-		if resp.Content != sw.FullContent() || hasMeta {
-			if err := sw.FinalUpdate(ctx, resp.Content); err != nil {
+		final := resp.Content
+		if formatting.Convert {
+			final = renderMarkdown(final, caps.ResponseFormat)
+		}
+		if final != sw.FullContent() || hasMeta {
+			if err := sw.FinalUpdate(ctx, final); err != nil {
 				logger.FromContext(ctx).Debug("stream final update failed", "channel", ch.ID(), "error", err)
 			}
 		}
@@ -298,8 +419,12 @@ func (r *Registry) handleMessage(ch pkg.Channel, m pkg.InboundMessage) {
 
 	logger.FromContext(ctx).Debug("registry: direct send path",
 		"channel", ch.ID(), "has_metadata", hasMeta, "sw_nil", sw == nil)
-	if err := ch.Send(ctx, resp); err != nil {
-		logger.FromContext(ctx).Error("sending response failed", "channel", ch.ID(), "error", err)
+	if !format {
+		r.deliver(ctx, ch, resp)
+		return
+	}
+	for _, part := range formatReply(resp, caps, formatting) {
+		r.deliver(ctx, ch, part)
 	}
 }
 
@@ -311,11 +436,32 @@ var typingIndicatorInterval = 25 * time.Second
 // startTypingIndicator launches a background goroutine that sends periodic
 // typing-indicator messages to the channel. This prevents WebSocket and
 // reverse-proxy idle timeouts from killing connections during long LLM calls.
-// Call the returned function to stop the goroutine.
-func startTypingIndicator(ctx context.Context, ch pkg.Channel, m pkg.InboundMessage) func() {
+// With immediate (channels declaring Typing) the first one goes out at once,
+// so the user sees the message was picked up. Call the returned function to
+// stop the goroutine.
+func startTypingIndicator(ctx context.Context, ch pkg.Channel, m pkg.InboundMessage, immediate bool) func() {
 	stop := make(chan struct{})
 	done := make(chan struct{})
 
+	send := func() bool {
+		msg := pkg.OutboundMessage{
+			ConversationID: m.ConversationID,
+			ThreadID:       m.ThreadID,
+			Metadata: map[string]string{
+				pkg.TypingMetadataKey: "true",
+			},
+		}
+		if err := ch.Send(ctx, msg); err != nil {
+			slog.Debug("typing indicator send failed", "channel", ch.ID(), "error", err)
+			return false
+		}
+		slog.Debug("typing indicator sent", "channel", ch.ID(), "conversation", m.ConversationID)
+		return true
+	}
+
+	if immediate && !send() {
+		return func() {}
+	}
 	go func() {
 		defer close(done)
 		ticker := time.NewTicker(typingIndicatorInterval)
@@ -327,18 +473,9 @@ func startTypingIndicator(ctx context.Context, ch pkg.Channel, m pkg.InboundMess
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				msg := pkg.OutboundMessage{
-					ConversationID: m.ConversationID,
-					ThreadID:       m.ThreadID,
-					Metadata: map[string]string{
-						"_typing": "true",
-					},
-				}
-				if err := ch.Send(ctx, msg); err != nil {
-					slog.Debug("typing indicator send failed", "channel", ch.ID(), "error", err)
+				if !send() {
 					return
 				}
-				slog.Debug("typing indicator sent", "channel", ch.ID(), "conversation", m.ConversationID)
 			}
 		}
 	}()
@@ -348,3 +485,48 @@ func startTypingIndicator(ctx context.Context, ch pkg.Channel, m pkg.InboundMess
 		<-done
 	}
 }
+
+// SetChannelProgress turns tool progress lines on for one channel instance
+// (its config key); they are sent only if the channel declares Status. Must
+// be called before that channel is registered.
+func (r *Registry) SetChannelProgress(channelID string, enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.channels[channelID]; exists {
+		panic("channel: SetChannelProgress called after channel registered")
+	}
+	if r.progress == nil {
+		r.progress = make(map[string]bool)
+	}
+	r.progress[channelID] = enabled
+}
+
+func (r *Registry) progressEnabled(channelID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.progress[channelID]
+}
+
+// statusSender returns the StatusFunc of one turn: each new line goes to
+// the conversation as a StatusMetadataKey frame; a repeat of the current
+// line is not sent again.
+func statusSender(ch pkg.Channel, m pkg.InboundMessage) pkg.StatusFunc {
+	var mu sync.Mutex
+	var last string
+	return func(ctx context.Context, status string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if status == "" || status == last {
+			return
+		}
+		last = status
+		msg := pkg.OutboundMessage{
+			ConversationID: m.ConversationID,
+			ThreadID:       m.ThreadID,
+			Metadata:       map[string]string{pkg.StatusMetadataKey: status},
+		}
+		if err := ch.Send(ctx, msg); err != nil {
+			slog.Debug("status update send failed", "channel", ch.ID(), "error", err)
+		}
+	}
+}
@@ -211,3 +211,82 @@ func TestRegistryDedup_DisabledWhenNotSet(t *testing.T) {
 		t.Errorf("expected handler called 2 times without dedup, got %d", got)
 	}
 }
+
+func TestRegistryDedup_KeysByMessageID(t *testing.T) {
+	var handled atomic.Int32
+	handler := func(_ context.Context, _ string, _ pkg.InboundMessage) (pkg.OutboundMessage, error) {
+		handled.Add(1)
+		return pkg.OutboundMessage{}, nil
+	}
+
+	reg := NewRegistry(handler)
+	reg.SetDebounceWindow(0)
+	d := newFakeDedup()
+	reg.SetDeduplicator(d, 5*time.Minute)
+
+	ch := &stubChannel{id: "webhook"}
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	// A redelivery is stamped with a new receive time but keeps its id.
+	first := newMsg("webhook", "C123", time.Now())
+	first.MessageID = "delivery-1"
+	retry := newMsg("webhook", "C123", time.Now().Add(30*time.Second))
+	retry.MessageID = "delivery-1"
+	ch.inbox <- first
+	ch.inbox <- retry
+	other := newMsg("webhook", "C123", time.Time{})
+	other.MessageID = "delivery-2"
+	ch.inbox <- other
+
+	waitHandled(t, &handled, 2)
+	reg.StopAll()
+
+	if got := handled.Load(); got != 2 {
+		t.Errorf("expected handler called 2 times, got %d", got)
+	}
+	if !d.acquired["dedup:webhook:C123:id:delivery-1"] {
+		t.Errorf("message id not used as the dedup key: %v", d.acquired)
+	}
+}
+
+func TestRegistryDedupWindow(t *testing.T) {
+	var handled atomic.Int32
+	var mu sync.Mutex
+	var ids []string
+	handler := func(_ context.Context, _ string, m pkg.InboundMessage) (pkg.OutboundMessage, error) {
+		mu.Lock()
+		ids = append(ids, m.MessageID)
+		mu.Unlock()
+		handled.Add(1)
+		return pkg.OutboundMessage{}, nil
+	}
+
+	reg := NewRegistry(handler)
+	reg.SetDebounceWindow(0)
+	reg.SetDedupWindow(time.Minute)
+
+	ch := &stubChannel{id: "telegram"}
+	if err := reg.Register(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := func(conv, id string) pkg.InboundMessage {
+		m := newMsg("telegram", conv, time.Now())
+		m.MessageID = id
+		return m
+	}
+	ch.inbox <- msg("C1", "7")
+	ch.inbox <- msg("C1", "7") // redelivered update
+	ch.inbox <- msg("C2", "7") // same id in another chat is another message
+	ch.inbox <- msg("C1", "")  // no id: never dropped
+	ch.inbox <- msg("C1", "")
+
+	waitHandled(t, &handled, 4)
+	reg.StopAll()
+
+	if got := handled.Load(); got != 4 {
+		t.Errorf("expected handler called 4 times, got %d (ids %q)", got, ids)
+	}
+}
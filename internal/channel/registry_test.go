@@ -776,3 +776,92 @@ func TestRegistryDispatchNoStreamWriterForNonUpdatableChannel(t *testing.T) {
 		t.Errorf("expected 1 Send call (final response only), got %d", len(sent))
 	}
 }
+
+func TestRegistryChannelDebounceOverride(t *testing.T) {
+	reg := NewRegistry(echoHandler)
+	defer reg.StopAll()
+	reg.SetDebounceWindow(time.Hour) // global window would hold messages for the whole test
+	reg.SetChannelDebounceWindow("api", 0)
+	reg.SetChannelDebounceWindow("chat", 50*time.Millisecond)
+
+	api := newMockChannel("api")
+	chat := newMockChannel("chat")
+	_ = reg.Register(api)
+	_ = reg.Register(chat)
+
+	api.pushMessage(pkg.InboundMessage{ConversationID: "c", Content: "direct"})
+	chat.pushMessage(pkg.InboundMessage{ConversationID: "c", SenderID: "u", Content: "one"})
+	chat.pushMessage(pkg.InboundMessage{ConversationID: "c", SenderID: "u", Content: "two"})
+
+	deadline := time.After(2 * time.Second)
+	for len(api.sentMessages()) == 0 || len(chat.sentMessages()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out: api=%v chat=%v", api.sentMessages(), chat.sentMessages())
+		default:
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if got := chat.sentMessages()[0].Content; got != "echo: one\ntwo" {
+		t.Errorf("chat content = %q; want merged burst", got)
+	}
+}
+
+func TestRegistrySetChannelDebounceAfterRegisterPanics(t *testing.T) {
+	reg := NewRegistry(echoHandler)
+	defer reg.StopAll()
+	_ = reg.Register(newMockChannel("x"))
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic")
+		}
+	}()
+	reg.SetChannelDebounceWindow("x", time.Second)
+}
+
+func TestRegistryStatusUpdates(t *testing.T) {
+	handler := func(ctx context.Context, _ string, msg pkg.InboundMessage) (pkg.OutboundMessage, error) {
+		pkg.ReportStatus(ctx, "Running gitlab → analyze_code…")
+		pkg.ReportStatus(ctx, "Running gitlab → analyze_code…")
+		pkg.ReportStatus(ctx, "Running jira → create_issue…")
+		return pkg.OutboundMessage{ConversationID: msg.ConversationID, Content: "done"}, nil
+	}
+	run := func(id string, progress bool) []pkg.OutboundMessage {
+		reg := NewRegistry(handler)
+		defer reg.StopAll()
+		reg.SetDebounceWindow(0)
+		reg.SetChannelProgress(id, progress)
+		ch := newMockChannel(id)
+		ch.caps.Typing, ch.caps.Status = true, true
+		_ = reg.Register(ch)
+		ch.pushMessage(pkg.InboundMessage{ChannelID: id, ConversationID: "c1", Content: "go"})
+		deadline := time.After(2 * time.Second)
+		for {
+			sent := ch.sentMessages()
+			if len(sent) > 0 && sent[len(sent)-1].Content == "done" {
+				return sent
+			}
+			select {
+			case <-deadline:
+				t.Fatalf("timed out waiting for the reply, sent %+v", sent)
+			default:
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+
+	sent := run("status-on", true)
+	if len(sent) != 4 || sent[0].Metadata[pkg.TypingMetadataKey] != "true" {
+		t.Fatalf("want typing, two status lines and the reply; got %+v", sent)
+	}
+	if sent[1].Metadata[pkg.StatusMetadataKey] != "Running gitlab → analyze_code…" ||
+		sent[2].Metadata[pkg.StatusMetadataKey] != "Running jira → create_issue…" || !pkg.IsSignal(sent[1]) {
+		t.Errorf("status frames = %+v, %+v", sent[1], sent[2])
+	}
+
+	for _, m := range run("status-off", false) {
+		if m.Metadata[pkg.StatusMetadataKey] != "" {
+			t.Errorf("status sent with progress off: %+v", m)
+		}
+	}
+}
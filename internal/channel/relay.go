@@ -0,0 +1,75 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// Relay carries outbound messages between replicas. A replica that holds a
+// channel connection announces it with Hold; Send on a replica without that
+// channel forwards the message, and the holder delivers it. This lets a
+// reply produced on one pod (a scheduler job, a background turn) reach a
+// channel whose connection lives on another.
+type Relay interface {
+	// Hold announces that this replica delivers for channelID.
+	Hold(ctx context.Context, channelID string) error
+	// Release withdraws a Hold.
+	Release(ctx context.Context, channelID string) error
+	// Forward hands msg to a replica holding channelID. It fails when no
+	// replica holds the channel.
+	Forward(ctx context.Context, channelID string, msg pkg.OutboundMessage) error
+	// Run delivers forwarded messages for held channels until ctx is done.
+	Run(ctx context.Context, deliver func(ctx context.Context, channelID string, msg pkg.OutboundMessage) error)
+}
+
+// SetRelay lets Send reach channels registered on other replicas and starts
+// delivering messages forwarded to this one. Must be called before any
+// channels are registered.
+func (r *Registry) SetRelay(rl Relay) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.channels) > 0 {
+		panic("channel: SetRelay called after channels registered")
+	}
+	r.relay = rl
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		rl.Run(r.ctx, r.sendLocal)
+	}()
+}
+
+// sendLocal delivers a forwarded message; it never forwards again, so two
+// replicas that both lost a channel cannot bounce a message between them.
+func (r *Registry) sendLocal(ctx context.Context, channelID string, msg pkg.OutboundMessage) error {
+	r.mu.RLock()
+	ch, ok := r.channels[channelID]
+	r.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("channel %q not found", channelID)
+	}
+	return ch.Send(ctx, msg)
+}
+
+// holdRelay announces a newly registered channel; a failure only costs other
+// replicas the ability to forward to it, so it is logged, not returned.
+func (r *Registry) holdRelay(channelID string) {
+	if r.relay == nil {
+		return
+	}
+	if err := r.relay.Hold(r.ctx, channelID); err != nil {
+		slog.Warn("relay: announcing channel failed; other replicas cannot forward to it", "channel", channelID, "error", err)
+	}
+}
+
+func (r *Registry) releaseRelay(channelID string) {
+	if r.relay == nil {
+		return
+	}
+	if err := r.relay.Release(r.ctx, channelID); err != nil {
+		slog.Warn("relay: withdrawing channel failed", "channel", channelID, "error", err)
+	}
+}
@@ -0,0 +1,102 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// memBus is an in-process stand-in for the pub/sub backend: each memRelay
+// attached to it is one replica.
+type memBus struct {
+	mu      sync.Mutex
+	holders map[string]*memRelay
+}
+
+type forwarded struct {
+	channelID string
+	msg       pkg.OutboundMessage
+}
+
+type memRelay struct {
+	bus   *memBus
+	inbox chan forwarded
+}
+
+func (b *memBus) relay() *memRelay { return &memRelay{bus: b, inbox: make(chan forwarded, 4)} }
+
+func (m *memRelay) Hold(_ context.Context, channelID string) error {
+	m.bus.mu.Lock()
+	defer m.bus.mu.Unlock()
+	m.bus.holders[channelID] = m
+	return nil
+}
+
+func (m *memRelay) Release(_ context.Context, channelID string) error {
+	m.bus.mu.Lock()
+	defer m.bus.mu.Unlock()
+	delete(m.bus.holders, channelID)
+	return nil
+}
+
+func (m *memRelay) Forward(_ context.Context, channelID string, msg pkg.OutboundMessage) error {
+	m.bus.mu.Lock()
+	h, ok := m.bus.holders[channelID]
+	m.bus.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("channel %q not found on any pod", channelID)
+	}
+	h.inbox <- forwarded{channelID, msg}
+	return nil
+}
+
+func (m *memRelay) Run(ctx context.Context, deliver func(context.Context, string, pkg.OutboundMessage) error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-m.inbox:
+			_ = deliver(ctx, f.channelID, f.msg)
+		}
+	}
+}
+
+func TestRegistrySendThroughRelay(t *testing.T) {
+	bus := &memBus{holders: make(map[string]*memRelay)}
+	podA := NewRegistry(echoHandler)
+	defer podA.StopAll()
+	podA.SetRelay(bus.relay())
+	podB := NewRegistry(echoHandler)
+	defer podB.StopAll()
+	podB.SetRelay(bus.relay())
+
+	web := newMockChannel("web")
+	if err := podB.Register(web); err != nil {
+		t.Fatal(err)
+	}
+	if err := podA.Send(context.Background(), "web", pkg.OutboundMessage{ConversationID: "c1", Content: "job done"}); err != nil {
+		t.Fatalf("Send via relay: %v", err)
+	}
+	deadline := time.After(2 * time.Second)
+	for len(web.sentMessages()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("forwarded message never reached the holding pod's channel")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if got := web.sentMessages()[0]; got.ConversationID != "c1" || got.Content != "job done" {
+		t.Errorf("delivered %+v", got)
+	}
+
+	if err := podB.Deregister("web"); err != nil {
+		t.Fatal(err)
+	}
+	if err := podA.Send(context.Background(), "web", pkg.OutboundMessage{}); err == nil {
+		t.Error("a deregistered channel should not be reachable through the relay")
+	}
+}
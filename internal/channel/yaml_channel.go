@@ -117,6 +117,10 @@ func (ch *YAMLChannel) Capabilities() pkg.Capabilities {
 		MaxMessageLength:     int64(ch.spec.Capabilities.MaxMessageLength),
 		ResponseFormat:       ch.spec.Capabilities.ResponseFormat,
 		ResponseFormatPrompt: ch.spec.Capabilities.ResponseFormatPrompt,
+		LinkThreads:          ch.spec.Capabilities.LinkThreads,
+		Voice:                ch.spec.Capabilities.Voice,
+		Typing:               ch.spec.Outbound.Status.URL != "",
+		Status:               ch.spec.Outbound.Status.URL != "",
 	}
 }
 
@@ -199,6 +203,9 @@ func (ch *YAMLChannel) Start(ctx context.Context, inbox chan<- pkg.InboundMessag
 // Send chunks and sends a message via the outbound HTTP call,
 // then runs on_response hooks.
 func (ch *YAMLChannel) Send(ctx context.Context, msg pkg.OutboundMessage) error {
+	if pkg.IsSignal(msg) && ch.spec.Outbound.Status.URL != "" {
+		return ch.sendStatus(ctx, msg)
+	}
 	chunks := ChunkMessage(msg.Content, ch.spec.Outbound.Chunking.MaxLength)
 
 	msgCtx := map[string]string{
@@ -237,6 +244,21 @@ func (ch *YAMLChannel) Send(ctx context.Context, msg pkg.OutboundMessage) error
 	return nil
 }
 
+// sendStatus delivers a typing or progress frame through outbound.status.
+func (ch *YAMLChannel) sendStatus(ctx context.Context, msg pkg.OutboundMessage) error {
+	contexts := ch.buildContexts()
+	contexts["msg"] = map[string]string{
+		"conversation_id": msg.ConversationID,
+		"thread_id":       msg.ThreadID,
+		"typing":          msg.Metadata[pkg.TypingMetadataKey],
+		"status":          msg.Metadata[pkg.StatusMetadataKey],
+	}
+	if err := ch.doHTTPCall(ctx, ch.spec.Outbound.Status, contexts); err != nil {
+		return fmt.Errorf("channel %s status: %w", ch.spec.ID, err)
+	}
+	return nil
+}
+
 // SendAndCapture implements pkg.UpdatableChannel. It sends a message using the
 // outbound.send spec and captures the message ID from the response (using the
 // field name configured in outbound.send_store_id, e.g. "ts" for Slack).
@@ -0,0 +1,60 @@
+package channel
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func TestYAMLChannel_SignalsUseStatusCall(t *testing.T) {
+	var mu sync.Mutex
+	got := map[string][]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got[r.URL.Path] = append(got[r.URL.Path], string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	ch := &YAMLChannel{
+		spec: &YAMLChannelSpec{
+			ID: "chat",
+			Outbound: OutboundSpec{
+				Send:   HTTPCallSpec{Method: "POST", URL: server.URL + "/send", Body: "{{msg.content}}"},
+				Status: HTTPCallSpec{Method: "POST", URL: server.URL + "/status", Body: "{{msg.typing}}|{{msg.status}}"},
+			},
+		},
+		selfVars: make(map[string]string),
+		config:   make(map[string]string),
+		client:   &http.Client{},
+		ctx:      context.Background(),
+	}
+	if caps := ch.Capabilities(); !caps.Typing || !caps.Status {
+		t.Errorf("capabilities = %+v; outbound.status should give typing and status", caps)
+	}
+
+	ctx := context.Background()
+	for _, msg := range []pkg.OutboundMessage{
+		{ConversationID: "c1", Metadata: map[string]string{pkg.TypingMetadataKey: "true"}},
+		{ConversationID: "c1", Metadata: map[string]string{pkg.StatusMetadataKey: "Running x…"}},
+		{ConversationID: "c1", Content: "done"},
+	} {
+		if err := ch.Send(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if s := got["/status"]; len(s) != 2 || s[0] != "true|" || s[1] != "|Running x…" {
+		t.Errorf("status calls = %q", s)
+	}
+	if s := got["/send"]; len(s) != 1 || s[0] != "done" {
+		t.Errorf("send calls = %q", s)
+	}
+}
@@ -34,6 +34,8 @@ type CapabilitiesSpec struct {
 	MaxMessageLength     int                `yaml:"max_message_length"`
 	ResponseFormat       pkg.ResponseFormat `yaml:"response_format"`
 	ResponseFormatPrompt string             `yaml:"response_format_prompt"`
+	LinkThreads          bool               `yaml:"link_threads"` // thread sessions inherit the parent conversation's summary and pinned facts
+	Voice                bool               `yaml:"voice"`        // replies may carry a synthesized voice note (speech.tts)
 }
 
 // InitStep is an HTTP call to run at startup. Results are stored in selfVars.
@@ -227,7 +229,8 @@ type MappingSpec struct {
 	SenderID       MappingField      `yaml:"sender_id"`
 	Content        MappingField      `yaml:"content"`
 	ThreadID       MappingField      `yaml:"thread_id"`
-	Metadata       map[string]string `yaml:"metadata"` // key = metadata key, value = event field name
+	MessageID      MappingField      `yaml:"message_id"` // platform event id, stable across redeliveries
+	Metadata       map[string]string `yaml:"metadata"`   // key = metadata key, value = event field name
 	// Files is the event field name whose value is an array of file objects.
 	// Each object must have: name (string), mime_type (string), data (base64 string), size (number).
 	Files string `yaml:"files"`
@@ -264,6 +267,11 @@ type OutboundSpec struct {
 	Send        HTTPCallSpec `yaml:"send"`
 	Update      HTTPCallSpec `yaml:"update"`        // optional: edit an existing message (for streaming); template has {{msg.message_id}}
 	SendStoreID string       `yaml:"send_store_id"` // optional: JSON field in send response to capture as message ID (e.g. "ts" for Slack)
+	// Status is an optional call for typing and progress frames instead of
+	// send; its templates see {{msg.typing}} ("true" or "") and
+	// {{msg.status}} (the progress line or ""). Declaring it gives the
+	// channel the typing and status capabilities.
+	Status HTTPCallSpec `yaml:"status"`
 }
 
 // ChunkingSpec configures message chunking.
@@ -399,6 +399,7 @@ func (ch *YAMLChannel) extractMessage(event map[string]interface{}, eventCtx map
 		SenderID:       ch.getMappedField(event, ch.spec.Inbound.Mapping.SenderID),
 		Content:        ch.getMappedField(event, ch.spec.Inbound.Mapping.Content),
 		ThreadID:       ch.getMappedField(event, ch.spec.Inbound.Mapping.ThreadID),
+		MessageID:      ch.getMappedField(event, ch.spec.Inbound.Mapping.MessageID),
 		Timestamp:      time.Now(),
 	}
 
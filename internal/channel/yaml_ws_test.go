@@ -332,6 +332,7 @@ func TestExtractMessage(t *testing.T) {
 					SenderID:       MappingField{Field: "user"},
 					Content:        MappingField{Field: "text"},
 					ThreadID:       MappingField{Field: "thread_ts", Fallback: "ts"},
+					MessageID:      MappingField{Field: "client_msg_id", Fallback: "ts"},
 					Metadata:       map[string]string{"ts": "ts"},
 				},
 			},
@@ -375,6 +376,9 @@ func TestExtractMessage(t *testing.T) {
 	if msg.Metadata["ts"] != "1234567890.123456" {
 		t.Errorf("Metadata[ts] = %q, want %q", msg.Metadata["ts"], "1234567890.123456")
 	}
+	if msg.MessageID != "1234567890.123456" {
+		t.Errorf("MessageID = %q, want %q (fallback to ts)", msg.MessageID, "1234567890.123456")
+	}
 }
 
 // TestExtractMessage_MultiInstance asserts that two YAMLChannel instances
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// BranchReplayer replays a branched session against its own model and
+// prompt (the orchestrator).
+type BranchReplayer interface {
+	ReplayBranch(ctx context.Context, branchID string) ([]orchestrator.ReplayTurn, error)
+}
+
+// maxBranches bounds the branch keys tried for one session.
+const maxBranches = 100
+
+// WithBranchReplay lets branch_session replay the rest of the conversation
+// on the new branch. Without it branches are only forked.
+func (e *Executor) WithBranchReplay(r BranchReplayer) *Executor {
+	e.replayer = r
+	return e
+}
+
+// branchSession forks the current session at a message index (/branch) into
+// "<session>:branch-N" and, unless replay is "false", replays the user
+// messages after that index on the branch in the background.
+func (e *Executor) branchSession(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	sess, err := e.sessions.Get(sessionID)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("session lookup: %v", err)}
+	}
+	at := lastUserMessage(sess.Messages)
+	if s := strings.TrimSpace(call.Args["at"]); s != "" {
+		if at, err = strconv.Atoi(s); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("at must be a message index, got %q", s)}
+		}
+	}
+	if at < 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "this conversation has no message to branch from"}
+	}
+	opts := orchestrator.BranchOptions{
+		Model:        strings.TrimSpace(call.Args["model"]),
+		SystemPrompt: call.Args["prompt"],
+	}
+	if e.cfg != nil {
+		opts.Model = e.cfg.Models.ResolveAlias(opts.Model)
+	}
+
+	var branchID string
+	for n := len(orchestrator.Branches(sess)) + 1; n <= maxBranches; n++ {
+		branchID = fmt.Sprintf("%s:branch-%d", sessionID, n)
+		err = orchestrator.BranchSession(ctx, e.sessions, sessionID, branchID, at, opts)
+		if !errors.Is(err, orchestrator.ErrSessionExists) {
+			break
+		}
+	}
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("branch: %v", err)}
+	}
+
+	msg := fmt.Sprintf("Branched this conversation at message %d into session %s.", at, branchID)
+	if e.replayer == nil || strings.EqualFold(call.Args["replay"], "false") || at == len(sess.Messages) {
+		return orchestrator.ToolResult{CallID: call.ID, Content: msg}
+	}
+	// The replay runs full turns on the branch, so it cannot run inside the
+	// turn that issued /branch; it keeps the caller's profile but not its
+	// deadline.
+	go func(ctx context.Context) {
+		turns, err := e.replayer.ReplayBranch(ctx, branchID)
+		if err != nil {
+			slog.Warn("branch replay failed", "session_id", branchID, "turns", len(turns), "error", err)
+		}
+	}(context.WithoutCancel(ctx))
+	return orchestrator.ToolResult{CallID: call.ID, Content: msg + " Replaying the rest of the conversation there; the outcome is recorded on the branch as " + orchestrator.MetaBranchReplay + "."}
+}
+
+// lastUserMessage returns the index of the last visible user message, so a
+// branch cut there re-asks it; -1 when there is none.
+func lastUserMessage(messages []provider.Message) int {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == provider.RoleUser && messages[i].Visibility != provider.VisibilityHidden {
+			return i
+		}
+	}
+	return -1
+}
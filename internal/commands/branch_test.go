@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// replayRecorder records the branches it is asked to replay.
+type replayRecorder struct {
+	replayed chan string
+}
+
+func (r *replayRecorder) ReplayBranch(_ context.Context, branchID string) ([]orchestrator.ReplayTurn, error) {
+	r.replayed <- branchID
+	return nil, nil
+}
+
+func TestExecutor_BranchSession(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	for _, m := range []provider.Message{
+		{Role: provider.RoleUser, Content: "hi"},
+		{Role: provider.RoleAssistant, Content: "hello"},
+		{Role: provider.RoleUser, Content: "why?"},
+		{Role: provider.RoleAssistant, Content: "because"},
+	} {
+		_ = sessions.AddMessage("web:c1", m)
+	}
+	rec := &replayRecorder{replayed: make(chan string, 1)}
+	e := NewExecutor(orchestrator.NewToolRegistry(), sessions, "", nil, "").WithBranchReplay(rec)
+	run := func(args map[string]string) orchestrator.ToolResult {
+		args["session_id"] = "web:c1"
+		return e.Execute(context.Background(), orchestrator.ToolCall{ID: "c", Plugin: PluginName, Action: ActionBranchSession, Args: args})
+	}
+
+	res := run(map[string]string{"model": "openai/gpt-x"})
+	if res.Error != "" || !strings.Contains(res.Content, "at message 2 into session web:c1:branch-1") {
+		t.Fatalf("branch: %+v", res)
+	}
+	if got := <-rec.replayed; got != "web:c1:branch-1" {
+		t.Errorf("replayed %q", got)
+	}
+	b, _ := sessions.Get("web:c1:branch-1")
+	if len(b.Messages) != 2 || b.Metadata[orchestrator.MetaBranchModel] != "openai/gpt-x" {
+		t.Errorf("branch = %+v", b)
+	}
+
+	res = run(map[string]string{"at": "4"})
+	if res.Error != "" || !strings.Contains(res.Content, "web:c1:branch-2.") || strings.Contains(res.Content, "Replaying") {
+		t.Errorf("branch at the end has nothing to replay: %+v", res)
+	}
+	res = run(map[string]string{"at": "2", "replay": "false"})
+	if res.Error != "" || strings.Contains(res.Content, "Replaying") {
+		t.Errorf("replay=false: %+v", res)
+	}
+	select {
+	case id := <-rec.replayed:
+		t.Errorf("unexpected replay of %s", id)
+	default:
+	}
+	if res = run(map[string]string{"at": "x"}); !strings.Contains(res.Error, "message index") {
+		t.Errorf("bad index: %+v", res)
+	}
+	if res = run(map[string]string{"at": "9"}); !strings.Contains(res.Error, "out of range") {
+		t.Errorf("index past the end: %+v", res)
+	}
+}
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// maxActionsPerCapability caps how many actions the summary lists per plugin;
+// large MCP servers expose dozens and the rest are folded into "and N more".
+const maxActionsPerCapability = 5
+
+// maxExampleAsks is the number of example requests suggested per plugin.
+const maxExampleAsks = 2
+
+// capabilities answers /help: a summary of what this deployment can do, built
+// from registry metadata and optionally rewritten by the LLM. Polished output
+// is cached per summary fingerprint, so the LLM only runs again when the set
+// of visible tools changes (install_skill, reload_mcp, profile changes).
+func (e *Executor) capabilities(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	var group string
+	if p := profile.FromContext(ctx); p != nil {
+		group = p.Group
+	}
+	summary := capabilitiesSummary(e.registry.ListCapabilities(), group)
+	if e.helpLLM == nil {
+		return orchestrator.ToolResult{CallID: call.ID, Content: summary}
+	}
+
+	sum := sha256.Sum256([]byte(summary))
+	key := hex.EncodeToString(sum[:])
+	e.helpMu.Lock()
+	cached, ok := e.helpCache[key]
+	e.helpMu.Unlock()
+	if ok {
+		return orchestrator.ToolResult{CallID: call.ID, Content: cached}
+	}
+
+	resp, err := e.helpLLM.Complete(ctx, &provider.CompletionRequest{
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: prompts.HelpPolish},
+			{Role: provider.RoleUser, Content: summary},
+		},
+	})
+	if err != nil || strings.TrimSpace(resp.Content) == "" {
+		// The raw summary is already readable; polishing is best-effort.
+		slog.Warn("help summary polish failed; serving raw summary", "error", err)
+		return orchestrator.ToolResult{CallID: call.ID, Content: summary}
+	}
+	polished := strings.TrimSpace(resp.Content)
+	e.helpMu.Lock()
+	if e.helpCache == nil {
+		e.helpCache = make(map[string]string)
+	}
+	e.helpCache[key] = polished
+	e.helpMu.Unlock()
+	return orchestrator.ToolResult{CallID: call.ID, Content: polished}
+}
+
+// capabilitiesSummary renders the registry-derived overview. Host-internal
+// plugins (underscore-prefixed, e.g. _meta) are hidden, as are plugins whose
+// AllowedGroups exclude group. The opentalon built-in plugin is rendered last
+// as the slash-command list rather than as a tool category.
+func capabilitiesSummary(caps []orchestrator.PluginCapability, group string) string {
+	visible := make([]orchestrator.PluginCapability, 0, len(caps))
+	for _, c := range caps {
+		if strings.HasPrefix(c.Name, "_") || c.Name == PluginName || len(c.Actions) == 0 {
+			continue
+		}
+		if len(c.AllowedGroups) > 0 && !containsString(c.AllowedGroups, group) {
+			continue
+		}
+		visible = append(visible, c)
+	}
+	sort.Slice(visible, func(i, j int) bool { return visible[i].Name < visible[j].Name })
+
+	var b strings.Builder
+	if len(visible) == 0 {
+		b.WriteString("No tools are connected to this assistant yet, but you can still chat with it.\n")
+	} else {
+		b.WriteString("Here is what I can help with:\n")
+	}
+	for _, c := range visible {
+		fmt.Fprintf(&b, "\n## %s\n", c.Name)
+		if d := firstSentence(c.Description); d != "" {
+			b.WriteString(d + "\n")
+		}
+		var examples []string
+		for i, a := range c.Actions {
+			if i == maxActionsPerCapability {
+				fmt.Fprintf(&b, "- …and %d more\n", len(c.Actions)-maxActionsPerCapability)
+				break
+			}
+			label := humanizeAction(a.Name)
+			if d := firstSentence(a.Description); d != "" {
+				fmt.Fprintf(&b, "- %s — %s\n", label, d)
+			} else {
+				fmt.Fprintf(&b, "- %s\n", label)
+			}
+			if !a.UserOnly && len(examples) < maxExampleAsks {
+				examples = append(examples, fmt.Sprintf("%q", label))
+			}
+		}
+		if len(examples) > 0 {
+			fmt.Fprintf(&b, "Try asking: %s\n", strings.Join(examples, ", "))
+		}
+	}
+	b.WriteString("\n## Commands\n")
+	b.WriteString(commandsHelp)
+	return b.String()
+}
+
+// humanizeAction turns a tool action name into a short label: the mcp-style
+// "server__" prefix is dropped and separators become spaces
+// ("timly__list-items" → "list items").
+func humanizeAction(name string) string {
+	if i := strings.LastIndex(name, "__"); i >= 0 {
+		name = name[i+2:]
+	}
+	return strings.TrimSpace(strings.NewReplacer("_", " ", "-", " ").Replace(name))
+}
+
+// firstSentence returns the first sentence of s (up to and including the
+// first ". "), or s trimmed when it has only one.
+func firstSentence(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.Index(s, ". "); i >= 0 {
+		return s[:i+1]
+	}
+	return s
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
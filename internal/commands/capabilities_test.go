@@ -0,0 +1,142 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+type noopExec struct{}
+
+func (noopExec) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	return orchestrator.ToolResult{CallID: call.ID}
+}
+
+type countingLLM struct {
+	calls int
+	reply string
+	err   error
+}
+
+func (c *countingLLM) Complete(_ context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &provider.CompletionResponse{Content: c.reply}, nil
+}
+
+func helpRegistry(t *testing.T) *orchestrator.ToolRegistry {
+	t.Helper()
+	reg := orchestrator.NewToolRegistry()
+	caps := []orchestrator.PluginCapability{
+		{Name: "jira", Description: "Jira issue tracking. Supports JQL.", Actions: []orchestrator.Action{
+			{Name: "jira__search-issues", Description: "Search issues by JQL."},
+			{Name: "create_issue", Description: "Create an issue."},
+			{Name: "delete_issue", Description: "Delete an issue."},
+		}},
+		{Name: "billing", Description: "Invoices.", AllowedGroups: []string{"finance"}, Actions: []orchestrator.Action{
+			{Name: "list_invoices", Description: "List invoices."},
+		}},
+		{Name: "_internal", Description: "Host-internal.", Actions: []orchestrator.Action{{Name: "run"}}},
+	}
+	for _, c := range caps {
+		if err := reg.Register(c, noopExec{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reg
+}
+
+func TestCapabilitiesSummary(t *testing.T) {
+	out := capabilitiesSummary(helpRegistry(t).ListCapabilities(), "")
+	for _, want := range []string{
+		"## jira",
+		"Jira issue tracking.",
+		"- search issues — Search issues by JQL.",
+		`Try asking: "search issues", "create issue"`,
+		"## Commands",
+		"/help",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("summary missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Supports JQL") {
+		t.Error("plugin description should be cut to its first sentence")
+	}
+	if strings.Contains(out, "_internal") {
+		t.Error("underscore-prefixed plugins must be hidden")
+	}
+	if strings.Contains(out, "billing") {
+		t.Error("group-restricted plugin shown to a user outside the group")
+	}
+
+	out = capabilitiesSummary(helpRegistry(t).ListCapabilities(), "finance")
+	if !strings.Contains(out, "## billing") {
+		t.Errorf("group-restricted plugin hidden from a member:\n%s", out)
+	}
+}
+
+func TestCapabilitiesSummary_TruncatesLongActionLists(t *testing.T) {
+	var actions []orchestrator.Action
+	for _, n := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		actions = append(actions, orchestrator.Action{Name: n})
+	}
+	out := capabilitiesSummary([]orchestrator.PluginCapability{{Name: "big", Actions: actions}}, "")
+	if !strings.Contains(out, "…and 2 more") {
+		t.Errorf("expected truncation marker:\n%s", out)
+	}
+}
+
+func TestExecutor_Capabilities_ProfileGroup(t *testing.T) {
+	e := NewExecutor(helpRegistry(t), state.NewSessionStore(""), "", nil, "")
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{Group: "finance"})
+	res := e.Execute(ctx, orchestrator.ToolCall{ID: "c1", Plugin: PluginName, Action: ActionCapabilities})
+	if res.CallID != "c1" || res.Error != "" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if !strings.Contains(res.Content, "## billing") {
+		t.Errorf("profile group not applied:\n%s", res.Content)
+	}
+}
+
+func TestExecutor_Capabilities_PolishCached(t *testing.T) {
+	reg := helpRegistry(t)
+	llm := &countingLLM{reply: "  Welcome! I can search Jira.  "}
+	e := NewExecutor(reg, state.NewSessionStore(""), "", nil, "").WithHelpPolish(llm)
+
+	for i := 0; i < 2; i++ {
+		res := e.Execute(context.Background(), orchestrator.ToolCall{ID: "c", Action: ActionCapabilities})
+		if res.Content != "Welcome! I can search Jira." {
+			t.Fatalf("content = %q", res.Content)
+		}
+	}
+	if llm.calls != 1 {
+		t.Errorf("LLM calls = %d; want 1 (second call served from cache)", llm.calls)
+	}
+
+	// A changed tool set invalidates the cache.
+	if err := reg.Register(orchestrator.PluginCapability{Name: "weather", Actions: []orchestrator.Action{{Name: "forecast"}}}, noopExec{}); err != nil {
+		t.Fatal(err)
+	}
+	e.Execute(context.Background(), orchestrator.ToolCall{ID: "c", Action: ActionCapabilities})
+	if llm.calls != 2 {
+		t.Errorf("LLM calls = %d; want 2 after registry change", llm.calls)
+	}
+}
+
+func TestExecutor_Capabilities_PolishFailureFallsBack(t *testing.T) {
+	llm := &countingLLM{err: errors.New("boom")}
+	e := NewExecutor(helpRegistry(t), state.NewSessionStore(""), "", nil, "").WithHelpPolish(llm)
+	res := e.Execute(context.Background(), orchestrator.ToolCall{ID: "c", Action: ActionCapabilities})
+	if res.Error != "" || !strings.Contains(res.Content, "## jira") {
+		t.Errorf("expected raw summary fallback, got %+v", res)
+	}
+}
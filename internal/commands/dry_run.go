@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+// setDryRun switches the session's dry-run flag (orchestrator.MetaDryRun),
+// which makes Run simulate the session's mutating tool calls.
+func (e *Executor) setDryRun(call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	sess, err := e.sessions.Get(sessionID)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("session lookup: %v", err)}
+	}
+	current := sess != nil && sess.Metadata[orchestrator.MetaDryRun] == "true"
+
+	enable := !current
+	switch mode := strings.ToLower(strings.TrimSpace(call.Args["mode"])); mode {
+	case "", "toggle":
+	case "on", "enable", "true":
+		enable = true
+	case "off", "disable", "false":
+		enable = false
+	case "status":
+		enable = current
+	default:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown mode %q (expected on, off, toggle, or status)", mode)}
+	}
+
+	if enable != current {
+		value := ""
+		if enable {
+			value = "true"
+		}
+		if err := e.sessions.SetMetadata(sessionID, orchestrator.MetaDryRun, value); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("persist dry-run flag: %v", err)}
+		}
+	}
+	if enable {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Dry-run mode ON. Tools that change something are simulated, not run; read-only tools still run. Send /dryrun off to stop."}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: "Dry-run mode OFF. Tools run for real."}
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func TestSetDryRun(t *testing.T) {
+	exec, sessions := newDebugTestExecutor(t)
+	run := func(mode string) orchestrator.ToolResult {
+		return exec.Execute(context.Background(), orchestrator.ToolCall{
+			ID: "x", Plugin: PluginName, Action: ActionSetDryRun,
+			Args: map[string]string{"session_id": "sess-1", "mode": mode},
+		})
+	}
+	flag := func() string {
+		sess, _ := sessions.Get("sess-1")
+		return sess.Metadata[orchestrator.MetaDryRun]
+	}
+
+	if res := run(""); !strings.Contains(res.Content, "ON") || flag() != "true" {
+		t.Errorf("toggle on: %+v, flag %q", res, flag())
+	}
+	if res := run("status"); !strings.Contains(res.Content, "ON") || flag() != "true" {
+		t.Errorf("status: %+v, flag %q", res, flag())
+	}
+	if res := run("on"); res.Error != "" || flag() != "true" {
+		t.Errorf("on again: %+v", res)
+	}
+	if res := run("off"); !strings.Contains(res.Content, "OFF") || flag() != "" {
+		t.Errorf("off: %+v, flag %q", res, flag())
+	}
+	if res := run("maybe"); res.Error == "" {
+		t.Error("unknown mode accepted")
+	}
+}
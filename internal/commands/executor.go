@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/opentalon/opentalon/internal/bundle"
 	"github.com/opentalon/opentalon/internal/config"
@@ -28,6 +29,17 @@ const (
 	ActionProfileAssign    = "profile_assign"
 	ActionProfileRevoke    = "profile_revoke"
 	ActionProfileListGroup = "profile_list_group"
+	ActionCapabilities     = "capabilities"
+	ActionLinkSession      = "link_session"
+	ActionPinFact          = "pin_fact"
+	ActionSystemPrompt     = "system_prompt"
+	ActionSkillSearch      = "skill_search"
+	ActionSkillList        = "skill_list"
+	ActionSkillUpdate      = "skill_update"
+	ActionSkillPin         = "skill_pin"
+	ActionBranchSession    = "branch_session"
+	ActionSetDryRun        = "set_dry_run"
+	ActionSetSessionModel  = "set_session_model"
 )
 
 // PluginReloader can reload a named plugin subprocess.
@@ -64,10 +76,15 @@ type Executor struct {
 	dataDir            string
 	cfg                *config.Config
 	runtimePromptPath  string
-	pluginReloader     PluginReloader     // optional; enables reload_mcp
-	mcpCacheDir        string             // optional; mcp-cache dir for cache invalidation on reload
-	groupPluginManager GroupPluginManager // optional; enables profile_assign/revoke/list_group
-	debugEventCounter  DebugEventCounter  // optional; populates "status" reply with row counts
+	pluginReloader     PluginReloader         // optional; enables reload_mcp
+	mcpCacheDir        string                 // optional; mcp-cache dir for cache invalidation on reload
+	groupPluginManager GroupPluginManager     // optional; enables profile_assign/revoke/list_group
+	debugEventCounter  DebugEventCounter      // optional; populates "status" reply with row counts
+	helpLLM            orchestrator.LLMClient // optional; polishes the capabilities summary
+	helpMu             sync.Mutex
+	helpCache          map[string]string // summary fingerprint → polished text
+	skills             *SkillMarket
+	replayer           BranchReplayer // optional; enables branch_session replay
 	onClearActions     []OnClearAction
 	runAction          func(ctx context.Context, plugin, action string, args map[string]string) (string, error)
 }
@@ -76,9 +93,9 @@ type Executor struct {
 func Capability() orchestrator.PluginCapability {
 	return orchestrator.PluginCapability{
 		Name:        PluginName,
-		Description: "Built-in OpenTalon commands: install skill, show config, list commands, set prompt, clear session, reload MCP, profile management.",
+		Description: "Built-in OpenTalon commands: install, search, update and pin skills, show config, list commands, capabilities summary, set prompt, clear session, link and branch sessions, pick a session's model, pin facts, session system prompt, reload MCP, profile management.",
 		Actions: []orchestrator.Action{
-			{Name: ActionInstallSkill, Description: "Install a skill from a GitHub URL (e.g. /install skill org/repo).", Parameters: []orchestrator.Parameter{{Name: "url", Description: "GitHub URL or org/repo", Required: true}, {Name: "ref", Description: "Branch or tag (default main)", Required: false}}, AuditLog: true, UserOnly: true},
+			{Name: ActionInstallSkill, Description: "Install a skill from a GitHub URL (e.g. /install skill org/repo) or by name from the skills index.", Parameters: []orchestrator.Parameter{{Name: "url", Description: "GitHub URL, org/repo, or a skill name from the skills index", Required: true}, {Name: "ref", Description: "Branch or tag (default main)", Required: false}}, AuditLog: true, UserOnly: true},
 			{Name: ActionShowConfig, Description: "Show current config (secrets redacted).", Parameters: nil},
 			{Name: ActionListCommands, Description: "List available slash commands.", Parameters: nil},
 			{Name: ActionSetPrompt, Description: "Set the editable runtime prompt.", Parameters: []orchestrator.Parameter{{Name: "text", Description: "Prompt text", Required: true}}},
@@ -88,6 +105,17 @@ func Capability() orchestrator.PluginCapability {
 			{Name: ActionProfileAssign, Description: "Assign a plugin to a profile group (admin). Source is set to 'admin' and cannot be overwritten by WhoAmI.", Parameters: []orchestrator.Parameter{{Name: "group", Description: "Group name", Required: true}, {Name: "plugin", Description: "Plugin ID", Required: true}}, AuditLog: true, UserOnly: true},
 			{Name: ActionProfileRevoke, Description: "Revoke a plugin from a profile group (admin).", Parameters: []orchestrator.Parameter{{Name: "group", Description: "Group name", Required: true}, {Name: "plugin", Description: "Plugin ID", Required: true}}, AuditLog: true, UserOnly: true},
 			{Name: ActionProfileListGroup, Description: "List plugins assigned to a profile group.", Parameters: []orchestrator.Parameter{{Name: "group", Description: "Group name", Required: true}}, UserOnly: true},
+			{Name: ActionLinkSession, Description: "Link the current conversation to a parent conversation so it sees the parent's summary and pinned facts (the user-facing /link command). Without a conversation id, a thread is linked to the conversation it belongs to; \"off\" removes the link.", Parameters: []orchestrator.Parameter{{Name: "conversation", Description: "Conversation id on this channel, or \"off\" (leave empty inside a thread)", Required: false}}, InjectContextArgs: []string{"session_id", "conversation_id"}, UserOnly: true},
+			{Name: ActionBranchSession, Description: "Fork the current conversation at a message index into a new session and replay the later user messages there, optionally with another model or system prompt (the user-facing /branch command). Use to debug why the assistant answered as it did, or to compare prompt variants.", Parameters: []orchestrator.Parameter{{Name: "at", Description: "Number of messages to keep (default: up to the last user message, which is asked again)", Required: false}, {Name: "model", Description: "Model for the branch, e.g. anthropic/claude-sonnet-4 (default: as this conversation)", Required: false}, {Name: "prompt", Description: "System prompt instructions for the branch, replacing this conversation's", Required: false}, {Name: "replay", Description: "\"false\" to only fork, without replaying", Required: false}}, InjectContextArgs: []string{"session_id"}, AuditLog: true, UserOnly: true},
+			{Name: ActionSetDryRun, Description: "Turn dry-run mode on or off for the current conversation (the user-facing /dryrun command). In dry-run mode the assistant works as usual, but tool calls that change something are simulated and reported instead of run; read-only tools still run.", Parameters: []orchestrator.Parameter{{Name: "mode", Description: "on, off, toggle (default), or status", Required: false}}, InjectContextArgs: []string{"session_id"}, AuditLog: true, UserOnly: true},
+			{Name: ActionSetSessionModel, Description: "Show or change the model the current conversation runs on (the user-facing /model command). Empty shows it; \"default\" goes back to the routing default. Use when the user asks for a specific model for this conversation.", Parameters: []orchestrator.Parameter{{Name: "model", Description: "Catalog alias (e.g. opus), provider/model, \"default\", or empty to show", Required: false}}, InjectContextArgs: []string{"session_id"}, AuditLog: true},
+			{Name: ActionPinFact, Description: "Pin a fact to the current conversation; pinned facts are shown to the assistant on every turn and in linked conversations (the user-facing /pin command). Empty lists the pinned facts; \"clear\" removes them.", Parameters: []orchestrator.Parameter{{Name: "fact", Description: "Fact to pin, \"clear\", or empty to list", Required: false}}, InjectContextArgs: []string{"session_id"}, UserOnly: true},
+			{Name: ActionSystemPrompt, Description: "Add instructions to the system prompt of the current conversation (admin; the user-facing /system command). Empty lists them; \"clear\" removes them.", Parameters: []orchestrator.Parameter{{Name: "text", Description: "Instruction to add, \"clear\", or empty to list", Required: false}}, InjectContextArgs: []string{"session_id"}, AuditLog: true, UserOnly: true},
+			{Name: ActionSkillSearch, Description: "Search the skills index for installable skills by name, description or tag.", Parameters: []orchestrator.Parameter{{Name: "query", Description: "Words to look for (empty lists every skill)", Required: false}}, ReadOnly: true},
+			{Name: ActionSkillList, Description: "List installed skills with their ref and locked commit.", Parameters: nil, ReadOnly: true},
+			{Name: ActionSkillUpdate, Description: "Update installed skills to the latest commit of their ref (as listed in the skills index); pinned skills are skipped.", Parameters: []orchestrator.Parameter{{Name: "name", Description: "Skill to update (empty = all)", Required: false}}, AuditLog: true, UserOnly: true},
+			{Name: ActionSkillPin, Description: "Pin an installed skill to a commit so updates leave it alone; installing it again unpins it.", Parameters: []orchestrator.Parameter{{Name: "name", Description: "Installed skill", Required: true}, {Name: "ref", Description: "Branch, tag or commit to pin (default the installed commit)", Required: false}}, AuditLog: true, UserOnly: true},
+			{Name: ActionCapabilities, Description: "Summarize what this assistant can do: available tools grouped by plugin, with example requests and the slash commands. Use when the user asks for help or what you can do.", Parameters: nil, ReadOnly: true},
 		},
 	}
 }
@@ -100,12 +128,17 @@ func NewExecutor(
 	cfg *config.Config,
 	runtimePromptPath string,
 ) *Executor {
+	var index config.SkillsIndexConfig
+	if cfg != nil {
+		index = cfg.RequestPackages.SkillsIndex
+	}
 	return &Executor{
 		registry:          registry,
 		sessions:          sessions,
 		dataDir:           dataDir,
 		cfg:               cfg,
 		runtimePromptPath: runtimePromptPath,
+		skills:            NewSkillMarket(dataDir, index),
 	}
 }
 
@@ -140,6 +173,15 @@ func (e *Executor) WithDebugEventCounter(c DebugEventCounter) *Executor {
 	return e
 }
 
+// WithHelpPolish makes the capabilities (/help) summary go through one LLM
+// pass that rewrites it into friendlier onboarding copy. Results are cached
+// until the set of visible tools changes. Without it the registry-generated
+// summary is returned as is.
+func (e *Executor) WithHelpPolish(llm orchestrator.LLMClient) *Executor {
+	e.helpLLM = llm
+	return e
+}
+
 // Execute implements orchestrator.PluginExecutor.
 func (e *Executor) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
 	switch call.Action {
@@ -163,6 +205,28 @@ func (e *Executor) Execute(ctx context.Context, call orchestrator.ToolCall) orch
 		return e.profileRevoke(ctx, call)
 	case ActionProfileListGroup:
 		return e.profileListGroup(ctx, call)
+	case ActionCapabilities:
+		return e.capabilities(ctx, call)
+	case ActionLinkSession:
+		return e.linkSession(call)
+	case ActionBranchSession:
+		return e.branchSession(ctx, call)
+	case ActionSetDryRun:
+		return e.setDryRun(call)
+	case ActionSetSessionModel:
+		return e.setSessionModel(ctx, call)
+	case ActionPinFact:
+		return e.pinFact(call)
+	case ActionSystemPrompt:
+		return e.systemPrompt(ctx, call)
+	case ActionSkillSearch:
+		return e.skillSearch(ctx, call)
+	case ActionSkillList:
+		return e.skillList(call)
+	case ActionSkillUpdate:
+		return e.skillUpdate(ctx, call)
+	case ActionSkillPin:
+		return e.skillPin(ctx, call)
 	default:
 		return orchestrator.ToolResult{
 			CallID: call.ID,
@@ -230,6 +294,10 @@ func (e *Executor) installSkill(ctx context.Context, call orchestrator.ToolCall)
 		ref = "main"
 	}
 
+	if safeSkillName(url) {
+		return e.installIndexedSkill(ctx, call, url, strings.TrimSpace(call.Args["ref"]))
+	}
+
 	github, name := parseInstallURL(url)
 	if github == "" || name == "" {
 		return orchestrator.ToolResult{CallID: call.ID, Error: "could not parse url: use https://github.com/org/repo or org/repo"}
@@ -318,15 +386,26 @@ func (e *Executor) showConfig(call orchestrator.ToolCall) orchestrator.ToolResul
 	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
 }
 
-func (e *Executor) listCommands(call orchestrator.ToolCall) orchestrator.ToolResult {
-	const msg = `/install skill <url> [ref] — Install a skill from a GitHub URL (or org/repo). Optional ref defaults to main.
+// commandsHelp is the slash-command reference shared by /commands and /help.
+const commandsHelp = `/help — Summarize what this assistant can do, with example requests.
+/install skill <url> [ref] — Install a skill from a GitHub URL (or org/repo), or by name from the skills index. Optional ref defaults to main.
+/skill search [words] — Search the skills index.
+/skill list — List installed skills and their commits.
+/skill update [name] — Update installed skills (pinned ones are skipped).
+/skill pin <name> [ref] — Pin an installed skill to a commit.
 /show config — Show current config (secrets redacted).
 /commands — List available commands (this message).
 /set prompt <text> — Set the editable runtime prompt; applies to the next message.
 /clear or /new — Clear the current session.
+/link [conversation|off] — Link this conversation to a parent so it sees the parent's summary and pinned facts. In a thread, no argument links it to its channel conversation.
+/branch [at] [model] — Fork this conversation at a message index into a new session and replay the rest there, e.g. with another model.
+/model [alias|provider/model|default] — Show or change the model of this conversation.
+/pin [fact|clear] — Pin a fact to this conversation (shown to the assistant every turn and in linked conversations). No argument lists pinned facts.
 /reload mcp [server] — Reload MCP server connections and refresh available tools. Optionally name a specific server (e.g. /reload mcp magtuner).
 /debug [on|off|status] — Toggle per-session deep debug logging. With no arg the flag toggles. Captured raw LLM HTTP bodies stay in ai_debug_events for 30 days.`
-	return orchestrator.ToolResult{CallID: call.ID, Content: msg}
+
+func (e *Executor) listCommands(call orchestrator.ToolCall) orchestrator.ToolResult {
+	return orchestrator.ToolResult{CallID: call.ID, Content: commandsHelp}
 }
 
 func (e *Executor) reloadMCP(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
@@ -0,0 +1,101 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// linkSession links the current session to a parent conversation (/link).
+// The target is another conversation id on the same channel and in the same
+// entity scope; empty means "the conversation this thread belongs to", and
+// "off" removes the link.
+func (e *Executor) linkSession(call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	target := strings.TrimSpace(call.Args["conversation"])
+	if strings.EqualFold(target, "off") {
+		if err := orchestrator.LinkSessions(e.sessions, sessionID, "", false); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unlink: %v", err)}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Link removed. This conversation no longer sees its parent's context."}
+	}
+
+	parent, ok := parentSessionKey(sessionID, call.Args["conversation_id"], target)
+	if !ok {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "this is not a thread; name the conversation to link to (/link <conversation>)"}
+	}
+	err := orchestrator.LinkSessions(e.sessions, sessionID, parent, false)
+	switch {
+	case errors.Is(err, state.ErrSessionNotFound):
+		return orchestrator.ToolResult{CallID: call.ID, Error: "no conversation with that id on this channel"}
+	case err != nil:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("link: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: "Linked. From the next message on, this conversation sees the parent's summary and pinned facts."}
+}
+
+// parentSessionKey derives the session key of the parent conversation from
+// the current key ([entity:]channel:conversation[:thread]). With an empty
+// target the current key must carry a thread suffix, which is dropped; with a
+// target the conversation (and any thread) is replaced by it. The entity and
+// channel prefix is always kept, so /link can never reach another entity's
+// sessions.
+func parentSessionKey(sessionID, conversationID, target string) (string, bool) {
+	if conversationID == "" {
+		return "", false
+	}
+	conv := ":" + conversationID
+	var prefix string
+	var isThread bool
+	if strings.HasSuffix(sessionID, conv) {
+		prefix = strings.TrimSuffix(sessionID, conv)
+	} else if i := strings.LastIndex(sessionID, conv+":"); i >= 0 {
+		prefix, isThread = sessionID[:i], true
+	} else {
+		return "", false
+	}
+	if target == "" {
+		if !isThread {
+			return "", false
+		}
+		target = conversationID
+	}
+	return prefix + ":" + target, true
+}
+
+// pinFact pins a fact on the current session (/pin). With no text it lists
+// the pinned facts; "clear" removes them all.
+func (e *Executor) pinFact(call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	fact := strings.TrimSpace(call.Args["fact"])
+	switch {
+	case fact == "":
+		sess, err := e.sessions.Get(sessionID)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("session lookup: %v", err)}
+		}
+		facts := orchestrator.PinnedFacts(sess)
+		if len(facts) == 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Content: "No pinned facts. Use /pin <fact> to add one."}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Pinned facts:\n- " + strings.Join(facts, "\n- ")}
+	case strings.EqualFold(fact, "clear"):
+		if err := orchestrator.UnpinFacts(e.sessions, sessionID); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unpin: %v", err)}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Pinned facts cleared."}
+	}
+	if err := orchestrator.PinFact(e.sessions, sessionID, fact); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("pin: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: "Pinned."}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestParentSessionKey(t *testing.T) {
+	tests := []struct {
+		session, conv, target string
+		want                  string
+		ok                    bool
+	}{
+		{"slack:C1:170.1", "C1", "", "slack:C1", true},
+		{"ent1:slack:C1:170.1", "C1", "", "ent1:slack:C1", true},
+		{"slack:C1", "C1", "", "", false}, // not a thread, no target
+		{"slack:C1", "C1", "C9", "slack:C9", true},
+		{"ent1:slack:C1:170.1", "C1", "C9", "ent1:slack:C9", true},
+		{"slack:C1", "", "C9", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parentSessionKey(tt.session, tt.conv, tt.target)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parentSessionKey(%q, %q, %q) = %q, %v; want %q, %v", tt.session, tt.conv, tt.target, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestExecutor_LinkAndPin(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	sessions.Create("slack:C1:170.1", "", "", "")
+	e := NewExecutor(orchestrator.NewToolRegistry(), sessions, "", nil, "")
+	run := func(action string, args map[string]string) orchestrator.ToolResult {
+		return e.Execute(context.Background(), orchestrator.ToolCall{ID: "c", Plugin: PluginName, Action: action, Args: args})
+	}
+
+	res := run(ActionLinkSession, map[string]string{"session_id": "slack:C1:170.1", "conversation_id": "C1"})
+	if res.Error != "" {
+		t.Fatalf("link: %s", res.Error)
+	}
+	if s, _ := sessions.Get("slack:C1:170.1"); s.Metadata[orchestrator.MetaParentSession] != "slack:C1" {
+		t.Errorf("parent = %q", s.Metadata[orchestrator.MetaParentSession])
+	}
+	if res := run(ActionLinkSession, map[string]string{"session_id": "slack:C1", "conversation_id": "C1", "conversation": "nope"}); !strings.Contains(res.Error, "no conversation") {
+		t.Errorf("link to unknown conversation: %+v", res)
+	}
+	if res := run(ActionLinkSession, map[string]string{"session_id": "slack:C1:170.1", "conversation_id": "C1", "conversation": "off"}); res.Error != "" {
+		t.Fatalf("unlink: %s", res.Error)
+	}
+	if s, _ := sessions.Get("slack:C1:170.1"); s.Metadata[orchestrator.MetaParentSession] != "" {
+		t.Error("link not removed")
+	}
+
+	if res := run(ActionPinFact, map[string]string{"session_id": "slack:C1", "fact": "Budget is $40k"}); res.Error != "" {
+		t.Fatalf("pin: %s", res.Error)
+	}
+	if res := run(ActionPinFact, map[string]string{"session_id": "slack:C1"}); !strings.Contains(res.Content, "Budget is $40k") {
+		t.Errorf("list = %q", res.Content)
+	}
+	run(ActionPinFact, map[string]string{"session_id": "slack:C1", "fact": "clear"})
+	if res := run(ActionPinFact, map[string]string{"session_id": "slack:C1"}); !strings.Contains(res.Content, "No pinned facts") {
+		t.Errorf("after clear = %q", res.Content)
+	}
+}
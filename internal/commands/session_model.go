@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+// setSessionModel shows or pins the model of one session (the user-facing
+// /model command) in orchestrator.MetaModel. Run sends a pinned session's
+// turns to that model instead of the routing default; "default" removes the
+// pin. Only the callers listed in commands.permissions.model may change it;
+// when that list is empty, everyone may.
+func (e *Executor) setSessionModel(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	if e.cfg == nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "config not available"}
+	}
+	name := strings.TrimSpace(call.Args["model"])
+	if name == "" {
+		sess, err := e.sessions.Get(sessionID)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("session lookup: %v", err)}
+		}
+		if pin := sess.Metadata[orchestrator.MetaModel]; pin != "" {
+			return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("This conversation uses %s.", pin)}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("This conversation uses the default model (%s).", e.cfg.Routing.Primary)}
+	}
+	caller := callerID(ctx)
+	if !e.mayPinModel(ctx, caller) {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "you are not allowed to change the model of this conversation"}
+	}
+	var ref string
+	if !strings.EqualFold(name, "default") {
+		resolved, err := e.cfg.Models.Resolve(name)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		ref = resolved
+	}
+	if err := e.sessions.SetMetadata(sessionID, orchestrator.MetaModel, ref); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("set model: %v", err)}
+	}
+	slog.Info("audit", "event", "session_model_set", "session_id", sessionID, "actor", caller, "model", ref)
+	if ref == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "This conversation is back on the default model."}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("This conversation now uses %s.", ref)}
+}
+
+// mayPinModel applies commands.permissions.model: the caller id or the
+// caller's profile group must be listed, unless nothing is.
+func (e *Executor) mayPinModel(ctx context.Context, caller string) bool {
+	perm := e.cfg.Commands.Permissions["model"]
+	if len(perm.Users) == 0 && len(perm.Groups) == 0 {
+		return true
+	}
+	if slices.Contains(perm.Users, caller) {
+		return true
+	}
+	p := profile.FromContext(ctx)
+	return p != nil && p.Group != "" && slices.Contains(perm.Groups, p.Group)
+}
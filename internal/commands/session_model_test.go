@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestExecutor_SetSessionModel(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	cfg := &config.Config{}
+	cfg.Routing.Primary = "anthropic/claude-sonnet-4"
+	cfg.Models.Catalog = map[string]config.CatalogEntry{"anthropic/claude-opus-4": {Alias: "opus"}}
+	cfg.Commands.Permissions = map[string]config.CommandPermission{"model": {Users: []string{"slack:U1"}, Groups: []string{"admins"}}}
+	e := NewExecutor(orchestrator.NewToolRegistry(), sessions, "", cfg, "")
+	run := func(ctx context.Context, model string) orchestrator.ToolResult {
+		return e.Execute(ctx, orchestrator.ToolCall{ID: "c", Plugin: PluginName, Action: ActionSetSessionModel, Args: map[string]string{"session_id": "slack:C1", "model": model}})
+	}
+	u1 := actor.WithActor(context.Background(), "slack:U1")
+
+	if res := run(u1, "opus"); res.Error != "" {
+		t.Fatalf("set: %s", res.Error)
+	}
+	if s, _ := sessions.Get("slack:C1"); s.Metadata[orchestrator.MetaModel] != "anthropic/claude-opus-4" {
+		t.Errorf("pinned model = %q", s.Metadata[orchestrator.MetaModel])
+	}
+	// Anyone may look; only the listed callers may change it.
+	u2 := actor.WithActor(context.Background(), "slack:U2")
+	if res := run(u2, ""); res.Content != "This conversation uses anthropic/claude-opus-4." {
+		t.Errorf("show: %+v", res)
+	}
+	if res := run(u2, "default"); !strings.Contains(res.Error, "not allowed") {
+		t.Errorf("unlisted caller: %+v", res)
+	}
+	admin := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "e9", Group: "admins"})
+	if res := run(admin, "gpt-9"); !strings.Contains(res.Error, `unknown model "gpt-9"`) {
+		t.Errorf("unknown model: %+v", res)
+	}
+	if res := run(admin, "default"); res.Error != "" {
+		t.Fatalf("default: %s", res.Error)
+	}
+	if res := run(u2, ""); !strings.Contains(res.Content, "default model (anthropic/claude-sonnet-4)") {
+		t.Errorf("show after default: %+v", res)
+	}
+}
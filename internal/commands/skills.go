@@ -0,0 +1,344 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/bundle"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/requestpkg"
+)
+
+// SkillMarket searches the configured skills index and installs, updates
+// and pins the skills it lists. Installed skills are recorded in
+// installed_skills.yaml (loaded at startup); their resolved commits and
+// checksums in skills.lock. A skill pinned to a commit is left alone by
+// Update until it is installed again.
+type SkillMarket struct {
+	dataDir string
+	index   config.SkillsIndexConfig
+}
+
+// NewSkillMarket returns a SkillMarket over the skills index in cfg.
+func NewSkillMarket(dataDir string, index config.SkillsIndexConfig) *SkillMarket {
+	if index.Ref == "" {
+		index.Ref = "main"
+	}
+	return &SkillMarket{dataDir: dataDir, index: index}
+}
+
+// InstalledSkill describes one entry of installed_skills.yaml.
+type InstalledSkill struct {
+	Name     string
+	GitHub   string
+	Ref      string
+	Resolved string // commit in skills.lock; empty when not fetched yet
+	Checksum string
+	Pinned   bool
+}
+
+// SkillUpdate is the outcome of updating one installed skill.
+type SkillUpdate struct {
+	Name     string
+	From, To string // resolved commits
+	Pinned   bool   // skipped: pinned to a commit
+	Err      error
+	Set      *requestpkg.Set // the reloaded skill when it changed
+}
+
+func (u SkillUpdate) String() string {
+	switch {
+	case u.Err != nil:
+		return fmt.Sprintf("%s: update failed: %v", u.Name, u.Err)
+	case u.Pinned:
+		return fmt.Sprintf("%s: pinned at %s, skipped", u.Name, shortSHA(u.From))
+	case u.From == u.To:
+		return fmt.Sprintf("%s: up to date (%s)", u.Name, shortSHA(u.To))
+	}
+	return fmt.Sprintf("%s: updated %s -> %s", u.Name, shortSHA(u.From), shortSHA(u.To))
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	if sha == "" {
+		return "(none)"
+	}
+	return sha
+}
+
+func (m *SkillMarket) loadIndex(ctx context.Context) (*bundle.SkillIndex, error) {
+	if m.index.GitHub == "" {
+		return nil, fmt.Errorf("no skills index configured (request_packages.skills_index.github)")
+	}
+	if m.dataDir == "" {
+		return nil, fmt.Errorf("data_dir is required for skills")
+	}
+	return bundle.LoadSkillIndex(ctx, m.dataDir, m.index.GitHub, m.index.Ref)
+}
+
+// Search returns the index entries matching query.
+func (m *SkillMarket) Search(ctx context.Context, query string) ([]bundle.IndexedSkill, error) {
+	idx, err := m.loadIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Search(query), nil
+}
+
+// Install fetches the named index skill into the data dir, verifies its
+// checksum and records it as installed. ref overrides the index's ref; the
+// index checksum is only checked at the index's own ref.
+func (m *SkillMarket) Install(ctx context.Context, name, ref string) (requestpkg.Set, error) {
+	idx, err := m.loadIndex(ctx)
+	if err != nil {
+		return requestpkg.Set{}, err
+	}
+	s, ok := idx.Find(name)
+	if !ok {
+		return requestpkg.Set{}, fmt.Errorf("skill %q is not in the index", name)
+	}
+	want := s.SHA256
+	if ref != "" && ref != s.Ref {
+		want = ""
+	} else {
+		ref = s.Ref
+	}
+	return m.install(ctx, name, s.GitHub, ref, want)
+}
+
+func (m *SkillMarket) install(ctx context.Context, name, github, ref, want string) (requestpkg.Set, error) {
+	if !safeSkillName(name) {
+		return requestpkg.Set{}, fmt.Errorf("invalid skill name %q", name)
+	}
+	dir, _, err := bundle.InstallSkillDir(ctx, m.dataDir, name, github, ref, want)
+	if err != nil {
+		return requestpkg.Set{}, err
+	}
+	set, err := requestpkg.LoadSkillDir(dir)
+	if err != nil {
+		return requestpkg.Set{}, fmt.Errorf("load skill: %w", err)
+	}
+	if err := config.UpsertInstalledSkill(m.dataDir, config.SkillEntry{Name: name, GitHub: github, Ref: ref}); err != nil {
+		return requestpkg.Set{}, fmt.Errorf("persist installed skills: %w", err)
+	}
+	return set, nil
+}
+
+// Update fetches the latest commit of each installed skill (or only name),
+// following the index's repo, ref and checksum for skills it lists. Pinned
+// skills are skipped. The index is optional: without one, skills are
+// updated from the repo and ref they were installed from.
+func (m *SkillMarket) Update(ctx context.Context, name string) ([]SkillUpdate, error) {
+	installed, err := m.List()
+	if err != nil {
+		return nil, err
+	}
+	var idx *bundle.SkillIndex
+	if m.index.GitHub != "" {
+		if idx, err = m.loadIndex(ctx); err != nil {
+			return nil, err
+		}
+	}
+	var updates []SkillUpdate
+	for _, s := range installed {
+		if name != "" && s.Name != name {
+			continue
+		}
+		u := SkillUpdate{Name: s.Name, From: s.Resolved}
+		if s.Pinned {
+			u.Pinned = true
+			updates = append(updates, u)
+			continue
+		}
+		github, ref, want := s.GitHub, s.Ref, ""
+		if idx != nil {
+			if entry, ok := idx.Find(s.Name); ok {
+				github, ref, want = entry.GitHub, entry.Ref, entry.SHA256
+			}
+		}
+		set, err := m.install(ctx, s.Name, github, ref, want)
+		if err != nil {
+			u.Err = err
+			updates = append(updates, u)
+			continue
+		}
+		u.To = m.resolved(s.Name)
+		if u.To != u.From {
+			u.Set = &set
+		}
+		updates = append(updates, u)
+	}
+	if name != "" && len(updates) == 0 {
+		return nil, fmt.Errorf("skill %q is not installed", name)
+	}
+	return updates, nil
+}
+
+// List returns the installed skills with their locked commits.
+func (m *SkillMarket) List() ([]InstalledSkill, error) {
+	entries, err := config.LoadInstalledSkills(m.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := bundle.LoadSkillsLock(m.dataDir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]InstalledSkill, 0, len(entries))
+	for _, e := range entries {
+		l := lock.Skills[e.Name]
+		out = append(out, InstalledSkill{
+			Name: e.Name, GitHub: e.GitHub, Ref: e.Ref,
+			Resolved: l.Resolved, Checksum: l.Checksum, Pinned: bundle.IsCommitSHA(e.Ref),
+		})
+	}
+	return out, nil
+}
+
+// Pin fixes an installed skill at a commit: ref resolved on the skill's repo,
+// or the currently locked commit when ref is empty. It returns the commit.
+func (m *SkillMarket) Pin(ctx context.Context, name, ref string) (string, error) {
+	s, err := m.installed(name)
+	if err != nil {
+		return "", err
+	}
+	sha := s.Resolved
+	if ref != "" {
+		if sha, err = bundle.ResolveRef(ctx, s.GitHub, ref); err != nil {
+			return "", err
+		}
+	}
+	if sha == "" {
+		return "", fmt.Errorf("skill %q has not been fetched yet; give a ref to pin", name)
+	}
+	if _, err := m.install(ctx, name, s.GitHub, sha, ""); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+func (m *SkillMarket) installed(name string) (InstalledSkill, error) {
+	list, err := m.List()
+	if err != nil {
+		return InstalledSkill{}, err
+	}
+	for _, s := range list {
+		if s.Name == name {
+			return s, nil
+		}
+	}
+	return InstalledSkill{}, fmt.Errorf("skill %q is not installed", name)
+}
+
+func (m *SkillMarket) resolved(name string) string {
+	lock, err := bundle.LoadSkillsLock(m.dataDir)
+	if err != nil {
+		return ""
+	}
+	return lock.Skills[name].Resolved
+}
+
+// FormatSkillSearch renders search results one per line.
+func FormatSkillSearch(skills []bundle.IndexedSkill) string {
+	if len(skills) == 0 {
+		return "No matching skills in the index."
+	}
+	var b strings.Builder
+	for _, s := range skills {
+		fmt.Fprintf(&b, "%s — %s (%s@%s)", s.Name, s.Description, s.GitHub, s.Ref)
+		if len(s.Tags) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(s.Tags, ", "))
+		}
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// FormatSkillList renders installed skills one per line.
+func FormatSkillList(skills []InstalledSkill) string {
+	if len(skills) == 0 {
+		return "No skills installed."
+	}
+	var b strings.Builder
+	for _, s := range skills {
+		fmt.Fprintf(&b, "%s — %s@%s at %s", s.Name, s.GitHub, s.Ref, shortSHA(s.Resolved))
+		if s.Pinned {
+			b.WriteString(" (pinned)")
+		}
+		b.WriteByte('\n')
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (e *Executor) installIndexedSkill(ctx context.Context, call orchestrator.ToolCall, name, ref string) orchestrator.ToolResult {
+	set, err := e.skills.Install(ctx, name, ref)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("install skill: %v", err)}
+	}
+	if err := e.registerSkill(set); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("Installed skill %q. It is available immediately.", name)}
+}
+
+// registerSkill (re)registers a skill's plugin so an install or update
+// takes effect without a restart.
+func (e *Executor) registerSkill(set requestpkg.Set) error {
+	e.registry.Deregister(set.Name())
+	if err := requestpkg.Register(e.registry, []requestpkg.Set{set}); err != nil {
+		return fmt.Errorf("register skill: %w", err)
+	}
+	return nil
+}
+
+func (e *Executor) skillSearch(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	found, err := e.skills.Search(ctx, call.Args["query"])
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("search skills: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: FormatSkillSearch(found)}
+}
+
+func (e *Executor) skillList(call orchestrator.ToolCall) orchestrator.ToolResult {
+	list, err := e.skills.List()
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("list skills: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: FormatSkillList(list)}
+}
+
+func (e *Executor) skillUpdate(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	updates, err := e.skills.Update(ctx, strings.TrimSpace(call.Args["name"]))
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("update skills: %v", err)}
+	}
+	if len(updates) == 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "No skills installed."}
+	}
+	lines := make([]string, 0, len(updates))
+	for _, u := range updates {
+		if u.Set != nil {
+			if err := e.registerSkill(*u.Set); err != nil {
+				u.Err = err
+			}
+		}
+		lines = append(lines, u.String())
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: strings.Join(lines, "\n")}
+}
+
+func (e *Executor) skillPin(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	name := strings.TrimSpace(call.Args["name"])
+	if name == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "missing name"}
+	}
+	sha, err := e.skills.Pin(ctx, name, strings.TrimSpace(call.Args["ref"]))
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("pin skill: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("Pinned skill %q at %s.", name, shortSHA(sha))}
+}
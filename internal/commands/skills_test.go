@@ -0,0 +1,198 @@
+package commands
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/bundle"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// localRepo is a git repo in a temp dir, addressed by its file:// URL.
+type localRepo struct {
+	t   *testing.T
+	dir string
+	url string
+}
+
+func newLocalRepo(t *testing.T) *localRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &localRepo{t: t, dir: t.TempDir()}
+	r.url = "file://" + r.dir
+	r.git("init", "-q")
+	return r
+}
+
+func (r *localRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com", "-c", "init.defaultBranch=main"}, args...)...)
+	cmd.Dir = r.dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *localRepo) commit(name, content string) string {
+	r.t.Helper()
+	if err := os.WriteFile(filepath.Join(r.dir, name), []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", "update "+name)
+	return r.git("rev-parse", "HEAD")
+}
+
+const helloRequest = `plugin: hello
+description: Greetings
+packages:
+  - action: greet
+    description: Say hello
+    method: GET
+    url: "https://example.com/hello"
+`
+
+func writeIndex(t *testing.T, index *localRepo, skill *localRepo, sha string) {
+	t.Helper()
+	entry := "skills:\n  - name: hello\n    description: Say hello to people\n    github: " + skill.url + "\n    tags: [greeting]\n"
+	if sha != "" {
+		entry += "    sha256: " + sha + "\n"
+	}
+	index.commit(bundle.SkillIndexFile, entry)
+}
+
+func TestSkillMarket(t *testing.T) {
+	skill := newLocalRepo(t)
+	first := skill.commit("request.yaml", helloRequest)
+	index := newLocalRepo(t)
+	writeIndex(t, index, skill, "")
+
+	dataDir := t.TempDir()
+	m := NewSkillMarket(dataDir, config.SkillsIndexConfig{GitHub: index.url})
+	ctx := context.Background()
+
+	found, err := m.Search(ctx, "greeting")
+	if err != nil || len(found) != 1 || found[0].Name != "hello" {
+		t.Fatalf("search: %v %+v", err, found)
+	}
+	if _, err := m.Install(ctx, "nope", ""); err == nil || !strings.Contains(err.Error(), "not in the index") {
+		t.Errorf("unknown skill: %v", err)
+	}
+	set, err := m.Install(ctx, "hello", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.PluginName != "hello" || len(set.Packages) != 1 {
+		t.Errorf("set = %+v", set)
+	}
+	list, _ := m.List()
+	if len(list) != 1 || list[0].Resolved != first || list[0].Checksum == "" || list[0].Pinned {
+		t.Fatalf("list = %+v", list)
+	}
+
+	second := skill.commit("request.yaml", strings.Replace(helloRequest, "Say hello", "Say hi", 1))
+	updates, err := m.Update(ctx, "")
+	if err != nil || len(updates) != 1 || updates[0].To != second || updates[0].Set == nil {
+		t.Fatalf("update: %v %+v", err, updates)
+	}
+	if updates, _ = m.Update(ctx, "hello"); updates[0].Set != nil || !strings.Contains(updates[0].String(), "up to date") {
+		t.Errorf("second update: %+v", updates)
+	}
+
+	sha, err := m.Pin(ctx, "hello", first)
+	if err != nil || sha != first {
+		t.Fatalf("pin: %s %v", sha, err)
+	}
+	skill.commit("request.yaml", helloRequest+"# third\n")
+	if updates, _ = m.Update(ctx, ""); !updates[0].Pinned {
+		t.Errorf("pinned skill updated: %+v", updates)
+	}
+	installed, _ := config.LoadInstalledSkills(dataDir)
+	if len(installed) != 1 || installed[0].Ref != first {
+		t.Errorf("installed_skills.yaml = %+v", installed)
+	}
+	if _, err := m.Update(ctx, "other"); err == nil {
+		t.Error("updating a skill that is not installed should fail")
+	}
+}
+
+func TestSkillMarket_ChecksumMismatch(t *testing.T) {
+	skill := newLocalRepo(t)
+	sha := skill.commit("request.yaml", helloRequest)
+	index := newLocalRepo(t)
+	writeIndex(t, index, skill, strings.Repeat("ab", 32))
+
+	dataDir := t.TempDir()
+	m := NewSkillMarket(dataDir, config.SkillsIndexConfig{GitHub: index.url})
+	if _, err := m.Install(context.Background(), "hello", ""); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("install: %v", err)
+	}
+	if list, _ := m.List(); len(list) != 0 {
+		t.Errorf("a rejected skill must not be recorded: %+v", list)
+	}
+	// A ref other than the index's is not covered by its checksum.
+	if _, err := m.Install(context.Background(), "hello", sha); err != nil {
+		t.Errorf("install at an explicit ref: %v", err)
+	}
+}
+
+func TestExecutor_SkillActions(t *testing.T) {
+	skill := newLocalRepo(t)
+	skill.commit("request.yaml", helloRequest)
+	index := newLocalRepo(t)
+	writeIndex(t, index, skill, "")
+
+	reg := orchestrator.NewToolRegistry()
+	cfg := &config.Config{}
+	cfg.RequestPackages.SkillsIndex = config.SkillsIndexConfig{GitHub: index.url}
+	e := NewExecutor(reg, state.NewSessionStore(""), t.TempDir(), cfg, "")
+	ctx := context.Background()
+
+	res := e.Execute(ctx, orchestrator.ToolCall{ID: "1", Action: ActionSkillSearch, Args: map[string]string{"query": "hello"}})
+	if res.Error != "" || !strings.HasPrefix(res.Content, "hello — Say hello to people") {
+		t.Errorf("search: %+v", res)
+	}
+	res = e.Execute(ctx, orchestrator.ToolCall{ID: "2", Action: ActionInstallSkill, Args: map[string]string{"url": "hello"}})
+	if res.Error != "" {
+		t.Fatalf("install by name: %+v", res)
+	}
+	if !reg.HasAction("hello", "greet") {
+		t.Error("installed skill is not registered")
+	}
+
+	skill.commit("request.yaml", strings.Replace(helloRequest, "action: greet", "action: wave", 1))
+	res = e.Execute(ctx, orchestrator.ToolCall{ID: "3", Action: ActionSkillUpdate})
+	if res.Error != "" || !strings.Contains(res.Content, "hello: updated") {
+		t.Fatalf("update: %+v", res)
+	}
+	if !reg.HasAction("hello", "wave") || reg.HasAction("hello", "greet") {
+		t.Error("updated skill was not re-registered")
+	}
+
+	res = e.Execute(ctx, orchestrator.ToolCall{ID: "4", Action: ActionSkillPin, Args: map[string]string{"name": "hello"}})
+	if res.Error != "" || !strings.HasPrefix(res.Content, `Pinned skill "hello" at `) {
+		t.Errorf("pin: %+v", res)
+	}
+	res = e.Execute(ctx, orchestrator.ToolCall{ID: "5", Action: ActionSkillList})
+	if !strings.HasSuffix(res.Content, "(pinned)") {
+		t.Errorf("list: %+v", res)
+	}
+}
+
+func TestSkillSearch_NoIndex(t *testing.T) {
+	e := NewExecutor(orchestrator.NewToolRegistry(), state.NewSessionStore(""), t.TempDir(), &config.Config{}, "")
+	res := e.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: ActionSkillSearch})
+	if !strings.Contains(res.Error, "no skills index configured") {
+		t.Errorf("res = %+v", res)
+	}
+}
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+// systemPrompt manages the session layer of the system prompt (/system).
+// With text it appends an instruction, "clear" removes them, and no text
+// lists them. Only callers in orchestrator.prompt_layers.admins may use it.
+func (e *Executor) systemPrompt(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	sessionID := call.Args["session_id"]
+	if sessionID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "session_id not set (internal error)"}
+	}
+	caller := callerID(ctx)
+	if e.cfg == nil || !slices.Contains(e.cfg.Orchestrator.PromptLayers.Admins, caller) {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "only admins listed in orchestrator.prompt_layers.admins can change session instructions"}
+	}
+	text := strings.TrimSpace(call.Args["text"])
+	switch {
+	case text == "":
+		sess, err := e.sessions.Get(sessionID)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("session lookup: %v", err)}
+		}
+		current := orchestrator.SessionSystemPrompt(sess)
+		if current == "" {
+			return orchestrator.ToolResult{CallID: call.ID, Content: "No session instructions. Use /system <text> to add one."}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Session instructions:\n" + current}
+	case strings.EqualFold(text, "clear"):
+		if err := orchestrator.ClearSessionSystemPrompt(e.sessions, sessionID); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("clear: %v", err)}
+		}
+		slog.Info("audit", "event", "session_system_prompt_cleared", "session_id", sessionID, "actor", caller)
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Session instructions cleared."}
+	}
+	limit := e.cfg.Orchestrator.PromptLayers.Budgets[orchestrator.LayerSession]
+	if err := orchestrator.AddSessionSystemPrompt(e.sessions, sessionID, text, limit); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	slog.Info("audit", "event", "session_system_prompt_added", "session_id", sessionID, "actor", caller, "bytes", len(text))
+	return orchestrator.ToolResult{CallID: call.ID, Content: "Session instructions updated. They apply from the next message."}
+}
+
+// callerID identifies the caller for admin checks: the profile entity id in
+// profile mode, else the "channel:sender" actor.
+func callerID(ctx context.Context) string {
+	if p := profile.FromContext(ctx); p != nil && p.EntityID != "" {
+		return p.EntityID
+	}
+	return actor.Actor(ctx)
+}
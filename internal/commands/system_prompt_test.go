@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestExecutor_SystemPrompt(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	cfg := &config.Config{}
+	cfg.Orchestrator.PromptLayers.Admins = []string{"slack:UADMIN"}
+	cfg.Orchestrator.PromptLayers.Budgets = map[string]int{orchestrator.LayerSession: 30}
+	e := NewExecutor(orchestrator.NewToolRegistry(), sessions, "", cfg, "")
+	run := func(who, text string) orchestrator.ToolResult {
+		ctx := actor.WithActor(context.Background(), who)
+		return e.Execute(ctx, orchestrator.ToolCall{ID: "c", Plugin: PluginName, Action: ActionSystemPrompt, Args: map[string]string{"session_id": "slack:C1", "text": text}})
+	}
+
+	if res := run("slack:U2", "Be rude."); !strings.Contains(res.Error, "only admins") {
+		t.Errorf("non-admin: %+v", res)
+	}
+	if res := run("slack:UADMIN", "Answer in Spanish."); res.Error != "" {
+		t.Fatalf("add: %s", res.Error)
+	}
+	if res := run("slack:UADMIN", "Cite a ticket id every time."); !strings.Contains(res.Error, "limit is 30") {
+		t.Errorf("over budget: %+v", res)
+	}
+	if res := run("slack:UADMIN", ""); !strings.Contains(res.Content, "Answer in Spanish.") {
+		t.Errorf("list: %+v", res)
+	}
+	if res := run("slack:UADMIN", "clear"); res.Error != "" {
+		t.Fatalf("clear: %s", res.Error)
+	}
+	if s, _ := sessions.Get("slack:C1"); s.Metadata[orchestrator.MetaSystemPrompt] != "" {
+		t.Errorf("session layer not cleared: %q", s.Metadata[orchestrator.MetaSystemPrompt])
+	}
+}
@@ -1,10 +1,13 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -23,13 +26,147 @@ type Config struct {
 	Scheduler       SchedulerConfig          `yaml:"scheduler"`
 	RequestPackages RequestPackagesConfig    `yaml:"request_packages"`
 	Lua             *LuaConfig               `yaml:"lua,omitempty"`
+	JS              *JSConfig                `yaml:"js,omitempty"`
 	Profiles        ProfilesConfig           `yaml:"profiles,omitempty"`
 	Bootstrap       BootstrapConfig          `yaml:"bootstrap,omitempty"`
 	Redis           RedisConfig              `yaml:"redis,omitempty"`
 	Cluster         ClusterConfig            `yaml:"cluster,omitempty"`
 	PluginExec      PluginExecConfig         `yaml:"plugin_exec,omitempty"`
 	Health          HealthConfig             `yaml:"health,omitempty"`
+	Daemon          DaemonConfig             `yaml:"daemon,omitempty"`
+	Ctl             CtlConfig                `yaml:"ctl,omitempty"`
+	Dashboard       DashboardConfig          `yaml:"dashboard,omitempty"`
 	EventWebhook    *EventWebhookConfig      `yaml:"event_webhook,omitempty"`
+	Events          EventsConfig             `yaml:"events,omitempty"`
+	Evaluation      EvaluationConfig         `yaml:"evaluation,omitempty"`
+	Notify          NotifyConfig             `yaml:"notify,omitempty"`
+	Workflows       WorkflowsConfig          `yaml:"workflows,omitempty"`
+	Reload          ReloadConfig             `yaml:"reload,omitempty"`
+	Approvals       ApprovalsConfig          `yaml:"approvals,omitempty"`
+	Agents          []AgentConfig            `yaml:"agents,omitempty"`
+	Experiments     ExperimentsConfig        `yaml:"experiments,omitempty"`
+	RAG             RAGConfig                `yaml:"rag,omitempty"`
+	Delivery        DeliveryConfig           `yaml:"delivery,omitempty"`
+	Speech          SpeechConfig             `yaml:"speech,omitempty"`
+	Bundles         BundlesConfig            `yaml:"bundles,omitempty"`
+	Search          SearchConfig             `yaml:"search,omitempty"`
+	Memory          MemoryConfig             `yaml:"memory,omitempty"`
+	Stats           StatsConfig              `yaml:"stats,omitempty"`
+	Digest          DigestConfig             `yaml:"digest,omitempty"`
+	Commands        CommandsConfig           `yaml:"commands,omitempty"`
+}
+
+// CommandsConfig enables the built-in slash commands (/help, /reset,
+// /model, /tools, /usage, /jobs) that the channel handler answers itself,
+// without an LLM turn. Other prefixed messages still reach the content
+// preparers, e.g. the opentalon-commands plugin.
+type CommandsConfig struct {
+	Enabled     bool                         `yaml:"enabled"`
+	Prefix      string                       `yaml:"prefix,omitempty"`      // default "/"
+	Permissions map[string]CommandPermission `yaml:"permissions,omitempty"` // command name (without prefix) → who may run it; missing = everyone
+}
+
+// CommandPermission restricts a built-in command to the listed callers.
+type CommandPermission struct {
+	Users  []string `yaml:"users,omitempty"`  // profile entity ids or "channel:sender" actors
+	Groups []string `yaml:"groups,omitempty"` // profile groups
+}
+
+// MemoryConfig enables the memory tool, which lists the caller's memories
+// and promotes a personal memory, or a finding from the conversation, to
+// the general scope every actor sees. Approvers promote directly; anyone
+// else's promotion goes to the approval queue (approvals.enabled).
+type MemoryConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	Approvers     []string `yaml:"approvers,omitempty"`      // entity ids (sender ids without profiles), as scheduler.approvers; empty = nobody
+	AllowedGroups []string `yaml:"allowed_groups,omitempty"` // profile groups that may use the memory tool; empty = everyone
+}
+
+// SearchConfig enables the built-in keyword search over memories and past
+// conversations: a full-text index in the state database ranked with BM25,
+// needing no embedding provider.
+type SearchConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	AllowedGroups []string `yaml:"allowed_groups,omitempty"` // profile groups that may use the search tool; empty = everyone
+}
+
+// StatsConfig enables the stats tool, with which a user asks about their own
+// usage (conversations, tokens, cost, most-used tools) as recorded in the
+// usage store. Needs the state DB and profiles, which usage is recorded by.
+type StatsConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	AllowedGroups []string `yaml:"allowed_groups,omitempty"` // profile groups that may use the stats tool; empty = everyone
+}
+
+// DigestConfig schedules activity digests: reports of the questions users
+// asked, tool failures, cost and unanswered threads across all
+// conversations, posted to an operator's channel. Each report becomes a
+// scheduler job; needs the state DB.
+type DigestConfig struct {
+	Reports []DigestReportConfig `yaml:"reports,omitempty"`
+}
+
+// DigestReportConfig is one scheduled digest. Exactly one of Cron and
+// Interval must be set.
+type DigestReportConfig struct {
+	Name           string `yaml:"name"`
+	Title          string `yaml:"title,omitempty"` // report heading; default "Activity digest"
+	Cron           string `yaml:"cron,omitempty"`
+	Interval       string `yaml:"interval,omitempty"`
+	Period         string `yaml:"period,omitempty"` // how far back the report looks, a Go duration; default "24h"
+	Channel        string `yaml:"channel"`
+	ConversationID string `yaml:"conversation_id"`
+	Template       string `yaml:"template,omitempty"` // Go text/template over the report; empty = the default layout
+}
+
+// BundlesConfig limits which github/ref plugins and channels are fetched
+// and built (see bundle.Policy). Per-bundle checksums go on the plugin or
+// channel entry (sha256, binary_sha256).
+type BundlesConfig struct {
+	NoRemoteBuild   bool     `yaml:"no_remote_build,omitempty"`  // refuse github/ref sources; also -no-remote-build
+	AllowedOrgs     []string `yaml:"allowed_orgs,omitempty"`     // e.g. ["opentalon", "myorg", "gitlab.example.com/team"]
+	SignedCommits   bool     `yaml:"signed_commits,omitempty"`   // the resolved commit must have a valid signature
+	AllowedSigners  string   `yaml:"allowed_signers,omitempty"`  // ssh allowed_signers file for signed_commits
+	RequireChecksum bool     `yaml:"require_checksum,omitempty"` // every github/ref bundle must set sha256 or binary_sha256
+}
+
+// SpeechConfig turns voice notes into text before the agent loop (STT) and,
+// on channels declaring the voice capability, replies into audio (TTS). Each
+// side names a key under models.providers whose base_url and api_key are
+// reused; it must speak the OpenAI-compatible /audio API. An empty provider
+// leaves that side off.
+type SpeechConfig struct {
+	STT SpeechSTTConfig `yaml:"stt,omitempty"`
+	TTS SpeechTTSConfig `yaml:"tts,omitempty"`
+}
+
+// SpeechSTTConfig picks the transcription model.
+type SpeechSTTConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`              // e.g. "whisper-1"
+	Language string `yaml:"language,omitempty"` // ISO 639-1 hint; empty = detect
+}
+
+// SpeechTTSConfig picks the speech model and when replies are spoken.
+type SpeechTTSConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"`            // e.g. "tts-1"
+	Voice    string `yaml:"voice"`            // e.g. "alloy"
+	Format   string `yaml:"format,omitempty"` // mp3 (default), opus, aac, flac, wav
+	Reply    string `yaml:"reply,omitempty"`  // "voice" (default): answer voice notes aloud; "always": every reply
+}
+
+// DeliveryConfig controls how final replies reach channels. A reply the
+// channel plugin rejects is retried with backoff; one that still fails is
+// kept in the outbox (<data_dir>/outbox), retried in the background, and
+// reported to the operator conversation when one is set.
+type DeliveryConfig struct {
+	Attempts               int    `yaml:"attempts,omitempty"`                 // send attempts per reply (default 3)
+	Backoff                string `yaml:"backoff,omitempty"`                  // Go duration before the second attempt, doubled per retry (default "2s")
+	RedeliverInterval      string `yaml:"redeliver_interval,omitempty"`       // Go duration between outbox retries (default "1m")
+	OutboxTTL              string `yaml:"outbox_ttl,omitempty"`               // Go duration after which an undelivered reply is dropped (default "24h")
+	OperatorChannel        string `yaml:"operator_channel,omitempty"`         // channel id told about undelivered replies
+	OperatorConversationID string `yaml:"operator_conversation_id,omitempty"` // chat/room on operator_channel
 }
 
 // EventWebhookConfig forwards persisted session-event types to an
@@ -56,17 +193,143 @@ type EventWebhookConfig struct {
 	MaxRetries int               `yaml:"max_retries,omitempty"`
 }
 
+// EvaluationConfig scores completed sessions (state.session.completion) with
+// an LLM judge against a rubric. Scores are stored in the state database,
+// exported as the opentalon_session_score metric and readable through the
+// evaluation tool.
+type EvaluationConfig struct {
+	Enabled       bool                  `yaml:"enabled"`
+	Model         string                `yaml:"model,omitempty"`          // judge "provider/model"; empty = the default model
+	Rubric        []EvaluationCriterion `yaml:"rubric,omitempty"`         // empty = helpfulness, tool_correctness, resolution
+	SampleRate    float64               `yaml:"sample_rate,omitempty"`    // fraction of sessions judged, 0 < rate <= 1 (default 1)
+	AllowedGroups []string              `yaml:"allowed_groups,omitempty"` // profile groups that may use the evaluation tool; empty = everyone
+}
+
+// NotifyConfig enables the notify tool, with which the agent posts to other
+// channels and conversations. Only the listed destinations are reachable.
+type NotifyConfig struct {
+	Enabled       bool                    `yaml:"enabled"`
+	Targets       map[string]NotifyTarget `yaml:"targets,omitempty"`        // named destinations, e.g. release: {channel: slack, conversation: C0123}
+	Channels      []string                `yaml:"channels,omitempty"`       // channels on which any conversation id may be addressed
+	AllowedGroups []string                `yaml:"allowed_groups,omitempty"` // profile groups that may use the tool; empty = everyone
+}
+
+// NotifyTarget is one named notify destination.
+type NotifyTarget struct {
+	Channel      string `yaml:"channel"`
+	Conversation string `yaml:"conversation"`
+}
+
+// WorkflowsConfig defines multi-step workflows, run by the workflow tool
+// (and so by scheduler jobs with action workflow__run) or the HTTP API.
+type WorkflowsConfig struct {
+	Definitions   []WorkflowConfig `yaml:"definitions,omitempty"`
+	AllowedGroups []string         `yaml:"allowed_groups,omitempty"` // profile groups that may use the workflow tool; empty = everyone
+	APIAddr       string           `yaml:"api_addr,omitempty"`       // e.g. ":8088"; empty = no HTTP API
+	APIToken      string           `yaml:"api_token,omitempty"`      // bearer token the API requires
+	Record        bool             `yaml:"record,omitempty"`         // save each turn's successful tool calls for workflow__replay
+	RecordLimit   int              `yaml:"record_limit,omitempty"`   // recordings kept, oldest dropped first; default 50
+}
+
+// WorkflowConfig is one workflow: steps run in order, templates see
+// .Input.<name> and .Steps.<id>.Output/.JSON/.Status/.Error.
+type WorkflowConfig struct {
+	Name        string               `yaml:"name"`
+	Description string               `yaml:"description,omitempty"`
+	Inputs      []string             `yaml:"inputs,omitempty"` // inputs every run must supply
+	Steps       []WorkflowStepConfig `yaml:"steps"`
+	Output      string               `yaml:"output,omitempty"` // result template; empty = last step's output
+}
+
+// WorkflowStepConfig is one step; set exactly one of tool, prompt or
+// parallel.
+type WorkflowStepConfig struct {
+	ID              string               `yaml:"id"`
+	Tool            string               `yaml:"tool,omitempty"`   // plugin__action
+	Args            map[string]string    `yaml:"args,omitempty"`   // templates
+	Prompt          string               `yaml:"prompt,omitempty"` // LLM step user message (template)
+	System          string               `yaml:"system,omitempty"` // LLM step system prompt (template)
+	Model           string               `yaml:"model,omitempty"`  // LLM step model; empty = default
+	Parallel        []WorkflowStepConfig `yaml:"parallel,omitempty"`
+	If              string               `yaml:"if,omitempty"` // template expression, e.g. 'ne .Steps.check.Output ""'
+	Retry           *JobRetryConfig      `yaml:"retry,omitempty"`
+	ContinueOnError bool                 `yaml:"continue_on_error,omitempty"`
+}
+
+// EvaluationCriterion is one rubric entry, scored 1 to 5.
+type EvaluationCriterion struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+}
+
+// EventsConfig subscribes plugins and Lua scripts to in-process lifecycle
+// events (see package eventbus): session_created, message_received,
+// tool_executed, session_completed, job_run, provider_failover, or "*" for
+// all. Unlike event_webhook these are not the persisted session events and
+// are not retried; a subscriber that is down misses them.
+type EventsConfig struct {
+	BufferSize    int                 `yaml:"buffer_size,omitempty"` // queued events before new ones are dropped (default 1000)
+	Subscriptions []EventSubscription `yaml:"subscriptions,omitempty"`
+}
+
+// EventSubscription runs a plugin action or Lua script for each matching
+// event. Plugin is a plugin name, called as plugin.action with the event
+// fields as arguments, or "lua:<script>", whose on_event(event) function
+// gets them as a table.
+type EventSubscription struct {
+	Events []string `yaml:"events"`           // event types, or ["*"]
+	Plugin string   `yaml:"plugin"`           // e.g. "score" or "lua:tool-stats"
+	Action string   `yaml:"action,omitempty"` // required for plugins; unused for Lua
+}
+
 // MetricsConfig enables a Prometheus /metrics HTTP endpoint.
 type MetricsConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Addr    string `yaml:"addr"` // e.g. ":2112"; defaults to ":2112" when enabled
 }
 
+// ApprovalsConfig enables the admin approval queue. Jobs created by
+// non-approvers (scheduler.approvers) and LLM calls to the listed tools are
+// filed there instead of being refused or run, and an admin approves or
+// rejects them through the HTTP API; the requester is notified either way.
+type ApprovalsConfig struct {
+	Enabled              bool     `yaml:"enabled"`
+	Tools                []string `yaml:"tools,omitempty"`                  // "plugin__action" or "plugin" names whose LLM calls need approval
+	AdminAddr            string   `yaml:"admin_addr,omitempty"`             // e.g. ":8087"; empty = no admin HTTP API
+	AdminToken           string   `yaml:"admin_token,omitempty"`            // bearer token for the admin API; required with admin_addr
+	DigestInterval       string   `yaml:"digest_interval,omitempty"`        // Go duration, e.g. "1h"; empty = no digests
+	DigestChannel        string   `yaml:"digest_channel,omitempty"`         // channel id to send digests to
+	DigestConversationID string   `yaml:"digest_conversation_id,omitempty"` // chat/room on digest_channel
+}
+
 // HealthConfig configures the gRPC health probe server.
 type HealthConfig struct {
 	Addr string `yaml:"addr"` // e.g. ":8086"; defaults to ":8086"
 }
 
+// DaemonConfig configures -daemon mode: the files a service manager or a
+// probe watches. Readiness is signalled to systemd (Type=notify) whenever
+// $NOTIFY_SOCKET is set.
+type DaemonConfig struct {
+	PIDFile        string `yaml:"pid_file,omitempty"`        // e.g. "/run/opentalon/opentalon.pid"; empty = none
+	HealthFile     string `yaml:"health_file,omitempty"`     // JSON status file for exec probes; empty = none
+	HealthInterval string `yaml:"health_interval,omitempty"` // Go duration the health file is rewritten at; default "10s"
+	ServiceName    string `yaml:"service_name,omitempty"`    // Windows service name; default "opentalon"
+}
+
+// CtlConfig configures the local admin socket `opentalon ctl` talks to.
+// The socket is created owner-only (0600).
+type CtlConfig struct {
+	Disabled bool   `yaml:"disabled,omitempty"` // default false; the socket is served whenever it has a path
+	Socket   string `yaml:"socket,omitempty"`   // default "<state.data_dir>/ctl.sock"
+}
+
+// DashboardConfig configures the embedded web admin dashboard.
+type DashboardConfig struct {
+	Addr  string `yaml:"addr,omitempty"`  // e.g. ":8089"; empty = no dashboard
+	Token string `yaml:"token,omitempty"` // bearer token the dashboard API requires; required with addr
+}
+
 // RedisConfig holds the connection details for the shared Redis instance used by
 // cluster deduplication and the plugin exec dispatcher. Having one block avoids
 // operators who want only one subsystem having to fill in a section named after
@@ -94,9 +357,16 @@ type PluginExecConfig struct {
 // When enabled: every inbound message acquires a Redis lock before processing, so only
 // one pod handles each unique message even when multiple pods receive it simultaneously.
 // Requires redis.redis_url (or sentinel config) to be set.
+//
+// SessionCache adds a Redis read-through cache in front of the session store;
+// ReplyRelay lets a pod send to a channel whose connection lives on another
+// pod, over Redis pub/sub. The per-session turn lease is always on.
 type ClusterConfig struct {
-	Enabled  bool   `yaml:"enabled"`
-	DedupTTL string `yaml:"dedup_ttl"` // Go duration for dedup lock TTL; default "5m"
+	Enabled         bool   `yaml:"enabled"`
+	DedupTTL        string `yaml:"dedup_ttl"`                   // Go duration for dedup lock TTL; default "5m"
+	SessionCache    bool   `yaml:"session_cache,omitempty"`     // cache sessions in Redis
+	SessionCacheTTL string `yaml:"session_cache_ttl,omitempty"` // Go duration; bounds staleness after writes that bypass the cache; default "10m"
+	ReplyRelay      bool   `yaml:"reply_relay,omitempty"`       // forward sends for channels held by another pod
 }
 
 // BootstrapConfig configures a remote HTTP endpoint that is called once at startup
@@ -156,13 +426,30 @@ type GroupConfig struct {
 	Plugins []string `yaml:"plugins"`
 }
 
-// LuaConfig configures embedded Lua plugins (content preparers). Use scripts_dir for local .lua files,
-// or plugins + default_github/ref to download by name from GitHub (one repo, one subdir per plugin).
+// LuaConfig configures embedded Lua plugins (content preparers, response hooks and tools). Use scripts_dir
+// for local .lua files, or plugins + default_github/ref to download by name from GitHub (one repo, one subdir per plugin).
 type LuaConfig struct {
 	ScriptsDir    string           `yaml:"scripts_dir"`    // local dir of .lua files (e.g. scripts/hello-world.lua)
 	Plugins       []LuaPluginEntry `yaml:"plugins"`        // plugin names to download (use default repo or per-plugin github/ref)
 	DefaultGitHub string           `yaml:"default_github"` // default repo for plugins (e.g. opentalon/lua-plugins)
 	DefaultRef    string           `yaml:"default_ref"`    // default ref (e.g. master)
+	Tools         []string         `yaml:"tools"`          // script names to register as tool plugins (plugin table + execute function)
+	HTTPAllow     []string         `yaml:"http_allow"`     // hosts tool scripts may call via http ("api.example.com", "*.example.com"); empty = none
+	HTTPTimeout   string           `yaml:"http_timeout"`   // per-request timeout for the http module (e.g. "10s"); default 15s
+	// Per-call limits for every Lua script (preparers, response hooks, tools). Unset = default; negative = no limit.
+	Timeout         string `yaml:"timeout"`          // wall clock per call (e.g. "5s"); default 30s
+	MaxInstructions int64  `yaml:"max_instructions"` // VM instructions per call; default 50000000
+	MaxMemoryMB     int64  `yaml:"max_memory_mb"`    // memory allocated per call, approximate; default 256
+	// Config gives each script (by name) values it reads from the read-only config table.
+	// Values support ${VAR} and file:<path> (see resolveSecret).
+	Config map[string]map[string]string `yaml:"config"`
+}
+
+// JSConfig configures JavaScript scripts (content preparers and tools). No engine ships yet, so
+// every js: script is rejected. Limits and the http allowlist come from the lua block.
+type JSConfig struct {
+	ScriptsDir string   `yaml:"scripts_dir"` // local dir of .js files; each is "js:<basename>" in content_preparers
+	Tools      []string `yaml:"tools"`       // script names to register as tool plugins (plugin object + execute function)
 }
 
 // LuaPluginEntry is one Lua plugin: either a name (string) or { name, github?, ref? }.
@@ -197,12 +484,33 @@ func (e *LuaPluginEntry) UnmarshalYAML(n *yaml.Node) error {
 
 // RequestPackagesConfig configures skill-style request packages (no compiled plugin).
 type RequestPackagesConfig struct {
-	Path               string          `yaml:"path"`                 // directory containing .yaml files (each file = one plugin set)
-	SkillsPath         string          `yaml:"skills_path"`          // directory of OpenClaw-style skills (each subdir: SKILL.md or request.yaml)
-	Skills             []SkillEntry    `yaml:"skills"`               // skill names to download (use default repo or per-skill github/ref)
-	DefaultSkillGitHub string          `yaml:"default_skill_github"` // default repo for skills (e.g. openclaw/skills)
-	DefaultSkillRef    string          `yaml:"default_skill_ref"`    // default ref (e.g. main)
-	Inline             []RequestSetInl `yaml:"inline"`               // inline plugin sets
+	Path               string            `yaml:"path"`                 // directory containing .yaml files (each file = one plugin set)
+	SkillsPath         string            `yaml:"skills_path"`          // directory of OpenClaw-style skills (each subdir: SKILL.md or request.yaml)
+	Skills             []SkillEntry      `yaml:"skills"`               // skill names to download (use default repo or per-skill github/ref)
+	DefaultSkillGitHub string            `yaml:"default_skill_github"` // default repo for skills (e.g. openclaw/skills)
+	DefaultSkillRef    string            `yaml:"default_skill_ref"`    // default ref (e.g. main)
+	Inline             []RequestSetInl   `yaml:"inline"`               // inline plugin sets
+	OpenAPI            []OpenAPIInl      `yaml:"openapi"`              // sets generated from OpenAPI 3 / Swagger 2 specs
+	SkillsIndex        SkillsIndexConfig `yaml:"skills_index"`         // repo listing installable skills (opentalon skill search/install)
+}
+
+// SkillsIndexConfig points at a repo with an index.yaml of installable
+// skills (see bundle.SkillIndex).
+type SkillsIndexConfig struct {
+	GitHub string `yaml:"github"` // org/repo, or an https:// or file:// git URL
+	Ref    string `yaml:"ref"`    // default main
+}
+
+// OpenAPIInl imports the operations of an OpenAPI 3 or Swagger 2 spec as
+// one request package set (see requestpkg.LoadOpenAPI).
+type OpenAPIInl struct {
+	Plugin        string   `yaml:"plugin"`
+	Spec          string   `yaml:"spec"` // file path or http(s) URL, JSON or YAML
+	Description   string   `yaml:"description"`
+	Operations    []string `yaml:"operations"` // operationIds to import; empty = all non-deprecated
+	BaseURL       string   `yaml:"base_url"`   // overrides the spec's servers; may use {{env.X}}
+	EnvPrefix     string   `yaml:"env_prefix"` // credential env vars: {PREFIX}_TOKEN, _API_KEY, ...; default the plugin name
+	AllowedGroups []string `yaml:"groups,omitempty"`
 }
 
 // SkillEntry is one skill to download: either a name (string in YAML) or { name, github?, ref? }.
@@ -210,21 +518,23 @@ type SkillEntry struct {
 	Name   string `yaml:"name"`
 	GitHub string `yaml:"github"`
 	Ref    string `yaml:"ref"`
+	Alias  string `yaml:"alias,omitempty"` // register the skill's tools under this name instead of its own
 }
 
-// UnmarshalYAML allows skill to be a string (name only) or a map (name, github, ref).
+// UnmarshalYAML allows skill to be a string (name only) or a map (name, github, ref, alias).
 func (s *SkillEntry) UnmarshalYAML(n *yaml.Node) error {
 	if n.Kind == yaml.ScalarNode {
 		s.Name = n.Value
 		return nil
 	}
 	if n.Kind != yaml.MappingNode {
-		return fmt.Errorf("skill must be a string (name) or object { name, github?, ref? }")
+		return fmt.Errorf("skill must be a string (name) or object { name, github?, ref?, alias? }")
 	}
 	var raw struct {
 		Name   string `yaml:"name"`
 		GitHub string `yaml:"github"`
 		Ref    string `yaml:"ref"`
+		Alias  string `yaml:"alias"`
 	}
 	if err := n.Decode(&raw); err != nil {
 		return err
@@ -232,6 +542,7 @@ func (s *SkillEntry) UnmarshalYAML(n *yaml.Node) error {
 	s.Name = raw.Name
 	s.GitHub = raw.GitHub
 	s.Ref = raw.Ref
+	s.Alias = raw.Alias
 	return nil
 }
 
@@ -247,22 +558,89 @@ type MCPServerConfigInl struct {
 // If MCP is set, Packages is ignored — the tools come from the MCP plugin binary.
 type RequestSetInl struct {
 	Plugin        string              `yaml:"plugin"`
+	Alias         string              `yaml:"alias,omitempty"`   // register the set under this name instead of plugin
+	Version       string              `yaml:"version,omitempty"` // shown next to the set's name in the system prompt
 	Description   string              `yaml:"description"`
 	Packages      []RequestPackageInl `yaml:"packages"`
 	MCP           *MCPServerConfigInl `yaml:"mcp,omitempty"`
 	AllowedGroups []string            `yaml:"groups,omitempty"` // restrict to these profile groups
+	Auth          *RequestAuthInl     `yaml:"auth,omitempty"`   // OAuth2 for every package without its own auth
+	Config        map[string]string   `yaml:"config,omitempty"` // {{config.X}} values; support ${VAR} and file:<path>
 }
 
 // RequestPackageInl is the config shape for one request package.
 type RequestPackageInl struct {
-	Action      string            `yaml:"action"`
-	Description string            `yaml:"description"`
-	Method      string            `yaml:"method"`
-	URL         string            `yaml:"url"`
-	Body        string            `yaml:"body"`
-	Headers     map[string]string `yaml:"headers"`
-	RequiredEnv []string          `yaml:"required_env"`
-	Parameters  []RequestParamInl `yaml:"parameters"`
+	Action      string              `yaml:"action"`
+	Description string              `yaml:"description"`
+	Type        string              `yaml:"type"` // http (default) or graphql
+	Method      string              `yaml:"method"`
+	URL         string              `yaml:"url"`
+	Body        string              `yaml:"body"`
+	JSONBody    map[string]any      `yaml:"json_body"` // JSON object body; "{{args.X}}" values are sent typed and dropped when X is missing
+	Query       string              `yaml:"query"`     // graphql: query or mutation
+	Variables   map[string]any      `yaml:"variables"` // graphql: name -> "{{args.X}}" template or literal
+	Headers     map[string]string   `yaml:"headers"`
+	RequiredEnv []string            `yaml:"required_env"`
+	Parameters  []RequestParamInl   `yaml:"parameters"`
+	Response    *RequestResponseInl `yaml:"response"` // optional; shape the result (see requestpkg.Response)
+	Steps       []RequestStepInl    `yaml:"steps"`    // optional; requests run first, their extracted values used as {{steps.NAME}}
+	Paginate    *RequestPaginateInl `yaml:"paginate"` // optional; fetch and merge further pages of the result
+	Auth        *RequestAuthInl     `yaml:"auth"`     // optional; OAuth2 token for this action (overrides the set's)
+
+	Timeout      string `yaml:"timeout"`       // per request, e.g. "10s"; default 30s
+	Retries      int    `yaml:"retries"`       // retries after a transport error or a retry_on status (max 10)
+	RetryBackoff string `yaml:"retry_backoff"` // first wait, doubled per retry; Retry-After wins. Default 1s
+	RetryOn      []int  `yaml:"retry_on"`      // default [429, 500, 502, 503, 504]
+	RateLimit    int    `yaml:"rate_limit"`    // max requests per minute for this action; 0 = unlimited
+
+	ReadOnly bool   `yaml:"read_only"` // a lookup: no confirmation prompt, and the result may be cached
+	CacheTTL string `yaml:"cache_ttl"` // tool cache lifetime for the result, e.g. "5m"; needs read_only
+
+	InjectContextArgs []string `yaml:"inject_context_args"` // host args usable as {{args.NAME}}, e.g. ["idempotency_key"]
+}
+
+// RequestAuthInl obtains and caches an OAuth2 access token for request
+// packages (see requestpkg.Auth).
+type RequestAuthInl struct {
+	Grant           string `yaml:"grant"`             // client_credentials (default) or refresh_token
+	TokenURL        string `yaml:"token_url"`         // e.g. "https://auth.atlassian.com/oauth/token"
+	ClientIDEnv     string `yaml:"client_id_env"`     // name of the env var with the client id
+	ClientSecretEnv string `yaml:"client_secret_env"` // name of the env var with the client secret
+	RefreshTokenEnv string `yaml:"refresh_token_env"` // refresh_token grant: initial refresh token
+	Scope           string `yaml:"scope"`             // space-separated
+	ClientAuth      string `yaml:"client_auth"`       // basic (default) or body
+	CacheTTL        string `yaml:"cache_ttl"`         // cap on the token lifetime, e.g. "50m"; default expires_in
+	Header          string `yaml:"header"`            // default "Authorization"
+	Format          string `yaml:"format"`            // default "Bearer {{token}}"
+}
+
+// RequestStepInl is a request run before the package's own one.
+type RequestStepInl struct {
+	Name    string            `yaml:"name"`
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Body    string            `yaml:"body"`
+	Headers map[string]string `yaml:"headers"`
+	Extract map[string]string `yaml:"extract"` // name -> path, as in response.extract
+}
+
+// RequestPaginateInl follows the pages of a request package's result.
+type RequestPaginateInl struct {
+	Param     string `yaml:"param"`     // query parameter (or body key) set for each page
+	In        string `yaml:"in"`        // query (default) or body
+	Next      string `yaml:"next"`      // path to the next cursor; empty = count up from start by increment
+	Start     int    `yaml:"start"`     // counter mode: first value (default 0)
+	Increment int    `yaml:"increment"` // counter mode: step per page (default 1)
+	Items     string `yaml:"items"`     // path to the array of results in a page
+	MaxPages  int    `yaml:"max_pages"` // default 10, at most 100
+}
+
+// RequestResponseInl extracts values from a request package's JSON response
+// and renders them with success/error templates.
+type RequestResponseInl struct {
+	Extract map[string]string `yaml:"extract"` // name -> path, e.g. key: "issues[0].key"
+	Success string            `yaml:"success"` // template: {{response.name}}, {{status}}, {{body}}, {{env.X}}, {{args.Y}}
+	Error   string            `yaml:"error"`   // template for non-2xx responses
 }
 
 // RequestParamInl describes one parameter.
@@ -270,6 +648,7 @@ type RequestParamInl struct {
 	Name        string `yaml:"name"`
 	Description string `yaml:"description"`
 	Required    bool   `yaml:"required"`
+	Type        string `yaml:"type,omitempty"` // e.g. "integer", "enum:open,closed", "array:string"; empty = string
 }
 
 // LogConfig holds logging options. Level can be overridden by the LOG_LEVEL env var.
@@ -278,22 +657,47 @@ type LogConfig struct {
 }
 
 type PluginConfig struct {
-	Enabled     bool                   `yaml:"enabled"`
-	Cache       bool                   `yaml:"cache,omitempty"` // when true, reuse cached binary from plugins.lock (default false = always rebuild)
-	Insecure    *bool                  `yaml:"insecure"`        // if true or omitted (default), preparer cannot run invoke; if false (trusted), can invoke
-	Plugin      string                 `yaml:"plugin"`          // path to binary or grpc://... (optional if github is set)
-	GitHub      string                 `yaml:"github"`          // e.g. "owner/repo" (bundler-style)
-	Ref         string                 `yaml:"ref"`             // branch, tag, or commit; resolved and pinned in plugins.lock
-	Config      map[string]interface{} `yaml:"config,omitempty"`
-	DBAccess    bool                   `yaml:"db_access,omitempty"`    // opt-in: inject state-store credentials into plugin config
-	DialTimeout string                 `yaml:"dial_timeout,omitempty"` // e.g. "30s"; overrides the default 5s gRPC init timeout
-	ExposeHTTP  bool                   `yaml:"expose_http,omitempty"`  // opt-in: reverse-proxy /{plugin-name}/* through the webhook server
+	Enabled      bool                   `yaml:"enabled"`
+	Cache        bool                   `yaml:"cache,omitempty"`         // when true, reuse cached binary from plugins.lock (default false = always rebuild)
+	Insecure     *bool                  `yaml:"insecure"`                // if true or omitted (default), preparer cannot run invoke; if false (trusted), can invoke
+	Plugin       string                 `yaml:"plugin"`                  // path to binary or grpc://... (optional if github is set)
+	GitHub       string                 `yaml:"github"`                  // e.g. "owner/repo" (bundler-style)
+	Ref          string                 `yaml:"ref"`                     // branch, tag, or commit; resolved and pinned in plugins.lock
+	SHA256       string                 `yaml:"sha256,omitempty"`        // checksum of the source at ref (see plugins.lock), checked before building
+	BinarySHA256 string                 `yaml:"binary_sha256,omitempty"` // checksum of the built binary
+	FromSource   bool                   `yaml:"from_source,omitempty"`   // always build; skip prebuilt GitHub release assets
+	Config       map[string]interface{} `yaml:"config,omitempty"`
+	DBAccess     bool                   `yaml:"db_access,omitempty"`    // opt-in: inject state-store credentials into plugin config
+	DialTimeout  string                 `yaml:"dial_timeout,omitempty"` // e.g. "30s"; overrides the default 5s gRPC init timeout
+	ExposeHTTP   bool                   `yaml:"expose_http,omitempty"`  // opt-in: reverse-proxy /{plugin-name}/* through the webhook server
+	// EnvAllow, when set (even to []), stops the plugin inheriting the whole
+	// environment: it sees only PATH, HOME, TMPDIR, LANG, TZ and these names
+	// ("AWS_*" matches by prefix). It also moves the plugin into its own
+	// working directory unless WorkDir says otherwise.
+	EnvAllow []string          `yaml:"env_allow,omitempty"`
+	Env      map[string]string `yaml:"env,omitempty"`      // extra variables for the plugin; values accept ${VAR} and file:/path
+	WorkDir  string            `yaml:"work_dir,omitempty"` // subprocess working directory; default with env_allow: <data_dir>/plugin-work/<name>
+	// Alias registers the plugin's tools under this name instead of the one
+	// the plugin reports, e.g. when two plugins both call themselves "jira".
+	Alias   string `yaml:"alias,omitempty"`
+	Version string `yaml:"version,omitempty"` // shown next to the plugin's name in the system prompt
 }
 
 type SchedulerConfig struct {
 	Jobs           []JobConfig `yaml:"jobs"`
 	Approvers      []string    `yaml:"approvers,omitempty"`
 	MaxJobsPerUser int         `yaml:"max_jobs_per_user,omitempty"`
+	FailureAlert   *JobAlert   `yaml:"failure_alert,omitempty"`  // where to report jobs that keep failing
+	MaxConcurrent  int         `yaml:"max_concurrent,omitempty"` // job runs executing at once across all jobs; 0 = unlimited
+	Jitter         string      `yaml:"jitter,omitempty"`         // default random delay per fire, e.g. "30s"
+}
+
+// JobAlert names the conversation told when a job fails several runs in a
+// row (after its retries).
+type JobAlert struct {
+	Channel        string `yaml:"channel"`
+	ConversationID string `yaml:"conversation_id"`
+	After          int    `yaml:"after,omitempty"` // failed runs in a row; default 3
 }
 
 type JobConfig struct {
@@ -305,15 +709,106 @@ type JobConfig struct {
 	Args          map[string]string `yaml:"args,omitempty"`
 	NotifyChannel string            `yaml:"notify_channel,omitempty"`
 	Enabled       *bool             `yaml:"enabled,omitempty"`
+	// NotifyConversationID is the chat/room on NotifyChannel that receives
+	// the result; without it the job runs but notifies nobody there.
+	NotifyConversationID string              `yaml:"notify_conversation_id,omitempty"`
+	NotifyTargets        []JobNotifyTarget   `yaml:"notify_targets,omitempty"`  // further destinations for the same notification
+	NotifyTemplate       string              `yaml:"notify_template,omitempty"` // Go text/template over .Result, .JSON, .Job, .Time
+	NotifyIf             *JobNotifyCondition `yaml:"notify_if,omitempty"`       // notify only when the result matches
+	Retry                *JobRetryConfig     `yaml:"retry,omitempty"`           // retry failed runs before dead-lettering them
+	NoOverlap            bool                `yaml:"no_overlap,omitempty"`      // skip a fire while the previous run is still going
+	Jitter               string              `yaml:"jitter,omitempty"`          // random delay per fire; overrides scheduler.jitter
+}
+
+// JobRetryConfig retries a failed job run with exponential backoff.
+type JobRetryConfig struct {
+	Attempts int    `yaml:"attempts"`          // retries after the first try, at most 10
+	Backoff  string `yaml:"backoff,omitempty"` // wait before the first retry, doubled each time; default "30s"
+}
+
+// JobNotifyTarget is one extra destination for a job's notification.
+type JobNotifyTarget struct {
+	Channel        string `yaml:"channel"`
+	ConversationID string `yaml:"conversation_id"`
+}
+
+// JobNotifyCondition gates a job's notification on its result. Both fields
+// must hold when both are set.
+type JobNotifyCondition struct {
+	Match string `yaml:"match,omitempty"` // regular expression over the result text
+	JSON  string `yaml:"json,omitempty"`  // "path [op value]" over the result parsed as JSON, e.g. "count > 0"
 }
 
 type ChannelConfig struct {
-	Enabled bool                   `yaml:"enabled"`
-	Cache   bool                   `yaml:"cache,omitempty"` // when true, reuse cached binary from channels.lock (default false = always rebuild)
-	Plugin  string                 `yaml:"plugin"`          // path to binary or grpc://... (optional if github is set)
-	GitHub  string                 `yaml:"github"`          // e.g. "opentalon/slack-channel" (bundler-style)
-	Ref     string                 `yaml:"ref"`             // branch, tag, or commit; pinned in channels.lock
-	Config  map[string]interface{} `yaml:"config"`
+	Enabled      bool                   `yaml:"enabled"`
+	Cache        bool                   `yaml:"cache,omitempty"`         // when true, reuse cached binary from channels.lock (default false = always rebuild)
+	Plugin       string                 `yaml:"plugin"`                  // path to binary or grpc://... (optional if github is set)
+	GitHub       string                 `yaml:"github"`                  // e.g. "opentalon/slack-channel" (bundler-style)
+	Ref          string                 `yaml:"ref"`                     // branch, tag, or commit; pinned in channels.lock
+	SHA256       string                 `yaml:"sha256,omitempty"`        // checksum of the source at ref (see channels.lock), checked before building
+	BinarySHA256 string                 `yaml:"binary_sha256,omitempty"` // checksum of the built binary
+	FromSource   bool                   `yaml:"from_source,omitempty"`   // always build; skip prebuilt GitHub release assets
+	Config       map[string]interface{} `yaml:"config"`
+	// DebounceWindow overrides orchestrator.debounce_window for this channel
+	// (Go duration; "0" disables it here). Empty = use the global window.
+	DebounceWindow string `yaml:"debounce_window,omitempty"`
+	// SystemPrompt overrides or extends the system prompt for turns that
+	// arrive on this channel (e.g. terse answers on SMS, richer on Slack).
+	SystemPrompt *SystemPromptOverride `yaml:"system_prompt,omitempty"`
+	// Agent names the agent (see agents:) that handles conversations on this
+	// channel unless a message @mentions another one.
+	Agent string `yaml:"agent,omitempty"`
+	// Formatting renders replies in the channel's response_format and splits
+	// those above its max_message_length. nil leaves replies untouched.
+	Formatting *FormattingConfig `yaml:"formatting,omitempty"`
+	// Locale overrides orchestrator.locale for conversations on this channel.
+	Locale string `yaml:"locale,omitempty"`
+	// Group is the policy for group conversations on this channel (messages
+	// the channel marks chat_type=group). nil answers every message in one
+	// shared session per conversation.
+	Group *GroupChatConfig `yaml:"group,omitempty"`
+	// Progress shows the tool a long turn is running ("Running gitlab →
+	// analyze_code…") on channels that declare the status capability.
+	Progress bool `yaml:"progress,omitempty"`
+	// Queue bounds the messages from this channel that wait for and run in
+	// the orchestrator. nil uses the defaults (64 waiting, 16 running, wait).
+	Queue *QueueConfig `yaml:"queue,omitempty"`
+	// AutoClose closes a conversation on this channel once it has been idle
+	// for After: its transcript is folded into a summary and the next
+	// message starts a fresh session that carries only that summary.
+	AutoClose *AutoCloseConfig `yaml:"auto_close,omitempty"`
+	// LoopLimits overrides orchestrator.loop_limits for turns on this
+	// channel; unset fields keep the global limits.
+	LoopLimits *LoopLimitsConfig `yaml:"loop_limits,omitempty"`
+}
+
+// AutoCloseConfig is the idle auto-close policy of one channel.
+type AutoCloseConfig struct {
+	After  string `yaml:"after"`            // Go duration of inactivity, e.g. "8h"
+	Notice string `yaml:"notice,omitempty"` // posted to the conversation when it closes; empty = close silently
+}
+
+// QueueConfig is the dispatch queue of one channel.
+type QueueConfig struct {
+	Size      int    `yaml:"size,omitempty"`       // messages waiting for a worker (default 64)
+	Workers   int    `yaml:"workers,omitempty"`    // messages handled at once (default 16)
+	Overflow  string `yaml:"overflow,omitempty"`   // full queue: "wait" (default), "reject" with busy_reply, or "coalesce" into the sender's queued message
+	BusyReply string `yaml:"busy_reply,omitempty"` // reply to a rejected message; default a short English notice
+	Merge     bool   `yaml:"merge,omitempty"`      // answer what a sender queued during the previous turn as one turn
+}
+
+// GroupChatConfig is the group-conversation policy of one channel.
+type GroupChatConfig struct {
+	RequireMention bool     `yaml:"require_mention,omitempty"` // answer only messages that @mention the bot (or that the channel flags as mentioning it)
+	MentionNames   []string `yaml:"mention_names,omitempty"`   // names that count as an @mention in the text, e.g. [opentalon]
+	PerSender      bool     `yaml:"per_sender,omitempty"`      // one session per member instead of one shared by the whole conversation
+}
+
+// FormattingConfig is the reply formatting policy of one channel.
+type FormattingConfig struct {
+	Convert  bool   `yaml:"convert,omitempty"`   // the model writes Markdown; the core renders slack, telegram, whatsapp, html or text
+	Overflow string `yaml:"overflow,omitempty"`  // "split" (default): several messages; "file": a preview plus the reply as a file
+	MaxParts int    `yaml:"max_parts,omitempty"` // split replies needing more messages go as a file on channels with files (default 4)
 }
 
 // ContentPreparerEntry configures a plugin action to run before the first LLM call; its output becomes the user message (or can block the LLM via send_to_llm: false).
@@ -336,6 +831,15 @@ type ResponseFormatterEntry struct {
 	FailOpen *bool  `yaml:"fail_open,omitempty"` // pointer; defaults to true when nil (don't block responses on formatter failure)
 }
 
+// ResponseModeratorEntry configures a plugin or Lua script that runs on the final
+// answer before it is stored and sent (PII scrubbing, profanity filters, disclaimers).
+// It returns the reply to send, or blocks it.
+type ResponseModeratorEntry struct {
+	Plugin   string `yaml:"plugin"`              // "my-plugin" for gRPC or "lua:my-script" for Lua
+	Action   string `yaml:"action"`              // gRPC action name; ignored for Lua scripts
+	FailOpen bool   `yaml:"fail_open,omitempty"` // default false (fail-closed): block the reply if the moderator fails
+}
+
 // KnowledgeConfig configures knowledge-augmented RAG: startup action sync and
 // optional knowledge directory scanning.
 type KnowledgeConfig struct {
@@ -352,16 +856,227 @@ type OrchestratorConfig struct {
 	PromptOverrides       map[string]string            `yaml:"prompt_overrides,omitempty"` // override built-in prompts by canonical name (.txt filename without extension); omitted key = embedded default, empty value = blank it. See prompts.OverridableNames().
 	ContentPreparers      []ContentPreparerEntry       `yaml:"content_preparers,omitempty"`
 	ResponseFormatters    []ResponseFormatterEntry     `yaml:"response_formatters,omitempty"`
+	ResponseModerators    []ResponseModeratorEntry     `yaml:"response_moderators,omitempty"`     // check the final answer before it is stored and sent; may rewrite or block it
 	PermissionPlugin      string                       `yaml:"permission_plugin,omitempty"`       // if set, core calls this plugin with action "check" (actor, plugin) before running a tool
 	MaxConcurrentSessions int                          `yaml:"max_concurrent_sessions,omitempty"` // max sessions running in parallel (default 1 = sequential)
 	DebounceWindow        string                       `yaml:"debounce_window,omitempty"`         // Go duration (e.g. "800ms"); merges rapid messages into one LLM call; default "0" = disabled
+	DebounceMaxWait       string                       `yaml:"debounce_max_wait,omitempty"`       // Go duration; longest a burst is held after its first message; default 5× debounce_window
+	DedupWindow           string                       `yaml:"dedup_window,omitempty"`            // Go duration; drops a redelivered message (same channel message id) seen within it; default "10m", "0" = off
 	Pipeline              PipelineOrchestratorConfig   `yaml:"pipeline,omitempty"`
-	Knowledge             KnowledgeConfig              `yaml:"knowledge,omitempty"`       // knowledge-augmented RAG configuration
-	Subprocess            SubprocessOrchestratorConfig `yaml:"subprocess,omitempty"`      // subprocess (sub-agent) support
-	Escalation            EscalationOrchestratorConfig `yaml:"escalation,omitempty"`      // background-trigger LLM turn entrypoint (_escalate)
-	ShowToolCalls         string                       `yaml:"show_tool_calls,omitempty"` // "raw" = debug blocks, "friendly" = short labels, "" = hidden
-	Preparer              PreparerOrchestratorConfig   `yaml:"preparer,omitempty"`        // RFC #249 preparer-phase behaviour (tool error handling)
-	Repair                RepairOrchestratorConfig     `yaml:"repair,omitempty"`          // post-failure tool-call repair phase; default off
+	Knowledge             KnowledgeConfig              `yaml:"knowledge,omitempty"`        // knowledge-augmented RAG configuration
+	Subprocess            SubprocessOrchestratorConfig `yaml:"subprocess,omitempty"`       // subprocess (sub-agent) support
+	Escalation            EscalationOrchestratorConfig `yaml:"escalation,omitempty"`       // background-trigger LLM turn entrypoint (_escalate)
+	AskUser               AskUserConfig                `yaml:"ask_user,omitempty"`         // _ask_user: let the model pause and ask for a missing value
+	Resume                ResumeConfig                 `yaml:"resume,omitempty"`           // checkpoint agent loops and resume turns cut off by a restart
+	Jobs                  JobsConfig                   `yaml:"jobs,omitempty"`             // async tool jobs: poll accepted jobs, expose _jobs, report when they finish
+	LoopLimits            LoopLimitsConfig             `yaml:"loop_limits,omitempty"`      // agent-loop rounds and wall-clock budget per turn
+	ToolCache             ToolCacheConfig              `yaml:"tool_cache,omitempty"`       // reuse results of identical read-only tool calls
+	LLMCache              LLMCacheConfig               `yaml:"llm_cache,omitempty"`        // reuse responses to identical LLM requests
+	Generation            GenerationConfig             `yaml:"generation,omitempty"`       // max_tokens, temperature, top_p, stop for every LLM request
+	TaskGeneration        map[string]GenerationConfig  `yaml:"task_generation,omitempty"`  // overrides per internal task: summary, title, confirmation, subagent
+	ShowToolCalls         string                       `yaml:"show_tool_calls,omitempty"`  // "raw" = debug blocks, "friendly" = short labels, "" = hidden
+	ToolExposure          string                       `yaml:"tool_exposure,omitempty"`    // "summary" = plugin names only in the prompt, full docs via _tools__describe; "" / "full" = every action
+	Preparer              PreparerOrchestratorConfig   `yaml:"preparer,omitempty"`         // RFC #249 preparer-phase behaviour (tool error handling)
+	Repair                RepairOrchestratorConfig     `yaml:"repair,omitempty"`           // post-failure tool-call repair phase; default off
+	HelpPolish            bool                         `yaml:"help_polish,omitempty"`      // rewrite the /help capability summary with one LLM pass (cached until tools change)
+	ReplyLanguage         string                       `yaml:"reply_language,omitempty"`   // pin every reply to this language ("German" or "de"); empty = answer in the user's detected language
+	Locale                string                       `yaml:"locale,omitempty"`           // deployment language for core replies and summaries until a user's own is known; empty = English
+	Attachments           AttachmentsConfig            `yaml:"attachments,omitempty"`      // store channel attachments and extract their text; needs state.blobs
+	SecretRedaction       SecretRedactionConfig        `yaml:"secret_redaction,omitempty"` // mask credentials found in tool outputs
+	PIIRedaction          PIIRedactionConfig           `yaml:"pii_redaction,omitempty"`    // replace personal data in user input with placeholders before the LLM
+	Policies              []ToolPolicyConfig           `yaml:"policies,omitempty"`         // enforced in code before every tool call the model makes; first match decides
+	ToolScopes            []ToolScopeConfig            `yaml:"tool_scopes,omitempty"`      // narrow the plugins a turn sees and calls by channel, group and task type; every match applies
+	DryRun                DryRunConfig                 `yaml:"dry_run,omitempty"`          // simulate mutating tool calls instead of running them
+	Guard                 GuardConfig                  `yaml:"guard,omitempty"`            // tool-output policy: deny patterns, plugin trust, injection classifier
+	// ReportToolFailures appends "N of M actions failed: …" to the tool
+	// output channels display when some of a turn's tool calls errored, so
+	// an answer that leaves them out does not hide them.
+	ReportToolFailures bool `yaml:"report_tool_failures,omitempty"`
+	// GroupSystemPrompts customizes the system prompt per profile group
+	// (key = group name from the WhoAmI server). Merged with the per-channel
+	// system_prompt; see SystemPromptOverride.
+	GroupSystemPrompts map[string]SystemPromptOverride `yaml:"group_system_prompts,omitempty"`
+	// Messages overrides or adds translations of the core replies the
+	// orchestrator writes itself (guard notices, offline mode, ...):
+	// locale (ISO 639-1) -> message key -> text.
+	Messages map[string]map[string]string `yaml:"messages,omitempty"`
+	// SystemPromptTemplate is a Go text/template that lays out the system
+	// prompt, e.g. "{{.Preamble}}{{.Rules}}Today is {{.Date}}.\n{{.Tools}}".
+	// Empty = built-in layout. See orchestrator.PromptTemplateData for fields.
+	SystemPromptTemplate string `yaml:"system_prompt_template,omitempty"`
+	// PromptLayers orders and budgets the instruction layers that follow the
+	// preamble and safety rules: deployment, channel, group and session.
+	PromptLayers PromptLayersConfig `yaml:"prompt_layers,omitempty"`
+}
+
+// PromptLayersConfig configures the layered part of the system prompt. The
+// core layer (preamble and safety rules) always comes first and is never
+// cut; the deployment, channel (channels.<name>.system_prompt.append), group
+// (group_system_prompts.<group>.append) and session (/system) layers follow
+// in Order.
+type PromptLayersConfig struct {
+	Deployment  string         `yaml:"deployment,omitempty"`   // deployment-wide persona and instructions
+	Order       []string       `yaml:"order,omitempty"`        // layer names, highest priority first; default deployment, channel, group, session
+	Budgets     map[string]int `yaml:"budgets,omitempty"`      // per-layer cap in bytes; session defaults to 4000
+	TotalBudget int            `yaml:"total_budget,omitempty"` // cap for all layers together; the last layers in order are cut first; 0 = unlimited
+	Admins      []string       `yaml:"admins,omitempty"`       // profile entity ids or "channel:sender" actors allowed to use /system
+}
+
+// AttachmentsConfig controls what happens to files users send. When enabled,
+// every attachment is kept in the blob store, text formats and PDFs are
+// extracted into the message, and the _files tool reads long documents page
+// by page.
+type AttachmentsConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	InlineBytes int  `yaml:"inline_bytes,omitempty"` // extracted text up to this size goes into the message whole (default 8192); longer text is previewed
+}
+
+// SecretRedactionConfig masks API keys, bearer tokens, AWS credentials,
+// private keys and high-entropy tokens in tool outputs before the LLM sees
+// them or they are stored in the session.
+type SecretRedactionConfig struct {
+	Enabled   bool     `yaml:"enabled"`
+	Allowlist []string `yaml:"allowlist,omitempty"` // regexps; a detected secret whose match (key and value) matches one is kept
+}
+
+// PIIRedactionConfig enables the built-in PII preparer: emails, phone
+// numbers, card numbers and national ids in user input become placeholders
+// such as [EMAIL_1] before preparers, the LLM and the session history see
+// them.
+type PIIRedactionConfig struct {
+	Enabled bool     `yaml:"enabled"`
+	Types   []string `yaml:"types,omitempty"`   // email, phone, credit_card, national_id; empty = all
+	Restore *bool    `yaml:"restore,omitempty"` // put the user's values back into the final reply; default true
+}
+
+// DryRunConfig turns on shadow mode: mutating tool calls are logged and
+// answered with a synthesized result instead of being run. Sessions can opt
+// in on their own with the /dryrun command.
+type DryRunConfig struct {
+	Enabled bool     `yaml:"enabled"`           // every session
+	Plugins []string `yaml:"plugins,omitempty"` // only these plugins, e.g. one being evaluated
+}
+
+// ToolPolicyConfig is one rule enforced in code before a tool call the model
+// makes, unlike the prompt-text rules. Every condition that is set must hold
+// for the policy to apply; the first policy that applies decides.
+type ToolPolicyConfig struct {
+	Name     string            `yaml:"name"`
+	Tools    []string          `yaml:"tools,omitempty"`    // "plugin" or "plugin__action"; empty = every tool
+	Actors   []string          `yaml:"actors,omitempty"`   // "channel:sender" actors or profile entity ids
+	Groups   []string          `yaml:"groups,omitempty"`   // profile groups
+	Hours    string            `yaml:"hours,omitempty"`    // "22:00-06:00"; a range ending before it starts wraps past midnight
+	Days     []string          `yaml:"days,omitempty"`     // mon..sun, with hours: the days the range starts on
+	Timezone string            `yaml:"timezone,omitempty"` // IANA name for hours; empty = local time
+	Args     map[string]string `yaml:"args,omitempty"`     // arg name -> regexp its value must match
+	When     []string          `yaml:"when,omitempty"`     // "arg op value" comparisons, e.g. "amount > 1000"
+	Effect   string            `yaml:"effect"`             // allow, deny or require_approval
+	Message  string            `yaml:"message,omitempty"`  // what the model is told on deny
+}
+
+// ToolScopeConfig narrows the plugins exposed to turns that match it, in
+// the system prompt and when the model calls them.
+type ToolScopeConfig struct {
+	Name     string   `yaml:"name,omitempty"`
+	Channels []string `yaml:"channels,omitempty"` // channel ids
+	Groups   []string `yaml:"groups,omitempty"`   // profile groups
+	Tasks    []string `yaml:"tasks,omitempty"`    // detected task type: code, chat, analysis, transform, deep_conversation, general
+	Plugins  []string `yaml:"plugins,omitempty"`  // only these plugins; empty = any
+	Deny     []string `yaml:"deny,omitempty"`     // never these plugins
+}
+
+// GuardConfig extends the guard that sanitizes tool output. Trust levels are
+// trusted (no forbidden-pattern masking), standard (the default) and
+// untrusted (masked, then checked by the classifier). HTTP request packages
+// and skills default to untrusted.
+type GuardConfig struct {
+	DenyPatterns []string                  `yaml:"deny_patterns,omitempty"` // extra regexps masked in tool output
+	Trust        map[string]string         `yaml:"trust,omitempty"`         // plugin name -> trust level
+	Classifier   InjectionClassifierConfig `yaml:"classifier,omitempty"`
+}
+
+// InjectionClassifierConfig enables an LLM check of untrusted tool output;
+// flagged output is withheld from the model.
+type InjectionClassifierConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Model    string `yaml:"model,omitempty"`     // model id for the check; empty = default model
+	FailOpen bool   `yaml:"fail_open,omitempty"` // pass output through when the check fails; default withholds it
+}
+
+// AgentConfig defines a persona. Agents share the process and plugin
+// registry; each turn runs as the agent picked by an @name mention, the
+// session's current agent, the channel's agent (channels.<name>.agent), or
+// the default agent, in that order.
+type AgentConfig struct {
+	Name         string   `yaml:"name"`                    // also the @mention handle
+	Description  string   `yaml:"description,omitempty"`   // what the agent handles
+	SystemPrompt string   `yaml:"system_prompt,omitempty"` // persona instructions, placed before the built-in preamble
+	Plugins      []string `yaml:"plugins,omitempty"`       // plugins the agent may use; empty = all
+	Model        string   `yaml:"model,omitempty"`         // model pin, e.g. "anthropic/claude-haiku-4"; empty = routing default
+	Default      bool     `yaml:"default,omitempty"`       // handles conversations nothing else routes
+	// HandoffApproval files handoffs to this agent in the approval queue
+	// (approvals.enabled) instead of switching immediately.
+	HandoffApproval bool `yaml:"handoff_approval,omitempty"`
+	// Generation overrides orchestrator.generation for this agent's turns.
+	Generation GenerationConfig `yaml:"generation,omitempty"`
+	// LoopLimits overrides the channel's and orchestrator.loop_limits for
+	// this agent's turns.
+	LoopLimits LoopLimitsConfig `yaml:"loop_limits,omitempty"`
+}
+
+// GenerationConfig controls how the model writes a reply. Unset fields fall
+// back to the next layer (agent or task, then orchestrator.generation, then
+// the model's max_tokens under models.providers, then the provider default).
+type GenerationConfig struct {
+	MaxTokens   int      `yaml:"max_tokens,omitempty"`  // cap on output tokens
+	Temperature *float64 `yaml:"temperature,omitempty"` // 0-2; e.g. 0.2 for consistent tool use
+	TopP        *float64 `yaml:"top_p,omitempty"`       // nucleus sampling, 0-1
+	Stop        []string `yaml:"stop,omitempty"`        // stop sequences
+}
+
+// ExperimentsConfig declares an A/B experiment: variant name -> variant.
+// Each new session is assigned a variant by a hash of its id, weighted by
+// Weight, and keeps it.
+type ExperimentsConfig map[string]ExperimentVariantConfig
+
+// ExperimentVariantConfig is one arm of the experiment.
+type ExperimentVariantConfig struct {
+	Model        string `yaml:"model,omitempty"`         // model pin, e.g. "openai/gpt-4o-mini"; empty = routing default. Profile, agent and branch pins win
+	PromptSuffix string `yaml:"prompt_suffix,omitempty"` // appended to the system prompt
+	Weight       int    `yaml:"weight,omitempty"`        // share of new sessions relative to the other variants (default 1)
+}
+
+// RAGConfig indexes local documents for retrieval. Sources are chunked,
+// embedded with the embeddings model and stored in <data_dir>/rag.db; the
+// LLM searches them with the built-in _knowledge__search tool, and with
+// InjectTopK > 0 the closest passages are added to every turn's system
+// prompt.
+type RAGConfig struct {
+	Enabled         bool               `yaml:"enabled"`
+	Sources         []string           `yaml:"sources"`                    // directories, files or http(s) URLs
+	Embeddings      RAGEmbeddingConfig `yaml:"embeddings"`                 // embeddings endpoint
+	RefreshInterval string             `yaml:"refresh_interval,omitempty"` // re-sync period, e.g. "1h" (default); "0" = at startup only
+	InjectTopK      int                `yaml:"inject_top_k,omitempty"`     // passages injected per turn; 0 = search tool only
+	MinScore        float64            `yaml:"min_score,omitempty"`        // minimum similarity (0-1) for an injected passage
+}
+
+// RAGEmbeddingConfig picks the embeddings model. Provider is a key under
+// models.providers whose base_url and api_key are reused; it must speak the
+// OpenAI-compatible /embeddings API.
+type RAGEmbeddingConfig struct {
+	Provider string `yaml:"provider"`
+	Model    string `yaml:"model"` // e.g. "text-embedding-3-small"
+}
+
+// SystemPromptOverride customizes the system prompt for a channel
+// (channels.<name>.system_prompt) or an actor group
+// (orchestrator.group_system_prompts.<group>). Replace swaps the built-in
+// preamble; the safety rules section always stays. Append is added right
+// after the rules. Merge order: group replace wins over channel replace;
+// channel append comes before group append.
+type SystemPromptOverride struct {
+	Replace string `yaml:"replace,omitempty"` // replaces the built-in preamble (identity + tool-calling instructions)
+	Append  string `yaml:"append,omitempty"`  // extra instructions placed after the rules section
 }
 
 // RepairOrchestratorConfig configures the post-failure tool-call repair
@@ -439,12 +1154,100 @@ type EscalationOrchestratorConfig struct {
 	Enabled bool `yaml:"enabled"` // default false
 }
 
+// AskUserConfig enables the built-in _ask_user tool: instead of guessing a
+// missing argument, the model asks the user and the turn pauses until the
+// reply arrives.
+type AskUserConfig struct {
+	Enabled bool   `yaml:"enabled"`           // default false
+	Timeout string `yaml:"timeout,omitempty"` // Go duration an open question waits; default "30m"
+}
+
+// ResumeConfig checkpoints each agent loop in the state database. On
+// startup, turns a restart cut off are resumed, or their sessions are told
+// the request was interrupted.
+type ResumeConfig struct {
+	Enabled    bool   `yaml:"enabled"`               // default false; needs the state database
+	MaxAge     string `yaml:"max_age,omitempty"`     // Go duration; older checkpoints are only notified; default "15m"
+	NotifyOnly bool   `yaml:"notify_only,omitempty"` // never re-run a turn, only notify its session
+}
+
+// JobsConfig tracks async tool jobs: an action that replies with structured
+// content {"status":"accepted","job_id":"..."} is polled through the
+// plugin's status action in the background, and the session is told when it
+// finishes.
+type JobsConfig struct {
+	Enabled      bool   `yaml:"enabled"`                 // default false
+	PollInterval string `yaml:"poll_interval,omitempty"` // Go duration between checks of running jobs; default "30s"
+	MaxAge       string `yaml:"max_age,omitempty"`       // Go duration after which a running job is given up; default "24h"
+}
+
+// LoopLimitsConfig bounds one turn's agent loop (LLM round, tool calls,
+// next round). A turn that runs out gets one last LLM call that sums up its
+// progress and asks the user how to go on; one that keeps repeating the same
+// tool calls is stopped earlier with a note to the user.
+type LoopLimitsConfig struct {
+	MaxIterations int    `yaml:"max_iterations,omitempty"` // LLM rounds per turn; default 20
+	MaxDuration   string `yaml:"max_duration,omitempty"`   // Go duration for the whole loop; default none
+}
+
+// ToolCacheConfig reuses the result of a read-only tool call when the same
+// user makes the identical call again within its TTL. Actions without
+// read_only are never cached.
+type ToolCacheConfig struct {
+	Enabled    bool              `yaml:"enabled"`
+	DefaultTTL string            `yaml:"default_ttl,omitempty"` // Go duration for read-only actions that declare no cache_ttl; "" = cache only those that do
+	TTL        map[string]string `yaml:"ttl,omitempty"`         // "plugin.action" -> Go duration, overriding the action's cache_ttl; "0" = never cache it
+	MaxEntries int               `yaml:"max_entries,omitempty"` // in-memory entries; default 1000
+	Persist    bool              `yaml:"persist,omitempty"`     // also keep results in the state database, shared by restarts and replicas
+}
+
+// LLMCacheConfig answers an LLM request that exactly repeats an earlier one
+// (same model, messages, tools and generation settings) from memory.
+// Streamed requests always reach the model.
+type LLMCacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	TTL        string `yaml:"ttl,omitempty"`         // Go duration; default "10m"
+	MaxEntries int    `yaml:"max_entries,omitempty"` // default 500
+}
+
 type StateConfig struct {
 	DataDir       string              `yaml:"data_dir"`
+	Backend       string              `yaml:"backend,omitempty"` // shorthand for db.driver: "sqlite" (default) or "postgres"
+	DSN           string              `yaml:"dsn,omitempty"`     // shorthand for db.dsn
 	DB            DBConfig            `yaml:"db,omitempty"`
 	Session       SessionConfig       `yaml:"session,omitempty"`
 	Debug         DebugConfig         `yaml:"debug,omitempty"`
 	SessionEvents SessionEventsConfig `yaml:"session_events,omitempty"`
+	Blobs         BlobsConfig         `yaml:"blobs,omitempty"`
+	Maintenance   MaintenanceConfig   `yaml:"maintenance,omitempty"`
+	Encryption    EncryptionConfig    `yaml:"encryption,omitempty"`
+}
+
+// BlobsConfig moves large payloads out of session messages into a
+// content-addressed blob store. Tool outputs above OffloadBytes are stored
+// whole; the session keeps a preview and a "blob:sha256:…" reference the LLM
+// can page through with the built-in _blob__read tool. Blobs no session
+// references are garbage-collected once older than session.max_idle_days
+// (one day when idle pruning is off).
+type BlobsConfig struct {
+	Enabled      bool         `yaml:"enabled"`
+	Backend      string       `yaml:"backend,omitempty"`        // "local" (default) or "s3"
+	Dir          string       `yaml:"dir,omitempty"`            // local backend directory; default <data_dir>/blobs
+	MaxBlobBytes int64        `yaml:"max_blob_bytes,omitempty"` // largest single blob (default 10 MiB); bigger outputs stay inline, truncated by the guard
+	OffloadBytes int          `yaml:"offload_bytes,omitempty"`  // tool outputs larger than this are offloaded (default 16384)
+	S3           BlobS3Config `yaml:"s3,omitempty"`
+}
+
+// BlobS3Config addresses an S3 bucket or S3-compatible service. Empty
+// credentials fall back to the standard AWS_* environment variables.
+type BlobS3Config struct {
+	Bucket          string `yaml:"bucket"`
+	Region          string `yaml:"region,omitempty"`            // default us-east-1
+	Endpoint        string `yaml:"endpoint,omitempty"`          // e.g. http://minio:9000; empty = AWS
+	Prefix          string `yaml:"prefix,omitempty"`            // key prefix, e.g. "opentalon/blobs/"
+	AccessKeyID     string `yaml:"access_key_id,omitempty"`     // supports ${ENV_VAR}
+	SecretAccessKey string `yaml:"secret_access_key,omitempty"` // supports ${ENV_VAR}
+	PathStyle       bool   `yaml:"path_style,omitempty"`        // bucket in the URL path; implied by endpoint
 }
 
 // DebugConfig configures deep raw-HTTP capture of every LLM exchange into the
@@ -496,6 +1299,24 @@ type SessionEventsConfig struct {
 	RetentionDisabled bool `yaml:"retention_disabled,omitempty"` // when true, never prune (overrides RetentionDays)
 }
 
+// applyBackend folds the state.backend / state.dsn shorthand into DB,
+// refusing values that contradict an explicit db block.
+func (s *StateConfig) applyBackend() error {
+	if s.Backend != "" {
+		if s.DB.Driver != "" && s.DB.Driver != s.Backend {
+			return fmt.Errorf("state.backend %q conflicts with state.db.driver %q", s.Backend, s.DB.Driver)
+		}
+		s.DB.Driver = s.Backend
+	}
+	if s.DSN != "" {
+		if s.DB.DSN != "" && s.DB.DSN != s.DSN {
+			return fmt.Errorf("state.dsn conflicts with state.db.dsn; set only one")
+		}
+		s.DB.DSN = s.DSN
+	}
+	return nil
+}
+
 // DBConfig selects the database backend. Driver defaults to "sqlite".
 // For postgres, set driver: "postgres" and provide a DSN.
 type DBConfig struct {
@@ -505,6 +1326,37 @@ type DBConfig struct {
 	MaxIdleConns    int    `yaml:"max_idle_conns"`     // max idle connections (postgres only; 0 = default 10)
 	ConnMaxLifetime string `yaml:"conn_max_lifetime"`  // max connection lifetime (postgres only; Go duration, e.g. "5m"; empty = default 5m)
 	ConnMaxIdleTime string `yaml:"conn_max_idle_time"` // max idle time per connection (postgres only; Go duration; empty = default 5m)
+
+	// SQLite only.
+	JournalMode string `yaml:"journal_mode,omitempty"` // "wal" (default), "delete", "truncate" or "persist"
+	BusyTimeout string `yaml:"busy_timeout,omitempty"` // Go duration a statement waits for a locked database (default "5s")
+}
+
+// MaintenanceConfig runs periodic housekeeping on the state database. Size
+// caps prune the least recently updated sessions (and their messages); then
+// ANALYZE refreshes the query planner's statistics and, with vacuum on
+// SQLite, VACUUM returns the freed pages to the filesystem.
+type MaintenanceConfig struct {
+	Interval    string `yaml:"interval,omitempty"`     // Go duration between runs, e.g. "24h"; empty = off
+	Vacuum      bool   `yaml:"vacuum,omitempty"`       // SQLite: VACUUM each run; rewrites the file and blocks writers meanwhile
+	MaxSizeMB   int    `yaml:"max_size_mb,omitempty"`  // SQLite: prune sessions until the data takes at most this; 0 = no cap
+	MaxSessions int    `yaml:"max_sessions,omitempty"` // keep at most this many sessions; 0 = no cap
+}
+
+// EncryptionConfig encrypts message content, session summaries and memories
+// in the state database with AES-256-GCM. Keys are 32 bytes, base64 or hex.
+// To rotate, make the new key current, keep the old one in PreviousKeys
+// until `opentalon state rotate-key` has re-encrypted everything, then drop
+// it.
+type EncryptionConfig struct {
+	Key          string   `yaml:"key,omitempty"`           // accepts ${VAR} and file:/path
+	KeyCommand   []string `yaml:"key_command,omitempty"`   // argv printing the key on stdout, e.g. a KMS decrypt; instead of key
+	PreviousKeys []string `yaml:"previous_keys,omitempty"` // still decrypted, never written; accept ${VAR} and file:/path
+}
+
+// Enabled reports whether a key source is configured.
+func (e EncryptionConfig) Enabled() bool {
+	return e.Key != "" || len(e.KeyCommand) > 0
 }
 
 // SessionConfig limits session size and optional idle pruning.
@@ -517,11 +1369,79 @@ type SessionConfig struct {
 	SummarizePrompt         string `yaml:"summarize_prompt"`           // system prompt for initial summarization (any language); empty = default English
 	SummarizeUpdatePrompt   string `yaml:"summarize_update_prompt"`    // system prompt for updating existing summary (any language); empty = default English
 	SessionTitlePrompt      string `yaml:"session_title_prompt"`       // system prompt for the background title-generation pass (any language); empty = default in internal/prompts/orchestrator_session_title.txt
+
+	Completion SessionCompletionConfig `yaml:"completion,omitempty"`
+}
+
+// SessionCompletionConfig marks a session completed once no turn has run for
+// its idle timeout: it publishes session_completed and runs the optional
+// summarization and actions. A later message resumes the session, and it
+// completes again after the next quiet period.
+type SessionCompletionConfig struct {
+	IdleTimeout string                    `yaml:"idle_timeout,omitempty"` // Go duration, e.g. "30m"; empty = sessions never complete
+	Channels    map[string]string         `yaml:"channels,omitempty"`     // per-channel idle_timeout by channel id; "0" turns completion off for that channel
+	Summarize   bool                      `yaml:"summarize,omitempty"`    // fold the session into its summary (keeps max_messages_after_summary)
+	Actions     []SessionCompletionAction `yaml:"actions,omitempty"`      // run in order with args session_id, idle_for
+}
+
+// SessionCompletionAction is a plugin action run when a session completes,
+// e.g. scoring or archiving the conversation.
+type SessionCompletionAction struct {
+	Plugin string `yaml:"plugin"`
+	Action string `yaml:"action"`
 }
 
 type ModelsConfig struct {
 	Providers map[string]ProviderConfig `yaml:"providers"`
 	Catalog   map[string]CatalogEntry   `yaml:"catalog"`
+	Discovery ModelDiscoveryConfig      `yaml:"discovery,omitempty"` // list models from the provider APIs
+}
+
+// ModelDiscoveryConfig lists each provider's models from its API (GET
+// /v1/models, Anthropic's Models API) at startup and, with an interval,
+// periodically. Listed models join the configured ones, configured models
+// without a context_window take the one the API reports, and a configured
+// model the API no longer lists is logged as a warning.
+type ModelDiscoveryConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Interval string `yaml:"interval,omitempty"` // Go duration between listings, e.g. "6h"; empty = at startup only
+}
+
+// ResolveAlias returns the catalog model ref whose alias is name (any
+// case), or name unchanged when no alias matches.
+func (m ModelsConfig) ResolveAlias(name string) string {
+	if ref, ok := m.Aliases()[strings.ToLower(name)]; ok {
+		return ref
+	}
+	return name
+}
+
+// Aliases maps each catalog alias, lower-cased, to its model ref.
+func (m ModelsConfig) Aliases() map[string]string {
+	aliases := make(map[string]string, len(m.Catalog))
+	for ref, entry := range m.Catalog {
+		if entry.Alias != "" {
+			aliases[strings.ToLower(entry.Alias)] = ref
+		}
+	}
+	return aliases
+}
+
+// Resolve turns a catalog alias ("opus", any case) or a provider/model ref
+// of a configured provider into a provider/model ref.
+func (m ModelsConfig) Resolve(name string) (string, error) {
+	if ref := m.ResolveAlias(name); ref != name {
+		return ref, nil
+	}
+	if _, ok := m.Catalog[name]; ok {
+		return name, nil
+	}
+	if providerID, modelID, ok := strings.Cut(name, "/"); ok && modelID != "" {
+		if _, ok := m.Providers[providerID]; ok {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown model %q: use an alias from models.catalog or provider/model", name)
 }
 
 type ProviderConfig struct {
@@ -530,6 +1450,9 @@ type ProviderConfig struct {
 	API     string            `yaml:"api"`
 	Models  []ModelDefinition `yaml:"models"`
 	Retry   RetryConfig       `yaml:"retry"` // optional; transient-failure (429/5xx) retry tuning
+	// PromptCache marks the system prompt and tool definitions as cacheable
+	// (anthropic-messages only). Default on; false turns it off.
+	PromptCache *bool `yaml:"prompt_cache,omitempty"`
 }
 
 // RetryConfig tunes per-provider retry on transient LLM failures (429 rate
@@ -556,8 +1479,10 @@ type ModelDefinition struct {
 }
 
 type CostConfig struct {
-	Input  float64 `yaml:"input"`
-	Output float64 `yaml:"output"`
+	Input      float64 `yaml:"input"`
+	Output     float64 `yaml:"output"`
+	CacheRead  float64 `yaml:"cache_read,omitempty"`  // prompt-cache reads; 0 = 10% of input
+	CacheWrite float64 `yaml:"cache_write,omitempty"` // prompt-cache writes; 0 = 125% of input
 }
 
 type CatalogEntry struct {
@@ -571,6 +1496,29 @@ type RoutingConfig struct {
 	Pin       map[string]string `yaml:"pin"`
 	Affinity  AffinityConfig    `yaml:"affinity"`
 	Health    HealthCheckConfig `yaml:"health"`
+	Offline   OfflineConfig     `yaml:"offline,omitempty"`
+	Ops       RoutingOpsConfig  `yaml:"ops,omitempty"`
+}
+
+// RoutingOpsConfig is where operators hear about provider trouble. With a
+// channel and conversation set, every failover, every request that failed on
+// all endpoints and every trip or recovery of the primary is posted there,
+// at most once per MinInterval for the same endpoint and kind of event. The
+// provider.status tool (registered when routing has fallbacks or an offline
+// model) is limited to AllowedGroups.
+type RoutingOpsConfig struct {
+	Channel       string   `yaml:"channel,omitempty"`        // channel id, e.g. "slack"
+	Conversation  string   `yaml:"conversation,omitempty"`   // conversation (room, chat) id on that channel
+	MinInterval   string   `yaml:"min_interval,omitempty"`   // Go duration between repeats of one alert; default "5m"
+	AllowedGroups []string `yaml:"allowed_groups,omitempty"` // profile groups that may use provider.status; empty = everyone
+}
+
+// OfflineConfig names a local model (typically Ollama) that serves turns
+// when neither routing.primary nor any fallback is reachable, at startup or
+// during an outage. Users get a one-time notice per outage that a smaller
+// model is answering. Reachability is probed with routing.health settings.
+type OfflineConfig struct {
+	Model string `yaml:"model"` // routing ref, e.g. "ollama/llama3.2"; empty = off
 }
 
 // HealthCheckConfig tunes the health-gated fallback that prefers routing.primary
@@ -586,6 +1534,14 @@ type HealthCheckConfig struct {
 	Interval     string `yaml:"interval"`      // probe interval (Go duration); default "10s"
 	Timeout      string `yaml:"timeout"`       // per-probe timeout (Go duration); default "3s"
 	RecoverAfter int    `yaml:"recover_after"` // consecutive healthy probes before switching back; default 3
+
+	// Every endpoint (fallbacks too) is probed at the same interval and
+	// scored over its recent probes; an endpoint that fails or answers slowly
+	// too often is degraded and tried after the others.
+	Probe         string  `yaml:"probe,omitempty"`          // "models" (GET the probe path; default) or "completion" (one-token completion; costs tokens)
+	Window        int     `yaml:"window,omitempty"`         // probes per endpoint the score is computed from; default 10
+	SlowAfter     string  `yaml:"slow_after,omitempty"`     // mean probe latency above which the score drops (Go duration); default "2s"
+	DegradedBelow float64 `yaml:"degraded_below,omitempty"` // score (0-1) under which an endpoint is degraded; default 0.5
 }
 
 type AffinityConfig struct {
@@ -625,8 +1581,61 @@ func expandTilde(s string) string {
 	return s
 }
 
-func expandEnvInRequestPackages(cfg *Config) {
+// resolveSecret expands ${VAR} in v; a result of the form file:<path> is
+// replaced by the file's contents without the trailing newline, so values
+// can come from mounted Kubernetes or Docker secrets.
+func resolveSecret(v string) (string, error) {
+	v = expandEnv(v)
+	path, ok := strings.CutPrefix(v, "file:")
+	if !ok {
+		return v, nil
+	}
+	data, err := os.ReadFile(expandTilde(path))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveSecrets applies resolveSecret to every value of m in place. where
+// names m in errors.
+func resolveSecrets(m map[string]string, where string) error {
+	for k, v := range m {
+		resolved, err := resolveSecret(v)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", where, k, err)
+		}
+		m[k] = resolved
+	}
+	return nil
+}
+
+// resolveEncryptionKeys resolves the ${VAR} and file: forms of the keys in
+// e. KeyCommand runs later, when the state database is opened.
+func resolveEncryptionKeys(e *EncryptionConfig) error {
+	if e.Key != "" && len(e.KeyCommand) > 0 {
+		return errors.New("state.encryption: set key or key_command, not both")
+	}
+	var err error
+	if e.Key, err = resolveSecret(e.Key); err != nil {
+		return fmt.Errorf("state.encryption.key: %w", err)
+	}
+	for i, k := range e.PreviousKeys {
+		if e.PreviousKeys[i], err = resolveSecret(k); err != nil {
+			return fmt.Errorf("state.encryption.previous_keys[%d]: %w", i, err)
+		}
+	}
+	if e.Key == "" && len(e.KeyCommand) == 0 && len(e.PreviousKeys) > 0 {
+		return errors.New("state.encryption: previous_keys needs a current key")
+	}
+	return nil
+}
+
+func expandEnvInRequestPackages(cfg *Config) error {
 	for i, inl := range cfg.RequestPackages.Inline {
+		if err := resolveSecrets(inl.Config, "request_packages.inline."+inl.Plugin+".config"); err != nil {
+			return err
+		}
 		if inl.MCP != nil {
 			inl.MCP.URL = expandEnv(inl.MCP.URL)
 			for k, v := range inl.MCP.Headers {
@@ -642,6 +1651,7 @@ func expandEnvInRequestPackages(cfg *Config) {
 			cfg.RequestPackages.Inline[i].Packages[j] = p
 		}
 	}
+	return nil
 }
 
 func expandEnvInProviders(cfg *Config) {
@@ -676,11 +1686,15 @@ func Parse(data []byte) (*Config, error) {
 		return nil, fmt.Errorf("parsing config: %w", err)
 	}
 	expandEnvInProviders(&cfg)
-	expandEnvInPlugins(&cfg)
+	if err := expandEnvInPlugins(&cfg); err != nil {
+		return nil, err
+	}
 	expandEnvInChannels(&cfg)
 	expandEnvInBootstrap(&cfg)
 	expandEnvInRedis(&cfg)
-	expandEnvInRequestPackages(&cfg)
+	if err := expandEnvInRequestPackages(&cfg); err != nil {
+		return nil, err
+	}
 	expandEnvInEventWebhook(&cfg)
 	cfg.Cluster.DedupTTL = expandEnv(cfg.Cluster.DedupTTL)
 	cfg.Metrics.Addr = expandEnv(cfg.Metrics.Addr)
@@ -688,12 +1702,30 @@ func Parse(data []byte) (*Config, error) {
 		cfg.Metrics.Addr = ":2112"
 	}
 	cfg.Health.Addr = expandEnv(cfg.Health.Addr)
+	cfg.Daemon.PIDFile = expandEnv(cfg.Daemon.PIDFile)
+	cfg.Daemon.HealthFile = expandEnv(cfg.Daemon.HealthFile)
+	cfg.Ctl.Socket = expandEnv(cfg.Ctl.Socket)
+	cfg.Dashboard.Addr = expandEnv(cfg.Dashboard.Addr)
+	cfg.Dashboard.Token = expandEnv(cfg.Dashboard.Token)
+	cfg.Approvals.AdminAddr = expandEnv(cfg.Approvals.AdminAddr)
+	cfg.Approvals.AdminToken = expandEnv(cfg.Approvals.AdminToken)
+	cfg.Workflows.APIAddr = expandEnv(cfg.Workflows.APIAddr)
+	cfg.Workflows.APIToken = expandEnv(cfg.Workflows.APIToken)
 	if cfg.Health.Addr == "" {
 		cfg.Health.Addr = ":8086"
 	}
+	if err := resolveModelAliases(&cfg); err != nil {
+		return nil, err
+	}
+	if err := cfg.State.applyBackend(); err != nil {
+		return nil, err
+	}
 	if cfg.State.DB.DSN != "" {
 		cfg.State.DB.DSN = expandEnv(cfg.State.DB.DSN)
 	}
+	if err := resolveEncryptionKeys(&cfg.State.Encryption); err != nil {
+		return nil, err
+	}
 	if cfg.State.DataDir == "" {
 		home, _ := os.UserHomeDir()
 		cfg.State.DataDir = filepath.Join(home, ".opentalon")
@@ -716,10 +1748,54 @@ func Parse(data []byte) (*Config, error) {
 		if cfg.Lua.DefaultRef != "" {
 			cfg.Lua.DefaultRef = expandEnv(cfg.Lua.DefaultRef)
 		}
+		for script, values := range cfg.Lua.Config {
+			if err := resolveSecrets(values, "lua.config."+script); err != nil {
+				return nil, err
+			}
+		}
 	}
 	return &cfg, nil
 }
 
+// resolveModelAliases checks the catalog aliases and replaces an alias with
+// its model ref in every setting that names a model to route to, so
+// "opus" works wherever "anthropic/claude-opus-4" does and a model upgrade
+// is a one-line catalog change.
+func resolveModelAliases(cfg *Config) error {
+	owner := make(map[string]string)
+	for _, ref := range slices.Sorted(maps.Keys(cfg.Models.Catalog)) {
+		alias := cfg.Models.Catalog[ref].Alias
+		if alias == "" {
+			continue
+		}
+		if strings.Contains(alias, "/") {
+			return fmt.Errorf("models.catalog.%s: alias %q must not contain \"/\"", ref, alias)
+		}
+		if other, ok := owner[strings.ToLower(alias)]; ok {
+			return fmt.Errorf("models.catalog: alias %q is used by both %s and %s", alias, other, ref)
+		}
+		owner[strings.ToLower(alias)] = ref
+	}
+	resolve := cfg.Models.ResolveAlias
+	cfg.Routing.Primary = resolve(cfg.Routing.Primary)
+	for i, ref := range cfg.Routing.Fallbacks {
+		cfg.Routing.Fallbacks[i] = resolve(ref)
+	}
+	for task, ref := range cfg.Routing.Pin {
+		cfg.Routing.Pin[task] = resolve(ref)
+	}
+	cfg.Routing.Offline.Model = resolve(cfg.Routing.Offline.Model)
+	for i := range cfg.Agents {
+		cfg.Agents[i].Model = resolve(cfg.Agents[i].Model)
+	}
+	for name, v := range cfg.Experiments {
+		v.Model = resolve(v.Model)
+		cfg.Experiments[name] = v
+	}
+	cfg.Evaluation.Model = resolve(cfg.Evaluation.Model)
+	return nil
+}
+
 // ResolveStateDataDir returns an absolute path for state data storage.
 // If state.data_dir is relative, it is resolved against the directory
 // containing configFile. configFile should be an absolute path; if it is not,
@@ -751,7 +1827,7 @@ func ResolveStateDataDir(cfg *Config, configFile string) string {
 	return filepath.Clean(filepath.Join(cfgDir, d))
 }
 
-func expandEnvInPlugins(cfg *Config) {
+func expandEnvInPlugins(cfg *Config) error {
 	for name, p := range cfg.Plugins {
 		p.Plugin = expandEnv(p.Plugin)
 		for k, v := range p.Config {
@@ -759,8 +1835,15 @@ func expandEnvInPlugins(cfg *Config) {
 				p.Config[k] = expandEnv(s)
 			}
 		}
+		if err := resolveSecrets(p.Env, "plugins."+name+".env"); err != nil {
+			return err
+		}
+		if p.WorkDir != "" {
+			p.WorkDir = expandTilde(expandEnv(p.WorkDir))
+		}
 		cfg.Plugins[name] = p
 	}
+	return nil
 }
 
 func expandEnvInBootstrap(cfg *Config) {
@@ -70,3 +70,19 @@ func AppendInstalledSkill(dataDir string, skill SkillEntry) error {
 	skills = append(skills, skill)
 	return SaveInstalledSkills(dataDir, skills)
 }
+
+// UpsertInstalledSkill saves skill to installed_skills.yaml, replacing an
+// entry with the same name.
+func UpsertInstalledSkill(dataDir string, skill SkillEntry) error {
+	skills, err := LoadInstalledSkills(dataDir)
+	if err != nil {
+		return err
+	}
+	for i, s := range skills {
+		if s.Name == skill.Name {
+			skills[i] = skill
+			return SaveInstalledSkills(dataDir, skills)
+		}
+	}
+	return SaveInstalledSkills(dataDir, append(skills, skill))
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// defaultReloadDebounce coalesces the burst of write/rename events editors and
+// config-management tools produce for a single save.
+const defaultReloadDebounce = 500 * time.Millisecond
+
+// ReloadConfig enables watching the config file and applying the safe subset
+// of changes without a restart: orchestrator rules, routing primary/fallbacks
+// (including model catalog weights and provider entries, which feed provider
+// selection), and static scheduler jobs. Changes to any other section are
+// logged as requiring a restart and otherwise ignored.
+type ReloadConfig struct {
+	Enabled  bool   `yaml:"enabled"`            // default false
+	Debounce string `yaml:"debounce,omitempty"` // Go duration; default "500ms"
+}
+
+// ReloadDiff is the outcome of comparing the running config with a freshly
+// parsed one. The boolean fields name the live-reloadable subsets that
+// changed; Ignored lists the other top-level sections that changed (by yaml
+// key) and only take effect after a restart.
+type ReloadDiff struct {
+	Rules     bool
+	Routing   bool
+	Scheduler bool
+	Ignored   []string
+}
+
+// Empty reports whether nothing reloadable changed.
+func (d ReloadDiff) Empty() bool {
+	return !d.Rules && !d.Routing && !d.Scheduler
+}
+
+// DiffReload compares old and next and classifies each change as live
+// (rules, routing, scheduler jobs) or restart-only.
+func DiffReload(old, next *Config) ReloadDiff {
+	var d ReloadDiff
+	d.Rules = !reflect.DeepEqual(old.Orchestrator.Rules, next.Orchestrator.Rules)
+	d.Routing = !reflect.DeepEqual(old.Routing, next.Routing) || !reflect.DeepEqual(old.Models, next.Models)
+	d.Scheduler = !reflect.DeepEqual(old.Scheduler.Jobs, next.Scheduler.Jobs)
+
+	// Everything else: compare top-level sections with the live fields
+	// blanked so a rules-only edit doesn't also report "orchestrator".
+	o, n := *old, *next
+	o.Orchestrator.Rules, n.Orchestrator.Rules = nil, nil
+	o.Scheduler.Jobs, n.Scheduler.Jobs = nil, nil
+	o.Routing, n.Routing = RoutingConfig{}, RoutingConfig{}
+	o.Models, n.Models = ModelsConfig{}, ModelsConfig{}
+	ov, nv := reflect.ValueOf(o), reflect.ValueOf(n)
+	t := ov.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(ov.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = t.Field(i).Name
+		}
+		d.Ignored = append(d.Ignored, name)
+	}
+	sort.Strings(d.Ignored)
+	return d
+}
+
+// Watch watches the config file at path and calls onChange with the newly
+// parsed config after each write settles. The parent directory is watched
+// rather than the file so atomic saves (write temp + rename) and Kubernetes
+// ConfigMap symlink swaps are picked up. A file that fails to parse is logged
+// and skipped; the running config stays in effect. Watch returns once the
+// watcher is set up; it stops when ctx is done.
+func Watch(ctx context.Context, path string, debounce time.Duration, onChange func(*Config)) error {
+	if debounce <= 0 {
+		debounce = defaultReloadDebounce
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := w.Add(filepath.Dir(abs)); err != nil {
+		_ = w.Close()
+		return err
+	}
+	base := filepath.Base(abs)
+
+	go func() {
+		defer func() { _ = w.Close() }()
+		var timer *time.Timer
+		var fire <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				// ConfigMap mounts swap a "..data" symlink rather than
+				// touching the file itself, so accept that name too.
+				name := filepath.Base(ev.Name)
+				if name != base && name != "..data" {
+					continue
+				}
+				if !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Rename) {
+					continue
+				}
+				if timer == nil {
+					timer = time.NewTimer(debounce)
+				} else {
+					timer.Reset(debounce)
+				}
+				fire = timer.C
+			case <-fire:
+				fire = nil
+				next, err := Load(abs)
+				if err != nil {
+					slog.Warn("config reload: keeping running config", "component", "config", "path", abs, "error", err)
+					continue
+				}
+				onChange(next)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("config watcher error", "component", "config", "error", err)
+			}
+		}
+	}()
+	return nil
+}
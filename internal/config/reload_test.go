@@ -0,0 +1,125 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reloadBaseYAML = `
+routing:
+  primary: a/m1
+orchestrator:
+  rules: ["be brief"]
+scheduler:
+  jobs:
+    - name: ping
+      interval: 1h
+      action: p__a
+log:
+  level: info
+`
+
+func mustParse(t *testing.T, y string) *Config {
+	t.Helper()
+	cfg, err := Parse([]byte(y))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestDiffReload_NoChange(t *testing.T) {
+	d := DiffReload(mustParse(t, reloadBaseYAML), mustParse(t, reloadBaseYAML))
+	if !d.Empty() || len(d.Ignored) != 0 {
+		t.Errorf("diff of identical configs = %+v; want empty", d)
+	}
+}
+
+func TestDiffReload_ClassifiesSections(t *testing.T) {
+	next := mustParse(t, `
+routing:
+  primary: b/m2
+orchestrator:
+  rules: ["be brief", "no emojis"]
+  max_concurrent_sessions: 4
+scheduler:
+  jobs:
+    - name: ping
+      interval: 30m
+      action: p__a
+log:
+  level: debug
+`)
+	d := DiffReload(mustParse(t, reloadBaseYAML), next)
+	if !d.Rules || !d.Routing || !d.Scheduler {
+		t.Errorf("live subsets not detected: %+v", d)
+	}
+	if want := []string{"log", "orchestrator"}; !reflect.DeepEqual(d.Ignored, want) {
+		t.Errorf("Ignored = %v; want %v", d.Ignored, want)
+	}
+}
+
+func TestDiffReload_RulesOnlyDoesNotFlagOrchestrator(t *testing.T) {
+	next := mustParse(t, reloadBaseYAML)
+	next.Orchestrator.Rules = []string{"other"}
+	d := DiffReload(mustParse(t, reloadBaseYAML), next)
+	if !d.Rules || d.Routing || d.Scheduler || len(d.Ignored) != 0 {
+		t.Errorf("diff = %+v; want rules only", d)
+	}
+}
+
+func TestDiffReload_CatalogWeightIsRouting(t *testing.T) {
+	old := mustParse(t, reloadBaseYAML)
+	next := mustParse(t, reloadBaseYAML)
+	next.Models.Catalog = map[string]CatalogEntry{"a/m1": {Weight: 80}}
+	if d := DiffReload(old, next); !d.Routing {
+		t.Errorf("catalog change not classified as routing: %+v", d)
+	}
+}
+
+func TestWatch_ReloadsOnWriteAndSkipsInvalid(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(reloadBaseYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	got := make(chan *Config, 4)
+	if err := Watch(ctx, path, 20*time.Millisecond, func(c *Config) { got <- c }); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unparseable file is skipped, not delivered.
+	if err := os.WriteFile(path, []byte("routing: ["), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-got:
+		t.Fatalf("invalid config delivered: %+v", c)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// Atomic save: write a sibling temp file, then rename over the config.
+	tmp := filepath.Join(dir, "config.yaml.tmp")
+	if err := os.WriteFile(tmp, []byte(strings.Replace(reloadBaseYAML, "a/m1", "b/m2", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case c := <-got:
+		if c.Routing.Primary != "b/m2" {
+			t.Errorf("reloaded primary = %q; want b/m2", c.Routing.Primary)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no reload after rename")
+	}
+}
@@ -0,0 +1,452 @@
+// Package ctl is the admin API `opentalon ctl` uses to inspect and manage a
+// running instance. It is served as JSON over HTTP on a local Unix socket;
+// access is governed by the socket file's permissions (owner only), the
+// same trust as reading the state database directly.
+package ctl
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scheduler"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+// SessionAdmin lists, reads and deletes sessions. *store.SessionStore
+// satisfies it.
+type SessionAdmin interface {
+	ListSummaries(ctx context.Context, limit int) ([]store.SessionSummary, error)
+	Get(id string) (*state.Session, error)
+	Messages(ctx context.Context, id string, page store.MessagePage) ([]store.StoredMessage, error)
+	Delete(id string) error
+}
+
+// JobAdmin lists scheduler jobs, runs one now and deletes one.
+// *scheduler.Scheduler satisfies it.
+type JobAdmin interface {
+	ListJobs() []scheduler.Job
+	RetryJob(ctx context.Context, name string) error
+	DeleteJob(name string) error
+}
+
+// PluginAdmin lists and reloads plugins. *plugin.Manager satisfies it.
+type PluginAdmin interface {
+	List() []string
+	Reload(ctx context.Context, name string) error
+}
+
+// MemoryAdmin searches long-term memories.
+type MemoryAdmin interface {
+	Search(query string) []*state.Memory
+}
+
+// UsageAdmin reports token usage. *store.UsageStore satisfies it.
+type UsageAdmin interface {
+	UsageReport(ctx context.Context, since time.Time, by string) ([]store.UsageTotal, error)
+}
+
+// ProviderAdmin reports the LLM endpoints behind the failover wrapper,
+// primary first. provider.StatusReporter satisfies it.
+type ProviderAdmin interface {
+	EndpointStatus() []provider.EndpointStatus
+}
+
+// ActorAdmin exports and erases an actor's data. *store.ActorDataStore
+// satisfies it.
+type ActorAdmin interface {
+	Export(ctx context.Context, actorID string) (*store.ActorExport, error)
+	Forget(ctx context.Context, actorID string, dryRun bool) (store.ForgetReport, error)
+}
+
+// ActorExport is the answer of GET /actors/{id}/export: the state database's
+// export plus the actor's scheduler jobs.
+type ActorExport struct {
+	*store.ActorExport
+	Jobs []scheduler.Job `json:"jobs,omitempty"`
+}
+
+// ActorForget is the answer of POST /actors/{id}/forget: the rows deleted,
+// or that would be, and the names of the scheduler jobs likewise.
+type ActorForget struct {
+	store.ForgetReport
+	Jobs []string `json:"jobs,omitempty"`
+}
+
+// Backends are what the API operates on. A nil backend answers its routes
+// with 501, e.g. sessions and usage without a state database.
+type Backends struct {
+	Sessions SessionAdmin
+	Jobs     JobAdmin
+	Plugins  PluginAdmin
+	Memory   MemoryAdmin
+	Usage    UsageAdmin
+
+	Providers ProviderAdmin
+	Actors    ActorAdmin
+}
+
+// NewHandler returns the admin API:
+//
+//	GET    /sessions?limit=N          newest sessions first (default 50)
+//	GET    /sessions/{id}             a session with its messages
+//	GET    /sessions/{id}/messages?after=N&before=N&limit=N
+//	                                  a page of messages by seq, oldest first (default 50)
+//	DELETE /sessions/{id}
+//	GET    /jobs
+//	POST   /jobs/{name}/run           run a job now; answers when it is done
+//	GET    /plugins
+//	POST   /plugins/{name}/reload
+//	GET    /memory?q=...
+//	GET    /usage?since=24h&by=entity
+//	GET    /providers                 LLM endpoint health, cooldown and success rates
+//	GET    /actors/{id}/export        everything stored about an actor
+//	POST   /actors/{id}/forget?dry_run=true
+//	                                  delete it; dry_run only counts
+//
+// Errors are {"error": "..."}.
+func NewHandler(b Backends) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Sessions != nil, "sessions") {
+			return
+		}
+		limit := 50
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "limit must be a number")
+				return
+			}
+			limit = n
+		}
+		list, err := b.Sessions.ListSummaries(r.Context(), limit)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, nonNil(list))
+	})
+	mux.HandleFunc("GET /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Sessions != nil, "sessions") {
+			return
+		}
+		sess, err := b.Sessions.Get(r.PathValue("id"))
+		if errors.Is(err, state.ErrSessionNotFound) || (err == nil && sess == nil) {
+			writeError(w, http.StatusNotFound, "unknown session "+r.PathValue("id"))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, sess)
+	})
+	mux.HandleFunc("GET /sessions/{id}/messages", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Sessions != nil, "sessions") {
+			return
+		}
+		var page store.MessagePage
+		for _, p := range []struct {
+			name string
+			dst  *int64
+		}{{"after", &page.AfterSeq}, {"before", &page.BeforeSeq}} {
+			if v := r.URL.Query().Get(p.name); v != "" {
+				n, err := strconv.ParseInt(v, 10, 64)
+				if err != nil || n < 0 {
+					writeError(w, http.StatusBadRequest, p.name+" must be a message seq")
+					return
+				}
+				*p.dst = n
+			}
+		}
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "limit must be a number")
+				return
+			}
+			page.Limit = n
+		}
+		msgs, err := b.Sessions.Messages(r.Context(), r.PathValue("id"), page)
+		if errors.Is(err, state.ErrSessionNotFound) {
+			writeError(w, http.StatusNotFound, "unknown session "+r.PathValue("id"))
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, nonNil(msgs))
+	})
+	mux.HandleFunc("DELETE /sessions/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Sessions != nil, "sessions") {
+			return
+		}
+		if err := b.Sessions.Delete(r.PathValue("id")); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /jobs", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Jobs != nil, "the scheduler") {
+			return
+		}
+		jobs := b.Jobs.ListJobs()
+		slices.SortFunc(jobs, func(a, b scheduler.Job) int { return strings.Compare(a.Name, b.Name) })
+		writeJSON(w, http.StatusOK, nonNil(jobs))
+	})
+	mux.HandleFunc("POST /jobs/{name}/run", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Jobs != nil, "the scheduler") {
+			return
+		}
+		start := time.Now()
+		if err := b.Jobs.RetryJob(r.Context(), r.PathValue("name")); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "succeeded", "duration": time.Since(start).Round(time.Millisecond).String()})
+	})
+	mux.HandleFunc("GET /plugins", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Plugins != nil, "plugins") {
+			return
+		}
+		names := b.Plugins.List()
+		slices.Sort(names)
+		writeJSON(w, http.StatusOK, nonNil(names))
+	})
+	mux.HandleFunc("POST /plugins/{name}/reload", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Plugins != nil, "plugins") {
+			return
+		}
+		if err := b.Plugins.Reload(r.Context(), r.PathValue("name")); err != nil {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "reloaded"})
+	})
+	mux.HandleFunc("GET /memory", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Memory != nil, "memory") {
+			return
+		}
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+		if q == "" {
+			writeError(w, http.StatusBadRequest, "q is required")
+			return
+		}
+		writeJSON(w, http.StatusOK, nonNil(b.Memory.Search(q)))
+	})
+	mux.HandleFunc("GET /usage", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Usage != nil, "usage") {
+			return
+		}
+		window := 24 * time.Hour
+		if v := r.URL.Query().Get("since"); v != "" {
+			d, err := ParseWindow(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			window = d
+		}
+		by := r.URL.Query().Get("by")
+		if by == "" {
+			by = "entity"
+		}
+		totals, err := b.Usage.UsageReport(r.Context(), time.Now().Add(-window), by)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, nonNil(totals))
+	})
+	mux.HandleFunc("GET /providers", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Providers != nil, "provider status") {
+			return
+		}
+		writeJSON(w, http.StatusOK, nonNil(b.Providers.EndpointStatus()))
+	})
+	mux.HandleFunc("GET /actors/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Actors != nil, "actor data") {
+			return
+		}
+		id := r.PathValue("id")
+		exp, err := b.Actors.Export(r.Context(), id)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := ActorExport{ActorExport: exp}
+		if b.Jobs != nil {
+			out.Jobs = actorJobs(b.Jobs.ListJobs(), id)
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("POST /actors/{id}/forget", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, b.Actors != nil, "actor data") {
+			return
+		}
+		id := r.PathValue("id")
+		dryRun, err := strconv.ParseBool(cmp.Or(r.URL.Query().Get("dry_run"), "false"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "dry_run must be true or false")
+			return
+		}
+		var out ActorForget
+		if b.Jobs != nil {
+			for _, j := range actorJobs(b.Jobs.ListJobs(), id) {
+				out.Jobs = append(out.Jobs, j.Name)
+			}
+		}
+		// The database goes first and in one transaction; jobs are only
+		// deleted once it committed, so a failed erasure changes nothing.
+		if out.ForgetReport, err = b.Actors.Forget(r.Context(), id, dryRun); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !dryRun {
+			for _, name := range out.Jobs {
+				if err := b.Jobs.DeleteJob(name); err != nil {
+					writeError(w, http.StatusInternalServerError, fmt.Sprintf("actor data deleted, but job %s was not: %v", name, err))
+					return
+				}
+			}
+			slog.Info("audit", "event", "actor_forgotten", "actor", id, "sessions", out.Sessions,
+				"messages", out.Messages, "memories", out.Memories, "usage", out.Usage, "jobs", len(out.Jobs))
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	return mux
+}
+
+// actorJobs returns the dynamic jobs of jobs that actorID created or that
+// run for it. Jobs record the creator's sender id without its channel when
+// there are no profiles, so "slack:U1" also owns jobs created by "U1".
+func actorJobs(jobs []scheduler.Job, actorID string) []scheduler.Job {
+	_, sender, _ := strings.Cut(actorID, ":")
+	var out []scheduler.Job
+	for _, j := range jobs {
+		if j.Source == "config" {
+			continue
+		}
+		if j.EntityID == actorID || j.CreatedBy == actorID || (sender != "" && j.CreatedBy == sender) {
+			out = append(out, j)
+		}
+	}
+	return out
+}
+
+// ParseWindow parses a look-back window: a Go duration or a number of days
+// such as "7d".
+func ParseWindow(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid window %q: use a duration like 12h or a number of days like 7d", s)
+	}
+	return d, nil
+}
+
+// Server serves the API on a Unix socket.
+type Server struct {
+	path string
+	srv  *http.Server
+}
+
+// Listen creates the socket at path, readable and writable by the owner
+// only, and starts serving. A socket left by a previous process is
+// replaced; one that still answers is not, since another instance owns it.
+//
+// The socket is bound inside a fresh 0700 directory, restricted, and only
+// then renamed into place, so no other user can connect while its mode
+// still follows the umask.
+func Listen(path string, b Backends) (*Server, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating ctl socket dir: %w", err)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("ctl socket %s is in use by another instance", path)
+	}
+	private, err := os.MkdirTemp(dir, ".ctl")
+	if err != nil {
+		return nil, fmt.Errorf("creating ctl socket dir: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(private) }()
+	tmp := filepath.Join(private, "s")
+	ln, err := net.Listen("unix", tmp)
+	if err != nil {
+		return nil, fmt.Errorf("listening on ctl socket: %w", err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	if err := os.Chmod(tmp, 0600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("restricting ctl socket: %w", err)
+	}
+	_ = os.Remove(path)
+	if err := os.Rename(tmp, path); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("placing ctl socket: %w", err)
+	}
+	s := &Server{path: path, srv: &http.Server{Handler: NewHandler(b), ReadHeaderTimeout: 5 * time.Second}}
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("ctl socket error", "component", "ctl", "error", err)
+		}
+	}()
+	slog.Info("ctl socket listening", "component", "ctl", "path", path)
+	return s, nil
+}
+
+// Close stops serving and removes the socket.
+func (s *Server) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = s.srv.Shutdown(ctx)
+	_ = os.Remove(s.path)
+}
+
+func available(w http.ResponseWriter, ok bool, what string) bool {
+	if !ok {
+		writeError(w, http.StatusNotImplemented, what+" not available on this instance")
+	}
+	return ok
+}
+
+// nonNil keeps an empty list from encoding as null.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
@@ -0,0 +1,321 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scheduler"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+type fakeSessions struct {
+	deleted []string
+	page    store.MessagePage
+}
+
+func (f *fakeSessions) ListSummaries(_ context.Context, limit int) ([]store.SessionSummary, error) {
+	all := []store.SessionSummary{{ID: "s2", Messages: 3}, {ID: "s1"}}
+	if limit > 0 && limit < len(all) {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+func (f *fakeSessions) Get(id string) (*state.Session, error) {
+	if id != "s1" {
+		return nil, fmt.Errorf("session %q: %w", id, state.ErrSessionNotFound)
+	}
+	return &state.Session{ID: "s1", Messages: []provider.Message{{Role: provider.RoleUser, Content: "hi"}}}, nil
+}
+
+// Messages pages over five messages of s1, recording the page asked for.
+func (f *fakeSessions) Messages(_ context.Context, id string, page store.MessagePage) ([]store.StoredMessage, error) {
+	if id != "s1" {
+		return nil, state.ErrSessionNotFound
+	}
+	f.page = page
+	var out []store.StoredMessage
+	for seq := page.AfterSeq + 1; seq <= 5 && len(out) < page.Limit; seq++ {
+		out = append(out, store.StoredMessage{Seq: seq, Role: provider.RoleUser, Content: fmt.Sprint("m", seq)})
+	}
+	return out, nil
+}
+
+func (f *fakeSessions) Delete(id string) error {
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+type fakeJobs struct {
+	ran, deleted []string
+	extra        []scheduler.Job
+}
+
+func (f *fakeJobs) ListJobs() []scheduler.Job {
+	return append([]scheduler.Job{{Name: "b", Interval: "1h"}, {Name: "a", Cron: "0 9 * * *"}}, f.extra...)
+}
+
+func (f *fakeJobs) DeleteJob(name string) error {
+	f.deleted = append(f.deleted, name)
+	return nil
+}
+
+func (f *fakeJobs) RetryJob(_ context.Context, name string) error {
+	if name != "a" {
+		return fmt.Errorf("job %q not found", name)
+	}
+	f.ran = append(f.ran, name)
+	return nil
+}
+
+type fakePlugins struct{ reloaded []string }
+
+func (f *fakePlugins) List() []string { return []string{"jira", "gitlab"} }
+
+func (f *fakePlugins) Reload(_ context.Context, name string) error {
+	f.reloaded = append(f.reloaded, name)
+	return nil
+}
+
+type fakeMemory struct{}
+
+func (fakeMemory) Search(q string) []*state.Memory {
+	return []*state.Memory{{ID: "m1", Content: "remember " + q}}
+}
+
+type fakeUsage struct {
+	since time.Time
+	by    string
+}
+
+func (f *fakeUsage) UsageReport(_ context.Context, since time.Time, by string) ([]store.UsageTotal, error) {
+	f.since, f.by = since, by
+	return []store.UsageTotal{{Key: "alice", Runs: 2, InputTokens: 10}}, nil
+}
+
+type fakeActors struct {
+	forgot []string
+	err    error
+}
+
+func (f *fakeActors) Export(_ context.Context, actorID string) (*store.ActorExport, error) {
+	return &store.ActorExport{ActorID: actorID, Memories: []*state.Memory{{ID: "mem_1", ActorID: actorID, Content: "likes tea"}}}, nil
+}
+
+func (f *fakeActors) Forget(_ context.Context, actorID string, dryRun bool) (store.ForgetReport, error) {
+	if f.err != nil {
+		return store.ForgetReport{}, f.err
+	}
+	if !dryRun {
+		f.forgot = append(f.forgot, actorID)
+	}
+	return store.ForgetReport{DryRun: dryRun, Sessions: 2, Memories: 1}, nil
+}
+
+// serve starts the API on a socket in a short temp dir (socket paths are
+// limited to about 100 bytes) and returns a client for it.
+func serve(t *testing.T, b Backends) *Client {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	path := filepath.Join(dir, "ctl.sock")
+	srv, err := Listen(path, b)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	t.Cleanup(srv.Close)
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("socket mode = %v; want 0600", fi.Mode().Perm())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("socket dir holds %d entries; want only the socket", len(entries))
+	}
+	return NewClient(path)
+}
+
+func TestAPI(t *testing.T) {
+	sessions, jobs, plugins, usage := &fakeSessions{}, &fakeJobs{}, &fakePlugins{}, &fakeUsage{}
+	c := serve(t, Backends{Sessions: sessions, Jobs: jobs, Plugins: plugins, Memory: fakeMemory{}, Usage: usage})
+	ctx := context.Background()
+
+	var list []store.SessionSummary
+	if err := c.Do(ctx, http.MethodGet, "/sessions", url.Values{"limit": {"1"}}, &list); err != nil || len(list) != 1 || list[0].ID != "s2" {
+		t.Errorf("sessions list = %+v, %v", list, err)
+	}
+	var sess state.Session
+	if err := c.Do(ctx, http.MethodGet, "/sessions/s1", nil, &sess); err != nil || len(sess.Messages) != 1 {
+		t.Errorf("sessions show = %+v, %v", sess, err)
+	}
+	if err := c.Do(ctx, http.MethodGet, "/sessions/nope", nil, &sess); err == nil || err.Error() != "unknown session nope" {
+		t.Errorf("unknown session error = %v", err)
+	}
+	var msgs []store.StoredMessage
+	if err := c.Do(ctx, http.MethodGet, "/sessions/s1/messages", url.Values{"after": {"2"}, "limit": {"2"}}, &msgs); err != nil ||
+		len(msgs) != 2 || msgs[0].Seq != 3 || msgs[1].Content != "m4" || sessions.page != (store.MessagePage{AfterSeq: 2, Limit: 2}) {
+		t.Errorf("sessions messages = %+v, %v (page %+v)", msgs, err, sessions.page)
+	}
+	if err := c.Do(ctx, http.MethodGet, "/sessions/s1/messages", url.Values{"before": {"x"}}, &msgs); err == nil {
+		t.Error("a bad before seq should fail")
+	}
+	if err := c.Do(ctx, http.MethodGet, "/sessions/nope/messages", nil, &msgs); err == nil || err.Error() != "unknown session nope" {
+		t.Errorf("unknown session messages error = %v", err)
+	}
+	if err := c.Do(ctx, http.MethodDelete, "/sessions/s1", nil, nil); err != nil || len(sessions.deleted) != 1 {
+		t.Errorf("sessions delete: %v, deleted %v", err, sessions.deleted)
+	}
+
+	var js []scheduler.Job
+	if err := c.Do(ctx, http.MethodGet, "/jobs", nil, &js); err != nil || len(js) != 2 || js[0].Name != "a" {
+		t.Errorf("jobs list = %+v, %v", js, err)
+	}
+	if err := c.Do(ctx, http.MethodPost, "/jobs/a/run", nil, nil); err != nil || len(jobs.ran) != 1 {
+		t.Errorf("jobs run-now: %v, ran %v", err, jobs.ran)
+	}
+	if err := c.Do(ctx, http.MethodPost, "/jobs/zzz/run", nil, nil); err == nil {
+		t.Error("running an unknown job should fail")
+	}
+
+	var names []string
+	if err := c.Do(ctx, http.MethodGet, "/plugins", nil, &names); err != nil || len(names) != 2 || names[0] != "gitlab" {
+		t.Errorf("plugins list = %v, %v", names, err)
+	}
+	if err := c.Do(ctx, http.MethodPost, "/plugins/jira/reload", nil, nil); err != nil || len(plugins.reloaded) != 1 {
+		t.Errorf("plugins reload: %v, reloaded %v", err, plugins.reloaded)
+	}
+
+	var mems []state.Memory
+	if err := c.Do(ctx, http.MethodGet, "/memory", url.Values{"q": {"deploys"}}, &mems); err != nil || len(mems) != 1 || mems[0].Content != "remember deploys" {
+		t.Errorf("memory search = %+v, %v", mems, err)
+	}
+
+	var totals []store.UsageTotal
+	if err := c.Do(ctx, http.MethodGet, "/usage", url.Values{"since": {"7d"}, "by": {"model"}}, &totals); err != nil || len(totals) != 1 {
+		t.Errorf("usage = %+v, %v", totals, err)
+	}
+	if usage.by != "model" || time.Since(usage.since) < 7*24*time.Hour-time.Minute {
+		t.Errorf("usage query by %q since %v", usage.by, usage.since)
+	}
+}
+
+type fakeProviders []provider.EndpointStatus
+
+func (f fakeProviders) EndpointStatus() []provider.EndpointStatus { return f }
+
+func TestAPI_Providers(t *testing.T) {
+	c := serve(t, Backends{Providers: fakeProviders{
+		{Provider: "openai", Model: "gpt-4o", Role: provider.RolePrimary, Requests: 2, Failures: 2, LastError: "429"},
+		{Provider: "azure", Model: "gpt-4o", Role: provider.RoleFallback, Healthy: true, Active: true, SuccessRate: 1},
+	}})
+	var st []provider.EndpointStatus
+	if err := c.Do(context.Background(), http.MethodGet, "/providers", nil, &st); err != nil || len(st) != 2 {
+		t.Fatalf("providers = %+v, %v", st, err)
+	}
+	if st[0].Healthy || st[0].LastError != "429" || !st[1].Active {
+		t.Errorf("providers = %+v", st)
+	}
+}
+
+func TestAPI_Actors(t *testing.T) {
+	jobs := &fakeJobs{extra: []scheduler.Job{
+		{Name: "mine", Source: "dynamic", CreatedBy: "U1"},
+		{Name: "for-me", Source: "dynamic", EntityID: "slack:U1"},
+		{Name: "theirs", Source: "dynamic", CreatedBy: "U2"},
+	}}
+	actors := &fakeActors{}
+	c := serve(t, Backends{Jobs: jobs, Actors: actors})
+	ctx := context.Background()
+
+	var exp ActorExport
+	if err := c.Do(ctx, http.MethodGet, "/actors/slack:U1/export", nil, &exp); err != nil ||
+		exp.ActorExport == nil || exp.ActorID != "slack:U1" || len(exp.Memories) != 1 || len(exp.Jobs) != 2 {
+		t.Fatalf("export = %+v, %v", exp, err)
+	}
+
+	var dry ActorForget
+	if err := c.Do(ctx, http.MethodPost, "/actors/slack:U1/forget", url.Values{"dry_run": {"true"}}, &dry); err != nil ||
+		!dry.DryRun || dry.Sessions != 2 || len(dry.Jobs) != 2 || len(jobs.deleted)+len(actors.forgot) != 0 {
+		t.Fatalf("dry run = %+v, %v (deleted %v, forgot %v)", dry, err, jobs.deleted, actors.forgot)
+	}
+	var done ActorForget
+	if err := c.Do(ctx, http.MethodPost, "/actors/slack:U1/forget", nil, &done); err != nil ||
+		done.DryRun || strings.Join(jobs.deleted, ",") != "mine,for-me" || len(actors.forgot) != 1 {
+		t.Errorf("forget = %+v, %v (deleted %v, forgot %v)", done, err, jobs.deleted, actors.forgot)
+	}
+	if err := c.Do(ctx, http.MethodPost, "/actors/slack:U1/forget", url.Values{"dry_run": {"maybe"}}, nil); err == nil {
+		t.Error("a bad dry_run should fail")
+	}
+}
+
+func TestAPI_ActorForgetFailureKeepsJobs(t *testing.T) {
+	jobs := &fakeJobs{extra: []scheduler.Job{{Name: "mine", Source: "dynamic", CreatedBy: "U1"}}}
+	c := serve(t, Backends{Jobs: jobs, Actors: &fakeActors{err: errors.New("database is locked")}})
+	if err := c.Do(context.Background(), http.MethodPost, "/actors/slack:U1/forget", nil, nil); err == nil {
+		t.Fatal("a failed erasure should fail the request")
+	}
+	if len(jobs.deleted) != 0 {
+		t.Errorf("jobs deleted although the erasure failed: %v", jobs.deleted)
+	}
+}
+
+func TestAPI_MissingBackend(t *testing.T) {
+	c := serve(t, Backends{})
+	err := c.Do(context.Background(), http.MethodGet, "/usage", nil, nil)
+	if err == nil || err.Error() != "usage not available on this instance" {
+		t.Errorf("error = %v", err)
+	}
+}
+
+func TestListen_SocketInUse(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "ctl.sock")
+	srv, err := Listen(path, Backends{})
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer srv.Close()
+	if _, err := Listen(path, Backends{}); err == nil {
+		t.Error("a second instance must not take over a live socket")
+	}
+}
+
+func TestClient_NoInstance(t *testing.T) {
+	c := NewClient(filepath.Join(t.TempDir(), "missing.sock"))
+	err := c.Do(context.Background(), http.MethodGet, "/jobs", nil, nil)
+	if err == nil {
+		t.Fatal("expected an error without a running instance")
+	}
+}
+
+func TestParseWindow(t *testing.T) {
+	for in, want := range map[string]time.Duration{"7d": 7 * 24 * time.Hour, "12h": 12 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := ParseWindow(in); err != nil || got != want {
+			t.Errorf("ParseWindow(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "0d", "-1h", "week"} {
+		if _, err := ParseWindow(bad); err == nil {
+			t.Errorf("ParseWindow(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+// Package daemon reports OpenTalon's lifecycle to the service manager that
+// runs it: systemd readiness and watchdog notifications, a PID file, a
+// health file for file-based probes, and the Windows service control
+// manager. It also defines the process exit codes.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Exit codes. They follow sysexits.h so service managers can tell a broken
+// config (do not restart) from a dependency that may come back (restart),
+// e.g. systemd's RestartPreventExitStatus=64 78.
+const (
+	ExitOK             = 0  // clean shutdown
+	ExitFailure        = 1  // unexpected runtime error
+	ExitUsage          = 64 // bad command line (EX_USAGE)
+	ExitUnavailable    = 69 // a required service (Redis, bootstrap server) is unreachable (EX_UNAVAILABLE)
+	ExitAlreadyRunning = 75 // the PID file names a live process (EX_TEMPFAIL)
+	ExitConfig         = 78 // invalid configuration (EX_CONFIG)
+)
+
+// Options configures Start. Empty paths disable the PID and health files.
+type Options struct {
+	PIDFile        string
+	HealthFile     string
+	HealthInterval time.Duration // how often the health file is rewritten; 0 = 10s
+	ServiceName    string        // Windows service name; empty = "opentalon"
+}
+
+const (
+	defaultHealthInterval = 10 * time.Second
+	defaultServiceName    = "opentalon"
+)
+
+// Supervisor carries the process through starting, ready and stopping. A
+// nil *Supervisor is valid and does nothing, so callers need not check
+// whether daemon mode is on.
+type Supervisor struct {
+	pidFile string
+	health  *healthFile
+
+	ready     chan struct{} // closed by Ready
+	stop      chan struct{} // closed when the service manager asks us to stop
+	done      chan struct{} // closed by Close
+	readyOnce sync.Once
+	stopOnce  sync.Once
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// Start enters daemon mode: it writes the PID file, starts the health file
+// and the systemd watchdog, and registers with the Windows service control
+// manager when started by it. A PID file naming another live process fails
+// with ErrAlreadyRunning.
+func Start(opts Options) (*Supervisor, error) {
+	s := &Supervisor{
+		ready: make(chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if opts.PIDFile != "" {
+		if err := writePIDFile(opts.PIDFile); err != nil {
+			return nil, err
+		}
+		s.pidFile = opts.PIDFile
+	}
+	if opts.HealthFile != "" {
+		interval := opts.HealthInterval
+		if interval <= 0 {
+			interval = defaultHealthInterval
+		}
+		s.health = newHealthFile(opts.HealthFile)
+		if err := s.health.set(StatusStarting); err != nil {
+			s.removePIDFile()
+			return nil, fmt.Errorf("writing health file: %w", err)
+		}
+		s.goRefresh(interval)
+	}
+	if interval, ok := watchdogInterval(); ok {
+		s.goWatchdog(interval)
+	}
+	name := opts.ServiceName
+	if name == "" {
+		name = defaultServiceName
+	}
+	if err := s.runService(name); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Ready reports that startup finished: systemd gets READY=1, the health
+// file says "ready" and a Windows service enters the running state.
+// Later calls do nothing.
+func (s *Supervisor) Ready() {
+	if s == nil {
+		return
+	}
+	s.readyOnce.Do(func() {
+		close(s.ready)
+		s.setHealth(StatusReady)
+		if err := notify("READY=1\nSTATUS=ready"); err != nil {
+			slog.Warn("sd_notify READY failed", "error", err)
+		}
+	})
+}
+
+// Stopping reports that graceful shutdown has begun.
+func (s *Supervisor) Stopping() {
+	if s == nil {
+		return
+	}
+	s.requestStop()
+	s.setHealth(StatusStopping)
+	if err := notify("STOPPING=1\nSTATUS=stopping"); err != nil {
+		slog.Warn("sd_notify STOPPING failed", "error", err)
+	}
+}
+
+// StopRequested is closed when the service manager asks the process to
+// stop without a signal (the Windows service control manager). It is nil,
+// and blocks forever, for a nil Supervisor.
+func (s *Supervisor) StopRequested() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	return s.stop
+}
+
+// Close stops the background writers and removes the PID and health
+// files. Call it last, once shutdown is complete.
+func (s *Supervisor) Close() {
+	if s == nil {
+		return
+	}
+	s.closeOnce.Do(func() {
+		close(s.done)
+		s.wg.Wait()
+		if s.health != nil {
+			s.health.remove()
+		}
+		s.removePIDFile()
+	})
+}
+
+func (s *Supervisor) requestStop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+func (s *Supervisor) setHealth(status string) {
+	if s.health == nil {
+		return
+	}
+	if err := s.health.set(status); err != nil {
+		slog.Warn("writing health file failed", "error", err)
+	}
+}
+
+func (s *Supervisor) removePIDFile() {
+	if s.pidFile == "" {
+		return
+	}
+	if err := removePIDFile(s.pidFile); err != nil && !errors.Is(err, errPIDFileNotOurs) {
+		slog.Warn("removing PID file failed", "path", s.pidFile, "error", err)
+	}
+}
+
+// goRefresh rewrites the health file every interval so a probe can treat
+// a stale file as a hung process.
+func (s *Supervisor) goRefresh(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-t.C:
+				if err := s.health.touch(); err != nil {
+					slog.Warn("refreshing health file failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// goWatchdog pings the systemd watchdog at half its timeout.
+func (s *Supervisor) goWatchdog(interval time.Duration) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		t := time.NewTicker(interval / 2)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.done:
+				return
+			case <-t.C:
+				if err := notify("WATCHDOG=1"); err != nil {
+					slog.Warn("sd_notify WATCHDOG failed", "error", err)
+				}
+			}
+		}
+	}()
+}
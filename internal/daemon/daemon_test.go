@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenNotify stands in for systemd's notify socket and returns the
+// messages it receives.
+func listenNotify(t *testing.T) <-chan string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	t.Setenv("NOTIFY_SOCKET", path)
+	out := make(chan string, 16)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			out <- string(buf[:n])
+		}
+	}()
+	return out
+}
+
+func recv(t *testing.T, ch <-chan string) string {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("no sd_notify message")
+		return ""
+	}
+}
+
+func readHealth(t *testing.T, path string) healthState {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var st healthState
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("health file %q: %v", data, err)
+	}
+	return st
+}
+
+func TestSupervisor_Lifecycle(t *testing.T) {
+	msgs := listenNotify(t)
+	dir := t.TempDir()
+	pidPath := filepath.Join(dir, "run", "opentalon.pid")
+	healthPath := filepath.Join(dir, "health.json")
+
+	s, err := Start(Options{PIDFile: pidPath, HealthFile: healthPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, err := readPIDFile(pidPath); err != nil || pid != os.Getpid() {
+		t.Fatalf("PID file = %d, %v", pid, err)
+	}
+	if st := readHealth(t, healthPath); st.Status != StatusStarting || st.PID != os.Getpid() {
+		t.Errorf("health before ready = %+v", st)
+	}
+
+	s.Ready()
+	s.Ready()
+	if got := recv(t, msgs); !strings.Contains(got, "READY=1") {
+		t.Errorf("notify = %q; want READY=1", got)
+	}
+	if st := readHealth(t, healthPath); st.Status != StatusReady {
+		t.Errorf("health after ready = %+v", st)
+	}
+
+	s.Stopping()
+	if got := recv(t, msgs); !strings.Contains(got, "STOPPING=1") {
+		t.Errorf("notify = %q; want STOPPING=1", got)
+	}
+	if st := readHealth(t, healthPath); st.Status != StatusStopping {
+		t.Errorf("health after stopping = %+v", st)
+	}
+	select {
+	case <-s.StopRequested():
+	default:
+		t.Error("StopRequested should be closed once stopping")
+	}
+	select {
+	case extra := <-msgs:
+		t.Errorf("Ready twice should notify once, got extra %q", extra)
+	default:
+	}
+
+	s.Close()
+	for _, p := range []string{pidPath, healthPath} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s should be removed on Close (%v)", p, err)
+		}
+	}
+}
+
+func TestSupervisor_HealthFileRefreshed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "health.json")
+	s, err := Start(Options{HealthFile: path, HealthInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	first := readHealth(t, path).UpdatedAt
+	deadline := time.Now().Add(2 * time.Second)
+	for readHealth(t, path).UpdatedAt.Equal(first) {
+		if time.Now().After(deadline) {
+			t.Fatal("health file was not refreshed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStart_PIDFileOfLiveProcess(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opentalon.pid")
+	// The test's parent process is certainly alive.
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Start(Options{PIDFile: path}); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Start = %v; want ErrAlreadyRunning", err)
+	}
+	if pid, _ := readPIDFile(path); pid != os.Getppid() {
+		t.Error("the other instance's PID file must be left alone")
+	}
+}
+
+func TestStart_ReplacesStalePIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "opentalon.pid")
+	if err := os.WriteFile(path, []byte("not a pid\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Start(Options{PIDFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid, _ := readPIDFile(path); pid != os.Getpid() {
+		t.Errorf("PID file = %d; want ours", pid)
+	}
+	// Another instance took over the file: Close must not remove it.
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("a PID file that is not ours should survive Close: %v", err)
+	}
+}
+
+func TestNilSupervisor(t *testing.T) {
+	var s *Supervisor
+	s.Ready()
+	s.Stopping()
+	s.Close()
+	if s.StopRequested() != nil {
+		t.Error("a nil supervisor never requests a stop")
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	t.Setenv("WATCHDOG_USEC", "30000000")
+	t.Setenv("WATCHDOG_PID", "")
+	if d, ok := watchdogInterval(); !ok || d != 30*time.Second {
+		t.Errorf("watchdogInterval = %v, %v; want 30s", d, ok)
+	}
+	t.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()+1))
+	if _, ok := watchdogInterval(); ok {
+		t.Error("a watchdog meant for another PID should be ignored")
+	}
+	t.Setenv("WATCHDOG_PID", "")
+	t.Setenv("WATCHDOG_USEC", "")
+	if _, ok := watchdogInterval(); ok {
+		t.Error("no WATCHDOG_USEC means no watchdog")
+	}
+}
+
+func TestNotify_NoSocket(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := notify("READY=1"); err != nil {
+		t.Errorf("notify without a socket = %v; want nil", err)
+	}
+}
@@ -0,0 +1,139 @@
+package daemon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrAlreadyRunning is returned by Start when the PID file names another
+// live process.
+var ErrAlreadyRunning = errors.New("another instance is already running")
+
+var errPIDFileNotOurs = errors.New("PID file belongs to another process")
+
+// writePIDFile writes this process's PID to path. A file left by a process
+// that is gone is replaced.
+func writePIDFile(path string) error {
+	if pid, err := readPIDFile(path); err == nil && pid != os.Getpid() && processAlive(pid) {
+		return fmt.Errorf("%w (PID %d in %s)", ErrAlreadyRunning, pid, path)
+	}
+	if err := writeFileAtomic(path, []byte(strconv.Itoa(os.Getpid())+"\n")); err != nil {
+		return fmt.Errorf("writing PID file: %w", err)
+	}
+	return nil
+}
+
+// removePIDFile removes path if it still holds this process's PID, so a
+// newer instance's file is left alone.
+func removePIDFile(path string) error {
+	pid, err := readPIDFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if pid != os.Getpid() {
+		return errPIDFileNotOurs
+	}
+	return os.Remove(path)
+}
+
+func readPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("PID file %s: invalid content", path)
+	}
+	return pid, nil
+}
+
+// Health file statuses.
+const (
+	StatusStarting = "starting"
+	StatusReady    = "ready"
+	StatusStopping = "stopping"
+)
+
+// healthState is the JSON content of the health file.
+type healthState struct {
+	Status    string    `json:"status"`
+	PID       int       `json:"pid"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// healthFile keeps the health file current. Each write replaces the file
+// atomically, so a probe never reads a partial one.
+type healthFile struct {
+	path  string
+	mu    sync.Mutex
+	state healthState
+}
+
+func newHealthFile(path string) *healthFile {
+	return &healthFile{path: path, state: healthState{PID: os.Getpid(), StartedAt: time.Now().UTC()}}
+}
+
+func (h *healthFile) set(status string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.state.Status = status
+	return h.writeLocked()
+}
+
+// touch rewrites the file with a fresh updated_at.
+func (h *healthFile) touch() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.writeLocked()
+}
+
+func (h *healthFile) writeLocked() error {
+	h.state.UpdatedAt = time.Now().UTC()
+	data, err := json.Marshal(h.state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(h.path, append(data, '\n'))
+}
+
+func (h *healthFile) remove() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_ = os.Remove(h.path)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
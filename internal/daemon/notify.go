@@ -0,0 +1,43 @@
+package daemon
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// notify sends state to systemd over $NOTIFY_SOCKET (sd_notify(3)). It does
+// nothing when the variable is unset, i.e. when not run by systemd with
+// Type=notify.
+func notify(state string) error {
+	sock := os.Getenv("NOTIFY_SOCKET")
+	if sock == "" {
+		return nil
+	}
+	// A leading '@' names a socket in the abstract namespace.
+	if sock[0] == '@' {
+		sock = "\x00" + sock[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: sock, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// watchdogInterval returns systemd's WatchdogSec for this process, taken
+// from $WATCHDOG_USEC. ok is false when the watchdog is off or meant for
+// another process ($WATCHDOG_PID).
+func watchdogInterval() (time.Duration, bool) {
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0, false
+	}
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, false
+	}
+	return time.Duration(usec) * time.Microsecond, true
+}
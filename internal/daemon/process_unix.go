@@ -0,0 +1,20 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive reports whether pid names a running process. EPERM means it
+// exists but belongs to another user.
+func processAlive(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// runService does nothing outside Windows; systemd is reached via notify.
+func (s *Supervisor) runService(string) error {
+	return nil
+}
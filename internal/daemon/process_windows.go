@@ -0,0 +1,80 @@
+//go:build windows
+
+package daemon
+
+import (
+	"fmt"
+	"log/slog"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+)
+
+// stillActive is the exit code GetExitCodeProcess reports for a running
+// process (STILL_ACTIVE).
+const stillActive = 259
+
+// processAlive reports whether pid names a running process.
+func processAlive(pid int) bool {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		// Access denied means the process exists but is not ours.
+		return err == windows.ERROR_ACCESS_DENIED
+	}
+	defer func() { _ = windows.CloseHandle(h) }()
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
+
+// runService registers with the service control manager when the process
+// was started by it. Stop and shutdown requests close StopRequested; the
+// service reports stopped once Close is called.
+func (s *Supervisor) runService(name string) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return fmt.Errorf("detecting Windows service: %w", err)
+	}
+	if !isService {
+		return nil
+	}
+	go func() {
+		if err := svc.Run(name, &serviceHandler{s: s}); err != nil {
+			slog.Error("windows service failed", "service", name, "error", err)
+			s.requestStop()
+		}
+	}()
+	return nil
+}
+
+type serviceHandler struct {
+	s *Supervisor
+}
+
+func (h *serviceHandler) Execute(_ []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	const accepts = svc.AcceptStop | svc.AcceptShutdown
+	changes <- svc.Status{State: svc.StartPending}
+	ready := h.s.ready
+	for {
+		select {
+		case <-ready:
+			changes <- svc.Status{State: svc.Running, Accepts: accepts}
+			ready = nil
+		case <-h.s.stop:
+			changes <- svc.Status{State: svc.StopPending}
+			<-h.s.done
+			return false, 0
+		case <-h.s.done:
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				h.s.requestStop()
+			}
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package dashboard
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/eventbus"
+)
+
+// DefaultActivitySize is how many job runs and failovers Activity keeps
+// when NewActivity gets size <= 0.
+const DefaultActivitySize = 200
+
+// JobRun is one finished scheduler job run.
+type JobRun struct {
+	Job    string    `json:"job"`
+	Action string    `json:"action"`
+	Status string    `json:"status"` // "ok" or "error"
+	Error  string    `json:"error,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Failover is one switch of the LLM provider to another endpoint.
+type Failover struct {
+	From      string    `json:"from"` // "provider/model"
+	To        string    `json:"to"`
+	Error     string    `json:"error,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Activity remembers the most recent job runs and provider failovers seen
+// on the event bus since the process started. Nothing is persisted; it
+// backs the run history and failover panels.
+type Activity struct {
+	size int
+
+	mu        sync.Mutex
+	runs      []JobRun
+	failovers []Failover
+}
+
+// NewActivity returns an Activity keeping the last size entries of each kind.
+func NewActivity(size int) *Activity {
+	if size <= 0 {
+		size = DefaultActivitySize
+	}
+	return &Activity{size: size}
+}
+
+// Attach subscribes a to job_run and provider_failover events on bus.
+func (a *Activity) Attach(bus *eventbus.Bus) {
+	if bus == nil {
+		return
+	}
+	bus.Subscribe(eventbus.JobRun, "dashboard", func(_ context.Context, e eventbus.Event) error {
+		a.addRun(JobRun{Job: e.Data["job"], Action: e.Data["action"], Status: e.Data["status"], Error: e.Data["error"], Time: e.Time})
+		return nil
+	})
+	bus.Subscribe(eventbus.ProviderFailover, "dashboard", func(_ context.Context, e eventbus.Event) error {
+		a.addFailover(Failover{From: e.Data["from"], To: e.Data["to"], Error: e.Data["error"], SessionID: e.SessionID, Time: e.Time})
+		return nil
+	})
+}
+
+func (a *Activity) addRun(r JobRun) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.runs = appendBounded(a.runs, r, a.size)
+}
+
+func (a *Activity) addFailover(f Failover) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failovers = appendBounded(a.failovers, f, a.size)
+}
+
+// JobRuns returns the remembered runs of job (all jobs when empty), newest
+// first.
+func (a *Activity) JobRuns(job string) []JobRun {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]JobRun, 0, len(a.runs))
+	for i := len(a.runs) - 1; i >= 0; i-- {
+		if job == "" || a.runs[i].Job == job {
+			out = append(out, a.runs[i])
+		}
+	}
+	return out
+}
+
+// Failovers returns the remembered failovers, newest first.
+func (a *Activity) Failovers() []Failover {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	out := make([]Failover, 0, len(a.failovers))
+	for i := len(a.failovers) - 1; i >= 0; i-- {
+		out = append(out, a.failovers[i])
+	}
+	return out
+}
+
+// appendBounded appends v, dropping the oldest entries beyond size.
+func appendBounded[T any](s []T, v T, size int) []T {
+	s = append(s, v)
+	if len(s) > size {
+		s = append(s[:0], s[len(s)-size:]...)
+	}
+	return s
+}
@@ -0,0 +1,141 @@
+// Package dashboard is the embedded web admin console: live sessions and
+// their transcripts, scheduler jobs with recent runs, plugin health,
+// provider failovers and usage charts. The page itself is static and
+// public; everything it shows comes from a JSON API behind a bearer token,
+// which the page asks for once and keeps in the browser's local storage.
+//
+// The API is the ctl admin API (package ctl) mounted under /api, plus a
+// few read-only routes only the dashboard needs.
+package dashboard
+
+import (
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/plugin"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+//go:embed static
+var static embed.FS
+
+// PluginHealth reports the health of every configured plugin.
+// *plugin.Manager satisfies it.
+type PluginHealth interface {
+	Status() []plugin.PluginStatus
+}
+
+// UsageSeries reports token usage over time. *store.UsageStore satisfies it.
+type UsageSeries interface {
+	UsageSeries(ctx context.Context, since time.Time, bucket string) ([]store.UsagePoint, error)
+}
+
+// Sources are what the dashboard shows. Like ctl.Backends, a nil source
+// answers its routes with 501, which the page shows in that panel.
+type Sources struct {
+	Admin    ctl.Backends
+	Plugins  PluginHealth
+	Usage    UsageSeries
+	Activity *Activity
+}
+
+// NewHandler returns the dashboard: the page at / and, behind token,
+//
+//	/api/...                        the ctl admin API
+//	GET /api/plugin-health
+//	GET /api/job-runs?job=name      recent runs, newest first
+//	GET /api/failovers              recent provider failovers, newest first
+//	GET /api/usage-series?since=7d&bucket=day
+func NewHandler(token string, s Sources) http.Handler {
+	api := http.NewServeMux()
+	api.Handle("/", ctl.NewHandler(s.Admin))
+	api.HandleFunc("GET /plugin-health", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, s.Plugins != nil, "plugin health") {
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Plugins.Status())
+	})
+	api.HandleFunc("GET /job-runs", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, s.Activity != nil, "job runs") {
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Activity.JobRuns(r.URL.Query().Get("job")))
+	})
+	api.HandleFunc("GET /failovers", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, s.Activity != nil, "failovers") {
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Activity.Failovers())
+	})
+	api.HandleFunc("GET /usage-series", func(w http.ResponseWriter, r *http.Request) {
+		if !available(w, s.Usage != nil, "usage") {
+			return
+		}
+		window := 7 * 24 * time.Hour
+		if v := r.URL.Query().Get("since"); v != "" {
+			d, err := ctl.ParseWindow(v)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			window = d
+		}
+		bucket := r.URL.Query().Get("bucket")
+		if bucket == "" {
+			bucket = "day"
+			if window <= 48*time.Hour {
+				bucket = "hour"
+			}
+		}
+		points, err := s.Usage.UsageSeries(r.Context(), time.Now().Add(-window), bucket)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if points == nil {
+			points = []store.UsagePoint{}
+		}
+		writeJSON(w, http.StatusOK, points)
+	})
+
+	page, _ := fs.Sub(static, "static")
+	mux := http.NewServeMux()
+	mux.Handle("/api/", http.StripPrefix("/api", requireToken(token, api)))
+	mux.Handle("/", http.FileServerFS(page))
+	return mux
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func available(w http.ResponseWriter, ok bool, what string) bool {
+	if !ok {
+		writeError(w, http.StatusNotImplemented, what+" not available on this instance")
+	}
+	return ok
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
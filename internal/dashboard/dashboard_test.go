@@ -0,0 +1,176 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/ctl"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/plugin"
+	"github.com/opentalon/opentalon/internal/scheduler"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+type fakePlugins struct{}
+
+func (fakePlugins) Status() []plugin.PluginStatus {
+	return []plugin.PluginStatus{{Name: "jira", Mode: "binary", Loaded: true}, {Name: "gitlab", Mode: "grpc", Error: "connection refused"}}
+}
+
+type fakeUsage struct {
+	since  time.Time
+	bucket string
+}
+
+func (f *fakeUsage) UsageSeries(_ context.Context, since time.Time, bucket string) ([]store.UsagePoint, error) {
+	f.since, f.bucket = since, bucket
+	if bucket == "week" {
+		return nil, fmt.Errorf("cannot bucket by %q", bucket)
+	}
+	return []store.UsagePoint{{Bucket: "2026-10-14", Runs: 3, InputTokens: 100}}, nil
+}
+
+type fakeJobs struct{}
+
+func (fakeJobs) ListJobs() []scheduler.Job {
+	return []scheduler.Job{{Name: "digest", Cron: "0 9 * * *"}}
+}
+func (fakeJobs) RetryJob(_ context.Context, _ string) error { return nil }
+func (fakeJobs) DeleteJob(string) error                     { return nil }
+
+func get(t *testing.T, h http.Handler, path, token string, out any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("GET %s: %v in %s", path, err, rec.Body)
+		}
+	}
+	return rec.Code
+}
+
+func TestHandler_Page(t *testing.T) {
+	h := NewHandler("secret", Sources{})
+	for _, path := range []string{"/", "/app.js", "/style.css"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.Len() == 0 {
+			t.Errorf("GET %s = %d; the page must load without a token", path, rec.Code)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if !strings.Contains(rec.Body.String(), "app.js") {
+		t.Error("index should load app.js")
+	}
+}
+
+func TestHandler_RequiresToken(t *testing.T) {
+	h := NewHandler("secret", Sources{Admin: ctl.Backends{Jobs: fakeJobs{}}})
+	for _, token := range []string{"", "wrong"} {
+		if code := get(t, h, "/api/jobs", token, nil); code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d; want 401", token, code)
+		}
+	}
+	if code := get(t, NewHandler("", Sources{}), "/api/jobs", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("an empty configured token must not open the API, got %d", code)
+	}
+}
+
+func TestHandler_API(t *testing.T) {
+	activity := NewActivity(0)
+	activity.addRun(JobRun{Job: "digest", Status: "error", Error: "boom"})
+	activity.addFailover(Failover{From: "local/llama", To: "openai/gpt-4o"})
+	usage := &fakeUsage{}
+	h := NewHandler("secret", Sources{
+		Admin:    ctl.Backends{Jobs: fakeJobs{}},
+		Plugins:  fakePlugins{},
+		Usage:    usage,
+		Activity: activity,
+	})
+
+	var jobs []scheduler.Job
+	if code := get(t, h, "/api/jobs", "secret", &jobs); code != http.StatusOK || len(jobs) != 1 {
+		t.Errorf("ctl route through the dashboard: %d %+v", code, jobs)
+	}
+	var plugins []plugin.PluginStatus
+	if code := get(t, h, "/api/plugin-health", "secret", &plugins); code != http.StatusOK || len(plugins) != 2 || plugins[1].Error == "" {
+		t.Errorf("plugin health: %d %+v", code, plugins)
+	}
+	var runs []JobRun
+	if code := get(t, h, "/api/job-runs?job=digest", "secret", &runs); code != http.StatusOK || len(runs) != 1 || runs[0].Error != "boom" {
+		t.Errorf("job runs: %d %+v", code, runs)
+	}
+	if get(t, h, "/api/job-runs?job=other", "secret", &runs); len(runs) != 0 {
+		t.Errorf("runs of another job = %+v", runs)
+	}
+	var failovers []Failover
+	if code := get(t, h, "/api/failovers", "secret", &failovers); code != http.StatusOK || len(failovers) != 1 {
+		t.Errorf("failovers: %d %+v", code, failovers)
+	}
+
+	var points []store.UsagePoint
+	if code := get(t, h, "/api/usage-series?since=24h", "secret", &points); code != http.StatusOK || len(points) != 1 {
+		t.Errorf("usage series: %d %+v", code, points)
+	}
+	if usage.bucket != "hour" {
+		t.Errorf("a 24h window should default to hourly buckets, got %q", usage.bucket)
+	}
+	if get(t, h, "/api/usage-series?since=30d", "secret", &points); usage.bucket != "day" || time.Since(usage.since) < 30*24*time.Hour-time.Minute {
+		t.Errorf("30d window: bucket %q since %v", usage.bucket, usage.since)
+	}
+	if code := get(t, h, "/api/usage-series?bucket=week", "secret", nil); code != http.StatusBadRequest {
+		t.Errorf("bad bucket: %d", code)
+	}
+	if code := get(t, h, "/api/usage-series?since=soon", "secret", nil); code != http.StatusBadRequest {
+		t.Errorf("bad window: %d", code)
+	}
+}
+
+func TestHandler_MissingSources(t *testing.T) {
+	h := NewHandler("secret", Sources{})
+	for _, path := range []string{"/api/plugin-health", "/api/job-runs", "/api/failovers", "/api/usage-series", "/api/sessions"} {
+		if code := get(t, h, path, "secret", nil); code != http.StatusNotImplemented {
+			t.Errorf("GET %s = %d; want 501", path, code)
+		}
+	}
+}
+
+func TestActivity(t *testing.T) {
+	bus := eventbus.New(10)
+	defer bus.Close(context.Background())
+	a := NewActivity(2)
+	a.Attach(bus)
+
+	ctx := context.Background()
+	for _, job := range []string{"a", "b", "c"} {
+		bus.Publish(ctx, eventbus.Event{Type: eventbus.JobRun, Data: map[string]string{"job": job, "status": "ok"}})
+	}
+	bus.Publish(ctx, eventbus.Event{Type: eventbus.ProviderFailover, SessionID: "s1", Data: map[string]string{"from": "x/1", "to": "y/2", "error": "timeout"}})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(a.Failovers()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	runs := a.JobRuns("")
+	if len(runs) != 2 || runs[0].Job != "c" || runs[1].Job != "b" {
+		t.Errorf("runs = %+v; want the last two, newest first", runs)
+	}
+	f := a.Failovers()
+	if len(f) != 1 || f[0].From != "x/1" || f[0].SessionID != "s1" || f[0].Time.IsZero() {
+		t.Errorf("failovers = %+v", f)
+	}
+}
@@ -0,0 +1,303 @@
+// OpenTalon admin dashboard. Plain DOM, no build step: every panel is a
+// function that fetches its JSON from /api and redraws its section.
+"use strict";
+
+const REFRESH_MS = 10000;
+const LIVE_MS = 15 * 60 * 1000; // a session updated this recently counts as live
+
+const $ = (sel, root = document) => root.querySelector(sel);
+
+let token = localStorage.getItem("opentalon.dashboard.token") || "";
+let current = "";
+let selectedSession = "";
+let timer = 0;
+
+class Unavailable extends Error {}
+
+async function api(path, opts = {}) {
+  const resp = await fetch("api" + path, {
+    ...opts,
+    headers: { Authorization: "Bearer " + token },
+  });
+  if (resp.status === 401) {
+    signOut();
+    throw new Error("unauthorized");
+  }
+  if (resp.status === 204) return null;
+  const body = await resp.json().catch(() => ({}));
+  if (resp.status === 501) throw new Unavailable(body.error);
+  if (!resp.ok) throw new Error(body.error || resp.statusText);
+  return body;
+}
+
+// el builds an element; string children become text nodes, so nothing
+// from the API is ever parsed as HTML.
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [k, v] of Object.entries(attrs)) {
+    if (k.startsWith("on")) node.addEventListener(k.slice(2), v);
+    else node.setAttribute(k, v);
+  }
+  for (const c of children) {
+    if (c !== null && c !== undefined) node.append(c);
+  }
+  return node;
+}
+
+function fill(tbody, rows, empty) {
+  tbody.replaceChildren(...rows);
+  if (rows.length === 0) {
+    const cols = tbody.closest("table").querySelectorAll("th").length;
+    tbody.append(el("tr", {}, el("td", { colspan: cols, class: "muted" }, empty)));
+  }
+}
+
+function when(t) {
+  if (!t || t.startsWith("0001-")) return "";
+  return new Date(t).toLocaleString();
+}
+
+function badge(ok, text) {
+  return el("span", { class: "badge " + (ok ? "ok" : "bad") }, text);
+}
+
+function showError(section, err) {
+  const note = $(".unavailable", section) || el("p", { class: "unavailable muted" });
+  note.textContent = err instanceof Unavailable ? err.message : "Error: " + err.message;
+  section.prepend(note);
+}
+
+function clearError(section) {
+  $(".unavailable", section)?.remove();
+}
+
+// Sessions
+
+async function sessions(section) {
+  const list = await api("/sessions?limit=100");
+  const now = Date.now();
+  fill($("tbody", section), list.map((s) => {
+    const live = now - new Date(s.updated_at).getTime() < LIVE_MS;
+    const row = el("tr", { class: s.id === selectedSession ? "selected" : "", onclick: () => openSession(s.id) },
+      el("td", {}, live ? badge(true, "live") : ""),
+      el("td", { title: s.id }, s.title || s.id),
+      el("td", {}, s.entity_id || ""),
+      el("td", {}, s.channel_id || ""),
+      el("td", { class: "num" }, String(s.messages)),
+      el("td", {}, when(s.updated_at)));
+    return row;
+  }), "No sessions yet.");
+  if (selectedSession) await transcript(selectedSession);
+}
+
+async function openSession(id) {
+  selectedSession = id;
+  for (const row of document.querySelectorAll("#sessions tbody tr")) row.classList.remove("selected");
+  await transcript(id).catch((err) => showError($("#sessions"), err));
+  refresh();
+}
+
+async function transcript(id) {
+  const s = await api("/sessions/" + encodeURIComponent(id));
+  const out = $("#transcript");
+  const parts = [el("h2", {}, s.Title || s.ID)];
+  if (s.Summary) parts.push(el("p", { class: "summary" }, s.Summary));
+  for (const m of s.Messages || []) {
+    const msg = el("div", { class: "msg " + m.role + (m.visibility === "hidden" ? " hidden-msg" : "") },
+      el("div", { class: "role" }, m.role + (m.tool_call_id ? " · " + m.tool_call_id : "")));
+    if (m.content) msg.append(el("pre", {}, m.content));
+    for (const call of m.tool_calls || []) {
+      msg.append(el("div", { class: "call" },
+        el("strong", {}, call.name),
+        el("pre", {}, JSON.stringify(call.arguments || {}, null, 2))));
+    }
+    parts.push(msg);
+  }
+  parts.push(el("button", {
+    type: "button", class: "danger", onclick: async () => {
+      if (!confirm("Delete session " + s.ID + " and all its messages?")) return;
+      await api("/sessions/" + encodeURIComponent(s.ID), { method: "DELETE" });
+      selectedSession = "";
+      out.replaceChildren(el("p", { class: "muted" }, "Session deleted."));
+      refresh();
+    },
+  }, "Delete session"));
+  out.replaceChildren(...parts);
+}
+
+// Jobs
+
+function schedule(j) {
+  if (j.cron) return "cron " + j.cron;
+  if (j.at) return "at " + when(j.at);
+  if (j.interval) return "every " + j.interval;
+  return "";
+}
+
+async function jobs(section) {
+  const [list, runs] = await Promise.all([api("/jobs"), api("/job-runs").catch(() => [])]);
+  const last = {};
+  for (const r of runs) last[r.job] ??= r;
+  fill($("tbody", section), list.map((j) => {
+    const r = last[j.name];
+    return el("tr", {},
+      el("td", {}, j.name),
+      el("td", {}, schedule(j)),
+      el("td", {}, j.action),
+      el("td", {}, r ? when(r.time) : ""),
+      el("td", {}, j.paused ? badge(false, "paused") : r ? badge(r.status === "ok", r.status) : ""),
+      el("td", {}, el("button", {
+        type: "button", onclick: async (e) => {
+          e.target.disabled = true;
+          try {
+            await api("/jobs/" + encodeURIComponent(j.name) + "/run", { method: "POST" });
+          } catch (err) {
+            alert(err.message);
+          }
+          refresh();
+        },
+      }, "Run now")));
+  }), "No jobs scheduled.");
+  fill($("#runs tbody"), runs.map((r) => el("tr", {},
+    el("td", {}, when(r.time)),
+    el("td", {}, r.job),
+    el("td", {}, badge(r.status === "ok", r.status)),
+    el("td", { class: "err" }, r.error || ""))), "No runs since this instance started.");
+}
+
+// Plugins
+
+async function plugins(section) {
+  const list = await api("/plugin-health");
+  fill($("tbody", section), list.map((p) => el("tr", {},
+    el("td", {}, p.name),
+    el("td", {}, p.mode),
+    el("td", {}, badge(p.loaded, p.loaded ? "loaded" : "down")),
+    el("td", { class: "err" }, p.error || ""),
+    el("td", {}, el("button", {
+      type: "button", onclick: async (e) => {
+        e.target.disabled = true;
+        try {
+          await api("/plugins/" + encodeURIComponent(p.name) + "/reload", { method: "POST" });
+        } catch (err) {
+          alert(err.message);
+        }
+        refresh();
+      },
+    }, "Reload")))), "No plugins configured.");
+}
+
+// Providers
+
+async function providers(section) {
+  const list = await api("/failovers");
+  fill($("tbody", section), list.map((f) => el("tr", {},
+    el("td", {}, when(f.time)),
+    el("td", {}, f.from),
+    el("td", {}, f.to),
+    el("td", {}, f.session_id || ""),
+    el("td", { class: "err" }, f.error || ""))), "No failovers: the preferred provider has served every request.");
+}
+
+// Usage
+
+const SVG = "http://www.w3.org/2000/svg";
+
+function svg(tag, attrs, text) {
+  const node = document.createElementNS(SVG, tag);
+  for (const [k, v] of Object.entries(attrs)) node.setAttribute(k, v);
+  if (text !== undefined) node.textContent = text;
+  return node;
+}
+
+// barChart draws one stacked bar per point; series are [key, class] pairs.
+function barChart(target, points, series, format) {
+  const w = 800, h = 200, pad = 24;
+  target.setAttribute("viewBox", `0 0 ${w} ${h + pad}`);
+  const max = Math.max(1e-9, ...points.map((p) => series.reduce((n, [k]) => n + p[k], 0)));
+  const bw = points.length ? (w - pad) / points.length : 0;
+  const nodes = [svg("text", { x: 0, y: 12, class: "axis" }, format(max))];
+  points.forEach((p, i) => {
+    let y = h;
+    for (const [k, cls] of series) {
+      const bh = (p[k] / max) * (h - pad);
+      y -= bh;
+      const bar = svg("rect", { x: pad + i * bw + 1, y, width: Math.max(1, bw - 2), height: bh, class: cls });
+      bar.append(svg("title", {}, `${p.bucket}: ${k.replace("_", " ")} ${format(p[k])}`));
+      nodes.push(bar);
+    }
+  });
+  if (points.length) {
+    nodes.push(svg("text", { x: pad, y: h + 16, class: "axis" }, points[0].bucket));
+    nodes.push(svg("text", { x: w, y: h + 16, class: "axis", "text-anchor": "end" }, points[points.length - 1].bucket));
+  } else {
+    nodes.push(svg("text", { x: w / 2, y: h / 2, class: "axis", "text-anchor": "middle" }, "No usage in this window."));
+  }
+  target.replaceChildren(...nodes);
+}
+
+async function usage() {
+  const since = $("#window").value;
+  const [points, byModel] = await Promise.all([
+    api("/usage-series?since=" + since),
+    api("/usage?by=model&since=" + since),
+  ]);
+  barChart($("#tokens-chart"), points, [["input_tokens", "in"], ["output_tokens", "out"]], (n) => Math.round(n).toLocaleString());
+  barChart($("#cost-chart"), points, [["cost", "cost"]], (n) => "$" + n.toFixed(4));
+  fill($("#by-model tbody"), byModel.map((t) => el("tr", {},
+    el("td", {}, t.key || "(none)"),
+    el("td", { class: "num" }, String(t.runs)),
+    el("td", { class: "num" }, t.input_tokens.toLocaleString()),
+    el("td", { class: "num" }, t.output_tokens.toLocaleString()),
+    el("td", { class: "num" }, "$" + t.cost.toFixed(4)))), "No usage in this window.");
+}
+
+// Navigation
+
+const panels = { sessions, jobs, plugins, providers, usage };
+
+async function refresh() {
+  clearTimeout(timer);
+  if (!token || !current) return;
+  const section = document.getElementById(current);
+  try {
+    await panels[current](section);
+    clearError(section);
+    $("#status").textContent = "Updated " + new Date().toLocaleTimeString();
+  } catch (err) {
+    if (token) showError(section, err);
+  }
+  timer = setTimeout(refresh, REFRESH_MS);
+}
+
+function show() {
+  current = panels[location.hash.slice(1)] ? location.hash.slice(1) : "sessions";
+  for (const name of Object.keys(panels)) {
+    document.getElementById(name).hidden = name !== current || !token;
+  }
+  for (const a of document.querySelectorAll("nav a")) {
+    a.classList.toggle("active", a.dataset.tab === current);
+  }
+  $("#login").hidden = !!token;
+  $("#logout").hidden = !token;
+  refresh();
+}
+
+function signOut() {
+  token = "";
+  localStorage.removeItem("opentalon.dashboard.token");
+  $("#status").textContent = "";
+  show();
+}
+
+$("#login").addEventListener("submit", (e) => {
+  e.preventDefault();
+  token = $("#token").value;
+  localStorage.setItem("opentalon.dashboard.token", token);
+  $("#token").value = "";
+  show();
+});
+$("#logout").addEventListener("click", signOut);
+$("#window").addEventListener("change", refresh);
+window.addEventListener("hashchange", show);
+show();
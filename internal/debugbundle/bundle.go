@@ -0,0 +1,267 @@
+// Package debugbundle collects what support needs to triage a bug report
+// into one tar.gz: version and platform, the config with secrets stripped,
+// the tail of the log files, bundle lock files, scheduler state, a listing
+// of the data directory and the result of probing each provider. Everything
+// text-shaped goes through RedactText or RedactYAML before it is written.
+package debugbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/version"
+)
+
+const (
+	// DefaultLogLines is how much of each log file is kept.
+	DefaultLogLines = 2000
+
+	probeTimeout = 10 * time.Second
+)
+
+// Probe checks one provider endpoint; nil means healthy.
+type Probe struct {
+	Name  string // provider id
+	URL   string
+	Check func(ctx context.Context) error // nil = not probed (built-in endpoint)
+}
+
+// Options selects what goes into a bundle.
+type Options struct {
+	ConfigPath string
+	DataDir    string
+	LogFiles   []string
+	LogLines   int // <= 0 = DefaultLogLines
+	Probes     []Probe
+}
+
+// lockFiles and stateFiles are read from the data directory when present;
+// the value is the name inside the archive.
+var (
+	lockFiles  = []string{"plugins.lock", "channels.lock", "skills.lock", "lua_plugins.lock"}
+	stateFiles = map[string]string{
+		filepath.Join("scheduler", "jobs.yaml"): "state/scheduler-jobs.yaml",
+	}
+)
+
+// Write builds the bundle into w and returns the names of the files it
+// contains. A part that cannot be collected is noted in errors.txt instead
+// of failing the bundle: a half-broken install is exactly when it is needed.
+func Write(ctx context.Context, w io.Writer, opts Options) ([]string, error) {
+	now := time.Now().UTC()
+	root := "opentalon-debug-" + now.Format("20060102-150405")
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var names, problems []string
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{Name: root + "/" + name, Mode: 0600, Size: int64(len(data)), ModTime: now}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+		names = append(names, name)
+		return nil
+	}
+	note := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if err := add("version.txt", []byte(versionText(now))); err != nil {
+		return nil, err
+	}
+
+	if opts.ConfigPath != "" {
+		if raw, err := os.ReadFile(opts.ConfigPath); err != nil {
+			note("config: %v", err)
+		} else if clean, err := RedactYAML(raw); err != nil {
+			note("config: %v", err)
+		} else if err := add("config.yaml", clean); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(opts.Probes) > 0 {
+		if err := add("health.txt", []byte(probeText(ctx, opts.Probes))); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range opts.LogFiles {
+		tail, err := tailLines(path, orDefault(opts.LogLines, DefaultLogLines))
+		if err != nil {
+			note("log %s: %v", path, err)
+			continue
+		}
+		if err := add("logs/"+filepath.Base(path), []byte(RedactText(tail))); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.DataDir != "" {
+		for _, name := range lockFiles {
+			data, err := os.ReadFile(filepath.Join(opts.DataDir, name))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					note("%s: %v", name, err)
+				}
+				continue
+			}
+			if err := add("locks/"+name, []byte(RedactText(string(data)))); err != nil {
+				return nil, err
+			}
+		}
+		rels := make([]string, 0, len(stateFiles))
+		for rel := range stateFiles {
+			rels = append(rels, rel)
+		}
+		sort.Strings(rels)
+		for _, rel := range rels {
+			data, err := os.ReadFile(filepath.Join(opts.DataDir, rel))
+			if err != nil {
+				if !os.IsNotExist(err) {
+					note("%s: %v", rel, err)
+				}
+				continue
+			}
+			clean, err := RedactYAML(data)
+			if err != nil {
+				note("%s: %v", rel, err)
+				continue
+			}
+			if err := add(stateFiles[rel], clean); err != nil {
+				return nil, err
+			}
+		}
+		listing, err := dirListing(opts.DataDir)
+		if err != nil {
+			note("data dir: %v", err)
+		} else if err := add("data_dir.txt", []byte(listing)); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(problems) > 0 {
+		if err := add("errors.txt", []byte(strings.Join(problems, "\n")+"\n")); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return names, gz.Close()
+}
+
+func versionText(now time.Time) string {
+	return fmt.Sprintf("%s\ngo: %s\nplatform: %s/%s\ncpus: %d\ncollected: %s\n",
+		version.Get(), runtime.Version(), runtime.GOOS, runtime.GOARCH, runtime.NumCPU(), now.Format(time.RFC3339))
+}
+
+// probeText runs the probes concurrently and reports one line per provider.
+func probeText(ctx context.Context, probes []Probe) string {
+	lines := make([]string, len(probes))
+	done := make(chan struct{}, len(probes))
+	for i, p := range probes {
+		go func() {
+			defer func() { done <- struct{}{} }()
+			if p.Check == nil {
+				lines[i] = fmt.Sprintf("%s: not probed (no base_url)", p.Name)
+				return
+			}
+			pctx, cancel := context.WithTimeout(ctx, probeTimeout)
+			defer cancel()
+			start := time.Now()
+			err := p.Check(pctx)
+			took := time.Since(start).Round(time.Millisecond)
+			if err != nil {
+				lines[i] = fmt.Sprintf("%s: UNHEALTHY %s (%s): %s", p.Name, RedactText(p.URL), took, RedactText(err.Error()))
+				return
+			}
+			lines[i] = fmt.Sprintf("%s: ok %s (%s)", p.Name, RedactText(p.URL), took)
+		}()
+	}
+	for range probes {
+		<-done
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// tailLines returns the last n lines of the file at path.
+func tailLines(path string, n int) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// dirListing lists the data directory two levels deep with sizes; deeper
+// trees (cached bundles, blobs) are summarised per directory.
+func dirListing(dir string) (string, error) {
+	var sb strings.Builder
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintf(&sb, "%s: %v\n", path, err)
+			return nil
+		}
+		rel, _ := filepath.Rel(dir, path)
+		if rel == "." {
+			return nil
+		}
+		depth := strings.Count(rel, string(filepath.Separator))
+		if d.IsDir() {
+			if depth >= 1 {
+				files, size := dirSize(path)
+				fmt.Fprintf(&sb, "%s/ (%d files, %d bytes)\n", rel, files, size)
+				return filepath.SkipDir
+			}
+			fmt.Fprintf(&sb, "%s/\n", rel)
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			fmt.Fprintf(&sb, "%s (%d bytes, modified %s)\n", rel, info.Size(), info.ModTime().UTC().Format(time.RFC3339))
+		}
+		return nil
+	})
+	return sb.String(), err
+}
+
+func dirSize(dir string) (files int, size int64) {
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files++
+			size += info.Size()
+		}
+		return nil
+	})
+	return files, size
+}
+
+func orDefault(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package debugbundle
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces every secret value in a bundle.
+const Redacted = "[REDACTED]"
+
+var (
+	// secretKey matches config keys whose values are credentials.
+	// "token" must be a whole word so max_tokens and the like stay readable.
+	secretKey = regexp.MustCompile(`(?i)(api_?key|(^|[_.-])token($|[_.-])|secret|passw(or)?d|dsn|authorization|private_?key|access_?key|credential|cookie|signing_?key)`)
+	// envRef is a value that only names an environment variable; it reveals
+	// nothing and tells support where the secret comes from.
+	envRef = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*\}$`)
+
+	textSecrets = []struct {
+		re   *regexp.Regexp
+		repl string
+	}{
+		{regexp.MustCompile(`(?i)(bearer\s+)[A-Za-z0-9._~+/=-]{8,}`), "${1}" + Redacted},
+		{regexp.MustCompile(`\b(sk|pk|rk|xox[abpr])-[A-Za-z0-9_-]{12,}`), Redacted},
+		{regexp.MustCompile(`(?i)("?[a-z_]*(api_?key|token|secret|passw(or)?d|authorization)"?\s*[:=]\s*"?(bearer\s+)?)[^"\s,}&]+`), "${1}" + Redacted},
+		{regexp.MustCompile(`(://[^/\s:@]+:)[^@\s/]+@`), "${1}" + Redacted + "@"},
+	}
+)
+
+// RedactText strips credentials from free text such as log lines: bearer
+// tokens, provider key formats, key=value / "key": "value" pairs with a
+// secret-looking key, and passwords in URLs.
+func RedactText(s string) string {
+	for _, p := range textSecrets {
+		s = p.re.ReplaceAllString(s, p.repl)
+	}
+	return s
+}
+
+// RedactYAML returns data with the value of every secret-looking key
+// replaced, ${ENV} references kept, and the remaining strings passed through
+// RedactText. Comments are dropped with the secrets they might mention.
+func RedactYAML(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	redactNode(&doc, false)
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, err
+	}
+	_ = enc.Close()
+	return buf.Bytes(), nil
+}
+
+func redactNode(n *yaml.Node, secret bool) {
+	n.HeadComment, n.LineComment, n.FootComment = "", "", ""
+	switch n.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, val := n.Content[i], n.Content[i+1]
+			key.HeadComment, key.LineComment, key.FootComment = "", "", ""
+			redactNode(val, secret || secretKey.MatchString(key.Value))
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, c := range n.Content {
+			redactNode(c, secret)
+		}
+	case yaml.ScalarNode:
+		v := strings.TrimSpace(n.Value)
+		switch {
+		case v == "" || envRef.MatchString(v):
+		case secret:
+			n.Value, n.Tag, n.Style = Redacted, "!!str", 0
+		default:
+			n.Value = RedactText(n.Value)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package digest compiles what happened across conversations over a period
+// (the questions users asked, tool failures, cost and threads left without
+// an answer) into a report for operators. Reports run as scheduler jobs that
+// call the digest tool and post its result to a channel; a Go text/template
+// lays each one out.
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// listLimit bounds each list of a report: questions, failing tools and
+// unresolved threads.
+const listLimit = 10
+
+// Activity is what happened over a period. Conversations, questions and
+// threads count chat sessions only; tool calls, failures and Spend count
+// every run, including system runs made on a user's behalf.
+type Activity struct {
+	Sessions   int           // conversations in which a user wrote
+	Messages   int           // messages users wrote
+	Questions  []Question    // most asked first
+	ToolCalls  int           // tool calls made
+	Failures   []ToolFailure // most failures first
+	Spend      Spend
+	Unresolved []Thread // newest first
+}
+
+// Question is a message users wrote, and how many times they wrote it.
+type Question struct {
+	Text  string
+	Count int
+}
+
+// ToolFailure counts the failed calls of one tool (plugin__action).
+type ToolFailure struct {
+	Tool      string
+	Count     int
+	LastError string
+}
+
+// Spend sums the model usage of the period's runs.
+type Spend struct {
+	Runs         int
+	InputTokens  int
+	OutputTokens int
+	Cost         float64 // at the models' configured prices
+}
+
+// Thread is a conversation whose last message is a user's that got no reply.
+type Thread struct {
+	SessionID   string
+	Title       string
+	Channel     string
+	LastMessage string
+	At          time.Time
+}
+
+// Source reads the activity recorded since a time; lists hold at most limit
+// entries. *store.ActivityStore satisfies it.
+type Source interface {
+	Activity(ctx context.Context, since time.Time, limit int) (Activity, error)
+}
+
+// Report is what a template renders: the activity and the period it covers.
+type Report struct {
+	Title string
+	Since time.Time
+	Until time.Time
+	Activity
+}
+
+// DefaultTemplate lays out a report when none is configured.
+const DefaultTemplate = `{{.Title}}: {{.Since.Format "Jan 2 15:04"}} to {{.Until.Format "Jan 2 15:04"}} UTC
+{{.Sessions}} conversation(s), {{.Messages}} message(s), {{.ToolCalls}} tool call(s).
+Cost: ${{printf "%.2f" .Spend.Cost}} ({{.Spend.InputTokens}} input, {{.Spend.OutputTokens}} output tokens in {{.Spend.Runs}} runs)
+
+Top questions:
+{{range .Questions}}- {{.Text}}{{if gt .Count 1}} (x{{.Count}}){{end}}
+{{else}}- none
+{{end}}
+Tool failures:
+{{range .Failures}}- {{.Tool}}: {{.Count}} failed; last error: {{.LastError}}
+{{else}}- none
+{{end}}
+Unresolved threads:
+{{range .Unresolved}}- {{or .Title .SessionID}}{{if .Channel}} ({{.Channel}}){{end}}: {{.LastMessage}}
+{{else}}- none
+{{end}}`
+
+// ParseTemplate parses a report template; empty text is DefaultTemplate.
+func ParseTemplate(text string) (*template.Template, error) {
+	if strings.TrimSpace(text) == "" {
+		text = DefaultTemplate
+	}
+	tmpl, err := template.New("digest").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("digest template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Compile reads the activity of the period before until and renders it.
+func Compile(ctx context.Context, src Source, tmpl *template.Template, title string, period time.Duration, until time.Time) (string, error) {
+	since := until.Add(-period)
+	a, err := src.Activity(ctx, since, listLimit)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, Report{Title: title, Since: since.UTC(), Until: until.UTC(), Activity: a}); err != nil {
+		return "", fmt.Errorf("digest template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,133 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+type fakeSource struct {
+	a     Activity
+	since time.Time
+}
+
+func (f *fakeSource) Activity(_ context.Context, since time.Time, _ int) (Activity, error) {
+	f.since = since
+	return f.a, nil
+}
+
+func TestCompile_DefaultTemplate(t *testing.T) {
+	src := &fakeSource{a: Activity{
+		Sessions:   2,
+		Messages:   5,
+		Questions:  []Question{{Text: "How do I reset my VPN?", Count: 3}, {Text: "Where is the wiki?", Count: 1}},
+		ToolCalls:  7,
+		Failures:   []ToolFailure{{Tool: "jira__search", Count: 2, LastError: "401 unauthorized"}},
+		Spend:      Spend{Runs: 4, InputTokens: 1200, OutputTokens: 300, Cost: 0.126},
+		Unresolved: []Thread{{SessionID: "s1", Title: "VPN", Channel: "slack", LastMessage: "still broken"}},
+	}}
+	tmpl, err := ParseTemplate("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	until := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	out, err := Compile(context.Background(), src, tmpl, "Daily digest", 24*time.Hour, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !src.since.Equal(until.Add(-24 * time.Hour)) {
+		t.Errorf("since = %v", src.since)
+	}
+	for _, want := range []string{
+		"Daily digest: Mar 1 09:00 to Mar 2 09:00 UTC",
+		"2 conversation(s), 5 message(s), 7 tool call(s).",
+		"Cost: $0.13 (1200 input, 300 output tokens in 4 runs)",
+		"- How do I reset my VPN? (x3)\n- Where is the wiki?\n",
+		"- jira__search: 2 failed; last error: 401 unauthorized",
+		"- VPN (slack): still broken",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report lacks %q:\n%s", want, out)
+		}
+	}
+
+	src.a = Activity{}
+	out, _ = Compile(context.Background(), src, tmpl, "Daily digest", 24*time.Hour, until)
+	if strings.Count(out, "- none") != 3 {
+		t.Errorf("empty report:\n%s", out)
+	}
+}
+
+func TestParseTemplate(t *testing.T) {
+	tmpl, err := ParseTemplate(`{{.Title}}: {{len .Unresolved}} unanswered`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := &fakeSource{a: Activity{Unresolved: []Thread{{SessionID: "s1"}}}}
+	out, err := Compile(context.Background(), src, tmpl, "Weekly", time.Hour, time.Now())
+	if err != nil || out != "Weekly: 1 unanswered" {
+		t.Errorf("out = %q, err = %v", out, err)
+	}
+	if _, err := ParseTemplate(`{{.Title`); err == nil {
+		t.Error("want an error for a malformed template")
+	}
+}
+
+// toolRunner runs the digest tool for the scheduler's jobs and records the
+// results.
+type toolRunner struct {
+	tool *Tool
+	mu   sync.Mutex
+	res  map[string]orchestrator.ToolResult
+}
+
+func (r *toolRunner) RunAction(ctx context.Context, plugin, action string, args map[string]string) (string, error) {
+	job, _ := scheduler.JobFromContext(ctx)
+	res := r.tool.Execute(ctx, orchestrator.ToolCall{ID: "c1", Plugin: plugin, Action: action, Args: args})
+	r.mu.Lock()
+	r.res[job.Name] = res
+	r.mu.Unlock()
+	if res.Error != "" {
+		return "", errors.New(res.Error)
+	}
+	return res.Content, nil
+}
+
+func TestTool_OnlyConfiguredJobs(t *testing.T) {
+	tool := NewTool(&fakeSource{})
+	runner := &toolRunner{tool: tool, res: map[string]orchestrator.ToolResult{}}
+	s := scheduler.New(runner, nil, "")
+	action := ToolName + "__" + CompileAction
+	if err := s.Start([]scheduler.Job{
+		{Name: "daily", Interval: "50ms", Action: action, Args: map[string]string{"title": "Daily"}},
+		{Name: "bad-period", Interval: "50ms", Action: action, Args: map[string]string{"period": "a day"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddJob(scheduler.Job{Name: "mine", Interval: "50ms", Action: action}, "U1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	s.Stop()
+
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	if res := runner.res["daily"]; res.Error != "" || !strings.HasPrefix(res.Content, "Daily: ") {
+		t.Errorf("configured job: %+v", res)
+	}
+	if res := runner.res["bad-period"]; !strings.Contains(res.Error, `invalid period "a day"`) {
+		t.Errorf("bad period: %+v", res)
+	}
+	if res := runner.res["mine"]; !strings.Contains(res.Error, "only as scheduler jobs from the configuration") {
+		t.Errorf("user job: %+v", res)
+	}
+	if res := tool.Execute(context.Background(), orchestrator.ToolCall{ID: "c2", Action: CompileAction}); res.Error == "" {
+		t.Errorf("outside the scheduler: %+v", res)
+	}
+}
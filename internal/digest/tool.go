@@ -0,0 +1,80 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+const (
+	ToolName      = "digest"
+	CompileAction = "compile"
+)
+
+// defaultPeriod is how far back a report looks without period.
+const defaultPeriod = 24 * time.Hour
+
+// Tool is the built-in digest plugin. Its compile action is hidden from the
+// model and runs only for scheduler jobs from the configuration: a report
+// covers every user's conversations, so neither users nor jobs they create
+// may run it.
+type Tool struct {
+	src Source
+}
+
+func NewTool(src Source) *Tool {
+	return &Tool{src: src}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:        ToolName,
+		Description: "Activity digests for operators, run by configured scheduler jobs.",
+		Actions: []orchestrator.Action{
+			{
+				Name:        CompileAction,
+				Description: "Compile the activity of a period into a report.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "title", Description: "Report heading", Required: false},
+					{Name: "period", Description: "Go duration to look back (default 24h)", Required: false},
+					{Name: "template", Description: "Go text/template laying out the report; empty = the default layout", Required: false},
+				},
+				UserOnly: true,
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Action != CompileAction {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown digest action: %s", call.Action)}
+	}
+	if job, ok := scheduler.JobFromContext(ctx); !ok || job.Source != "config" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "digest reports run only as scheduler jobs from the configuration"}
+	}
+	period := defaultPeriod
+	if s := strings.TrimSpace(call.Args["period"]); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid period %q: want a Go duration such as 24h", s)}
+		}
+		period = d
+	}
+	tmpl, err := ParseTemplate(call.Args["template"])
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	title := strings.TrimSpace(call.Args["title"])
+	if title == "" {
+		title = "Activity digest"
+	}
+	out, err := Compile(ctx, t.src, tmpl, title, period, time.Now())
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: out}
+}
@@ -4,7 +4,6 @@ package eval_test
 
 import (
 	"context"
-	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -13,10 +12,8 @@ import (
 
 	"github.com/opentalon/opentalon/internal/eval"
 	"github.com/opentalon/opentalon/internal/orchestrator"
-	"github.com/opentalon/opentalon/internal/profile"
 	"github.com/opentalon/opentalon/internal/provider"
 	"github.com/opentalon/opentalon/internal/scenarios"
-	"github.com/opentalon/opentalon/internal/state"
 	"github.com/opentalon/opentalon/internal/testutil"
 )
 
@@ -62,56 +59,11 @@ func currentTag() string {
 	return strings.TrimSpace(string(out))
 }
 
-func newOrchestrator(llm orchestrator.LLMClient) (*orchestrator.Orchestrator, string) {
-	registry := orchestrator.NewToolRegistry()
-	_ = registry.Register(orchestrator.PluginCapability{
-		Name:        "gitlab",
-		Description: "GitLab integration",
-		Actions: []orchestrator.Action{
-			{Name: "analyze_code", Description: "Analyze code", Parameters: []orchestrator.Parameter{{Name: "repo", Description: "Repository"}}},
-			{Name: "create_pr", Description: "Create PR"},
-		},
-	}, &echoExecutor{})
-	_ = registry.Register(orchestrator.PluginCapability{
-		Name:        "jira",
-		Description: "Jira integration",
-		Actions:     []orchestrator.Action{{Name: "create_issue", Description: "Create issue"}},
-	}, &echoExecutor{})
-
-	memory := state.NewMemoryStore("")
-	sessions := state.NewSessionStore("")
-	sessID := "eval-session"
-	sessions.Create(sessID)
-	return orchestrator.New(llm, orchestrator.DefaultParser, registry, memory, sessions), sessID
-}
-
-type echoExecutor struct{}
-
-func (e *echoExecutor) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
-	return orchestrator.ToolResult{
-		CallID:  call.ID,
-		Content: fmt.Sprintf("executed %s.%s", call.Plugin, call.Action),
-	}
-}
-
-func runScenario(prov evalProvider, s scenarios.Scenario) (scenarios.RunResult, error) {
-	ctx := profile.WithProfile(context.Background(), &profile.Profile{
-		Model: prov.profileModel,
-	})
-	orch, sessID := newOrchestrator(prov.llm)
-	res, err := orch.Run(ctx, sessID, s.Input)
-	if err != nil {
-		return scenarios.RunResult{}, err
-	}
-	out := scenarios.RunResult{Response: res.Response}
-	for _, tc := range res.ToolCalls {
-		out.ToolCalls = append(out.ToolCalls, scenarios.ToolCallResult{
-			Plugin: tc.Plugin,
-			Action: tc.Action,
-			Args:   tc.Args,
-		})
-	}
-	return out, nil
+// vcrTools stand in for the plugins the embedded scenarios were written for.
+var vcrTools = []scenarios.Tool{
+	{Name: "gitlab__analyze_code", Description: "Analyze code", Parameters: []string{"repo"}, Result: "executed gitlab.analyze_code"},
+	{Name: "gitlab__create_pr", Description: "Create PR", Result: "executed gitlab.create_pr"},
+	{Name: "jira__create_issue", Description: "Create issue", Result: "executed jira.create_issue"},
 }
 
 func TestEval(t *testing.T) {
@@ -120,45 +72,32 @@ func TestEval(t *testing.T) {
 		t.Skip("set ANTHROPIC_API_KEY and/or OPENROUTER_API_KEY to run eval")
 	}
 
-	all, err := scenarios.LoadScenarios(scenariosDir)
+	files, err := scenarios.LoadFiles(scenariosDir)
 	if err != nil {
 		t.Fatalf("load scenarios: %v", err)
 	}
-	if len(all) == 0 {
-		t.Fatal("no scenarios found in " + scenariosDir)
-	}
 
 	var results []eval.ScenarioResult
-	passed := 0
-	total := 0
-
 	for _, prov := range providers {
-		prov := prov
-		t.Run(prov.name, func(t *testing.T) {
-			for _, s := range all {
-				s := s
-				total++
-				t.Run(s.Name, func(t *testing.T) {
-					res, err := runScenario(prov, s)
-					name := prov.name + "/" + s.Name
-					if err != nil {
-						results = append(results, eval.ScenarioResult{Name: name, Reason: err.Error()})
-						t.Errorf("run error: %v", err)
-						return
-					}
-					reason := scenarios.CheckAssertions(s, res)
-					sr := eval.ScenarioResult{Name: name, Passed: reason == "", Reason: reason}
-					results = append(results, sr)
-					if reason != "" {
-						t.Errorf("FAIL: %s", reason)
-					} else {
-						passed++
-					}
-				})
+		res := eval.Run(context.Background(), files, eval.Options{LLM: prov.llm, Model: prov.profileModel, Tools: vcrTools})
+		for _, r := range res.Results {
+			r.Name = prov.name + "/" + r.Name
+			if !r.Passed {
+				t.Errorf("FAIL %s: %s", r.Name, r.Reason)
 			}
-		})
+			results = append(results, r)
+		}
 	}
-
+	if len(results) == 0 {
+		t.Fatal("no scenarios found in " + scenariosDir)
+	}
+	passed := 0
+	for _, r := range results {
+		if r.Passed {
+			passed++
+		}
+	}
+	total := len(results)
 	passRate := float64(passed) / float64(total)
 	t.Logf("pass rate: %d/%d (%.0f%%)", passed, total, passRate*100)
 
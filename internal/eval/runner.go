@@ -1,26 +1,179 @@
+// Package eval runs YAML scenarios (see package scenarios) against a live
+// model and reports which pass, with the tokens and cost they took. It backs
+// `opentalon eval`, a regression gate for prompt, model and plugin changes.
 package eval
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/scenarios"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/pkg/toolfqn"
 )
 
 // ScenarioResult is the outcome of running one scenario.
 type ScenarioResult struct {
-	Name   string `json:"name"`
-	Passed bool   `json:"passed"`
-	Reason string `json:"reason,omitempty"`
+	Name         string  `json:"name"`
+	File         string  `json:"file,omitempty"`
+	Passed       bool    `json:"passed"`
+	Reason       string  `json:"reason,omitempty"`
+	LLMCalls     int     `json:"llm_calls,omitempty"`
+	InputTokens  int     `json:"input_tokens,omitempty"`
+	OutputTokens int     `json:"output_tokens,omitempty"`
+	Cost         float64 `json:"cost,omitempty"`
+	DurationMS   int64   `json:"duration_ms,omitempty"`
 }
 
 // EvalResult aggregates all scenario outcomes for one run.
 type EvalResult struct {
-	Tag      string           `json:"tag"`
-	PassRate float64          `json:"pass_rate"`
-	Passed   int              `json:"passed"`
-	Total    int              `json:"total"`
-	Results  []ScenarioResult `json:"results"`
+	Tag          string           `json:"tag"`
+	Model        string           `json:"model,omitempty"`
+	PassRate     float64          `json:"pass_rate"`
+	Passed       int              `json:"passed"`
+	Total        int              `json:"total"`
+	InputTokens  int              `json:"input_tokens,omitempty"`
+	OutputTokens int              `json:"output_tokens,omitempty"`
+	Cost         float64          `json:"cost,omitempty"` // at the models' configured prices
+	Results      []ScenarioResult `json:"results"`
+}
+
+// Options configure Run.
+type Options struct {
+	LLM   orchestrator.LLMClient
+	Opts  orchestrator.OrchestratorOpts // e.g. the deployment's rules and prompt overrides
+	Model string                        // "provider/model" to run on; "" = the LLM's default
+	Tools []scenarios.Tool              // offered in every scenario, next to each file's own
+}
+
+// Run runs every scenario of files, each in a fresh orchestrator and
+// session, and returns the report. A scenario whose turn fails counts as
+// failed with the error as its reason.
+func Run(ctx context.Context, files []scenarios.ScenarioFile, opts Options) EvalResult {
+	res := EvalResult{Model: opts.Model}
+	if opts.Model != "" {
+		ctx = profile.WithProfile(ctx, &profile.Profile{Model: opts.Model})
+	}
+	for _, f := range files {
+		tools := mergeTools(opts.Tools, f.Tools)
+		for _, s := range f.Scenarios {
+			sr := runScenario(ctx, opts, tools, s)
+			sr.File = f.Path
+			res.Results = append(res.Results, sr)
+			res.Total++
+			if sr.Passed {
+				res.Passed++
+			}
+			res.InputTokens += sr.InputTokens
+			res.OutputTokens += sr.OutputTokens
+			res.Cost += sr.Cost
+		}
+	}
+	if res.Total > 0 {
+		res.PassRate = float64(res.Passed) / float64(res.Total)
+	}
+	return res
+}
+
+func runScenario(ctx context.Context, opts Options, tools []scenarios.Tool, s scenarios.Scenario) ScenarioResult {
+	sr := ScenarioResult{Name: s.Name}
+	start := time.Now()
+	defer func() { sr.DurationMS = time.Since(start).Milliseconds() }()
+
+	reg, err := registry(tools)
+	if err != nil {
+		sr.Reason = err.Error()
+		return sr
+	}
+	sessions := state.NewSessionStore("")
+	sessions.Create("eval", "", "", "")
+	orch := orchestrator.NewWithRules(opts.LLM, orchestrator.DefaultParser, reg, state.NewMemoryStore(""), sessions, opts.Opts)
+
+	var out scenarios.RunResult
+	for _, msg := range s.Turns() {
+		r, err := orch.Run(ctx, "eval", msg)
+		if r != nil && r.Usage != nil {
+			out.LLMCalls += r.Usage.LLMCalls
+			sr.InputTokens += r.Usage.InputTokens
+			sr.OutputTokens += r.Usage.OutputTokens
+			sr.Cost += r.Usage.Cost
+		}
+		if err != nil {
+			sr.LLMCalls = out.LLMCalls
+			sr.Reason = err.Error()
+			return sr
+		}
+		out.Response = r.Response
+		for _, tc := range r.ToolCalls {
+			out.ToolCalls = append(out.ToolCalls, scenarios.ToolCallResult{Plugin: tc.Plugin, Action: tc.Action, Args: tc.Args})
+		}
+	}
+	sr.LLMCalls = out.LLMCalls
+	sr.Reason = scenarios.CheckAssertions(s, out)
+	sr.Passed = sr.Reason == ""
+	return sr
+}
+
+// mergeTools returns base with own added; a tool in own replaces the one
+// of the same name in base.
+func mergeTools(base, own []scenarios.Tool) []scenarios.Tool {
+	out := make([]scenarios.Tool, 0, len(base)+len(own))
+	for _, t := range base {
+		if !slices.ContainsFunc(own, func(o scenarios.Tool) bool { return o.Name == t.Name }) {
+			out = append(out, t)
+		}
+	}
+	return append(out, own...)
+}
+
+// registry offers tools to the model, grouped by plugin.
+func registry(tools []scenarios.Tool) (*orchestrator.ToolRegistry, error) {
+	exec := toolExecutor{}
+	var order []string
+	caps := map[string]*orchestrator.PluginCapability{}
+	for _, t := range tools {
+		plugin, action, err := toolfqn.Split(t.Name)
+		if err != nil {
+			return nil, fmt.Errorf("tool %q: %w", t.Name, err)
+		}
+		c := caps[plugin]
+		if c == nil {
+			c = &orchestrator.PluginCapability{Name: plugin, Description: plugin}
+			caps[plugin] = c
+			order = append(order, plugin)
+		}
+		a := orchestrator.Action{Name: action, Description: t.Description}
+		for _, p := range t.Parameters {
+			a.Parameters = append(a.Parameters, orchestrator.Parameter{Name: p})
+		}
+		c.Actions = append(c.Actions, a)
+		exec[plugin+"__"+action] = t
+	}
+	reg := orchestrator.NewToolRegistry()
+	for _, plugin := range order {
+		if err := reg.Register(*caps[plugin], exec); err != nil {
+			return nil, err
+		}
+	}
+	return reg, nil
+}
+
+// toolExecutor answers scenario tools with their canned result.
+type toolExecutor map[string]scenarios.Tool
+
+func (e toolExecutor) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	t := e[call.Plugin+"__"+call.Action]
+	if t.Error != "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: t.Error}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: t.Result}
 }
 
 // LoadBaseline reads a previously saved EvalResult from path.
@@ -0,0 +1,98 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/scenarios"
+)
+
+// answeringLLM calls weather__now once per turn, then answers with what the
+// tool returned, at a fixed price per call.
+type answeringLLM struct{}
+
+func (answeringLLM) Complete(_ context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	usage := provider.Usage{InputTokens: 100, OutputTokens: 10, Cost: 0.001}
+	last := req.Messages[len(req.Messages)-1].Content
+	if out, ok := strings.CutPrefix(last, "[plugin_output]\n"); ok {
+		return &provider.CompletionResponse{Content: "Forecast: " + strings.TrimSuffix(out, "\n[/plugin_output]"), Usage: usage}, nil
+	}
+	return &provider.CompletionResponse{Content: "[tool_call] weather__now(city=Paris)", Usage: usage}, nil
+}
+
+const suite = `
+tools:
+  - name: weather__now
+    description: Current weather in a city
+    parameters: [city]
+    result: sunny, 21C
+scenarios:
+  - name: tool and reply
+    input: weather in Paris?
+    max_iterations: 2
+    assert:
+      tools_called: [weather__now]
+      arg_equals: {city: Paris}
+      tool_called: weather__now
+      response_matches: '\d+C'
+  - name: two turns
+    messages: ["weather?", "and tomorrow?"]
+    assert:
+      tools_called: [weather__now, weather.now]
+  - name: over budget
+    messages: ["weather?", "and tomorrow?"]
+    max_iterations: 3
+    assert:
+      response_not_empty: true
+  - name: forbidden tool
+    input: weather?
+    assert:
+      tools_not_called: [weather__now]
+      response_not_contains: [rain]
+`
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weather.yaml")
+	if err := os.WriteFile(path, []byte(suite), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files, err := scenarios.LoadFiles(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := Run(context.Background(), files, Options{LLM: answeringLLM{}})
+
+	want := map[string]string{
+		"tool and reply": "",
+		"two turns":      "",
+		"over budget":    "took 4 LLM calls, budget 3",
+		"forbidden tool": "weather__now called",
+	}
+	for _, r := range res.Results {
+		if r.Reason != want[r.Name] || r.Passed != (want[r.Name] == "") {
+			t.Errorf("%s: passed=%v reason=%q, want %q", r.Name, r.Passed, r.Reason, want[r.Name])
+		}
+		if r.File != path {
+			t.Errorf("%s: file = %q", r.Name, r.File)
+		}
+	}
+	if res.Total != 4 || res.Passed != 2 || res.PassRate != 0.5 {
+		t.Errorf("totals = %d/%d (%v)", res.Passed, res.Total, res.PassRate)
+	}
+	// 2 + 4 + 4 + 2 LLM calls.
+	if res.InputTokens != 1200 || res.OutputTokens != 120 || res.Cost < 0.0119 || res.Cost > 0.0121 {
+		t.Errorf("usage = %d in, %d out, $%v", res.InputTokens, res.OutputTokens, res.Cost)
+	}
+}
+
+func TestLoadFilesRejectsBadTools(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.yaml")
+	_ = os.WriteFile(path, []byte("tools:\n  - name: weather\nscenarios: []\n"), 0o600)
+	if _, err := scenarios.LoadFiles(path); err == nil {
+		t.Error("a tool without an action was accepted")
+	}
+}
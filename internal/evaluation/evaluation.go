@@ -0,0 +1,251 @@
+// Package evaluation scores completed sessions with an LLM judge. When a
+// session completes (see package sessionidle) the judge reads its transcript
+// and grades it against a rubric; the scores are stored per session with the
+// model that served it, its experiment variant and the built-in prompt hash,
+// so averages can be compared before and after a prompt or model change, or
+// between the arms of an experiment.
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// Criterion is one rubric entry the judge scores from 1 to 5.
+type Criterion struct {
+	Name        string
+	Description string
+}
+
+// DefaultRubric is used when the config names no criteria.
+var DefaultRubric = []Criterion{
+	{Name: "helpfulness", Description: "Did the replies address what the user actually asked, clearly and without padding?"},
+	{Name: "tool_correctness", Description: "Were the right tools called with the right arguments, and were their results used correctly?"},
+	{Name: "resolution", Description: "Was the user's request resolved by the end of the conversation?"},
+}
+
+// Score is one criterion's grade for one session.
+type Score struct {
+	SessionID  string    `json:"session_id"`
+	Criterion  string    `json:"criterion"`
+	Score      float64   `json:"score"` // 1 to 5
+	Reason     string    `json:"reason,omitempty"`
+	ModelID    string    `json:"model_id,omitempty"` // model that served the session
+	Variant    string    `json:"variant,omitempty"`  // experiment variant that served the session
+	JudgeModel string    `json:"judge_model,omitempty"`
+	PromptHash string    `json:"prompt_hash,omitempty"` // prompts.Hash() when the session was judged
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Summary is the average of one criterion over the sessions one model (and
+// experiment variant) served under one prompt hash.
+type Summary struct {
+	ModelID    string  `json:"model_id"`
+	Variant    string  `json:"variant,omitempty"`
+	PromptHash string  `json:"prompt_hash"`
+	Criterion  string  `json:"criterion"`
+	Sessions   int     `json:"sessions"`
+	Average    float64 `json:"average"`
+}
+
+// ScoreStore persists scores.
+type ScoreStore interface {
+	RecordScores(ctx context.Context, scores []Score) error
+	SessionScores(ctx context.Context, sessionID string) ([]Score, error)
+	Summarize(ctx context.Context, since time.Time) ([]Summary, error)
+}
+
+// SessionReader loads the session to judge.
+type SessionReader interface {
+	Get(id string) (*state.Session, error)
+}
+
+// Options configures a Judge.
+type Options struct {
+	LLM        orchestrator.LLMClient
+	JudgeModel string        // recorded with each score; the LLM client picks the model
+	Rubric     []Criterion   // empty = DefaultRubric
+	SampleRate float64       // fraction of sessions judged, by session id hash; <= 0 or >= 1 = all
+	Sessions   SessionReader // required
+	Store      ScoreStore    // required
+	// ModelFor names the model that served sess; nil records no model.
+	ModelFor func(sess *state.Session) string
+	// Observe, when set, is called for each recorded score (metrics).
+	Observe func(Score)
+}
+
+// Judge scores sessions.
+type Judge struct {
+	opts Options
+}
+
+// New returns a Judge.
+func New(opts Options) *Judge {
+	if len(opts.Rubric) == 0 {
+		opts.Rubric = DefaultRubric
+	}
+	return &Judge{opts: opts}
+}
+
+// maxToolResultChars caps each tool result in the transcript the judge
+// reads; whether the result was used matters more than its full text.
+const maxToolResultChars = 500
+
+// Evaluate judges the session and stores its scores. Sessions outside the
+// sample and sessions without an assistant reply are skipped.
+func (j *Judge) Evaluate(ctx context.Context, sessionID string) ([]Score, error) {
+	if !j.sampled(sessionID) {
+		return nil, nil
+	}
+	sess, err := j.opts.Sessions.Get(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("evaluation: load session: %w", err)
+	}
+	transcript, ok := renderTranscript(sess)
+	if !ok {
+		return nil, nil
+	}
+	resp, err := j.opts.LLM.Complete(ctx, &provider.CompletionRequest{
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: j.systemPrompt()},
+			{Role: provider.RoleUser, Content: transcript},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("evaluation: judge call: %w", err)
+	}
+	scores, err := j.parse(resp.Content)
+	if err != nil {
+		return nil, err
+	}
+	var model string
+	if j.opts.ModelFor != nil {
+		model = j.opts.ModelFor(sess)
+	}
+	now := time.Now().UTC()
+	hash := prompts.Hash()
+	for i := range scores {
+		scores[i].SessionID = sessionID
+		scores[i].ModelID = model
+		scores[i].Variant = sess.Metadata[orchestrator.MetaVariant]
+		scores[i].JudgeModel = j.opts.JudgeModel
+		scores[i].PromptHash = hash
+		scores[i].CreatedAt = now
+	}
+	if err := j.opts.Store.RecordScores(ctx, scores); err != nil {
+		return nil, err
+	}
+	if j.opts.Observe != nil {
+		for _, s := range scores {
+			j.opts.Observe(s)
+		}
+	}
+	return scores, nil
+}
+
+func (j *Judge) sampled(sessionID string) bool {
+	rate := j.opts.SampleRate
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sessionID))
+	return float64(h.Sum32()%10000) < rate*10000
+}
+
+func (j *Judge) systemPrompt() string {
+	var b strings.Builder
+	b.WriteString(prompts.EvaluationJudge)
+	b.WriteString("\n\nCriteria:\n")
+	for _, c := range j.opts.Rubric {
+		fmt.Fprintf(&b, "- %s: %s\n", c.Name, c.Description)
+	}
+	return b.String()
+}
+
+// parse reads the judge's JSON, accepting a Markdown fence around it. Every
+// rubric criterion must be scored 1 to 5; extra criteria are dropped.
+func (j *Judge) parse(content string) ([]Score, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	var out struct {
+		Scores []struct {
+			Criterion string  `json:"criterion"`
+			Score     float64 `json:"score"`
+			Reason    string  `json:"reason"`
+		} `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &out); err != nil {
+		return nil, fmt.Errorf("evaluation: judge reply is not JSON: %w", err)
+	}
+	byName := make(map[string]Score, len(out.Scores))
+	for _, s := range out.Scores {
+		byName[s.Criterion] = Score{Criterion: s.Criterion, Score: s.Score, Reason: s.Reason}
+	}
+	scores := make([]Score, 0, len(j.opts.Rubric))
+	var errs []error
+	for _, c := range j.opts.Rubric {
+		s, ok := byName[c.Name]
+		switch {
+		case !ok:
+			errs = append(errs, fmt.Errorf("criterion %q not scored", c.Name))
+		case s.Score < 1 || s.Score > 5:
+			errs = append(errs, fmt.Errorf("criterion %q scored %v, want 1-5", c.Name, s.Score))
+		default:
+			scores = append(scores, s)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("evaluation: %w", errors.Join(errs...))
+	}
+	return scores, nil
+}
+
+// renderTranscript renders sess for the judge. It reports false when the session
+// has no assistant reply, so there is nothing to grade.
+func renderTranscript(sess *state.Session) (string, bool) {
+	var b strings.Builder
+	if sess.Summary != "" {
+		fmt.Fprintf(&b, "Summary of earlier messages: %s\n\n", sess.Summary)
+	}
+	replied := false
+	for _, m := range sess.Messages {
+		switch m.Role {
+		case provider.RoleUser:
+			fmt.Fprintf(&b, "user: %s\n", m.Content)
+		case provider.RoleAssistant:
+			if m.Content != "" {
+				replied = true
+				fmt.Fprintf(&b, "assistant: %s\n", m.Content)
+			}
+			for _, tc := range m.ToolCalls {
+				args, _ := json.Marshal(tc.Arguments)
+				fmt.Fprintf(&b, "assistant called %s %s\n", tc.Name, args)
+			}
+		case provider.RoleTool:
+			content := m.Content
+			if len(content) > maxToolResultChars {
+				cut := maxToolResultChars
+				for cut > 0 && !utf8.RuneStart(content[cut]) {
+					cut--
+				}
+				content = content[:cut] + "…"
+			}
+			fmt.Fprintf(&b, "tool result: %s\n", content)
+		}
+	}
+	return b.String(), replied
+}
@@ -0,0 +1,172 @@
+package evaluation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+type judgeLLM struct {
+	reply string
+	reqs  []*provider.CompletionRequest
+}
+
+func (l *judgeLLM) Complete(_ context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	l.reqs = append(l.reqs, req)
+	return &provider.CompletionResponse{Content: l.reply}, nil
+}
+
+// memStore is an in-memory ScoreStore.
+type memStore struct{ scores []Score }
+
+func (m *memStore) RecordScores(_ context.Context, scores []Score) error {
+	m.scores = append(m.scores, scores...)
+	return nil
+}
+
+func (m *memStore) SessionScores(_ context.Context, id string) ([]Score, error) {
+	var out []Score
+	for _, s := range m.scores {
+		if s.SessionID == id {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *memStore) Summarize(context.Context, time.Time) ([]Summary, error) {
+	return []Summary{{ModelID: "gpt-4o", Criterion: "helpfulness", Sessions: 1, Average: 4}}, nil
+}
+
+func newSession(t *testing.T, msgs ...provider.Message) *state.SessionStore {
+	t.Helper()
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	for _, m := range msgs {
+		if err := sessions.AddMessage("web:c1", m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return sessions
+}
+
+func TestEvaluateScoresAndStores(t *testing.T) {
+	sessions := newSession(t,
+		provider.Message{Role: provider.RoleUser, Content: "open issues?"},
+		provider.Message{Role: provider.RoleAssistant, ToolCalls: []provider.ToolCall{{Name: "gitlab__list_issues", Arguments: map[string]string{"state": "open"}}}},
+		provider.Message{Role: provider.RoleTool, Content: strings.Repeat("é", 400)},
+		provider.Message{Role: provider.RoleAssistant, Content: "There are 3 open issues."},
+	)
+	if err := sessions.SetMetadata("web:c1", orchestrator.MetaVariant, "terse"); err != nil {
+		t.Fatal(err)
+	}
+	llm := &judgeLLM{reply: "```json\n" + `{"scores": [
+		{"criterion": "helpfulness", "score": 4, "reason": "direct"},
+		{"criterion": "tool_correctness", "score": 5, "reason": "right tool"},
+		{"criterion": "resolution", "score": 3, "reason": "no links"},
+		{"criterion": "tone", "score": 1}
+	]}` + "\n```"}
+	store := &memStore{}
+	var observed int
+	j := New(Options{
+		LLM: llm, JudgeModel: "gpt-4o-mini", Sessions: sessions, Store: store,
+		ModelFor: func(*state.Session) string { return "gpt-4o" },
+		Observe:  func(Score) { observed++ },
+	})
+
+	scores, err := j.Evaluate(context.Background(), "web:c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 3 || len(store.scores) != 3 || observed != 3 {
+		t.Fatalf("scores = %+v, stored %d, observed %d", scores, len(store.scores), observed)
+	}
+	s := store.scores[2]
+	if s.Criterion != "resolution" || s.Score != 3 || s.ModelID != "gpt-4o" || s.Variant != "terse" || s.JudgeModel != "gpt-4o-mini" || s.PromptHash == "" || s.SessionID != "web:c1" {
+		t.Errorf("stored score = %+v", s)
+	}
+
+	sys, transcript := llm.reqs[0].Messages[0].Content, llm.reqs[0].Messages[1].Content
+	if !strings.Contains(sys, "- tool_correctness: ") {
+		t.Errorf("rubric missing from the system prompt:\n%s", sys)
+	}
+	if !strings.Contains(transcript, `assistant called gitlab__list_issues {"state":"open"}`) || !strings.Contains(transcript, "assistant: There are 3 open issues.") {
+		t.Errorf("transcript:\n%s", transcript)
+	}
+	if !strings.Contains(transcript, "…") || strings.Count(transcript, "é") > maxToolResultChars/2 {
+		t.Error("long tool result was not truncated")
+	}
+}
+
+func TestEvaluateRejectsIncompleteVerdict(t *testing.T) {
+	sessions := newSession(t,
+		provider.Message{Role: provider.RoleUser, Content: "hi"},
+		provider.Message{Role: provider.RoleAssistant, Content: "hello"},
+	)
+	store := &memStore{}
+	j := New(Options{
+		LLM:      &judgeLLM{reply: `{"scores": [{"criterion": "helpfulness", "score": 9}]}`},
+		Rubric:   []Criterion{{Name: "helpfulness"}, {Name: "resolution"}},
+		Sessions: sessions, Store: store,
+	})
+	_, err := j.Evaluate(context.Background(), "web:c1")
+	if err == nil || !strings.Contains(err.Error(), `"helpfulness" scored 9`) || !strings.Contains(err.Error(), `"resolution" not scored`) {
+		t.Errorf("err = %v", err)
+	}
+	if len(store.scores) != 0 {
+		t.Error("a rejected verdict must not be stored")
+	}
+}
+
+func TestEvaluateSkips(t *testing.T) {
+	llm := &judgeLLM{}
+	j := New(Options{LLM: llm, Sessions: newSession(t, provider.Message{Role: provider.RoleUser, Content: "hi"}), Store: &memStore{}})
+	if scores, err := j.Evaluate(context.Background(), "web:c1"); err != nil || scores != nil {
+		t.Errorf("unanswered session: %v, %v", scores, err)
+	}
+
+	sampled := 0
+	j = New(Options{SampleRate: 0.25})
+	for i := range 1000 {
+		if j.sampled("web:" + string(rune('a'+i%26)) + strings.Repeat("x", i)) {
+			sampled++
+		}
+	}
+	if sampled < 150 || sampled > 350 {
+		t.Errorf("sampled %d of 1000 at rate 0.25", sampled)
+	}
+	if len(llm.reqs) != 0 {
+		t.Error("judge called for a skipped session")
+	}
+}
+
+func TestTool(t *testing.T) {
+	store := &memStore{scores: []Score{{SessionID: "web:c1", Criterion: "helpfulness", Score: 4}}}
+	tool := NewTool(store, []string{"admins"})
+	if got := tool.Capability().AllowedGroups; len(got) != 1 || got[0] != "admins" {
+		t.Errorf("allowed groups = %v", got)
+	}
+
+	ctx := actor.WithSessionID(context.Background(), "web:c1")
+	res := tool.Execute(ctx, orchestrator.ToolCall{ID: "1", Action: "session_scores"})
+	if res.Error != "" || !strings.Contains(res.Content, `"criterion":"helpfulness"`) {
+		t.Errorf("session_scores = %+v", res)
+	}
+	res = tool.Execute(ctx, orchestrator.ToolCall{ID: "2", Action: "session_scores", Args: map[string]string{"session_id": "web:other"}})
+	if res.Content != "This session has not been scored." {
+		t.Errorf("unscored session = %+v", res)
+	}
+	res = tool.Execute(ctx, orchestrator.ToolCall{ID: "3", Action: "score_summary", Args: map[string]string{"since": "24h"}})
+	if res.Error != "" || !strings.Contains(res.Content, `"average":4`) {
+		t.Errorf("score_summary = %+v", res)
+	}
+	if res = tool.Execute(ctx, orchestrator.ToolCall{ID: "4", Action: "score_summary", Args: map[string]string{"since": "7d"}}); res.Error == "" {
+		t.Error("expected error for a non-Go duration")
+	}
+}
@@ -0,0 +1,103 @@
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+const ToolName = "evaluation"
+
+// defaultSummaryWindow is how far back score_summary looks without since.
+const defaultSummaryWindow = 7 * 24 * time.Hour
+
+// Tool exposes stored scores as a built-in tool so an operator can ask how
+// sessions were graded and compare models, prompt revisions and experiment
+// variants.
+type Tool struct {
+	store         ScoreStore
+	allowedGroups []string
+}
+
+// NewTool returns the tool. allowedGroups restricts it to those profile
+// groups; empty leaves it visible to everyone.
+func NewTool(store ScoreStore, allowedGroups []string) *Tool {
+	return &Tool{store: store, allowedGroups: allowedGroups}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   "Read LLM-judge scores (1-5 per rubric criterion) of completed conversations.",
+		AllowedGroups: t.allowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name:        "session_scores",
+				Description: "Scores of one conversation, with the judge's reason per criterion.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "session_id", Description: "Session id; defaults to the current conversation", Required: false},
+				},
+			},
+			{
+				Name:        "score_summary",
+				Description: "Average score per criterion for each model, experiment variant and prompt revision, to compare them after a change or between variants.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "since", Description: "Go duration to look back, e.g. 24h or 720h (default 168h)", Required: false},
+				},
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	switch call.Action {
+	case "session_scores":
+		id := strings.TrimSpace(call.Args["session_id"])
+		if id == "" {
+			id = actor.SessionID(ctx)
+		}
+		if id == "" {
+			return orchestrator.ToolResult{CallID: call.ID, Error: "session_id is required"}
+		}
+		scores, err := t.store.SessionScores(ctx, id)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		if len(scores) == 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Content: "This session has not been scored."}
+		}
+		return jsonResult(call.ID, scores)
+	case "score_summary":
+		window := defaultSummaryWindow
+		if s := strings.TrimSpace(call.Args["since"]); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid since %q: want a Go duration such as 168h", s)}
+			}
+			window = d
+		}
+		sum, err := t.store.Summarize(ctx, time.Now().Add(-window))
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		if len(sum) == 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Content: "No sessions were scored in that period."}
+		}
+		return jsonResult(call.ID, sum)
+	default:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown evaluation action: %s", call.Action)}
+	}
+}
+
+func jsonResult(callID string, v any) orchestrator.ToolResult {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: callID, Error: fmt.Sprintf("marshaling scores: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: callID, Content: string(data)}
+}
@@ -0,0 +1,214 @@
+// Package eventbus is the in-process bus for lifecycle events: a session was
+// created, a message arrived, a tool ran, a scheduler job fired, the LLM
+// provider failed over. Extensions that react to what the agent does (a
+// score plugin grading finished conversations, a Lua script counting tool
+// calls) subscribe here instead of being wired into each subsystem.
+//
+// It is separate from the session_events log (package emit): that log is
+// the persisted, schema-versioned audit trail; these events are
+// fire-and-forget notifications for in-process subscribers.
+//
+// Publish never blocks. Events are queued and handed to subscribers by a
+// single worker, in publish order; a full queue drops the event with a
+// throttled warning. Events published from inside a subscriber (a plugin
+// action run for an event executes tools, which would publish
+// tool_executed) are dropped, so subscribers cannot trigger themselves.
+package eventbus
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Event types.
+const (
+	SessionCreated    = "session_created"    // data: entity_id, group_id, kind
+	MessageReceived   = "message_received"   // data: channel, content
+	ToolExecuted      = "tool_executed"      // data: plugin, action, status ("ok" | "error"), error, duration_ms
+	SessionCompleted  = "session_completed"  // the session went idle; data: idle_for
+	SessionClosed     = "session_closed"     // an idle session was auto-closed; data: idle_for
+	JobRun            = "job_run"            // data: job, action, status, error
+	ProviderFailover  = "provider_failover"  // data: from, to, error
+	ProviderExhausted = "provider_exhausted" // every endpoint failed a request; data: attempted (comma-separated), error
+	ProviderHealth    = "provider_health"    // the preferred endpoint tripped or recovered; data: endpoint, healthy ("true" | "false"), error
+)
+
+// All matches every event type in Subscribe.
+const All = "*"
+
+// Types lists the known event types, for validating subscriptions.
+var Types = []string{SessionCreated, MessageReceived, ToolExecuted, SessionCompleted, SessionClosed, JobRun, ProviderFailover, ProviderExhausted, ProviderHealth}
+
+// Known reports whether eventType is a known event type or All.
+func Known(eventType string) bool {
+	return eventType == All || slices.Contains(Types, eventType)
+}
+
+const (
+	// DefaultBufferSize is the queue length used when New gets size <= 0.
+	DefaultBufferSize = 1000
+	// handlerTimeout bounds one subscriber call so a hung plugin cannot
+	// stall delivery to the others.
+	handlerTimeout = 30 * time.Second
+	dropWarnEvery  = time.Minute
+)
+
+// Event is one lifecycle event.
+type Event struct {
+	Type      string            `json:"type"`
+	Time      time.Time         `json:"time"`
+	SessionID string            `json:"session_id,omitempty"`
+	Data      map[string]string `json:"data,omitempty"`
+}
+
+// Handler receives events. A returned error is logged.
+type Handler func(ctx context.Context, e Event) error
+
+type subscription struct {
+	name    string
+	handler Handler
+}
+
+// Bus fans events out to subscribers. A nil *Bus is valid and discards
+// every event, so producers need no nil checks.
+type Bus struct {
+	mu     sync.RWMutex
+	subs   map[string][]subscription // by event type; All for every type
+	closed bool
+
+	queue    chan Event
+	dropped  atomic.Int64
+	lastWarn atomic.Int64 // unix nanos of the last drop warning
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New returns a running bus with a queue of size events. Close stops it.
+func New(size int) *Bus {
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &Bus{
+		subs:   make(map[string][]subscription),
+		queue:  make(chan Event, size),
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Subscribe registers h for eventType (or All). name identifies the
+// subscriber in logs.
+func (b *Bus) Subscribe(eventType, name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[eventType] = append(b.subs[eventType], subscription{name: name, handler: h})
+}
+
+// Subscribed reports whether anything listens for eventType, so a producer
+// can skip work that only serves the event.
+func (b *Bus) Subscribed(eventType string) bool {
+	if b == nil {
+		return false
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.subscribedLocked(eventType)
+}
+
+func (b *Bus) subscribedLocked(eventType string) bool {
+	return len(b.subs[eventType]) > 0 || len(b.subs[All]) > 0
+}
+
+// Publish queues e for its subscribers. Time defaults to now.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if b == nil || inHandler(ctx) {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed || !b.subscribedLocked(e.Type) {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	select {
+	case b.queue <- e:
+	default:
+		n := b.dropped.Add(1)
+		now := time.Now().UnixNano()
+		if last := b.lastWarn.Load(); now-last >= int64(dropWarnEvery) && b.lastWarn.CompareAndSwap(last, now) {
+			slog.Warn("event bus full, dropping events", "component", "events", "event", e.Type, "dropped_total", n)
+		}
+	}
+}
+
+// Close stops delivery after the events already queued are handed out or
+// ctx ends, whichever comes first. Later events are discarded.
+func (b *Bus) Close(ctx context.Context) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	b.mu.Unlock()
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		b.cancel()
+		<-b.done
+	}
+}
+
+func (b *Bus) run() {
+	defer close(b.done)
+	for e := range b.queue {
+		if b.ctx.Err() != nil {
+			continue
+		}
+		b.mu.RLock()
+		subs := append(slices.Clone(b.subs[e.Type]), b.subs[All]...)
+		b.mu.RUnlock()
+		for _, s := range subs {
+			b.deliver(s, e)
+		}
+	}
+}
+
+func (b *Bus) deliver(s subscription, e Event) {
+	ctx, cancel := context.WithTimeout(withHandler(b.ctx), handlerTimeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("event subscriber panicked", "component", "events", "subscriber", s.name, "event", e.Type, "panic", r)
+		}
+	}()
+	if err := s.handler(ctx, e); err != nil {
+		slog.Warn("event subscriber failed", "component", "events", "subscriber", s.name, "event", e.Type, "error", err)
+	}
+}
+
+type handlerKey struct{}
+
+func withHandler(ctx context.Context) context.Context {
+	return context.WithValue(ctx, handlerKey{}, true)
+}
+
+func inHandler(ctx context.Context) bool {
+	return ctx != nil && ctx.Value(handlerKey{}) != nil
+}
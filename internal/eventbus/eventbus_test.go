@@ -0,0 +1,116 @@
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (r *recorder) handle(_ context.Context, e Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+func (r *recorder) types() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]string, len(r.events))
+	for i, e := range r.events {
+		out[i] = e.Type
+	}
+	return out
+}
+
+func closeBus(t *testing.T, b *Bus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	b.Close(ctx)
+}
+
+func TestPublishDeliversInOrder(t *testing.T) {
+	b := New(0)
+	tools, all := &recorder{}, &recorder{}
+	b.Subscribe(ToolExecuted, "tools", tools.handle)
+	b.Subscribe(All, "all", all.handle)
+	b.Subscribe(JobRun, "failing", func(context.Context, Event) error { return errors.New("boom") })
+
+	ctx := context.Background()
+	b.Publish(ctx, Event{Type: SessionCreated, SessionID: "s1"})
+	b.Publish(ctx, Event{Type: ToolExecuted, SessionID: "s1", Data: map[string]string{"plugin": "p"}})
+	b.Publish(ctx, Event{Type: JobRun})
+	closeBus(t, b)
+
+	if got := tools.types(); len(got) != 1 || got[0] != ToolExecuted {
+		t.Errorf("tool subscriber got %v", got)
+	}
+	if got := all.types(); len(got) != 3 || got[0] != SessionCreated || got[2] != JobRun {
+		t.Errorf("wildcard subscriber got %v", got)
+	}
+	if tools.events[0].Time.IsZero() || tools.events[0].Data["plugin"] != "p" {
+		t.Errorf("event = %+v", tools.events[0])
+	}
+
+	b.Publish(ctx, Event{Type: JobRun}) // after Close: discarded, no panic
+}
+
+func TestPublishFromHandlerIsDropped(t *testing.T) {
+	b := New(0)
+	rec := &recorder{}
+	b.Subscribe(All, "rec", rec.handle)
+	b.Subscribe(SessionCreated, "loop", func(ctx context.Context, e Event) error {
+		b.Publish(ctx, Event{Type: ToolExecuted})
+		return nil
+	})
+	b.Publish(context.Background(), Event{Type: SessionCreated})
+	closeBus(t, b)
+	if got := rec.types(); len(got) != 1 {
+		t.Errorf("events = %v; a subscriber's own events must not be published", got)
+	}
+}
+
+func TestFullQueueDrops(t *testing.T) {
+	b := New(1)
+	release := make(chan struct{})
+	b.Subscribe(JobRun, "slow", func(context.Context, Event) error {
+		<-release
+		return nil
+	})
+	for range 5 {
+		b.Publish(context.Background(), Event{Type: JobRun})
+	}
+	close(release)
+	closeBus(t, b)
+	if n := b.dropped.Load(); n < 3 {
+		t.Errorf("dropped = %d, want the overflow dropped", n)
+	}
+}
+
+func TestNilBus(t *testing.T) {
+	var b *Bus
+	b.Publish(context.Background(), Event{Type: JobRun})
+	if b.Subscribed(JobRun) {
+		t.Error("a nil bus has no subscribers")
+	}
+	b.Close(context.Background())
+}
+
+func TestKnown(t *testing.T) {
+	for _, typ := range append(Types, All) {
+		if !Known(typ) {
+			t.Errorf("%s should be known", typ)
+		}
+	}
+	if Known("session_started") {
+		t.Error("unknown type accepted")
+	}
+}
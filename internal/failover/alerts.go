@@ -0,0 +1,106 @@
+package failover
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/eventbus"
+)
+
+// DefaultAlertInterval is how long Alerts stays quiet about the same
+// endpoint and kind of event after posting about it, when NewAlerts gets
+// minInterval <= 0.
+const DefaultAlertInterval = 5 * time.Minute
+
+// Sender delivers a message to a conversation on a channel. The scheduler's
+// channel notifier satisfies it.
+type Sender interface {
+	Notify(ctx context.Context, channelID, conversationID, content string) error
+}
+
+// Alerts tells operators about LLM provider trouble: it turns the
+// provider_failover, provider_exhausted and provider_health events into
+// messages in one ops conversation. Repeats of the same alert within the
+// minimum interval are dropped, so a flapping endpoint or a burst of failing
+// requests during an outage posts once, not once per request.
+type Alerts struct {
+	sender       Sender
+	channel      string
+	conversation string
+	minInterval  time.Duration
+
+	mu   sync.Mutex
+	last map[string]time.Time // alert key -> when it was last posted
+	now  func() time.Time
+}
+
+// NewAlerts returns Alerts posting to conversation on channel.
+func NewAlerts(sender Sender, channel, conversation string, minInterval time.Duration) *Alerts {
+	if minInterval <= 0 {
+		minInterval = DefaultAlertInterval
+	}
+	return &Alerts{
+		sender:       sender,
+		channel:      channel,
+		conversation: conversation,
+		minInterval:  minInterval,
+		last:         make(map[string]time.Time),
+		now:          time.Now,
+	}
+}
+
+// Attach subscribes a to the provider events on bus.
+func (a *Alerts) Attach(bus *eventbus.Bus) {
+	if bus == nil {
+		return
+	}
+	for _, typ := range []string{eventbus.ProviderFailover, eventbus.ProviderExhausted, eventbus.ProviderHealth} {
+		bus.Subscribe(typ, "ops-alerts", a.handle)
+	}
+}
+
+func (a *Alerts) handle(ctx context.Context, e eventbus.Event) error {
+	key, msg := alertFor(e)
+	if msg == "" || !a.due(key) {
+		return nil
+	}
+	if err := a.sender.Notify(ctx, a.channel, a.conversation, msg); err != nil {
+		return fmt.Errorf("posting provider alert to %s/%s: %w", a.channel, a.conversation, err)
+	}
+	return nil
+}
+
+// due reports whether the alert under key may be posted now and, if so,
+// starts its quiet period.
+func (a *Alerts) due(key string) bool {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if last, ok := a.last[key]; ok && now.Sub(last) < a.minInterval {
+		return false
+	}
+	a.last[key] = now
+	return true
+}
+
+// alertFor renders e; key identifies the alert for throttling. An event it
+// does not know renders empty.
+func alertFor(e eventbus.Event) (key, msg string) {
+	d := e.Data
+	switch e.Type {
+	case eventbus.ProviderFailover:
+		return "failover:" + d["from"], fmt.Sprintf("LLM failover: %s failed, switched to %s. Error: %s", d["from"], d["to"], d["error"])
+	case eventbus.ProviderExhausted:
+		tried := strings.ReplaceAll(d["attempted"], ",", ", ")
+		return "exhausted:" + d["attempted"], fmt.Sprintf("LLM providers exhausted: every endpoint failed (tried %s); requests are failing. Error: %s", tried, d["error"])
+	case eventbus.ProviderHealth:
+		if d["healthy"] == "true" {
+			return "recovered:" + d["endpoint"], fmt.Sprintf("LLM endpoint %s is healthy again; traffic is switching back to it.", d["endpoint"])
+		}
+		return "unhealthy:" + d["endpoint"], fmt.Sprintf("LLM endpoint %s is unhealthy; traffic goes to the fallbacks until it recovers. Error: %s", d["endpoint"], d["error"])
+	}
+	return "", ""
+}
@@ -0,0 +1,58 @@
+package failover
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/eventbus"
+)
+
+type fakeSender struct{ sent []string }
+
+func (f *fakeSender) Notify(_ context.Context, channelID, conversationID, content string) error {
+	f.sent = append(f.sent, channelID+"/"+conversationID+": "+content)
+	return nil
+}
+
+func TestAlerts_PostsAndThrottles(t *testing.T) {
+	sender := &fakeSender{}
+	a := NewAlerts(sender, "slack", "C0OPS", time.Minute)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	ctx := context.Background()
+	failover := eventbus.Event{Type: eventbus.ProviderFailover, Data: map[string]string{"from": "openai/gpt-4o", "to": "azure/gpt-4o", "error": "429 rate limited"}}
+
+	_ = a.handle(ctx, failover)
+	_ = a.handle(ctx, failover)
+	if len(sender.sent) != 1 {
+		t.Fatalf("repeat within the interval was posted: %q", sender.sent)
+	}
+	if got := sender.sent[0]; !strings.HasPrefix(got, "slack/C0OPS: ") || !strings.Contains(got, "openai/gpt-4o") || !strings.Contains(got, "429 rate limited") {
+		t.Errorf("failover alert = %q", got)
+	}
+
+	_ = a.handle(ctx, eventbus.Event{Type: eventbus.ProviderExhausted, Data: map[string]string{"attempted": "openai/gpt-4o,azure/gpt-4o", "error": "503"}})
+	_ = a.handle(ctx, eventbus.Event{Type: eventbus.ProviderHealth, Data: map[string]string{"endpoint": "openai/gpt-4o", "healthy": "false", "error": "timeout"}})
+	_ = a.handle(ctx, eventbus.Event{Type: eventbus.ProviderHealth, Data: map[string]string{"endpoint": "openai/gpt-4o", "healthy": "true"}})
+	if len(sender.sent) != 4 {
+		t.Fatalf("different alerts must not throttle each other: %q", sender.sent)
+	}
+	if got := sender.sent[1]; !strings.Contains(got, "exhausted") || !strings.Contains(got, "openai/gpt-4o, azure/gpt-4o") {
+		t.Errorf("exhausted alert = %q", got)
+	}
+	if !strings.Contains(sender.sent[2], "unhealthy") || !strings.Contains(sender.sent[3], "healthy again") {
+		t.Errorf("health alerts = %q", sender.sent[2:])
+	}
+
+	now = now.Add(2 * time.Minute)
+	_ = a.handle(ctx, failover)
+	if len(sender.sent) != 5 {
+		t.Errorf("alert not posted again after the interval: %q", sender.sent)
+	}
+	_ = a.handle(ctx, eventbus.Event{Type: eventbus.JobRun})
+	if len(sender.sent) != 5 {
+		t.Errorf("unrelated event was posted: %q", sender.sent)
+	}
+}
@@ -0,0 +1,57 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+const ToolName = "provider"
+
+// Tool is the built-in provider tool: provider.status reports each LLM
+// endpoint behind the failover wrapper, so an operator can ask in chat
+// which endpoint is serving, which is cooling down and why.
+type Tool struct {
+	status        provider.StatusReporter
+	allowedGroups []string
+}
+
+// NewTool returns the tool over status. allowedGroups restricts it to those
+// profile groups; empty leaves it visible to everyone.
+func NewTool(status provider.StatusReporter, allowedGroups []string) *Tool {
+	return &Tool{status: status, allowedGroups: allowedGroups}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   "Inspect the LLM providers this assistant runs on.",
+		AllowedGroups: t.allowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name: "status",
+				Description: "Per endpoint (primary, fallbacks, offline model): whether it is healthy or cooling down after a failure and since when, " +
+					"whether it serves new requests, request and failure counts, success rate and the last error.",
+				ReadOnly: true,
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Action != "status" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown provider action: %s", call.Action)}
+	}
+	endpoints := t.status.EndpointStatus()
+	if len(endpoints) == 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "No failover is configured: a single provider serves every request."}
+	}
+	data, err := json.Marshal(endpoints)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling provider status: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
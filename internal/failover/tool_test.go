@@ -0,0 +1,41 @@
+package failover
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+type fakeStatus []provider.EndpointStatus
+
+func (f fakeStatus) EndpointStatus() []provider.EndpointStatus { return f }
+
+func TestTool_Status(t *testing.T) {
+	tool := NewTool(fakeStatus{
+		{Provider: "openai", Model: "gpt-4o", Role: provider.RolePrimary, Requests: 4, Failures: 1, SuccessRate: 0.75, LastError: "429"},
+		{Provider: "azure", Model: "gpt-4o", Role: provider.RoleFallback, Healthy: true, Active: true, SuccessRate: 1},
+	}, []string{"ops"})
+	if got := tool.Capability().AllowedGroups; len(got) != 1 || got[0] != "ops" {
+		t.Errorf("allowed groups = %v", got)
+	}
+
+	res := tool.Execute(context.Background(), orchestrator.ToolCall{ID: "c1", Plugin: ToolName, Action: "status"})
+	var got []provider.EndpointStatus
+	if res.Error != "" || json.Unmarshal([]byte(res.Content), &got) != nil {
+		t.Fatalf("status = %+v", res)
+	}
+	if len(got) != 2 || got[0].LastError != "429" || !got[1].Active || res.CallID != "c1" {
+		t.Errorf("status = %+v", got)
+	}
+
+	if res := NewTool(fakeStatus{}, nil).Execute(context.Background(), orchestrator.ToolCall{Action: "status"}); !strings.Contains(res.Content, "No failover") {
+		t.Errorf("without failover = %+v", res)
+	}
+	if res := tool.Execute(context.Background(), orchestrator.ToolCall{Action: "reset"}); res.Error == "" {
+		t.Error("unknown action accepted")
+	}
+}
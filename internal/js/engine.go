@@ -0,0 +1,22 @@
+package js
+
+import (
+	"context"
+
+	"github.com/opentalon/opentalon/internal/lua"
+)
+
+// RunPrepare calls prepare(text) in the script at scriptPath.
+func RunPrepare(context.Context, string, lua.Options, string) (*lua.PrepareResult, error) {
+	return nil, ErrNotBuilt
+}
+
+// LoadTool reads the plugin object of the tool script at scriptPath.
+func LoadTool(string, lua.Options) (*lua.ToolSpec, error) {
+	return nil, ErrNotBuilt
+}
+
+// RunTool calls execute(action, args) in the tool script at scriptPath.
+func RunTool(context.Context, string, lua.Options, string, map[string]string) (lua.ToolOutput, error) {
+	return lua.ToolOutput{}, ErrNotBuilt
+}
@@ -0,0 +1,21 @@
+package js
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/lua"
+)
+
+func TestStubReportsMissingEngine(t *testing.T) {
+	if _, err := RunPrepare(context.Background(), "x.js", lua.Options{}, "hi"); !errors.Is(err, ErrNotBuilt) {
+		t.Errorf("RunPrepare err = %v", err)
+	}
+	if _, err := LoadTool("x.js", lua.Options{}); !errors.Is(err, ErrNotBuilt) {
+		t.Errorf("LoadTool err = %v", err)
+	}
+	if _, err := RunTool(context.Background(), "x.js", lua.Options{}, "a", nil); !errors.Is(err, ErrNotBuilt) {
+		t.Errorf("RunTool err = %v", err)
+	}
+}
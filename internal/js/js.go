@@ -0,0 +1,15 @@
+// Package js is the entry point for JavaScript content preparers and tool
+// plugins, the counterpart of package lua for users who write automations in
+// JS. Scripts follow the Lua contracts (prepare(text), the plugin table and
+// execute(action, args)) and are meant to run under the same lua.Options
+// limits and http allowlist.
+//
+// No engine ships yet: every call returns ErrNotBuilt, so a config naming
+// js: scripts fails loudly instead of silently skipping them.
+package js
+
+import "errors"
+
+// ErrNotBuilt is returned by every call while no JavaScript engine is
+// compiled in.
+var ErrNotBuilt = errors.New("js: no JavaScript engine in this build")
@@ -0,0 +1,101 @@
+package lua
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// compiledScript is a parsed and compiled script plus the file version it
+// was compiled from.
+type compiledScript struct {
+	modTime time.Time
+	size    int64
+	proto   *lua.FunctionProto
+}
+
+// scriptCache keeps compiled scripts so a call does not re-parse the file.
+// Every lookup stats the file and recompiles it when it changed, so edits
+// take effect on the next call without a restart. If the new version does
+// not compile, the last good one keeps running.
+type scriptCache struct {
+	mu      sync.Mutex
+	scripts map[string]*compiledScript
+}
+
+var scripts = &scriptCache{scripts: map[string]*compiledScript{}}
+
+func (c *scriptCache) load(path string) (*lua.FunctionProto, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := c.scripts[path]
+	if cached != nil && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.proto, nil
+	}
+	proto, err := compileFile(path)
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+		// Remember the broken version so it is reported once, not per call.
+		slog.Warn("lua script reload failed, keeping previous version", "component", "lua", "script", path, "error", err)
+		c.scripts[path] = &compiledScript{modTime: info.ModTime(), size: info.Size(), proto: cached.proto}
+		return cached.proto, nil
+	}
+	if cached != nil {
+		slog.Info("lua script reloaded", "component", "lua", "script", path)
+	}
+	c.scripts[path] = &compiledScript{modTime: info.ModTime(), size: info.Size(), proto: proto}
+	return proto, nil
+}
+
+func compileFile(path string) (*lua.FunctionProto, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	chunk, err := parse.Parse(bufio.NewReader(f), path)
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, path)
+}
+
+// runScript loads the script at scriptPath into a fresh sandbox, runs its
+// top-level code and then call, all under the limits in opts. A limit hit
+// is returned as ErrTimeout, ErrInstructionLimit or ErrMemoryLimit.
+func runScript(ctx context.Context, scriptPath string, opts Options, withHTTP bool, call func(*lua.LState) error) error {
+	absPath, err := filepath.Abs(scriptPath)
+	if err != nil {
+		return fmt.Errorf("script path: %w", err)
+	}
+	proto, err := scripts.load(absPath)
+	if err != nil {
+		return fmt.Errorf("load script: %w", err)
+	}
+	lctx, stop := opts.Limit(ctx)
+	defer stop()
+	lState := newState(lctx, opts, withHTTP)
+	defer lState.Close()
+	lState.SetGlobal("config", configTable(lState, opts.Config[strings.TrimSuffix(filepath.Base(absPath), ".lua")]))
+
+	lState.Push(lState.NewFunctionFromProto(proto))
+	if err := lState.PCall(0, 0, nil); err != nil {
+		return LimitErr(lctx, fmt.Errorf("load script: %w", err))
+	}
+	return LimitErr(lctx, call(lState))
+}
@@ -0,0 +1,123 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"runtime/metrics"
+	"sync/atomic"
+	"time"
+)
+
+// Default limits for one script call. gopher-lua's call stack (256 frames)
+// and data stack are fixed-size too, so runaway recursion already fails.
+const (
+	DefaultTimeout         = 30 * time.Second
+	DefaultMaxInstructions = 50_000_000
+	DefaultMaxMemoryBytes  = 256 << 20
+
+	memorySampleInterval = 10 * time.Millisecond
+)
+
+var (
+	ErrTimeout          = errors.New("lua: script exceeded its time limit")
+	ErrInstructionLimit = errors.New("lua: script exceeded its instruction limit")
+	ErrMemoryLimit      = errors.New("lua: script exceeded its memory limit")
+)
+
+// limitContext counts VM instructions: gopher-lua calls Done once per
+// instruction when a context is set, so the budget is spent there and the
+// context cancelled with ErrInstructionLimit once it runs out.
+type limitContext struct {
+	context.Context
+	cancel   context.CancelCauseFunc
+	steps    atomic.Int64
+	maxSteps int64
+}
+
+func (c *limitContext) Done() <-chan struct{} {
+	if c.maxSteps > 0 && c.steps.Add(1) == c.maxSteps+1 {
+		c.cancel(ErrInstructionLimit)
+	}
+	return c.Context.Done()
+}
+
+// Err reports the limit that stopped the script; the VM raises it as the
+// Lua error message.
+func (c *limitContext) Err() error {
+	if c.Context.Err() == nil {
+		return nil
+	}
+	return context.Cause(c.Context)
+}
+
+// Limit derives the context one script call runs under: it is cancelled
+// with ErrTimeout or ErrMemoryLimit when the call runs out of time or
+// memory, and with ErrInstructionLimit when a Lua VM running under it
+// exhausts its instruction budget. The returned stop func must be called
+// when the call returns.
+func (o Options) Limit(parent context.Context) (context.Context, func()) {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	maxSteps := o.MaxInstructions
+	if maxSteps == 0 {
+		maxSteps = DefaultMaxInstructions
+	}
+	maxMem := o.MaxMemoryBytes
+	if maxMem == 0 {
+		maxMem = DefaultMaxMemoryBytes
+	}
+
+	ctx, cancel := context.WithCancelCause(parent)
+	stopTimer := func() bool { return false }
+	if timeout > 0 {
+		t := time.AfterFunc(timeout, func() { cancel(ErrTimeout) })
+		stopTimer = t.Stop
+	}
+	lctx := &limitContext{Context: ctx, cancel: cancel, maxSteps: maxSteps}
+	if maxMem > 0 {
+		go watchMemory(ctx, uint64(maxMem), cancel)
+	}
+	return lctx, func() {
+		stopTimer()
+		cancel(context.Canceled)
+	}
+}
+
+// watchMemory cancels ctx once the process has allocated more than limit
+// bytes since the call started. Go offers no per-goroutine accounting, so
+// this is an upper bound: allocations elsewhere in the process during the
+// call count too, which is why the default is generous.
+func watchMemory(ctx context.Context, limit uint64, cancel context.CancelCauseFunc) {
+	sample := []metrics.Sample{{Name: "/gc/heap/allocs:bytes"}}
+	metrics.Read(sample)
+	base := sample[0].Value.Uint64()
+	ticker := time.NewTicker(memorySampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			metrics.Read(sample)
+			if sample[0].Value.Uint64()-base > limit {
+				cancel(ErrMemoryLimit)
+				return
+			}
+		}
+	}
+}
+
+// LimitErr returns the limit that stopped the call running under ctx (see
+// Limit), if any, in place of the script error it surfaced as.
+func LimitErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch cause := context.Cause(ctx); cause {
+	case ErrTimeout, ErrInstructionLimit, ErrMemoryLimit:
+		return cause
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+package lua
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		opts   Options
+		want   error
+	}{
+		{"instruction limit", `function prepare(text) while true do end end`,
+			Options{MaxInstructions: 100_000, Timeout: -1, MaxMemoryBytes: -1}, ErrInstructionLimit},
+		{"timeout", `function prepare(text) while true do end end`,
+			Options{Timeout: 50 * time.Millisecond, MaxInstructions: -1, MaxMemoryBytes: -1}, ErrTimeout},
+		{"memory limit", `function prepare(text) local s = "x" while true do s = s .. s end end`,
+			Options{MaxMemoryBytes: 8 << 20, MaxInstructions: -1, Timeout: 10 * time.Second}, ErrMemoryLimit},
+		{"top-level code is limited too", `while true do end`,
+			Options{MaxInstructions: 100_000}, ErrInstructionLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeScript(t, "limit.lua", tt.script)
+			_, err := RunPrepare(context.Background(), path, tt.opts, "hi")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimits_WithinBudget(t *testing.T) {
+	path := writeScript(t, "ok.lua", `function prepare(text) local n = 0 for i = 1, 1000 do n = n + i end return text .. n end`)
+	res, err := RunPrepare(context.Background(), path, Options{MaxInstructions: 100_000}, "sum=")
+	if err != nil || res.Content != "sum=500500" {
+		t.Fatalf("res = %+v, err = %v", res, err)
+	}
+}
+
+func TestLimits_ParentCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	path := writeScript(t, "loop.lua", `function prepare(text) while true do end end`)
+	_, err := RunPrepare(ctx, path, Options{}, "hi")
+	if err == nil || errors.Is(err, ErrTimeout) || errors.Is(err, ErrInstructionLimit) {
+		t.Errorf("err = %v, want the caller's cancellation", err)
+	}
+}
+
+func TestScriptReload(t *testing.T) {
+	path := writeScript(t, "greet.lua", `function prepare(text) return "v1 " .. text end`)
+	run := func() string {
+		t.Helper()
+		res, err := RunPrepare(context.Background(), path, Options{}, "hi")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Content
+	}
+	if got := run(); got != "v1 hi" {
+		t.Fatalf("got %q", got)
+	}
+
+	version := 0
+	rewrite := func(src string) {
+		t.Helper()
+		version++
+		if err := os.WriteFile(path, []byte(src), 0600); err != nil {
+			t.Fatal(err)
+		}
+		// Make the change visible even on file systems with coarse mtimes.
+		future := time.Now().Add(time.Duration(version) * time.Minute)
+		if err := os.Chtimes(path, future, future); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rewrite(`function prepare(text) return "v2 " .. text end`)
+	if got := run(); got != "v2 hi" {
+		t.Errorf("edit not picked up: %q", got)
+	}
+	rewrite(`function prepare(text) return "v3 " .. text`)
+	if got := run(); got != "v2 hi" {
+		t.Errorf("broken edit should keep the last good version: %q", got)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := writeScript(t, "tool.lua", `-- v1`)
+	other := writeScript(t, "other.lua", `-- v1`)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := make(chan string, 4)
+	if err := Watch(ctx, []string{path}, func(p string) { changed <- p }); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(other, []byte(`-- v2`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(`-- v2`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case p := <-changed:
+		if p != path {
+			t.Errorf("changed = %q, want %q", p, path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change reported")
+	}
+	select {
+	case p := <-changed:
+		t.Errorf("unexpected second change %q", p)
+	case <-time.After(2 * watchDebounce):
+	}
+}
@@ -1,9 +1,9 @@
 package lua
 
 import (
+	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	lua "github.com/yuin/gopher-lua"
@@ -26,62 +26,57 @@ type PrepareResult struct {
 // RunPrepare runs the Lua script at scriptPath, calling the global prepare(text) function.
 // The script must return either a string (new content, SendToLLM true) or a table
 // with send_to_llm (bool) and message (string) to block and return a message.
-// Scripts can use os.getenv for environment variables (e.g. HELLO_WORLD_PROMPT_FRAGMENT).
-func RunPrepare(scriptPath, text string) (*PrepareResult, error) {
-	lState := lua.NewState()
-	defer lState.Close()
-
-	// Allow os.getenv so scripts can read env vars (e.g. HELLO_WORLD_PROMPT_FRAGMENT).
-	lState.PreloadModule("os", osModuleLoader)
-
-	absPath, err := filepath.Abs(scriptPath)
-	if err != nil {
-		return nil, fmt.Errorf("script path: %w", err)
-	}
-	if err := lState.DoFile(absPath); err != nil {
-		return nil, fmt.Errorf("load script: %w", err)
-	}
+// Scripts run in the sandbox (see newState) under the limits in opts and can
+// use os.getenv for environment variables (e.g. HELLO_WORLD_PROMPT_FRAGMENT).
+func RunPrepare(ctx context.Context, scriptPath string, opts Options, text string) (*PrepareResult, error) {
+	var result *PrepareResult
+	err := runScript(ctx, scriptPath, opts, false, func(lState *lua.LState) error {
+		fn := lState.GetGlobal("prepare")
+		if fn.Type() == lua.LTNil {
+			return fmt.Errorf("script must define global function prepare(text)")
+		}
+		if fn.Type() != lua.LTFunction {
+			return fmt.Errorf("prepare must be a function, got %s", fn.Type().String())
+		}
 
-	fn := lState.GetGlobal("prepare")
-	if fn.Type() == lua.LTNil {
-		return nil, fmt.Errorf("script must define global function prepare(text)")
-	}
-	if fn.Type() != lua.LTFunction {
-		return nil, fmt.Errorf("prepare must be a function, got %s", fn.Type().String())
-	}
+		lState.Push(fn)
+		lState.Push(lua.LString(text))
+		if err := lState.PCall(1, 1, nil); err != nil {
+			return fmt.Errorf("prepare(): %w", err)
+		}
 
-	lState.Push(fn)
-	lState.Push(lua.LString(text))
-	if err := lState.PCall(1, 1, nil); err != nil {
-		return nil, fmt.Errorf("prepare(): %w", err)
-	}
+		ret := lState.Get(-1)
+		lState.Pop(1)
 
-	ret := lState.Get(-1)
-	lState.Pop(1)
-
-	switch ret.Type() {
-	case lua.LTString:
-		return &PrepareResult{Content: ret.String(), SendToLLM: true}, nil
-	case lua.LTTable:
-		tbl := ret.(*lua.LTable)
-		sendToLLM := true
-		var message string
-		var invokeSteps []InvokeStep
-		tbl.ForEach(func(k, v lua.LValue) {
-			if k.String() == "send_to_llm" && v.Type() == lua.LTBool {
-				sendToLLM = v.(lua.LBool) == lua.LTrue
-			}
-			if k.String() == "message" && v.Type() == lua.LTString {
-				message = v.String()
-			}
-			if k.String() == "invoke" && v.Type() == lua.LTTable {
-				invokeSteps = parseInvokeSteps(v.(*lua.LTable))
-			}
-		})
-		return &PrepareResult{Content: message, SendToLLM: sendToLLM, InvokeSteps: invokeSteps}, nil
-	default:
-		return nil, fmt.Errorf("prepare() must return string or table { send_to_llm, message }, got %s", ret.Type().String())
+		switch ret.Type() {
+		case lua.LTString:
+			result = &PrepareResult{Content: ret.String(), SendToLLM: true}
+		case lua.LTTable:
+			tbl := ret.(*lua.LTable)
+			sendToLLM := true
+			var message string
+			var invokeSteps []InvokeStep
+			tbl.ForEach(func(k, v lua.LValue) {
+				if k.String() == "send_to_llm" && v.Type() == lua.LTBool {
+					sendToLLM = v.(lua.LBool) == lua.LTrue
+				}
+				if k.String() == "message" && v.Type() == lua.LTString {
+					message = v.String()
+				}
+				if k.String() == "invoke" && v.Type() == lua.LTTable {
+					invokeSteps = parseInvokeSteps(v.(*lua.LTable))
+				}
+			})
+			result = &PrepareResult{Content: message, SendToLLM: sendToLLM, InvokeSteps: invokeSteps}
+		default:
+			return fmt.Errorf("prepare() must return string or table { send_to_llm, message }, got %s", ret.Type().String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return result, nil
 }
 
 // parseInvokeSteps parses Lua "invoke" as a single step table or array of step tables.
@@ -139,45 +134,111 @@ func getTableString(tbl *lua.LTable, key string) string {
 // RunFormat runs the Lua script at scriptPath, calling the global format(text, response_format)
 // function. The script must return a string (the formatted text). This is the post-LLM counterpart
 // of RunPrepare — simpler because formatters are text-in/text-out with no blocking or invoke.
-func RunFormat(scriptPath, text, responseFormat string) (string, error) {
-	lState := lua.NewState()
-	defer lState.Close()
+func RunFormat(ctx context.Context, scriptPath string, opts Options, text, responseFormat string) (string, error) {
+	var formatted string
+	err := runScript(ctx, scriptPath, opts, false, func(lState *lua.LState) error {
+		fn := lState.GetGlobal("format")
+		if fn.Type() == lua.LTNil {
+			return fmt.Errorf("script must define global function format(text, response_format)")
+		}
+		if fn.Type() != lua.LTFunction {
+			return fmt.Errorf("format must be a function, got %s", fn.Type().String())
+		}
 
-	lState.PreloadModule("os", osModuleLoader)
+		lState.Push(fn)
+		lState.Push(lua.LString(text))
+		lState.Push(lua.LString(responseFormat))
+		if err := lState.PCall(2, 1, nil); err != nil {
+			return fmt.Errorf("format(): %w", err)
+		}
 
-	absPath, err := filepath.Abs(scriptPath)
-	if err != nil {
-		return "", fmt.Errorf("script path: %w", err)
-	}
-	if err := lState.DoFile(absPath); err != nil {
-		return "", fmt.Errorf("load script: %w", err)
-	}
+		ret := lState.Get(-1)
+		lState.Pop(1)
 
-	fn := lState.GetGlobal("format")
-	if fn.Type() == lua.LTNil {
-		return "", fmt.Errorf("script must define global function format(text, response_format)")
-	}
-	if fn.Type() != lua.LTFunction {
-		return "", fmt.Errorf("format must be a function, got %s", fn.Type().String())
-	}
+		if ret.Type() != lua.LTString {
+			return fmt.Errorf("format() must return a string, got %s", ret.Type().String())
+		}
+		formatted = ret.String()
+		return nil
+	})
+	return formatted, err
+}
 
-	lState.Push(fn)
-	lState.Push(lua.LString(text))
-	lState.Push(lua.LString(responseFormat))
-	if err := lState.PCall(2, 1, nil); err != nil {
-		return "", fmt.Errorf("format(): %w", err)
-	}
+// ModerateResult is the result of running a Lua response moderator script.
+type ModerateResult struct {
+	Text    string // reply to send (the input when the script did not rewrite it)
+	Send    bool   // if false, the reply is blocked and Message is sent instead
+	Message string // optional replacement for a blocked reply
+}
+
+// RunModerate runs the Lua script at scriptPath, calling the global
+// moderate(text, channel) function on the final reply. The script returns
+// a string (the reply to send, rewritten or not) or a table with send
+// (bool) and message (string); send = false blocks the reply.
+func RunModerate(ctx context.Context, scriptPath string, opts Options, text, channel string) (*ModerateResult, error) {
+	var result *ModerateResult
+	err := runScript(ctx, scriptPath, opts, false, func(lState *lua.LState) error {
+		fn := lState.GetGlobal("moderate")
+		if fn.Type() == lua.LTNil {
+			return fmt.Errorf("script must define global function moderate(text, channel)")
+		}
+		if fn.Type() != lua.LTFunction {
+			return fmt.Errorf("moderate must be a function, got %s", fn.Type().String())
+		}
 
-	ret := lState.Get(-1)
-	lState.Pop(1)
+		lState.Push(fn)
+		lState.Push(lua.LString(text))
+		lState.Push(lua.LString(channel))
+		if err := lState.PCall(2, 1, nil); err != nil {
+			return fmt.Errorf("moderate(): %w", err)
+		}
+
+		ret := lState.Get(-1)
+		lState.Pop(1)
 
-	if ret.Type() != lua.LTString {
-		return "", fmt.Errorf("format() must return a string, got %s", ret.Type().String())
+		switch ret.Type() {
+		case lua.LTString:
+			result = &ModerateResult{Text: ret.String(), Send: true}
+		case lua.LTTable:
+			tbl := ret.(*lua.LTable)
+			result = &ModerateResult{Text: text, Send: true, Message: getTableString(tbl, "message")}
+			if v := tbl.RawGetString("send"); v.Type() == lua.LTBool {
+				result.Send = v.(lua.LBool) == lua.LTrue
+			}
+		default:
+			return fmt.Errorf("moderate() must return string or table { send, message }, got %s", ret.Type().String())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return ret.String(), nil
+	return result, nil
 }
 
 // osModuleLoader provides a minimal os module: getenv and time (for math.randomseed).
+// RunEvent runs the Lua script at scriptPath, calling the global
+// on_event(event) function with fields as a table of strings (type,
+// session_id, time and the event's data). Return values are ignored.
+func RunEvent(ctx context.Context, scriptPath string, opts Options, fields map[string]string) error {
+	return runScript(ctx, scriptPath, opts, false, func(lState *lua.LState) error {
+		fn := lState.GetGlobal("on_event")
+		if fn.Type() != lua.LTFunction {
+			return fmt.Errorf("script must define global function on_event(event)")
+		}
+		tbl := lState.NewTable()
+		for k, v := range fields {
+			tbl.RawSetString(k, lua.LString(v))
+		}
+		lState.Push(fn)
+		lState.Push(tbl)
+		if err := lState.PCall(1, 0, nil); err != nil {
+			return fmt.Errorf("on_event(): %w", err)
+		}
+		return nil
+	})
+}
+
 func osModuleLoader(lState *lua.LState) int {
 	mod := lState.NewTable()
 	lState.SetField(mod, "getenv", lState.NewFunction(func(ls *lua.LState) int {
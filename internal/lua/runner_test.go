@@ -1,6 +1,7 @@
 package lua
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -27,7 +28,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunPrepare(path, "deploy branch one to staging")
+	result, err := RunPrepare(context.Background(), path, Options{}, "deploy branch one to staging")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -65,7 +66,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunPrepare(path, "analyze and create issue")
+	result, err := RunPrepare(context.Background(), path, Options{}, "analyze and create issue")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -94,7 +95,7 @@ func TestRunPrepareReturnsString(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	result, err := RunPrepare(path, "hello")
+	result, err := RunPrepare(context.Background(), path, Options{}, "hello")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -121,7 +122,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunPrepare(path, "sensitive")
+	result, err := RunPrepare(context.Background(), path, Options{}, "sensitive")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -148,7 +149,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunFormat(path, "hello **world**", "slack")
+	result, err := RunFormat(context.Background(), path, Options{}, "hello **world**", "slack")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -169,7 +170,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunFormat(path, "anything", "teams")
+	result, err := RunFormat(context.Background(), path, Options{}, "anything", "teams")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -186,7 +187,7 @@ func TestRunFormatMissingFunction(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	_, err := RunFormat(path, "hello", "slack")
+	_, err := RunFormat(context.Background(), path, Options{}, "hello", "slack")
 	if err == nil {
 		t.Fatal("expected error for missing format function")
 	}
@@ -204,7 +205,7 @@ end
 		t.Fatal(err)
 	}
 
-	_, err := RunFormat(path, "hello", "slack")
+	_, err := RunFormat(context.Background(), path, Options{}, "hello", "slack")
 	if err == nil {
 		t.Fatal("expected error for non-string return")
 	}
@@ -226,7 +227,7 @@ end
 		t.Fatal(err)
 	}
 
-	result, err := RunFormat(path, "keep me", "")
+	result, err := RunFormat(context.Background(), path, Options{}, "keep me", "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -235,6 +236,80 @@ end
 	}
 }
 
+func TestRunModerate(t *testing.T) {
+	script := `
+function moderate(text, channel)
+  if string.find(text, "damn") then
+    return { send = false, message = "Reply withheld on " .. channel }
+  end
+  return (string.gsub(text, "%d%d%d%-%d%d%-%d%d%d%d", "[SSN]"))
+end
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "mod.lua")
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := RunModerate(context.Background(), path, Options{}, "SSN is 123-45-6789.", "slack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Send || res.Text != "SSN is [SSN]." {
+		t.Errorf("rewrite: %+v", res)
+	}
+	res, err = RunModerate(context.Background(), path, Options{}, "damn it", "slack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Send || res.Message != "Reply withheld on slack" {
+		t.Errorf("block: %+v", res)
+	}
+	if _, err := RunModerate(context.Background(), path, Options{}, "", "slack"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(path, []byte(`function moderate(text) return 42 end`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RunModerate(context.Background(), path, Options{}, "hi", "slack"); err == nil {
+		t.Error("expected error for a number return")
+	}
+}
+
+func TestRunEvent(t *testing.T) {
+	// Scripts have no io library, so the handler reports what it saw by
+	// raising it as an error.
+	script := `
+function on_event(event)
+  if event.type == "tool_executed" then
+    error(event.session_id .. " ran " .. event.plugin)
+  end
+end
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ev.lua")
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunEvent(context.Background(), path, Options{}, map[string]string{"type": "job_run"}); err != nil {
+		t.Fatal(err)
+	}
+	err := RunEvent(context.Background(), path, Options{}, map[string]string{
+		"type": "tool_executed", "session_id": "web:c1", "plugin": "gitlab",
+	})
+	if err == nil || !strings.Contains(err.Error(), "web:c1 ran gitlab") {
+		t.Errorf("on_event fields: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`function moderate(text) return text end`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := RunEvent(context.Background(), path, Options{}, nil); err == nil {
+		t.Error("expected error for a script without on_event")
+	}
+}
+
 // formatResponseScriptPath returns the path to the bundled format-response.lua script.
 func formatResponseScriptPath(t *testing.T) string {
 	t.Helper()
@@ -254,7 +329,7 @@ func TestFormatResponseSlackToolDebug(t *testing.T) {
 	path := formatResponseScriptPath(t)
 
 	input := "[tool_call] inventory.list-containers(page=1, per_page=1)\n[tool_result] {\"total\": 7}\n\n---\n\nYou have **7** containers."
-	result, err := RunFormat(path, input, "slack")
+	result, err := RunFormat(context.Background(), path, Options{}, input, "slack")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -279,7 +354,7 @@ func TestFormatResponseSlackToolDebugError(t *testing.T) {
 	path := formatResponseScriptPath(t)
 
 	input := "[tool_call] jira.get-issue(id=123)\n[tool_result] error: not found\n\n---\n\nI could not find that issue."
-	result, err := RunFormat(path, input, "slack")
+	result, err := RunFormat(context.Background(), path, Options{}, input, "slack")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -292,7 +367,7 @@ func TestFormatResponseMarkdownToolDebug(t *testing.T) {
 	path := formatResponseScriptPath(t)
 
 	input := "[tool_call] inventory.list-containers(page=1)\n[tool_result] {\"total\": 7}\n\n---\n\nYou have **7** containers."
-	result, err := RunFormat(path, input, "markdown")
+	result, err := RunFormat(context.Background(), path, Options{}, input, "markdown")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -309,7 +384,7 @@ func TestFormatResponseHTMLToolDebug(t *testing.T) {
 	path := formatResponseScriptPath(t)
 
 	input := "[tool_call] inventory.list-containers(page=1)\n[tool_result] ok\n\n---\n\nDone."
-	result, err := RunFormat(path, input, "html")
+	result, err := RunFormat(context.Background(), path, Options{}, input, "html")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -326,7 +401,7 @@ func TestFormatResponseNoDebugUnchanged(t *testing.T) {
 
 	// Normal response without tool debug blocks
 	input := "You have **7** containers."
-	result, err := RunFormat(path, input, "slack")
+	result, err := RunFormat(context.Background(), path, Options{}, input, "slack")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -0,0 +1,345 @@
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Options configures the sandbox a script runs in. For the limits, 0 means
+// the default and a negative value means no limit.
+type Options struct {
+	// HTTPAllow lists the hosts the http module may call: "api.example.com"
+	// or "*.example.com" (subdomains only). Empty = the http module refuses
+	// every request.
+	HTTPAllow   []string
+	HTTPTimeout time.Duration // per request; 0 = DefaultHTTPTimeout
+
+	Timeout         time.Duration // wall clock per call, including loading the script; 0 = DefaultTimeout
+	MaxInstructions int64         // VM instructions per call; 0 = DefaultMaxInstructions
+	MaxMemoryBytes  int64         // bytes allocated per call (approximate, see watchMemory); 0 = DefaultMaxMemoryBytes
+
+	// Config holds per-script values keyed by script name (the file name
+	// without .lua). A script sees only its own, as the read-only global
+	// table config; scripts without an entry get an empty one.
+	Config map[string]map[string]string
+}
+
+const (
+	DefaultHTTPTimeout = 15 * time.Second
+	maxHTTPBodyBytes   = 1 << 20 // responses larger than this fail instead of being cut
+	maxJSONDepth       = 64
+)
+
+// removedBaseFuncs are base-library globals that reach the file system or
+// load code from outside the script.
+var removedBaseFuncs = []string{"dofile", "loadfile", "load", "loadstring", "require", "module"}
+
+// newState returns a Lua state with the sandboxed standard library: base
+// (without file and code loading), table, string and math, plus the
+// minimal os module and json. The http module is added when withHTTP is
+// set, which only tool scripts get.
+func newState(ctx context.Context, opts Options, withHTTP bool) *lua.LState {
+	lState := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		lState.Push(lState.NewFunction(lib.open))
+		lState.Push(lua.LString(lib.name))
+		lState.Call(1, 0)
+	}
+	for _, name := range removedBaseFuncs {
+		lState.SetGlobal(name, lua.LNil)
+	}
+	lState.Push(lState.NewFunction(osModuleLoader))
+	lState.Call(0, 1)
+	lState.SetGlobal("os", lState.Get(-1))
+	lState.Pop(1)
+	lState.SetGlobal("json", jsonModule(lState))
+	if withHTTP {
+		lState.SetGlobal("http", httpModule(lState, opts))
+	}
+	lState.SetContext(ctx)
+	return lState
+}
+
+// configTable returns a read-only view of values: reads go through
+// __index, and assigning a key raises an error.
+func configTable(lState *lua.LState, values map[string]string) *lua.LTable {
+	data := lState.NewTable()
+	for k, v := range values {
+		data.RawSetString(k, lua.LString(v))
+	}
+	mt := lState.NewTable()
+	mt.RawSetString("__index", data)
+	mt.RawSetString("__newindex", lState.NewFunction(func(l *lua.LState) int {
+		l.RaiseError("config is read-only")
+		return 0
+	}))
+	mt.RawSetString("__metatable", lua.LFalse)
+	view := lState.NewTable()
+	lState.SetMetatable(view, mt)
+	return view
+}
+
+// jsonModule provides json.encode(value) and json.decode(string).
+func jsonModule(lState *lua.LState) *lua.LTable {
+	mod := lState.NewTable()
+	lState.SetField(mod, "encode", lState.NewFunction(func(ls *lua.LState) int {
+		v, err := toGo(ls.CheckAny(1), 0)
+		if err != nil {
+			ls.ArgError(1, err.Error())
+			return 0
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			ls.ArgError(1, err.Error())
+			return 0
+		}
+		ls.Push(lua.LString(b))
+		return 1
+	}))
+	lState.SetField(mod, "decode", lState.NewFunction(func(ls *lua.LState) int {
+		var v any
+		if err := json.Unmarshal([]byte(ls.CheckString(1)), &v); err != nil {
+			ls.Push(lua.LNil)
+			ls.Push(lua.LString(err.Error()))
+			return 2
+		}
+		ls.Push(fromGo(ls, v))
+		return 1
+	}))
+	return mod
+}
+
+// toGo converts a Lua value for JSON encoding. Tables with keys 1..n become
+// arrays, other tables objects; an empty table encodes as {}.
+func toGo(v lua.LValue, depth int) (any, error) {
+	if depth > maxJSONDepth {
+		return nil, fmt.Errorf("value nested deeper than %d levels", maxJSONDepth)
+	}
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return nil, nil
+	case lua.LBool:
+		return bool(v), nil
+	case lua.LNumber:
+		f := float64(v)
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("cannot encode %v", f)
+		}
+		return f, nil
+	case lua.LString:
+		return string(v), nil
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 && n == countKeys(v) {
+			arr := make([]any, 0, n)
+			for i := 1; i <= n; i++ {
+				item, err := toGo(v.RawGetInt(i), depth+1)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, item)
+			}
+			return arr, nil
+		}
+		obj := map[string]any{}
+		var err error
+		v.ForEach(func(k, val lua.LValue) {
+			if err != nil {
+				return
+			}
+			var item any
+			if item, err = toGo(val, depth+1); err == nil {
+				obj[k.String()] = item
+			}
+		})
+		return obj, err
+	default:
+		return nil, fmt.Errorf("cannot encode a %s", v.Type())
+	}
+}
+
+func countKeys(t *lua.LTable) int {
+	n := 0
+	t.ForEach(func(lua.LValue, lua.LValue) { n++ })
+	return n
+}
+
+// fromGo converts a decoded JSON value to Lua; null becomes nil.
+func fromGo(lState *lua.LState, v any) lua.LValue {
+	switch v := v.(type) {
+	case bool:
+		return lua.LBool(v)
+	case float64:
+		return lua.LNumber(v)
+	case string:
+		return lua.LString(v)
+	case []any:
+		t := lState.CreateTable(len(v), 0)
+		for _, item := range v {
+			t.Append(fromGo(lState, item))
+		}
+		return t
+	case map[string]any:
+		t := lState.CreateTable(0, len(v))
+		for k, item := range v {
+			t.RawSetString(k, fromGo(lState, item))
+		}
+		return t
+	default:
+		return lua.LNil
+	}
+}
+
+// httpModule provides http.request{method, url, headers, body} and the
+// http.get(url, headers) / http.post(url, body, headers) shorthands. Each
+// returns a table {status, body, headers}, or nil and an error message.
+// Hosts outside Options.HTTPAllow are refused, including on redirect.
+func httpModule(lState *lua.LState, opts Options) *lua.LTable {
+	client := opts.httpClient()
+	do := func(ls *lua.LState, method, rawURL, body string, headers *lua.LTable) int {
+		h := map[string]string{}
+		if headers != nil {
+			headers.ForEach(func(k, v lua.LValue) { h[k.String()] = v.String() })
+		}
+		res, err := opts.fetch(ls.Context(), client, method, rawURL, body, h)
+		if err != nil {
+			ls.Push(lua.LNil)
+			ls.Push(lua.LString(err.Error()))
+			return 2
+		}
+		t := ls.NewTable()
+		t.RawSetString("status", lua.LNumber(res.Status))
+		t.RawSetString("body", lua.LString(res.Body))
+		ht := ls.NewTable()
+		for k, v := range res.Headers {
+			ht.RawSetString(k, lua.LString(v))
+		}
+		t.RawSetString("headers", ht)
+		ls.Push(t)
+		return 1
+	}
+	mod := lState.NewTable()
+	lState.SetField(mod, "request", lState.NewFunction(func(ls *lua.LState) int {
+		req := ls.CheckTable(1)
+		method := getTableString(req, "method")
+		if method == "" {
+			method = http.MethodGet
+		}
+		headers, _ := req.RawGetString("headers").(*lua.LTable)
+		return do(ls, strings.ToUpper(method), getTableString(req, "url"), getTableString(req, "body"), headers)
+	}))
+	lState.SetField(mod, "get", lState.NewFunction(func(ls *lua.LState) int {
+		return do(ls, http.MethodGet, ls.CheckString(1), "", ls.OptTable(2, nil))
+	}))
+	lState.SetField(mod, "post", lState.NewFunction(func(ls *lua.LState) int {
+		return do(ls, http.MethodPost, ls.CheckString(1), ls.OptString(2, ""), ls.OptTable(3, nil))
+	}))
+	return mod
+}
+
+// HTTPResponse is the result of an allowlisted script request. Header
+// names are lowercased.
+type HTTPResponse struct {
+	Status  int
+	Body    string
+	Headers map[string]string
+}
+
+// Fetch makes one request on behalf of a script under the http rules of o:
+// only hosts in HTTPAllow, HTTPTimeout per request, a capped body. Other
+// script engines use it to offer the same http module as Lua tools.
+func (o Options) Fetch(ctx context.Context, method, rawURL, body string, headers map[string]string) (HTTPResponse, error) {
+	return o.fetch(ctx, o.httpClient(), method, rawURL, body, headers)
+}
+
+func (o Options) httpClient() *http.Client {
+	timeout := o.HTTPTimeout
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 5 {
+				return fmt.Errorf("too many redirects")
+			}
+			return checkHost(req.URL, o.HTTPAllow)
+		},
+	}
+}
+
+func (o Options) fetch(ctx context.Context, client *http.Client, method, rawURL, body string, headers map[string]string) (HTTPResponse, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return HTTPResponse{}, fmt.Errorf("invalid url: %w", err)
+	}
+	if err := checkHost(u, o.HTTPAllow); err != nil {
+		return HTTPResponse{}, err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodyBytes+1))
+	if err != nil {
+		return HTTPResponse{}, err
+	}
+	if len(data) > maxHTTPBodyBytes {
+		return HTTPResponse{}, fmt.Errorf("response body exceeds %d bytes", maxHTTPBodyBytes)
+	}
+	res := HTTPResponse{Status: resp.StatusCode, Body: string(data), Headers: make(map[string]string, len(resp.Header))}
+	for k := range resp.Header {
+		res.Headers[strings.ToLower(k)] = resp.Header.Get(k)
+	}
+	return res, nil
+}
+
+// checkHost refuses non-HTTP URLs and hosts not in allow.
+func checkHost(u *url.URL, allow []string) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url scheme %q not allowed", u.Scheme)
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range allow {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return nil
+			}
+		} else if host == pattern {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %q is not in lua.http_allow", host)
+}
@@ -0,0 +1,193 @@
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ToolSpec is the capability a tool script declares in its global plugin
+// table:
+//
+//	plugin = {
+//	  name = "weather",              -- optional; default = script name
+//	  description = "Weather lookups",
+//	  actions = {
+//	    { name = "forecast", description = "...", read_only = true, cache_ttl = "10m",
+//	      parameters = { { name = "city", description = "...", required = true, type = "string" } } },
+//	  },
+//	}
+type ToolSpec struct {
+	Name        string
+	Description string
+	Actions     []ToolAction
+}
+
+// ToolAction is one action of a tool script.
+type ToolAction struct {
+	Name        string
+	Description string
+	ReadOnly    bool
+	CacheTTL    time.Duration // how long the tool cache may reuse a read-only result; 0 = its default
+	Parameters  []ToolParam
+}
+
+// ToolParam is one parameter of a tool action. Type uses the plugin
+// protocol's type strings (string, number, integer, boolean, array, enum:a,b).
+type ToolParam struct {
+	Name        string
+	Description string
+	Type        string
+	Required    bool
+}
+
+// ToolOutput is the result of one execute call.
+type ToolOutput struct {
+	Content string
+	Error   string
+}
+
+// LoadTool runs the script at scriptPath once and reads its plugin table.
+// The script must also define execute(action, args).
+func LoadTool(scriptPath string, opts Options) (*ToolSpec, error) {
+	var spec *ToolSpec
+	err := runScript(context.Background(), scriptPath, opts, true, func(lState *lua.LState) error {
+		var err error
+		spec, err = readToolSpec(lState, scriptPath)
+		return err
+	})
+	return spec, err
+}
+
+func readToolSpec(lState *lua.LState, scriptPath string) (*ToolSpec, error) {
+	if fn := lState.GetGlobal("execute"); fn.Type() != lua.LTFunction {
+		return nil, fmt.Errorf("script must define global function execute(action, args)")
+	}
+	tbl, ok := lState.GetGlobal("plugin").(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("script must define a global plugin table")
+	}
+	spec := &ToolSpec{
+		Name:        getTableString(tbl, "name"),
+		Description: getTableString(tbl, "description"),
+	}
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(filepath.Base(scriptPath), ".lua")
+	}
+	actions, _ := tbl.RawGetString("actions").(*lua.LTable)
+	if actions == nil || actions.Len() == 0 {
+		return nil, fmt.Errorf("plugin table must list at least one action")
+	}
+	for i := 1; i <= actions.Len(); i++ {
+		at, ok := actions.RawGetInt(i).(*lua.LTable)
+		if !ok || getTableString(at, "name") == "" {
+			return nil, fmt.Errorf("action %d: must be a table with a name", i)
+		}
+		action := ToolAction{
+			Name:        getTableString(at, "name"),
+			Description: getTableString(at, "description"),
+			ReadOnly:    lua.LVAsBool(at.RawGetString("read_only")),
+		}
+		if v := getTableString(at, "cache_ttl"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("action %q: invalid cache_ttl %q", action.Name, v)
+			}
+			action.CacheTTL = d
+		}
+		if params, ok := at.RawGetString("parameters").(*lua.LTable); ok {
+			for j := 1; j <= params.Len(); j++ {
+				pt, ok := params.RawGetInt(j).(*lua.LTable)
+				if !ok || getTableString(pt, "name") == "" {
+					return nil, fmt.Errorf("action %q parameter %d: must be a table with a name", action.Name, j)
+				}
+				action.Parameters = append(action.Parameters, ToolParam{
+					Name:        getTableString(pt, "name"),
+					Description: getTableString(pt, "description"),
+					Type:        getTableString(pt, "type"),
+					Required:    lua.LVAsBool(pt.RawGetString("required")),
+				})
+			}
+		}
+		spec.Actions = append(spec.Actions, action)
+	}
+	return spec, nil
+}
+
+// RunTool calls execute(action, args) of the script at scriptPath in a fresh
+// sandbox. execute returns a string, a table { content = ..., error = ... }
+// (a table content is JSON-encoded), any other table (JSON-encoded as the
+// content), or nil and an error message. A Lua runtime error or a limit
+// hit is returned as err.
+func RunTool(ctx context.Context, scriptPath string, opts Options, action string, args map[string]string) (ToolOutput, error) {
+	var out ToolOutput
+	err := runScript(ctx, scriptPath, opts, true, func(lState *lua.LState) error {
+		fn := lState.GetGlobal("execute")
+		if fn.Type() != lua.LTFunction {
+			return fmt.Errorf("script must define global function execute(action, args)")
+		}
+		argTbl := lState.CreateTable(0, len(args))
+		for k, v := range args {
+			argTbl.RawSetString(k, lua.LString(v))
+		}
+		lState.Push(fn)
+		lState.Push(lua.LString(action))
+		lState.Push(argTbl)
+		if err := lState.PCall(2, 2, nil); err != nil {
+			return fmt.Errorf("execute(): %w", err)
+		}
+		ret, msg := lState.Get(-2), lState.Get(-1)
+		lState.Pop(2)
+		var err error
+		out, err = toolOutput(ret, msg)
+		return err
+	})
+	if err != nil {
+		return ToolOutput{}, err
+	}
+	return out, nil
+}
+
+func toolOutput(ret, msg lua.LValue) (ToolOutput, error) {
+	switch ret := ret.(type) {
+	case *lua.LNilType:
+		if msg.Type() == lua.LTString {
+			return ToolOutput{Error: msg.String()}, nil
+		}
+		return ToolOutput{}, nil
+	case lua.LString:
+		return ToolOutput{Content: string(ret)}, nil
+	case *lua.LTable:
+		content, errVal := ret.RawGetString("content"), ret.RawGetString("error")
+		if content == lua.LNil && errVal == lua.LNil {
+			content = ret
+		}
+		out := ToolOutput{}
+		if errVal.Type() == lua.LTString {
+			out.Error = errVal.String()
+		}
+		switch content.Type() {
+		case lua.LTNil:
+		case lua.LTTable:
+			v, err := toGo(content, 0)
+			if err != nil {
+				return ToolOutput{}, fmt.Errorf("execute(): encode result: %w", err)
+			}
+			b, err := json.Marshal(v)
+			if err != nil {
+				return ToolOutput{}, fmt.Errorf("execute(): encode result: %w", err)
+			}
+			out.Content = string(b)
+		default:
+			out.Content = content.String()
+		}
+		return out, nil
+	default:
+		return ToolOutput{Content: ret.String()}, nil
+	}
+}
@@ -0,0 +1,203 @@
+package lua
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScript(t *testing.T, name, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const weatherTool = `
+plugin = {
+  description = "Weather lookups",
+  actions = {
+    { name = "forecast", description = "Forecast for a city", read_only = true, cache_ttl = "10m",
+      parameters = { { name = "city", description = "City name", required = true },
+                     { name = "days", type = "integer" } } },
+    { name = "alerts" },
+  },
+}
+
+function execute(action, args)
+  if action == "forecast" then
+    if args.city == "" or args.city == nil then
+      return nil, "city is required"
+    end
+    return "Sunny in " .. args.city
+  end
+  return { content = { alerts = { "wind", "rain" } } }
+end
+`
+
+func TestLoadTool(t *testing.T) {
+	spec, err := LoadTool(writeScript(t, "weather.lua", weatherTool), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Name != "weather" || spec.Description != "Weather lookups" || len(spec.Actions) != 2 {
+		t.Fatalf("spec = %+v", spec)
+	}
+	forecast := spec.Actions[0]
+	if !forecast.ReadOnly || forecast.CacheTTL != 10*time.Minute || len(forecast.Parameters) != 2 || !forecast.Parameters[0].Required || forecast.Parameters[1].Type != "integer" {
+		t.Errorf("forecast = %+v", forecast)
+	}
+
+	if _, err := LoadTool(writeScript(t, "bad.lua", `plugin = { actions = { { name = "x" } } }`), Options{}); err == nil {
+		t.Error("a script without execute should not load")
+	}
+	if _, err := LoadTool(writeScript(t, "bad.lua", `plugin = { actions = { { name = "x", cache_ttl = "soon" } } }
+function execute() end`), Options{}); err == nil {
+		t.Error("an invalid cache_ttl should not load")
+	}
+	if _, err := LoadTool(writeScript(t, "bad.lua", `function execute() end`), Options{}); err == nil {
+		t.Error("a script without a plugin table should not load")
+	}
+}
+
+func TestRunTool(t *testing.T) {
+	path := writeScript(t, "weather.lua", weatherTool)
+	tests := []struct {
+		action string
+		args   map[string]string
+		want   ToolOutput
+	}{
+		{"forecast", map[string]string{"city": "Oslo"}, ToolOutput{Content: "Sunny in Oslo"}},
+		{"forecast", nil, ToolOutput{Error: "city is required"}},
+		{"alerts", nil, ToolOutput{Content: `{"alerts":["wind","rain"]}`}},
+	}
+	for _, tt := range tests {
+		got, err := RunTool(context.Background(), path, Options{}, tt.action, tt.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s(%v) = %+v, want %+v", tt.action, tt.args, got, tt.want)
+		}
+	}
+
+	_, err := RunTool(context.Background(), writeScript(t, "boom.lua", `function execute() error("boom") end`), Options{}, "x", nil)
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("runtime error = %v", err)
+	}
+}
+
+func TestSandbox_Stdlib(t *testing.T) {
+	script := `
+function execute(action, args)
+  local hidden = {}
+  for _, name in ipairs({ "io", "dofile", "loadfile", "load", "require", "debug", "package" }) do
+    if _G[name] ~= nil then table.insert(hidden, name) end
+  end
+  if os.execute ~= nil or os.remove ~= nil then table.insert(hidden, "os.execute") end
+  local v = json.decode('{"a":[1,2,{"b":true}],"c":null}')
+  return json.encode({ leaked = hidden, a2 = v.a[2], b = v.a[3].b, upper = string.upper("ok"), env = os.getenv("LUA_SANDBOX_TEST") })
+end
+`
+	t.Setenv("LUA_SANDBOX_TEST", "yes")
+	got, err := RunTool(context.Background(), writeScript(t, "sb.lua", script), Options{}, "x", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"a2":2,"b":true,"env":"yes","leaked":{},"upper":"OK"}`
+	if got.Content != want {
+		t.Errorf("got %s, want %s", got.Content, want)
+	}
+}
+
+func TestSandbox_Config(t *testing.T) {
+	script := `
+function execute(action, args)
+  if action == "write" then
+    config.project = "HACK"
+    return "written"
+  end
+  return tostring(config.project) .. "/" .. tostring(config.missing)
+end
+`
+	opts := Options{Config: map[string]map[string]string{
+		"triage": {"project": "OPS"},
+		"other":  {"project": "NOT-MINE"},
+	}}
+	got, err := RunTool(context.Background(), writeScript(t, "triage.lua", script), opts, "read", nil)
+	if err != nil || got.Content != "OPS/nil" {
+		t.Errorf("read = %+v, %v; want only the script's own config", got, err)
+	}
+	if _, err := RunTool(context.Background(), writeScript(t, "triage.lua", script), opts, "write", nil); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("write err = %v; config must be read-only", err)
+	}
+	got, err = RunTool(context.Background(), writeScript(t, "unconfigured.lua", script), opts, "read", nil)
+	if err != nil || got.Content != "nil/nil" {
+		t.Errorf("unconfigured script = %+v, %v", got, err)
+	}
+}
+
+func TestSandbox_HTTPAllowlist(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get("X-Token"))
+		_, _ = fmt.Fprintf(w, "%s %s", r.Method, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	script := `
+function execute(action, args)
+  local res, err
+  if action == "post" then
+    res, err = http.request{ method = "post", url = args.url .. "/items", headers = { ["X-Token"] = "t1" }, body = "{}" }
+  else
+    res, err = http.get(args.url .. "/ping")
+  end
+  if not res then return nil, err end
+  return res.status .. " " .. res.body .. " " .. (res.headers["x-echo"] or "")
+end
+`
+	path := writeScript(t, "net.lua", script)
+	args := map[string]string{"url": srv.URL}
+
+	got, err := RunTool(context.Background(), path, Options{}, "get", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got.Error, "not in lua.http_allow") {
+		t.Errorf("request without allowlist: %+v", got)
+	}
+
+	opts := Options{HTTPAllow: []string{"127.0.0.1"}}
+	if got, _ = RunTool(context.Background(), path, opts, "get", args); got.Content != "200 GET /ping " {
+		t.Errorf("get = %+v", got)
+	}
+	if got, _ = RunTool(context.Background(), path, opts, "post", args); got.Content != "200 POST /items t1" {
+		t.Errorf("post = %+v", got)
+	}
+}
+
+func TestCheckHostWildcard(t *testing.T) {
+	allow := []string{"*.example.com", "api.other.org"}
+	for host, ok := range map[string]bool{
+		"https://a.example.com/x":  true,
+		"https://example.com/x":    false,
+		"https://api.other.org":    true,
+		"https://evil.com":         false,
+		"file:///etc/passwd":       false,
+		"https://a.example.com.io": false,
+	} {
+		u, _ := url.Parse(host)
+		if got := checkHost(u, allow) == nil; got != ok {
+			t.Errorf("%s allowed = %v, want %v", host, got, ok)
+		}
+	}
+}
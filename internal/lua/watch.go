@@ -0,0 +1,82 @@
+package lua
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the events an editor produces for one save.
+const watchDebounce = 300 * time.Millisecond
+
+// Watch calls onChange with the absolute path of each script in paths after
+// a write to it settles. Calls already pick up edits through the compile
+// cache; Watch is for state read once from a script, such as the plugin
+// table of a tool. Directories are watched rather than files so atomic
+// saves (write temp + rename) are seen. Watch returns once the watcher is
+// set up; it stops when ctx is done.
+func Watch(ctx context.Context, paths []string, onChange func(path string)) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	watched := make(map[string]bool, len(paths))
+	dirs := make(map[string]bool)
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			_ = w.Close()
+			return err
+		}
+		watched[abs] = true
+		dir := filepath.Dir(abs)
+		if dirs[dir] {
+			continue
+		}
+		if err := w.Add(dir); err != nil {
+			_ = w.Close()
+			return err
+		}
+		dirs[dir] = true
+	}
+
+	go func() {
+		defer func() { _ = w.Close() }()
+		pending := make(map[string]bool)
+		timer := time.NewTimer(watchDebounce)
+		timer.Stop()
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if !watched[ev.Name] || (!ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Rename)) {
+					continue
+				}
+				pending[ev.Name] = true
+				timer.Reset(watchDebounce)
+			case <-timer.C:
+				for p := range pending {
+					onChange(p)
+				}
+				clear(pending)
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("lua script watcher error", "component", "lua", "error", err)
+			}
+		}
+	}()
+	return nil
+}
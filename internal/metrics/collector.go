@@ -3,14 +3,19 @@ package metrics
 import (
 	"context"
 	"net/http"
+	"time"
 
+	"github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/internal/orchestrator"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Collector holds all OpenTalon Prometheus metrics and implements
-// orchestrator.UsageRecorder and orchestrator.PluginCallObserver.
+// orchestrator.UsageRecorder, orchestrator.PluginCallObserver,
+// orchestrator.TimingObserver, orchestrator.ExperimentObserver,
+// orchestrator.LoopObserver and channel.QueueObserver.
 type Collector struct {
 	reg *prometheus.Registry
 
@@ -23,6 +28,21 @@ type Collector struct {
 
 	pluginInputTokens  *prometheus.CounterVec
 	pluginOutputTokens *prometheus.CounterVec
+
+	runDuration      *prometheus.HistogramVec
+	runFirstToken    *prometheus.HistogramVec
+	stageDuration    *prometheus.HistogramVec
+	toolCallDuration *prometheus.HistogramVec
+	agentLoopStops   *prometheus.CounterVec
+
+	sessionScore *prometheus.HistogramVec
+
+	experimentRuns      *prometheus.CounterVec
+	experimentTokens    *prometheus.CounterVec
+	experimentToolCalls *prometheus.CounterVec
+
+	channelQueueDepth    *prometheus.GaugeVec
+	channelQueueOverflow *prometheus.CounterVec
 }
 
 // New creates and registers all metrics.
@@ -73,6 +93,66 @@ func New() *Collector {
 			Name: "opentalon_plugin_output_tokens_total",
 			Help: "Total LLM output tokens attributed to plugin/tool calls.",
 		}, []string{"plugin", "action"}),
+
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "opentalon_run_duration_seconds",
+			Help:    "Wall-clock time of orchestrator runs.",
+			Buckets: durationBuckets,
+		}, []string{"channel"}),
+
+		runFirstToken: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "opentalon_run_first_token_seconds",
+			Help:    "Time from the start of a run to the first streamed answer token.",
+			Buckets: durationBuckets,
+		}, []string{"channel"}),
+
+		stageDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "opentalon_stage_duration_seconds",
+			Help:    "Time spent in each stage of a run: preparers, planner, llm_round, format, summarize.",
+			Buckets: durationBuckets,
+		}, []string{"stage"}),
+
+		toolCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "opentalon_tool_call_duration_seconds",
+			Help:    "Time spent in each plugin/tool call.",
+			Buckets: durationBuckets,
+		}, []string{"plugin", "action", "status"}),
+
+		agentLoopStops: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opentalon_agent_loop_stops_total",
+			Help: "Agent loops stopped without an answer from the model: budget exhausted (iterations, duration) or looping (repeat, oscillation).",
+		}, []string{"channel", "reason"}),
+
+		sessionScore: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "opentalon_session_score",
+			Help:    "LLM-judge scores (1-5) of completed sessions, per rubric criterion, serving model and experiment variant.",
+			Buckets: []float64{1, 2, 3, 4, 5},
+		}, []string{"criterion", "model", "variant"}),
+
+		experimentRuns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opentalon_experiment_runs_total",
+			Help: "Orchestrator runs served by each experiment variant.",
+		}, []string{"variant"}),
+
+		experimentTokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opentalon_experiment_tokens_total",
+			Help: "LLM tokens used by runs of each experiment variant.",
+		}, []string{"variant", "direction"}),
+
+		experimentToolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opentalon_experiment_tool_calls_total",
+			Help: "Tool calls made by runs of each experiment variant.",
+		}, []string{"variant", "status"}),
+
+		channelQueueDepth: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "opentalon_channel_queue_depth",
+			Help: "Inbound messages waiting for a dispatch worker, per channel.",
+		}, []string{"channel"}),
+
+		channelQueueOverflow: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "opentalon_channel_queue_overflow_total",
+			Help: "Inbound messages that found their channel's dispatch queue full.",
+		}, []string{"channel", "action"}),
 	}
 
 	reg.MustRegister(
@@ -84,6 +164,17 @@ func New() *Collector {
 		c.pluginCalls,
 		c.pluginInputTokens,
 		c.pluginOutputTokens,
+		c.runDuration,
+		c.runFirstToken,
+		c.stageDuration,
+		c.toolCallDuration,
+		c.agentLoopStops,
+		c.sessionScore,
+		c.experimentRuns,
+		c.experimentTokens,
+		c.experimentToolCalls,
+		c.channelQueueDepth,
+		c.channelQueueOverflow,
 	)
 
 	return c
@@ -125,6 +216,70 @@ func (c *Collector) ObservePluginCall(plugin, action string, failed bool, inputT
 	c.pluginOutputTokens.With(tokenLabels).Add(float64(outputTokens))
 }
 
+// durationBuckets span fast tool calls to slow multi-round runs.
+var durationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// ObserveRunTiming implements orchestrator.TimingObserver. Stages that did
+// not run are not observed, so their histograms only count runs that used
+// them.
+func (c *Collector) ObserveRunTiming(channel string, t orchestrator.RunTiming) {
+	c.runDuration.WithLabelValues(channel).Observe(t.Total.Seconds())
+	if t.FirstToken > 0 {
+		c.runFirstToken.WithLabelValues(channel).Observe(t.FirstToken.Seconds())
+	}
+	for stage, d := range map[string]time.Duration{"preparers": t.Preparers, "planner": t.Planner, "format": t.Format} {
+		if d > 0 {
+			c.stageDuration.WithLabelValues(stage).Observe(d.Seconds())
+		}
+	}
+	for _, d := range t.LLMRounds {
+		c.stageDuration.WithLabelValues("llm_round").Observe(d.Seconds())
+	}
+	for _, tc := range t.ToolCalls {
+		status := "success"
+		if tc.Failed {
+			status = "error"
+		}
+		c.toolCallDuration.WithLabelValues(tc.Plugin, tc.Action, status).Observe(tc.Duration.Seconds())
+	}
+}
+
+// ObserveSummarization implements orchestrator.TimingObserver.
+func (c *Collector) ObserveSummarization(d time.Duration) {
+	c.stageDuration.WithLabelValues("summarize").Observe(d.Seconds())
+}
+
+// ObserveLoopStop implements orchestrator.LoopObserver.
+func (c *Collector) ObserveLoopStop(channel, reason string) {
+	c.agentLoopStops.WithLabelValues(channel, reason).Inc()
+}
+
+// ObserveSessionScore records one evaluation score. variant is empty for
+// sessions outside an experiment.
+func (c *Collector) ObserveSessionScore(criterion, model, variant string, score float64) {
+	c.sessionScore.WithLabelValues(criterion, model, variant).Observe(score)
+}
+
+// ObserveExperimentRun implements orchestrator.ExperimentObserver. The tool
+// error rate of a variant is its error calls over all its calls.
+func (c *Collector) ObserveExperimentRun(variant string, run orchestrator.ExperimentRun) {
+	c.experimentRuns.WithLabelValues(variant).Inc()
+	c.experimentTokens.WithLabelValues(variant, "input").Add(float64(run.InputTokens))
+	c.experimentTokens.WithLabelValues(variant, "output").Add(float64(run.OutputTokens))
+	c.experimentToolCalls.WithLabelValues(variant, "success").Add(float64(run.ToolCalls - run.ToolErrors))
+	c.experimentToolCalls.WithLabelValues(variant, "error").Add(float64(run.ToolErrors))
+}
+
+// ObserveQueueDepth implements channel.QueueObserver.
+func (c *Collector) ObserveQueueDepth(ch string, depth int) {
+	c.channelQueueDepth.WithLabelValues(ch).Set(float64(depth))
+}
+
+// ObserveQueueOverflow implements channel.QueueObserver.
+func (c *Collector) ObserveQueueOverflow(ch string, action channel.QueueOverflow) {
+	c.channelQueueOverflow.WithLabelValues(ch, string(action)).Inc()
+}
+
 // Handler returns an http.Handler that serves the /metrics endpoint.
 func (c *Collector) Handler() http.Handler {
 	return promhttp.HandlerFor(c.reg, promhttp.HandlerOpts{})
@@ -7,7 +7,10 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/opentalon/opentalon/internal/channel"
+	"github.com/opentalon/opentalon/internal/orchestrator"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 )
@@ -150,6 +153,117 @@ func TestObservePluginCallAccumulates(t *testing.T) {
 	}
 }
 
+func TestObserveRunTiming(t *testing.T) {
+	c := New()
+	c.ObserveRunTiming("slack", orchestrator.RunTiming{
+		Total:      3 * time.Second,
+		FirstToken: 800 * time.Millisecond,
+		Preparers:  100 * time.Millisecond,
+		LLMRounds:  []time.Duration{time.Second, 700 * time.Millisecond},
+		ToolCalls: []orchestrator.ToolTiming{
+			{Plugin: "jira", Action: "search", Duration: time.Second},
+			{Plugin: "jira", Action: "search", Duration: 2 * time.Second, Failed: true},
+		},
+	})
+	c.ObserveSummarization(4 * time.Second)
+
+	want := `
+# HELP opentalon_run_duration_seconds Wall-clock time of orchestrator runs.
+# TYPE opentalon_run_duration_seconds histogram
+opentalon_run_duration_seconds_bucket{channel="slack",le="0.05"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="0.1"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="0.25"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="0.5"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="1"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="2.5"} 0
+opentalon_run_duration_seconds_bucket{channel="slack",le="5"} 1
+opentalon_run_duration_seconds_bucket{channel="slack",le="10"} 1
+opentalon_run_duration_seconds_bucket{channel="slack",le="30"} 1
+opentalon_run_duration_seconds_bucket{channel="slack",le="60"} 1
+opentalon_run_duration_seconds_bucket{channel="slack",le="120"} 1
+opentalon_run_duration_seconds_bucket{channel="slack",le="+Inf"} 1
+opentalon_run_duration_seconds_sum{channel="slack"} 3
+opentalon_run_duration_seconds_count{channel="slack"} 1
+`
+	if err := testutil.GatherAndCompare(c.reg, strings.NewReader(want), "opentalon_run_duration_seconds"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(c.runFirstToken); n != 1 {
+		t.Errorf("first token series = %d, want 1", n)
+	}
+	// preparers, llm_round and summarize; planner and format did not run.
+	if n := testutil.CollectAndCount(c.stageDuration); n != 3 {
+		t.Errorf("stage series = %d, want 3", n)
+	}
+	if n := testutil.CollectAndCount(c.toolCallDuration); n != 2 {
+		t.Errorf("tool call series = %d, want 2 (success and error)", n)
+	}
+}
+
+func TestObserveSessionScore(t *testing.T) {
+	c := New()
+	c.ObserveSessionScore("helpfulness", "gpt-4o", "terse", 4)
+	c.ObserveSessionScore("helpfulness", "gpt-4o", "terse", 2)
+	c.ObserveSessionScore("resolution", "gpt-4o", "terse", 5)
+
+	want := `
+# HELP opentalon_session_score LLM-judge scores (1-5) of completed sessions, per rubric criterion, serving model and experiment variant.
+# TYPE opentalon_session_score histogram
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="1"} 0
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="2"} 1
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="3"} 1
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="4"} 2
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="5"} 2
+opentalon_session_score_bucket{criterion="helpfulness",model="gpt-4o",variant="terse",le="+Inf"} 2
+opentalon_session_score_sum{criterion="helpfulness",model="gpt-4o",variant="terse"} 6
+opentalon_session_score_count{criterion="helpfulness",model="gpt-4o",variant="terse"} 2
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="1"} 0
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="2"} 0
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="3"} 0
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="4"} 0
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="5"} 1
+opentalon_session_score_bucket{criterion="resolution",model="gpt-4o",variant="terse",le="+Inf"} 1
+opentalon_session_score_sum{criterion="resolution",model="gpt-4o",variant="terse"} 5
+opentalon_session_score_count{criterion="resolution",model="gpt-4o",variant="terse"} 1
+`
+	if err := testutil.GatherAndCompare(c.reg, strings.NewReader(want), "opentalon_session_score"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestObserveExperimentRun(t *testing.T) {
+	c := New()
+	c.ObserveExperimentRun("terse", orchestrator.ExperimentRun{InputTokens: 100, OutputTokens: 20, ToolCalls: 3, ToolErrors: 1})
+	c.ObserveExperimentRun("terse", orchestrator.ExperimentRun{InputTokens: 50, OutputTokens: 10})
+
+	if got := testutil.ToFloat64(c.experimentRuns.WithLabelValues("terse")); got != 2 {
+		t.Errorf("runs = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.experimentTokens.WithLabelValues("terse", "input")); got != 150 {
+		t.Errorf("input tokens = %v, want 150", got)
+	}
+	if got := testutil.ToFloat64(c.experimentToolCalls.WithLabelValues("terse", "success")); got != 2 {
+		t.Errorf("successful tool calls = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.experimentToolCalls.WithLabelValues("terse", "error")); got != 1 {
+		t.Errorf("failed tool calls = %v, want 1", got)
+	}
+}
+
+func TestObserveLoopStop(t *testing.T) {
+	c := New()
+	c.ObserveLoopStop("slack", orchestrator.LoopStopRepeat)
+	c.ObserveLoopStop("slack", orchestrator.LoopStopRepeat)
+	c.ObserveLoopStop("sms", orchestrator.LoopStopIterations)
+
+	if got := testutil.ToFloat64(c.agentLoopStops.WithLabelValues("slack", "repeat")); got != 2 {
+		t.Errorf("slack repeat stops = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(c.agentLoopStops.WithLabelValues("sms", "iterations")); got != 1 {
+		t.Errorf("sms iteration stops = %v, want 1", got)
+	}
+}
+
 func TestHandlerServesMetrics(t *testing.T) {
 	c := New()
 	c.RecordUsage(context.Background(), "e", "g1", "ch1", "s", "m1", 1, 1, 0, 0.0, 0.0)
@@ -182,3 +296,19 @@ func TestCollectAndCompareAfterUsage(t *testing.T) {
 		t.Errorf("metric mismatch: %v", err)
 	}
 }
+
+func TestObserveChannelQueue(t *testing.T) {
+	c := New()
+	var _ channel.QueueObserver = c
+	c.ObserveQueueDepth("slack", 3)
+	c.ObserveQueueDepth("slack", 1)
+	c.ObserveQueueOverflow("slack", channel.QueueReject)
+	c.ObserveQueueOverflow("slack", channel.QueueReject)
+
+	if got := testutil.ToFloat64(c.channelQueueDepth.WithLabelValues("slack")); got != 1 {
+		t.Errorf("queue depth = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(c.channelQueueOverflow.WithLabelValues("slack", "reject")); got != 2 {
+		t.Errorf("rejected = %v, want 2", got)
+	}
+}
@@ -0,0 +1,141 @@
+// Package notify provides the built-in notify tool, through which the agent
+// posts a message to another channel or conversation on its own initiative
+// ("also post this summary to #release"). Only configured targets are
+// reachable: named targets (a fixed channel and conversation) and channels
+// on which any conversation may be addressed.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+const ToolName = "notify"
+
+// maxMessageChars bounds one message; a longer one is almost certainly a
+// whole transcript the model should not be broadcasting.
+const maxMessageChars = 4000
+
+// Sender delivers a message to a conversation on a channel. The scheduler's
+// channel notifier satisfies it.
+type Sender interface {
+	Notify(ctx context.Context, channelID, conversationID, content string) error
+}
+
+// Target is a named destination, e.g. "release" for the #release channel
+// on Slack.
+type Target struct {
+	Channel      string
+	Conversation string
+}
+
+// Config lists where the tool may post.
+type Config struct {
+	Targets       map[string]Target // name -> destination
+	Channels      []string          // channels on which any conversation may be addressed by id
+	AllowedGroups []string          // profile groups that may use the tool; empty = everyone
+}
+
+// Validate reports a configuration that leaves the tool nowhere to post or
+// names an incomplete target.
+func (c Config) Validate() error {
+	if len(c.Targets) == 0 && len(c.Channels) == 0 {
+		return fmt.Errorf("no targets or channels configured")
+	}
+	for name, t := range c.Targets {
+		if t.Channel == "" || t.Conversation == "" {
+			return fmt.Errorf("target %q needs both channel and conversation", name)
+		}
+	}
+	return nil
+}
+
+// Tool is the built-in notify plugin.
+type Tool struct {
+	sender Sender
+	cfg    Config
+}
+
+func NewTool(sender Sender, cfg Config) *Tool {
+	return &Tool{sender: sender, cfg: cfg}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	var where []string
+	if len(t.cfg.Targets) > 0 {
+		where = append(where, "named targets: "+strings.Join(slices.Sorted(maps.Keys(t.cfg.Targets)), ", "))
+	}
+	if len(t.cfg.Channels) > 0 {
+		where = append(where, "any conversation id on channels: "+strings.Join(t.cfg.Channels, ", "))
+	}
+	return orchestrator.PluginCapability{
+		Name: ToolName,
+		Description: "Post a message to another channel or conversation, e.g. when the user asks to also share a summary with a team. " +
+			"The reply to the user still goes to the current conversation as usual; use this only for an explicit extra destination. Allowed " + strings.Join(where, "; ") + ".",
+		AllowedGroups: t.cfg.AllowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name:        "send",
+				Description: "Send a message to a named target, or to a conversation on an allowed channel.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "message", Description: "The text to post, written for its readers", Required: true},
+					{Name: "target", Description: "Named target; alternatively give channel and conversation_id", Required: false},
+					{Name: "channel", Description: "Channel id, used with conversation_id", Required: false},
+					{Name: "conversation_id", Description: "Conversation (room, chat) id on that channel", Required: false},
+				},
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Action != "send" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown notify action: %s", call.Action)}
+	}
+	msg := strings.TrimSpace(call.Args["message"])
+	if msg == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "message is required"}
+	}
+	if n := utf8.RuneCountInString(msg); n > maxMessageChars {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("message is %d characters; the limit is %d", n, maxMessageChars)}
+	}
+	dest, err := t.resolve(call.Args)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if err := t.sender.Notify(ctx, dest.Channel, dest.Conversation, msg); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("sending to %s/%s failed: %v", dest.Channel, dest.Conversation, err)}
+	}
+	slog.Info("audit", "event", "notification_sent", "actor", actor.Actor(ctx), "session_id", actor.SessionID(ctx),
+		"channel", dest.Channel, "conversation", dest.Conversation, "chars", utf8.RuneCountInString(msg))
+	return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("Sent to %s/%s.", dest.Channel, dest.Conversation)}
+}
+
+// resolve picks the destination from a target name or channel and
+// conversation id, refusing anything the config does not allow.
+func (t *Tool) resolve(args map[string]string) (Target, error) {
+	if name := strings.TrimPrefix(strings.TrimSpace(args["target"]), "#"); name != "" {
+		for n, dest := range t.cfg.Targets {
+			if strings.EqualFold(n, name) {
+				return dest, nil
+			}
+		}
+		return Target{}, fmt.Errorf("unknown target %q", name)
+	}
+	ch, conv := strings.TrimSpace(args["channel"]), strings.TrimSpace(args["conversation_id"])
+	if ch == "" || conv == "" {
+		return Target{}, fmt.Errorf("give a target, or both channel and conversation_id")
+	}
+	if !slices.Contains(t.cfg.Channels, ch) {
+		return Target{}, fmt.Errorf("channel %q is not allowed for notifications", ch)
+	}
+	return Target{Channel: ch, Conversation: conv}, nil
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+type sent struct{ channel, conversation, content string }
+
+type fakeSender struct {
+	sent []sent
+	err  error
+}
+
+func (f *fakeSender) Notify(_ context.Context, channelID, conversationID, content string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, sent{channelID, conversationID, content})
+	return nil
+}
+
+func send(tool *Tool, args map[string]string) orchestrator.ToolResult {
+	return tool.Execute(context.Background(), orchestrator.ToolCall{ID: "c1", Plugin: ToolName, Action: "send", Args: args})
+}
+
+func TestSend(t *testing.T) {
+	sender := &fakeSender{}
+	tool := NewTool(sender, Config{
+		Targets:  map[string]Target{"release": {Channel: "slack", Conversation: "C0REL"}},
+		Channels: []string{"telegram"},
+	})
+	if !strings.Contains(tool.Capability().Description, "named targets: release") {
+		t.Errorf("description does not name the targets: %s", tool.Capability().Description)
+	}
+
+	if res := send(tool, map[string]string{"target": "#Release", "message": "v1.2 is out"}); res.Error != "" || res.Content != "Sent to slack/C0REL." {
+		t.Fatalf("named target = %+v", res)
+	}
+	if res := send(tool, map[string]string{"channel": "telegram", "conversation_id": "-100", "message": "hi"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	want := []sent{{"slack", "C0REL", "v1.2 is out"}, {"telegram", "-100", "hi"}}
+	if len(sender.sent) != 2 || sender.sent[0] != want[0] || sender.sent[1] != want[1] {
+		t.Errorf("sent = %+v, want %+v", sender.sent, want)
+	}
+}
+
+func TestSendRefuses(t *testing.T) {
+	sender := &fakeSender{}
+	tool := NewTool(sender, Config{
+		Targets:  map[string]Target{"release": {Channel: "slack", Conversation: "C0REL"}},
+		Channels: []string{"telegram"},
+	})
+	for name, args := range map[string]map[string]string{
+		"no message":          {"target": "release"},
+		"unknown target":      {"target": "general", "message": "x"},
+		"channel not allowed": {"channel": "slack", "conversation_id": "C0GEN", "message": "x"},
+		"no conversation":     {"channel": "telegram", "message": "x"},
+		"too long":            {"target": "release", "message": strings.Repeat("x", maxMessageChars+1)},
+	} {
+		if res := send(tool, args); res.Error == "" {
+			t.Errorf("%s: expected an error, got %+v", name, res)
+		}
+	}
+	if len(sender.sent) != 0 {
+		t.Errorf("refused calls were sent: %+v", sender.sent)
+	}
+
+	sender.err = errors.New("channel down")
+	if res := send(tool, map[string]string{"target": "release", "message": "x"}); !strings.Contains(res.Error, "channel down") {
+		t.Errorf("send failure = %+v", res)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{}).Validate(); err == nil {
+		t.Error("a config with nowhere to post should fail")
+	}
+	if err := (Config{Targets: map[string]Target{"x": {Channel: "slack"}}}).Validate(); err == nil {
+		t.Error("a target without conversation should fail")
+	}
+	if err := (Config{Channels: []string{"slack"}}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,87 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// ActorProfileStore loads and saves the preferences actors set for
+// themselves (see state.ActorProfile). ActorProfile returns nil, nil when
+// the actor has none.
+type ActorProfileStore interface {
+	ActorProfile(ctx context.Context, actorID string) (*state.ActorProfile, error)
+	SaveActorProfile(ctx context.Context, p *state.ActorProfile) error
+}
+
+type actorProfileKey struct{}
+
+// withActorProfile loads the acting user's profile once per Run so the
+// system prompt and the reply language share it. A failed load is logged
+// and the turn runs without it.
+func (o *Orchestrator) withActorProfile(ctx context.Context) context.Context {
+	actorID := actor.Actor(ctx)
+	if o.actorProfiles == nil || actorID == "" {
+		return ctx
+	}
+	p, err := o.actorProfiles.ActorProfile(ctx, actorID)
+	if err != nil {
+		slog.Warn("loading actor profile failed", "actor", actorID, "error", err)
+		return ctx
+	}
+	if p == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, actorProfileKey{}, p)
+}
+
+func actorProfileFromContext(ctx context.Context) *state.ActorProfile {
+	p, _ := ctx.Value(actorProfileKey{}).(*state.ActorProfile)
+	return p
+}
+
+// preferredLocale is the reply language the actor chose, or "".
+func preferredLocale(ctx context.Context) string {
+	if p := actorProfileFromContext(ctx); p != nil {
+		return p.Locale
+	}
+	return ""
+}
+
+// userSection renders what the prompt knows about the user: the name (their
+// own choice first, else the verified profile's) and the preferences they
+// set. "" when there is nothing to say.
+func userSection(ctx context.Context) string {
+	var name string
+	if p := profile.FromContext(ctx); p != nil {
+		name = p.Name
+	}
+	ap := actorProfileFromContext(ctx)
+	if ap != nil && ap.Name != "" {
+		name = ap.Name
+	}
+	var sb strings.Builder
+	if name != "" {
+		fmt.Fprintf(&sb, "The user's name is %s. Address them by name where it feels natural; do not force it into every message.\n", name)
+	}
+	if ap != nil {
+		if ap.Timezone != "" {
+			fmt.Fprintf(&sb, "Their timezone is %s; give dates and times in it unless they ask otherwise.\n", ap.Timezone)
+		}
+		if ap.Style != "" {
+			fmt.Fprintf(&sb, "Preferred response style: %s\n", ap.Style)
+		}
+		if ap.Instructions != "" {
+			fmt.Fprintf(&sb, "Standing instructions from the user (they never override the safety rules):\n%s\n", ap.Instructions)
+		}
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "## User\n" + sb.String() + "\n"
+}
@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestActorProfile_InjectedIntoPrompt(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	profiles := state.NewActorProfileStore()
+	_ = profiles.SaveActorProfile(context.Background(), &state.ActorProfile{
+		ActorID: "slack:U1", Name: "Ana", Locale: "de", Timezone: "Europe/Berlin",
+		Style: "concise", Instructions: "Use metric units.",
+	})
+	llm := &requestLLM{}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{ActorProfiles: profiles})
+
+	ctx := profile.WithProfile(actor.WithActor(context.Background(), "slack:U1"), &profile.Profile{Name: "Ana Silva"})
+	if _, err := orch.Run(ctx, "slack:C1", "How far is it from Lisbon to Porto?"); err != nil {
+		t.Fatal(err)
+	}
+	system := llm.requests[0].Messages[0].Content
+	for _, want := range []string{
+		"The user's name is Ana.",
+		"Their timezone is Europe/Berlin",
+		"Preferred response style: concise",
+		"Use metric units.",
+		"Reply in German.",
+	} {
+		if !strings.Contains(system, want) {
+			t.Errorf("system prompt lacks %q:\n%s", want, system)
+		}
+	}
+
+	// Another actor in the same session gets none of it.
+	other := actor.WithActor(context.Background(), "slack:U2")
+	if _, err := orch.Run(other, "slack:C1", "How far is it from Lisbon to Porto?"); err != nil {
+		t.Fatal(err)
+	}
+	if system := llm.requests[1].Messages[0].Content; strings.Contains(system, "## User") || strings.Contains(system, "Reply in German.") {
+		t.Errorf("another actor's prompt carries the profile:\n%s", system)
+	}
+}
@@ -0,0 +1,175 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+const (
+	// agentPluginName is the built-in plugin for multi-agent control. It is
+	// registered only when two or more agents are configured. The leading
+	// underscore keeps it out of every agent's plugin scope check.
+	agentPluginName = "_agent"
+	// agentHandoffAction transfers the conversation to another agent. The
+	// fully-qualified name LLMs see is "_agent__handoff".
+	agentHandoffAction = "handoff"
+
+	// MetaHandoffNote is the session metadata key holding the latest
+	// handoff (JSON handoffNote), shown to the receiving agent.
+	MetaHandoffNote = "handoff_note"
+
+	maxHandoffSummaryRunes = 2000
+)
+
+// handoffNote is what the receiving agent is told about a handoff.
+type handoffNote struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Summary string `json:"summary"`
+}
+
+// registerAgentTools registers _agent when there is more than one agent to
+// hand off between.
+func (o *Orchestrator) registerAgentTools() {
+	if len(o.agents.List) < 2 {
+		return
+	}
+	var names strings.Builder
+	for _, ag := range o.agents.List {
+		fmt.Fprintf(&names, "\n- %s", ag.Name)
+		if ag.Description != "" {
+			fmt.Fprintf(&names, ": %s", ag.Description)
+		}
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        agentPluginName,
+		Description: "Hand the conversation to another agent",
+		Actions: []Action{{
+			Name: agentHandoffAction,
+			Description: "Transfer this conversation to another agent when the request is outside your scope. " +
+				"The other agent takes over from the user's next message and sees your summary. Available agents:" + names.String(),
+			AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "agent", Description: "Name of the agent to hand off to", Required: true},
+				{Name: "summary", Description: "What the user needs and what has been done so far, for the next agent", Required: true},
+			},
+		}},
+	}, &agentExecutor{orch: o})
+	if o.toolApprovals.queue != nil {
+		o.toolApprovals.queue.Handle(approval.KindHandoff, o.runApprovedHandoff)
+	}
+}
+
+type agentExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *agentExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	if call.Action != agentHandoffAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	o := e.orch
+	sessionID := actor.SessionID(ctx)
+	if sessionID == "" {
+		return ToolResult{CallID: call.ID, Error: "handoff needs a session"}
+	}
+	target := o.agents.lookup(strings.TrimSpace(call.Args["agent"]))
+	if target == nil {
+		names := make([]string, 0, len(o.agents.List))
+		for _, ag := range o.agents.List {
+			names = append(names, ag.Name)
+		}
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown agent %q; available: %s", call.Args["agent"], strings.Join(names, ", "))}
+	}
+	var from string
+	if cur := agentFromContext(ctx); cur != nil {
+		from = cur.Name
+	}
+	if strings.EqualFold(from, target.Name) {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("this conversation is already handled by %s", target.Name)}
+	}
+	summary := truncateRunes(strings.TrimSpace(call.Args["summary"]), maxHandoffSummaryRunes)
+	if summary == "" {
+		return ToolResult{CallID: call.ID, Error: "summary is required so the next agent knows what the user needs"}
+	}
+	note := handoffNote{From: from, To: target.Name, Summary: summary}
+
+	if target.HandoffApproval && o.toolApprovals.queue != nil {
+		data, _ := json.Marshal(note)
+		req, err := o.toolApprovals.queue.Submit(approval.Request{
+			Kind:           approval.KindHandoff,
+			Summary:        fmt.Sprintf("hand conversation %s to %s: %s", sessionID, target.Name, truncateRunes(summary, 120)),
+			Requester:      actor.Actor(ctx),
+			ChannelID:      currentChannelID(ctx),
+			ConversationID: actor.ConversationID(ctx),
+			SessionID:      sessionID,
+			Payload:        map[string]string{"note": string(data)},
+		})
+		if err != nil {
+			return ToolResult{CallID: call.ID, Error: fmt.Sprintf("handoff to %s needs approval, but filing it failed: %v", target.Name, err)}
+		}
+		return ToolResult{CallID: call.ID, Content: fmt.Sprintf(
+			"Handoff to %s needs an admin's approval and was submitted (request %s). Until then you keep handling this conversation; tell the user it is being escalated.",
+			target.Name, req.ID)}
+	}
+
+	if err := handOff(o.sessions, sessionID, note); err != nil {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("handoff: %v", err)}
+	}
+	slog.Info("audit", "event", "agent_handoff", "session_id", sessionID, "from", from, "to", target.Name)
+	return ToolResult{CallID: call.ID, Content: fmt.Sprintf(
+		"Conversation handed off to %s, who takes over from the user's next message with your summary. "+
+			"Tell the user in one short sentence that %s will continue from here. Do not try to answer the request yourself.",
+		target.Name, target.Name)}
+}
+
+// runApprovedHandoff is the approval.KindHandoff handler.
+func (o *Orchestrator) runApprovedHandoff(_ context.Context, req approval.Request) (string, error) {
+	var note handoffNote
+	if err := json.Unmarshal([]byte(req.Payload["note"]), &note); err != nil {
+		return "", fmt.Errorf("decoding handoff: %w", err)
+	}
+	if o.agents.lookup(note.To) == nil {
+		return "", fmt.Errorf("agent %q is no longer configured", note.To)
+	}
+	if err := handOff(o.sessions, req.SessionID, note); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s will continue this conversation from your next message.", note.To), nil
+}
+
+// handOff makes note.To the session's agent and leaves the note for it.
+func handOff(sessions SessionStoreInterface, sessionID string, note handoffNote) error {
+	data, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+	if err := sessions.SetMetadata(sessionID, MetaHandoffNote, string(data)); err != nil {
+		return err
+	}
+	return sessions.SetMetadata(sessionID, MetaAgent, note.To)
+}
+
+// handoffSection renders the latest handoff for the agent it was addressed
+// to, or "" when there is none for ag.
+func handoffSection(sess *state.Session, ag *Agent) string {
+	if sess == nil || ag == nil || sess.Metadata[MetaHandoffNote] == "" {
+		return ""
+	}
+	var note handoffNote
+	if err := json.Unmarshal([]byte(sess.Metadata[MetaHandoffNote]), &note); err != nil || !strings.EqualFold(note.To, ag.Name) {
+		return ""
+	}
+	from := note.From
+	if from == "" {
+		from = "the assistant"
+	}
+	return fmt.Sprintf("## Handoff\nThis conversation was handed to you by %s. Their summary:\n%s\n\n", from, note.Summary)
+}
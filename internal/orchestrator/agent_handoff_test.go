@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newHandoffOrchestrator(t *testing.T, llm LLMClient, q *approval.Queue) (*Orchestrator, *state.SessionStore) {
+	t.Helper()
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{
+			Agents: Agents{List: []Agent{
+				{Name: "support-bot", Description: "Customer questions", Plugins: []string{"tickets"}},
+				{Name: "billing-bot", Description: "Refunds and invoices"},
+				{Name: "devops-bot", HandoffApproval: true},
+			}, Default: "support-bot"},
+			ToolApprovals: ToolApprovals{Queue: q},
+		})
+	return orch, sessions
+}
+
+func TestAgentHandoff_SwitchesAgentAndBriefsIt(t *testing.T) {
+	llm := &requestLLM{}
+	orch, sessions := newHandoffOrchestrator(t, llm, nil)
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+
+	if _, err := orch.Run(ctx, "slack:C1", "I was charged twice"); err != nil {
+		t.Fatal(err)
+	}
+	if s := llm.requests[0].Messages[0].Content; !strings.Contains(s, "## _agent") || !strings.Contains(s, "billing-bot: Refunds and invoices") {
+		t.Errorf("handoff tool should be offered despite the agent's plugin scope:\n%s", s)
+	}
+
+	turnCtx := withAgent(actor.WithSessionID(ctx, "slack:C1"), orch.agents.lookup("support-bot"))
+	res := orch.executeCall(turnCtx, ToolCall{ID: "h1", Plugin: agentPluginName, Action: agentHandoffAction, FromLLM: true,
+		Args: map[string]string{"agent": "billing-bot", "summary": "Customer was charged twice for order 1182."}})
+	if res.Error != "" || !strings.Contains(res.Content, "handed off to billing-bot") {
+		t.Fatalf("handoff result = %+v", res)
+	}
+	if got := mustSession(t, sessions, "slack:C1").Metadata[MetaAgent]; got != "billing-bot" {
+		t.Errorf("session agent = %q", got)
+	}
+
+	if _, err := orch.Run(ctx, "slack:C1", "any news?"); err != nil {
+		t.Fatal(err)
+	}
+	s := llm.requests[len(llm.requests)-1].Messages[0].Content
+	for _, want := range []string{"## You are billing-bot", "handed to you by support-bot", "charged twice for order 1182"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("billing-bot prompt missing %q:\n%s", want, s)
+		}
+	}
+
+	for args, wantErr := range map[[2]string]string{
+		{"billing-bot", "x"}:  "already handled by billing-bot",
+		{"nobody", "x"}:       "unknown agent",
+		{"support-bot", "  "}: "summary is required",
+	} {
+		res := orch.executeCall(withAgent(turnCtx, orch.agents.lookup("billing-bot")), ToolCall{ID: "h", Plugin: agentPluginName, Action: agentHandoffAction,
+			Args: map[string]string{"agent": args[0], "summary": args[1]}})
+		if !strings.Contains(res.Error, wantErr) {
+			t.Errorf("handoff(%q) error = %q; want %q", args, res.Error, wantErr)
+		}
+	}
+}
+
+func TestAgentHandoff_NeedsApproval(t *testing.T) {
+	q, err := approval.NewQueue(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orch, sessions := newHandoffOrchestrator(t, &requestLLM{}, q)
+	ctx := withAgent(actor.WithSessionID(actor.WithActor(context.Background(), "slack:U1"), "slack:C1"), orch.agents.lookup("support-bot"))
+
+	res := orch.executeCall(ctx, ToolCall{ID: "h1", Plugin: agentPluginName, Action: agentHandoffAction,
+		Args: map[string]string{"agent": "devops-bot", "summary": "Checkout returns 502s."}})
+	if res.Error != "" || !strings.Contains(res.Content, "needs an admin's approval") {
+		t.Fatalf("result = %+v", res)
+	}
+	if got := mustSession(t, sessions, "slack:C1").Metadata[MetaAgent]; got == "devops-bot" {
+		t.Fatal("agent must not switch before approval")
+	}
+	pending := q.List(approval.StatusPending)
+	if len(pending) != 1 || pending[0].Kind != approval.KindHandoff {
+		t.Fatalf("queue = %+v", pending)
+	}
+	if _, err := q.Approve(context.Background(), pending[0].ID, "alice", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, sessions, "slack:C1").Metadata[MetaAgent]; got != "devops-bot" {
+		t.Errorf("after approval agent = %q", got)
+	}
+}
+
+func TestAgentHandoff_NotRegisteredForSingleAgent(t *testing.T) {
+	reg := NewToolRegistry()
+	NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{Agents: Agents{List: []Agent{{Name: "solo"}}}})
+	if _, ok := reg.GetCapability(agentPluginName); ok {
+		t.Error("_agent needs at least two agents")
+	}
+}
@@ -0,0 +1,206 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// MetaAgent is the session metadata key recording the agent that handles the
+// session. Later turns stay with that agent until a message @mentions another.
+const MetaAgent = "agent"
+
+// Agent is a config-defined persona. Several agents can share one process
+// and one plugin registry; each turn runs as exactly one of them.
+type Agent struct {
+	Name         string
+	Description  string   // one line on what the agent handles
+	SystemPrompt string   // persona instructions, rendered before the built-in preamble
+	Plugins      []string // plugins the agent may use; empty = every plugin the caller may use
+	Model        string   // model pin ("provider/model" or "model"); empty = routing default
+	// HandoffApproval files handoffs to this agent in the approval queue
+	// instead of switching immediately (e.g. escalation to a privileged agent).
+	HandoffApproval bool
+	// Generation tunes this agent's turns (e.g. a low temperature for a
+	// support agent); unset fields use the orchestrator-wide settings.
+	Generation Generation
+	// LoopLimits bounds this agent's agent loop; unset fields use the
+	// channel's, then the orchestrator-wide limits.
+	LoopLimits LoopLimits
+}
+
+// Agents holds the configured personas and the rules that pick one per turn.
+// Selection order: an @name mention in the message, else the agent already
+// recorded on the session, else the channel's agent (keyed by channel id,
+// i.e. the entry name under `channels:`), else Default. With no agents
+// configured every turn runs as the plain assistant.
+type Agents struct {
+	List     []Agent
+	Channels map[string]string // channel id -> agent name
+	Default  string            // agent name; empty = plain assistant
+}
+
+// Validate reports duplicate agent names and routes to unknown agents.
+func (a Agents) Validate() error {
+	names := make(map[string]bool, len(a.List))
+	for _, ag := range a.List {
+		key := strings.ToLower(ag.Name)
+		if !agentNamePattern.MatchString(ag.Name) {
+			return fmt.Errorf("agent name %q: use letters, digits, '-', '_' or '.'", ag.Name)
+		}
+		if names[key] {
+			return fmt.Errorf("duplicate agent %q", ag.Name)
+		}
+		names[key] = true
+		if err := ag.Generation.Validate(); err != nil {
+			return fmt.Errorf("agent %q: %w", ag.Name, err)
+		}
+		if err := ag.LoopLimits.Validate(); err != nil {
+			return fmt.Errorf("agent %q: %w", ag.Name, err)
+		}
+	}
+	for ch, name := range a.Channels {
+		if !names[strings.ToLower(name)] {
+			return fmt.Errorf("channel %q routes to unknown agent %q", ch, name)
+		}
+	}
+	if a.Default != "" && !names[strings.ToLower(a.Default)] {
+		return fmt.Errorf("default agent %q is not configured", a.Default)
+	}
+	return nil
+}
+
+var (
+	agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	agentMention     = regexp.MustCompile(`(?:^|\s)@([A-Za-z0-9][A-Za-z0-9_.-]*)`)
+)
+
+// lookup returns the agent with name (case-insensitive), or nil.
+func (a Agents) lookup(name string) *Agent {
+	if name == "" {
+		return nil
+	}
+	for i := range a.List {
+		if strings.EqualFold(a.List[i].Name, name) {
+			return &a.List[i]
+		}
+	}
+	return nil
+}
+
+// mentioned returns the first configured agent @mentioned in message.
+func (a Agents) mentioned(message string) *Agent {
+	for _, m := range agentMention.FindAllStringSubmatch(message, -1) {
+		// Trailing punctuation ("@devops-bot, can you…") is not part of the name.
+		if ag := a.lookup(strings.TrimRight(m[1], ".-_")); ag != nil {
+			return ag
+		}
+	}
+	return nil
+}
+
+type agentKey struct{}
+
+// agentTurn is the agent a turn runs as, plus the handoff it was given.
+type agentTurn struct {
+	agent   *Agent
+	handoff string // rendered handoffSection; "" when none
+}
+
+// withAgent records the acting agent on ctx.
+func withAgent(ctx context.Context, ag *Agent) context.Context {
+	return context.WithValue(ctx, agentKey{}, agentTurn{agent: ag})
+}
+
+// agentFromContext returns the agent the turn runs as, or nil.
+func agentFromContext(ctx context.Context) *Agent {
+	t, _ := ctx.Value(agentKey{}).(agentTurn)
+	return t.agent
+}
+
+// selectAgent picks the agent for this turn, records it on the session when
+// it changed, and puts it (with any handoff addressed to it) on ctx.
+// sess may be nil.
+func (o *Orchestrator) selectAgent(ctx context.Context, sessions SessionStoreInterface, sessionID string, sess *state.Session, userMessage string) context.Context {
+	if len(o.agents.List) == 0 {
+		return ctx
+	}
+	var recorded string
+	if sess != nil {
+		recorded = sess.Metadata[MetaAgent]
+	}
+	ag := o.agents.mentioned(userMessage)
+	if ag == nil {
+		ag = o.agents.lookup(recorded)
+	}
+	if ag == nil {
+		ag = o.agents.lookup(o.agents.Channels[currentChannelID(ctx)])
+	}
+	if ag == nil {
+		ag = o.agents.lookup(o.agents.Default)
+	}
+	if ag == nil {
+		return ctx
+	}
+	if ag.Name != recorded {
+		if err := sessions.SetMetadata(sessionID, MetaAgent, ag.Name); err != nil {
+			slog.Warn("recording session agent failed", "session_id", sessionID, "agent", ag.Name, "error", err)
+		}
+	}
+	return context.WithValue(ctx, agentKey{}, agentTurn{agent: ag, handoff: handoffSection(sess, ag)})
+}
+
+// agentSection renders the acting agent's persona, and the handoff that
+// brought the conversation to it, for the system prompt; "" when the turn
+// runs as the plain assistant.
+func agentSection(ctx context.Context) string {
+	t, _ := ctx.Value(agentKey{}).(agentTurn)
+	if t.agent == nil {
+		return ""
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## You are %s\n", t.agent.Name)
+	if s := strings.TrimSpace(t.agent.SystemPrompt); s != "" {
+		sb.WriteString(s)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(t.handoff)
+	return sb.String()
+}
+
+// agentPlugins returns the acting agent's plugin allowlist, or nil when the
+// agent (or the plain assistant) is not restricted.
+func agentPlugins(ctx context.Context) map[string]bool {
+	ag := agentFromContext(ctx)
+	if ag == nil || len(ag.Plugins) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(ag.Plugins))
+	for _, p := range ag.Plugins {
+		m[p] = true
+	}
+	return m
+}
+
+// MetaModel is the session metadata key pinning one session to a model
+// ("provider/model"); the set_session_model command (/model) sets it. It is
+// kept apart from the session's ActiveModel, which Run stamps with whichever
+// model answered the last turn.
+const MetaModel = "model"
+
+// modelPin resolves a catalog alias ("opus") and strips the provider prefix
+// from the "provider/model" pin.
+func (o *Orchestrator) modelPin(m string) string {
+	if ref, ok := o.modelAliases[strings.ToLower(m)]; ok {
+		m = ref
+	}
+	if idx := strings.Index(m, "/"); idx >= 0 {
+		return m[idx+1:]
+	}
+	return m
+}
@@ -0,0 +1,127 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// requestLLM records every completion request it serves.
+type requestLLM struct {
+	requests []provider.CompletionRequest
+}
+
+func (r *requestLLM) Complete(_ context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	r.requests = append(r.requests, *req)
+	return &provider.CompletionResponse{Content: "ok"}, nil
+}
+
+func TestAgents_Validate(t *testing.T) {
+	ok := Agents{List: []Agent{{Name: "support-bot"}, {Name: "devops-bot"}}, Channels: map[string]string{"slack": "Support-Bot"}, Default: "devops-bot"}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []Agents{
+		{List: []Agent{{Name: "a"}, {Name: "A"}}},
+		{List: []Agent{{Name: "has space"}}},
+		{List: []Agent{{Name: "a"}}, Channels: map[string]string{"slack": "b"}},
+		{List: []Agent{{Name: "a"}}, Default: "b"},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v should not validate", bad)
+		}
+	}
+}
+
+func TestAgents_Mentioned(t *testing.T) {
+	a := Agents{List: []Agent{{Name: "support-bot"}, {Name: "devops-bot"}}}
+	for msg, want := range map[string]string{
+		"@devops-bot, is prod up?":  "devops-bot",
+		"hey @Support-Bot help":     "support-bot",
+		"mail me at ops@devops-bot": "",
+		"@nobody and @devops-bot.":  "devops-bot",
+		"no mention":                "",
+	} {
+		got := ""
+		if ag := a.mentioned(msg); ag != nil {
+			got = ag.Name
+		}
+		if got != want {
+			t.Errorf("mentioned(%q) = %q; want %q", msg, got, want)
+		}
+	}
+}
+
+func TestAgents_RoutingAndScope(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "tickets", Description: "Support tickets",
+		Actions: []Action{{Name: "open", Description: "Open a ticket"}}}, &echoExecutor{})
+	_ = reg.Register(PluginCapability{Name: "k8s", Description: "Cluster ops",
+		Actions: []Action{{Name: "rollout", Description: "Restart a deployment"}}}, &echoExecutor{})
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	llm := &requestLLM{}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{Agents: Agents{
+			List: []Agent{
+				{Name: "support-bot", SystemPrompt: "You help customers.", Plugins: []string{"tickets"}},
+				{Name: "devops-bot", SystemPrompt: "You run the cluster.", Plugins: []string{"k8s"}, Model: "anthropic/claude-ops"},
+			},
+			Channels: map[string]string{"slack": "support-bot"},
+		}})
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+	system := func() string { return llm.requests[len(llm.requests)-1].Messages[0].Content }
+
+	// Channel route.
+	if _, err := orch.Run(ctx, "slack:C1", "my order is late"); err != nil {
+		t.Fatal(err)
+	}
+	if s := system(); !strings.Contains(s, "## You are support-bot\nYou help customers.") || !strings.Contains(s, "## tickets") || strings.Contains(s, "## k8s") {
+		t.Errorf("support-bot prompt:\n%s", s)
+	}
+	if got := mustSession(t, sessions, "slack:C1").Metadata[MetaAgent]; got != "support-bot" {
+		t.Errorf("session agent = %q", got)
+	}
+
+	// An @mention switches the session, and the switch sticks.
+	if _, err := orch.Run(ctx, "slack:C1", "@devops-bot restart the api"); err != nil {
+		t.Fatal(err)
+	}
+	if s := system(); !strings.Contains(s, "## You are devops-bot") || !strings.Contains(s, "## k8s") || strings.Contains(s, "## tickets") {
+		t.Errorf("devops-bot prompt:\n%s", s)
+	}
+	if got := llm.requests[len(llm.requests)-1].Model; got != "claude-ops" {
+		t.Errorf("model = %q; want the agent's pin without the provider prefix", got)
+	}
+	if _, err := orch.Run(ctx, "slack:C1", "and the worker too"); err != nil {
+		t.Fatal(err)
+	}
+	if s := system(); !strings.Contains(s, "## You are devops-bot") {
+		t.Error("the session should stay with the agent it was switched to")
+	}
+
+	// An LLM call outside the agent's plugins is refused.
+	scoped := withAgent(ctx, orch.agents.lookup("devops-bot"))
+	res := orch.executeCall(withAllowedPlugins(scoped, orch.resolveAllowedPlugins(scoped)),
+		ToolCall{ID: "c", Plugin: "tickets", Action: "open", FromLLM: true})
+	if !strings.Contains(res.Error, "not available") {
+		t.Errorf("out-of-scope call = %+v", res)
+	}
+}
+
+func TestAgents_NoneConfiguredLeavesPromptAlone(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: []string{"ok"}}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	if _, err := orch.Run(context.Background(), "s1", "@devops-bot hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, sessions, "s1").Metadata[MetaAgent]; got != "" {
+		t.Errorf("session agent = %q; want none without configured agents", got)
+	}
+}
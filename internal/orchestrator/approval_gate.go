@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/state/store/events/emit"
+)
+
+// ToolApprovals routes LLM tool calls that need an admin's sign-off through
+// the approval queue. Tools lists "plugin__action" names, or a bare plugin
+// name to cover every action of that plugin.
+type ToolApprovals struct {
+	Queue *approval.Queue
+	Tools []string
+}
+
+// toolApprovalGate is the resolved form of ToolApprovals.
+type toolApprovalGate struct {
+	queue *approval.Queue
+	tools map[string]bool
+}
+
+// newToolApprovalGate keeps the queue even with no tools listed: other
+// built-ins (agent handoffs) file their requests through it too.
+func newToolApprovalGate(cfg ToolApprovals) toolApprovalGate {
+	g := toolApprovalGate{queue: cfg.Queue}
+	if cfg.Queue == nil || len(cfg.Tools) == 0 {
+		return g
+	}
+	g.tools = make(map[string]bool, len(cfg.Tools))
+	for _, t := range cfg.Tools {
+		if t = strings.TrimSpace(t); t != "" {
+			g.tools[t] = true
+		}
+	}
+	return g
+}
+
+func (g toolApprovalGate) requires(call ToolCall) bool {
+	return g.tools[call.Plugin] || g.tools[toolFQN(call.Plugin, call.Action)]
+}
+
+// submitToolCallForApproval files call in the approval queue instead of
+// running it. The LLM gets a normal (non-error) result saying the call is
+// waiting, so it tells the user rather than retrying; the real result reaches
+// the user through the queue's notification once an admin decides.
+func (o *Orchestrator) submitToolCallForApproval(ctx context.Context, call ToolCall, dispatchStart time.Time) ToolResult {
+	args, err := json.Marshal(call.Args)
+	if err != nil {
+		return o.emitRefusalResult(ctx, call, fmt.Sprintf("cannot file %s for approval: %v", toolFQN(call.Plugin, call.Action), err), dispatchStart)
+	}
+	req, err := o.toolApprovals.queue.Submit(approval.Request{
+		Kind:           approval.KindToolCall,
+		Summary:        toolCallSummary(call),
+		Requester:      actor.Actor(ctx),
+		ChannelID:      currentChannelID(ctx),
+		ConversationID: actor.ConversationID(ctx),
+		SessionID:      actor.SessionID(ctx),
+		Payload:        map[string]string{"plugin": call.Plugin, "action": call.Action, "args": string(args)},
+	})
+	if err != nil {
+		slog.Warn("filing tool call for approval failed", "plugin", call.Plugin, "action", call.Action, "error", err)
+		return o.emitRefusalResult(ctx, call, "this action needs an admin's approval, but the approval queue is unavailable", dispatchStart)
+	}
+	content := fmt.Sprintf("%s needs an admin's approval and was submitted (request %s). It has NOT run yet. "+
+		"Tell the user it is awaiting approval; they will get the result here once an admin decides. Do not call it again.",
+		toolFQN(call.Plugin, call.Action), req.ID)
+	if call.FromLLM {
+		emit.EmitToolCallResult(ctx, o.eventSink, emit.ToolCallResultArgs{
+			CallID:    call.ID,
+			Status:    "ok",
+			Response:  content,
+			LatencyMS: time.Since(dispatchStart).Milliseconds(),
+		})
+	}
+	return ToolResult{CallID: call.ID, Content: content}
+}
+
+// runApprovedToolCall is the approval.KindToolCall handler. The call runs as
+// a host call: the admin's approval stands in for the per-call LLM gates, and
+// the args were captured after context-arg injection.
+func (o *Orchestrator) runApprovedToolCall(ctx context.Context, req approval.Request) (string, error) {
+	var args map[string]string
+	if raw := req.Payload["args"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &args); err != nil {
+			return "", fmt.Errorf("decoding args: %w", err)
+		}
+	}
+	if req.SessionID != "" {
+		ctx = actor.WithSessionID(ctx, req.SessionID)
+	}
+	return o.RunAction(ctx, req.Payload["plugin"], req.Payload["action"], args)
+}
+
+// toolCallSummary is the one-line listing text for a filed tool call.
+func toolCallSummary(call ToolCall) string {
+	keys := make([]string, 0, len(call.Args))
+	for k := range call.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+truncateRunes(call.Args[k], 60))
+	}
+	return toolFQN(call.Plugin, call.Action) + "(" + strings.Join(parts, ", ") + ")"
+}
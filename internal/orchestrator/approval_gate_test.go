@@ -0,0 +1,94 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// deployExecutor records the calls that actually reached the plugin.
+type deployExecutor struct {
+	mu    sync.Mutex
+	calls []ToolCall
+}
+
+func (e *deployExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, call)
+	return ToolResult{CallID: call.ID, Content: "deployed " + call.Args["env"]}
+}
+
+func TestToolApprovals_FilesLLMCallAndRunsOnApproval(t *testing.T) {
+	exec := &deployExecutor{}
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{
+		Name: "deploy", Description: "Deployments",
+		Actions: []Action{
+			{Name: "run", Description: "Deploy", Parameters: []Parameter{{Name: "env"}}},
+			{Name: "status", Description: "Deploy status"},
+		},
+	}, exec)
+	q, err := approval.NewQueue(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg,
+		state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{ToolApprovals: ToolApprovals{Queue: q, Tools: []string{"deploy__run"}}})
+
+	ctx := actor.WithConversationID(actor.WithActor(context.Background(), "slack:U1"), "C1")
+	res := orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "deploy", Action: "run", Args: map[string]string{"env": "prod"}, FromLLM: true})
+	if res.Error != "" || !strings.Contains(res.Content, "NOT run yet") {
+		t.Fatalf("result = %+v; want a pending-approval message", res)
+	}
+	if len(exec.calls) != 0 {
+		t.Fatal("a gated call must not reach the plugin before approval")
+	}
+	pending := q.List(approval.StatusPending)
+	if len(pending) != 1 {
+		t.Fatalf("queue = %+v", pending)
+	}
+	req := pending[0]
+	if req.Kind != approval.KindToolCall || req.Requester != "slack:U1" || req.ChannelID != "slack" || req.ConversationID != "C1" ||
+		req.Summary != "deploy__run(env=prod)" {
+		t.Errorf("filed request = %+v", req)
+	}
+
+	// Ungated actions and host calls run straight through.
+	if res := orch.executeCall(ctx, ToolCall{ID: "c2", Plugin: "deploy", Action: "status", FromLLM: true}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if _, err := orch.RunAction(ctx, "deploy", "run", map[string]string{"env": "staging"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.calls) != 2 {
+		t.Fatalf("calls = %d; want the ungated and host calls only", len(exec.calls))
+	}
+
+	got, err := q.Approve(context.Background(), req.ID, "alice", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Result != "deployed prod" || len(exec.calls) != 3 {
+		t.Errorf("after approval: result %q, %d calls", got.Result, len(exec.calls))
+	}
+}
+
+func TestToolApprovals_WholePlugin(t *testing.T) {
+	g := newToolApprovalGate(ToolApprovals{Queue: &approval.Queue{}, Tools: []string{"shell"}})
+	if !g.requires(ToolCall{Plugin: "shell", Action: "exec"}) {
+		t.Error("a bare plugin name should gate all of its actions")
+	}
+	if g.requires(ToolCall{Plugin: "weather", Action: "forecast"}) {
+		t.Error("unlisted plugin gated")
+	}
+	if (toolApprovalGate{}).requires(ToolCall{Plugin: "shell", Action: "exec"}) {
+		t.Error("no queue, no gate")
+	}
+}
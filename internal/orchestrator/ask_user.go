@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/logger"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+const (
+	// askUserPluginName is the built-in plugin the model calls when it lacks
+	// a value it needs, instead of guessing one. The fully-qualified name the
+	// LLM sees is "_ask_user__ask".
+	askUserPluginName = "_ask_user"
+	askUserAction     = "ask"
+
+	// MetaPendingQuestion is the session metadata key holding the question
+	// the model is waiting on (JSON pendingQuestion).
+	MetaPendingQuestion = "pending_question"
+
+	defaultAskUserTimeout = 30 * time.Minute
+)
+
+// AskUserConfig enables _ask_user. Timeout is how long a question stays
+// open; a reply that comes later is treated as a new request and the task
+// that asked is abandoned. Zero means 30 minutes.
+type AskUserConfig struct {
+	Enabled bool
+	Timeout time.Duration
+}
+
+// pendingQuestion is the persisted state of an open _ask_user question.
+// The turn's earlier tool calls and results are already in the session
+// history, so this is all the next turn needs to pick the task up again.
+type pendingQuestion struct {
+	CallID   string    `json:"call_id"`
+	Question string    `json:"question"`
+	AskedAt  time.Time `json:"asked_at"`
+}
+
+func (o *Orchestrator) registerAskUserTool() {
+	if !o.askUser.Enabled {
+		return
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        askUserPluginName,
+		Description: "Ask the user for missing information",
+		Actions: []Action{{
+			Name: askUserAction,
+			Description: "Ask the user a question and wait for the answer. Use it when a tool needs a value you do not have " +
+				"(an id, a date, a version, a choice between options) instead of guessing one. The conversation pauses until the user replies; " +
+				"the reply arrives as their next message and you continue the task from there.",
+			AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "question", Description: "The question to show the user, in their language; one short question", Required: true},
+			},
+		}},
+	}, &askUserExecutor{})
+}
+
+// askUserExecutor only runs when _ask_user is called outside the agent loop
+// (a pipeline step, a plugin's RunAction); the loop itself intercepts the
+// call in suspendForQuestion.
+type askUserExecutor struct{}
+
+func (e *askUserExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	return ToolResult{CallID: call.ID, Error: "_ask_user can only be used by the assistant during a conversation"}
+}
+
+// isAskUserCall reports whether the agent loop should suspend on call.
+func (o *Orchestrator) isAskUserCall(call ToolCall) bool {
+	return o.askUser.Enabled && call.Plugin == askUserPluginName && call.Action == askUserAction
+}
+
+// suspendForQuestion ends the turn with the model's question as the reply
+// and records it as pending. ok is false when the call has no question; the
+// caller then executes it, which returns an error the model can correct.
+func (o *Orchestrator) suspendForQuestion(ctx context.Context, sessions SessionStoreInterface, sessionID string, call ToolCall) (*RunResult, bool) {
+	question := strings.TrimSpace(call.Args["question"])
+	if question == "" {
+		return nil, false
+	}
+	data, err := json.Marshal(pendingQuestion{CallID: call.ID, Question: question, AskedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, false
+	}
+	if err := sessions.SetMetadata(sessionID, MetaPendingQuestion, string(data)); err != nil {
+		logger.FromContext(ctx).Warn("recording pending question failed", "error", err)
+	}
+	meta := map[string]string{"action": "ask_user"}
+	_ = sessions.AddMessageWithMetadata(sessionID, provider.Message{Role: provider.RoleAssistant, Content: question}, meta)
+	logger.FromContext(ctx).Info("waiting for the user's answer", "session", sessionID)
+	return &RunResult{Response: question, Metadata: meta}, true
+}
+
+// resolvePendingQuestion consumes the session's open question, if any.
+// answered is true when userMessage answers it in time: the turn then
+// continues the suspended task from history and skips the planner. A reply
+// after the timeout abandons the task; notice is the line telling the user
+// so, already added to history ahead of the new message.
+func (o *Orchestrator) resolvePendingQuestion(ctx context.Context, sessions SessionStoreInterface, sessionID string) (answered bool, notice string) {
+	sess, err := sessions.Get(sessionID)
+	if err != nil || sess == nil || sess.Metadata[MetaPendingQuestion] == "" {
+		return false, ""
+	}
+	log := logger.FromContext(ctx)
+	var q pendingQuestion
+	if jerr := json.Unmarshal([]byte(sess.Metadata[MetaPendingQuestion]), &q); jerr != nil {
+		log.Warn("dropping unreadable pending question", "error", jerr)
+	}
+	if err := sessions.SetMetadata(sessionID, MetaPendingQuestion, ""); err != nil {
+		log.Warn("clearing pending question failed", "error", err)
+	}
+	if q.Question == "" {
+		return false, ""
+	}
+	timeout := o.askUser.Timeout
+	if timeout <= 0 {
+		timeout = defaultAskUserTimeout
+	}
+	if waited := time.Since(q.AskedAt); waited > timeout {
+		log.Info("pending question expired", "session", sessionID, "waited", waited.Round(time.Second).String())
+		notice = o.coreStringFor(ctx, msgQuestionExpired)
+		_ = sessions.AddMessage(sessionID, provider.Message{Role: provider.RoleAssistant, Content: notice})
+		return false, notice
+	}
+	log.Debug("resuming after the user's answer", "session", sessionID, "call_id", q.CallID)
+	return true, ""
+}
+
+// prefixNotice puts notice above the reply, as announceOffline does.
+func prefixNotice(res *RunResult, notice string) {
+	if res == nil || notice == "" {
+		return
+	}
+	if res.Response == "" {
+		res.Response = notice
+		return
+	}
+	res.Response = fmt.Sprintf("%s\n\n%s", notice, res.Response)
+}
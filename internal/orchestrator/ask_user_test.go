@@ -0,0 +1,107 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// askUserParser turns the scripted LLM responses into tool calls.
+var askUserParser = &fakeParser{parseFn: func(response string) []ToolCall {
+	switch response {
+	case "ANALYZE":
+		return []ToolCall{{ID: "c1", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": "api"}}}
+	case "ASK":
+		return []ToolCall{{ID: "c2", Plugin: askUserPluginName, Action: askUserAction, Args: map[string]string{"question": "Which branch should the PR target?"}}}
+	case "CREATE":
+		return []ToolCall{{ID: "c3", Plugin: "gitlab", Action: "create_pr"}}
+	}
+	return nil
+}}
+
+func TestAskUser_SuspendsAndResumes(t *testing.T) {
+	llm := &fakeLLM{responses: []string{"ANALYZE", "ASK", "CREATE", "Opened the PR against main."}}
+	orch, sessID := setupOrchestratorWithOpts(llm, askUserParser, OrchestratorOpts{AskUser: AskUserConfig{Enabled: true}})
+	ctx := context.Background()
+
+	res, err := orch.Run(ctx, sessID, "Open a PR for the api repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "Which branch should the PR target?" || res.Metadata["action"] != "ask_user" {
+		t.Fatalf("first turn = %q %v; want the question", res.Response, res.Metadata)
+	}
+	sess, _ := orch.sessions.Get(sessID)
+	var q pendingQuestion
+	if err := json.Unmarshal([]byte(sess.Metadata[MetaPendingQuestion]), &q); err != nil || q.CallID != "c2" {
+		t.Fatalf("pending question = %q (%v)", sess.Metadata[MetaPendingQuestion], err)
+	}
+
+	res, err = orch.Run(ctx, sessID, "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "Opened the PR against main." {
+		t.Errorf("resumed turn = %q", res.Response)
+	}
+	if len(res.ToolCalls) != 1 || res.ToolCalls[0].Action != "create_pr" {
+		t.Errorf("resumed tool calls = %+v", res.ToolCalls)
+	}
+	sess, _ = orch.sessions.Get(sessID)
+	if sess.Metadata[MetaPendingQuestion] != "" {
+		t.Error("the pending question should be cleared once answered")
+	}
+	// The question and the answer sit next to each other in history, after
+	// the tool call the task made before asking.
+	var seq []string
+	for _, m := range sess.Messages {
+		seq = append(seq, string(m.Role)+":"+m.Content)
+	}
+	joined := strings.Join(seq, "\n")
+	if !strings.Contains(joined, "assistant:Which branch should the PR target?\nuser:main") {
+		t.Errorf("history:\n%s", joined)
+	}
+	if strings.Index(joined, "analyze_code") > strings.Index(joined, "Which branch") {
+		t.Errorf("the earlier tool call should precede the question:\n%s", joined)
+	}
+}
+
+func TestAskUser_ExpiredQuestion(t *testing.T) {
+	llm := &fakeLLM{responses: []string{"Hello!"}}
+	orch, sessID := setupOrchestratorWithOpts(llm, askUserParser, OrchestratorOpts{AskUser: AskUserConfig{Enabled: true, Timeout: time.Minute}})
+	data, _ := json.Marshal(pendingQuestion{CallID: "c2", Question: "Which branch?", AskedAt: time.Now().Add(-time.Hour)})
+	if err := orch.sessions.SetMetadata(sessID, MetaPendingQuestion, string(data)); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := orch.Run(context.Background(), sessID, "hi there")
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := coreString("en", msgQuestionExpired)
+	if res.Response != notice+"\n\nHello!" {
+		t.Errorf("response = %q", res.Response)
+	}
+	sess, _ := orch.sessions.Get(sessID)
+	if sess.Metadata[MetaPendingQuestion] != "" {
+		t.Error("an expired question should be cleared")
+	}
+	if n := len(sess.Messages); n < 2 || sess.Messages[0].Content != notice || sess.Messages[1].Role != provider.RoleUser {
+		t.Errorf("history = %+v; want the notice before the new message", sess.Messages)
+	}
+}
+
+func TestAskUser_DisabledAndOutsideLoop(t *testing.T) {
+	orch, _ := setupOrchestratorWithOpts(&fakeLLM{}, askUserParser, OrchestratorOpts{})
+	if _, ok := orch.registry.GetCapability(askUserPluginName); ok {
+		t.Error("_ask_user should not be registered unless enabled")
+	}
+	res := (&askUserExecutor{}).Execute(context.Background(), ToolCall{ID: "x", Plugin: askUserPluginName, Action: askUserAction})
+	if res.Error == "" {
+		t.Error("_ask_user outside the agent loop should fail")
+	}
+}
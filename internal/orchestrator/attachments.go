@@ -0,0 +1,307 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/attachment"
+	"github.com/opentalon/opentalon/internal/blob"
+	"github.com/opentalon/opentalon/internal/logger"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+const (
+	// DefaultAttachmentInlineBytes is the extracted-text size up to which an
+	// attachment is placed in the message itself when Attachments.InlineBytes
+	// is unset; longer text is previewed and read with _files__read.
+	DefaultAttachmentInlineBytes = 8 * 1024
+
+	// MetaAttachments is the session metadata key listing the conversation's
+	// stored attachments (JSON array of storedAttachment).
+	MetaAttachments = "attachments"
+
+	filesPluginName = "_files"
+	filesReadAction = "read"
+
+	attachmentFetchTimeout = 30 * time.Second
+)
+
+// InputAcceptor is an optional extension of LLMClient: AcceptsInput reports
+// whether the default model declares an input type ("image", …) in its
+// models.providers entry.
+type InputAcceptor interface {
+	AcceptsInput(kind string) bool
+}
+
+// Attachments stores files users send with a message in the blob store and
+// turns them into text the model can use: extracted text goes into the user
+// message (or a preview plus _files__read for long documents), images are
+// passed through only to models that accept image input, and everything is
+// listed in the session so later turns can read it again.
+type Attachments struct {
+	Store       *blob.Store
+	InlineBytes int // <= 0 = DefaultAttachmentInlineBytes
+}
+
+func (a Attachments) inlineBytes() int {
+	if a.InlineBytes <= 0 {
+		return DefaultAttachmentInlineBytes
+	}
+	return a.InlineBytes
+}
+
+// storedAttachment is one entry of MetaAttachments.
+type storedAttachment struct {
+	Name string `json:"name"`
+	Mime string `json:"mime"`
+	Size int    `json:"size"`
+	Blob string `json:"blob"`           // stored bytes: the extracted text when Text, else the file itself
+	Text bool   `json:"text,omitempty"` // Blob holds extracted text readable with _files__read
+}
+
+var attachmentClient = &http.Client{Timeout: attachmentFetchTimeout}
+
+// ingestAttachments downloads linked files and, with a store configured,
+// replaces each file by a note in content: extracted text, or a description
+// of what could not be read. Files the model takes natively (images for an
+// image-capable model) stay in the returned slice. Without a store the files
+// pass through as before.
+func (o *Orchestrator) ingestAttachments(ctx context.Context, sessions SessionStoreInterface, sessionID, content string, files []provider.MessageFile) (string, []provider.MessageFile) {
+	if len(files) == 0 {
+		return content, files
+	}
+	log := logger.FromContext(ctx)
+	var notes []string
+	var kept []provider.MessageFile
+	var stored []storedAttachment
+	for _, f := range files {
+		name := f.Name
+		if name == "" {
+			name = "attachment"
+		}
+		if len(f.Data) == 0 && f.URL != "" {
+			data, mime, err := o.fetchAttachment(ctx, f.URL)
+			if err != nil {
+				log.Warn("downloading attachment failed", "name", name, "error", err)
+				notes = append(notes, fmt.Sprintf("[Attachment %q could not be downloaded: %v]", name, err))
+				continue
+			}
+			f.Data = data
+			if f.MimeType == "" {
+				f.MimeType = mime
+			}
+		}
+		if o.attachments.Store == nil {
+			kept = append(kept, f)
+			continue
+		}
+		note, entry, keep := o.storeAttachment(ctx, name, f)
+		notes = append(notes, note)
+		if entry.Blob != "" {
+			stored = append(stored, entry)
+		}
+		if keep {
+			kept = append(kept, f)
+		}
+	}
+	if len(stored) > 0 {
+		o.recordAttachments(ctx, sessions, sessionID, stored)
+	}
+	if len(notes) > 0 {
+		content = strings.TrimSpace(content + "\n\n" + strings.Join(notes, "\n\n"))
+	}
+	return content, kept
+}
+
+// storeAttachment stores one file and returns the note for the message, its
+// session entry, and whether the file should also go to the model as is.
+func (o *Orchestrator) storeAttachment(ctx context.Context, name string, f provider.MessageFile) (string, storedAttachment, bool) {
+	entry := storedAttachment{Name: name, Mime: f.MimeType, Size: len(f.Data)}
+	desc := fmt.Sprintf("%s (%s, %s)", name, orDefault(f.MimeType, "unknown type"), humanBytes(len(f.Data)))
+
+	if provider.ClassifyFile(f.MimeType, f.Data) == provider.FileClassImage {
+		id, err := o.attachments.Store.Put(ctx, f.Data)
+		if err == nil {
+			entry.Blob = blob.Ref(id)
+		}
+		if o.acceptsInput("image") {
+			return fmt.Sprintf("[Image attachment %s]", desc), entry, true
+		}
+		return fmt.Sprintf("[Image attachment %s: the current model cannot view images.]", desc), entry, false
+	}
+
+	text, err := attachment.Text(f.MimeType, name, f.Data)
+	if err != nil {
+		id, perr := o.attachments.Store.Put(ctx, f.Data)
+		if perr != nil {
+			return fmt.Sprintf("[Attachment %s could not be stored: %v]", desc, perr), entry, false
+		}
+		entry.Blob = blob.Ref(id)
+		reason := "its format is not supported"
+		if !errors.Is(err, attachment.ErrUnsupported) {
+			reason = err.Error()
+		}
+		return fmt.Sprintf("[Attachment %s stored as %s; its text could not be extracted: %s]", desc, entry.Blob, reason), entry, false
+	}
+	id, err := o.attachments.Store.Put(ctx, []byte(text))
+	if err != nil {
+		// Too large for the store: the guard's size cap still bounds the turn.
+		return fmt.Sprintf("[Attachment %s]\n%s\n[end of attachment]", desc, cutBytes(text, o.attachments.inlineBytes())), entry, false
+	}
+	entry.Blob, entry.Text = blob.Ref(id), true
+	if len(text) <= o.attachments.inlineBytes() {
+		return fmt.Sprintf("[Attachment %s, %s]\n%s\n[end of attachment]", desc, entry.Blob, text), entry, false
+	}
+	return fmt.Sprintf("[Attachment %s, %s — text is %s; the beginning follows. Call %s with this name and an offset to read more.]\n%s\n[…]",
+		desc, entry.Blob, humanBytes(len(text)), toolFQN(filesPluginName, filesReadAction), cutBytes(text, min(blobPreviewBytes, o.attachments.inlineBytes()))), entry, false
+}
+
+func (o *Orchestrator) fetchAttachment(ctx context.Context, url string) ([]byte, string, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, "", fmt.Errorf("unsupported URL scheme")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("GET: %s", resp.Status)
+	}
+	limit := int64(blob.DefaultMaxBytes)
+	if o.attachments.Store != nil {
+		limit = o.attachments.Store.MaxBytes()
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > limit {
+		return nil, "", fmt.Errorf("larger than %s", humanBytes(int(limit)))
+	}
+	return data, strings.TrimSpace(strings.SplitN(resp.Header.Get("Content-Type"), ";", 2)[0]), nil
+}
+
+func (o *Orchestrator) acceptsInput(kind string) bool {
+	a, ok := o.llm.(InputAcceptor)
+	return ok && a.AcceptsInput(kind)
+}
+
+// recordAttachments appends entries to the session's MetaAttachments list.
+func (o *Orchestrator) recordAttachments(ctx context.Context, sessions SessionStoreInterface, sessionID string, entries []storedAttachment) {
+	list := sessionAttachments(sessions, sessionID)
+	for _, e := range entries {
+		// A re-sent file replaces the older entry of the same name.
+		list = slices.DeleteFunc(list, func(x storedAttachment) bool { return strings.EqualFold(x.Name, e.Name) })
+		list = append(list, e)
+	}
+	data, _ := json.Marshal(list)
+	if err := sessions.SetMetadata(sessionID, MetaAttachments, string(data)); err != nil {
+		logger.FromContext(ctx).Warn("recording attachments failed", "error", err)
+	}
+}
+
+func sessionAttachments(sessions SessionStoreInterface, sessionID string) []storedAttachment {
+	sess, _ := sessions.Get(sessionID)
+	if sess == nil || sess.Metadata[MetaAttachments] == "" {
+		return nil
+	}
+	var list []storedAttachment
+	_ = json.Unmarshal([]byte(sess.Metadata[MetaAttachments]), &list)
+	return list
+}
+
+// registerFileTools registers _files when attachments are stored.
+func (o *Orchestrator) registerFileTools() {
+	if o.attachments.Store == nil {
+		return
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        filesPluginName,
+		Description: "Read files attached to this conversation",
+		Actions: []Action{{
+			Name:          filesReadAction,
+			Description:   "Read the text of a file the user attached in this conversation, page by page. Call without a name to list the attached files.",
+			AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "name", Description: "File name as shown in the attachment note (or its blob id)"},
+				{Name: "offset", Description: "Byte offset to start at (default 0)"},
+				{Name: "limit", Description: fmt.Sprintf("Bytes to return (default and max %d)", blobReadDefault)},
+			},
+		}},
+	}, &filesExecutor{orch: o})
+}
+
+type filesExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *filesExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	if call.Action != filesReadAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	list := sessionAttachments(e.orch.sessions, actor.SessionID(ctx))
+	name := strings.TrimSpace(call.Args["name"])
+	if name == "" {
+		if len(list) == 0 {
+			return ToolResult{CallID: call.ID, Content: "No files are attached to this conversation."}
+		}
+		var sb strings.Builder
+		for _, a := range list {
+			fmt.Fprintf(&sb, "- %s (%s, %s)", a.Name, orDefault(a.Mime, "unknown type"), humanBytes(a.Size))
+			if !a.Text {
+				sb.WriteString(" — no readable text")
+			}
+			sb.WriteString("\n")
+		}
+		return ToolResult{CallID: call.ID, Content: strings.TrimRight(sb.String(), "\n")}
+	}
+	idx := slices.IndexFunc(list, func(a storedAttachment) bool {
+		return strings.EqualFold(a.Name, name) || a.Blob == name || a.Blob == blob.Ref(name)
+	})
+	if idx < 0 {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("no attachment %q in this conversation", name)}
+	}
+	a := list[idx]
+	if !a.Text {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("%s has no readable text", a.Name)}
+	}
+	data, err := e.orch.attachments.Store.Get(ctx, strings.TrimPrefix(a.Blob, "blob:"))
+	if err != nil {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("reading %s: %v", a.Name, err)}
+	}
+	page, err := readPage(string(data), call.Args["offset"], call.Args["limit"])
+	if err != nil {
+		return ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return ToolResult{CallID: call.ID, Content: page}
+}
+
+func humanBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d bytes", n)
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
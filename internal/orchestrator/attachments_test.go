@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/blob"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// imageLLM is a requestLLM whose default model declares image input.
+type imageLLM struct{ requestLLM }
+
+func (*imageLLM) AcceptsInput(kind string) bool { return kind == "image" }
+
+func newAttachmentOrch(t *testing.T, llm LLMClient, inline int) (*Orchestrator, *state.SessionStore) {
+	t.Helper()
+	backend, err := blob.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{Attachments: Attachments{Store: blob.New(backend, 0), InlineBytes: inline}})
+	return orch, sessions
+}
+
+func TestAttachments_TextInlinedAndReadable(t *testing.T) {
+	llm := &requestLLM{}
+	orch, sessions := newAttachmentOrch(t, llm, 100)
+	ctx := actor.WithSessionID(context.Background(), "s1")
+	long := strings.Repeat("line of the contract\n", 50) // 1050 bytes, above the inline limit
+
+	if _, err := orch.Run(ctx, "s1", "summarise these", provider.MessageFile{Name: "stock.csv", MimeType: "text/csv", Data: []byte("item,qty\nbolt,3\n")},
+		provider.MessageFile{Name: "contract.txt", MimeType: "text/plain", Data: []byte(long)}); err != nil {
+		t.Fatal(err)
+	}
+	req := llm.requests[len(llm.requests)-1]
+	user := req.Messages[len(req.Messages)-1]
+	if !strings.Contains(user.Content, "item,qty\nbolt,3\n\n[end of attachment]") {
+		t.Errorf("small file should be inlined:\n%s", user.Content)
+	}
+	if !strings.Contains(user.Content, "contract.txt (text/plain, 1 KB)") || !strings.Contains(user.Content, "_files__read") || strings.Count(user.Content, "line of the contract") > 10 {
+		t.Errorf("large file should be previewed with a pointer to _files__read:\n%s", user.Content)
+	}
+	if len(user.Files) != 0 {
+		t.Errorf("extracted files should not also be sent raw: %d files", len(user.Files))
+	}
+
+	read := func(args map[string]string) ToolResult {
+		return orch.executeCall(ctx, ToolCall{ID: "r", Plugin: filesPluginName, Action: filesReadAction, Args: args})
+	}
+	if r := read(nil); !strings.Contains(r.Content, "- stock.csv (text/csv, 16 bytes)") || !strings.Contains(r.Content, "- contract.txt") {
+		t.Errorf("listing = %+v", r)
+	}
+	if r := read(map[string]string{"name": "CONTRACT.txt", "offset": "1029"}); r.Error != "" || r.Content != "line of the contract\n\n\n[end of output]" {
+		t.Errorf("page = %+v", r)
+	}
+	if r := read(map[string]string{"name": "other.pdf"}); r.Error == "" {
+		t.Error("reading an unknown attachment should fail")
+	}
+	if got := len(sessionAttachments(sessions, "s1")); got != 2 {
+		t.Errorf("session lists %d attachments; want 2", got)
+	}
+}
+
+func TestAttachments_ImagesFollowModelInput(t *testing.T) {
+	png := provider.MessageFile{Name: "shot.png", MimeType: "image/png", Data: []byte("\x89PNG fake")}
+
+	orch, sessions := newAttachmentOrch(t, &requestLLM{}, 0)
+	content, files := orch.ingestAttachments(context.Background(), sessions, "s1", "what is this?", []provider.MessageFile{png})
+	if len(files) != 0 || !strings.Contains(content, "cannot view images") {
+		t.Errorf("text-only model: content %q, %d files", content, len(files))
+	}
+
+	orch, sessions = newAttachmentOrch(t, &imageLLM{}, 0)
+	content, files = orch.ingestAttachments(context.Background(), sessions, "s1", "what is this?", []provider.MessageFile{png})
+	if len(files) != 1 || strings.Contains(content, "cannot view") {
+		t.Errorf("image model: content %q, %d files", content, len(files))
+	}
+}
+
+func TestAttachments_URLAndUnsupported(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, _ = w.Write([]byte("# Runbook\nRestart the worker."))
+	}))
+	defer srv.Close()
+
+	orch, sessions := newAttachmentOrch(t, &requestLLM{}, 0)
+	content, _ := orch.ingestAttachments(context.Background(), sessions, "s1", "", []provider.MessageFile{
+		{Name: "runbook.md", URL: srv.URL + "/runbook.md"},
+		{Name: "gone.md", URL: srv.URL + "/missing"},
+		{Name: "data.bin", MimeType: "application/octet-stream", Data: []byte{0, 1, 2}},
+	})
+	for _, want := range []string{"runbook.md (text/markdown, 29 bytes)", "Restart the worker.", `"gone.md" could not be downloaded`, "data.bin (application/octet-stream, 3 bytes) stored as blob:sha256:"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("content lacks %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestAttachments_DisabledPassesFilesThrough(t *testing.T) {
+	orch := NewWithRules(&requestLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+	in := []provider.MessageFile{{Name: "a.pdf", MimeType: "application/pdf", Data: []byte("%PDF-1.4")}}
+	content, files := orch.ingestAttachments(context.Background(), orch.sessions, "s1", "hi", in)
+	if content != "hi" || len(files) != 1 {
+		t.Errorf("without a store: content %q, %d files", content, len(files))
+	}
+	if _, ok := orch.registry.GetCapability(filesPluginName); ok {
+		t.Error("_files registered without an attachment store")
+	}
+}
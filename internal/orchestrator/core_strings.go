@@ -0,0 +1,214 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Core system replies — the few messages the orchestrator writes to the user
+// itself rather than through the LLM — in the languages reply-language
+// detection covers. English is the fallback for any other locale. Operators
+// override or add translations with a MessageCatalog.
+const (
+	msgConfirmationExpired = "confirmation_expired"
+	msgEmptyContent        = "empty_content"
+	msgGuardBlocked        = "guard_blocked" // %s = guard name
+	msgOfflineMode         = "offline_mode"
+	msgRequestBlocked      = "request_blocked" // a guard stopped the message without saying why
+	msgNoResponse          = "no_response"
+	msgInvokeFailed        = "invoke_failed" // %s = the tool's error
+	msgToolProgress        = "tool_progress" // %s = "plugin → action"; a progress line, not a reply
+	msgQuestionExpired     = "question_expired"
+	msgRunInterrupted      = "run_interrupted"
+	msgLoopStopped         = "loop_stopped" // %s = the tools the model kept calling
+	msgToolsFailed         = "tools_failed" // %d of %d calls, %s = "tool (error)" list; a display note, not a reply
+)
+
+var coreStrings = map[string]map[string]string{
+	msgConfirmationExpired: {
+		"en": "This confirmation is no longer active. Please try your request again.",
+		"de": "Diese Bestätigung ist nicht mehr aktiv. Bitte stelle deine Anfrage erneut.",
+		"fr": "Cette confirmation n'est plus active. Veuillez renouveler votre demande.",
+		"es": "Esta confirmación ya no está activa. Vuelve a intentar tu solicitud.",
+		"it": "Questa conferma non è più attiva. Riprova a inviare la richiesta.",
+		"pt": "Esta confirmação já não está ativa. Tente fazer o pedido novamente.",
+		"pl": "To potwierdzenie jest już nieaktywne. Spróbuj ponownie wysłać prośbę.",
+		"lt": "Šis patvirtinimas nebegalioja. Pabandykite pateikti užklausą dar kartą.",
+	},
+	msgEmptyContent: {
+		"en": "I received your message but couldn't read its content. Could you try sending it as text?",
+		"de": "Ich habe deine Nachricht erhalten, konnte ihren Inhalt aber nicht lesen. Kannst du sie als Text senden?",
+		"fr": "J'ai bien reçu votre message, mais je n'ai pas pu en lire le contenu. Pouvez-vous l'envoyer sous forme de texte ?",
+		"es": "Recibí tu mensaje, pero no pude leer su contenido. ¿Puedes enviarlo como texto?",
+		"it": "Ho ricevuto il tuo messaggio ma non sono riuscito a leggerne il contenuto. Puoi inviarlo come testo?",
+		"pt": "Recebi a sua mensagem, mas não consegui ler o conteúdo. Pode enviá-la como texto?",
+		"pl": "Otrzymałem Twoją wiadomość, ale nie mogłem odczytać jej treści. Czy możesz wysłać ją jako tekst?",
+		"lt": "Gavau jūsų žinutę, bet nepavyko perskaityti jos turinio. Gal galite atsiųsti ją tekstu?",
+	},
+	msgGuardBlocked: {
+		"en": "Request blocked: guard %s failed.",
+		"de": "Anfrage blockiert: Prüfung %s ist fehlgeschlagen.",
+		"fr": "Demande bloquée : le contrôle %s a échoué.",
+		"es": "Solicitud bloqueada: la comprobación %s falló.",
+		"it": "Richiesta bloccata: il controllo %s non è riuscito.",
+		"pt": "Pedido bloqueado: a verificação %s falhou.",
+		"pl": "Żądanie zablokowane: kontrola %s nie powiodła się.",
+		"lt": "Užklausa užblokuota: patikra %s nepavyko.",
+	},
+	msgOfflineMode: {
+		"en": "⚠️ I can't reach my usual AI service right now, so I'm answering with a smaller local model. Answers may be less accurate until the connection is back.",
+		"de": "⚠️ Mein üblicher KI-Dienst ist gerade nicht erreichbar, deshalb antworte ich mit einem kleineren lokalen Modell. Bis die Verbindung wieder steht, können Antworten ungenauer sein.",
+		"fr": "⚠️ Mon service d'IA habituel est injoignable pour le moment : je réponds avec un modèle local plus petit. Les réponses peuvent être moins précises jusqu'au rétablissement de la connexion.",
+		"es": "⚠️ Ahora mismo no puedo acceder a mi servicio de IA habitual, así que respondo con un modelo local más pequeño. Las respuestas pueden ser menos precisas hasta que vuelva la conexión.",
+		"it": "⚠️ Al momento non riesco a raggiungere il mio solito servizio di IA, quindi rispondo con un modello locale più piccolo. Le risposte potrebbero essere meno accurate finché la connessione non torna.",
+		"pt": "⚠️ Neste momento não consigo aceder ao meu serviço de IA habitual, por isso respondo com um modelo local mais pequeno. As respostas podem ser menos precisas até a ligação voltar.",
+		"pl": "⚠️ Nie mogę teraz połączyć się z moją zwykłą usługą AI, więc odpowiadam mniejszym modelem lokalnym. Do czasu przywrócenia połączenia odpowiedzi mogą być mniej dokładne.",
+		"lt": "⚠️ Šiuo metu nepasiekiu įprastos DI paslaugos, todėl atsakau mažesniu vietiniu modeliu. Kol ryšys nebus atkurtas, atsakymai gali būti mažiau tikslūs.",
+	},
+	msgRequestBlocked: {
+		"en": "Request blocked by guard.",
+		"de": "Anfrage von einer Prüfung blockiert.",
+		"fr": "Demande bloquée par un contrôle.",
+		"es": "Solicitud bloqueada por una comprobación.",
+		"it": "Richiesta bloccata da un controllo.",
+		"pt": "Pedido bloqueado por uma verificação.",
+		"pl": "Żądanie zablokowane przez kontrolę.",
+		"lt": "Užklausą užblokavo patikra.",
+	},
+	msgNoResponse: {
+		"en": "(no response)",
+		"de": "(keine Antwort)",
+		"fr": "(pas de réponse)",
+		"es": "(sin respuesta)",
+		"it": "(nessuna risposta)",
+		"pt": "(sem resposta)",
+		"pl": "(brak odpowiedzi)",
+		"lt": "(atsakymo nėra)",
+	},
+	msgInvokeFailed: {
+		"en": "Invoke step failed: %s",
+		"de": "Schritt fehlgeschlagen: %s",
+		"fr": "L'étape a échoué : %s",
+		"es": "El paso falló: %s",
+		"it": "Il passaggio non è riuscito: %s",
+		"pt": "O passo falhou: %s",
+		"pl": "Krok nie powiódł się: %s",
+		"lt": "Žingsnis nepavyko: %s",
+	},
+	msgQuestionExpired: {
+		"en": "I stopped waiting for an answer to my earlier question, so I dropped that task. Ask again if you still need it.",
+		"de": "Ich habe nicht länger auf eine Antwort auf meine Frage gewartet und die Aufgabe abgebrochen. Frag gern noch einmal, falls du sie noch brauchst.",
+		"fr": "Je n'ai pas reçu de réponse à ma question à temps, j'ai donc abandonné cette tâche. Redemandez-la si vous en avez encore besoin.",
+		"es": "Dejé de esperar la respuesta a mi pregunta anterior, así que abandoné esa tarea. Vuelve a pedirla si todavía la necesitas.",
+		"it": "Non ho ricevuto in tempo una risposta alla mia domanda, quindi ho interrotto quell'attività. Chiedila di nuovo se ti serve ancora.",
+		"pt": "Deixei de esperar pela resposta à minha pergunta anterior, por isso abandonei essa tarefa. Peça de novo se ainda precisar.",
+		"pl": "Przestałem czekać na odpowiedź na moje wcześniejsze pytanie, więc przerwałem to zadanie. Poproś ponownie, jeśli nadal tego potrzebujesz.",
+		"lt": "Nebelaukiau atsakymo į ankstesnį klausimą, todėl tą užduotį nutraukiau. Paprašyk dar kartą, jei jos vis dar reikia.",
+	},
+	msgRunInterrupted: {
+		"en": "I was restarted while working on your last request and could not finish it. Please send it again if you still need it.",
+		"de": "Ich wurde neu gestartet, während ich an deiner letzten Anfrage gearbeitet habe, und konnte sie nicht abschließen. Schick sie bitte noch einmal, falls du sie noch brauchst.",
+		"fr": "J'ai été redémarré pendant le traitement de votre dernière demande et je n'ai pas pu la terminer. Renvoyez-la si vous en avez encore besoin.",
+		"es": "Me reiniciaron mientras trabajaba en tu última solicitud y no pude terminarla. Vuelve a enviarla si todavía la necesitas.",
+		"it": "Sono stato riavviato mentre lavoravo alla tua ultima richiesta e non sono riuscito a completarla. Inviala di nuovo se ti serve ancora.",
+		"pt": "Fui reiniciado enquanto trabalhava no seu último pedido e não consegui concluí-lo. Envie-o de novo se ainda precisar.",
+		"pl": "Zostałem uruchomiony ponownie podczas pracy nad Twoją ostatnią prośbą i nie mogłem jej dokończyć. Wyślij ją ponownie, jeśli nadal jej potrzebujesz.",
+		"lt": "Buvau paleistas iš naujo, kol dirbau su tavo paskutine užklausa, ir negalėjau jos užbaigti. Atsiųsk ją dar kartą, jei jos vis dar reikia.",
+	},
+	msgLoopStopped: {
+		"en": "I stopped because I kept calling %s without getting closer to an answer. Could you rephrase the request or tell me more about what you need?",
+		"de": "Ich habe aufgehört, weil ich immer wieder %s aufgerufen habe, ohne einer Antwort näherzukommen. Kannst du die Anfrage anders formulieren oder genauer sagen, was du brauchst?",
+		"fr": "Je me suis arrêté, car j'appelais sans cesse %s sans me rapprocher d'une réponse. Pouvez-vous reformuler la demande ou préciser ce dont vous avez besoin ?",
+		"es": "Me detuve porque seguía llamando a %s sin acercarme a una respuesta. ¿Puedes reformular la solicitud o contarme más sobre lo que necesitas?",
+		"it": "Mi sono fermato perché continuavo a chiamare %s senza avvicinarmi a una risposta. Puoi riformulare la richiesta o dirmi meglio di cosa hai bisogno?",
+		"pt": "Parei porque continuava a chamar %s sem me aproximar de uma resposta. Pode reformular o pedido ou dizer-me mais sobre o que precisa?",
+		"pl": "Przerwałem, bo ciągle wywoływałem %s, nie zbliżając się do odpowiedzi. Czy możesz przeformułować prośbę albo powiedzieć więcej o tym, czego potrzebujesz?",
+		"lt": "Sustojau, nes vis kviečiau %s ir nepriartėjau prie atsakymo. Ar galėtum performuluoti užklausą arba papasakoti daugiau, ko tau reikia?",
+	},
+	msgToolsFailed: {
+		"en": "%d of %d actions failed: %s",
+		"de": "%d von %d Aktionen fehlgeschlagen: %s",
+		"fr": "%d actions sur %d ont échoué : %s",
+		"es": "%d de %d acciones fallaron: %s",
+		"it": "%d azioni su %d non sono riuscite: %s",
+		"pt": "%d de %d ações falharam: %s",
+		"pl": "Nie powiodło się %d z %d działań: %s",
+		"lt": "Nepavyko %d iš %d veiksmų: %s",
+	},
+	msgToolProgress: {
+		"en": "Running %s…",
+		"de": "Führe %s aus…",
+		"fr": "Exécution de %s…",
+		"es": "Ejecutando %s…",
+		"it": "Esecuzione di %s…",
+		"pt": "A executar %s…",
+		"pl": "Uruchamiam %s…",
+		"lt": "Vykdoma %s…",
+	},
+}
+
+// MessageCatalog overrides or adds translations of the core replies:
+// locale (ISO 639-1) -> message key -> text. A locale missing a key falls
+// back to the built-in translation, then to English.
+type MessageCatalog map[string]map[string]string
+
+// MessageKeys returns the keys a MessageCatalog may set.
+func MessageKeys() []string {
+	return slices.Sorted(maps.Keys(coreStrings))
+}
+
+// Validate reports unknown keys and texts whose %s placeholders do not match
+// the built-in English text.
+func (c MessageCatalog) Validate() error {
+	for locale, msgs := range c {
+		for key, text := range msgs {
+			en, ok := coreStrings[key]["en"]
+			if !ok {
+				return fmt.Errorf("%s.%s: unknown message (want one of %s)", locale, key, strings.Join(MessageKeys(), ", "))
+			}
+			if got, want := strings.Count(text, "%s"), strings.Count(en, "%s"); got != want {
+				return fmt.Errorf("%s.%s: has %d %%s placeholders, want %d", locale, key, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+// text returns key in locale: the catalog's text, else the built-in one.
+func (c MessageCatalog) text(locale, key string, args ...any) string {
+	s, ok := c[locale][key]
+	if !ok {
+		if _, built := coreStrings[key][locale]; !built {
+			s, ok = c["en"][key]
+		}
+	}
+	if !ok {
+		return coreString(locale, key, args...)
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// coreString returns the core reply key in locale (English when the locale
+// has no translation), formatted with args.
+func coreString(locale, key string, args ...any) string {
+	s, ok := coreStrings[key][locale]
+	if !ok {
+		s = coreStrings[key]["en"]
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(s, args...)
+	}
+	return s
+}
+
+// coreStringFor returns key in the turn's locale, using the configured
+// catalog.
+func (o *Orchestrator) coreStringFor(ctx context.Context, key string, args ...any) string {
+	return o.messages.text(turnLocale(ctx), key, args...)
+}
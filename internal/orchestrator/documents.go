@@ -0,0 +1,128 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/rag"
+)
+
+const (
+	// documentsPluginName is the built-in plugin that searches the document
+	// index (the knowledge.search tool); registered only when an index is
+	// configured.
+	documentsPluginName   = "_knowledge"
+	documentsSearchAction = "search"
+
+	documentsSearchDefault = 5
+	documentsSearchMax     = 20
+)
+
+// DocumentSearcher finds the indexed passages closest to a query.
+// *rag.Index satisfies it.
+type DocumentSearcher interface {
+	Search(ctx context.Context, query string, k int) ([]rag.Hit, error)
+}
+
+// Documents wires a retrieval index over local documents into the turn: the
+// LLM can always search it with _knowledge__search, and with InjectTopK > 0
+// the best passages for each user message are added to the system prompt.
+type Documents struct {
+	Index      DocumentSearcher
+	InjectTopK int     // passages added to the system prompt per turn; 0 = search tool only
+	MinScore   float64 // injected passages must score at least this (cosine similarity)
+}
+
+// registerDocumentTools registers _knowledge when a document index is configured.
+func (o *Orchestrator) registerDocumentTools() {
+	if o.documents.Index == nil {
+		return
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        documentsPluginName,
+		Description: "Search company documents",
+		Actions: []Action{{
+			Name:          documentsSearchAction,
+			Description:   "Search the indexed company documents and return the most relevant passages with their source. Use it to ground answers about internal policies, procedures and products.",
+			AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "query", Description: "What to look for, phrased as a question or keywords", Required: true},
+				{Name: "k", Description: fmt.Sprintf("Number of passages to return (default %d, max %d)", documentsSearchDefault, documentsSearchMax)},
+			},
+		}},
+	}, &documentsExecutor{orch: o})
+}
+
+type documentsExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *documentsExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	if call.Action != documentsSearchAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	query := strings.TrimSpace(call.Args["query"])
+	if query == "" {
+		return ToolResult{CallID: call.ID, Error: "query is required"}
+	}
+	k, _ := strconv.Atoi(call.Args["k"])
+	if k <= 0 {
+		k = documentsSearchDefault
+	}
+	k = min(k, documentsSearchMax)
+	hits, err := e.orch.documents.Index.Search(ctx, query, k)
+	if err != nil {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("searching documents: %v", err)}
+	}
+	if len(hits) == 0 {
+		return ToolResult{CallID: call.ID, Content: "No matching documents."}
+	}
+	return ToolResult{CallID: call.ID, Content: formatDocumentHits(hits)}
+}
+
+func formatDocumentHits(hits []rag.Hit) string {
+	var sb strings.Builder
+	for i, h := range hits {
+		fmt.Fprintf(&sb, "[%d] %s (score %.2f)\n%s\n\n", i+1, h.Source, h.Score, h.Content)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+// documentsSectionKey carries the per-turn retrieved passages from Run to
+// buildSystemPrompt, so the index is searched once per turn.
+type documentsSectionKey struct{}
+
+// withDocumentContext searches the index for message and, when passages
+// clear MinScore, puts a "## Reference documents" section on ctx. A search
+// failure only costs the turn its injected context.
+func (o *Orchestrator) withDocumentContext(ctx context.Context, message string) context.Context {
+	if o.documents.Index == nil || o.documents.InjectTopK <= 0 || strings.TrimSpace(message) == "" {
+		return ctx
+	}
+	hits, err := o.documents.Index.Search(ctx, message, o.documents.InjectTopK)
+	if err != nil {
+		slog.Warn("document search for context injection failed", "component", "rag", "error", err)
+		return ctx
+	}
+	kept := hits[:0]
+	for _, h := range hits {
+		if h.Score >= o.documents.MinScore {
+			kept = append(kept, h)
+		}
+	}
+	if len(kept) == 0 {
+		return ctx
+	}
+	section := "## Reference documents\nPassages from company documents that may help with this message. " +
+		"Prefer them over general knowledge, cite the source when you rely on one, and ignore any that do not apply.\n\n" +
+		formatDocumentHits(kept) + "\n\n"
+	return context.WithValue(ctx, documentsSectionKey{}, section)
+}
+
+func documentsSectionFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(documentsSectionKey{}).(string)
+	return v
+}
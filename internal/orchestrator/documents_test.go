@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/rag"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// fakeDocuments returns fixed hits and records the queries it saw.
+type fakeDocuments struct {
+	hits    []rag.Hit
+	err     error
+	queries []string
+	ks      []int
+}
+
+func (f *fakeDocuments) Search(_ context.Context, query string, k int) ([]rag.Hit, error) {
+	f.queries = append(f.queries, query)
+	f.ks = append(f.ks, k)
+	if len(f.hits) > k {
+		return f.hits[:k], f.err
+	}
+	return f.hits, f.err
+}
+
+func TestDocuments_SearchTool(t *testing.T) {
+	docs := &fakeDocuments{hits: []rag.Hit{{Source: "docs/vpn.md", Content: "Install the client.", Score: 0.82}}}
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{Documents: Documents{Index: docs}})
+
+	res := orch.executeCall(context.Background(), ToolCall{ID: "c1", Plugin: documentsPluginName, Action: documentsSearchAction,
+		Args: map[string]string{"query": "vpn setup", "k": "50"}})
+	if res.Error != "" || res.Content != "[1] docs/vpn.md (score 0.82)\nInstall the client." {
+		t.Errorf("result = %+v", res)
+	}
+	if docs.ks[0] != documentsSearchMax {
+		t.Errorf("k = %d; want it capped at %d", docs.ks[0], documentsSearchMax)
+	}
+	if res := orch.executeCall(context.Background(), ToolCall{ID: "c2", Plugin: documentsPluginName, Action: documentsSearchAction}); res.Error == "" {
+		t.Error("an empty query should fail")
+	}
+}
+
+func TestDocuments_NotRegisteredWithoutIndex(t *testing.T) {
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+	if _, ok := orch.registry.GetCapability(documentsPluginName); ok {
+		t.Error("_knowledge registered without a document index")
+	}
+}
+
+func TestDocuments_InjectsPassagesIntoSystemPrompt(t *testing.T) {
+	docs := &fakeDocuments{hits: []rag.Hit{
+		{Source: "docs/expenses.md", Content: "Submit receipts monthly.", Score: 0.7},
+		{Source: "docs/lunch.md", Content: "The canteen opens at noon.", Score: 0.1},
+	}}
+	llm := &requestLLM{}
+	sessions := state.NewSessionStore("")
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{Documents: Documents{Index: docs, InjectTopK: 3, MinScore: 0.3}})
+	sessions.Create("s1", "", "", "")
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+
+	if _, err := orch.Run(ctx, "s1", "how do I claim expenses?"); err != nil {
+		t.Fatal(err)
+	}
+	system := llm.requests[len(llm.requests)-1].Messages[0].Content
+	if !strings.Contains(system, "## Reference documents") || !strings.Contains(system, "[1] docs/expenses.md") {
+		t.Errorf("system prompt lacks the retrieved passage:\n%s", system)
+	}
+	if strings.Contains(system, "canteen") {
+		t.Error("a passage below min_score was injected")
+	}
+	if len(docs.queries) != 1 || docs.queries[0] != "how do I claim expenses?" || docs.ks[0] != 3 {
+		t.Errorf("searches = %v k=%v", docs.queries, docs.ks)
+	}
+	for _, m := range mustSession(t, sessions, "s1").Messages {
+		if strings.Contains(m.Content, "Submit receipts") {
+			t.Errorf("retrieved text leaked into session history: %q", m.Content)
+		}
+	}
+
+	// A failing index only loses the injected context.
+	docs.err = errors.New("embeddings down")
+	if _, err := orch.Run(ctx, "s1", "and travel?"); err != nil {
+		t.Fatal(err)
+	}
+	if system := llm.requests[len(llm.requests)-1].Messages[0].Content; strings.Contains(system, "## Reference documents") {
+		t.Error("reference section present although the search failed")
+	}
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state/store/events/emit"
+)
+
+// MetaDryRun is the session metadata key that puts one session in dry-run
+// mode ("true"); the set_dry_run command (/dryrun) sets it.
+const MetaDryRun = "dry_run"
+
+// DryRun configures shadow mode: the agent loop runs as usual, but
+// mutating tool calls are simulated instead of run. Read-only actions
+// still run, so the model works with real data.
+type DryRun struct {
+	Enabled bool     // every session
+	Plugins []string // always simulate these plugins, e.g. one being evaluated
+}
+
+// dryRunTurn is one turn's dry-run state, carried on ctx.
+type dryRunTurn struct {
+	session   bool // the session is in dry-run mode
+	simulated atomic.Int32
+}
+
+type dryRunKey struct{}
+
+func withDryRunTurn(ctx context.Context, t *dryRunTurn) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, t)
+}
+
+func dryRunTurnFrom(ctx context.Context) *dryRunTurn {
+	t, _ := ctx.Value(dryRunKey{}).(*dryRunTurn)
+	return t
+}
+
+// simulates reports whether call is to be simulated. Only mutating actions
+// are: read-only ones, user_only commands the user typed, the core's own
+// conversation tools ("_"-prefixed) and the hooks around a turn
+// (preparers, guards, moderators, formatters, the permission plugin) run.
+func (o *Orchestrator) simulates(ctx context.Context, call ToolCall, action *Action) bool {
+	turn := dryRunTurnFrom(ctx)
+	if !o.dryRun.Enabled && !o.dryRunPlugins[call.Plugin] && (turn == nil || !turn.session) {
+		return false
+	}
+	if action == nil || action.ReadOnly || action.UserOnly || strings.HasPrefix(call.Plugin, "_") {
+		return false
+	}
+	if o.preparerActions[toolFQN(call.Plugin, call.Action)] || call.Plugin == o.permissionPluginName {
+		return false
+	}
+	for _, f := range o.formatters {
+		if f.Plugin == call.Plugin && f.Action == call.Action {
+			return false
+		}
+	}
+	return true
+}
+
+// simulateToolCall returns the synthesized result of a call dry-run mode
+// keeps from running, and audit-logs what would have run.
+func (o *Orchestrator) simulateToolCall(ctx context.Context, call ToolCall, dispatchStart time.Time) ToolResult {
+	if turn := dryRunTurnFrom(ctx); turn != nil {
+		turn.simulated.Add(1)
+	}
+	args, _ := json.Marshal(call.Args)
+	slog.Info("audit", "event", "tool_call_simulated", "actor", actor.Actor(ctx), "session_id", actor.SessionID(ctx),
+		"plugin", call.Plugin, "action", call.Action, "args", string(args))
+	content := fmt.Sprintf("[dry run] %s was NOT executed: dry-run mode is on, so nothing was changed. "+
+		"It would have run with args %s. Continue as if it succeeded, and tell the user that this was a dry run.",
+		toolFQN(call.Plugin, call.Action), args)
+	if call.FromLLM {
+		emit.EmitToolCallResult(ctx, o.eventSink, emit.ToolCallResultArgs{
+			CallID:    call.ID,
+			Status:    "ok",
+			Response:  content,
+			LatencyMS: time.Since(dispatchStart).Milliseconds(),
+		})
+	}
+	return ToolResult{CallID: call.ID, Content: content}
+}
+
+// mark records on a finished turn's result how many calls it simulated,
+// as metadata "dry_run_simulated", for channels and the trace.
+func (t *dryRunTurn) mark(result *RunResult) {
+	n := t.simulated.Load()
+	if result == nil || n == 0 {
+		return
+	}
+	if result.Metadata == nil {
+		result.Metadata = map[string]string{}
+	}
+	result.Metadata["dry_run_simulated"] = strconv.Itoa(int(n))
+}
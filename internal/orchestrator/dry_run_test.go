@@ -0,0 +1,98 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newDryRunTestOrchestrator(t *testing.T, opts OrchestratorOpts, responses ...string) (*Orchestrator, *deployExecutor, *state.SessionStore) {
+	t.Helper()
+	exec := &deployExecutor{}
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{
+		Name: "deploy", Description: "Deploys",
+		Actions: []Action{
+			{Name: "run", Description: "Deploy", Parameters: []Parameter{{Name: "env"}}},
+			{Name: "status", Description: "Deploy status", ReadOnly: true},
+		},
+	}, exec)
+	_ = reg.Register(PluginCapability{
+		Name: "docs", Description: "Docs",
+		Actions: []Action{{Name: "publish", Description: "Publish"}},
+	}, exec)
+	parser := &fakeParser{parseFn: func(resp string) []ToolCall {
+		plugin, action, ok := strings.Cut(resp, ".")
+		if !ok {
+			return nil
+		}
+		call := ToolCall{ID: "c1", Plugin: plugin, Action: action}
+		if action == "run" {
+			call.Args = map[string]string{"env": "prod"}
+		}
+		return []ToolCall{call}
+	}}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: responses}, parser, reg, state.NewMemoryStore(""), sessions, opts)
+	return orch, exec, sessions
+}
+
+func TestDryRun_SimulatesMutatingCalls(t *testing.T) {
+	orch, exec, _ := newDryRunTestOrchestrator(t, OrchestratorOpts{DryRun: DryRun{Enabled: true}},
+		"deploy.status", "deploy.run", "Deployed, as a dry run")
+
+	res, err := orch.Run(context.Background(), "s1", "deploy to prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.calls) != 1 || exec.calls[0].Action != "status" {
+		t.Fatalf("calls that ran = %+v, want only the read-only status", exec.calls)
+	}
+	if res.Metadata["dry_run_simulated"] != "1" {
+		t.Errorf("metadata = %v, want dry_run_simulated=1", res.Metadata)
+	}
+	sim := orch.executeCall(context.Background(), ToolCall{ID: "c2", Plugin: "deploy", Action: "run", Args: map[string]string{"env": "prod"}, FromLLM: true})
+	if sim.Error != "" || !strings.Contains(sim.Content, "deploy__run was NOT executed") || !strings.Contains(sim.Content, `"env":"prod"`) {
+		t.Errorf("simulated result = %+v", sim)
+	}
+}
+
+func TestDryRun_PerPlugin(t *testing.T) {
+	orch, exec, _ := newDryRunTestOrchestrator(t, OrchestratorOpts{DryRun: DryRun{Plugins: []string{"docs"}}})
+	ctx := context.Background()
+
+	if res := orch.executeCall(ctx, ToolCall{ID: "a", Plugin: "docs", Action: "publish", FromLLM: true}); !strings.Contains(res.Content, "[dry run]") {
+		t.Errorf("docs.publish = %+v, want simulated", res)
+	}
+	if res := orch.executeCall(ctx, ToolCall{ID: "b", Plugin: "deploy", Action: "run", Args: map[string]string{"env": "qa"}, FromLLM: true}); res.Content != "deployed qa" {
+		t.Errorf("deploy.run = %+v, want run", res)
+	}
+	if len(exec.calls) != 1 {
+		t.Errorf("%d calls ran, want 1", len(exec.calls))
+	}
+}
+
+func TestDryRun_PerSession(t *testing.T) {
+	orch, exec, sessions := newDryRunTestOrchestrator(t, OrchestratorOpts{},
+		"deploy.run", "done", "deploy.run", "done")
+	_ = sessions.SetMetadata("s1", MetaDryRun, "true")
+	sessions.Create("s2", "", "", "")
+
+	res, err := orch.Run(context.Background(), "s1", "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.calls) != 0 || res.Metadata["dry_run_simulated"] != "1" {
+		t.Errorf("dry-run session: %d calls ran, metadata %v", len(exec.calls), res.Metadata)
+	}
+	res, err = orch.Run(context.Background(), "s2", "deploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.calls) != 1 || res.Metadata["dry_run_simulated"] != "" {
+		t.Errorf("other session: %d calls ran, metadata %v", len(exec.calls), res.Metadata)
+	}
+}
@@ -0,0 +1,166 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// MetaVariant is the session metadata key recording the experiment variant
+// that serves the session. A session keeps its variant for its whole life,
+// so changing the traffic split only moves new sessions.
+const MetaVariant = "experiment_variant"
+
+// ExperimentVariant is one arm of an A/B experiment.
+type ExperimentVariant struct {
+	Name         string
+	Model        string // model pin ("provider/model" or "model"); empty = routing default
+	PromptSuffix string // appended to the system prompt
+	Weight       int    // share of new sessions relative to the other variants; 0 = 1
+}
+
+// Experiments splits sessions between variants by a hash of the session id.
+// With no variants every turn runs unchanged.
+type Experiments struct {
+	Variants []ExperimentVariant
+}
+
+// Validate reports duplicate or malformed variant names and negative weights.
+func (e Experiments) Validate() error {
+	names := make(map[string]bool, len(e.Variants))
+	for _, v := range e.Variants {
+		if !agentNamePattern.MatchString(v.Name) {
+			return fmt.Errorf("variant name %q: use letters, digits, '-', '_' or '.'", v.Name)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("duplicate variant %q", v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight < 0 {
+			return fmt.Errorf("variant %q: weight must not be negative", v.Name)
+		}
+	}
+	return nil
+}
+
+func (e Experiments) lookup(name string) *ExperimentVariant {
+	if name == "" {
+		return nil
+	}
+	for i := range e.Variants {
+		if e.Variants[i].Name == name {
+			return &e.Variants[i]
+		}
+	}
+	return nil
+}
+
+// assign picks the variant for a session that has none yet. The same id
+// always lands on the same variant for a given variant list.
+func (e Experiments) assign(sessionID string) *ExperimentVariant {
+	total := 0
+	for _, v := range e.Variants {
+		total += max(v.Weight, 1)
+	}
+	if total == 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(sessionID))
+	n := int(h.Sum32() % uint32(total))
+	for i := range e.Variants {
+		n -= max(e.Variants[i].Weight, 1)
+		if n < 0 {
+			return &e.Variants[i]
+		}
+	}
+	return nil
+}
+
+// ExperimentRun is what one turn served by a variant cost and how its tools
+// fared.
+type ExperimentRun struct {
+	InputTokens  int
+	OutputTokens int
+	ToolCalls    int
+	ToolErrors   int
+}
+
+// ExperimentObserver is told about every turn an experiment variant served,
+// so outcomes can be compared per variant.
+type ExperimentObserver interface {
+	ObserveExperimentRun(variant string, run ExperimentRun)
+}
+
+type variantKey struct{}
+
+// variantTurn is the variant a turn runs as, plus its tool error count.
+type variantTurn struct {
+	variant    *ExperimentVariant
+	toolErrors atomic.Int64
+}
+
+// selectVariant returns ctx carrying the session's variant, assigning and
+// recording one on the session's first turn. A recorded variant that is no
+// longer configured is replaced. sess may be nil.
+func (o *Orchestrator) selectVariant(ctx context.Context, sessions SessionStoreInterface, sessionID string, sess *state.Session) context.Context {
+	if len(o.experiments.Variants) == 0 {
+		return ctx
+	}
+	var recorded string
+	if sess != nil {
+		recorded = sess.Metadata[MetaVariant]
+	}
+	v := o.experiments.lookup(recorded)
+	if v == nil {
+		v = o.experiments.assign(sessionID)
+	}
+	if v == nil {
+		return ctx
+	}
+	if v.Name != recorded {
+		if err := sessions.SetMetadata(sessionID, MetaVariant, v.Name); err != nil {
+			slog.Warn("recording session experiment variant failed", "session_id", sessionID, "variant", v.Name, "error", err)
+		}
+	}
+	return context.WithValue(ctx, variantKey{}, &variantTurn{variant: v})
+}
+
+func variantTurnFromContext(ctx context.Context) *variantTurn {
+	t, _ := ctx.Value(variantKey{}).(*variantTurn)
+	return t
+}
+
+// variantFromContext returns the variant the turn runs as, or nil.
+func variantFromContext(ctx context.Context) *ExperimentVariant {
+	if t := variantTurnFromContext(ctx); t != nil {
+		return t.variant
+	}
+	return nil
+}
+
+// countVariantToolError records a failed tool call against the turn's variant.
+func countVariantToolError(ctx context.Context) {
+	if t := variantTurnFromContext(ctx); t != nil {
+		t.toolErrors.Add(1)
+	}
+}
+
+// experimentSection renders the variant's prompt suffix; "" when the turn is
+// not part of an experiment.
+func experimentSection(ctx context.Context) string {
+	v := variantFromContext(ctx)
+	if v == nil {
+		return ""
+	}
+	s := strings.TrimSpace(v.PromptSuffix)
+	if s == "" {
+		return ""
+	}
+	return "\n" + s + "\n"
+}
@@ -0,0 +1,130 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestExperiments_Validate(t *testing.T) {
+	ok := Experiments{Variants: []ExperimentVariant{{Name: "control"}, {Name: "terse", Weight: 3}}}
+	if err := ok.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []Experiments{
+		{Variants: []ExperimentVariant{{Name: "a"}, {Name: "a"}}},
+		{Variants: []ExperimentVariant{{Name: "has space"}}},
+		{Variants: []ExperimentVariant{{Name: "a", Weight: -1}}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v should not validate", bad)
+		}
+	}
+}
+
+func TestExperiments_AssignIsStableAndWeighted(t *testing.T) {
+	e := Experiments{Variants: []ExperimentVariant{{Name: "a"}, {Name: "b", Weight: 3}}}
+	counts := map[string]int{}
+	for i := range 4000 {
+		id := fmt.Sprintf("web:c%d", i)
+		v := e.assign(id)
+		if again := e.assign(id); again != v {
+			t.Fatalf("%s assigned %s then %s", id, v.Name, again.Name)
+		}
+		counts[v.Name]++
+	}
+	// b carries three times a's weight: expect roughly 1000 / 3000.
+	if counts["a"] < 800 || counts["a"] > 1200 {
+		t.Errorf("split = %v; want about 1000/3000", counts)
+	}
+}
+
+// experimentRecorder collects ObserveExperimentRun calls.
+type experimentRecorder struct {
+	runs map[string][]ExperimentRun
+}
+
+func (r *experimentRecorder) ObserveExperimentRun(variant string, run ExperimentRun) {
+	r.runs[variant] = append(r.runs[variant], run)
+}
+
+// failingExecutor fails every call.
+type failingExecutor struct{}
+
+func (failingExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	return ToolResult{CallID: call.ID, Error: "backend down"}
+}
+
+// scriptedLLM answers with responses in order and records the requests.
+type scriptedLLM struct {
+	requestLLM
+	responses []string
+}
+
+func (s *scriptedLLM) Complete(ctx context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	resp, _ := s.requestLLM.Complete(ctx, req)
+	if n := len(s.requests); n <= len(s.responses) {
+		resp.Content = s.responses[n-1]
+	}
+	return resp, nil
+}
+
+func TestExperiments_RunAppliesAndRecordsVariant(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "tickets", Description: "Support tickets",
+		Actions: []Action{{Name: "open", Description: "Open a ticket"}}}, failingExecutor{})
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	llm := &scriptedLLM{responses: []string{"call", "sorry, tickets are down"}}
+	parser := &fakeParser{parseFn: func(s string) []ToolCall {
+		if s == "call" {
+			return []ToolCall{{ID: "c1", Plugin: "tickets", Action: "open"}}
+		}
+		return nil
+	}}
+	obs := &experimentRecorder{runs: map[string][]ExperimentRun{}}
+	orch := NewWithRules(llm, parser, reg, state.NewMemoryStore(""), sessions, OrchestratorOpts{
+		Experiments: Experiments{Variants: []ExperimentVariant{
+			{Name: "terse", Model: "openai/gpt-small", PromptSuffix: "Answer in one sentence."},
+		}},
+		ExperimentObserver: obs,
+	})
+
+	if _, err := orch.Run(context.Background(), "web:c1", "open a ticket"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, sessions, "web:c1").Metadata[MetaVariant]; got != "terse" {
+		t.Errorf("session variant = %q", got)
+	}
+	req := llm.requests[0]
+	if req.Model != "gpt-small" {
+		t.Errorf("model = %q; want the variant's pin without the provider prefix", req.Model)
+	}
+	if s := req.Messages[0].Content; !strings.HasSuffix(strings.TrimSpace(s), "Answer in one sentence.") {
+		t.Errorf("system prompt should end with the variant suffix:\n%s", s)
+	}
+	runs := obs.runs["terse"]
+	if len(runs) != 1 || runs[0].ToolCalls != 1 || runs[0].ToolErrors != 1 {
+		t.Errorf("observed runs = %+v; want one run with one failed tool call", runs)
+	}
+}
+
+func TestExperiments_ReplacesUnknownRecordedVariant(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	_ = sessions.SetMetadata("s1", MetaVariant, "retired")
+	orch := NewWithRules(&requestLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{
+			Experiments: Experiments{Variants: []ExperimentVariant{{Name: "control"}}},
+		})
+	if _, err := orch.Run(context.Background(), "s1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, sessions, "s1").Metadata[MetaVariant]; got != "control" {
+		t.Errorf("session variant = %q; want a configured variant", got)
+	}
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// Internal LLM tasks that can be tuned separately from conversation turns
+// via OrchestratorOpts.TaskGeneration.
+const (
+	TaskSummary      = "summary"      // conversation summarization
+	TaskTitle        = "title"        // session title generation
+	TaskConfirmation = "confirmation" // narrating what a confirmed action will do
+	TaskSubagent     = "subagent"     // sub-agent (_subprocess) loops
+)
+
+// Generation controls how the model produces a reply. A zero field leaves
+// the choice to the next layer: agent or task, then the orchestrator-wide
+// setting, then the model's max_tokens from models.providers, then the
+// provider's own default.
+type Generation struct {
+	MaxTokens   int      // cap on output tokens
+	Temperature *float64 // 0-2; lower is more deterministic
+	TopP        *float64 // nucleus sampling, 0-1
+	Stop        []string // stop sequences
+}
+
+// Validate rejects values no provider accepts.
+func (g Generation) Validate() error {
+	if g.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must not be negative")
+	}
+	if g.Temperature != nil && (*g.Temperature < 0 || *g.Temperature > 2) {
+		return fmt.Errorf("temperature %v out of range 0-2", *g.Temperature)
+	}
+	if g.TopP != nil && (*g.TopP < 0 || *g.TopP > 1) {
+		return fmt.Errorf("top_p %v out of range 0-1", *g.TopP)
+	}
+	for _, s := range g.Stop {
+		if s == "" {
+			return fmt.Errorf("stop sequences must not be empty")
+		}
+	}
+	return nil
+}
+
+// or returns g with its unset fields taken from base.
+func (g Generation) or(base Generation) Generation {
+	if g.MaxTokens == 0 {
+		g.MaxTokens = base.MaxTokens
+	}
+	if g.Temperature == nil {
+		g.Temperature = base.Temperature
+	}
+	if g.TopP == nil {
+		g.TopP = base.TopP
+	}
+	if g.Stop == nil {
+		g.Stop = base.Stop
+	}
+	return g
+}
+
+// apply sets the request's generation fields that the caller left unset.
+func (g Generation) apply(req *provider.CompletionRequest) {
+	if req.MaxTokens == 0 {
+		req.MaxTokens = g.MaxTokens
+	}
+	if req.Temperature == nil {
+		req.Temperature = g.Temperature
+	}
+	if req.TopP == nil {
+		req.TopP = g.TopP
+	}
+	if req.Stop == nil {
+		req.Stop = g.Stop
+	}
+}
+
+// generationFor resolves the settings for a request: a conversation turn
+// (task "") uses the current agent's, an internal task its own, each
+// falling back to the orchestrator-wide settings.
+func (o *Orchestrator) generationFor(ctx context.Context, task string) Generation {
+	if task != "" {
+		return o.taskGeneration[task].or(o.generation)
+	}
+	if ag := agentFromContext(ctx); ag != nil {
+		return ag.Generation.or(o.generation)
+	}
+	return o.generation
+}
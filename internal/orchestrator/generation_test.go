@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestGeneration_AppliedPerAgent(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	sessions.Create("web:W1", "", "", "")
+	llm := &requestLLM{}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{
+			Generation: Generation{MaxTokens: 800, Temperature: float(0.7), Stop: []string{"\nUser:"}},
+			Agents: Agents{
+				List:     []Agent{{Name: "support-bot", Generation: Generation{Temperature: float(0)}}},
+				Channels: map[string]string{"slack": "support-bot"},
+			},
+		})
+	if _, err := orch.Run(actor.WithActor(context.Background(), "web:U1"), "web:W1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	req := llm.requests[0]
+	if req.MaxTokens != 800 || req.Temperature == nil || *req.Temperature != 0.7 || !slices.Equal(req.Stop, []string{"\nUser:"}) || req.TopP != nil {
+		t.Errorf("orchestrator settings not applied: max=%d temp=%v stop=%q top_p=%v", req.MaxTokens, req.Temperature, req.Stop, req.TopP)
+	}
+
+	llm.requests = nil
+	if _, err := orch.Run(actor.WithActor(context.Background(), "slack:U1"), "slack:C1", "my order is late"); err != nil {
+		t.Fatal(err)
+	}
+	req = llm.requests[0]
+	if req.Temperature == nil || *req.Temperature != 0 {
+		t.Errorf("agent temperature not applied: %v", req.Temperature)
+	}
+	if req.MaxTokens != 800 {
+		t.Errorf("agent without max_tokens should inherit the orchestrator's, got %d", req.MaxTokens)
+	}
+}
+
+func TestGeneration_TaskOverride(t *testing.T) {
+	orch := NewWithRules(&requestLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{
+			Generation:     Generation{MaxTokens: 800, Temperature: float(0.7)},
+			TaskGeneration: map[string]Generation{TaskTitle: {MaxTokens: 20}},
+		})
+	title := orch.generationFor(context.Background(), TaskTitle)
+	if title.MaxTokens != 20 || title.Temperature == nil || *title.Temperature != 0.7 {
+		t.Errorf("title = %+v; want its own max_tokens and the orchestrator's temperature", title)
+	}
+	if summary := orch.generationFor(context.Background(), TaskSummary); summary.MaxTokens != 800 {
+		t.Errorf("task without an override = %+v; want the orchestrator's settings", summary)
+	}
+
+	// A value the caller set on the request is never overwritten.
+	req := &provider.CompletionRequest{MaxTokens: 5}
+	title.apply(req)
+	if req.MaxTokens != 5 || *req.Temperature != 0.7 {
+		t.Errorf("apply: max=%d temp=%v", req.MaxTokens, req.Temperature)
+	}
+}
+
+func TestGeneration_Validate(t *testing.T) {
+	if err := (Generation{MaxTokens: 100, Temperature: float(2), TopP: float(0.9), Stop: []string{"END"}}).Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []Generation{
+		{MaxTokens: -1},
+		{Temperature: float(2.5)},
+		{TopP: float(1.1)},
+		{Stop: []string{""}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v should not validate", bad)
+		}
+	}
+	if err := (Agents{List: []Agent{{Name: "a", Generation: Generation{TopP: float(-1)}}}}).Validate(); err == nil {
+		t.Error("an agent's generation settings must be validated")
+	}
+}
@@ -27,6 +27,10 @@ type Guard struct {
 	MaxResponseBytes  int
 	Timeout           time.Duration
 	ForbiddenPatterns []*regexp.Regexp
+
+	secrets    *secretRedactor      // nil = tool outputs are not scanned for credentials
+	trust      map[string]string    // plugin -> trust level; missing = TrustStandard
+	classifier *injectionClassifier // nil = untrusted output is only masked
 }
 
 func NewGuard() *Guard {
@@ -38,14 +42,51 @@ func NewGuard() *Guard {
 }
 
 func (g *Guard) Sanitize(result ToolResult) ToolResult {
-	result.Content = g.sanitizeContent(result.Content)
+	return g.sanitize(ToolCall{ID: result.CallID}, result, true)
+}
+
+// SanitizeCall is Sanitize under the guard policy of the plugin that
+// produced result: trusted plugins skip the forbidden-pattern masking, and
+// untrusted ones also go through the injection classifier when one is set.
+func (g *Guard) SanitizeCall(ctx context.Context, call ToolCall, result ToolResult) ToolResult {
+	trust := g.trustOf(call.Plugin)
+	result = g.sanitize(call, result, trust != TrustTrusted)
+	if trust == TrustUntrusted && g.classifier != nil {
+		result = g.classifier.check(ctx, call, result)
+	}
+	return result
+}
+
+func (g *Guard) sanitize(call ToolCall, result ToolResult, mask bool) ToolResult {
+	if mask {
+		var masked int
+		result.Content, masked = g.sanitizeContent(result.Content)
+		var n int
+		result.Error, n = g.sanitizeContent(result.Error)
+		if masked += n; masked > 0 {
+			auditMasked(call, "forbidden_pattern", "matches", masked)
+		}
+	} else {
+		result.Content = g.truncate(result.Content)
+		result.Error = g.truncate(result.Error)
+	}
 	// Structured content is JSON: pattern-replacement would silently corrupt
 	// it (e.g. asterisks inside object keys produce invalid JSON the model
 	// then can't parse). Plugins are a trusted subprocess boundary per the
 	// project's threat model, so we cap size only and leave the payload
 	// intact.
 	result.StructuredContent = g.truncate(result.StructuredContent)
-	result.Error = g.sanitizeContent(result.Error)
+	// Masked spans never include a quote, so a secret inside a JSON string
+	// value is replaced in place and structured content stays parseable.
+	if g.secrets != nil {
+		found := map[string]int{}
+		result.Content = g.secrets.redact(result.Content, found)
+		result.StructuredContent = g.secrets.redact(result.StructuredContent, found)
+		result.Error = g.secrets.redact(result.Error, found)
+		if len(found) > 0 {
+			auditMasked(call, "secret", "kinds", secretKinds(found))
+		}
+	}
 	// Plugin responses can contain invalid UTF-8 from external sources.
 	// gRPC proto marshaling rejects any string field with invalid UTF-8,
 	// so replace bad sequences with the Unicode replacement character.
@@ -63,17 +104,21 @@ func toValidUTF8(s string) string {
 	return strings.ToValidUTF8(s, "\ufffd")
 }
 
-func (g *Guard) sanitizeContent(s string) string {
+// sanitizeContent truncates s and masks forbidden patterns, returning the
+// number of masked matches.
+func (g *Guard) sanitizeContent(s string) (string, int) {
 	if s == "" {
-		return s
+		return s, 0
 	}
 	s = g.truncate(s)
+	masked := 0
 	for _, pat := range g.ForbiddenPatterns {
 		s = pat.ReplaceAllStringFunc(s, func(match string) string {
+			masked++
 			return strings.Repeat("*", len(match))
 		})
 	}
-	return s
+	return s, masked
 }
 
 func (g *Guard) truncate(s string) string {
@@ -0,0 +1,138 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// Plugin trust levels for the guard policy.
+const (
+	TrustTrusted   = "trusted"   // output is passed on without forbidden-pattern masking
+	TrustStandard  = "standard"  // default: forbidden patterns are masked
+	TrustUntrusted = "untrusted" // masked, then checked by the injection classifier when one is set
+)
+
+// classifierMaxBytes caps how much of a tool output the injection
+// classifier reads; injections aimed at the model sit where it would read
+// them, and the guard already truncates at MaxResponseBytes.
+const classifierMaxBytes = 16 * 1024
+
+// GuardPolicy configures how the guard treats tool output beyond the
+// built-in masking of tool-call markers.
+type GuardPolicy struct {
+	DenyPatterns []*regexp.Regexp // masked in tool output in addition to the built-in patterns
+	Trust        map[string]string
+	Classifier   InjectionClassifier
+}
+
+// InjectionClassifier asks an LLM whether untrusted tool output tries to
+// steer the model. Disabled when LLM is nil.
+type InjectionClassifier struct {
+	LLM      LLMClient
+	Model    string // "" = the client's default model
+	FailOpen bool   // pass output through when the classifier fails; default withholds it
+}
+
+// Validate checks the trust levels.
+func (p GuardPolicy) Validate() error {
+	for plugin, level := range p.Trust {
+		if !slices.Contains([]string{TrustTrusted, TrustStandard, TrustUntrusted}, level) {
+			return fmt.Errorf("plugin %q: unknown trust level %q (want trusted, standard or untrusted)", plugin, level)
+		}
+	}
+	return nil
+}
+
+// applyPolicy adds the policy's deny patterns, trust levels and classifier
+// to g.
+func (g *Guard) applyPolicy(p GuardPolicy) {
+	if len(p.DenyPatterns) > 0 {
+		g.ForbiddenPatterns = append(slices.Clone(g.ForbiddenPatterns), p.DenyPatterns...)
+	}
+	g.trust = p.Trust
+	if p.Classifier.LLM != nil {
+		g.classifier = &injectionClassifier{cfg: p.Classifier}
+	}
+}
+
+func (g *Guard) trustOf(plugin string) string {
+	if level := g.trust[plugin]; level != "" {
+		return level
+	}
+	return TrustStandard
+}
+
+// auditMasked records that the guard changed a tool output before the model
+// saw it.
+func auditMasked(call ToolCall, reason string, detail ...any) {
+	args := append([]any{"event", "tool_output_masked", "reason", reason,
+		"plugin", call.Plugin, "action", call.Action, "call_id", call.ID}, detail...)
+	slog.Info("audit", args...)
+}
+
+type injectionClassifier struct {
+	cfg InjectionClassifier
+}
+
+// check withholds result when the classifier flags it. Errors are left as
+// they are: they are short, already masked, and withholding them would hide
+// why the call failed.
+func (c *injectionClassifier) check(ctx context.Context, call ToolCall, result ToolResult) ToolResult {
+	text := result.Content
+	if result.StructuredContent != "" {
+		text += "\n\n" + result.StructuredContent
+	}
+	if strings.TrimSpace(text) == "" || result.Error != "" {
+		return result
+	}
+	if len(text) > classifierMaxBytes {
+		cut := classifierMaxBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		text = text[:cut]
+	}
+	resp, err := c.cfg.LLM.Complete(ctx, &provider.CompletionRequest{
+		Model: c.cfg.Model,
+		Messages: []provider.Message{
+			{Role: provider.RoleSystem, Content: prompts.InjectionClassifier},
+			{Role: provider.RoleUser, Content: text},
+		},
+	})
+	var verdict string
+	if err == nil {
+		verdict = strings.TrimSpace(resp.Content)
+	}
+	switch upper := strings.ToUpper(verdict); {
+	case strings.HasPrefix(upper, "SAFE"):
+		return result
+	case strings.HasPrefix(upper, "INJECTION"):
+		reason := strings.TrimSpace(strings.TrimLeft(verdict[len("INJECTION"):], ": "))
+		auditMasked(call, "injection_classifier", "verdict", reason)
+		return withheld(result, fmt.Sprintf("the response of %s looked like a prompt injection attempt", toolFQN(call.Plugin, call.Action)))
+	}
+	if err == nil {
+		err = fmt.Errorf("unexpected verdict %q", verdict)
+	}
+	slog.Warn("injection classifier failed", "plugin", call.Plugin, "action", call.Action, "fail_open", c.cfg.FailOpen, "error", err)
+	if c.cfg.FailOpen {
+		return result
+	}
+	auditMasked(call, "injection_classifier_failed")
+	return withheld(result, fmt.Sprintf("the response of %s could not be checked for prompt injection", toolFQN(call.Plugin, call.Action)))
+}
+
+// withheld replaces the output of result with a note the model can relay.
+func withheld(result ToolResult, why string) ToolResult {
+	result.Content = "[output withheld: " + why + "]"
+	result.StructuredContent = ""
+	return result
+}
@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"sync"
+
+	"github.com/opentalon/opentalon/internal/actor"
+)
+
+// A redelivered inbound message (a webhook retry that got past the dedup
+// window, say after a restart) runs a fresh turn. Actions that opt into the
+// idempotency_key context arg receive a key that is the same in both runs,
+// so the plugin or the remote API can refuse the second side effect.
+//
+// The key is derived from the message key the channel handler attached
+// (channel, conversation, platform message id), the tool, and how many
+// times this turn has called that tool before. The LLM's args are left
+// out on purpose: a re-run rarely words a ticket summary identically, but
+// it does make the same calls in the same order.
+
+type idempotencyScopeKey struct{}
+type injectingCallKey struct{}
+
+// idempotencyScope counts calls per tool within one turn.
+type idempotencyScope struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// withIdempotencyScope starts the per-turn call count. Without a message
+// key there is nothing stable to derive keys from, and none are issued.
+func withIdempotencyScope(ctx context.Context) context.Context {
+	if actor.MessageKey(ctx) == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, idempotencyScopeKey{}, &idempotencyScope{calls: make(map[string]int)})
+}
+
+// withInjectingCall exposes the call whose context args are being resolved
+// to providers that need it.
+func withInjectingCall(ctx context.Context, call ToolCall) context.Context {
+	return context.WithValue(ctx, injectingCallKey{}, call)
+}
+
+// idempotencyKey returns the key for the call being injected, or "" when
+// the turn was not started by an identified inbound message.
+func idempotencyKey(ctx context.Context) string {
+	scope, _ := ctx.Value(idempotencyScopeKey{}).(*idempotencyScope)
+	call, ok := ctx.Value(injectingCallKey{}).(ToolCall)
+	if scope == nil || !ok {
+		return ""
+	}
+	tool := call.Plugin + "." + call.Action
+	scope.mu.Lock()
+	n := scope.calls[tool]
+	scope.calls[tool] = n + 1
+	scope.mu.Unlock()
+	h := sha256.New()
+	for _, part := range []string{actor.MessageKey(ctx), tool, strconv.Itoa(n)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state"
+	"github.com/opentalon/opentalon/pkg/plugin/contextargs"
+)
+
+// runCreatingIssues runs one turn in which the LLM creates two issues and
+// returns the idempotency keys the plugin received, in call order.
+func runCreatingIssues(t *testing.T, ctx context.Context, summary string) []string {
+	t.Helper()
+	var keys []string
+	registry := NewToolRegistry()
+	_ = registry.Register(PluginCapability{
+		Name: "jira", Description: "Jira",
+		Actions: []Action{{
+			Name:              "create_issue",
+			Description:       "Create issue",
+			Parameters:        []Parameter{{Name: "summary", Description: "Summary"}},
+			InjectContextArgs: []string{contextargs.IdempotencyKey},
+		}},
+	}, &capturingExecutor{fn: func(call ToolCall) ToolResult {
+		keys = append(keys, call.Args[contextargs.IdempotencyKey])
+		return ToolResult{CallID: call.ID, Content: "created"}
+	}})
+	callNum := 0
+	parser := &fakeParser{parseFn: func(string) []ToolCall {
+		callNum++
+		if callNum > 1 {
+			return nil
+		}
+		return []ToolCall{
+			{ID: "c1", Plugin: "jira", Action: "create_issue", Args: map[string]string{"summary": summary}},
+			{ID: "c2", Plugin: "jira", Action: "create_issue", Args: map[string]string{"summary": summary + " (follow-up)"}},
+		}
+	}}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := New(&fakeLLM{responses: []string{"[tool] jira.create_issue", "Done."}}, parser, registry, state.NewMemoryStore(""), sessions)
+	if _, err := orch.Run(ctx, "s1", "file the bug"); err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("create_issue ran %d times, want 2", len(keys))
+	}
+	return keys
+}
+
+func TestIdempotencyKey_StableAcrossRedelivery(t *testing.T) {
+	msg := actor.WithMessageKey(context.Background(), "webhook:C1:delivery-1")
+
+	first := runCreatingIssues(t, msg, "Login broken")
+	if first[0] == "" || first[0] == first[1] {
+		t.Fatalf("each call in a turn needs its own key, got %q", first)
+	}
+	// The redelivered message runs a fresh turn in which the LLM words the
+	// issue differently; the plugin must still see the same keys.
+	retry := runCreatingIssues(t, msg, "Cannot log in")
+	if retry[0] != first[0] || retry[1] != first[1] {
+		t.Errorf("redelivery keys %q differ from the first delivery's %q", retry, first)
+	}
+
+	other := runCreatingIssues(t, actor.WithMessageKey(context.Background(), "webhook:C1:delivery-2"), "Login broken")
+	if other[0] == first[0] {
+		t.Error("another message reused the first message's key")
+	}
+}
+
+func TestIdempotencyKey_EmptyWithoutMessageID(t *testing.T) {
+	keys := runCreatingIssues(t, context.Background(), "Login broken")
+	if keys[0] != "" || keys[1] != "" {
+		t.Errorf("without a message id there is nothing stable to key on, got %q", keys)
+	}
+}
@@ -0,0 +1,399 @@
+package orchestrator
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+const (
+	// jobsPluginName is the built-in plugin the model uses to check on
+	// background jobs; registered only when JobsConfig.Enabled is set.
+	jobsPluginName   = "_jobs"
+	jobsStatusAction = "status"
+	jobsResultAction = "result"
+
+	// defaultJobStatusAction is the plugin action polled with job_id when
+	// an accepted reply names none.
+	defaultJobStatusAction = "job_status"
+	defaultJobPollInterval = 30 * time.Second
+	defaultJobMaxAge       = 24 * time.Hour
+
+	// jobMessageType tags the pushed reply of a finished-job turn.
+	jobMessageType = "agent.job"
+)
+
+// Job states, as reported in a plugin's structured content.
+const (
+	jobAccepted = "accepted"
+	jobRunning  = "running"
+	jobDone     = "done"
+	jobFailed   = "failed"
+)
+
+// JobsConfig enables async tool jobs. An action that starts long work (a CI
+// pipeline, a data export) replies at once with structured content
+// {"status":"accepted","job_id":"..."}; the orchestrator then polls the
+// plugin's status action until the job is done or failed, and runs a hidden
+// turn so the model reports the outcome to the session. Jobs are kept in
+// memory: a restart forgets the ones still running.
+type JobsConfig struct {
+	Enabled      bool
+	PollInterval time.Duration // how often RunJobs checks due jobs; 0 = 30s
+	MaxAge       time.Duration // a job still running after this is given up; 0 = 24h
+}
+
+// jobState is the structured content of an accepted reply and of every
+// reply of the status action.
+type jobState struct {
+	Status       string `json:"status"`
+	JobID        string `json:"job_id,omitempty"`
+	StatusAction string `json:"status_action,omitempty"` // accepted only; default "job_status"
+	CheckAfter   string `json:"check_after,omitempty"`   // Go duration until the next check is worthwhile
+	Message      string `json:"message,omitempty"`       // short progress note
+}
+
+func parseJobState(structured string) (jobState, bool) {
+	if !strings.HasPrefix(strings.TrimSpace(structured), "{") {
+		return jobState{}, false
+	}
+	var s jobState
+	if err := json.Unmarshal([]byte(structured), &s); err != nil {
+		return jobState{}, false
+	}
+	return s, s.Status != ""
+}
+
+// jobPollKey marks the status-action calls of pollJob, which must never be
+// answered from the tool cache.
+type jobPollKey struct{}
+
+// asyncJob is one tracked job and the identity of the session that started it.
+type asyncJob struct {
+	ID           string // "<plugin>:<job_id>", what the model passes to _jobs
+	Plugin       string
+	Action       string // the action that started the job
+	JobID        string
+	StatusAction string
+	SessionID    string
+	Actor        string
+	EntityID     string
+	Group        string
+	Started      time.Time
+
+	status   string
+	message  string
+	result   string // the status action's content when done, its reason when failed
+	next     time.Time
+	finished time.Time
+	seen     bool // the model already saw the outcome; no follow-up turn
+}
+
+// jobTracker holds the jobs of every session.
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*asyncJob
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{jobs: make(map[string]*asyncJob)}
+}
+
+func (c JobsConfig) pollInterval() time.Duration {
+	return cmp.Or(c.PollInterval, defaultJobPollInterval)
+}
+
+func (c JobsConfig) maxAge() time.Duration {
+	return cmp.Or(c.MaxAge, defaultJobMaxAge)
+}
+
+// checkAfter is when a job reporting s should next be polled.
+func (c JobsConfig) checkAfter(s jobState) time.Time {
+	d := c.pollInterval()
+	if after, err := time.ParseDuration(s.CheckAfter); err == nil && after > 0 {
+		d = after
+	}
+	return time.Now().Add(d)
+}
+
+// trackAcceptedJob records the job behind an accepted reply and tells the
+// model it runs in the background. Replies that are not an accepted job, and
+// jobs whose plugin has no status action to poll, pass through unchanged.
+func (o *Orchestrator) trackAcceptedJob(ctx context.Context, call ToolCall, result ToolResult) ToolResult {
+	if o.jobs == nil || result.Error != "" || call.Plugin == jobsPluginName {
+		return result
+	}
+	s, ok := parseJobState(result.StructuredContent)
+	if !ok || s.Status != jobAccepted || s.JobID == "" {
+		return result
+	}
+	sessionID := actor.SessionID(ctx)
+	statusAction := cmp.Or(s.StatusAction, defaultJobStatusAction)
+	capability, _ := o.registry.GetCapability(call.Plugin)
+	if sessionID == "" || !slices.ContainsFunc(capability.Actions, func(a Action) bool { return a.Name == statusAction }) {
+		slog.Warn("accepted job not tracked: no session or no status action", "plugin", call.Plugin,
+			"action", call.Action, "job_id", s.JobID, "status_action", statusAction)
+		return result
+	}
+	job := &asyncJob{
+		ID:           call.Plugin + ":" + s.JobID,
+		Plugin:       call.Plugin,
+		Action:       call.Action,
+		JobID:        s.JobID,
+		StatusAction: statusAction,
+		SessionID:    sessionID,
+		Actor:        actor.Actor(ctx),
+		Started:      time.Now(),
+		status:       jobRunning,
+		message:      s.Message,
+		next:         o.jobsConfig.checkAfter(s),
+	}
+	if p := profile.FromContext(ctx); p != nil {
+		job.EntityID, job.Group = p.EntityID, p.Group
+	}
+	o.jobs.mu.Lock()
+	o.jobs.jobs[job.ID] = job
+	o.jobs.mu.Unlock()
+	slog.Info("audit", "event", "job_accepted", "session_id", sessionID, "plugin", call.Plugin, "action", call.Action, "job_id", s.JobID)
+
+	result.Content = strings.TrimSpace(result.Content + "\n\n" +
+		fmt.Sprintf(prompts.JobStarted, job.ID, toolFQN(jobsPluginName, jobsStatusAction), job.ID))
+	return result
+}
+
+// pollJob asks the plugin for the job's state and records it. It reports
+// whether this call moved the job to done or failed, so exactly one caller
+// delivers the outcome.
+func (o *Orchestrator) pollJob(ctx context.Context, job *asyncJob) bool {
+	res := o.executeCall(context.WithValue(ctx, jobPollKey{}, true), ToolCall{
+		ID:     "job-" + job.ID,
+		Plugin: job.Plugin,
+		Action: job.StatusAction,
+		Args:   map[string]string{"job_id": job.JobID},
+	})
+	o.jobs.mu.Lock()
+	defer o.jobs.mu.Unlock()
+	if job.status != jobRunning {
+		return false
+	}
+	s, ok := parseJobState(res.StructuredContent)
+	switch {
+	case res.Error != "" || !ok:
+		// A failed poll is retried; only the job's own state or its age ends it.
+		slog.Warn("polling job failed", "plugin", job.Plugin, "job_id", job.JobID, "error", cmp.Or(res.Error, "no job status in the reply"))
+		if time.Since(job.Started) < o.jobsConfig.maxAge() {
+			job.next = time.Now().Add(o.jobsConfig.pollInterval())
+			return false
+		}
+		job.status, job.result = jobFailed, fmt.Sprintf("no result after %s; stopped checking", o.jobsConfig.maxAge())
+	case s.Status == jobDone:
+		job.status, job.result = jobDone, res.Content
+	case s.Status == jobFailed:
+		job.status, job.result = jobFailed, cmp.Or(res.Content, s.Message)
+	case time.Since(job.Started) >= o.jobsConfig.maxAge():
+		job.status, job.result = jobFailed, fmt.Sprintf("still running after %s; stopped checking", o.jobsConfig.maxAge())
+	default:
+		job.message = s.Message
+		job.next = o.jobsConfig.checkAfter(s)
+		return false
+	}
+	job.message = s.Message
+	job.finished = time.Now()
+	slog.Info("audit", "event", "job_finished", "session_id", job.SessionID, "plugin", job.Plugin, "job_id", job.JobID, "status", job.status)
+	return true
+}
+
+// RunJobs polls due jobs every JobsConfig.PollInterval until ctx is done.
+// A job that finishes gets a hidden turn in its session so the model can
+// tell the user; finished jobs are forgotten after MaxAge. Call once, in its
+// own goroutine, when jobs are enabled.
+func (o *Orchestrator) RunJobs(ctx context.Context) {
+	if o.jobs == nil {
+		return
+	}
+	ticker := time.NewTicker(o.jobsConfig.pollInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			o.pollDueJobs(ctx)
+		}
+	}
+}
+
+func (o *Orchestrator) pollDueJobs(ctx context.Context) {
+	now := time.Now()
+	var due []*asyncJob
+	o.jobs.mu.Lock()
+	for id, job := range o.jobs.jobs {
+		switch {
+		case job.status == jobRunning && !job.next.After(now):
+			due = append(due, job)
+		case job.status != jobRunning && now.Sub(job.finished) > o.jobsConfig.maxAge():
+			delete(o.jobs.jobs, id)
+		}
+	}
+	o.jobs.mu.Unlock()
+	for _, job := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		if o.pollJob(o.jobContext(job), job) {
+			go o.reportJob(job)
+		}
+	}
+}
+
+// jobContext rebuilds the identity of the session that started job.
+func (o *Orchestrator) jobContext(job *asyncJob) context.Context {
+	ctx := context.Background()
+	if job.EntityID != "" {
+		ctx = profile.WithProfile(ctx, &profile.Profile{EntityID: job.EntityID, Group: job.Group, Kind: profile.KindChat})
+		ctx = actor.WithGroupID(ctx, job.Group)
+	}
+	if job.Actor != "" {
+		ctx = actor.WithActor(ctx, job.Actor)
+	}
+	return actor.WithSessionID(ctx, job.SessionID)
+}
+
+// reportJob runs a hidden turn with the job's outcome and pushes the reply,
+// unless the model already saw the outcome through _jobs.
+func (o *Orchestrator) reportJob(job *asyncJob) {
+	o.jobs.mu.Lock()
+	seen := job.seen
+	job.seen = true
+	prompt := jobPrompt(job)
+	o.jobs.mu.Unlock()
+	if seen {
+		return
+	}
+	ctx := actor.WithVisibility(o.jobContext(job), provider.VisibilityHidden)
+	res, err := o.Run(ctx, job.SessionID, prompt)
+	if err != nil {
+		slog.Warn("job follow-up turn failed", "session_id", job.SessionID, "job", job.ID, "error", err)
+		return
+	}
+	if res != nil && res.Response != "" {
+		o.pushToSession(ctx, job.SessionID, res.Response, jobMessageType)
+	}
+}
+
+// jobPrompt tells the model how a job it started ended. Callers hold jobs.mu.
+func jobPrompt(job *asyncJob) string {
+	tmpl := prompts.JobFinished
+	if job.status != jobDone {
+		tmpl = prompts.JobFailed
+	}
+	return fmt.Sprintf(tmpl, job.ID, toolFQN(job.Plugin, job.Action), cmp.Or(job.result, "(no output)"))
+}
+
+// registerJobTools registers _jobs when async jobs are enabled.
+func (o *Orchestrator) registerJobTools() {
+	if o.jobs == nil {
+		return
+	}
+	jobID := Parameter{Name: "job_id", Description: "The job id given when the job started", Required: true}
+	_ = o.registry.Register(PluginCapability{
+		Name:        jobsPluginName,
+		Description: "Check on background jobs",
+		Actions: []Action{
+			{
+				Name:          jobsStatusAction,
+				Description:   "Check whether a background job has finished. Without job_id, lists the jobs of this conversation.",
+				AlwaysInclude: true,
+				Parameters:    []Parameter{{Name: "job_id", Description: "The job id given when the job started"}},
+			},
+			{
+				Name:          jobsResultAction,
+				Description:   "Get the result of a finished background job.",
+				AlwaysInclude: true,
+				Parameters:    []Parameter{jobID},
+			},
+		},
+	}, &jobsExecutor{orch: o})
+}
+
+type jobsExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *jobsExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	o := e.orch
+	sessionID := actor.SessionID(ctx)
+	if call.Action == jobsStatusAction && strings.TrimSpace(call.Args["job_id"]) == "" {
+		return ToolResult{CallID: call.ID, Content: o.listJobs(sessionID)}
+	}
+	if call.Action != jobsStatusAction && call.Action != jobsResultAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	id := strings.TrimSpace(call.Args["job_id"])
+	o.jobs.mu.Lock()
+	job, ok := o.jobs.jobs[id]
+	running := ok && job.status == jobRunning
+	o.jobs.mu.Unlock()
+	// Jobs are only visible to the conversation that started them.
+	if !ok || job.SessionID != sessionID {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown job %q", id)}
+	}
+	if running && call.Action == jobsStatusAction {
+		o.pollJob(ctx, job)
+	}
+
+	o.jobs.mu.Lock()
+	defer o.jobs.mu.Unlock()
+	switch job.status {
+	case jobRunning:
+		msg := fmt.Sprintf("Job %s is still running (started %s ago).", job.ID, time.Since(job.Started).Round(time.Second))
+		if job.message != "" {
+			msg += " " + job.message
+		}
+		return ToolResult{CallID: call.ID, Content: msg + " You will be told when it finishes."}
+	case jobFailed:
+		job.seen = true
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("job %s failed: %s", job.ID, job.result)}
+	}
+	job.seen = true
+	if call.Action == jobsStatusAction {
+		return ToolResult{CallID: call.ID, Content: fmt.Sprintf("Job %s is done. Result:\n%s", job.ID, job.result)}
+	}
+	return ToolResult{CallID: call.ID, Content: job.result}
+}
+
+// listJobs renders the jobs of one session, oldest first.
+func (o *Orchestrator) listJobs(sessionID string) string {
+	o.jobs.mu.Lock()
+	defer o.jobs.mu.Unlock()
+	var jobs []*asyncJob
+	for _, job := range o.jobs.jobs {
+		if job.SessionID == sessionID {
+			jobs = append(jobs, job)
+		}
+	}
+	if len(jobs) == 0 {
+		return "No background jobs in this conversation."
+	}
+	slices.SortFunc(jobs, func(a, b *asyncJob) int { return a.Started.Compare(b.Started) })
+	var b strings.Builder
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "- %s (%s): %s", job.ID, toolFQN(job.Plugin, job.Action), job.status)
+		if job.message != "" {
+			b.WriteString(" — " + job.message)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,132 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// ciExecutor starts a pipeline as an async job and answers job_status with
+// the next of its states.
+type ciExecutor struct {
+	mu     sync.Mutex
+	states []ToolResult
+	polls  int
+}
+
+func (e *ciExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	switch call.Action {
+	case "run_pipeline":
+		return ToolResult{CallID: call.ID, Content: "Pipeline queued.",
+			StructuredContent: `{"status":"accepted","job_id":"p-42","message":"queued"}`}
+	case "job_status":
+		if call.Args["job_id"] != "p-42" {
+			return ToolResult{CallID: call.ID, Error: "no such job"}
+		}
+		r := e.states[min(e.polls, len(e.states)-1)]
+		e.polls++
+		r.CallID = call.ID
+		return r
+	}
+	return ToolResult{CallID: call.ID, Error: "unknown action"}
+}
+
+func newJobsOrch(t *testing.T, llm LLMClient, exec *ciExecutor, opts OrchestratorOpts) (*Orchestrator, context.Context) {
+	t.Helper()
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "ci", Description: "CI pipelines", Actions: []Action{
+		{Name: "run_pipeline", Description: "Start a pipeline"},
+		{Name: "job_status", Description: "Pipeline state", Parameters: []Parameter{{Name: "job_id", Required: true}}},
+	}}, exec)
+	sessions := state.NewSessionStore("")
+	sessions.Create("sess-1", "", "", "")
+	opts.Jobs = JobsConfig{Enabled: true}
+	noCalls := &fakeParser{parseFn: func(string) []ToolCall { return nil }}
+	orch := NewWithRules(llm, noCalls, reg, state.NewMemoryStore(""), sessions, opts)
+	return orch, actor.WithSessionID(actor.WithActor(context.Background(), "slack:U1"), "sess-1")
+}
+
+func TestJobs_AcceptedJobStatusAndResult(t *testing.T) {
+	exec := &ciExecutor{states: []ToolResult{
+		{StructuredContent: `{"status":"running","message":"stage 2 of 3"}`},
+		{Content: "All 120 tests passed.", StructuredContent: `{"status":"done"}`},
+	}}
+	orch, ctx := newJobsOrch(t, &fakeLLM{}, exec, OrchestratorOpts{})
+	jobs := func(action string, args map[string]string) ToolResult {
+		return orch.executeCall(ctx, ToolCall{ID: "j", Plugin: jobsPluginName, Action: action, Args: args, FromLLM: true})
+	}
+
+	start := orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "ci", Action: "run_pipeline", FromLLM: true})
+	if !strings.HasPrefix(start.Content, "Pipeline queued.") || !strings.Contains(start.Content, `_jobs__status with job_id="ci:p-42"`) {
+		t.Fatalf("accepted reply = %q", start.Content)
+	}
+	if list := jobs(jobsStatusAction, nil); !strings.Contains(list.Content, "- ci:p-42 (ci__run_pipeline): running — queued") {
+		t.Errorf("job list = %q", list.Content)
+	}
+
+	if r := jobs(jobsStatusAction, map[string]string{"job_id": "ci:p-42"}); !strings.Contains(r.Content, "still running") || !strings.Contains(r.Content, "stage 2 of 3") {
+		t.Errorf("first status = %+v", r)
+	}
+	if r := jobs(jobsResultAction, map[string]string{"job_id": "ci:p-42"}); !strings.Contains(r.Content, "still running") || exec.polls != 1 {
+		t.Errorf("result while running = %+v (polls %d); result must not poll", r, exec.polls)
+	}
+	if r := jobs(jobsStatusAction, map[string]string{"job_id": "ci:p-42"}); !strings.Contains(r.Content, "is done") || !strings.Contains(r.Content, "All 120 tests passed.") {
+		t.Errorf("second status = %+v", r)
+	}
+	if r := jobs(jobsResultAction, map[string]string{"job_id": "ci:p-42"}); r.Content != "All 120 tests passed." {
+		t.Errorf("result = %+v", r)
+	}
+
+	other := actor.WithSessionID(context.Background(), "sess-2")
+	if r := orch.executeCall(other, ToolCall{ID: "j", Plugin: jobsPluginName, Action: jobsResultAction, Args: map[string]string{"job_id": "ci:p-42"}}); !strings.Contains(r.Error, "unknown job") {
+		t.Errorf("another session read the job: %+v", r)
+	}
+}
+
+func TestJobs_BackgroundPollReportsOutcome(t *testing.T) {
+	exec := &ciExecutor{states: []ToolResult{
+		{Content: "Export failed: disk full.", StructuredContent: `{"status":"failed"}`},
+	}}
+	send, pushed := pushRecorder()
+	orch, ctx := newJobsOrch(t, &fakeLLM{responses: []string{"The export failed because the disk is full."}}, exec, OrchestratorOpts{ChannelSender: send})
+
+	orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "ci", Action: "run_pipeline", FromLLM: true})
+	orch.jobs.jobs["ci:p-42"].next = orch.jobs.jobs["ci:p-42"].Started // due now
+	orch.pollDueJobs(context.Background())
+
+	msg := waitPush(t, pushed)
+	if msg.Content != "The export failed because the disk is full." || msg.Metadata["type"] != jobMessageType {
+		t.Errorf("pushed %+v", msg)
+	}
+	sess, _ := orch.sessions.Get("sess-1")
+	if len(sess.Messages) == 0 || !strings.Contains(sess.Messages[0].Content, "ci:p-42 started by ci__run_pipeline has failed:\nExport failed: disk full.") {
+		t.Errorf("follow-up prompt = %+v", sess.Messages)
+	}
+
+	// The outcome is delivered once; later ticks leave the job alone.
+	orch.pollDueJobs(context.Background())
+	if exec.polls != 1 {
+		t.Errorf("job polled %d times after it failed", exec.polls)
+	}
+}
+
+func TestJobs_DisabledLeavesAcceptedRepliesAlone(t *testing.T) {
+	reg := NewToolRegistry()
+	exec := &ciExecutor{}
+	_ = reg.Register(PluginCapability{Name: "ci", Description: "CI", Actions: []Action{{Name: "run_pipeline"}, {Name: "job_status"}}}, exec)
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{}, reg, state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+
+	r := orch.executeCall(actor.WithSessionID(context.Background(), "s"), ToolCall{ID: "c1", Plugin: "ci", Action: "run_pipeline", FromLLM: true})
+	if r.Content != "Pipeline queued." {
+		t.Errorf("content = %q", r.Content)
+	}
+	if _, ok := reg.GetCapability(jobsPluginName); ok {
+		t.Error("_jobs registered while jobs are disabled")
+	}
+}
@@ -0,0 +1,159 @@
+package orchestrator
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// defaultMaxAgentLoopIterations bounds the agent loop when no LoopLimits set
+// MaxIterations.
+const defaultMaxAgentLoopIterations = 20
+
+// Reasons the agent loop stopped before the model answered, as reported to
+// the LoopObserver.
+const (
+	LoopStopIterations  = "iterations"  // MaxIterations rounds ran
+	LoopStopDuration    = "duration"    // MaxDuration passed
+	LoopStopRepeat      = "repeat"      // the model kept making the same calls
+	LoopStopOscillation = "oscillation" // the model kept alternating between two sets of calls
+)
+
+// LoopLimits bounds one turn's agent loop. A zero field leaves the choice to
+// the next layer: agent, then channel, then the orchestrator-wide limits,
+// then the defaults (20 rounds, no time limit).
+type LoopLimits struct {
+	MaxIterations int           // LLM rounds per turn
+	MaxDuration   time.Duration // wall-clock time for the loop, checked before every round after the first
+}
+
+// Validate rejects negative limits.
+func (l LoopLimits) Validate() error {
+	if l.MaxIterations < 0 {
+		return fmt.Errorf("max_iterations must not be negative")
+	}
+	if l.MaxDuration < 0 {
+		return fmt.Errorf("max_duration must not be negative")
+	}
+	return nil
+}
+
+// or returns l with its unset fields taken from base.
+func (l LoopLimits) or(base LoopLimits) LoopLimits {
+	l.MaxIterations = cmp.Or(l.MaxIterations, base.MaxIterations)
+	l.MaxDuration = cmp.Or(l.MaxDuration, base.MaxDuration)
+	return l
+}
+
+// LoopObserver is told when a turn's agent loop stops without an answer
+// from the model: its budget ran out or it was going in circles. channel is
+// the channel id, "" when unknown; reason is one of the LoopStop constants.
+type LoopObserver interface {
+	ObserveLoopStop(channel, reason string)
+}
+
+// loopLimitsFor resolves the limits of the current turn.
+func (o *Orchestrator) loopLimitsFor(ctx context.Context) LoopLimits {
+	l := o.channelLoopLimits[currentChannelID(ctx)].or(o.loopLimits)
+	if ag := agentFromContext(ctx); ag != nil {
+		l = ag.LoopLimits.or(l)
+	}
+	return l.or(LoopLimits{MaxIterations: defaultMaxAgentLoopIterations})
+}
+
+// observeLoopStop logs and reports a loop that stopped without an answer.
+func (o *Orchestrator) observeLoopStop(ctx context.Context, reason string, round int) {
+	slog.Warn("agent loop stopped", "reason", reason, "round", round,
+		"channel", currentChannelID(ctx), "session_id", actor.SessionID(ctx))
+	if o.loopObserver != nil {
+		o.loopObserver.ObserveLoopStop(currentChannelID(ctx), reason)
+	}
+}
+
+// loopDetector spots a model going in circles from the tool calls of each
+// round. The first time it fires the model is nudged to answer; the second
+// time the turn stops.
+type loopDetector struct {
+	sigs   []string // toolCallSignature of each round that called tools
+	nudged bool
+}
+
+// observe records one round's calls and returns LoopStopRepeat when they
+// equal the previous round's, LoopStopOscillation when the last four rounds
+// alternate between two sets of calls, and "" otherwise.
+func (d *loopDetector) observe(sig string) string {
+	d.sigs = append(d.sigs, sig)
+	n := len(d.sigs)
+	switch {
+	case n >= 2 && d.sigs[n-1] == d.sigs[n-2]:
+		return LoopStopRepeat
+	case n >= 4 && d.sigs[n-1] == d.sigs[n-3] && d.sigs[n-2] == d.sigs[n-4]:
+		return LoopStopOscillation
+	}
+	return ""
+}
+
+// loopNudge is the hidden message that asks a looping model to answer.
+func loopNudge(reason string) string {
+	if reason == LoopStopOscillation {
+		return prompts.LoopOscillation
+	}
+	return prompts.LoopRepeat
+}
+
+// callNames lists the distinct tools of calls as plugin__action, in order.
+func callNames(calls []ToolCall) string {
+	var names []string
+	for _, c := range calls {
+		if n := toolFQN(c.Plugin, c.Action); !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// recoverExhaustedLoop answers a turn whose loop ran out of budget instead of
+// failing it: one last LLM call, with the session so far, asks the model to
+// sum up what it did and found and to ask the user how to go on. The tool
+// calls and results of the turn stay attached to the result. When that call
+// fails or yields no text, budgetErr is returned as before.
+func (o *Orchestrator) recoverExhaustedLoop(ctx context.Context, sessions SessionStoreInterface, sessionID, sysPrompt string, req *provider.CompletionRequest, result *RunResult, budgetErr error) (*RunResult, error) {
+	sess, err := sessions.Get(sessionID)
+	if err != nil {
+		return nil, budgetErr
+	}
+	messages := o.buildMessagesWithPrompt(ctx, sess, sysPrompt)
+	messages = append(messages, provider.Message{Role: provider.RoleUser, Content: prompts.LoopRecovery})
+	guarded, blocked, err := o.runGuardPlugins(ctx, messages)
+	if err != nil || blocked != nil {
+		return nil, budgetErr
+	}
+	req.Messages = guarded
+	o.generationFor(ctx, "").apply(req)
+	resp, err := o.llm.Complete(ctx, req)
+	if err != nil {
+		slog.Warn("loop recovery call failed", "session_id", sessionID, "error", err)
+		return nil, budgetErr
+	}
+	recordUsage(ctx, resp)
+	// Any tool call the model still made is dropped; only its text counts.
+	answer := StripInternalBlocks(resp.Content)
+	if answer == "" {
+		return nil, budgetErr
+	}
+	slog.Info("loop budget exhausted; answered with a progress summary", "session_id", sessionID,
+		"tool_calls", len(result.ToolCalls), "budget", budgetErr.Error())
+	result.Response = answer
+	if err := o.finishAnswer(ctx, sessions, sessionID, result, answer); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,151 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+type loopStops struct{ reasons []string }
+
+func (l *loopStops) ObserveLoopStop(channel, reason string) {
+	l.reasons = append(l.reasons, channel+"/"+reason)
+}
+
+// scriptedCalls maps each LLM response to the gitlab action it calls.
+var scriptedCalls = &fakeParser{parseFn: func(resp string) []ToolCall {
+	action, ok := map[string]string{"A": "analyze_code", "B": "create_pr"}[resp]
+	if !ok {
+		return nil
+	}
+	return []ToolCall{{ID: "c-" + resp, Plugin: "gitlab", Action: action}}
+}}
+
+func TestLoopDetection_StopsAfterNudge(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		responses []string
+		reason    string
+		tools     string
+	}{
+		{"repeat", []string{"A", "A", "A", "A"}, LoopStopRepeat, "gitlab__analyze_code"},
+		{"oscillation", []string{"A", "B", "A", "B", "A", "B"}, LoopStopOscillation, "gitlab__analyze_code"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			stops := &loopStops{}
+			llm := &fakeLLM{responses: tc.responses}
+			orch, sessID := setupOrchestratorWithOpts(llm, scriptedCalls, OrchestratorOpts{LoopObserver: stops})
+
+			res, err := orch.Run(actor.WithActor(context.Background(), "slack:U1"), sessID, "analyze the repo")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := coreStrings[msgLoopStopped]["en"]; res.Response != fmt.Sprintf(want, tc.tools) {
+				t.Errorf("response = %q", res.Response)
+			}
+			if len(stops.reasons) != 1 || stops.reasons[0] != "slack/"+tc.reason {
+				t.Errorf("observed stops = %v", stops.reasons)
+			}
+			if llm.callCount != len(tc.responses)-1 {
+				t.Errorf("LLM called %d times; the turn should stop on the second loop", llm.callCount)
+			}
+		})
+	}
+}
+
+func TestLoopLimits_DurationBudget(t *testing.T) {
+	stops := &loopStops{}
+	round := 0
+	parser := &fakeParser{parseFn: func(string) []ToolCall {
+		round++
+		return []ToolCall{{ID: "cx", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": fmt.Sprint(round)}}}
+	}}
+	// No response for the recovery call, so the budget error comes through.
+	llm := &fakeLLM{responses: []string{"x"}}
+	orch, sessID := setupOrchestratorWithOpts(llm, parser, OrchestratorOpts{
+		LoopLimits:   LoopLimits{MaxIterations: 3, MaxDuration: time.Nanosecond},
+		LoopObserver: stops,
+	})
+
+	_, err := orch.Run(context.Background(), sessID, "analyze")
+	if err == nil || !strings.Contains(err.Error(), "1ns time budget after 1 rounds") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(stops.reasons) != 1 || stops.reasons[0] != "/"+LoopStopDuration {
+		t.Errorf("observed stops = %v", stops.reasons)
+	}
+}
+
+func TestLoopLimits_Layers(t *testing.T) {
+	orch, _ := setupOrchestratorWithOpts(&fakeLLM{}, scriptedCalls, OrchestratorOpts{
+		LoopLimits:        LoopLimits{MaxDuration: time.Minute},
+		ChannelLoopLimits: map[string]LoopLimits{"sms": {MaxIterations: 4}},
+	})
+	sms := actor.WithActor(context.Background(), "sms:+1555")
+	support := withAgent(sms, &Agent{Name: "support", LoopLimits: LoopLimits{MaxDuration: 10 * time.Second}})
+
+	for name, tc := range map[string]struct {
+		ctx  context.Context
+		want LoopLimits
+	}{
+		"defaults": {context.Background(), LoopLimits{MaxIterations: defaultMaxAgentLoopIterations, MaxDuration: time.Minute}},
+		"channel":  {sms, LoopLimits{MaxIterations: 4, MaxDuration: time.Minute}},
+		"agent":    {support, LoopLimits{MaxIterations: 4, MaxDuration: 10 * time.Second}},
+	} {
+		if got := orch.loopLimitsFor(tc.ctx); got != tc.want {
+			t.Errorf("%s: limits = %+v, want %+v", name, got, tc.want)
+		}
+	}
+	if err := (LoopLimits{MaxIterations: -1}).Validate(); err == nil {
+		t.Error("negative max_iterations accepted")
+	}
+}
+
+// recordingLLM is a fakeLLM that keeps the last request it was sent.
+type recordingLLM struct {
+	fakeLLM
+	last *provider.CompletionRequest
+}
+
+func (r *recordingLLM) Complete(ctx context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	r.last = req
+	return r.fakeLLM.Complete(ctx, req)
+}
+
+func TestLoopLimits_ExhaustedBudgetGetsRecoveryTurn(t *testing.T) {
+	round := 0
+	parser := &fakeParser{parseFn: func(resp string) []ToolCall {
+		if resp != "call" {
+			return nil
+		}
+		round++
+		return []ToolCall{{ID: fmt.Sprint("c", round), Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": fmt.Sprint(round)}}}
+	}}
+	summary := "I analyzed 3 repos; 2 are left. Should I continue with those?"
+	llm := &recordingLLM{fakeLLM: fakeLLM{responses: []string{"call", "call", "call", summary}}}
+	orch, sessID := setupOrchestratorWithOpts(llm, parser, OrchestratorOpts{LoopLimits: LoopLimits{MaxIterations: 3}})
+
+	res, err := orch.Run(context.Background(), sessID, "analyze all repos")
+	if err != nil {
+		t.Fatalf("Run failed instead of recovering: %v", err)
+	}
+	if res.Response != summary {
+		t.Errorf("response = %q", res.Response)
+	}
+	if len(res.Results) != 3 || !strings.Contains(res.InputForDisplay, "executed gitlab.analyze_code") {
+		t.Errorf("tool results not attached: %d results, display %q", len(res.Results), res.InputForDisplay)
+	}
+	if last := llm.last.Messages[len(llm.last.Messages)-1]; last.Content != prompts.LoopRecovery {
+		t.Errorf("recovery call ended with %q", last.Content)
+	}
+	sess, _ := orch.sessions.Get(sessID)
+	if m := sess.Messages[len(sess.Messages)-1]; m.Role != provider.RoleAssistant || m.Content != summary {
+		t.Errorf("last session message = %+v", m)
+	}
+}
@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/lua"
+)
+
+// ResponseModeratorEntry configures a plugin or Lua script that checks the
+// final answer before it is stored and sent to the channel. Unlike a
+// formatter it may block the reply, and it runs on the answer the session
+// keeps, so a scrubbed reply is scrubbed in the history too.
+type ResponseModeratorEntry struct {
+	Plugin   string // "my-plugin" for gRPC or "lua:my-script" for Lua
+	Action   string // gRPC action name; ignored for Lua scripts
+	FailOpen bool   // if true, a failing moderator is skipped; default false blocks the reply
+}
+
+// DefaultModerationBlockedReply is sent in place of a blocked reply when the
+// moderator gives no message of its own.
+const DefaultModerationBlockedReply = "Sorry, I can't send that reply."
+
+// moderatorVerdict is the JSON a gRPC moderator may return instead of plain
+// text: {"send": false, "message": "..."} blocks the reply.
+type moderatorVerdict struct {
+	Send    *bool  `json:"send"`
+	Message string `json:"message"`
+}
+
+// moderate runs the configured moderators on reply in order, each seeing the
+// previous one's output, and returns the reply to send. A blocked reply (or
+// one a fail-closed moderator could not check) becomes the block message.
+func (o *Orchestrator) moderate(ctx context.Context, sessionID, reply string) string {
+	if len(o.moderators) == 0 || reply == "" {
+		return reply
+	}
+	channel := currentChannelID(ctx)
+	for _, m := range o.moderators {
+		name := m.Plugin
+		if m.Action != "" && !strings.HasPrefix(m.Plugin, "lua:") {
+			name = toolFQN(m.Plugin, m.Action)
+		}
+		text, send, message, err := o.runModerator(ctx, m, reply, channel)
+		if err != nil {
+			if m.FailOpen {
+				slog.Warn("response moderator failed, skipping", "moderator", name, "error", err)
+				continue
+			}
+			slog.Warn("response moderator failed, blocking reply", "moderator", name, "error", err)
+			send, message = false, ""
+		}
+		if !send {
+			if message == "" {
+				message = DefaultModerationBlockedReply
+			}
+			slog.Info("audit", "event", "response_moderated", "outcome", "blocked", "moderator", name, "session_id", sessionID, "channel", channel)
+			return message
+		}
+		// An empty result is a no-op, as for formatters, so a broken
+		// moderator cannot blank the reply.
+		if text != "" && text != reply {
+			slog.Info("audit", "event", "response_moderated", "outcome", "rewritten", "moderator", name, "session_id", sessionID, "channel", channel)
+			reply = text
+		}
+	}
+	return reply
+}
+
+func (o *Orchestrator) runModerator(ctx context.Context, m ResponseModeratorEntry, reply, channel string) (text string, send bool, message string, err error) {
+	if scriptName, ok := strings.CutPrefix(m.Plugin, "lua:"); ok {
+		scriptPath := o.luaScriptPaths[scriptName]
+		if scriptPath == "" {
+			return "", false, "", fmt.Errorf("lua script path not found for %q", scriptName)
+		}
+		res, err := lua.RunModerate(ctx, scriptPath, o.luaOpts, reply, channel)
+		if err != nil {
+			return "", false, "", err
+		}
+		return res.Text, res.Send, res.Message, nil
+	}
+	if !o.registry.HasAction(m.Plugin, m.Action) {
+		return "", false, "", fmt.Errorf("action %s not found", toolFQN(m.Plugin, m.Action))
+	}
+	toolResult := o.executeCall(ctx, ToolCall{
+		ID:     fmt.Sprintf("moderator-%s-%s", m.Plugin, m.Action),
+		Plugin: m.Plugin,
+		Action: m.Action,
+		Args:   map[string]string{"text": reply, "channel": channel},
+	})
+	if toolResult.Error != "" {
+		return "", false, "", fmt.Errorf("%s", toolResult.Error)
+	}
+	var v moderatorVerdict
+	if err := json.Unmarshal([]byte(toolResult.Content), &v); err == nil && v.Send != nil {
+		if *v.Send {
+			return reply, true, "", nil
+		}
+		return "", false, v.Message, nil
+	}
+	return toolResult.Content, true, "", nil
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func moderationOrchestrator(t *testing.T, answer string, moderators []ResponseModeratorEntry, scripts map[string]string) (*Orchestrator, *state.SessionStore) {
+	t.Helper()
+	registry := NewToolRegistry()
+	_ = registry.Register(PluginCapability{
+		Name:    "pii",
+		Actions: []Action{{Name: "scrub"}, {Name: "block"}},
+	}, &moderatorExecutor{})
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: []string{answer}}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		registry, state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{ResponseModerators: moderators, LuaScriptPaths: scripts})
+	return orch, sessions
+}
+
+type moderatorExecutor struct{}
+
+func (moderatorExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	if call.Action == "block" {
+		return ToolResult{CallID: call.ID, Content: `{"send": false, "message": "Blocked by policy."}`}
+	}
+	return ToolResult{CallID: call.ID, Content: strings.ReplaceAll(call.Args["text"], "123-45-6789", "[SSN]")}
+}
+
+func lastAssistant(t *testing.T, sessions *state.SessionStore) string {
+	t.Helper()
+	sess, err := sessions.Get("s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sess.Messages[len(sess.Messages)-1].Content
+}
+
+func TestModeration_RewriteReachesHistory(t *testing.T) {
+	orch, sessions := moderationOrchestrator(t, "Your SSN is 123-45-6789.",
+		[]ResponseModeratorEntry{{Plugin: "pii", Action: "scrub"}}, nil)
+	res, err := orch.Run(context.Background(), "s1", "what is my ssn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "Your SSN is [SSN]." {
+		t.Errorf("response = %q", res.Response)
+	}
+	if got := lastAssistant(t, sessions); got != "Your SSN is [SSN]." {
+		t.Errorf("history kept the unmoderated answer: %q", got)
+	}
+}
+
+func TestModeration_Block(t *testing.T) {
+	orch, sessions := moderationOrchestrator(t, "Something rude.",
+		[]ResponseModeratorEntry{{Plugin: "pii", Action: "scrub"}, {Plugin: "pii", Action: "block"}}, nil)
+	res, err := orch.Run(context.Background(), "s1", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "Blocked by policy." || lastAssistant(t, sessions) != "Blocked by policy." {
+		t.Errorf("response = %q", res.Response)
+	}
+}
+
+func TestModeration_FailureModes(t *testing.T) {
+	missing := ResponseModeratorEntry{Plugin: "nope", Action: "check"}
+
+	orch, _ := moderationOrchestrator(t, "Hello.", []ResponseModeratorEntry{missing}, nil)
+	if res, err := orch.Run(context.Background(), "s1", "hi"); err != nil || res.Response != DefaultModerationBlockedReply {
+		t.Errorf("fail-closed: %+v, %v", res, err)
+	}
+
+	missing.FailOpen = true
+	orch, _ = moderationOrchestrator(t, "Hello.", []ResponseModeratorEntry{missing}, nil)
+	if res, err := orch.Run(context.Background(), "s1", "hi"); err != nil || res.Response != "Hello." {
+		t.Errorf("fail-open: %+v, %v", res, err)
+	}
+}
+
+func TestModeration_LuaDisclaimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "disclaimer.lua")
+	script := `function moderate(text, channel) return text .. "\n\n_Not financial advice._" end`
+	if err := os.WriteFile(path, []byte(script), 0600); err != nil {
+		t.Fatal(err)
+	}
+	orch, _ := moderationOrchestrator(t, "Buy low.",
+		[]ResponseModeratorEntry{{Plugin: "lua:disclaimer"}}, map[string]string{"disclaimer": path})
+	res, err := orch.Run(context.Background(), "s1", "stocks?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "Buy low.\n\n_Not financial advice._" {
+		t.Errorf("response = %q", res.Response)
+	}
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/opentalon/opentalon/internal/logger"
+)
+
+// OfflineReporter is an optional extension of LLMClient: Offline reports
+// whether completions are currently served by the local offline model
+// (routing.offline) because no remote provider is reachable.
+type OfflineReporter interface {
+	Offline() bool
+}
+
+// MetaOfflineNotice marks a session as already told about the current
+// outage; it is cleared once the remote provider is back so the next outage
+// is announced again.
+const MetaOfflineNotice = "offline_notice"
+
+// announceOffline prefixes the first LLM answer a session gets during an
+// outage with a notice that a smaller local model is answering. System
+// replies (commands, confirmations) carry a type and are left alone.
+func (o *Orchestrator) announceOffline(ctx context.Context, sessions SessionStoreInterface, sessionID string, res *RunResult) {
+	r, ok := o.llm.(OfflineReporter)
+	if !ok || res == nil || res.Response == "" || res.Metadata["type"] != "" {
+		return
+	}
+	sess, _ := sessions.Get(sessionID)
+	if sess == nil {
+		return
+	}
+	told := sess.Metadata[MetaOfflineNotice] != ""
+	offline := r.Offline()
+	switch {
+	case offline && !told:
+		res.Response = o.coreStringFor(ctx, msgOfflineMode) + "\n\n" + res.Response
+		if err := sessions.SetMetadata(sessionID, MetaOfflineNotice, "true"); err != nil {
+			logger.FromContext(ctx).Warn("recording offline notice failed", "error", err)
+		}
+	case !offline && told:
+		if err := sessions.SetMetadata(sessionID, MetaOfflineNotice, ""); err != nil {
+			logger.FromContext(ctx).Warn("clearing offline notice failed", "error", err)
+		}
+	}
+}
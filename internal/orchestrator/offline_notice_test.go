@@ -0,0 +1,59 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// offlineLLM answers "ok" and reports a switchable offline state.
+type offlineLLM struct {
+	requestLLM
+	offline bool
+}
+
+func (l *offlineLLM) Offline() bool { return l.offline }
+
+func TestAnnounceOffline_OncePerOutage(t *testing.T) {
+	llm := &offlineLLM{offline: true}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	ctx := context.Background()
+	run := func(msg string) string {
+		t.Helper()
+		res, err := orch.Run(ctx, "s1", msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Response
+	}
+	notice := coreString("en", msgOfflineMode)
+
+	if got := run("hello"); got != notice+"\n\nok" {
+		t.Errorf("first offline reply = %q", got)
+	}
+	if got := run("still there?"); got != "ok" {
+		t.Errorf("second offline reply = %q; the notice should appear once per outage", got)
+	}
+	for _, m := range mustSession(t, sessions, "s1").Messages {
+		if strings.Contains(m.Content, notice) {
+			t.Error("the offline notice was stored in the conversation")
+		}
+	}
+
+	llm.offline = false
+	if got := run("back?"); got != "ok" {
+		t.Errorf("online reply = %q", got)
+	}
+	if mustSession(t, sessions, "s1").Metadata[MetaOfflineNotice] != "" {
+		t.Error("offline_notice should be cleared once the remote is back")
+	}
+	llm.offline = true
+	if got := run("again"); !strings.HasPrefix(got, notice) {
+		t.Errorf("a new outage should be announced again: %q", got)
+	}
+}
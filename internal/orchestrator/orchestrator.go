@@ -1,6 +1,7 @@
 package orchestrator
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
@@ -11,18 +12,25 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"text/template"
 	"time"
 	"unicode/utf8"
 
 	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/eventbus"
+	"github.com/opentalon/opentalon/internal/js"
 	"github.com/opentalon/opentalon/internal/logger"
 	"github.com/opentalon/opentalon/internal/lua"
 	"github.com/opentalon/opentalon/internal/pipeline"
 	"github.com/opentalon/opentalon/internal/profile"
 	"github.com/opentalon/opentalon/internal/prompts"
 	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/router"
 	"github.com/opentalon/opentalon/internal/sessionlock"
 	"github.com/opentalon/opentalon/internal/state"
 	"github.com/opentalon/opentalon/internal/state/store/events"
@@ -32,8 +40,6 @@ import (
 	lingua "github.com/pemistahl/lingua-go"
 )
 
-const maxAgentLoopIterations = 20
-
 // PermissionAction is the fixed action name the core uses when calling the permission plugin.
 const PermissionAction = "check"
 
@@ -108,8 +114,9 @@ type GroupPluginLookup interface {
 // UsageRecorder records LLM usage statistics after an orchestrator run.
 // interactionKind is the run's kind ("chat" | "system"); systemSource is an
 // optional per-feature label for system runs. Both come from the run's Profile.
+// tools names the tool (plugin__action) of each of the run's toolCalls.
 type UsageRecorder interface {
-	RecordUsage(ctx context.Context, entityID, groupID, channelID, sessionID, modelID, interactionKind, systemSource string, inputTokens, outputTokens, toolCalls int)
+	RecordUsage(ctx context.Context, entityID, groupID, channelID, sessionID, modelID, interactionKind, systemSource string, inputTokens, outputTokens, toolCalls int, tools []string)
 }
 
 // PromptSnapshotUpserter persists content-addressed prompt bodies so a
@@ -128,6 +135,14 @@ type PluginCallObserver interface {
 	ObservePluginCall(plugin, action string, failed bool, inputTokens, outputTokens int)
 }
 
+// SessionActivityObserver is told when a turn starts and finishes, under the
+// session's turn lock. Idle-session completion uses it to know when a
+// session went quiet.
+type SessionActivityObserver interface {
+	TurnStarted(sessionID string)
+	TurnFinished(sessionID string)
+}
+
 // KnowledgeConfig configures the knowledge directory scanning feature.
 type KnowledgeConfig struct {
 	Plugin string // plugin name to call for ingestion (e.g. "weaviate")
@@ -164,16 +179,42 @@ type OrchestratorOpts struct {
 	CustomRules             []string
 	ContentPreparers        []ContentPreparerEntry
 	ResponseFormatters      []ResponseFormatterEntry
+	ResponseModerators      []ResponseModeratorEntry // optional; run on the final answer before it is stored and sent; may rewrite or block it
 	LuaScriptPaths          map[string]string
+	LuaOptions              lua.Options       // optional; time, instruction and memory limits for Lua preparers and response hooks; zero value = defaults
+	JSScriptPaths           map[string]string // optional; script name -> path to .js script (for "js:name" preparers); run under LuaOptions
 	PermissionChecker       PermissionChecker
 	PermissionPluginName    string
 	RuntimePromptPath       string                        // optional path to editable prompt file (e.g. data_dir/custom_prompt.txt); appended to system prompt
+	PromptOverrides         PromptOverrides               // optional per-channel / per-group system prompt replace+append; see PromptOverrides for merge order
+	PromptLayers            PromptLayers                  // optional deployment layer, layer order and size budgets; zero value = default order, session layer capped at DefaultSessionLayerBytes
+	SystemPromptTemplate    *template.Template            // optional; lays out the system prompt sections (see PromptTemplateData); nil = built-in layout
+	ToolApprovals           ToolApprovals                 // optional; LLM calls to the listed tools are filed for admin approval instead of running
+	Agents                  Agents                        // optional personas and the channel/@mention rules that pick one per turn
+	Experiments             Experiments                   // optional A/B variants (model, prompt suffix) split by session
+	ModelAliases            map[string]string             // optional catalog aliases (lower case) → "provider/model", resolved in profile, agent, session and branch model pins
+	ActorProfiles           ActorProfileStore             // optional; users' own preferences (name, locale, timezone, style, instructions) added to the prompt
+	ToolOutputBlobs         ToolOutputBlobs               // optional; tool outputs above the threshold are stored as blobs and referenced from the message
+	ToolExposure            ToolExposure                  // optional; "summary" lists plugin names only and adds _tools__describe; empty = full
+	ToolScopes              []ToolScope                   // optional; narrow the plugins a turn sees and calls by channel, group and task type
+	Documents               Documents                     // optional; document index behind _knowledge__search and per-turn context injection
+	Attachments             Attachments                   // optional; store user files, extract their text and expose _files__read
+	Transcriber             Transcriber                   // optional; built-in STT for audio files, tried before STT preparers
+	SecretRedaction         SecretRedaction               // optional; mask credentials in tool outputs before the LLM or history sees them
+	PIIRedaction            PIIRedaction                  // optional; replace personal data in user input with placeholders, restored in the reply
+	ToolPolicies            []ToolPolicy                  // optional; enforced in order before every LLM tool call; the first that applies decides
+	DryRun                  DryRun                        // optional; simulate mutating tool calls everywhere or for some plugins (sessions opt in via MetaDryRun)
+	GuardPolicy             GuardPolicy                   // optional; extra deny patterns, per-plugin trust and the injection classifier
+	ReplyLanguage           string                        // optional; pin every reply to this language (name or ISO 639-1 code); empty = match the user
+	Locale                  string                        // optional; deployment language (name or ISO 639-1 code) used until a user's own is known; empty = English
+	ChannelLocales          map[string]string             // optional; channel id -> language, overriding Locale for that channel
+	Messages                MessageCatalog                // optional; overrides and extra translations of the core replies
 	ContextArgProviders     map[string]ContextArgProvider // optional; if nil, default providers (e.g. session_id) are used
 	ContextMessages         int                           // send only last N messages to LLM (0 = all)
 	SummarizeAfterMessages  int                           // 0 = off
 	MaxMessagesAfterSummary int                           // keep this many messages after summarization
-	SummarizePrompt         string                        // empty = default English
-	SummarizeUpdatePrompt   string                        // empty = default English
+	SummarizePrompt         string                        // empty = default English; a language line is appended for non-English sessions
+	SummarizeUpdatePrompt   string                        // empty = default English; a language line is appended for non-English sessions
 	SessionTitlePrompt      string                        // system prompt for the background title-generation pass (maybeGenerateTitle); empty = default in internal/prompts
 	SessionTitlesEnabled    bool                          // master switch for first-turn title generation; default false so tests that don't allocate the extra LLM response don't deadlock on a shared fake. Production (main.go) sets true.
 	PipelineEnabled         bool                          // when true, create Planner from llm
@@ -194,16 +235,31 @@ type OrchestratorOpts struct {
 	GroupPluginLookup             GroupPluginLookup       // optional; when set, filters tool list by profile group
 	UsageRecorder                 UsageRecorder           // optional; when set, records LLM usage after each run
 	PluginCallObserver            PluginCallObserver      // optional; when set, notified after each plugin/tool call
+	TimingObserver                TimingObserver          // optional; receives each Run's timing breakdown and summarization durations
+	ActivityObserver              SessionActivityObserver // optional; told when each Run starts and finishes
+	WorkflowRecorder              WorkflowRecorder        // optional; receives the plugin calls of each turn that made several, for replay
+	ExperimentObserver            ExperimentObserver      // optional; told about each turn an experiment variant served
 	EventSink                     emit.Sink               // optional; nil defaults to emit.NoOpSink (helpers run unconditionally, the no-op sink discards them)
+	Events                        *eventbus.Bus           // optional; lifecycle events (message_received, tool_executed) for plugin and Lua subscribers
 	PromptSnapshotStore           PromptSnapshotUpserter  // optional; when set, system prompt + server instructions + tool descriptions are persisted by sha256 so turn_start hashes resolve to content
 	SyncActionsPlugin             string                  // optional; plugin name for action sync (e.g. "weaviate")
 	SyncActionsAction             string                  // optional; action name for sync (e.g. "sync_actions"); requires SyncActionsPlugin
 	Knowledge                     KnowledgeConfig         // optional; knowledge directory ingestion
 	Subprocess                    SubprocessConfig        // optional; subprocess (sub-agent) support
 	Escalation                    EscalationConfig        // optional; background-trigger LLM turn entrypoint (_escalate)
+	AskUser                       AskUserConfig           // optional; _ask_user lets the model pause for a missing value
+	Resume                        ResumeConfig            // optional; checkpoints agent loops so a restart can resume them
+	Jobs                          JobsConfig              // optional; track async tool jobs and expose _jobs
+	LoopLimits                    LoopLimits              // optional; agent-loop rounds and wall-clock budget per turn; zero = 20 rounds, no time limit
+	ChannelLoopLimits             map[string]LoopLimits   // optional; channel id -> limits overriding LoopLimits there
+	LoopObserver                  LoopObserver            // optional; told when a loop runs out of budget or is stopped for looping
+	ToolCache                     ToolCacheConfig         // optional; reuse results of identical read-only tool calls within a TTL
+	Generation                    Generation              // optional; max_tokens, temperature, top_p and stop for every LLM request
+	TaskGeneration                map[string]Generation   // optional; per internal task (TaskSummary, ...) overrides of Generation
 	EscalationLimitChecker        UsageLimitChecker       // optional; pre-checks a background turn against the entity's token budget
 	OnStreamChunk                 StreamChunkCallback     // optional; when set and LLM supports streaming, final answers are streamed
 	ShowToolCalls                 string                  // "raw" = debug blocks, "friendly" = short labels, "" = hidden
+	ReportToolFailures            bool                    // append "N of M actions failed: …" to InputForDisplay when some of a turn's calls errored
 	InjectionStateStore           InjectionStateStore     // optional; persists known_tools sticky tiers across turns (load_tools promotion)
 	ToolErrorHandling             ToolErrorHandlingConfig // tool-failure protections; opt-in (zero/unset thresholds disable each protection)
 	ChannelSender                 ChannelSender           // optional; when set, maybeGenerateTitle pushes the generated title to the originating channel as a session.title frame
@@ -357,22 +413,44 @@ type Orchestrator struct {
 	memory        MemoryStoreInterface
 	sessions      SessionStoreInterface
 	guard         *Guard
+	pii           *piiRedactor  // nil unless PIIRedaction.Enabled
+	policies      *toolPolicies // nil when no ToolPolicies are configured
+	dryRun        DryRun
+	dryRunPlugins map[string]bool // set of DryRun.Plugins
+	rulesMu       sync.RWMutex    // guards rules; SetRules swaps it on config reload
 	rules         *RulesConfig
 	preparers     []ContentPreparerEntry
 	formatters    []ResponseFormatterEntry // run after final response; text-in/text-out
+	moderators    []ResponseModeratorEntry // run on the final answer before history and formatters; may block
 	guards        []ContentPreparerEntry   // subset of preparers with Guard:true; run before every LLM call
 	// preparerActions is the immutable "plugin__action" set of all
-	// preparers + guards, computed once in NewWithRules from the
-	// preparers/guards slices above (both append-once at construction).
+	// preparers + guards + moderators, computed once in NewWithRules from
+	// the slices above (all append-once at construction).
 	// Reads happen on every Run (system prompt assembly, native tool
 	// definitions, palette computation) and every _meta__load_tools
 	// call — rebuilding the map per read is wasted work that scales
 	// linearly with the LLM's appetite for meta-tool lookups.
 	preparerActions         map[string]bool
 	luaScriptPaths          map[string]string             // optional; plugin name -> path to .lua script (for "lua:name" preparers)
+	luaOpts                 lua.Options                   // sandbox limits for Lua preparers and response hooks
+	jsScriptPaths           map[string]string             // optional; script name -> path to .js script (for "js:name" preparers)
 	permissionChecker       PermissionChecker             // optional; when set, executeCall checks permission before running
 	permissionPluginName    string                        // name of the permission plugin (skip permission check when executing it)
 	runtimePromptPath       string                        // optional; if set, buildSystemPrompt appends file contents
+	promptOverrides         PromptOverrides               // per-channel / per-group preamble replace + extra instructions after the rules
+	promptLayers            PromptLayers                  // deployment layer, layer order and budgets
+	promptTemplate          *template.Template            // operator layout for the system prompt; nil = built-in order
+	toolApprovals           toolApprovalGate              // tools whose LLM calls wait in the approval queue
+	agents                  Agents                        // configured personas; empty = plain assistant
+	experiments             Experiments                   // A/B variants; empty = no experiment
+	modelAliases            map[string]string             // catalog alias → model ref for model pins
+	actorProfiles           ActorProfileStore             // per-actor preferences; nil = none
+	toolOutputBlobs         ToolOutputBlobs               // blob store for oversized tool outputs; nil Store = keep inline
+	toolExposure            ToolExposure                  // summary = plugin names in the prompt, docs via _tools__describe
+	toolScopes              []ToolScope                   // applied by resolveAllowedPlugins
+	documents               Documents                     // document index; nil Index = no _knowledge tool
+	attachments             Attachments                   // attachment store; nil Store = files go to the model as sent
+	transcriber             Transcriber                   // nil = audio is transcribed by STT preparers only
 	contextArgProviders     map[string]ContextArgProvider // name -> extract from context; used to inject args per action
 	contextMessages         int                           // send only last N messages to LLM (0 = all)
 	summarizeAfterMessages  int                           // 0 = off; after this many messages run summarization
@@ -405,21 +483,37 @@ type Orchestrator struct {
 	// already resolved it.
 	pendingPipelines   map[string]pendingPipeline
 	pipelineConfig     pipeline.PipelineConfig
-	confirmationPlugin string                 // optional; plugin for confirmation strategy
-	confirmationAction string                 // optional; action name for confirmation check
-	contextWindow      int                    // model context window in tokens; 0 = no trimming
-	maxOutputTokens    int                    // reserved output budget (max_tokens) subtracted from the window when trimming; 0 = flat 10% reserve
-	groupPluginLookup  GroupPluginLookup      // optional; nil = no group-based filtering
-	usageRecorder      UsageRecorder          // optional; nil = no usage tracking
-	pluginCallObserver PluginCallObserver     // optional; nil = no plugin call observation
-	eventSink          emit.Sink              // structured session event sink; always non-nil (NoOpSink default)
-	snapshotStore      PromptSnapshotUpserter // optional; nil = turn_start hashes are emitted but content is not persisted
-	syncActionsPlugin  string                 // optional; plugin name for action sync
-	syncActionsAction  string                 // optional; action name for action sync
-	knowledge          KnowledgeConfig        // optional; knowledge directory ingestion
-	subprocessConfig   SubprocessConfig       // optional; subprocess (sub-agent) support
-	escalationConfig   EscalationConfig       // optional; background-trigger LLM turn entrypoint (_escalate)
-	escalationLimit    UsageLimitChecker      // optional; pre-checks a background turn against the entity's token budget
+	confirmationPlugin string                  // optional; plugin for confirmation strategy
+	confirmationAction string                  // optional; action name for confirmation check
+	contextWindow      int                     // model context window in tokens; 0 = no trimming
+	maxOutputTokens    int                     // reserved output budget (max_tokens) subtracted from the window when trimming; 0 = flat 10% reserve
+	groupPluginLookup  GroupPluginLookup       // optional; nil = no group-based filtering
+	usageRecorder      UsageRecorder           // optional; nil = no usage tracking
+	pluginCallObserver PluginCallObserver      // optional; nil = no plugin call observation
+	timingObserver     TimingObserver          // optional; nil = timing only on RunResult
+	activityObserver   SessionActivityObserver // optional; nil = no turn start/finish notifications
+	workflowRecorder   WorkflowRecorder        // optional; nil = turns are not recorded as workflows
+	experimentObserver ExperimentObserver      // optional; nil = no per-variant outcomes
+	eventSink          emit.Sink               // structured session event sink; always non-nil (NoOpSink default)
+	events             *eventbus.Bus           // lifecycle event bus; nil discards
+	snapshotStore      PromptSnapshotUpserter  // optional; nil = turn_start hashes are emitted but content is not persisted
+	syncActionsPlugin  string                  // optional; plugin name for action sync
+	syncActionsAction  string                  // optional; action name for action sync
+	knowledge          KnowledgeConfig         // optional; knowledge directory ingestion
+	subprocessConfig   SubprocessConfig        // optional; subprocess (sub-agent) support
+	escalationConfig   EscalationConfig        // optional; background-trigger LLM turn entrypoint (_escalate)
+	askUser            AskUserConfig           // _ask_user; disabled by default
+	resume             ResumeConfig            // agent-loop checkpoints; nil Store = off
+	jobsConfig         JobsConfig              // async tool jobs
+	jobs               *jobTracker             // running and finished jobs; nil = jobs disabled
+	loopLimits         LoopLimits              // orchestrator-wide agent-loop budget
+	channelLoopLimits  map[string]LoopLimits   // channel id -> agent-loop budget
+	loopObserver       LoopObserver            // optional; nil = loop stops are only logged
+	toolCache          *toolCache              // read-only tool results; nil = off
+	generation         Generation              // orchestrator-wide generation settings
+	taskGeneration     map[string]Generation   // per internal task overrides
+	stopping           atomic.Bool             // set by BeginShutdown; failed turns then keep their checkpoints
+	escalationLimit    UsageLimitChecker       // optional; pre-checks a background turn against the entity's token budget
 	// escalationMuxes is a per-session in-flight guard for background
 	// escalation turns: tryLock drops a second escalation for a session
 	// already running one, so a flapping deterministic trigger can't stack
@@ -428,6 +522,7 @@ type Orchestrator struct {
 	escalationMuxes     *keyedMutex
 	onStreamChunk       StreamChunkCallback     // optional; when set, final answers stream to caller
 	showToolCalls       string                  // "raw" = debug blocks, "friendly" = short labels, "" = hidden
+	reportToolFailures  bool                    // note failed calls in InputForDisplay
 	injectionStateStore InjectionStateStore     // optional; nil = load_tools sticky promotions don't persist across turns
 	toolErrorHandling   ToolErrorHandlingConfig // tool-failure protections; opt-in (zero/unset thresholds disable each protection)
 	toolErrorTracker    *toolErrorTracker       // RFC #249 Phase 4 in-memory consecutive-error counters (per session, per tool); always allocated, gated at use-site by injectionStateStore
@@ -463,6 +558,14 @@ type Orchestrator struct {
 	// preloaded at construction; nil-safe (a nil detector simply skips the
 	// directive, e.g. in tests).
 	langDetector lingua.LanguageDetector
+	// forcedLanguage is the ISO 639-1 code every reply is pinned to
+	// (orchestrator.reply_language); "" = follow the user's language.
+	forcedLanguage string
+	// locale and channelLocales are the ISO 639-1 fallbacks used before a
+	// user's language is known (orchestrator.locale, channels.<id>.locale).
+	locale         string
+	channelLocales map[string]string
+	messages       MessageCatalog
 }
 
 // resolveAllowedPluginNames returns a JSON array of allowed plugin names for the
@@ -564,6 +667,7 @@ func defaultContextArgProviders(o *Orchestrator, custom map[string]ContextArgPro
 	builtin := map[string]ContextArgProvider{
 		contextargs.SessionID:      func(ctx context.Context, _ string) string { return actor.SessionID(ctx) },
 		contextargs.ConversationID: func(ctx context.Context, _ string) string { return actor.ConversationID(ctx) },
+		contextargs.IdempotencyKey: func(ctx context.Context, _ string) string { return idempotencyKey(ctx) },
 		// GroupID / EntityID bridge the authenticated actor scope to the
 		// injected args a plugin opts into via InjectContextArgs. Empty
 		// when the actor has no group/identity (e.g. profile-less dev);
@@ -694,12 +798,29 @@ func NewWithRules(
 		rules:                   NewRulesConfig(opts.CustomRules),
 		preparers:               preparers,
 		formatters:              opts.ResponseFormatters,
+		moderators:              opts.ResponseModerators,
 		guards:                  guards,
-		preparerActions:         preparerActionSet(preparers, guards),
+		preparerActions:         preparerActionSet(preparers, guards, opts.ResponseModerators),
 		luaScriptPaths:          opts.LuaScriptPaths,
+		luaOpts:                 opts.LuaOptions,
+		jsScriptPaths:           opts.JSScriptPaths,
 		permissionChecker:       opts.PermissionChecker,
 		permissionPluginName:    opts.PermissionPluginName,
 		runtimePromptPath:       opts.RuntimePromptPath,
+		promptOverrides:         opts.PromptOverrides,
+		promptLayers:            opts.PromptLayers,
+		promptTemplate:          opts.SystemPromptTemplate,
+		toolApprovals:           newToolApprovalGate(opts.ToolApprovals),
+		agents:                  opts.Agents,
+		experiments:             opts.Experiments,
+		modelAliases:            opts.ModelAliases,
+		actorProfiles:           opts.ActorProfiles,
+		toolOutputBlobs:         opts.ToolOutputBlobs,
+		toolExposure:            opts.ToolExposure,
+		toolScopes:              opts.ToolScopes,
+		documents:               opts.Documents,
+		attachments:             opts.Attachments,
+		transcriber:             opts.Transcriber,
 		contextMessages:         opts.ContextMessages,
 		summarizeAfterMessages:  opts.SummarizeAfterMessages,
 		maxMessagesAfterSummary: opts.MaxMessagesAfterSummary,
@@ -720,24 +841,63 @@ func NewWithRules(
 		groupPluginLookup:       opts.GroupPluginLookup,
 		usageRecorder:           opts.UsageRecorder,
 		pluginCallObserver:      opts.PluginCallObserver,
+		timingObserver:          opts.TimingObserver,
+		activityObserver:        opts.ActivityObserver,
+		workflowRecorder:        opts.WorkflowRecorder,
+		experimentObserver:      opts.ExperimentObserver,
 		eventSink:               eventSink,
+		events:                  opts.Events,
 		snapshotStore:           opts.PromptSnapshotStore,
 		syncActionsPlugin:       opts.SyncActionsPlugin,
 		syncActionsAction:       opts.SyncActionsAction,
 		knowledge:               opts.Knowledge,
 		onStreamChunk:           opts.OnStreamChunk,
 		showToolCalls:           opts.ShowToolCalls,
+		reportToolFailures:      opts.ReportToolFailures,
 		injectionStateStore:     opts.InjectionStateStore,
 		toolErrorHandling:       errCfg,
 		toolErrorTracker:        newToolErrorTracker(),
 		channelSender:           opts.ChannelSender,
 		titleMuxes:              newKeyedMutex(),
 		escalationLimit:         opts.EscalationLimitChecker,
+		askUser:                 opts.AskUser,
+		resume:                  opts.Resume,
+		jobsConfig:              opts.Jobs,
+		loopLimits:              opts.LoopLimits,
+		channelLoopLimits:       opts.ChannelLoopLimits,
+		loopObserver:            opts.LoopObserver,
+		toolCache:               newToolCache(opts.ToolCache),
+		generation:              opts.Generation,
+		taskGeneration:          opts.TaskGeneration,
 		escalationMuxes:         newKeyedMutex(),
 		langDetector:            buildReplyLanguageDetector(),
+		forcedLanguage:          configLanguageCode(opts.ReplyLanguage),
+		locale:                  configLanguageCode(opts.Locale),
+		channelLocales:          channelLocaleCodes(opts.ChannelLocales),
+		messages:                opts.Messages,
+	}
+	if opts.SecretRedaction.Enabled {
+		o.guard.secrets = newSecretRedactor(opts.SecretRedaction)
+	}
+	if opts.PIIRedaction.Enabled {
+		o.pii = newPIIRedactor(opts.PIIRedaction)
+	}
+	if len(opts.ToolPolicies) > 0 {
+		o.policies = &toolPolicies{list: opts.ToolPolicies, now: time.Now}
 	}
+	o.dryRun = opts.DryRun
+	for _, p := range opts.DryRun.Plugins {
+		if o.dryRunPlugins == nil {
+			o.dryRunPlugins = make(map[string]bool)
+		}
+		o.dryRunPlugins[p] = true
+	}
+	o.guard.applyPolicy(opts.GuardPolicy)
 	// Context arg providers need access to 'o' for allowed_plugins resolution.
 	o.contextArgProviders = defaultContextArgProviders(o, opts.ContextArgProviders)
+	if o.toolApprovals.queue != nil {
+		o.toolApprovals.queue.Handle(approval.KindToolCall, o.runApprovedToolCall)
+	}
 
 	// Register the orchestrator-owned load_tools meta-tool unconditionally
 	// — it is the core discovery mechanism that turns the system-prompt
@@ -745,6 +905,20 @@ func NewWithRules(
 	// registered action pins it into the always-present core set so the
 	// LLM always has a path to load catalog tools.
 	o.registerLoadToolsTool()
+	o.registerDescribeTool()
+
+	// Register _agent (handoff between personas) when several are configured.
+	o.registerAgentTools()
+
+	// Register _blob (paging through offloaded tool outputs) with a blob store.
+	o.registerBlobTools()
+	o.registerDocumentTools()
+	o.registerFileTools()
+	o.registerAskUserTool()
+	if opts.Jobs.Enabled {
+		o.jobs = newJobTracker()
+	}
+	o.registerJobTools()
 
 	// Register the built-in _subprocess plugin when enabled.
 	o.subprocessConfig = opts.Subprocess
@@ -811,7 +985,10 @@ type RunResult struct {
 	InputForDisplay string // optional: what we sent to the LLM (e.g. tool results), for channels that want to show it
 	ToolCalls       []ToolCall
 	Results         []ToolResult
+	CallStatuses    []CallStatus      // outcome of each tool call, in call order; set on every result Run returns that ran tools
 	Metadata        map[string]string // optional key-value pairs passed to the channel response (e.g. type=system for commands)
+	Timing          *RunTiming        // where the turn spent its time; set on every result Run returns
+	Usage           *RunUsage         // tokens and cost the turn spent on the model; set on every result Run returns
 }
 
 // InvokeStep is one step in a preparer-driven invoke (run this plugin action without LLM).
@@ -925,9 +1102,9 @@ type PreparerCorpusMetrics struct {
 	LatencyMS        int64   `json:"latency_ms,omitempty"`
 }
 
-func (o *Orchestrator) handlePreparerFailure(prep ContentPreparerEntry, details string) *RunResult {
+func (o *Orchestrator) handlePreparerFailure(ctx context.Context, prep ContentPreparerEntry, details string) *RunResult {
 	name := toolFQN(prep.Plugin, prep.Action)
-	if strings.HasPrefix(prep.Plugin, "lua:") {
+	if isScriptPlugin(prep.Plugin) {
 		name = prep.Plugin
 	}
 	slog.Warn("guard failed", "guard", name, "details", details)
@@ -935,7 +1112,7 @@ func (o *Orchestrator) handlePreparerFailure(prep ContentPreparerEntry, details
 		return nil
 	}
 	return &RunResult{
-		Response: fmt.Sprintf("Request blocked: guard %s failed.", name),
+		Response: o.coreStringFor(ctx, msgGuardBlocked, name),
 		Metadata: map[string]string{
 			"type":       "error",
 			"error_code": "guard_blocked",
@@ -943,14 +1120,16 @@ func (o *Orchestrator) handlePreparerFailure(prep ContentPreparerEntry, details
 	}
 }
 
-// runSTTPreparers transcribes audio/* files using STT-flagged preparers.
-// Each audio file is passed to every STT preparer as base64 args; the returned transcript
+// runSTTPreparers transcribes audio/* files with the built-in Transcriber,
+// when configured, and then STT-flagged preparers. A voice note sent as a
+// link is downloaded first. Each audio file is passed to every STT preparer
+// as base64 args until one succeeds; the returned transcript
 // is prepended to content and the audio file is removed from the slice.
 // Non-audio files and non-STT preparers are unaffected.
 // On error with FailOpen=true the audio file is passed through; with FailOpen=false the
 // original content and files are returned unchanged.
 func (o *Orchestrator) runSTTPreparers(ctx context.Context, content string, files []provider.MessageFile) (string, []provider.MessageFile) {
-	hasSTT := false
+	hasSTT := o.transcriber != nil
 	for _, p := range o.preparers {
 		if p.STT {
 			hasSTT = true
@@ -970,10 +1149,35 @@ func (o *Orchestrator) runSTTPreparers(ctx context.Context, content string, file
 		return content, files
 	}
 
+	addTranscript := func(transcript string) {
+		if content == "" {
+			content = transcript
+		} else {
+			content = transcript + "\n\n" + content
+		}
+	}
 	for _, af := range audioFiles {
+		if len(af.Data) == 0 && af.URL != "" {
+			data, _, err := o.fetchAttachment(ctx, af.URL)
+			if err != nil {
+				slog.WarnContext(ctx, "downloading voice message failed", "name", af.Name, "error", err)
+				remaining = append(remaining, af)
+				continue
+			}
+			af.Data = data
+		}
 		transcribed := false
+		if o.transcriber != nil {
+			transcript, err := o.transcribe(ctx, af)
+			if err == nil {
+				addTranscript(transcript)
+				transcribed = true
+			} else {
+				slog.WarnContext(ctx, "stt transcription failed", "component", "speech", "error", err)
+			}
+		}
 		for _, prep := range o.preparers {
-			if !prep.STT {
+			if transcribed || !prep.STT {
 				continue
 			}
 			transcript, err := o.runSTTPreparer(ctx, prep, af)
@@ -984,13 +1188,8 @@ func (o *Orchestrator) runSTTPreparers(ctx context.Context, content string, file
 				}
 				continue // try next STT preparer
 			}
-			if content == "" {
-				content = transcript
-			} else {
-				content = transcript + "\n\n" + content
-			}
-			transcribed = true
-			break // file handled, don't try more preparers
+			addTranscript(transcript)
+			transcribed = true // file handled, don't try more preparers
 		}
 		if !transcribed {
 			remaining = append(remaining, af) // no preparer succeeded, pass through
@@ -999,6 +1198,25 @@ func (o *Orchestrator) runSTTPreparers(ctx context.Context, content string, file
 	return content, remaining
 }
 
+// Transcriber turns speech into text (provider.OpenAITranscriber).
+type Transcriber interface {
+	Transcribe(ctx context.Context, data []byte, mimeType, name string) (string, error)
+}
+
+func (o *Orchestrator) transcribe(ctx context.Context, f provider.MessageFile) (string, error) {
+	if len(f.Data) > maxSTTFileSize {
+		return "", fmt.Errorf("audio file too large for STT (%d bytes, max %d)", len(f.Data), maxSTTFileSize)
+	}
+	transcript, err := o.transcriber.Transcribe(ctx, f.Data, f.MimeType, f.Name)
+	if err != nil {
+		return "", err
+	}
+	if transcript == "" {
+		return "", fmt.Errorf("empty transcript")
+	}
+	return transcript, nil
+}
+
 // maxSTTFileSize is the maximum audio file size accepted for STT transcription.
 // Larger files are rejected to avoid doubling peak memory during base64 encoding.
 const maxSTTFileSize = 25 << 20 // 25 MB
@@ -1058,6 +1276,28 @@ func (o *Orchestrator) runSinglePreparerWithSearch(ctx context.Context, prep Con
 	return o.runSinglePreparer(ctx, prep, content, callPrefix, allowInvoke)
 }
 
+// isScriptPlugin reports whether a preparer names an embedded script
+// ("lua:name" or "js:name") rather than a plugin action.
+func isScriptPlugin(plugin string) bool {
+	return strings.HasPrefix(plugin, "lua:") || strings.HasPrefix(plugin, "js:")
+}
+
+// runPrepareScript runs the prepare function of a "lua:" or "js:" preparer.
+func (o *Orchestrator) runPrepareScript(ctx context.Context, plugin, content string) (*lua.PrepareResult, error) {
+	if scriptName, ok := strings.CutPrefix(plugin, "js:"); ok {
+		scriptPath := o.jsScriptPaths[scriptName]
+		if scriptPath == "" {
+			return nil, fmt.Errorf("js script path not found")
+		}
+		return js.RunPrepare(ctx, scriptPath, o.luaOpts, content)
+	}
+	scriptPath := o.luaScriptPaths[strings.TrimPrefix(plugin, "lua:")]
+	if scriptPath == "" {
+		return nil, fmt.Errorf("lua script path not found")
+	}
+	return lua.RunPrepare(ctx, scriptPath, o.luaOpts, content)
+}
+
 // runSinglePreparer executes one content preparer. Errors and "fail
 // open" preparer failures both return a non-blocked outcome with
 // Content == the input content; an explicit guard block returns
@@ -1066,15 +1306,10 @@ func (o *Orchestrator) runSinglePreparerWithSearch(ctx context.Context, prep Con
 // plugin JSON (RFC #249 candidate fields included) so callers can emit
 // retrieval events without re-parsing.
 func (o *Orchestrator) runSinglePreparer(ctx context.Context, prep ContentPreparerEntry, content, callPrefix string, allowInvoke bool) (preparerOutcome, error) {
-	if strings.HasPrefix(prep.Plugin, "lua:") {
-		scriptName := strings.TrimPrefix(prep.Plugin, "lua:")
-		scriptPath := o.luaScriptPaths[scriptName]
-		if scriptPath == "" {
-			return blockedPreparerOutcome(content, o.handlePreparerFailure(prep, "Lua script path not found")), nil
-		}
-		result, err := lua.RunPrepare(scriptPath, content)
+	if isScriptPlugin(prep.Plugin) {
+		result, err := o.runPrepareScript(ctx, prep.Plugin, content)
 		if err != nil {
-			return blockedPreparerOutcome(content, o.handlePreparerFailure(prep, err.Error())), nil
+			return blockedPreparerOutcome(content, o.handlePreparerFailure(ctx, prep, err.Error())), nil
 		}
 		if !result.SendToLLM {
 			if allowInvoke && len(result.InvokeSteps) > 0 {
@@ -1087,7 +1322,7 @@ func (o *Orchestrator) runSinglePreparer(ctx context.Context, prep ContentPrepar
 			}
 			msg := result.Content
 			if msg == "" {
-				msg = "Request blocked by guard."
+				msg = o.coreStringFor(ctx, msgRequestBlocked)
 			}
 			return preparerOutcome{Blocked: &RunResult{Response: msg}}, nil
 		}
@@ -1099,7 +1334,7 @@ func (o *Orchestrator) runSinglePreparer(ctx context.Context, prep ContentPrepar
 		argKey = "text"
 	}
 	if !o.registry.HasAction(prep.Plugin, prep.Action) {
-		return blockedPreparerOutcome(content, o.handlePreparerFailure(prep, "action not found")), nil
+		return blockedPreparerOutcome(content, o.handlePreparerFailure(ctx, prep, "action not found")), nil
 	}
 	call := ToolCall{
 		ID:     fmt.Sprintf("%s-%s-%s", callPrefix, prep.Plugin, prep.Action),
@@ -1122,7 +1357,7 @@ func (o *Orchestrator) runSinglePreparer(ctx context.Context, prep ContentPrepar
 	}
 	toolResult := o.executeCall(ctx, call)
 	if toolResult.Error != "" {
-		return blockedPreparerOutcome(content, o.handlePreparerFailure(prep, toolResult.Error)), nil
+		return blockedPreparerOutcome(content, o.handlePreparerFailure(ctx, prep, toolResult.Error)), nil
 	}
 	var pr preparerResponse
 	if err := json.Unmarshal([]byte(toolResult.Content), &pr); err == nil && pr.SendToLLM != nil && !*pr.SendToLLM {
@@ -1139,7 +1374,7 @@ func (o *Orchestrator) runSinglePreparer(ctx context.Context, prep ContentPrepar
 			msg = toolResult.Content
 		}
 		if msg == "" {
-			msg = "Request blocked by guard."
+			msg = o.coreStringFor(ctx, msgRequestBlocked)
 		}
 		return preparerOutcome{Blocked: &RunResult{Response: msg}, Response: pr}, nil
 	}
@@ -1178,6 +1413,15 @@ func (o *Orchestrator) applyShowToolCalls(result *RunResult) {
 	}
 }
 
+// reportToolProgress shows the tool a turn is about to run on channels that
+// take progress updates ("Running gitlab → analyze_code…").
+func (o *Orchestrator) reportToolProgress(ctx context.Context, call ToolCall) {
+	if call.Plugin == "" {
+		return
+	}
+	pkgchannel.ReportStatus(ctx, o.coreStringFor(ctx, msgToolProgress, call.Plugin+" → "+call.Action))
+}
+
 // formatResponse runs all configured response formatters on result.Response.
 // Each formatter receives the response text and the channel's response_format.
 // Formatters are text-in/text-out (no invoke, no blocking). On error the
@@ -1198,7 +1442,7 @@ func (o *Orchestrator) formatResponse(ctx context.Context, result *RunResult) er
 			if scriptPath == "" {
 				err = fmt.Errorf("lua script path not found for %q", scriptName)
 			} else {
-				formatted, err = lua.RunFormat(scriptPath, result.Response, responseFormat)
+				formatted, err = lua.RunFormat(ctx, scriptPath, o.luaOpts, result.Response, responseFormat)
 			}
 		} else {
 			if !o.registry.HasAction(f.Plugin, f.Action) {
@@ -1318,6 +1562,10 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		return nil, err
 	}
 	defer unlock()
+	if o.activityObserver != nil {
+		o.activityObserver.TurnStarted(sessionID)
+		defer o.activityObserver.TurnFinished(sessionID)
+	}
 
 	// Request-scoped session cache: avoids redundant DB roundtrips for
 	// sessions.Get() on every agent loop iteration. The per-session lock
@@ -1329,6 +1577,21 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		return nil, fmt.Errorf("session lookup: %w", err)
 	}
 	ctx = actor.WithSessionID(ctx, sessionID)
+	ctx = o.withActorProfile(ctx)
+	// Core system replies before language detection (an expired
+	// confirmation) use the locale the user chose or the session already has.
+	if sess != nil {
+		ctx = withTurnLocale(ctx, o.coreLocale(cmp.Or(preferredLocale(ctx), sess.Metadata[MetaLocale], o.deploymentLocale(ctx))))
+		reopenSession(sessions, sessionID, sess.Metadata)
+	}
+	ctx = withSessionLayer(ctx, sess)
+	ctx = withIdempotencyScope(ctx)
+
+	// Multi-agent: pick the persona this turn runs as and record it on the
+	// session so follow-up messages stay with it.
+	ctx = o.selectAgent(ctx, sessions, sessionID, sess, userMessage)
+	// A/B experiments: the session's variant, assigned on its first turn.
+	ctx = o.selectVariant(ctx, sessions, sessionID, sess)
 
 	// Set up trace_id for this session so all logs are correlated.
 	traceID := logger.TraceIDFromSessionKey(sessionID)
@@ -1348,6 +1611,10 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	// rely on per RFC #249.
 	userMessageID := emit.EmitUserMessage(ctx, o.eventSink, userMessage)
 	ctx = emit.WithParent(ctx, userMessageID)
+	o.events.Publish(ctx, eventbus.Event{Type: eventbus.MessageReceived, SessionID: sessionID, Data: map[string]string{
+		"channel": currentChannelID(ctx),
+		"content": userMessage,
+	}})
 
 	// turn_finished closes the bracket opened by user_message on EVERY
 	// return path below — the normal agent-loop answer, the pending-
@@ -1366,6 +1633,16 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	defer func() {
 		emit.EmitTurnFinished(finishCtx, o.eventSink, turnFinishedArgs(runResult, runErr, turnStartedAt))
 	}()
+	// Registered after turn_finished so the notice is part of the reply it reports.
+	defer func() { o.announceOffline(ctx, sessions, sessionID, runResult) }()
+	defer func() {
+		if runErr == nil {
+			o.maybeRecordWorkflow(ctx, userMessage, runResult)
+		}
+	}()
+	// Registered last so every return path, and the notices above, see the
+	// user's own values instead of their placeholders.
+	defer func() { o.restorePII(sessionID, runResult) }()
 
 	// Per-session deep debug: enabled by the set_debug_mode command, which
 	// stores debug=true in session metadata. With the flag set, the slog
@@ -1378,13 +1655,35 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 
 	log := logger.FromContext(ctx)
 
-	// Phase timing: when debug is active, track wall-clock duration of each
-	// major phase so operators can see where time is spent.
-	var timing *runTiming
-	if logger.IsSessionDebug(ctx) {
-		timing = newRunTiming()
-		defer timing.log(log)
-	}
+	// Phase timing: wall-clock duration of each major phase, returned on
+	// RunResult.Timing and reported to the timing observer. The breakdown is
+	// logged only for debug sessions.
+	timing := newRunTiming()
+	ctx = withRunTiming(ctx, timing)
+	debugTiming := logger.IsSessionDebug(ctx)
+	usage := &runUsage{}
+	ctx = withRunUsage(ctx, usage)
+	// Dry run: a session in dry-run mode has its mutating tool calls
+	// simulated; the result reports how many were.
+	dryRun := &dryRunTurn{session: sess != nil && sess.Metadata[MetaDryRun] == "true"}
+	ctx = withDryRunTurn(ctx, dryRun)
+	defer func() { dryRun.mark(runResult) }()
+	defer func() { markCallStatuses(runResult) }()
+	defer func() {
+		rt := timing.snapshot()
+		ru := usage.snapshot()
+		if runResult != nil {
+			runResult.Timing = &rt
+			runResult.Usage = &ru
+		}
+		persistUsage(ctx, sessions, sessionID, ru)
+		if o.timingObserver != nil {
+			o.timingObserver.ObserveRunTiming(currentChannelID(ctx), rt)
+		}
+		if debugTiming {
+			timing.log(log, rt)
+		}
+	}()
 
 	// Snapshot session state before this Run so we can rollback on rejection.
 	var msgCountAtStart int
@@ -1393,9 +1692,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	}
 
 	// Block A: Check for pending pipeline confirmation.
-	if timing != nil {
-		timing.begin("confirmation_check")
-	}
+	timing.begin("confirmation_check")
 	// A hidden (system-injected) turn — a background-job status note delivered
 	// via /inject — is NOT a confirmation reply: its text is automated, not the
 	// user's approve/reject. Skip confirmation resolution entirely for it, so a
@@ -1431,6 +1728,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			log.Debug("pipeline executing", "pipeline_id", p.ID, "steps", len(p.Steps))
 			res, err := o.executePipeline(ctx, sessionID, p)
 			if err == nil && res != nil {
+				o.noteToolFailures(ctx, res)
 				o.applyShowToolCalls(res)
 				if fmtErr := o.formatResponse(ctx, res); fmtErr != nil {
 					return nil, fmtErr
@@ -1614,7 +1912,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		log.Info("confirmation decision with nothing pending — prompt expired",
 			"session", sessionID, "decision", actor.ConfirmationDecision(ctx))
 		return &RunResult{
-			Response: "This confirmation is no longer active. Please try your request again.",
+			Response: o.coreStringFor(ctx, msgConfirmationExpired),
 			Metadata: map[string]string{
 				"type":   "system",
 				"action": "confirmation_expired",
@@ -1622,6 +1920,18 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		}, nil
 	}
 
+	// Block A3: an open _ask_user question. A timely reply resumes the task
+	// the model paused (its progress is already in history); a late one is
+	// a new request, prefixed with a note that the old task was dropped.
+	questionAnswered := false
+	if !hidden && pendingCall == nil {
+		var notice string
+		questionAnswered, notice = o.resolvePendingQuestion(ctx, sessions, sessionID)
+		if notice != "" {
+			defer func() { prefixNotice(runResult, notice) }()
+		}
+	}
+
 	content := userMessage
 	// When a tool call was just confirmed and executed, the result is already
 	// in session history. Skip preparers, planner, and user-message addition
@@ -1629,9 +1939,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	toolCallSeeded := toolCallConfirmed
 
 	// Transcribe any audio files using STT-flagged preparers before the main preparer loop.
-	if timing != nil {
-		timing.begin("preparers")
-	}
+	timing.begin("preparers")
 	if !toolCallSeeded {
 		content, files = o.runSTTPreparers(ctx, content, files)
 	}
@@ -1648,20 +1956,43 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	// asked in, not the model default. msgCountAtStart excludes this turn's own
 	// rows (the approval reply + tool result just added above).
 	priorMessages := []provider.Message(nil)
-	if sess, _ := sessions.Get(sessionID); sess != nil && msgCountAtStart <= len(sess.Messages) {
-		priorMessages = sess.Messages[:msgCountAtStart]
+	var sessionLocale string
+	if sess, _ := sessions.Get(sessionID); sess != nil {
+		sessionLocale = sess.Metadata[MetaLocale]
+		if msgCountAtStart <= len(sess.Messages) {
+			priorMessages = sess.Messages[:msgCountAtStart]
+		}
 	}
 	// A hidden (system-injected) turn — e.g. a background-job status note pushed
 	// in via the inject path — carries no signal about the user's own language,
 	// so detecting on its text would answer the conversation in the note's
-	// language. Derive the reply language from the visible history instead.
-	var replyLangDirective string
-	if hidden { // computed once at the confirmation-skip guard above
-		replyLangDirective = o.replyLanguageDirectiveForHidden(priorMessages)
-	} else {
-		replyLangDirective = o.replyLanguageDirectiveWithHistory(content, priorMessages)
+	// language. Derive the reply language from the visible history instead
+	// (hidden is computed once at the confirmation-skip guard above).
+	replyLangDirective, detectedLocale := o.turnLanguage(content, priorMessages, hidden, cmp.Or(sessionLocale, o.deploymentLocale(ctx)), preferredLocale(ctx))
+	if detectedLocale != "" && detectedLocale != sessionLocale {
+		if err := sessions.SetMetadata(sessionID, MetaLocale, detectedLocale); err != nil {
+			log.Warn("recording session locale failed", "error", err)
+		}
+		sessionLocale = detectedLocale
 	}
 	ctx = withReplyLanguageDirective(ctx, replyLangDirective)
+	ctx = withTurnLocale(ctx, o.coreLocale(cmp.Or(preferredLocale(ctx), sessionLocale, o.deploymentLocale(ctx))))
+	// Retrieved document passages go in the system prompt, never the user
+	// turn; a hidden note or a confirmation summary has nothing to look up.
+	if !hidden && !toolCallSeeded {
+		ctx = o.withDocumentContext(ctx, content)
+	}
+	// Attachments become notes in the message (extracted text, or why it
+	// could not be read) before preparers and guards see the content.
+	if !toolCallSeeded {
+		content, files = o.ingestAttachments(ctx, sessions, sessionID, content, files)
+	}
+	// The built-in PII preparer runs ahead of the configured ones, so
+	// neither preparer plugins, the LLM nor the session history get the
+	// raw values.
+	if !toolCallSeeded && o.pii != nil {
+		content = o.pii.redact(sessionID, content)
+	}
 
 	// Run content preparers before the first LLM call (config-driven).
 	// Preparers no longer narrow the LLM's tool set — tools come from the
@@ -1755,14 +2086,12 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		}
 	}
 
-	if timing != nil {
-		timing.begin("planner")
-	}
+	timing.begin("planner")
 	// Block B: Run planner to check if this requires a multi-step pipeline.
 	// The planner cost (~3s) is always worth it: even for single-action requests,
 	// it enables server-side tool execution which saves ~20s of failed LLM rounds.
 	singleStepSeeded := toolCallSeeded
-	if o.planner != nil && !toolCallSeeded {
+	if o.planner != nil && !toolCallSeeded && !questionAnswered {
 		log.Debug("planner running", "session", sessionID, "message", content)
 		// The planner sees the full capability set — discovery is no longer
 		// RAG-narrowed; the LLM loads what it needs from the system-prompt
@@ -2045,7 +2374,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		if content == "" && len(files) == 0 {
 			log.Debug("empty content and no files, returning fallback")
 			return &RunResult{
-				Response: "I received your message but couldn't read its content. Could you try sending it as text?",
+				Response: o.coreStringFor(ctx, msgEmptyContent),
 				Metadata: map[string]string{
 					"type":       "error",
 					"error_code": "empty_content",
@@ -2085,27 +2414,60 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	result := &RunResult{}
 
 	// Resolve profile model override: strip the provider prefix if present (e.g. "anthropic/claude-3-5" -> "claude-3-5").
+	// The acting agent's pin, when set, takes precedence: the persona was
+	// configured for a specific model.
 	profileModel := ""
 	if p := profile.FromContext(ctx); p != nil && p.Model != "" {
-		if idx := strings.Index(p.Model, "/"); idx >= 0 {
-			profileModel = p.Model[idx+1:]
-		} else {
-			profileModel = p.Model
-		}
+		profileModel = o.modelPin(p.Model)
+	}
+	if ag := agentFromContext(ctx); ag != nil && ag.Model != "" {
+		profileModel = o.modelPin(ag.Model)
+	}
+	// A model the user picked for this conversation (/model) wins over
+	// both: it is the most specific choice.
+	if sess != nil && sess.Metadata[MetaModel] != "" {
+		profileModel = o.modelPin(sess.Metadata[MetaModel])
+	}
+	// A branch replayed against another model (see BranchSession) pins it
+	// on the session; that pin wins over both.
+	if sess != nil && sess.Metadata[MetaBranchModel] != "" {
+		profileModel = o.modelPin(sess.Metadata[MetaBranchModel])
+	}
+	// An experiment variant's model only replaces the routing default, so
+	// pinned sessions still take part, comparing the prompt suffix alone.
+	if v := variantFromContext(ctx); profileModel == "" && v != nil && v.Model != "" {
+		profileModel = o.modelPin(v.Model)
 	}
 
 	var totalInputTokens, totalOutputTokens, totalToolCalls int
+	var toolsUsed []string
 	var modelUsed string
 	defer func() {
 		if o.usageRecorder != nil {
 			if p := profile.FromContext(ctx); p != nil {
 				o.usageRecorder.RecordUsage(ctx, p.EntityID, p.Group,
 					p.ChannelID, sessionID, modelUsed, p.Kind, p.SystemSource,
-					totalInputTokens, totalOutputTokens, totalToolCalls)
+					totalInputTokens, totalOutputTokens, totalToolCalls, toolsUsed)
 			}
 		}
+		if t := variantTurnFromContext(ctx); t != nil && o.experimentObserver != nil {
+			o.experimentObserver.ObserveExperimentRun(t.variant.Name, ExperimentRun{
+				InputTokens:  totalInputTokens,
+				OutputTokens: totalOutputTokens,
+				ToolCalls:    totalToolCalls,
+				ToolErrors:   int(t.toolErrors.Load()),
+			})
+		}
 	}()
 
+	// Tool scopes may match on the task type, classified from the
+	// conversation the same way the router classifies it.
+	if o.scopesUseTasks() {
+		if s, _ := sessions.Get(sessionID); s != nil {
+			ctx = withTaskType(ctx, router.NewTaskClassifier().Classify(s.Messages))
+		}
+	}
+
 	// Resolve allowed plugins once per Run call and cache in ctx so that
 	// buildSystemPrompt and executeCall share the result without a second DB hit.
 	ctx = withAllowedPlugins(ctx, o.resolveAllowedPlugins(ctx))
@@ -2152,11 +2514,20 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 	var stripRetries int
 	var toolRetries int // retries when planner expected tools but LLM didn't call any
 	var transientMessages []provider.Message
-	var lastCallSig string // "plugin__action\x00arg1=val1\x00..." for loop detection
-	var repeatCount int
+	var loops loopDetector
 	agentRound := 0
-	for i := 0; i < maxAgentLoopIterations; i++ {
+	checkpoint := o.newRunCheckpointer(ctx, sessionID, userMessage)
+	defer func() { checkpoint.finish(runErr) }()
+	limits := o.loopLimitsFor(ctx)
+	loopStart := time.Now()
+	stopReason := LoopStopIterations
+	for i := 0; i < limits.MaxIterations; i++ {
+		if i > 0 && limits.MaxDuration > 0 && time.Since(loopStart) >= limits.MaxDuration {
+			stopReason = LoopStopDuration
+			break
+		}
 		agentRound = i + 1
+		checkpoint.round(agentRound)
 		sess, _ := sessions.Get(sessionID)
 
 		// Pick the cached system prompt variant based on whether tool
@@ -2189,6 +2560,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		if profileModel != "" {
 			req.Model = profileModel
 		}
+		o.generationFor(ctx, "").apply(req)
 
 		// Native tool calling: reuse cached tool definitions on every round
 		// so the LLM can chain multiple tool calls. The identical prefix
@@ -2245,7 +2617,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		// collecting the full response for tool-call parsing.
 		// A per-request callback (from context) takes priority over the global one.
 		var resp *provider.CompletionResponse
-		if timing != nil {
+		if debugTiming {
 			// Measure what we're sending to the LLM so operators can see
 			// where the 98k tokens come from.
 			var systemChars, messagesChars, toolCount int
@@ -2271,8 +2643,8 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 				"messages_chars", messagesChars,
 				"tools_count", toolCount,
 				"tools_chars", toolChars)
-			timing.begin(fmt.Sprintf("llm_round_%d", agentRound))
 		}
+		timing.begin(fmt.Sprintf("llm_round_%d", agentRound))
 		llmStart := time.Now()
 		streamCB := o.resolveStreamCallback(ctx)
 		if streamCB != nil {
@@ -2284,6 +2656,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		if err != nil {
 			return nil, fmt.Errorf("LLM completion: %w", err)
 		}
+		recordUsage(ctx, resp)
 		// Stamp the just-emitted llm_response as parent for the rest of
 		// this iteration: tool_call_extracted / tool_call_result / retry
 		// / confirmation events all form a tight subtree under the LLM
@@ -2381,7 +2754,7 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 				// Retry once asking for a plain-language answer.
 				if stripRetries >= 5 {
 					log.Debug("LLM repeatedly produced empty/unparseable response, giving up", "round", i+1)
-					result.Response = "(no response)"
+					result.Response = o.coreStringFor(ctx, msgNoResponse)
 					_ = sessions.AddMessage(sessionID, provider.Message{
 						Role:    provider.RoleAssistant,
 						Content: result.Response,
@@ -2472,38 +2845,11 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			}
 
 			result.Response = stripped
-			if len(result.Results) > 0 {
-				var parts []string
-				for i, r := range result.Results {
-					if i < len(result.ToolCalls) {
-						parts = append(parts, formatToolCallMessage(result.ToolCalls[i]))
-					}
-					if r.Error != "" {
-						parts = append(parts, "[tool_result] error: "+r.Error)
-					} else if r.Content != "" {
-						preview := r.Content
-						if len(preview) > 500 {
-							preview = preview[:500] + "..."
-						}
-						parts = append(parts, "[tool_result] "+preview)
-					}
-				}
-				result.InputForDisplay = strings.TrimSpace(strings.Join(parts, "\n"))
-			}
-			_ = sessions.AddMessage(sessionID, provider.Message{
-				Role:    provider.RoleAssistant,
-				Content: resp.Content,
-			})
-			o.applyShowToolCalls(result)
-			if timing != nil {
-				timing.begin("format")
-			}
-			if fmtErr := o.formatResponse(ctx, result); fmtErr != nil {
+			timing.begin("format")
+			if fmtErr := o.finishAnswer(ctx, sessions, sessionID, result, resp.Content); fmtErr != nil {
 				return nil, fmtErr
 			}
-			if timing != nil {
-				timing.end()
-			}
+			timing.end()
 			return result, nil
 		}
 
@@ -2518,19 +2864,22 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			continue
 		}
 
-		// Detect repeated identical tool calls — the LLM is stuck in a loop.
-		callSig := toolCallSignature(calls)
-		if callSig == lastCallSig {
-			repeatCount++
-		} else {
-			lastCallSig = callSig
-			repeatCount = 1
-		}
-		if repeatCount >= 2 {
-			log.Warn("repeated identical tool call detected, breaking loop", "round", i+1, "repeat", repeatCount)
+		// Detect a model going in circles: the same calls again, or two sets
+		// of calls in turn. The first time it is told to answer; if it keeps
+		// looping the turn ends with a note to the user.
+		if loop := loops.observe(toolCallSignature(calls)); loop != "" {
+			if loops.nudged {
+				o.observeLoopStop(ctx, loop, i+1)
+				result.Response = o.coreStringFor(ctx, msgLoopStopped, callNames(calls))
+				_ = sessions.AddMessage(sessionID, provider.Message{Role: provider.RoleAssistant, Content: result.Response})
+				timing.end()
+				return result, nil
+			}
+			log.Warn("tool call loop detected, asking the model to answer", "round", i+1, "kind", loop)
+			loops.nudged = true
 			transientMessages = []provider.Message{
 				{Role: provider.RoleAssistant, Content: resp.Content},
-				{Role: provider.RoleUser, Content: "[system] You are repeating the same tool call. Stop calling tools and answer the user based on the information you already have."},
+				{Role: provider.RoleUser, Content: loopNudge(loop)},
 			}
 			continue
 		}
@@ -2568,10 +2917,16 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			if rr, raised := o.maybeRequireConfirmation(ctx, sessions, sessionID, calls[i], userMessage); raised {
 				return rr, nil
 			}
-
-			if timing != nil {
-				timing.begin("tool_" + toolFQN(calls[i].Plugin, calls[i].Action))
+			// _ask_user: pause the loop and send the model's question; the
+			// user's reply resumes it (see resolvePendingQuestion).
+			if o.isAskUserCall(calls[i]) {
+				if rr, ok := o.suspendForQuestion(ctx, sessions, sessionID, calls[i]); ok {
+					return rr, nil
+				}
 			}
+
+			checkpoint.dispatch(calls[i])
+			timing.begin("tool_" + toolFQN(calls[i].Plugin, calls[i].Action))
 			pluginStart := time.Now()
 			toolResult := o.executeCall(ctx, calls[i])
 			// Captured before the repair block so the "plugin call" log line
@@ -2596,6 +2951,8 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			result.ToolCalls = append(result.ToolCalls, call)
 			result.Results = append(result.Results, toolResult)
 			totalToolCalls++
+			toolsUsed = append(toolsUsed, toolFQN(call.Plugin, call.Action))
+			checkpoint.finished(call)
 
 			log.Info("plugin call", "plugin", call.Plugin, "action", call.Action, "duration", pluginDuration.Round(time.Millisecond).String(), "error", toolResult.Error != "")
 			if toolResult.Error != "" {
@@ -2634,6 +2991,9 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 			if o.pluginCallObserver != nil {
 				o.pluginCallObserver.ObservePluginCall(call.Plugin, call.Action, toolResult.Error != "", perCallInput, perCallOutput)
 			}
+			if toolResult.Error != "" {
+				countVariantToolError(ctx)
+			}
 
 			if nativeToolCalls {
 				// Native tool calling: store assistant message with tool_calls
@@ -2666,7 +3026,59 @@ func (o *Orchestrator) Run(ctx context.Context, sessionID, userMessage string, f
 		}
 	}
 
-	return nil, fmt.Errorf("agent loop exceeded %d iterations", maxAgentLoopIterations)
+	o.observeLoopStop(ctx, stopReason, agentRound)
+	budgetErr := fmt.Errorf("agent loop exceeded %d iterations", limits.MaxIterations)
+	if stopReason == LoopStopDuration {
+		budgetErr = fmt.Errorf("agent loop exceeded its %s time budget after %d rounds", limits.MaxDuration, agentRound)
+	}
+	req := &provider.CompletionRequest{Model: profileModel}
+	if nativeMode {
+		// Tool calls in the history need their definitions; the prompt
+		// tells the model not to call any.
+		req.Tools = cachedTools
+	}
+	timing.begin(fmt.Sprintf("llm_round_%d", agentRound+1))
+	recovered, err := o.recoverExhaustedLoop(ctx, sessions, sessionID, sysPromptWithHint, req, result, budgetErr)
+	timing.end()
+	return recovered, err
+}
+
+// finishAnswer completes a turn whose answer is in result.Response: it
+// attaches the tool calls and results for display (noting failed calls when
+// ReportToolFailures is on), lets moderators check the answer, stores it in
+// the session (raw, internal blocks included, unless a moderator changed it)
+// and formats it for the channel.
+func (o *Orchestrator) finishAnswer(ctx context.Context, sessions SessionStoreInterface, sessionID string, result *RunResult, raw string) error {
+	if len(result.Results) > 0 {
+		var parts []string
+		for i, r := range result.Results {
+			if i < len(result.ToolCalls) {
+				parts = append(parts, formatToolCallMessage(result.ToolCalls[i]))
+			}
+			if r.Error != "" {
+				parts = append(parts, "[tool_result] error: "+r.Error)
+			} else if r.Content != "" {
+				preview := r.Content
+				if len(preview) > 500 {
+					preview = preview[:500] + "..."
+				}
+				parts = append(parts, "[tool_result] "+preview)
+			}
+		}
+		result.InputForDisplay = strings.TrimSpace(strings.Join(parts, "\n"))
+	}
+	stored := raw
+	if moderated := o.moderate(ctx, sessionID, result.Response); moderated != result.Response {
+		result.Response = moderated
+		stored = moderated
+	}
+	_ = sessions.AddMessage(sessionID, provider.Message{
+		Role:    provider.RoleAssistant,
+		Content: stored,
+	})
+	o.noteToolFailures(ctx, result)
+	o.applyShowToolCalls(result)
+	return o.formatResponse(ctx, result)
 }
 
 // resolveStreamCallback returns the streaming callback for the current request.
@@ -2728,10 +3140,7 @@ func (o *Orchestrator) buildToolDefinitions(ctx context.Context) []provider.Tool
 			properties := make(map[string]interface{})
 			var required []string
 			for _, p := range action.Parameters {
-				properties[p.Name] = map[string]interface{}{
-					"type":        "string",
-					"description": p.Description,
-				}
+				properties[p.Name] = p.schema()
 				if p.Required {
 					required = append(required, p.Name)
 				}
@@ -2865,6 +3274,15 @@ func (o *Orchestrator) retryToolCallsEnabled() bool {
 }
 
 func (o *Orchestrator) resolveStreamCallback(ctx context.Context) StreamChunkCallback {
+	// Streamed chunks reach the user before the answer is complete, so
+	// they would bypass the moderators.
+	if len(o.moderators) > 0 {
+		return nil
+	}
+	// Likewise they would show placeholders the final reply restores.
+	if o.pii != nil && o.pii.restore {
+		return nil
+	}
 	if sw := pkgchannel.StreamWriterFromContext(ctx); sw != nil {
 		return sw.OnChunk
 	}
@@ -3000,6 +3418,9 @@ func (o *Orchestrator) PendingConfirmationFrame(sessionID string) (content strin
 	// state (survives restart / failover, shared across pods) and carries the
 	// approved-intent prompt it was stored with.
 	if pendingCall, _, prompt := loadPendingToolCall(o.sessions, sessionID); pendingCall != nil {
+		if o.pii != nil {
+			prompt = o.pii.restoreText(sessionID, prompt)
+		}
 		return prompt, toolConfirmationFrameMetadata(pendingCall.ID), true
 	}
 
@@ -3011,7 +3432,11 @@ func (o *Orchestrator) PendingConfirmationFrame(sessionID string) (content strin
 	pendingPlan := o.pendingPipelines[sessionID]
 	o.pendingMu.Unlock()
 	if p := pendingPlan.plan; p != nil {
-		return p.FormatForConfirmation(), pipelineConfirmationFrameMetadata(p.ID), true
+		prompt := p.FormatForConfirmation()
+		if o.pii != nil {
+			prompt = o.pii.restoreText(sessionID, prompt)
+		}
+		return prompt, pipelineConfirmationFrameMetadata(p.ID), true
 	}
 	return "", nil, false
 }
@@ -3153,16 +3578,19 @@ func (o *Orchestrator) narrateConfirmation(ctx context.Context, recent []provide
 			fmt.Fprintf(&ub, "[%s] %s\n", m.Role, c)
 		}
 	}
-	resp, err := o.llm.Complete(ctx, &provider.CompletionRequest{
+	req := &provider.CompletionRequest{
 		Messages: []provider.Message{
 			{Role: provider.RoleSystem, Content: confirmationNarratePrompt},
 			{Role: provider.RoleUser, Content: ub.String()},
 		},
-	})
+	}
+	o.generationFor(ctx, TaskConfirmation).apply(req)
+	resp, err := o.llm.Complete(ctx, req)
 	if err != nil {
 		slog.Warn("confirmation narration failed, using fallback", "error", err)
 		return ""
 	}
+	recordUsage(ctx, resp)
 	return strings.TrimSpace(resp.Content)
 }
 
@@ -3206,6 +3634,9 @@ func (o *Orchestrator) streamComplete(ctx context.Context, req *provider.Complet
 			return nil, fmt.Errorf("stream recv: %w", err)
 		}
 		if chunk.Content != "" {
+			if buf.Len() == 0 {
+				runTimingFrom(ctx).markFirstToken()
+			}
 			buf.WriteString(chunk.Content)
 			if cb != nil {
 				// Filter out [tool_call]...[/tool_call] blocks so users
@@ -3586,6 +4017,7 @@ func (o *Orchestrator) executePipeline(ctx context.Context, sessionID string, p
 			Action: action,
 			Args:   wireArgs,
 		}
+		o.reportToolProgress(ctx, call)
 		result := o.executeCall(ctx, call)
 		if result.Error != "" {
 			log.Warn("pipeline step failed", "plugin", pluginName, "action", action, "error", result.Error)
@@ -3643,10 +4075,11 @@ func (o *Orchestrator) executePipeline(ctx context.Context, sessionID string, p
 			_ = o.sessions.AddMessage(sessionID, provider.Message{Role: provider.RoleUser, Content: o.guard.WrapContent(tr)})
 		}
 	}
-	_ = o.sessions.AddMessage(sessionID, provider.Message{Role: provider.RoleAssistant, Content: execResult.Summary})
+	summary := o.moderate(ctx, sessionID, execResult.Summary)
+	_ = o.sessions.AddMessage(sessionID, provider.Message{Role: provider.RoleAssistant, Content: summary})
 
 	return &RunResult{
-		Response:  execResult.Summary,
+		Response:  summary,
 		ToolCalls: toolCalls,
 		Results:   toolResults,
 	}, nil
@@ -3804,6 +4237,9 @@ func (o *Orchestrator) appendConversation(ctx context.Context, sess *state.Sessi
 		systemSuffix.WriteString("\n\nPrevious conversation summary: ")
 		systemSuffix.WriteString(sess.Summary)
 	}
+	// Pinned facts, plus the parent's summary and facts for a linked session
+	// (a thread spun off a channel conversation, or a manual /link).
+	systemSuffix.WriteString(o.linkedContext(sess))
 	// For weaker / OSS models, repeat the channel format hint — but only after a
 	// tool result exists, so it doesn't compete with the tool-calling
 	// instruction on the first round.
@@ -3961,31 +4397,38 @@ func trimToContextWindow(ctx context.Context, messages []provider.Message, conte
 }
 
 func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string, includeServerInstructions bool) string {
-	var sb strings.Builder
+	sec := PromptTemplateData{ctx: ctx, memory: o.memory}
+	sec.Agent = agentSection(ctx)
+	sec.Experiment = experimentSection(ctx)
+	chOverride, groupOverride := o.promptOverrides.resolve(ctx)
 	// When the provider supports native tool calling, use a preamble that
 	// omits the text-based [tool_call] format instructions. Sending both
 	// the text format and native tools confuses weaker models — they
 	// narrate instead of calling tools.
-	if o.supportsNativeTools() {
-		sb.WriteString(prompts.OrchestratorPreambleNative)
+	if custom := overridePreamble(chOverride, groupOverride); custom != "" {
+		sec.Preamble = custom
+	} else if o.supportsNativeTools() {
+		sec.Preamble = prompts.OrchestratorPreambleNative
 	} else {
-		sb.WriteString(prompts.OrchestratorPreamble)
+		sec.Preamble = prompts.OrchestratorPreamble
 	}
 
-	sb.WriteString(o.rules.BuildPromptSection())
+	o.rulesMu.RLock()
+	rules := o.rules
+	o.rulesMu.RUnlock()
+	sec.Rules = rules.BuildPromptSection()
+	sec.layers = o.layers(ctx, chOverride, groupOverride)
+	sec.Instructions = renderLayers(sec.layers)
 
 	// Always-on knowledge catalog: titles + slugs of pullable articles, so the
 	// model knows what background it can fetch via ask_knowledge. Served from
 	// cache; a stale cache triggers a non-blocking background refresh.
-	if catalog := o.knowledgeCatalogSection(); catalog != "" {
-		sb.WriteString(catalog)
-	}
+	sec.Knowledge = o.knowledgeCatalogSection()
+	sec.Documents = documentsSectionFromContext(ctx)
 
 	if o.runtimePromptPath != "" {
 		if data, err := os.ReadFile(o.runtimePromptPath); err == nil {
-			sb.WriteString("\n## Additional instructions (editable from chat)\n")
-			sb.WriteString(string(data))
-			sb.WriteString("\n\n")
+			sec.RuntimeInstructions = "\n## Additional instructions (editable from chat)\n" + string(data) + "\n\n"
 		}
 	}
 
@@ -3996,9 +4439,8 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 	// the Slack-shaped channel ids in other tool schemas and conclude they
 	// don't have a valid one on Telegram/Discord/etc.
 	if session := sessionDescriptor(ctx); session != "" {
-		sb.WriteString("## Current session\n")
-		sb.WriteString(session)
-		sb.WriteString("\nWhen a tool parameter's description says it defaults to the current channel or conversation, OMIT it — the host injects these values automatically. Do not try to invent or ask for an id.\n\n")
+		sec.Session = "## Current session\n" + session +
+			"\nWhen a tool parameter's description says it defaults to the current channel or conversation, OMIT it — the host injects these values automatically. Do not try to invent or ask for an id.\n\n"
 	}
 
 	// Don't list content-preparer or guard actions as tools; they run automatically before LLM calls.
@@ -4009,7 +4451,12 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 
 	caps := o.registry.ListCapabilities()
 
+	var sb strings.Builder
+
 	for _, cap := range caps {
+		if o.summarized(cap) {
+			continue // listed by renderToolSummary below
+		}
 		if !o.pluginAllowed(cap, allowedPlugins) {
 			slog.Debug("plugin excluded from system prompt",
 				"plugin", cap.Name,
@@ -4039,7 +4486,7 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 			continue
 		}
 
-		fmt.Fprintf(&sb, "## %s\n%s\n", cap.Name, cap.Description)
+		fmt.Fprintf(&sb, "## %s\n%s\n", cap.heading(), cap.Description)
 		// Server instructions first — domain context the LLM needs before
 		// reading tool definitions (e.g. entity relationships, counting
 		// patterns, field semantics).
@@ -4058,7 +4505,7 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 					if p.Required {
 						req = " (required)"
 					}
-					fmt.Fprintf(&sb, "  - %s: %s%s\n", p.Name, p.Description, req)
+					fmt.Fprintf(&sb, "  - %s%s: %s%s\n", p.Name, p.typeNote(), p.Description, req)
 				}
 			}
 		}
@@ -4074,32 +4521,28 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 	if o.supportsNativeTools() {
 		sb.WriteString(o.renderToolCatalog(o.promotedToolSet(ctx), allowedPlugins))
 	}
+	// Summary mode: the other plugins by name only, first page.
+	sb.WriteString(o.renderToolSummary(allowedPlugins, 1))
+	sec.Tools = sb.String()
 
 	if o.subprocessConfig.Enabled {
-		sb.WriteString(prompts.OrchestratorSubprocess)
+		sec.Subprocess = prompts.OrchestratorSubprocess
 	}
 
 	if hint := channelFormatHint(ctx); hint != "" && !skipFormatHintFromContext(ctx) {
-		sb.WriteString("## OUTPUT FORMAT\n")
-		sb.WriteString(hint)
-		sb.WriteString("\n")
+		sec.OutputFormat = "## OUTPUT FORMAT\n" + hint + "\n"
 	}
 
-	if p := profile.FromContext(ctx); p != nil && p.Name != "" {
-		sb.WriteString("## User\n")
-		fmt.Fprintf(&sb, "The user's name is %s. Address them by name where it feels natural; do not force it into every message.\n\n", p.Name)
-	}
+	sec.User = userSection(ctx)
 
 	// Reply-language directive, appended at the end of buildSystemPrompt's
 	// own output. Note it is no longer the very last thing in the system
 	// block: appendConversation folds the conversation-summary and the
 	// format/don't-repeat reminders onto messages[0] AFTER this. Empty
 	// unless detection was confident (see replyLanguageDirective).
-	if dir := replyLanguageDirectiveFromContext(ctx); dir != "" {
-		sb.WriteString(dir)
-	}
+	sec.Language = replyLanguageDirectiveFromContext(ctx)
 
-	return sb.String()
+	return o.renderSystemPrompt(sec)
 }
 
 // sessionDescriptor returns a 1-2 line string describing the caller's current
@@ -4107,15 +4550,7 @@ func (o *Orchestrator) buildSystemPrompt(ctx context.Context, userMessage string
 // empty string when neither is known (e.g. in unit tests) so the block can
 // be safely omitted rather than rendering an empty "## Current session".
 func sessionDescriptor(ctx context.Context) string {
-	channelID := ""
-	if p := profile.FromContext(ctx); p != nil && p.ChannelID != "" {
-		channelID = p.ChannelID
-	} else if a := actor.Actor(ctx); a != "" {
-		// Classic mode: actor is "channel:sender". Take the channel prefix.
-		if i := strings.IndexByte(a, ':'); i > 0 {
-			channelID = a[:i]
-		}
-	}
+	channelID := currentChannelID(ctx)
 	conversationID := actor.ConversationID(ctx)
 	if channelID == "" && conversationID == "" {
 		return ""
@@ -4200,7 +4635,7 @@ func (o *Orchestrator) runInvokeSteps(ctx context.Context, steps []InvokeStep) (
 		results = append(results, toolResult)
 		if toolResult.Error != "" {
 			return &RunResult{
-				Response:  "Invoke step failed: " + toolResult.Error,
+				Response:  o.coreStringFor(ctx, msgInvokeFailed, toolResult.Error),
 				ToolCalls: toolCalls,
 				Results:   results,
 				Metadata: map[string]string{
@@ -4293,6 +4728,7 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 		if extractedID != "" {
 			ctx = emit.WithParent(ctx, extractedID)
 		}
+		o.reportToolProgress(ctx, call)
 	}
 
 	if call.Plugin == "" {
@@ -4378,6 +4814,7 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 				"allowed_groups", capForCheck.AllowedGroups,
 				"in_allowlist", allowed.m[capForCheck.Name],
 				"allowlist_keys", mapKeys(allowed.m),
+				"tool_scopes", allowed.scope.scopes,
 			)
 			return o.emitRefusalResult(ctx, call,
 				fmt.Sprintf("plugin %q is not available for this profile", call.Plugin),
@@ -4451,6 +4888,25 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 			return ToolResult{CallID: call.ID, Error: err.Error(), EventID: invalidID, ArgsInvalid: true}
 		}
 	}
+	// Typed parameters: a value that does not fit its declared type is
+	// rejected the same way, and one that does is put in canonical form
+	// ("3.0" → "3", "Yes" → "true", "a, b" → ["a","b"]) so plugins can parse
+	// args by their declared type. Not marked ArgsInvalid: repair only
+	// renames unknown keys, it never changes values.
+	if call.FromLLM && action != nil && len(call.Args) > 0 && paramTypesKnown(action) {
+		args, err := coerceArgs(call, action)
+		if err != nil {
+			slog.Warn("BLOCKED LLM call with mistyped args", "plugin", call.Plugin, "action", call.Action, "error", err.Error())
+			invalidID := emit.EmitToolCallArgsInvalid(ctx, o.eventSink, emit.ToolCallArgsInvalidArgs{
+				CallID:          call.ID,
+				Plugin:          call.Plugin,
+				Action:          call.Action,
+				ValidationError: err.Error(),
+			})
+			return ToolResult{CallID: call.ID, Error: err.Error(), EventID: invalidID}
+		}
+		call.Args = args
+	}
 	if action != nil {
 		// Inject only declared context arg names that have a provider (e.g. session_id). Plugins never receive session content or message history.
 		if len(action.InjectContextArgs) > 0 {
@@ -4458,9 +4914,10 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 			for k, v := range call.Args {
 				args[k] = v
 			}
+			injectCtx := withInjectingCall(ctx, call)
 			for _, name := range action.InjectContextArgs {
 				if provide := o.contextArgProviders[name]; provide != nil {
-					if v := provide(ctx, name); v != "" {
+					if v := provide(injectCtx, name); v != "" {
 						args[name] = v
 					}
 				}
@@ -4472,28 +4929,92 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 			slog.Info("audit", "actor", actorID, "plugin", call.Plugin, "action", call.Action, "args", call.Args)
 		}
 	}
+	// Tool policies: checked in code after the refusal gates and context-arg
+	// injection, like the approval gate below. A policy's allow does not
+	// skip approvals.tools; the two are configured independently.
+	var policy ToolPolicy
+	if call.FromLLM && o.policies != nil {
+		policy = o.policies.decide(ctx, call)
+		if policy.Effect == PolicyDeny {
+			return o.emitRefusalResult(ctx, call, policy.refusal(call), dispatchStart)
+		}
+	}
+	// Dry run: a mutating call that would have been allowed is simulated,
+	// and is not filed for approval either, since that notifies admins.
+	if o.simulates(ctx, call, action) {
+		return o.simulateToolCall(ctx, call, dispatchStart)
+	}
+	if policy.Effect == PolicyRequireApproval {
+		if o.toolApprovals.queue == nil {
+			return o.emitRefusalResult(ctx, call, policy.refusal(call), dispatchStart)
+		}
+		return o.submitToolCallForApproval(ctx, call, dispatchStart)
+	}
+	// Approval gate: LLM calls to tools listed under approvals.tools wait for
+	// an admin. Placed after every refusal gate (a call that would be refused
+	// is never queued) and after context-arg injection, so the filed args are
+	// exactly what would have been dispatched.
+	if call.FromLLM && o.toolApprovals.requires(call) {
+		return o.submitToolCallForApproval(ctx, call, dispatchStart)
+	}
+	// The plugin acts on the user's values, not their placeholders. They are
+	// put back only here, so events, policies and approval requests keep
+	// the placeholders.
+	if o.pii != nil {
+		call.Args = o.pii.restoreArgs(actor.SessionID(ctx), call.Args)
+	}
 
-	// Pick bidi when the plugin declared SupportsCallbacks AND the
-	// underlying executor implements BidiExecutor (today: the gRPC
-	// Client at internal/plugin.Client). Otherwise fall back to the
-	// existing unary path — every existing plugin keeps working.
+	// Pick bidi when the plugin declared SupportsCallbacks or
+	// SupportsProgress AND the underlying executor implements
+	// BidiExecutor (today: the gRPC Client at internal/plugin.Client).
+	// Otherwise fall back to the existing unary path — every existing
+	// plugin keeps working.
 	var result ToolResult
-	if cap, hasCap := o.registry.GetCapability(call.Plugin); hasCap && cap.SupportsCallbacks {
-		if bidi, isBidi := exec.(BidiExecutor); isBidi {
+	execStart := time.Now()
+	// Read-only results are looked up after every gate above, so a cached
+	// answer is only ever returned to a call that would have been allowed
+	// to run.
+	cacheTTL := o.toolCache.ttl(call, action)
+	if ctx.Value(jobPollKey{}) != nil {
+		cacheTTL = 0
+	}
+	var cacheKey string
+	cached := false
+	if cacheTTL > 0 {
+		cacheKey = o.toolCache.key(ctx, call)
+		result, cached = o.toolCache.get(ctx, cacheKey)
+		result.CallID = call.ID
+	}
+	if cached {
+		slog.Debug("tool result served from cache", "plugin", call.Plugin, "action", call.Action)
+	} else if cap, hasCap := o.registry.GetCapability(call.Plugin); hasCap && (cap.SupportsCallbacks || cap.SupportsProgress) {
+		if bidi, isBidi := exec.(BidiExecutor); isBidi && cap.SupportsProgress {
+			result = o.executeWithProgress(ctx, bidi, call)
+		} else if isBidi {
 			result = o.guard.ExecuteBidiWithTimeout(ctx, bidi, call, o)
 		} else {
 			// Capability says one thing, transport another. Surface
 			// loudly so the operator notices; fall back to unary so
 			// the call still completes (without callbacks).
-			slog.Warn("plugin declares supports_callbacks but executor lacks BidiExecutor; falling back to unary",
+			slog.Warn("plugin declares supports_callbacks or supports_progress but executor lacks BidiExecutor; falling back to unary",
 				"plugin", call.Plugin)
 			result = o.guard.ExecuteWithTimeout(ctx, exec, call)
 		}
 	} else {
 		result = o.guard.ExecuteWithTimeout(ctx, exec, call)
 	}
-	result = o.guard.ValidateResult(call, result)
-	result = o.guard.Sanitize(result)
+	if !cached {
+		result = o.guard.ValidateResult(call, result)
+		if cacheTTL > 0 && result.Error == "" {
+			o.toolCache.put(ctx, cacheKey, result, cacheTTL)
+		}
+	}
+	runTimingFrom(ctx).recordTool(call, time.Since(execStart), result.Error != "")
+	if call.FromLLM {
+		result = o.trackAcceptedJob(ctx, call, result)
+	}
+	result = o.offloadToolOutput(ctx, call, result)
+	result = o.guard.SanitizeCall(ctx, call, result)
 	if call.FromLLM {
 		status := "ok"
 		respBody := result.Content
@@ -4512,6 +5033,14 @@ func (o *Orchestrator) executeCall(ctx context.Context, call ToolCall) ToolResul
 			Structured: structBody,
 			LatencyMS:  time.Since(dispatchStart).Milliseconds(),
 		})
+		o.events.Publish(ctx, eventbus.Event{Type: eventbus.ToolExecuted, SessionID: actor.SessionID(ctx), Data: map[string]string{
+			"plugin":      call.Plugin,
+			"action":      call.Action,
+			"status":      status,
+			"error":       result.Error,
+			"duration_ms": strconv.FormatInt(time.Since(dispatchStart).Milliseconds(), 10),
+			"cached":      strconv.FormatBool(cached),
+		}})
 	}
 	return result
 }
@@ -4666,6 +5195,7 @@ func (o *Orchestrator) maybeGenerateTitle(ctx context.Context, sessionID string)
 			{Role: provider.RoleUser, Content: firstUser},
 		},
 	}
+	o.generationFor(ctx, TaskTitle).apply(req)
 	start := time.Now()
 	resp, err := o.llm.Complete(titleCtx, req)
 	if err != nil {
@@ -4746,12 +5276,30 @@ func normalizeSessionTitle(raw string) string {
 }
 
 // maybeSummarizeSession runs summarization when the session has enough messages and config is set.
+func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID string) {
+	if o.summarizeAfterMessages <= 0 {
+		return
+	}
+	o.summarizeSession(ctx, sessionID, o.summarizeAfterMessages, "threshold_reached")
+}
+
+// SummarizeSession folds every message but the last
+// max_messages_after_summary into the session summary, whatever
+// summarize_after_messages says. It runs when a session completes, so the
+// summary is current before analytics read it; a session with nothing
+// beyond the kept messages is left alone.
+func (o *Orchestrator) SummarizeSession(ctx context.Context, sessionID string) {
+	o.summarizeSession(ctx, sessionID, o.maxMessagesAfterSummary+1, "session_completed")
+}
+
+// summarizeSession summarizes once the session has at least minMessages
+// messages; reason is recorded on summarization_triggered.
 // It takes the same locks as Run (via lockSessionTurn): SetSummary rewrites the
 // session's message rows (delete + reinsert), so it must hold both the in-pod
 // mutex and the cross-pod turn lease — otherwise a concurrent turn on another
 // pod could commit messages that the reinsert then drops permanently.
-func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID string) {
-	if o.summarizeAfterMessages <= 0 || o.maxMessagesAfterSummary <= 0 {
+func (o *Orchestrator) summarizeSession(ctx context.Context, sessionID string, minMessages int, reason string) {
+	if o.maxMessagesAfterSummary <= 0 {
 		return
 	}
 	unlock, err := o.lockSessionTurn(ctx, sessionID)
@@ -4767,23 +5315,27 @@ func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID stri
 	if err != nil {
 		return
 	}
-	if len(sess.Messages) < o.summarizeAfterMessages {
+	if len(sess.Messages) < minMessages {
 		return
 	}
+	o.foldIntoSummary(ctx, sessionID, sess, o.maxMessagesAfterSummary, reason)
+}
+
+// foldIntoSummary summarizes all but the last keep messages of sess into its
+// summary and reports whether the summary was stored. The caller holds the
+// session's turn lock.
+func (o *Orchestrator) foldIntoSummary(ctx context.Context, sessionID string, sess *state.Session, keep int, reason string) bool {
 	// This fires from a background goroutine started in Run with
 	// context.Background(), so actor.SessionID(ctx) would resolve to "" and
 	// session_events writes would fail validation. Wrap the session id back
 	// onto ctx here so the emit helpers can read it via the standard slot.
 	ctx = actor.WithSessionID(ctx, sessionID)
-	keep := o.maxMessagesAfterSummary
-	if keep > len(sess.Messages) {
-		keep = len(sess.Messages)
-	}
+	keep = min(keep, len(sess.Messages))
 	toSummarize := sess.Messages[:len(sess.Messages)-keep]
 	keepMessages := sess.Messages[len(sess.Messages)-keep:]
 	summTriggeredID := emit.EmitSummarizationTriggered(ctx, o.eventSink, emit.SummarizationTriggeredArgs{
 		MessageCount: len(sess.Messages),
-		Reason:       "threshold_reached",
+		Reason:       reason,
 	})
 	// Scope the provider's auto-emitted llm_request / llm_response under
 	// summarization_triggered so summarization's nested LLM call is
@@ -4820,6 +5372,7 @@ func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID stri
 		}
 		userContent = b.String()
 	}
+	sysPrompt += o.summaryLanguageDirective(ctx, sess)
 	req := &provider.CompletionRequest{
 		Model: "",
 		Messages: []provider.Message{
@@ -4827,18 +5380,22 @@ func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID stri
 			{Role: provider.RoleUser, Content: userContent},
 		},
 	}
+	o.generationFor(ctx, TaskSummary).apply(req)
 	summarizeStart := time.Now()
 	resp, err := o.llm.Complete(summCtx, req)
+	if o.timingObserver != nil {
+		o.timingObserver.ObserveSummarization(time.Since(summarizeStart))
+	}
 	if err != nil {
 		slog.Warn("session summarization failed", "error", err)
 		// No completed event on LLM failure: triggered-without-completed
 		// is the analytics signal for "summarization started but did not
 		// finish". Same pattern as other failure-mode events in this file.
-		return
+		return false
 	}
 	newSummary := strings.TrimSpace(resp.Content)
 	if newSummary == "" {
-		return
+		return false
 	}
 	if err := o.sessions.SetSummary(sessionID, newSummary, keepMessages); err != nil {
 		// Same "triggered without completed" failure signal as the LLM
@@ -4848,13 +5405,24 @@ func (o *Orchestrator) maybeSummarizeSession(ctx context.Context, sessionID stri
 		// being deleted concurrently between Get and SetSummary. Treated
 		// as a defensive return for that race, no dedicated test.
 		slog.Warn("set session summary failed", "error", err)
-		return
+		return false
 	}
 	emit.EmitSummarizationCompleted(summCtx, o.eventSink, emit.SummarizationCompletedArgs{
 		Summary:      newSummary,
 		KeptMessages: len(keepMessages),
 		LatencyMS:    time.Since(summarizeStart).Milliseconds(),
 	})
+	return true
+}
+
+// SetRules replaces the custom safety rules (orchestrator.rules in config).
+// The built-in rules are kept. Takes effect from the next system prompt
+// build, so a turn already in flight finishes under the old rules.
+func (o *Orchestrator) SetRules(customRules []string) {
+	rc := NewRulesConfig(customRules)
+	o.rulesMu.Lock()
+	o.rules = rc
+	o.rulesMu.Unlock()
 }
 
 // RunAction executes a single plugin action directly, bypassing the LLM loop.
@@ -5075,6 +5643,8 @@ type allowedPluginsKey struct{}
 type cachedAllowedPlugins struct {
 	m      map[string]bool
 	strict bool
+	agent  map[string]bool // acting agent's plugin list; nil = agent unrestricted
+	scope  pluginScope     // tool scopes for the turn's channel, group and task
 }
 
 func withAllowedPlugins(ctx context.Context, c cachedAllowedPlugins) context.Context {
@@ -5224,6 +5794,14 @@ func (o *Orchestrator) resolveAllowedPlugins(ctx context.Context) cachedAllowedP
 	if cached, ok := ctx.Value(allowedPluginsKey{}).(cachedAllowedPlugins); ok {
 		return cached
 	}
+	c := o.resolveProfilePlugins(ctx)
+	c.agent = agentPlugins(ctx)
+	c.scope = o.resolveToolScope(ctx)
+	return c
+}
+
+// resolveProfilePlugins is the profile half of resolveAllowedPlugins.
+func (o *Orchestrator) resolveProfilePlugins(ctx context.Context) cachedAllowedPlugins {
 	p := profile.FromContext(ctx)
 	if p == nil {
 		return cachedAllowedPlugins{}
@@ -5280,6 +5858,15 @@ func (o *Orchestrator) pluginAllowed(cap PluginCapability, allowed cachedAllowed
 		// otherwise has a restricted plugin allowlist.
 		return true
 	}
+	// The acting agent's plugin list narrows whatever the profile allows.
+	// Host built-ins ("_"-prefixed) are exempt, like _meta above.
+	if allowed.agent != nil && !strings.HasPrefix(cap.Name, "_") && !o.nameAllowed(cap.Name, allowed.agent) {
+		return false
+	}
+	// So do the tool scopes that apply to the turn.
+	if !strings.HasPrefix(cap.Name, "_") && !allowed.scope.admits(o, cap.Name) {
+		return false
+	}
 	if allowed.m == nil {
 		// No profile / no lookup configured — unrestricted.
 		return true
@@ -5288,15 +5875,20 @@ func (o *Orchestrator) pluginAllowed(cap PluginCapability, allowed cachedAllowed
 		// Non-strict mode: capability has no group restriction — always visible.
 		return true
 	}
-	if allowed.m[cap.Name] {
+	return o.nameAllowed(cap.Name, allowed.m)
+}
+
+// nameAllowed reports whether plugin, or one of its aliases, is in m.
+func (o *Orchestrator) nameAllowed(plugin string, m map[string]bool) bool {
+	if m[plugin] {
 		return true
 	}
 	// Check aliases: if the capability is an alias target (e.g. "mcp") and any
 	// of its aliases (e.g. "jira", "appsignal") are in the allowlist, allow it.
 	// This shouldn't normally fire because ListCapabilities already replaces
 	// alias targets with per-alias entries, but it's defense-in-depth.
-	for _, alias := range o.registry.AliasesFor(cap.Name) {
-		if allowed.m[alias] {
+	for _, alias := range o.registry.AliasesFor(plugin) {
+		if m[alias] {
 			return true
 		}
 	}
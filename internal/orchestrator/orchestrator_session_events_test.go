@@ -1746,6 +1746,42 @@ func TestOrchestrator_Summarization_BelowThreshold_NoEvents(t *testing.T) {
 	}
 }
 
+func TestOrchestrator_SummarizeSession_IgnoresThreshold(t *testing.T) {
+	// Completion summarizes whatever is beyond the kept messages, even with
+	// threshold summarization off.
+	sink := &recordingEventSink{}
+	sessions := state.NewSessionStore("")
+	sessions.Create("sess", "", "", "")
+	for i := 0; i < 3; i++ {
+		_ = sessions.AddMessage("sess", provider.Message{Role: provider.RoleUser, Content: "m"})
+	}
+	orch := NewWithRules(&fakeLLM{responses: []string{"wrap-up"}}, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{
+		EventSink:               sink,
+		MaxMessagesAfterSummary: 3,
+	})
+
+	orch.SummarizeSession(context.Background(), "sess")
+	if n := len(findPayloadsByType(t, sink.snapshot(), events.TypeSummarizationTriggered)); n != 0 {
+		t.Fatalf("summarized a session with nothing beyond the kept messages (%d events)", n)
+	}
+
+	_ = sessions.AddMessage("sess", provider.Message{Role: provider.RoleAssistant, Content: "m"})
+	orch.SummarizeSession(context.Background(), "sess")
+	trig := findPayloadsByType(t, sink.snapshot(), events.TypeSummarizationTriggered)
+	if len(trig) != 1 {
+		t.Fatalf("summarization_triggered count = %d, want 1", len(trig))
+	}
+	var tp events.SummarizationTriggeredPayload
+	_ = json.Unmarshal(trig[0], &tp)
+	if tp.Reason != "session_completed" {
+		t.Errorf("triggered.Reason = %q, want session_completed", tp.Reason)
+	}
+	sess, _ := sessions.Get("sess")
+	if sess.Summary != "wrap-up" || len(sess.Messages) != 3 {
+		t.Errorf("summary %q, %d messages kept", sess.Summary, len(sess.Messages))
+	}
+}
+
 func TestOrchestrator_Planner_NoEventsWhenPlannerDisabled(t *testing.T) {
 	// PipelineEnabled defaults to false → planner is nil → no events.
 	sink := &recordingEventSink{}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
@@ -169,6 +170,26 @@ func TestStripRetryCapAtFive(t *testing.T) {
 	}
 }
 
+type recordingActivity struct{ calls []string }
+
+func (r *recordingActivity) TurnStarted(id string)  { r.calls = append(r.calls, "start "+id) }
+func (r *recordingActivity) TurnFinished(id string) { r.calls = append(r.calls, "finish "+id) }
+
+func TestOrchestratorActivityObserver(t *testing.T) {
+	activity := &recordingActivity{}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: []string{"hi"}}, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{
+		ActivityObserver: activity,
+	})
+	if _, err := orch.Run(context.Background(), "s1", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"start s1", "finish s1"}; !slices.Equal(activity.calls, want) {
+		t.Errorf("activity = %q, want %q", activity.calls, want)
+	}
+}
+
 func TestOrchestratorDirectAnswer(t *testing.T) {
 	llm := &fakeLLM{responses: []string{"Hello! How can I help?"}}
 	parser := &fakeParser{parseFn: func(string) []ToolCall { return nil }}
@@ -314,12 +335,17 @@ func TestOrchestratorSessionNotFound(t *testing.T) {
 }
 
 func TestOrchestratorMaxIterationsExceeded(t *testing.T) {
-	llm := &fakeLLM{responses: make([]string, 25)}
+	// One response per round and none for the recovery call, so the turn
+	// still fails with the budget error.
+	llm := &fakeLLM{responses: make([]string, defaultMaxAgentLoopIterations)}
 	for i := range llm.responses {
 		llm.responses[i] = "[tool] gitlab.analyze_code"
 	}
+	// A different repo each round, so the loop detector never fires.
+	round := 0
 	parser := &fakeParser{parseFn: func(string) []ToolCall {
-		return []ToolCall{{ID: "cx", Plugin: "gitlab", Action: "analyze_code"}}
+		round++
+		return []ToolCall{{ID: "cx", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": fmt.Sprint(round)}}}
 	}}
 
 	orch, sessID := setupOrchestrator(llm, parser)
@@ -2373,6 +2399,39 @@ func TestResponseFormatterLuaMissingScript(t *testing.T) {
 	}
 }
 
+func TestContentPreparerScriptEngines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shout.lua")
+	if err := os.WriteFile(path, []byte(`function prepare(text) return string.upper(text) end`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	run := func(prep ContentPreparerEntry) (*capturingLLM, *RunResult) {
+		t.Helper()
+		llm := &capturingLLM{responses: []string{"ok"}}
+		registry := NewToolRegistry()
+		sessions := state.NewSessionStore("")
+		sessions.Create("s1", "", "", "")
+		orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, registry, state.NewMemoryStore(""), sessions,
+			OrchestratorOpts{ContentPreparers: []ContentPreparerEntry{prep}, LuaScriptPaths: map[string]string{"shout": path}})
+		res, err := orch.Run(context.Background(), "s1", "hello")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return llm, res
+	}
+
+	llm, _ := run(ContentPreparerEntry{Plugin: "lua:shout"})
+	if len(llm.requests) != 1 || !strings.Contains(llm.requests[0].Messages[len(llm.requests[0].Messages)-1].Content, "HELLO") {
+		t.Error("lua preparer output should reach the LLM")
+	}
+	// A js: script with no path is a preparer failure like a missing Lua script.
+	if llm, res := run(ContentPreparerEntry{Plugin: "js:missing"}); llm.callCount != 0 || !strings.Contains(res.Response, "js:missing") {
+		t.Errorf("fail-closed js preparer: %q", res.Response)
+	}
+	if llm, _ := run(ContentPreparerEntry{Plugin: "js:missing", FailOpen: true}); llm.callCount != 1 {
+		t.Error("fail-open js preparer should let the request through")
+	}
+}
+
 func TestShowToolCallsPrependsInputForDisplay(t *testing.T) {
 	// LLM responds with a tool call first, then a final answer.
 	llm := &fakeLLM{responses: []string{
@@ -3824,3 +3883,68 @@ func TestAppendStrippingHistoricalKC_NoUserMessages(t *testing.T) {
 		t.Errorf("non-user messages should pass through unchanged, got: %+v", out)
 	}
 }
+
+func TestExecuteCall_ReportsToolProgress(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "gitlab", Description: "GitLab", Actions: []Action{{Name: "analyze_code", Description: "Analyze"}}}, &echoExecutor{})
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg,
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+
+	var statuses []string
+	ctx := pkgchannel.WithStatus(context.Background(), func(_ context.Context, s string) { statuses = append(statuses, s) })
+	orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "gitlab", Action: "analyze_code", FromLLM: true})
+	orch.executeCall(ctx, ToolCall{ID: "c2", Plugin: "gitlab", Action: "analyze_code"}) // host call: no progress line
+	if len(statuses) != 1 || statuses[0] != "Running gitlab → analyze_code…" {
+		t.Fatalf("statuses = %q", statuses)
+	}
+
+	orch.executeCall(withTurnLocale(ctx, "de"), ToolCall{ID: "c3", Plugin: "gitlab", Action: "analyze_code", FromLLM: true})
+	if statuses[1] != "Führe gitlab → analyze_code aus…" {
+		t.Errorf("German status = %q", statuses[1])
+	}
+}
+
+func TestRunUsesSessionModel(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	llm := &capturingLLM{responses: []string{"one", "two"}}
+	orch := NewWithRules(llm, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{Model: "openai/gpt-4o-mini"})
+
+	if err := sessions.SetMetadata("web:c1", MetaModel, "anthropic/claude-opus-4"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orch.Run(ctx, "web:c1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	// The model that answered is stamped as the session's active model;
+	// that is no pin. Without one the profile pin applies again.
+	if err := sessions.SetModel("web:c1", "anthropic/claude-opus-4"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.SetMetadata("web:c1", MetaModel, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orch.Run(ctx, "web:c1", "again"); err != nil {
+		t.Fatal(err)
+	}
+	if got := []string{llm.requests[0].Model, llm.requests[1].Model}; got[0] != "claude-opus-4" || got[1] != "gpt-4o-mini" {
+		t.Errorf("request models = %q", got)
+	}
+}
+
+func TestRunResolvesModelAliases(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	llm := &capturingLLM{responses: []string{"ok"}}
+	orch := NewWithRules(llm, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{
+		ModelAliases: map[string]string{"opus": "anthropic/claude-opus-4"},
+	})
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{Model: "Opus"})
+	if _, err := orch.Run(ctx, "web:c1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if got := llm.requests[0].Model; got != "claude-opus-4" {
+		t.Errorf("request model = %q, want claude-opus-4", got)
+	}
+}
@@ -0,0 +1,240 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter types. The plugin protocol carries a parameter's type as one
+// string, so enums and array element types are spelled into it:
+// "enum:low,medium,high", "array:integer". An empty or unknown type is a
+// string.
+const (
+	ParamString  = "string"
+	ParamNumber  = "number"
+	ParamInteger = "integer"
+	ParamBoolean = "boolean"
+	ParamArray   = "array"
+)
+
+// ParameterFromWire builds a Parameter from a protocol type string.
+func ParameterFromWire(name, description, wireType string, required bool) Parameter {
+	p := Parameter{Name: name, Description: description, Required: required}
+	t := strings.TrimSpace(strings.ToLower(wireType))
+	switch {
+	case strings.HasPrefix(t, "enum:"):
+		p.Type = ParamString
+		for _, v := range strings.Split(strings.TrimSpace(wireType)[len("enum:"):], ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				p.Enum = append(p.Enum, v)
+			}
+		}
+	case t == ParamArray || strings.HasPrefix(t, "array:"):
+		p.Type = ParamArray
+		if items, ok := strings.CutPrefix(t, "array:"); ok {
+			p.Items = knownType(items) // object and other element types stay unchecked
+		}
+	default:
+		p.Type = scalarType(t)
+	}
+	return p
+}
+
+// scalarType maps a type name to a scalar parameter type, string if unknown.
+func scalarType(t string) string {
+	if k := knownType(t); k != "" {
+		return k
+	}
+	return ParamString
+}
+
+func knownType(t string) string {
+	switch t {
+	case ParamString, ParamNumber, ParamInteger, ParamBoolean:
+		return t
+	case "int":
+		return ParamInteger
+	case "float", "double":
+		return ParamNumber
+	case "bool":
+		return ParamBoolean
+	}
+	return ""
+}
+
+// schema returns the JSON Schema of the parameter for native tool calling.
+func (p Parameter) schema() map[string]interface{} {
+	s := map[string]interface{}{
+		"type":        scalarOrArray(p.Type),
+		"description": p.Description,
+	}
+	if p.Type == ParamArray {
+		items := map[string]interface{}{} // any element
+		if p.Items != "" {
+			items["type"] = scalarType(p.Items)
+		}
+		s["items"] = items
+	}
+	if len(p.Enum) > 0 {
+		s["enum"] = p.Enum
+	}
+	return s
+}
+
+// typeNote is the type shown next to a parameter in text-mode tool lists;
+// empty for plain strings so untyped tools read as before.
+func (p Parameter) typeNote() string {
+	switch {
+	case len(p.Enum) > 0:
+		return " (one of: " + strings.Join(p.Enum, ", ") + ")"
+	case p.Type == ParamArray && p.Items != "":
+		return " (JSON array of " + p.Items + ")"
+	case p.Type == ParamArray:
+		return " (JSON array)"
+	case p.Type == "" || p.Type == ParamString:
+		return ""
+	}
+	return " (" + p.Type + ")"
+}
+
+func scalarOrArray(t string) string {
+	if t == ParamArray {
+		return t
+	}
+	return scalarType(t)
+}
+
+// coerceArgs checks LLM-supplied args against the declared parameter types
+// and returns them in canonical wire form: plain decimal numbers,
+// "true"/"false", enum values in their declared spelling, and arrays as JSON.
+// Args whose parameter is an untyped string pass through untouched.
+func coerceArgs(call ToolCall, action *Action) (map[string]string, error) {
+	var out map[string]string
+	var problems []string
+	for _, p := range action.Parameters {
+		v, ok := call.Args[p.Name]
+		if !ok || (p.Type == "" || p.Type == ParamString) && len(p.Enum) == 0 {
+			continue
+		}
+		canon, err := coerceValue(p, v)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", p.Name, err))
+			continue
+		}
+		if canon != v {
+			if out == nil {
+				out = make(map[string]string, len(call.Args))
+				for k, a := range call.Args {
+					out[k] = a
+				}
+			}
+			out[p.Name] = canon
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("invalid argument(s) for %s: %s",
+			toolFQN(call.Plugin, call.Action), strings.Join(problems, "; "))
+	}
+	if out == nil {
+		return call.Args, nil
+	}
+	return out, nil
+}
+
+func coerceValue(p Parameter, v string) (string, error) {
+	if len(p.Enum) > 0 {
+		for _, e := range p.Enum {
+			if strings.EqualFold(strings.TrimSpace(v), e) {
+				return e, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not one of %s", v, strings.Join(p.Enum, ", "))
+	}
+	if p.Type != ParamArray {
+		return coerceScalar(p.Type, v)
+	}
+	var items []interface{}
+	if err := json.Unmarshal([]byte(v), &items); err != nil {
+		// Models often send "a, b" for a list of strings or numbers.
+		if strings.HasPrefix(strings.TrimSpace(v), "[") {
+			return "", fmt.Errorf("not a JSON array")
+		}
+		items = nil
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				items = append(items, s)
+			}
+		}
+	}
+	if p.Items == "" {
+		b, err := json.Marshal(items)
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	elem := scalarType(p.Items)
+	typed := make([]interface{}, len(items))
+	for i, it := range items {
+		s, ok := it.(string)
+		if !ok {
+			b, _ := json.Marshal(it)
+			s = string(b)
+		}
+		c, err := coerceScalar(elem, s)
+		if err != nil {
+			return "", fmt.Errorf("item %d: %v", i+1, err)
+		}
+		switch elem {
+		case ParamString:
+			typed[i] = c
+		default:
+			typed[i] = json.RawMessage(c)
+		}
+	}
+	b, err := json.Marshal(typed)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func coerceScalar(t, v string) (string, error) {
+	s := strings.TrimSpace(v)
+	switch t {
+	case ParamNumber:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a number", v)
+		}
+		return formatFloat(f), nil
+	case ParamInteger:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil || f != float64(int64(f)) {
+			return "", fmt.Errorf("%q is not an integer", v)
+		}
+		return strconv.FormatInt(int64(f), 10), nil
+	case ParamBoolean:
+		switch strings.ToLower(s) {
+		case "true", "yes", "1":
+			return "true", nil
+		case "false", "no", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%q is not a boolean", v)
+	}
+	return v, nil
+}
+
+// paramTypesKnown reports whether any parameter of action has a type other
+// than plain string, i.e. whether coerceArgs has anything to do.
+func paramTypesKnown(action *Action) bool {
+	return slices.ContainsFunc(action.Parameters, func(p Parameter) bool {
+		return p.Type != "" && p.Type != ParamString || len(p.Enum) > 0
+	})
+}
@@ -0,0 +1,146 @@
+package orchestrator
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestParameterFromWire(t *testing.T) {
+	tests := map[string]Parameter{
+		"":                   {Type: ParamString},
+		"INTEGER":            {Type: ParamInteger},
+		"bool":               {Type: ParamBoolean},
+		"object":             {Type: ParamString},
+		"enum:Low, Medium,,": {Type: ParamString, Enum: []string{"Low", "Medium"}},
+		"array":              {Type: ParamArray},
+		"array:number":       {Type: ParamArray, Items: ParamNumber},
+		"array:object":       {Type: ParamArray},
+	}
+	for wire, want := range tests {
+		got := ParameterFromWire("p", "d", wire, true)
+		want.Name, want.Description, want.Required = "p", "d", true
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ParameterFromWire(%q) = %+v; want %+v", wire, got, want)
+		}
+	}
+}
+
+func TestCoerceArgs(t *testing.T) {
+	action := &Action{Parameters: []Parameter{
+		{Name: "n", Type: ParamNumber},
+		{Name: "i", Type: ParamInteger},
+		{Name: "b", Type: ParamBoolean},
+		{Name: "level", Type: ParamString, Enum: []string{"low", "high"}},
+		{Name: "ids", Type: ParamArray, Items: ParamInteger},
+		{Name: "tags", Type: ParamArray},
+		{Name: "s"},
+	}}
+	call := ToolCall{Plugin: "p", Action: "a", Args: map[string]string{
+		"n": " 2.50 ", "i": "3.0", "b": "Yes", "level": "HIGH", "ids": "1, 2", "tags": `["a",{"k":1}]`, "s": " keep ",
+	}}
+	got, err := coerceArgs(call, action)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"n": "2.5", "i": "3", "b": "true", "level": "high", "ids": "[1,2]", "tags": `["a",{"k":1}]`, "s": " keep "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("coerceArgs = %v; want %v", got, want)
+	}
+	if call.Args["i"] != "3.0" {
+		t.Error("coerceArgs must not modify the call's args in place")
+	}
+
+	call.Args = map[string]string{"i": "1.5", "level": "medium", "ids": "[1, \"x\"]"}
+	_, err = coerceArgs(call, action)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"i: \"1.5\" is not an integer", "level: \"medium\" is not one of low, high", "ids: item 2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q lacks %q", err, want)
+		}
+	}
+}
+
+func TestBuildToolDefinitions_TypedParameters(t *testing.T) {
+	registry := NewToolRegistry()
+	_ = registry.Register(PluginCapability{
+		Name: "tickets", Description: "Tickets",
+		Actions: []Action{{
+			Name: "list", Description: "List tickets", AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "limit", Type: ParamInteger},
+				{Name: "status", Type: ParamString, Enum: []string{"open", "closed"}},
+				{Name: "labels", Type: ParamArray, Items: ParamString},
+				{Name: "query"},
+			},
+		}},
+	}, &fixedResultExecutor{content: "ok"})
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(nativeToolsLLM{&fakeLLM{}}, &fakeParser{}, registry,
+		state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	var props map[string]interface{}
+	for _, td := range orch.buildToolDefinitions(actor.WithSessionID(context.Background(), "s1")) {
+		if td.Name == toolFQN("tickets", "list") {
+			props = td.Parameters["properties"].(map[string]interface{})
+		}
+	}
+	if props == nil {
+		t.Fatal("tickets__list missing from the tools array")
+	}
+	schema := func(name string) map[string]interface{} { return props[name].(map[string]interface{}) }
+	if schema("limit")["type"] != "integer" || schema("query")["type"] != "string" {
+		t.Errorf("limit/query schemas = %v / %v", schema("limit"), schema("query"))
+	}
+	if enum, _ := schema("status")["enum"].([]string); len(enum) != 2 {
+		t.Errorf("status schema = %v", schema("status"))
+	}
+	if items, _ := schema("labels")["items"].(map[string]interface{}); schema("labels")["type"] != "array" || items["type"] != "string" {
+		t.Errorf("labels schema = %v", schema("labels"))
+	}
+}
+
+func TestTypedArgsCheckedBeforeDispatch(t *testing.T) {
+	var got []map[string]string
+	exec := &capturingExecutor{fn: func(c ToolCall) ToolResult {
+		got = append(got, c.Args)
+		return ToolResult{CallID: c.ID, Content: "ok"}
+	}}
+	registry := NewToolRegistry()
+	_ = registry.Register(PluginCapability{
+		Name: "tools", Description: "Tools",
+		Actions: []Action{{Name: "run", Description: "Run it", Parameters: []Parameter{
+			{Name: "count", Type: ParamInteger, Required: true},
+		}}},
+	}, exec)
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	callNum := 0
+	parser := &fakeParser{parseFn: func(string) []ToolCall {
+		callNum++
+		switch callNum {
+		case 1:
+			return []ToolCall{{ID: "c1", Plugin: "tools", Action: "run", Args: map[string]string{"count": "three"}}}
+		case 2:
+			return []ToolCall{{ID: "c2", Plugin: "tools", Action: "run", Args: map[string]string{"count": "3.0"}}}
+		}
+		return nil
+	}}
+	orch := New(&fakeLLM{responses: []string{"[tool]", "[tool]", "done"}}, parser, registry, state.NewMemoryStore(""), sessions)
+	result, err := orch.Run(context.Background(), "s1", "go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Results) < 2 || !strings.Contains(result.Results[0].Error, `"three" is not an integer`) || result.Results[0].ArgsInvalid {
+		t.Fatalf("results = %+v; want the mistyped call rejected (without ArgsInvalid)", result.Results)
+	}
+	if len(got) != 1 || got[0]["count"] != "3" {
+		t.Errorf("dispatched args = %v; want one call with count=3", got)
+	}
+}
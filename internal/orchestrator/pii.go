@@ -0,0 +1,285 @@
+package orchestrator
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PII kinds the built-in redactor detects.
+const (
+	PIIEmail      = "email"
+	PIIPhone      = "phone"
+	PIICreditCard = "credit_card"
+	PIINationalID = "national_id"
+)
+
+// PIIKinds lists every kind PIIRedaction.Kinds accepts.
+var PIIKinds = []string{PIIEmail, PIIPhone, PIICreditCard, PIINationalID}
+
+// PIIRedaction configures the built-in PII preparer. When Enabled, email
+// addresses, phone numbers, payment card numbers and national ids (US SSN,
+// UK NINO) in user input are replaced with placeholders such as "[EMAIL_1]"
+// before the content preparers, the LLM or the session history see them.
+type PIIRedaction struct {
+	Enabled bool
+	Kinds   []string // subset of PIIKinds; empty = all
+	// Restore puts the values back into the final reply and into the
+	// arguments of the tool calls the session dispatches. Only placeholders
+	// this session's own input produced are restored; card numbers reach
+	// tools whole but show up in replies masked to their last four digits.
+	Restore bool
+}
+
+// piiPattern is one detector; valid, when set, rejects look-alikes.
+type piiPattern struct {
+	kind  string
+	label string // placeholder prefix
+	re    *regexp.Regexp
+	valid func(match string) bool
+}
+
+// Order matters: emails go first so their digits are not read as phone
+// numbers, cards and national ids before the looser phone pattern.
+var piiPatterns = []piiPattern{
+	{kind: PIIEmail, label: "EMAIL", re: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{kind: PIICreditCard, label: "CARD", re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), valid: luhnValid},
+	{kind: PIINationalID, label: "NATIONAL_ID", re: regexp.MustCompile(`\b(?:\d{3}-\d{2}-\d{4}|[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D])\b`), valid: nationalIDValid},
+	{kind: PIIPhone, label: "PHONE", re: regexp.MustCompile(`(?:\+|\b)\d[\d ().-]{6,}\d\b`), valid: phoneValid},
+}
+
+// piiPlaceholderRe finds placeholders in a reply.
+var piiPlaceholderRe = regexp.MustCompile(`\[(EMAIL|CARD|NATIONAL_ID|PHONE)_(\d+)\]`)
+
+// luhnValid reports whether the digits of s pass the Luhn checksum, which
+// every payment card number does and most other digit runs do not.
+func luhnValid(s string) bool {
+	d := digitsOf(s)
+	if len(d) < 13 || len(d) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range len(d) {
+		n := int(d[len(d)-1-i] - '0')
+		if i%2 == 1 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// nationalIDValid rejects SSNs from ranges the SSA never issues.
+func nationalIDValid(s string) bool {
+	parts := strings.Split(s, "-")
+	if len(parts) != 3 {
+		return true // NINO; its letters already constrain it
+	}
+	return parts[0] != "000" && parts[0] != "666" && parts[0][0] != '9' && parts[1] != "00" && parts[2] != "0000"
+}
+
+// notPhoneRe matches digit groups that only look like phone numbers: an ISO
+// date (possibly followed by a time) and an IPv4 address.
+var notPhoneRe = regexp.MustCompile(`^(?:\d{4}-\d{2}-\d{2}|\d{1,3}(?:\.\d{1,3}){3}$)`)
+
+// phoneValid accepts 8 to 15 digits written like a phone number: with a
+// leading "+", or with separators and at least ten digits. Bare digit runs
+// (order numbers, Unix timestamps), dates and IP addresses are left alone.
+func phoneValid(s string) bool {
+	n := len(digitsOf(s))
+	if n < 8 || n > 15 || notPhoneRe.MatchString(s) {
+		return false
+	}
+	if strings.HasPrefix(s, "+") {
+		return true
+	}
+	return n >= 10 && strings.ContainsAny(s, " ().-")
+}
+
+func digitsOf(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// piiVaultTTL is how long a session's placeholders stay restorable after
+// its last turn. The vault lives in memory only: the raw values never reach
+// the state database, and after a restart old placeholders stay as they are.
+const piiVaultTTL = 24 * time.Hour
+
+// piiSession holds one session's placeholders in both directions.
+type piiSession struct {
+	byValue       map[string]string // kind + normalized value -> placeholder
+	byPlaceholder map[string]string // placeholder -> value as the user wrote it
+	next          map[string]int    // label -> last number used
+	used          time.Time
+}
+
+// piiRedactor replaces PII in user input with per-session placeholders and
+// restores them in replies.
+type piiRedactor struct {
+	patterns []piiPattern
+	restore  bool
+
+	mu       sync.Mutex
+	sessions map[string]*piiSession
+	now      func() time.Time
+}
+
+func newPIIRedactor(cfg PIIRedaction) *piiRedactor {
+	r := &piiRedactor{restore: cfg.Restore, sessions: make(map[string]*piiSession), now: time.Now}
+	for _, p := range piiPatterns {
+		if len(cfg.Kinds) == 0 || slices.Contains(cfg.Kinds, p.kind) {
+			r.patterns = append(r.patterns, p)
+		}
+	}
+	return r
+}
+
+// redact returns content with every detected value replaced by its
+// placeholder. The same value gets the same placeholder for the whole
+// session, so the model can tell two addresses apart across turns.
+func (r *piiRedactor) redact(sessionID, content string) string {
+	if content == "" || len(r.patterns) == 0 {
+		return content
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess := r.session(sessionID)
+	found := map[string]int{}
+	for _, p := range r.patterns {
+		content = p.re.ReplaceAllStringFunc(content, func(m string) string {
+			if p.valid != nil && !p.valid(m) {
+				return m
+			}
+			found[p.kind]++
+			return sess.placeholder(p, m)
+		})
+	}
+	if len(found) > 0 {
+		slog.Debug("pii redacted from user input", "session_id", sessionID, "found", found)
+	}
+	return content
+}
+
+// restoreText puts this session's values back in place of their
+// placeholders, with card numbers masked to their last four digits, for
+// text shown to the user. Unknown placeholders (from another session, before
+// a restart, or made up by the model) are left as they are.
+func (r *piiRedactor) restoreText(sessionID, text string) string {
+	return r.restoreValues(sessionID, text, true)
+}
+
+// restoreArgs returns args with this session's values in place of their
+// placeholders; args is not modified. Unlike restoreText, card numbers come
+// back whole: the plugin acts on the user's values, not on their display.
+func (r *piiRedactor) restoreArgs(sessionID string, args map[string]string) map[string]string {
+	if !r.restore || len(args) == 0 {
+		return args
+	}
+	out := make(map[string]string, len(args))
+	for k, v := range args {
+		out[k] = r.restoreValues(sessionID, v, false)
+	}
+	return out
+}
+
+// restoreValues replaces the placeholders in text; maskCards shows only the
+// last four digits of a card number.
+func (r *piiRedactor) restoreValues(sessionID, text string, maskCards bool) string {
+	if !r.restore || !strings.Contains(text, "[") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sess := r.sessions[sessionID]
+	if sess == nil {
+		return text
+	}
+	return piiPlaceholderRe.ReplaceAllStringFunc(text, func(ph string) string {
+		v, ok := sess.byPlaceholder[ph]
+		if !ok {
+			return ph
+		}
+		if maskCards && strings.HasPrefix(ph, "[CARD_") {
+			d := digitsOf(v)
+			return "•••• " + d[len(d)-4:]
+		}
+		return v
+	})
+}
+
+// session returns the vault entry for id, creating it and dropping
+// expired ones. Callers hold r.mu.
+func (r *piiRedactor) session(id string) *piiSession {
+	now := r.now()
+	sess := r.sessions[id]
+	if sess == nil {
+		for k, s := range r.sessions {
+			if now.Sub(s.used) > piiVaultTTL {
+				delete(r.sessions, k)
+			}
+		}
+		sess = &piiSession{byValue: map[string]string{}, byPlaceholder: map[string]string{}, next: map[string]int{}}
+		r.sessions[id] = sess
+	}
+	sess.used = now
+	return sess
+}
+
+func (s *piiSession) placeholder(p piiPattern, value string) string {
+	key := p.kind + ":" + normalizePII(p.kind, value)
+	if ph, ok := s.byValue[key]; ok {
+		return ph
+	}
+	s.next[p.label]++
+	ph := "[" + p.label + "_" + strconv.Itoa(s.next[p.label]) + "]"
+	s.byValue[key] = ph
+	s.byPlaceholder[ph] = value
+	return ph
+}
+
+// normalizePII makes differently written forms of one value share a
+// placeholder: "+49 30 1234567" and "+49-30-1234567", or a mixed-case email.
+func normalizePII(kind, v string) string {
+	switch kind {
+	case PIIEmail:
+		return strings.ToLower(v)
+	case PIINationalID:
+		return strings.ToUpper(strings.ReplaceAll(v, " ", ""))
+	default:
+		if strings.HasPrefix(v, "+") {
+			return "+" + digitsOf(v)
+		}
+		return digitsOf(v)
+	}
+}
+
+// restorePII puts the user's values back into a finished turn's reply.
+func (o *Orchestrator) restorePII(sessionID string, result *RunResult) {
+	if o.pii == nil || result == nil {
+		return
+	}
+	result.Response = o.pii.restoreText(sessionID, result.Response)
+}
+
+// ValidatePIIKinds reports the first kind PIIRedaction does not know.
+func ValidatePIIKinds(kinds []string) error {
+	for _, k := range kinds {
+		if !slices.Contains(PIIKinds, k) {
+			return fmt.Errorf("unknown kind %q (want one of %s)", k, strings.Join(PIIKinds, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestPIIRedactDetects(t *testing.T) {
+	tests := []struct {
+		name, input, leak, placeholder string
+	}{
+		{"email", "write to Jane.Doe+work@example.co.uk please", "Jane.Doe", "[EMAIL_1]"},
+		{"international phone", "call me at +49 30 1234567", "1234567", "[PHONE_1]"},
+		{"us phone", "my number is (555) 123-4567", "123-4567", "[PHONE_1]"},
+		{"visa", "card 4111 1111 1111 1111 exp 12/29", "4111", "[CARD_1]"},
+		{"amex", "use 378282246310005", "378282246310005", "[CARD_1]"},
+		{"ssn", "SSN 123-45-6789", "6789", "[NATIONAL_ID_1]"},
+		{"nino", "NI number AB 12 34 56 C", "12 34 56", "[NATIONAL_ID_1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPIIRedactor(PIIRedaction{Enabled: true}).redact("s1", tt.input)
+			if strings.Contains(got, tt.leak) || !strings.Contains(got, tt.placeholder) {
+				t.Errorf("redact(%q) = %q, want %s", tt.input, got, tt.placeholder)
+			}
+		})
+	}
+}
+
+func TestPIIRedactKeepsLookAlikes(t *testing.T) {
+	r := newPIIRedactor(PIIRedaction{Enabled: true})
+	for _, in := range []string{
+		"order 1234567890123456 shipped", // fails the Luhn check
+		"timestamp 1760486400",           // bare digit run
+		"meet on 2026-10-15 10:30",       // date and time
+		"the host is at 192.168.100.200", // IPv4
+		"SSN-like 000-12-3456 and 666-12-3456",
+		"version 1.24.3, build 20261015",
+	} {
+		if got := r.redact("s1", in); got != in {
+			t.Errorf("redact(%q) = %q; want unchanged", in, got)
+		}
+	}
+}
+
+func TestPIIPlaceholdersPerSession(t *testing.T) {
+	r := newPIIRedactor(PIIRedaction{Enabled: true, Restore: true})
+	got := r.redact("s1", "a@example.com, b@example.com and A@Example.com")
+	if got != "[EMAIL_1], [EMAIL_2] and [EMAIL_1]" {
+		t.Errorf("first turn = %q", got)
+	}
+	// A later turn reuses the session's numbering.
+	if got := r.redact("s1", "also b@example.com and c@example.com"); got != "also [EMAIL_2] and [EMAIL_3]" {
+		t.Errorf("second turn = %q", got)
+	}
+
+	reply := "Sent to [EMAIL_2], not [EMAIL_9]."
+	if got := r.restoreText("s1", reply); got != "Sent to b@example.com, not [EMAIL_9]." {
+		t.Errorf("restore = %q", got)
+	}
+	// Another session's placeholders are never filled in.
+	if got := r.restoreText("s2", reply); got != reply {
+		t.Errorf("restore in another session = %q", got)
+	}
+
+	r.redact("s1", "card 4111-1111-1111-1111")
+	if got := r.restoreText("s1", "Charged [CARD_1]."); got != "Charged •••• 1111." {
+		t.Errorf("card restore = %q", got)
+	}
+
+	off := newPIIRedactor(PIIRedaction{Enabled: true})
+	off.redact("s1", "a@example.com")
+	if got := off.restoreText("s1", "[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("restore off = %q", got)
+	}
+}
+
+func TestPIIKindsAndExpiry(t *testing.T) {
+	r := newPIIRedactor(PIIRedaction{Enabled: true, Kinds: []string{PIIEmail}, Restore: true})
+	if got := r.redact("s1", "a@example.com +49 30 1234567"); got != "[EMAIL_1] +49 30 1234567" {
+		t.Errorf("email only = %q", got)
+	}
+	if err := ValidatePIIKinds([]string{PIIPhone, "passport"}); err == nil {
+		t.Error("unknown kind accepted")
+	}
+
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	r.redact("old", "x@example.com")
+	now = now.Add(piiVaultTTL + time.Minute)
+	r.redact("new", "y@example.com")
+	if got := r.restoreText("old", "[EMAIL_1]"); got != "[EMAIL_1]" {
+		t.Errorf("expired session restored: %q", got)
+	}
+}
+
+func TestPIIRedactionInRun(t *testing.T) {
+	llm := &capturingLLM{responses: []string{"I will email [EMAIL_1] and call [PHONE_1]."}}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{PIIRedaction: PIIRedaction{Enabled: true, Restore: true}})
+
+	res, err := orch.Run(context.Background(), "s1", "Contact jane@example.com or +1 555 123 4567")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != "I will email jane@example.com and call +1 555 123 4567." {
+		t.Errorf("response = %q", res.Response)
+	}
+	for _, m := range llm.requests[0].Messages {
+		if strings.Contains(m.Content, "jane@example.com") || strings.Contains(m.Content, "555 123") {
+			t.Fatalf("the LLM saw raw PII: %q", m.Content)
+		}
+	}
+	sess, _ := sessions.Get("s1")
+	for _, m := range sess.Messages {
+		if strings.Contains(m.Content, "jane@example.com") {
+			t.Errorf("history kept raw PII: %q", m.Content)
+		}
+	}
+}
+
+func TestPIIRestoredInToolArgs(t *testing.T) {
+	exec := &recordingExecutor{}
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "tickets", Description: "Tickets", Actions: []Action{{Name: "open", Parameters: []Parameter{{Name: "reporter"}, {Name: "card"}, {Name: "other"}}}}}, exec)
+	llm := &capturingLLM{responses: []string{"call", "Opened a ticket for [EMAIL_1]."}}
+	parser := &fakeParser{parseFn: func(resp string) []ToolCall {
+		if resp != "call" {
+			return nil
+		}
+		return []ToolCall{{ID: "c1", Plugin: "tickets", Action: "open", Args: map[string]string{"reporter": "[EMAIL_1]", "card": "[CARD_1]", "other": "[EMAIL_7]"}}}
+	}}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(llm, parser, reg, state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{PIIRedaction: PIIRedaction{Enabled: true, Restore: true}})
+
+	res, err := orch.Run(context.Background(), "s1", "open a ticket for bob@x.com, card 4111 1111 1111 1111")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := exec.snapshot()
+	if len(calls) != 1 {
+		t.Fatalf("calls = %+v", calls)
+	}
+	want := map[string]string{"reporter": "bob@x.com", "card": "4111 1111 1111 1111", "other": "[EMAIL_7]"}
+	for k, v := range want {
+		if calls[0].Args[k] != v {
+			t.Errorf("arg %s = %q, want %q", k, calls[0].Args[k], v)
+		}
+	}
+	if res.ToolCalls[0].Args["reporter"] != "[EMAIL_1]" {
+		t.Errorf("the turn's record of the call = %+v; it should keep the placeholder", res.ToolCalls[0].Args)
+	}
+}
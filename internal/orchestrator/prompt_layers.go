@@ -0,0 +1,205 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// System prompt layers. The core layer — preamble and safety rules — always
+// comes first and is never cut; the others follow in PromptLayers.Order.
+const (
+	LayerDeployment = "deployment" // deployment-wide persona (orchestrator.prompt_layers.deployment)
+	LayerChannel    = "channel"    // channels.<name>.system_prompt.append
+	LayerGroup      = "group"      // orchestrator.group_system_prompts.<group>.append
+	LayerSession    = "session"    // additions an admin made to one session with /system
+)
+
+// DefaultLayerOrder is the order layers render in when PromptLayers.Order is
+// empty: broadest audience first, so narrower layers can refine it.
+var DefaultLayerOrder = []string{LayerDeployment, LayerChannel, LayerGroup, LayerSession}
+
+// MetaSystemPrompt is the session metadata key holding the session layer.
+const MetaSystemPrompt = "system_prompt"
+
+// DefaultSessionLayerBytes bounds the session layer when no budget is set
+// for it, since chat users rather than operators write it.
+const DefaultSessionLayerBytes = 4000
+
+var layerHeadings = map[string]string{
+	LayerDeployment: "## Deployment instructions\n",
+	LayerChannel:    "## Channel instructions\n",
+	LayerGroup:      "## Audience instructions\n",
+	LayerSession:    "## Session instructions (set by an admin)\n",
+}
+
+// PromptLayers configures the layered part of the system prompt.
+type PromptLayers struct {
+	Deployment  string         // deployment layer text
+	Order       []string       // layer names, first = highest priority; missing names follow in DefaultLayerOrder
+	Budgets     map[string]int // per-layer cap in bytes; <= 0 = unlimited (session: DefaultSessionLayerBytes)
+	TotalBudget int            // cap for all layers together; layers at the end of Order are cut first. <= 0 = unlimited
+}
+
+// Validate checks the layer names in Order and Budgets.
+func (p PromptLayers) Validate() error {
+	seen := map[string]bool{}
+	for _, name := range p.Order {
+		if !slices.Contains(DefaultLayerOrder, name) {
+			return fmt.Errorf("unknown layer %q (want one of %s)", name, strings.Join(DefaultLayerOrder, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("layer %q listed twice in order", name)
+		}
+		seen[name] = true
+	}
+	for name := range p.Budgets {
+		if !slices.Contains(DefaultLayerOrder, name) {
+			return fmt.Errorf("budget for unknown layer %q", name)
+		}
+	}
+	return nil
+}
+
+// order returns every layer name, configured ones first.
+func (p PromptLayers) order() []string {
+	out := slices.Clone(p.Order)
+	for _, name := range DefaultLayerOrder {
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// budget is the byte cap of one layer, 0 = unlimited.
+func (p PromptLayers) budget(name string) int {
+	if b := p.Budgets[name]; b > 0 {
+		return b
+	}
+	if name == LayerSession {
+		return DefaultSessionLayerBytes
+	}
+	return 0
+}
+
+// promptLayer is one rendered layer: its heading and text.
+type promptLayer struct {
+	name string
+	text string
+}
+
+// sessionLayerKey carries the session layer from Run to buildSystemPrompt.
+type sessionLayerKey struct{}
+
+func withSessionLayer(ctx context.Context, sess *state.Session) context.Context {
+	if text := SessionSystemPrompt(sess); text != "" {
+		return context.WithValue(ctx, sessionLayerKey{}, text)
+	}
+	return ctx
+}
+
+func sessionLayerFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(sessionLayerKey{}).(string)
+	return v
+}
+
+// layers resolves the non-core layers for the caller in ctx, in order, each
+// rendered with its heading and trimmed to its budget. Empty layers are
+// left out.
+func (o *Orchestrator) layers(ctx context.Context, channel, group PromptOverride) []promptLayer {
+	texts := map[string]string{
+		LayerDeployment: o.promptLayers.Deployment,
+		LayerChannel:    channel.Append,
+		LayerGroup:      group.Append,
+		LayerSession:    sessionLayerFromContext(ctx),
+	}
+	var out []promptLayer
+	remaining := o.promptLayers.TotalBudget
+	for _, name := range o.promptLayers.order() {
+		text := strings.TrimSpace(texts[name])
+		if text == "" {
+			continue
+		}
+		limit := o.promptLayers.budget(name)
+		if o.promptLayers.TotalBudget > 0 && (limit == 0 || remaining < limit) {
+			limit = remaining
+		}
+		if o.promptLayers.TotalBudget > 0 && limit <= 0 {
+			slog.Debug("system prompt layer dropped: total budget spent", "layer", name)
+			continue
+		}
+		if limit > 0 && len(text) > limit {
+			slog.Debug("system prompt layer cut to budget", "layer", name, "bytes", len(text), "budget", limit)
+			text = truncateLayer(text, limit)
+		}
+		remaining -= len(text)
+		out = append(out, promptLayer{name: name, text: layerHeadings[name] + text + "\n\n"})
+	}
+	return out
+}
+
+// truncateLayer cuts s to at most limit bytes at a rune boundary, preferring
+// the last line break so a layer does not end mid-sentence.
+func truncateLayer(s string, limit int) string {
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if nl := strings.LastIndexByte(s[:cut], '\n'); nl > cut/2 {
+		cut = nl
+	}
+	return strings.TrimSpace(s[:cut])
+}
+
+// renderLayers concatenates layers in order.
+func renderLayers(layers []promptLayer) string {
+	var sb strings.Builder
+	for _, l := range layers {
+		sb.WriteString(l.text)
+	}
+	return sb.String()
+}
+
+// SessionSystemPrompt returns the session layer of sess ("" when unset).
+func SessionSystemPrompt(sess *state.Session) string {
+	if sess == nil {
+		return ""
+	}
+	return sess.Metadata[MetaSystemPrompt]
+}
+
+// AddSessionSystemPrompt appends text to the session layer of sessionID,
+// refusing additions that would take it past limit bytes (<= 0 =
+// DefaultSessionLayerBytes).
+func AddSessionSystemPrompt(store SessionStoreInterface, sessionID, text string, limit int) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("text is empty")
+	}
+	if limit <= 0 {
+		limit = DefaultSessionLayerBytes
+	}
+	sess, err := store.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	current := SessionSystemPrompt(sess)
+	if current != "" {
+		text = current + "\n" + text
+	}
+	if len(text) > limit {
+		return fmt.Errorf("session instructions would be %d bytes; the limit is %d", len(text), limit)
+	}
+	return store.SetMetadata(sessionID, MetaSystemPrompt, text)
+}
+
+// ClearSessionSystemPrompt removes the session layer of sessionID.
+func ClearSessionSystemPrompt(store SessionStoreInterface, sessionID string) error {
+	return store.SetMetadata(sessionID, MetaSystemPrompt, "")
+}
@@ -0,0 +1,132 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newLayersOrchestrator(layers PromptLayers, po PromptOverrides) *Orchestrator {
+	return NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{CustomRules: []string{"global rule"}, PromptOverrides: po, PromptLayers: layers})
+}
+
+func layersCtx(sessionLayer string) context.Context {
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "e1", ChannelID: "slack", Group: "support"})
+	sess := &state.Session{Metadata: map[string]string{MetaSystemPrompt: sessionLayer}}
+	return withSessionLayer(ctx, sess)
+}
+
+var layerOverrides = PromptOverrides{
+	Channels: map[string]PromptOverride{"slack": {Append: "CHANNEL LAYER"}},
+	Groups:   map[string]PromptOverride{"support": {Append: "GROUP LAYER"}},
+}
+
+func TestPromptLayers_DefaultOrderAfterRules(t *testing.T) {
+	orch := newLayersOrchestrator(PromptLayers{Deployment: "DEPLOYMENT LAYER"}, layerOverrides)
+	prompt := orch.buildSystemPrompt(layersCtx("SESSION LAYER"), "hi", true)
+
+	last := strings.Index(prompt, "[custom] global rule")
+	if last < 0 {
+		t.Fatalf("rules missing:\n%s", prompt)
+	}
+	for _, want := range []string{"DEPLOYMENT LAYER", "CHANNEL LAYER", "GROUP LAYER", "## Session instructions (set by an admin)\nSESSION LAYER"} {
+		i := strings.Index(prompt, want)
+		if i < last {
+			t.Fatalf("%q missing or out of order:\n%s", want, prompt)
+		}
+		last = i
+	}
+}
+
+func TestPromptLayers_CustomOrderAndTemplateLayer(t *testing.T) {
+	tmpl, err := ParseSystemPromptTemplate(`{{.Rules}}[{{.Layer "session"}}]{{.Layer "nope"}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{
+			PromptOverrides:      layerOverrides,
+			PromptLayers:         PromptLayers{Order: []string{LayerSession, LayerGroup}},
+			SystemPromptTemplate: tmpl,
+		})
+	prompt := orch.buildSystemPrompt(layersCtx("SESSION LAYER"), "hi", true)
+	if !strings.Contains(prompt, "[## Session instructions (set by an admin)\nSESSION LAYER\n\n]") {
+		t.Errorf("template should place the session layer on its own:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "GROUP LAYER") {
+		t.Error("layers the template leaves out must not be sent")
+	}
+
+	orch.promptTemplate = nil
+	prompt = orch.buildSystemPrompt(layersCtx("SESSION LAYER"), "hi", true)
+	s, g, c := strings.Index(prompt, "SESSION LAYER"), strings.Index(prompt, "GROUP LAYER"), strings.Index(prompt, "CHANNEL LAYER")
+	if s < 0 || !(s < g && g < c) {
+		t.Errorf("want session, group, channel (configured then remaining default order); got %d %d %d", s, g, c)
+	}
+}
+
+func TestPromptLayers_Budgets(t *testing.T) {
+	orch := newLayersOrchestrator(PromptLayers{
+		Deployment:  strings.Repeat("d", 50),
+		Budgets:     map[string]int{LayerDeployment: 20},
+		TotalBudget: 33, // deployment 20 + channel 13
+	}, layerOverrides)
+	prompt := orch.buildSystemPrompt(layersCtx(""), "hi", true)
+	if !strings.Contains(prompt, "## Deployment instructions\n"+strings.Repeat("d", 20)+"\n") {
+		t.Errorf("deployment layer should be cut to its 20-byte budget:\n%s", prompt)
+	}
+	if !strings.Contains(prompt, "CHANNEL LAYER") {
+		t.Error("channel layer fits in the remaining total budget")
+	}
+	if strings.Contains(prompt, "GROUP LAYER") || strings.Contains(prompt, "Audience instructions") {
+		t.Error("group layer should be dropped once the total budget is spent")
+	}
+}
+
+func TestPromptLayers_Validate(t *testing.T) {
+	if err := (PromptLayers{Order: []string{LayerSession, LayerChannel}}).Validate(); err != nil {
+		t.Errorf("valid order rejected: %v", err)
+	}
+	for _, bad := range []PromptLayers{
+		{Order: []string{"persona"}},
+		{Order: []string{LayerGroup, LayerGroup}},
+		{Budgets: map[string]int{"core": 10}},
+	} {
+		if bad.Validate() == nil {
+			t.Errorf("%+v should not validate", bad)
+		}
+	}
+}
+
+func TestSessionSystemPrompt_AddRespectsLimit(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	if err := AddSessionSystemPrompt(sessions, "s1", "Reply in French.", 40); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddSessionSystemPrompt(sessions, "s1", "Keep answers under 50 words.", 40); err == nil {
+		t.Error("addition past the limit should be refused")
+	}
+	sess, _ := sessions.Get("s1")
+	if got := SessionSystemPrompt(sess); got != "Reply in French." {
+		t.Errorf("session layer = %q", got)
+	}
+
+	// Run carries the session layer into the prompt.
+	llm := &capturingLLM{responses: []string{"Bonjour"}}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	if _, err := orch.Run(actor.WithActor(context.Background(), "slack:U1"), "s1", "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if sys := llm.requests[0].Messages[0].Content; !strings.Contains(sys, "Reply in French.") {
+		t.Errorf("session layer not in the system prompt:\n%s", sys)
+	}
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+// PromptOverride customizes the system prompt for one channel or actor group.
+// Replace swaps out the built-in preamble (identity and tool-calling
+// instructions); the safety rules section is never replaced. Append is the
+// channel or group layer (see PromptLayers). Both are optional.
+type PromptOverride struct {
+	Replace string
+	Append  string
+}
+
+// PromptOverrides holds the per-channel (keyed by channel id, i.e. the entry
+// name under `channels:`) and per-group (keyed by profile group) overrides.
+//
+// Merge order in buildSystemPrompt:
+//  1. preamble — group Replace, else channel Replace, else the built-in one
+//  2. global rules section (built-in + orchestrator.rules)
+//  3. the layers in PromptLayers order; by default the channel Append comes
+//     before the group Append
+//
+// The group wins for Replace because it is the more specific audience; both
+// Appends apply, so a group instruction can refine a channel one.
+type PromptOverrides struct {
+	Channels map[string]PromptOverride
+	Groups   map[string]PromptOverride
+}
+
+// resolve returns the overrides that apply to the caller in ctx.
+func (p PromptOverrides) resolve(ctx context.Context) (channel, group PromptOverride) {
+	if len(p.Channels) > 0 {
+		if id := currentChannelID(ctx); id != "" {
+			channel = p.Channels[id]
+		}
+	}
+	if len(p.Groups) > 0 {
+		if prof := profile.FromContext(ctx); prof != nil && prof.Group != "" {
+			group = p.Groups[prof.Group]
+		}
+	}
+	return channel, group
+}
+
+// overridePreamble returns the replacement preamble for the caller, or "" to
+// keep the built-in one.
+func overridePreamble(channel, group PromptOverride) string {
+	if s := strings.TrimSpace(group.Replace); s != "" {
+		return s + "\n\n"
+	}
+	if s := strings.TrimSpace(channel.Replace); s != "" {
+		return s + "\n\n"
+	}
+	return ""
+}
+
+// currentChannelID returns the id of the channel the turn came from: the
+// profile's channel in profile mode, else the channel prefix of the classic
+// "channel:sender" actor. Empty when neither is known.
+func currentChannelID(ctx context.Context) string {
+	if p := profile.FromContext(ctx); p != nil && p.ChannelID != "" {
+		return p.ChannelID
+	}
+	if a := actor.Actor(ctx); a != "" {
+		if i := strings.IndexByte(a, ':'); i > 0 {
+			return a[:i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/prompts"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newOverrideOrchestrator(po PromptOverrides) *Orchestrator {
+	return NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{CustomRules: []string{"global rule"}, PromptOverrides: po})
+}
+
+func TestPromptOverrides_ChannelAppendAfterRules(t *testing.T) {
+	orch := newOverrideOrchestrator(PromptOverrides{
+		Channels: map[string]PromptOverride{"sms": {Append: "Answer in one short sentence."}},
+	})
+	prompt := orch.buildSystemPrompt(actor.WithActor(context.Background(), "sms:+100"), "hi", true)
+
+	rules := strings.Index(prompt, "[custom] global rule")
+	extra := strings.Index(prompt, "Answer in one short sentence.")
+	if rules < 0 || extra < 0 || extra < rules {
+		t.Fatalf("channel append should follow the rules section (rules=%d, append=%d):\n%s", rules, extra, prompt)
+	}
+	if !strings.HasPrefix(prompt, prompts.OrchestratorPreamble) {
+		t.Error("append-only override must keep the built-in preamble")
+	}
+
+	other := orch.buildSystemPrompt(actor.WithActor(context.Background(), "slack:U1"), "hi", true)
+	if strings.Contains(other, "one short sentence") {
+		t.Error("override for sms leaked into another channel")
+	}
+}
+
+func TestPromptOverrides_MergeOrder(t *testing.T) {
+	orch := newOverrideOrchestrator(PromptOverrides{
+		Channels: map[string]PromptOverride{"slack": {Replace: "CHANNEL PREAMBLE", Append: "channel extra"}},
+		Groups:   map[string]PromptOverride{"support": {Replace: "GROUP PREAMBLE", Append: "group extra"}},
+	})
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "e1", ChannelID: "slack", Group: "support"})
+	prompt := orch.buildSystemPrompt(ctx, "hi", true)
+
+	if !strings.HasPrefix(prompt, "GROUP PREAMBLE") {
+		t.Errorf("group replace should win over channel replace:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "CHANNEL PREAMBLE") || strings.Contains(prompt, prompts.OrchestratorPreamble) {
+		t.Error("only one preamble may be rendered")
+	}
+	if !strings.Contains(prompt, "CRITICAL SAFETY RULE") {
+		t.Error("replace must never drop the safety rules")
+	}
+	ch, gr := strings.Index(prompt, "channel extra"), strings.Index(prompt, "group extra")
+	if ch < 0 || gr < 0 || ch > gr {
+		t.Errorf("want channel append before group append (channel=%d, group=%d)", ch, gr)
+	}
+}
+
+func TestPromptOverrides_ChannelReplaceWithoutGroup(t *testing.T) {
+	orch := newOverrideOrchestrator(PromptOverrides{
+		Channels: map[string]PromptOverride{"slack": {Replace: "CHANNEL PREAMBLE"}},
+		Groups:   map[string]PromptOverride{"support": {Replace: "GROUP PREAMBLE"}},
+	})
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "e1", ChannelID: "slack", Group: "sales"})
+	prompt := orch.buildSystemPrompt(ctx, "hi", true)
+	if !strings.HasPrefix(prompt, "CHANNEL PREAMBLE") {
+		t.Errorf("channel replace should apply when the group has no override:\n%s", prompt)
+	}
+	if strings.Contains(prompt, "## Channel and audience instructions") {
+		t.Error("no append configured, section should be omitted")
+	}
+}
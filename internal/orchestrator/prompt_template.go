@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// maxTemplateMemories bounds {{.Memories}} so a large memory store cannot
+// blow up every system prompt.
+const maxTemplateMemories = 20
+
+// PromptTemplateData is the data a system prompt template
+// (orchestrator.system_prompt_template) is executed with. Each section field
+// holds exactly what the built-in layout would emit for it — heading and
+// trailing newlines included — and is empty when that section does not apply
+// to the turn, so a template can use {{with .Session}}…{{end}} freely.
+// The built-in layout is, in order:
+//
+//	Agent Preamble Rules Instructions Knowledge Documents RuntimeInstructions
+//	Session Tools Subprocess OutputFormat User Language Experiment
+type PromptTemplateData struct {
+	Agent               string // acting agent's name and persona prompt (see Agents)
+	Preamble            string // identity + tool-calling instructions (or a channel/group replace)
+	Rules               string // "## MANDATORY SAFETY RULES" with built-in and custom rules
+	Instructions        string // every prompt layer in order (see PromptLayers); use Layer to place them one by one
+	Knowledge           string // knowledge catalog
+	Documents           string // document passages retrieved for this message (rag.inject_top_k)
+	RuntimeInstructions string // /set prompt text
+	Session             string // current channel + conversation
+	Tools               string // plugin sections and, in native mode, the tool catalog
+	Subprocess          string // sub-agent instructions when subprocesses are enabled
+	OutputFormat        string // channel output-format hint
+	User                string // the user's name and their own profile preferences (see ActorProfileStore)
+	Language            string // reply-language directive for this turn
+	Experiment          string // the session's A/B variant prompt suffix (see Experiments)
+
+	Date        string // current date, e.g. "2026-03-14 (Saturday)"
+	ChannelName string // channel display name, falling back to its id
+	ChannelID   string // channel id (the entry name under channels:)
+
+	ctx    context.Context
+	memory MemoryStoreInterface
+	layers []promptLayer
+}
+
+// Layer renders one prompt layer ("deployment", "channel", "group",
+// "session"), heading included, or "" when it is empty for this turn. Called
+// from a template as {{.Layer "session"}}.
+func (d PromptTemplateData) Layer(name string) string {
+	for _, l := range d.layers {
+		if l.name == name {
+			return l.text
+		}
+	}
+	return ""
+}
+
+// Memories renders the memories visible to the caller (general plus the
+// actor's own) as a bullet list, capped at maxTemplateMemories. Called from a
+// template as {{.Memories}}; only templates that use it pay for the lookup.
+func (d PromptTemplateData) Memories() string {
+	if d.memory == nil {
+		return ""
+	}
+	mems, err := d.memory.MemoriesForContext(d.ctx, "")
+	if err != nil {
+		slog.Warn("system prompt template: memories unavailable", "error", err)
+		return ""
+	}
+	var sb strings.Builder
+	for i, m := range mems {
+		if i == maxTemplateMemories {
+			break
+		}
+		sb.WriteString("- ")
+		sb.WriteString(strings.TrimSpace(m.Content))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// ParseSystemPromptTemplate parses an operator-supplied system prompt
+// template. Unknown fields are caught here rather than on the first turn.
+func ParseSystemPromptTemplate(text string) (*template.Template, error) {
+	t, err := template.New("system_prompt").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	// Execute once against empty data so a misspelled field fails at startup.
+	if err := t.Execute(&strings.Builder{}, PromptTemplateData{ctx: context.Background()}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// renderSystemPrompt lays out the sections: through the configured template
+// when there is one, else in the built-in order. A template that fails at
+// execution time falls back to the built-in layout so the turn still runs.
+func (o *Orchestrator) renderSystemPrompt(sec PromptTemplateData) string {
+	if o.promptTemplate != nil {
+		caps := pkgchannel.CapabilitiesFromContext(sec.ctx)
+		sec.ChannelID = currentChannelID(sec.ctx)
+		sec.ChannelName = caps.Name
+		if sec.ChannelName == "" {
+			sec.ChannelName = sec.ChannelID
+		}
+		sec.Date = time.Now().Format("2006-01-02 (Monday)")
+		var sb strings.Builder
+		err := o.promptTemplate.Execute(&sb, sec)
+		if err == nil {
+			return sb.String()
+		}
+		slog.Warn("system prompt template failed; using the built-in layout", "error", err)
+	}
+	return sec.Agent + sec.Preamble + sec.Rules + sec.Instructions + sec.Knowledge + sec.Documents +
+		sec.RuntimeInstructions + sec.Session + sec.Tools + sec.Subprocess + sec.OutputFormat + sec.User + sec.Language + sec.Experiment
+}
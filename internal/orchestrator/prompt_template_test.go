@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state"
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+func TestParseSystemPromptTemplate_RejectsUnknownField(t *testing.T) {
+	if _, err := ParseSystemPromptTemplate("{{.Tols}}"); err == nil {
+		t.Error("misspelled field should fail at parse time")
+	}
+	if _, err := ParseSystemPromptTemplate("{{.Rules"); err == nil {
+		t.Error("syntax error should fail at parse time")
+	}
+}
+
+func TestSystemPromptTemplate_RendersSections(t *testing.T) {
+	tpl, err := ParseSystemPromptTemplate(
+		"Hoje é {{.Date}}. Canal: {{.ChannelName}}.\n{{.Rules}}{{with .Memories}}## Memories\n{{.}}{{end}}{{.Tools}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := state.NewMemoryStore("")
+	mem.Add("The office closes at 6pm")
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{
+		Name: "weather", Description: "Weather lookups",
+		Actions: []Action{{Name: "forecast", Description: "Get the forecast"}},
+	}, &echoExecutor{})
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, mem,
+		state.NewSessionStore(""), OrchestratorOpts{CustomRules: []string{"be brief"}, SystemPromptTemplate: tpl})
+
+	ctx := pkgchannel.WithCapabilities(actor.WithActor(context.Background(), "slack:U1"), pkgchannel.Capabilities{Name: "Slack"})
+	prompt := orch.buildSystemPrompt(ctx, "hi", true)
+
+	for _, want := range []string{
+		"Hoje é " + time.Now().Format("2006-01-02"),
+		"Canal: Slack.",
+		"[custom] be brief",
+		"## Memories\n- The office closes at 6pm",
+		"## weather",
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, prompt)
+		}
+	}
+	if strings.Contains(prompt, "## Current session") {
+		t.Error("sections the template leaves out must not be rendered")
+	}
+}
+
+func TestSystemPromptTemplate_ChannelNameFallsBackToID(t *testing.T) {
+	tpl, err := ParseSystemPromptTemplate("{{.ChannelName}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{SystemPromptTemplate: tpl})
+	if got := orch.buildSystemPrompt(actor.WithActor(context.Background(), "telegram:42"), "hi", true); got != "telegram" {
+		t.Errorf("ChannelName = %q; want the channel id", got)
+	}
+}
+
+func TestSystemPromptTemplate_DefaultLayoutUnchanged(t *testing.T) {
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+	tpl, err := ParseSystemPromptTemplate("{{.Preamble}}{{.Rules}}{{.Instructions}}{{.Knowledge}}{{.RuntimeInstructions}}" +
+		"{{.Session}}{{.Tools}}{{.Subprocess}}{{.OutputFormat}}{{.User}}{{.Language}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTpl := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{SystemPromptTemplate: tpl})
+	if a, b := orch.buildSystemPrompt(ctx, "hi", true), withTpl.buildSystemPrompt(ctx, "hi", true); a != b {
+		t.Errorf("template spelling out the built-in order should match the default layout\ndefault:\n%s\ntemplate:\n%s", a, b)
+	}
+}
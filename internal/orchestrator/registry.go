@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -44,6 +45,19 @@ type CallbackHandler interface {
 	RunActionResult(ctx context.Context, plugin, action string, args map[string]string) (content, structured string, err error)
 }
 
+// ProgressHandler is optionally implemented by a CallbackHandler to
+// receive the ToolProgress frames a plugin sends during ExecuteBidi.
+type ProgressHandler interface {
+	ToolProgress(ctx context.Context, call ToolCall, p ToolProgress)
+}
+
+// ToolProgress is one progress report from a running plugin action.
+type ToolProgress struct {
+	Message string // status line for the user
+	Partial string // next piece of the result
+	Percent int    // 1-100; 0 = unknown
+}
+
 type ToolRegistry struct {
 	mu        sync.RWMutex
 	plugins   map[string]PluginCapability
@@ -65,8 +79,11 @@ func (r *ToolRegistry) Register(cap PluginCapability, exec PluginExecutor) error
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if _, exists := r.plugins[cap.Name]; exists {
-		return fmt.Errorf("plugin %q already registered", cap.Name)
+	if existing, exists := r.plugins[cap.Name]; exists {
+		return &ToolConflictError{Name: cap.Name, Existing: existing.Version, Version: cap.Version, Suggestion: r.freeName(cap.Name, cap.Version)}
+	}
+	if target, exists := r.aliases[cap.Name]; exists {
+		return &ToolConflictError{Name: cap.Name, AliasOf: target, Version: cap.Version, Suggestion: r.freeName(cap.Name, cap.Version)}
 	}
 	// Reject any action whose composed FQN won't pass the provider tool-name
 	// charset, so a dotted plugin/action name fails here with a clear error
@@ -84,6 +101,61 @@ func (r *ToolRegistry) Register(cap PluginCapability, exec PluginExecutor) error
 	return nil
 }
 
+// ToolConflictError is returned by Register when the capability's name is
+// taken by a registered plugin or alias. Suggestion is a free name to give
+// one of the two entries as its alias.
+type ToolConflictError struct {
+	Name       string
+	AliasOf    string // set when Name is an alias of this plugin (e.g. an MCP server)
+	Existing   string // version of the registered plugin; "" = unversioned
+	Version    string // version of the rejected capability; "" = unversioned
+	Suggestion string
+}
+
+func (e *ToolConflictError) Error() string {
+	var b strings.Builder
+	if e.AliasOf != "" {
+		fmt.Fprintf(&b, "plugin %q already registered as an alias of %q", e.Name, e.AliasOf)
+	} else {
+		fmt.Fprintf(&b, "plugin %q already registered", e.Name)
+	}
+	if e.Existing != "" || e.Version != "" {
+		fmt.Fprintf(&b, " (registered %s, new %s)", versionOrNone(e.Existing), versionOrNone(e.Version))
+	}
+	fmt.Fprintf(&b, "; set alias: %s on one of the entries", e.Suggestion)
+	return b.String()
+}
+
+func versionOrNone(v string) string {
+	if v == "" {
+		return "unversioned"
+	}
+	return v
+}
+
+var versionNameRe = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+
+// freeName suggests a plugin name derived from name that is neither
+// registered nor an alias: name_<version> when the version makes a valid
+// name, else name_2, name_3, ... The caller holds the lock.
+func (r *ToolRegistry) freeName(name, version string) string {
+	taken := func(n string) bool {
+		_, plugin := r.plugins[n]
+		_, alias := r.aliases[n]
+		return plugin || alias
+	}
+	if v := strings.Trim(versionNameRe.ReplaceAllString(version, "-"), "-"); v != "" {
+		if n := name + "_" + v; !taken(n) && fqnCharsetRe.MatchString(n) {
+			return n
+		}
+	}
+	for i := 2; ; i++ {
+		if n := fmt.Sprintf("%s_%d", name, i); !taken(n) {
+			return n
+		}
+	}
+}
+
 // UpdateCapability replaces a registered plugin's capability in place, keeping
 // its executor. Used by the periodic refresh poll to propagate upstream changes
 // (tool descriptions, server instructions, knowledge articles) without a plugin
@@ -119,10 +191,10 @@ func (r *ToolRegistry) RegisterAlias(alias, target string) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, exists := r.plugins[alias]; exists {
-		return fmt.Errorf("cannot alias %q: a plugin with that name is already registered", alias)
+		return fmt.Errorf("cannot alias %q: a plugin with that name is already registered; rename one, e.g. %q", alias, r.freeName(alias, ""))
 	}
 	if _, exists := r.aliases[alias]; exists {
-		return fmt.Errorf("alias %q already registered", alias)
+		return fmt.Errorf("alias %q already registered; rename one, e.g. %q", alias, r.freeName(alias, ""))
 	}
 	if _, exists := r.plugins[target]; !exists {
 		return fmt.Errorf("alias target %q not registered", target)
@@ -2,6 +2,7 @@ package orchestrator
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"testing"
 )
@@ -65,6 +66,45 @@ func TestRegistryDuplicateRegister(t *testing.T) {
 	}
 }
 
+func TestRegistryConflictSuggestsAlias(t *testing.T) {
+	reg := NewToolRegistry()
+	if err := reg.Register(PluginCapability{Name: "jira", Version: "1.4"}, &mockExecutor{}); err != nil {
+		t.Fatal(err)
+	}
+	err := reg.Register(PluginCapability{Name: "jira", Version: "v2.0"}, &mockExecutor{})
+	var conflict *ToolConflictError
+	if !errors.As(err, &conflict) || conflict.Suggestion != "jira_v2-0" {
+		t.Fatalf("err = %v, want a conflict suggesting jira_v2-0", err)
+	}
+	if want := `plugin "jira" already registered (registered 1.4, new v2.0); set alias: jira_v2-0 on one of the entries`; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+
+	// Unversioned: numbered names, skipping taken ones. An alias (e.g. an
+	// MCP server) counts as taken too.
+	_ = reg.Register(PluginCapability{Name: "jira_2"}, &mockExecutor{})
+	err = reg.Register(PluginCapability{Name: "jira"}, &mockExecutor{})
+	if !errors.As(err, &conflict) || conflict.Suggestion != "jira_3" {
+		t.Errorf("err = %v, want jira_3", err)
+	}
+	if err := reg.RegisterAlias("confluence", "jira"); err != nil {
+		t.Fatal(err)
+	}
+	err = reg.Register(PluginCapability{Name: "confluence"}, &mockExecutor{})
+	if !errors.As(err, &conflict) || conflict.AliasOf != "jira" || conflict.Suggestion != "confluence_2" {
+		t.Errorf("err = %v, want an alias conflict suggesting confluence_2", err)
+	}
+}
+
+func TestCapabilityHeadingShowsVersion(t *testing.T) {
+	if got := (PluginCapability{Name: "jira", Version: "v2"}).heading(); got != "jira (v2)" {
+		t.Errorf("heading = %q", got)
+	}
+	if got := (PluginCapability{Name: "jira"}).heading(); got != "jira" {
+		t.Errorf("heading = %q", got)
+	}
+}
+
 func TestRegistryDeregister(t *testing.T) {
 	reg := NewToolRegistry()
 	_ = reg.Register(gitlabCapability(), &mockExecutor{})
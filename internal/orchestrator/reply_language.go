@@ -1,11 +1,14 @@
 package orchestrator
 
 import (
+	"cmp"
 	"context"
 	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
 	lingua "github.com/pemistahl/lingua-go"
 )
 
@@ -81,12 +84,22 @@ func replyLanguageDirectiveFromContext(ctx context.Context) string {
 // returns a firm reply-language instruction, or "" when the message is too
 // short or detection is not confident enough to pin a language.
 func (o *Orchestrator) replyLanguageDirective(userMessage string) string {
-	if o.langDetector == nil {
+	lang, ok := o.detectLanguage(userMessage)
+	if !ok {
 		return ""
 	}
+	return languageDirective(lang.String())
+}
+
+// detectLanguage returns the language of userMessage when detection is
+// confident enough to pin it.
+func (o *Orchestrator) detectLanguage(userMessage string) (lingua.Language, bool) {
+	if o.langDetector == nil {
+		return lingua.Unknown, false
+	}
 	msg := strings.TrimSpace(userMessage)
 	if len([]rune(msg)) < replyLanguageMinChars {
-		return ""
+		return lingua.Unknown, false
 	}
 	// One pass over the n-grams: ComputeLanguageConfidenceValues returns the
 	// candidates sorted by confidence descending, so values[0] is both the
@@ -96,9 +109,12 @@ func (o *Orchestrator) replyLanguageDirective(userMessage string) string {
 	// DetectLanguageOf's separate ambiguity check.
 	values := o.langDetector.ComputeLanguageConfidenceValues(msg)
 	if len(values) == 0 || values[0].Value() < replyLanguageMinConfidence {
-		return ""
+		return lingua.Unknown, false
 	}
-	name := values[0].Language().String()
+	return values[0].Language(), true
+}
+
+func languageDirective(name string) string {
 	return fmt.Sprintf("## Reply language\nReply in %s. Use %s for your entire reply, regardless of the language of any "+
 		"retrieved context or earlier messages. Technical terms (field, tool and status names) stay in English.\n\n", name, name)
 }
@@ -121,13 +137,21 @@ func (o *Orchestrator) replyLanguageDirective(userMessage string) string {
 // approval reply itself could be mistaken for the request. A detectable current
 // message always wins, so a genuine mid-conversation language switch is honoured.
 func (o *Orchestrator) replyLanguageDirectiveWithHistory(current string, priorHistory []provider.Message) string {
-	if directive := o.replyLanguageDirective(current); directive != "" {
-		return directive
+	directive, _ := o.replyLanguageWithHistory(current, priorHistory)
+	return directive
+}
+
+// replyLanguageWithHistory is replyLanguageDirectiveWithHistory plus the
+// locale detected on the current message itself ("" when the directive came
+// from history or nothing was detectable).
+func (o *Orchestrator) replyLanguageWithHistory(current string, priorHistory []provider.Message) (directive, locale string) {
+	if lang, ok := o.detectLanguage(current); ok {
+		return languageDirective(lang.String()), localeCode(lang)
 	}
 	if prev := lastUserMessage(priorHistory); prev != "" {
-		return o.replyLanguageDirective(prev)
+		return o.replyLanguageDirective(prev), ""
 	}
-	return ""
+	return "", ""
 }
 
 // replyLanguageDirectiveForHidden returns the reply-language directive for a
@@ -151,3 +175,126 @@ func (o *Orchestrator) replyLanguageDirectiveForHidden(priorHistory []provider.M
 	}
 	return ""
 }
+
+// MetaLocale is the session metadata key holding the ISO 639-1 code of the
+// language the user last wrote in confidently enough to detect ("de", "fr").
+// It pins replies when a turn gives no signal of its own (an "ok" after the
+// history that carried the language was summarised away) and selects the
+// language of core system replies.
+const MetaLocale = "locale"
+
+// turnLanguage resolves the turn's reply-language directive and the locale
+// to record for the session ("" = leave it). preferred is the language the
+// actor chose in their profile; it wins over detection. A forced language
+// (config orchestrator.reply_language) always wins the directive, but the
+// user's detected locale is still recorded.
+func (o *Orchestrator) turnLanguage(current string, priorHistory []provider.Message, hidden bool, sessionLocale, preferred string) (directive, locale string) {
+	if hidden {
+		directive = o.replyLanguageDirectiveForHidden(priorHistory)
+	} else {
+		directive, locale = o.replyLanguageWithHistory(current, priorHistory)
+	}
+	if directive == "" {
+		if lang, ok := ResolveLanguage(sessionLocale); ok {
+			directive = languageDirective(lang.String())
+		}
+	}
+	if lang, ok := ResolveLanguage(preferred); ok {
+		directive = languageDirective(lang.String())
+	}
+	if lang, ok := ResolveLanguage(o.forcedLanguage); ok {
+		directive = languageDirective(lang.String())
+	}
+	return directive, locale
+}
+
+// ResolveLanguage maps a language name ("German") or ISO 639-1 code ("de"),
+// case-insensitively, to a lingua language.
+func ResolveLanguage(s string) (lingua.Language, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return lingua.Unknown, false
+	}
+	for _, lang := range lingua.AllLanguages() {
+		if strings.EqualFold(lang.String(), s) || strings.EqualFold(lang.IsoCode639_1().String(), s) {
+			return lang, true
+		}
+	}
+	return lingua.Unknown, false
+}
+
+// coreLocale is the locale for core system replies: the forced reply
+// language when one is configured, else the session's.
+func (o *Orchestrator) coreLocale(sessionLocale string) string {
+	if o.forcedLanguage != "" {
+		return o.forcedLanguage
+	}
+	return sessionLocale
+}
+
+// deploymentLocale is the configured language for the turn's channel, else
+// the global one; "" when neither is set.
+func (o *Orchestrator) deploymentLocale(ctx context.Context) string {
+	if l := o.channelLocales[currentChannelID(ctx)]; l != "" {
+		return l
+	}
+	return o.locale
+}
+
+// summaryLanguageDirective asks for the summary in the session's language
+// (forced, detected or configured) so a German conversation is not
+// summarised into English. "" for English or an unknown language.
+func (o *Orchestrator) summaryLanguageDirective(ctx context.Context, sess *state.Session) string {
+	locale := o.coreLocale(cmp.Or(sess.Metadata[MetaLocale], o.deploymentLocale(ctx)))
+	lang, ok := ResolveLanguage(locale)
+	if !ok || lang == lingua.English {
+		return ""
+	}
+	return "\n\nWrite the summary in " + lang.String() + "."
+}
+
+// channelLocaleCodes resolves OrchestratorOpts.ChannelLocales, dropping (and
+// logging) unknown languages.
+func channelLocaleCodes(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for ch, l := range m {
+		if code := configLanguageCode(l); code != "" {
+			out[ch] = code
+		}
+	}
+	return out
+}
+
+// configLanguageCode resolves a configured language (ReplyLanguage, Locale,
+// ChannelLocales) to its ISO 639-1 code; an unknown language is logged and
+// ignored (main validates them at startup).
+func configLanguageCode(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return ""
+	}
+	lang, ok := ResolveLanguage(s)
+	if !ok {
+		slog.Warn("unknown configured language; ignored", "language", s)
+		return ""
+	}
+	return localeCode(lang)
+}
+
+func localeCode(lang lingua.Language) string {
+	return strings.ToLower(lang.IsoCode639_1().String())
+}
+
+// turnLocaleKey carries the locale core system replies use this turn.
+type turnLocaleKey struct{}
+
+func withTurnLocale(ctx context.Context, locale string) context.Context {
+	if locale == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, turnLocaleKey{}, locale)
+}
+
+func turnLocale(ctx context.Context) string {
+	v, _ := ctx.Value(turnLocaleKey{}).(string)
+	return v
+}
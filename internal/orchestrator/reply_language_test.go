@@ -1,10 +1,13 @@
 package orchestrator
 
 import (
+	"context"
 	"strings"
 	"testing"
 
+	"github.com/opentalon/opentalon/internal/actor"
 	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
 )
 
 func newDetectorOrchestrator() *Orchestrator {
@@ -206,3 +209,161 @@ func TestReplyLanguageDirectiveForHidden_NoDetectableHistory(t *testing.T) {
 		t.Errorf("undetectable history should leave the turn unpinned, got %q", got)
 	}
 }
+
+// The session locale carries the language across turns that give no signal of
+// their own and whose history no longer holds it (e.g. after summarization).
+func TestTurnLanguage_SessionLocaleAndForcedLanguage(t *testing.T) {
+	o := newDetectorOrchestrator()
+
+	directive, locale := o.turnLanguage("Kannst du mir bitte alle offenen Tickets zeigen?", nil, false, "", "")
+	if !strings.Contains(directive, "Reply in German.") || locale != "de" {
+		t.Errorf("detected turn = %q, locale %q", directive, locale)
+	}
+	directive, locale = o.turnLanguage("ok", nil, false, "de", "")
+	if !strings.Contains(directive, "Reply in German.") || locale != "" {
+		t.Errorf("undetectable turn should fall back to the session locale, got %q, locale %q", directive, locale)
+	}
+	directive, locale = o.turnLanguage("Can you show me all open tickets please?", nil, false, "", "de")
+	if !strings.Contains(directive, "Reply in German.") || locale != "en" {
+		t.Errorf("the actor's preferred language should win over detection, got %q, locale %q", directive, locale)
+	}
+
+	o.forcedLanguage = configLanguageCode("French")
+	directive, locale = o.turnLanguage("Can you show me all open tickets please?", nil, false, "", "de")
+	if !strings.Contains(directive, "Reply in French.") || locale != "en" {
+		t.Errorf("forced language should pin French but still record the user's locale, got %q, locale %q", directive, locale)
+	}
+	if got := o.coreLocale("en"); got != "fr" {
+		t.Errorf("coreLocale = %q; want the forced language", got)
+	}
+}
+
+func TestResolveLanguage(t *testing.T) {
+	for in, want := range map[string]string{"German": "de", "de": "de", "PT": "pt", " lithuanian ": "lt"} {
+		lang, ok := ResolveLanguage(in)
+		if !ok || localeCode(lang) != want {
+			t.Errorf("ResolveLanguage(%q) = %v, %v; want %s", in, lang, ok, want)
+		}
+	}
+	for _, bad := range []string{"", "Klingon", "xx"} {
+		if _, ok := ResolveLanguage(bad); ok {
+			t.Errorf("ResolveLanguage(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCoreString_FallsBackToEnglish(t *testing.T) {
+	if got := coreString("de", msgGuardBlocked, "lua:pii"); got != "Anfrage blockiert: Prüfung lua:pii ist fehlgeschlagen." {
+		t.Errorf("German = %q", got)
+	}
+	if got := coreString("ja", msgConfirmationExpired); got != coreStrings[msgConfirmationExpired]["en"] {
+		t.Errorf("unknown locale = %q", got)
+	}
+	for key, byLocale := range coreStrings {
+		for _, l := range replyLanguageCandidates {
+			if _, ok := byLocale[localeCode(l)]; !ok {
+				t.Errorf("%s has no %s translation", key, l)
+			}
+		}
+	}
+}
+
+func TestRun_RecordsLocaleAndLocalizesCoreReplies(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: []string{"Hier sind deine Tickets."}}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+
+	if _, err := orch.Run(context.Background(), "s1", "Kannst du mir bitte alle offenen Tickets zeigen?"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, sessions, "s1").Metadata[MetaLocale]; got != "de" {
+		t.Fatalf("session locale = %q; want de", got)
+	}
+	res, err := orch.Run(context.Background(), "s1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != coreString("de", msgEmptyContent) {
+		t.Errorf("empty-content reply = %q; want the German one", res.Response)
+	}
+}
+
+func TestMessageCatalog(t *testing.T) {
+	c := MessageCatalog{
+		"de": {msgNoResponse: "(nichts)"},
+		"en": {msgOfflineMode: "We are offline."},
+		"ja": {msgGuardBlocked: "ブロックされました: %s"},
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.text("de", msgNoResponse); got != "(nichts)" {
+		t.Errorf("override = %q", got)
+	}
+	if got := c.text("de", msgOfflineMode); got != coreString("de", msgOfflineMode) {
+		t.Errorf("a built-in translation should beat the English override, got %q", got)
+	}
+	if got := c.text("ja", msgGuardBlocked, "lua:pii"); got != "ブロックされました: lua:pii" {
+		t.Errorf("added locale = %q", got)
+	}
+	if got := c.text("ja", msgOfflineMode); got != "We are offline." {
+		t.Errorf("a locale without the key should use the English override, got %q", got)
+	}
+
+	for name, bad := range map[string]MessageCatalog{
+		"unknown key":  {"de": {"hello": "Hallo"}},
+		"placeholders": {"de": {msgGuardBlocked: "Blockiert."}},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRun_ChannelLocaleLocalizesBeforeDetection(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }},
+		NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{
+			Locale:         "French",
+			ChannelLocales: map[string]string{"slack": "de"},
+		})
+
+	res, err := orch.Run(actor.WithActor(context.Background(), "slack:U1"), "s1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != coreString("de", msgEmptyContent) {
+		t.Errorf("slack reply = %q; want the channel's German", res.Response)
+	}
+	res, err = orch.Run(actor.WithActor(context.Background(), "sms:+1"), "s1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Response != coreString("fr", msgEmptyContent) {
+		t.Errorf("sms reply = %q; want the global French", res.Response)
+	}
+}
+
+func TestSummarize_AsksForSessionLanguage(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("sess", "", "", "")
+	for i := 0; i < 4; i++ {
+		_ = sessions.AddMessage("sess", provider.Message{Role: provider.RoleUser, Content: "m"})
+	}
+	_ = sessions.SetMetadata("sess", MetaLocale, "de")
+	llm := &requestLLM{}
+	orch := NewWithRules(llm, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{MaxMessagesAfterSummary: 1})
+
+	orch.SummarizeSession(context.Background(), "sess")
+	if len(llm.requests) != 1 {
+		t.Fatalf("%d LLM requests; want 1", len(llm.requests))
+	}
+	if sys := llm.requests[0].Messages[0].Content; !strings.HasSuffix(sys, "Write the summary in German.") {
+		t.Errorf("summarize prompt does not ask for German:\n%s", sys)
+	}
+	if got := orch.summaryLanguageDirective(context.Background(), &state.Session{}); got != "" {
+		t.Errorf("no locale should add nothing, got %q", got)
+	}
+}
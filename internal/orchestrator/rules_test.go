@@ -154,6 +154,36 @@ func TestRulesInjectedIntoSystemPrompt(t *testing.T) {
 	}
 }
 
+func TestSetRulesAppliesToNextTurn(t *testing.T) {
+	llm := &fakeLLM{responses: []string{"one", "two"}}
+	parser := &fakeParser{parseFn: func(string) []ToolCall { return nil }}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+
+	var capturedMessages []provider.Message
+	capturingLLM := &captureLLM{inner: llm, captured: &capturedMessages}
+	orch := NewWithRules(capturingLLM, parser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{CustomRules: []string{"old rule"}})
+	if _, err := orch.Run(context.Background(), "s1", "hello"); err != nil {
+		t.Fatal(err)
+	}
+
+	orch.SetRules([]string{"new rule"})
+	capturedMessages = nil
+	if _, err := orch.Run(context.Background(), "s1", "again"); err != nil {
+		t.Fatal(err)
+	}
+	systemPrompt := capturedMessages[0].Content
+	if !strings.Contains(systemPrompt, "[custom] new rule") {
+		t.Error("system prompt should contain the replaced custom rule")
+	}
+	if strings.Contains(systemPrompt, "old rule") {
+		t.Error("system prompt still contains the previous custom rule")
+	}
+	if !strings.Contains(systemPrompt, "CRITICAL SAFETY RULE") {
+		t.Error("SetRules must keep the default rules")
+	}
+}
+
 type captureLLM struct {
 	inner    LLMClient
 	captured *[]provider.Message
@@ -0,0 +1,238 @@
+package orchestrator
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/logger"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// defaultResumeMaxAge is how old a checkpoint may be and still be resumed
+// when ResumeConfig.MaxAge is unset. Past it the user has likely moved on,
+// so the session is only told its request was interrupted.
+const defaultResumeMaxAge = 15 * time.Minute
+
+// resumedRunMessageType tags the pushed reply of a resumed turn and the
+// interruption notice, so clients can tell them from a reply to a message.
+const resumedRunMessageType = "agent.resumed"
+
+// RunCheckpointStore persists agent-loop checkpoints. *store.RunCheckpointStore
+// satisfies it.
+type RunCheckpointStore interface {
+	SaveRunCheckpoint(ctx context.Context, cp *state.RunCheckpoint) error
+	DeleteRunCheckpoint(ctx context.Context, sessionID string) error
+	ListRunCheckpoints(ctx context.Context) ([]state.RunCheckpoint, error)
+}
+
+// ResumeConfig checkpoints running agent loops so turns cut off by a
+// restart are picked up by ResumeInterruptedRuns.
+type ResumeConfig struct {
+	Store      RunCheckpointStore // nil = turns are not checkpointed
+	MaxAge     time.Duration      // older checkpoints are only notified; 0 = 15m
+	NotifyOnly bool               // never re-run a turn, only tell its session it was interrupted
+}
+
+// runCheckpointer saves one turn's checkpoint at the agent loop's safe
+// points. A nil checkpointer does nothing.
+type runCheckpointer struct {
+	o     *Orchestrator
+	ctx   context.Context
+	cp    state.RunCheckpoint
+	saved bool
+}
+
+type resumedRunKey struct{}
+
+// newRunCheckpointer returns the checkpointer for a turn entering the agent
+// loop, or nil when checkpoints are off.
+func (o *Orchestrator) newRunCheckpointer(ctx context.Context, sessionID, userMessage string) *runCheckpointer {
+	if o.resume.Store == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	cp := state.RunCheckpoint{
+		SessionID:   sessionID,
+		Actor:       actor.Actor(ctx),
+		ChannelID:   currentChannelID(ctx),
+		UserMessage: userMessage,
+		StartedAt:   now,
+	}
+	if p := profile.FromContext(ctx); p != nil {
+		cp.EntityID, cp.Group = p.EntityID, p.Group
+	}
+	// A resumed turn keeps the original request and is not resumed again.
+	if prev, ok := ctx.Value(resumedRunKey{}).(state.RunCheckpoint); ok {
+		cp.UserMessage, cp.StartedAt, cp.Completed, cp.Resumed = prev.UserMessage, prev.StartedAt, prev.Completed, true
+	}
+	return &runCheckpointer{o: o, ctx: context.WithoutCancel(ctx), cp: cp}
+}
+
+func (c *runCheckpointer) round(n int) {
+	if c == nil {
+		return
+	}
+	c.cp.Iteration = n
+	c.save()
+}
+
+func (c *runCheckpointer) dispatch(call ToolCall) {
+	if c == nil {
+		return
+	}
+	c.cp.Pending = toolFQN(call.Plugin, call.Action)
+	c.save()
+}
+
+func (c *runCheckpointer) finished(call ToolCall) {
+	if c == nil {
+		return
+	}
+	c.cp.Pending = ""
+	c.cp.Completed = append(c.cp.Completed, toolFQN(call.Plugin, call.Action))
+	c.save()
+}
+
+func (c *runCheckpointer) save() {
+	c.cp.UpdatedAt = time.Now().UTC()
+	if err := c.o.resume.Store.SaveRunCheckpoint(c.ctx, &c.cp); err != nil {
+		logger.FromContext(c.ctx).Warn("saving run checkpoint failed", "error", err)
+		return
+	}
+	c.saved = true
+}
+
+// finish drops the checkpoint once the turn is over. A turn that failed
+// because the process is shutting down keeps it, to be resumed.
+func (c *runCheckpointer) finish(runErr error) {
+	if c == nil || !c.saved {
+		return
+	}
+	if runErr != nil && c.o.stopping.Load() {
+		logger.FromContext(c.ctx).Info("keeping checkpoint of turn cut off by shutdown", "session", c.cp.SessionID)
+		return
+	}
+	if err := c.o.resume.Store.DeleteRunCheckpoint(c.ctx, c.cp.SessionID); err != nil {
+		logger.FromContext(c.ctx).Warn("deleting run checkpoint failed", "error", err)
+	}
+}
+
+// BeginShutdown tells the orchestrator the process is stopping: turns that
+// fail from here on keep their checkpoints for ResumeInterruptedRuns.
+func (o *Orchestrator) BeginShutdown() {
+	o.stopping.Store(true)
+}
+
+// ResumeInterruptedRuns handles the checkpoints a previous process left
+// behind. A recent one is resumed in the background: a hidden turn tells the
+// model what was done before the restart and the reply is pushed to the
+// session's channel. An old one, one that was already resumed, and every
+// one under NotifyOnly only get a notice that the request was interrupted.
+// Each checkpoint is deleted first, so a turn that crashes again is not
+// retried forever. Call once at startup, after the channels are running.
+func (o *Orchestrator) ResumeInterruptedRuns(ctx context.Context) (resumed, notified int, err error) {
+	if o.resume.Store == nil {
+		return 0, 0, nil
+	}
+	cps, err := o.resume.Store.ListRunCheckpoints(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	maxAge := cmp.Or(o.resume.MaxAge, defaultResumeMaxAge)
+	for _, cp := range cps {
+		if err := o.resume.Store.DeleteRunCheckpoint(ctx, cp.SessionID); err != nil {
+			slog.Warn("deleting run checkpoint failed; skipping it", "session_id", cp.SessionID, "error", err)
+			continue
+		}
+		if o.resume.NotifyOnly || cp.Resumed || time.Since(cp.UpdatedAt) > maxAge {
+			o.notifyInterrupted(ctx, cp)
+			notified++
+			continue
+		}
+		go o.resumeRun(cp)
+		resumed++
+	}
+	return resumed, notified, nil
+}
+
+// checkpointContext rebuilds the identity the checkpointed turn ran under.
+func (o *Orchestrator) checkpointContext(cp state.RunCheckpoint) context.Context {
+	ctx := context.Background()
+	if cp.EntityID != "" {
+		ctx = profile.WithProfile(ctx, &profile.Profile{EntityID: cp.EntityID, Group: cp.Group, ChannelID: cp.ChannelID, Kind: profile.KindChat})
+		ctx = actor.WithGroupID(ctx, cp.Group)
+	}
+	if cp.Actor != "" {
+		ctx = actor.WithActor(ctx, cp.Actor)
+	}
+	return actor.WithSessionID(ctx, cp.SessionID)
+}
+
+func (o *Orchestrator) resumeRun(cp state.RunCheckpoint) {
+	ctx := o.checkpointContext(cp)
+	ctx = context.WithValue(ctx, resumedRunKey{}, cp)
+	// The resume instructions are for the model, not the transcript.
+	ctx = actor.WithVisibility(ctx, provider.VisibilityHidden)
+	slog.Info("resuming interrupted turn", "session_id", cp.SessionID, "iteration", cp.Iteration, "completed", len(cp.Completed))
+	res, err := o.Run(ctx, cp.SessionID, resumePrompt(cp))
+	if err != nil {
+		slog.Warn("resuming interrupted turn failed", "session_id", cp.SessionID, "error", err)
+		o.notifyInterrupted(context.Background(), cp)
+		return
+	}
+	if res != nil && res.Response != "" {
+		o.pushToSession(ctx, cp.SessionID, res.Response, resumedRunMessageType)
+	}
+}
+
+// resumePrompt tells the model where the interrupted turn stopped.
+func resumePrompt(cp state.RunCheckpoint) string {
+	var b strings.Builder
+	b.WriteString("[system] Your previous turn in this conversation was interrupted by a restart before you answered. ")
+	fmt.Fprintf(&b, "The user's request was: %q. ", cp.UserMessage)
+	if len(cp.Completed) > 0 {
+		fmt.Fprintf(&b, "These tool calls finished and their results are above: %s. Do not repeat them. ", strings.Join(cp.Completed, ", "))
+	}
+	if cp.Pending != "" {
+		fmt.Fprintf(&b, "A call to %s was running and may or may not have taken effect; check its outcome before calling it again. ", cp.Pending)
+	}
+	b.WriteString("Continue the request from where it stopped and then answer the user.")
+	return b.String()
+}
+
+// notifyInterrupted tells the session its last request was cut off.
+func (o *Orchestrator) notifyInterrupted(ctx context.Context, cp state.RunCheckpoint) {
+	locale := o.deploymentLocale(ctx)
+	if sess, err := o.sessions.Get(cp.SessionID); err == nil && sess != nil {
+		locale = cmp.Or(sess.Metadata[MetaLocale], locale)
+	}
+	msg := o.coreStringFor(withTurnLocale(ctx, o.coreLocale(locale)), msgRunInterrupted)
+	if err := o.sessions.AddMessage(cp.SessionID, provider.Message{Role: provider.RoleAssistant, Content: msg}); err != nil {
+		slog.Warn("recording interruption notice failed", "session_id", cp.SessionID, "error", err)
+	}
+	o.pushToSession(o.checkpointContext(cp), cp.SessionID, msg, resumedRunMessageType)
+}
+
+// pushToSession sends a reply produced outside an inbound message to the
+// session's channel, tagged with msgType.
+func (o *Orchestrator) pushToSession(ctx context.Context, sessionID, content, msgType string) {
+	if o.channelSender == nil {
+		return
+	}
+	err := o.channelSender(ctx, sessionID, pkgchannel.OutboundMessage{
+		Content:  content,
+		Metadata: map[string]string{"type": msgType},
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		slog.Warn("pushing reply failed", "session_id", sessionID, "type", msgType, "error", err)
+	}
+}
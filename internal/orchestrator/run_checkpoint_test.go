@@ -0,0 +1,212 @@
+package orchestrator
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// fakeCheckpoints is an in-memory RunCheckpointStore that also keeps every
+// saved snapshot.
+type fakeCheckpoints struct {
+	mu    sync.Mutex
+	items map[string]state.RunCheckpoint
+	saves []state.RunCheckpoint
+}
+
+func newFakeCheckpoints(cps ...state.RunCheckpoint) *fakeCheckpoints {
+	f := &fakeCheckpoints{items: make(map[string]state.RunCheckpoint)}
+	for _, cp := range cps {
+		f.items[cp.SessionID] = cp
+	}
+	return f
+}
+
+func (f *fakeCheckpoints) SaveRunCheckpoint(_ context.Context, cp *state.RunCheckpoint) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := *cp
+	c.Completed = slices.Clone(cp.Completed)
+	f.items[cp.SessionID] = c
+	f.saves = append(f.saves, c)
+	return nil
+}
+
+func (f *fakeCheckpoints) DeleteRunCheckpoint(_ context.Context, sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.items, sessionID)
+	return nil
+}
+
+func (f *fakeCheckpoints) ListRunCheckpoints(context.Context) ([]state.RunCheckpoint, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []state.RunCheckpoint
+	for _, cp := range f.items {
+		out = append(out, cp)
+	}
+	return out, nil
+}
+
+var checkpointParser = &fakeParser{parseFn: func(response string) []ToolCall {
+	if response == "ANALYZE" {
+		return []ToolCall{{ID: "c1", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": "api"}}}
+	}
+	return nil
+}}
+
+func TestRunCheckpoint_SavedDuringLoopAndDroppedWhenDone(t *testing.T) {
+	cps := newFakeCheckpoints()
+	llm := &fakeLLM{responses: []string{"ANALYZE", "All good."}}
+	orch, sessID := setupOrchestratorWithOpts(llm, checkpointParser, OrchestratorOpts{Resume: ResumeConfig{Store: cps}})
+
+	if _, err := orch.Run(context.Background(), sessID, "Analyze the api repo"); err != nil {
+		t.Fatal(err)
+	}
+	fqn := toolFQN("gitlab", "analyze_code")
+	var sawPending, sawCompleted bool
+	for _, cp := range cps.saves {
+		if cp.SessionID != sessID || cp.UserMessage != "Analyze the api repo" {
+			t.Fatalf("checkpoint = %+v", cp)
+		}
+		sawPending = sawPending || cp.Pending == fqn
+		sawCompleted = sawCompleted || slices.Contains(cp.Completed, fqn)
+	}
+	if !sawPending || !sawCompleted {
+		t.Errorf("saves should record the call as pending, then completed: %+v", cps.saves)
+	}
+	if last := cps.saves[len(cps.saves)-1]; last.Iteration != 2 {
+		t.Errorf("last saved iteration = %d; want 2", last.Iteration)
+	}
+	if len(cps.items) != 0 {
+		t.Errorf("a finished turn should drop its checkpoint, have %+v", cps.items)
+	}
+}
+
+func TestRunCheckpoint_KeptWhenShutdownCutsTurnOff(t *testing.T) {
+	cps := newFakeCheckpoints()
+	// The second LLM call fails, standing in for the turn being cancelled.
+	llm := &fakeLLM{responses: []string{"ANALYZE"}}
+	orch, sessID := setupOrchestratorWithOpts(llm, checkpointParser, OrchestratorOpts{Resume: ResumeConfig{Store: cps}})
+	orch.BeginShutdown()
+
+	if _, err := orch.Run(context.Background(), sessID, "Analyze the api repo"); err == nil {
+		t.Fatal("expected the turn to fail")
+	}
+	cp, ok := cps.items[sessID]
+	if !ok {
+		t.Fatal("checkpoint should survive a shutdown")
+	}
+	if cp.Iteration != 2 || len(cp.Completed) != 1 || cp.Pending != "" {
+		t.Errorf("checkpoint = %+v", cp)
+	}
+}
+
+func TestRunCheckpoint_DroppedWhenTurnFailsWhileRunning(t *testing.T) {
+	cps := newFakeCheckpoints()
+	llm := &fakeLLM{responses: []string{"ANALYZE"}}
+	orch, sessID := setupOrchestratorWithOpts(llm, checkpointParser, OrchestratorOpts{Resume: ResumeConfig{Store: cps}})
+
+	if _, err := orch.Run(context.Background(), sessID, "Analyze the api repo"); err == nil {
+		t.Fatal("expected the turn to fail")
+	}
+	if len(cps.items) != 0 {
+		t.Errorf("a failed turn outside shutdown should not be resumed, have %+v", cps.items)
+	}
+}
+
+// pushRecorder is a ChannelSender that hands every push to a channel.
+func pushRecorder() (ChannelSender, <-chan pkgchannel.OutboundMessage) {
+	ch := make(chan pkgchannel.OutboundMessage, 4)
+	return func(_ context.Context, _ string, msg pkgchannel.OutboundMessage) error {
+		ch <- msg
+		return nil
+	}, ch
+}
+
+func waitPush(t *testing.T, ch <-chan pkgchannel.OutboundMessage) pkgchannel.OutboundMessage {
+	t.Helper()
+	select {
+	case msg := <-ch:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing was pushed to the session")
+		return pkgchannel.OutboundMessage{}
+	}
+}
+
+func TestResumeInterruptedRuns_ResumesRecentTurn(t *testing.T) {
+	now := time.Now().UTC()
+	cps := newFakeCheckpoints(state.RunCheckpoint{
+		SessionID:   "test-session-obs",
+		UserMessage: "Analyze the api repo and open a PR",
+		Iteration:   2,
+		Completed:   []string{toolFQN("gitlab", "analyze_code")},
+		Pending:     toolFQN("gitlab", "create_pr"),
+		StartedAt:   now.Add(-time.Minute),
+		UpdatedAt:   now.Add(-30 * time.Second),
+	})
+	llm := &fakeLLM{responses: []string{"The PR is open."}}
+	send, pushed := pushRecorder()
+	orch, sessID := setupOrchestratorWithOpts(llm, checkpointParser, OrchestratorOpts{
+		Resume:        ResumeConfig{Store: cps},
+		ChannelSender: send,
+	})
+
+	resumed, notified, err := orch.ResumeInterruptedRuns(context.Background())
+	if err != nil || resumed != 1 || notified != 0 {
+		t.Fatalf("ResumeInterruptedRuns = %d, %d, %v; want 1 resumed", resumed, notified, err)
+	}
+	msg := waitPush(t, pushed)
+	if msg.Content != "The PR is open." || msg.Metadata["type"] != resumedRunMessageType {
+		t.Errorf("pushed %+v", msg)
+	}
+	sess, _ := orch.sessions.Get(sessID)
+	var prompt string
+	for _, m := range sess.Messages {
+		if strings.Contains(m.Content, "interrupted by a restart") {
+			prompt = m.Content
+		}
+	}
+	if !strings.Contains(prompt, "Analyze the api repo and open a PR") || !strings.Contains(prompt, toolFQN("gitlab", "create_pr")) {
+		t.Errorf("resume prompt = %q", prompt)
+	}
+}
+
+func TestResumeInterruptedRuns_NotifiesStaleOrResumedTurns(t *testing.T) {
+	for name, cp := range map[string]state.RunCheckpoint{
+		"stale":   {SessionID: "test-session-obs", UserMessage: "x", UpdatedAt: time.Now().Add(-time.Hour)},
+		"resumed": {SessionID: "test-session-obs", UserMessage: "x", UpdatedAt: time.Now(), Resumed: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			cps := newFakeCheckpoints(cp)
+			send, pushed := pushRecorder()
+			orch, sessID := setupOrchestratorWithOpts(&fakeLLM{}, checkpointParser, OrchestratorOpts{
+				Resume:        ResumeConfig{Store: cps},
+				ChannelSender: send,
+			})
+			resumed, notified, err := orch.ResumeInterruptedRuns(context.Background())
+			if err != nil || resumed != 0 || notified != 1 {
+				t.Fatalf("ResumeInterruptedRuns = %d, %d, %v; want 1 notified", resumed, notified, err)
+			}
+			want := coreStrings[msgRunInterrupted]["en"]
+			if msg := waitPush(t, pushed); msg.Content != want {
+				t.Errorf("pushed %q; want %q", msg.Content, want)
+			}
+			sess, _ := orch.sessions.Get(sessID)
+			if n := len(sess.Messages); n == 0 || sess.Messages[n-1].Content != want {
+				t.Error("the notice should be added to the session history")
+			}
+			if len(cps.items) != 0 {
+				t.Error("a handled checkpoint should be deleted")
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package orchestrator
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SecretRedaction configures masking of credentials in tool outputs. When
+// Enabled, Guard.Sanitize replaces API keys, bearer tokens, AWS credentials,
+// private keys and other high-entropy tokens with "[REDACTED:<kind>]"
+// before the output reaches the LLM or the session history.
+type SecretRedaction struct {
+	Enabled bool
+	// Allowlist keeps matches that any of these patterns finds in the
+	// matched text, e.g. `page_token` for a pagination cursor the model must
+	// pass back.
+	Allowlist []*regexp.Regexp
+}
+
+// secretPattern is one detector. When the pattern has capture groups only
+// the first one that matched is masked, so "api_key: abc" keeps its key
+// name.
+type secretPattern struct {
+	kind string
+	re   *regexp.Regexp
+	// minEntropy rejects matches whose masked part has fewer bits per
+	// character, so "token: aaaaaaaa" or a long lowercase word is not
+	// flagged by the generic patterns. 0 = no check.
+	minEntropy float64
+}
+
+var secretPatterns = []secretPattern{
+	{kind: "private_key", re: regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY-----`)},
+	{kind: "aws_access_key", re: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{kind: "aws_secret_key", re: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{kind: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{kind: "api_key", re: regexp.MustCompile(`\b(?:sk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}|gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{40,}|glpat-[A-Za-z0-9_-]{20,}|xox[abprs]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35})`)},
+	{kind: "bearer_token", re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9._~+/-]{16,}=*)`)},
+	{kind: "credential", re: regexp.MustCompile(`(?i)[a-z0-9_.-]*(?:api[_-]?key|secret|token|passw(?:or)?d|access[_-]?key|private[_-]?key)["']?\s*[:=]\s*(?:"([^"\\\s]{8,})"|'([^'\s]{8,})'|([^\s"'\\,;&}]{8,}))`), minEntropy: 2.5},
+	{kind: "high_entropy", re: regexp.MustCompile(`[A-Za-z0-9+_=-]{32,}`), minEntropy: 4},
+}
+
+const redactedPrefix = "[REDACTED:"
+
+// secretRedactor masks secrets in text.
+type secretRedactor struct {
+	allow []*regexp.Regexp
+}
+
+func newSecretRedactor(cfg SecretRedaction) *secretRedactor {
+	return &secretRedactor{allow: cfg.Allowlist}
+}
+
+// redact returns s with every detected secret masked, counting them per
+// kind in found.
+func (r *secretRedactor) redact(s string, found map[string]int) string {
+	if s == "" {
+		return s
+	}
+	for _, p := range secretPatterns {
+		s = r.apply(p, s, found)
+	}
+	return s
+}
+
+func (r *secretRedactor) apply(p secretPattern, s string, found map[string]int) string {
+	matches := p.re.FindAllStringSubmatchIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		for g := 2; g+1 < len(m); g += 2 {
+			if m[g] >= 0 {
+				start, end = m[g], m[g+1]
+				break
+			}
+		}
+		if !r.isSecret(p, s[m[0]:m[1]], s[start:end]) {
+			continue
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(redactedPrefix + p.kind + "]")
+		last = end
+		found[p.kind]++
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (r *secretRedactor) isSecret(p secretPattern, match, value string) bool {
+	if strings.Contains(value, redactedPrefix) || strings.HasPrefix(value, "REDACTED") {
+		return false // already masked by an earlier pattern
+	}
+	if p.minEntropy > 0 {
+		if isPlaceholder(value) || shannonEntropy(value) < p.minEntropy {
+			return false
+		}
+		if p.kind == "high_entropy" && !mixedClasses(value) {
+			return false // hex digests, UUIDs and identifiers
+		}
+	}
+	for _, a := range r.allow {
+		if a.MatchString(match) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPlaceholder reports values that name a secret rather than hold one:
+// "${API_KEY}", "{{env.TOKEN}}", "<token>", "xxxxxxxx".
+func isPlaceholder(v string) bool {
+	if strings.HasPrefix(v, "${") || strings.HasPrefix(v, "{{") || strings.HasPrefix(v, "<") {
+		return true
+	}
+	return strings.Trim(v, "x*X.") == ""
+}
+
+// mixedClasses reports whether v has at least two each of upper case, lower
+// case and digits, which random tokens nearly always do and CamelCase
+// identifiers rarely do.
+func mixedClasses(v string) bool {
+	var upper, lower, digit int
+	for _, c := range v {
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper++
+		case c >= 'a' && c <= 'z':
+			lower++
+		case c >= '0' && c <= '9':
+			digit++
+		}
+	}
+	return upper >= 2 && lower >= 2 && digit >= 2
+}
+
+// shannonEntropy returns the bits per character of s.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	n := 0
+	for _, c := range s {
+		counts[c]++
+		n++
+	}
+	var h float64
+	for _, c := range counts {
+		p := float64(c) / float64(n)
+		h -= p * math.Log2(p)
+	}
+	return h
+}
+
+// secretKinds renders found as "api_key=2, jwt=1" for logs.
+func secretKinds(found map[string]int) string {
+	kinds := make([]string, 0, len(found))
+	for k := range found {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for i, k := range kinds {
+		kinds[i] = k + "=" + strconv.Itoa(found[k])
+	}
+	return strings.Join(kinds, ", ")
+}
@@ -0,0 +1,188 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// Session metadata keys for branched sessions. A branch is a copy of another
+// session cut at a message index; it records where it came from, and the
+// source lists its branches, so a replayed conversation can always be put
+// next to the one it was forked from.
+const (
+	MetaBranchOf     = "branch_of"     // source session key
+	MetaBranchAt     = "branch_at"     // number of source messages copied into the branch
+	MetaBranches     = "branches"      // on the source: newline-separated branch keys
+	MetaBranchModel  = "branch_model"  // model pin for the branch's turns; wins over agent and profile pins
+	MetaBranchReplay = "branch_replay" // outcome of the last ReplayBranch, e.g. "replayed 3 turns, answers changed in 1"
+)
+
+// ErrSessionExists is returned by BranchSession when the branch key is taken.
+var ErrSessionExists = errors.New("session already exists")
+
+// BranchOptions varies a branch from its source.
+type BranchOptions struct {
+	Model        string // model pin ("provider/model" or "model"); empty = as the source
+	SystemPrompt string // replaces the session prompt layer; empty = as the source
+}
+
+// BranchSession copies the first at messages of src, with its summary and
+// metadata, into a new session dst and links the two. at may be 0 (only the
+// summary and metadata carry over) up to len(src.Messages). The new session
+// row belongs to the profile on ctx, like any session the caller opens.
+func BranchSession(ctx context.Context, store SessionStoreInterface, src, dst string, at int, opts BranchOptions) error {
+	source, err := store.Get(src)
+	if err != nil {
+		return err
+	}
+	if at < 0 || at > len(source.Messages) {
+		return fmt.Errorf("message index %d is out of range (the session has %d messages)", at, len(source.Messages))
+	}
+	if _, err := store.Get(dst); err == nil {
+		return fmt.Errorf("%w: %s", ErrSessionExists, dst)
+	} else if !errors.Is(err, state.ErrSessionNotFound) {
+		return err
+	}
+
+	var entityID, groupID, kind string
+	if p := profile.FromContext(ctx); p != nil {
+		entityID, groupID, kind = p.EntityID, p.Group, p.Kind
+	}
+	store.Create(dst, entityID, groupID, kind)
+	messages := make([]provider.Message, at)
+	copy(messages, source.Messages[:at])
+	if err := store.SetSummary(dst, source.Summary, messages); err != nil {
+		return err
+	}
+	if source.ActiveModel != "" {
+		if err := store.SetModel(dst, source.ActiveModel); err != nil {
+			return err
+		}
+	}
+	for key, value := range source.Metadata {
+		if key == MetaBranches {
+			continue
+		}
+		if err := store.SetMetadata(dst, key, value); err != nil {
+			return err
+		}
+	}
+	meta := map[string]string{MetaBranchOf: src, MetaBranchAt: strconv.Itoa(at)}
+	if opts.Model != "" {
+		meta[MetaBranchModel] = opts.Model
+	}
+	if opts.SystemPrompt != "" {
+		meta[MetaSystemPrompt] = strings.TrimSpace(opts.SystemPrompt)
+	}
+	for key, value := range meta {
+		if err := store.SetMetadata(dst, key, value); err != nil {
+			return err
+		}
+	}
+	branches := source.Metadata[MetaBranches]
+	if branches != "" {
+		branches += "\n"
+	}
+	return store.SetMetadata(src, MetaBranches, branches+dst)
+}
+
+// Branches returns the keys of the sessions branched off sess, oldest first.
+func Branches(sess *state.Session) []string {
+	if sess == nil || sess.Metadata[MetaBranches] == "" {
+		return nil
+	}
+	return strings.Split(sess.Metadata[MetaBranches], "\n")
+}
+
+// ReplayTurn is one user message of a replayed branch with the answer the
+// source session gave and the one the branch gives now.
+type ReplayTurn struct {
+	Input    string
+	Original string
+	Replayed string
+}
+
+// Changed reports whether the replayed answer differs from the original.
+func (t ReplayTurn) Changed() bool {
+	return strings.TrimSpace(t.Original) != strings.TrimSpace(t.Replayed)
+}
+
+// ReplayBranch runs the user messages that follow the branch point in the
+// source session against the branch, one turn each, so the branch's model or
+// prompt variant answers the same conversation. Turns stop at the first
+// error; the turns replayed so far are returned with it. The outcome is
+// recorded on the branch under MetaBranchReplay.
+func (o *Orchestrator) ReplayBranch(ctx context.Context, branchID string) (turns []ReplayTurn, err error) {
+	branch, err := o.sessions.Get(branchID)
+	if err != nil {
+		return nil, err
+	}
+	src := branch.Metadata[MetaBranchOf]
+	if src == "" {
+		return nil, fmt.Errorf("session %s is not a branch", branchID)
+	}
+	at, err := strconv.Atoi(branch.Metadata[MetaBranchAt])
+	if err != nil {
+		return nil, fmt.Errorf("session %s: bad %s: %w", branchID, MetaBranchAt, err)
+	}
+	source, err := o.sessions.Get(src)
+	if err != nil {
+		return nil, fmt.Errorf("source session %s: %w", src, err)
+	}
+	if at > len(source.Messages) {
+		at = len(source.Messages)
+	}
+
+	pending := userTurns(source.Messages[at:])
+	defer func() {
+		if serr := o.sessions.SetMetadata(branchID, MetaBranchReplay, replayOutcome(turns, len(pending), err)); serr != nil {
+			slog.Warn("recording replay outcome failed", "session_id", branchID, "error", serr)
+		}
+	}()
+	for _, t := range pending {
+		res, err := o.Run(ctx, branchID, t.Input)
+		if err != nil {
+			return turns, fmt.Errorf("replay %q: %w", t.Input, err)
+		}
+		t.Replayed = res.Response
+		turns = append(turns, t)
+	}
+	return turns, nil
+}
+
+// replayOutcome summarizes a replay for MetaBranchReplay.
+func replayOutcome(turns []ReplayTurn, total int, err error) string {
+	if err != nil {
+		return fmt.Sprintf("failed after %d of %d turns: %v", len(turns), total, err)
+	}
+	changed := 0
+	for _, t := range turns {
+		if t.Changed() {
+			changed++
+		}
+	}
+	return fmt.Sprintf("replayed %d turns, answers changed in %d", len(turns), changed)
+}
+
+// userTurns splits messages into the visible user messages and the last
+// plain assistant answer that followed each one.
+func userTurns(messages []provider.Message) []ReplayTurn {
+	var turns []ReplayTurn
+	for _, m := range messages {
+		switch {
+		case m.Role == provider.RoleUser && m.Visibility != provider.VisibilityHidden:
+			turns = append(turns, ReplayTurn{Input: m.Content})
+		case m.Role == provider.RoleAssistant && len(m.ToolCalls) == 0 && len(turns) > 0:
+			turns[len(turns)-1].Original = m.Content
+		}
+	}
+	return turns
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func seedConversation(t *testing.T, store *state.SessionStore, id string) {
+	t.Helper()
+	store.Create(id, "", "", "")
+	for _, m := range []provider.Message{
+		{Role: provider.RoleUser, Content: "what is 2+2?"},
+		{Role: provider.RoleAssistant, Content: "4"},
+		{Role: provider.RoleUser, Content: "and times 3?"},
+		{Role: provider.RoleAssistant, ToolCalls: []provider.ToolCall{{ID: "c1", Name: "calc"}}},
+		{Role: provider.RoleTool, ToolCallID: "c1", Content: "12"},
+		{Role: provider.RoleAssistant, Content: "12"},
+	} {
+		if err := store.AddMessage(id, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = store.SetMetadata(id, MetaPinnedFacts, "likes math")
+}
+
+func TestBranchSession(t *testing.T) {
+	store := state.NewSessionStore("")
+	seedConversation(t, store, "web:c1")
+	ctx := context.Background()
+
+	if err := BranchSession(ctx, store, "web:c1", "web:c1:branch-1", 2, BranchOptions{Model: "openai/gpt-x", SystemPrompt: "Be terse."}); err != nil {
+		t.Fatal(err)
+	}
+	b := mustSession(t, store, "web:c1:branch-1")
+	if len(b.Messages) != 2 || b.Messages[1].Content != "4" {
+		t.Errorf("branch messages = %+v", b.Messages)
+	}
+	for key, want := range map[string]string{
+		MetaBranchOf: "web:c1", MetaBranchAt: "2", MetaBranchModel: "openai/gpt-x",
+		MetaSystemPrompt: "Be terse.", MetaPinnedFacts: "likes math",
+	} {
+		if b.Metadata[key] != want {
+			t.Errorf("branch %s = %q; want %q", key, b.Metadata[key], want)
+		}
+	}
+	if err := BranchSession(ctx, store, "web:c1", "web:c1:branch-2", 0, BranchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := Branches(mustSession(t, store, "web:c1")); strings.Join(got, ",") != "web:c1:branch-1,web:c1:branch-2" {
+		t.Errorf("branches = %v", got)
+	}
+	if _, ok := mustSession(t, store, "web:c1:branch-2").Metadata[MetaBranches]; ok {
+		t.Error("the source's branch list must not be copied")
+	}
+
+	if err := BranchSession(ctx, store, "web:c1", "web:c1:branch-1", 1, BranchOptions{}); !errors.Is(err, ErrSessionExists) {
+		t.Errorf("taken key: %v", err)
+	}
+	if err := BranchSession(ctx, store, "web:c1", "web:c1:x", 7, BranchOptions{}); err == nil {
+		t.Error("an index past the end should fail")
+	}
+	if err := BranchSession(ctx, store, "missing", "missing:b", 0, BranchOptions{}); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("missing source: %v", err)
+	}
+}
+
+func TestReplayBranch(t *testing.T) {
+	store := state.NewSessionStore("")
+	seedConversation(t, store, "web:c1")
+	llm := &requestLLM{}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(),
+		state.NewMemoryStore(""), store, OrchestratorOpts{})
+	ctx := context.Background()
+
+	if err := BranchSession(ctx, store, "web:c1", "web:c1:branch-1", 0, BranchOptions{Model: "anthropic/claude-test"}); err != nil {
+		t.Fatal(err)
+	}
+	turns, err := orch.ReplayBranch(ctx, "web:c1:branch-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(turns) != 2 || turns[0].Input != "what is 2+2?" || turns[1].Original != "12" || turns[1].Replayed != "ok" {
+		t.Fatalf("turns = %+v", turns)
+	}
+	if len(llm.requests) != 2 || llm.requests[0].Model != "claude-test" {
+		t.Errorf("replay should use the branch model, got %d requests, model %q", len(llm.requests), llm.requests[0].Model)
+	}
+	b := mustSession(t, store, "web:c1:branch-1")
+	if got := b.Metadata[MetaBranchReplay]; got != "replayed 2 turns, answers changed in 2" {
+		t.Errorf("outcome = %q", got)
+	}
+	if len(mustSession(t, store, "web:c1").Messages) != 6 {
+		t.Error("replay must not touch the source session")
+	}
+
+	if _, err := orch.ReplayBranch(ctx, "web:c1"); err == nil {
+		t.Error("replaying a session that is not a branch should fail")
+	}
+}
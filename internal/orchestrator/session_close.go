@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+)
+
+// MetaClosedAt records (RFC 3339) when an idle session was closed. The next
+// turn removes it: the conversation goes on in a fresh session seeded with
+// the closed one's summary.
+const MetaClosedAt = "closed_at"
+
+// ErrSessionNotSummarized is returned by CloseSession when the final
+// summary could not be written; the session is left open.
+var ErrSessionNotSummarized = errors.New("session summary could not be generated")
+
+// CloseSession ends a conversation that went quiet. The whole transcript is
+// folded into the session summary, the messages are dropped and the session
+// is marked closed, so the next message starts from an empty history that
+// knows only the summary. A session without messages is just marked. It
+// takes the session's turn lock, like a turn.
+func (o *Orchestrator) CloseSession(ctx context.Context, sessionID string) error {
+	unlock, err := o.lockSessionTurn(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	sess, err := o.sessions.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	if sess.Metadata[MetaClosedAt] != "" {
+		return nil
+	}
+	ctx = actor.WithSessionID(ctx, sessionID)
+	if len(sess.Messages) > 0 && !o.foldIntoSummary(ctx, sessionID, sess, 0, "session_closed") {
+		return ErrSessionNotSummarized
+	}
+	return o.sessions.SetMetadata(sessionID, MetaClosedAt, time.Now().UTC().Format(time.RFC3339))
+}
+
+// reopenSession clears the closed mark when a closed session gets a new
+// turn. Its history is already empty; only the summary carries over.
+func reopenSession(sessions SessionStoreInterface, sessionID string, meta map[string]string) {
+	if meta[MetaClosedAt] == "" {
+		return
+	}
+	if err := sessions.SetMetadata(sessionID, MetaClosedAt, ""); err != nil {
+		slog.Warn("reopening closed session failed", "session", sessionID, "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestCloseSessionSeedsNextTurnWithSummary(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	for _, m := range []string{"order 42 is late", "it ships tomorrow"} {
+		_ = sessions.AddMessage("web:c1", provider.Message{Role: provider.RoleUser, Content: m})
+	}
+	llm := &capturingLLM{responses: []string{"Order 42 ships tomorrow.", "Hello again."}}
+	orch := NewWithRules(llm, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+
+	if err := orch.CloseSession(context.Background(), "web:c1"); err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := sessions.Get("web:c1")
+	if sess.Summary != "Order 42 ships tomorrow." || len(sess.Messages) != 0 || sess.Metadata[MetaClosedAt] == "" {
+		t.Fatalf("closed session: summary %q, %d messages, metadata %v", sess.Summary, len(sess.Messages), sess.Metadata)
+	}
+	// Closing again is a no-op: no second summary call.
+	if err := orch.CloseSession(context.Background(), "web:c1"); err != nil || len(llm.requests) != 1 {
+		t.Fatalf("second close: %v after %d LLM calls", err, len(llm.requests))
+	}
+
+	if _, err := orch.Run(context.Background(), "web:c1", "any news?"); err != nil {
+		t.Fatal(err)
+	}
+	sess, _ = sessions.Get("web:c1")
+	if sess.Metadata[MetaClosedAt] != "" {
+		t.Error("the next turn did not reopen the session")
+	}
+	req := llm.requests[1]
+	if !strings.Contains(req.Messages[0].Content, "Order 42 ships tomorrow.") {
+		t.Error("the fresh session does not carry the summary")
+	}
+	for _, m := range req.Messages[1:] {
+		if strings.Contains(m.Content, "order 42 is late") {
+			t.Errorf("the closed transcript leaked into the next turn: %q", m.Content)
+		}
+	}
+}
+
+func TestCloseSessionKeepsSessionOpenWithoutSummary(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("web:c1", "", "", "")
+	_ = sessions.AddMessage("web:c1", provider.Message{Role: provider.RoleUser, Content: "hi"})
+	orch := NewWithRules(&fakeLLM{}, DefaultParser, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+
+	if err := orch.CloseSession(context.Background(), "web:c1"); !errors.Is(err, ErrSessionNotSummarized) {
+		t.Fatalf("CloseSession = %v, want ErrSessionNotSummarized", err)
+	}
+	sess, _ := sessions.Get("web:c1")
+	if len(sess.Messages) != 1 || sess.Metadata[MetaClosedAt] != "" {
+		t.Errorf("a failed close changed the session: %d messages, metadata %v", len(sess.Messages), sess.Metadata)
+	}
+}
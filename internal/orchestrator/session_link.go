@@ -0,0 +1,162 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// Session metadata keys for linked sessions. A child session (e.g. a Slack
+// thread spun off a channel conversation) records its parent's session key;
+// every turn of the child then sees the parent's summary and pinned facts in
+// its system block. Linking is one level deep: a parent's own parent is not
+// followed, so a chain of threads never fans out into unbounded context.
+const (
+	MetaParentSession = "parent_session"
+	MetaPinnedFacts   = "pinned_facts" // newline-separated; see PinFact
+)
+
+const (
+	maxPinnedFacts      = 20
+	maxPinnedFactBytes  = 500
+	linkedRecentTurns   = 6   // parent turns carried over when it has no summary yet
+	linkedTurnMaxRunes  = 300 // per carried-over turn
+	linkedContextHeader = "Context carried over from the linked parent conversation"
+)
+
+// ErrSessionLinkCycle is returned by LinkSessions when the parent is the
+// child itself or is already linked to the child.
+var ErrSessionLinkCycle = errors.New("cannot link a session to itself or to its own child")
+
+// LinkSessions records parent as the linked parent of child. Both sessions
+// must exist. When onlyIfUnset is true an existing link is kept (used for
+// automatic thread linking, so a manual /link is never clobbered by the next
+// thread message); otherwise it is replaced. An empty parent removes the link.
+func LinkSessions(store SessionStoreInterface, child, parent string, onlyIfUnset bool) error {
+	c, err := store.Get(child)
+	if err != nil {
+		return err
+	}
+	if parent == "" {
+		return store.SetMetadata(child, MetaParentSession, "")
+	}
+	if onlyIfUnset && c.Metadata[MetaParentSession] != "" {
+		return nil
+	}
+	if parent == child {
+		return ErrSessionLinkCycle
+	}
+	p, err := store.Get(parent)
+	if err != nil {
+		return err
+	}
+	if p.Metadata[MetaParentSession] == child {
+		return ErrSessionLinkCycle
+	}
+	return store.SetMetadata(child, MetaParentSession, parent)
+}
+
+// PinnedFacts returns the facts pinned on sess, oldest first.
+func PinnedFacts(sess *state.Session) []string {
+	if sess == nil || sess.Metadata[MetaPinnedFacts] == "" {
+		return nil
+	}
+	return strings.Split(sess.Metadata[MetaPinnedFacts], "\n")
+}
+
+// PinFact appends fact to the session's pinned facts. Pinned facts are shown
+// to the model on every turn of the session and of sessions linked to it.
+func PinFact(store SessionStoreInterface, sessionID, fact string) error {
+	fact = strings.Join(strings.Fields(fact), " ")
+	if fact == "" {
+		return errors.New("fact is empty")
+	}
+	if len(fact) > maxPinnedFactBytes {
+		return fmt.Errorf("fact exceeds %d bytes", maxPinnedFactBytes)
+	}
+	sess, err := store.Get(sessionID)
+	if err != nil {
+		return err
+	}
+	facts := PinnedFacts(sess)
+	if slices.Contains(facts, fact) {
+		return nil
+	}
+	if len(facts) >= maxPinnedFacts {
+		return fmt.Errorf("at most %d facts can be pinned; unpin one first", maxPinnedFacts)
+	}
+	return store.SetMetadata(sessionID, MetaPinnedFacts, strings.Join(append(facts, fact), "\n"))
+}
+
+// UnpinFacts removes every pinned fact from the session.
+func UnpinFacts(store SessionStoreInterface, sessionID string) error {
+	return store.SetMetadata(sessionID, MetaPinnedFacts, "")
+}
+
+// linkedContext renders the pinned facts of sess and, when sess is linked,
+// its parent's summary (or last few turns while the parent has none) and
+// pinned facts. Returns "" when there is nothing to add. A parent that has
+// since been deleted is skipped silently.
+func (o *Orchestrator) linkedContext(sess *state.Session) string {
+	facts := PinnedFacts(sess)
+	var parentCtx string
+	if parentID := sess.Metadata[MetaParentSession]; parentID != "" {
+		if parent, err := o.sessions.Get(parentID); err == nil && parent != nil {
+			for _, f := range PinnedFacts(parent) {
+				if !slices.Contains(facts, f) {
+					facts = append(facts, f)
+				}
+			}
+			parentCtx = parentDigest(parent)
+		}
+	}
+
+	var sb strings.Builder
+	if parentCtx != "" {
+		fmt.Fprintf(&sb, "\n\n%s: %s", linkedContextHeader, parentCtx)
+	}
+	if len(facts) > 0 {
+		sb.WriteString("\n\nPinned facts (treat as established for this conversation):")
+		for _, f := range facts {
+			sb.WriteString("\n- ")
+			sb.WriteString(f)
+		}
+	}
+	return sb.String()
+}
+
+// parentDigest is the parent's summary, or its most recent user/assistant
+// turns when summarization has not run yet.
+func parentDigest(parent *state.Session) string {
+	if parent.Summary != "" {
+		return parent.Summary
+	}
+	var turns []string
+	for i := len(parent.Messages) - 1; i >= 0 && len(turns) < linkedRecentTurns; i-- {
+		m := parent.Messages[i]
+		if (m.Role != provider.RoleUser && m.Role != provider.RoleAssistant) || strings.TrimSpace(m.Content) == "" {
+			continue
+		}
+		turns = append(turns, fmt.Sprintf("%s: %s", m.Role, truncateRunes(strings.TrimSpace(m.Content), linkedTurnMaxRunes)))
+	}
+	if len(turns) == 0 {
+		return ""
+	}
+	for i, j := 0, len(turns)-1; i < j; i, j = i+1, j-1 {
+		turns[i], turns[j] = turns[j], turns[i]
+	}
+	return "\n" + strings.Join(turns, "\n")
+}
+
+// truncateRunes cuts s to at most n runes, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,121 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestLinkSessions(t *testing.T) {
+	store := state.NewSessionStore("")
+	store.Create("slack:C1", "", "", "")
+	store.Create("slack:C1:t1", "", "", "")
+	store.Create("slack:C2", "", "", "")
+
+	if err := LinkSessions(store, "slack:C1:t1", "slack:C1", true); err != nil {
+		t.Fatal(err)
+	}
+	// Automatic linking keeps an existing link.
+	if err := LinkSessions(store, "slack:C1:t1", "slack:C2", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, store, "slack:C1:t1").Metadata[MetaParentSession]; got != "slack:C1" {
+		t.Errorf("parent = %q; onlyIfUnset must not replace the link", got)
+	}
+	// A manual link replaces it.
+	if err := LinkSessions(store, "slack:C1:t1", "slack:C2", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, store, "slack:C1:t1").Metadata[MetaParentSession]; got != "slack:C2" {
+		t.Errorf("parent = %q; want slack:C2", got)
+	}
+
+	if err := LinkSessions(store, "slack:C2", "slack:C1:t1", false); !errors.Is(err, ErrSessionLinkCycle) {
+		t.Errorf("linking a parent to its child: err = %v; want ErrSessionLinkCycle", err)
+	}
+	if err := LinkSessions(store, "slack:C1", "slack:C1", false); !errors.Is(err, ErrSessionLinkCycle) {
+		t.Errorf("self link: err = %v; want ErrSessionLinkCycle", err)
+	}
+	if err := LinkSessions(store, "slack:C1", "slack:missing", false); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("missing parent: err = %v; want ErrSessionNotFound", err)
+	}
+	if err := LinkSessions(store, "slack:C1:t1", "", false); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustSession(t, store, "slack:C1:t1").Metadata[MetaParentSession]; got != "" {
+		t.Errorf("empty parent should unlink, got %q", got)
+	}
+}
+
+func TestPinFact(t *testing.T) {
+	store := state.NewSessionStore("")
+	store.Create("s1", "", "", "")
+	for _, f := range []string{"Budget is  $40k", "Budget is $40k", "Launch is in May"} {
+		if err := PinFact(store, "s1", f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got := PinnedFacts(mustSession(t, store, "s1"))
+	if len(got) != 2 || got[0] != "Budget is $40k" || got[1] != "Launch is in May" {
+		t.Errorf("facts = %q; want normalized, de-duplicated, in order", got)
+	}
+	if err := PinFact(store, "s1", "   "); err == nil {
+		t.Error("empty fact should be rejected")
+	}
+	if err := UnpinFacts(store, "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := PinnedFacts(mustSession(t, store, "s1")); len(got) != 0 {
+		t.Errorf("facts after unpin = %q", got)
+	}
+}
+
+func TestLinkedSessionContextReachesLLM(t *testing.T) {
+	sessions := state.NewSessionStore("")
+	sessions.Create("slack:C1", "", "", "")
+	_ = sessions.AddMessage("slack:C1", provider.Message{Role: provider.RoleUser, Content: "We are planning the Q3 offsite in Lisbon."})
+	_ = sessions.AddMessage("slack:C1", provider.Message{Role: provider.RoleAssistant, Content: "Noted, Lisbon for Q3."})
+	_ = PinFact(sessions, "slack:C1", "Budget is $40k")
+	sessions.Create("slack:C1:t1", "", "", "")
+	if err := LinkSessions(sessions, "slack:C1:t1", "slack:C1", true); err != nil {
+		t.Fatal(err)
+	}
+
+	var captured []provider.Message
+	llm := &captureLLM{inner: &fakeLLM{responses: []string{"ok"}}, captured: &captured}
+	orch := NewWithRules(llm, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), sessions, OrchestratorOpts{})
+	if _, err := orch.Run(context.Background(), "slack:C1:t1", "which hotel?"); err != nil {
+		t.Fatal(err)
+	}
+	sys := captured[0].Content
+	for _, want := range []string{linkedContextHeader, "offsite in Lisbon", "Budget is $40k"} {
+		if !strings.Contains(sys, want) {
+			t.Errorf("system block missing %q:\n%s", want, sys)
+		}
+	}
+
+	// With a summary, the summary replaces the raw recent turns.
+	_ = sessions.SetSummary("slack:C1", "Team offsite: Lisbon, Q3.", nil)
+	captured = nil
+	llm.inner = &fakeLLM{responses: []string{"ok"}}
+	if _, err := orch.Run(context.Background(), "slack:C1:t1", "and dates?"); err != nil {
+		t.Fatal(err)
+	}
+	sys = captured[0].Content
+	if !strings.Contains(sys, "Team offsite: Lisbon, Q3.") || strings.Contains(sys, "Noted, Lisbon") {
+		t.Errorf("want the parent's summary instead of its turns:\n%s", sys)
+	}
+}
+
+func mustSession(t *testing.T, store *state.SessionStore, id string) *state.Session {
+	t.Helper()
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -3,6 +3,9 @@ package orchestrator
 import (
 	"context"
 	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -422,3 +425,52 @@ func TestRun_AudioFileTranscribedBeforeLLM(t *testing.T) {
 		t.Error("transcript should appear in LLM messages")
 	}
 }
+
+// fakeTranscriber is a built-in Transcriber returning a fixed transcript or error.
+type fakeTranscriber struct {
+	transcript string
+	err        error
+	got        []byte
+}
+
+func (f *fakeTranscriber) Transcribe(_ context.Context, data []byte, _, _ string) (string, error) {
+	f.got = data
+	return f.transcript, f.err
+}
+
+func TestRunSTTPreparers_BuiltInTranscriber(t *testing.T) {
+	tr := &fakeTranscriber{transcript: "book a table"}
+	o := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{Transcriber: tr})
+
+	content, files := o.runSTTPreparers(context.Background(), "", []provider.MessageFile{audioFile("audio/ogg", []byte("voice"))})
+	if content != "book a table" || len(files) != 0 {
+		t.Errorf("content %q, %d files; want the transcript and no audio", content, len(files))
+	}
+}
+
+func TestRunSTTPreparers_TranscriberFailureFallsBackToPreparer(t *testing.T) {
+	prep := ContentPreparerEntry{Plugin: "stt", Action: "transcribe", STT: true}
+	o := newSTTOrchestrator(&sttExecutor{transcript: "from plugin"}, prep)
+	o.transcriber = &fakeTranscriber{err: errors.New("503")}
+
+	content, files := o.runSTTPreparers(context.Background(), "", []provider.MessageFile{audioFile("audio/ogg", []byte("voice"))})
+	if content != "from plugin" || len(files) != 0 {
+		t.Errorf("content %q, %d files; want the plugin transcript", content, len(files))
+	}
+}
+
+func TestRunSTTPreparers_DownloadsLinkedVoiceNote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ogg bytes"))
+	}))
+	defer srv.Close()
+	tr := &fakeTranscriber{transcript: "hello"}
+	o := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, NewToolRegistry(), state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{Transcriber: tr})
+
+	content, _ := o.runSTTPreparers(context.Background(), "", []provider.MessageFile{{MimeType: "audio/ogg", URL: srv.URL + "/note.ogg"}})
+	if content != "hello" || string(tr.got) != "ogg bytes" {
+		t.Errorf("content %q, transcribed %q", content, tr.got)
+	}
+}
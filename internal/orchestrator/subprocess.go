@@ -253,10 +253,13 @@ func (o *Orchestrator) runSubprocess(ctx context.Context, req subprocessRequest,
 			return result, nil
 		}
 
-		resp, err := o.llm.Complete(ctx, &provider.CompletionRequest{Messages: guardedMessages})
+		llmReq := &provider.CompletionRequest{Messages: guardedMessages}
+		o.generationFor(ctx, TaskSubagent).apply(llmReq)
+		resp, err := o.llm.Complete(ctx, llmReq)
 		if err != nil {
 			return nil, fmt.Errorf("subprocess LLM: %w", err)
 		}
+		recordUsage(ctx, resp)
 
 		calls := o.parser.Parse(resp.Content)
 		if calls == nil {
@@ -356,7 +359,7 @@ func (o *Orchestrator) buildSubprocessSystemPrompt(ctx context.Context, req subp
 			continue
 		}
 
-		fmt.Fprintf(&sb, "## %s\n%s\n", cap.Name, cap.Description)
+		fmt.Fprintf(&sb, "## %s\n%s\n", cap.heading(), cap.Description)
 		for _, action := range visibleActions {
 			fmt.Fprintf(&sb, "- %s: %s\n", toolFQN(cap.Name, action.Name), action.Description)
 			for _, p := range action.Parameters {
@@ -364,7 +367,7 @@ func (o *Orchestrator) buildSubprocessSystemPrompt(ctx context.Context, req subp
 				if p.Required {
 					reqMark = " (required)"
 				}
-				fmt.Fprintf(&sb, "  - %s: %s%s\n", p.Name, p.Description, reqMark)
+				fmt.Fprintf(&sb, "  - %s%s: %s%s\n", p.Name, p.typeNote(), p.Description, reqMark)
 			}
 		}
 		sb.WriteString("\n")
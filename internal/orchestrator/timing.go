@@ -1,20 +1,58 @@
 package orchestrator
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/opentalon/opentalon/internal/logger"
 )
 
+// RunTiming is where one Run spent its wall-clock time, so slowness can be
+// pinned on the model, a plugin or the core. Phases that did not run are
+// zero.
+type RunTiming struct {
+	Total time.Duration
+	// FirstToken is the time from the start of Run to the first streamed
+	// token of the answer; 0 when the answer was not streamed.
+	FirstToken time.Duration
+	Preparers  time.Duration   // STT, content preparers and guards before the first LLM call
+	Planner    time.Duration   // pipeline planning and server-side step execution
+	LLMRounds  []time.Duration // each agent-loop LLM call, in order
+	ToolCalls  []ToolTiming    // each plugin call the turn dispatched, in order
+	Format     time.Duration   // response moderators and formatters
+}
+
+// ToolTiming is the duration of one plugin call.
+type ToolTiming struct {
+	Plugin   string
+	Action   string
+	Duration time.Duration
+	Failed   bool
+}
+
+// TimingObserver receives timing for metrics. ObserveRunTiming is called
+// once per Run (channel is the channel id, "" when unknown);
+// ObserveSummarization after each background summarization pass.
+type TimingObserver interface {
+	ObserveRunTiming(channel string, t RunTiming)
+	ObserveSummarization(d time.Duration)
+}
+
 // runTiming tracks wall-clock durations for the major phases of a Run() call.
-// Zero-cost when not used: callers only call methods when debug is active.
+// Phases are sequential; tool calls and the first token are recorded
+// separately because they may come from other goroutines.
 type runTiming struct {
 	start  time.Time
 	phases []timedPhase
 	active string
 	phaseT time.Time
+
+	mu         sync.Mutex // guards firstToken and tools
+	firstToken time.Duration
+	tools      []ToolTiming
 }
 
 type timedPhase struct {
@@ -27,6 +65,18 @@ func newRunTiming() *runTiming {
 	return &runTiming{start: now, phaseT: now}
 }
 
+type runTimingKey struct{}
+
+func withRunTiming(ctx context.Context, t *runTiming) context.Context {
+	return context.WithValue(ctx, runTimingKey{}, t)
+}
+
+// runTimingFrom returns the Run's timing, nil outside Run.
+func runTimingFrom(ctx context.Context) *runTiming {
+	t, _ := ctx.Value(runTimingKey{}).(*runTiming)
+	return t
+}
+
 // begin marks the start of a named phase. If a previous phase was active,
 // it is automatically ended.
 func (t *runTiming) begin(name string) {
@@ -48,17 +98,62 @@ func (t *runTiming) end() {
 	t.phaseT = time.Now()
 }
 
-// log emits the timing breakdown as an Info-level log line.
-func (t *runTiming) log(log *logger.Logger) {
-	t.end() // close any active phase
-	total := time.Since(t.start)
+// markFirstToken records the first streamed token; later calls are no-ops.
+func (t *runTiming) markFirstToken() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if t.firstToken == 0 {
+		t.firstToken = time.Since(t.start)
+	}
+	t.mu.Unlock()
+}
+
+func (t *runTiming) recordTool(call ToolCall, d time.Duration, failed bool) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.tools = append(t.tools, ToolTiming{Plugin: call.Plugin, Action: call.Action, Duration: d, Failed: failed})
+	t.mu.Unlock()
+}
+
+// snapshot closes the active phase and sums the phases into a RunTiming.
+func (t *runTiming) snapshot() RunTiming {
+	t.end()
+	rt := RunTiming{Total: time.Since(t.start)}
+	for _, p := range t.phases {
+		switch {
+		case p.name == "preparers":
+			rt.Preparers += p.duration
+		case p.name == "planner":
+			rt.Planner += p.duration
+		case p.name == "format":
+			rt.Format += p.duration
+		case strings.HasPrefix(p.name, "llm_round_"):
+			rt.LLMRounds = append(rt.LLMRounds, p.duration)
+		}
+	}
+	t.mu.Lock()
+	rt.FirstToken = t.firstToken
+	rt.ToolCalls = append([]ToolTiming(nil), t.tools...)
+	t.mu.Unlock()
+	return rt
+}
 
+// log emits the timing breakdown as an Info-level log line.
+func (t *runTiming) log(log *logger.Logger, rt RunTiming) {
 	var parts []string
 	for _, p := range t.phases {
 		parts = append(parts, fmt.Sprintf("%s=%s", p.name, p.duration.Round(time.Millisecond)))
 	}
-
-	log.Info("run timing",
-		"total", total.Round(time.Millisecond).String(),
-		"breakdown", strings.Join(parts, " "))
+	args := []any{
+		"total", rt.Total.Round(time.Millisecond).String(),
+		"breakdown", strings.Join(parts, " "),
+	}
+	if rt.FirstToken > 0 {
+		args = append(args, "first_token", rt.FirstToken.Round(time.Millisecond).String())
+	}
+	log.Info("run timing", args...)
 }
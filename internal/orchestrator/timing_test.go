@@ -0,0 +1,92 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type recordingTimingObserver struct {
+	channel string
+	runs    []RunTiming
+}
+
+func (r *recordingTimingObserver) ObserveRunTiming(channel string, t RunTiming) {
+	r.channel = channel
+	r.runs = append(r.runs, t)
+}
+
+func (r *recordingTimingObserver) ObserveSummarization(time.Duration) {}
+
+func TestRunTiming_ReportedOnResultAndObserver(t *testing.T) {
+	llm := &fakeLLM{responses: []string{
+		"[tool] gitlab.analyze_code repo=myrepo",
+		"The code looks good!",
+	}}
+	calls := 0
+	parser := &fakeParser{parseFn: func(string) []ToolCall {
+		calls++
+		if calls == 1 {
+			return []ToolCall{{ID: "call_1", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": "myrepo"}}}
+		}
+		return nil
+	}}
+	orch, sessID := setupOrchestrator(llm, parser)
+	obs := &recordingTimingObserver{}
+	orch.timingObserver = obs
+
+	result, err := orch.Run(context.Background(), sessID, "Analyze myrepo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := result.Timing
+	if rt == nil {
+		t.Fatal("RunResult.Timing not set")
+	}
+	if len(rt.LLMRounds) != 2 {
+		t.Errorf("LLM rounds = %d, want 2", len(rt.LLMRounds))
+	}
+	if len(rt.ToolCalls) != 1 || rt.ToolCalls[0].Plugin != "gitlab" || rt.ToolCalls[0].Action != "analyze_code" || rt.ToolCalls[0].Failed {
+		t.Errorf("tool timings = %+v", rt.ToolCalls)
+	}
+	var parts time.Duration
+	for _, d := range rt.LLMRounds {
+		parts += d
+	}
+	if rt.Total <= 0 || rt.Total < parts {
+		t.Errorf("total %s should cover the LLM rounds (%s)", rt.Total, parts)
+	}
+	if len(obs.runs) != 1 {
+		t.Fatalf("observer saw %d runs, want 1", len(obs.runs))
+	}
+	if obs.runs[0].Total != rt.Total {
+		t.Error("observer and RunResult should see the same timing")
+	}
+}
+
+func TestRunTiming_Snapshot(t *testing.T) {
+	rt := newRunTiming()
+	rt.begin("preparers")
+	rt.begin("llm_round_1")
+	rt.begin("llm_round_2")
+	rt.begin("format")
+	rt.recordTool(ToolCall{Plugin: "jira", Action: "search"}, 5*time.Millisecond, true)
+	rt.markFirstToken()
+	first := rt.firstToken
+	rt.markFirstToken()
+
+	got := rt.snapshot()
+	if len(got.LLMRounds) != 2 {
+		t.Errorf("LLM rounds = %d, want 2", len(got.LLMRounds))
+	}
+	if got.FirstToken != first || got.FirstToken <= 0 {
+		t.Errorf("first token = %s, want the first mark %s", got.FirstToken, first)
+	}
+	if len(got.ToolCalls) != 1 || !got.ToolCalls[0].Failed || got.ToolCalls[0].Duration != 5*time.Millisecond {
+		t.Errorf("tool timings = %+v", got.ToolCalls)
+	}
+
+	var nilTiming *runTiming
+	nilTiming.markFirstToken()
+	nilTiming.recordTool(ToolCall{}, time.Second, false)
+}
@@ -0,0 +1,147 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+)
+
+// defaultToolCacheEntries bounds the in-memory tier when
+// ToolCacheConfig.MaxEntries is unset.
+const defaultToolCacheEntries = 1000
+
+// ToolCacheStore is the persistent tier of the tool result cache, shared
+// across restarts and replicas. *store.ToolCacheStore satisfies it.
+type ToolCacheStore interface {
+	GetToolResult(ctx context.Context, key string, now time.Time) (content, structured string, ok bool, err error)
+	PutToolResult(ctx context.Context, key, content, structured string, expires time.Time) error
+}
+
+// ToolCacheConfig caches the results of read-only tool calls so an
+// identical call (same user, plugin, action and args) within the TTL is
+// answered without running the tool again. Only actions that declare
+// read_only are ever cached: anything else may mutate state, and replaying
+// its result would hide the side effect.
+type ToolCacheConfig struct {
+	Enabled    bool
+	DefaultTTL time.Duration            // read-only actions without a TTL of their own; 0 = cache only actions that declare one
+	TTLs       map[string]time.Duration // "plugin.action" -> TTL, overriding the capability's cache_ttl; 0 turns caching off for that action
+	MaxEntries int                      // in-memory entries; 0 = 1000
+	Store      ToolCacheStore           // optional persistent tier; nil = memory only
+}
+
+type toolCacheEntry struct {
+	content    string
+	structured string
+	expires    time.Time
+}
+
+// toolCache is the two-tier cache behind ToolCacheConfig. A nil
+// *toolCache caches nothing.
+type toolCache struct {
+	cfg     ToolCacheConfig
+	mu      sync.Mutex
+	entries map[string]toolCacheEntry
+	now     func() time.Time
+}
+
+func newToolCache(cfg ToolCacheConfig) *toolCache {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultToolCacheEntries
+	}
+	return &toolCache{cfg: cfg, entries: make(map[string]toolCacheEntry), now: time.Now}
+}
+
+// ttl is how long the result of call may be reused; 0 means not at all.
+func (c *toolCache) ttl(call ToolCall, action *Action) time.Duration {
+	if c == nil || action == nil || !action.ReadOnly {
+		return 0
+	}
+	if d, ok := c.cfg.TTLs[call.Plugin+"."+call.Action]; ok {
+		return d
+	}
+	if action.CacheTTL > 0 {
+		return action.CacheTTL
+	}
+	return c.cfg.DefaultTTL
+}
+
+// key identifies a call by who made it and what it asked for. The actor is
+// part of the key because a plugin may answer per user (its own token,
+// its own permissions), so one user's result is never served to another.
+func (c *toolCache) key(ctx context.Context, call ToolCall) string {
+	args, _ := json.Marshal(call.Args) // map keys marshal sorted
+	h := sha256.New()
+	for _, part := range []string{actor.Actor(ctx), call.Plugin, call.Action, string(args)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *toolCache) get(ctx context.Context, key string) (ToolResult, bool) {
+	now := c.now()
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	if ok && !now.Before(e.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if ok {
+		return ToolResult{Content: e.content, StructuredContent: e.structured}, true
+	}
+	if c.cfg.Store == nil {
+		return ToolResult{}, false
+	}
+	content, structured, ok, err := c.cfg.Store.GetToolResult(ctx, key, now)
+	if err != nil {
+		slog.Warn("tool cache lookup failed", "error", err)
+		return ToolResult{}, false
+	}
+	return ToolResult{Content: content, StructuredContent: structured}, ok
+}
+
+func (c *toolCache) put(ctx context.Context, key string, result ToolResult, ttl time.Duration) {
+	expires := c.now().Add(ttl)
+	c.mu.Lock()
+	if len(c.entries) >= c.cfg.MaxEntries {
+		c.evictLocked()
+	}
+	c.entries[key] = toolCacheEntry{content: result.Content, structured: result.StructuredContent, expires: expires}
+	c.mu.Unlock()
+	if c.cfg.Store != nil {
+		if err := c.cfg.Store.PutToolResult(ctx, key, result.Content, result.StructuredContent, expires); err != nil {
+			slog.Warn("tool cache write failed", "error", err)
+		}
+	}
+}
+
+// evictLocked makes room for one entry: expired entries go first, and if
+// none had expired, the one closest to expiry.
+func (c *toolCache) evictLocked() {
+	now := c.now()
+	var victim string
+	var soonest time.Time
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+			continue
+		}
+		if victim == "" || e.expires.Before(soonest) {
+			victim, soonest = k, e.expires
+		}
+	}
+	if len(c.entries) >= c.cfg.MaxEntries && victim != "" {
+		delete(c.entries, victim)
+	}
+}
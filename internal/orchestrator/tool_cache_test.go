@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// cachedToolExecutor answers with how many times it has run.
+type cachedToolExecutor struct {
+	mu    sync.Mutex
+	calls int
+	fail  bool
+}
+
+func (c *cachedToolExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if c.fail {
+		return ToolResult{CallID: call.ID, Error: "upstream down"}
+	}
+	return ToolResult{CallID: call.ID, Content: fmt.Sprintf("run %d", c.calls)}
+}
+
+type fakeToolCacheStore struct {
+	entries map[string]string
+}
+
+func (f *fakeToolCacheStore) GetToolResult(_ context.Context, key string, _ time.Time) (string, string, bool, error) {
+	v, ok := f.entries[key]
+	return v, "", ok, nil
+}
+
+func (f *fakeToolCacheStore) PutToolResult(_ context.Context, key, content, _ string, _ time.Time) error {
+	f.entries[key] = content
+	return nil
+}
+
+func newToolCacheOrch(t *testing.T, exec *cachedToolExecutor, cfg ToolCacheConfig) *Orchestrator {
+	t.Helper()
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "weather", Description: "Weather", Actions: []Action{
+		{Name: "forecast", Description: "Forecast", ReadOnly: true, CacheTTL: time.Minute, Parameters: []Parameter{{Name: "city"}}},
+		{Name: "current", Description: "Current", ReadOnly: true, Parameters: []Parameter{{Name: "city"}}},
+		{Name: "subscribe", Description: "Subscribe", CacheTTL: time.Minute, Parameters: []Parameter{{Name: "city"}}},
+	}}, exec)
+	cfg.Enabled = true
+	return NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{ToolCache: cfg})
+}
+
+func TestToolCache_ReusesReadOnlyResults(t *testing.T) {
+	exec := &cachedToolExecutor{}
+	orch := newToolCacheOrch(t, exec, ToolCacheConfig{})
+	ctx := actor.WithActor(context.Background(), "alice")
+	call := func(ctx context.Context, action, city string) ToolResult {
+		return orch.executeCall(ctx, ToolCall{ID: "c-" + city, Plugin: "weather", Action: action, Args: map[string]string{"city": city}, FromLLM: true})
+	}
+
+	first := call(ctx, "forecast", "Berlin")
+	second := call(ctx, "forecast", "Berlin")
+	if exec.calls != 1 || second.Content != first.Content || second.CallID != "c-Berlin" {
+		t.Fatalf("identical read-only call ran again: calls=%d first=%+v second=%+v", exec.calls, first, second)
+	}
+	if call(ctx, "forecast", "Paris"); exec.calls != 2 {
+		t.Errorf("different args must miss the cache, calls=%d", exec.calls)
+	}
+	if call(actor.WithActor(context.Background(), "bob"), "forecast", "Berlin"); exec.calls != 3 {
+		t.Errorf("another user's result was served, calls=%d", exec.calls)
+	}
+
+	// Without a TTL of its own and no default, a read-only action is not cached.
+	call(ctx, "current", "Berlin")
+	call(ctx, "current", "Berlin")
+	if exec.calls != 5 {
+		t.Errorf("action without TTL was cached, calls=%d", exec.calls)
+	}
+	// An action that is not read-only always runs, whatever its TTL.
+	call(ctx, "subscribe", "Berlin")
+	call(ctx, "subscribe", "Berlin")
+	if exec.calls != 7 {
+		t.Errorf("mutating action was cached, calls=%d", exec.calls)
+	}
+}
+
+func TestToolCache_TTLAndOverrides(t *testing.T) {
+	exec := &cachedToolExecutor{}
+	orch := newToolCacheOrch(t, exec, ToolCacheConfig{
+		DefaultTTL: time.Minute,
+		TTLs:       map[string]time.Duration{"weather.forecast": 0},
+	})
+	now := time.Now()
+	orch.toolCache.now = func() time.Time { return now }
+	run := func(action string) {
+		orch.executeCall(context.Background(), ToolCall{ID: "c", Plugin: "weather", Action: action, Args: map[string]string{"city": "Oslo"}})
+	}
+
+	run("current")
+	run("current")
+	if exec.calls != 1 {
+		t.Fatalf("default_ttl did not apply, calls=%d", exec.calls)
+	}
+	now = now.Add(2 * time.Minute)
+	if run("current"); exec.calls != 2 {
+		t.Errorf("expired entry was reused, calls=%d", exec.calls)
+	}
+
+	run("forecast")
+	run("forecast")
+	if exec.calls != 4 {
+		t.Errorf("a TTL override of 0 must disable caching, calls=%d", exec.calls)
+	}
+}
+
+func TestToolCache_SkipsErrorsAndUsesStore(t *testing.T) {
+	exec := &cachedToolExecutor{fail: true}
+	st := &fakeToolCacheStore{entries: map[string]string{}}
+	orch := newToolCacheOrch(t, exec, ToolCacheConfig{Store: st})
+	call := ToolCall{ID: "c", Plugin: "weather", Action: "forecast", Args: map[string]string{"city": "Rome"}}
+
+	orch.executeCall(context.Background(), call)
+	orch.executeCall(context.Background(), call)
+	if exec.calls != 2 || len(st.entries) != 0 {
+		t.Fatalf("failed results must not be cached: calls=%d stored=%v", exec.calls, st.entries)
+	}
+
+	exec.fail = false
+	orch.executeCall(context.Background(), call)
+	if len(st.entries) != 1 {
+		t.Fatalf("result not written to the store: %v", st.entries)
+	}
+
+	// A fresh process with an empty memory tier finds the result in the store.
+	restarted := newToolCacheOrch(t, exec, ToolCacheConfig{Store: st})
+	if res := restarted.executeCall(context.Background(), call); exec.calls != 3 || res.Content != "run 3" {
+		t.Errorf("store tier not consulted: calls=%d res=%+v", exec.calls, res)
+	}
+}
+
+func TestToolCache_Eviction(t *testing.T) {
+	c := newToolCache(ToolCacheConfig{Enabled: true, MaxEntries: 2})
+	ctx := context.Background()
+	c.put(ctx, "a", ToolResult{Content: "a"}, time.Minute)
+	c.put(ctx, "b", ToolResult{Content: "b"}, time.Hour)
+	c.put(ctx, "c", ToolResult{Content: "c"}, time.Hour)
+	if _, ok := c.get(ctx, "a"); ok {
+		t.Error("the entry closest to expiry should have been evicted")
+	}
+	for _, k := range []string{"b", "c"} {
+		if _, ok := c.get(ctx, k); !ok {
+			t.Errorf("entry %s evicted", k)
+		}
+	}
+	if newToolCache(ToolCacheConfig{}) != nil {
+		t.Error("a disabled cache must be nil")
+	}
+}
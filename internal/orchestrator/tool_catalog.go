@@ -150,7 +150,8 @@ func (o *Orchestrator) renderToolCatalog(promoted map[string]bool, allowedPlugin
 
 	var entries []catalogEntry
 	for _, cap := range o.registry.ListCapabilities() {
-		if !o.pluginAllowed(cap, allowedPlugins) {
+		// Summarized plugins are described on demand, not cataloged.
+		if o.summarized(cap) || !o.pluginAllowed(cap, allowedPlugins) {
 			continue
 		}
 		for _, action := range cap.Actions {
@@ -192,18 +193,24 @@ func (o *Orchestrator) renderToolCatalog(promoted map[string]bool, allowedPlugin
 }
 
 // preparerActionSet pre-computes the FQNs the preparer pipeline itself
-// owns (both pre-LLM preparers and guard preparers) so they're excluded
-// from the LLM-visible tool surface. Computed once in NewWithRules and
-// read by buildToolDefinitions, buildSystemPrompt, renderToolCatalog, and
-// allowedToolsSet so the filter chain stays consistent across them.
-func preparerActionSet(preparers, guards []ContentPreparerEntry) map[string]bool {
-	out := make(map[string]bool, len(preparers)+len(guards))
+// owns (pre-LLM preparers, guard preparers and response moderators) so
+// they're excluded from the LLM-visible tool surface. Computed once in
+// NewWithRules and read by buildToolDefinitions, buildSystemPrompt,
+// renderToolCatalog, and allowedToolsSet so the filter chain stays
+// consistent across them.
+func preparerActionSet(preparers, guards []ContentPreparerEntry, moderators []ResponseModeratorEntry) map[string]bool {
+	out := make(map[string]bool, len(preparers)+len(guards)+len(moderators))
 	for _, prep := range preparers {
 		out[toolFQN(prep.Plugin, prep.Action)] = true
 	}
 	for _, g := range guards {
 		out[toolFQN(g.Plugin, g.Action)] = true
 	}
+	for _, m := range moderators {
+		if !strings.HasPrefix(m.Plugin, "lua:") {
+			out[toolFQN(m.Plugin, m.Action)] = true
+		}
+	}
 	return out
 }
 
@@ -0,0 +1,182 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ToolExposure selects how much of the tool registry the system prompt
+// carries. Full lists every plugin with its actions (inline in text mode, as
+// catalog lines in native mode), so the prompt grows with every installed
+// skill. Summary lists only plugin names and descriptions, a page at a time,
+// and the model reads a plugin's actions and parameters on demand with
+// _tools__describe, which keeps per-turn prompt size bounded by the page.
+type ToolExposure string
+
+const (
+	ToolExposureFull    ToolExposure = "full"
+	ToolExposureSummary ToolExposure = "summary"
+)
+
+// Validate rejects an unknown mode; empty means full.
+func (e ToolExposure) Validate() error {
+	switch e {
+	case "", ToolExposureFull, ToolExposureSummary:
+		return nil
+	}
+	return fmt.Errorf("tool_exposure %q: want %q or %q", e, ToolExposureFull, ToolExposureSummary)
+}
+
+const (
+	// toolsPluginName is the built-in plugin that describes summarized
+	// plugins; registered only in summary mode.
+	toolsPluginName     = "_tools"
+	toolsDescribeAction = "describe"
+
+	// toolSummaryPageSize is how many plugins one summary page lists, in the
+	// prompt and per _tools__describe page.
+	toolSummaryPageSize = 50
+)
+
+// summarized reports whether cap is listed by name only. Built-in host
+// plugins ("_meta", "_blob", ...) are few and small and stay in full so the
+// model always knows how to call them, _tools__describe included.
+func (o *Orchestrator) summarized(cap PluginCapability) bool {
+	return o.toolExposure == ToolExposureSummary && !strings.HasPrefix(cap.Name, "_")
+}
+
+func (o *Orchestrator) registerDescribeTool() {
+	if o.toolExposure != ToolExposureSummary {
+		return
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        toolsPluginName,
+		Description: "Describe installed plugins",
+		Actions: []Action{{
+			Name: toolsDescribeAction,
+			Description: "Return a plugin's actions and their parameters. " +
+				"Without a plugin, list the installed plugins a page at a time.",
+			AlwaysInclude: true,
+			ReadOnly:      true,
+			Parameters: []Parameter{
+				{Name: "plugin", Description: "Plugin name as listed under Tools"},
+				{Name: "page", Description: "Page of the plugin list to return when no plugin is given (default 1)"},
+			},
+		}},
+	}, &describeExecutor{orch: o})
+}
+
+type describeExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *describeExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	if call.Action != toolsDescribeAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	allowed := e.orch.resolveAllowedPlugins(ctx)
+	name := strings.TrimSpace(call.Args["plugin"])
+	if name == "" {
+		page, _ := strconv.Atoi(call.Args["page"])
+		return ToolResult{CallID: call.ID, Content: e.orch.renderToolSummary(allowed, max(page, 1))}
+	}
+	cap, ok := e.orch.registry.GetCapability(name)
+	if !ok || !e.orch.summarized(cap) || !e.orch.pluginAllowed(cap, allowed) {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown plugin %q; call %s without a plugin to list them",
+			name, toolFQN(toolsPluginName, toolsDescribeAction))}
+	}
+	return ToolResult{CallID: call.ID, Content: e.orch.describePlugin(cap)}
+}
+
+// describableActions returns the actions of cap the model may call:
+// preparer/guard actions and user-only actions are left out, as in the
+// prompt and the catalog.
+func (o *Orchestrator) describableActions(cap PluginCapability) []Action {
+	var out []Action
+	for _, action := range cap.Actions {
+		if o.preparerActions[toolFQN(cap.Name, action.Name)] || action.UserOnly {
+			continue
+		}
+		out = append(out, action)
+	}
+	return out
+}
+
+// describePlugin renders what full mode puts in the text-mode prompt for
+// cap: heading, description, server instructions, and every action with its
+// parameters.
+func (o *Orchestrator) describePlugin(cap PluginCapability) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s\n%s\n", cap.heading(), cap.Description)
+	if cap.SystemPromptAddition != "" {
+		fmt.Fprintf(&sb, "--- plugin: %s ---\n%s\n--- end plugin: %s ---\n", cap.Name, cap.SystemPromptAddition, cap.Name)
+	}
+	actions := o.describableActions(cap)
+	if len(actions) == 0 {
+		sb.WriteString("This plugin has no actions you can call.\n")
+		return sb.String()
+	}
+	for _, action := range actions {
+		fmt.Fprintf(&sb, "- %s: %s\n", toolFQN(cap.Name, action.Name), action.Description)
+		for _, p := range action.Parameters {
+			req := ""
+			if p.Required {
+				req = " (required)"
+			}
+			fmt.Fprintf(&sb, "  - %s%s: %s%s\n", p.Name, p.typeNote(), p.Description, req)
+		}
+	}
+	if o.supportsNativeTools() {
+		fmt.Fprintf(&sb, "\nLoad the actions you need with `%s(names=\"plugin__action,...\")` before calling them.\n",
+			toolFQN(metaPluginName, metaLoadTools))
+	}
+	return sb.String()
+}
+
+// renderToolSummary returns the given page of the "## Tools" summary: one
+// line per allowed, summarized plugin with a callable action, sorted by
+// name, and a pointer to the next page when there is one. Returns "" when
+// no plugin is summarized.
+func (o *Orchestrator) renderToolSummary(allowed cachedAllowedPlugins, page int) string {
+	type entry struct {
+		heading, summary string
+		actions          int
+	}
+	var entries []entry
+	for _, cap := range o.registry.ListCapabilities() {
+		if !o.summarized(cap) || !o.pluginAllowed(cap, allowed) {
+			continue
+		}
+		n := len(o.describableActions(cap))
+		if n == 0 {
+			continue
+		}
+		entries = append(entries, entry{heading: cap.heading(), summary: firstLine(cap.Description), actions: n})
+	}
+	if len(entries) == 0 {
+		return ""
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].heading < entries[j].heading })
+
+	pages := (len(entries) + toolSummaryPageSize - 1) / toolSummaryPageSize
+	page = min(page, pages)
+	start := (page - 1) * toolSummaryPageSize
+	end := min(start+toolSummaryPageSize, len(entries))
+	describe := toolFQN(toolsPluginName, toolsDescribeAction)
+
+	var sb strings.Builder
+	sb.WriteString("## Tools — plugin summary\n")
+	fmt.Fprintf(&sb, "These plugins are installed. Their actions are NOT listed here: call `%s(plugin=\"name\")` "+
+		"to read a plugin's actions and parameters before you use it, and never guess them.\n", describe)
+	for _, e := range entries[start:end] {
+		fmt.Fprintf(&sb, "- %s: %s (%d actions)\n", e.heading, e.summary, e.actions)
+	}
+	if page < pages {
+		fmt.Fprintf(&sb, "Showing plugins %d-%d of %d. Call `%s(page=\"%d\")` for more.\n", start+1, end, len(entries), describe, page+1)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
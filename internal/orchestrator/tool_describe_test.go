@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newSummaryOrch(t *testing.T, llm LLMClient, plugins int) *Orchestrator {
+	t.Helper()
+	reg := NewToolRegistry()
+	for i := range plugins {
+		_ = reg.Register(PluginCapability{
+			Name:                 fmt.Sprintf("p%03d", i),
+			Description:          fmt.Sprintf("Plugin %d\nLong description that stays out of the prompt", i),
+			SystemPromptAddition: "Issues belong to projects.",
+			Actions: []Action{
+				{Name: "search", Description: "Search things", Parameters: []Parameter{{Name: "query", Description: "Search text", Required: true}}},
+				{Name: "admin", Description: "Admin only", UserOnly: true},
+			},
+		}, &bulkExecutor{})
+	}
+	return NewWithRules(llm, &fakeParser{}, reg, state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{ToolExposure: ToolExposureSummary})
+}
+
+func TestToolExposureSummary_PromptListsPluginsOnly(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		llm  LLMClient
+	}{{"text", &fakeLLM{}}, {"native", nativeToolsLLM{&fakeLLM{}}}} {
+		t.Run(tc.name, func(t *testing.T) {
+			orch := newSummaryOrch(t, tc.llm, 60)
+			prompt := orch.buildSystemPrompt(context.Background(), "hi", true)
+
+			if !strings.Contains(prompt, "- p000: Plugin 0 (1 actions)") {
+				t.Errorf("summary line missing:\n%s", prompt)
+			}
+			if strings.Contains(prompt, "p000__search") || strings.Contains(prompt, "Issues belong to projects") || strings.Contains(prompt, "Long description") {
+				t.Errorf("summarized plugin leaked actions or details into the prompt:\n%s", prompt)
+			}
+			if strings.Contains(prompt, "p050") || !strings.Contains(prompt, `_tools__describe(page="2")`) {
+				t.Errorf("first page should stop at %d plugins and point to page 2:\n%s", toolSummaryPageSize, prompt)
+			}
+			// Host plugins stay in full so the model can call describe itself.
+			if !strings.Contains(prompt, "_tools") || strings.Contains(prompt, "## Tool catalog") {
+				t.Errorf("host plugins / catalog:\n%s", prompt)
+			}
+		})
+	}
+}
+
+func TestToolExposureSummary_Describe(t *testing.T) {
+	orch := newSummaryOrch(t, nativeToolsLLM{&fakeLLM{}}, 60)
+	describe := func(args map[string]string) ToolResult {
+		return orch.executeCall(context.Background(), ToolCall{ID: "d", Plugin: toolsPluginName, Action: toolsDescribeAction, Args: args})
+	}
+
+	r := describe(map[string]string{"plugin": "p007"})
+	for _, want := range []string{"## p007", "Issues belong to projects.", "- p007__search: Search things", "  - query: Search text (required)", "_meta__load_tools"} {
+		if !strings.Contains(r.Content, want) {
+			t.Errorf("describe p007 missing %q:\n%s", want, r.Content)
+		}
+	}
+	if strings.Contains(r.Content, "p007__admin") {
+		t.Errorf("user-only action described:\n%s", r.Content)
+	}
+
+	page := describe(map[string]string{"page": "2"})
+	if !strings.Contains(page.Content, "- p050: Plugin 50") || strings.Contains(page.Content, "- p049:") || strings.Contains(page.Content, "page=") {
+		t.Errorf("page 2:\n%s", page.Content)
+	}
+
+	for _, name := range []string{"nope", metaPluginName} {
+		if r := describe(map[string]string{"plugin": name}); !strings.Contains(r.Error, "unknown plugin") {
+			t.Errorf("describe %s = %+v", name, r)
+		}
+	}
+}
+
+func TestToolExposureFull_ListsActionsWithoutDescribe(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "jira", Description: "Jira",
+		Actions: []Action{{Name: "search", Description: "Search issues"}}}, &bulkExecutor{})
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{}, reg, state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+
+	if _, ok := reg.GetCapability(toolsPluginName); ok {
+		t.Error("_tools registered in full mode")
+	}
+	if prompt := orch.buildSystemPrompt(context.Background(), "hi", true); !strings.Contains(prompt, "- jira__search: Search issues") {
+		t.Errorf("full mode prompt:\n%s", prompt)
+	}
+	if err := ToolExposure("compact").Validate(); err == nil {
+		t.Error("unknown tool_exposure accepted")
+	}
+}
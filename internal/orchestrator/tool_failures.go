@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"cmp"
+	"context"
+	"strings"
+)
+
+// CallStatus is the outcome of one tool call of a turn.
+type CallStatus struct {
+	CallID string
+	Tool   string // plugin__action
+	OK     bool
+	Error  string // the tool's error when !OK
+}
+
+// FailedCalls returns the calls of the turn that errored, in call order.
+func (r *RunResult) FailedCalls() []CallStatus {
+	var failed []CallStatus
+	for _, s := range r.CallStatuses {
+		if !s.OK {
+			failed = append(failed, s)
+		}
+	}
+	return failed
+}
+
+// markCallStatuses records on a finished turn's result the outcome of each
+// tool call it ran. Results line up with ToolCalls by index.
+func markCallStatuses(result *RunResult) {
+	if result == nil || len(result.Results) == 0 {
+		return
+	}
+	statuses := make([]CallStatus, 0, len(result.Results))
+	for i, r := range result.Results {
+		s := CallStatus{CallID: r.CallID, OK: r.Error == "", Error: r.Error}
+		if i < len(result.ToolCalls) {
+			c := result.ToolCalls[i]
+			s.Tool = toolFQN(c.Plugin, c.Action)
+			if s.CallID == "" {
+				s.CallID = c.ID
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	result.CallStatuses = statuses
+}
+
+// noteToolFailures appends "N of M actions failed: …" to the result's
+// InputForDisplay when ReportToolFailures is on and some of the turn's calls
+// errored, so a channel can show what the answer may leave out. It runs
+// before applyShowToolCalls, so raw mode shows the note too.
+func (o *Orchestrator) noteToolFailures(ctx context.Context, result *RunResult) {
+	if !o.reportToolFailures {
+		return
+	}
+	markCallStatuses(result)
+	failed := result.FailedCalls()
+	if len(failed) == 0 {
+		return
+	}
+	items := make([]string, len(failed))
+	for i, f := range failed {
+		items[i] = cmp.Or(f.Tool, f.CallID) + " (" + f.Error + ")"
+	}
+	note := o.coreStringFor(ctx, msgToolsFailed, len(failed), len(result.CallStatuses), strings.Join(items, "; "))
+	if result.InputForDisplay == "" {
+		result.InputForDisplay = note
+		return
+	}
+	result.InputForDisplay += "\n\n" + note
+}
@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func runPartlyFailingTurn(t *testing.T, opts OrchestratorOpts) *RunResult {
+	t.Helper()
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "gitlab", Description: "GitLab", Actions: []Action{{Name: "create_pr"}}}, &echoExecutor{})
+	_ = reg.Register(PluginCapability{Name: "jira", Description: "Jira", Actions: []Action{{Name: "create_issue"}}}, &errorReturningExecutor{err: "project OPS not found"})
+	parser := &fakeParser{parseFn: func(resp string) []ToolCall {
+		if resp != "calls" {
+			return nil
+		}
+		return []ToolCall{{ID: "c1", Plugin: "gitlab", Action: "create_pr"}, {ID: "c2", Plugin: "jira", Action: "create_issue"}}
+	}}
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{responses: []string{"calls", "I opened the PR."}}, parser, reg, state.NewMemoryStore(""), sessions, opts)
+
+	res, err := orch.Run(context.Background(), "s1", "open a PR and file a ticket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestToolFailures_StatusesAndNote(t *testing.T) {
+	res := runPartlyFailingTurn(t, OrchestratorOpts{ReportToolFailures: true})
+
+	want := []CallStatus{
+		{CallID: "c1", Tool: "gitlab__create_pr", OK: true},
+		{CallID: "c2", Tool: "jira__create_issue", Error: "project OPS not found"},
+	}
+	if len(res.CallStatuses) != 2 || res.CallStatuses[0] != want[0] || res.CallStatuses[1] != want[1] {
+		t.Errorf("call statuses = %+v", res.CallStatuses)
+	}
+	if f := res.FailedCalls(); len(f) != 1 || f[0].CallID != "c2" {
+		t.Errorf("failed calls = %+v", f)
+	}
+	if !strings.HasSuffix(res.InputForDisplay, "\n\n1 of 2 actions failed: jira__create_issue (project OPS not found)") {
+		t.Errorf("display = %q", res.InputForDisplay)
+	}
+	if res.Response != "I opened the PR." {
+		t.Errorf("response = %q", res.Response)
+	}
+}
+
+func TestToolFailures_NoNoteByDefault(t *testing.T) {
+	res := runPartlyFailingTurn(t, OrchestratorOpts{})
+
+	if len(res.FailedCalls()) != 1 {
+		t.Errorf("call statuses = %+v; they are set without the option", res.CallStatuses)
+	}
+	if strings.Contains(res.InputForDisplay, "actions failed") {
+		t.Errorf("display = %q", res.InputForDisplay)
+	}
+}
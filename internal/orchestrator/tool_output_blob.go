@@ -0,0 +1,167 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/blob"
+)
+
+const (
+	// DefaultBlobOffloadBytes is the tool-output size above which the output
+	// is stored as a blob when ToolOutputBlobs.Threshold is unset.
+	DefaultBlobOffloadBytes = 16 * 1024
+
+	// blobPluginName is the built-in plugin that reads offloaded outputs
+	// back; registered only when a blob store is configured.
+	blobPluginName = "_blob"
+	blobReadAction = "read"
+
+	blobPreviewBytes = 2000
+	blobReadDefault  = 8 * 1024
+)
+
+// ToolOutputBlobs keeps oversized tool outputs out of session messages: the
+// full output goes to the blob store and the message holds a preview plus a
+// blob reference the LLM can page through with _blob__read.
+type ToolOutputBlobs struct {
+	Store     *blob.Store
+	Threshold int // bytes; <= 0 = DefaultBlobOffloadBytes
+}
+
+func (b ToolOutputBlobs) threshold() int {
+	if b.Threshold <= 0 {
+		return DefaultBlobOffloadBytes
+	}
+	return b.Threshold
+}
+
+// offloadToolOutput replaces a successful result's oversized Content with a
+// preview and a blob reference. It runs before the guard's size cap, so the
+// stored copy is complete. A store failure keeps the inline output (the cap
+// still applies) rather than failing the call.
+func (o *Orchestrator) offloadToolOutput(ctx context.Context, call ToolCall, result ToolResult) ToolResult {
+	if o.toolOutputBlobs.Store == nil || result.Error != "" || call.Plugin == blobPluginName ||
+		len(result.Content) <= o.toolOutputBlobs.threshold() {
+		return result
+	}
+	id, err := o.toolOutputBlobs.Store.Put(ctx, []byte(result.Content))
+	if err != nil {
+		slog.Warn("storing tool output as blob failed; keeping it inline", "component", "blob",
+			"plugin", call.Plugin, "action", call.Action, "bytes", len(result.Content), "error", err)
+		return result
+	}
+	slog.Info("audit", "event", "tool_output_offloaded", "session_id", actor.SessionID(ctx),
+		"plugin", call.Plugin, "action", call.Action, "blob", blob.Ref(id), "bytes", len(result.Content))
+	result.Content = fmt.Sprintf("%s\n\n[output truncated: %d bytes in total, stored as %s. "+
+		"Call %s with this id and an offset to read the rest.]",
+		cutBytes(result.Content, blobPreviewBytes), len(result.Content), blob.Ref(id), toolFQN(blobPluginName, blobReadAction))
+	return result
+}
+
+// cutBytes returns at most n bytes of s without splitting a rune.
+func cutBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// registerBlobTools registers _blob when a blob store is configured.
+func (o *Orchestrator) registerBlobTools() {
+	if o.toolOutputBlobs.Store == nil {
+		return
+	}
+	_ = o.registry.Register(PluginCapability{
+		Name:        blobPluginName,
+		Description: "Read stored tool outputs",
+		Actions: []Action{{
+			Name:          blobReadAction,
+			Description:   "Read part of a tool output that was too large to show in full. Only ids shown in this conversation can be read.",
+			AlwaysInclude: true,
+			Parameters: []Parameter{
+				{Name: "id", Description: "The blob id from the truncated output (blob:sha256:...)", Required: true},
+				{Name: "offset", Description: "Byte offset to start at (default 0)"},
+				{Name: "limit", Description: fmt.Sprintf("Bytes to return (default and max %d)", blobReadDefault)},
+			},
+		}},
+	}, &blobExecutor{orch: o})
+}
+
+type blobExecutor struct {
+	orch *Orchestrator
+}
+
+func (e *blobExecutor) Execute(ctx context.Context, call ToolCall) ToolResult {
+	if call.Action != blobReadAction {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown action %q", call.Action)}
+	}
+	id := strings.TrimPrefix(strings.TrimSpace(call.Args["id"]), "blob:")
+	// Content-addressed ids are not secrets, so reads are limited to blobs
+	// the current conversation already references.
+	if !e.orch.sessionReferencesBlob(actor.SessionID(ctx), id) {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("blob %q is not referenced in this conversation", call.Args["id"])}
+	}
+	data, err := e.orch.toolOutputBlobs.Store.Get(ctx, id)
+	if err != nil {
+		return ToolResult{CallID: call.ID, Error: fmt.Sprintf("reading blob: %v", err)}
+	}
+	page, err := readPage(string(data), call.Args["offset"], call.Args["limit"])
+	if err != nil {
+		return ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return ToolResult{CallID: call.ID, Content: page}
+}
+
+// readPage returns up to limit bytes of s from offset (both tool arguments,
+// defaulting to 0 and blobReadDefault) with a footer giving the next offset.
+func readPage(s, offsetArg, limitArg string) (string, error) {
+	offset, _ := strconv.Atoi(offsetArg)
+	limit, _ := strconv.Atoi(limitArg)
+	if limit <= 0 || limit > blobReadDefault {
+		limit = blobReadDefault
+	}
+	if offset < 0 || offset >= len(s) {
+		return "", fmt.Errorf("offset %d is outside the blob (%d bytes)", offset, len(s))
+	}
+	for offset > 0 && !utf8.RuneStart(s[offset]) {
+		offset--
+	}
+	chunk := cutBytes(s[offset:], limit)
+	end := offset + len(chunk)
+	footer := "[end of output]"
+	if end < len(s) {
+		footer = fmt.Sprintf("[bytes %d-%d of %d; next offset %d]", offset, end, len(s), end)
+	}
+	return chunk + "\n\n" + footer, nil
+}
+
+// sessionReferencesBlob reports whether id appears as a blob reference in the
+// session's messages or summary.
+func (o *Orchestrator) sessionReferencesBlob(sessionID, id string) bool {
+	if sessionID == "" {
+		return false
+	}
+	sess, err := o.sessions.Get(sessionID)
+	if err != nil || sess == nil {
+		return false
+	}
+	ref := blob.Ref(id)
+	if strings.Contains(sess.Summary, ref) {
+		return true
+	}
+	for _, m := range sess.Messages {
+		if strings.Contains(m.Content, ref) {
+			return true
+		}
+	}
+	return false
+}
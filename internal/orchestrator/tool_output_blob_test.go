@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/blob"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// bulkExecutor returns a fixed large output.
+type bulkExecutor struct{ out string }
+
+func (b *bulkExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	return ToolResult{CallID: call.ID, Content: b.out}
+}
+
+func TestToolOutputBlobs_OffloadAndRead(t *testing.T) {
+	backend, err := blob.NewLocalBackend(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := blob.New(backend, 0)
+	full := strings.Repeat("0123456789", 3000) // 30000 bytes
+
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "logs", Description: "Logs",
+		Actions: []Action{{Name: "tail", Description: "Tail logs"}}}, &bulkExecutor{out: full})
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, state.NewMemoryStore(""), sessions,
+		OrchestratorOpts{ToolOutputBlobs: ToolOutputBlobs{Store: store, Threshold: 10000}})
+	ctx := actor.WithSessionID(context.Background(), "s1")
+
+	res := orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "logs", Action: "tail"})
+	refs := blob.Refs(res.Content)
+	if len(refs) != 1 || len(res.Content) > 3000 || !strings.Contains(res.Content, "30000 bytes in total") {
+		t.Fatalf("offloaded result = %q", res.Content)
+	}
+	if got, err := store.Get(ctx, refs[0]); err != nil || string(got) != full {
+		t.Fatalf("stored blob = %d bytes, %v", len(got), err)
+	}
+
+	read := func(args map[string]string) ToolResult {
+		return orch.executeCall(ctx, ToolCall{ID: "r", Plugin: blobPluginName, Action: blobReadAction, Args: args})
+	}
+	// The conversation has not seen the ref yet.
+	if r := read(map[string]string{"id": blob.Ref(refs[0])}); !strings.Contains(r.Error, "not referenced") {
+		t.Errorf("unreferenced read = %+v", r)
+	}
+	_ = sessions.AddMessage("s1", provider.Message{Role: provider.RoleTool, Content: res.Content, ToolCallID: "c1"})
+
+	r := read(map[string]string{"id": blob.Ref(refs[0]), "offset": "29995", "limit": "100"})
+	if r.Error != "" || !strings.HasPrefix(r.Content, "56789") || !strings.Contains(r.Content, "[end of output]") {
+		t.Errorf("tail read = %+v", r)
+	}
+	r = read(map[string]string{"id": refs[0]})
+	if !strings.Contains(r.Content, "next offset 8192") {
+		t.Errorf("first page footer: %q", r.Content[len(r.Content)-60:])
+	}
+
+	// Offsets outside the blob are refused.
+	bad := orch.executeCall(ctx, ToolCall{ID: "c2", Plugin: blobPluginName, Action: blobReadAction, Args: map[string]string{"id": refs[0], "offset": "-1"}})
+	if !strings.Contains(bad.Error, "outside the blob") {
+		t.Errorf("bad offset = %+v", bad)
+	}
+}
+
+func TestToolOutputBlobs_DisabledKeepsOutputInline(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "logs", Description: "Logs",
+		Actions: []Action{{Name: "tail", Description: "Tail logs"}}}, &bulkExecutor{out: strings.Repeat("x", 20000)})
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg, state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+	res := orch.executeCall(context.Background(), ToolCall{ID: "c1", Plugin: "logs", Action: "tail"})
+	if len(res.Content) != 20000 {
+		t.Errorf("content length = %d; want the output untouched", len(res.Content))
+	}
+	if _, ok := reg.GetCapability(blobPluginName); ok {
+		t.Error("_blob must not be registered without a store")
+	}
+}
@@ -0,0 +1,234 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+// Tool policy effects.
+const (
+	PolicyAllow           = "allow"            // run the call; later policies are not checked
+	PolicyDeny            = "deny"             // refuse the call
+	PolicyRequireApproval = "require_approval" // file the call in the approval queue
+)
+
+// ToolPolicy is an enforceable rule checked in code before every tool call
+// the model makes. Unlike orchestrator.rules, which are prompt text the
+// model may ignore, a policy cannot be talked around. Every condition that
+// is set must hold for the policy to apply.
+type ToolPolicy struct {
+	Name    string
+	Tools   []string // "plugin" or "plugin__action"; empty = every tool
+	Actors  []string // "channel:sender" actors or profile entity ids; empty = everyone
+	Groups  []string // profile groups; empty = every group
+	Window  *TimeWindow
+	Args    map[string]*regexp.Regexp // arg name -> pattern its value must match
+	When    []ArgCondition
+	Effect  string // PolicyAllow, PolicyDeny or PolicyRequireApproval
+	Message string // told to the model when the policy denies; "" = a generic refusal
+}
+
+// TimeWindow is a daily time range in which a policy applies.
+type TimeWindow struct {
+	Start, End int            // minutes after midnight; End <= Start wraps past midnight
+	Days       []time.Weekday // days the window starts on; empty = every day
+	Location   *time.Location
+}
+
+// ArgCondition compares a tool call arg with a value: numerically when
+// both are numbers, otherwise as strings (== and != only).
+type ArgCondition struct {
+	Arg, Op, Value string
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// ParseTimeWindow parses hours ("22:00-06:00"), days ("mon".."sun") and an
+// IANA timezone ("" = local time).
+func ParseTimeWindow(hours string, days []string, timezone string) (*TimeWindow, error) {
+	w := &TimeWindow{Location: time.Local}
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return nil, fmt.Errorf("hours %q: want HH:MM-HH:MM", hours)
+	}
+	var err error
+	if w.Start, err = parseClock(from); err != nil {
+		return nil, fmt.Errorf("hours %q: %w", hours, err)
+	}
+	if w.End, err = parseClock(to); err != nil {
+		return nil, fmt.Errorf("hours %q: %w", hours, err)
+	}
+	for _, d := range days {
+		key := strings.ToLower(strings.TrimSpace(d))
+		wd, ok := weekdays[key[:min(3, len(key))]] // "mon" or "monday"
+		if !ok {
+			return nil, fmt.Errorf("unknown day %q", d)
+		}
+		w.Days = append(w.Days, wd)
+	}
+	if timezone != "" {
+		if w.Location, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("timezone: %w", err)
+		}
+	}
+	return w, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", strings.TrimSpace(s))
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// contains reports whether t falls inside the window.
+func (w *TimeWindow) contains(t time.Time) bool {
+	t = t.In(w.Location)
+	m := t.Hour()*60 + t.Minute()
+	start := t
+	switch {
+	case w.Start < w.End:
+		if m < w.Start || m >= w.End {
+			return false
+		}
+	case m >= w.Start:
+	case m < w.End:
+		start = t.AddDate(0, 0, -1) // the window opened yesterday
+	default:
+		return false
+	}
+	return len(w.Days) == 0 || slices.Contains(w.Days, start.Weekday())
+}
+
+// argConditionOps is ordered so two-character operators are tried first.
+var argConditionOps = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+// ParseArgCondition parses "arg op value", e.g. "amount > 1000".
+func ParseArgCondition(s string) (ArgCondition, error) {
+	for _, op := range argConditionOps {
+		if i := strings.Index(s, op); i >= 0 {
+			c := ArgCondition{Arg: strings.TrimSpace(s[:i]), Op: op, Value: strings.TrimSpace(s[i+len(op):])}
+			if c.Arg == "" || c.Value == "" {
+				return c, fmt.Errorf("%q: want \"arg op value\"", s)
+			}
+			return c, nil
+		}
+	}
+	return ArgCondition{}, fmt.Errorf("%q: no operator (want one of %s)", s, strings.Join(argConditionOps, " "))
+}
+
+// holds reports whether args satisfy c; an absent arg never does.
+func (c ArgCondition) holds(args map[string]string) bool {
+	v, ok := args[c.Arg]
+	if !ok {
+		return false
+	}
+	a, aErr := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	b, bErr := strconv.ParseFloat(c.Value, 64)
+	if aErr != nil || bErr != nil {
+		switch c.Op {
+		case "==":
+			return v == c.Value
+		case "!=":
+			return v != c.Value
+		}
+		return false
+	}
+	switch c.Op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	default:
+		return a <= b
+	}
+}
+
+// Validate checks the effect.
+func (p ToolPolicy) Validate() error {
+	if !slices.Contains([]string{PolicyAllow, PolicyDeny, PolicyRequireApproval}, p.Effect) {
+		return fmt.Errorf("policy %q: unknown effect %q (want allow, deny or require_approval)", p.Name, p.Effect)
+	}
+	return nil
+}
+
+// matches reports whether every condition of p holds for call.
+func (p ToolPolicy) matches(ctx context.Context, call ToolCall, now time.Time) bool {
+	if len(p.Tools) > 0 && !slices.Contains(p.Tools, call.Plugin) && !slices.Contains(p.Tools, toolFQN(call.Plugin, call.Action)) {
+		return false
+	}
+	prof := profile.FromContext(ctx)
+	if len(p.Actors) > 0 {
+		id := actor.Actor(ctx)
+		if !slices.Contains(p.Actors, id) && (prof == nil || prof.EntityID == "" || !slices.Contains(p.Actors, prof.EntityID)) {
+			return false
+		}
+	}
+	if len(p.Groups) > 0 && (prof == nil || !slices.Contains(p.Groups, prof.Group)) {
+		return false
+	}
+	if p.Window != nil && !p.Window.contains(now) {
+		return false
+	}
+	for name, re := range p.Args {
+		v, ok := call.Args[name]
+		if !ok || !re.MatchString(v) {
+			return false
+		}
+	}
+	for _, c := range p.When {
+		if !c.holds(call.Args) {
+			return false
+		}
+	}
+	return true
+}
+
+// toolPolicies evaluates the configured policies in order.
+type toolPolicies struct {
+	list []ToolPolicy
+	now  func() time.Time
+}
+
+// decide returns the first policy that applies to call, or a nameless
+// allow when none does, and audit-logs the decision either way.
+func (tp *toolPolicies) decide(ctx context.Context, call ToolCall) ToolPolicy {
+	decision := ToolPolicy{Effect: PolicyAllow}
+	now := tp.now()
+	for _, p := range tp.list {
+		if p.matches(ctx, call, now) {
+			decision = p
+			break
+		}
+	}
+	slog.Info("audit", "event", "policy_decision", "policy", decision.Name, "effect", decision.Effect,
+		"actor", actor.Actor(ctx), "plugin", call.Plugin, "action", call.Action, "call_id", call.ID)
+	return decision
+}
+
+// refusal is what the model is told when p denies a call.
+func (p ToolPolicy) refusal(call ToolCall) string {
+	if p.Message != "" {
+		return p.Message
+	}
+	return fmt.Sprintf("%s is not allowed by policy %q; do not retry it", toolFQN(call.Plugin, call.Action), p.Name)
+}
@@ -0,0 +1,144 @@
+package orchestrator
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestTimeWindow(t *testing.T) {
+	night, err := ParseTimeWindow("22:00-06:00", []string{"Friday"}, "UTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fri := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC) // a Friday
+	for _, tt := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{fri.Add(23 * time.Hour), true},
+		{fri.Add(21*time.Hour + 59*time.Minute), false},
+		{fri.Add(29 * time.Hour), true},                              // Saturday 05:00, opened on Friday
+		{fri.Add(30 * time.Hour), false},                             // Saturday 06:00
+		{fri.Add(-19 * time.Hour), false},                            // Thursday 05:00, opened on Wednesday
+		{fri.Add(23 * time.Hour).In(time.FixedZone("", 3600)), true}, // the window's zone counts
+	} {
+		if got := night.contains(tt.at); got != tt.want {
+			t.Errorf("contains(%s) = %v, want %v", tt.at, got, tt.want)
+		}
+	}
+
+	day, _ := ParseTimeWindow("09:00-17:00", nil, "UTC")
+	if !day.contains(fri.Add(9*time.Hour)) || day.contains(fri.Add(17*time.Hour)) {
+		t.Error("daytime window bounds")
+	}
+	for _, bad := range [][2]string{{"22:00", ""}, {"25:00-06:00", ""}, {"22:00-06:00", "Mars/Base"}} {
+		if _, err := ParseTimeWindow(bad[0], nil, bad[1]); err == nil {
+			t.Errorf("ParseTimeWindow(%q, %q) accepted", bad[0], bad[1])
+		}
+	}
+	if _, err := ParseTimeWindow("22:00-06:00", []string{"someday"}, ""); err == nil {
+		t.Error("unknown day accepted")
+	}
+}
+
+func TestArgCondition(t *testing.T) {
+	args := map[string]string{"amount": "1500.50", "currency": "EUR"}
+	for _, tt := range []struct {
+		cond string
+		want bool
+	}{
+		{"amount > 1000", true},
+		{"amount <= 1000", false},
+		{"amount == 1500.5", true},
+		{"currency == EUR", true},
+		{"currency != EUR", false},
+		{"currency > 10", false},
+		{"missing != x", false},
+	} {
+		c, err := ParseArgCondition(tt.cond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := c.holds(args); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+	for _, bad := range []string{"amount", "> 5", "amount >"} {
+		if _, err := ParseArgCondition(bad); err == nil {
+			t.Errorf("ParseArgCondition(%q) accepted", bad)
+		}
+	}
+}
+
+func TestToolPolicies_Enforced(t *testing.T) {
+	exec := &deployExecutor{}
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{
+		Name: "payments", Description: "Payments",
+		Actions: []Action{
+			{Name: "transfer", Description: "Send money", Parameters: []Parameter{{Name: "amount"}, {Name: "iban"}}},
+			{Name: "balance", Description: "Balance"},
+		},
+	}, exec)
+	q, err := approval.NewQueue(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	night, _ := ParseTimeWindow("22:00-06:00", nil, "UTC")
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{parseFn: func(string) []ToolCall { return nil }}, reg,
+		state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{
+			ToolApprovals: ToolApprovals{Queue: q},
+			ToolPolicies: []ToolPolicy{
+				{Name: "finance-team", Groups: []string{"finance"}, Effect: PolicyAllow},
+				{Name: "no-night-payments", Tools: []string{"payments"}, Window: night, Effect: PolicyDeny, Message: "Payments are closed at night."},
+				{Name: "foreign-iban", Tools: []string{"payments__transfer"}, Args: map[string]*regexp.Regexp{"iban": regexp.MustCompile(`^(?:[^D]|D[^E])`)}, Effect: PolicyDeny},
+				{Name: "large-transfer", Tools: []string{"payments__transfer"}, When: []ArgCondition{{Arg: "amount", Op: ">", Value: "1000"}}, Effect: PolicyRequireApproval},
+			},
+		})
+	clock := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	orch.policies.now = func() time.Time { return clock }
+
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+	transfer := func(ctx context.Context, amount, iban string) ToolResult {
+		return orch.executeCall(ctx, ToolCall{ID: "c", Plugin: "payments", Action: "transfer", Args: map[string]string{"amount": amount, "iban": iban}, FromLLM: true})
+	}
+
+	if res := transfer(ctx, "50", "DE89370400440532013000"); res.Error != "" {
+		t.Fatalf("small domestic transfer = %+v", res)
+	}
+	if res := transfer(ctx, "50", "FR7630006000011234567890189"); !strings.Contains(res.Error, `policy "foreign-iban"`) {
+		t.Errorf("foreign transfer = %+v", res)
+	}
+	if res := transfer(ctx, "5000", "DE89370400440532013000"); res.Error != "" || !strings.Contains(res.Content, "NOT run yet") {
+		t.Errorf("large transfer = %+v", res)
+	}
+	if n := len(q.List(approval.StatusPending)); n != 1 {
+		t.Errorf("%d pending approvals, want 1", n)
+	}
+
+	clock = clock.Add(11 * time.Hour) // 23:00
+	if res := orch.executeCall(ctx, ToolCall{ID: "c", Plugin: "payments", Action: "balance", FromLLM: true}); res.Error != "Payments are closed at night." {
+		t.Errorf("night balance = %+v", res)
+	}
+	// The first policy that applies decides: finance may pay at night.
+	finance := profile.WithProfile(ctx, &profile.Profile{EntityID: "u1", Group: "finance"})
+	if res := transfer(finance, "5000", "FR7630006000011234567890189"); res.Error != "" {
+		t.Errorf("finance transfer = %+v", res)
+	}
+	// Host calls are not policed.
+	if _, err := orch.RunAction(ctx, "payments", "balance", nil); err != nil {
+		t.Errorf("host call = %v", err)
+	}
+	if len(exec.calls) != 3 {
+		t.Errorf("%d calls reached the plugin, want 3", len(exec.calls))
+	}
+}
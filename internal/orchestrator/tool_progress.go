@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// progressRelay is the CallbackHandler for one call to a plugin that
+// declares SupportsProgress. It forwards callbacks to the orchestrator,
+// shows each progress frame on the channel as a status line, collects the
+// partial results, and signals activity so a plugin that keeps reporting
+// is not cut off by the idle timeout.
+type progressRelay struct {
+	CallbackHandler
+	o        *Orchestrator
+	activity chan struct{}
+
+	mu       sync.Mutex
+	partial  strings.Builder
+	inflight int // callbacks running; the idle timer waits for them
+}
+
+func newProgressRelay(o *Orchestrator) *progressRelay {
+	return &progressRelay{CallbackHandler: o, o: o, activity: make(chan struct{}, 1)}
+}
+
+func (r *progressRelay) beat() {
+	select {
+	case r.activity <- struct{}{}:
+	default:
+	}
+}
+
+func (r *progressRelay) ToolProgress(ctx context.Context, call ToolCall, p ToolProgress) {
+	r.mu.Lock()
+	r.partial.WriteString(p.Partial)
+	r.mu.Unlock()
+	r.beat()
+
+	line := p.Message
+	if p.Percent > 0 {
+		line = strings.TrimSpace(fmt.Sprintf("%s (%d%%)", line, p.Percent))
+	}
+	if line == "" {
+		return
+	}
+	slog.Debug("tool progress", "plugin", call.Plugin, "action", call.Action, "message", p.Message, "percent", p.Percent)
+	pkgchannel.ReportStatus(ctx, r.o.coreStringFor(ctx, msgToolProgress, call.Plugin+" → "+call.Action)+" "+line)
+}
+
+func (r *progressRelay) RunActionResult(ctx context.Context, plugin, action string, args map[string]string) (string, string, error) {
+	r.mu.Lock()
+	r.inflight++
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.inflight--
+		r.mu.Unlock()
+		r.beat()
+	}()
+	return r.CallbackHandler.RunActionResult(ctx, plugin, action, args)
+}
+
+func (r *progressRelay) busy() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.inflight > 0
+}
+
+// merge puts the collected partial results in front of the final content.
+// A failed call keeps them after the error, so the model still sees what
+// the plugin produced before it stopped.
+func (r *progressRelay) merge(result ToolResult) ToolResult {
+	r.mu.Lock()
+	partial := r.partial.String()
+	r.mu.Unlock()
+	if partial == "" {
+		return result
+	}
+	if result.Error != "" {
+		result.Error += "\n\nPartial output before the failure:\n" + partial
+		return result
+	}
+	result.Content = partial + result.Content
+	return result
+}
+
+// executeWithProgress runs a call to a progress-reporting plugin. Instead of
+// one fixed deadline it fails when the plugin goes quiet — no progress frame
+// and no callback for the guard timeout — so long jobs that keep reporting
+// run on, up to the bidi cap, while a hung one is still caught.
+func (o *Orchestrator) executeWithProgress(ctx context.Context, exec BidiExecutor, call ToolCall) ToolResult {
+	relay := newProgressRelay(o)
+	idle := o.guard.Timeout
+	callCtx, cancel := context.WithTimeout(ctx, bidiTimeout)
+	defer cancel()
+
+	done := make(chan ToolResult, 1)
+	go func() {
+		done <- exec.ExecuteBidi(callCtx, call, relay)
+	}()
+
+	timer := time.NewTimer(idle)
+	defer timer.Stop()
+	for {
+		select {
+		case result := <-done:
+			return relay.merge(result)
+		case <-relay.activity:
+			timer.Reset(idle)
+		case <-timer.C:
+			if relay.busy() {
+				timer.Reset(idle)
+				continue
+			}
+			return relay.merge(ToolResult{
+				CallID: call.ID,
+				Error:  fmt.Sprintf("plugin %q sent no progress for %s", call.Plugin, idle),
+			})
+		case <-callCtx.Done():
+			return relay.merge(ToolResult{
+				CallID: call.ID,
+				Error:  fmt.Sprintf("plugin %q bidi timed out after %s", call.Plugin, bidiTimeout),
+			})
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+	pkgchannel "github.com/opentalon/opentalon/pkg/channel"
+)
+
+// progressExecutor is a BidiExecutor whose body reports progress through
+// the CallbackHandler it is given.
+type progressExecutor struct {
+	body func(ctx context.Context, call ToolCall, ph ProgressHandler) ToolResult
+}
+
+func (e *progressExecutor) Execute(_ context.Context, call ToolCall) ToolResult {
+	return ToolResult{CallID: call.ID, Error: "unary not supported"}
+}
+
+func (e *progressExecutor) ExecuteBidi(ctx context.Context, call ToolCall, cb CallbackHandler) ToolResult {
+	return e.body(ctx, call, cb.(ProgressHandler))
+}
+
+func newProgressOrch(t *testing.T, body func(ctx context.Context, call ToolCall, ph ProgressHandler) ToolResult) *Orchestrator {
+	t.Helper()
+	reg := NewToolRegistry()
+	_ = reg.Register(PluginCapability{Name: "analyzer", Description: "Code analysis", SupportsProgress: true,
+		Actions: []Action{{Name: "scan", Description: "Scan the repo"}}}, &progressExecutor{body: body})
+	orch := NewWithRules(&fakeLLM{}, &fakeParser{}, reg, state.NewMemoryStore(""), state.NewSessionStore(""), OrchestratorOpts{})
+	orch.guard.Timeout = 50 * time.Millisecond
+	return orch
+}
+
+func TestToolProgress_StatusAndPartialResults(t *testing.T) {
+	orch := newProgressOrch(t, func(ctx context.Context, call ToolCall, ph ProgressHandler) ToolResult {
+		// Reporting more often than the idle timeout keeps a long call alive.
+		for i, part := range []string{"a.go: ok\n", "b.go: 2 issues\n", "c.go: ok\n"} {
+			time.Sleep(30 * time.Millisecond)
+			ph.ToolProgress(ctx, call, ToolProgress{Message: "Scanned " + part[:4], Partial: part, Percent: (i + 1) * 33})
+		}
+		return ToolResult{CallID: call.ID, Content: "3 files scanned"}
+	})
+	var mu sync.Mutex
+	var statuses []string
+	ctx := pkgchannel.WithStatus(context.Background(), func(_ context.Context, s string) {
+		mu.Lock()
+		statuses = append(statuses, s)
+		mu.Unlock()
+	})
+
+	res := orch.executeCall(ctx, ToolCall{ID: "c1", Plugin: "analyzer", Action: "scan"})
+	if res.Error != "" {
+		t.Fatalf("call failed: %s", res.Error)
+	}
+	if want := "a.go: ok\nb.go: 2 issues\nc.go: ok\n3 files scanned"; res.Content != want {
+		t.Errorf("content = %q, want %q", res.Content, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(statuses) != 3 || statuses[1] != "Running analyzer → scan… Scanned b.go (66%)" {
+		t.Errorf("statuses = %q", statuses)
+	}
+}
+
+func TestToolProgress_IdleTimeoutKeepsPartialOutput(t *testing.T) {
+	orch := newProgressOrch(t, func(ctx context.Context, call ToolCall, ph ProgressHandler) ToolResult {
+		ph.ToolProgress(ctx, call, ToolProgress{Partial: "first half"})
+		<-ctx.Done()
+		return ToolResult{CallID: call.ID, Error: ctx.Err().Error()}
+	})
+
+	res := orch.executeCall(context.Background(), ToolCall{ID: "c1", Plugin: "analyzer", Action: "scan"})
+	if !strings.Contains(res.Error, "sent no progress for 50ms") || !strings.Contains(res.Error, "first half") {
+		t.Errorf("result = %+v, want the idle timeout with the partial output", res)
+	}
+}
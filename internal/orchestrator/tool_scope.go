@@ -0,0 +1,123 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/router"
+)
+
+// ToolScope narrows the plugins a turn can see and call by where it comes
+// from: a channel, a profile group or the detected task type. A scope
+// applies when every condition it sets holds (an unset one always holds),
+// and every scope that applies narrows the set, so a finance channel scope
+// and a support group scope both take effect for a support agent in the
+// finance channel. Scopes only ever remove plugins: they cannot grant one
+// the profile or agent hides. Host built-ins ("_"-prefixed) are exempt.
+type ToolScope struct {
+	Name     string
+	Channels []string // channel ids; empty = every channel
+	Groups   []string // profile groups; empty = every group
+	Tasks    []string // router task types (code, chat, ...); empty = every task
+	Plugins  []string // only these plugins; empty = any
+	Deny     []string // never these plugins
+}
+
+// taskTypes are the router.TaskClassifier results a scope can match.
+var taskTypes = []router.TaskType{
+	router.TaskCode, router.TaskChat, router.TaskAnalysis,
+	router.TaskTransform, router.TaskDeepConversation, router.TaskGeneral,
+}
+
+// Validate requires the scope to narrow something and its task types to
+// be ones the classifier returns.
+func (s ToolScope) Validate() error {
+	if len(s.Plugins) == 0 && len(s.Deny) == 0 {
+		return fmt.Errorf("tool scope %q: set plugins or deny", s.Name)
+	}
+	for _, t := range s.Tasks {
+		if !slices.Contains(taskTypes, router.TaskType(t)) {
+			return fmt.Errorf("tool scope %q: unknown task %q (want one of %v)", s.Name, t, taskTypes)
+		}
+	}
+	return nil
+}
+
+// matches reports whether every condition of s holds for ctx.
+func (s ToolScope) matches(ctx context.Context) bool {
+	if len(s.Channels) > 0 && !slices.Contains(s.Channels, currentChannelID(ctx)) {
+		return false
+	}
+	if len(s.Groups) > 0 {
+		if prof := profile.FromContext(ctx); prof == nil || !slices.Contains(s.Groups, prof.Group) {
+			return false
+		}
+	}
+	if len(s.Tasks) > 0 && !slices.Contains(s.Tasks, string(taskTypeFromContext(ctx))) {
+		return false
+	}
+	return true
+}
+
+// pluginScope is the combined effect of the scopes that apply to a turn.
+type pluginScope struct {
+	allow  map[string]bool // nil = any plugin
+	deny   map[string]bool
+	scopes []string // names of the applied scopes, for logs
+}
+
+// admits reports whether plugin (or one of its aliases) passes the scope.
+func (s pluginScope) admits(o *Orchestrator, plugin string) bool {
+	if s.deny != nil && o.nameAllowed(plugin, s.deny) {
+		return false
+	}
+	return s.allow == nil || o.nameAllowed(plugin, s.allow)
+}
+
+// resolveToolScope intersects the plugin lists and unions the deny lists of
+// every scope that applies to ctx.
+func (o *Orchestrator) resolveToolScope(ctx context.Context) pluginScope {
+	var out pluginScope
+	for _, s := range o.toolScopes {
+		if !s.matches(ctx) {
+			continue
+		}
+		out.scopes = append(out.scopes, s.Name)
+		if len(s.Plugins) > 0 {
+			next := make(map[string]bool, len(s.Plugins))
+			for _, p := range s.Plugins {
+				if out.allow == nil || out.allow[p] {
+					next[p] = true
+				}
+			}
+			out.allow = next
+		}
+		for _, p := range s.Deny {
+			if out.deny == nil {
+				out.deny = make(map[string]bool)
+			}
+			out.deny[p] = true
+		}
+	}
+	return out
+}
+
+// scopesUseTasks reports whether any scope matches on the task type, so Run only
+// classifies the turn when it matters.
+func (o *Orchestrator) scopesUseTasks() bool {
+	return slices.ContainsFunc(o.toolScopes, func(s ToolScope) bool { return len(s.Tasks) > 0 })
+}
+
+type taskTypeKey struct{}
+
+// withTaskType records the turn's detected task type for tool scopes.
+func withTaskType(ctx context.Context, t router.TaskType) context.Context {
+	return context.WithValue(ctx, taskTypeKey{}, t)
+}
+
+func taskTypeFromContext(ctx context.Context) router.TaskType {
+	t, _ := ctx.Value(taskTypeKey{}).(router.TaskType)
+	return t
+}
@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/router"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func newScopedOrch(t *testing.T, scopes ...ToolScope) *Orchestrator {
+	t.Helper()
+	reg := NewToolRegistry()
+	for _, name := range []string{"gitlab", "jira", "ledger"} {
+		_ = reg.Register(PluginCapability{Name: name, Description: name,
+			Actions: []Action{{Name: "run", Description: "Run " + name}}}, &bulkExecutor{out: "ok"})
+	}
+	return NewWithRules(&fakeLLM{}, &fakeParser{}, reg, state.NewMemoryStore(""), state.NewSessionStore(""),
+		OrchestratorOpts{ToolScopes: scopes})
+}
+
+func TestToolScopes_NarrowPromptAndCalls(t *testing.T) {
+	orch := newScopedOrch(t,
+		ToolScope{Name: "finance", Channels: []string{"finance-slack"}, Deny: []string{"gitlab"}},
+		ToolScope{Name: "accountants", Groups: []string{"accounting"}, Plugins: []string{"ledger", "jira"}},
+		ToolScope{Name: "chat-light", Tasks: []string{"chat"}, Plugins: []string{"ledger"}},
+	)
+	finance := actor.WithActor(context.Background(), "finance-slack:U1")
+
+	cases := []struct {
+		name    string
+		ctx     context.Context
+		visible []string
+		hidden  []string
+	}{
+		{"unscoped", actor.WithActor(context.Background(), "dev-slack:U1"), []string{"gitlab", "jira", "ledger"}, nil},
+		{"channel deny", finance, []string{"jira", "ledger"}, []string{"gitlab"}},
+		{"channel and group", profile.WithProfile(finance, &profile.Profile{Group: "accounting"}), []string{"jira", "ledger"}, []string{"gitlab"}},
+		{"group and task intersect", withTaskType(profile.WithProfile(context.Background(), &profile.Profile{Group: "accounting"}), router.TaskChat), []string{"ledger"}, []string{"gitlab", "jira"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompt := orch.buildSystemPrompt(tc.ctx, "hi", true)
+			for _, p := range tc.visible {
+				if !strings.Contains(prompt, p+"__run") {
+					t.Errorf("%s missing from prompt:\n%s", p, prompt)
+				}
+				if r := orch.executeCall(tc.ctx, ToolCall{ID: "c", Plugin: p, Action: "run", FromLLM: true}); r.Error != "" {
+					t.Errorf("%s call refused: %s", p, r.Error)
+				}
+			}
+			for _, p := range tc.hidden {
+				if strings.Contains(prompt, p+"__run") {
+					t.Errorf("%s in prompt despite scope:\n%s", p, prompt)
+				}
+				if r := orch.executeCall(tc.ctx, ToolCall{ID: "c", Plugin: p, Action: "run", FromLLM: true}); !strings.Contains(r.Error, "not available") {
+					t.Errorf("%s call = %+v, want refused", p, r)
+				}
+			}
+		})
+	}
+
+	// Internal calls (preparers, pipelines) are not scoped.
+	if r := orch.executeCall(finance, ToolCall{ID: "c", Plugin: "gitlab", Action: "run"}); r.Error != "" {
+		t.Errorf("internal call refused: %s", r.Error)
+	}
+}
+
+func TestToolScopes_Validate(t *testing.T) {
+	for _, s := range []ToolScope{
+		{Name: "empty", Channels: []string{"x"}},
+		{Name: "bad task", Tasks: []string{"coding"}, Deny: []string{"gitlab"}},
+	} {
+		if err := s.Validate(); err == nil {
+			t.Errorf("%s: accepted", s.Name)
+		}
+	}
+	if err := (ToolScope{Tasks: []string{"code"}, Plugins: []string{"gitlab"}}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
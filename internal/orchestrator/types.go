@@ -1,9 +1,14 @@
 package orchestrator
 
+import "time"
+
 type Parameter struct {
-	Name        string `yaml:"name"`
-	Description string `yaml:"description"`
-	Required    bool   `yaml:"required"`
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description"`
+	Required    bool     `yaml:"required"`
+	Type        string   `yaml:"type,omitempty"`  // string (default), number, integer, boolean or array; see ParameterFromWire
+	Enum        []string `yaml:"enum,omitempty"`  // allowed values of a string parameter
+	Items       string   `yaml:"items,omitempty"` // element type of an array parameter; empty = any
 }
 
 // Action describes one action a plugin supports.
@@ -23,19 +28,21 @@ type Parameter struct {
 // visible state (list/show/get/query). Default false — fail-safe to
 // "treat as potential write".
 type Action struct {
-	Name              string      `yaml:"name"`
-	Description       string      `yaml:"description"`
-	Parameters        []Parameter `yaml:"parameters,omitempty"`
-	InjectContextArgs []string    `yaml:"inject_context_args,omitempty"`
-	AuditLog          bool        `yaml:"audit_log,omitempty"`      // if true, log invocation for audit
-	UserOnly          bool        `yaml:"user_only,omitempty"`      // if true, hidden from LLM and blocked from LLM-sourced calls
-	AlwaysInclude     bool        `yaml:"always_include,omitempty"` // RFC #249 Phase 4: pin to Tier 0 regardless of RAG score
-	ReadOnly          bool        `yaml:"read_only,omitempty"`      // if true, skip per-call user-confirmation gate (pure query)
+	Name              string        `yaml:"name"`
+	Description       string        `yaml:"description"`
+	Parameters        []Parameter   `yaml:"parameters,omitempty"`
+	InjectContextArgs []string      `yaml:"inject_context_args,omitempty"`
+	AuditLog          bool          `yaml:"audit_log,omitempty"`      // if true, log invocation for audit
+	UserOnly          bool          `yaml:"user_only,omitempty"`      // if true, hidden from LLM and blocked from LLM-sourced calls
+	AlwaysInclude     bool          `yaml:"always_include,omitempty"` // RFC #249 Phase 4: pin to Tier 0 regardless of RAG score
+	ReadOnly          bool          `yaml:"read_only,omitempty"`      // if true, skip per-call user-confirmation gate (pure query)
+	CacheTTL          time.Duration `yaml:"cache_ttl,omitempty"`      // how long a read-only result may be reused when the tool cache is on; 0 = its default
 }
 
 type PluginCapability struct {
 	Name                 string             `yaml:"name"`
 	Description          string             `yaml:"description"`
+	Version              string             `yaml:"version,omitempty"` // release of the plugin or package set, shown next to its name in the system prompt
 	Actions              []Action           `yaml:"actions"`
 	AllowedGroups        []string           `yaml:"allowed_groups,omitempty"`         // empty = unrestricted; when set, only listed groups can use this plugin
 	SystemPromptAddition string             `yaml:"system_prompt_addition,omitempty"` // optional text appended to LLM system prompt when this plugin is loaded
@@ -46,6 +53,19 @@ type PluginCapability struct {
 	// mid-execution. False (default) means the host uses the unary
 	// Execute path — existing plugins unchanged.
 	SupportsCallbacks bool `yaml:"supports_callbacks,omitempty"`
+	// SupportsProgress declares the plugin reports progress (status lines
+	// and partial results) while an action runs. The host dispatches its
+	// actions over ExecuteBidi as well and relays the reports.
+	SupportsProgress bool `yaml:"supports_progress,omitempty"`
+}
+
+// heading is the capability's name as the system prompt shows it: with
+// its version when it has one, so two releases of a tool read apart.
+func (c PluginCapability) heading() string {
+	if c.Version == "" {
+		return c.Name
+	}
+	return c.Name + " (" + c.Version + ")"
 }
 
 // KnowledgeArticle is one self-contained reference section a plugin
@@ -95,13 +115,14 @@ type ToolResult struct {
 }
 
 type WorkflowStep struct {
-	Plugin string `yaml:"plugin"`
-	Action string `yaml:"action"`
-	Order  int    `yaml:"order"`
+	Plugin string            `yaml:"plugin" json:"plugin"`
+	Action string            `yaml:"action" json:"action"`
+	Order  int               `yaml:"order" json:"order"`
+	Args   map[string]string `yaml:"args,omitempty" json:"args,omitempty"`
 }
 
 type Workflow struct {
-	Trigger string         `yaml:"trigger"`
-	Steps   []WorkflowStep `yaml:"steps"`
-	Outcome string         `yaml:"outcome"`
+	Trigger string         `yaml:"trigger" json:"trigger"`
+	Steps   []WorkflowStep `yaml:"steps" json:"steps"`
+	Outcome string         `yaml:"outcome" json:"outcome"`
 }
@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/opentalon/opentalon/internal/logger"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// MetaUsage holds the running LLM usage of a session as JSON (SessionUsage),
+// updated after every turn that called the model.
+const MetaUsage = "usage"
+
+// RunUsage is what one Run spent on the model: every agent-loop round,
+// confirmation narration and subagent call made on its behalf. Background
+// work after the reply (titles, summaries) is not included.
+type RunUsage struct {
+	LLMCalls         int
+	InputTokens      int
+	OutputTokens     int
+	CacheReadTokens  int
+	CacheWriteTokens int
+	Cost             float64 // at the models' configured prices; 0 when none are set
+}
+
+// Tokens is every token the run sent or received, cached ones included.
+func (u RunUsage) Tokens() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheWriteTokens
+}
+
+// SessionUsage is the accumulated RunUsage of a session's turns, stored
+// under MetaUsage.
+type SessionUsage struct {
+	Turns            int     `json:"turns"`
+	LLMCalls         int     `json:"llm_calls"`
+	InputTokens      int     `json:"input_tokens"`
+	OutputTokens     int     `json:"output_tokens"`
+	CacheReadTokens  int     `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int     `json:"cache_write_tokens,omitempty"`
+	Cost             float64 `json:"cost,omitempty"`
+}
+
+// SessionUsageOf reads the usage recorded on sess; zero when none is.
+func SessionUsageOf(sess *state.Session) SessionUsage {
+	var u SessionUsage
+	if sess != nil && sess.Metadata[MetaUsage] != "" {
+		_ = json.Unmarshal([]byte(sess.Metadata[MetaUsage]), &u)
+	}
+	return u
+}
+
+func (s *SessionUsage) add(u RunUsage) {
+	s.Turns++
+	s.LLMCalls += u.LLMCalls
+	s.InputTokens += u.InputTokens
+	s.OutputTokens += u.OutputTokens
+	s.CacheReadTokens += u.CacheReadTokens
+	s.CacheWriteTokens += u.CacheWriteTokens
+	s.Cost += u.Cost
+}
+
+// runUsage accumulates a Run's usage. Subagents may call the model from
+// other goroutines, hence the lock.
+type runUsage struct {
+	mu sync.Mutex
+	u  RunUsage
+}
+
+type runUsageKey struct{}
+
+func withRunUsage(ctx context.Context, u *runUsage) context.Context {
+	return context.WithValue(ctx, runUsageKey{}, u)
+}
+
+// recordUsage adds the usage of one LLM response to the Run in ctx, if any.
+func recordUsage(ctx context.Context, resp *provider.CompletionResponse) {
+	t, _ := ctx.Value(runUsageKey{}).(*runUsage)
+	if t == nil || resp == nil {
+		return
+	}
+	t.mu.Lock()
+	t.u.LLMCalls++
+	t.u.InputTokens += resp.Usage.InputTokens
+	t.u.OutputTokens += resp.Usage.OutputTokens
+	t.u.CacheReadTokens += resp.Usage.CacheReadTokens
+	t.u.CacheWriteTokens += resp.Usage.CacheWriteTokens
+	t.u.Cost += resp.Usage.Cost
+	t.mu.Unlock()
+}
+
+func (t *runUsage) snapshot() RunUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.u
+}
+
+// persistUsage adds u to the session's running totals.
+func persistUsage(ctx context.Context, sessions SessionStoreInterface, sessionID string, u RunUsage) {
+	if u.LLMCalls == 0 {
+		return
+	}
+	sess, _ := sessions.Get(sessionID)
+	if sess == nil {
+		return
+	}
+	total := SessionUsageOf(sess)
+	total.add(u)
+	data, _ := json.Marshal(total)
+	if err := sessions.SetMetadata(sessionID, MetaUsage, string(data)); err != nil {
+		logger.FromContext(ctx).Warn("recording session usage failed", "error", err)
+	}
+}
@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// usageLLM answers like fakeLLM and reports usage on every response.
+type usageLLM struct {
+	fakeLLM
+	usage provider.Usage
+}
+
+func (u *usageLLM) Complete(ctx context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	resp, err := u.fakeLLM.Complete(ctx, req)
+	if resp != nil {
+		resp.Usage = u.usage
+	}
+	return resp, err
+}
+
+func TestRunUsage_ResultAndSessionTotals(t *testing.T) {
+	llm := &usageLLM{
+		fakeLLM: fakeLLM{responses: []string{"[tool] gitlab.analyze_code repo=r", "Looks good.", "Still good."}},
+		usage:   provider.Usage{InputTokens: 1000, OutputTokens: 200, CacheReadTokens: 3000, Cost: 0.01},
+	}
+	calls := 0
+	parser := &fakeParser{parseFn: func(string) []ToolCall {
+		calls++
+		if calls == 1 {
+			return []ToolCall{{ID: "c1", Plugin: "gitlab", Action: "analyze_code", Args: map[string]string{"repo": "r"}}}
+		}
+		return nil
+	}}
+	orch, sessID := setupOrchestrator(llm, parser)
+
+	result, err := orch.Run(context.Background(), sessID, "Analyze r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := result.Usage
+	if u == nil || u.LLMCalls != 2 || u.InputTokens != 2000 || u.OutputTokens != 400 || u.CacheReadTokens != 6000 {
+		t.Fatalf("usage = %+v; want two rounds summed", u)
+	}
+	if u.Tokens() != 8400 || u.Cost < 0.0199 || u.Cost > 0.0201 {
+		t.Errorf("tokens = %d cost = %v", u.Tokens(), u.Cost)
+	}
+
+	if _, err := orch.Run(context.Background(), sessID, "And now?"); err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := orch.sessions.Get(sessID)
+	total := SessionUsageOf(sess)
+	if total.Turns != 2 || total.LLMCalls != 3 || total.InputTokens != 3000 || total.OutputTokens != 600 {
+		t.Errorf("session usage = %+v; want both turns accumulated", total)
+	}
+}
+
+func TestSessionUsageOf_Unset(t *testing.T) {
+	if u := SessionUsageOf(nil); u != (SessionUsage{}) {
+		t.Errorf("nil session: %+v", u)
+	}
+}
@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"context"
+	"maps"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/logger"
+)
+
+// minRecordedSteps is the fewest successful plugin calls a turn needs to be
+// recorded as a workflow; a single call is cheaper to redo than to replay.
+const minRecordedSteps = 2
+
+// maxOutcomeLen caps the answer excerpt kept as a recording's outcome.
+const maxOutcomeLen = 300
+
+// WorkflowRecorder persists the plugin calls of a finished turn so they can
+// be replayed later without the LLM (see package workflow).
+type WorkflowRecorder interface {
+	RecordWorkflow(ctx context.Context, wf Workflow) error
+}
+
+// maybeRecordWorkflow hands the turn's successful plugin calls, in order and
+// with their arguments, to the workflow recorder. Failed calls and internal
+// tools (plugins starting with "_") are left out; turns with fewer than
+// minRecordedSteps calls left are not recorded.
+func (o *Orchestrator) maybeRecordWorkflow(ctx context.Context, userMessage string, res *RunResult) {
+	if o.workflowRecorder == nil || res == nil {
+		return
+	}
+	var steps []WorkflowStep
+	for i, call := range res.ToolCalls {
+		if i >= len(res.Results) || res.Results[i].Error != "" || strings.HasPrefix(call.Plugin, "_") {
+			continue
+		}
+		steps = append(steps, WorkflowStep{Plugin: call.Plugin, Action: call.Action, Order: len(steps) + 1, Args: maps.Clone(call.Args)})
+	}
+	if len(steps) < minRecordedSteps {
+		return
+	}
+	outcome := res.Response
+	if r := []rune(outcome); len(r) > maxOutcomeLen {
+		outcome = string(r[:maxOutcomeLen]) + "…"
+	}
+	wf := Workflow{Trigger: strings.TrimSpace(userMessage), Steps: steps, Outcome: outcome}
+	if err := o.workflowRecorder.RecordWorkflow(ctx, wf); err != nil {
+		logger.FromContext(ctx).Warn("recording workflow failed", "steps", len(steps), "error", err)
+	}
+}
@@ -0,0 +1,54 @@
+package orchestrator
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeWorkflowRecorder struct {
+	got []Workflow
+}
+
+func (f *fakeWorkflowRecorder) RecordWorkflow(_ context.Context, wf Workflow) error {
+	f.got = append(f.got, wf)
+	return nil
+}
+
+func TestMaybeRecordWorkflow(t *testing.T) {
+	rec := &fakeWorkflowRecorder{}
+	o := &Orchestrator{workflowRecorder: rec}
+	ctx := context.Background()
+
+	o.maybeRecordWorkflow(ctx, " tag the release ", &RunResult{
+		Response: "Tagged v1.2 and posted the notes.",
+		ToolCalls: []ToolCall{
+			{Plugin: "git", Action: "tag", Args: map[string]string{"version": "v1.2"}},
+			{Plugin: "_meta", Action: "load_tools"},
+			{Plugin: "slack", Action: "post", Args: map[string]string{"text": "bad"}},
+			{Plugin: "slack", Action: "post", Args: map[string]string{"text": "v1.2 is out"}},
+		},
+		Results: []ToolResult{{Content: "ok"}, {Content: "ok"}, {Error: "rate limited"}, {Content: "ok"}},
+	})
+	if len(rec.got) != 1 {
+		t.Fatalf("recorded %d workflows, want 1", len(rec.got))
+	}
+	wf := rec.got[0]
+	if wf.Trigger != "tag the release" || wf.Outcome != "Tagged v1.2 and posted the notes." {
+		t.Errorf("trigger/outcome = %q / %q", wf.Trigger, wf.Outcome)
+	}
+	if len(wf.Steps) != 2 {
+		t.Fatalf("steps = %+v; internal and failed calls should be left out", wf.Steps)
+	}
+	if s := wf.Steps[1]; s.Plugin != "slack" || s.Order != 2 || s.Args["text"] != "v1.2 is out" {
+		t.Errorf("second step = %+v", s)
+	}
+
+	// A single successful call is not worth recording.
+	o.maybeRecordWorkflow(ctx, "hi", &RunResult{
+		ToolCalls: []ToolCall{{Plugin: "git", Action: "status"}},
+		Results:   []ToolResult{{Content: "clean"}},
+	})
+	if len(rec.got) != 1 {
+		t.Errorf("a one-call turn was recorded")
+	}
+}
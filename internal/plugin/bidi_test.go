@@ -213,3 +213,45 @@ type stringError string
 func (e stringError) Error() string { return string(e) }
 
 var errBoom = stringError("boom")
+
+// progressRecorder is a CallbackHandler that also takes progress frames.
+type progressRecorder struct {
+	recordingCallbackHandler
+	progress []orchestrator.ToolProgress
+}
+
+func (p *progressRecorder) ToolProgress(_ context.Context, _ orchestrator.ToolCall, tp orchestrator.ToolProgress) {
+	p.progress = append(p.progress, tp)
+}
+
+func TestClient_ExecuteBidi_Progress(t *testing.T) {
+	body := func(ctx context.Context, req pkg.Request, host pkg.HostCaller) pkg.Response {
+		_ = pkg.ReportProgress(ctx, host, pkg.Progress{Message: "indexing", Percent: 40, Partial: "part 1\n"})
+		_ = pkg.ReportProgress(ctx, host, pkg.Progress{Partial: "part 2\n", Percent: 250})
+		return pkg.Response{CallID: req.ID, Content: "done"}
+	}
+	client := startBidiServer(t, body)
+
+	cb := &progressRecorder{}
+	result := client.ExecuteBidi(context.Background(), orchestrator.ToolCall{ID: "c4"}, cb)
+	if result.Error != "" || result.Content != "done" {
+		t.Fatalf("result = %+v", result)
+	}
+	want := []orchestrator.ToolProgress{
+		{Message: "indexing", Partial: "part 1\n", Percent: 40},
+		{Partial: "part 2\n", Percent: 100},
+	}
+	if len(cb.progress) != len(want) {
+		t.Fatalf("progress = %+v, want %+v", cb.progress, want)
+	}
+	for i := range want {
+		if cb.progress[i] != want[i] {
+			t.Errorf("progress[%d] = %+v, want %+v", i, cb.progress[i], want[i])
+		}
+	}
+
+	// A handler without ProgressHandler drops the frames.
+	if r := client.ExecuteBidi(context.Background(), orchestrator.ToolCall{ID: "c5"}, &recordingCallbackHandler{}); r.Content != "done" {
+		t.Errorf("result without progress handler = %+v", r)
+	}
+}
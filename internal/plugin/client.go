@@ -147,7 +147,8 @@ func (c *Client) ExecuteContext(ctx context.Context, call orchestrator.ToolCall)
 
 // ExecuteBidi sends a tool call to the plugin over the bidirectional
 // streaming RPC, dispatches any inbound CallbackRequest frames via
-// cb.RunAction, and returns the plugin's final ToolResultResponse.
+// cb.RunAction, hands ToolProgress frames to cb when it is a
+// ProgressHandler, and returns the plugin's final ToolResultResponse.
 // Implements orchestrator.BidiExecutor. The orchestrator picks this
 // path when the plugin's PluginCapability.SupportsCallbacks is true.
 func (c *Client) ExecuteBidi(ctx context.Context, call orchestrator.ToolCall, cb orchestrator.CallbackHandler) orchestrator.ToolResult {
@@ -198,6 +199,15 @@ func (c *Client) ExecuteBidi(ctx context.Context, call orchestrator.ToolCall, cb
 			// fire multiple callbacks in parallel; today the SDK
 			// serialises them, but the wire protocol allows parallel).
 			go c.handleCallback(ctx, stream, payload.CallbackRequest, cb)
+		case *pluginpb.PluginMessage_Progress:
+			if ph, ok := cb.(orchestrator.ProgressHandler); ok {
+				p := payload.Progress
+				ph.ToolProgress(ctx, call, orchestrator.ToolProgress{
+					Message: p.GetMessage(),
+					Partial: p.GetPartialContent(),
+					Percent: int(p.GetPercent()),
+				})
+			}
 		default:
 			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("grpc bidi: unknown payload %T", payload)}
 		}
@@ -252,11 +262,7 @@ func toPluginCapability(pb *pluginpb.PluginCapabilities) orchestrator.PluginCapa
 	for i, a := range pb.Actions {
 		params := make([]orchestrator.Parameter, len(a.Parameters))
 		for j, p := range a.Parameters {
-			params[j] = orchestrator.Parameter{
-				Name:        p.Name,
-				Description: p.Description,
-				Required:    p.Required,
-			}
+			params[j] = orchestrator.ParameterFromWire(p.Name, p.Description, p.Type, p.Required)
 		}
 		actions[i] = orchestrator.Action{
 			Name:              a.Name,
@@ -284,5 +290,6 @@ func toPluginCapability(pb *pluginpb.PluginCapabilities) orchestrator.PluginCapa
 		SystemPromptAddition: pb.SystemPromptAddition,
 		KnowledgeArticles:    knowledge,
 		SupportsCallbacks:    pb.SupportsCallbacks,
+		SupportsProgress:     pb.SupportsProgress,
 	}
 }
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -26,9 +28,27 @@ type PluginEntry struct {
 	Plugin      string // path to binary or grpc://...
 	Enabled     bool
 	Config      map[string]interface{}
-	Env         []string      // if non-nil, used as the subprocess env verbatim; use WithEnvOverride to build it
+	Env         []string      // if non-nil, used as the subprocess env verbatim; use WithEnvOverride or IsolatedEnv to build it
+	WorkDir     string        // subprocess working directory, created on launch if missing ("" = inherit)
 	DialTimeout time.Duration // overrides defaultDialTimeout for the gRPC Init call (0 = use default)
 	ExposeHTTP  bool          // operator opt-in: reverse-proxy /{name}/* through the webhook server
+	Alias       string        // registers the plugin's tools under this name instead of the one it reports
+	Version     string        // shown next to the plugin's name in the system prompt
+}
+
+// capability applies the entry's name, alias and version to the
+// capability the plugin reported.
+func (e PluginEntry) capability(cap orchestrator.PluginCapability) orchestrator.PluginCapability {
+	switch {
+	case e.Alias != "":
+		cap.Name = e.Alias
+	case cap.Name == "":
+		cap.Name = e.Name
+	}
+	if e.Version != "" {
+		cap.Version = e.Version
+	}
+	return cap
 }
 
 // WithEnvOverride starts from the current process environment (or the entry's
@@ -50,10 +70,51 @@ func (e *PluginEntry) WithEnvOverride(key, value string) {
 	e.Env = result
 }
 
+// isolatedBase are the variables every isolated plugin gets so it can find
+// binaries, a home and a temp dir, and format text; anything else must be
+// allowlisted.
+var isolatedBase = []string{"PATH", "HOME", "TMPDIR", "LANG", "TZ"}
+
+// IsolatedEnv returns the subset of the current process environment a plugin
+// may see: the base variables plus every name in allow. A name ending in *
+// matches by prefix ("AWS_*"). The result is non-nil even when nothing
+// matches, so the plugin never falls back to inheriting the full environment.
+func IsolatedEnv(allow []string) []string {
+	env := []string{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if envAllowed(name, isolatedBase) || envAllowed(name, allow) {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+func envAllowed(name string, allow []string) bool {
+	for _, a := range allow {
+		if prefix, ok := strings.CutSuffix(a, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == a {
+			return true
+		}
+	}
+	return false
+}
+
 type managed struct {
-	entry   PluginEntry
-	process *Process
-	client  *Client
+	entry      PluginEntry
+	registered string // name in the tool registry: the alias, or the name the plugin reported
+	process    *Process
+	client     *Client
+}
+
+func (mg *managed) registryName() string {
+	if mg.registered != "" {
+		return mg.registered
+	}
+	return mg.entry.Name
 }
 
 // PluginLoadedFunc is called after a plugin is successfully loaded and registered.
@@ -66,6 +127,7 @@ type Manager struct {
 	mu             sync.Mutex
 	plugins        map[string]*managed
 	known          map[string]PluginEntry // all configured entries, including those that failed to load
+	failures       map[string]string      // why a plugin last failed to load or exited; cleared on load
 	registry       *orchestrator.ToolRegistry
 	onPluginLoaded PluginLoadedFunc
 }
@@ -76,6 +138,7 @@ func NewManager(registry *orchestrator.ToolRegistry) *Manager {
 	return &Manager{
 		plugins:  make(map[string]*managed),
 		known:    make(map[string]PluginEntry),
+		failures: make(map[string]string),
 		registry: registry,
 	}
 }
@@ -90,7 +153,8 @@ func (m *Manager) OnPluginLoaded(fn PluginLoadedFunc) {
 }
 
 // RefreshCapabilities asks a loaded plugin to re-fetch its capabilities from its
-// upstream source and returns the fresh set. The caller updates the registry and
+// upstream source and returns the fresh set, under the name it is registered
+// with (its alias, when configured). The caller updates the registry and
 // re-syncs the corpus. Plugins that don't support refresh return a gRPC
 // Unimplemented error (inspect via status.Code).
 func (m *Manager) RefreshCapabilities(ctx context.Context, name string) (orchestrator.PluginCapability, error) {
@@ -100,7 +164,11 @@ func (m *Manager) RefreshCapabilities(ctx context.Context, name string) (orchest
 	if !ok {
 		return orchestrator.PluginCapability{}, fmt.Errorf("plugin %q not loaded", name)
 	}
-	return mg.client.RefreshCapabilities(ctx)
+	cap, err := mg.client.RefreshCapabilities(ctx)
+	if err != nil {
+		return cap, err
+	}
+	return mg.entry.capability(cap), nil
 }
 
 // LoadAll launches all enabled plugins and registers them. Plugins that fail
@@ -134,13 +202,16 @@ func (m *Manager) LoadAll(ctx context.Context, entries []PluginEntry) error {
 // Load launches a single plugin and registers it.
 func (m *Manager) Load(ctx context.Context, entry PluginEntry) error {
 	name, err := m.loadLocked(ctx, entry)
+	m.mu.Lock()
 	if err != nil {
+		m.failures[entry.Name] = err.Error()
+		m.mu.Unlock()
 		return err
 	}
+	delete(m.failures, entry.Name)
 
 	// Fire callback outside the lock to avoid deadlocks if the callback
 	// calls back into the manager.
-	m.mu.Lock()
 	fn := m.onPluginLoaded
 	m.mu.Unlock()
 	if fn != nil {
@@ -176,10 +247,7 @@ func (m *Manager) loadLocked(ctx context.Context, entry PluginEntry) (string, er
 		return "", err
 	}
 
-	cap := client.Capability()
-	if cap.Name == "" {
-		cap.Name = entry.Name
-	}
+	cap := entry.capability(client.Capability())
 
 	if err := m.registry.Register(cap, client); err != nil {
 		_ = client.Close()
@@ -203,9 +271,10 @@ func (m *Manager) loadLocked(ctx context.Context, entry PluginEntry) (string, er
 	}
 
 	mg := &managed{
-		entry:   entry,
-		process: proc,
-		client:  client,
+		entry:      entry,
+		registered: cap.Name,
+		process:    proc,
+		client:     client,
 	}
 	m.plugins[entry.Name] = mg
 
@@ -256,6 +325,7 @@ func (m *Manager) watchProcess(ctx context.Context, name string, proc *Process)
 			current, ok := m.plugins[name]
 			if ok && current.process == proc {
 				delete(m.plugins, name)
+				m.failures[name] = fmt.Sprintf("exited: %v", exitErr)
 			} else {
 				ok = false
 			}
@@ -264,7 +334,7 @@ func (m *Manager) watchProcess(ctx context.Context, name string, proc *Process)
 				if current.client != nil {
 					_ = current.client.Close()
 				}
-				m.registry.Deregister(name)
+				m.registry.Deregister(current.registryName())
 			}
 		case <-ctx.Done():
 		}
@@ -279,10 +349,22 @@ func (m *Manager) dialTimeout(entry PluginEntry) time.Duration {
 }
 
 func (m *Manager) launchBinary(ctx context.Context, entry PluginEntry) (*Process, *Client, error) {
-	proc := NewProcess(entry.Plugin)
-	if len(entry.Env) > 0 {
+	path := entry.Plugin
+	if entry.WorkDir != "" {
+		// A relative binary path would otherwise resolve against WorkDir.
+		if abs, err := filepath.Abs(path); err == nil && strings.ContainsRune(path, filepath.Separator) {
+			path = abs
+		}
+		if err := os.MkdirAll(entry.WorkDir, 0o700); err != nil {
+			return nil, nil, fmt.Errorf("work dir for %s: %w", entry.Name, err)
+		}
+	}
+	proc := NewProcess(path)
+	proc.SetName(entry.Name)
+	if entry.Env != nil {
 		proc.SetEnv(entry.Env)
 	}
+	proc.SetDir(entry.WorkDir)
 	hs, err := proc.Start(ctx, defaultHandshakeTimeout)
 	if err != nil {
 		return nil, nil, fmt.Errorf("start %s: %w", entry.Name, err)
@@ -356,7 +438,7 @@ func (m *Manager) Unload(name string) error {
 	delete(m.plugins, name)
 	m.mu.Unlock()
 
-	m.registry.Deregister(name)
+	m.registry.Deregister(mg.registryName())
 
 	if mg.client != nil {
 		_ = mg.client.Close()
@@ -433,6 +515,35 @@ func (m *Manager) List() []string {
 	return names
 }
 
+// PluginStatus is the health of one configured plugin.
+type PluginStatus struct {
+	Name   string `json:"name"`
+	Mode   string `json:"mode"` // "binary" or "grpc"
+	Loaded bool   `json:"loaded"`
+	Error  string `json:"error,omitempty"` // why it last failed to load or exited, until it loads again
+}
+
+// Status reports every configured plugin, loaded or not, sorted by name.
+func (m *Manager) Status() []PluginStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries := make(map[string]PluginEntry, len(m.known))
+	for name, e := range m.known {
+		entries[name] = e
+	}
+	for name, mg := range m.plugins {
+		entries[name] = mg.entry
+	}
+	out := make([]PluginStatus, 0, len(entries))
+	for name, e := range entries {
+		_, loaded := m.plugins[name]
+		out = append(out, PluginStatus{Name: name, Mode: string(detectPluginMode(e.Plugin)), Loaded: loaded, Error: m.failures[name]})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
 // Ready returns true when every configured (known) plugin has been loaded.
 func (m *Manager) Ready() bool {
 	m.mu.Lock()
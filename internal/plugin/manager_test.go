@@ -65,6 +65,44 @@ func TestWithEnvOverride(t *testing.T) {
 	}
 }
 
+func TestIsolatedEnv(t *testing.T) {
+	t.Setenv("PATH", "/usr/bin")
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "x")
+	t.Setenv("JIRA_TOKEN", "j")
+	t.Setenv("OPENAI_API_KEY", "leak")
+
+	got := map[string]bool{}
+	for _, kv := range IsolatedEnv([]string{"AWS_*", "JIRA_TOKEN"}) {
+		got[kv] = true
+	}
+	for _, want := range []string{"PATH=/usr/bin", "AWS_REGION=eu-west-1", "AWS_SECRET_ACCESS_KEY=x", "JIRA_TOKEN=j"} {
+		if !got[want] {
+			t.Errorf("missing %s", want)
+		}
+	}
+	if got["OPENAI_API_KEY=leak"] {
+		t.Error("a variable outside the allowlist reached the plugin")
+	}
+
+	if env := IsolatedEnv(nil); env == nil {
+		t.Error("IsolatedEnv must never return nil: a nil Env means inherit everything")
+	}
+}
+
+func TestEntryCapabilityAppliesAliasAndVersion(t *testing.T) {
+	reported := orchestrator.PluginCapability{Name: "jira", Version: "0.9"}
+	if got := (PluginEntry{Name: "jira-server"}).capability(reported); got.Name != "jira" || got.Version != "0.9" {
+		t.Errorf("no alias: %+v", got)
+	}
+	if got := (PluginEntry{Name: "jira-server", Alias: "jira_dc", Version: "9.12"}).capability(reported); got.Name != "jira_dc" || got.Version != "9.12" {
+		t.Errorf("alias: %+v", got)
+	}
+	if got := (PluginEntry{Name: "jira-server"}).capability(orchestrator.PluginCapability{}); got.Name != "jira-server" {
+		t.Errorf("unnamed plugin: %+v", got)
+	}
+}
+
 func TestWatchProcessCleansUpOnExit(t *testing.T) {
 	registry := orchestrator.NewToolRegistry()
 	m := NewManager(registry)
@@ -232,6 +270,31 @@ func TestReady_AllLoaded(t *testing.T) {
 	}
 }
 
+func TestStatus(t *testing.T) {
+	m := NewManager(orchestrator.NewToolRegistry())
+
+	m.mu.Lock()
+	m.known["b"] = PluginEntry{Name: "b", Plugin: "grpc://localhost:9000"}
+	m.known["a"] = PluginEntry{Name: "a", Plugin: "/bin/a"}
+	m.plugins["a"] = &managed{entry: m.known["a"]}
+	m.failures["b"] = "connection refused"
+	m.mu.Unlock()
+
+	got := m.Status()
+	want := []PluginStatus{
+		{Name: "a", Mode: "binary", Loaded: true},
+		{Name: "b", Mode: "grpc", Error: "connection refused"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Status() = %+v; want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Status()[%d] = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+}
+
 func TestMCPServerNames(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -8,6 +8,8 @@ import (
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -21,8 +23,10 @@ type Process struct {
 	path    string
 	args    []string
 	env     []string // if non-nil, used as cmd.Env (replaces inherited env)
+	dir     string   // if non-empty, used as cmd.Dir
 	cmd     *exec.Cmd
 	hs      pkg.Handshake
+	name    string // tags the plugin's log records; defaults to the binary name
 	exited  chan struct{}
 	exitErr error // set before exited is closed
 }
@@ -43,9 +47,25 @@ func (p *Process) SetEnv(env []string) {
 	p.env = env
 }
 
+// SetDir sets the subprocess working directory. If called before Start,
+// the plugin runs in dir instead of the parent's working directory.
+func (p *Process) SetDir(dir string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dir = dir
+}
+
+// SetName sets the name the plugin's log records are tagged with.
+func (p *Process) SetName(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.name = name
+}
+
 // Start launches the plugin binary and reads its handshake line from
 // stdout. The plugin must print "version|network|address\n" within
-// the given timeout.
+// the given timeout. The plugin is offered the protocol versions the core
+// speaks in pkg.EnvProtocolVersions.
 func (p *Process) Start(ctx context.Context, timeout time.Duration) (pkg.Handshake, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -55,10 +75,20 @@ func (p *Process) Start(ctx context.Context, timeout time.Duration) (pkg.Handsha
 	// request contexts (e.g. a reload_mcp tool call). Cancellation of ctx is
 	// respected only during the handshake phase below.
 	cmd := exec.Command(p.path, p.args...)
-	cmd.Stderr = os.Stderr
-	if len(p.env) > 0 {
-		cmd.Env = p.env
+	name := p.name
+	if name == "" {
+		name = filepath.Base(p.path)
+	}
+	stderr := &stderrLog{name: name, out: os.Stderr}
+	cmd.Stderr = stderr
+	// Until a handshake says otherwise, the plugin speaks the base version.
+	defer stderr.setVersion(pkg.HandshakeVersion)
+	env := p.env
+	if env == nil {
+		env = os.Environ()
 	}
+	cmd.Env = append(slices.Clip(env), pkg.EnvProtocolVersions+"="+pkg.ProtocolVersions())
+	cmd.Dir = p.dir
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -104,6 +134,7 @@ func (p *Process) Start(ctx context.Context, timeout time.Duration) (pkg.Handsha
 			return pkg.Handshake{}, err
 		}
 		p.hs = hs
+		stderr.setVersion(hs.Version)
 		return hs, nil
 	case err := <-hsErr:
 		_ = cmd.Process.Kill()
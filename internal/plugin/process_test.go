@@ -3,7 +3,9 @@ package plugin
 import (
 	"context"
 	"errors"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -70,6 +72,31 @@ func TestProcess_ExitErrCapturedFromRealProcess(t *testing.T) {
 	}
 }
 
+func TestProcess_EnvAndDir(t *testing.T) {
+	t.Setenv("OPENTALON_TEST_LEAK", "1")
+	dir := t.TempDir()
+	out := filepath.Join(dir, "seen")
+	proc := NewProcess("/bin/sh", "-c", `printf '%s|%s' "$(pwd)" "$OPENTALON_TEST_LEAK" > seen; echo "1|unix|/tmp/fake-plugin-test.sock"; while true; do sleep 1; done`)
+	proc.SetEnv([]string{})
+	proc.SetDir(dir)
+	if _, err := proc.Start(context.Background(), 5*time.Second); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = proc.Stop(500 * time.Millisecond) }()
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, leaked, _ := strings.Cut(string(data), "|")
+	if resolved, _ := filepath.EvalSymlinks(dir); wd != dir && wd != resolved {
+		t.Errorf("working dir = %q, want %q", wd, dir)
+	}
+	if leaked != "" {
+		t.Error("an empty env must not fall back to the parent environment")
+	}
+}
+
 // TestProcessSurvivesContextCancelAfterHandshake is the regression test for the
 // reload_mcp bug: when exec.CommandContext was used, cancelling the caller's
 // context (e.g. when a reload tool-call request completed) would kill the
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// stderrLog is a plugin's stderr. From protocol version 2 on, a plugin
+// writes its log records there as JSON lines (see pkg/plugin.Logger); they
+// are logged through slog at their own level, tagged with the plugin name.
+// Anything else, and everything a version 1 plugin writes, passes through
+// to out unchanged. Lines written before the handshake settles the version
+// are held until it does.
+type stderrLog struct {
+	name string
+	out  io.Writer
+
+	mu      sync.Mutex
+	version int // 0 until the handshake is in
+	buf     []byte
+	held    [][]byte
+}
+
+// setVersion records the negotiated protocol version and handles the
+// lines held until now.
+func (w *stderrLog) setVersion(v int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.version != 0 {
+		return
+	}
+	w.version = v
+	for _, line := range w.held {
+		w.line(line)
+	}
+	w.held = nil
+}
+
+func (w *stderrLog) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, b...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := w.buf[:i+1]
+		w.buf = w.buf[i+1:]
+		if w.version == 0 {
+			w.held = append(w.held, bytes.Clone(line))
+			continue
+		}
+		w.line(line)
+	}
+	return len(b), nil
+}
+
+// line handles one complete line, newline included.
+func (w *stderrLog) line(line []byte) {
+	if w.version < 2 || !w.record(bytes.TrimSpace(line)) {
+		_, _ = w.out.Write(line)
+	}
+}
+
+// record logs line if it is a JSON log record and reports whether it was.
+func (w *stderrLog) record(line []byte) bool {
+	if len(line) == 0 || line[0] != '{' {
+		return false
+	}
+	var rec map[string]any
+	if json.Unmarshal(line, &rec) != nil {
+		return false
+	}
+	msg, ok := rec[slog.MessageKey].(string)
+	levelText, ok2 := rec[slog.LevelKey].(string)
+	var level slog.Level
+	if !ok || !ok2 || level.UnmarshalText([]byte(levelText)) != nil {
+		return false
+	}
+	delete(rec, slog.MessageKey)
+	delete(rec, slog.LevelKey)
+	delete(rec, slog.TimeKey) // the host stamps its own
+	attrs := make([]any, 0, 2+2*len(rec))
+	attrs = append(attrs, "plugin", w.name)
+	for _, k := range slices.Sorted(maps.Keys(rec)) {
+		attrs = append(attrs, k, rec[k])
+	}
+	slog.Log(context.Background(), level, msg, attrs...)
+	return true
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	pkg "github.com/opentalon/opentalon/pkg/plugin"
+)
+
+func TestStderrLog(t *testing.T) {
+	var logged bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer slog.SetDefault(prev)
+
+	record := `{"time":"2026-01-01T00:00:00Z","level":"WARN","msg":"upstream slow","latency_ms":900}` + "\n"
+
+	var out bytes.Buffer
+	w := &stderrLog{name: "weather", out: &out}
+	_, _ = w.Write([]byte(record)) // held until the version is known
+	if out.Len() != 0 || logged.Len() != 0 {
+		t.Fatal("a line was handled before the handshake")
+	}
+	w.setVersion(1) // a version 1 plugin's JSON is just text
+	if out.String() != record || logged.Len() != 0 {
+		t.Errorf("version 1 passed through %q, logged %q", out.String(), logged.String())
+	}
+
+	out.Reset()
+	w = &stderrLog{name: "weather", out: &out}
+	w.setVersion(2)
+	_, _ = w.Write([]byte("panic: oh no\n" + record[:20]))
+	_, _ = w.Write([]byte(record[20:]))
+	if out.String() != "panic: oh no\n" {
+		t.Errorf("passed through %q", out.String())
+	}
+	got := logged.String()
+	if strings.Count(got, "msg=") != 1 || !strings.Contains(got, `level=WARN msg="upstream slow" plugin=weather latency_ms=900`) {
+		t.Errorf("logged %q", got)
+	}
+}
+
+// TestProcess_NegotiatesVersion checks the core offers its versions and
+// takes structured logs from a plugin that picked version 2.
+func TestProcess_NegotiatesVersion(t *testing.T) {
+	var logged bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logged, nil)))
+	defer slog.SetDefault(prev)
+
+	dir := t.TempDir()
+	proc := NewProcess("/bin/sh", "-c", `printf '%s' "$`+pkg.EnvProtocolVersions+`" > offered; `+
+		`echo "2|unix|/tmp/fake-plugin-test.sock"; echo '{"level":"INFO","msg":"ready"}' >&2; while true; do sleep 1; done`)
+	proc.SetName("fake")
+	proc.SetEnv([]string{})
+	proc.SetDir(dir)
+	hs, err := proc.Start(context.Background(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer func() { _ = proc.Stop(500 * time.Millisecond) }()
+	if hs.Version != 2 {
+		t.Errorf("version = %d", hs.Version)
+	}
+	offered, _ := os.ReadFile(filepath.Join(dir, "offered"))
+	if string(offered) != pkg.ProtocolVersions() {
+		t.Errorf("offered %q, want %q", offered, pkg.ProtocolVersions())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for !strings.Contains(logged.String(), "msg=ready plugin=fake") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(logged.String(), "msg=ready plugin=fake") {
+		t.Errorf("logged %q", logged.String())
+	}
+}
@@ -0,0 +1,255 @@
+// Package promotion lets useful knowledge learned in one conversation reach
+// everyone on purpose: the memory tool promotes a per-actor memory, or a
+// finding from the current conversation, to the general memory scope that
+// every actor's prompt includes. Promotion is admin-gated: approvers
+// promote directly, anyone else's promotion waits in the approval queue.
+package promotion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+const ToolName = "memory"
+
+// ErrNotAuthorized is returned to non-approvers when no approval queue is
+// set.
+var ErrNotAuthorized = errors.New("not authorized: only memory approvers can share memories with everyone")
+
+// Store is the memory store; *store.MemoryStore satisfies it.
+type Store interface {
+	AddScoped(ctx context.Context, actorID string, content string, tags ...string) (*state.Memory, error)
+	MemoriesForContext(ctx context.Context, tag string) ([]*state.Memory, error)
+	Get(ctx context.Context, id string) (*state.Memory, error)
+	Promote(ctx context.Context, id string) (*state.Memory, error)
+}
+
+// Tool is the built-in memory tool: memory.list shows the memories the
+// caller sees and memory.promote shares one with everyone.
+type Tool struct {
+	store         Store
+	approvers     map[string]bool
+	approvals     *approval.Queue
+	allowedGroups []string
+}
+
+// NewTool returns the tool over store. approvers are the users who promote
+// directly: profile entity ids, or sender ids without profiles, as for
+// scheduler.approvers. Unlike the scheduler, an empty list makes nobody an
+// approver. allowedGroups restricts the tool to those profile groups; empty
+// leaves it visible to everyone.
+func NewTool(store Store, approvers, allowedGroups []string) *Tool {
+	a := make(map[string]bool, len(approvers))
+	for _, id := range approvers {
+		if id = strings.TrimSpace(id); id != "" {
+			a[id] = true
+		}
+	}
+	return &Tool{store: store, approvers: a, allowedGroups: allowedGroups}
+}
+
+// SetApprovalQueue files promotions by non-approvers in q instead of
+// refusing them, and registers the handler that carries them out.
+func (t *Tool) SetApprovalQueue(q *approval.Queue) {
+	t.approvals = q
+	q.Handle(approval.KindMemory, t.runApproved)
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   "Long-term memories: list what you remember and share useful knowledge with every user.",
+		AllowedGroups: t.allowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name:        "list",
+				Description: "Memories visible in this conversation, with their id and whether they are shared with everyone or personal.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "tag", Description: "Only memories with this tag", Required: false},
+				},
+				ReadOnly: true,
+			},
+			{
+				Name: "promote",
+				Description: "Share knowledge with every user from now on: either a personal memory (memory_id) or a finding from this conversation (content), " +
+					"e.g. a workflow that worked. Only when the user asks for it. Admins share directly; for others the request waits for an admin's approval.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "memory_id", Description: "Id of a personal memory to share (see memory.list)", Required: false},
+					{Name: "content", Description: "Self-contained text to remember for everyone, instead of memory_id", Required: false},
+					{Name: "tags", Description: "Comma-separated tags for content, e.g. workflow,deploy", Required: false},
+				},
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	switch call.Action {
+	case "list":
+		return t.list(ctx, call)
+	case "promote":
+		return t.promote(ctx, call)
+	default:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown memory action: %s", call.Action)}
+	}
+}
+
+// listedMemory is a memory as memory.list shows it.
+type listedMemory struct {
+	ID      string   `json:"id"`
+	Scope   string   `json:"scope"` // "everyone" or "personal"
+	Content string   `json:"content"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+func (t *Tool) list(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	mems, err := t.store.MemoriesForContext(ctx, strings.TrimSpace(call.Args["tag"]))
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if len(mems) == 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "No memories."}
+	}
+	out := make([]listedMemory, len(mems))
+	for i, m := range mems {
+		scope := "everyone"
+		if m.ActorID != "" {
+			scope = "personal"
+		}
+		out[i] = listedMemory{ID: m.ID, Scope: scope, Content: m.Content, Tags: m.Tags}
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling memories: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
+
+func (t *Tool) promote(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	id := strings.TrimSpace(call.Args["memory_id"])
+	content := strings.TrimSpace(call.Args["content"])
+	if (id == "") == (content == "") {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "exactly one of memory_id or content is required"}
+	}
+	caller, channelID := callerOf(ctx)
+	payload := map[string]string{}
+	var summary string
+	if id != "" {
+		m, err := t.store.Get(ctx, id)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		// Only the owner, or an approver, may share a personal memory.
+		if m == nil || (m.ActorID != actor.Actor(ctx) && !t.approvers[caller]) {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("memory %q not found", id)}
+		}
+		if m.ActorID == "" {
+			return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("Memory %s is already shared with everyone.", id)}
+		}
+		payload["memory_id"] = id
+		summary = fmt.Sprintf("share memory %s with everyone: %s", id, truncate(m.Content, 120))
+	} else {
+		payload["content"] = content
+		payload["tags"] = normalizeTags(call.Args["tags"])
+		summary = "remember for everyone: " + truncate(content, 120)
+	}
+
+	if !t.approvers[caller] {
+		if t.approvals == nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: ErrNotAuthorized.Error()}
+		}
+		payload["requester"] = actor.Actor(ctx)
+		req, err := t.approvals.Submit(approval.Request{
+			Kind:           approval.KindMemory,
+			Summary:        summary,
+			Requester:      caller,
+			ChannelID:      channelID,
+			ConversationID: actor.ConversationID(ctx),
+			SessionID:      actor.SessionID(ctx),
+			Payload:        payload,
+		})
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("filing promotion for approval: %v", err)}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf(
+			"Submitted for an admin's approval (request %s). It is NOT shared yet; the user is told here once an admin decides.", req.ID)}
+	}
+
+	result, err := t.apply(ctx, payload)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	slog.Info("audit", "event", "memory_promoted", "by", caller, "memory", result)
+	return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("Shared with everyone as memory %s.", result)}
+}
+
+// runApproved is the approval.KindMemory handler.
+func (t *Tool) runApproved(ctx context.Context, req approval.Request) (string, error) {
+	id, err := t.apply(ctx, req.Payload)
+	if err != nil {
+		return "", err
+	}
+	slog.Info("audit", "event", "memory_promoted", "by", req.DecidedBy, "requester", req.Requester, "memory", id)
+	return fmt.Sprintf("It is now shared with everyone as memory %s.", id), nil
+}
+
+// apply carries out a promotion payload and returns the id of the general
+// memory.
+func (t *Tool) apply(ctx context.Context, payload map[string]string) (string, error) {
+	if id := payload["memory_id"]; id != "" {
+		m, err := t.store.Promote(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		return m.ID, nil
+	}
+	var tags []string
+	if s := payload["tags"]; s != "" {
+		tags = strings.Split(s, ",")
+	}
+	m, err := t.store.AddScoped(ctx, "", payload["content"], tags...)
+	if err != nil {
+		return "", err
+	}
+	return m.ID, nil
+}
+
+// callerOf returns the id approvers are matched against and the channel
+// the caller is reached on, as the scheduler resolves them.
+func callerOf(ctx context.Context) (userID, channelID string) {
+	if p := profile.FromContext(ctx); p != nil && p.EntityID != "" {
+		return p.EntityID, p.ChannelID
+	}
+	channel, sender, ok := strings.Cut(actor.Actor(ctx), ":")
+	if !ok {
+		return actor.Actor(ctx), ""
+	}
+	return sender, channel
+}
+
+func normalizeTags(s string) string {
+	var tags []string
+	for _, tag := range strings.Split(s, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return strings.Join(tags, ",")
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
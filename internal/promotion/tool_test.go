@@ -0,0 +1,146 @@
+package promotion
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// fakeStore keeps memories in a map; the empty ActorID is general.
+type fakeStore struct {
+	mems map[string]*state.Memory
+	next int
+}
+
+func newFakeStore(mems ...*state.Memory) *fakeStore {
+	s := &fakeStore{mems: make(map[string]*state.Memory)}
+	for _, m := range mems {
+		s.mems[m.ID] = m
+	}
+	return s
+}
+
+func (s *fakeStore) AddScoped(_ context.Context, actorID, content string, tags ...string) (*state.Memory, error) {
+	s.next++
+	m := &state.Memory{ID: fmt.Sprintf("mem_new%d", s.next), ActorID: actorID, Content: content, Tags: tags}
+	s.mems[m.ID] = m
+	return m, nil
+}
+
+func (s *fakeStore) MemoriesForContext(ctx context.Context, _ string) ([]*state.Memory, error) {
+	var out []*state.Memory
+	for _, m := range s.mems {
+		if m.ActorID == "" || m.ActorID == actor.Actor(ctx) {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
+
+func (s *fakeStore) Get(_ context.Context, id string) (*state.Memory, error) {
+	return s.mems[id], nil
+}
+
+func (s *fakeStore) Promote(_ context.Context, id string) (*state.Memory, error) {
+	m := s.mems[id]
+	if m == nil || m.ActorID == "" {
+		return nil, fmt.Errorf("cannot promote %s", id)
+	}
+	m.ActorID = ""
+	return m, nil
+}
+
+func promoteCall(args map[string]string) orchestrator.ToolCall {
+	return orchestrator.ToolCall{ID: "c1", Plugin: ToolName, Action: "promote", Args: args}
+}
+
+func TestTool_ApproverPromotesDirectly(t *testing.T) {
+	st := newFakeStore(
+		&state.Memory{ID: "mem_1", ActorID: "slack:U1", Content: "deploys go through the canary first"},
+		&state.Memory{ID: "mem_2", ActorID: "slack:U2", Content: "someone else's note"},
+	)
+	tool := NewTool(st, []string{"U1"}, nil)
+	ctx := actor.WithActor(context.Background(), "slack:U1")
+
+	res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_1"}))
+	if res.Error != "" || !strings.Contains(res.Content, "mem_1") || st.mems["mem_1"].ActorID != "" {
+		t.Fatalf("promote own = %+v, memory %+v", res, st.mems["mem_1"])
+	}
+	// An approver may share another user's personal memory.
+	if res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_2"})); res.Error != "" || st.mems["mem_2"].ActorID != "" {
+		t.Errorf("promote other = %+v", res)
+	}
+
+	res = tool.Execute(ctx, promoteCall(map[string]string{"content": "Release notes live in docs/releases", "tags": " workflow, ,release "}))
+	m := st.mems["mem_new1"]
+	if res.Error != "" || m == nil || m.ActorID != "" || strings.Join(m.Tags, "|") != "workflow|release" {
+		t.Errorf("promote content = %+v, memory %+v", res, m)
+	}
+
+	if res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_1"})); !strings.Contains(res.Content, "already shared") {
+		t.Errorf("promote shared = %+v", res)
+	}
+	if res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_1", "content": "x"})); res.Error == "" {
+		t.Error("memory_id and content together accepted")
+	}
+}
+
+func TestTool_NonApproverNeedsApproval(t *testing.T) {
+	st := newFakeStore(
+		&state.Memory{ID: "mem_1", ActorID: "slack:U2", Content: "my runbook"},
+		&state.Memory{ID: "mem_3", ActorID: "slack:U3", Content: "not mine"},
+	)
+	tool := NewTool(st, []string{"U1"}, nil)
+	ctx := actor.WithConversationID(actor.WithActor(context.Background(), "slack:U2"), "C1")
+
+	if res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_1"})); !strings.Contains(res.Error, "not authorized") {
+		t.Errorf("without a queue = %+v", res)
+	}
+
+	q, err := approval.NewQueue(nil, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool.SetApprovalQueue(q)
+	if res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_3"})); !strings.Contains(res.Error, "not found") {
+		t.Errorf("someone else's memory = %+v", res)
+	}
+	res := tool.Execute(ctx, promoteCall(map[string]string{"memory_id": "mem_1"}))
+	if res.Error != "" || !strings.Contains(res.Content, "approval") || st.mems["mem_1"].ActorID == "" {
+		t.Fatalf("filed = %+v", res)
+	}
+	pending := q.List(approval.StatusPending)
+	if len(pending) != 1 || pending[0].Kind != approval.KindMemory || pending[0].Requester != "U2" ||
+		pending[0].ChannelID != "slack" || pending[0].ConversationID != "C1" || !strings.Contains(pending[0].Summary, "my runbook") {
+		t.Fatalf("pending = %+v", pending)
+	}
+
+	req, err := q.Approve(context.Background(), pending[0].ID, "U1", "useful")
+	if err != nil || req.Error != "" || !strings.Contains(req.Result, "mem_1") {
+		t.Fatalf("approve = %+v, %v", req, err)
+	}
+	if st.mems["mem_1"].ActorID != "" {
+		t.Error("approved memory was not promoted")
+	}
+}
+
+func TestTool_List(t *testing.T) {
+	st := newFakeStore(
+		&state.Memory{ID: "mem_1", ActorID: "slack:U1", Content: "mine"},
+		&state.Memory{ID: "mem_2", Content: "everyone's"},
+	)
+	tool := NewTool(st, nil, nil)
+	res := tool.Execute(actor.WithActor(context.Background(), "slack:U1"), orchestrator.ToolCall{Action: "list"})
+	if res.Error != "" || !strings.Contains(res.Content, `"id":"mem_1","scope":"personal"`) || !strings.Contains(res.Content, `"id":"mem_2","scope":"everyone"`) {
+		t.Errorf("list = %+v", res)
+	}
+	if res := tool.Execute(context.Background(), orchestrator.ToolCall{Action: "forget"}); res.Error == "" {
+		t.Error("unknown action accepted")
+	}
+}
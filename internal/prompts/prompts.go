@@ -92,6 +92,73 @@ var sessionTitleRaw string
 // user's language. Trailing newline trimmed so callers don't have to.
 var SessionTitle = strings.TrimRight(sessionTitleRaw, "\n")
 
+//go:embed help_polish.txt
+var helpPolishRaw string
+
+// HelpPolish is the system prompt for the optional LLM pass that rewrites the
+// registry-generated /help capability summary into onboarding copy.
+var HelpPolish = strings.TrimRight(helpPolishRaw, "\n")
+
+//go:embed injection_classifier.txt
+var injectionClassifierRaw string
+
+// InjectionClassifier is the system prompt for the guard's optional check of
+// untrusted plugin output. Output contract: one line, "SAFE" or
+// "INJECTION: <reason>".
+var InjectionClassifier = strings.TrimRight(injectionClassifierRaw, "\n")
+
+//go:embed evaluation_judge.txt
+var evaluationJudgeRaw string
+
+// EvaluationJudge is the system prompt for scoring a completed session
+// against the evaluation rubric, which the caller appends. Output contract:
+// JSON {"scores": [{"criterion", "score", "reason"}]}.
+var EvaluationJudge = strings.TrimRight(evaluationJudgeRaw, "\n")
+
+//go:embed orchestrator_loop_repeat.txt
+var loopRepeatRaw string
+
+// LoopRepeat is the hidden message that stops a model repeating the same
+// tool call and asks it to answer instead.
+var LoopRepeat = strings.TrimRight(loopRepeatRaw, "\n")
+
+//go:embed orchestrator_loop_oscillation.txt
+var loopOscillationRaw string
+
+// LoopOscillation is LoopRepeat for a model alternating between two sets of
+// tool calls.
+var LoopOscillation = strings.TrimRight(loopOscillationRaw, "\n")
+
+//go:embed orchestrator_loop_recovery.txt
+var loopRecoveryRaw string
+
+// LoopRecovery is the hidden instruction of the last call a turn makes when
+// its loop ran out of budget: answer with what was done and found so far.
+var LoopRecovery = strings.TrimRight(loopRecoveryRaw, "\n")
+
+//go:embed orchestrator_job_started.txt
+var jobStartedRaw string
+
+// JobStarted is appended to the result of a tool call that started a
+// background job. Format verbs: the job id (%s), the status tool (%s) and
+// the job id again, quoted (%q).
+var JobStarted = strings.TrimRight(jobStartedRaw, "\n")
+
+//go:embed orchestrator_job_finished.txt
+var jobFinishedRaw string
+
+// JobFinished opens the hidden turn that reports a finished background job.
+// Format verbs: the job id, the tool that started it and its result (%s
+// each).
+var JobFinished = strings.TrimRight(jobFinishedRaw, "\n")
+
+//go:embed orchestrator_job_failed.txt
+var jobFailedRaw string
+
+// JobFailed is JobFinished for a job that failed; the last verb is the
+// reason.
+var JobFailed = strings.TrimRight(jobFailedRaw, "\n")
+
 //go:embed subprocess_preamble.txt
 var SubprocessPreamble string
 
@@ -176,6 +243,15 @@ var promptSetters = map[string]func(string){
 	"orchestrator_summarize":        func(s string) { SummarizeDefault = strings.TrimRight(s, "\n") },
 	"orchestrator_summarize_update": func(s string) { SummarizeUpdate = strings.TrimRight(s, "\n") },
 	"orchestrator_session_title":    func(s string) { SessionTitle = strings.TrimRight(s, "\n") },
+	"help_polish":                   func(s string) { HelpPolish = strings.TrimRight(s, "\n") },
+	"injection_classifier":          func(s string) { InjectionClassifier = strings.TrimRight(s, "\n") },
+	"evaluation_judge":              func(s string) { EvaluationJudge = strings.TrimRight(s, "\n") },
+	"orchestrator_loop_repeat":      func(s string) { LoopRepeat = strings.TrimRight(s, "\n") },
+	"orchestrator_loop_oscillation": func(s string) { LoopOscillation = strings.TrimRight(s, "\n") },
+	"orchestrator_loop_recovery":    func(s string) { LoopRecovery = strings.TrimRight(s, "\n") },
+	"orchestrator_job_started":      func(s string) { JobStarted = strings.TrimRight(s, "\n") },
+	"orchestrator_job_finished":     func(s string) { JobFinished = strings.TrimRight(s, "\n") },
+	"orchestrator_job_failed":       func(s string) { JobFailed = strings.TrimRight(s, "\n") },
 	"rules_default":                 func(s string) { DefaultRules = splitLines(s) },
 	"rules_scheduling":              func(s string) { SchedulingRules = splitLines(s) },
 	"format_slack":                  func(s string) { FormatSlack = strings.TrimRight(s, "\n") },
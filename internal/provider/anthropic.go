@@ -52,10 +52,11 @@ type AnthropicProvider struct {
 	id        string
 	baseURL   string
 	apiKey    string
-	models    []ModelInfo
+	models    modelSet
 	client    *http.Client
 	eventSink emit.Sink   // structured session-event sink; nil disables emission
 	retry     RetryPolicy // transient-failure retry policy (DefaultRetryPolicy unless configured)
+	noCache   bool        // skip the cache_control breakpoints (WithAnthropicPromptCaching(false))
 }
 
 // AnthropicOption configures an AnthropicProvider.
@@ -83,6 +84,14 @@ func WithAnthropicRetryPolicy(rp RetryPolicy) AnthropicOption {
 	return func(p *AnthropicProvider) { p.retry = rp.withDefaults() }
 }
 
+// WithAnthropicPromptCaching turns prompt caching on or off. It is on by
+// default: the tool definitions and the system prompt are marked with
+// cache_control breakpoints, so the static prefix of every request after
+// the first is read from Anthropic's cache at a tenth of the input price.
+func WithAnthropicPromptCaching(on bool) AnthropicOption {
+	return func(p *AnthropicProvider) { p.noCache = !on }
+}
+
 // NewAnthropicProvider creates a provider for the Anthropic API.
 func NewAnthropicProvider(id, baseURL, apiKey string, models []ModelInfo, opts ...AnthropicOption) *AnthropicProvider {
 	if baseURL == "" {
@@ -92,7 +101,7 @@ func NewAnthropicProvider(id, baseURL, apiKey string, models []ModelInfo, opts .
 		id:      id,
 		baseURL: baseURL,
 		apiKey:  apiKey,
-		models:  models,
+		models:  modelSet{models: models},
 		client:  &http.Client{Timeout: 120 * time.Second},
 		retry:   DefaultRetryPolicy(),
 	}
@@ -105,31 +114,31 @@ func NewAnthropicProvider(id, baseURL, apiKey string, models []ModelInfo, opts .
 	return p
 }
 
-// costForTokens computes input/output cost for tokensIn/tokensOut against
-// this provider's configured per-million-token rate for modelID. Returns
+// costForTokens computes input/output cost for u against this provider's
+// configured per-million-token rates for modelID, cache reads and writes
+// included on the input side. Returns
 // (0, 0) when modelID is not in p.models — emit helpers stamp
 // LLMResponsePayload with omitempty so a zero cost simply leaves the
 // fields out rather than recording a misleading "free call". Currency
 // is unitless: ModelInfo.Cost values are stamped as-is, the operator's
 // deployment convention sets the unit. Mirrors openai.go's costForTokens
 // so the two pricing paths stay consistent.
-func (p *AnthropicProvider) costForTokens(modelID string, tokensIn, tokensOut int) (float64, float64) {
-	for _, m := range p.models {
+func (p *AnthropicProvider) costForTokens(modelID string, u Usage) (float64, float64) {
+	for _, m := range p.models.list() {
 		if m.ID != modelID {
 			continue
 		}
-		return float64(tokensIn) * m.Cost.Input / 1_000_000,
-			float64(tokensOut) * m.Cost.Output / 1_000_000
+		return m.Cost.InputCost(u), float64(u.OutputTokens) * m.Cost.Output / 1_000_000
 	}
 	return 0, 0
 }
 
 func (p *AnthropicProvider) ID() string { return p.id }
 
-func (p *AnthropicProvider) Models() []ModelInfo { return p.models }
+func (p *AnthropicProvider) Models() []ModelInfo { return p.models.list() }
 
 func (p *AnthropicProvider) SupportsFeature(f Feature) bool {
-	for _, m := range p.models {
+	for _, m := range p.models.list() {
 		if m.SupportsFeature(f) {
 			return true
 		}
@@ -140,18 +149,35 @@ func (p *AnthropicProvider) SupportsFeature(f Feature) bool {
 // -- Anthropic wire types --
 
 type anthRequest struct {
-	Model     string        `json:"model"`
-	System    string        `json:"system,omitempty"`
-	Messages  []anthMessage `json:"messages"`
-	MaxTokens int           `json:"max_tokens"`
-	Thinking  *anthThinking `json:"thinking,omitempty"`
-	Tools     []anthTool    `json:"tools,omitempty"`
+	Model         string        `json:"model"`
+	System        any           `json:"system,omitempty"` // string, or []anthSystemBlock to carry a cache breakpoint
+	Messages      []anthMessage `json:"messages"`
+	MaxTokens     int           `json:"max_tokens"`
+	Temperature   *float64      `json:"temperature,omitempty"`
+	TopP          *float64      `json:"top_p,omitempty"`
+	StopSequences []string      `json:"stop_sequences,omitempty"`
+	Thinking      *anthThinking `json:"thinking,omitempty"`
+	Tools         []anthTool    `json:"tools,omitempty"`
 }
 
 type anthTool struct {
-	Name        string                 `json:"name"`
-	Description string                 `json:"description,omitempty"`
-	InputSchema map[string]interface{} `json:"input_schema"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description,omitempty"`
+	InputSchema  map[string]interface{} `json:"input_schema"`
+	CacheControl *anthCacheControl      `json:"cache_control,omitempty"`
+}
+
+type anthSystemBlock struct {
+	Type         string            `json:"type"` // "text"
+	Text         string            `json:"text"`
+	CacheControl *anthCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthCacheControl marks the end of a cacheable prefix: everything up to
+// and including the marked block is cached (tools, then system, then
+// messages, in that order).
+type anthCacheControl struct {
+	Type string `json:"type"` // "ephemeral"
 }
 
 type anthThinking struct {
@@ -191,14 +217,17 @@ type anthContentBlock struct {
 }
 
 type anthImageSource struct {
-	Type      string `json:"type"`                 // "base64" or "text"
-	MediaType string `json:"media_type,omitempty"` // e.g. "image/png"; omitted for text sources
-	Data      string `json:"data"`                 // base64-encoded bytes or plain text
+	Type      string `json:"type"`                 // "base64", "text" or "url"
+	MediaType string `json:"media_type,omitempty"` // e.g. "image/png"; omitted for text and url sources
+	Data      string `json:"data,omitempty"`       // base64-encoded bytes or plain text
+	URL       string `json:"url,omitempty"`        // url sources: Anthropic fetches the file itself
 }
 
 type anthUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 type anthError struct {
@@ -330,13 +359,22 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req *CompletionRequest
 		nativeToolCallsRaw, _ = json.Marshal(toolBlocks)
 	}
 
-	costIn, costOut := p.costForTokens(anthReq.Model, anthResp.Usage.InputTokens, anthResp.Usage.OutputTokens)
+	usage := Usage{
+		InputTokens:      anthResp.Usage.InputTokens,
+		OutputTokens:     anthResp.Usage.OutputTokens,
+		CacheReadTokens:  anthResp.Usage.CacheReadInputTokens,
+		CacheWriteTokens: anthResp.Usage.CacheCreationInputTokens,
+	}
+	costIn, costOut := p.costForTokens(anthReq.Model, usage)
+	usage.Cost = costIn + costOut
 	eventID := emit.EmitLLMResponse(ctx, p.eventSink, emit.LLMResponseArgs{
 		RawContent:         content,
 		NativeToolCallsRaw: nativeToolCallsRaw,
 		FinishReason:       anthResp.StopReason,
-		TokensIn:           anthResp.Usage.InputTokens,
-		TokensOut:          anthResp.Usage.OutputTokens,
+		TokensIn:           usage.InputTokens,
+		TokensOut:          usage.OutputTokens,
+		TokensCacheRead:    usage.CacheReadTokens,
+		TokensCacheWrite:   usage.CacheWriteTokens,
 		CostInput:          costIn,
 		CostOutput:         costOut,
 		LatencyMS:          latencyMS,
@@ -348,11 +386,8 @@ func (p *AnthropicProvider) Complete(ctx context.Context, req *CompletionRequest
 		Model:     anthResp.Model,
 		Content:   content,
 		ToolCalls: toolCalls,
-		Usage: Usage{
-			InputTokens:  anthResp.Usage.InputTokens,
-			OutputTokens: anthResp.Usage.OutputTokens,
-		},
-		EventID: eventID,
+		Usage:     usage,
+		EventID:   eventID,
 	}, nil
 }
 
@@ -367,6 +402,7 @@ func (p *AnthropicProvider) toAnthRequest(req *CompletionRequest) (anthRequest,
 	// by a blank line) rather than last-wins overwriting: the normal case is
 	// one leading system message, but a stray mid-array system message (e.g.
 	// a transient nudge) must not silently clobber the real prompt.
+	req = acceptedFiles(p.models.list(), req)
 	var systemParts []string
 	msgs := make([]anthMessage, 0, len(req.Messages))
 
@@ -389,11 +425,27 @@ func (p *AnthropicProvider) toAnthRequest(req *CompletionRequest) (anthRequest,
 	}
 
 	ar := anthRequest{
-		Model:     req.Model,
-		System:    system,
-		Messages:  msgs,
-		MaxTokens: maxTokens,
-		Tools:     toolDefsToAnthTools(req.Tools),
+		Model:         req.Model,
+		Messages:      msgs,
+		MaxTokens:     maxTokens,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+		Tools:         toolDefsToAnthTools(req.Tools),
+	}
+	switch {
+	case system == "":
+	case p.noCache:
+		ar.System = system
+	default:
+		// The system prompt and the tool catalog are the same on every
+		// round of a run and usually across runs, so each gets a
+		// breakpoint: a change in the system prompt (memories, a new
+		// date) still reuses the cached tools.
+		ar.System = []anthSystemBlock{{Type: "text", Text: system, CacheControl: &anthCacheControl{Type: "ephemeral"}}}
+	}
+	if !p.noCache && len(ar.Tools) > 0 {
+		ar.Tools[len(ar.Tools)-1].CacheControl = &anthCacheControl{Type: "ephemeral"}
 	}
 
 	if req.Reasoning {
@@ -406,6 +458,9 @@ func (p *AnthropicProvider) toAnthRequest(req *CompletionRequest) (anthRequest,
 		if ar.MaxTokens < budget+1024 {
 			ar.MaxTokens = budget + 4096
 		}
+		// Extended thinking rejects sampling changes; the configured
+		// temperature and top_p apply to turns without it.
+		ar.Temperature, ar.TopP = nil, nil
 	}
 
 	return ar, nil
@@ -447,7 +502,8 @@ func toolDefsToAnthTools(defs []ToolDefinition) []anthTool {
 //   - Messages with file attachments: content becomes a JSON array of
 //     blocks (files first, then optional text). Image mime types map
 //     to "image" blocks, application/pdf maps to "document" base64
-//     source, text-like types map to "document" text source.
+//     source, text-like types map to "document" text source. Images and
+//     PDFs the channel sent as a link (URL, no bytes) use a "url" source.
 func (p *AnthropicProvider) toAnthMessage(m Message) (anthMessage, error) {
 	if m.Role == RoleTool {
 		return toolResultMessage(m)
@@ -465,7 +521,16 @@ func (p *AnthropicProvider) toAnthMessage(m Message) (anthMessage, error) {
 
 	var blocks []anthContentBlock
 	for _, f := range m.Files {
-		switch ClassifyFile(f.MimeType, f.Data) {
+		class := ClassifyFile(f.MimeType, f.Data)
+		if len(f.Data) == 0 && f.URL != "" && (class == FileClassImage || class == FileClassPDF) {
+			typ := "image"
+			if class == FileClassPDF {
+				typ = "document"
+			}
+			blocks = append(blocks, anthContentBlock{Type: typ, Source: &anthImageSource{Type: "url", URL: f.URL}})
+			continue
+		}
+		switch class {
 		case FileClassImage:
 			blocks = append(blocks, anthContentBlock{
 				Type: "image",
@@ -30,8 +30,8 @@ func TestAnthropicComplete(t *testing.T) {
 		if req.Model != "claude-sonnet-4-20250514" {
 			t.Errorf("model = %q", req.Model)
 		}
-		if req.System != "You are helpful." {
-			t.Errorf("system = %q", req.System)
+		if got := anthSystemText(req.System); got != "You are helpful." {
+			t.Errorf("system = %q", got)
 		}
 		if len(req.Messages) != 1 {
 			t.Fatalf("messages = %d, want 1 (system extracted)", len(req.Messages))
@@ -675,8 +675,8 @@ func TestAnthropicConcatenatesSystemMessages(t *testing.T) {
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			t.Fatal(err)
 		}
-		if req.System != "Real prompt.\n\nStray nudge." {
-			t.Errorf("system = %q, want both system messages concatenated", req.System)
+		if got := anthSystemText(req.System); got != "Real prompt.\n\nStray nudge." {
+			t.Errorf("system = %q, want both system messages concatenated", got)
 		}
 		resp := anthResponse{ID: "m", Content: []anthContentBlock{{Type: "text", Text: "ok"}}}
 		_ = json.NewEncoder(w).Encode(resp)
@@ -696,3 +696,126 @@ func TestAnthropicConcatenatesSystemMessages(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestToAnthMessageFileURL(t *testing.T) {
+	p := NewAnthropicProvider("anthropic", "", "key", nil)
+	msg, err := p.toAnthMessage(Message{
+		Role:  RoleUser,
+		Files: []MessageFile{{MimeType: "image/jpeg", URL: "https://example.com/a.jpg"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var blocks []anthContentBlock
+	if err := json.Unmarshal(msg.Content, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 1 || blocks[0].Type != "image" || blocks[0].Source.Type != "url" || blocks[0].Source.URL != "https://example.com/a.jpg" {
+		t.Errorf("blocks = %+v", blocks)
+	}
+}
+
+func TestAnthropicSamplingParams(t *testing.T) {
+	p := NewAnthropicProvider("anthropic", "", "key", nil)
+	temp, topP := 0.2, 0.9
+	req := &CompletionRequest{
+		Model:       "claude-sonnet-4-20250514",
+		Messages:    []Message{{Role: RoleUser, Content: "hi"}},
+		MaxTokens:   1000,
+		Temperature: &temp,
+		TopP:        &topP,
+		Stop:        []string{"END"},
+	}
+	ar, err := p.toAnthRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.MaxTokens != 1000 || ar.Temperature == nil || *ar.Temperature != 0.2 || ar.TopP == nil || *ar.TopP != 0.9 || len(ar.StopSequences) != 1 {
+		t.Errorf("sampling params not sent: %+v", ar)
+	}
+
+	// Extended thinking rejects temperature and top_p; stop sequences stay.
+	req.Reasoning = true
+	ar, err = p.toAnthRequest(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.Temperature != nil || ar.TopP != nil || len(ar.StopSequences) != 1 {
+		t.Errorf("with thinking: temperature=%v top_p=%v stop=%q", ar.Temperature, ar.TopP, ar.StopSequences)
+	}
+}
+
+// anthSystemText reads the system prompt back from a decoded request,
+// whether it went out as a plain string or as text blocks.
+func anthSystemText(system any) string {
+	switch v := system.(type) {
+	case string:
+		return v
+	case []any:
+		var parts []string
+		for _, b := range v {
+			if m, ok := b.(map[string]any); ok {
+				text, _ := m["text"].(string)
+				parts = append(parts, text)
+			}
+		}
+		return joinStrings(parts)
+	}
+	return ""
+}
+
+func TestAnthropicPromptCaching(t *testing.T) {
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write([]byte(`{"id":"m","content":[{"type":"text","text":"ok"}],"usage":{"input_tokens":20,"output_tokens":5,"cache_creation_input_tokens":300,"cache_read_input_tokens":1000}}`))
+	}))
+	defer server.Close()
+
+	models := []ModelInfo{{ID: "claude", Cost: ModelCost{Input: 3, Output: 15}}}
+	req := &CompletionRequest{
+		Model:    "claude",
+		Messages: []Message{{Role: RoleSystem, Content: "Rules."}, {Role: RoleUser, Content: "Hi"}},
+		Tools:    []ToolDefinition{{Name: "a"}, {Name: "b"}},
+	}
+
+	p := NewAnthropicProvider("anthropic", server.URL, "key", models)
+	resp, err := p.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	system, _ := body["system"].([]any)
+	if len(system) != 1 || system[0].(map[string]any)["cache_control"] == nil {
+		t.Errorf("system = %v; want one text block with a cache breakpoint", body["system"])
+	}
+	tools := body["tools"].([]any)
+	if tools[0].(map[string]any)["cache_control"] != nil || tools[1].(map[string]any)["cache_control"] == nil {
+		t.Errorf("tools = %v; want a breakpoint on the last tool only", tools)
+	}
+	want := Usage{InputTokens: 20, OutputTokens: 5, CacheReadTokens: 1000, CacheWriteTokens: 300}
+	if got := resp.Usage; got.InputTokens != want.InputTokens || got.OutputTokens != want.OutputTokens ||
+		got.CacheReadTokens != want.CacheReadTokens || got.CacheWriteTokens != want.CacheWriteTokens {
+		t.Errorf("usage = %+v; want %+v", got, want)
+	}
+	// 20*3 + 1000*0.3 + 300*3.75 per million for input, 5*15 for output.
+	if got, want := models[0].Cost.InputCost(resp.Usage), 1485.0/1_000_000; got < want-1e-12 || got > want+1e-12 {
+		t.Errorf("input cost = %v; want %v", got, want)
+	}
+	if got, want := resp.Usage.Cost, 1560.0/1_000_000; got < want-1e-12 || got > want+1e-12 {
+		t.Errorf("usage cost = %v; want %v", got, want)
+	}
+
+	off := NewAnthropicProvider("anthropic", server.URL, "key", models, WithAnthropicPromptCaching(false))
+	if _, err := off.Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if body["system"] != "Rules." {
+		t.Errorf("with caching off, system = %v; want the plain string", body["system"])
+	}
+	if tools := body["tools"].([]any); tools[1].(map[string]any)["cache_control"] != nil {
+		t.Errorf("with caching off, a tool carries a breakpoint: %v", tools)
+	}
+}
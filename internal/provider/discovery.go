@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	openAIModelsPath    = "/models"
+	anthropicModelsPath = "/v1/models"
+
+	// defaultDiscoveryTimeout bounds one listing round across all endpoints.
+	defaultDiscoveryTimeout = 30 * time.Second
+)
+
+// ModelLister is implemented by providers that can ask their endpoint which
+// models it serves: GET /v1/models on OpenAI-compatible APIs, the Models API
+// on Anthropic.
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]ModelInfo, error)
+}
+
+// modelDiscoverer is a ModelLister whose model list discovery may extend.
+type modelDiscoverer interface {
+	ModelLister
+	Provider
+	mergeModels(found []ModelInfo) (added, missing []string)
+}
+
+// backendProvider is implemented by wrappers (failover, offline) so
+// discovery reaches the endpoints behind them.
+type backendProvider interface {
+	backends() []Provider
+}
+
+// modelSet is a provider's model list. Discovery replaces it while requests
+// read it, so it is swapped under a lock and never modified in place.
+type modelSet struct {
+	mu     sync.RWMutex
+	models []ModelInfo
+}
+
+func (s *modelSet) list() []ModelInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.models
+}
+
+// merge adds the found models that are not configured and fills in the
+// context window and output limit of configured ones that left them unset.
+// Configured models are never dropped: missing lists those the endpoint no
+// longer reports, and added those it reported for the first time.
+func (s *modelSet) merge(providerID string, found []ModelInfo) (added, missing []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	byID := make(map[string]ModelInfo, len(found))
+	for _, m := range found {
+		byID[m.ID] = m
+	}
+	next := slices.Clone(s.models)
+	for i, m := range next {
+		f, ok := byID[m.ID]
+		if !ok {
+			if !m.Discovered {
+				missing = append(missing, m.ID)
+			}
+			continue
+		}
+		delete(byID, m.ID)
+		if m.ContextWindow == 0 {
+			next[i].ContextWindow = f.ContextWindow
+		}
+		if m.MaxTokens == 0 {
+			next[i].MaxTokens = f.MaxTokens
+		}
+	}
+	for _, m := range found {
+		if _, ok := byID[m.ID]; !ok {
+			continue
+		}
+		delete(byID, m.ID)
+		m.ProviderID = providerID
+		m.Discovered = true
+		next = append(next, m)
+		added = append(added, m.ID)
+	}
+	s.models = next
+	return added, missing
+}
+
+// DiscoverModels lists the models of every endpoint behind p that supports
+// listing and merges them into that endpoint's model list. A configured
+// model the endpoint no longer reports is logged as a warning and kept, so
+// a flaky listing never takes a working model away. Listing errors are
+// logged and joined into the returned error; the other endpoints still run.
+func DiscoverModels(ctx context.Context, p Provider, log *slog.Logger) error {
+	if log == nil {
+		log = slog.Default()
+	}
+	var errs []error
+	for _, d := range discoverers(p, nil) {
+		found, err := d.ListModels(ctx)
+		if err != nil {
+			log.Warn("model discovery failed", "provider", d.ID(), "error", err)
+			errs = append(errs, fmt.Errorf("provider %s: %w", d.ID(), err))
+			continue
+		}
+		added, missing := d.mergeModels(found)
+		for _, id := range missing {
+			log.Warn("configured model not listed by provider", "provider", d.ID(), "model", id)
+		}
+		if len(added) > 0 {
+			log.Info("models discovered", "provider", d.ID(), "listed", len(found), "added", added)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunModelDiscovery calls DiscoverModels every interval until ctx is done.
+// Each round is bounded by a timeout so a hung endpoint cannot stall the
+// next one.
+func RunModelDiscovery(ctx context.Context, p Provider, interval time.Duration, log *slog.Logger) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			roundCtx, cancel := context.WithTimeout(ctx, defaultDiscoveryTimeout)
+			_ = DiscoverModels(roundCtx, p, log)
+			cancel()
+		}
+	}
+}
+
+// discoverers collects the listable endpoints behind p, each once.
+func discoverers(p Provider, out []modelDiscoverer) []modelDiscoverer {
+	if w, ok := p.(backendProvider); ok {
+		for _, b := range w.backends() {
+			out = discoverers(b, out)
+		}
+		return out
+	}
+	if d, ok := p.(modelDiscoverer); ok && !slices.Contains(out, d) {
+		out = append(out, d)
+	}
+	return out
+}
+
+// -- OpenAI-compatible listing --
+
+// oaiModel is one entry of GET /models. OpenAI itself reports only the id;
+// compatible servers add the context length under their own names
+// (OpenRouter context_length, vLLM max_model_len).
+type oaiModel struct {
+	ID            string `json:"id"`
+	ContextWindow int    `json:"context_window"`
+	ContextLength int    `json:"context_length"`
+	MaxModelLen   int    `json:"max_model_len"`
+}
+
+// ListModels returns the models the endpoint reports at GET /models.
+func (p *OpenAIProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var resp struct {
+		Data []oaiModel `json:"data"`
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+openAIModelsPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	p.setHeaders(req)
+	if err := getJSON(p.client, req, &resp); err != nil {
+		return nil, err
+	}
+	out := make([]ModelInfo, 0, len(resp.Data))
+	for _, m := range resp.Data {
+		if m.ID == "" {
+			continue
+		}
+		out = append(out, ModelInfo{
+			ID:            m.ID,
+			Name:          m.ID,
+			ProviderID:    p.id,
+			ContextWindow: max(m.ContextWindow, m.ContextLength, m.MaxModelLen),
+		})
+	}
+	return out, nil
+}
+
+func (p *OpenAIProvider) mergeModels(found []ModelInfo) (added, missing []string) {
+	return p.models.merge(p.id, found)
+}
+
+// -- Anthropic listing --
+
+// anthropicModelsPageSize is the largest page the Models API returns.
+const anthropicModelsPageSize = 1000
+
+type anthModel struct {
+	ID             string `json:"id"`
+	DisplayName    string `json:"display_name"`
+	MaxInputTokens int    `json:"max_input_tokens"`
+	MaxTokens      int    `json:"max_tokens"`
+}
+
+// ListModels returns the models the Anthropic Models API reports, following
+// its pagination.
+func (p *AnthropicProvider) ListModels(ctx context.Context) ([]ModelInfo, error) {
+	var out []ModelInfo
+	after := ""
+	for {
+		q := url.Values{"limit": {fmt.Sprint(anthropicModelsPageSize)}}
+		if after != "" {
+			q.Set("after_id", after)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+anthropicModelsPath+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, fmt.Errorf("create request: %w", err)
+		}
+		p.setHeaders(req)
+		var page struct {
+			Data    []anthModel `json:"data"`
+			HasMore bool        `json:"has_more"`
+			LastID  string      `json:"last_id"`
+		}
+		if err := getJSON(p.client, req, &page); err != nil {
+			return nil, err
+		}
+		for _, m := range page.Data {
+			name := m.DisplayName
+			if name == "" {
+				name = m.ID
+			}
+			out = append(out, ModelInfo{
+				ID:            m.ID,
+				Name:          name,
+				ProviderID:    p.id,
+				ContextWindow: m.MaxInputTokens,
+				MaxTokens:     m.MaxTokens,
+			})
+		}
+		if !page.HasMore || page.LastID == "" || page.LastID == after {
+			return out, nil
+		}
+		after = page.LastID
+	}
+}
+
+func (p *AnthropicProvider) mergeModels(found []ModelInfo) (added, missing []string) {
+	return p.models.merge(p.id, found)
+}
+
+// getJSON sends req and decodes a 200 JSON body into v.
+func getJSON(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("http request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("api error (status %d): %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+	return nil
+}
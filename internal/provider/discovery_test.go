@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDiscoverModelsOpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/v1/models" {
+			t.Errorf("%s %s, want GET /v1/models", r.Method, r.URL.Path)
+		}
+		if r.Header.Get("Authorization") != "Bearer sk-test" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		_, _ = w.Write([]byte(`{"object":"list","data":[
+			{"id":"gpt-4o","object":"model"},
+			{"id":"llama-3-70b","context_length":131072},
+			{"id":"qwen","max_model_len":32768}
+		]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("openai", server.URL+"/v1", "sk-test", []ModelInfo{
+		{ID: "gpt-4o", ProviderID: "openai", ContextWindow: 128000, Cost: ModelCost{Input: 2.5}},
+		{ID: "qwen", ProviderID: "openai"},
+		{ID: "gpt-3.5-turbo", ProviderID: "openai"},
+	})
+	var logs bytes.Buffer
+	if err := DiscoverModels(context.Background(), p, slog.New(slog.NewTextHandler(&logs, nil))); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]ModelInfo{}
+	for _, m := range p.Models() {
+		got[m.ID] = m
+	}
+	if len(got) != 4 {
+		t.Fatalf("models = %v, want the 3 configured plus llama-3-70b", got)
+	}
+	if m := got["gpt-4o"]; m.ContextWindow != 128000 || m.Cost.Input != 2.5 || m.Discovered {
+		t.Errorf("configured gpt-4o changed: %+v", m)
+	}
+	if m := got["qwen"]; m.ContextWindow != 32768 {
+		t.Errorf("qwen context window = %d, want 32768 from the listing", m.ContextWindow)
+	}
+	if m := got["llama-3-70b"]; !m.Discovered || m.ContextWindow != 131072 || m.ProviderID != "openai" {
+		t.Errorf("discovered llama-3-70b = %+v", m)
+	}
+	if m, ok := got["gpt-3.5-turbo"]; !ok || m.Discovered {
+		t.Error("a configured model missing upstream was dropped")
+	}
+	if !strings.Contains(logs.String(), "configured model not listed by provider") || !strings.Contains(logs.String(), "gpt-3.5-turbo") {
+		t.Errorf("no warning for the missing model:\n%s", logs.String())
+	}
+
+	// A second round adds nothing and does not warn about discovered models.
+	logs.Reset()
+	if err := DiscoverModels(context.Background(), p, slog.New(slog.NewTextHandler(&logs, nil))); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(p.Models()); n != 4 {
+		t.Errorf("models after second round = %d, want 4", n)
+	}
+	if strings.Count(logs.String(), "configured model not listed") != 1 {
+		t.Errorf("second round warnings:\n%s", logs.String())
+	}
+}
+
+func TestDiscoverModelsAnthropicPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/models" || r.Header.Get("x-api-key") != "sk-ant" {
+			t.Errorf("path %s, x-api-key %q", r.URL.Path, r.Header.Get("x-api-key"))
+		}
+		switch r.URL.Query().Get("after_id") {
+		case "":
+			_, _ = w.Write([]byte(`{"data":[{"id":"claude-opus-4-1","display_name":"Claude Opus 4.1","max_input_tokens":200000,"max_tokens":32000}],"has_more":true,"last_id":"claude-opus-4-1"}`))
+		case "claude-opus-4-1":
+			_, _ = w.Write([]byte(`{"data":[{"id":"claude-haiku-4-5","display_name":"Claude Haiku 4.5"}],"has_more":false,"last_id":"claude-haiku-4-5"}`))
+		default:
+			t.Errorf("after_id = %q", r.URL.Query().Get("after_id"))
+		}
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("anthropic", server.URL, "sk-ant", nil)
+	models, err := p.ListModels(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("models = %+v, want both pages", models)
+	}
+	if m := models[0]; m.Name != "Claude Opus 4.1" || m.ContextWindow != 200000 || m.MaxTokens != 32000 {
+		t.Errorf("models[0] = %+v", m)
+	}
+}
+
+func TestDiscoverModelsBehindFailover(t *testing.T) {
+	listed := func(body string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(body))
+		}))
+	}
+	primary := listed(`{"data":[{"id":"a"},{"id":"a2"}]}`)
+	defer primary.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "nope", http.StatusUnauthorized)
+	}))
+	defer broken.Close()
+
+	pa := NewOpenAIProvider("pa", primary.URL, "", []ModelInfo{{ID: "a"}})
+	pb := NewOpenAIProvider("pb", broken.URL, "", []ModelInfo{{ID: "b"}}, WithOpenAIRetryPolicy(RetryPolicy{MaxAttempts: 1}))
+	hg := NewHealthGatedProvider(context.Background(), []ProviderEntry{{Prov: pa, Model: "a"}, {Prov: pb, Model: "b"}}, nil, HealthGateConfig{}, slog.Default())
+
+	err := DiscoverModels(context.Background(), hg, slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil)))
+	if err == nil || !strings.Contains(err.Error(), "provider pb") {
+		t.Errorf("err = %v, want the pb listing failure", err)
+	}
+	if n := len(pa.Models()); n != 2 {
+		t.Errorf("primary models = %d, want a and the discovered a2", n)
+	}
+	if n := len(hg.Models()); n != 3 {
+		t.Errorf("failover models = %d, want 3", n)
+	}
+}
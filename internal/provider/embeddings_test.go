@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIEmbedder_BatchesAndOrders(t *testing.T) {
+	var batches []int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req oaiEmbeddingRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		batches = append(batches, len(req.Input))
+		var resp oaiEmbeddingResponse
+		// Answer in reverse order: the client must place vectors by index.
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{i, []float32{float32(len(req.Input[i]))}})
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	texts := make([]string, embedBatchSize+3)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	vecs, err := NewOpenAIEmbedder(srv.URL+"/v1/", "key", "text-embedding-3-small").Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0] != embedBatchSize || batches[1] != 3 {
+		t.Errorf("batches = %v", batches)
+	}
+	for i, v := range vecs {
+		if len(v) != 1 || int(v[0]) != i+1 {
+			t.Fatalf("vector %d = %v; want [%d]", i, v, i+1)
+		}
+	}
+}
+
+func TestOpenAIEmbedder_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+	_, err := NewOpenAIEmbedder(srv.URL, "", "nope").Embed(context.Background(), []string{"a"})
+	if err == nil || !strings.Contains(err.Error(), "model not found") {
+		t.Errorf("err = %v", err)
+	}
+}
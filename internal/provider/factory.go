@@ -32,6 +32,10 @@ type ProviderConfig struct {
 	DebugResolve DebugContextResolver
 	EventSink    emit.Sink
 	Retry        RetryPolicy // transient-failure retry; zero fields -> DefaultRetryPolicy
+
+	// NoPromptCache stops the Anthropic provider from marking the system
+	// prompt and tools as cacheable.
+	NoPromptCache bool
 }
 
 // FromConfig creates a Provider from a config entry. The api field
@@ -58,7 +62,7 @@ func FromConfig(cfg ProviderConfig) (Provider, error) {
 		if cfg.EventSink != nil {
 			opts = append(opts, WithAnthropicSessionEventSink(cfg.EventSink))
 		}
-		opts = append(opts, WithAnthropicRetryPolicy(cfg.Retry))
+		opts = append(opts, WithAnthropicRetryPolicy(cfg.Retry), WithAnthropicPromptCaching(!cfg.NoPromptCache))
 		return NewAnthropicProvider(cfg.ID, cfg.BaseURL, cfg.APIKey, cfg.Models, opts...), nil
 	default:
 		return nil, fmt.Errorf("unknown api type %q for provider %q (supported: %s, %s)",
@@ -1,6 +1,9 @@
 package provider
 
-import "strings"
+import (
+	"encoding/base64"
+	"strings"
+)
 
 // FileClass is the broad category of a file attachment, used by providers to
 // decide how to encode it in their API request.
@@ -36,3 +39,75 @@ func ClassifyFile(mimeType string, _ []byte) FileClass {
 		return FileClassBinary
 	}
 }
+
+// AcceptsInput reports whether m lists kind ("text", "image", …) among its
+// declared input types.
+func (m ModelInfo) AcceptsInput(kind string) bool {
+	for _, t := range m.InputTypes {
+		if t == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptedFiles drops image attachments the request's model cannot take.
+// Only models that declare their input types are gated; an undeclared model
+// gets files as before. Each dropped image leaves a note in the message
+// text, so the model can tell the user instead of answering blind.
+// req is not modified; a copy is returned when anything changes.
+func acceptedFiles(models []ModelInfo, req *CompletionRequest) *CompletionRequest {
+	var info ModelInfo
+	for _, m := range models {
+		if m.ID == req.Model {
+			info = m
+			break
+		}
+	}
+	if len(info.InputTypes) == 0 || info.AcceptsInput("image") {
+		return req
+	}
+	var msgs []Message
+	for i, m := range req.Messages {
+		var kept []MessageFile
+		var dropped []string
+		for _, f := range m.Files {
+			if ClassifyFile(f.MimeType, f.Data) == FileClassImage {
+				dropped = append(dropped, fileLabel(f))
+				continue
+			}
+			kept = append(kept, f)
+		}
+		if len(dropped) == 0 {
+			continue
+		}
+		if msgs == nil {
+			msgs = append([]Message(nil), req.Messages...)
+		}
+		m.Files = kept
+		m.Content = strings.TrimSpace(m.Content + "\n\n[Image " + strings.Join(dropped, ", ") + " omitted: model " + req.Model + " does not accept image input.]")
+		msgs[i] = m
+	}
+	if msgs == nil {
+		return req
+	}
+	cp := *req
+	cp.Messages = msgs
+	return &cp
+}
+
+func fileLabel(f MessageFile) string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return f.MimeType
+}
+
+// dataURI encodes f as a data: URI, or returns its URL when the channel sent
+// a link and no bytes.
+func dataURI(f MessageFile) string {
+	if len(f.Data) == 0 && f.URL != "" {
+		return f.URL
+	}
+	return "data:" + f.MimeType + ";base64," + base64.StdEncoding.EncodeToString(f.Data)
+}
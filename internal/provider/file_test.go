@@ -1,6 +1,9 @@
 package provider
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
 
 func TestClassifyFile(t *testing.T) {
 	tests := []struct {
@@ -42,3 +45,39 @@ func TestClassifyFile(t *testing.T) {
 		})
 	}
 }
+
+func TestAcceptedFiles(t *testing.T) {
+	models := []ModelInfo{
+		{ID: "text-only", InputTypes: []string{"text"}},
+		{ID: "vision", InputTypes: []string{"text", "image"}},
+		{ID: "undeclared"},
+	}
+	req := &CompletionRequest{Messages: []Message{
+		{Role: RoleUser, Content: "look", Files: []MessageFile{
+			{Name: "shot.png", MimeType: "image/png", Data: []byte{1}},
+			{MimeType: "text/csv", Data: []byte("a,b")},
+		}},
+	}}
+
+	for _, model := range []string{"vision", "undeclared", "unknown"} {
+		r := *req
+		r.Model = model
+		if got := acceptedFiles(models, &r); got != &r {
+			t.Errorf("%s: request should pass unchanged", model)
+		}
+	}
+
+	r := *req
+	r.Model = "text-only"
+	got := acceptedFiles(models, &r)
+	m := got.Messages[0]
+	if len(m.Files) != 1 || m.Files[0].MimeType != "text/csv" {
+		t.Errorf("files = %+v; want only the CSV", m.Files)
+	}
+	if !strings.Contains(m.Content, "[Image shot.png omitted: model text-only does not accept image input.]") {
+		t.Errorf("content = %q", m.Content)
+	}
+	if len(req.Messages[0].Files) != 2 {
+		t.Error("the caller's request was modified")
+	}
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -21,6 +22,9 @@ type ProviderEntry struct {
 	Model string
 }
 
+// name is the entry as "provider/model", the form used in failover events.
+func (e ProviderEntry) name() string { return e.Prov.ID() + "/" + e.Model }
+
 // HealthProbe reports whether the preferred endpoint is reachable: nil means
 // healthy, a non-nil error means unhealthy. It is a function so tests can fake
 // it without real network I/O.
@@ -32,6 +36,23 @@ type HealthGateConfig struct {
 	Interval     time.Duration // how often to probe the preferred endpoint
 	Timeout      time.Duration // per-probe timeout
 	RecoverAfter int           // consecutive healthy probes required to switch back to the preferred endpoint
+
+	// OnFailover, when set, is called each time a request fails on one
+	// endpoint and moves on to the next. from and to are "provider/model".
+	OnFailover func(ctx context.Context, from, to string, err error)
+	// OnExhausted, when set, is called when a request failed on every
+	// endpoint; attempted lists them in the order they were tried.
+	OnExhausted func(ctx context.Context, attempted []string, err error)
+	// OnHealthChange, when set, is called when the preferred endpoint trips
+	// (healthy false, err the cause) and when it recovers.
+	OnHealthChange func(ctx context.Context, endpoint string, healthy bool, err error)
+
+	// Monitor, when set, scores the endpoints from periodic probes. The
+	// preferred endpoint's probe results count towards its score; the
+	// caller watches the fallbacks. Degraded endpoints are tried after the
+	// others, so traffic moves away from a slow or flaky endpoint before
+	// requests fail on it.
+	Monitor *HealthMonitor
 }
 
 const (
@@ -48,9 +69,13 @@ const (
 // available — e.g. a self-hosted GPU node that is warming up, being cycled on a
 // schedule, or briefly unreachable.
 type healthGatedProvider struct {
-	entries []ProviderEntry // [0] preferred, [1:] fallbacks in priority order
-	health  *endpointHealth
-	log     *slog.Logger
+	entries     []ProviderEntry // [0] preferred, [1:] fallbacks in priority order
+	stats       []endpointStats // per entry
+	health      *endpointHealth
+	log         *slog.Logger
+	monitor     *HealthMonitor // optional
+	onFailover  func(ctx context.Context, from, to string, err error)
+	onExhausted func(ctx context.Context, attempted []string, err error)
 }
 
 // NewHealthGatedProvider wraps entries[0] (preferred) with entries[1:] as
@@ -86,7 +111,22 @@ func NewHealthGatedProvider(ctx context.Context, entries []ProviderEntry, probe
 	// consecutive healthy probes.
 	h.healthy.Store(true)
 	h.consecutiveOK = recoverAfter
-	hg := &healthGatedProvider{entries: entries, health: h, log: log}
+	hg := &healthGatedProvider{
+		entries:     entries,
+		stats:       make([]endpointStats, len(entries)),
+		health:      h,
+		log:         log,
+		onFailover:  cfg.OnFailover,
+		onExhausted: cfg.OnExhausted,
+		monitor:     cfg.Monitor,
+	}
+	if cfg.Monitor != nil && probe != nil {
+		h.probe = cfg.Monitor.Observe(entries[0].name(), probe)
+	}
+	if cfg.OnHealthChange != nil {
+		name := entries[0].name()
+		h.onChange = func(ctx context.Context, healthy bool, err error) { cfg.OnHealthChange(ctx, name, healthy, err) }
+	}
 	if probe != nil {
 		go h.run(ctx)
 	}
@@ -109,9 +149,19 @@ func (h *healthGatedProvider) Models() []ModelInfo {
 	return out
 }
 
+func (h *healthGatedProvider) backends() []Provider {
+	out := make([]Provider, len(h.entries))
+	for i, e := range h.entries {
+		out[i] = e.Prov
+	}
+	return out
+}
+
 // order returns the indices of entries to try, in priority order: preferred
 // first when healthy; otherwise fallbacks first with the preferred endpoint
-// kept as a last resort (in case it recovered between probes).
+// kept as a last resort (in case it recovered between probes). With a
+// monitor, degraded endpoints then move behind the others, keeping their
+// relative order.
 func (h *healthGatedProvider) order() []int {
 	n := len(h.entries)
 	idx := make([]int, 0, n)
@@ -119,60 +169,122 @@ func (h *healthGatedProvider) order() []int {
 		for i := 0; i < n; i++ {
 			idx = append(idx, i)
 		}
+	} else {
+		for i := 1; i < n; i++ {
+			idx = append(idx, i)
+		}
+		idx = append(idx, 0)
+	}
+	if h.monitor == nil {
 		return idx
 	}
-	for i := 1; i < n; i++ {
-		idx = append(idx, i)
+	var degraded []int
+	kept := idx[:0:0]
+	for _, i := range idx {
+		if h.monitor.Degraded(h.entries[i].name()) {
+			degraded = append(degraded, i)
+		} else {
+			kept = append(kept, i)
+		}
 	}
-	idx = append(idx, 0)
-	return idx
+	return append(kept, degraded...)
 }
 
 func (h *healthGatedProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
 	var lastErr error
-	for _, idx := range h.order() {
+	order := h.order()
+	for i, idx := range order {
 		e := h.entries[idx]
 		cp := *req
 		cp.Model = e.Model
 		resp, err := e.Prov.Complete(ctx, &cp)
+		h.stats[idx].record(ctx, err)
 		if err == nil {
 			return resp, nil
 		}
 		lastErr = err
 		if idx == 0 {
-			h.health.trip()
+			h.health.trip(ctx, err)
 		}
 		if len(h.entries) > 1 {
 			h.log.Warn("llm provider failed; trying next endpoint",
 				"provider", e.Prov.ID(), "model", e.Model, "error", err)
 		}
+		if i+1 < len(order) {
+			h.failover(ctx, e, h.entries[order[i+1]], err)
+		}
 	}
+	h.exhausted(ctx, order, lastErr)
 	return nil, lastErr
 }
 
 func (h *healthGatedProvider) Stream(ctx context.Context, req *CompletionRequest) (ResponseStream, error) {
 	var lastErr error
-	for _, idx := range h.order() {
+	order := h.order()
+	for i, idx := range order {
 		e := h.entries[idx]
 		cp := *req
 		cp.Model = e.Model
 		cp.Stream = true
 		stream, err := e.Prov.Stream(ctx, &cp)
+		h.stats[idx].record(ctx, err)
 		if err == nil {
 			return stream, nil
 		}
 		lastErr = err
 		if idx == 0 {
-			h.health.trip()
+			h.health.trip(ctx, err)
 		}
 		if len(h.entries) > 1 {
 			h.log.Warn("llm provider stream failed; trying next endpoint",
 				"provider", e.Prov.ID(), "model", e.Model, "error", err)
 		}
+		if i+1 < len(order) {
+			h.failover(ctx, e, h.entries[order[i+1]], err)
+		}
 	}
+	h.exhausted(ctx, order, lastErr)
 	return nil, lastErr
 }
 
+func (h *healthGatedProvider) failover(ctx context.Context, from, to ProviderEntry, err error) {
+	if h.onFailover != nil {
+		h.onFailover(ctx, from.name(), to.name(), err)
+	}
+}
+
+// exhausted reports a request that failed on every endpoint in order,
+// unless the caller gave up on it.
+func (h *healthGatedProvider) exhausted(ctx context.Context, order []int, err error) {
+	if h.onExhausted == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	attempted := make([]string, len(order))
+	for i, idx := range order {
+		attempted[i] = h.entries[idx].name()
+	}
+	h.onExhausted(ctx, attempted, err)
+}
+
+// EndpointStatus implements StatusReporter.
+func (h *healthGatedProvider) EndpointStatus() []EndpointStatus {
+	active := h.order()[0]
+	out := make([]EndpointStatus, len(h.entries))
+	for i, e := range h.entries {
+		st := EndpointStatus{Provider: e.Prov.ID(), Model: e.Model, Role: RoleFallback, Healthy: true, Active: i == active}
+		if i == 0 {
+			st.Role = RolePrimary
+			st.Healthy, st.UnhealthySince = h.health.state()
+		}
+		h.stats[i].fill(&st)
+		if h.monitor != nil {
+			st.fillProbes(h.monitor, e.name())
+		}
+		out[i] = st
+	}
+	return out
+}
+
 // endpointHealth tracks the reachability of the preferred endpoint with
 // hysteresis on recovery.
 type endpointHealth struct {
@@ -184,20 +296,42 @@ type endpointHealth struct {
 
 	mu            sync.Mutex
 	consecutiveOK int
+	since         time.Time // when the endpoint last went unhealthy; zero while healthy
 
-	log *slog.Logger
+	log      *slog.Logger
+	onChange func(ctx context.Context, healthy bool, err error) // optional
 }
 
 func (h *endpointHealth) isHealthy() bool { return h.healthy.Load() }
 
-// trip marks the endpoint unhealthy immediately, called on a live request
-// failure against the preferred endpoint. Recovery then requires recoverAfter
-// consecutive healthy probes.
-func (h *endpointHealth) trip() {
+// state reports whether the endpoint is healthy and, if not, since when.
+func (h *endpointHealth) state() (bool, time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.healthy.Load(), h.since
+}
+
+// markDown marks the endpoint unhealthy and resets the recovery count. It
+// reports whether the endpoint was healthy until now.
+func (h *endpointHealth) markDown(ctx context.Context, err error) bool {
 	h.mu.Lock()
 	h.consecutiveOK = 0
+	changed := h.healthy.Swap(false)
+	if changed {
+		h.since = time.Now()
+	}
 	h.mu.Unlock()
-	if h.healthy.Swap(false) {
+	if changed && h.onChange != nil {
+		h.onChange(ctx, false, err)
+	}
+	return changed
+}
+
+// trip marks the endpoint unhealthy immediately, called on a live request
+// failure against the preferred endpoint. Recovery then requires recoverAfter
+// consecutive healthy probes.
+func (h *endpointHealth) trip(ctx context.Context, err error) {
+	if h.markDown(ctx, err) {
 		h.log.Warn("preferred llm endpoint tripped to unhealthy after a live failure")
 	}
 }
@@ -223,20 +357,24 @@ func (h *endpointHealth) probeOnce(ctx context.Context) {
 		defer cancel()
 	}
 	err := h.probe(pctx)
-	h.mu.Lock()
-	defer h.mu.Unlock()
 	if err != nil {
-		h.consecutiveOK = 0
-		if h.healthy.Swap(false) {
+		if h.markDown(ctx, err) {
 			h.log.Warn("preferred llm endpoint probe failed; falling back", "error", err)
 		}
 		return
 	}
+	h.mu.Lock()
 	h.consecutiveOK++
-	if !h.healthy.Load() && h.consecutiveOK >= h.recoverAfter {
+	recovered := !h.healthy.Load() && h.consecutiveOK >= h.recoverAfter
+	if recovered {
 		h.healthy.Store(true)
+		h.since = time.Time{}
 		h.log.Info("preferred llm endpoint healthy again; switching back", "consecutive_ok", h.consecutiveOK)
 	}
+	h.mu.Unlock()
+	if recovered && h.onChange != nil {
+		h.onChange(ctx, true, nil)
+	}
 }
 
 // NewHTTPHealthProbe returns a HealthProbe that GETs probeURL with an optional
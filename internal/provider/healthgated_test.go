@@ -56,6 +56,7 @@ func newTestHG(preferred, fallback *fakeProvider, recoverAfter int, probe Health
 			{Prov: preferred, Model: preferred.model},
 			{Prov: fallback, Model: fallback.model},
 		},
+		stats:  make([]endpointStats, 2),
 		health: h,
 		log:    slog.Default(),
 	}
@@ -164,3 +165,33 @@ func TestHealthGatedModelsUnion(t *testing.T) {
 		t.Fatalf("expected wrapper ID to be preferred's, got %q", hg.ID())
 	}
 }
+
+func TestHealthGatedOnFailover(t *testing.T) {
+	preferred := &fakeProvider{id: "dedicated", model: "gpt-oss-120b", failNext: true}
+	fallback := &fakeProvider{id: "shared", model: "gpt-oss-120b-ovh"}
+	var got []string
+	p := NewHealthGatedProvider(context.Background(), []ProviderEntry{
+		{Prov: preferred, Model: preferred.model},
+		{Prov: fallback, Model: fallback.model},
+	}, nil, HealthGateConfig{OnFailover: func(_ context.Context, from, to string, err error) {
+		got = append(got, from+" -> "+to+": "+err.Error())
+	}}, nil)
+
+	if _, err := p.Complete(context.Background(), &CompletionRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "dedicated/gpt-oss-120b -> shared/gpt-oss-120b-ovh: boom"
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("failovers = %q, want [%q]", got, want)
+	}
+
+	// The last endpoint failing has nowhere to fail over to.
+	fallback.failNext = true
+	got = nil
+	if _, err := p.Complete(context.Background(), &CompletionRequest{}); err == nil {
+		t.Fatal("expected error when every endpoint fails")
+	}
+	if want := "shared/gpt-oss-120b-ovh -> dedicated/gpt-oss-120b: boom"; len(got) != 1 || got[0] != want {
+		t.Fatalf("failovers = %q, want [%q]", got, want)
+	}
+}
@@ -0,0 +1,199 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// HealthMonitorConfig tunes a HealthMonitor. Zero values use the defaults
+// below.
+type HealthMonitorConfig struct {
+	Interval      time.Duration // between probes of one endpoint; default 10s
+	Timeout       time.Duration // per probe; default 3s
+	Window        int           // recent probes the score is computed from; default 10
+	SlowAfter     time.Duration // mean probe latency above which the score drops; default 2s
+	DegradedBelow float64       // score under which an endpoint counts as degraded; default 0.5
+}
+
+const (
+	defaultMonitorWindow        = 10
+	defaultMonitorSlowAfter     = 2 * time.Second
+	defaultMonitorDegradedBelow = 0.5
+)
+
+// EndpointHealth is what the probes of one endpoint measured recently.
+type EndpointHealth struct {
+	// Score is 0-1: the share of probes in the window that succeeded,
+	// scaled down by how far their mean latency exceeds SlowAfter. 1
+	// before the first probe.
+	Score     float64
+	Latency   time.Duration // mean latency of the successful probes in the window
+	ErrorRate float64
+	Probes    int // in the window
+	LastError string
+}
+
+// HealthMonitor keeps a rolling health score per LLM endpoint from
+// periodic lightweight probes (a models listing or a one-token completion).
+// Unlike the health gate, which only knows up or down for the preferred
+// endpoint, it notices an endpoint that is slow or failing intermittently,
+// so routing can move traffic away before live requests fail on it.
+// Endpoints are named "provider/model", as in failover events.
+type HealthMonitor struct {
+	cfg HealthMonitorConfig
+
+	mu        sync.Mutex
+	endpoints map[string]*probeWindow
+}
+
+type probeResult struct {
+	latency time.Duration
+	err     error
+}
+
+// probeWindow is a ring of the most recent probe results.
+type probeWindow struct {
+	results []probeResult
+	next    int
+}
+
+// NewHealthMonitor returns a monitor with no endpoints; add them with
+// Watch or Observe.
+func NewHealthMonitor(cfg HealthMonitorConfig) *HealthMonitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultHealthInterval
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHealthTimeout
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = defaultMonitorWindow
+	}
+	if cfg.SlowAfter <= 0 {
+		cfg.SlowAfter = defaultMonitorSlowAfter
+	}
+	if cfg.DegradedBelow <= 0 {
+		cfg.DegradedBelow = defaultMonitorDegradedBelow
+	}
+	return &HealthMonitor{cfg: cfg, endpoints: make(map[string]*probeWindow)}
+}
+
+// Observe wraps probe so its results count towards name's score, for an
+// endpoint some other loop already probes (the health gate's primary).
+func (m *HealthMonitor) Observe(name string, probe HealthProbe) HealthProbe {
+	return func(ctx context.Context) error {
+		start := time.Now()
+		err := probe(ctx)
+		// A probe cut off by shutdown says nothing; one that timed out does.
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			m.record(name, time.Since(start), err)
+		}
+		return err
+	}
+}
+
+// Watch probes name now and then every Interval until ctx is done.
+func (m *HealthMonitor) Watch(ctx context.Context, name string, probe HealthProbe) {
+	probe = m.Observe(name, probe)
+	run := func() {
+		pctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+		defer cancel()
+		_ = probe(pctx)
+	}
+	go func() {
+		run()
+		t := time.NewTicker(m.cfg.Interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				run()
+			}
+		}
+	}()
+}
+
+func (m *HealthMonitor) record(name string, latency time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := m.endpoints[name]
+	if w == nil {
+		w = &probeWindow{}
+		m.endpoints[name] = w
+	}
+	r := probeResult{latency: latency, err: err}
+	if len(w.results) < m.cfg.Window {
+		w.results = append(w.results, r)
+		return
+	}
+	w.results[w.next] = r
+	w.next = (w.next + 1) % m.cfg.Window
+}
+
+// Health reports name's recent probes. A nil monitor, or an endpoint not
+// probed yet, reports a perfect score.
+func (m *HealthMonitor) Health(name string) EndpointHealth {
+	h := EndpointHealth{Score: 1}
+	if m == nil {
+		return h
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w := m.endpoints[name]
+	if w == nil || len(w.results) == 0 {
+		return h
+	}
+	var failures int
+	var total time.Duration
+	for _, r := range w.results {
+		if r.err != nil {
+			failures++
+			continue
+		}
+		total += r.latency
+	}
+	h.Probes = len(w.results)
+	h.ErrorRate = float64(failures) / float64(h.Probes)
+	h.Score = 1 - h.ErrorRate
+	if ok := h.Probes - failures; ok > 0 {
+		h.Latency = total / time.Duration(ok)
+		if h.Latency > m.cfg.SlowAfter {
+			h.Score *= float64(m.cfg.SlowAfter) / float64(h.Latency)
+		}
+	}
+	last := w.results[len(w.results)-1]
+	if len(w.results) == m.cfg.Window {
+		last = w.results[(w.next+m.cfg.Window-1)%m.cfg.Window]
+	}
+	if last.err != nil {
+		h.LastError = last.err.Error()
+	}
+	return h
+}
+
+// Degraded reports whether name's score has dropped below DegradedBelow.
+func (m *HealthMonitor) Degraded(name string) bool {
+	if m == nil {
+		return false
+	}
+	return m.Health(name).Score < m.cfg.DegradedBelow
+}
+
+// NewCompletionProbe checks an endpoint by asking model for a one-token
+// completion. It costs a few tokens per probe but, unlike a models listing,
+// exercises the inference path, and works for providers without one.
+func NewCompletionProbe(p Provider, model string) HealthProbe {
+	return func(ctx context.Context) error {
+		_, err := p.Complete(ctx, &CompletionRequest{
+			Model:     model,
+			Messages:  []Message{{Role: RoleUser, Content: "ping"}},
+			MaxTokens: 1,
+			NoCache:   true,
+		})
+		return err
+	}
+}
@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHealthMonitor_Score(t *testing.T) {
+	m := NewHealthMonitor(HealthMonitorConfig{Window: 4, SlowAfter: time.Second})
+	if h := m.Health("a/m"); h.Score != 1 || h.Probes != 0 || m.Degraded("a/m") {
+		t.Fatalf("unprobed endpoint = %+v", h)
+	}
+
+	m.record("a/m", 100*time.Millisecond, nil)
+	m.record("a/m", 300*time.Millisecond, nil)
+	m.record("a/m", time.Second, errors.New("timeout"))
+	h := m.Health("a/m")
+	if h.Probes != 3 || h.Latency != 200*time.Millisecond || h.LastError != "timeout" {
+		t.Errorf("health = %+v", h)
+	}
+	if want := 2.0 / 3; h.Score < want-1e-9 || h.Score > want+1e-9 {
+		t.Errorf("score = %v, want %v", h.Score, want)
+	}
+
+	// The window keeps the last 4 probes: two more failures push the
+	// error rate to 3/4 and the endpoint is degraded.
+	m.record("a/m", 0, errors.New("503"))
+	m.record("a/m", 0, errors.New("503"))
+	if h := m.Health("a/m"); h.Probes != 4 || h.ErrorRate != 0.75 || h.LastError != "503" || !m.Degraded("a/m") {
+		t.Errorf("after failures = %+v, degraded=%v", h, m.Degraded("a/m"))
+	}
+
+	// Slow but successful probes lower the score too.
+	for range 4 {
+		m.record("slow/m", 4*time.Second, nil)
+	}
+	if h := m.Health("slow/m"); h.Score != 0.25 || h.LastError != "" || !m.Degraded("slow/m") {
+		t.Errorf("slow endpoint = %+v", h)
+	}
+
+	var nilMonitor *HealthMonitor
+	if nilMonitor.Degraded("a/m") || nilMonitor.Health("a/m").Score != 1 {
+		t.Error("a nil monitor must report every endpoint healthy")
+	}
+}
+
+func TestHealthMonitor_ObserveSkipsShutdown(t *testing.T) {
+	m := NewHealthMonitor(HealthMonitorConfig{})
+	probe := m.Observe("a/m", func(ctx context.Context) error { return ctx.Err() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = probe(ctx)
+	if m.Health("a/m").Probes != 0 {
+		t.Error("a probe cancelled by shutdown was recorded")
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	_ = probe(ctx)
+	if h := m.Health("a/m"); h.Probes != 1 || h.ErrorRate != 1 {
+		t.Errorf("a timed-out probe must count as a failure: %+v", h)
+	}
+}
+
+func TestHealthGatedPrefersNonDegradedEndpoints(t *testing.T) {
+	ctx := context.Background()
+	preferred := &fakeProvider{id: "dedicated", model: "m1"}
+	fallback := &fakeProvider{id: "shared", model: "m2"}
+	hg := newTestHG(preferred, fallback, 1, nil)
+	hg.monitor = NewHealthMonitor(HealthMonitorConfig{Window: 2})
+
+	for range 2 {
+		hg.monitor.record("dedicated/m1", time.Millisecond, errors.New("flaky"))
+	}
+	if got := hg.order(); !slices.Equal(got, []int{1, 0}) {
+		t.Fatalf("order with a degraded primary = %v, want fallback first", got)
+	}
+	resp, err := hg.Complete(ctx, &CompletionRequest{})
+	if err != nil || resp.Content != "ok-shared" || preferred.calls != 0 {
+		t.Fatalf("got %+v, %v; preferred calls %d", resp, err, preferred.calls)
+	}
+	st := hg.EndpointStatus()
+	if !st[0].Degraded || st[0].HealthScore == nil || *st[0].HealthScore != 0 || st[0].Active || !st[1].Active {
+		t.Errorf("status = %+v", st)
+	}
+	if st[1].HealthScore != nil {
+		t.Errorf("unprobed fallback reports a score: %v", *st[1].HealthScore)
+	}
+
+	// Once the primary probes well again it is preferred again.
+	for range 2 {
+		hg.monitor.record("dedicated/m1", time.Millisecond, nil)
+	}
+	if got := hg.order(); !slices.Equal(got, []int{0, 1}) {
+		t.Errorf("order after recovery = %v", got)
+	}
+}
@@ -56,6 +56,23 @@ const (
 type ModelCost struct {
 	Input  float64 `json:"input" yaml:"input"`
 	Output float64 `json:"output" yaml:"output"`
+	// Prompt cache rates per million tokens; 0 = Anthropic's ratio to
+	// Input (reads 0.1x, writes 1.25x).
+	CacheRead  float64 `json:"cache_read,omitempty" yaml:"cache_read,omitempty"`
+	CacheWrite float64 `json:"cache_write,omitempty" yaml:"cache_write,omitempty"`
+}
+
+// InputCost prices the prompt side of u: uncached input plus cache reads
+// and writes, at rates per million tokens.
+func (c ModelCost) InputCost(u Usage) float64 {
+	read, write := c.CacheRead, c.CacheWrite
+	if read == 0 {
+		read = c.Input * 0.1
+	}
+	if write == 0 {
+		write = c.Input * 1.25
+	}
+	return (float64(u.InputTokens)*c.Input + float64(u.CacheReadTokens)*read + float64(u.CacheWriteTokens)*write) / 1_000_000
 }
 
 type ModelInfo struct {
@@ -69,6 +86,9 @@ type ModelInfo struct {
 	MaxTokens       int       `json:"max_tokens" yaml:"max_tokens"`
 	Cost            ModelCost `json:"cost" yaml:"cost"`
 	Features        []Feature `json:"features" yaml:"features"`
+	// Discovered marks a model listed by the provider's API rather than
+	// configured; it has no cost, so usage on it is recorded at zero.
+	Discovered bool `json:"discovered,omitempty" yaml:"-"`
 }
 
 func (m ModelInfo) Ref() ModelRef {
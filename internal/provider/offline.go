@@ -0,0 +1,197 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// OfflineProvider serves completions from a remote provider while it is
+// reachable and from a local model (typically Ollama) otherwise. Unlike the
+// health-gated fallback, which moves between peer endpoints, the switch here
+// is a degradation the caller can observe through Offline so users can be
+// told answers come from a smaller model.
+//
+// The remote side is probed once at construction, so a process that starts
+// without network access goes straight to the local model instead of failing
+// its first turns; afterwards a failed live request or probe switches to the
+// local model and RecoverAfter consecutive healthy probes switch back.
+type OfflineProvider struct {
+	remote Provider
+	local  ProviderEntry
+	health *endpointHealth
+	log    *slog.Logger
+
+	remoteStats, localStats endpointStats
+
+	onFailover  func(ctx context.Context, from, to string, err error)
+	onExhausted func(ctx context.Context, attempted []string, err error)
+}
+
+// NewOfflineProvider wraps remote with local as the offline model. probe
+// checks whether any remote endpoint is reachable; nil disables probing, so
+// only live failures switch to the local model and the switch is permanent
+// until the provider is rebuilt. The probe loop stops when ctx is done.
+func NewOfflineProvider(ctx context.Context, remote Provider, local ProviderEntry, probe HealthProbe, cfg HealthGateConfig, log *slog.Logger) *OfflineProvider {
+	if log == nil {
+		log = slog.Default()
+	}
+	h := &endpointHealth{
+		probe:        probe,
+		interval:     cfg.Interval,
+		timeout:      cfg.Timeout,
+		recoverAfter: cfg.RecoverAfter,
+		log:          log,
+	}
+	if h.interval <= 0 {
+		h.interval = defaultHealthInterval
+	}
+	if h.timeout <= 0 {
+		h.timeout = defaultHealthTimeout
+	}
+	if h.recoverAfter <= 0 {
+		h.recoverAfter = defaultHealthRecoverAfter
+	}
+	h.healthy.Store(true)
+	h.consecutiveOK = h.recoverAfter
+	if cfg.OnHealthChange != nil {
+		name := remote.ID()
+		h.onChange = func(ctx context.Context, healthy bool, err error) { cfg.OnHealthChange(ctx, name, healthy, err) }
+	}
+	p := &OfflineProvider{remote: remote, local: local, health: h, log: log, onFailover: cfg.OnFailover, onExhausted: cfg.OnExhausted}
+	if probe != nil {
+		h.probeOnce(ctx)
+		if !h.isHealthy() {
+			log.Warn("no remote llm provider reachable at startup; running on the offline model",
+				"model", local.Model)
+		}
+		go h.run(ctx)
+	}
+	return p
+}
+
+// Offline reports whether completions currently go to the local model.
+func (p *OfflineProvider) Offline() bool { return !p.health.isHealthy() }
+
+func (p *OfflineProvider) ID() string { return p.remote.ID() }
+
+// SupportsFeature reports the remote's features while online and the local
+// model's while offline, so callers do not send native tools to a model
+// that cannot take them.
+func (p *OfflineProvider) SupportsFeature(f Feature) bool {
+	if p.Offline() {
+		return p.local.Prov.SupportsFeature(f)
+	}
+	return p.remote.SupportsFeature(f)
+}
+
+// Models returns the remote and local models so usage lookups resolve both.
+func (p *OfflineProvider) Models() []ModelInfo {
+	return append(append([]ModelInfo(nil), p.remote.Models()...), p.local.Prov.Models()...)
+}
+
+func (p *OfflineProvider) backends() []Provider { return []Provider{p.remote, p.local.Prov} }
+
+func (p *OfflineProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	var attempted []string
+	if !p.Offline() {
+		resp, err := p.remote.Complete(ctx, req)
+		p.remoteStats.record(ctx, err)
+		if err == nil || !p.goOffline(ctx, err) {
+			return resp, err
+		}
+		attempted = append(attempted, p.remote.ID())
+	}
+	cp := *req
+	cp.Model = p.local.Model
+	resp, err := p.local.Prov.Complete(ctx, &cp)
+	p.localStats.record(ctx, err)
+	if err != nil {
+		p.exhausted(ctx, attempted, err)
+	}
+	return resp, err
+}
+
+func (p *OfflineProvider) Stream(ctx context.Context, req *CompletionRequest) (ResponseStream, error) {
+	var attempted []string
+	if !p.Offline() {
+		stream, err := p.remote.Stream(ctx, req)
+		p.remoteStats.record(ctx, err)
+		if err == nil || !p.goOffline(ctx, err) {
+			return stream, err
+		}
+		attempted = append(attempted, p.remote.ID())
+	}
+	cp := *req
+	cp.Model = p.local.Model
+	cp.Stream = true
+	stream, err := p.local.Prov.Stream(ctx, &cp)
+	p.localStats.record(ctx, err)
+	if err != nil {
+		p.exhausted(ctx, attempted, err)
+	}
+	return stream, err
+}
+
+// exhausted reports a request the local model failed too, after the remote
+// side (attempted, empty when already offline) had failed.
+func (p *OfflineProvider) exhausted(ctx context.Context, attempted []string, err error) {
+	if p.onExhausted == nil || ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	p.onExhausted(ctx, append(attempted, p.local.name()), err)
+}
+
+// EndpointStatus implements StatusReporter: the remote endpoints (each of
+// them when the remote is itself a failover wrapper), then the local model.
+func (p *OfflineProvider) EndpointStatus() []EndpointStatus {
+	offline := p.Offline()
+	var out []EndpointStatus
+	if r, ok := p.remote.(StatusReporter); ok {
+		out = r.EndpointStatus()
+		for i := range out {
+			out[i].Active = out[i].Active && !offline
+		}
+	} else {
+		st := EndpointStatus{Provider: p.remote.ID(), Role: RolePrimary, Active: !offline}
+		st.Healthy, st.UnhealthySince = p.health.state()
+		p.remoteStats.fill(&st)
+		out = append(out, st)
+	}
+	local := EndpointStatus{Provider: p.local.Prov.ID(), Model: p.local.Model, Role: RoleOffline, Healthy: true, Active: offline}
+	p.localStats.fill(&local)
+	return append(out, local)
+}
+
+// goOffline switches to the local model after a remote failure and reports
+// whether the request should be retried there. A cancelled request is the
+// caller's doing, not an outage.
+func (p *OfflineProvider) goOffline(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if p.health.markDown(ctx, err) {
+		p.log.Warn("remote llm provider failed; switching to the offline model",
+			"provider", p.remote.ID(), "model", p.local.Model, "error", err)
+		if p.onFailover != nil {
+			p.onFailover(ctx, p.remote.ID(), p.local.Prov.ID()+"/"+p.local.Model, err)
+		}
+	}
+	return true
+}
+
+// AnyHealthy combines probes into one that succeeds when any of them does,
+// for checking that at least one remote endpoint is reachable.
+func AnyHealthy(probes ...HealthProbe) HealthProbe {
+	return func(ctx context.Context) error {
+		var errs []error
+		for _, probe := range probes {
+			err := probe(ctx)
+			if err == nil {
+				return nil
+			}
+			errs = append(errs, err)
+		}
+		return errors.Join(errs...)
+	}
+}
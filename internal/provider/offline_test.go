@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOfflineProvider_StartsOfflineWhenRemoteUnreachable(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := &fakeProvider{id: "openai", model: "gpt-4o"}
+	local := &fakeProvider{id: "ollama", model: "llama3.2"}
+	probeErr := errors.New("dial tcp: no route to host")
+	p := NewOfflineProvider(ctx, remote, ProviderEntry{Prov: local, Model: local.model},
+		func(context.Context) error { return probeErr }, HealthGateConfig{RecoverAfter: 2}, nil)
+
+	if !p.Offline() {
+		t.Fatal("an unreachable remote at startup should start offline")
+	}
+	resp, err := p.Complete(ctx, &CompletionRequest{Model: "gpt-4o"})
+	if err != nil || resp.Content != "ok-ollama" || local.lastModel != "llama3.2" || remote.calls != 0 {
+		t.Fatalf("offline completion = %+v, %v (local model %q, remote calls %d)", resp, err, local.lastModel, remote.calls)
+	}
+
+	// Recovery needs RecoverAfter healthy probes.
+	probeErr = nil
+	p.health.probeOnce(ctx)
+	if !p.Offline() {
+		t.Error("switched back after a single healthy probe")
+	}
+	p.health.probeOnce(ctx)
+	if p.Offline() {
+		t.Fatal("still offline after RecoverAfter healthy probes")
+	}
+	if resp, _ := p.Complete(ctx, &CompletionRequest{Model: "gpt-4o"}); resp.Content != "ok-openai" || remote.lastModel != "gpt-4o" {
+		t.Errorf("online completion = %+v; remote got model %q", resp, remote.lastModel)
+	}
+}
+
+func TestOfflineProvider_LiveFailureSwitchesToLocal(t *testing.T) {
+	ctx := context.Background()
+	remote := &fakeProvider{id: "openai", model: "gpt-4o", failNext: true}
+	local := &fakeProvider{id: "ollama", model: "llama3.2"}
+	p := NewOfflineProvider(ctx, remote, ProviderEntry{Prov: local, Model: local.model}, nil, HealthGateConfig{}, nil)
+
+	if p.Offline() {
+		t.Fatal("without a probe the provider starts online")
+	}
+	resp, err := p.Complete(ctx, &CompletionRequest{})
+	if err != nil || resp.Content != "ok-ollama" || !p.Offline() {
+		t.Fatalf("after a remote failure = %+v, %v, offline=%v", resp, err, p.Offline())
+	}
+	if len(p.Models()) != 2 {
+		t.Errorf("models = %+v; want remote and local", p.Models())
+	}
+}
+
+func TestOfflineProvider_CancelledRequestStaysOnline(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	remote := &fakeProvider{id: "openai", model: "gpt-4o", failNext: true}
+	local := &fakeProvider{id: "ollama", model: "llama3.2"}
+	p := NewOfflineProvider(context.Background(), remote, ProviderEntry{Prov: local, Model: local.model}, nil, HealthGateConfig{}, nil)
+	cancel()
+	if _, err := p.Complete(ctx, &CompletionRequest{}); err == nil {
+		t.Fatal("expected the remote error")
+	}
+	if p.Offline() || local.calls != 0 {
+		t.Errorf("a cancelled request must not switch to the offline model (offline=%v, local calls %d)", p.Offline(), local.calls)
+	}
+}
+
+func TestAnyHealthy(t *testing.T) {
+	down := func(context.Context) error { return errors.New("down") }
+	up := func(context.Context) error { return nil }
+	if err := AnyHealthy(down, up)(context.Background()); err != nil {
+		t.Errorf("one reachable endpoint should pass: %v", err)
+	}
+	if err := AnyHealthy(down, down)(context.Background()); err == nil {
+		t.Error("no reachable endpoint should fail")
+	}
+}
@@ -29,7 +29,7 @@ type OpenAIProvider struct {
 	id           string
 	baseURL      string
 	apiKey       string
-	models       []ModelInfo
+	models       modelSet
 	client       *http.Client
 	debugSink    DebugEventSink       // optional; nil disables persistent debug capture
 	debugResolve DebugContextResolver // optional; returns (sessionID, traceID, enabled?) for this ctx
@@ -106,7 +106,7 @@ func NewOpenAIProvider(id, baseURL, apiKey string, models []ModelInfo, opts ...O
 		id:        id,
 		baseURL:   baseURL,
 		apiKey:    apiKey,
-		models:    models,
+		models:    modelSet{models: models},
 		client:    &http.Client{Timeout: 120 * time.Second},
 		eventSink: emit.NoOpSink{},
 		retry:     DefaultRetryPolicy(),
@@ -122,7 +122,7 @@ func NewOpenAIProvider(id, baseURL, apiKey string, models []ModelInfo, opts ...O
 
 func (p *OpenAIProvider) ID() string { return p.id }
 
-func (p *OpenAIProvider) Models() []ModelInfo { return p.models }
+func (p *OpenAIProvider) Models() []ModelInfo { return p.models.list() }
 
 // costForTokens computes input/output cost for tokensIn/tokensOut against
 // this provider's configured per-million-token rate for modelID. Returns
@@ -136,7 +136,7 @@ func (p *OpenAIProvider) Models() []ModelInfo { return p.models }
 // operator's deployment convention sets the unit. Centralising the math
 // here keeps the streaming and non-streaming emit sites from drifting.
 func (p *OpenAIProvider) costForTokens(modelID string, tokensIn, tokensOut int) (float64, float64) {
-	for _, m := range p.models {
+	for _, m := range p.models.list() {
 		if m.ID != modelID {
 			continue
 		}
@@ -147,7 +147,7 @@ func (p *OpenAIProvider) costForTokens(modelID string, tokensIn, tokensOut int)
 }
 
 func (p *OpenAIProvider) SupportsFeature(f Feature) bool {
-	for _, m := range p.models {
+	for _, m := range p.models.list() {
 		if m.SupportsFeature(f) {
 			return true
 		}
@@ -167,6 +167,8 @@ type oaiRequest struct {
 	Tools           []oaiTool         `json:"tools,omitempty"`
 	MaxTokens       int               `json:"max_tokens,omitempty"`
 	Temperature     *float64          `json:"temperature,omitempty"`
+	TopP            *float64          `json:"top_p,omitempty"`
+	Stop            []string          `json:"stop,omitempty"`
 	Stream          bool              `json:"stream,omitempty"`
 	StreamOptions   *oaiStreamOptions `json:"stream_options,omitempty"`
 	ReasoningEffort string            `json:"reasoning_effort,omitempty"` // "low", "medium", "high" for reasoning models (gpt-oss-120b, o1, etc.)
@@ -189,6 +191,38 @@ type oaiMessage struct {
 	ReasoningContent oaiContent    `json:"reasoning_content,omitempty"` // reasoning models return thinking here (string or array-of-parts)
 	ToolCalls        []oaiToolCall `json:"tool_calls,omitempty"`        // native tool calls from LLM
 	ToolCallID       string        `json:"tool_call_id,omitempty"`      // for role=tool messages
+	// Parts replaces Content with an array of content parts on requests
+	// whose message carries files (image_url, file and text parts).
+	Parts []oaiPart `json:"-"`
+}
+
+// MarshalJSON sends Parts as the content array when set; otherwise the
+// message keeps the plain string content.
+func (m oaiMessage) MarshalJSON() ([]byte, error) {
+	type plain oaiMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []oaiPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+type oaiPart struct {
+	Type     string       `json:"type"` // "text", "image_url" or "file"
+	Text     string       `json:"text,omitempty"`
+	ImageURL *oaiImageURL `json:"image_url,omitempty"`
+	File     *oaiFilePart `json:"file,omitempty"`
+}
+
+type oaiImageURL struct {
+	URL string `json:"url"` // https URL or data: URI
+}
+
+type oaiFilePart struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"` // data: URI
 }
 
 // oaiContent is a message content field that tolerates both content shapes the
@@ -597,14 +631,17 @@ func (p *OpenAIProvider) Complete(ctx context.Context, req *CompletionRequest) (
 		Model:     oaiResp.Model,
 		Content:   content,
 		ToolCalls: toolCalls,
-		Usage: Usage{
-			InputTokens:  oaiResp.Usage.PromptTokens,
-			OutputTokens: oaiResp.Usage.CompletionTokens,
-		},
-		EventID: eventID,
+		Usage:     p.usage(oaiReq.Model, oaiResp.Usage),
+		EventID:   eventID,
 	}, nil
 }
 
+// usage converts the wire usage of a response to modelID, priced.
+func (p *OpenAIProvider) usage(modelID string, u oaiUsage) Usage {
+	costIn, costOut := p.costForTokens(modelID, u.PromptTokens, u.CompletionTokens)
+	return Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens, Cost: costIn + costOut}
+}
+
 // openAIFinishReasonContentFilter is the wire-level finish_reason that
 // OpenAI returns when the response was suppressed by content moderation.
 // Centralised here so the Complete and Stream paths agree on the spelling.
@@ -721,6 +758,16 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req *CompletionRequest) (Re
 	return stream, nil
 }
 
+// usage converts a chunk's wire usage, priced like Complete's.
+func (s *oaiResponseStream) usage(u oaiUsage) Usage {
+	out := Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+	if s.costFn != nil {
+		costIn, costOut := s.costFn(u.PromptTokens, u.CompletionTokens)
+		out.Cost = costIn + costOut
+	}
+	return out
+}
+
 func (s *oaiResponseStream) Recv() (StreamChunk, error) {
 	for s.scanner.Scan() {
 		line := s.scanner.Text()
@@ -776,22 +823,13 @@ func (s *oaiResponseStream) Recv() (StreamChunk, error) {
 		// Usage-only chunk (sent as the final chunk when stream_options.include_usage is true).
 		// It has no choices but carries the complete usage for the request.
 		if chunk.Usage != nil && len(chunk.Choices) == 0 {
-			return StreamChunk{
-				Model: chunk.Model,
-				Usage: Usage{
-					InputTokens:  chunk.Usage.PromptTokens,
-					OutputTokens: chunk.Usage.CompletionTokens,
-				},
-			}, nil
+			return StreamChunk{Model: chunk.Model, Usage: s.usage(*chunk.Usage)}, nil
 		}
 
 		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
 			sc := StreamChunk{Content: string(chunk.Choices[0].Delta.Content), Model: chunk.Model}
 			if chunk.Usage != nil {
-				sc.Usage = Usage{
-					InputTokens:  chunk.Usage.PromptTokens,
-					OutputTokens: chunk.Usage.CompletionTokens,
-				}
+				sc.Usage = s.usage(*chunk.Usage)
 			}
 			return sc, nil
 		}
@@ -886,11 +924,9 @@ func (s *oaiResponseStream) emitStreamEnd() {
 }
 
 func (p *OpenAIProvider) toOAIRequest(req *CompletionRequest) (oaiRequest, error) {
+	req = acceptedFiles(p.models.list(), req)
 	msgs := make([]oaiMessage, 0, len(req.Messages))
 	for _, m := range req.Messages {
-		if len(m.Files) > 0 {
-			return oaiRequest{}, fmt.Errorf("provider %s does not support file attachments", p.id)
-		}
 		// Skip malformed messages from old sessions:
 		// - role=tool without tool_call_id (OpenAI API rejects these)
 		// - role=assistant with empty content and no tool calls (orphans)
@@ -901,6 +937,13 @@ func (p *OpenAIProvider) toOAIRequest(req *CompletionRequest) (oaiRequest, error
 			continue
 		}
 		oMsg := oaiMessage{Role: string(m.Role), Content: oaiContent(m.Content)}
+		if len(m.Files) > 0 {
+			parts, err := oaiFileParts(m)
+			if err != nil {
+				return oaiRequest{}, fmt.Errorf("provider %s: %w", p.id, err)
+			}
+			oMsg.Parts = parts
+		}
 		// Native tool calling: pass tool_call_id for tool result messages.
 		if m.Role == RoleTool && m.ToolCallID != "" {
 			oMsg.ToolCallID = m.ToolCallID
@@ -924,6 +967,8 @@ func (p *OpenAIProvider) toOAIRequest(req *CompletionRequest) (oaiRequest, error
 		Messages:    msgs,
 		MaxTokens:   req.MaxTokens,
 		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
 	}
 	if req.Reasoning {
 		oai.ReasoningEffort = req.ReasoningEffort
@@ -942,6 +987,32 @@ func (p *OpenAIProvider) toOAIRequest(req *CompletionRequest) (oaiRequest, error
 	return oai, nil
 }
 
+// oaiFileParts maps a message's files to content parts, followed by its
+// text: images become image_url parts, PDFs file parts, and text formats
+// text parts. Other binary types are rejected, as for Anthropic.
+func oaiFileParts(m Message) ([]oaiPart, error) {
+	var parts []oaiPart
+	for _, f := range m.Files {
+		switch ClassifyFile(f.MimeType, f.Data) {
+		case FileClassImage:
+			parts = append(parts, oaiPart{Type: "image_url", ImageURL: &oaiImageURL{URL: dataURI(f)}})
+		case FileClassPDF:
+			if len(f.Data) == 0 {
+				return nil, fmt.Errorf("PDF %s has no data; file parts cannot be fetched by URL", fileLabel(f))
+			}
+			parts = append(parts, oaiPart{Type: "file", File: &oaiFilePart{Filename: f.Name, FileData: dataURI(f)}})
+		case FileClassText:
+			parts = append(parts, oaiPart{Type: "text", Text: string(f.Data)})
+		default:
+			return nil, fmt.Errorf("unsupported file mime type %q", f.MimeType)
+		}
+	}
+	if m.Content != "" {
+		parts = append(parts, oaiPart{Type: "text", Text: m.Content})
+	}
+	return parts, nil
+}
+
 func (p *OpenAIProvider) setHeaders(req *http.Request) {
 	req.Header.Set("Content-Type", "application/json")
 	if p.apiKey != "" {
@@ -286,19 +286,93 @@ func TestOpenAIStreamError(t *testing.T) {
 	}
 }
 
-func TestOpenAIRejectsFileAttachments(t *testing.T) {
+func TestOpenAIRejectsUnsupportedFiles(t *testing.T) {
 	p := NewOpenAIProvider("openai", "", "key", nil)
 
 	_, err := p.Complete(context.Background(), &CompletionRequest{
 		Model: "gpt-4o",
 		Messages: []Message{
 			{Role: RoleUser, Content: "see attached", Files: []MessageFile{
-				{MimeType: "text/csv", Data: []byte("a,b\n1,2")},
+				{MimeType: "application/zip", Data: []byte("PK")},
 			}},
 		},
 	})
-	if err == nil {
-		t.Fatal("expected error for file attachment, got nil")
+	if err == nil || !strings.Contains(err.Error(), "unsupported file mime type") {
+		t.Fatalf("err = %v; want unsupported file mime type", err)
+	}
+}
+
+func TestOpenAISamplingParams(t *testing.T) {
+	p := NewOpenAIProvider("openai", "", "key", nil)
+	topP := 0.5
+	oai, err := p.toOAIRequest(&CompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []Message{{Role: RoleUser, Content: "hi"}},
+		TopP:     &topP,
+		Stop:     []string{"\nUser:"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := json.Marshal(oai)
+	if !strings.Contains(string(body), `"top_p":0.5`) || !strings.Contains(string(body), `"stop":["\nUser:"]`) {
+		t.Errorf("request = %s", body)
+	}
+	if strings.Contains(string(body), "temperature") {
+		t.Errorf("unset temperature must be omitted: %s", body)
+	}
+}
+
+func TestOpenAIFilesBecomeContentParts(t *testing.T) {
+	p := NewOpenAIProvider("openai", "", "key", nil)
+	oai, err := p.toOAIRequest(&CompletionRequest{
+		Model: "gpt-4o",
+		Messages: []Message{
+			{Role: RoleSystem, Content: "be brief"},
+			{Role: RoleUser, Content: "what is this?", Files: []MessageFile{
+				{MimeType: "image/png", Data: []byte{0x89, 'P'}},
+				{MimeType: "image/jpeg", URL: "https://example.com/a.jpg"},
+				{Name: "r.pdf", MimeType: "application/pdf", Data: []byte("%PDF")},
+				{MimeType: "text/csv", Data: []byte("a,b")},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := json.Marshal(oai)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(decoded.Messages[0].Content); got != `"be brief"` {
+		t.Errorf("text-only message content = %s; want a plain string", got)
+	}
+	var parts []oaiPart
+	if err := json.Unmarshal(decoded.Messages[1].Content, &parts); err != nil {
+		t.Fatalf("content with files should be an array: %v", err)
+	}
+	if len(parts) != 5 {
+		t.Fatalf("parts = %d, want 5", len(parts))
+	}
+	if parts[0].Type != "image_url" || parts[0].ImageURL.URL != "data:image/png;base64,iVA=" {
+		t.Errorf("parts[0] = %+v", parts[0])
+	}
+	if parts[1].ImageURL == nil || parts[1].ImageURL.URL != "https://example.com/a.jpg" {
+		t.Errorf("parts[1] = %+v; want the image link passed through", parts[1])
+	}
+	if parts[2].Type != "file" || parts[2].File.Filename != "r.pdf" || !strings.HasPrefix(parts[2].File.FileData, "data:application/pdf;base64,") {
+		t.Errorf("parts[2] = %+v", parts[2])
+	}
+	if parts[3].Text != "a,b" || parts[4].Text != "what is this?" {
+		t.Errorf("text parts = %+v, %+v", parts[3], parts[4])
 	}
 }
 
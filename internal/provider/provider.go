@@ -16,6 +16,8 @@ const (
 type MessageFile struct {
 	MimeType string `json:"mime_type"`
 	Data     []byte `json:"data"`
+	Name     string `json:"name,omitempty"`
+	URL      string `json:"url,omitempty"` // where to fetch Data when the channel sent a link instead of bytes
 }
 
 type Message struct {
@@ -56,15 +58,28 @@ type CompletionRequest struct {
 	Tools           []ToolDefinition `json:"tools,omitempty"` // native tool definitions; nil = text-based tool calling
 	MaxTokens       int              `json:"max_tokens,omitempty"`
 	Temperature     *float64         `json:"temperature,omitempty"`
+	TopP            *float64         `json:"top_p,omitempty"`
+	Stop            []string         `json:"stop,omitempty"` // stop sequences; generation ends before any of them
 	Stream          bool             `json:"stream,omitempty"`
 	Reasoning       bool             `json:"reasoning,omitempty"`        // enable extended thinking / reasoning
 	BudgetTokens    int              `json:"budget_tokens,omitempty"`    // Anthropic: max tokens for thinking (0 = provider default)
 	ReasoningEffort string           `json:"reasoning_effort,omitempty"` // OpenAI: "low", "medium", "high" (0 = "medium")
+	NoCache         bool             `json:"-"`                          // always ask the model, even with a response cache configured
 }
 
 type Usage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
+
+	// Prompt caching (Anthropic): tokens read from and written to the
+	// provider's prompt cache. They are not part of InputTokens and are
+	// billed at their own rates (see ModelCost).
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+
+	// Cost is what the call cost at the model's configured prices
+	// (models.providers.*.models[].cost); 0 when the model has none.
+	Cost float64 `json:"cost,omitempty"`
 }
 
 type CompletionResponse struct {
@@ -81,6 +96,10 @@ type CompletionResponse struct {
 	// tool_call_extracted events so the analytics graph links each tool
 	// dispatch back to the LLM round that produced it.
 	EventID string `json:"-"`
+
+	// Cached is set when the response was served by a ResponseCache
+	// instead of the model. Usage is zero then: nothing was spent.
+	Cached bool `json:"-"`
 }
 
 type StreamChunk struct {
@@ -0,0 +1,127 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultResponseCacheEntries bounds a ResponseCache created without a size.
+const defaultResponseCacheEntries = 500
+
+// ResponseCache answers a completion request that exactly matches an
+// earlier one (same model, messages, tools and generation settings) with
+// the earlier response, until its TTL runs out. It pays off where prompts
+// repeat verbatim: scheduler prompt jobs, a summarization re-run over the
+// same history, test suites. Errors and empty responses are never cached,
+// and a request with NoCache always reaches the model.
+type ResponseCache struct {
+	ttl time.Duration
+	max int
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]cachedResponse
+
+	hits, misses atomic.Int64
+}
+
+type cachedResponse struct {
+	resp    CompletionResponse
+	expires time.Time
+}
+
+// NewResponseCache returns a cache keeping responses for ttl, at most
+// maxEntries of them (0 = 500).
+func NewResponseCache(ttl time.Duration, maxEntries int) *ResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = defaultResponseCacheEntries
+	}
+	return &ResponseCache{ttl: ttl, max: maxEntries, now: time.Now, entries: make(map[string]cachedResponse)}
+}
+
+// Hits is the number of requests answered from the cache.
+func (c *ResponseCache) Hits() int64 { return c.hits.Load() }
+
+// Misses is the number of cacheable requests that went to the model.
+func (c *ResponseCache) Misses() int64 { return c.misses.Load() }
+
+// Complete serves req from the cache or calls complete and remembers its
+// response. req.Model must already be resolved: it is part of the key.
+func (c *ResponseCache) Complete(ctx context.Context, req *CompletionRequest, complete func(context.Context, *CompletionRequest) (*CompletionResponse, error)) (*CompletionResponse, error) {
+	if c == nil || req.NoCache {
+		return complete(ctx, req)
+	}
+	key, ok := responseCacheKey(req)
+	if !ok {
+		return complete(ctx, req)
+	}
+	now := c.now()
+	c.mu.Lock()
+	e, found := c.entries[key]
+	if found && !now.Before(e.expires) {
+		delete(c.entries, key)
+		found = false
+	}
+	c.mu.Unlock()
+	if found {
+		c.hits.Add(1)
+		resp := e.resp
+		resp.ToolCalls = slices.Clone(e.resp.ToolCalls)
+		resp.Usage = Usage{}
+		resp.Cached = true
+		return &resp, nil
+	}
+	c.misses.Add(1)
+	resp, err := complete(ctx, req)
+	if err != nil || resp == nil || (resp.Content == "" && len(resp.ToolCalls) == 0) {
+		return resp, err
+	}
+	stored := *resp
+	stored.EventID = "" // belongs to the call that produced it
+	c.mu.Lock()
+	if len(c.entries) >= c.max {
+		c.evictLocked(now)
+	}
+	c.entries[key] = cachedResponse{resp: stored, expires: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return resp, nil
+}
+
+// evictLocked drops expired entries, or if none had expired, the one
+// closest to expiry.
+func (c *ResponseCache) evictLocked(now time.Time) {
+	var victim string
+	var soonest time.Time
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+			continue
+		}
+		if victim == "" || e.expires.Before(soonest) {
+			victim, soonest = k, e.expires
+		}
+	}
+	if len(c.entries) >= c.max && victim != "" {
+		delete(c.entries, victim)
+	}
+}
+
+// responseCacheKey hashes everything that shapes the model's answer. The
+// request's JSON form covers model, messages (file bytes included), tools
+// and generation settings; Stream only changes delivery and is left out.
+func responseCacheKey(req *CompletionRequest) (string, bool) {
+	cp := *req
+	cp.Stream = false
+	b, err := json.Marshal(&cp)
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), true
+}
@@ -0,0 +1,140 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type countingCompleter struct {
+	calls int
+	err   error
+	empty bool
+}
+
+func (c *countingCompleter) complete(_ context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	c.calls++
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.empty {
+		return &CompletionResponse{}, nil
+	}
+	return &CompletionResponse{
+		Content:   fmt.Sprintf("answer %d", c.calls),
+		Model:     req.Model,
+		ToolCalls: []ToolCall{{ID: "t1", Name: "echo"}},
+		Usage:     Usage{InputTokens: 10, OutputTokens: 5},
+		EventID:   fmt.Sprintf("ev-%d", c.calls),
+	}, nil
+}
+
+func cacheReq(content string) *CompletionRequest {
+	return &CompletionRequest{Model: "gpt-4o", Messages: []Message{{Role: RoleUser, Content: content}}}
+}
+
+func TestResponseCache_HitsAndMisses(t *testing.T) {
+	c := NewResponseCache(time.Minute, 0)
+	llm := &countingCompleter{}
+	ctx := context.Background()
+
+	first, err := c.Complete(ctx, cacheReq("hi"), llm.complete)
+	if err != nil || first.Cached {
+		t.Fatalf("first call: %+v %v", first, err)
+	}
+	second, _ := c.Complete(ctx, cacheReq("hi"), llm.complete)
+	if llm.calls != 1 || !second.Cached || second.Content != first.Content {
+		t.Fatalf("identical request reached the model: calls=%d resp=%+v", llm.calls, second)
+	}
+	if second.Usage != (Usage{}) || second.EventID != "" {
+		t.Errorf("a cached response must not report usage or the original event: %+v", second)
+	}
+	second.ToolCalls[0].Name = "changed"
+	if third, _ := c.Complete(ctx, cacheReq("hi"), llm.complete); third.ToolCalls[0].Name != "echo" {
+		t.Error("a caller's edit leaked into the cache")
+	}
+
+	// Streaming is a delivery detail; anything else in the request is part of the key.
+	streamed := cacheReq("hi")
+	streamed.Stream = true
+	c.Complete(ctx, streamed, llm.complete)
+	other := cacheReq("hi")
+	other.Model = "gpt-4o-mini"
+	c.Complete(ctx, other, llm.complete)
+	temp := cacheReq("hi")
+	temp.Temperature = new(float64)
+	c.Complete(ctx, temp, llm.complete)
+	if llm.calls != 3 {
+		t.Errorf("calls = %d; want 3", llm.calls)
+	}
+	if c.Hits() != 3 || c.Misses() != 3 {
+		t.Errorf("hits=%d misses=%d; want 3/3", c.Hits(), c.Misses())
+	}
+}
+
+func TestResponseCache_BypassAndExpiry(t *testing.T) {
+	c := NewResponseCache(time.Minute, 0)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	llm := &countingCompleter{}
+	ctx := context.Background()
+
+	c.Complete(ctx, cacheReq("hi"), llm.complete)
+	bypass := cacheReq("hi")
+	bypass.NoCache = true
+	if resp, _ := c.Complete(ctx, bypass, llm.complete); llm.calls != 2 || resp.Cached {
+		t.Errorf("NoCache was served from the cache: calls=%d", llm.calls)
+	}
+	if c.Hits()+c.Misses() != 1 {
+		t.Errorf("a bypassed request must not count: hits=%d misses=%d", c.Hits(), c.Misses())
+	}
+
+	now = now.Add(2 * time.Minute)
+	if c.Complete(ctx, cacheReq("hi"), llm.complete); llm.calls != 3 {
+		t.Errorf("expired entry was reused, calls=%d", llm.calls)
+	}
+
+	var nilCache *ResponseCache
+	if nilCache.Complete(ctx, cacheReq("hi"), llm.complete); llm.calls != 4 {
+		t.Errorf("a nil cache must pass through, calls=%d", llm.calls)
+	}
+}
+
+func TestResponseCache_SkipsFailures(t *testing.T) {
+	c := NewResponseCache(time.Minute, 0)
+	ctx := context.Background()
+
+	failing := &countingCompleter{err: errors.New("rate limited")}
+	c.Complete(ctx, cacheReq("hi"), failing.complete)
+	c.Complete(ctx, cacheReq("hi"), failing.complete)
+	empty := &countingCompleter{empty: true}
+	c.Complete(ctx, cacheReq("hi"), empty.complete)
+	c.Complete(ctx, cacheReq("hi"), empty.complete)
+	if failing.calls != 2 || empty.calls != 2 {
+		t.Errorf("errors or empty responses were cached: failing=%d empty=%d", failing.calls, empty.calls)
+	}
+}
+
+func TestResponseCache_Eviction(t *testing.T) {
+	c := NewResponseCache(time.Minute, 2)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	llm := &countingCompleter{}
+	ctx := context.Background()
+
+	for _, p := range []string{"a", "b", "c"} {
+		c.Complete(ctx, cacheReq(p), llm.complete)
+		now = now.Add(time.Second)
+	}
+	if len(c.entries) != 2 {
+		t.Fatalf("entries = %d; want 2", len(c.entries))
+	}
+	if c.Complete(ctx, cacheReq("a"), llm.complete); llm.calls != 4 {
+		t.Errorf("the oldest entry should have been evicted, calls=%d", llm.calls)
+	}
+	if c.Complete(ctx, cacheReq("c"), llm.complete); llm.calls != 4 {
+		t.Errorf("a newer entry was evicted, calls=%d", llm.calls)
+	}
+}
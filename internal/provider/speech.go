@@ -0,0 +1,187 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// MaxSpeechInput is the longest text OpenAI's /audio/speech accepts.
+const MaxSpeechInput = 4096
+
+// ErrSpeechTooLong is returned by Synthesize for text above MaxSpeechInput.
+var ErrSpeechTooLong = errors.New("speech: text too long to synthesize")
+
+// OpenAITranscriber calls an OpenAI-compatible POST /audio/transcriptions
+// endpoint (OpenAI Whisper, faster-whisper-server, LocalAI, Groq).
+type OpenAITranscriber struct {
+	baseURL  string
+	apiKey   string
+	model    string
+	language string
+	client   *http.Client
+}
+
+// NewOpenAITranscriber returns a transcriber for model at baseURL ("" =
+// OpenAI). language is an optional ISO 639-1 hint; empty lets the model
+// detect it.
+func NewOpenAITranscriber(baseURL, apiKey, model, language string) *OpenAITranscriber {
+	if baseURL == "" {
+		baseURL = openAIDefaultBaseURL
+	}
+	return &OpenAITranscriber{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		language: language,
+		client:   withRetry(&http.Client{Timeout: 2 * time.Minute}, DefaultRetryPolicy(), nil),
+	}
+}
+
+// Transcribe returns the text spoken in an audio file. name gives the
+// endpoint the file extension it uses to pick a decoder.
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, data []byte, mimeType, name string) (string, error) {
+	if name == "" {
+		name = "audio" + AudioExtension(mimeType)
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", name)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	_ = w.WriteField("model", t.model)
+	_ = w.WriteField("response_format", "json")
+	if t.language != "" {
+		_ = w.WriteField("language", t.language)
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/audio/transcriptions", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("transcription: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var parsed struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("transcription: decoding response: %w", err)
+	}
+	return strings.TrimSpace(parsed.Text), nil
+}
+
+// OpenAISpeaker calls an OpenAI-compatible POST /audio/speech endpoint
+// (OpenAI TTS, openedai-speech, LocalAI, Kokoro-FastAPI).
+type OpenAISpeaker struct {
+	baseURL string
+	apiKey  string
+	model   string
+	voice   string
+	format  string
+	client  *http.Client
+}
+
+// NewOpenAISpeaker returns a speaker for model at baseURL ("" = OpenAI).
+// format is the audio encoding ("" = mp3).
+func NewOpenAISpeaker(baseURL, apiKey, model, voice, format string) *OpenAISpeaker {
+	if baseURL == "" {
+		baseURL = openAIDefaultBaseURL
+	}
+	if format == "" {
+		format = "mp3"
+	}
+	return &OpenAISpeaker{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		model:   model,
+		voice:   voice,
+		format:  format,
+		client:  withRetry(&http.Client{Timeout: 2 * time.Minute}, DefaultRetryPolicy(), nil),
+	}
+}
+
+// Synthesize renders text to audio and returns the bytes and their MIME type.
+func (s *OpenAISpeaker) Synthesize(ctx context.Context, text string) ([]byte, string, error) {
+	if len([]rune(text)) > MaxSpeechInput {
+		return nil, "", ErrSpeechTooLong
+	}
+	body, err := json.Marshal(map[string]string{"model": s.model, "input": text, "voice": s.voice, "response_format": s.format})
+	if err != nil {
+		return nil, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/audio/speech", bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("speech request: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", fmt.Errorf("speech: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, 50<<20))
+	if err != nil {
+		return nil, "", fmt.Errorf("speech: reading audio: %w", err)
+	}
+	return audio, speechMimeTypes[s.format], nil
+}
+
+var speechMimeTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"opus": "audio/ogg",
+	"aac":  "audio/aac",
+	"flac": "audio/flac",
+	"wav":  "audio/wav",
+}
+
+// AudioExtension returns the usual file extension for an audio MIME type.
+func AudioExtension(mimeType string) string {
+	switch strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0])) {
+	case "audio/mpeg", "audio/mp3":
+		return ".mp3"
+	case "audio/ogg", "audio/opus":
+		return ".ogg"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return ".wav"
+	case "audio/webm":
+		return ".webm"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		return ".m4a"
+	case "audio/aac":
+		return ".aac"
+	case "audio/flac":
+		return ".flac"
+	}
+	return ".bin"
+}
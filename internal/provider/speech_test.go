@@ -0,0 +1,88 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOpenAITranscriber(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Fatal(err)
+		}
+		if r.FormValue("model") != "whisper-1" || r.FormValue("language") != "de" {
+			t.Errorf("model = %q, language = %q", r.FormValue("model"), r.FormValue("language"))
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(f)
+		if hdr.Filename != "audio.ogg" || string(data) != "ogg bytes" {
+			t.Errorf("file %q = %q", hdr.Filename, data)
+		}
+		_, _ = w.Write([]byte(`{"text":" Guten Tag. "}`))
+	}))
+	defer srv.Close()
+
+	text, err := NewOpenAITranscriber(srv.URL+"/v1/", "key", "whisper-1", "de").Transcribe(context.Background(), []byte("ogg bytes"), "audio/ogg; codecs=opus", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Guten Tag." {
+		t.Errorf("text = %q", text)
+	}
+}
+
+func TestOpenAITranscriber_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "unsupported file format", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	_, err := NewOpenAITranscriber(srv.URL, "", "whisper-1", "").Transcribe(context.Background(), []byte("x"), "audio/amr", "note.amr")
+	if err == nil || !strings.Contains(err.Error(), "unsupported file format") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestOpenAISpeaker(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/speech" || r.Header.Get("Authorization") != "Bearer key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["model"] != "tts-1" || req["voice"] != "alloy" || req["input"] != "hello" || req["response_format"] != "opus" {
+			t.Errorf("request = %v", req)
+		}
+		_, _ = w.Write([]byte("opus bytes"))
+	}))
+	defer srv.Close()
+
+	audio, mime, err := NewOpenAISpeaker(srv.URL, "key", "tts-1", "alloy", "opus").Synthesize(context.Background(), "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(audio) != "opus bytes" || mime != "audio/ogg" {
+		t.Errorf("audio %q (%s)", audio, mime)
+	}
+}
+
+func TestOpenAISpeaker_TooLong(t *testing.T) {
+	_, _, err := NewOpenAISpeaker("http://unused", "", "tts-1", "alloy", "").Synthesize(context.Background(), strings.Repeat("a", MaxSpeechInput+1))
+	if !errors.Is(err, ErrSpeechTooLong) {
+		t.Errorf("err = %v; want ErrSpeechTooLong", err)
+	}
+}
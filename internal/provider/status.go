@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Endpoint roles in EndpointStatus.
+const (
+	RolePrimary  = "primary"
+	RoleFallback = "fallback"
+	RoleOffline  = "offline"
+)
+
+// EndpointStatus is the state of one LLM endpoint behind a failover
+// wrapper: whether it is healthy, whether it takes traffic now, and how
+// its requests went since the process started.
+type EndpointStatus struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model,omitempty"`
+	Role     string `json:"role"`    // RolePrimary, RoleFallback or RoleOffline
+	Healthy  bool   `json:"healthy"` // false while the endpoint is cooling down after a failure
+	Active   bool   `json:"active"`  // new requests go here first
+
+	// UnhealthySince is when the endpoint last tripped; zero while healthy.
+	// Only the primary trips: fallbacks are tried whenever they are next in
+	// line.
+	UnhealthySince time.Time `json:"unhealthy_since,omitzero"`
+
+	Requests      int64     `json:"requests"`
+	Failures      int64     `json:"failures"`
+	SuccessRate   float64   `json:"success_rate"` // 0-1; 1 before the first request
+	LastError     string    `json:"last_error,omitempty"`
+	LastErrorAt   time.Time `json:"last_error_at,omitzero"`
+	LastSuccessAt time.Time `json:"last_success_at,omitzero"`
+
+	// From the periodic probes of a HealthMonitor; unset without one or
+	// before the endpoint's first probe.
+	HealthScore    *float64 `json:"health_score,omitempty"` // 0-1, see EndpointHealth.Score
+	Degraded       bool     `json:"degraded,omitempty"`     // tried after the other endpoints
+	ProbeLatencyMS int64    `json:"probe_latency_ms,omitempty"`
+}
+
+// StatusReporter is implemented by the failover wrappers (the health-gated
+// and the offline provider) to report their endpoints, primary first.
+type StatusReporter interface {
+	EndpointStatus() []EndpointStatus
+}
+
+func (st *EndpointStatus) fillProbes(m *HealthMonitor, name string) {
+	h := m.Health(name)
+	if h.Probes == 0 {
+		return
+	}
+	st.HealthScore = &h.Score
+	st.Degraded = m.Degraded(name)
+	st.ProbeLatencyMS = h.Latency.Milliseconds()
+}
+
+// endpointStats counts the requests of one endpoint.
+type endpointStats struct {
+	mu        sync.Mutex
+	requests  int64
+	failures  int64
+	lastErr   string
+	lastErrAt time.Time
+	lastOK    time.Time
+}
+
+// record counts one request. A request the caller cancelled says nothing
+// about the endpoint and is not counted.
+func (s *endpointStats) record(ctx context.Context, err error) {
+	if err != nil && (ctx.Err() != nil || errors.Is(err, context.Canceled)) {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if err != nil {
+		s.failures++
+		s.lastErr, s.lastErrAt = err.Error(), now
+		return
+	}
+	s.lastOK = now
+}
+
+func (s *endpointStats) fill(st *EndpointStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st.Requests, st.Failures = s.requests, s.failures
+	st.SuccessRate = 1
+	if s.requests > 0 {
+		st.SuccessRate = float64(s.requests-s.failures) / float64(s.requests)
+	}
+	st.LastError, st.LastErrorAt, st.LastSuccessAt = s.lastErr, s.lastErrAt, s.lastOK
+}
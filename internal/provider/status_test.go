@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestHealthGatedEndpointStatus(t *testing.T) {
+	ctx := context.Background()
+	preferred := &fakeProvider{id: "dedicated", model: "gpt-oss-120b"}
+	fallback := &fakeProvider{id: "shared", model: "gpt-oss-120b-ovh"}
+	hg := newTestHG(preferred, fallback, 1, func(context.Context) error { return nil })
+	var changes []bool
+	hg.health.onChange = func(_ context.Context, healthy bool, _ error) { changes = append(changes, healthy) }
+	var exhausted []string
+	hg.onExhausted = func(_ context.Context, attempted []string, _ error) { exhausted = attempted }
+
+	_, _ = hg.Complete(ctx, &CompletionRequest{})
+	preferred.failNext = true
+	_, _ = hg.Complete(ctx, &CompletionRequest{})
+
+	st := hg.EndpointStatus()
+	if len(st) != 2 {
+		t.Fatalf("status = %+v", st)
+	}
+	p, f := st[0], st[1]
+	if p.Role != RolePrimary || p.Healthy || p.Active || p.UnhealthySince.IsZero() {
+		t.Errorf("tripped primary = %+v", p)
+	}
+	if p.Requests != 2 || p.Failures != 1 || p.SuccessRate != 0.5 || p.LastError != "boom" || p.LastSuccessAt.IsZero() {
+		t.Errorf("primary counters = %+v", p)
+	}
+	if f.Role != RoleFallback || !f.Healthy || !f.Active || f.Requests != 1 || f.SuccessRate != 1 {
+		t.Errorf("fallback = %+v", f)
+	}
+	if exhausted != nil {
+		t.Errorf("exhausted reported although the fallback answered: %v", exhausted)
+	}
+
+	fallback.failNext = true
+	if _, err := hg.Complete(ctx, &CompletionRequest{}); err == nil {
+		t.Fatal("expected an error with every endpoint down")
+	}
+	if want := []string{"shared/gpt-oss-120b-ovh", "dedicated/gpt-oss-120b"}; !reflect.DeepEqual(exhausted, want) {
+		t.Errorf("exhausted attempted = %v, want %v", exhausted, want)
+	}
+
+	hg.health.probeOnce(ctx)
+	if p := hg.EndpointStatus()[0]; !p.Healthy || !p.Active || !p.UnhealthySince.IsZero() {
+		t.Errorf("recovered primary = %+v", p)
+	}
+	if !reflect.DeepEqual(changes, []bool{false, true}) {
+		t.Errorf("health changes = %v, want trip then recovery", changes)
+	}
+}
+
+func TestHealthGatedExhaustedIgnoresCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	preferred := &fakeProvider{id: "a", model: "m", failNext: true}
+	fallback := &fakeProvider{id: "b", model: "m", failNext: true}
+	hg := newTestHG(preferred, fallback, 1, nil)
+	hg.onExhausted = func(context.Context, []string, error) { t.Error("a cancelled request must not count as exhaustion") }
+	_, _ = hg.Complete(ctx, &CompletionRequest{})
+	if st := hg.EndpointStatus(); st[0].Requests != 0 || st[1].Requests != 0 {
+		t.Errorf("cancelled requests were counted: %+v", st)
+	}
+}
+
+func TestOfflineEndpointStatus(t *testing.T) {
+	ctx := context.Background()
+	remote := &fakeProvider{id: "openai", model: "gpt-4o", failNext: true}
+	local := &fakeProvider{id: "ollama", model: "llama3.2", failNext: true}
+	var exhausted []string
+	p := NewOfflineProvider(ctx, remote, ProviderEntry{Prov: local, Model: local.model}, nil, HealthGateConfig{
+		OnExhausted: func(_ context.Context, attempted []string, err error) { exhausted = attempted },
+	}, nil)
+
+	if _, err := p.Complete(ctx, &CompletionRequest{}); err == nil {
+		t.Fatal("expected an error with remote and local down")
+	}
+	if want := []string{"openai", "ollama/llama3.2"}; !reflect.DeepEqual(exhausted, want) {
+		t.Errorf("exhausted attempted = %v, want %v", exhausted, want)
+	}
+
+	st := p.EndpointStatus()
+	if len(st) != 2 {
+		t.Fatalf("status = %+v", st)
+	}
+	if r := st[0]; r.Role != RolePrimary || r.Healthy || r.Active || r.Failures != 1 {
+		t.Errorf("remote = %+v", r)
+	}
+	if l := st[1]; l.Role != RoleOffline || !l.Active || l.Model != "llama3.2" || l.Failures != 1 || l.SuccessRate != 0 {
+		t.Errorf("local = %+v", l)
+	}
+}
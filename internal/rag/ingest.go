@@ -0,0 +1,367 @@
+package rag
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// chunkRunes is the target chunk size: a few paragraphs, small enough
+	// that top-k passages fit a prompt, large enough to keep context.
+	chunkRunes = 1500
+	// maxDocumentBytes skips files and pages too large to be documentation.
+	maxDocumentBytes = 5 << 20
+)
+
+// indexedExtensions are the file types Sync reads from directories.
+var indexedExtensions = map[string]bool{".md": true, ".markdown": true, ".txt": true, ".rst": true, ".html": true, ".htm": true}
+
+// SyncStats reports what one Sync changed.
+type SyncStats struct {
+	Indexed   int // documents (re-)embedded
+	Unchanged int
+	Removed   int
+	Failed    int
+}
+
+type document struct {
+	source string
+	text   string
+}
+
+// Sync brings the index in line with sources: each entry is a directory
+// (walked for text, Markdown and HTML files), a single file, or an http(s)
+// URL. Documents whose content is unchanged are not re-embedded; documents
+// no longer present are removed. A source that cannot be read is logged
+// and skipped, keeping its previously indexed chunks.
+func (idx *Index) Sync(ctx context.Context, sources []string) (SyncStats, error) {
+	var stats SyncStats
+	seen := map[string]bool{}
+	for _, src := range sources {
+		docs, err := loadSource(ctx, src)
+		if err != nil {
+			slog.Warn("rag: reading source failed", "component", "rag", "source", src, "error", err)
+			stats.Failed++
+			// Keep whatever this source had indexed before.
+			if err := idx.markSourcePrefix(src, seen); err != nil {
+				return stats, err
+			}
+			continue
+		}
+		for _, d := range docs {
+			seen[d.source] = true
+			changed, err := idx.upsert(ctx, d)
+			switch {
+			case err != nil:
+				slog.Warn("rag: indexing document failed", "component", "rag", "source", d.source, "error", err)
+				stats.Failed++
+			case changed:
+				stats.Indexed++
+			default:
+				stats.Unchanged++
+			}
+		}
+	}
+	removed, err := idx.removeUnseen(seen)
+	stats.Removed = removed
+	if err != nil {
+		return stats, err
+	}
+	return stats, idx.load()
+}
+
+// RunSync syncs now and then every interval until ctx is cancelled;
+// interval <= 0 syncs once.
+func RunSync(ctx context.Context, idx *Index, sources []string, interval time.Duration) {
+	run := func() {
+		stats, err := idx.Sync(ctx, sources)
+		if err != nil {
+			slog.Warn("rag sync failed", "component", "rag", "error", err)
+			return
+		}
+		slog.Info("rag sync", "component", "rag", "indexed", stats.Indexed, "unchanged", stats.Unchanged,
+			"removed", stats.Removed, "failed", stats.Failed, "chunks", idx.Len())
+	}
+	run()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			run()
+		}
+	}
+}
+
+// upsert (re-)indexes d when its content changed; reports whether it did.
+func (idx *Index) upsert(ctx context.Context, d document) (bool, error) {
+	sum := sha256.Sum256([]byte(d.text))
+	digest := hex.EncodeToString(sum[:])
+	var stored string
+	_ = idx.db.QueryRowContext(ctx, `SELECT sha256 FROM rag_documents WHERE source = ?`, d.source).Scan(&stored)
+	if stored == digest {
+		return false, nil
+	}
+	parts := splitChunks(d.text, chunkRunes)
+	var vecs [][]float32
+	if len(parts) > 0 {
+		var err error
+		if vecs, err = idx.embedder.Embed(ctx, parts); err != nil {
+			return false, err
+		}
+		if len(vecs) != len(parts) {
+			return false, fmt.Errorf("embedder returned %d vectors for %d chunks", len(vecs), len(parts))
+		}
+	}
+	tx, err := idx.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`DELETE FROM rag_chunks WHERE source = ?`, d.source); err != nil {
+		return false, err
+	}
+	for i, p := range parts {
+		if _, err := tx.Exec(`INSERT INTO rag_chunks (source, seq, content, embedding) VALUES (?, ?, ?, ?)`,
+			d.source, i, p, encodeVector(normalize(vecs[i]))); err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO rag_documents (source, sha256, indexed_at) VALUES (?, ?, ?)
+		ON CONFLICT(source) DO UPDATE SET sha256 = excluded.sha256, indexed_at = excluded.indexed_at`,
+		d.source, digest, time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// markSourcePrefix marks every indexed document under src as seen.
+func (idx *Index) markSourcePrefix(src string, seen map[string]bool) error {
+	rows, err := idx.db.Query(`SELECT source FROM rag_documents`)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return err
+		}
+		if s == src || strings.HasPrefix(s, strings.TrimRight(src, "/")+"/") {
+			seen[s] = true
+		}
+	}
+	return rows.Err()
+}
+
+func (idx *Index) removeUnseen(seen map[string]bool) (int, error) {
+	rows, err := idx.db.Query(`SELECT source FROM rag_documents`)
+	if err != nil {
+		return 0, err
+	}
+	var gone []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if !seen[s] {
+			gone = append(gone, s)
+		}
+	}
+	_ = rows.Close()
+	for _, s := range gone {
+		if _, err := idx.db.Exec(`DELETE FROM rag_chunks WHERE source = ?`, s); err != nil {
+			return 0, err
+		}
+		if _, err := idx.db.Exec(`DELETE FROM rag_documents WHERE source = ?`, s); err != nil {
+			return 0, err
+		}
+	}
+	return len(gone), nil
+}
+
+func loadSource(ctx context.Context, src string) ([]document, error) {
+	if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+		text, err := fetchURL(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		return []document{{source: src, text: text}}, nil
+	}
+	info, err := os.Stat(src)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		text, err := readFile(src)
+		if err != nil {
+			return nil, err
+		}
+		return []document{{source: src, text: text}}, nil
+	}
+	var docs []document
+	err = filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != src && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !indexedExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		text, err := readFile(p)
+		if err != nil {
+			slog.Warn("rag: skipping file", "component", "rag", "path", p, "error", err)
+			return nil
+		}
+		docs = append(docs, document{source: filepath.ToSlash(p), text: text})
+		return nil
+	})
+	return docs, err
+}
+
+func readFile(p string) (string, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > maxDocumentBytes {
+		return "", fmt.Errorf("larger than %d bytes", maxDocumentBytes)
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("not UTF-8 text")
+	}
+	text := string(data)
+	if ext := strings.ToLower(filepath.Ext(p)); ext == ".html" || ext == ".htm" {
+		text = htmlToText(text)
+	}
+	return text, nil
+}
+
+var fetchClient = &http.Client{Timeout: 30 * time.Second}
+
+func fetchURL(ctx context.Context, u string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := fetchClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDocumentBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxDocumentBytes {
+		return "", fmt.Errorf("larger than %d bytes", maxDocumentBytes)
+	}
+	text := strings.ToValidUTF8(string(data), "�")
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		text = htmlToText(text)
+	}
+	return text, nil
+}
+
+var (
+	htmlDropBlocks = regexp.MustCompile(`(?is)<(script|style|noscript|head)\b.*?</(script|style|noscript|head)>`)
+	htmlBreaks     = regexp.MustCompile(`(?i)<(br|/p|/div|/h[1-6]|/li|/tr|/section|/article)\b[^>]*>`)
+	htmlTags       = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankRuns      = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+)
+
+// htmlToText is a deliberately small tag stripper: good enough for
+// documentation pages, not a general HTML renderer.
+func htmlToText(s string) string {
+	s = htmlDropBlocks.ReplaceAllString(s, "")
+	s = htmlBreaks.ReplaceAllString(s, "\n\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+	s = blankRuns.ReplaceAllString(s, "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// splitChunks groups paragraphs into chunks of at most max runes; a single
+// paragraph longer than that is cut at whitespace.
+func splitChunks(text string, max int) []string {
+	var chunks []string
+	var cur strings.Builder
+	curRunes := 0
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		cur.Reset()
+		curRunes = 0
+	}
+	for _, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		n := utf8.RuneCountInString(para)
+		if curRunes > 0 && curRunes+n+2 > max {
+			flush()
+		}
+		for n > max {
+			head, rest := cutAtSpace(para, max)
+			cur.WriteString(head)
+			flush()
+			para = rest
+			n = utf8.RuneCountInString(para)
+		}
+		if curRunes > 0 {
+			cur.WriteString("\n\n")
+			curRunes += 2
+		}
+		cur.WriteString(para)
+		curRunes += n
+	}
+	flush()
+	return chunks
+}
+
+// cutAtSpace splits s after at most max runes, preferring the last space.
+func cutAtSpace(s string, max int) (string, string) {
+	r := []rune(s)
+	cut := max
+	for i := max; i > max/2; i-- {
+		if r[i] == ' ' || r[i] == '\n' {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimSpace(string(r[:cut])), strings.TrimSpace(string(r[cut:]))
+}
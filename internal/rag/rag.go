@@ -0,0 +1,209 @@
+// Package rag indexes local documents (and fetched URLs) for retrieval:
+// documents are split into chunks, embedded with the configured embeddings
+// model and stored in a SQLite file; Search embeds the query and returns the
+// closest chunks by cosine similarity. The corpus is expected to be company
+// docs — thousands of chunks, not millions — so search is an in-memory scan
+// over normalized vectors rather than an ANN index.
+package rag
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+// Embedder turns texts into vectors, one per input, in input order.
+// provider.OpenAIEmbedder satisfies it.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Hit is one retrieved chunk.
+type Hit struct {
+	Source  string  // file path or URL the chunk came from
+	Content string  // chunk text
+	Score   float64 // cosine similarity to the query, -1..1
+}
+
+type chunk struct {
+	source  string
+	content string
+	vec     []float32 // unit length
+}
+
+// Index is a persistent chunk index plus its in-memory search copy.
+type Index struct {
+	db       *sql.DB
+	embedder Embedder
+	model    string
+
+	mu     sync.RWMutex
+	chunks []chunk
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS rag_meta (key TEXT PRIMARY KEY, value TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS rag_documents (source TEXT PRIMARY KEY, sha256 TEXT NOT NULL, indexed_at TEXT NOT NULL);
+CREATE TABLE IF NOT EXISTS rag_chunks (
+	source    TEXT NOT NULL,
+	seq       INTEGER NOT NULL,
+	content   TEXT NOT NULL,
+	embedding BLOB NOT NULL,
+	PRIMARY KEY (source, seq)
+);`
+
+// Open opens (creating if needed) the index at path. model names the
+// embeddings model; when it differs from the one the index was built with,
+// the stored chunks are dropped so the next Sync re-embeds everything —
+// vectors from different models are not comparable.
+func Open(path string, embedder Embedder, model string) (*Index, error) {
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("rag: open: %w", err)
+	}
+	if _, err := db.Exec(schema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("rag: schema: %w", err)
+	}
+	idx := &Index{db: db, embedder: embedder, model: model}
+	var stored string
+	err = db.QueryRow(`SELECT value FROM rag_meta WHERE key = 'model'`).Scan(&stored)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		_ = db.Close()
+		return nil, fmt.Errorf("rag: reading meta: %w", err)
+	}
+	if stored != model {
+		if _, err := db.Exec(`DELETE FROM rag_chunks; DELETE FROM rag_documents;`); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("rag: resetting index: %w", err)
+		}
+		if _, err := db.Exec(`INSERT INTO rag_meta (key, value) VALUES ('model', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, model); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("rag: writing meta: %w", err)
+		}
+	}
+	if err := idx.load(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+// Close closes the index database.
+func (idx *Index) Close() error { return idx.db.Close() }
+
+// Len is the number of indexed chunks.
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.chunks)
+}
+
+// load refreshes the in-memory copy from the database.
+func (idx *Index) load() error {
+	rows, err := idx.db.Query(`SELECT source, content, embedding FROM rag_chunks ORDER BY source, seq`)
+	if err != nil {
+		return fmt.Errorf("rag: loading chunks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var chunks []chunk
+	for rows.Next() {
+		var c chunk
+		var blob []byte
+		if err := rows.Scan(&c.source, &c.content, &blob); err != nil {
+			return fmt.Errorf("rag: loading chunks: %w", err)
+		}
+		c.vec = decodeVector(blob)
+		chunks = append(chunks, c)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	idx.mu.Lock()
+	idx.chunks = chunks
+	idx.mu.Unlock()
+	return nil
+}
+
+// Search returns up to k chunks most similar to query, best first.
+func (idx *Index) Search(ctx context.Context, query string, k int) ([]Hit, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	idx.mu.RLock()
+	empty := len(idx.chunks) == 0
+	idx.mu.RUnlock()
+	if empty {
+		return nil, nil
+	}
+	vecs, err := idx.embedder.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("rag: embedding query: %w", err)
+	}
+	if len(vecs) != 1 {
+		return nil, fmt.Errorf("rag: embedder returned %d vectors for one query", len(vecs))
+	}
+	q := normalize(vecs[0])
+
+	idx.mu.RLock()
+	hits := make([]Hit, 0, len(idx.chunks))
+	for _, c := range idx.chunks {
+		if len(c.vec) != len(q) {
+			continue
+		}
+		hits = append(hits, Hit{Source: c.source, Content: c.content, Score: dot(q, c.vec)})
+	}
+	idx.mu.RUnlock()
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits, nil
+}
+
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	n := math.Sqrt(sum)
+	out := make([]float32, len(v))
+	for i, x := range v {
+		out[i] = float32(float64(x) / n)
+	}
+	return out
+}
+
+func dot(a, b []float32) float64 {
+	var s float64
+	for i := range a {
+		s += float64(a[i]) * float64(b[i])
+	}
+	return s
+}
+
+func encodeVector(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(x))
+	}
+	return b
+}
+
+func decodeVector(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
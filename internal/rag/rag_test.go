@@ -0,0 +1,148 @@
+package rag
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// wordEmbedder is a bag-of-words hashing embedder: texts sharing words get
+// similar vectors, which is all retrieval tests need.
+type wordEmbedder struct{ calls int }
+
+func (e *wordEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, 64)
+		for _, w := range strings.Fields(strings.ToLower(t)) {
+			h := fnv.New32a()
+			_, _ = h.Write([]byte(strings.Trim(w, ".,?!:")))
+			v[h.Sum32()%64]++
+		}
+		out[i] = v
+	}
+	return out, nil
+}
+
+func writeDoc(t *testing.T, dir, name, text string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndex_SyncAndSearch(t *testing.T) {
+	ctx := context.Background()
+	docs := t.TempDir()
+	writeDoc(t, docs, "vpn.md", "# VPN\n\nTo connect to the office VPN install the client and sign in with your SSO account.")
+	writeDoc(t, docs, "hr/holidays.txt", "Holiday requests go through the HR portal at least two weeks ahead.")
+	writeDoc(t, docs, "logo.png", "not text")
+	writeDoc(t, docs, ".git/config", "ignored")
+
+	emb := &wordEmbedder{}
+	idx, err := Open(filepath.Join(t.TempDir(), "rag.db"), emb, "words-v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idx.Close() }()
+
+	stats, err := idx.Sync(ctx, []string{docs})
+	if err != nil || stats.Indexed != 2 {
+		t.Fatalf("first sync = %+v, %v", stats, err)
+	}
+	hits, err := idx.Search(ctx, "how do I connect to the VPN?", 1)
+	if err != nil || len(hits) != 1 || !strings.HasSuffix(hits[0].Source, "vpn.md") {
+		t.Fatalf("search = %+v, %v", hits, err)
+	}
+
+	calls := emb.calls
+	if stats, _ = idx.Sync(ctx, []string{docs}); stats.Unchanged != 2 || emb.calls != calls {
+		t.Errorf("unchanged sync = %+v, embed calls %d -> %d", stats, calls, emb.calls)
+	}
+
+	if err := os.Remove(filepath.Join(docs, "vpn.md")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ = idx.Sync(ctx, []string{docs}); stats.Removed != 1 || idx.Len() != 1 {
+		t.Errorf("after delete = %+v, %d chunks", stats, idx.Len())
+	}
+}
+
+func TestIndex_ModelChangeResetsAndSourceFailureKeepsChunks(t *testing.T) {
+	ctx := context.Background()
+	docs := t.TempDir()
+	writeDoc(t, docs, "a.md", "alpha beta gamma")
+	path := filepath.Join(t.TempDir(), "rag.db")
+
+	idx, err := Open(path, &wordEmbedder{}, "m1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Sync(ctx, []string{docs}); err != nil {
+		t.Fatal(err)
+	}
+	// An unreadable source does not wipe what it indexed before.
+	if err := os.Rename(docs, docs+"-moved"); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := idx.Sync(ctx, []string{docs}); stats.Failed != 1 || idx.Len() != 1 {
+		t.Errorf("failed source = %+v, %d chunks", stats, idx.Len())
+	}
+	_ = idx.Close()
+
+	idx, err = Open(path, &wordEmbedder{}, "m2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idx.Close() }()
+	if idx.Len() != 0 {
+		t.Errorf("a new embeddings model must drop old vectors; %d chunks left", idx.Len())
+	}
+}
+
+func TestIndex_URLSource(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head><title>x</title><style>p{}</style></head><body><h1>Expenses</h1><p>Submit receipts &amp; invoices monthly.</p><script>track()</script></body></html>`))
+	}))
+	defer srv.Close()
+
+	idx, err := Open(filepath.Join(t.TempDir(), "rag.db"), &wordEmbedder{}, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = idx.Close() }()
+	if _, err := idx.Sync(context.Background(), []string{srv.URL + "/policy"}); err != nil {
+		t.Fatal(err)
+	}
+	hits, _ := idx.Search(context.Background(), "receipts", 3)
+	if len(hits) != 1 || hits[0].Content != "Expenses\n\nSubmit receipts & invoices monthly." {
+		t.Errorf("hits = %+v", hits)
+	}
+}
+
+func TestSplitChunks(t *testing.T) {
+	text := "one\n\ntwo\n\n" + strings.Repeat("word ", 100)
+	chunks := splitChunks(text, 120)
+	if chunks[0] != "one\n\ntwo" {
+		t.Errorf("small paragraphs should share a chunk: %q", chunks[0])
+	}
+	for _, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 120 {
+			t.Errorf("chunk of %d runes exceeds the limit", n)
+		}
+	}
+	if got := strings.Join(chunks[1:], " "); strings.Count(got, "word") != 100 {
+		t.Errorf("long paragraph lost words: %d", strings.Count(got, "word"))
+	}
+}
@@ -0,0 +1,335 @@
+// Package replay re-runs a recorded session against the current
+// orchestrator code. The LLM is played back from the session's assistant
+// messages and every tool answers with its recorded result, so nothing
+// outside the process is called and a replay is deterministic. Where the
+// orchestrator now parses other tool calls, or stores other messages (a
+// guard or message-construction change), the replay reports a divergence.
+//
+// A transcript holds what the session stored, not every raw LLM response:
+// a response the orchestrator retried (empty, unparseable) was never
+// stored, so it is not replayed either.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// Transcript is a recorded session split into turns.
+type Transcript struct {
+	SessionID string
+	History   []provider.Message // stored before the first user message; seeded as is
+	Turns     []Turn
+}
+
+// Turn is one user message and what the session stored after it.
+type Turn struct {
+	Input     string
+	Responses []*provider.CompletionResponse // the LLM's answers in order; the last is the reply
+	Results   []Result                       // tool results in call order
+	Messages  []provider.Message             // everything the turn stored, the user message first
+}
+
+// Result is one recorded tool call and its outcome.
+type Result struct {
+	CallID     string
+	Tool       string // "plugin__action"
+	Args       map[string]string
+	Content    string
+	Structured string
+	Error      string
+}
+
+// Divergence is one difference between the recording and the replay.
+type Divergence struct {
+	Turn      int // 1-based
+	What      string
+	Want, Got string
+}
+
+func (d Divergence) String() string {
+	return fmt.Sprintf("turn %d: %s\n  want: %s\n  got:  %s", d.Turn, d.What, d.Want, d.Got)
+}
+
+// Report is the outcome of a replay.
+type Report struct {
+	Turns       int
+	Divergences []Divergence
+}
+
+// OK reports whether the replay matched the recording.
+func (r *Report) OK() bool { return len(r.Divergences) == 0 }
+
+// Options configure a replay.
+type Options struct {
+	// Opts are the orchestrator options to replay with, e.g. a deployment's
+	// guard, rules or policies. Features that call the LLM on their own
+	// (session titles, summaries, judges) would draw on the recording and
+	// must stay off.
+	Opts   orchestrator.OrchestratorOpts
+	Parser orchestrator.ToolCallParser // nil = orchestrator.DefaultParser
+}
+
+// FromSession splits a stored session into turns. Tool results are read
+// back from both the native (role=tool) and the text ([plugin_output])
+// form the orchestrator stores them in.
+func FromSession(sess *state.Session) (*Transcript, error) {
+	if sess == nil {
+		return nil, fmt.Errorf("no session")
+	}
+	tr := &Transcript{SessionID: sess.ID}
+	var turn *Turn
+	var pending []int // indexes into turn.Results still waiting for their output
+	for i, m := range sess.Messages {
+		switch {
+		case m.Role == provider.RoleUser && strings.HasPrefix(m.Content, pluginOutputOpen):
+			if turn == nil || len(pending) == 0 {
+				return nil, fmt.Errorf("message %d: tool output without a tool call", i)
+			}
+			body := strings.TrimSuffix(strings.TrimPrefix(m.Content, pluginOutputOpen), pluginOutputClose)
+			readOutput(&turn.Results[pending[0]], body)
+			pending = pending[1:]
+		case m.Role == provider.RoleUser:
+			tr.Turns = append(tr.Turns, Turn{Input: m.Content})
+			turn, pending = &tr.Turns[len(tr.Turns)-1], nil
+		case turn == nil:
+			tr.History = append(tr.History, m)
+		case m.Role == provider.RoleTool:
+			idx := slices.IndexFunc(pending, func(r int) bool { return turn.Results[r].CallID == m.ToolCallID })
+			if idx < 0 {
+				return nil, fmt.Errorf("message %d: result for unknown tool call %q", i, m.ToolCallID)
+			}
+			readOutput(&turn.Results[pending[idx]], m.Content)
+			pending = slices.Delete(pending, idx, idx+1)
+		case m.Role == provider.RoleAssistant && len(m.ToolCalls) > 0:
+			turn.Responses = append(turn.Responses, &provider.CompletionResponse{Content: m.Content, ToolCalls: m.ToolCalls})
+			for _, tc := range m.ToolCalls {
+				pending = append(pending, len(turn.Results))
+				turn.Results = append(turn.Results, Result{CallID: tc.ID, Tool: tc.Name, Args: tc.Arguments})
+			}
+		case m.Role == provider.RoleAssistant:
+			turn.Responses = append(turn.Responses, &provider.CompletionResponse{Content: m.Content})
+			if strings.HasPrefix(m.Content, "[tool_call]") {
+				for _, c := range orchestrator.DefaultParser.Parse(m.Content) {
+					pending = append(pending, len(turn.Results))
+					turn.Results = append(turn.Results, Result{Tool: c.Plugin + "__" + c.Action, Args: c.Args})
+				}
+			}
+		}
+		if turn != nil {
+			turn.Messages = append(turn.Messages, m)
+		}
+	}
+	return tr, nil
+}
+
+const (
+	pluginOutputOpen  = "[plugin_output]\n"
+	pluginOutputClose = "\n[/plugin_output]"
+)
+
+// readOutput fills r from a stored tool output, the inverse of how the
+// orchestrator writes one: "error: ..." for a failure, and structured
+// content in a trailing [structured] block.
+func readOutput(r *Result, s string) {
+	if msg, ok := strings.CutPrefix(s, "error: "); ok {
+		r.Error = msg
+		return
+	}
+	if content, rest, ok := strings.Cut(s, "\n\n[structured]\n"); ok {
+		r.Content, r.Structured = content, strings.TrimSuffix(rest, "\n[/structured]")
+		return
+	}
+	r.Content = s
+}
+
+// Run replays tr turn by turn in a fresh in-memory session and reports
+// where the orchestrator now behaves differently. The error is for a
+// replay that could not be set up; a turn that fails is a divergence.
+func Run(ctx context.Context, tr *Transcript, opts Options) (*Report, error) {
+	llm := &llmPlayer{}
+	tools := &toolPlayer{}
+	reg := orchestrator.NewToolRegistry()
+	for _, c := range tr.capabilities() {
+		if err := reg.Register(c, tools); err != nil {
+			return nil, fmt.Errorf("register %s: %w", c.Name, err)
+		}
+	}
+	parser := opts.Parser
+	if parser == nil {
+		parser = orchestrator.DefaultParser
+	}
+	sessionID := tr.SessionID
+	if sessionID == "" {
+		sessionID = "replay"
+	}
+	sessions := state.NewSessionStore("")
+	sessions.Create(sessionID, "", "", "")
+	for _, m := range tr.History {
+		_ = sessions.AddMessage(sessionID, m)
+	}
+	orch := orchestrator.NewWithRules(llm, parser, reg, state.NewMemoryStore(""), sessions, opts.Opts)
+
+	rep := &Report{Turns: len(tr.Turns)}
+	for i, turn := range tr.Turns {
+		n := i + 1
+		llm.load(turn.Responses)
+		tools.load(n, turn.Results)
+		sess, _ := sessions.Get(sessionID)
+		before := len(sess.Messages)
+
+		if _, err := orch.Run(ctx, sessionID, turn.Input); err != nil {
+			rep.Divergences = append(rep.Divergences, Divergence{Turn: n, What: "the turn failed", Want: "a reply", Got: err.Error()})
+		}
+		rep.Divergences = append(rep.Divergences, tools.divergences...)
+		if left := len(llm.responses) - llm.pos; left > 0 {
+			rep.Divergences = append(rep.Divergences, Divergence{Turn: n, What: "the turn ended before the recording did",
+				Want: fmt.Sprintf("%d more LLM responses", left), Got: "none requested"})
+		}
+		if left := len(tools.results); left > 0 {
+			rep.Divergences = append(rep.Divergences, Divergence{Turn: n, What: "recorded tool calls were not made",
+				Want: tools.results[0].Tool, Got: fmt.Sprintf("%d calls missing", left)})
+		}
+		sess, _ = sessions.Get(sessionID)
+		rep.Divergences = append(rep.Divergences, diffMessages(n, turn.Messages, sess.Messages[before:])...)
+	}
+	return rep, nil
+}
+
+// capabilities describes the recorded tools to the registry, with every
+// arg the recording passed as a parameter. The orchestrator's own tools
+// ("_" plugins) are left to it.
+func (tr *Transcript) capabilities() []orchestrator.PluginCapability {
+	params := map[string]map[string]map[string]bool{} // plugin -> action -> args
+	for _, t := range tr.Turns {
+		for _, r := range t.Results {
+			plugin, action, ok := strings.Cut(r.Tool, "__")
+			if !ok || strings.HasPrefix(plugin, "_") {
+				continue
+			}
+			if params[plugin] == nil {
+				params[plugin] = map[string]map[string]bool{}
+			}
+			if params[plugin][action] == nil {
+				params[plugin][action] = map[string]bool{}
+			}
+			for a := range r.Args {
+				params[plugin][action][a] = true
+			}
+		}
+	}
+	var caps []orchestrator.PluginCapability
+	for _, plugin := range slices.Sorted(maps.Keys(params)) {
+		c := orchestrator.PluginCapability{Name: plugin, Description: "Recorded " + plugin}
+		for _, action := range slices.Sorted(maps.Keys(params[plugin])) {
+			a := orchestrator.Action{Name: action, Description: "Recorded " + plugin + "__" + action}
+			for _, p := range slices.Sorted(maps.Keys(params[plugin][action])) {
+				a.Parameters = append(a.Parameters, orchestrator.Parameter{Name: p})
+			}
+			c.Actions = append(c.Actions, a)
+		}
+		caps = append(caps, c)
+	}
+	return caps
+}
+
+// llmPlayer answers each completion with the turn's next recorded response.
+type llmPlayer struct {
+	responses []*provider.CompletionResponse
+	pos       int
+}
+
+func (p *llmPlayer) load(rs []*provider.CompletionResponse) { p.responses, p.pos = rs, 0 }
+
+func (p *llmPlayer) Complete(context.Context, *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	if p.pos >= len(p.responses) {
+		return nil, fmt.Errorf("replay: the recording has no more LLM responses for this turn")
+	}
+	r := *p.responses[p.pos]
+	p.pos++
+	return &r, nil
+}
+
+// toolPlayer answers each tool call with the turn's next recorded result
+// and notes calls that differ from the recording.
+type toolPlayer struct {
+	turn        int
+	results     []Result
+	divergences []Divergence
+}
+
+func (p *toolPlayer) load(turn int, rs []Result) {
+	p.turn, p.results, p.divergences = turn, nil, nil
+	for _, r := range rs {
+		if !strings.HasPrefix(r.Tool, "_") {
+			p.results = append(p.results, r)
+		}
+	}
+}
+
+func (p *toolPlayer) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	got := describeCall(call.Plugin+"__"+call.Action, call.Args)
+	if len(p.results) == 0 {
+		p.divergences = append(p.divergences, Divergence{Turn: p.turn, What: "unexpected tool call", Want: "no more calls", Got: got})
+		return orchestrator.ToolResult{CallID: call.ID, Error: "replay: the recording has no such call"}
+	}
+	r := p.results[0]
+	p.results = p.results[1:]
+	if want := describeCall(r.Tool, r.Args); want != got {
+		p.divergences = append(p.divergences, Divergence{Turn: p.turn, What: "tool call differs", Want: want, Got: got})
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: r.Content, StructuredContent: r.Structured, Error: r.Error}
+}
+
+// describeCall renders a call with its args in a stable order.
+func describeCall(tool string, args map[string]string) string {
+	parts := make([]string, 0, len(args))
+	for _, k := range slices.Sorted(maps.Keys(args)) {
+		parts = append(parts, k+"="+args[k])
+	}
+	return tool + "(" + strings.Join(parts, ", ") + ")"
+}
+
+// diffMessages compares what a turn stored with the recording.
+func diffMessages(turn int, want, got []provider.Message) []Divergence {
+	var ds []Divergence
+	for i := range max(len(want), len(got)) {
+		switch {
+		case i >= len(got):
+			ds = append(ds, Divergence{Turn: turn, What: fmt.Sprintf("message %d is missing", i+1), Want: describeMessage(want[i]), Got: "nothing"})
+		case i >= len(want):
+			ds = append(ds, Divergence{Turn: turn, What: fmt.Sprintf("message %d is extra", i+1), Want: "nothing", Got: describeMessage(got[i])})
+		case describeMessage(want[i]) != describeMessage(got[i]):
+			ds = append(ds, Divergence{Turn: turn, What: fmt.Sprintf("message %d differs", i+1), Want: describeMessage(want[i]), Got: describeMessage(got[i])})
+		}
+	}
+	return ds
+}
+
+// describeMessage renders a message for comparison. Text-form tool calls
+// are re-parsed, since the orchestrator writes their args in map order.
+func describeMessage(m provider.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: ", m.Role)
+	if m.Role == provider.RoleAssistant && strings.HasPrefix(m.Content, "[tool_call]") && len(m.ToolCalls) == 0 {
+		for _, c := range orchestrator.DefaultParser.Parse(m.Content) {
+			b.WriteString("[tool_call] " + describeCall(c.Plugin+"__"+c.Action, c.Args))
+		}
+		return b.String()
+	}
+	fmt.Fprintf(&b, "%q", m.Content)
+	for _, tc := range m.ToolCalls {
+		fmt.Fprintf(&b, " [tool_call %s] %s", tc.ID, describeCall(tc.Name, tc.Arguments))
+	}
+	if m.ToolCallID != "" {
+		fmt.Fprintf(&b, " (result of %s)", m.ToolCallID)
+	}
+	return b.String()
+}
@@ -0,0 +1,139 @@
+package replay
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+type scriptedLLM struct {
+	responses []*provider.CompletionResponse
+}
+
+func (s *scriptedLLM) Complete(context.Context, *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	r := s.responses[0]
+	s.responses = s.responses[1:]
+	return r, nil
+}
+
+type weatherTool struct{}
+
+func (weatherTool) Execute(_ context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Args["city"] == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "city is required"}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: "Sunny in " + call.Args["city"], StructuredContent: `{"temp":21}`}
+}
+
+// record runs a live session the way production does and returns what it
+// stored.
+func record(t *testing.T, responses ...*provider.CompletionResponse) *state.Session {
+	t.Helper()
+	reg := orchestrator.NewToolRegistry()
+	_ = reg.Register(orchestrator.PluginCapability{
+		Name: "weather", Description: "Weather",
+		Actions: []orchestrator.Action{{Name: "now", Description: "Current weather", Parameters: []orchestrator.Parameter{{Name: "city"}, {Name: "units"}}}},
+	}, weatherTool{})
+	sessions := state.NewSessionStore("")
+	sessions.Create("s1", "", "", "")
+	orch := orchestrator.NewWithRules(&scriptedLLM{responses: responses}, orchestrator.DefaultParser, reg,
+		state.NewMemoryStore(""), sessions, orchestrator.OrchestratorOpts{})
+	for _, in := range []string{"weather in Paris?", "and in Rome?"} {
+		if _, err := orch.Run(context.Background(), "s1", in); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sess, _ := sessions.Get("s1")
+	return sess
+}
+
+func textResponses() []*provider.CompletionResponse {
+	return []*provider.CompletionResponse{
+		{Content: "[tool_call] weather__now(city=Paris, units=metric)"},
+		{Content: "[tool_call] weather__now(units=metric)"},
+		{Content: "It is sunny in Paris."},
+		{Content: "[tool_call]\n{\"tool\": \"weather__now\", \"args\": {\"city\": \"Rome\"}}\n[/tool_call]"},
+		{Content: "Rome is sunny too."},
+	}
+}
+
+func nativeResponses() []*provider.CompletionResponse {
+	return []*provider.CompletionResponse{
+		{ToolCalls: []provider.ToolCall{{ID: "t1", Name: "weather__now", Arguments: map[string]string{"city": "Paris"}}}},
+		{Content: "It is sunny in Paris."},
+		{ToolCalls: []provider.ToolCall{{ID: "t2", Name: "weather__now", Arguments: map[string]string{}}}},
+		{Content: "I need a city."},
+	}
+}
+
+func TestReplayMatchesRecording(t *testing.T) {
+	for name, responses := range map[string][]*provider.CompletionResponse{"text": textResponses(), "native": nativeResponses()} {
+		t.Run(name, func(t *testing.T) {
+			tr, err := FromSession(record(t, responses...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tr.Turns) != 2 || len(tr.Turns[0].Results) == 0 {
+				t.Fatalf("transcript = %+v", tr)
+			}
+			rep, err := Run(context.Background(), tr, Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rep.OK() {
+				t.Errorf("divergences:\n%v", rep.Divergences)
+			}
+		})
+	}
+}
+
+func TestReplayReportsDivergences(t *testing.T) {
+	sess := record(t, textResponses()...)
+	tr, err := FromSession(sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := &tr.Turns[0]
+	if first.Results[0].Content != "Sunny in Paris" || first.Results[0].Structured != `{"temp":21}` || first.Results[1].Error != "city is required" {
+		t.Fatalf("results read back = %+v", first.Results)
+	}
+
+	// The guard used to wrap output another way.
+	first.Messages[2].Content = strings.Replace(first.Messages[2].Content, "[plugin_output]", "[tool_output]", 1)
+	// The model's reply now names another city.
+	first.Responses[0] = &provider.CompletionResponse{Content: "[tool_call] weather__now(city=Lyon, units=metric)"}
+
+	rep, err := Run(context.Background(), tr, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var whats []string
+	for _, d := range rep.Divergences {
+		if d.Turn != 1 {
+			t.Errorf("divergence outside turn 1: %v", d)
+		}
+		whats = append(whats, d.What)
+	}
+	got := strings.Join(whats, "; ")
+	for _, want := range []string{"tool call differs", "message 2 differs", "message 3 differs"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("divergences %q lack %q", got, want)
+		}
+	}
+}
+
+func TestReplayShortRecording(t *testing.T) {
+	tr, err := FromSession(record(t, nativeResponses()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.Turns[1].Responses = tr.Turns[1].Responses[:1] // the reply is lost
+	rep, _ := Run(context.Background(), tr, Options{})
+	if rep.OK() || rep.Divergences[0].What != "the turn failed" {
+		t.Errorf("divergences = %v", rep.Divergences)
+	}
+}
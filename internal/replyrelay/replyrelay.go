@@ -0,0 +1,123 @@
+// Package replyrelay forwards outbound channel messages between pods over
+// Redis pub/sub, so a reply produced on a pod without the channel
+// connection (a scheduler job, a background turn) is delivered by a pod that
+// has it.
+//
+// Each pod subscribes to one topic per channel it holds. PUBLISH reports how
+// many pods received a message, which lets Forward fail fast when no pod
+// holds the channel instead of dropping the reply silently. When several
+// pods hold the same channel, each receives the message; a SET NX claim on
+// the message id lets exactly one of them deliver it.
+//
+// Delivery is at most once: a holder that receives the message but fails
+// to send it logs the failure; the forwarding pod has already returned.
+package replyrelay
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+const (
+	topicPrefix = "opentalon:channel:out:"
+	claimPrefix = "opentalon:channel:out:claim:"
+	claimTTL    = 10 * time.Minute
+	sendTimeout = 30 * time.Second
+)
+
+// envelope is the pub/sub payload.
+type envelope struct {
+	ID      string              `json:"id"`
+	Message pkg.OutboundMessage `json:"message"`
+}
+
+// Redis is a channel.Relay backed by Redis pub/sub.
+type Redis struct {
+	client redis.UniversalClient
+	pubsub *redis.PubSub
+}
+
+// NewRedis creates a relay on client. The caller owns the client.
+func NewRedis(client redis.UniversalClient) *Redis {
+	return &Redis{client: client, pubsub: client.Subscribe(context.Background())}
+}
+
+func (r *Redis) Hold(ctx context.Context, channelID string) error {
+	return r.pubsub.Subscribe(ctx, topicPrefix+channelID)
+}
+
+func (r *Redis) Release(ctx context.Context, channelID string) error {
+	return r.pubsub.Unsubscribe(ctx, topicPrefix+channelID)
+}
+
+func (r *Redis) Forward(ctx context.Context, channelID string, msg pkg.OutboundMessage) error {
+	data, err := json.Marshal(envelope{ID: newID(), Message: msg})
+	if err != nil {
+		return fmt.Errorf("replyrelay: encode: %w", err)
+	}
+	n, err := r.client.Publish(ctx, topicPrefix+channelID, data).Result()
+	if err != nil {
+		return fmt.Errorf("replyrelay: publish: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("channel %q not found on any pod", channelID)
+	}
+	return nil
+}
+
+// Run delivers messages for held channels until ctx is done, then closes
+// the subscription.
+func (r *Redis) Run(ctx context.Context, deliver func(ctx context.Context, channelID string, msg pkg.OutboundMessage) error) {
+	defer func() { _ = r.pubsub.Close() }()
+	msgs := r.pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-msgs:
+			if !ok {
+				return
+			}
+			r.handle(ctx, m, deliver)
+		}
+	}
+}
+
+func (r *Redis) handle(ctx context.Context, m *redis.Message, deliver func(context.Context, string, pkg.OutboundMessage) error) {
+	channelID := strings.TrimPrefix(m.Channel, topicPrefix)
+	var env envelope
+	if err := json.Unmarshal([]byte(m.Payload), &env); err != nil {
+		slog.Warn("replyrelay: dropping malformed message", "channel", channelID, "error", err)
+		return
+	}
+	won, err := r.client.SetNX(ctx, claimPrefix+env.ID, 1, claimTTL).Result()
+	if err != nil {
+		slog.Warn("replyrelay: claim failed; not delivering", "channel", channelID, "error", err)
+		return
+	}
+	if !won {
+		return // another pod holding the channel delivers it
+	}
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	defer cancel()
+	if err := deliver(sendCtx, channelID, env.Message); err != nil {
+		slog.Warn("replyrelay: delivering forwarded message failed", "channel", channelID,
+			"conversation", env.Message.ConversationID, "error", err)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,93 @@
+package replyrelay
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	pkg "github.com/opentalon/opentalon/pkg/channel"
+)
+
+type delivered struct {
+	pod       string
+	channelID string
+	msg       pkg.OutboundMessage
+}
+
+// startPod runs a relay for a pod holding channels and reports what it
+// delivers on out.
+func startPod(t *testing.T, ctx context.Context, addr, pod string, out chan<- delivered, channels ...string) *Redis {
+	t.Helper()
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	r := NewRedis(client)
+	for _, id := range channels {
+		if err := r.Hold(ctx, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	go r.Run(ctx, func(_ context.Context, channelID string, msg pkg.OutboundMessage) error {
+		out <- delivered{pod: pod, channelID: channelID, msg: msg}
+		return nil
+	})
+	return r
+}
+
+func TestForwardDeliversOnceOnHolder(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan delivered, 4)
+
+	sender := startPod(t, ctx, mr.Addr(), "a", out)
+	startPod(t, ctx, mr.Addr(), "b", out, "web")
+	startPod(t, ctx, mr.Addr(), "c", out, "web", "slack")
+
+	if err := sender.Forward(ctx, "web", pkg.OutboundMessage{ConversationID: "c1", Content: "done"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case d := <-out:
+		if d.channelID != "web" || d.msg.ConversationID != "c1" || d.msg.Content != "done" || d.pod == "a" {
+			t.Errorf("delivered %+v", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("forwarded message was not delivered")
+	}
+	select {
+	case d := <-out:
+		t.Errorf("message delivered twice, again by %s", d.pod)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestForwardWithoutHolder(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out := make(chan delivered, 1)
+
+	holder := startPod(t, ctx, mr.Addr(), "b", out, "web")
+	sender := startPod(t, ctx, mr.Addr(), "a", out)
+
+	err := sender.Forward(ctx, "teams", pkg.OutboundMessage{Content: "x"})
+	if err == nil || !strings.Contains(err.Error(), "not found on any pod") {
+		t.Errorf("forward to an unheld channel: %v", err)
+	}
+
+	if err := holder.Release(ctx, "web"); err != nil {
+		t.Fatal(err)
+	}
+	// The unsubscribe is confirmed asynchronously.
+	deadline := time.Now().Add(2 * time.Second)
+	for sender.Forward(ctx, "web", pkg.OutboundMessage{Content: "x"}) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("a released channel should not be reachable")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,228 @@
+package requestpkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auth grant types.
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantRefreshToken      = "refresh_token"
+)
+
+const (
+	// defaultTokenTTL applies when the token response has no expires_in
+	// and no cache_ttl is configured.
+	defaultTokenTTL = 5 * time.Minute
+	// tokenExpirySkew renews a token this long before the server says it
+	// expires, so it does not run out in flight.
+	tokenExpirySkew = 30 * time.Second
+)
+
+// Auth obtains an OAuth2 access token for a package's requests and puts it
+// in a header. Tokens are cached until they expire; a 401 from the API
+// drops the cached token and the request is retried once with a new one.
+// Credentials are read from the named env vars on every fetch.
+type Auth struct {
+	Grant           string `yaml:"grant,omitempty"`             // client_credentials (default) or refresh_token
+	TokenURL        string `yaml:"token_url"`                   // template: {{env.X}} allowed
+	ClientIDEnv     string `yaml:"client_id_env,omitempty"`     // env var holding the client id
+	ClientSecretEnv string `yaml:"client_secret_env,omitempty"` // env var holding the client secret
+	RefreshTokenEnv string `yaml:"refresh_token_env,omitempty"` // refresh_token grant: env var holding the initial refresh token
+	Scope           string `yaml:"scope,omitempty"`             // space-separated scopes
+	ClientAuth      string `yaml:"client_auth,omitempty"`       // basic (default: HTTP Basic) or body (client_id/client_secret form fields)
+	CacheTTL        string `yaml:"cache_ttl,omitempty"`         // e.g. "50m"; caps the server's expires_in. Default: expires_in, else 5m
+	Header          string `yaml:"header,omitempty"`            // default "Authorization"
+	Format          string `yaml:"format,omitempty"`            // header value; default "Bearer {{token}}"
+}
+
+// Validate checks the grant, the credential env names and cache_ttl.
+func (a *Auth) Validate() error {
+	if a == nil {
+		return nil
+	}
+	if a.TokenURL == "" {
+		return fmt.Errorf("auth.token_url is required")
+	}
+	switch a.Grant {
+	case "", GrantClientCredentials:
+		if a.ClientIDEnv == "" {
+			return fmt.Errorf("auth.client_id_env is required for the client_credentials grant")
+		}
+	case GrantRefreshToken:
+		if a.RefreshTokenEnv == "" {
+			return fmt.Errorf("auth.refresh_token_env is required for the refresh_token grant")
+		}
+	default:
+		return fmt.Errorf("auth.grant must be client_credentials or refresh_token, got %q", a.Grant)
+	}
+	if a.ClientAuth != "" && a.ClientAuth != "basic" && a.ClientAuth != "body" {
+		return fmt.Errorf("auth.client_auth must be basic or body, got %q", a.ClientAuth)
+	}
+	if a.CacheTTL != "" {
+		if d, err := time.ParseDuration(a.CacheTTL); err != nil || d <= 0 {
+			return fmt.Errorf("auth.cache_ttl: invalid duration %q", a.CacheTTL)
+		}
+	}
+	return nil
+}
+
+// apply sets the auth header on h.
+func (a *Auth) apply(h http.Header, token string) {
+	header := a.Header
+	if header == "" {
+		header = "Authorization"
+	}
+	format := a.Format
+	if format == "" {
+		format = "Bearer {{token}}"
+	}
+	h.Set(header, strings.ReplaceAll(format, "{{token}}", token))
+}
+
+// tokenKey identifies one set of credentials; packages that share them
+// share the cached token.
+type tokenKey struct {
+	grant, tokenURL, clientID, scope string
+}
+
+type cachedToken struct {
+	access  string
+	expires time.Time
+	refresh string // rotated refresh token, if the server issued one
+}
+
+// tokenCache holds the access tokens of one executor.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[tokenKey]*cachedToken
+	now    func() time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{tokens: make(map[tokenKey]*cachedToken), now: time.Now}
+}
+
+func (a *Auth) key() tokenKey {
+	grant := a.Grant
+	if grant == "" {
+		grant = GrantClientCredentials
+	}
+	return tokenKey{grant: grant, tokenURL: Substitute(a.TokenURL, nil), clientID: envOf(a.ClientIDEnv), scope: a.Scope}
+}
+
+// token returns a valid access token for a, fetching one when none is
+// cached, the cached one has expired, or fresh is set. The lock is held
+// during the fetch so concurrent calls wait for one token request.
+func (c *tokenCache) token(ctx context.Context, client *http.Client, a *Auth, fresh bool) (string, error) {
+	key := a.key()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := c.tokens[key]
+	if cached != nil && !fresh && c.now().Before(cached.expires) {
+		return cached.access, nil
+	}
+	refresh := ""
+	if cached != nil {
+		refresh = cached.refresh
+	}
+	tok, err := c.fetch(ctx, client, a, refresh)
+	if err != nil {
+		return "", err
+	}
+	c.tokens[key] = tok
+	return tok.access, nil
+}
+
+// fetch requests a token from a.TokenURL. For the refresh_token grant a
+// refresh token rotated by an earlier response is preferred over the env
+// var, which only seeds the first exchange.
+func (c *tokenCache) fetch(ctx context.Context, client *http.Client, a *Auth, refresh string) (*cachedToken, error) {
+	form := url.Values{}
+	if a.Grant == GrantRefreshToken {
+		if refresh == "" {
+			if refresh = envOf(a.RefreshTokenEnv); refresh == "" {
+				return nil, fmt.Errorf("auth: env %q is not set", a.RefreshTokenEnv)
+			}
+		}
+		form.Set("grant_type", GrantRefreshToken)
+		form.Set("refresh_token", refresh)
+	} else {
+		form.Set("grant_type", GrantClientCredentials)
+	}
+	if a.Scope != "" {
+		form.Set("scope", a.Scope)
+	}
+	clientID, secret := envOf(a.ClientIDEnv), envOf(a.ClientSecretEnv)
+	if a.ClientIDEnv != "" && clientID == "" {
+		return nil, fmt.Errorf("auth: env %q is not set", a.ClientIDEnv)
+	}
+	if a.ClientAuth == "body" && clientID != "" {
+		form.Set("client_id", clientID)
+		if secret != "" {
+			form.Set("client_secret", secret)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, Substitute(a.TokenURL, nil), strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("auth: build token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if a.ClientAuth != "body" && clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(secret))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	if !isSuccess(resp.StatusCode) {
+		return nil, fmt.Errorf("auth: token endpoint returned HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	var tr struct {
+		AccessToken  string `json:"access_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.Unmarshal(body, &tr); err != nil || tr.AccessToken == "" {
+		return nil, fmt.Errorf("auth: token response has no access_token")
+	}
+
+	ttl := defaultTokenTTL
+	if tr.ExpiresIn > 0 {
+		ttl = time.Duration(tr.ExpiresIn)*time.Second - tokenExpirySkew
+		if ttl <= 0 {
+			ttl = time.Duration(tr.ExpiresIn) * time.Second / 2
+		}
+	}
+	if d, err := time.ParseDuration(a.CacheTTL); err == nil && d > 0 && (tr.ExpiresIn <= 0 || d < ttl) {
+		ttl = d
+	}
+	tok := &cachedToken{access: tr.AccessToken, expires: c.now().Add(ttl), refresh: refresh}
+	if tr.RefreshToken != "" {
+		tok.refresh = tr.RefreshToken
+	}
+	return tok, nil
+}
+
+func envOf(name string) string {
+	if name == "" {
+		return ""
+	}
+	return os.Getenv(name)
+}
@@ -0,0 +1,143 @@
+package requestpkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+// oauthServer issues tokens tok-1, tok-2, ... at /token and serves /api,
+// which only accepts the most recently issued token.
+type oauthServer struct {
+	*httptest.Server
+	issued  atomic.Int32
+	refresh atomic.Value // last refresh_token received
+}
+
+func newOAuthServer(t *testing.T, expiresIn int) *oauthServer {
+	s := &oauthServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Error(err)
+			}
+			switch r.Form.Get("grant_type") {
+			case "client_credentials":
+				if id, secret, _ := r.BasicAuth(); id != "app" || secret != "s3cret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.Form.Get("scope") != "read:jira" {
+					t.Errorf("scope = %q", r.Form.Get("scope"))
+				}
+			case "refresh_token":
+				s.refresh.Store(r.Form.Get("refresh_token"))
+			}
+			n := s.issued.Add(1)
+			_, _ = fmt.Fprintf(w, `{"access_token": "tok-%d", "expires_in": %d, "refresh_token": "rt-%d"}`, n, expiresIn, n)
+		case "/api":
+			if r.Header.Get("Authorization") != fmt.Sprintf("Bearer tok-%d", s.issued.Load()) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestExecutor_AuthClientCredentials(t *testing.T) {
+	t.Setenv("TEST_OAUTH_ID", "app")
+	t.Setenv("TEST_OAUTH_SECRET", "s3cret")
+	srv := newOAuthServer(t, 3600)
+
+	auth := &Auth{TokenURL: srv.URL + "/token", ClientIDEnv: "TEST_OAUTH_ID", ClientSecretEnv: "TEST_OAUTH_SECRET", Scope: "read:jira"}
+	exec := NewExecutor("svc", withSetAuth(Set{Auth: auth, Packages: []Package{{Action: "get", URL: srv.URL + "/api"}}}))
+	for i := 0; i < 3; i++ {
+		res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"})
+		if res.Error != "" || res.Content != "ok" {
+			t.Fatalf("call %d: %+v", i, res)
+		}
+	}
+	if n := srv.issued.Load(); n != 1 {
+		t.Errorf("tokens issued = %d, want 1 (cached)", n)
+	}
+
+	// The API revoking the token (here: a newer one being issued elsewhere)
+	// answers 401; the executor renews its token and retries once.
+	srv.issued.Add(1)
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "get"})
+	if res.Error != "" || srv.issued.Load() != 3 {
+		t.Errorf("after revoke: %+v, issued %d", res, srv.issued.Load())
+	}
+}
+
+func TestExecutor_AuthRefreshTokenExpiry(t *testing.T) {
+	t.Setenv("TEST_OAUTH_REFRESH", "rt-seed")
+	srv := newOAuthServer(t, 60)
+
+	exec := NewExecutor("svc", []Package{{
+		Action: "get",
+		URL:    srv.URL + "/api",
+		Auth:   &Auth{Grant: GrantRefreshToken, TokenURL: srv.URL + "/token", RefreshTokenEnv: "TEST_OAUTH_REFRESH"},
+	}})
+	now := time.Now()
+	exec.tokens.now = func() time.Time { return now }
+
+	if res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if got := srv.refresh.Load(); got != "rt-seed" {
+		t.Errorf("first refresh token = %v", got)
+	}
+	now = now.Add(31 * time.Second) // past expires_in minus the skew
+	if res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "get"}); res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if got := srv.refresh.Load(); got != "rt-1" {
+		t.Errorf("second exchange should use the rotated refresh token, got %v", got)
+	}
+}
+
+func TestExecutor_AuthTokenErrors(t *testing.T) {
+	t.Setenv("TEST_OAUTH_ID", "app")
+	t.Setenv("TEST_OAUTH_SECRET", "wrong")
+	srv := newOAuthServer(t, 3600)
+
+	exec := NewExecutor("svc", []Package{{
+		Action: "get",
+		URL:    srv.URL + "/api",
+		Auth:   &Auth{TokenURL: srv.URL + "/token", ClientIDEnv: "TEST_OAUTH_ID", ClientSecretEnv: "TEST_OAUTH_SECRET"},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"})
+	if res.Error != "auth: token endpoint returned HTTP 401: " {
+		t.Errorf("error = %q", res.Error)
+	}
+}
+
+func TestAuthValidate(t *testing.T) {
+	bad := []*Auth{
+		{ClientIDEnv: "ID"},
+		{TokenURL: "https://x/token"},
+		{TokenURL: "https://x/token", Grant: GrantRefreshToken},
+		{TokenURL: "https://x/token", Grant: "password", ClientIDEnv: "ID"},
+		{TokenURL: "https://x/token", ClientIDEnv: "ID", ClientAuth: "jwt"},
+		{TokenURL: "https://x/token", ClientIDEnv: "ID", CacheTTL: "soon"},
+	}
+	for i, a := range bad {
+		if a.Validate() == nil {
+			t.Errorf("auth %d should not validate", i)
+		}
+	}
+	if err := (&Auth{TokenURL: "https://x/token", ClientIDEnv: "ID", CacheTTL: "10m"}).Validate(); err != nil {
+		t.Error(err)
+	}
+}
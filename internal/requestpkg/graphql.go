@@ -0,0 +1,76 @@
+package requestpkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Package types.
+const (
+	TypeHTTP    = "http"
+	TypeGraphQL = "graphql"
+)
+
+// graphQLRequest builds the POST for a graphql package: the query is sent
+// as written, and the variables are rendered like a json_body (see object).
+func (t *templater) graphQLRequest(p Package) (outgoing, error) {
+	method := p.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	out, err := t.request(method, p.URL, "", p.Headers)
+	if err != nil {
+		return outgoing{}, err
+	}
+	body, err := json.Marshal(map[string]any{"query": p.Query, "variables": t.object(p.Variables, p.Parameters)})
+	if err != nil {
+		return outgoing{}, fmt.Errorf("graphql: encode variables: %w", err)
+	}
+	out.body = string(body)
+	if out.header.Get("Content-Type") == "" {
+		out.header.Set("Content-Type", "application/json")
+	}
+	return out, nil
+}
+
+// graphQLErrors returns the messages of a GraphQL response's errors array
+// as one error string, or "" when there are none. GraphQL reports these
+// with HTTP 200, possibly next to partial data.
+func graphQLErrors(body []byte) string {
+	var r struct {
+		Errors []struct {
+			Message string `json:"message"`
+			Path    []any  `json:"path"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &r) != nil || len(r.Errors) == 0 {
+		return ""
+	}
+	msgs := make([]string, 0, len(r.Errors))
+	for _, e := range r.Errors {
+		msg := e.Message
+		if len(e.Path) > 0 {
+			parts := make([]string, len(e.Path))
+			for i, p := range e.Path {
+				parts[i] = fmt.Sprint(p)
+			}
+			msg += " (at " + strings.Join(parts, ".") + ")"
+		}
+		msgs = append(msgs, msg)
+	}
+	return "GraphQL error: " + strings.Join(msgs, "; ")
+}
+
+// graphQLData returns the data member of a GraphQL response, which is
+// what a package without a response section reports.
+func graphQLData(body []byte) string {
+	var r struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if json.Unmarshal(body, &r) != nil || len(r.Data) == 0 || string(r.Data) == "null" {
+		return string(body)
+	}
+	return string(r.Data)
+}
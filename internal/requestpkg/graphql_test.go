@@ -0,0 +1,135 @@
+package requestpkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+type graphQLBody struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func graphQLServer(t *testing.T, answer func(graphQLBody) string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("%s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var req graphQLBody
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Error(err)
+		}
+		_, _ = w.Write([]byte(answer(req)))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+const issuesQuery = `query($team: String!, $first: Int, $after: String) {
+  issues(filter: {team: {key: {eq: $team}}}, first: $first, after: $after) { nodes { identifier } pageInfo { endCursor } }
+}`
+
+func TestExecutor_GraphQLVariables(t *testing.T) {
+	var got graphQLBody
+	srv := graphQLServer(t, func(req graphQLBody) string {
+		got = req
+		return `{"data": {"issues": {"nodes": [{"identifier": "ENG-1"}]}}}`
+	})
+	exec := NewExecutor("linear", []Package{{
+		Action: "issues",
+		Type:   TypeGraphQL,
+		URL:    srv.URL,
+		Query:  issuesQuery,
+		Variables: map[string]any{
+			"team":   "{{args.team}}",
+			"first":  "{{args.first}}",
+			"after":  "{{args.after}}",
+			"label":  "team {{args.team}}",
+			"states": []any{"started", "unstarted"},
+		},
+		Parameters: []ParamDefinition{{Name: "team"}, {Name: "first", Type: "integer"}, {Name: "after"}},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "issues", Args: map[string]string{"team": "ENG", "first": "25"}})
+	if res.Error != "" || res.Content != `{"issues": {"nodes": [{"identifier": "ENG-1"}]}}` {
+		t.Errorf("result = %+v", res)
+	}
+	if got.Query != issuesQuery {
+		t.Errorf("query changed: %q", got.Query)
+	}
+	want := map[string]any{"team": "ENG", "first": float64(25), "label": "team ENG", "states": []any{"started", "unstarted"}}
+	if !reflect.DeepEqual(got.Variables, want) {
+		t.Errorf("variables = %#v, want %#v", got.Variables, want)
+	}
+}
+
+func TestExecutor_GraphQLErrors(t *testing.T) {
+	srv := graphQLServer(t, func(graphQLBody) string {
+		return `{"data": {"repository": null}, "errors": [
+		  {"message": "Could not resolve to a Repository with the name 'x'.", "path": ["repository"]},
+		  {"message": "Rate limited"}]}`
+	})
+	pkg := Package{Action: "repo", Type: TypeGraphQL, URL: srv.URL, Query: "{ repository { id } }"}
+	res := NewExecutor("github", []Package{pkg}).Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "repo"})
+	want := "GraphQL error: Could not resolve to a Repository with the name 'x'. (at repository); Rate limited"
+	if res.Content != "" || res.Error != want {
+		t.Errorf("default: %+v", res)
+	}
+
+	pkg.Response = &Response{Extract: map[string]string{"why": "errors[0].message"}, Error: "GitHub said: {{response.why}}"}
+	res = NewExecutor("github", []Package{pkg}).Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "repo"})
+	if res.Error != "GitHub said: Could not resolve to a Repository with the name 'x'." {
+		t.Errorf("error template: %+v", res)
+	}
+}
+
+func TestExecutor_GraphQLPaginateVariables(t *testing.T) {
+	srv := graphQLServer(t, func(req graphQLBody) string {
+		if req.Variables["team"] != "ENG" {
+			t.Errorf("variables lost across pages: %v", req.Variables)
+		}
+		switch req.Variables["after"] {
+		case nil:
+			return `{"data": {"issues": {"nodes": [{"identifier": "ENG-1"}], "pageInfo": {"endCursor": "c1"}}}}`
+		case "c1":
+			return `{"data": {"issues": {"nodes": [{"identifier": "ENG-2"}], "pageInfo": {"endCursor": "c2"}}}}`
+		}
+		return `{"data": {"issues": {"nodes": [], "pageInfo": {"endCursor": null}}}}`
+	})
+	exec := NewExecutor("linear", []Package{{
+		Action:    "issues",
+		Type:      TypeGraphQL,
+		URL:       srv.URL,
+		Query:     issuesQuery,
+		Variables: map[string]any{"team": "{{args.team}}"},
+		Paginate:  &Pagination{Param: "after", In: "variables", Next: "data.issues.pageInfo.endCursor", Items: "data.issues.nodes"},
+		Response:  &Response{Extract: map[string]string{"ids": "data.issues.nodes[*].identifier"}, Success: "{{response.ids}}"},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "issues", Args: map[string]string{"team": "ENG"}})
+	if res.Error != "" || res.Content != `["ENG-1","ENG-2"]` {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestPackageValidateGraphQL(t *testing.T) {
+	bad := []Package{
+		{Type: TypeGraphQL, URL: "https://x"},
+		{Type: TypeGraphQL, URL: "https://x", Query: "{ viewer { id } }", Body: "{}"},
+		{Query: "{ viewer { id } }"},
+		{Type: "soap"},
+	}
+	for i, p := range bad {
+		if p.Validate() == nil {
+			t.Errorf("package %d should not validate", i)
+		}
+	}
+	ok := Package{Type: TypeGraphQL, URL: "https://x", Query: "{ viewer { id } }", Variables: map[string]any{"n": 3}}
+	if err := ok.Validate(); err != nil {
+		t.Error(err)
+	}
+}
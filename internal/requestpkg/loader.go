@@ -1,10 +1,12 @@
 package requestpkg
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strings"
 
 	"github.com/opentalon/opentalon/internal/orchestrator"
 	"gopkg.in/yaml.v3"
@@ -29,15 +31,48 @@ func expandEnvInSet(s *Set) {
 			s.MCP.Headers[k] = expandEnv(v)
 		}
 	}
+	if s.Auth != nil {
+		s.Auth.TokenURL = expandEnv(s.Auth.TokenURL)
+	}
 	for i, p := range s.Packages {
 		p.URL = expandEnv(p.URL)
+		if p.Auth != nil {
+			p.Auth.TokenURL = expandEnv(p.Auth.TokenURL)
+		}
 		for k, v := range p.Headers {
 			p.Headers[k] = expandEnv(v)
 		}
+		for j, st := range p.Steps {
+			st.URL = expandEnv(st.URL)
+			for k, v := range st.Headers {
+				st.Headers[k] = expandEnv(v)
+			}
+			p.Steps[j] = st
+		}
 		s.Packages[i] = p
 	}
 }
 
+// resolveConfig expands ${VAR} in the set's config values and replaces a
+// file:<path> value with the file's contents (trailing newline trimmed),
+// e.g. a mounted Kubernetes secret. Only operator-owned sets are resolved;
+// a downloaded skill's config is used as written, so it cannot pull in
+// environment variables or files.
+func resolveConfig(s *Set) error {
+	for k, v := range s.Config {
+		v = expandEnv(v)
+		if path, ok := strings.CutPrefix(v, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("config.%s: %w", k, err)
+			}
+			v = strings.TrimRight(string(data), "\r\n")
+		}
+		s.Config[k] = v
+	}
+	return nil
+}
+
 // LoadDir loads all request package sets from a directory. Each .yaml file is one Set.
 func LoadDir(dir string) ([]Set, error) {
 	entries, err := os.ReadDir(dir)
@@ -67,7 +102,18 @@ func LoadDir(dir string) ([]Set, error) {
 		if s.MCP != nil && s.MCP.URL == "" {
 			return nil, fmt.Errorf("%s: mcp section requires a non-empty url", path)
 		}
+		if err := s.Auth.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, p := range s.Packages {
+			if err := p.Validate(); err != nil {
+				return nil, fmt.Errorf("%s: action %s: %w", path, p.Action, err)
+			}
+		}
 		expandEnvInSet(&s)
+		if err := resolveConfig(&s); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
 		sets = append(sets, s)
 	}
 	return sets, nil
@@ -75,19 +121,37 @@ func LoadDir(dir string) ([]Set, error) {
 
 // Register registers each set with the tool registry (capability + executor).
 // Sets with a non-nil MCP field are skipped — their capabilities come from the
-// opentalon-mcp plugin binary, not the built-in HTTP executor.
+// opentalon-mcp plugin binary, not the built-in HTTP executor. A set whose
+// name is taken does not stop the others; the errors are joined.
 func Register(registry *orchestrator.ToolRegistry, sets []Set) error {
+	var errs []error
 	for _, set := range sets {
 		if set.MCP != nil {
 			continue
 		}
 		cap := ToCapability(set)
-		exec := NewExecutor(set.PluginName, set.Packages)
+		exec := NewExecutor(set.Name(), withSetAuth(set)).WithConfig(set.Config)
 		if err := registry.Register(cap, exec); err != nil {
-			return fmt.Errorf("register request package %q: %w", set.PluginName, err)
+			errs = append(errs, fmt.Errorf("register request package %q: %w", set.Name(), err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
+}
+
+// withSetAuth returns the set's packages with the set-level auth filled in
+// where a package has none of its own.
+func withSetAuth(set Set) []Package {
+	if set.Auth == nil {
+		return set.Packages
+	}
+	packages := make([]Package, len(set.Packages))
+	for i, p := range set.Packages {
+		if p.Auth == nil {
+			p.Auth = set.Auth
+		}
+		packages[i] = p
+	}
+	return packages
 }
 
 // CollectMCPServers returns the MCPServerConfig from every set that has one.
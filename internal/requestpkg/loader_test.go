@@ -1,6 +1,9 @@
 package requestpkg
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/opentalon/opentalon/internal/orchestrator"
@@ -59,6 +62,24 @@ func TestRegister_SkipsMCPSets(t *testing.T) {
 	}
 }
 
+func TestRegister_AliasAndConflicts(t *testing.T) {
+	reg := orchestrator.NewToolRegistry()
+	pkgs := []Package{{Action: "search", Description: "d", Method: "GET", URL: "http://x"}}
+	sets := []Set{
+		{PluginName: "jira", Packages: pkgs},
+		{PluginName: "jira", Packages: pkgs},
+		{PluginName: "jira", Alias: "jira_cloud", Version: "v3", Packages: pkgs},
+	}
+	err := Register(reg, sets)
+	if err == nil || !strings.Contains(err.Error(), "set alias: jira_2") {
+		t.Fatalf("Register = %v, want a conflict suggesting jira_2", err)
+	}
+	cap, ok := reg.GetCapability("jira_cloud")
+	if !ok || cap.Version != "v3" {
+		t.Errorf("the aliased set after the conflict was not registered: %+v", cap)
+	}
+}
+
 func TestRegister_OnlyMCPSets(t *testing.T) {
 	reg := orchestrator.NewToolRegistry()
 	sets := []Set{
@@ -71,3 +92,39 @@ func TestRegister_OnlyMCPSets(t *testing.T) {
 		t.Errorf("expected no registrations, got %d", len(caps))
 	}
 }
+
+func TestLoadDir_ResolvesConfig(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	if err := os.WriteFile(secret, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TRACKER_HOST", "tracker.example.com")
+	set := `plugin: tracker
+config:
+  base_url: "https://${TRACKER_HOST}"
+  token: "file:` + secret + `"
+  project: OPS
+packages:
+  - action: list
+    url: "{{config.base_url}}/items"
+`
+	if err := os.WriteFile(filepath.Join(dir, "tracker.yaml"), []byte(set), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sets, err := LoadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := sets[0].Config
+	if got["base_url"] != "https://tracker.example.com" || got["token"] != "s3cret" || got["project"] != "OPS" {
+		t.Errorf("config = %v", got)
+	}
+
+	if err := os.Remove(secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDir(dir); err == nil {
+		t.Error("a missing secret file should fail loading the set")
+	}
+}
@@ -0,0 +1,574 @@
+package requestpkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxSpecBytes bounds a downloaded or read OpenAPI document.
+const maxSpecBytes = 32 << 20
+
+// OpenAPIOptions selects and adapts the operations imported from a spec.
+type OpenAPIOptions struct {
+	Plugin        string   // plugin name of the generated set (required)
+	Description   string   // default: the spec's info.title
+	Operations    []string // operationIds to import, in this order; empty = every operation not marked deprecated
+	BaseURL       string   // overrides the spec's servers (or host/basePath)
+	EnvPrefix     string   // prefix of the credential env vars; default the plugin name upper-cased
+	AllowedGroups []string // restrict the plugin to these profile groups
+}
+
+// LoadOpenAPI reads an OpenAPI 3 or Swagger 2 document (JSON or YAML) from
+// a file path or an http(s) URL and turns its operations into a Set:
+//
+//   - one action per operation, named by its operationId (or method_path);
+//   - path, query and required header parameters, and the top-level
+//     properties of a JSON request body, as action parameters, typed from
+//     their schemas; the body is sent as a json_body;
+//   - auth from the operation's security requirement: a bearer token
+//     ({PREFIX}_TOKEN), an API key ({PREFIX}_API_KEY), HTTP basic
+//     ({PREFIX}_BASIC_AUTH, base64 of user:password) or OAuth2 client
+//     credentials ({PREFIX}_CLIENT_ID / {PREFIX}_CLIENT_SECRET).
+func LoadOpenAPI(ctx context.Context, source string, opts OpenAPIOptions) (Set, error) {
+	data, err := readSpec(ctx, source)
+	if err != nil {
+		return Set{}, err
+	}
+	specURL := ""
+	if isURL(source) {
+		specURL = source
+	}
+	return parseOpenAPI(data, specURL, opts)
+}
+
+func isURL(s string) bool {
+	return strings.HasPrefix(s, "http://") || strings.HasPrefix(s, "https://")
+}
+
+func readSpec(ctx context.Context, source string) ([]byte, error) {
+	if !isURL(source) {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, fmt.Errorf("read openapi spec: %w", err)
+		}
+		defer func() { _ = f.Close() }()
+		return io.ReadAll(io.LimitReader(f, maxSpecBytes))
+	}
+	ctx, cancel := context.WithTimeout(ctx, defaultTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch openapi spec: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch openapi spec: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if !isSuccess(resp.StatusCode) {
+		return nil, fmt.Errorf("fetch openapi spec: HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxSpecBytes))
+}
+
+var httpMethods = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+
+// apiDoc wraps a decoded spec for $ref lookups.
+type apiDoc struct {
+	root    map[string]any
+	swagger bool // Swagger 2.0 rather than OpenAPI 3
+}
+
+type operation struct {
+	id, method, path string
+	op, pathItem     map[string]any
+}
+
+func parseOpenAPI(data []byte, specURL string, opts OpenAPIOptions) (Set, error) {
+	if opts.Plugin == "" {
+		return Set{}, fmt.Errorf("openapi: plugin name is required")
+	}
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Set{}, fmt.Errorf("openapi: parse spec: %w", err)
+	}
+	doc := &apiDoc{root: asMap(raw)}
+	switch {
+	case strings.HasPrefix(str(doc.root, "openapi"), "3."):
+	case str(doc.root, "swagger") == "2.0":
+		doc.swagger = true
+	default:
+		return Set{}, fmt.Errorf("openapi: not an OpenAPI 3 or Swagger 2.0 document")
+	}
+
+	base := opts.BaseURL
+	if base == "" {
+		var err error
+		if base, err = doc.baseURL(specURL); err != nil {
+			return Set{}, err
+		}
+	}
+	base = strings.TrimRight(base, "/")
+	prefix := opts.EnvPrefix
+	if prefix == "" {
+		prefix = strings.ToUpper(sanitizeName(opts.Plugin))
+	}
+
+	ops, err := doc.operations(opts.Operations)
+	if err != nil {
+		return Set{}, err
+	}
+	set := Set{PluginName: opts.Plugin, Description: opts.Description, AllowedGroups: opts.AllowedGroups}
+	if set.Description == "" {
+		set.Description = str(asMap(doc.root["info"]), "title")
+	}
+	for _, o := range ops {
+		pkg := doc.buildPackage(o, base)
+		doc.applySecurity(&pkg, o.op, prefix)
+		if err := pkg.Validate(); err != nil {
+			return Set{}, fmt.Errorf("openapi: operation %s: %w", o.id, err)
+		}
+		set.Packages = append(set.Packages, pkg)
+	}
+	return set, nil
+}
+
+// baseURL is the first server URL (OpenAPI 3, with its variables set to
+// their defaults) or scheme://host/basePath (Swagger 2). A relative URL is
+// resolved against the URL the spec was fetched from.
+func (d *apiDoc) baseURL(specURL string) (string, error) {
+	var base string
+	if d.swagger {
+		if host := str(d.root, "host"); host != "" {
+			scheme := "https"
+			if schemes, _ := d.root["schemes"].([]any); len(schemes) > 0 && !contains(schemes, "https") {
+				scheme = fmt.Sprint(schemes[0])
+			}
+			base = scheme + "://" + host
+		}
+		base += str(d.root, "basePath")
+	} else if servers, _ := d.root["servers"].([]any); len(servers) > 0 {
+		server := asMap(servers[0])
+		base = str(server, "url")
+		for name, v := range asMap(server["variables"]) {
+			base = strings.ReplaceAll(base, "{"+name+"}", fmt.Sprint(asMap(v)["default"]))
+		}
+	}
+	if specURL != "" && !isURL(base) {
+		if ref, err := url.Parse(specURL); err == nil {
+			if rel, err := url.Parse(base); err == nil {
+				base = ref.ResolveReference(rel).String()
+			}
+		}
+	}
+	if !isURL(base) {
+		return "", fmt.Errorf("openapi: the spec has no absolute server URL; set base_url")
+	}
+	return base, nil
+}
+
+// operations lists the operations to import: those in allow, in that
+// order, or every non-deprecated one sorted by path and method.
+func (d *apiDoc) operations(allow []string) ([]operation, error) {
+	byID := make(map[string]operation)
+	var all []operation
+	paths := asMap(d.root["paths"])
+	keys := make([]string, 0, len(paths))
+	for p := range paths {
+		keys = append(keys, p)
+	}
+	sort.Strings(keys)
+	for _, p := range keys {
+		item := d.resolve(paths[p])
+		for _, m := range httpMethods {
+			op := asMap(item[m])
+			if op == nil {
+				continue
+			}
+			id := str(op, "operationId")
+			if id == "" {
+				id = m + " " + p
+			}
+			o := operation{id: sanitizeName(id), method: strings.ToUpper(m), path: p, op: op, pathItem: item}
+			byID[str(op, "operationId")] = o
+			byID[o.id] = o
+			if dep, _ := op["deprecated"].(bool); !dep {
+				all = append(all, o)
+			}
+		}
+	}
+	if len(allow) == 0 {
+		return all, nil
+	}
+	var ops []operation
+	var missing []string
+	for _, id := range allow {
+		o, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		ops = append(ops, o)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("openapi: operations not in the spec: %s", strings.Join(missing, ", "))
+	}
+	return ops, nil
+}
+
+func (d *apiDoc) buildPackage(o operation, base string) Package {
+	pkg := Package{Action: o.id, Method: o.method, Description: str(o.op, "summary")}
+	if pkg.Description == "" {
+		pkg.Description, _, _ = strings.Cut(strings.TrimSpace(str(o.op, "description")), "\n")
+	}
+
+	path := o.path
+	var query []string
+	taken := make(map[string]bool)
+	for _, param := range d.parameters(o) {
+		name, in := str(param, "name"), str(param, "in")
+		arg := sanitizeName(name)
+		schema := d.resolve(param["schema"])
+		if d.swagger && in != "body" {
+			schema = param // Swagger 2 puts type, items and enum on the parameter itself
+		}
+		required, _ := param["required"].(bool)
+		switch in {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+name+"}", "{{args."+arg+"}}")
+			required = true
+		case "query":
+			query = append(query, url.QueryEscape(name)+"={{args."+arg+"}}")
+		case "header":
+			if !required {
+				continue
+			}
+			if pkg.Headers == nil {
+				pkg.Headers = map[string]string{}
+			}
+			pkg.Headers[name] = "{{args." + arg + "}}"
+		case "body":
+			d.addBody(&pkg, schema, required, taken)
+			continue
+		default: // cookie, formData
+			slog.Warn("openapi parameter not supported", "component", "requestpkg", "operation", o.id, "name", name, "in", in)
+			continue
+		}
+		taken[arg] = true
+		pkg.Parameters = append(pkg.Parameters, ParamDefinition{
+			Name: arg, Description: str(param, "description"), Required: required, Type: d.schemaType(schema),
+		})
+	}
+	if !d.swagger {
+		if body := d.resolve(o.op["requestBody"]); body != nil {
+			required, _ := body["required"].(bool)
+			d.addBody(&pkg, jsonSchema(d, body), required, taken)
+		}
+	}
+
+	pkg.URL = base + path
+	if len(query) > 0 {
+		pkg.URL += "?" + strings.Join(query, "&")
+	}
+	return pkg
+}
+
+// parameters merges path-level and operation-level parameters; the
+// operation's win for the same name and location.
+func (d *apiDoc) parameters(o operation) []map[string]any {
+	var params []map[string]any
+	index := make(map[string]int)
+	for _, list := range []any{o.pathItem["parameters"], o.op["parameters"]} {
+		items, _ := list.([]any)
+		for _, item := range items {
+			p := d.resolve(item)
+			if p == nil {
+				continue
+			}
+			key := str(p, "in") + ":" + str(p, "name")
+			if i, ok := index[key]; ok {
+				params[i] = p
+				continue
+			}
+			index[key] = len(params)
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// jsonSchema returns the schema of an OpenAPI 3 request body's JSON
+// content, or nil when it has none.
+func jsonSchema(d *apiDoc, body map[string]any) map[string]any {
+	content := asMap(body["content"])
+	if c := asMap(content["application/json"]); c != nil {
+		return d.resolve(c["schema"])
+	}
+	for ct, c := range content {
+		if strings.Contains(ct, "json") {
+			return d.resolve(asMap(c)["schema"])
+		}
+	}
+	return nil
+}
+
+// addBody maps a JSON body schema to parameters: each top-level property
+// of an object becomes one (prefixed body_ when a path or query parameter
+// has its name), sent through json_body. Any other schema becomes a single
+// body parameter sent as the raw body.
+func (d *apiDoc) addBody(pkg *Package, schema map[string]any, required bool, taken map[string]bool) {
+	if schema == nil {
+		return
+	}
+	props, requiredProps := d.properties(schema)
+	if len(props) == 0 {
+		pkg.Body = "{{args.body}}"
+		pkg.Parameters = append(pkg.Parameters, ParamDefinition{
+			Name: "body", Description: "Request body (JSON)", Required: required, Type: d.schemaType(schema),
+		})
+		return
+	}
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pkg.JSONBody = make(map[string]any, len(names))
+	for _, name := range names {
+		prop := d.resolve(props[name])
+		if ro, _ := prop["readOnly"].(bool); ro {
+			continue
+		}
+		arg := sanitizeName(name)
+		if taken[arg] {
+			arg = "body_" + arg
+		}
+		pkg.JSONBody[name] = "{{args." + arg + "}}"
+		pkg.Parameters = append(pkg.Parameters, ParamDefinition{
+			Name: arg, Description: str(prop, "description"), Required: required && requiredProps[name], Type: d.schemaType(prop),
+		})
+	}
+}
+
+// properties collects the properties of an object schema, including those
+// of its allOf members.
+func (d *apiDoc) properties(schema map[string]any) (map[string]any, map[string]bool) {
+	props := make(map[string]any)
+	required := make(map[string]bool)
+	var walk func(s map[string]any, depth int)
+	walk = func(s map[string]any, depth int) {
+		if s == nil || depth > 8 {
+			return
+		}
+		for k, v := range asMap(s["properties"]) {
+			props[k] = v
+		}
+		if req, ok := s["required"].([]any); ok {
+			for _, r := range req {
+				required[fmt.Sprint(r)] = true
+			}
+		}
+		if all, ok := s["allOf"].([]any); ok {
+			for _, sub := range all {
+				walk(d.resolve(sub), depth+1)
+			}
+		}
+	}
+	walk(schema, 0)
+	return props, required
+}
+
+// schemaType maps a schema to a ParamDefinition type.
+func (d *apiDoc) schemaType(schema map[string]any) string {
+	if schema == nil {
+		return ""
+	}
+	if enum, ok := schema["enum"].([]any); ok && len(enum) > 0 && str(schema, "type") != "array" {
+		vals := make([]string, 0, len(enum))
+		for _, v := range enum {
+			vals = append(vals, fmt.Sprint(v))
+		}
+		return "enum:" + strings.Join(vals, ",")
+	}
+	switch t := str(schema, "type"); t {
+	case "integer", "number", "boolean":
+		return t
+	case "array":
+		switch item := d.schemaType(d.resolve(schema["items"])); item {
+		case "integer", "number", "boolean", "string", "":
+			if item == "" {
+				item = "string"
+			}
+			return "array:" + item
+		default:
+			return "array"
+		}
+	case "object":
+		return "object"
+	}
+	if schema["properties"] != nil || schema["allOf"] != nil {
+		return "object"
+	}
+	return ""
+}
+
+// applySecurity adds the auth for the first security requirement of op
+// (or of the document) whose schemes can all be mapped.
+func (d *apiDoc) applySecurity(pkg *Package, op map[string]any, prefix string) {
+	reqs, ok := op["security"].([]any)
+	if !ok {
+		reqs, _ = d.root["security"].([]any)
+	}
+	var schemes map[string]any
+	if d.swagger {
+		schemes = asMap(d.root["securityDefinitions"])
+	} else {
+		schemes = asMap(asMap(d.root["components"])["securitySchemes"])
+	}
+	for _, r := range reqs {
+		req := asMap(r)
+		trial := *pkg
+		trial.Headers = maps.Clone(pkg.Headers)
+		mapped := true
+		for name, scopes := range req {
+			if !d.applyScheme(&trial, d.resolve(schemes[name]), scopes, prefix) {
+				mapped = false
+				break
+			}
+		}
+		if mapped {
+			*pkg = trial
+			return
+		}
+	}
+	if len(reqs) > 0 {
+		slog.Warn("openapi security scheme not supported; set credentials in headers", "component", "requestpkg", "operation", pkg.Action)
+	}
+}
+
+func (d *apiDoc) applyScheme(pkg *Package, scheme map[string]any, scopes any, prefix string) bool {
+	setHeader := func(name, value, env string) {
+		if pkg.Headers == nil {
+			pkg.Headers = map[string]string{}
+		}
+		pkg.Headers[name] = value
+		pkg.RequiredEnv = appendUnique(pkg.RequiredEnv, env)
+	}
+	switch typ := str(scheme, "type"); {
+	case typ == "http" && strings.EqualFold(str(scheme, "scheme"), "bearer"):
+		setHeader("Authorization", "Bearer {{env."+prefix+"_TOKEN}}", prefix+"_TOKEN")
+	case typ == "basic" || typ == "http" && strings.EqualFold(str(scheme, "scheme"), "basic"):
+		setHeader("Authorization", "Basic {{env."+prefix+"_BASIC_AUTH}}", prefix+"_BASIC_AUTH")
+	case typ == "apiKey" && str(scheme, "in") == "header":
+		setHeader(str(scheme, "name"), "{{env."+prefix+"_API_KEY}}", prefix+"_API_KEY")
+	case typ == "apiKey" && str(scheme, "in") == "query":
+		sep := "?"
+		if strings.Contains(pkg.URL, "?") {
+			sep = "&"
+		}
+		pkg.URL += sep + url.QueryEscape(str(scheme, "name")) + "={{env." + prefix + "_API_KEY}}"
+		pkg.RequiredEnv = appendUnique(pkg.RequiredEnv, prefix+"_API_KEY")
+	case typ == "oauth2":
+		tokenURL := str(asMap(asMap(scheme["flows"])["clientCredentials"]), "tokenUrl")
+		if d.swagger && str(scheme, "flow") == "application" {
+			tokenURL = str(scheme, "tokenUrl")
+		}
+		if tokenURL == "" {
+			return false
+		}
+		var scope []string
+		if list, ok := scopes.([]any); ok {
+			for _, s := range list {
+				scope = append(scope, fmt.Sprint(s))
+			}
+		}
+		pkg.Auth = &Auth{
+			TokenURL:        tokenURL,
+			ClientIDEnv:     prefix + "_CLIENT_ID",
+			ClientSecretEnv: prefix + "_CLIENT_SECRET",
+			Scope:           strings.Join(scope, " "),
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// resolve follows a local $ref ("#/components/schemas/X") and returns the
+// target as a map; other values are returned as maps when they are ones.
+func (d *apiDoc) resolve(v any) map[string]any {
+	m := asMap(v)
+	for i := 0; i < 16 && m != nil; i++ {
+		ref := str(m, "$ref")
+		if ref == "" {
+			return m
+		}
+		path, ok := strings.CutPrefix(ref, "#/")
+		if !ok {
+			return nil // remote refs are not followed
+		}
+		var cur any = d.root
+		for _, part := range strings.Split(path, "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			cur = asMap(cur)[part]
+		}
+		m = asMap(cur)
+	}
+	return m
+}
+
+// asMap returns v as a map with string keys; YAML mappings with non-string
+// keys (such as unquoted status codes) are converted.
+func asMap(v any) map[string]any {
+	switch v := v.(type) {
+	case map[string]any:
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = val
+		}
+		return m
+	}
+	return nil
+}
+
+var nonNameRe = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// sanitizeName makes s usable as an action or {{args.X}} name.
+func sanitizeName(s string) string {
+	return strings.Trim(nonNameRe.ReplaceAllString(s, "_"), "_")
+}
+
+func contains(list []any, s string) bool {
+	for _, v := range list {
+		if fmt.Sprint(v) == s {
+			return true
+		}
+	}
+	return false
+}
+
+func appendUnique(list []string, s string) []string {
+	if slices.Contains(list, s) {
+		return list
+	}
+	return append(list, s)
+}
+
+func str(m map[string]any, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
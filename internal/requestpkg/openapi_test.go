@@ -0,0 +1,249 @@
+package requestpkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const petstoreOAS3 = `
+openapi: 3.0.3
+info:
+  title: Petstore
+servers:
+  - url: https://{region}.pets.example.com/v1
+    variables:
+      region:
+        default: eu
+security:
+  - bearer: []
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        schema: {type: integer}
+    get:
+      operationId: getPet
+      summary: Get a pet
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: array
+            items: {type: string}
+        - name: X-Trace
+          in: header
+          schema: {type: string}
+    put:
+      operationId: updatePet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+  /pets:
+    post:
+      operationId: createPet
+      description: |
+        Create a pet.
+        Longer explanation.
+      security:
+        - oauth: [pets.write]
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+    get:
+      operationId: listPets
+      deprecated: true
+components:
+  securitySchemes:
+    bearer:
+      type: http
+      scheme: bearer
+    oauth:
+      type: oauth2
+      flows:
+        clientCredentials:
+          tokenUrl: https://auth.example.com/token
+          scopes:
+            pets.write: write pets
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        id: {type: integer, readOnly: true}
+        petId: {type: string}
+        name: {type: string, description: Pet name}
+        status: {type: string, enum: [available, sold]}
+`
+
+func TestParseOpenAPI(t *testing.T) {
+	set, err := parseOpenAPI([]byte(petstoreOAS3), "", OpenAPIOptions{Plugin: "pet-store"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.PluginName != "pet-store" || set.Description != "Petstore" {
+		t.Errorf("set = %q %q", set.PluginName, set.Description)
+	}
+	var actions []string
+	byAction := map[string]Package{}
+	for _, p := range set.Packages {
+		actions = append(actions, p.Action)
+		byAction[p.Action] = p
+	}
+	if want := []string{"createPet", "getPet", "updatePet"}; !reflect.DeepEqual(actions, want) {
+		t.Fatalf("actions = %v, want %v (deprecated listPets skipped)", actions, want)
+	}
+
+	get := byAction["getPet"]
+	if get.Method != "GET" || get.URL != "https://eu.pets.example.com/v1/pets/{{args.petId}}?fields={{args.fields}}" {
+		t.Errorf("getPet: %s %s", get.Method, get.URL)
+	}
+	if get.Headers["Authorization"] != "Bearer {{env.PET_STORE_TOKEN}}" || !reflect.DeepEqual(get.RequiredEnv, []string{"PET_STORE_TOKEN"}) {
+		t.Errorf("getPet auth: %v %v", get.Headers, get.RequiredEnv)
+	}
+	if _, ok := get.Headers["X-Trace"]; ok {
+		t.Error("optional header parameter should not be imported")
+	}
+	wantParams := []ParamDefinition{{Name: "petId", Required: true, Type: "integer"}, {Name: "fields", Type: "array:string"}}
+	if !reflect.DeepEqual(get.Parameters, wantParams) {
+		t.Errorf("getPet params = %+v", get.Parameters)
+	}
+
+	update := byAction["updatePet"]
+	wantBody := map[string]any{"petId": "{{args.body_petId}}", "name": "{{args.name}}", "status": "{{args.status}}"}
+	if !reflect.DeepEqual(update.JSONBody, wantBody) {
+		t.Errorf("updatePet json_body = %v", update.JSONBody)
+	}
+	wantParams = []ParamDefinition{
+		{Name: "petId", Required: true, Type: "integer"},
+		{Name: "name", Description: "Pet name", Required: true},
+		{Name: "body_petId"},
+		{Name: "status", Type: "enum:available,sold"},
+	}
+	if !reflect.DeepEqual(update.Parameters, wantParams) {
+		t.Errorf("updatePet params = %+v", update.Parameters)
+	}
+
+	create := byAction["createPet"]
+	if create.Description != "Create a pet." {
+		t.Errorf("createPet description = %q", create.Description)
+	}
+	if create.Headers["Authorization"] != "" || create.Auth == nil {
+		t.Fatalf("createPet should use oauth2: %v %v", create.Headers, create.Auth)
+	}
+	wantAuth := Auth{TokenURL: "https://auth.example.com/token", ClientIDEnv: "PET_STORE_CLIENT_ID", ClientSecretEnv: "PET_STORE_CLIENT_SECRET", Scope: "pets.write"}
+	if *create.Auth != wantAuth {
+		t.Errorf("createPet auth = %+v", *create.Auth)
+	}
+	for _, p := range create.Parameters {
+		if p.Required {
+			t.Errorf("%s: properties of an optional body are not required", p.Name)
+		}
+	}
+}
+
+const issuesSwagger2 = `{
+  "swagger": "2.0",
+  "info": {"title": "Issues"},
+  "host": "api.example.com",
+  "basePath": "/rest",
+  "schemes": ["http"],
+  "securityDefinitions": {"key": {"type": "apiKey", "in": "query", "name": "api_key"}},
+  "security": [{"key": []}],
+  "paths": {
+    "/issues": {
+      "post": {
+        "operationId": "create-issue",
+        "parameters": [
+          {"name": "project", "in": "query", "type": "string", "required": true},
+          {"name": "issue", "in": "body", "required": true, "schema": {"type": "object", "required": ["title"],
+            "properties": {"title": {"type": "string"}, "labels": {"type": "array", "items": {"type": "string"}}}}}
+        ]
+      }
+    },
+    "/issues/{id}/comments": {
+      "post": {
+        "parameters": [
+          {"name": "id", "in": "path", "type": "integer", "required": true},
+          {"name": "text", "in": "body", "schema": {"type": "string"}}
+        ]
+      }
+    }
+  }
+}`
+
+func TestParseSwagger2(t *testing.T) {
+	set, err := parseOpenAPI([]byte(issuesSwagger2), "", OpenAPIOptions{Plugin: "issues", EnvPrefix: "TRACKER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Packages) != 2 {
+		t.Fatalf("packages = %+v", set.Packages)
+	}
+	create := set.Packages[0]
+	if create.Action != "create_issue" || create.URL != "http://api.example.com/rest/issues?project={{args.project}}&api_key={{env.TRACKER_API_KEY}}" {
+		t.Errorf("create: %s %s", create.Action, create.URL)
+	}
+	wantParams := []ParamDefinition{{Name: "project", Required: true}, {Name: "labels", Type: "array:string"}, {Name: "title", Required: true}}
+	if !reflect.DeepEqual(create.Parameters, wantParams) {
+		t.Errorf("create params = %+v", create.Parameters)
+	}
+
+	comment := set.Packages[1]
+	if comment.Action != "post_issues_id_comments" || comment.Body != "{{args.body}}" || comment.JSONBody != nil {
+		t.Errorf("comment: %s body=%q json_body=%v", comment.Action, comment.Body, comment.JSONBody)
+	}
+}
+
+func TestParseOpenAPIErrors(t *testing.T) {
+	_, err := parseOpenAPI([]byte(petstoreOAS3), "", OpenAPIOptions{Plugin: "pets", Operations: []string{"getPet", "deletePet"}})
+	if err == nil || !strings.Contains(err.Error(), "operations not in the spec: deletePet") {
+		t.Errorf("unknown operation: %v", err)
+	}
+	set, err := parseOpenAPI([]byte(petstoreOAS3), "", OpenAPIOptions{Plugin: "pets", Operations: []string{"listPets"}})
+	if err != nil || len(set.Packages) != 1 {
+		t.Errorf("a listed deprecated operation is imported: %v %+v", err, set.Packages)
+	}
+	if _, err := parseOpenAPI([]byte(`{"openapi": "3.1.0", "paths": {}}`), "", OpenAPIOptions{Plugin: "x"}); err == nil || !strings.Contains(err.Error(), "set base_url") {
+		t.Errorf("no servers: %v", err)
+	}
+	if _, err := parseOpenAPI([]byte(`{"info": {}}`), "", OpenAPIOptions{Plugin: "x"}); err == nil {
+		t.Error("a document without a version should be rejected")
+	}
+	if _, err := parseOpenAPI([]byte(petstoreOAS3), "", OpenAPIOptions{}); err == nil {
+		t.Error("the plugin name is required")
+	}
+}
+
+func TestLoadOpenAPI_URL(t *testing.T) {
+	spec := `{"openapi": "3.0.0", "servers": [{"url": "/api/v2"}],
+	  "paths": {"/status": {"get": {"operationId": "status"}}}}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/docs/openapi.json" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(spec))
+	}))
+	defer srv.Close()
+
+	set, err := LoadOpenAPI(context.Background(), srv.URL+"/docs/openapi.json", OpenAPIOptions{Plugin: "svc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Packages) != 1 || set.Packages[0].URL != srv.URL+"/api/v2/status" {
+		t.Errorf("packages = %+v", set.Packages)
+	}
+	if _, err := LoadOpenAPI(context.Background(), srv.URL+"/missing.json", OpenAPIOptions{Plugin: "svc"}); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("missing spec: %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -21,16 +22,82 @@ import (
 type MCPServerConfig = pkgrpkg.MCPServerConfig
 
 // Package defines a single request package (skill-style): an HTTP request
-// with URL/body/headers templated with {{env.X}} and {{args.Y}}.
+// with URL/body/headers templated with {{env.X}}, {{config.X}} and
+// {{args.Y}}, or with
+// type graphql a GraphQL query with templated variables.
 type Package struct {
 	Action      string            `yaml:"action"`       // action name, e.g. create_issue
 	Description string            `yaml:"description"`  // for capability
-	Method      string            `yaml:"method"`       // GET, POST, etc.
+	Type        string            `yaml:"type"`         // http (default) or graphql
+	Method      string            `yaml:"method"`       // GET, POST, etc.; graphql default POST
 	URL         string            `yaml:"url"`          // template: {{env.JIRA_URL}}/rest/api/3/issue
-	Body        string            `yaml:"body"`         // optional JSON/body template
+	Body        string            `yaml:"body"`         // optional JSON/body template; not used with graphql
+	JSONBody    map[string]any    `yaml:"json_body"`    // optional; JSON body with typed {{args.X}} values, instead of body
+	Query       string            `yaml:"query"`        // graphql: the query or mutation, sent as written
+	Variables   map[string]any    `yaml:"variables"`    // graphql: name -> template ("{{args.first}}") or literal value
 	Headers     map[string]string `yaml:"headers"`      // optional, values are templates
 	RequiredEnv []string          `yaml:"required_env"` // e.g. ["JIRA_URL", "JIRA_API_TOKEN"]
 	Parameters  []ParamDefinition `yaml:"parameters"`   // for capability; name, description, required
+	Response    *Response         `yaml:"response"`     // optional; extraction and message templates for the result
+	Steps       []Step            `yaml:"steps"`        // optional; requests run first, their values used as {{steps.NAME}}
+	Paginate    *Pagination       `yaml:"paginate"`     // optional; fetch further pages of the result
+	Auth        *Auth             `yaml:"auth"`         // optional; OAuth2 token for every request of the action (default: the set's)
+
+	Timeout      string `yaml:"timeout"`       // per request, e.g. "10s"; default 30s
+	Retries      int    `yaml:"retries"`       // extra attempts after a transport error or a retry_on status; default 0, at most 10
+	RetryBackoff string `yaml:"retry_backoff"` // first wait, doubled per retry (Retry-After wins); default 1s
+	RetryOn      []int  `yaml:"retry_on"`      // statuses to retry; default 429, 500, 502, 503, 504
+	RateLimit    int    `yaml:"rate_limit"`    // max requests per minute for this action, steps and pages included; 0 = unlimited
+
+	ReadOnly bool   `yaml:"read_only"` // the request changes nothing (a lookup); no confirmation, and its result may be cached
+	CacheTTL string `yaml:"cache_ttl"` // how long the tool cache may reuse the result, e.g. "5m"; needs read_only
+
+	// InjectContextArgs names host-provided args (see package contextargs)
+	// added to every call, usable as {{args.NAME}}; e.g. idempotency_key
+	// for an Idempotency-Key header so a redelivered message cannot
+	// create the same issue twice.
+	InjectContextArgs []string `yaml:"inject_context_args"`
+}
+
+// Validate checks the package type and the response, steps, auth and
+// paginate sections.
+func (p Package) Validate() error {
+	switch p.Type {
+	case "", TypeHTTP:
+		if p.Query != "" || len(p.Variables) > 0 {
+			return fmt.Errorf("query and variables need type: graphql")
+		}
+	case TypeGraphQL:
+		if p.Query == "" {
+			return fmt.Errorf("type graphql needs a query")
+		}
+		if p.Body != "" || p.JSONBody != nil {
+			return fmt.Errorf("type graphql builds the body from query and variables; remove body")
+		}
+	default:
+		return fmt.Errorf("type must be http or graphql, got %q", p.Type)
+	}
+	if p.Body != "" && p.JSONBody != nil {
+		return fmt.Errorf("set body or json_body, not both")
+	}
+	if err := p.Response.Validate(); err != nil {
+		return err
+	}
+	for i, s := range p.Steps {
+		if s.URL == "" {
+			return fmt.Errorf("step %s: url is required", s.label(i))
+		}
+		if err := validateExtract("steps."+s.label(i)+".extract", s.Extract); err != nil {
+			return err
+		}
+	}
+	if err := p.Auth.Validate(); err != nil {
+		return err
+	}
+	if err := p.validatePolicy(); err != nil {
+		return err
+	}
+	return p.Paginate.Validate()
 }
 
 // ParamDefinition describes one argument (for capability and docs).
@@ -38,24 +105,43 @@ type ParamDefinition struct {
 	Name        string `yaml:"name"`
 	Description string `yaml:"description"`
 	Required    bool   `yaml:"required"`
+	Type        string `yaml:"type,omitempty"` // e.g. "integer", "enum:open,closed", "array:string"; empty = string
 }
 
 // Set groups request packages by plugin name. Each plugin has a list of actions (packages).
 // If MCP is non-nil, this set is handled by the opentalon-mcp plugin binary instead of
 // the built-in HTTP executor; Packages is ignored in that case.
 type Set struct {
-	PluginName    string           `yaml:"plugin"` // e.g. jira, or "mcp"
+	PluginName    string           `yaml:"plugin"`            // e.g. jira, or "mcp"
+	Alias         string           `yaml:"alias,omitempty"`   // registered name when another plugin already uses PluginName
+	Version       string           `yaml:"version,omitempty"` // shown next to the name in the system prompt
 	Description   string           `yaml:"description"`
 	Packages      []Package        `yaml:"packages"`
 	MCP           *MCPServerConfig `yaml:"mcp,omitempty"`
 	AllowedGroups []string         `yaml:"groups,omitempty"` // restrict to these profile groups; empty = unrestricted
+	Auth          *Auth            `yaml:"auth,omitempty"`   // default auth for packages without their own
+	// Config holds the set's {{config.X}} values, so its templates need not
+	// read credentials and endpoints from the process environment. ${VAR}
+	// and file:<path> values are resolved when the set is loaded.
+	Config map[string]string `yaml:"config,omitempty"`
 }
 
 var (
-	envRe  = regexp.MustCompile(`\{\{env\.(\w+)\}\}`)
-	argsRe = regexp.MustCompile(`\{\{args\.(\w+)\}\}`)
+	envRe    = regexp.MustCompile(`\{\{env\.(\w+)\}\}`)
+	argsRe   = regexp.MustCompile(`\{\{args\.(\w+)\}\}`)
+	stepsRe  = regexp.MustCompile(`\{\{steps\.(\w+)\}\}`)
+	configRe = regexp.MustCompile(`\{\{config\.(\w+)\}\}`)
 )
 
+// Name is the plugin name the set's actions are registered under: its
+// alias when it has one.
+func (s Set) Name() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.PluginName
+}
+
 // Substitute replaces {{env.X}} and {{args.Y}} in s. Missing env vars are empty; missing args are left as literal.
 func Substitute(s string, args map[string]string) string {
 	return substitute(s, args, false)
@@ -70,8 +156,7 @@ func SubstituteJSON(s string, args map[string]string) string {
 func substitute(s string, args map[string]string, jsonEscape bool) string {
 	escape := func(v string) string {
 		if jsonEscape {
-			b, _ := json.Marshal(v)
-			return string(b[1 : len(b)-1]) // strip surrounding quotes from json.Marshal
+			return escapeJSON(v)
 		}
 		return v
 	}
@@ -89,6 +174,41 @@ func substitute(s string, args map[string]string, jsonEscape bool) string {
 	return s
 }
 
+// substituteConfig replaces {{config.X}} with the set's config values;
+// unknown keys become empty, like unset env vars.
+func substituteConfig(s string, values map[string]string, jsonEscape bool) string {
+	if !strings.Contains(s, "{{config.") {
+		return s
+	}
+	return configRe.ReplaceAllStringFunc(s, func(match string) string {
+		v := values[configRe.FindStringSubmatch(match)[1]]
+		if jsonEscape {
+			return escapeJSON(v)
+		}
+		return v
+	})
+}
+
+// substituteSteps replaces {{steps.NAME}} with the values extracted by a
+// package's steps; unknown names become empty.
+func substituteSteps(s string, values map[string]any, jsonEscape bool) string {
+	if !strings.Contains(s, "{{steps.") {
+		return s
+	}
+	return stepsRe.ReplaceAllStringFunc(s, func(match string) string {
+		v := formatValue(values[stepsRe.FindStringSubmatch(match)[1]])
+		if jsonEscape {
+			return escapeJSON(v)
+		}
+		return v
+	})
+}
+
+func escapeJSON(v string) string {
+	b, _ := json.Marshal(v)
+	return string(b[1 : len(b)-1]) // strip surrounding quotes from json.Marshal
+}
+
 // cleanURLParams removes query parameters whose values still contain
 // unsubstituted {{args.X}} templates. This allows request packages to
 // define optional query parameters that are only included when the
@@ -156,22 +276,35 @@ func cleanJSONBody(s string) string {
 type Executor struct {
 	pluginName string
 	packages   map[string]Package
+	policies   map[string]*callPolicy
 	client     *http.Client
+	tokens     *tokenCache
+	config     map[string]string
 }
 
 // NewExecutor builds an executor for the given plugin and packages.
 func NewExecutor(pluginName string, packages []Package) *Executor {
 	pm := make(map[string]Package)
+	policies := make(map[string]*callPolicy)
 	for _, p := range packages {
 		pm[p.Action] = p
+		policies[p.Action] = newCallPolicy(p)
 	}
 	return &Executor{
 		pluginName: pluginName,
 		packages:   pm,
-		client:     &http.Client{Timeout: 30 * time.Second},
+		policies:   policies,
+		client:     &http.Client{}, // timeouts are per request, see callPolicy
+		tokens:     newTokenCache(),
 	}
 }
 
+// WithConfig sets the values {{config.X}} expands to (Set.Config).
+func (e *Executor) WithConfig(values map[string]string) *Executor {
+	e.config = values
+	return e
+}
+
 // Execute runs the request package for call.Action and returns a ToolResult.
 // If the context carries a Profile, {{profile.token}} in any URL, header, or body
 // template is replaced with the profile's bearer token before other substitutions.
@@ -193,67 +326,66 @@ func (e *Executor) Execute(ctx context.Context, call orchestrator.ToolCall) orch
 		}
 	}
 
-	// Resolve profile token once; used to expand {{profile.token}} in templates.
-	profileToken := ""
+	t := &templater{args: call.Args, config: e.config, policy: e.policies[call.Action]}
 	if p := profile.FromContext(ctx); p != nil {
-		profileToken = p.Token
+		t.token = p.Token
 	}
-	injectToken := func(s string) string {
-		if !strings.Contains(s, "{{profile.token}}") {
-			return s
+	for i, step := range pkg.Steps {
+		if err := e.runStep(ctx, step, t); err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("step %s: %v", step.label(i), err)}
 		}
-		return strings.ReplaceAll(s, "{{profile.token}}", profileToken)
 	}
 
-	url := encodeURLParams(cleanURLParams(Substitute(injectToken(pkg.URL), call.Args)))
-	if url == "" {
-		return orchestrator.ToolResult{CallID: call.ID, Error: "URL is empty after substitution"}
+	var req outgoing
+	var err error
+	switch {
+	case pkg.Type == TypeGraphQL:
+		req, err = t.graphQLRequest(pkg)
+	case pkg.JSONBody != nil:
+		req, err = t.jsonRequest(pkg)
+	default:
+		req, err = t.request(pkg.Method, pkg.URL, pkg.Body, pkg.Headers)
 	}
-
-	req, err := http.NewRequestWithContext(ctx, strings.ToUpper(pkg.Method), url, nil)
 	if err != nil {
-		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("build request: %v", err)}
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
 	}
-
-	for k, v := range pkg.Headers {
-		req.Header.Set(k, Substitute(injectToken(v), call.Args))
+	status, body, header, err := e.fetchPages(ctx, req, pkg.Paginate)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
 	}
-
-	if pkg.Body != "" {
-		// Use JSON-escaping for JSON bodies to handle quotes/newlines in values.
-		ct := req.Header.Get("Content-Type")
-		isJSON := strings.EqualFold(ct, "application/json") || strings.Contains(ct, "json")
-		var body string
-		if isJSON {
-			body = SubstituteJSON(injectToken(pkg.Body), call.Args)
-			body = cleanJSONBody(body)
-		} else {
-			body = Substitute(injectToken(pkg.Body), call.Args)
-		}
-		req.Body = io.NopCloser(strings.NewReader(body))
-		req.ContentLength = int64(len(body))
-		if req.Header.Get("Content-Type") == "" {
-			req.Header.Set("Content-Type", "application/json")
+	failed := !isSuccess(status)
+	if pkg.Type == TypeGraphQL && !failed {
+		if msg := graphQLErrors(body); msg != "" {
+			if pkg.Response == nil || pkg.Response.Error == "" {
+				return orchestrator.ToolResult{CallID: call.ID, Error: msg}
+			}
+			failed = true
 		}
 	}
-
-	resp, err := e.client.Do(req)
-	if err != nil {
-		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("request failed: %v", err)}
+	if pkg.Response != nil {
+		text, err := pkg.Response.shape(failed, status, body, call.Args, e.config, t.steps)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		if failed {
+			return orchestrator.ToolResult{CallID: call.ID, Error: text}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: text}
 	}
-	defer func() { _ = resp.Body.Close() }()
-
-	body, _ := io.ReadAll(resp.Body)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+	if failed {
 		return orchestrator.ToolResult{
 			CallID: call.ID,
-			Error:  fmt.Sprintf("HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body)),
+			Error:  fmt.Sprintf("HTTP %d: %s", status, bytes.TrimSpace(body)),
 		}
 	}
+	if pkg.Type == TypeGraphQL {
+		return orchestrator.ToolResult{CallID: call.ID, Content: graphQLData(body)}
+	}
 
-	// Try to extract issue key/link from JSON for friendlier output
+	// Without a response section, keep the original shortcut for Jira-style
+	// create responses: report the issue key/link instead of the raw JSON.
 	content := string(body)
-	if resp.Header.Get("Content-Type") == "application/json" && len(body) > 0 {
+	if header.Get("Content-Type") == "application/json" && len(body) > 0 {
 		var m map[string]interface{}
 		if json.Unmarshal(body, &m) == nil {
 			if key, _ := m["key"].(string); key != "" {
@@ -273,26 +405,134 @@ func (e *Executor) Execute(ctx context.Context, call orchestrator.ToolCall) orch
 	}
 }
 
+// templater expands the placeholders of one call: {{profile.token}} first,
+// then {{config.X}}, then {{env.X}} and {{args.Y}}, then {{steps.NAME}}.
+// Values extracted from API responses go in last so they are never expanded
+// themselves.
+type templater struct {
+	args   map[string]string
+	config map[string]string
+	token  string
+	steps  map[string]any
+	policy *callPolicy
+}
+
+func (t *templater) expand(s string, jsonEscape bool) string {
+	if strings.Contains(s, "{{profile.token}}") {
+		s = strings.ReplaceAll(s, "{{profile.token}}", t.token)
+	}
+	s = substituteConfig(s, t.config, jsonEscape)
+	return substituteSteps(substitute(s, t.args, jsonEscape), t.steps, jsonEscape)
+}
+
+// outgoing is a templated request, ready to send (or to page through).
+type outgoing struct {
+	method string
+	url    string
+	body   string
+	header http.Header
+	policy *callPolicy
+}
+
+// request expands the templates of one request definition.
+func (t *templater) request(method, rawURL, body string, headers map[string]string) (outgoing, error) {
+	u := encodeURLParams(cleanURLParams(t.expand(rawURL, false)))
+	if u == "" {
+		return outgoing{}, errors.New("URL is empty after substitution")
+	}
+	out := outgoing{method: strings.ToUpper(method), url: u, header: http.Header{}, policy: t.policy}
+	for k, v := range headers {
+		// A header templated on an arg the call did not get (an injected
+		// context arg that was empty) is left out rather than sent as a
+		// literal template.
+		if hv := t.expand(v, false); !strings.Contains(hv, "{{args.") {
+			out.header.Set(k, hv)
+		}
+	}
+	if body != "" {
+		// Use JSON-escaping for JSON bodies to handle quotes/newlines in values.
+		ct := out.header.Get("Content-Type")
+		if strings.EqualFold(ct, "application/json") || strings.Contains(ct, "json") {
+			out.body = cleanJSONBody(t.expand(body, true))
+		} else {
+			out.body = t.expand(body, false)
+		}
+		if ct == "" {
+			out.header.Set("Content-Type", "application/json")
+		}
+	}
+	return out, nil
+}
+
+// sendOnce performs one request and reads the whole response, within the
+// action's timeout and rate limit.
+func (e *Executor) sendOnce(ctx context.Context, o outgoing, freshToken bool) (int, []byte, http.Header, error) {
+	var body io.Reader
+	if o.body != "" {
+		body = strings.NewReader(o.body)
+	}
+	var token string
+	if o.policy.auth != nil {
+		var err error
+		if token, err = e.tokens.token(ctx, e.client, o.policy.auth, freshToken); err != nil {
+			return 0, nil, nil, err
+		}
+	}
+	if err := o.policy.limiter.wait(ctx); err != nil {
+		return 0, nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, o.policy.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, o.method, o.url, body)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header = o.header.Clone()
+	if o.policy.auth != nil {
+		o.policy.auth.apply(req.Header, token)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", o.policy.timeout)
+		}
+		return 0, nil, nil, &transportError{err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, &transportError{fmt.Errorf("read response: %w", err)}
+	}
+	return resp.StatusCode, data, resp.Header, nil
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
 // ToCapability converts the package set into an orchestrator.PluginCapability for registration.
 func ToCapability(set Set) orchestrator.PluginCapability {
 	actions := make([]orchestrator.Action, 0, len(set.Packages))
 	for _, p := range set.Packages {
 		params := make([]orchestrator.Parameter, 0, len(p.Parameters))
 		for _, q := range p.Parameters {
-			params = append(params, orchestrator.Parameter{
-				Name:        q.Name,
-				Description: q.Description,
-				Required:    q.Required,
-			})
+			params = append(params, orchestrator.ParameterFromWire(q.Name, q.Description, q.Type, q.Required))
 		}
+		ttl, _ := time.ParseDuration(p.CacheTTL) // checked by Validate
 		actions = append(actions, orchestrator.Action{
 			Name:        p.Action,
 			Description: p.Description,
 			Parameters:  params,
+			ReadOnly:    p.ReadOnly,
+			CacheTTL:    ttl,
+
+			InjectContextArgs: p.InjectContextArgs,
 		})
 	}
 	return orchestrator.PluginCapability{
-		Name:          set.PluginName,
+		Name:          set.Name(),
+		Version:       set.Version,
 		Description:   set.Description,
 		Actions:       actions,
 		AllowedGroups: set.AllowedGroups,
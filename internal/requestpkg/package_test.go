@@ -2,6 +2,7 @@ package requestpkg
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -201,6 +202,72 @@ func TestExecutor_Execute(t *testing.T) {
 	}
 }
 
+func TestExecutor_Execute_Config(t *testing.T) {
+	var gotAuth, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		_, _ = w.Write([]byte(`{"id": 7}`))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("tracker", []Package{{
+		Action:   "create",
+		Method:   "POST",
+		URL:      "{{config.base_url}}/items",
+		Body:     `{"project": "{{config.project}}", "title": "{{args.title}}"}`,
+		Headers:  map[string]string{"Authorization": "Bearer {{config.token}}", "Content-Type": "application/json"},
+		Response: &Response{Extract: map[string]string{"id": "id"}, Success: "Created {{response.id}} in {{config.project}}"},
+	}}).WithConfig(map[string]string{"base_url": srv.URL, "token": "t0k", "project": `O"PS`})
+
+	result := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "create", Args: map[string]string{"title": "x"}})
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if gotAuth != "Bearer t0k" {
+		t.Errorf("Authorization = %q", gotAuth)
+	}
+	if gotBody != `{"project":"O\"PS","title":"x"}` {
+		t.Errorf("body = %s; config values must be JSON-escaped", gotBody)
+	}
+	if result.Content != `Created 7 in O"PS` {
+		t.Errorf("Content = %q", result.Content)
+	}
+}
+
+func TestExecutor_Execute_IdempotencyHeader(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v, ok := r.Header["Idempotency-Key"]; ok {
+			got = append(got, v[0])
+		} else {
+			got = append(got, "<absent>")
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	set := Set{PluginName: "tracker", Packages: []Package{{
+		Action:            "create",
+		Method:            "POST",
+		URL:               srv.URL + "/items",
+		Headers:           map[string]string{"Idempotency-Key": "{{args.idempotency_key}}"},
+		InjectContextArgs: []string{"idempotency_key"},
+	}}}
+	if c := ToCapability(set); len(c.Actions[0].InjectContextArgs) != 1 {
+		t.Fatalf("inject_context_args not on the capability: %+v", c.Actions[0])
+	}
+	exec := NewExecutor("tracker", set.Packages)
+	exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "create", Args: map[string]string{"idempotency_key": "k1"}})
+	// No key injected (the turn had no message id): the header is left out,
+	// not sent as a literal template.
+	exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "create"})
+	if len(got) != 2 || got[0] != "k1" || got[1] != "<absent>" {
+		t.Errorf("Idempotency-Key headers = %q; want [k1 <absent>]", got)
+	}
+}
+
 func TestExecutor_Execute_RequiredEnv(t *testing.T) {
 	_ = os.Unsetenv("MISSING_VAR")
 	exec := NewExecutor("test", []Package{
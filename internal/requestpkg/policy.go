@@ -0,0 +1,202 @@
+package requestpkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout      = 30 * time.Second
+	defaultRetryBackoff = time.Second
+	maxRetryDelay       = 30 * time.Second
+	maxRetries          = 10
+	rateWindow          = time.Minute
+)
+
+// defaultRetryOn are the statuses retried when retry_on is not set: rate
+// limiting and transient server errors. Other 4xx are permanent.
+var defaultRetryOn = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// callPolicy is how every request of one action is sent: steps, pages and
+// retries alike. It is built once per action by NewExecutor.
+type callPolicy struct {
+	auth    *Auth
+	timeout time.Duration
+	retries int
+	backoff time.Duration
+	retryOn []int
+	limiter *rateLimiter
+}
+
+// newCallPolicy reads the transport settings of p. Invalid durations fall
+// back to the defaults; LoadDir and the config loader reject them earlier.
+func newCallPolicy(p Package) *callPolicy {
+	cp := &callPolicy{auth: p.Auth, timeout: defaultTimeout, retries: p.Retries, backoff: defaultRetryBackoff, retryOn: p.RetryOn}
+	if d, err := time.ParseDuration(p.Timeout); err == nil && d > 0 {
+		cp.timeout = d
+	}
+	if d, err := time.ParseDuration(p.RetryBackoff); err == nil && d > 0 {
+		cp.backoff = d
+	}
+	if len(cp.retryOn) == 0 {
+		cp.retryOn = defaultRetryOn
+	}
+	if p.RateLimit > 0 {
+		cp.limiter = newRateLimiter(p.RateLimit)
+	}
+	return cp
+}
+
+// validatePolicy checks timeout, retries, retry_backoff, retry_on and
+// rate_limit.
+func (p Package) validatePolicy() error {
+	for name, v := range map[string]string{"timeout": p.Timeout, "retry_backoff": p.RetryBackoff, "cache_ttl": p.CacheTTL} {
+		if v == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(v); err != nil || d <= 0 {
+			return fmt.Errorf("%s: invalid duration %q", name, v)
+		}
+	}
+	if p.Retries < 0 || p.Retries > maxRetries {
+		return fmt.Errorf("retries must be between 0 and %d", maxRetries)
+	}
+	for _, code := range p.RetryOn {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("retry_on: %d is not an HTTP status", code)
+		}
+	}
+	if p.RateLimit < 0 {
+		return fmt.Errorf("rate_limit must not be negative")
+	}
+	if p.CacheTTL != "" && !p.ReadOnly {
+		return fmt.Errorf("cache_ttl needs read_only: true; only lookups are cached")
+	}
+	return nil
+}
+
+// transportError is a request that got no response; it is retried like a
+// retry_on status.
+type transportError struct{ err error }
+
+func (e *transportError) Error() string { return "request failed: " + e.err.Error() }
+func (e *transportError) Unwrap() error { return e.err }
+
+func (cp *callPolicy) shouldRetry(status int, err error) bool {
+	if err != nil {
+		var te *transportError
+		return errors.As(err, &te)
+	}
+	return slices.Contains(cp.retryOn, status)
+}
+
+// delay is the wait before retry n (1-based): the server's Retry-After in
+// seconds when it sent one, else backoff doubled per retry, capped at
+// maxRetryDelay either way.
+func (cp *callPolicy) delay(n int, header http.Header) time.Duration {
+	if ra := strings.TrimSpace(header.Get("Retry-After")); ra != "" {
+		if secs, err := strconv.Atoi(ra); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxRetryDelay)
+		}
+	}
+	d := cp.backoff << (n - 1)
+	if d <= 0 || d > maxRetryDelay {
+		return maxRetryDelay
+	}
+	return d
+}
+
+// send performs o under its policy: rate limited, with a timeout per
+// attempt, and retried on a transport error or a retry_on status.
+func (e *Executor) send(ctx context.Context, o outgoing) (int, []byte, http.Header, error) {
+	cp := o.policy
+	status, body, header, err := e.attempt(ctx, o)
+	for n := 1; n <= cp.retries && cp.shouldRetry(status, err) && ctx.Err() == nil; n++ {
+		delay := cp.delay(n, header)
+		reason := strconv.Itoa(status)
+		if err != nil {
+			reason = err.Error()
+		}
+		slog.Warn("request package retry", "component", "requestpkg", "url", redactQuery(o.url), "attempt", n, "delay", delay.String(), "reason", reason)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return 0, nil, nil, &transportError{ctx.Err()}
+		}
+		status, body, header, err = e.attempt(ctx, o)
+	}
+	return status, body, header, err
+}
+
+// attempt sends o once. With auth set, a 401 renews the access token and
+// the request is sent once more.
+func (e *Executor) attempt(ctx context.Context, o outgoing) (int, []byte, http.Header, error) {
+	status, body, header, err := e.sendOnce(ctx, o, false)
+	if err == nil && status == http.StatusUnauthorized && o.policy.auth != nil {
+		return e.sendOnce(ctx, o, true)
+	}
+	return status, body, header, err
+}
+
+// redactQuery drops the query string, which may carry keys or user data,
+// from a URL before it is logged.
+func redactQuery(u string) string {
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		return u[:i]
+	}
+	return u
+}
+
+// rateLimiter allows at most limit requests in any window (one minute),
+// making callers wait for the oldest request to leave the window.
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  int
+	window time.Duration
+	sent   []time.Time // send times inside the window, oldest first
+}
+
+func newRateLimiter(perMinute int) *rateLimiter {
+	return &rateLimiter{limit: perMinute, window: rateWindow}
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		expired := 0
+		for expired < len(l.sent) && now.Sub(l.sent[expired]) >= l.window {
+			expired++
+		}
+		l.sent = l.sent[expired:]
+		if len(l.sent) < l.limit {
+			l.sent = append(l.sent, now)
+			l.mu.Unlock()
+			return nil
+		}
+		d := l.sent[0].Add(l.window).Sub(now)
+		l.mu.Unlock()
+		select {
+		case <-time.After(d):
+		case <-ctx.Done():
+			return fmt.Errorf("rate limit: %w", ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package requestpkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+// flakyServer fails the first `failures` requests with status, then
+// answers "ok".
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte("busy"))
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestExecutor_Retries(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	exec := NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Retries: 2}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"})
+	if res.Error != "" || res.Content != "ok" || calls.Load() != 3 {
+		t.Errorf("result = %+v after %d calls", res, calls.Load())
+	}
+
+	srv, calls = flakyServer(t, 5, http.StatusServiceUnavailable)
+	exec = NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Retries: 1}})
+	res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "get"})
+	if res.Error != "HTTP 503: busy" || calls.Load() != 2 {
+		t.Errorf("exhausted: %+v after %d calls", res, calls.Load())
+	}
+
+	srv, calls = flakyServer(t, 1, http.StatusBadRequest)
+	exec = NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Retries: 3}})
+	if res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "3", Action: "get"}); calls.Load() != 1 {
+		t.Errorf("400 is not in the default retry_on, got %d calls (%+v)", calls.Load(), res)
+	}
+
+	srv, calls = flakyServer(t, 1, http.StatusConflict)
+	exec = NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Retries: 1, RetryOn: []int{http.StatusConflict}}})
+	if res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "4", Action: "get"}); res.Content != "ok" {
+		t.Errorf("retry_on 409: %+v", res)
+	}
+}
+
+func TestExecutor_TimeoutIsRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Timeout: "50ms"}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"})
+	if res.Error != "request failed: timed out after 50ms" {
+		t.Errorf("no retries: %+v", res)
+	}
+
+	calls.Store(0)
+	exec = NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, Timeout: "50ms", Retries: 1, RetryBackoff: "1ms"}})
+	res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "get"})
+	if res.Error != "" || res.Content != "ok" {
+		t.Errorf("with a retry: %+v", res)
+	}
+}
+
+func TestExecutor_RateLimit(t *testing.T) {
+	srv, _ := flakyServer(t, 0, 0)
+	exec := NewExecutor("svc", []Package{{Action: "get", URL: srv.URL, RateLimit: 2}})
+	exec.policies["get"].limiter.window = 150 * time.Millisecond
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "get"}); res.Error != "" {
+			t.Fatal(res.Error)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("third request went out after %v, want it held for the window", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	exec.Execute(ctx, orchestrator.ToolCall{ID: "2", Action: "get"})
+	if res := exec.Execute(ctx, orchestrator.ToolCall{ID: "3", Action: "get"}); res.Error != "rate limit: context deadline exceeded" {
+		t.Errorf("waiting past the deadline: %+v", res)
+	}
+}
+
+func TestCallPolicyDelay(t *testing.T) {
+	cp := newCallPolicy(Package{RetryBackoff: "2s"})
+	for n, want := range map[int]time.Duration{1: 2 * time.Second, 2: 4 * time.Second, 5: maxRetryDelay, 70: maxRetryDelay} {
+		if got := cp.delay(n, http.Header{}); got != want {
+			t.Errorf("delay(%d) = %v, want %v", n, got, want)
+		}
+	}
+	if got := cp.delay(1, http.Header{"Retry-After": {"7"}}); got != 7*time.Second {
+		t.Errorf("Retry-After: %v", got)
+	}
+	if got := cp.delay(1, http.Header{"Retry-After": {"3600"}}); got != maxRetryDelay {
+		t.Errorf("large Retry-After: %v", got)
+	}
+}
+
+func TestPackageValidatePolicy(t *testing.T) {
+	bad := []Package{
+		{Timeout: "fast"},
+		{RetryBackoff: "-1s"},
+		{Retries: -1},
+		{Retries: maxRetries + 1},
+		{RetryOn: []int{42}},
+		{RateLimit: -5},
+		{CacheTTL: "5m"},
+		{ReadOnly: true, CacheTTL: "later"},
+	}
+	for i, p := range bad {
+		if p.Validate() == nil {
+			t.Errorf("package %d should not validate", i)
+		}
+	}
+	if err := (Package{Timeout: "5s", Retries: 3, RetryBackoff: "200ms", RetryOn: []int{409}, RateLimit: 30, ReadOnly: true, CacheTTL: "5m"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	capability := ToCapability(Set{PluginName: "jira", Packages: []Package{{Action: "get_issue", ReadOnly: true, CacheTTL: "5m"}}})
+	if a := capability.Actions[0]; !a.ReadOnly || a.CacheTTL != 5*time.Minute {
+		t.Errorf("action = %+v", a)
+	}
+}
@@ -0,0 +1,220 @@
+package requestpkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Response shapes what a package returns to the LLM instead of the raw
+// body. Extract pulls named values out of the JSON response; Success and
+// Error are templates rendered for 2xx and other statuses. Templates may use
+// {{response.NAME}} (an extracted value), {{status}}, {{body}} (the raw
+// response), {{env.X}} and {{args.Y}}.
+type Response struct {
+	// Extract maps a name to a path into the JSON body: "key",
+	// "$.fields.summary", "issues[0].key", "items[-1].id" (last element),
+	// "data[*].name" (every element). Strings render as-is, other values as
+	// compact JSON; a path that matches nothing renders empty.
+	Extract map[string]string `yaml:"extract,omitempty"`
+	Success string            `yaml:"success,omitempty"` // e.g. "Created {{response.key}}: {{env.JIRA_URL}}/browse/{{response.key}}"; empty = extracted values as a JSON object, or the raw body
+	Error   string            `yaml:"error,omitempty"`   // e.g. "Jira rejected the issue: {{response.errors}}"; empty = "HTTP <status>: <body>"
+}
+
+// pathStep is one step of an extraction path: a field, an index or [*].
+type pathStep struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+var pathTokenRe = regexp.MustCompile(`^(?:\.?([^.\[\]]+)|\[(-?\d+|\*)\])`)
+
+// parsePath parses a JSONPath-style path: an optional "$", then .field,
+// [n] and [*] steps.
+func parsePath(path string) ([]pathStep, error) {
+	rest := strings.TrimPrefix(strings.TrimSpace(path), "$")
+	if rest == "" {
+		return nil, nil
+	}
+	var steps []pathStep
+	for rest != "" {
+		m := pathTokenRe.FindStringSubmatch(rest)
+		if m == nil {
+			return nil, fmt.Errorf("invalid path %q at %q", path, rest)
+		}
+		switch {
+		case m[1] != "":
+			steps = append(steps, pathStep{field: m[1]})
+		case m[2] == "*":
+			steps = append(steps, pathStep{wildcard: true})
+		default:
+			n, _ := strconv.Atoi(m[2])
+			steps = append(steps, pathStep{index: n, isIndex: true})
+		}
+		rest = rest[len(m[0]):]
+	}
+	return steps, nil
+}
+
+// lookup follows steps from v. After a [*] step the rest of the path is
+// applied to every element and the matches are collected into an array.
+func lookup(v any, steps []pathStep) (any, bool) {
+	for i, s := range steps {
+		switch {
+		case s.wildcard:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			out := make([]any, 0, len(arr))
+			for _, item := range arr {
+				if found, ok := lookup(item, steps[i+1:]); ok {
+					out = append(out, found)
+				}
+			}
+			return out, true
+		case s.isIndex:
+			arr, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			idx := s.index
+			if idx < 0 {
+				idx += len(arr)
+			}
+			if idx < 0 || idx >= len(arr) {
+				return nil, false
+			}
+			v = arr[idx]
+		default:
+			obj, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = obj[s.field]; !ok {
+				return nil, false
+			}
+		}
+	}
+	return v, true
+}
+
+// Validate reports the first extraction path that does not parse.
+func (r *Response) Validate() error {
+	if r == nil {
+		return nil
+	}
+	return validateExtract("response.extract", r.Extract)
+}
+
+func validateExtract(prefix string, extract map[string]string) error {
+	for name, path := range extract {
+		if _, err := parsePath(path); err != nil {
+			return fmt.Errorf("%s.%s: %w", prefix, name, err)
+		}
+	}
+	return nil
+}
+
+// extractValues evaluates every path in extract against body. A body that
+// is not JSON yields no values; a path that matches nothing is left out.
+func extractValues(extract map[string]string, body []byte) (map[string]any, error) {
+	values := make(map[string]any, len(extract))
+	doc, err := decodeJSON(body)
+	if err != nil {
+		return values, nil
+	}
+	for name, path := range extract {
+		steps, err := parsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if v, ok := lookup(doc, steps); ok {
+			values[name] = v
+		}
+	}
+	return values, nil
+}
+
+// decodeJSON decodes body keeping numbers as json.Number, so large IDs
+// survive being extracted or re-encoded.
+func decodeJSON(body []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+var responseRe = regexp.MustCompile(`\{\{(response\.(\w+)|status|body)\}\}`)
+
+// render fills {{config.X}}, {{env.X}} and {{args.Y}} in tmpl, then the extracted values,
+// {{steps.NAME}}, status and raw body. That order keeps a value returned by
+// the API from being expanded as a template and pulling in environment
+// variables.
+func (r *Response) render(tmpl string, values map[string]any, status int, body []byte, args, config map[string]string, steps map[string]any) string {
+	tmpl = Substitute(substituteConfig(tmpl, config, false), args)
+	tmpl = responseRe.ReplaceAllStringFunc(tmpl, func(match string) string {
+		m := responseRe.FindStringSubmatch(match)
+		switch {
+		case m[1] == "status":
+			return strconv.Itoa(status)
+		case m[1] == "body":
+			return strings.TrimSpace(string(body))
+		default:
+			return formatValue(values[m[2]])
+		}
+	})
+	return substituteSteps(tmpl, steps, false)
+}
+
+// shape builds the tool output for a response: the Success template, or
+// the Error template when the call failed (a non-2xx status, or a GraphQL
+// errors array). config holds the set's config values and steps the values
+// extracted by the package's steps, if any.
+func (r *Response) shape(failed bool, status int, body []byte, args, config map[string]string, steps map[string]any) (string, error) {
+	values, err := extractValues(r.Extract, body)
+	if err != nil {
+		return "", fmt.Errorf("response.extract.%w", err)
+	}
+	tmpl := r.Success
+	if failed {
+		tmpl = r.Error
+		if tmpl == "" {
+			tmpl = "HTTP {{status}}: {{body}}"
+		}
+	}
+	if tmpl != "" {
+		return r.render(tmpl, values, status, body, args, config, steps), nil
+	}
+	if len(r.Extract) == 0 {
+		return string(body), nil
+	}
+	out, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
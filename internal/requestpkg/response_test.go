@@ -0,0 +1,128 @@
+package requestpkg
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+const searchBody = `{"total": 2, "issues": [
+  {"key": "OPS-1", "fields": {"summary": "Disk full", "labels": ["infra"]}},
+  {"key": "OPS-2", "fields": {"summary": "Pager noisy"}}
+]}`
+
+func TestResponseExtract(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"total", "2"},
+		{"$.issues[0].key", "OPS-1"},
+		{"issues[-1].fields.summary", "Pager noisy"},
+		{"issues[*].key", `["OPS-1","OPS-2"]`},
+		{"issues[*].fields.labels", `[["infra"]]`},
+		{"issues[0].fields", `{"labels":["infra"],"summary":"Disk full"}`},
+		{"issues[5].key", ""},
+		{"missing.field", ""},
+	}
+	for _, tt := range tests {
+		r := &Response{Extract: map[string]string{"v": tt.path}, Success: "{{response.v}}"}
+		got, err := r.shape(false, 200, []byte(searchBody), nil, nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.path, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResponseValidate(t *testing.T) {
+	for _, path := range []string{"a..b", "a[x]", "a]"} {
+		if (&Response{Extract: map[string]string{"v": path}}).Validate() == nil {
+			t.Errorf("path %q should not validate", path)
+		}
+	}
+	var none *Response
+	if none.Validate() != nil {
+		t.Error("nil response should validate")
+	}
+}
+
+func TestResponseShapeDefaults(t *testing.T) {
+	r := &Response{Extract: map[string]string{"first": "issues[0].key", "n": "total"}}
+	if got, _ := r.shape(false, 200, []byte(searchBody), nil, nil, nil); got != `{"first":"OPS-1","n":2}` {
+		t.Errorf("no success template: %q", got)
+	}
+	if got, _ := r.shape(true, 404, []byte(" not found \n"), nil, nil, nil); got != "HTTP 404: not found" {
+		t.Errorf("no error template: %q", got)
+	}
+	if got, _ := (&Response{}).shape(false, 200, []byte("plain"), nil, nil, nil); got != "plain" {
+		t.Errorf("empty response section: %q", got)
+	}
+}
+
+func TestResponseTemplateDoesNotExpandAPIValues(t *testing.T) {
+	t.Setenv("RESPONSE_TEST_SECRET", "s3cret")
+	r := &Response{Extract: map[string]string{"msg": "msg"}, Success: "{{args.id}}: {{response.msg}}"}
+	got, _ := r.shape(false, 200, []byte(`{"msg": "{{env.RESPONSE_TEST_SECRET}}"}`), map[string]string{"id": "7"}, nil, nil)
+	if got != "7: {{env.RESPONSE_TEST_SECRET}}" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestExecutor_ResponseSection(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("jql") == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errorMessages": ["Field 'x' does not exist."]}`))
+			return
+		}
+		_, _ = w.Write([]byte(searchBody))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("jira", []Package{{
+		Action: "search",
+		Method: "GET",
+		URL:    srv.URL + "/search?jql={{args.jql}}",
+		Response: &Response{
+			Extract: map[string]string{"keys": "issues[*].key", "n": "total", "why": "errorMessages[0]"},
+			Success: "{{response.n}} issues for {{args.jql}}: {{response.keys}}",
+			Error:   "Jira rejected the query ({{status}}): {{response.why}}",
+		},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "search", Args: map[string]string{"jql": "project=OPS"}})
+	if res.Error != "" || res.Content != `2 issues for project=OPS: ["OPS-1","OPS-2"]` {
+		t.Errorf("success = %+v", res)
+	}
+	res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "search", Args: map[string]string{"jql": "bad"}})
+	if res.Content != "" || res.Error != "Jira rejected the query (400): Field 'x' does not exist." {
+		t.Errorf("error = %+v", res)
+	}
+}
+
+func TestLoadDir_RejectsInvalidResponsePath(t *testing.T) {
+	dir := t.TempDir()
+	yaml := `plugin: svc
+packages:
+  - action: get
+    url: https://example.com
+    response:
+      extract:
+        id: "items[x]"
+`
+	if err := os.WriteFile(filepath.Join(dir, "svc.yaml"), []byte(yaml), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err := LoadDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "response.extract.id") {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package requestpkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Step is a request run before the package's own one, e.g. to look up an
+// ID the main request needs. The values it extracts are available to later
+// steps, the main request and the response templates as {{steps.NAME}}.
+type Step struct {
+	Name    string            `yaml:"name,omitempty"` // for error messages; default is the step's position
+	Method  string            `yaml:"method"`
+	URL     string            `yaml:"url"`
+	Body    string            `yaml:"body,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Extract map[string]string `yaml:"extract"` // name -> path into the JSON response, as in response.extract
+}
+
+func (s Step) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return strconv.Itoa(i + 1)
+}
+
+// runStep sends s and adds its extracted values to t.steps. A failed
+// request or a value the response does not contain fails the step, so the
+// main request never runs with a blank ID.
+func (e *Executor) runStep(ctx context.Context, s Step, t *templater) error {
+	req, err := t.request(s.Method, s.URL, s.Body, s.Headers)
+	if err != nil {
+		return err
+	}
+	status, body, _, err := e.send(ctx, req)
+	if err != nil {
+		return err
+	}
+	if !isSuccess(status) {
+		return fmt.Errorf("HTTP %d: %s", status, bytes.TrimSpace(body))
+	}
+	values, err := extractValues(s.Extract, body)
+	if err != nil {
+		return err
+	}
+	for name := range s.Extract {
+		if _, ok := values[name]; !ok {
+			return fmt.Errorf("no value for %s in the response", name)
+		}
+	}
+	if t.steps == nil {
+		t.steps = make(map[string]any, len(values))
+	}
+	maps.Copy(t.steps, values)
+	return nil
+}
+
+// Pagination limits.
+const (
+	defaultMaxPages = 10
+	maxPagesLimit   = 100
+)
+
+// Pagination fetches further pages of the main request and merges them:
+// the array at Items in the first page ends up holding the items of every
+// page. With Next set, each page's cursor is read from the previous
+// response; without it, Param counts up from Start by Increment (page
+// numbers or offsets). Paging stops at the last cursor, at a page with no
+// items, or after MaxPages requests.
+type Pagination struct {
+	Param     string `yaml:"param"`               // query parameter (or body key, with in: body) set for each page
+	In        string `yaml:"in,omitempty"`        // "query" (default), "body" (top-level key of a JSON body) or "variables" (graphql)
+	Next      string `yaml:"next,omitempty"`      // path to the next cursor, e.g. "nextPageToken"; empty = counter mode
+	Start     int    `yaml:"start,omitempty"`     // counter mode: value for the first page (default 0)
+	Increment int    `yaml:"increment,omitempty"` // counter mode: added per page (default 1; the page size for offsets)
+	Items     string `yaml:"items"`               // path to the array of results in a page, e.g. "issues"; "$" = the body itself
+	MaxPages  int    `yaml:"max_pages,omitempty"` // default 10, at most 100
+}
+
+// Validate checks that p names a parameter and valid paths.
+func (p *Pagination) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.Param == "" {
+		return fmt.Errorf("paginate.param is required")
+	}
+	switch p.In {
+	case "", "query", "body", "variables":
+	default:
+		return fmt.Errorf("paginate.in must be query, body or variables, got %q", p.In)
+	}
+	if p.Items == "" {
+		return fmt.Errorf("paginate.items is required")
+	}
+	items, err := parsePath(p.Items)
+	if err != nil {
+		return fmt.Errorf("paginate.items: %w", err)
+	}
+	for _, s := range items {
+		if s.wildcard {
+			return fmt.Errorf("paginate.items: [*] is not allowed")
+		}
+	}
+	if _, err := parsePath(p.Next); err != nil {
+		return fmt.Errorf("paginate.next: %w", err)
+	}
+	if p.MaxPages < 0 || p.MaxPages > maxPagesLimit {
+		return fmt.Errorf("paginate.max_pages must be between 1 and %d", maxPagesLimit)
+	}
+	if p.Increment < 0 {
+		return fmt.Errorf("paginate.increment must not be negative")
+	}
+	return nil
+}
+
+// fetchPages sends req, following pages when p is set. It returns the
+// status, body and headers of the response to report: the merged pages, or
+// the first one that failed.
+func (e *Executor) fetchPages(ctx context.Context, req outgoing, p *Pagination) (int, []byte, http.Header, error) {
+	if p == nil {
+		return e.send(ctx, req)
+	}
+	itemsPath, _ := parsePath(p.Items)
+	nextPath, _ := parsePath(p.Next)
+	maxPages := p.MaxPages
+	if maxPages == 0 {
+		maxPages = defaultMaxPages
+	}
+	increment := p.Increment
+	if increment == 0 {
+		increment = 1
+	}
+
+	var (
+		doc    any
+		items  []any
+		status int
+		header http.Header
+		cursor any = p.Start
+		seen   string
+	)
+	for page := 0; page < maxPages; page++ {
+		if p.Next == "" || page > 0 {
+			var err error
+			if req, err = p.apply(req, cursor); err != nil {
+				return 0, nil, nil, err
+			}
+		}
+		code, body, h, err := e.send(ctx, req)
+		if err != nil || !isSuccess(code) {
+			return code, body, h, err
+		}
+		status, header = code, h
+		pageDoc, err := decodeJSON(body)
+		if err != nil {
+			return 0, nil, nil, fmt.Errorf("paginate: page %d is not JSON", page+1)
+		}
+		found, _ := lookup(pageDoc, itemsPath)
+		arr, ok := found.([]any)
+		if !ok && page == 0 {
+			return 0, nil, nil, fmt.Errorf("paginate: no array at %q in the response", p.Items)
+		}
+		if page == 0 {
+			doc = pageDoc
+		}
+		items = append(items, arr...)
+		if len(arr) == 0 {
+			break
+		}
+		if p.Next == "" {
+			cursor = p.Start + (page+1)*increment
+			continue
+		}
+		next, ok := lookup(pageDoc, nextPath)
+		s := formatValue(next)
+		if !ok || s == "" || s == seen {
+			break
+		}
+		cursor, seen = next, s
+	}
+
+	if items == nil {
+		items = []any{}
+	}
+	if len(itemsPath) == 0 {
+		doc = items
+	} else {
+		setPath(doc, itemsPath, items)
+	}
+	merged, err := json.Marshal(doc)
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("paginate: %w", err)
+	}
+	return status, merged, header, nil
+}
+
+// apply sets the page parameter of req to cursor.
+func (p *Pagination) apply(req outgoing, cursor any) (outgoing, error) {
+	if p.In == "body" || p.In == "variables" {
+		obj := map[string]any{}
+		if req.body != "" {
+			doc, err := decodeJSON([]byte(req.body))
+			if obj, _ = doc.(map[string]any); err != nil || obj == nil {
+				return req, fmt.Errorf("paginate: request body is not a JSON object")
+			}
+		}
+		target := obj
+		if p.In == "variables" {
+			if target, _ = obj["variables"].(map[string]any); target == nil {
+				target = map[string]any{}
+				obj["variables"] = target
+			}
+		}
+		target[p.Param] = cursor
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return req, fmt.Errorf("paginate: %w", err)
+		}
+		req.body = string(b)
+		return req, nil
+	}
+	u, err := url.Parse(req.url)
+	if err != nil {
+		return req, fmt.Errorf("paginate: %w", err)
+	}
+	q := u.Query()
+	q.Set(p.Param, formatValue(cursor))
+	u.RawQuery = q.Encode()
+	req.url = u.String()
+	return req, nil
+}
+
+// setPath replaces the value at steps (fields and indexes only) in doc.
+func setPath(doc any, steps []pathStep, v any) bool {
+	parent, ok := lookup(doc, steps[:len(steps)-1])
+	if !ok {
+		return false
+	}
+	last := steps[len(steps)-1]
+	switch parent := parent.(type) {
+	case map[string]any:
+		if last.isIndex {
+			return false
+		}
+		parent[last.field] = v
+	case []any:
+		if !last.isIndex {
+			return false
+		}
+		idx := last.index
+		if idx < 0 {
+			idx += len(parent)
+		}
+		if idx < 0 || idx >= len(parent) {
+			return false
+		}
+		parent[idx] = v
+	default:
+		return false
+	}
+	return true
+}
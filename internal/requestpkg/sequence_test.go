@@ -0,0 +1,182 @@
+package requestpkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func TestExecutor_PaginateCursor(t *testing.T) {
+	pages := map[string]string{
+		"":   `{"issues": [{"key": "OPS-1"}, {"key": "OPS-2"}], "nextPageToken": "p2"}`,
+		"p2": `{"issues": [{"key": "OPS-3"}], "nextPageToken": "p3"}`,
+		"p3": `{"issues": [{"key": "OPS-4"}], "isLast": true}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("jql") != "status=open" {
+			t.Errorf("jql lost across pages: %s", r.URL.RawQuery)
+		}
+		_, _ = w.Write([]byte(pages[r.URL.Query().Get("nextPageToken")]))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("jira", []Package{{
+		Action:   "open_issues",
+		Method:   "GET",
+		URL:      srv.URL + "/search/jql?jql={{args.jql}}",
+		Paginate: &Pagination{Param: "nextPageToken", Next: "nextPageToken", Items: "issues"},
+		Response: &Response{Extract: map[string]string{"keys": "issues[*].key"}, Success: "{{response.keys}}"},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "open_issues", Args: map[string]string{"jql": "status=open"}})
+	if res.Error != "" || res.Content != `["OPS-1","OPS-2","OPS-3","OPS-4"]` {
+		t.Errorf("result = %+v", res)
+	}
+}
+
+func TestExecutor_PaginateCounterInBody(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			Project string `json:"project"`
+			StartAt int    `json:"startAt"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &req); err != nil || req.Project != "OPS" {
+			t.Errorf("body = %s", data)
+		}
+		var items []string
+		for i := req.StartAt; i < req.StartAt+2 && i < 5; i++ {
+			items = append(items, fmt.Sprintf(`{"id": %d}`, i))
+		}
+		_, _ = fmt.Fprintf(w, `{"total": 5, "values": [%s]}`, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("svc", []Package{{
+		Action:   "list",
+		Method:   "POST",
+		URL:      srv.URL,
+		Body:     `{"project": "{{args.project}}"}`,
+		Headers:  map[string]string{"Content-Type": "application/json"},
+		Paginate: &Pagination{Param: "startAt", In: "body", Increment: 2, Items: "values"},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "list", Args: map[string]string{"project": "OPS"}})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	if res.Content != `{"total":5,"values":[{"id":0},{"id":1},{"id":2},{"id":3},{"id":4}]}` {
+		t.Errorf("content = %s", res.Content)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("requests = %d, want 4 (the last one returns no items)", n)
+	}
+}
+
+func TestExecutor_PaginateMaxPagesAndErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 4 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte("slow down"))
+			return
+		}
+		_, _ = fmt.Fprintf(w, `[%d]`, page)
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("svc", []Package{
+		{Action: "some", URL: srv.URL, Paginate: &Pagination{Param: "page", Start: 1, Items: "$", MaxPages: 2}},
+		{Action: "all", URL: srv.URL, Paginate: &Pagination{Param: "page", Start: 1, Items: "$"}},
+	})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "some"})
+	if res.Error != "" || res.Content != "[1,2]" {
+		t.Errorf("max_pages: %+v", res)
+	}
+	res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "all"})
+	if res.Error != "HTTP 429: slow down" {
+		t.Errorf("failed page: %+v", res)
+	}
+}
+
+func TestExecutor_Steps(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/user/search":
+			if r.URL.Query().Get("query") == "nobody" {
+				_, _ = w.Write([]byte(`[]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"accountId": "acc-7", "displayName": "Ann \"Ops\" Lee"}]`))
+		case "/issue":
+			data, _ := io.ReadAll(r.Body)
+			gotBody = string(data)
+			_, _ = w.Write([]byte(`{"key": "OPS-9"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("jira", []Package{{
+		Action: "assign_new",
+		Method: "POST",
+		URL:    srv.URL + "/issue",
+		Body:   `{"summary": "{{args.summary}}", "assignee": "{{steps.account}}", "note": "for {{steps.name}}"}`,
+		Steps: []Step{{
+			Name:    "find_user",
+			Method:  "GET",
+			URL:     srv.URL + "/user/search?query={{args.user}}",
+			Extract: map[string]string{"account": "[0].accountId", "name": "[0].displayName"},
+		}},
+		Headers:  map[string]string{"Content-Type": "application/json"},
+		Response: &Response{Extract: map[string]string{"key": "key"}, Success: "{{response.key}} assigned to {{steps.name}}"},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "assign_new", Args: map[string]string{"summary": "X", "user": "ann"}})
+	if res.Error != "" || res.Content != `OPS-9 assigned to Ann "Ops" Lee` {
+		t.Errorf("result = %+v", res)
+	}
+	var body map[string]string
+	if err := json.Unmarshal([]byte(gotBody), &body); err != nil || body["assignee"] != "acc-7" || body["note"] != `for Ann "Ops" Lee` {
+		t.Errorf("main request body = %s (%v)", gotBody, err)
+	}
+
+	res = exec.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "assign_new", Args: map[string]string{"summary": "X", "user": "nobody"}})
+	if !strings.HasPrefix(res.Error, "step find_user: no value for ") {
+		t.Errorf("missing step value: %+v", res)
+	}
+}
+
+func TestPackageValidate(t *testing.T) {
+	bad := []Package{
+		{Steps: []Step{{Extract: map[string]string{"id": "id"}}}},
+		{Steps: []Step{{URL: "https://x", Extract: map[string]string{"id": "a..b"}}}},
+		{Paginate: &Pagination{Items: "items"}},
+		{Paginate: &Pagination{Param: "page"}},
+		{Paginate: &Pagination{Param: "page", Items: "items[*]"}},
+		{Paginate: &Pagination{Param: "page", Items: "items", In: "header"}},
+		{Paginate: &Pagination{Param: "page", Items: "items", MaxPages: maxPagesLimit + 1}},
+	}
+	for i, p := range bad {
+		if p.Validate() == nil {
+			t.Errorf("package %d should not validate", i)
+		}
+	}
+	ok := Package{
+		Steps:    []Step{{URL: "https://x", Extract: map[string]string{"id": "[0].id"}}},
+		Paginate: &Pagination{Param: "cursor", Next: "meta.next", Items: "data"},
+	}
+	if err := ok.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,106 @@
+package requestpkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonRequest builds a request whose body is p.JSONBody rendered by object.
+func (t *templater) jsonRequest(p Package) (outgoing, error) {
+	out, err := t.request(p.Method, p.URL, "", p.Headers)
+	if err != nil {
+		return outgoing{}, err
+	}
+	body, err := json.Marshal(t.object(p.JSONBody, p.Parameters))
+	if err != nil {
+		return outgoing{}, fmt.Errorf("encode json_body: %w", err)
+	}
+	out.body = string(body)
+	if out.header.Get("Content-Type") == "" {
+		out.header.Set("Content-Type", "application/json")
+	}
+	return out, nil
+}
+
+// object renders a JSON object template (json_body, graphql variables).
+// A string that is exactly "{{args.X}}" takes the type of parameter X
+// (integer, number, boolean, array or object) and its key is left out when
+// X is not given; other strings are templates rendered as strings; nested
+// maps and lists are rendered the same way, and other YAML values are kept
+// as they are.
+func (t *templater) object(m map[string]any, params []ParamDefinition) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		if val, ok := t.value(v, params); ok {
+			out[k] = val
+		}
+	}
+	return out
+}
+
+func (t *templater) value(v any, params []ParamDefinition) (any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		return t.object(v, params), true
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			if val, ok := t.value(item, params); ok {
+				out = append(out, val)
+			}
+		}
+		return out, true
+	case string:
+		if m := argsRe.FindStringSubmatch(v); m != nil && m[0] == strings.TrimSpace(v) {
+			raw, ok := t.args[m[1]]
+			if !ok {
+				return nil, false
+			}
+			return typedArg(raw, paramType(params, m[1])), true
+		}
+		s := t.expand(v, false)
+		if strings.Contains(s, "{{args.") {
+			return nil, false
+		}
+		return s, true
+	default:
+		return v, true
+	}
+}
+
+func paramType(params []ParamDefinition, name string) string {
+	for _, p := range params {
+		if p.Name == name {
+			return p.Type
+		}
+	}
+	return ""
+}
+
+// typedArg converts a tool argument to the JSON type its parameter
+// declares; a value that does not parse is passed on as a string for the
+// server to reject with a clear error.
+func typedArg(raw, typ string) any {
+	switch {
+	case typ == "integer":
+		if n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64); err == nil {
+			return n
+		}
+	case typ == "number":
+		if f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64); err == nil {
+			return f
+		}
+	case typ == "boolean":
+		if b, err := strconv.ParseBool(strings.TrimSpace(raw)); err == nil {
+			return b
+		}
+	case typ == "object" || typ == "array" || strings.HasPrefix(typ, "array:"):
+		var v any
+		if json.Unmarshal([]byte(raw), &v) == nil {
+			return v
+		}
+	}
+	return raw
+}
@@ -0,0 +1,82 @@
+package requestpkg
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func TestExecutor_JSONBody(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Errorf("body %s: %v", data, err)
+		}
+		_, _ = w.Write([]byte(`{"id": 1}`))
+	}))
+	defer srv.Close()
+
+	exec := NewExecutor("shop", []Package{{
+		Action: "create_order",
+		Method: "POST",
+		URL:    srv.URL,
+		JSONBody: map[string]any{
+			"quantity": "{{args.quantity}}",
+			"gift":     "{{args.gift}}",
+			"note":     "{{args.note}}",
+			"customer": map[string]any{"email": "{{args.email}}", "tags": []any{"bot", "{{args.tag}}"}},
+			"lines":    "{{args.lines}}",
+			"channel":  "chat",
+		},
+		Parameters: []ParamDefinition{
+			{Name: "quantity", Type: "integer"},
+			{Name: "gift", Type: "boolean"},
+			{Name: "lines", Type: "array"},
+		},
+	}})
+	res := exec.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "create_order", Args: map[string]string{
+		"quantity": "3", "gift": "true", "email": `a"b@example.com`, "lines": `[{"sku": "X"}]`,
+	}})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	want := map[string]any{
+		"quantity": float64(3),
+		"gift":     true,
+		"customer": map[string]any{"email": `a"b@example.com`, "tags": []any{"bot"}},
+		"lines":    []any{map[string]any{"sku": "X"}},
+		"channel":  "chat",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("body = %#v\nwant %#v", got, want)
+	}
+}
+
+func TestTypedArg(t *testing.T) {
+	tests := []struct {
+		raw, typ string
+		want     any
+	}{
+		{"42", "integer", int64(42)},
+		{"4.5", "number", 4.5},
+		{"true", "boolean", true},
+		{`["a","b"]`, "array:string", []any{"a", "b"}},
+		{"abc", "integer", "abc"},
+		{"42", "", "42"},
+	}
+	for _, tt := range tests {
+		if got := typedArg(tt.raw, tt.typ); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("typedArg(%q, %q) = %#v, want %#v", tt.raw, tt.typ, got, tt.want)
+		}
+	}
+}
@@ -18,11 +18,18 @@ type Override struct {
 	Scope string // "request", "session", "pin"
 }
 
+// HealthScores reports which models' endpoints are degraded.
+// *provider.HealthMonitor satisfies it.
+type HealthScores interface {
+	Degraded(endpoint string) bool
+}
+
 type WeightedRouter struct {
 	catalog   []CatalogModel
 	pins      map[TaskType]provider.ModelRef
 	affinity  *AffinityStore
-	threshold float64 // minimum affinity score to use learned model
+	threshold float64      // minimum affinity score to use learned model
+	health    HealthScores // optional; degraded models are passed over
 }
 
 func NewWeightedRouter(catalog []CatalogModel, pins map[TaskType]provider.ModelRef, affinity *AffinityStore) *WeightedRouter {
@@ -52,7 +59,7 @@ func (r *WeightedRouter) Route(taskType TaskType, override *Override) (provider.
 	if r.affinity != nil {
 		scores := r.affinity.Get(taskType)
 		for _, ms := range scores {
-			if ms.Score >= r.threshold {
+			if ms.Score >= r.threshold && !r.degraded(ms.Model) {
 				return ms.Model, nil
 			}
 		}
@@ -61,18 +68,43 @@ func (r *WeightedRouter) Route(taskType TaskType, override *Override) (provider.
 	if len(r.catalog) == 0 {
 		return "", fmt.Errorf("no models in catalog")
 	}
+	for _, m := range r.catalog {
+		if !r.degraded(m.Ref) {
+			return m.Ref, nil
+		}
+	}
 	return r.catalog[0].Ref, nil
 }
 
+// NextModel returns the model to escalate to after current: the next one
+// by weight whose endpoint is not degraded, or simply the next one when
+// all of them are.
 func (r *WeightedRouter) NextModel(current provider.ModelRef) (provider.ModelRef, error) {
 	for i, m := range r.catalog {
 		if m.Ref == current && i+1 < len(r.catalog) {
+			for _, next := range r.catalog[i+1:] {
+				if !r.degraded(next.Ref) {
+					return next.Ref, nil
+				}
+			}
 			return r.catalog[i+1].Ref, nil
 		}
 	}
 	return "", fmt.Errorf("no next model available after %s", current)
 }
 
+// SetHealth makes the router pass over models whose endpoint h reports
+// degraded, so traffic moves to healthy ones before requests fail. Learned
+// and weight-based choices are affected; overrides and pins are explicit
+// and kept. When every model is degraded the usual choice stands.
+func (r *WeightedRouter) SetHealth(h HealthScores) {
+	r.health = h
+}
+
+func (r *WeightedRouter) degraded(ref provider.ModelRef) bool {
+	return r.health != nil && r.health.Degraded(string(ref))
+}
+
 func (r *WeightedRouter) RecordSignal(taskType TaskType, model provider.ModelRef, signal Signal) {
 	if r.affinity != nil {
 		r.affinity.Record(taskType, model, signal)
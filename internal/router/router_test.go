@@ -242,3 +242,46 @@ func TestRouteSessionOverrideScope(t *testing.T) {
 		t.Errorf("session override = %s, want openai/gpt-5.2", ref)
 	}
 }
+
+type fakeHealth map[string]bool
+
+func (f fakeHealth) Degraded(endpoint string) bool { return f[endpoint] }
+
+func TestRouteSkipsDegradedModels(t *testing.T) {
+	affinity := NewAffinityStore("", 30)
+	for i := 0; i < 10; i++ {
+		affinity.Record(TaskCode, "anthropic/claude-sonnet-4", SignalAccepted)
+	}
+	pins := map[TaskType]provider.ModelRef{TaskAnalysis: "anthropic/claude-haiku-4"}
+	r := NewWeightedRouter(testCatalog(), pins, affinity)
+	r.SetHealth(fakeHealth{"anthropic/claude-haiku-4": true, "anthropic/claude-sonnet-4": true})
+
+	if ref, _ := r.Route(TaskChat, nil); ref != "ovh/gpt-oss-120b" {
+		t.Errorf("chat = %s, want the best non-degraded model", ref)
+	}
+	if ref, _ := r.Route(TaskCode, nil); ref != "ovh/gpt-oss-120b" {
+		t.Errorf("code = %s, want the learned model passed over while degraded", ref)
+	}
+	if ref, _ := r.Route(TaskAnalysis, nil); ref != "anthropic/claude-haiku-4" {
+		t.Errorf("analysis = %s, want the pin kept", ref)
+	}
+	if next, _ := r.NextModel("ovh/gpt-oss-120b"); next != "openai/gpt-5.2" {
+		t.Errorf("next after ovh = %s, want sonnet skipped", next)
+	}
+}
+
+func TestRouteAllDegraded(t *testing.T) {
+	r := NewWeightedRouter(testCatalog(), nil, nil)
+	all := fakeHealth{}
+	for _, m := range testCatalog() {
+		all[string(m.Ref)] = true
+	}
+	r.SetHealth(all)
+
+	if ref, _ := r.Route(TaskChat, nil); ref != "anthropic/claude-haiku-4" {
+		t.Errorf("route = %s, want the usual choice when everything is degraded", ref)
+	}
+	if next, _ := r.NextModel("anthropic/claude-haiku-4"); next != "ovh/gpt-oss-120b" {
+		t.Errorf("next = %s", next)
+	}
+}
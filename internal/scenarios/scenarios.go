@@ -8,6 +8,7 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/opentalon/opentalon/pkg/toolfqn"
@@ -36,22 +37,51 @@ func Hash() string {
 
 // ScenarioAssert describes structural checks on an orchestrator run result.
 type ScenarioAssert struct {
-	NoToolCalls      bool              `yaml:"no_tool_calls"`
-	ResponseContains []string          `yaml:"response_contains"`
-	ResponseNotEmpty bool              `yaml:"response_not_empty"`
-	ToolCalled       string            `yaml:"tool_called"` // "plugin__action"
-	ArgEquals        map[string]string `yaml:"arg_equals"`
+	NoToolCalls         bool              `yaml:"no_tool_calls"`
+	ResponseContains    []string          `yaml:"response_contains"`
+	ResponseNotContains []string          `yaml:"response_not_contains"`
+	ResponseMatches     string            `yaml:"response_matches"` // regexp
+	ResponseNotEmpty    bool              `yaml:"response_not_empty"`
+	ToolCalled          string            `yaml:"tool_called"` // "plugin__action"
+	ArgEquals           map[string]string `yaml:"arg_equals"`
+	ToolsCalled         []string          `yaml:"tools_called"`     // all of these, in this order (others may come between)
+	ToolsNotCalled      []string          `yaml:"tools_not_called"` // none of these
 }
 
 // Scenario is one test case: an input message and structural assertions on the result.
 type Scenario struct {
-	Name   string         `yaml:"name"`
-	Input  string         `yaml:"input"`
-	Assert ScenarioAssert `yaml:"assert"`
+	Name  string `yaml:"name"`
+	Input string `yaml:"input"`
+	// Messages, when set instead of Input, are sent in turn in one session;
+	// assertions see the last reply and the tool calls of every turn.
+	Messages      []string       `yaml:"messages"`
+	MaxIterations int            `yaml:"max_iterations"` // LLM calls allowed over all turns; 0 = no limit
+	Assert        ScenarioAssert `yaml:"assert"`
+}
+
+// Turns returns the user messages the scenario sends.
+func (s Scenario) Turns() []string {
+	if len(s.Messages) > 0 {
+		return s.Messages
+	}
+	return []string{s.Input}
+}
+
+// Tool is a stand-in plugin action for scenarios run against a live model
+// (opentalon eval): it is offered to the model like a real tool and answers
+// every call with Result, or fails with Error.
+type Tool struct {
+	Name        string   `yaml:"name"` // "plugin__action"
+	Description string   `yaml:"description"`
+	Parameters  []string `yaml:"parameters"`
+	Result      string   `yaml:"result"`
+	Error       string   `yaml:"error"`
 }
 
 // ScenarioFile is the top-level YAML structure.
 type ScenarioFile struct {
+	Path      string     `yaml:"-"`
+	Tools     []Tool     `yaml:"tools"`
 	Scenarios []Scenario `yaml:"scenarios"`
 }
 
@@ -66,6 +96,7 @@ type ToolCallResult struct {
 type RunResult struct {
 	Response  string
 	ToolCalls []ToolCallResult
+	LLMCalls  int // checked against Scenario.MaxIterations when set
 }
 
 // CassetteName returns the VCR cassette filename for a scenario (without directory).
@@ -125,6 +156,51 @@ func LoadScenarios(dir string) ([]Scenario, error) {
 	return checkCassetteCollisions(all)
 }
 
+// LoadFiles reads scenario files; a directory stands for every *.yaml file
+// in it. Scenario names must be unique across all of them.
+func LoadFiles(paths ...string) ([]ScenarioFile, error) {
+	var files []ScenarioFile
+	var all []Scenario
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		names := []string{p}
+		if info.IsDir() {
+			names, _ = filepath.Glob(filepath.Join(p, "*.yaml"))
+		}
+		for _, name := range names {
+			data, err := os.ReadFile(name)
+			if err != nil {
+				return nil, err
+			}
+			sf := ScenarioFile{Path: name}
+			if err := yaml.Unmarshal(data, &sf); err != nil {
+				return nil, fmt.Errorf("parse %s: %w", name, err)
+			}
+			for _, tool := range sf.Tools {
+				if _, _, err := toolfqn.Split(tool.Name); err != nil {
+					return nil, fmt.Errorf("%s: tool %q: want plugin__action", name, tool.Name)
+				}
+			}
+			for _, s := range sf.Scenarios {
+				if s.Assert.ResponseMatches != "" {
+					if _, err := regexp.Compile(s.Assert.ResponseMatches); err != nil {
+						return nil, fmt.Errorf("%s: scenario %q: response_matches: %w", name, s.Name, err)
+					}
+				}
+			}
+			files = append(files, sf)
+			all = append(all, sf.Scenarios...)
+		}
+	}
+	if _, err := checkCassetteCollisions(all); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
 // checkCassetteCollisions returns an error if any two scenarios map to the same cassette filename.
 func checkCassetteCollisions(scenarios []Scenario) ([]Scenario, error) {
 	seen := make(map[string]string, len(scenarios))
@@ -151,6 +227,37 @@ func CheckAssertions(s Scenario, result RunResult) string {
 			return fmt.Sprintf("response missing %q", want)
 		}
 	}
+	for _, unwanted := range s.Assert.ResponseNotContains {
+		if strings.Contains(result.Response, unwanted) {
+			return fmt.Sprintf("response contains %q", unwanted)
+		}
+	}
+	if s.Assert.ResponseMatches != "" {
+		re, err := regexp.Compile(s.Assert.ResponseMatches)
+		if err != nil {
+			return fmt.Sprintf("invalid response_matches %q: %v", s.Assert.ResponseMatches, err)
+		}
+		if !re.MatchString(result.Response) {
+			return fmt.Sprintf("response does not match %q", s.Assert.ResponseMatches)
+		}
+	}
+	if s.MaxIterations > 0 && result.LLMCalls > s.MaxIterations {
+		return fmt.Sprintf("took %d LLM calls, budget %d", result.LLMCalls, s.MaxIterations)
+	}
+	next := 0 // tools_called must appear in order
+	for _, tc := range result.ToolCalls {
+		if next < len(s.Assert.ToolsCalled) && isTool(s.Assert.ToolsCalled[next], tc) {
+			next++
+		}
+		for _, name := range s.Assert.ToolsNotCalled {
+			if isTool(name, tc) {
+				return fmt.Sprintf("%s called", name)
+			}
+		}
+	}
+	if next < len(s.Assert.ToolsCalled) {
+		return fmt.Sprintf("%s not called (in order)", s.Assert.ToolsCalled[next])
+	}
 	if s.Assert.ToolCalled != "" {
 		// Decode via the shared single decoder so scenarios authored with either
 		// the canonical "plugin__action" or the legacy "plugin.action" form match.
@@ -176,3 +283,10 @@ func CheckAssertions(s Scenario, result RunResult) string {
 	}
 	return ""
 }
+
+// isTool reports whether tc is a call to name ("plugin__action", or the
+// legacy "plugin.action").
+func isTool(name string, tc ToolCallResult) bool {
+	plugin, action, err := toolfqn.Split(name)
+	return err == nil && tc.Plugin == plugin && tc.Action == action
+}
@@ -0,0 +1,279 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// JobStore persists dynamic jobs; config jobs are never stored. The default
+// is a YAML file in the data dir. A database store (store.SchedulerJobStore)
+// lets every replica of a cluster see the same jobs.
+type JobStore interface {
+	LoadJobs() ([]Job, error)
+	PutJob(job Job) error // insert or replace by name
+	DeleteJob(name string) error
+}
+
+// RunClaimer is implemented by job stores shared between replicas. Before a
+// job fires, ClaimRun must report true on exactly one replica for slot; for
+// a dynamic job it reports false once the job was deleted elsewhere.
+type RunClaimer interface {
+	ClaimRun(ctx context.Context, name string, slot time.Time, dynamic bool) (bool, error)
+}
+
+// sharedSyncInterval is how often a scheduler on a shared store picks up
+// jobs created, changed or deleted by other replicas.
+const sharedSyncInterval = time.Minute
+
+// fileJobStore keeps dynamic jobs in one YAML file and dead letters in
+// another next to it.
+type fileJobStore struct {
+	mu       sync.Mutex
+	path     string
+	deadPath string
+}
+
+func newFileJobStore(dataDir string) *fileJobStore {
+	dir := filepath.Join(dataDir, "scheduler")
+	return &fileJobStore{path: filepath.Join(dir, "jobs.yaml"), deadPath: filepath.Join(dir, "dead_letters.yaml")}
+}
+
+func (f *fileJobStore) LoadJobs() ([]Job, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read()
+}
+
+func (f *fileJobStore) PutJob(job Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jobs, err := f.read()
+	if err != nil {
+		return err
+	}
+	for i := range jobs {
+		if jobs[i].Name == job.Name {
+			jobs[i] = job
+			return f.write(jobs)
+		}
+	}
+	return f.write(append(jobs, job))
+}
+
+func (f *fileJobStore) DeleteJob(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	jobs, err := f.read()
+	if err != nil {
+		return err
+	}
+	kept := jobs[:0]
+	for _, j := range jobs {
+		if j.Name != name {
+			kept = append(kept, j)
+		}
+	}
+	return f.write(kept)
+}
+
+func (f *fileJobStore) read() ([]Job, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading jobs file: %w", err)
+	}
+	var jobs []Job
+	if err := yaml.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("parsing jobs file: %w", err)
+	}
+	return jobs, nil
+}
+
+func (f *fileJobStore) write(jobs []Job) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("creating scheduler dir: %w", err)
+	}
+	data, err := yaml.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("marshaling jobs: %w", err)
+	}
+	return os.WriteFile(f.path, data, 0600)
+}
+
+func (f *fileJobStore) PutDeadLetter(dl DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dls, err := f.readDead()
+	if err != nil {
+		return err
+	}
+	for i := range dls {
+		if dls[i].Job == dl.Job {
+			dls[i] = dl
+			return f.writeDead(dls)
+		}
+	}
+	return f.writeDead(append(dls, dl))
+}
+
+func (f *fileJobStore) LoadDeadLetters() ([]DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.readDead()
+}
+
+func (f *fileJobStore) DeleteDeadLetter(job string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	dls, err := f.readDead()
+	if err != nil {
+		return err
+	}
+	kept := dls[:0]
+	for _, dl := range dls {
+		if dl.Job != job {
+			kept = append(kept, dl)
+		}
+	}
+	if len(kept) == len(dls) {
+		return nil
+	}
+	return f.writeDead(kept)
+}
+
+func (f *fileJobStore) readDead() ([]DeadLetter, error) {
+	data, err := os.ReadFile(f.deadPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading dead letters file: %w", err)
+	}
+	var dls []DeadLetter
+	if err := yaml.Unmarshal(data, &dls); err != nil {
+		return nil, fmt.Errorf("parsing dead letters file: %w", err)
+	}
+	return dls, nil
+}
+
+func (f *fileJobStore) writeDead(dls []DeadLetter) error {
+	if err := os.MkdirAll(filepath.Dir(f.deadPath), 0700); err != nil {
+		return fmt.Errorf("creating scheduler dir: %w", err)
+	}
+	data, err := yaml.Marshal(dls)
+	if err != nil {
+		return fmt.Errorf("marshaling dead letters: %w", err)
+	}
+	return os.WriteFile(f.deadPath, data, 0600)
+}
+
+// SetJobStore replaces the file store. Call before Start.
+func (s *Scheduler) SetJobStore(store JobStore) {
+	s.store = store
+}
+
+// putJob persists a dynamic job; config jobs and schedulers without a store
+// are a no-op.
+func (s *Scheduler) putJob(job Job) error {
+	if s.store == nil || job.Source != "dynamic" {
+		return nil
+	}
+	return s.store.PutJob(job)
+}
+
+func (s *Scheduler) deleteJob(name string) error {
+	if s.store == nil {
+		return nil
+	}
+	return s.store.DeleteJob(name)
+}
+
+// claim reports whether this replica runs job for the fire at fireAt. With
+// an unshared store it always does; a claim error skips the run rather than
+// risk running it on every replica.
+func (s *Scheduler) claim(job Job, sch schedule, fireAt time.Time) bool {
+	claimer, ok := s.store.(RunClaimer)
+	if !ok {
+		return true
+	}
+	won, err := claimer.ClaimRun(s.ctx, job.Name, sch.slot(fireAt), job.Source == "dynamic")
+	if err != nil {
+		slog.Warn("job run claim failed; skipping this run", "component", "scheduler", "job", job.Name, "error", err)
+		return false
+	}
+	return won
+}
+
+// syncShared keeps the dynamic jobs of a shared store in step with the
+// other replicas until the scheduler stops.
+func (s *Scheduler) syncShared() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(sharedSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			jobs, err := s.store.LoadJobs()
+			if err != nil {
+				slog.Warn("syncing shared jobs failed", "component", "scheduler", "error", err)
+				continue
+			}
+			s.reconcile(jobs)
+		}
+	}
+}
+
+// reconcile makes the running dynamic jobs match stored: new jobs start,
+// changed ones restart and those missing from the store stop.
+func (s *Scheduler) reconcile(stored []Job) {
+	want := make(map[string]Job, len(stored))
+	for _, j := range stored {
+		j.Source = "dynamic"
+		want[j.Name] = j
+	}
+	var stale []Job
+	s.mu.Lock()
+	for name, rj := range s.jobs {
+		if rj.job.Source != "dynamic" {
+			delete(want, name)
+			continue
+		}
+		j, ok := want[name]
+		if ok && reflect.DeepEqual(j, rj.job) {
+			delete(want, name)
+			continue
+		}
+		rj.cancel()
+		delete(s.jobs, name)
+		if ok {
+			stale = append(stale, j)
+			delete(want, name)
+		}
+	}
+	s.mu.Unlock()
+	for _, j := range append(stale, mapValues(want)...) {
+		if err := s.addJobLocked(j); err != nil {
+			slog.Warn("skipping shared job", "component", "scheduler", "job", j.Name, "error", err)
+		}
+	}
+}
+
+func mapValues(m map[string]Job) []Job {
+	out := make([]Job, 0, len(m))
+	for _, j := range m {
+		out = append(out, j)
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sharedStore is an in-memory JobStore + RunClaimer standing in for the
+// database store replicas share.
+type sharedStore struct {
+	mu     sync.Mutex
+	jobs   map[string]Job
+	claims map[string]bool // job name + slot -> claimed
+	deny   bool            // every claim loses, as if another replica won
+}
+
+func newSharedStore(jobs ...Job) *sharedStore {
+	st := &sharedStore{jobs: make(map[string]Job), claims: make(map[string]bool)}
+	for _, j := range jobs {
+		st.jobs[j.Name] = j
+	}
+	return st
+}
+
+func (st *sharedStore) LoadJobs() ([]Job, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return mapValues(st.jobs), nil
+}
+
+func (st *sharedStore) PutJob(job Job) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.jobs[job.Name] = job
+	return nil
+}
+
+func (st *sharedStore) DeleteJob(name string) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	delete(st.jobs, name)
+	return nil
+}
+
+func (st *sharedStore) ClaimRun(_ context.Context, name string, slot time.Time, dynamic bool) (bool, error) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if _, ok := st.jobs[name]; st.deny || (dynamic && !ok) {
+		return false, nil
+	}
+	key := name + "@" + slot.Format(time.RFC3339Nano)
+	if st.claims[key] {
+		return false, nil
+	}
+	st.claims[key] = true
+	return true, nil
+}
+
+func TestFileJobStorePutDelete(t *testing.T) {
+	f := newFileJobStore(t.TempDir())
+	if jobs, err := f.LoadJobs(); err != nil || len(jobs) != 0 {
+		t.Fatalf("empty store: %v, %v", jobs, err)
+	}
+	_ = f.PutJob(Job{Name: "a", Interval: "1h", Action: "x.y"})
+	_ = f.PutJob(Job{Name: "b", Interval: "1h", Action: "x.y"})
+	_ = f.PutJob(Job{Name: "a", Interval: "2h", Action: "x.y"})
+	if err := f.DeleteJob("b"); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := f.LoadJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || jobs[0].Name != "a" || jobs[0].Interval != "2h" {
+		t.Errorf("jobs = %+v", jobs)
+	}
+}
+
+func TestSchedulerSharedStoreClaims(t *testing.T) {
+	runner := &fakeRunner{}
+	st := newSharedStore()
+	st.deny = true
+	s := New(runner, nil, "")
+	s.SetJobStore(st)
+	if err := s.Start([]Job{{Name: "cfg", Interval: "20ms", Action: "test.ping"}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	s.Stop()
+	if n := runner.callCount(); n != 0 {
+		t.Errorf("runs claimed by another replica must not run here, got %d", n)
+	}
+
+	runner = &fakeRunner{}
+	s = New(runner, nil, "")
+	s.SetJobStore(newSharedStore())
+	if err := s.Start([]Job{{Name: "cfg", Interval: "20ms", Action: "test.ping"}}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(90 * time.Millisecond)
+	s.Stop()
+	if n := runner.callCount(); n < 2 {
+		t.Errorf("won claims should run, got %d runs", n)
+	}
+}
+
+func TestSchedulerReconcile(t *testing.T) {
+	st := newSharedStore(
+		Job{Name: "keep", Interval: "1h", Action: "a.b"},
+		Job{Name: "change", Interval: "1h", Action: "a.b"},
+		Job{Name: "gone", Interval: "1h", Action: "a.b"},
+	)
+	s := New(&fakeRunner{}, nil, "")
+	s.SetJobStore(st)
+	if err := s.Start([]Job{{Name: "cfg", Interval: "1h", Action: "a.b"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if len(s.ListJobs()) != 4 {
+		t.Fatalf("jobs after start = %+v", s.ListJobs())
+	}
+
+	if err := s.AddJob(Job{Name: "local", Interval: "1h", Action: "a.b"}, "u"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := st.jobs["local"]; !ok {
+		t.Error("a job added on this replica should be stored")
+	}
+
+	// Another replica changes, deletes and creates jobs.
+	_ = st.PutJob(Job{Name: "change", Interval: "2h", Action: "a.b", Paused: true})
+	_ = st.DeleteJob("gone")
+	_ = st.PutJob(Job{Name: "new", Cron: "0 9 * * *", Action: "c.d"})
+	jobs, _ := st.LoadJobs()
+	s.reconcile(jobs)
+
+	if _, ok := s.GetJob("gone"); ok {
+		t.Error("a job deleted elsewhere should stop")
+	}
+	if j, ok := s.GetJob("change"); !ok || j.Interval != "2h" || !j.Paused {
+		t.Errorf("changed job = %+v", j)
+	}
+	if j, ok := s.GetJob("new"); !ok || j.Source != "dynamic" {
+		t.Errorf("new job = %+v", j)
+	}
+	for _, name := range []string{"keep", "local", "cfg"} {
+		if _, ok := s.GetJob(name); !ok {
+			t.Errorf("%s should keep running", name)
+		}
+	}
+}
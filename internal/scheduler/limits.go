@@ -0,0 +1,109 @@
+package scheduler
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// SetMaxConcurrent caps how many job runs execute at once across all jobs;
+// a run that finds every slot taken waits for one. n <= 0 means no cap.
+// Call before Start.
+func (s *Scheduler) SetMaxConcurrent(n int) {
+	if n <= 0 {
+		s.slots = nil
+		return
+	}
+	s.slots = make(chan struct{}, n)
+}
+
+// SetJitter sets the jitter of jobs that do not set their own. Call before
+// Start.
+func (s *Scheduler) SetJitter(d time.Duration) {
+	s.jitter = d
+}
+
+// parseJitter validates the job's jitter; zero when unset.
+func (j *Job) parseJitter() (time.Duration, error) {
+	if j.Jitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(j.Jitter)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("job %q: invalid jitter %q", j.Name, j.Jitter)
+	}
+	return d, nil
+}
+
+// jitterFor returns a random delay in [0, jitter) for one fire of job, so
+// jobs sharing an interval or cron spec do not all hit their plugins in the
+// same instant.
+func (s *Scheduler) jitterFor(job Job) time.Duration {
+	d, _ := job.parseJitter()
+	if job.Jitter == "" {
+		d = s.jitter
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// dispatch starts one run of rj in its own goroutine, so a slow run does not
+// push back the job's next fire. With NoOverlap the fire is skipped while a
+// previous run is still going.
+func (s *Scheduler) dispatch(rj *runningJob, name string) {
+	if !s.beginRun(rj) {
+		slog.Info("job still running; skipping this run", "component", "scheduler", "job", name)
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.endRun(rj)
+		if !s.acquireSlot() {
+			return
+		}
+		defer s.releaseSlot()
+		s.executeJob(rj)
+	}()
+}
+
+// beginRun counts a run of rj as started, unless the job forbids overlapping
+// runs and one is in progress.
+func (s *Scheduler) beginRun(rj *runningJob) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rj.job.NoOverlap && rj.active > 0 {
+		return false
+	}
+	rj.active++
+	return true
+}
+
+func (s *Scheduler) endRun(rj *runningJob) {
+	s.mu.Lock()
+	rj.active--
+	s.mu.Unlock()
+}
+
+// acquireSlot waits for a free execution slot; false when the scheduler
+// stops first.
+func (s *Scheduler) acquireSlot() bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *Scheduler) releaseSlot() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
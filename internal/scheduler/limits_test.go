@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// blockingRunner holds every run until release is closed and records how
+// many ran at once.
+type blockingRunner struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	calls   int
+	running int
+	peak    int
+}
+
+func newBlockingRunner() *blockingRunner {
+	return &blockingRunner{release: make(chan struct{})}
+}
+
+func (b *blockingRunner) RunAction(ctx context.Context, _, _ string, _ map[string]string) (string, error) {
+	b.mu.Lock()
+	b.calls++
+	b.running++
+	b.peak = max(b.peak, b.running)
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		b.running--
+		b.mu.Unlock()
+	}()
+	select {
+	case <-b.release:
+	case <-ctx.Done():
+	}
+	return "ok", nil
+}
+
+func (b *blockingRunner) stats() (calls, peak int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls, b.peak
+}
+
+func TestSchedulerNoOverlap(t *testing.T) {
+	for _, noOverlap := range []bool{true, false} {
+		runner := newBlockingRunner()
+		s := New(runner, nil, "")
+		if err := s.Start([]Job{{Name: "slow", Interval: "20ms", Action: "x.y", NoOverlap: noOverlap}}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(110 * time.Millisecond)
+		calls, peak := runner.stats()
+		close(runner.release)
+		s.Stop()
+
+		if noOverlap && (calls != 1 || peak != 1) {
+			t.Errorf("no_overlap: calls = %d, peak = %d; want a single run", calls, peak)
+		}
+		if !noOverlap && peak < 2 {
+			t.Errorf("overlap allowed: peak = %d, want runs to overlap", peak)
+		}
+	}
+}
+
+func TestSchedulerMaxConcurrent(t *testing.T) {
+	runner := newBlockingRunner()
+	s := New(runner, nil, "")
+	s.SetMaxConcurrent(1)
+	if err := s.Start([]Job{
+		{Name: "a", Interval: "20ms", Action: "x.y"},
+		{Name: "b", Interval: "20ms", Action: "x.y"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(80 * time.Millisecond)
+	_, peak := runner.stats()
+	close(runner.release)
+	s.Stop()
+	if peak != 1 {
+		t.Errorf("peak concurrency = %d, want 1", peak)
+	}
+}
+
+func TestRetryJobRespectsNoOverlap(t *testing.T) {
+	runner := newBlockingRunner()
+	s := New(runner, nil, "")
+	if err := s.Start([]Job{{Name: "slow", Interval: "20ms", Action: "x.y", NoOverlap: true}}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	defer close(runner.release)
+	time.Sleep(40 * time.Millisecond)
+	if err := s.RetryJob(context.Background(), "slow"); err == nil {
+		t.Error("retrying a job whose run is in progress should fail")
+	}
+}
+
+func TestJitter(t *testing.T) {
+	s := New(&fakeRunner{}, nil, "")
+	s.SetJitter(time.Second)
+	for range 50 {
+		if d := s.jitterFor(Job{}); d < 0 || d >= time.Second {
+			t.Fatalf("default jitter = %v, want [0, 1s)", d)
+		}
+		if d := s.jitterFor(Job{Jitter: "10ms"}); d >= 10*time.Millisecond {
+			t.Fatalf("job jitter = %v, want < 10ms", d)
+		}
+	}
+	if d := s.jitterFor(Job{Jitter: "0s"}); d != 0 {
+		t.Errorf("jitter 0s should disable the default, got %v", d)
+	}
+	for _, bad := range []string{"soon", "-1s"} {
+		j := Job{Name: "j", Jitter: bad}
+		if _, err := j.parseJitter(); err == nil {
+			t.Errorf("jitter %q: expected an error", bad)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// NotifyTarget is one extra destination for a job's result.
+type NotifyTarget struct {
+	Channel        string `yaml:"channel" json:"channel"`
+	ConversationID string `yaml:"conversation_id" json:"conversation_id"`
+}
+
+// NotifyCondition gates a job's notification on its result. When both
+// fields are set, both must hold. A result that does not satisfy the
+// condition is dropped silently, so "check violations hourly" only speaks up
+// when something is found.
+type NotifyCondition struct {
+	// Match is a regular expression the result text must match.
+	Match string `yaml:"match,omitempty" json:"match,omitempty"`
+	// JSON is a predicate over the result parsed as JSON: "path" (truthy)
+	// or "path op value" with op one of == != > >= < <=, e.g.
+	// "violations.0" or "summary.count > 0". The path is dot-separated; a
+	// numeric segment indexes an array and "." alone is the whole result.
+	JSON string `yaml:"json,omitempty" json:"json,omitempty"`
+}
+
+// notifyData is what NotifyTemplate renders.
+type notifyData struct {
+	Job    string    // job name
+	Result string    // raw action result
+	JSON   any       // result parsed as JSON; nil when it is not JSON
+	Time   time.Time // when the run finished
+}
+
+// validateNotify checks the job's notification settings up front, so a bad
+// template or pattern is refused at creation rather than on every run.
+func (j *Job) validateNotify() error {
+	for _, t := range j.NotifyTargets {
+		if t.Channel == "" || t.ConversationID == "" {
+			return fmt.Errorf("job %q: notify target needs both channel and conversation_id", j.Name)
+		}
+	}
+	if j.NotifyTemplate != "" {
+		if _, err := template.New(j.Name).Parse(j.NotifyTemplate); err != nil {
+			return fmt.Errorf("job %q: notify template: %w", j.Name, err)
+		}
+	}
+	if j.NotifyIf != nil {
+		if j.NotifyIf.Match != "" {
+			if _, err := regexp.Compile(j.NotifyIf.Match); err != nil {
+				return fmt.Errorf("job %q: notify_if match: %w", j.Name, err)
+			}
+		}
+		if j.NotifyIf.JSON != "" {
+			if _, err := parseJSONPredicate(j.NotifyIf.JSON); err != nil {
+				return fmt.Errorf("job %q: notify_if json: %w", j.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// notifyTargets lists every destination of the job's result: the primary
+// NotifyChannel (whose conversation id may be missing on legacy jobs)
+// followed by NotifyTargets, without duplicates.
+func (j *Job) notifyTargets() []NotifyTarget {
+	var out []NotifyTarget
+	if j.NotifyChannel != "" {
+		out = append(out, NotifyTarget{Channel: j.NotifyChannel, ConversationID: j.NotifyConversationID})
+	}
+	for _, t := range j.NotifyTargets {
+		if !containsTarget(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func containsTarget(ts []NotifyTarget, t NotifyTarget) bool {
+	for _, x := range ts {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+// shouldNotify reports whether result satisfies the job's NotifyIf.
+func (j *Job) shouldNotify(result string) (bool, error) {
+	c := j.NotifyIf
+	if c == nil {
+		return true, nil
+	}
+	if c.Match != "" {
+		re, err := regexp.Compile(c.Match)
+		if err != nil {
+			return false, err
+		}
+		if !re.MatchString(result) {
+			return false, nil
+		}
+	}
+	if c.JSON != "" {
+		p, err := parseJSONPredicate(c.JSON)
+		if err != nil {
+			return false, err
+		}
+		var v any
+		if err := json.Unmarshal([]byte(result), &v); err != nil {
+			return false, fmt.Errorf("result is not JSON: %w", err)
+		}
+		return p.eval(v), nil
+	}
+	return true, nil
+}
+
+// notifyMessage renders the job's NotifyTemplate over result, or returns
+// result as-is when the job has no template.
+func (j *Job) notifyMessage(result string, now time.Time) (string, error) {
+	if j.NotifyTemplate == "" {
+		return result, nil
+	}
+	tmpl, err := template.New(j.Name).Option("missingkey=zero").Parse(j.NotifyTemplate)
+	if err != nil {
+		return "", err
+	}
+	data := notifyData{Job: j.Name, Result: result, Time: now}
+	var v any
+	if json.Unmarshal([]byte(result), &v) == nil {
+		data.JSON = v
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// jsonPredicate is a parsed NotifyCondition.JSON.
+type jsonPredicate struct {
+	path  []string
+	op    string // "" tests the value for truthiness
+	value any
+}
+
+// predicateOps is ordered so two-character operators are tried first.
+var predicateOps = []string{"==", "!=", ">=", "<=", ">", "<"}
+
+func parseJSONPredicate(s string) (*jsonPredicate, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, fmt.Errorf("empty predicate")
+	}
+	p := &jsonPredicate{}
+	lhs := s
+	for _, op := range predicateOps {
+		if i := strings.Index(s, op); i >= 0 {
+			lhs, p.op = s[:i], op
+			rhs := strings.TrimSpace(s[i+len(op):])
+			if rhs == "" {
+				return nil, fmt.Errorf("%q: missing value after %s", s, op)
+			}
+			if err := json.Unmarshal([]byte(rhs), &p.value); err != nil {
+				p.value = rhs // bare word: compare as a string
+			}
+			break
+		}
+	}
+	lhs = strings.TrimSpace(lhs)
+	if lhs == "" {
+		return nil, fmt.Errorf("%q: missing path", s)
+	}
+	if lhs != "." {
+		p.path = strings.Split(strings.TrimPrefix(lhs, "."), ".")
+		for _, seg := range p.path {
+			if seg == "" {
+				return nil, fmt.Errorf("%q: empty path segment", s)
+			}
+		}
+	}
+	return p, nil
+}
+
+func (p *jsonPredicate) eval(root any) bool {
+	v, ok := lookupJSON(root, p.path)
+	switch p.op {
+	case "":
+		return ok && truthy(v)
+	case "==":
+		return ok && reflect.DeepEqual(v, p.value)
+	case "!=":
+		return !ok || !reflect.DeepEqual(v, p.value)
+	}
+	a, aok := v.(float64)
+	b, bok := p.value.(float64)
+	if !ok || !aok || !bok {
+		return false
+	}
+	switch p.op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	default:
+		return a <= b
+	}
+}
+
+// lookupJSON follows path through decoded JSON; ok is false when a segment
+// does not exist.
+func lookupJSON(v any, path []string) (any, bool) {
+	for _, seg := range path {
+		switch x := v.(type) {
+		case map[string]any:
+			next, found := x[seg]
+			if !found {
+				return nil, false
+			}
+			v = next
+		case []any:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// truthy follows the usual JSON reading: null, false, 0, "" and empty
+// arrays or objects are false.
+func truthy(v any) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []any:
+		return len(x) > 0
+	case map[string]any:
+		return len(x) > 0
+	}
+	return true
+}
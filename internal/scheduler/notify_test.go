@@ -0,0 +1,90 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONPredicate(t *testing.T) {
+	const result = `{"summary":{"count":3,"status":"failed"},"violations":[{"rule":"R1"}],"empty":[]}`
+	for pred, want := range map[string]bool{
+		"violations":                true,
+		"violations.0.rule == R1":   true,
+		`violations.0.rule == "R2"`: false,
+		"violations.5":              false,
+		"empty":                     false,
+		"summary.count > 0":         true,
+		"summary.count >= 3":        true,
+		"summary.count < 3":         false,
+		`summary.status != "ok"`:    true,
+		"summary.missing != 1":      true,
+		"summary.status > 1":        false,
+		".":                         true,
+	} {
+		j := Job{Name: "j", NotifyIf: &NotifyCondition{JSON: pred}}
+		got, err := j.shouldNotify(result)
+		if err != nil {
+			t.Fatalf("%q: %v", pred, err)
+		}
+		if got != want {
+			t.Errorf("%q = %v, want %v", pred, got, want)
+		}
+	}
+}
+
+func TestShouldNotify(t *testing.T) {
+	j := Job{Name: "j", NotifyIf: &NotifyCondition{Match: `(?i)violation`}}
+	if ok, _ := j.shouldNotify("all clear"); ok {
+		t.Error("non-matching result should be suppressed")
+	}
+	if ok, _ := j.shouldNotify("2 Violations found"); !ok {
+		t.Error("matching result should notify")
+	}
+	j.NotifyIf.JSON = "count > 0"
+	if ok, err := j.shouldNotify("violation, not JSON"); ok || err == nil {
+		t.Errorf("non-JSON result with a JSON predicate: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := (&Job{}).shouldNotify(""); !ok {
+		t.Error("a job without a condition always notifies")
+	}
+}
+
+func TestNotifyMessage(t *testing.T) {
+	now := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	j := Job{Name: "audit", NotifyTemplate: `{{.Job}} at {{.Time.Format "15:04"}}: {{len .JSON.violations}} found ({{.Result | printf "%.5s"}})`}
+	got, err := j.notifyMessage(`{"violations":[1,2]}`, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `audit at 09:00: 2 found ({"vio)`; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, _ := (&Job{}).notifyMessage("raw", now); got != "raw" {
+		t.Errorf("no template: got %q", got)
+	}
+}
+
+func TestValidateNotify(t *testing.T) {
+	for name, j := range map[string]Job{
+		"template": {NotifyTemplate: "{{.Result"},
+		"regex":    {NotifyIf: &NotifyCondition{Match: "("}},
+		"json":     {NotifyIf: &NotifyCondition{JSON: "count >"}},
+		"path":     {NotifyIf: &NotifyCondition{JSON: "a..b"}},
+		"target":   {NotifyTargets: []NotifyTarget{{Channel: "slack"}}},
+	} {
+		if err := j.validateNotify(); err == nil || !strings.Contains(err.Error(), "job") {
+			t.Errorf("%s: expected a validation error, got %v", name, err)
+		}
+	}
+}
+
+func TestNotifyTargetsDeduplicates(t *testing.T) {
+	j := Job{NotifyChannel: "slack", NotifyConversationID: "C1", NotifyTargets: []NotifyTarget{
+		{Channel: "slack", ConversationID: "C1"},
+		{Channel: "telegram", ConversationID: "42"},
+	}}
+	if got := j.notifyTargets(); len(got) != 2 || got[1].Channel != "telegram" {
+		t.Errorf("targets = %+v", got)
+	}
+}
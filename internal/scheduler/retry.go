@@ -0,0 +1,189 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// defaultRetryBackoff is the wait before the first retry when a policy
+	// gives none.
+	defaultRetryBackoff = 30 * time.Second
+	// maxRetryAttempts bounds a policy so a broken job cannot hold its
+	// goroutine in retries for hours.
+	maxRetryAttempts = 10
+	// defaultAlertAfter is how many failed runs in a row raise the failure
+	// alert when FailureAlert.After is unset.
+	defaultAlertAfter = 3
+)
+
+// RetryPolicy retries a failed run before it counts as failed.
+type RetryPolicy struct {
+	Attempts int    `yaml:"attempts" json:"attempts"`                   // retries after the first try
+	Backoff  string `yaml:"backoff,omitempty" json:"backoff,omitempty"` // wait before the first retry, doubled for each further one; default 30s
+}
+
+// DeadLetter records a job whose latest run failed even after its retries.
+// It is kept until the job succeeds again or is retried with RetryJob.
+type DeadLetter struct {
+	Job      string    `yaml:"job" json:"job"`
+	Action   string    `yaml:"action" json:"action"`
+	Error    string    `yaml:"error" json:"error"`
+	Attempts int       `yaml:"attempts" json:"attempts"` // tries made by the failed run
+	Failures int       `yaml:"failures" json:"failures"` // failed runs in a row
+	FailedAt time.Time `yaml:"failed_at" json:"failed_at"`
+}
+
+// DeadLetterStore is implemented by job stores that also keep dead letters.
+// Without one, exhausted runs are only logged.
+type DeadLetterStore interface {
+	PutDeadLetter(dl DeadLetter) error // insert or replace by job name
+	LoadDeadLetters() ([]DeadLetter, error)
+	DeleteDeadLetter(job string) error
+}
+
+// FailureAlert names the conversation told when a job keeps failing.
+type FailureAlert struct {
+	Channel        string
+	ConversationID string
+	After          int // failed runs in a row that raise the alert; 0 = 3
+}
+
+// SetFailureAlert enables failure alerts. Call before Start.
+func (s *Scheduler) SetFailureAlert(a FailureAlert) {
+	if a.After <= 0 {
+		a.After = defaultAlertAfter
+	}
+	s.alert = &a
+}
+
+// validateRetry checks the job's retry policy.
+func (j *Job) validateRetry() error {
+	if j.Retry == nil {
+		return nil
+	}
+	if j.Retry.Attempts < 0 || j.Retry.Attempts > maxRetryAttempts {
+		return fmt.Errorf("job %q: retry attempts must be between 0 and %d", j.Name, maxRetryAttempts)
+	}
+	if j.Retry.Backoff != "" {
+		if d, err := time.ParseDuration(j.Retry.Backoff); err != nil || d <= 0 {
+			return fmt.Errorf("job %q: invalid retry backoff %q", j.Name, j.Retry.Backoff)
+		}
+	}
+	return nil
+}
+
+// retryBackoff is the wait before retry n (1-based).
+func (j *Job) retryBackoff(n int) time.Duration {
+	d := defaultRetryBackoff
+	if j.Retry != nil && j.Retry.Backoff != "" {
+		if parsed, err := time.ParseDuration(j.Retry.Backoff); err == nil && parsed > 0 {
+			d = parsed
+		}
+	}
+	return d << (n - 1)
+}
+
+// runWithRetry runs the job's action, retrying under its policy. It returns
+// the result of the first successful try, or the last error, and the number
+// of tries made.
+func (s *Scheduler) runWithRetry(ctx context.Context, job Job, plugin, action string) (string, int, error) {
+	retries := 0
+	if job.Retry != nil {
+		retries = job.Retry.Attempts
+	}
+	for try := 1; ; try++ {
+		result, err := s.runner.RunAction(ctx, plugin, action, job.Args)
+		if err == nil || try > retries {
+			return result, try, err
+		}
+		wait := job.retryBackoff(try)
+		slog.Info("job failed; retrying", "component", "scheduler", "job", job.Name, "attempt", try, "in", wait, "error", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", try, err
+		case <-timer.C:
+		}
+	}
+}
+
+// recordFailure counts a failed run, dead-letters it and raises the failure
+// alert when the job has now failed alert.After runs in a row.
+func (s *Scheduler) recordFailure(rj *runningJob, job Job, tries int, runErr error) {
+	s.mu.Lock()
+	rj.failures++
+	failures := rj.failures
+	s.mu.Unlock()
+
+	if dls, ok := s.store.(DeadLetterStore); ok {
+		dl := DeadLetter{Job: job.Name, Action: job.Action, Error: runErr.Error(), Attempts: tries, Failures: failures, FailedAt: time.Now().UTC()}
+		if err := dls.PutDeadLetter(dl); err != nil {
+			slog.Warn("recording dead letter failed", "component", "scheduler", "job", job.Name, "error", err)
+		}
+	}
+	if s.alert == nil || s.notifier == nil || failures != s.alert.After {
+		return
+	}
+	msg := fmt.Sprintf("Scheduled job %q has failed %d runs in a row (%s). Last error: %v", job.Name, failures, job.Action, runErr)
+	if err := s.notifier.Notify(s.ctx, s.alert.Channel, s.alert.ConversationID, msg); err != nil {
+		slog.Warn("job failure alert failed", "component", "scheduler", "job", job.Name, "error", err)
+	}
+}
+
+// recordSuccess ends a failure streak and drops the job's dead letter.
+func (s *Scheduler) recordSuccess(rj *runningJob, job Job) {
+	s.mu.Lock()
+	failed := rj.failures > 0
+	rj.failures = 0
+	s.mu.Unlock()
+	if failed {
+		s.clearDeadLetter(job.Name)
+	}
+}
+
+func (s *Scheduler) clearDeadLetter(name string) {
+	if dls, ok := s.store.(DeadLetterStore); ok {
+		if err := dls.DeleteDeadLetter(name); err != nil {
+			slog.Warn("clearing dead letter failed", "component", "scheduler", "job", name, "error", err)
+		}
+	}
+}
+
+// DeadLetters returns the recorded dead letters; nil when the store keeps
+// none.
+func (s *Scheduler) DeadLetters() ([]DeadLetter, error) {
+	dls, ok := s.store.(DeadLetterStore)
+	if !ok {
+		return nil, nil
+	}
+	return dls.LoadDeadLetters()
+}
+
+// RetryJob runs a job now, outside its schedule and with its retry policy,
+// delivering the result as a scheduled run would. A success clears the
+// job's dead letter.
+func (s *Scheduler) RetryJob(ctx context.Context, name string) error {
+	s.mu.RLock()
+	rj, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("job %q not found", name)
+	}
+	if !s.beginRun(rj) {
+		return fmt.Errorf("job %q is already running", name)
+	}
+	defer s.endRun(rj)
+	if !s.acquireSlot() {
+		return fmt.Errorf("scheduler stopped")
+	}
+	defer s.releaseSlot()
+	if err := s.runOnce(ctx, rj); err != nil {
+		return fmt.Errorf("job %q failed: %w", name, err)
+	}
+	s.clearDeadLetter(name)
+	return nil
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyRunner fails its first failN calls, then returns "done".
+type flakyRunner struct {
+	mu    sync.Mutex
+	calls int
+	failN int
+}
+
+func (f *flakyRunner) RunAction(context.Context, string, string, map[string]string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.failN {
+		return "", errors.New("upstream down")
+	}
+	return "done", nil
+}
+
+func (f *flakyRunner) set(failN int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls, f.failN = 0, failN
+}
+
+func startPaused(t *testing.T, runner ActionRunner, notifier Notifier, job Job) *Scheduler {
+	t.Helper()
+	s := New(runner, notifier, t.TempDir())
+	job.Paused = true
+	if err := s.Start([]Job{job}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestRetrySucceedsWithinPolicy(t *testing.T) {
+	runner := &flakyRunner{failN: 2}
+	notifier := &fakeNotifier{}
+	s := startPaused(t, runner, notifier, Job{Name: "flaky", Interval: "1h", Action: "x.y",
+		NotifyChannel: "slack", NotifyConversationID: "C1", Retry: &RetryPolicy{Attempts: 2, Backoff: "1ms"}})
+
+	if err := s.RetryJob(context.Background(), "flaky"); err != nil {
+		t.Fatal(err)
+	}
+	if runner.calls != 3 {
+		t.Errorf("calls = %d, want 3", runner.calls)
+	}
+	if notifier.messageCount() != 1 || notifier.messages[0].Content != "done" {
+		t.Errorf("notifications = %+v", notifier.messages)
+	}
+	if dls, _ := s.DeadLetters(); len(dls) != 0 {
+		t.Errorf("a run that succeeded on retry left dead letters: %+v", dls)
+	}
+}
+
+func TestRetryExhaustedDeadLettersAndAlerts(t *testing.T) {
+	runner := &flakyRunner{failN: 100}
+	notifier := &fakeNotifier{}
+	s := startPaused(t, runner, notifier, Job{Name: "broken", Interval: "1h", Action: "x.y",
+		NotifyChannel: "slack", NotifyConversationID: "C1", Retry: &RetryPolicy{Attempts: 1, Backoff: "1ms"}})
+	s.SetFailureAlert(FailureAlert{Channel: "slack", ConversationID: "OPS", After: 2})
+	ctx := context.Background()
+
+	if err := s.RetryJob(ctx, "broken"); err == nil || !strings.Contains(err.Error(), "upstream down") {
+		t.Fatalf("err = %v", err)
+	}
+	if notifier.messageCount() != 0 {
+		t.Errorf("alert raised after one failed run: %+v", notifier.messages)
+	}
+	_ = s.RetryJob(ctx, "broken")
+	dls, err := s.DeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dls) != 1 || dls[0].Job != "broken" || dls[0].Attempts != 2 || dls[0].Failures != 2 || dls[0].Error != "upstream down" {
+		t.Fatalf("dead letters = %+v", dls)
+	}
+	if notifier.messageCount() != 1 || notifier.messages[0].ConversationID != "OPS" || !strings.Contains(notifier.messages[0].Content, `"broken" has failed 2 runs`) {
+		t.Fatalf("alert = %+v", notifier.messages)
+	}
+
+	runner.set(0)
+	if err := s.RetryJob(ctx, "broken"); err != nil {
+		t.Fatal(err)
+	}
+	if dls, _ := s.DeadLetters(); len(dls) != 0 {
+		t.Errorf("dead letter kept after a successful retry: %+v", dls)
+	}
+	if err := s.RetryJob(ctx, "missing"); err == nil {
+		t.Error("retrying an unknown job should fail")
+	}
+}
+
+func TestValidateRetry(t *testing.T) {
+	for _, p := range []RetryPolicy{{Attempts: -1}, {Attempts: maxRetryAttempts + 1}, {Attempts: 1, Backoff: "soon"}, {Attempts: 1, Backoff: "0s"}} {
+		j := Job{Name: "j", Retry: &p}
+		if err := j.validateRetry(); err == nil {
+			t.Errorf("%+v: expected an error", p)
+		}
+	}
+	j := Job{Retry: &RetryPolicy{Attempts: 3, Backoff: "2s"}}
+	if err := j.validateRetry(); err != nil {
+		t.Fatal(err)
+	}
+	if got := []time.Duration{j.retryBackoff(1), j.retryBackoff(2), j.retryBackoff(3)}; got[0] != 2*time.Second || got[2] != 8*time.Second {
+		t.Errorf("backoff = %v", got)
+	}
+	if got := (&Job{}).retryBackoff(1); got != defaultRetryBackoff {
+		t.Errorf("default backoff = %v", got)
+	}
+}
@@ -2,14 +2,18 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/eventbus"
 	"github.com/opentalon/opentalon/pkg/toolfqn"
 	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
@@ -47,21 +51,49 @@ type Job struct {
 	CreatedBy            string `yaml:"created_by,omitempty" json:"created_by,omitempty"` // raw sender/actor ID used at creation
 	EntityID             string `yaml:"entity_id,omitempty" json:"entity_id,omitempty"`   // tenant identity (profile.EntityID) — empty when no profile system is configured
 	Group                string `yaml:"group,omitempty" json:"group,omitempty"`           // tenant group (profile.Group) — empty when no profile system is configured
+	// NotifyTargets are further destinations that receive the same
+	// notification as NotifyChannel, e.g. a team room besides the creator's
+	// own chat.
+	NotifyTargets []NotifyTarget `yaml:"notify_targets,omitempty" json:"notify_targets,omitempty"`
+	// NotifyTemplate is a Go text/template rendering the notification from
+	// the result ({{.Result}}, {{.JSON}}, {{.Job}}, {{.Time}}); empty sends
+	// the result as-is.
+	NotifyTemplate string `yaml:"notify_template,omitempty" json:"notify_template,omitempty"`
+	// NotifyIf, when set, suppresses notifications whose result does not
+	// satisfy it. The run itself is unaffected.
+	NotifyIf *NotifyCondition `yaml:"notify_if,omitempty" json:"notify_if,omitempty"`
+	// Retry, when set, retries a failed run with backoff before it is
+	// dead-lettered.
+	Retry *RetryPolicy `yaml:"retry,omitempty" json:"retry,omitempty"`
+	// NoOverlap skips a fire while the job's previous run is still going.
+	NoOverlap bool `yaml:"no_overlap,omitempty" json:"no_overlap,omitempty"`
+	// Jitter delays each fire by a random duration below it (Go duration);
+	// empty uses the scheduler's default.
+	Jitter string `yaml:"jitter,omitempty" json:"jitter,omitempty"`
 }
 
 // schedule computes successive fire times for a job.
+// slot names the fire at t for RunClaimer: replicas whose timers fire for
+// the same occurrence must map it to the same slot.
 type schedule interface {
 	next(time.Time) time.Time
+	slot(time.Time) time.Time
 }
 
 type intervalSchedule struct{ d time.Duration }
 
 func (s intervalSchedule) next(t time.Time) time.Time { return t.Add(s.d) }
 
+// Interval timers start with the process, so replicas fire out of phase;
+// one run per interval-aligned window is what they agree on.
+func (s intervalSchedule) slot(t time.Time) time.Time { return t.UTC().Truncate(s.d) }
+
 type cronSchedule struct{ s cron.Schedule }
 
 func (c cronSchedule) next(t time.Time) time.Time { return c.s.Next(t) }
 
+func (c cronSchedule) slot(t time.Time) time.Time { return t.UTC().Truncate(time.Minute) }
+
 // oneShotSchedule fires exactly once at the given time. After firing, next()
 // returns the zero Time to signal the runner to exit.
 type oneShotSchedule struct {
@@ -77,15 +109,46 @@ func (s *oneShotSchedule) next(now time.Time) time.Time {
 	return s.t
 }
 
+func (s *oneShotSchedule) slot(time.Time) time.Time { return s.t.UTC() }
+
 var (
 	ErrConfigProtected = fmt.Errorf("config-defined jobs cannot be modified or removed")
 	ErrNotAuthorized   = fmt.Errorf("not authorized — only designated approvers can manage scheduled jobs")
 )
 
+// PendingApprovalError is returned by AddJob when a non-approver's job was
+// filed in the approval queue instead of being created.
+type PendingApprovalError struct {
+	RequestID string
+}
+
+func (e *PendingApprovalError) Error() string {
+	return fmt.Sprintf("job submitted for approval (request %s); it is created once an approver accepts it", e.RequestID)
+}
+
 func (j *Job) parseDuration() (time.Duration, error) {
 	return time.ParseDuration(j.Interval)
 }
 
+// validate checks everything about the job that can be checked before it is
+// registered: schedule, action, notification, retry and jitter.
+func (j *Job) validate() error {
+	if _, err := j.schedule(); err != nil {
+		return err
+	}
+	if _, _, err := j.parseAction(); err != nil {
+		return err
+	}
+	if err := j.validateNotify(); err != nil {
+		return err
+	}
+	if err := j.validateRetry(); err != nil {
+		return err
+	}
+	_, err := j.parseJitter()
+	return err
+}
+
 // schedule parses and validates the job's time spec. Exactly one of
 // Interval, Cron, or At must be set.
 func (j *Job) schedule() (schedule, error) {
@@ -160,6 +223,10 @@ type runningJob struct {
 	// for this job so recurring legacy jobs (e.g. "@every 5m") don't spam the
 	// log on every fire. Reset per process lifetime.
 	warnedMissingConv bool
+	// failures counts failed runs in a row, for the failure alert.
+	failures int
+	// active counts runs in progress, for NoOverlap.
+	active int
 }
 
 // Scheduler manages periodic background jobs.
@@ -168,10 +235,15 @@ type Scheduler struct {
 	jobs     map[string]*runningJob
 	runner   ActionRunner
 	notifier Notifier
-	dataDir  string
+	store    JobStore // dynamic jobs; nil = not persisted
+	events   *eventbus.Bus
+	alert    *FailureAlert // nil = no failure alerts
+	slots    chan struct{} // execution slots; nil = unlimited
+	jitter   time.Duration // default jitter for jobs without their own
 
 	approvers      map[string]bool
 	maxJobsPerUser int
+	approvals      *approval.Queue // when set, non-approver job creation is queued instead of refused
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -191,11 +263,15 @@ func NewWithPolicy(runner ActionRunner, notifier Notifier, dataDir string, appro
 	for _, a := range approvers {
 		aMap[a] = true
 	}
+	var store JobStore
+	if dataDir != "" {
+		store = newFileJobStore(dataDir)
+	}
 	return &Scheduler{
 		jobs:           make(map[string]*runningJob),
 		runner:         runner,
 		notifier:       notifier,
-		dataDir:        dataDir,
+		store:          store,
 		approvers:      aMap,
 		maxJobsPerUser: maxPerUser,
 		ctx:            ctx,
@@ -203,6 +279,13 @@ func NewWithPolicy(runner ActionRunner, notifier Notifier, dataDir string, appro
 	}
 }
 
+// SetApprovalQueue routes job creation by non-approvers through q: AddJob
+// files a KindJob request and the job is created when an admin approves it.
+func (s *Scheduler) SetApprovalQueue(q *approval.Queue) {
+	s.approvals = q
+	q.Handle(approval.KindJob, s.runApprovedJob)
+}
+
 func (s *Scheduler) isApprover(userID string) bool {
 	if len(s.approvers) == 0 {
 		return true
@@ -229,25 +312,27 @@ func (s *Scheduler) Start(staticJobs []Job) error {
 		}
 	}
 
-	dynamicJobs, err := s.loadDynamic()
+	if s.store == nil {
+		return nil
+	}
+	dynamicJobs, err := s.store.LoadJobs()
 	if err != nil {
 		slog.Warn("loading dynamic jobs failed", "component", "scheduler", "error", err)
 	}
-	rejected := 0
 	for _, j := range dynamicJobs {
 		j.Source = "dynamic"
 		if err := s.addJobLocked(j); err != nil {
 			slog.Warn("skipping dynamic job", "component", "scheduler", "job", j.Name, "error", err)
-			rejected++
+			// Prune rejected jobs (e.g. past-due one-shots) from the store.
+			if err := s.deleteJob(j.Name); err != nil {
+				slog.Warn("persist after load pruning failed", "component", "scheduler", "error", err)
+			}
 		}
 	}
-	// Re-persist so rejected (e.g. past-due one-shots) are pruned from disk.
-	if rejected > 0 {
-		if err := s.persistDynamic(); err != nil {
-			slog.Warn("persist after load pruning failed", "component", "scheduler", "error", err)
-		}
+	if _, shared := s.store.(RunClaimer); shared {
+		s.wg.Add(1)
+		go s.syncShared()
 	}
-
 	return nil
 }
 
@@ -257,27 +342,17 @@ func (s *Scheduler) Stop() {
 	s.wg.Wait()
 }
 
-// AddJob creates a new dynamic job at runtime.
+// AddJob creates a new dynamic job at runtime. When userID is not an approver
+// and an approval queue is set, the job is validated and filed for approval
+// instead, and a *PendingApprovalError carrying the request id is returned.
 func (s *Scheduler) AddJob(job Job, userID string) error {
 	if !s.isApprover(userID) {
-		return ErrNotAuthorized
-	}
-	job.Source = "dynamic"
-	job.CreatedBy = userID
-
-	if s.maxJobsPerUser > 0 {
-		s.mu.RLock()
-		count := s.countUserJobs(userID)
-		s.mu.RUnlock()
-		if count >= s.maxJobsPerUser {
-			return fmt.Errorf("job limit reached: user %q already has %d jobs (max %d)", userID, count, s.maxJobsPerUser)
+		if s.approvals == nil {
+			return ErrNotAuthorized
 		}
+		return s.submitJob(job, userID)
 	}
-
-	if err := s.addJobLocked(job); err != nil {
-		return err
-	}
-	return s.persistDynamic()
+	return s.addDynamicJob(job, userID)
 }
 
 // AddPersonalJob creates a dynamic job on behalf of userID without the approver
@@ -287,22 +362,85 @@ func (s *Scheduler) AddPersonalJob(job Job, userID string) error {
 	if userID == "" {
 		return fmt.Errorf("user_id required for personal job")
 	}
+	return s.addDynamicJob(job, userID)
+}
+
+func (s *Scheduler) addDynamicJob(job Job, userID string) error {
 	job.Source = "dynamic"
 	job.CreatedBy = userID
 
-	if s.maxJobsPerUser > 0 {
-		s.mu.RLock()
-		count := s.countUserJobs(userID)
-		s.mu.RUnlock()
-		if count >= s.maxJobsPerUser {
-			return fmt.Errorf("job limit reached: user %q already has %d jobs (max %d)", userID, count, s.maxJobsPerUser)
-		}
+	if err := s.checkJobLimit(userID); err != nil {
+		return err
 	}
-
 	if err := s.addJobLocked(job); err != nil {
 		return err
 	}
-	return s.persistDynamic()
+	return s.putJob(job)
+}
+
+func (s *Scheduler) checkJobLimit(userID string) error {
+	if s.maxJobsPerUser <= 0 {
+		return nil
+	}
+	s.mu.RLock()
+	count := s.countUserJobs(userID)
+	s.mu.RUnlock()
+	if count >= s.maxJobsPerUser {
+		return fmt.Errorf("job limit reached: user %q already has %d jobs (max %d)", userID, count, s.maxJobsPerUser)
+	}
+	return nil
+}
+
+// submitJob files job in the approval queue. Everything that can be checked
+// up front is, so approvers only see jobs that would be created as filed.
+func (s *Scheduler) submitJob(job Job, userID string) error {
+	if err := job.validate(); err != nil {
+		return err
+	}
+	if err := s.checkJobLimit(userID); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	_, exists := s.jobs[job.Name]
+	s.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("job %q already exists", job.Name)
+	}
+	data, err := yaml.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("marshaling job: %w", err)
+	}
+	when := "every " + job.Interval
+	if job.Cron != "" {
+		when = "cron " + job.Cron
+	} else if job.At != "" {
+		when = "at " + job.At
+	}
+	req, err := s.approvals.Submit(approval.Request{
+		Kind:           approval.KindJob,
+		Summary:        fmt.Sprintf("scheduled job %q: %s %s", job.Name, job.Action, when),
+		Requester:      userID,
+		ChannelID:      job.NotifyChannel,
+		ConversationID: job.NotifyConversationID,
+		Payload:        map[string]string{"job": string(data), "user_id": userID},
+	})
+	if err != nil {
+		return fmt.Errorf("filing job for approval: %w", err)
+	}
+	return &PendingApprovalError{RequestID: req.ID}
+}
+
+// runApprovedJob is the approval.KindJob handler: it creates the job filed by
+// submitJob on behalf of its original requester.
+func (s *Scheduler) runApprovedJob(_ context.Context, req approval.Request) (string, error) {
+	var job Job
+	if err := yaml.Unmarshal([]byte(req.Payload["job"]), &job); err != nil {
+		return "", fmt.Errorf("decoding job: %w", err)
+	}
+	if err := s.addDynamicJob(job, req.Payload["user_id"]); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Job %q created.", job.Name), nil
 }
 
 // RemoveJob stops and removes a job by name. Config-defined jobs cannot be removed.
@@ -310,6 +448,12 @@ func (s *Scheduler) RemoveJob(name, userID string) error {
 	if !s.isApprover(userID) {
 		return ErrNotAuthorized
 	}
+	return s.DeleteJob(name)
+}
+
+// DeleteJob removes a dynamic job without RemoveJob's approver check. It
+// serves the admin API, whose callers are trusted like the state database.
+func (s *Scheduler) DeleteJob(name string) error {
 	s.mu.Lock()
 	rj, ok := s.jobs[name]
 	if !ok {
@@ -324,7 +468,7 @@ func (s *Scheduler) RemoveJob(name, userID string) error {
 	delete(s.jobs, name)
 	s.mu.Unlock()
 
-	return s.persistDynamic()
+	return s.deleteJob(name)
 }
 
 // PauseJob pauses a running job.
@@ -337,9 +481,10 @@ func (s *Scheduler) PauseJob(name string) error {
 	}
 	rj.cancel()
 	rj.job.Paused = true
+	job := rj.job
 	s.mu.Unlock()
 
-	return s.persistDynamic()
+	return s.putJob(job)
 }
 
 // ResumeJob resumes a paused job.
@@ -355,10 +500,11 @@ func (s *Scheduler) ResumeJob(name string) error {
 		return fmt.Errorf("job %q is not paused", name)
 	}
 	rj.job.Paused = false
+	job := rj.job
 	s.mu.Unlock()
 
 	s.startTicker(rj)
-	return s.persistDynamic()
+	return s.putJob(job)
 }
 
 // UpdateJob updates the time spec (interval or cron), notify channel, and/or
@@ -415,7 +561,73 @@ func (s *Scheduler) UpdateJob(name, userID string, interval, cronExpr, notifyCha
 	s.mu.Unlock()
 
 	s.startTicker(rj)
-	return s.persistDynamic()
+	return s.putJob(patched)
+}
+
+// ReplaceStaticJobs reconciles the config-defined jobs with staticJobs after a
+// config reload: jobs no longer listed are stopped, new ones are started, and
+// a job whose definition changed is restarted with the new definition.
+// Unchanged jobs keep running undisturbed (their tick phase and pause state
+// survive), as does a job whose new definition is invalid. Dynamic jobs are
+// never touched; a static job whose name is taken by a dynamic job is
+// skipped, as at startup. err joins the reasons of every job that was kept
+// or skipped, so the caller can retry the same list later.
+func (s *Scheduler) ReplaceStaticJobs(staticJobs []Job) (added, removed, changed []string, err error) {
+	var errs []error
+	want := make(map[string]Job, len(staticJobs))
+	for _, j := range staticJobs {
+		j.Source = "config"
+		want[j.Name] = j
+	}
+
+	s.mu.Lock()
+	for name, rj := range s.jobs {
+		if rj.job.Source != "config" {
+			continue
+		}
+		next, ok := want[name]
+		if !ok {
+			rj.cancel()
+			delete(s.jobs, name)
+			removed = append(removed, name)
+			continue
+		}
+		cur := rj.job
+		cur.Paused = false
+		if reflect.DeepEqual(cur, next) {
+			delete(want, name)
+			continue
+		}
+		// An edit that does not validate keeps the job as it runs now
+		// rather than dropping it.
+		if err := next.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("job %s kept: %w", name, err))
+			delete(want, name)
+			continue
+		}
+		rj.cancel()
+		delete(s.jobs, name)
+		changed = append(changed, name)
+	}
+	s.mu.Unlock()
+
+	for _, j := range staticJobs {
+		if _, ok := want[j.Name]; !ok {
+			continue
+		}
+		j.Source = "config"
+		if err := s.addJobLocked(j); err != nil {
+			errs = append(errs, fmt.Errorf("job %s skipped: %w", j.Name, err))
+			continue
+		}
+		if !slices.Contains(changed, j.Name) {
+			added = append(added, j.Name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+	return added, removed, changed, errors.Join(errs...)
 }
 
 // ListJobs returns all registered jobs.
@@ -487,6 +699,16 @@ func (s *Scheduler) ListJobsForCaller(entityID, userID string) []Job {
 	return out
 }
 
+// ListJobsForContext lists the jobs of the caller in ctx, identified as the
+// scheduler tool identifies it; none without caller context.
+func (s *Scheduler) ListJobsForContext(ctx context.Context) []Job {
+	caller, err := resolveCaller(ctx)
+	if err != nil {
+		return nil
+	}
+	return s.ListJobsForCaller(caller.entityID, caller.userID)
+}
+
 // GetJob returns a job by name.
 func (s *Scheduler) GetJob(name string) (Job, bool) {
 	s.mu.RLock()
@@ -503,10 +725,7 @@ func (s *Scheduler) addJobLocked(job Job) error {
 	if job.Name == "" {
 		return fmt.Errorf("job name is required")
 	}
-	if _, err := job.schedule(); err != nil {
-		return err
-	}
-	if _, _, err := job.parseAction(); err != nil {
+	if err := job.validate(); err != nil {
 		return err
 	}
 
@@ -562,15 +781,21 @@ func (s *Scheduler) runJob(ctx context.Context, rj *runningJob) {
 		return
 	}
 
+	var lag time.Duration // jitter of the previous fire
 	for {
 		now := time.Now()
-		fireAt := sch.next(now)
+		// Schedule from the previous fire's nominal time, so jitter does not
+		// accumulate into interval drift.
+		fireAt := sch.next(now.Add(-lag))
 		if fireAt.IsZero() {
 			// schedule has no more fires (one-shot already fired)
 			s.removeOneShot(job.Name)
 			return
 		}
-		wait := fireAt.Sub(now)
+		// Jitter only delays the timer; the claim still names the
+		// scheduled slot, which every replica agrees on.
+		lag = s.jitterFor(job)
+		wait := fireAt.Sub(now) + lag
 		if wait < 0 {
 			wait = 0
 		}
@@ -580,13 +805,15 @@ func (s *Scheduler) runJob(ctx context.Context, rj *runningJob) {
 			timer.Stop()
 			return
 		case <-timer.C:
-			s.executeJob(rj)
+			if s.claim(job, sch, fireAt) {
+				s.dispatch(rj, job.Name)
+			}
 		}
 	}
 }
 
 // removeOneShot removes a one-shot job from the registry after it has fired,
-// and re-persists the dynamic jobs file.
+// and from the job store.
 func (s *Scheduler) removeOneShot(name string) {
 	s.mu.Lock()
 	rj, ok := s.jobs[name]
@@ -598,29 +825,66 @@ func (s *Scheduler) removeOneShot(name string) {
 	delete(s.jobs, name)
 	s.mu.Unlock()
 	if isDynamic {
-		if err := s.persistDynamic(); err != nil {
+		if err := s.deleteJob(name); err != nil {
 			slog.Warn("persist after one-shot removal failed", "component", "scheduler", "job", name, "error", err)
 		}
 	}
 }
 
 func (s *Scheduler) executeJob(rj *runningJob) {
+	_ = s.runOnce(s.ctx, rj)
+}
+
+// runOnce runs the job under its retry policy, records the outcome and
+// delivers the result.
+func (s *Scheduler) runOnce(ctx context.Context, rj *runningJob) error {
 	job := s.snapshotJob(rj)
 
 	plugin, action, err := job.parseAction()
 	if err != nil {
 		slog.Warn("job bad action", "component", "scheduler", "job", job.Name, "error", err)
-		return
+		return err
 	}
 
-	result, err := s.runner.RunAction(s.ctx, plugin, action, job.Args)
+	result, tries, err := s.runWithRetry(context.WithValue(ctx, jobKey{}, job), job, plugin, action)
+	s.publishRun(job, err)
 	if err != nil {
-		slog.Warn("job execution failed", "component", "scheduler", "job", job.Name, "error", err)
-		return
+		slog.Warn("job execution failed", "component", "scheduler", "job", job.Name, "attempts", tries, "error", err)
+		s.recordFailure(rj, job, tries, err)
+		return err
 	}
+	s.recordSuccess(rj, job)
+	s.notifyResult(rj, job, result)
+	return nil
+}
+
+type jobKey struct{}
 
-	if job.NotifyChannel != "" && s.notifier != nil {
-		if job.NotifyConversationID == "" {
+// JobFromContext returns the job whose action ctx runs; ok is false outside a
+// scheduler run. Actions use it to tell operator-configured jobs (Source
+// "config") from ones users created.
+func JobFromContext(ctx context.Context) (job Job, ok bool) {
+	job, ok = ctx.Value(jobKey{}).(Job)
+	return job, ok
+}
+
+// notifyResult sends a successful run's result to the job's targets.
+func (s *Scheduler) notifyResult(rj *runningJob, job Job, result string) {
+	targets := job.notifyTargets()
+	if len(targets) == 0 || s.notifier == nil {
+		return
+	}
+	if ok, err := job.shouldNotify(result); !ok {
+		slog.Debug("job notify skipped: result does not match notify_if", "component", "scheduler", "job", job.Name, "error", err)
+		return
+	}
+	msg, err := job.notifyMessage(result, time.Now())
+	if err != nil {
+		slog.Warn("job notify template failed; sending the raw result", "component", "scheduler", "job", job.Name, "error", err)
+		msg = result
+	}
+	for _, t := range targets {
+		if t.ConversationID == "" {
 			// Pre-fix jobs persisted without a conversation id would render an
 			// empty chat_id on the channel side and fail with a cryptic 400.
 			// Warn once per job per process lifetime and skip the notify —
@@ -632,63 +896,25 @@ func (s *Scheduler) executeJob(rj *runningJob) {
 			s.mu.Unlock()
 			if !alreadyWarned {
 				slog.Warn("job notify skipped: missing conversation id — recreate the job",
-					"component", "scheduler", "job", job.Name, "channel", job.NotifyChannel)
-			}
-		} else {
-			if err := s.notifier.Notify(s.ctx, job.NotifyChannel, job.NotifyConversationID, result); err != nil {
-				slog.Warn("job notify failed", "component", "scheduler", "job", job.Name, "error", err)
+					"component", "scheduler", "job", job.Name, "channel", t.Channel)
 			}
+			continue
 		}
-	}
-}
-
-func (s *Scheduler) persistPath() string {
-	return filepath.Join(s.dataDir, "scheduler", "jobs.yaml")
-}
-
-func (s *Scheduler) persistDynamic() error {
-	if s.dataDir == "" {
-		return nil
-	}
-
-	s.mu.RLock()
-	var dynamicJobs []Job
-	for _, rj := range s.jobs {
-		if rj.job.Source == "dynamic" {
-			dynamicJobs = append(dynamicJobs, rj.job)
+		if err := s.notifier.Notify(s.ctx, t.Channel, t.ConversationID, msg); err != nil {
+			slog.Warn("job notify failed", "component", "scheduler", "job", job.Name, "channel", t.Channel, "error", err)
 		}
 	}
-	s.mu.RUnlock()
-
-	dir := filepath.Dir(s.persistPath())
-	if err := os.MkdirAll(dir, 0700); err != nil {
-		return fmt.Errorf("creating scheduler dir: %w", err)
-	}
-
-	data, err := yaml.Marshal(dynamicJobs)
-	if err != nil {
-		return fmt.Errorf("marshaling jobs: %w", err)
-	}
-
-	return os.WriteFile(s.persistPath(), data, 0600)
 }
 
-func (s *Scheduler) loadDynamic() ([]Job, error) {
-	if s.dataDir == "" {
-		return nil, nil
-	}
+// SetEventBus publishes a job_run event after every run. Call before Start.
+func (s *Scheduler) SetEventBus(b *eventbus.Bus) {
+	s.events = b
+}
 
-	data, err := os.ReadFile(s.persistPath())
+func (s *Scheduler) publishRun(job Job, err error) {
+	data := map[string]string{"job": job.Name, "action": job.Action, "status": "ok"}
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("reading jobs file: %w", err)
-	}
-
-	var jobs []Job
-	if err := yaml.Unmarshal(data, &jobs); err != nil {
-		return nil, fmt.Errorf("parsing jobs file: %w", err)
+		data["status"], data["error"] = "error", err.Error()
 	}
-	return jobs, nil
+	s.events.Publish(s.ctx, eventbus.Event{Type: eventbus.JobRun, Data: data})
 }
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -11,6 +12,8 @@ import (
 	"time"
 
 	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/approval"
+	"github.com/opentalon/opentalon/internal/eventbus"
 	"github.com/opentalon/opentalon/internal/orchestrator"
 	"github.com/opentalon/opentalon/internal/profile"
 )
@@ -153,6 +156,49 @@ func TestSchedulerTickExecution(t *testing.T) {
 	}
 }
 
+// jobRunner records the job each run finds in its context.
+type jobRunner struct {
+	mu   sync.Mutex
+	jobs []Job
+}
+
+func (r *jobRunner) RunAction(ctx context.Context, _, _ string, _ map[string]string) (string, error) {
+	job, ok := JobFromContext(ctx)
+	if !ok {
+		return "", errors.New("no job in context")
+	}
+	r.mu.Lock()
+	r.jobs = append(r.jobs, job)
+	r.mu.Unlock()
+	return "ok", nil
+}
+
+func TestSchedulerRunCarriesJob(t *testing.T) {
+	runner := &jobRunner{}
+	s := New(runner, nil, "")
+	if err := s.Start([]Job{{Name: "static", Interval: "50ms", Action: "test.ping"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddJob(Job{Name: "mine", Interval: "50ms", Action: "test.ping"}, "U1"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(120 * time.Millisecond)
+	s.Stop()
+
+	runner.mu.Lock()
+	defer runner.mu.Unlock()
+	sources := map[string]string{}
+	for _, j := range runner.jobs {
+		sources[j.Name] = j.Source
+	}
+	if sources["static"] != "config" || sources["mine"] != "dynamic" {
+		t.Errorf("job sources seen by runs = %v", sources)
+	}
+	if _, ok := JobFromContext(context.Background()); ok {
+		t.Error("job found outside a run")
+	}
+}
+
 func TestSchedulerNotification(t *testing.T) {
 	runner := &fakeRunner{results: map[string]string{"test.ping": "pong"}}
 	notifier := &fakeNotifier{}
@@ -208,6 +254,53 @@ func TestSchedulerNotifySkipsWithoutConversationID(t *testing.T) {
 	}
 }
 
+func TestSchedulerNotifyFanOutAndCondition(t *testing.T) {
+	runner := &fakeRunner{results: map[string]string{
+		"audit.check": `{"violations":["R1"]}`,
+		"audit.quiet": `{"violations":[]}`,
+	}}
+	notifier := &fakeNotifier{}
+	s := New(runner, notifier, "")
+
+	targets := []NotifyTarget{{Channel: "telegram", ConversationID: "42"}}
+	err := s.Start([]Job{
+		{Name: "loud", Interval: "50ms", Action: "audit.check", NotifyChannel: "slack", NotifyConversationID: "C1",
+			NotifyTargets: targets, NotifyTemplate: "found {{len .JSON.violations}}", NotifyIf: &NotifyCondition{JSON: "violations"}},
+		{Name: "quiet", Interval: "50ms", Action: "audit.quiet", NotifyChannel: "slack", NotifyConversationID: "C1",
+			NotifyTargets: targets, NotifyIf: &NotifyCondition{JSON: "violations"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	s.Stop()
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	// Only "loud" notifies, once per target on every run.
+	if n := len(notifier.messages); n < 2 || n%2 != 0 {
+		t.Fatalf("expected one notification per target, got %+v", notifier.messages)
+	}
+	for i, want := range []string{"slack", "telegram"} {
+		if m := notifier.messages[i]; m.ChannelID != want || m.Content != "found 1" {
+			t.Errorf("message %d = %+v", i, m)
+		}
+	}
+}
+
+func TestSchedulerAddJobRejectsBadNotifyTemplate(t *testing.T) {
+	s := New(&fakeRunner{}, &fakeNotifier{}, "")
+	if err := s.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	err := s.AddJob(Job{Name: "bad", Interval: "1h", Action: "test.ping", NotifyTemplate: "{{.Result"}, "u1")
+	if err == nil {
+		t.Fatal("expected a template error")
+	}
+}
+
 func TestSchedulerDynamicCRUD(t *testing.T) {
 	runner := &fakeRunner{}
 	dir := t.TempDir()
@@ -548,6 +641,31 @@ func TestSchedulerRunnerError(t *testing.T) {
 	}
 }
 
+func TestSchedulerPublishesJobRun(t *testing.T) {
+	runner := &fakeRunner{err: fmt.Errorf("plugin crashed")}
+	bus := eventbus.New(10)
+	got := make(chan eventbus.Event, 10)
+	bus.Subscribe(eventbus.JobRun, "test", func(_ context.Context, e eventbus.Event) error {
+		got <- e
+		return nil
+	})
+	s := New(runner, nil, "")
+	s.SetEventBus(bus)
+	if err := s.Start([]Job{{Name: "err-test", Interval: "50ms", Action: "crash.now"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	select {
+	case e := <-got:
+		if e.Data["job"] != "err-test" || e.Data["action"] != "crash.now" || e.Data["status"] != "error" || e.Data["error"] != "plugin crashed" {
+			t.Errorf("job_run data = %v", e.Data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no job_run event")
+	}
+}
+
 func TestSchedulerResumeNonPaused(t *testing.T) {
 	runner := &fakeRunner{}
 	s := New(runner, nil, "")
@@ -692,6 +810,27 @@ func TestSchedulerApproverDeleteRequired(t *testing.T) {
 	}
 }
 
+func TestSchedulerDeleteJobSkipsApprovers(t *testing.T) {
+	s := NewWithPolicy(&fakeRunner{}, nil, "", []string{"admin@co.com"}, 0)
+	if err := s.Start([]Job{{Name: "static1", Interval: "1h", Action: "a.b"}}); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if err := s.AddJob(Job{Name: "j1", Interval: "1h", Action: "a.b"}, "admin@co.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteJob("j1"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	if _, ok := s.GetJob("j1"); ok {
+		t.Error("j1 still exists")
+	}
+	if err := s.DeleteJob("static1"); err != ErrConfigProtected {
+		t.Errorf("deleting a config job: got %v", err)
+	}
+}
+
 func TestSchedulerApproverUpdateRequired(t *testing.T) {
 	runner := &fakeRunner{}
 	s := NewWithPolicy(runner, nil, "", []string{"admin@co.com"}, 0)
@@ -749,6 +888,44 @@ func TestSchedulerMultipleApprovers(t *testing.T) {
 	}
 }
 
+func TestSchedulerApprovalQueue(t *testing.T) {
+	notifier := &fakeNotifier{}
+	s := NewWithPolicy(&fakeRunner{}, notifier, "", []string{"admin@co.com"}, 0)
+	if err := s.Start(nil); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	q, err := approval.NewQueue(notifier, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetApprovalQueue(q)
+
+	if err := s.AddJob(Job{Name: "bad", Interval: "nope", Action: "a.b"}, "random@co.com"); err == nil || errors.As(err, new(*PendingApprovalError)) {
+		t.Errorf("invalid job should be rejected up front, got %v", err)
+	}
+	job := Job{Name: "j1", Interval: "1h", Action: "a.b", NotifyChannel: "slack", NotifyConversationID: "C1"}
+	err = s.AddJob(job, "random@co.com")
+	var pending *PendingApprovalError
+	if !errors.As(err, &pending) {
+		t.Fatalf("non-approver should be queued, got %v", err)
+	}
+	if _, ok := s.GetJob("j1"); ok {
+		t.Fatal("job must not exist before approval")
+	}
+
+	if _, err := q.Approve(context.Background(), pending.RequestID, "admin@co.com", ""); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := s.GetJob("j1")
+	if !ok || got.CreatedBy != "random@co.com" || got.Source != "dynamic" {
+		t.Errorf("approved job = %+v, %v; want created on behalf of the requester", got, ok)
+	}
+	if len(notifier.messages) != 1 || notifier.messages[0].ConversationID != "C1" || !strings.Contains(notifier.messages[0].Content, "approved by admin@co.com") {
+		t.Errorf("requester notifications = %+v", notifier.messages)
+	}
+}
+
 // --- Max jobs per user tests ---
 
 func TestSchedulerMaxJobsPerUser(t *testing.T) {
@@ -1214,6 +1391,24 @@ func TestToolApproverEnforced(t *testing.T) {
 	}
 }
 
+func TestToolCreateJobQueuedForApproval(t *testing.T) {
+	tool := newTestToolWithPolicy(t, []string{"admin@co.com"}, 0)
+	q, _ := approval.NewQueue(nil, "")
+	tool.sched.SetApprovalQueue(q)
+
+	ctx := actor.WithActor(context.Background(), "slack:random@co.com")
+	result := tool.Execute(ctx, orchestrator.ToolCall{
+		ID: "1", Plugin: ToolName, Action: "create_job",
+		Args: map[string]string{"name": "j1", "interval": "1h", "action": "a.b"},
+	})
+	if result.Error != "" || !strings.Contains(result.Content, "submitted for approval") {
+		t.Errorf("result = %+v; want a pending-approval message", result)
+	}
+	if pending := q.List(approval.StatusPending); len(pending) != 1 || pending[0].Kind != approval.KindJob {
+		t.Errorf("queue = %+v", pending)
+	}
+}
+
 // --- remind_me tool tests ---
 
 func TestToolRemindMeMessageShortcut(t *testing.T) {
@@ -1528,3 +1723,54 @@ func TestToolListShowsSourceAndCreator(t *testing.T) {
 		t.Errorf("list should contain creator diana, got: %s", result.Content)
 	}
 }
+
+func TestReplaceStaticJobs(t *testing.T) {
+	runner := &fakeRunner{}
+	s := New(runner, nil, "")
+	defer s.Stop()
+
+	if err := s.Start([]Job{
+		{Name: "keep", Interval: "1h", Action: "test.ping"},
+		{Name: "drop", Interval: "1h", Action: "test.ping"},
+		{Name: "edit", Interval: "1h", Action: "test.ping"},
+		{Name: "broken", Interval: "1h", Action: "test.ping"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddJob(Job{Name: "dyn", Interval: "1h", Action: "test.ping"}, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PauseJob("keep"); err != nil {
+		t.Fatal(err)
+	}
+
+	added, removed, changed, err := s.ReplaceStaticJobs([]Job{
+		{Name: "keep", Interval: "1h", Action: "test.ping"},
+		{Name: "edit", Interval: "2h", Action: "test.ping"},
+		{Name: "new", Interval: "1h", Action: "test.ping"},
+		{Name: "dyn", Interval: "1h", Action: "test.ping"}, // collides with a dynamic job
+		{Name: "broken", Interval: "soon", Action: "test.ping"},
+	})
+	if fmt.Sprint(added) != "[new]" || fmt.Sprint(removed) != "[drop]" || fmt.Sprint(changed) != "[edit]" {
+		t.Errorf("added=%v removed=%v changed=%v", added, removed, changed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "job broken kept") || !strings.Contains(err.Error(), "job dyn skipped") {
+		t.Errorf("err = %v; want the kept and skipped jobs reported", err)
+	}
+
+	if _, ok := s.GetJob("drop"); ok {
+		t.Error("removed static job still registered")
+	}
+	if j, _ := s.GetJob("edit"); j.Interval != "2h" || j.Source != "config" {
+		t.Errorf("edited job = %+v", j)
+	}
+	if j, _ := s.GetJob("keep"); !j.Paused {
+		t.Error("unchanged job lost its paused state")
+	}
+	if j, _ := s.GetJob("dyn"); j.Source != "dynamic" {
+		t.Errorf("dynamic job replaced by static one: %+v", j)
+	}
+	if j, ok := s.GetJob("broken"); !ok || j.Interval != "1h" {
+		t.Errorf("a job with an invalid edit = %+v, %v; want the old definition kept", j, ok)
+	}
+}
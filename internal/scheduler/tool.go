@@ -3,6 +3,7 @@ package scheduler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -40,6 +41,8 @@ func (t *SchedulerTool) Capability() orchestrator.PluginCapability {
 					{Name: "args", Description: "JSON-encoded object passed as a string, e.g. args={\"issue_id\":\"XYZ\"}. Action-specific keys MUST go inside this object, NOT at top level (top-level unknown keys are rejected). Mutually exclusive with 'message'.", Required: false},
 					{Name: "message", Description: "Shortcut for args={\"message\":\"...\"} — use this for reminder__say and similar message-only actions instead of JSON-encoding args", Required: false},
 					{Name: "notify_channel", Description: "OMIT this parameter in almost all cases. Defaults to the caller's current channel (works for Telegram, Slack, Discord, or any other channel identically — no channel-specific format is required). Only set this when the user explicitly asks to deliver results somewhere other than the current conversation.", Required: false},
+					{Name: "notify_if", Description: "Regular expression; the result is only sent when it matches, so e.g. an hourly check stays quiet until something is found. Omit to always notify.", Required: false},
+					{Name: "notify_template", Description: "Go text/template for the notification, e.g. 'Found: {{.Result}}'. Fields: .Result (text), .JSON (result parsed as JSON), .Job, .Time. Omit to send the result as-is.", Required: false},
 				},
 			},
 			{
@@ -72,6 +75,13 @@ func (t *SchedulerTool) Capability() orchestrator.PluginCapability {
 					{Name: "name", Description: "Job name to resume", Required: true},
 				},
 			},
+			{
+				Name:        "retry_job",
+				Description: "Run a job right now, outside its schedule — typically one that failed and was dead-lettered. The result is delivered like a scheduled run's; success clears the failure record.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "name", Description: "Job name to run", Required: true},
+				},
+			},
 			{
 				Name: "remind_me",
 				Description: "Schedule a personal one-shot reminder for the current user. " +
@@ -115,6 +125,8 @@ func (t *SchedulerTool) Execute(ctx context.Context, call orchestrator.ToolCall)
 		return t.resumeJob(call)
 	case "update_job":
 		return t.updateJob(ctx, call)
+	case "retry_job":
+		return t.retryJob(ctx, call)
 	case "remind_me":
 		return t.remindMe(ctx, call)
 	default:
@@ -223,9 +235,22 @@ func (t *SchedulerTool) createJob(ctx context.Context, call orchestrator.ToolCal
 		NotifyConversationID: caller.conversationID,
 		EntityID:             caller.entityID,
 		Group:                caller.group,
+		NotifyTemplate:       call.Args["notify_template"],
+	}
+	if m := call.Args["notify_if"]; m != "" {
+		job.NotifyIf = &NotifyCondition{Match: m}
 	}
 
 	if err := t.sched.AddJob(job, caller.userID); err != nil {
+		var pending *PendingApprovalError
+		if errors.As(err, &pending) {
+			// Not a failure: tell the LLM so it relays the wait to the user
+			// instead of retrying.
+			return orchestrator.ToolResult{
+				CallID:  call.ID,
+				Content: fmt.Sprintf("Job %q needs an approver's sign-off and was submitted for approval (request %s). The user will be notified here once it is decided.", name, pending.RequestID),
+			}
+		}
 		return orchestrator.ToolResult{
 			CallID: call.ID,
 			Error:  err.Error(),
@@ -247,13 +272,7 @@ func (t *SchedulerTool) listJobs(ctx context.Context, call orchestrator.ToolCall
 	var jobs []Job
 	switch scope {
 	case "", "mine":
-		caller, err := resolveCaller(ctx)
-		if err != nil {
-			// No caller context: nothing to filter by.
-			jobs = nil
-		} else {
-			jobs = t.sched.ListJobsForCaller(caller.entityID, caller.userID)
-		}
+		jobs = t.sched.ListJobsForContext(ctx)
 	case "all":
 		jobs = t.sched.ListJobs()
 	default:
@@ -312,6 +331,20 @@ func (t *SchedulerTool) pauseJob(call orchestrator.ToolCall) orchestrator.ToolRe
 	}
 }
 
+func (t *SchedulerTool) retryJob(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	name := call.Args["name"]
+	if name == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "name is required"}
+	}
+	if err := t.sched.RetryJob(ctx, name); err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	return orchestrator.ToolResult{
+		CallID:  call.ID,
+		Content: fmt.Sprintf("Job %q ran successfully.", name),
+	}
+}
+
 func (t *SchedulerTool) resumeJob(call orchestrator.ToolCall) orchestrator.ToolResult {
 	name := call.Args["name"]
 	if name == "" {
@@ -38,15 +38,15 @@ func TestToolCapability(t *testing.T) {
 	if cap.Name != ToolName {
 		t.Errorf("name = %q, want %q", cap.Name, ToolName)
 	}
-	if len(cap.Actions) != 7 {
-		t.Errorf("expected 7 actions, got %d", len(cap.Actions))
+	if len(cap.Actions) != 8 {
+		t.Errorf("expected 8 actions, got %d", len(cap.Actions))
 	}
 
 	names := make(map[string]bool)
 	for _, a := range cap.Actions {
 		names[a.Name] = true
 	}
-	expected := []string{"create_job", "list_jobs", "delete_job", "pause_job", "resume_job", "update_job", "remind_me"}
+	expected := []string{"create_job", "list_jobs", "delete_job", "pause_job", "resume_job", "update_job", "retry_job", "remind_me"}
 	for _, n := range expected {
 		if !names[n] {
 			t.Errorf("missing action %q", n)
@@ -517,3 +517,20 @@ func TestParseArgsField(t *testing.T) {
 		})
 	}
 }
+
+func TestToolRetryJob(t *testing.T) {
+	tool := newTestTool(t)
+	ctx := testCtx("user1")
+	res := tool.Execute(ctx, orchestrator.ToolCall{ID: "1", Action: "create_job", Args: map[string]string{"name": "sync", "interval": "1h", "action": "test.ping"}})
+	if res.Error != "" {
+		t.Fatal(res.Error)
+	}
+	res = tool.Execute(ctx, orchestrator.ToolCall{ID: "2", Action: "retry_job", Args: map[string]string{"name": "sync"}})
+	if res.Error != "" || !strings.Contains(res.Content, "ran successfully") {
+		t.Errorf("retry_job = %+v", res)
+	}
+	res = tool.Execute(ctx, orchestrator.ToolCall{ID: "3", Action: "retry_job", Args: map[string]string{"name": "nope"}})
+	if !strings.Contains(res.Error, "not found") {
+		t.Errorf("retry_job on unknown job = %+v", res)
+	}
+}
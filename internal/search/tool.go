@@ -0,0 +1,106 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+const ToolName = "search"
+
+// maxLimit caps the hits one call returns.
+const maxLimit = 30
+
+// Index runs keyword searches; *store.SearchIndex satisfies it.
+type Index interface {
+	Search(ctx context.Context, q store.SearchQuery) ([]store.SearchHit, error)
+}
+
+// Tool is the built-in search tool: search.history finds memories and
+// earlier messages by keyword, so "what did we decide about the deploy
+// pipeline last week" can be answered without an embedding provider. The
+// caller sees general memories and their own, and messages of their own
+// conversations plus the current one.
+type Tool struct {
+	index         Index
+	allowedGroups []string
+}
+
+// NewTool returns the tool over index. allowedGroups restricts it to those
+// profile groups; empty leaves it visible to everyone.
+func NewTool(index Index, allowedGroups []string) *Tool {
+	return &Tool{index: index, allowedGroups: allowedGroups}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   "Keyword search over saved memories and past conversation messages.",
+		AllowedGroups: t.allowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name: "history",
+				Description: "Find memories and earlier user and assistant messages containing the query's words, best match first. " +
+					"Use distinctive keywords (names, topics) rather than a whole question; matched words are shown in [brackets].",
+				Parameters: []orchestrator.Parameter{
+					{Name: "query", Description: "Keywords to look for, e.g. deploy pipeline canary", Required: true},
+					{Name: "since", Description: "Go duration to look back, e.g. 168h for the last week (default: no limit)", Required: false},
+					{Name: "kind", Description: "memory or message to search only one of them (default both)", Required: false},
+					{Name: "limit", Description: "Maximum results (default 10, at most 30)", Required: false},
+				},
+				ReadOnly: true,
+			},
+		},
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Action != "history" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown search action: %s", call.Action)}
+	}
+	q := store.SearchQuery{
+		Text:      strings.TrimSpace(call.Args["query"]),
+		Actor:     actor.Actor(ctx),
+		SessionID: actor.SessionID(ctx),
+		Kind:      strings.TrimSpace(call.Args["kind"]),
+	}
+	if q.Text == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "query is required"}
+	}
+	if q.Kind != "" && q.Kind != store.SearchKindMemory && q.Kind != store.SearchKindMessage {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid kind %q: want memory or message", q.Kind)}
+	}
+	if s := strings.TrimSpace(call.Args["since"]); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid since %q: want a Go duration such as 168h", s)}
+		}
+		q.Since = time.Now().Add(-d)
+	}
+	if s := strings.TrimSpace(call.Args["limit"]); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid limit %q: want a positive number", s)}
+		}
+		q.Limit = min(n, maxLimit)
+	}
+	hits, err := t.index.Search(ctx, q)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if len(hits) == 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Nothing matched. Try other or fewer keywords, or a longer since."}
+	}
+	data, err := json.Marshal(hits)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling search results: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/actor"
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/state/store"
+)
+
+type fakeIndex struct {
+	got  store.SearchQuery
+	hits []store.SearchHit
+}
+
+func (f *fakeIndex) Search(_ context.Context, q store.SearchQuery) ([]store.SearchHit, error) {
+	f.got = q
+	return f.hits, nil
+}
+
+func TestTool_History(t *testing.T) {
+	idx := &fakeIndex{hits: []store.SearchHit{{Kind: store.SearchKindMessage, SessionID: "alice:s1", Role: "user", Snippet: "the [deploy] [pipeline]", Score: 3}}}
+	tool := NewTool(idx, nil)
+	ctx := actor.WithSessionID(actor.WithActor(context.Background(), "alice"), "alice:s1")
+
+	res := tool.Execute(ctx, orchestrator.ToolCall{ID: "c1", Action: "history", Args: map[string]string{"query": " deploy pipeline ", "since": "168h", "limit": "100"}})
+	var hits []store.SearchHit
+	if res.Error != "" || json.Unmarshal([]byte(res.Content), &hits) != nil || len(hits) != 1 || res.CallID != "c1" {
+		t.Fatalf("history = %+v", res)
+	}
+	q := idx.got
+	if q.Text != "deploy pipeline" || q.Actor != "alice" || q.SessionID != "alice:s1" || q.Limit != maxLimit || q.Kind != "" {
+		t.Errorf("query = %+v", q)
+	}
+	if ago := time.Since(q.Since); ago < 167*time.Hour || ago > 169*time.Hour {
+		t.Errorf("since = %v", q.Since)
+	}
+
+	idx.hits = nil
+	if res := tool.Execute(ctx, orchestrator.ToolCall{Action: "history", Args: map[string]string{"query": "lunch", "kind": "memory"}}); !strings.Contains(res.Content, "Nothing matched") || idx.got.Kind != store.SearchKindMemory {
+		t.Errorf("no hits = %+v", res)
+	}
+
+	for _, args := range []map[string]string{
+		{},
+		{"query": "x", "since": "last week"},
+		{"query": "x", "limit": "-1"},
+		{"query": "x", "kind": "files"},
+	} {
+		if res := tool.Execute(ctx, orchestrator.ToolCall{Action: "history", Args: args}); res.Error == "" {
+			t.Errorf("args %v accepted", args)
+		}
+	}
+	if res := tool.Execute(ctx, orchestrator.ToolCall{Action: "reindex"}); res.Error == "" {
+		t.Error("unknown action accepted")
+	}
+}
@@ -0,0 +1,150 @@
+// Package sessioncache puts a Redis read-through cache in front of the
+// session store for multi-pod deployments.
+//
+// Every turn reads its session before it runs; with several pods on one
+// Postgres that read is the hottest query. Get serves the session from
+// Redis when cached and fills the cache on a miss. Every write goes to the
+// backing store first and then deletes the cached copy, so the next Get on
+// any pod reloads it.
+//
+// Staleness is bounded by the TTL: a Get that loaded the old row just
+// before another pod's write can put it back after that write's delete.
+// The cross-pod session-turn lease makes this rare, since writes for one
+// session come from the pod running its turn. Writes that bypass this type
+// (retention pruning, plugins writing the tables directly) are likewise
+// picked up once the entry expires.
+//
+// Fail-open: a Redis error logs a warning and the call goes to the backing
+// store, as if the cache were disabled.
+package sessioncache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+const (
+	keyPrefix  = "opentalon:session:cache:"
+	DefaultTTL = 10 * time.Minute
+	opTimeout  = 500 * time.Millisecond
+)
+
+// Store caches a session store in Redis. It implements
+// orchestrator.SessionStoreInterface.
+type Store struct {
+	backend orchestrator.SessionStoreInterface
+	client  redis.UniversalClient
+	ttl     time.Duration
+}
+
+// New wraps backend with a cache on client; ttl <= 0 uses DefaultTTL. The
+// caller owns the client.
+func New(backend orchestrator.SessionStoreInterface, client redis.UniversalClient, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Store{backend: backend, client: client, ttl: ttl}
+}
+
+// Backend returns the wrapped session store, for callers that need more than
+// orchestrator.SessionStoreInterface (e.g. the admin API listing sessions).
+// Writes made through it bypass the cache, so callers Invalidate what they
+// change.
+func (s *Store) Backend() orchestrator.SessionStoreInterface {
+	return s.backend
+}
+
+func (s *Store) Get(id string) (*state.Session, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+	data, err := s.client.Get(ctx, keyPrefix+id).Bytes()
+	switch {
+	case err == nil:
+		var sess state.Session
+		if err := json.Unmarshal(data, &sess); err == nil {
+			return &sess, nil
+		}
+		// A cached copy from an older build may not decode; reload it.
+	case !errors.Is(err, redis.Nil):
+		slog.Warn("sessioncache: redis read failed, using the session store", "session", id, "error", err)
+	}
+
+	sess, err := s.backend.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if data, err := json.Marshal(sess); err == nil {
+		if err := s.client.Set(ctx, keyPrefix+id, data, s.ttl).Err(); err != nil {
+			slog.Warn("sessioncache: redis write failed", "session", id, "error", err)
+		}
+	}
+	return sess, nil
+}
+
+func (s *Store) Create(id, entityID, groupID, kind string) *state.Session {
+	sess := s.backend.Create(id, entityID, groupID, kind)
+	s.invalidate(id)
+	return sess
+}
+
+func (s *Store) AddMessage(id string, msg provider.Message) error {
+	return s.after(id, s.backend.AddMessage(id, msg))
+}
+
+func (s *Store) AddMessageWithMetadata(id string, msg provider.Message, metadata map[string]string) error {
+	return s.after(id, s.backend.AddMessageWithMetadata(id, msg, metadata))
+}
+
+func (s *Store) SetModel(id string, model provider.ModelRef) error {
+	return s.after(id, s.backend.SetModel(id, model))
+}
+
+func (s *Store) SetSummary(id string, summary string, messages []provider.Message) error {
+	return s.after(id, s.backend.SetSummary(id, summary, messages))
+}
+
+func (s *Store) SetTitle(id, title string) error {
+	return s.after(id, s.backend.SetTitle(id, title))
+}
+
+func (s *Store) SetMetadata(id, key, value string) error {
+	return s.after(id, s.backend.SetMetadata(id, key, value))
+}
+
+func (s *Store) ClearMessages(id string) error {
+	return s.after(id, s.backend.ClearMessages(id))
+}
+
+func (s *Store) Delete(id string) error {
+	return s.after(id, s.backend.Delete(id))
+}
+
+// after invalidates id once a write has been attempted. A failed write may
+// still have changed the row, so the entry is dropped either way.
+func (s *Store) after(id string, err error) error {
+	s.invalidate(id)
+	return err
+}
+
+// Invalidate drops the cached copy of id, for writes made to the backing
+// store without going through s.
+func (s *Store) Invalidate(id string) {
+	s.invalidate(id)
+}
+
+func (s *Store) invalidate(id string) {
+	ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+	defer cancel()
+	if err := s.client.Del(ctx, keyPrefix+id).Err(); err != nil {
+		slog.Warn("sessioncache: invalidation failed; the cached session may be stale until it expires", "session", id, "error", err)
+	}
+}
@@ -0,0 +1,84 @@
+package sessioncache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// countingStore counts Get calls that reach the backing store.
+type countingStore struct {
+	*state.SessionStore
+	gets int
+}
+
+func (c *countingStore) Get(id string) (*state.Session, error) {
+	c.gets++
+	return c.SessionStore.Get(id)
+}
+
+func newCached(t *testing.T) (*Store, *countingStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	backend := &countingStore{SessionStore: state.NewSessionStore("")}
+	return New(backend, client, 0), backend, mr
+}
+
+func TestGetReadsThrough(t *testing.T) {
+	s, backend, _ := newCached(t)
+	s.Create("web:c1", "e1", "", "")
+	if err := s.AddMessage("web:c1", provider.Message{Role: provider.RoleUser, Content: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for range 3 {
+		sess, err := s.Get("web:c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sess.Messages) != 1 || sess.Messages[0].Content != "hi" {
+			t.Fatalf("session = %+v", sess)
+		}
+	}
+	if backend.gets != 1 {
+		t.Errorf("backing store reads = %d, want 1", backend.gets)
+	}
+
+	if err := s.SetMetadata("web:c1", "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	sess, _ := s.Get("web:c1")
+	if sess.Metadata["k"] != "v" || backend.gets != 2 {
+		t.Errorf("a write should invalidate the cached copy: metadata %v, reads %d", sess.Metadata, backend.gets)
+	}
+
+	if err := s.Delete("web:c1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("web:c1"); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("deleted session: %v", err)
+	}
+}
+
+func TestRedisDownFailsOpen(t *testing.T) {
+	s, backend, mr := newCached(t)
+	s.Create("web:c1", "", "", "")
+	mr.Close()
+
+	if _, err := s.Get("web:c1"); err != nil {
+		t.Fatalf("get with redis down: %v", err)
+	}
+	if err := s.SetTitle("web:c1", "t"); err != nil {
+		t.Fatalf("write with redis down: %v", err)
+	}
+	if backend.gets != 1 {
+		t.Errorf("backing store reads = %d, want 1", backend.gets)
+	}
+}
@@ -0,0 +1,96 @@
+// Package sessionidle decides when a conversation has ended. Sessions have
+// no explicit close, so a session counts as completed once no turn has run
+// for its idle timeout; a later message resumes it and starts the clock
+// again, and it completes again after the next quiet period.
+//
+// Timers live in process memory: a restart forgets them, so a session that
+// was waiting out its timeout when the process stopped does not complete.
+// In cluster mode each pod tracks the turns it ran.
+package sessionidle
+
+import (
+	"sync"
+	"time"
+)
+
+// Tracker fires onIdle for a session once timeoutFor(session) has passed
+// since its last turn finished. It implements
+// orchestrator.SessionActivityObserver.
+type Tracker struct {
+	timeoutFor func(sessionID string) time.Duration
+	onIdle     func(sessionID string, idleFor time.Duration)
+
+	mu      sync.Mutex
+	timers  map[string]*idleTimer
+	stopped bool
+}
+
+// idleTimer is one armed timeout; fire compares it by pointer to tell
+// whether it is still the session's current one.
+type idleTimer struct{ t *time.Timer }
+
+// New returns a tracker. timeoutFor returning <= 0 leaves that session
+// untracked. onIdle runs on its own goroutine.
+func New(timeoutFor func(sessionID string) time.Duration, onIdle func(sessionID string, idleFor time.Duration)) *Tracker {
+	return &Tracker{timeoutFor: timeoutFor, onIdle: onIdle, timers: make(map[string]*idleTimer)}
+}
+
+// TurnStarted cancels the session's pending completion: it is active again.
+func (t *Tracker) TurnStarted(sessionID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if it, ok := t.timers[sessionID]; ok {
+		it.t.Stop()
+		delete(t.timers, sessionID)
+	}
+}
+
+// TurnFinished starts the session's idle clock.
+func (t *Tracker) TurnFinished(sessionID string) {
+	d := t.timeoutFor(sessionID)
+	if d <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return
+	}
+	if it, ok := t.timers[sessionID]; ok {
+		it.t.Stop()
+	}
+	it := &idleTimer{}
+	it.t = time.AfterFunc(d, func() { t.fire(sessionID, it, d) })
+	t.timers[sessionID] = it
+}
+
+// Pending reports how many sessions are waiting out their idle timeout.
+func (t *Tracker) Pending() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.timers)
+}
+
+// Stop cancels every pending completion.
+func (t *Tracker) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	for id, it := range t.timers {
+		it.t.Stop()
+		delete(t.timers, id)
+	}
+}
+
+func (t *Tracker) fire(sessionID string, it *idleTimer, idleFor time.Duration) {
+	t.mu.Lock()
+	// A turn that started (or finished again) after this timer was armed
+	// replaced or removed it; only the current timer completes the session.
+	if t.timers[sessionID] != it {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.timers, sessionID)
+	t.mu.Unlock()
+	t.onIdle(sessionID, idleFor)
+}
@@ -0,0 +1,84 @@
+package sessionidle
+
+import (
+	"testing"
+	"time"
+)
+
+type completion struct {
+	sessionID string
+	idleFor   time.Duration
+}
+
+func newTracker(timeout time.Duration) (*Tracker, chan completion) {
+	done := make(chan completion, 4)
+	t := New(func(sessionID string) time.Duration {
+		if sessionID == "cli:off" {
+			return 0
+		}
+		return timeout
+	}, func(sessionID string, idleFor time.Duration) {
+		done <- completion{sessionID, idleFor}
+	})
+	return t, done
+}
+
+func TestCompletesAfterIdleTimeout(t *testing.T) {
+	tr, done := newTracker(30 * time.Millisecond)
+	defer tr.Stop()
+	tr.TurnStarted("web:c1")
+	tr.TurnFinished("web:c1")
+
+	select {
+	case c := <-done:
+		if c.sessionID != "web:c1" || c.idleFor != 30*time.Millisecond {
+			t.Errorf("completion = %+v", c)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("session never completed")
+	}
+	if tr.Pending() != 0 {
+		t.Errorf("pending = %d after completion", tr.Pending())
+	}
+}
+
+func TestResumeResetsIdleClock(t *testing.T) {
+	tr, done := newTracker(80 * time.Millisecond)
+	defer tr.Stop()
+	tr.TurnFinished("web:c1")
+	time.Sleep(50 * time.Millisecond)
+	tr.TurnStarted("web:c1")
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case c := <-done:
+		t.Fatalf("completed during a turn: %+v", c)
+	default:
+	}
+	tr.TurnFinished("web:c1")
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("session never completed after the resumed turn")
+	}
+	select {
+	case c := <-done:
+		t.Errorf("completed twice: %+v", c)
+	case <-time.After(120 * time.Millisecond):
+	}
+}
+
+func TestZeroTimeoutAndStop(t *testing.T) {
+	tr, done := newTracker(20 * time.Millisecond)
+	tr.TurnFinished("cli:off")
+	if tr.Pending() != 0 {
+		t.Error("a session with no timeout should not be tracked")
+	}
+	tr.TurnFinished("web:c1")
+	tr.Stop()
+	tr.TurnFinished("web:c2")
+	select {
+	case c := <-done:
+		t.Errorf("completed after Stop: %+v", c)
+	case <-time.After(60 * time.Millisecond):
+	}
+}
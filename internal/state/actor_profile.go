@@ -0,0 +1,64 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ActorProfile holds what one actor (channel:user, see actor.Actor) told the
+// assistant about themselves. It is injected into the system prompt of every
+// turn they run, in any session.
+type ActorProfile struct {
+	ActorID      string    `yaml:"actor_id"`
+	Name         string    `yaml:"name,omitempty"`         // how to address them
+	Locale       string    `yaml:"locale,omitempty"`       // ISO 639-1 reply language, e.g. "de"
+	Timezone     string    `yaml:"timezone,omitempty"`     // IANA zone, e.g. "Europe/Berlin"
+	Style        string    `yaml:"style,omitempty"`        // response style, e.g. "concise, bullet points"
+	Instructions string    `yaml:"instructions,omitempty"` // standing instructions in their own words
+	UpdatedAt    time.Time `yaml:"updated_at"`
+}
+
+// Empty reports whether no preference is set.
+func (p *ActorProfile) Empty() bool {
+	return p.Name == "" && p.Locale == "" && p.Timezone == "" && p.Style == "" && p.Instructions == ""
+}
+
+// ActorProfileStore keeps actor profiles in memory, for runs without a
+// state database.
+type ActorProfileStore struct {
+	mu       sync.RWMutex
+	profiles map[string]ActorProfile
+}
+
+func NewActorProfileStore() *ActorProfileStore {
+	return &ActorProfileStore{profiles: make(map[string]ActorProfile)}
+}
+
+// ActorProfile returns actorID's profile, or nil when they have none.
+func (s *ActorProfileStore) ActorProfile(_ context.Context, actorID string) (*ActorProfile, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.profiles[actorID]
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// SaveActorProfile replaces the profile of p.ActorID. An empty profile is
+// removed.
+func (s *ActorProfileStore) SaveActorProfile(_ context.Context, p *ActorProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Empty() {
+		delete(s.profiles, p.ActorID)
+		return nil
+	}
+	cp := *p
+	if cp.UpdatedAt.IsZero() {
+		cp.UpdatedAt = time.Now()
+	}
+	s.profiles[p.ActorID] = cp
+	return nil
+}
@@ -0,0 +1,31 @@
+package state
+
+import (
+	"context"
+	"testing"
+)
+
+func TestActorProfileStoreSaveAndClear(t *testing.T) {
+	ctx := context.Background()
+	store := NewActorProfileStore()
+
+	if p, err := store.ActorProfile(ctx, "slack:U1"); p != nil || err != nil {
+		t.Fatalf("missing profile = %+v, %v; want nil, nil", p, err)
+	}
+	_ = store.SaveActorProfile(ctx, &ActorProfile{ActorID: "slack:U1", Locale: "de", Timezone: "Europe/Berlin"})
+	p, err := store.ActorProfile(ctx, "slack:U1")
+	if err != nil || p == nil || p.Locale != "de" || p.UpdatedAt.IsZero() {
+		t.Fatalf("profile = %+v, %v", p, err)
+	}
+
+	// The returned copy is not the stored one.
+	p.Locale = "fr"
+	if again, _ := store.ActorProfile(ctx, "slack:U1"); again.Locale != "de" {
+		t.Errorf("stored locale changed through the returned profile: %q", again.Locale)
+	}
+
+	_ = store.SaveActorProfile(ctx, &ActorProfile{ActorID: "slack:U1"})
+	if p, _ := store.ActorProfile(ctx, "slack:U1"); p != nil {
+		t.Errorf("saving an empty profile should remove it, got %+v", p)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 type Memory struct {
 	ID        string    `yaml:"id"`
+	ActorID   string    `yaml:"actor_id,omitempty"` // owner; empty = general, visible to every actor
 	Content   string    `yaml:"content"`
 	Tags      []string  `yaml:"tags,omitempty"`
 	CreatedAt time.Time `yaml:"created_at"`
@@ -63,8 +64,12 @@ func (s *MemoryStore) AddScoped(ctx context.Context, actorID string, content str
 	return s.Add(content, tags...), nil
 }
 
-// MemoriesForContext returns memories for prompt building. For in-memory store, returns SearchByTag(tag).
+// MemoriesForContext returns memories for prompt building. For in-memory store, returns SearchByTag(tag),
+// or every memory when tag is empty (matching the SQL store).
 func (s *MemoryStore) MemoriesForContext(ctx context.Context, tag string) ([]*Memory, error) {
+	if tag == "" {
+		return s.List(), nil
+	}
 	return s.SearchByTag(tag), nil
 }
 
@@ -0,0 +1,22 @@
+package state
+
+import "time"
+
+// RunCheckpoint is the progress of an agent-loop turn that has not finished,
+// saved at safe points so a restart can pick the turn up again. The tool
+// calls and results themselves are already in the session history; the
+// checkpoint says which turn was running, as whom, and how far it got.
+type RunCheckpoint struct {
+	SessionID   string    `json:"session_id"`
+	Actor       string    `json:"actor,omitempty"`     // channel:user the turn ran for
+	EntityID    string    `json:"entity_id,omitempty"` // profile to resume under
+	Group       string    `json:"group,omitempty"`
+	ChannelID   string    `json:"channel_id,omitempty"`
+	UserMessage string    `json:"user_message"`
+	Iteration   int       `json:"iteration"`           // agent-loop round reached
+	Completed   []string  `json:"completed,omitempty"` // plugin__action of each finished tool call, in order
+	Pending     string    `json:"pending,omitempty"`   // plugin__action dispatched but not finished
+	Resumed     bool      `json:"resumed,omitempty"`   // the turn already resumed once and is not resumed again
+	StartedAt   time.Time `json:"started_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/opentalon/opentalon/internal/digest"
+	"github.com/opentalon/opentalon/internal/state/store/events"
+)
+
+// maxQuestionRows bounds the user messages Activity reads to rank questions;
+// beyond it only the newest count.
+const maxQuestionRows = 5000
+
+// activityExcerpt is the length, in runes, of message and error texts in a
+// digest.
+const activityExcerpt = 200
+
+// ActivityStore reads what happened across conversations for digests. It
+// implements digest.Source.
+type ActivityStore struct {
+	db *DB
+}
+
+// NewActivityStore returns an ActivityStore backed by db.
+func NewActivityStore(db *DB) *ActivityStore {
+	return &ActivityStore{db: db}
+}
+
+// Activity reads the chat messages, tool calls and usage recorded on or after
+// since. Tool calls and failures come from session_events.
+func (s *ActivityStore) Activity(ctx context.Context, since time.Time, limit int) (digest.Activity, error) {
+	var a digest.Activity
+	sinceStr := since.UTC().Format(time.RFC3339)
+	sqlDB, d := s.db.SQLDB(), s.db.Dialect()
+
+	if err := sqlDB.QueryRowContext(ctx, d.Rebind(`
+		SELECT COUNT(DISTINCT m.session_id), COUNT(*)
+		FROM messages m JOIN sessions s ON s.id = m.session_id
+		WHERE m.role = 'user' AND m.visibility IS NULL AND m.created_at >= ? AND s.interaction_kind = 'chat'`),
+		sinceStr).Scan(&a.Sessions, &a.Messages); err != nil {
+		return a, fmt.Errorf("activity: messages: %w", err)
+	}
+	var err error
+	if a.Questions, err = s.questions(ctx, sinceStr, limit); err != nil {
+		return a, err
+	}
+
+	// session_events timestamps carry fractions of a second.
+	eventsSince := since.UTC().Format(time.RFC3339Nano)
+	if err := sqlDB.QueryRowContext(ctx, d.Rebind(`
+		SELECT COUNT(*) FROM session_events WHERE event_type = ? AND ts >= ?`),
+		events.TypeToolCallResult, eventsSince).Scan(&a.ToolCalls); err != nil {
+		return a, fmt.Errorf("activity: tool calls: %w", err)
+	}
+	if a.Failures, err = s.failures(ctx, eventsSince, limit); err != nil {
+		return a, err
+	}
+
+	if err := sqlDB.QueryRowContext(ctx, d.Rebind(`
+		SELECT COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+		       COALESCE(SUM(input_cost + output_cost), 0)
+		FROM profile_usage WHERE created_at >= ?`),
+		sinceStr).Scan(&a.Spend.Runs, &a.Spend.InputTokens, &a.Spend.OutputTokens, &a.Spend.Cost); err != nil {
+		return a, fmt.Errorf("activity: usage: %w", err)
+	}
+
+	if a.Unresolved, err = s.unresolved(ctx, sinceStr, limit); err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// questions ranks the user messages of the period by how often they were
+// written, ignoring case and spacing; ties go to the newest. Contents may be
+// encrypted, so they are compared here rather than grouped in SQL.
+func (s *ActivityStore) questions(ctx context.Context, since string, limit int) ([]digest.Question, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT m.content
+		FROM messages m JOIN sessions s ON s.id = m.session_id
+		WHERE m.role = 'user' AND m.visibility IS NULL AND m.created_at >= ? AND s.interaction_kind = 'chat'
+		ORDER BY m.created_at DESC
+		LIMIT ?`),
+		since, maxQuestionRows)
+	if err != nil {
+		return nil, fmt.Errorf("activity: questions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []digest.Question
+	index := map[string]int{}
+	for rows.Next() {
+		var content string
+		if err := rows.Scan(&content); err != nil {
+			return nil, fmt.Errorf("activity: questions: %w", err)
+		}
+		text, err := s.db.open(content)
+		if err != nil {
+			return nil, fmt.Errorf("activity: questions: %w", err)
+		}
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		key := strings.ToLower(text)
+		if i, ok := index[key]; ok {
+			out[i].Count++
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, digest.Question{Text: excerptRunes(text, activityExcerpt), Count: 1})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("activity: questions: %w", err)
+	}
+	// Stable, so equal counts keep newest-first order.
+	slices.SortStableFunc(out, func(a, b digest.Question) int { return b.Count - a.Count })
+	return out[:min(len(out), limit)], nil
+}
+
+// failures counts the failed tool calls of the period per tool, from the
+// tool_call_result events and the tool_call_extracted events they point at.
+func (s *ActivityStore) failures(ctx context.Context, since string, limit int) ([]digest.ToolFailure, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT x.payload, r.payload
+		FROM session_events r JOIN session_events x ON x.id = r.parent_id
+		WHERE r.event_type = ? AND x.event_type = ? AND r.ts >= ? AND r.payload LIKE ?
+		ORDER BY r.ts`),
+		events.TypeToolCallResult, events.TypeToolCallExtracted, since, `%"status":"error"%`)
+	if err != nil {
+		return nil, fmt.Errorf("activity: tool failures: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []digest.ToolFailure
+	index := map[string]int{}
+	for rows.Next() {
+		var callJSON, resJSON string
+		if err := rows.Scan(&callJSON, &resJSON); err != nil {
+			return nil, fmt.Errorf("activity: tool failures: %w", err)
+		}
+		var call events.ToolCallExtractedPayload
+		var res events.ToolCallResultPayload
+		if json.Unmarshal([]byte(callJSON), &call) != nil || json.Unmarshal([]byte(resJSON), &res) != nil || res.Status != "error" {
+			continue
+		}
+		tool := call.Plugin + "__" + call.Action
+		i, ok := index[tool]
+		if !ok {
+			i = len(out)
+			index[tool] = i
+			out = append(out, digest.ToolFailure{Tool: tool})
+		}
+		out[i].Count++
+		out[i].LastError = excerptRunes(res.ResponseExcerpt, activityExcerpt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("activity: tool failures: %w", err)
+	}
+	slices.SortStableFunc(out, func(a, b digest.ToolFailure) int { return b.Count - a.Count })
+	return out[:min(len(out), limit)], nil
+}
+
+// unresolved lists the chat conversations of the period whose last message
+// is a visible one from the user, newest first.
+func (s *ActivityStore) unresolved(ctx context.Context, since string, limit int) ([]digest.Thread, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT s.id, COALESCE(s.title, ''), s.channel_id, m.content, m.created_at
+		FROM messages m JOIN sessions s ON s.id = m.session_id
+		WHERE m.role = 'user' AND m.visibility IS NULL AND m.created_at >= ? AND s.interaction_kind = 'chat'
+		  AND m.seq = (SELECT MAX(seq) FROM messages WHERE session_id = m.session_id)
+		ORDER BY m.created_at DESC
+		LIMIT ?`),
+		since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("activity: unresolved: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []digest.Thread
+	for rows.Next() {
+		var t digest.Thread
+		var content, at string
+		if err := rows.Scan(&t.SessionID, &t.Title, &t.Channel, &content, &at); err != nil {
+			return nil, fmt.Errorf("activity: unresolved: %w", err)
+		}
+		text, err := s.db.open(content)
+		if err != nil {
+			return nil, fmt.Errorf("activity: unresolved: %w", err)
+		}
+		t.LastMessage = excerptRunes(strings.Join(strings.Fields(text), " "), activityExcerpt)
+		t.At, _ = time.Parse(time.RFC3339, at)
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// excerptRunes cuts s to n runes, marking the cut with an ellipsis.
+func excerptRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
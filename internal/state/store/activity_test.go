@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/digest"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state/store/events"
+)
+
+func TestActivityStore_Activity(t *testing.T) {
+	db := openTestDB(t)
+	sessions := NewSessionStore(db, 0, 0)
+	ctx := context.Background()
+	say := func(id string, role provider.Role, content string) {
+		t.Helper()
+		if err := sessions.AddMessage(id, provider.Message{Role: role, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sessions.Create("s1", "alice", "", "chat")
+	_ = sessions.SetTitle("s1", "VPN trouble")
+	say("s1", provider.RoleUser, "How do I reset my VPN?")
+	say("s1", provider.RoleAssistant, "Open the VPN app and ...")
+	say("s1", provider.RoleUser, "how do I   reset my vpn?")
+	sessions.Create("s2", "bob", "", "chat")
+	say("s2", provider.RoleUser, "Where is the wiki?")
+	say("s2", provider.RoleAssistant, "Here.")
+	say("s2", provider.RoleUser, "How do I reset my VPN?")
+	say("s2", provider.RoleAssistant, "As before.")
+	// System sessions are not conversations users had.
+	sessions.Create("s3", "alice", "", "system")
+	say("s3", provider.RoleUser, "Sync the CRM")
+
+	evs := NewSessionEventStore(db)
+	insert := func(id, parent, typ string, payload any) {
+		t.Helper()
+		data, _ := json.Marshal(payload)
+		if err := evs.Insert(ctx, SessionEvent{ID: id, SessionID: "s2", ParentID: parent, EventType: typ, Payload: data}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	insert("c1", "", events.TypeToolCallExtracted, events.ToolCallExtractedPayload{CallID: "1", Plugin: "jira", Action: "search"})
+	insert("r1", "c1", events.TypeToolCallResult, events.ToolCallResultPayload{CallID: "1", Status: "error", ResponseExcerpt: "timeout"})
+	insert("c2", "", events.TypeToolCallExtracted, events.ToolCallExtractedPayload{CallID: "2", Plugin: "jira", Action: "search"})
+	insert("r2", "c2", events.TypeToolCallResult, events.ToolCallResultPayload{CallID: "2", Status: "error", ResponseExcerpt: "401 unauthorized"})
+	insert("c3", "", events.TypeToolCallExtracted, events.ToolCallExtractedPayload{CallID: "3", Plugin: "wiki", Action: "get"})
+	insert("r3", "c3", events.TypeToolCallResult, events.ToolCallResultPayload{CallID: "3", Status: "ok"})
+
+	us := NewUsageStore(db)
+	_ = us.Record(ctx, UsageRecord{EntityID: "alice", SessionID: "s1", InputTokens: 100, OutputTokens: 20, InputCost: 0.5})
+	_ = us.Record(ctx, UsageRecord{EntityID: "alice", SessionID: "s3", InteractionKind: "system", InputTokens: 10, OutputCost: 0.25})
+
+	a, err := NewActivityStore(db).Activity(ctx, time.Now().Add(-time.Hour), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Sessions != 2 || a.Messages != 4 {
+		t.Errorf("sessions %d, messages %d; want 2 chat sessions with 4 user messages", a.Sessions, a.Messages)
+	}
+	if len(a.Questions) != 2 || a.Questions[0].Count != 3 || a.Questions[1].Text != "Where is the wiki?" {
+		t.Errorf("questions = %+v", a.Questions)
+	}
+	if a.ToolCalls != 3 || len(a.Failures) != 1 || a.Failures[0] != (digest.ToolFailure{Tool: "jira__search", Count: 2, LastError: "401 unauthorized"}) {
+		t.Errorf("tool calls %d, failures %+v", a.ToolCalls, a.Failures)
+	}
+	if a.Spend.Runs != 2 || a.Spend.InputTokens != 110 || a.Spend.Cost != 0.75 {
+		t.Errorf("spend = %+v", a.Spend)
+	}
+	if len(a.Unresolved) != 1 || a.Unresolved[0].SessionID != "s1" || a.Unresolved[0].Title != "VPN trouble" || a.Unresolved[0].LastMessage != "how do I reset my vpn?" {
+		t.Errorf("unresolved = %+v", a.Unresolved)
+	}
+
+	if later, err := NewActivityStore(db).Activity(ctx, time.Now().Add(time.Hour), 10); err != nil || later.Messages != 0 || len(later.Questions) != 0 || later.ToolCalls != 0 || len(later.Unresolved) != 0 {
+		t.Errorf("activity before since should be excluded, got %+v, %v", later, err)
+	}
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// ActorDataStore collects and erases everything the state database holds
+// about one actor, for data-subject access and erasure requests. An actor
+// owns the memories stored under its id and the sessions whose entity_id
+// it is (the profile system sets that), with their messages, events,
+// debug captures, scores and checkpoints.
+type ActorDataStore struct {
+	db *DB
+}
+
+// NewActorDataStore returns an ActorDataStore backed by db.
+func NewActorDataStore(db *DB) *ActorDataStore {
+	return &ActorDataStore{db: db}
+}
+
+// ActorExport is the answer to an access request.
+type ActorExport struct {
+	ActorID    string               `json:"actor_id"`
+	ExportedAt time.Time            `json:"exported_at"`
+	Profile    *state.ActorProfile  `json:"profile,omitempty"`
+	Memories   []*state.Memory      `json:"memories"`
+	Sessions   []ActorSessionExport `json:"sessions"`
+	Usage      []ActorUsage         `json:"usage"`
+}
+
+// ActorSessionExport is one owned session with its full history.
+type ActorSessionExport struct {
+	ID        string          `json:"id"`
+	ChannelID string          `json:"channel_id,omitempty"`
+	Title     string          `json:"title,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Messages  []StoredMessage `json:"messages"`
+	Events    []ActorEvent    `json:"events,omitempty"`
+}
+
+// ActorEvent is a session_events row as exported.
+type ActorEvent struct {
+	Seq       int64           `json:"seq"`
+	Timestamp time.Time       `json:"ts"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// ActorUsage is a profile_usage row as exported.
+type ActorUsage struct {
+	SessionID    string    `json:"session_id"`
+	ChannelID    string    `json:"channel_id"`
+	ModelID      string    `json:"model_id"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	ToolCalls    int       `json:"tool_calls"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ForgetReport counts the rows Forget deleted, or would delete on a dry
+// run.
+type ForgetReport struct {
+	DryRun      bool `json:"dry_run"`
+	Sessions    int  `json:"sessions"`
+	Messages    int  `json:"messages"`
+	Events      int  `json:"events"`
+	DebugEvents int  `json:"debug_events"`
+	Scores      int  `json:"scores"`
+	Checkpoints int  `json:"checkpoints"`
+	Memories    int  `json:"memories"`
+	Usage       int  `json:"usage"`
+	Profiles    int  `json:"profiles"`
+	Entities    int  `json:"entities"`
+	// SessionIDs are the deleted (or, dry run, owned) sessions, for callers
+	// that cache sessions outside the database.
+	SessionIDs []string `json:"-"`
+}
+
+// Export returns everything stored about actorID. Personal memories only:
+// memories shared with everyone belong to no one.
+func (s *ActorDataStore) Export(ctx context.Context, actorID string) (*ActorExport, error) {
+	d := s.db.Dialect()
+	out := &ActorExport{ActorID: actorID, ExportedAt: time.Now().UTC(), Memories: []*state.Memory{}, Sessions: []ActorSessionExport{}, Usage: []ActorUsage{}}
+	var err error
+	if out.Profile, err = NewActorProfileStore(s.db).ActorProfile(ctx, actorID); err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+
+	rows, err := s.db.SQLDB().QueryContext(ctx,
+		d.Rebind(`SELECT id, actor_id, content, tags, created_at FROM memories WHERE actor_id = ? ORDER BY created_at`), actorID)
+	if err != nil {
+		return nil, fmt.Errorf("export memories: %w", err)
+	}
+	out.Memories = append(out.Memories, (&MemoryStore{db: s.db}).scanMemories(rows)...)
+	_ = rows.Close()
+
+	rows, err = s.db.SQLDB().QueryContext(ctx,
+		d.Rebind(`SELECT id, channel_id, COALESCE(title,''), COALESCE(summary,''), created_at, updated_at FROM sessions WHERE entity_id = ? ORDER BY created_at, id`), actorID)
+	if err != nil {
+		return nil, fmt.Errorf("export sessions: %w", err)
+	}
+	for rows.Next() {
+		var se ActorSessionExport
+		var createdAt, updatedAt string
+		if err := rows.Scan(&se.ID, &se.ChannelID, &se.Title, &se.Summary, &createdAt, &updatedAt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("export sessions: %w", err)
+		}
+		if se.Summary, err = s.db.open(se.Summary); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("export sessions: %w", err)
+		}
+		se.CreatedAt, se.UpdatedAt = parseTimeOrZero(createdAt), parseTimeOrZero(updatedAt)
+		out.Sessions = append(out.Sessions, se)
+	}
+	err = rows.Err()
+	_ = rows.Close()
+	if err != nil {
+		return nil, fmt.Errorf("export sessions: %w", err)
+	}
+
+	sessions := &SessionStore{db: s.db}
+	events := NewSessionEventStore(s.db)
+	for i := range out.Sessions {
+		se := &out.Sessions[i]
+		se.Messages = []StoredMessage{}
+		for page := (MessagePage{Limit: 500}); ; {
+			msgs, err := sessions.Messages(ctx, se.ID, page)
+			if err != nil {
+				return nil, fmt.Errorf("export messages: %w", err)
+			}
+			se.Messages = append(se.Messages, msgs...)
+			if len(msgs) < page.Limit {
+				break
+			}
+			page.AfterSeq = msgs[len(msgs)-1].Seq
+		}
+		evs, err := events.ListForSession(ctx, se.ID, 0, 0)
+		if err != nil {
+			return nil, fmt.Errorf("export events: %w", err)
+		}
+		for _, ev := range evs {
+			se.Events = append(se.Events, ActorEvent{Seq: ev.Seq, Timestamp: ev.Timestamp, Type: ev.EventType, Payload: ev.Payload})
+		}
+	}
+
+	rows, err = s.db.SQLDB().QueryContext(ctx, d.Rebind(`SELECT session_id, channel_id, model_id,
+		COALESCE(input_tokens,0), COALESCE(output_tokens,0), COALESCE(tool_calls,0), created_at
+		FROM profile_usage WHERE entity_id = ? ORDER BY created_at`), actorID)
+	if err != nil {
+		return nil, fmt.Errorf("export usage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var u ActorUsage
+		var createdAt string
+		if err := rows.Scan(&u.SessionID, &u.ChannelID, &u.ModelID, &u.InputTokens, &u.OutputTokens, &u.ToolCalls, &createdAt); err != nil {
+			return nil, fmt.Errorf("export usage: %w", err)
+		}
+		u.CreatedAt = parseTimeOrZero(createdAt)
+		out.Usage = append(out.Usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("export usage: %w", err)
+	}
+	return out, nil
+}
+
+// Forget deletes everything Export returns for actorID in one transaction.
+// With dryRun it only counts. Memories the actor shared with everyone
+// stay.
+func (s *ActorDataStore) Forget(ctx context.Context, actorID string, dryRun bool) (ForgetReport, error) {
+	r := ForgetReport{DryRun: dryRun}
+	owned := `session_id IN (SELECT id FROM sessions WHERE entity_id = ?)`
+	// Session-scoped rows go before the sessions that select them.
+	steps := []struct {
+		table, where string
+		n            *int
+	}{
+		{"messages", owned, &r.Messages},
+		{"session_events", owned, &r.Events},
+		{"ai_debug_events", owned, &r.DebugEvents},
+		{"session_scores", owned, &r.Scores},
+		{"run_checkpoints", owned, &r.Checkpoints},
+		{"sessions", `entity_id = ?`, &r.Sessions},
+		{"memories", `actor_id = ?`, &r.Memories},
+		{"profile_usage", `entity_id = ?`, &r.Usage},
+		{"actor_profiles", `actor_id = ?`, &r.Profiles},
+		{"entities", `id = ?`, &r.Entities},
+	}
+	d := s.db.Dialect()
+	tx, err := s.db.SQLDB().BeginTx(ctx, nil)
+	if err != nil {
+		return r, fmt.Errorf("forget: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	rows, err := tx.QueryContext(ctx, d.Rebind(`SELECT id FROM sessions WHERE entity_id = ?`), actorID)
+	if err != nil {
+		return r, fmt.Errorf("forget: list sessions: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return r, fmt.Errorf("forget: list sessions: %w", err)
+		}
+		r.SessionIDs = append(r.SessionIDs, id)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return r, fmt.Errorf("forget: list sessions: %w", err)
+	}
+	for _, st := range steps {
+		if dryRun {
+			if err := tx.QueryRowContext(ctx, d.Rebind(`SELECT COUNT(*) FROM `+st.table+` WHERE `+st.where), actorID).Scan(st.n); err != nil {
+				return r, fmt.Errorf("forget: count %s: %w", st.table, err)
+			}
+			continue
+		}
+		res, err := tx.ExecContext(ctx, d.Rebind(`DELETE FROM `+st.table+` WHERE `+st.where), actorID)
+		if err != nil {
+			return r, fmt.Errorf("forget: delete %s: %w", st.table, err)
+		}
+		n, _ := res.RowsAffected()
+		*st.n = int(n)
+	}
+	if dryRun {
+		return r, nil
+	}
+	return r, tx.Commit()
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestActorDataStore_ExportAndForget(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	sessions := NewSessionStore(db, 0, 0)
+	mems := NewMemoryStore(db)
+	events := NewSessionEventStore(db)
+	usage := NewUsageStore(db)
+
+	for _, who := range []string{"ent-1", "ent-2"} {
+		sid := who + ":slack:C1"
+		sessions.Create(sid, who, "acme", "")
+		_ = sessions.AddMessage(sid, provider.Message{Role: provider.RoleUser, Content: "hello from " + who})
+		_ = sessions.SetSummary(sid, "greeting by "+who, []provider.Message{{Role: provider.RoleUser, Content: "hello from " + who}})
+		if err := events.Insert(ctx, SessionEvent{SessionID: sid, Timestamp: time.Now(), EventType: "user_message", Payload: json.RawMessage(`{"text":"hi"}`)}); err != nil {
+			t.Fatal(err)
+		}
+		_ = usage.Record(ctx, UsageRecord{EntityID: who, ChannelID: "slack", SessionID: sid, ModelID: "m", InputTokens: 10})
+		_, _ = mems.AddScoped(ctx, who, "likes tea, says "+who)
+		_ = NewActorProfileStore(db).SaveActorProfile(ctx, &state.ActorProfile{ActorID: who, Name: who})
+		_ = NewEntityStore(db).Upsert(ctx, who, "acme")
+	}
+	_, _ = mems.AddScoped(ctx, "", "shared with everyone")
+
+	data := NewActorDataStore(db)
+	exp, err := data.Export(ctx, "ent-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp.Profile == nil || exp.Profile.Name != "ent-1" || len(exp.Memories) != 1 || exp.Memories[0].Content != "likes tea, says ent-1" ||
+		len(exp.Usage) != 1 || exp.Usage[0].InputTokens != 10 || len(exp.Sessions) != 1 {
+		t.Fatalf("export = %+v", exp)
+	}
+	se := exp.Sessions[0]
+	if se.Summary != "greeting by ent-1" || len(se.Messages) != 1 || se.Messages[0].Content != "hello from ent-1" || len(se.Events) != 1 || se.Events[0].Type != "user_message" {
+		t.Errorf("exported session = %+v", se)
+	}
+
+	want := ForgetReport{Sessions: 1, Messages: 1, Events: 1, Memories: 1, Usage: 1, Profiles: 1, Entities: 1, SessionIDs: []string{"ent-1:slack:C1"}}
+	dry, err := data.Forget(ctx, "ent-1", true)
+	want.DryRun = true
+	if err != nil || !reflect.DeepEqual(dry, want) {
+		t.Fatalf("dry run = %+v, %v; want %+v", dry, err, want)
+	}
+	if s, _ := sessions.Get("ent-1:slack:C1"); s == nil {
+		t.Fatal("the dry run deleted the session")
+	}
+	got, err := data.Forget(ctx, "ent-1", false)
+	want.DryRun = false
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Fatalf("forget = %+v, %v; want %+v", got, err, want)
+	}
+
+	after, err := data.Export(ctx, "ent-1")
+	if err != nil || after.Profile != nil || len(after.Memories)+len(after.Sessions)+len(after.Usage) != 0 {
+		t.Errorf("export after forget = %+v, %v", after, err)
+	}
+	// Other actors and shared memories are untouched.
+	if other, _ := data.Export(ctx, "ent-2"); len(other.Sessions) != 1 || len(other.Memories) != 1 {
+		t.Errorf("ent-2 after forgetting ent-1 = %+v", other)
+	}
+	if got := mems.Search("shared with everyone"); len(got) != 1 {
+		t.Error("a shared memory was forgotten")
+	}
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// ActorProfileStore keeps actor profiles in the database. It implements
+// orchestrator.ActorProfileStore.
+type ActorProfileStore struct {
+	db *DB
+}
+
+// NewActorProfileStore returns an ActorProfileStore backed by db.
+func NewActorProfileStore(db *DB) *ActorProfileStore {
+	return &ActorProfileStore{db: db}
+}
+
+// ActorProfile returns actorID's profile, or nil when they have none.
+func (s *ActorProfileStore) ActorProfile(ctx context.Context, actorID string) (*state.ActorProfile, error) {
+	p := state.ActorProfile{ActorID: actorID}
+	var updated string
+	err := s.db.SQLDB().QueryRowContext(ctx, s.db.Dialect().Rebind(`
+		SELECT name, locale, timezone, style, instructions, updated_at
+		FROM actor_profiles WHERE actor_id = ?`), actorID).
+		Scan(&p.Name, &p.Locale, &p.Timezone, &p.Style, &p.Instructions, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("actor profile store: get: %w", err)
+	}
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updated)
+	return &p, nil
+}
+
+// SaveActorProfile replaces the profile of p.ActorID. An empty profile is
+// deleted.
+func (s *ActorProfileStore) SaveActorProfile(ctx context.Context, p *state.ActorProfile) error {
+	if p.Empty() {
+		if _, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(
+			`DELETE FROM actor_profiles WHERE actor_id = ?`), p.ActorID); err != nil {
+			return fmt.Errorf("actor profile store: delete: %w", err)
+		}
+		return nil
+	}
+	updated := p.UpdatedAt
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	_, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(`
+		INSERT INTO actor_profiles (actor_id, name, locale, timezone, style, instructions, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (actor_id) DO UPDATE SET
+		  name = excluded.name, locale = excluded.locale, timezone = excluded.timezone,
+		  style = excluded.style, instructions = excluded.instructions, updated_at = excluded.updated_at`),
+		p.ActorID, p.Name, p.Locale, p.Timezone, p.Style, p.Instructions, updated.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("actor profile store: save: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestActorProfileStore_SaveUpdateDelete(t *testing.T) {
+	s := NewActorProfileStore(openTestDB(t))
+	ctx := context.Background()
+
+	if p, err := s.ActorProfile(ctx, "slack:U1"); p != nil || err != nil {
+		t.Fatalf("missing profile = %+v, %v; want nil, nil", p, err)
+	}
+	if err := s.SaveActorProfile(ctx, &state.ActorProfile{ActorID: "slack:U1", Name: "Ana", Locale: "de"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveActorProfile(ctx, &state.ActorProfile{ActorID: "slack:U1", Name: "Ana", Timezone: "Europe/Berlin", Instructions: "Use metric units."}); err != nil {
+		t.Fatal(err)
+	}
+	p, err := s.ActorProfile(ctx, "slack:U1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil || p.Name != "Ana" || p.Locale != "" || p.Timezone != "Europe/Berlin" || p.Instructions != "Use metric units." || p.UpdatedAt.IsZero() {
+		t.Fatalf("profile = %+v; want the second save to replace the first", p)
+	}
+
+	if err := s.SaveActorProfile(ctx, &state.ActorProfile{ActorID: "slack:U1"}); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.ActorProfile(ctx, "slack:U1"); p != nil {
+		t.Errorf("empty save should delete the profile, got %+v", p)
+	}
+}
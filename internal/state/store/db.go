@@ -22,7 +22,8 @@ import (
 var migrationsFS embed.FS
 
 // Open opens the database described by cfg.
-// For sqlite (default): opens dataDir/state.db with WAL mode.
+// For sqlite (default): opens dataDir/state.db with WAL mode (or
+// cfg.JournalMode) and a 5s busy timeout (or cfg.BusyTimeout).
 // For postgres: connects to cfg.DSN (dataDir is unused for the main connection but
 // still needed for plugin databases).
 // Caller must call Close when done.
@@ -47,17 +48,21 @@ func Open(cfg config.DBConfig, dataDir string) (*DB, error) {
 		if err = os.MkdirAll(dataDir, 0700); err != nil {
 			return nil, fmt.Errorf("state store: %w", err)
 		}
+		journal, busy, perr := sqlitePragmas(cfg)
+		if perr != nil {
+			return nil, fmt.Errorf("state store: %w", perr)
+		}
 		dbPath := filepath.Join(dataDir, "state.db")
 		// modernc.org/sqlite DSN pragma syntax is ?_pragma=name(value). These
 		// apply on every connection the pool opens; busy_timeout is per-connection,
 		// so it must live in the DSN, not a one-off Exec that only hits one conn.
-		rawDB, err = sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+		rawDB, err = sql.Open("sqlite", fmt.Sprintf("%s?_pragma=journal_mode(%s)&_pragma=busy_timeout(%d)", dbPath, journal, busy))
 		if err != nil {
 			return nil, fmt.Errorf("state store: open db: %w", err)
 		}
 		// Assert the pragmas actually applied — the original bug was a silently
 		// ignored pragma, so verify rather than assume.
-		if err = verifySQLitePragmas(rawDB); err != nil {
+		if err = verifySQLitePragmas(rawDB, journal, busy); err != nil {
 			_ = rawDB.Close()
 			return nil, fmt.Errorf("state store: %w", err)
 		}
@@ -115,23 +120,50 @@ func Open(cfg config.DBConfig, dataDir string) (*DB, error) {
 	return d, nil
 }
 
-// verifySQLitePragmas confirms WAL and busy_timeout took effect on a fresh
-// connection. It fails loudly instead of silently running with default
-// rollback-journal mode and no busy wait (the SQLITE_BUSY root cause).
-func verifySQLitePragmas(db *sql.DB) error {
+// sqliteJournalModes are the journal modes state.db.journal_mode accepts.
+// "memory" and "off" are left out: a crash mid-transaction could corrupt
+// the database.
+var sqliteJournalModes = map[string]bool{"wal": true, "delete": true, "truncate": true, "persist": true}
+
+// sqlitePragmas returns the journal mode and busy timeout (ms) cfg asks
+// for, defaulting to WAL and 5s.
+func sqlitePragmas(cfg config.DBConfig) (journal string, busyMS int, err error) {
+	journal = strings.ToLower(strings.TrimSpace(cfg.JournalMode))
+	if journal == "" {
+		journal = "wal"
+	}
+	if !sqliteJournalModes[journal] {
+		return "", 0, fmt.Errorf("unsupported journal_mode %q (use wal, delete, truncate or persist)", cfg.JournalMode)
+	}
+	busyMS = 5000
+	if cfg.BusyTimeout != "" {
+		d, perr := time.ParseDuration(cfg.BusyTimeout)
+		if perr != nil || d < 0 {
+			return "", 0, fmt.Errorf("invalid busy_timeout %q: want a Go duration such as 5s", cfg.BusyTimeout)
+		}
+		busyMS = int(d.Milliseconds())
+	}
+	return journal, busyMS, nil
+}
+
+// verifySQLitePragmas confirms the journal mode and busy_timeout took effect
+// on a fresh connection. It fails loudly instead of silently running with
+// default rollback-journal mode and no busy wait (the SQLITE_BUSY root
+// cause).
+func verifySQLitePragmas(db *sql.DB, wantJournal string, wantBusy int) error {
 	var journal string
 	if err := db.QueryRow("PRAGMA journal_mode").Scan(&journal); err != nil {
 		return fmt.Errorf("read journal_mode: %w", err)
 	}
-	if !strings.EqualFold(journal, "wal") {
-		return fmt.Errorf("journal_mode is %q, want wal", journal)
+	if !strings.EqualFold(journal, wantJournal) {
+		return fmt.Errorf("journal_mode is %q, want %s", journal, wantJournal)
 	}
 	var busy int
 	if err := db.QueryRow("PRAGMA busy_timeout").Scan(&busy); err != nil {
 		return fmt.Errorf("read busy_timeout: %w", err)
 	}
-	if busy != 5000 {
-		return fmt.Errorf("busy_timeout is %d, want 5000", busy)
+	if busy != wantBusy {
+		return fmt.Errorf("busy_timeout is %d, want %d", busy, wantBusy)
 	}
 	return nil
 }
@@ -140,6 +172,7 @@ func verifySQLitePragmas(db *sql.DB) error {
 type DB struct {
 	db      *sql.DB
 	dialect Dialect
+	cipher  *Cipher // encrypts message content, summaries and memories; nil = plaintext
 }
 
 // SQLDB returns the underlying *sql.DB. Do not close it directly; use Close on DB.
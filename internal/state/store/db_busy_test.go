@@ -85,3 +85,25 @@ func TestVerifySQLitePragmas(t *testing.T) {
 		t.Errorf("busy_timeout = %d, want 5000", busy)
 	}
 }
+
+func TestOpenSQLitePragmaConfig(t *testing.T) {
+	db, err := Open(config.DBConfig{JournalMode: "DELETE", BusyTimeout: "1500ms"}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	var journal string
+	var busy int
+	_ = db.SQLDB().QueryRow("PRAGMA journal_mode").Scan(&journal)
+	_ = db.SQLDB().QueryRow("PRAGMA busy_timeout").Scan(&busy)
+	if journal != "delete" || busy != 1500 {
+		t.Errorf("journal_mode = %q, busy_timeout = %d; want delete, 1500", journal, busy)
+	}
+
+	for _, cfg := range []config.DBConfig{{JournalMode: "off"}, {BusyTimeout: "soon"}} {
+		if db, err := Open(cfg, t.TempDir()); err == nil {
+			_ = db.Close()
+			t.Errorf("Open(%+v) succeeded", cfg)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encPrefix marks an encrypted column value: enc:v1:<key id>:<base64 of
+// nonce and AES-GCM ciphertext>. Values without it are plaintext, written
+// before encryption was turned on.
+const encPrefix = "enc:v1:"
+
+// rotateBatch is how many rows one RotateKey step re-encrypts.
+const rotateBatch = 500
+
+// Cipher encrypts state at rest with AES-256-GCM. It writes with the
+// current key and reads values written with any of its keys, so a rotation
+// can run while older rows still carry the previous key.
+type Cipher struct {
+	current string
+	aeads   map[string]cipher.AEAD
+}
+
+// NewCipher returns a cipher that encrypts with key and also decrypts with
+// previous. Keys are 32 bytes.
+func NewCipher(key []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{aeads: make(map[string]cipher.AEAD)}
+	for i, k := range append([][]byte{key}, previous...) {
+		if len(k) != 32 {
+			return nil, fmt.Errorf("encryption key %d is %d bytes, want 32", i, len(k))
+		}
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		id := keyID(k)
+		if i == 0 {
+			c.current = id
+		}
+		c.aeads[id] = aead
+	}
+	return c, nil
+}
+
+// ParseKey decodes a key given as base64 or hex.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if k, err := hex.DecodeString(s); err == nil && len(k) == 32 {
+		return k, nil
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if k, err := enc.DecodeString(s); err == nil {
+			if len(k) != 32 {
+				return nil, fmt.Errorf("encryption key is %d bytes, want 32", len(k))
+			}
+			return k, nil
+		}
+	}
+	return nil, errors.New("encryption key is neither base64 nor hex")
+}
+
+// GenerateKey returns a new random key, base64-encoded.
+func GenerateKey() (string, error) {
+	k := make([]byte, 32)
+	if _, err := rand.Read(k); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(k), nil
+}
+
+// keyID names a key in ciphertexts without revealing it.
+func keyID(k []byte) string {
+	sum := sha256.Sum256(k)
+	return hex.EncodeToString(sum[:4])
+}
+
+// SetCipher makes the stores over d encrypt what they write and decrypt
+// what they read. Call it before using them.
+func (d *DB) SetCipher(c *Cipher) {
+	d.cipher = c
+}
+
+// seal encrypts s for storage. The empty string stays empty, so "no
+// summary" reads the same either way.
+func (d *DB) seal(s string) (string, error) {
+	if d.cipher == nil || s == "" {
+		return s, nil
+	}
+	aead := d.cipher.aeads[d.cipher.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("encrypt: %w", err)
+	}
+	ct := aead.Seal(nonce, nonce, []byte(s), nil)
+	return encPrefix + d.cipher.current + ":" + base64.StdEncoding.EncodeToString(ct), nil
+}
+
+// open decrypts a stored value; plaintext passes through.
+func (d *DB) open(s string) (string, error) {
+	rest, ok := strings.CutPrefix(s, encPrefix)
+	if !ok {
+		return s, nil
+	}
+	if d.cipher == nil {
+		return "", errors.New("decrypt: the state is encrypted; configure state.encryption")
+	}
+	id, data, _ := strings.Cut(rest, ":")
+	aead := d.cipher.aeads[id]
+	if aead == nil {
+		return "", fmt.Errorf("decrypt: no configured key has id %s; add it to state.encryption.previous_keys", id)
+	}
+	ct, err := base64.StdEncoding.DecodeString(data)
+	if err != nil || len(ct) < aead.NonceSize() {
+		return "", errors.New("decrypt: malformed ciphertext")
+	}
+	plain, err := aead.Open(nil, ct[:aead.NonceSize()], ct[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(plain), nil
+}
+
+// RotationReport counts the values RotateKey re-encrypted.
+type RotationReport struct {
+	Messages  int
+	Summaries int
+	Memories  int
+}
+
+// RotateKey re-encrypts every message content, session summary and memory
+// not yet under the current key: values under a previous key and plaintext
+// written before encryption was on. It is safe to run while the instance
+// is serving, which writes with the same current key. On SQLite it first
+// drops the keyword search index, which holds the plaintext's words.
+func (d *DB) RotateKey(ctx context.Context) (RotationReport, error) {
+	var r RotationReport
+	if d.cipher == nil {
+		return r, errors.New("rotate: state.encryption is not configured")
+	}
+	if d.dialect != PostgresDialect {
+		if err := d.dropSearchIndex(ctx); err != nil {
+			return r, err
+		}
+	}
+	current := encPrefix + d.cipher.current + ":%"
+	for _, t := range []struct {
+		table, col, key string
+		n               *int
+	}{
+		{"messages", "content", "session_id, seq", &r.Messages},
+		{"sessions", "summary", "id", &r.Summaries},
+		{"memories", "content", "id", &r.Memories},
+	} {
+		n, err := d.reencrypt(ctx, t.table, t.col, t.key, current)
+		*t.n = n
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// dropSearchIndex removes the FTS5 tables and triggers NewSearchIndex
+// creates.
+func (d *DB) dropSearchIndex(ctx context.Context) error {
+	for _, stmt := range []string{
+		`DROP TRIGGER IF EXISTS memories_fts_ai`, `DROP TRIGGER IF EXISTS memories_fts_ad`, `DROP TRIGGER IF EXISTS memories_fts_au`,
+		`DROP TRIGGER IF EXISTS messages_fts_ai`, `DROP TRIGGER IF EXISTS messages_fts_ad`, `DROP TRIGGER IF EXISTS messages_fts_au`,
+		`DROP TABLE IF EXISTS memories_fts`, `DROP TABLE IF EXISTS messages_fts`,
+	} {
+		if _, err := d.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("rotate: drop search index: %w", err)
+		}
+	}
+	return nil
+}
+
+// reencrypt rewrites col of table under the current key in batches until
+// no value outside the current key is left. key lists the primary key
+// columns.
+func (d *DB) reencrypt(ctx context.Context, table, col, key, current string) (int, error) {
+	keyCols := strings.Split(key, ", ")
+	where := make([]string, len(keyCols))
+	for i, k := range keyCols {
+		where[i] = k + " = ?"
+	}
+	sel := d.dialect.Rebind(`SELECT ` + key + `, ` + col + ` FROM ` + table +
+		` WHERE ` + col + ` IS NOT NULL AND ` + col + ` <> '' AND ` + col + ` NOT LIKE ? LIMIT ?`)
+	upd := d.dialect.Rebind(`UPDATE ` + table + ` SET ` + col + ` = ? WHERE ` + strings.Join(where, " AND ") + ` AND ` + col + ` = ?`)
+	total := 0
+	for {
+		rows, err := d.db.QueryContext(ctx, sel, current, rotateBatch)
+		if err != nil {
+			return total, fmt.Errorf("rotate %s: %w", table, err)
+		}
+		type row struct {
+			keys  []any
+			value string
+		}
+		var batch []row
+		for rows.Next() {
+			rw := row{keys: make([]any, len(keyCols))}
+			dst := make([]any, 0, len(keyCols)+1)
+			for i := range rw.keys {
+				dst = append(dst, &rw.keys[i])
+			}
+			if err := rows.Scan(append(dst, &rw.value)...); err != nil {
+				_ = rows.Close()
+				return total, fmt.Errorf("rotate %s: %w", table, err)
+			}
+			batch = append(batch, rw)
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return total, fmt.Errorf("rotate %s: %w", table, err)
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+
+		tx, err := d.db.BeginTx(ctx, nil)
+		if err != nil {
+			return total, fmt.Errorf("rotate %s: %w", table, err)
+		}
+		done := 0
+		for _, rw := range batch {
+			plain, err := d.open(rw.value)
+			if err != nil {
+				_ = tx.Rollback()
+				return total, fmt.Errorf("rotate %s: %w", table, err)
+			}
+			sealed, err := d.seal(plain)
+			if err != nil {
+				_ = tx.Rollback()
+				return total, err
+			}
+			// The value guard skips rows the instance rewrote meanwhile.
+			res, err := tx.ExecContext(ctx, upd, append(append([]any{sealed}, rw.keys...), rw.value)...)
+			if err != nil {
+				_ = tx.Rollback()
+				return total, fmt.Errorf("rotate %s: %w", table, err)
+			}
+			n, _ := res.RowsAffected()
+			done += int(n)
+		}
+		if err := tx.Commit(); err != nil {
+			return total, fmt.Errorf("rotate %s: %w", table, err)
+		}
+		total += done
+	}
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/blob"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+func testCipher(t *testing.T, key byte, previous ...byte) *Cipher {
+	t.Helper()
+	var prev [][]byte
+	for _, p := range previous {
+		prev = append(prev, []byte(strings.Repeat(string(rune(p)), 32)))
+	}
+	c, err := NewCipher([]byte(strings.Repeat(string(rune(key)), 32)), prev...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestEncryptionAtRest(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	db.SetCipher(testCipher(t, 'a'))
+	sessions := NewSessionStore(db, 0, 0)
+	mems := NewMemoryStore(db)
+
+	ref := blob.Ref("sha256:" + strings.Repeat("0", 64))
+	sessions.Create("s1", "", "", "")
+	_ = sessions.AddMessage("s1", provider.Message{Role: provider.RoleUser, Content: "my card is 4111"})
+	_ = sessions.AddMessage("s1", provider.Message{Role: provider.RoleTool, Content: "output at " + ref})
+	if err := sessions.SetSummary("s1", "the user shared a card", []provider.Message{{Role: provider.RoleUser, Content: "my card is 4111"}, {Role: provider.RoleTool, Content: "output at " + ref}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mems.AddScoped(ctx, "", "the vault PIN is 1234"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing readable lands in the database.
+	for _, q := range []string{`SELECT content FROM messages`, `SELECT summary FROM sessions`, `SELECT content FROM memories`} {
+		rows, err := db.SQLDB().Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var v string
+			_ = rows.Scan(&v)
+			if !strings.HasPrefix(v, encPrefix) || strings.Contains(v, "4111") || strings.Contains(v, "1234") {
+				t.Errorf("%s stored %q", q, v)
+			}
+		}
+		_ = rows.Close()
+	}
+
+	// Callers see plaintext.
+	sess, err := sessions.Get("s1")
+	if err != nil || sess.Summary != "the user shared a card" || len(sess.Messages) != 2 || sess.Messages[0].Content != "my card is 4111" {
+		t.Fatalf("Get = %+v, %v", sess, err)
+	}
+	page, err := sessions.Messages(ctx, "s1", MessagePage{})
+	if err != nil || len(page) != 2 || page[1].Content != "output at "+ref {
+		t.Errorf("Messages = %+v, %v", page, err)
+	}
+	if got := mems.Search("VAULT pin"); len(got) != 1 || got[0].Content != "the vault PIN is 1234" {
+		t.Errorf("Search = %+v", got)
+	}
+	refs, err := sessions.BlobRefs(ctx)
+	if err != nil || len(refs) != 1 {
+		t.Errorf("BlobRefs = %v, %v", refs, err)
+	}
+	if _, err := NewSearchIndex(ctx, db); err == nil {
+		t.Error("keyword search accepted encrypted state")
+	}
+
+	// Without the key the state is unreadable, not silently garbled.
+	db.SetCipher(nil)
+	if _, err := sessions.Get("s1"); err == nil || !strings.Contains(err.Error(), "state.encryption") {
+		t.Errorf("Get without key: %v", err)
+	}
+	db.SetCipher(testCipher(t, 'b'))
+	if _, err := sessions.Get("s1"); err == nil || !strings.Contains(err.Error(), "previous_keys") {
+		t.Errorf("Get with another key: %v", err)
+	}
+}
+
+func TestRotateKey(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	sessions := NewSessionStore(db, 0, 0)
+	mems := NewMemoryStore(db)
+
+	// Plaintext from before encryption was turned on, indexed for search.
+	if _, err := NewSearchIndex(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	sessions.Create("s1", "", "", "")
+	for _, c := range []string{"one", "two", "three"} {
+		_ = sessions.AddMessage("s1", provider.Message{Role: provider.RoleUser, Content: c})
+	}
+	_ = sessions.SetSummary("s1", "counting", nil)
+	_ = sessions.AddMessage("s1", provider.Message{Role: provider.RoleUser, Content: "four"})
+	_, _ = mems.AddScoped(ctx, "", "remember me")
+
+	if _, err := db.RotateKey(ctx); err == nil {
+		t.Error("RotateKey without a key succeeded")
+	}
+	db.SetCipher(testCipher(t, 'a'))
+	r, err := db.RotateKey(ctx)
+	if err != nil || r != (RotationReport{Messages: 1, Summaries: 1, Memories: 1}) {
+		t.Fatalf("first rotation = %+v, %v", r, err)
+	}
+	var fts int
+	_ = db.SQLDB().QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE name LIKE '%_fts%'`).Scan(&fts)
+	if fts != 0 {
+		t.Errorf("%d search index objects left after rotation", fts)
+	}
+
+	// Rotate to a new key; the old one stays readable meanwhile.
+	db.SetCipher(testCipher(t, 'b', 'a'))
+	if sess, err := sessions.Get("s1"); err != nil || sess.Messages[0].Content != "four" {
+		t.Fatalf("Get during rotation = %+v, %v", sess, err)
+	}
+	if r, err = db.RotateKey(ctx); err != nil || r != (RotationReport{Messages: 1, Summaries: 1, Memories: 1}) {
+		t.Fatalf("second rotation = %+v, %v", r, err)
+	}
+	if r, err = db.RotateKey(ctx); err != nil || r != (RotationReport{}) {
+		t.Errorf("rotation with nothing left = %+v, %v", r, err)
+	}
+
+	db.SetCipher(testCipher(t, 'b'))
+	sess, err := sessions.Get("s1")
+	if err != nil || sess.Summary != "counting" || sess.Messages[0].Content != "four" {
+		t.Errorf("Get after dropping the old key = %+v, %v", sess, err)
+	}
+	if got := mems.Search("remember"); len(got) != 1 {
+		t.Errorf("Search after rotation = %+v", got)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k, err := ParseKey(key); err != nil || len(k) != 32 {
+		t.Errorf("ParseKey(generated) = %d bytes, %v", len(k), err)
+	}
+	raw := []byte(strings.Repeat("k", 32))
+	if k, err := ParseKey(hex.EncodeToString(raw)); err != nil || string(k) != string(raw) {
+		t.Errorf("ParseKey(hex) = %q, %v", k, err)
+	}
+	for _, bad := range []string{"", "c2hvcnQ=", "not a key!"} {
+		if _, err := ParseKey(bad); err == nil {
+			t.Errorf("ParseKey(%q) accepted", bad)
+		}
+	}
+}
@@ -55,6 +55,8 @@ type LLMResponseArgs struct {
 	FinishReason       string
 	TokensIn           int
 	TokensOut          int
+	TokensCacheRead    int // prompt-cache reads, not part of TokensIn
+	TokensCacheWrite   int // prompt-cache writes, not part of TokensIn
 	CostInput          float64
 	CostOutput         float64
 	LatencyMS          int64
@@ -78,6 +80,8 @@ func EmitLLMResponse(ctx context.Context, sink Sink, args LLMResponseArgs) strin
 		FinishReason:        args.FinishReason,
 		TokensIn:            args.TokensIn,
 		TokensOut:           args.TokensOut,
+		TokensCacheRead:     args.TokensCacheRead,
+		TokensCacheWrite:    args.TokensCacheWrite,
 		CostInput:           args.CostInput,
 		CostOutput:          args.CostOutput,
 		LatencyMS:           args.LatencyMS,
@@ -424,6 +424,8 @@ type LLMResponsePayload struct {
 	FinishReason        string          `json:"finish_reason,omitempty"`
 	TokensIn            int             `json:"tokens_in,omitempty"`
 	TokensOut           int             `json:"tokens_out,omitempty"`
+	TokensCacheRead     int             `json:"tokens_cache_read,omitempty"`
+	TokensCacheWrite    int             `json:"tokens_cache_write,omitempty"`
 	CostInput           float64         `json:"cost_input,omitempty"`
 	CostOutput          float64         `json:"cost_output,omitempty"`
 	LatencyMS           int64           `json:"latency_ms,omitempty"`
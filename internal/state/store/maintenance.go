@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MaintenancePolicy is what one Maintain run does.
+type MaintenancePolicy struct {
+	MaxBytes    int64 // SQLite: prune sessions until the data takes at most this; 0 = no cap
+	MaxSessions int   // keep at most this many sessions; 0 = no cap
+	Vacuum      bool  // SQLite: VACUUM after pruning
+}
+
+// MaintenanceReport says what a Maintain run did.
+type MaintenanceReport struct {
+	SessionsPruned int
+	SizeBefore     int64 // SQLite: bytes in use before the run; 0 on Postgres
+	SizeAfter      int64
+	Vacuumed       bool
+}
+
+// pruneBatch is how many sessions one size-cap step deletes before the
+// size is measured again.
+const pruneBatch = 10
+
+// Maintain enforces the size caps of p by deleting the least recently
+// updated sessions with their messages, then runs ANALYZE and, when
+// p.Vacuum is set on SQLite, VACUUM. A VACUUM can renumber rowids, so the
+// search index is rebuilt after one when it exists.
+func (d *DB) Maintain(ctx context.Context, p MaintenancePolicy) (MaintenanceReport, error) {
+	var r MaintenanceReport
+	sqlite := d.dialect != PostgresDialect
+	if sqlite {
+		size, err := d.UsedBytes(ctx)
+		if err != nil {
+			return r, err
+		}
+		r.SizeBefore, r.SizeAfter = size, size
+	}
+
+	if p.MaxSessions > 0 {
+		var count int
+		if err := d.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sessions`).Scan(&count); err != nil {
+			return r, fmt.Errorf("maintenance: count sessions: %w", err)
+		}
+		if excess := count - p.MaxSessions; excess > 0 {
+			n, err := d.pruneOldestSessions(ctx, excess)
+			r.SessionsPruned += n
+			if err != nil {
+				return r, err
+			}
+		}
+	}
+
+	if sqlite && p.MaxBytes > 0 {
+		for r.SizeAfter > p.MaxBytes {
+			n, err := d.pruneOldestSessions(ctx, pruneBatch)
+			r.SessionsPruned += n
+			if err != nil {
+				return r, err
+			}
+			if r.SizeAfter, err = d.UsedBytes(ctx); err != nil {
+				return r, err
+			}
+			if n == 0 {
+				slog.Warn("state database is over its size cap with no sessions left to prune", "component", "maintenance", "bytes", r.SizeAfter, "max_bytes", p.MaxBytes)
+				break
+			}
+		}
+	}
+
+	if _, err := d.db.ExecContext(ctx, `ANALYZE`); err != nil {
+		return r, fmt.Errorf("maintenance: analyze: %w", err)
+	}
+	if sqlite && p.Vacuum {
+		if _, err := d.db.ExecContext(ctx, `VACUUM`); err != nil {
+			return r, fmt.Errorf("maintenance: vacuum: %w", err)
+		}
+		r.Vacuumed = true
+		if err := d.rebuildSearchIndex(ctx); err != nil {
+			return r, err
+		}
+	}
+	if sqlite {
+		var err error
+		if r.SizeAfter, err = d.UsedBytes(ctx); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// pruneOldestSessions deletes up to n of the least recently updated
+// sessions and their messages, and returns how many went.
+func (d *DB) pruneOldestSessions(ctx context.Context, n int) (int, error) {
+	oldest := d.dialect.Rebind(`SELECT id FROM sessions ORDER BY updated_at, id LIMIT ?`)
+	if _, err := d.db.ExecContext(ctx, `DELETE FROM messages WHERE session_id IN (`+oldest+`)`, n); err != nil {
+		return 0, fmt.Errorf("maintenance: prune messages: %w", err)
+	}
+	res, err := d.db.ExecContext(ctx, `DELETE FROM sessions WHERE id IN (`+oldest+`)`, n)
+	if err != nil {
+		return 0, fmt.Errorf("maintenance: prune sessions: %w", err)
+	}
+	pruned, _ := res.RowsAffected()
+	return int(pruned), nil
+}
+
+// rebuildSearchIndex rebuilds the FTS5 index when NewSearchIndex created
+// one.
+func (d *DB) rebuildSearchIndex(ctx context.Context) error {
+	var n int
+	if err := d.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'messages_fts'`).Scan(&n); err != nil {
+		return fmt.Errorf("maintenance: %w", err)
+	}
+	if n == 0 {
+		return nil
+	}
+	return (&SearchIndex{db: d}).Rebuild(ctx)
+}
+
+// UsedBytes returns the bytes of the SQLite database holding data: its
+// size minus free pages, which deletions add to and VACUUM releases.
+func (d *DB) UsedBytes(ctx context.Context) (int64, error) {
+	if d.dialect == PostgresDialect {
+		return 0, errors.New("database size is only measured on sqlite")
+	}
+	var pages, free, pageSize int64
+	for _, q := range []struct {
+		pragma string
+		dst    *int64
+	}{{"page_count", &pages}, {"freelist_count", &free}, {"page_size", &pageSize}} {
+		if err := d.db.QueryRowContext(ctx, "PRAGMA "+q.pragma).Scan(q.dst); err != nil {
+			return 0, fmt.Errorf("maintenance: read %s: %w", q.pragma, err)
+		}
+	}
+	return (pages - free) * pageSize, nil
+}
+
+// Backup writes a consistent snapshot of the SQLite database to path while
+// it stays in use (VACUUM INTO). path must not exist. On Postgres, use
+// pg_dump.
+func (d *DB) Backup(ctx context.Context, path string) error {
+	if d.dialect == PostgresDialect {
+		return errors.New("backup: the state database is on postgres; use pg_dump")
+	}
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup: %s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	if _, err := d.db.ExecContext(ctx, `VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	return os.Chmod(path, 0600)
+}
+
+// RunMaintenance runs Maintain every interval until ctx is cancelled, the
+// first time one interval after start so it does not slow startup.
+// interval <= 0 disables it.
+func RunMaintenance(ctx context.Context, db *DB, interval time.Duration, p MaintenancePolicy) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			r, err := db.Maintain(ctx, p)
+			if err != nil {
+				slog.Warn("state maintenance failed", "component", "maintenance", "error", err)
+				continue
+			}
+			slog.Info("state maintenance", "component", "maintenance", "sessions_pruned", r.SessionsPruned,
+				"bytes_before", r.SizeBefore, "bytes_after", r.SizeAfter, "vacuumed", r.Vacuumed, "duration", time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/config"
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+func TestMaintain_SizeCaps(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	sessions := NewSessionStore(db, 0, 0)
+	idx, err := NewSearchIndex(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("lorem ipsum dolor sit amet ", 400)
+	for i := range 120 {
+		id := fmt.Sprintf("s%03d", i)
+		sessions.Create(id, "", "", "")
+		if err := sessions.AddMessage(id, provider.Message{Role: provider.RoleUser, Content: fmt.Sprintf("session %s marker%03d %s", id, i, big)}); err != nil {
+			t.Fatal(err)
+		}
+		// Spread updated_at so the prune order is deterministic.
+		if _, err := db.SQLDB().ExecContext(ctx, `UPDATE sessions SET updated_at = ? WHERE id = ?`, fmt.Sprintf("2026-01-01T00:%02d:%02dZ", i/60, i%60), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := db.Maintain(ctx, MaintenancePolicy{MaxSessions: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SessionsPruned != 20 {
+		t.Errorf("pruned %d sessions, want 20", r.SessionsPruned)
+	}
+	if s, _ := sessions.Get("s019"); s != nil {
+		t.Error("the oldest sessions must go first")
+	}
+	if s, _ := sessions.Get("s020"); s == nil {
+		t.Error("s020 was pruned")
+	}
+
+	// Cap the size at three quarters of what is used now.
+	used, err := db.UsedBytes(ctx)
+	if err != nil || used <= 0 {
+		t.Fatalf("UsedBytes = %d, %v", used, err)
+	}
+	limit := used * 3 / 4
+	r, err = db.Maintain(ctx, MaintenancePolicy{MaxBytes: limit, Vacuum: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.SessionsPruned == 0 || r.SessionsPruned >= 100 || r.SizeAfter > limit || !r.Vacuumed || r.SizeBefore != used {
+		t.Errorf("report = %+v (cap %d)", r, limit)
+	}
+	var left int
+	_ = db.SQLDB().QueryRow(`SELECT COUNT(*) FROM messages`).Scan(&left)
+	if left != 100-r.SessionsPruned {
+		t.Errorf("%d messages left, want %d", left, 100-r.SessionsPruned)
+	}
+
+	// After the VACUUM the search index still points at the right rows.
+	hits, err := idx.Search(ctx, SearchQuery{Text: "marker119", SessionID: "s119"})
+	if err != nil || len(hits) != 1 || !strings.Contains(hits[0].Snippet, "marker119") {
+		t.Errorf("search after vacuum = %+v, %v", hits, err)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	mem := NewMemoryStore(db)
+	if _, err := mem.AddScoped(ctx, "", "backed up"); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "snap", "state.db")
+	if err := db.Backup(ctx, target); err != nil {
+		t.Fatalf("Backup: %v", err)
+	}
+	if err := db.Backup(ctx, target); err == nil {
+		t.Error("Backup over an existing file succeeded")
+	}
+
+	// The snapshot is a complete state database.
+	snap, err := Open(config.DBConfig{}, filepath.Dir(target))
+	if err != nil {
+		t.Fatalf("open snapshot: %v", err)
+	}
+	defer func() { _ = snap.Close() }()
+	if got := NewMemoryStore(snap).Search("backed up"); len(got) != 1 {
+		t.Errorf("snapshot memories = %+v", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"strings"
 	"time"
 
@@ -36,14 +37,19 @@ func (s *MemoryStore) AddScoped(ctx context.Context, actorID string, content str
 	if actorID != "" {
 		aid = &actorID
 	}
+	sealed, err := s.db.seal(content)
+	if err != nil {
+		return nil, fmt.Errorf("memory add: %w", err)
+	}
 	_, err = s.db.SQLDB().ExecContext(ctx,
 		s.db.Dialect().Rebind(`INSERT INTO memories (id, actor_id, content, tags, created_at) VALUES (?, ?, ?, ?, ?)`),
-		id, aid, content, string(tagsJSON), now)
+		id, aid, sealed, string(tagsJSON), now)
 	if err != nil {
 		return nil, fmt.Errorf("memory add: %w", err)
 	}
 	return &state.Memory{
 		ID:        id,
+		ActorID:   actorID,
 		Content:   content,
 		Tags:      tags,
 		CreatedAt: parseTimeOrZero(now),
@@ -75,6 +81,10 @@ func (s *MemoryStore) MemoriesForContext(ctx context.Context, tag string) ([]*st
 		if err := rows.Scan(&id, &actorIDNull, &content, &tagsJSON, &createdAt); err != nil {
 			return nil, fmt.Errorf("memories scan: %w", err)
 		}
+		content, err := s.db.open(content)
+		if err != nil {
+			return nil, fmt.Errorf("memories for context: %w", err)
+		}
 		var tags []string
 		if tagsJSON != "" {
 			_ = json.Unmarshal([]byte(tagsJSON), &tags)
@@ -82,6 +92,7 @@ func (s *MemoryStore) MemoriesForContext(ctx context.Context, tag string) ([]*st
 		t, _ := time.Parse(time.RFC3339, createdAt)
 		out = append(out, &state.Memory{
 			ID:        id,
+			ActorID:   stringOrEmpty(actorIDNull),
 			Content:   content,
 			Tags:      tags,
 			CreatedAt: t,
@@ -91,9 +102,23 @@ func (s *MemoryStore) MemoriesForContext(ctx context.Context, tag string) ([]*st
 }
 
 // Search returns memories whose content contains the query (case-insensitive). No actor scope; global only.
-// For backward compatibility when needed.
+// For backward compatibility when needed. With encryption at rest the match runs after decrypting every memory.
 func (s *MemoryStore) Search(query string) []*state.Memory {
 	lower := strings.ToLower(query)
+	if s.db.cipher != nil {
+		rows, err := s.db.SQLDB().Query(`SELECT id, actor_id, content, tags, created_at FROM memories ORDER BY created_at DESC`)
+		if err != nil {
+			return nil
+		}
+		defer func() { _ = rows.Close() }()
+		var out []*state.Memory
+		for _, m := range s.scanMemories(rows) {
+			if strings.Contains(strings.ToLower(m.Content), lower) {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
 	rows, err := s.db.SQLDB().Query(
 		s.db.Dialect().Rebind(`SELECT id, actor_id, content, tags, created_at FROM memories WHERE LOWER(content) LIKE ? ORDER BY created_at DESC`),
 		"%"+lower+"%")
@@ -101,7 +126,7 @@ func (s *MemoryStore) Search(query string) []*state.Memory {
 		return nil
 	}
 	defer func() { _ = rows.Close() }()
-	return scanMemories(rows)
+	return s.scanMemories(rows)
 }
 
 // SearchByTag returns all memories that have the given tag (exact match). No actor scope; global only.
@@ -113,10 +138,11 @@ func (s *MemoryStore) SearchByTag(tag string) []*state.Memory {
 		return nil
 	}
 	defer func() { _ = rows.Close() }()
-	return scanMemories(rows)
+	return s.scanMemories(rows)
 }
 
-func scanMemories(rows *sql.Rows) []*state.Memory {
+// scanMemories reads memory rows, skipping those that fail to decrypt.
+func (s *MemoryStore) scanMemories(rows *sql.Rows) []*state.Memory {
 	var out []*state.Memory
 	for rows.Next() {
 		var id, content, tagsJSON, createdAt string
@@ -124,16 +150,69 @@ func scanMemories(rows *sql.Rows) []*state.Memory {
 		if err := rows.Scan(&id, &actorIDNull, &content, &tagsJSON, &createdAt); err != nil {
 			return nil
 		}
+		content, err := s.db.open(content)
+		if err != nil {
+			slog.Warn("skipping memory", "id", id, "error", err)
+			continue
+		}
 		var tags []string
 		if tagsJSON != "" {
 			_ = json.Unmarshal([]byte(tagsJSON), &tags)
 		}
 		t, _ := time.Parse(time.RFC3339, createdAt)
-		out = append(out, &state.Memory{ID: id, Content: content, Tags: tags, CreatedAt: t})
+		out = append(out, &state.Memory{ID: id, ActorID: stringOrEmpty(actorIDNull), Content: content, Tags: tags, CreatedAt: t})
 	}
 	return out
 }
 
+// Get returns the memory with id, whoever owns it, or nil when there is
+// none.
+func (s *MemoryStore) Get(ctx context.Context, id string) (*state.Memory, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx,
+		s.db.Dialect().Rebind(`SELECT id, actor_id, content, tags, created_at FROM memories WHERE id = ?`), id)
+	if err != nil {
+		return nil, fmt.Errorf("memory get: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	mems := s.scanMemories(rows)
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("memory get: %w", err)
+	}
+	if len(mems) == 0 {
+		return nil, nil
+	}
+	return mems[0], nil
+}
+
+// Promote moves the per-actor memory id to the general scope, so every
+// actor sees it from the next turn on. It fails when the memory does not
+// exist or is general already.
+func (s *MemoryStore) Promote(ctx context.Context, id string) (*state.Memory, error) {
+	res, err := s.db.SQLDB().ExecContext(ctx,
+		s.db.Dialect().Rebind(`UPDATE memories SET actor_id = NULL WHERE id = ? AND actor_id IS NOT NULL`), id)
+	if err != nil {
+		return nil, fmt.Errorf("memory promote: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		m, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if m == nil {
+			return nil, fmt.Errorf("memory %q not found", id)
+		}
+		return nil, fmt.Errorf("memory %q is already shared with everyone", id)
+	}
+	return s.Get(ctx, id)
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 func parseTimeOrZero(s string) time.Time {
 	t, _ := time.Parse(time.RFC3339, s)
 	return t
@@ -0,0 +1,26 @@
+-- Dynamic scheduler jobs (created with scheduler.create_job) for deployments
+-- that run several replicas against one database. Without a shared table each
+-- pod keeps its own jobs.yaml, so a job created through one pod is unknown to
+-- the others, and a config job fires once per pod.
+--
+-- scheduler_jobs: one row per dynamic job; job is the JSON-encoded
+-- scheduler.Job. Config jobs are never stored: every replica loads them from
+-- its own config.
+--
+-- scheduler_runs: one row per (job, schedule slot) claimed by the replica that
+-- runs it. The primary key makes the claim an atomic insert; rows older than
+-- a week are pruned on insert.
+--
+-- Portability: TEXT only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS scheduler_jobs (
+    name       TEXT PRIMARY KEY,
+    job        TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS scheduler_runs (
+    job_name   TEXT NOT NULL,
+    slot       TEXT NOT NULL,
+    claimed_at TEXT NOT NULL,
+    PRIMARY KEY (job_name, slot)
+);
@@ -0,0 +1,23 @@
+-- LLM-judge scores for completed sessions (evaluation: in config). One row per
+-- (session, criterion, judging): a session that is resumed and completes
+-- again is judged again, and both judgings are kept.
+--
+-- model_id is the model that served the session and prompt_hash the built-in
+-- prompt set hash at judging time, so averages can be grouped by them to
+-- compare before and after a model or prompt change.
+--
+-- Portability: TEXT/REAL only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS session_scores (
+    id          TEXT PRIMARY KEY,
+    session_id  TEXT NOT NULL,
+    criterion   TEXT NOT NULL,
+    score       REAL NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    model_id    TEXT NOT NULL DEFAULT '',
+    judge_model TEXT NOT NULL DEFAULT '',
+    prompt_hash TEXT NOT NULL DEFAULT '',
+    created_at  TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_scores_session ON session_scores(session_id);
+CREATE INDEX IF NOT EXISTS idx_session_scores_created ON session_scores(created_at);
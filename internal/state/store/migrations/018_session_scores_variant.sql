@@ -0,0 +1,4 @@
+-- Experiment variant (experiments: in config) that served the scored
+-- session, so judge averages can be compared per variant. '' for sessions
+-- outside an experiment and for scores recorded before this migration.
+ALTER TABLE session_scores ADD COLUMN variant TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,16 @@
+-- Per-actor preferences (name, reply language, timezone, response style,
+-- standing instructions) set through the built-in profile tool. One row per
+-- actor id (channel:user); the orchestrator adds them to the system prompt
+-- of every turn the actor runs, in any session. A profile whose fields are
+-- all cleared is deleted.
+--
+-- Portability: TEXT only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS actor_profiles (
+    actor_id     TEXT PRIMARY KEY,
+    name         TEXT NOT NULL DEFAULT '',
+    locale       TEXT NOT NULL DEFAULT '',
+    timezone     TEXT NOT NULL DEFAULT '',
+    style        TEXT NOT NULL DEFAULT '',
+    instructions TEXT NOT NULL DEFAULT '',
+    updated_at   TEXT NOT NULL
+);
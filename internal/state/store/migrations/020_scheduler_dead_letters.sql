@@ -0,0 +1,11 @@
+-- Scheduler dead letters: one row per job whose latest run failed even after
+-- its retries, so an operator can see what is broken across replicas and
+-- re-run it with scheduler.retry_job. The row is deleted when the job
+-- succeeds again. dead_letter is the JSON-encoded scheduler.DeadLetter.
+--
+-- Portability: TEXT only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS scheduler_dead_letters (
+    job_name    TEXT PRIMARY KEY,
+    dead_letter TEXT NOT NULL,
+    failed_at   TEXT NOT NULL
+);
@@ -0,0 +1,12 @@
+-- Agent-loop checkpoints: one row per session whose turn is still running,
+-- rewritten at each safe point (a new loop round, a tool call dispatched or
+-- finished) and deleted when the turn ends. Rows left after a restart are
+-- the turns it interrupted. checkpoint is the JSON-encoded
+-- state.RunCheckpoint.
+--
+-- Portability: TEXT only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS run_checkpoints (
+    session_id TEXT PRIMARY KEY,
+    checkpoint TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
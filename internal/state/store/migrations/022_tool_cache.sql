@@ -0,0 +1,12 @@
+-- Tool result cache: results of read-only tool calls, reused until
+-- expires_at by any replica sharing the database. cache_key is a hash of
+-- the caller, plugin, action and args (see orchestrator.toolCache).
+--
+-- Portability: TEXT only; times are RFC3339 UTC so they sort as strings.
+CREATE TABLE IF NOT EXISTS tool_cache (
+    cache_key  TEXT PRIMARY KEY,
+    content    TEXT NOT NULL,
+    structured TEXT NOT NULL,
+    expires_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_tool_cache_expires ON tool_cache (expires_at);
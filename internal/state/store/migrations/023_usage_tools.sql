@@ -0,0 +1,7 @@
+-- profile_usage.tools: the tools a run called, one plugin__action per call
+-- joined with commas (a tool called twice appears twice), so per-user
+-- statistics can name the tools used most and not only count calls. NULL
+-- for runs that called no tools and for rows written before this migration.
+--
+-- Portability: TEXT only; no arrays or jsonb. Runs on SQLite and PostgreSQL.
+ALTER TABLE profile_usage ADD COLUMN tools TEXT;
@@ -49,7 +49,7 @@ func RunPluginMigrations(dataDir, pluginName, pluginPath string) error {
 		return fmt.Errorf("plugin db open: %w", err)
 	}
 	defer func() { _ = db.Close() }()
-	if err := verifySQLitePragmas(db); err != nil {
+	if err := verifySQLitePragmas(db, "wal", 5000); err != nil {
 		return fmt.Errorf("plugin db: %w", err)
 	}
 	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL PRIMARY KEY)"); err != nil {
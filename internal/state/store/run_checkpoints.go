@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+// RunCheckpointStore keeps agent-loop checkpoints in the database. It
+// implements orchestrator.RunCheckpointStore.
+type RunCheckpointStore struct {
+	db *DB
+}
+
+// NewRunCheckpointStore returns a RunCheckpointStore backed by db.
+func NewRunCheckpointStore(db *DB) *RunCheckpointStore {
+	return &RunCheckpointStore{db: db}
+}
+
+// SaveRunCheckpoint inserts cp or replaces the session's checkpoint.
+func (s *RunCheckpointStore) SaveRunCheckpoint(ctx context.Context, cp *state.RunCheckpoint) error {
+	raw, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("run checkpoint store: encode: %w", err)
+	}
+	_, err = s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(`
+		INSERT INTO run_checkpoints (session_id, checkpoint, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (session_id) DO UPDATE SET checkpoint = excluded.checkpoint, updated_at = excluded.updated_at`),
+		cp.SessionID, string(raw), cp.UpdatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("run checkpoint store: save %s: %w", cp.SessionID, err)
+	}
+	return nil
+}
+
+// DeleteRunCheckpoint drops the session's checkpoint, if any.
+func (s *RunCheckpointStore) DeleteRunCheckpoint(ctx context.Context, sessionID string) error {
+	_, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(`DELETE FROM run_checkpoints WHERE session_id = ?`), sessionID)
+	if err != nil {
+		return fmt.Errorf("run checkpoint store: delete %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// ListRunCheckpoints returns every stored checkpoint, oldest first.
+func (s *RunCheckpointStore) ListRunCheckpoints(ctx context.Context) ([]state.RunCheckpoint, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, `SELECT checkpoint FROM run_checkpoints ORDER BY updated_at, session_id`)
+	if err != nil {
+		return nil, fmt.Errorf("run checkpoint store: list: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []state.RunCheckpoint
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("run checkpoint store: list: %w", err)
+		}
+		var cp state.RunCheckpoint
+		if err := json.Unmarshal([]byte(raw), &cp); err != nil {
+			return nil, fmt.Errorf("run checkpoint store: decode: %w", err)
+		}
+		out = append(out, cp)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/state"
+)
+
+func TestRunCheckpointStore(t *testing.T) {
+	s := NewRunCheckpointStore(openTestDB(t))
+	ctx := context.Background()
+	t0 := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	save := func(cp state.RunCheckpoint) {
+		t.Helper()
+		if err := s.SaveRunCheckpoint(ctx, &cp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(state.RunCheckpoint{SessionID: "b", UserMessage: "deploy", Iteration: 1, StartedAt: t0, UpdatedAt: t0.Add(time.Minute)})
+	save(state.RunCheckpoint{SessionID: "a", UserMessage: "release", Iteration: 1, StartedAt: t0, UpdatedAt: t0})
+	save(state.RunCheckpoint{SessionID: "a", UserMessage: "release", Iteration: 3, Completed: []string{"git__tag"}, Pending: "slack__post", StartedAt: t0, UpdatedAt: t0.Add(2 * time.Minute)})
+
+	cps, err := s.ListRunCheckpoints(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cps) != 2 || cps[0].SessionID != "b" || cps[1].Iteration != 3 || cps[1].Pending != "slack__post" || cps[1].Completed[0] != "git__tag" {
+		t.Fatalf("checkpoints = %+v", cps)
+	}
+
+	if err := s.DeleteRunCheckpoint(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteRunCheckpoint(ctx, "missing"); err != nil {
+		t.Errorf("deleting a missing checkpoint: %v", err)
+	}
+	if cps, _ = s.ListRunCheckpoints(ctx); len(cps) != 1 || cps[0].SessionID != "a" {
+		t.Errorf("after delete: %+v", cps)
+	}
+}
@@ -0,0 +1,159 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+// schedulerRunRetention is how long claimed run slots are kept. A slot only
+// has to outlive the clock skew between replicas; a week leaves room for
+// inspecting recent runs.
+const schedulerRunRetention = 7 * 24 * time.Hour
+
+// SchedulerJobStore keeps dynamic scheduler jobs in the database so every
+// replica sharing it sees the same jobs. It implements scheduler.JobStore,
+// scheduler.RunClaimer and scheduler.DeadLetterStore.
+type SchedulerJobStore struct {
+	db *DB
+}
+
+// NewSchedulerJobStore returns a SchedulerJobStore backed by db.
+func NewSchedulerJobStore(db *DB) *SchedulerJobStore {
+	return &SchedulerJobStore{db: db}
+}
+
+// LoadJobs returns every stored job, ordered by name.
+func (s *SchedulerJobStore) LoadJobs() ([]scheduler.Job, error) {
+	rows, err := s.db.SQLDB().Query(`SELECT job FROM scheduler_jobs ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler job store: load: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var jobs []scheduler.Job
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("scheduler job store: load: %w", err)
+		}
+		var job scheduler.Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			return nil, fmt.Errorf("scheduler job store: decode job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// PutJob inserts job or replaces the stored job with the same name.
+func (s *SchedulerJobStore) PutJob(job scheduler.Job) error {
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("scheduler job store: encode job: %w", err)
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	_, err = s.db.SQLDB().Exec(s.db.Dialect().Rebind(`
+		INSERT INTO scheduler_jobs (name, job, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET job = excluded.job, updated_at = excluded.updated_at`),
+		job.Name, string(raw), now)
+	if err != nil {
+		return fmt.Errorf("scheduler job store: put %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// DeleteJob removes the job; deleting a missing job is not an error.
+func (s *SchedulerJobStore) DeleteJob(name string) error {
+	_, err := s.db.SQLDB().Exec(s.db.Dialect().Rebind(`DELETE FROM scheduler_jobs WHERE name = ?`), name)
+	if err != nil {
+		return fmt.Errorf("scheduler job store: delete %s: %w", name, err)
+	}
+	return nil
+}
+
+// ClaimRun records that this replica runs name for slot. The first replica
+// to insert the (name, slot) row wins; the others get false. A dynamic job
+// that is no longer stored cannot be claimed, so a job deleted on one replica
+// stops firing on the others before their next sync.
+func (s *SchedulerJobStore) ClaimRun(ctx context.Context, name string, slot time.Time, dynamic bool) (bool, error) {
+	now := time.Now().UTC()
+	slotStr := slot.UTC().Format(time.RFC3339Nano)
+	nowStr := now.Format(time.RFC3339)
+	q := `INSERT INTO scheduler_runs (job_name, slot, claimed_at) VALUES (?, ?, ?)
+		ON CONFLICT (job_name, slot) DO NOTHING`
+	args := []any{name, slotStr, nowStr}
+	if dynamic {
+		q = `INSERT INTO scheduler_runs (job_name, slot, claimed_at)
+		SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM scheduler_jobs WHERE name = ?)
+		ON CONFLICT (job_name, slot) DO NOTHING`
+		args = append(args, name)
+	}
+	res, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(q), args...)
+	if err != nil {
+		return false, fmt.Errorf("scheduler job store: claim %s: %w", name, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("scheduler job store: claim %s: %w", name, err)
+	}
+	if n == 1 {
+		cutoff := now.Add(-schedulerRunRetention).Format(time.RFC3339)
+		if _, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(
+			`DELETE FROM scheduler_runs WHERE claimed_at < ?`), cutoff); err != nil {
+			// The claim stands; pruning is retried on the next one.
+			slog.Warn("scheduler job store: prune runs failed", "error", err)
+		}
+	}
+	return n == 1, nil
+}
+
+// PutDeadLetter records dl, replacing the job's previous dead letter.
+func (s *SchedulerJobStore) PutDeadLetter(dl scheduler.DeadLetter) error {
+	raw, err := json.Marshal(dl)
+	if err != nil {
+		return fmt.Errorf("scheduler job store: encode dead letter: %w", err)
+	}
+	_, err = s.db.SQLDB().Exec(s.db.Dialect().Rebind(`
+		INSERT INTO scheduler_dead_letters (job_name, dead_letter, failed_at) VALUES (?, ?, ?)
+		ON CONFLICT (job_name) DO UPDATE SET dead_letter = excluded.dead_letter, failed_at = excluded.failed_at`),
+		dl.Job, string(raw), dl.FailedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("scheduler job store: put dead letter %s: %w", dl.Job, err)
+	}
+	return nil
+}
+
+// LoadDeadLetters returns every dead letter, most recent failure first.
+func (s *SchedulerJobStore) LoadDeadLetters() ([]scheduler.DeadLetter, error) {
+	rows, err := s.db.SQLDB().Query(`SELECT dead_letter FROM scheduler_dead_letters ORDER BY failed_at DESC, job_name`)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler job store: load dead letters: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var dls []scheduler.DeadLetter
+	for rows.Next() {
+		var raw string
+		if err := rows.Scan(&raw); err != nil {
+			return nil, fmt.Errorf("scheduler job store: load dead letters: %w", err)
+		}
+		var dl scheduler.DeadLetter
+		if err := json.Unmarshal([]byte(raw), &dl); err != nil {
+			return nil, fmt.Errorf("scheduler job store: decode dead letter: %w", err)
+		}
+		dls = append(dls, dl)
+	}
+	return dls, rows.Err()
+}
+
+// DeleteDeadLetter drops the job's dead letter, if any.
+func (s *SchedulerJobStore) DeleteDeadLetter(job string) error {
+	_, err := s.db.SQLDB().Exec(s.db.Dialect().Rebind(`DELETE FROM scheduler_dead_letters WHERE job_name = ?`), job)
+	if err != nil {
+		return fmt.Errorf("scheduler job store: delete dead letter %s: %w", job, err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/scheduler"
+)
+
+func TestSchedulerJobStore_PutLoadDelete(t *testing.T) {
+	s := NewSchedulerJobStore(openTestDB(t))
+
+	if err := s.PutJob(scheduler.Job{Name: "b", Interval: "1h", Action: "x.y", Source: "dynamic"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutJob(scheduler.Job{Name: "a", Cron: "0 9 * * *", Action: "x.y", Args: map[string]string{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutJob(scheduler.Job{Name: "b", Interval: "2h", Action: "x.y", Paused: true}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := s.LoadJobs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].Name != "a" || jobs[0].Args["k"] != "v" || jobs[1].Interval != "2h" || !jobs[1].Paused {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+
+	if err := s.DeleteJob("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteJob("missing"); err != nil {
+		t.Errorf("deleting a missing job: %v", err)
+	}
+	if jobs, _ = s.LoadJobs(); len(jobs) != 1 || jobs[0].Name != "b" {
+		t.Errorf("after delete: %+v", jobs)
+	}
+}
+
+func TestSchedulerJobStore_ClaimRun(t *testing.T) {
+	s := NewSchedulerJobStore(openTestDB(t))
+	ctx := context.Background()
+	slot := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	claim := func(name string, slot time.Time, dynamic bool) bool {
+		t.Helper()
+		won, err := s.ClaimRun(ctx, name, slot, dynamic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return won
+	}
+	if !claim("cfg", slot, false) {
+		t.Error("first claim of a config job should win")
+	}
+	if claim("cfg", slot, false) {
+		t.Error("second claim of the same slot should lose")
+	}
+	if !claim("cfg", slot.Add(time.Hour), false) {
+		t.Error("the next slot is a new run")
+	}
+
+	if claim("dyn", slot, true) {
+		t.Error("a dynamic job that is not stored cannot be claimed")
+	}
+	if err := s.PutJob(scheduler.Job{Name: "dyn", Interval: "1h", Action: "x.y"}); err != nil {
+		t.Fatal(err)
+	}
+	if !claim("dyn", slot, true) {
+		t.Error("a stored dynamic job should be claimable")
+	}
+}
+
+func TestSchedulerJobStore_DeadLetters(t *testing.T) {
+	s := NewSchedulerJobStore(openTestDB(t))
+	at := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	for _, dl := range []scheduler.DeadLetter{
+		{Job: "a", Action: "x.y", Error: "boom", Attempts: 3, Failures: 1, FailedAt: at},
+		{Job: "b", Action: "x.z", Error: "down", Attempts: 1, Failures: 1, FailedAt: at.Add(time.Minute)},
+		{Job: "a", Action: "x.y", Error: "boom again", Attempts: 3, Failures: 2, FailedAt: at.Add(time.Hour)},
+	} {
+		if err := s.PutDeadLetter(dl); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dls, err := s.LoadDeadLetters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dls) != 2 || dls[0].Job != "a" || dls[0].Failures != 2 || dls[0].Error != "boom again" || dls[1].Job != "b" {
+		t.Fatalf("dead letters = %+v", dls)
+	}
+	if err := s.DeleteDeadLetter("a"); err != nil {
+		t.Fatal(err)
+	}
+	if dls, _ = s.LoadDeadLetters(); len(dls) != 1 || dls[0].Job != "b" {
+		t.Errorf("after delete: %+v", dls)
+	}
+}
@@ -0,0 +1,273 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search hit kinds.
+const (
+	SearchKindMemory  = "memory"
+	SearchKindMessage = "message"
+)
+
+// SearchQuery is a keyword search over memories and conversation messages.
+type SearchQuery struct {
+	Text      string
+	Actor     string    // memories: general ones plus this actor's; messages: sessions owned by this entity
+	SessionID string    // messages of this session are searched whoever owns it (the current conversation)
+	Since     time.Time // zero = no lower bound
+	Kind      string    // SearchKindMemory or SearchKindMessage; empty = both
+	Limit     int       // 0 = 10
+}
+
+// SearchHit is one memory or message matching a SearchQuery.
+type SearchHit struct {
+	Kind      string    `json:"kind"`
+	ID        string    `json:"id,omitempty"`         // memory id
+	SessionID string    `json:"session_id,omitempty"` // message hits
+	Role      string    `json:"role,omitempty"`       // message hits
+	Snippet   string    `json:"snippet"`              // the matching part, terms in [brackets]
+	CreatedAt time.Time `json:"created_at"`
+	Score     float64   `json:"score"` // higher is better
+}
+
+const defaultSearchLimit = 10
+
+// SearchIndex is a full-text index over memories and visible user and
+// assistant messages, ranked with BM25. It needs no embedding provider: on SQLite it
+// is an FTS5 index kept current by triggers, on Postgres a tsvector search
+// evaluated per query.
+//
+// The SQLite index refers to rows by rowid, which VACUUM may renumber on
+// tables without an INTEGER PRIMARY KEY; run Rebuild after one.
+type SearchIndex struct {
+	db *DB
+}
+
+// sqliteSearchSchema creates the FTS5 tables and the triggers that keep
+// them in step with memories and messages.
+var sqliteSearchSchema = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS memories_fts USING fts5(content, content='memories', content_rowid='rowid', tokenize='unicode61 remove_diacritics 2')`,
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_ai AFTER INSERT ON memories BEGIN
+		INSERT INTO memories_fts(rowid, content) VALUES (new.rowid, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_ad AFTER DELETE ON memories BEGIN
+		INSERT INTO memories_fts(memories_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS memories_fts_au AFTER UPDATE OF content ON memories BEGIN
+		INSERT INTO memories_fts(memories_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		INSERT INTO memories_fts(rowid, content) VALUES (new.rowid, new.content);
+	END`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(content, content='messages', content_rowid='rowid', tokenize='unicode61 remove_diacritics 2')`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+	END`,
+	`CREATE TRIGGER IF NOT EXISTS messages_fts_au AFTER UPDATE OF content ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+		INSERT INTO messages_fts(rowid, content) VALUES (new.rowid, new.content);
+	END`,
+}
+
+// NewSearchIndex returns the index over db. On SQLite it creates the FTS5
+// tables on first use and indexes the existing memories and messages. It
+// fails with encryption at rest, which an index of the words would defeat.
+func NewSearchIndex(ctx context.Context, db *DB) (*SearchIndex, error) {
+	if db.cipher != nil {
+		return nil, errors.New("keyword search is not available with state.encryption")
+	}
+	idx := &SearchIndex{db: db}
+	if db.Dialect() == PostgresDialect {
+		return idx, nil
+	}
+	var existing int
+	if err := db.SQLDB().QueryRowContext(ctx, `SELECT COUNT(*) FROM sqlite_master WHERE name = 'messages_fts'`).Scan(&existing); err != nil {
+		return nil, fmt.Errorf("search index: %w", err)
+	}
+	for _, stmt := range sqliteSearchSchema {
+		if _, err := db.SQLDB().ExecContext(ctx, stmt); err != nil {
+			return nil, fmt.Errorf("search index: %w", err)
+		}
+	}
+	if existing == 0 {
+		if err := idx.Rebuild(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return idx, nil
+}
+
+// Rebuild re-indexes every memory and message. It is a no-op on Postgres.
+func (idx *SearchIndex) Rebuild(ctx context.Context) error {
+	if idx.db.Dialect() == PostgresDialect {
+		return nil
+	}
+	for _, table := range []string{"memories_fts", "messages_fts"} {
+		if _, err := idx.db.SQLDB().ExecContext(ctx, `INSERT INTO `+table+`(`+table+`) VALUES ('rebuild')`); err != nil {
+			return fmt.Errorf("search index: rebuild %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// Search returns the best matches for q, best first. Any word of q may
+// match: BM25 ranks documents with more, and rarer, matching words higher.
+func (idx *SearchIndex) Search(ctx context.Context, q SearchQuery) ([]SearchHit, error) {
+	terms := searchTerms(q.Text)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("search: the query has no words to look for")
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultSearchLimit
+	}
+	since := ""
+	if !q.Since.IsZero() {
+		since = q.Since.UTC().Format(time.RFC3339)
+	}
+	var hits []SearchHit
+	if q.Kind == "" || q.Kind == SearchKindMemory {
+		mem, err := idx.searchMemories(ctx, terms, q, since)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, mem...)
+	}
+	if q.Kind == "" || q.Kind == SearchKindMessage {
+		msgs, err := idx.searchMessages(ctx, terms, q, since)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, msgs...)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > q.Limit {
+		hits = hits[:q.Limit]
+	}
+	return hits, nil
+}
+
+func (idx *SearchIndex) searchMemories(ctx context.Context, terms []string, q SearchQuery, since string) ([]SearchHit, error) {
+	if idx.db.Dialect() == PostgresDialect {
+		query := `SELECT m.id, ts_headline('simple', m.content, to_tsquery('simple', ?), 'StartSel=[, StopSel=], MaxWords=30, MinWords=12'),
+			m.created_at, ts_rank(to_tsvector('simple', m.content), to_tsquery('simple', ?)) AS score
+			FROM memories m
+			WHERE to_tsvector('simple', m.content) @@ to_tsquery('simple', ?)
+			  AND (m.actor_id IS NULL OR m.actor_id = ?) AND m.created_at >= ?
+			ORDER BY score DESC LIMIT ?`
+		pq := postgresTSQuery(terms)
+		return idx.scanHits(ctx, SearchKindMemory, query, pq, pq, pq, q.Actor, since, q.Limit)
+	}
+	query := `SELECT m.id, snippet(memories_fts, 0, '[', ']', '…', 24), m.created_at, -bm25(memories_fts) AS score
+		FROM memories_fts JOIN memories m ON m.rowid = memories_fts.rowid
+		WHERE memories_fts MATCH ? AND (m.actor_id IS NULL OR m.actor_id = ?) AND m.created_at >= ?
+		ORDER BY bm25(memories_fts) LIMIT ?`
+	return idx.scanHits(ctx, SearchKindMemory, query, ftsQuery(terms), q.Actor, since, q.Limit)
+}
+
+func (idx *SearchIndex) searchMessages(ctx context.Context, terms []string, q SearchQuery, since string) ([]SearchHit, error) {
+	if idx.db.Dialect() == PostgresDialect {
+		query := `SELECT m.session_id, m.role, ts_headline('simple', m.content, to_tsquery('simple', ?), 'StartSel=[, StopSel=], MaxWords=30, MinWords=12'),
+			m.created_at, ts_rank(to_tsvector('simple', m.content), to_tsquery('simple', ?)) AS score
+			FROM messages m JOIN sessions s ON s.id = m.session_id
+			WHERE to_tsvector('simple', m.content) @@ to_tsquery('simple', ?)
+			  AND m.role IN ('user', 'assistant') AND COALESCE(m.visibility, '') <> 'hidden' AND (s.entity_id = ? OR m.session_id = ?) AND m.created_at >= ?
+			ORDER BY score DESC LIMIT ?`
+		pq := postgresTSQuery(terms)
+		return idx.scanHits(ctx, SearchKindMessage, query, pq, pq, pq, q.Actor, q.SessionID, since, q.Limit)
+	}
+	query := `SELECT m.session_id, m.role, snippet(messages_fts, 0, '[', ']', '…', 24), m.created_at, -bm25(messages_fts) AS score
+		FROM messages_fts JOIN messages m ON m.rowid = messages_fts.rowid JOIN sessions s ON s.id = m.session_id
+		WHERE messages_fts MATCH ? AND m.role IN ('user', 'assistant') AND COALESCE(m.visibility, '') <> 'hidden' AND (s.entity_id = ? OR m.session_id = ?) AND m.created_at >= ?
+		ORDER BY bm25(messages_fts) LIMIT ?`
+	return idx.scanHits(ctx, SearchKindMessage, query, ftsQuery(terms), q.Actor, q.SessionID, since, q.Limit)
+}
+
+// scanHits runs query; memory rows are (id, snippet, created_at, score),
+// message rows (session_id, role, snippet, created_at, score).
+func (idx *SearchIndex) scanHits(ctx context.Context, kind, query string, args ...any) ([]SearchHit, error) {
+	rows, err := idx.db.SQLDB().QueryContext(ctx, idx.db.Dialect().Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("search %ss: %w", kind, err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []SearchHit
+	for rows.Next() {
+		h := SearchHit{Kind: kind}
+		var created string
+		var score sql.NullFloat64
+		if kind == SearchKindMemory {
+			err = rows.Scan(&h.ID, &h.Snippet, &created, &score)
+		} else {
+			err = rows.Scan(&h.SessionID, &h.Role, &h.Snippet, &created, &score)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("search %ss: scan: %w", kind, err)
+		}
+		h.CreatedAt = parseTimeOrZero(created)
+		h.Score = score.Float64
+		out = append(out, h)
+	}
+	return out, rows.Err()
+}
+
+// searchStopWords are left out of queries: they match nearly every message
+// and only dilute the ranking.
+var searchStopWords = map[string]bool{
+	"a": true, "about": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"by": true, "did": true, "do": true, "does": true, "for": true, "from": true, "how": true, "i": true,
+	"in": true, "is": true, "it": true, "me": true, "my": true, "of": true, "on": true, "or": true,
+	"our": true, "that": true, "the": true, "this": true, "to": true, "was": true, "we": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "who": true, "why": true, "with": true, "you": true,
+}
+
+// searchTerms splits text into lower-case words, without stop words and
+// duplicates.
+func searchTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	seen := make(map[string]bool, len(words))
+	var terms []string
+	for _, w := range words {
+		if searchStopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// ftsQuery ORs the terms as FTS5 strings; words of four or more letters
+// also match as prefixes, so "deploy" finds "deployment".
+func ftsQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = `"` + t + `"`
+		if len([]rune(t)) >= 4 {
+			parts[i] += "*"
+		}
+	}
+	return strings.Join(parts, " OR ")
+}
+
+// postgresTSQuery is ftsQuery for to_tsquery.
+func postgresTSQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, t := range terms {
+		parts[i] = t
+		if len([]rune(t)) >= 4 {
+			parts[i] += ":*"
+		}
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+func TestSearchIndex(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	mem := NewMemoryStore(db)
+	sessions := NewSessionStore(db, 0, 0)
+
+	// Rows written before the index exists are backfilled.
+	if _, err := mem.AddScoped(ctx, "alice", "We decided the deploy pipeline runs canary first, then production."); err != nil {
+		t.Fatal(err)
+	}
+	sessions.Create("alice:s1", "alice", "", "")
+	if err := sessions.AddMessage("alice:s1", provider.Message{Role: provider.RoleUser, Content: "Should the deployment pipeline block on flaky tests?"}); err != nil {
+		t.Fatal(err)
+	}
+
+	idx, err := NewSearchIndex(ctx, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Written after: kept current by the triggers.
+	if _, err := mem.AddScoped(ctx, "bob", "Bob's deploy pipeline notes."); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.AddScoped(ctx, "", "Lunch is at noon."); err != nil {
+		t.Fatal(err)
+	}
+	_ = sessions.AddMessage("alice:s1", provider.Message{Role: provider.RoleAssistant, Content: "Yes: the pipeline retries flaky tests once, then blocks the deploy."})
+	_ = sessions.AddMessage("alice:s1", provider.Message{Role: provider.RoleTool, Content: "deploy pipeline tool output"})
+	_ = sessions.AddMessage("alice:s1", provider.Message{Role: provider.RoleUser, Content: "hidden deploy pipeline turn", Visibility: provider.VisibilityHidden})
+	sessions.Create("bob:s2", "bob", "", "")
+	_ = sessions.AddMessage("bob:s2", provider.Message{Role: provider.RoleUser, Content: "deploy pipeline secrets"})
+
+	hits, err := idx.Search(ctx, SearchQuery{Text: "What did we decide about the deploy pipeline?", Actor: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 3 {
+		t.Fatalf("hits = %+v", hits)
+	}
+	// The memory matches "decide", "deploy" and "pipeline" and ranks first.
+	if hits[0].Kind != SearchKindMemory || hits[0].ID == "" || hits[0].Snippet == "" || hits[0].CreatedAt.IsZero() {
+		t.Errorf("top hit = %+v", hits[0])
+	}
+	for i, h := range hits {
+		if i > 0 && h.Score > hits[i-1].Score {
+			t.Errorf("hits not ordered by score: %+v", hits)
+		}
+		if h.Kind == SearchKindMessage && (h.SessionID != "alice:s1" || h.Role == string(provider.RoleTool) || strings.Contains(h.Snippet, "hidden")) {
+			t.Errorf("message hit out of scope: %+v", h)
+		}
+	}
+
+	// Without a profile the current session is searched whoever owns it.
+	hits, err = idx.Search(ctx, SearchQuery{Text: "secrets", Actor: "carol", SessionID: "bob:s2"})
+	if err != nil || len(hits) != 1 || hits[0].SessionID != "bob:s2" {
+		t.Errorf("current-session hits = %+v, %v", hits, err)
+	}
+
+	// Since excludes older rows.
+	if _, err := db.SQLDB().ExecContext(ctx, `UPDATE messages SET created_at = ? WHERE session_id = 'alice:s1'`,
+		time.Now().Add(-30*24*time.Hour).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+	hits, err = idx.Search(ctx, SearchQuery{Text: "flaky", Actor: "alice", Since: time.Now().Add(-7 * 24 * time.Hour)})
+	if err != nil || len(hits) != 0 {
+		t.Errorf("hits since last week = %+v, %v", hits, err)
+	}
+
+	// Deleted rows leave the index, and a rebuild does not bring them back.
+	if err := sessions.Delete("alice:s1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, rebuild := range []bool{false, true} {
+		if rebuild {
+			if err := idx.Rebuild(ctx); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var n int
+		if err := db.SQLDB().QueryRowContext(ctx, `SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'flaky'`).Scan(&n); err != nil || n != 0 {
+			t.Errorf("indexed messages after delete (rebuild %v) = %d, %v", rebuild, n, err)
+		}
+	}
+
+	// Opening the index again on an existing database is fine.
+	if _, err := NewSearchIndex(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Search(ctx, SearchQuery{Text: "the of and"}); err == nil {
+		t.Error("a query of stop words only must fail")
+	}
+}
+
+func TestFTSQuery(t *testing.T) {
+	terms := searchTerms("What did we decide about the Deploy-pipeline, v2 deploy?")
+	if got, want := ftsQuery(terms), `"decide"* OR "deploy"* OR "pipeline"* OR "v2"`; got != want {
+		t.Errorf("ftsQuery = %s, want %s", got, want)
+	}
+	if got, want := postgresTSQuery(terms), `decide:* | deploy:* | pipeline:* | v2`; got != want {
+		t.Errorf("postgresTSQuery = %s, want %s", got, want)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/opentalon/opentalon/internal/blob"
 	"github.com/opentalon/opentalon/internal/provider"
 	"github.com/opentalon/opentalon/internal/state"
 )
@@ -72,6 +74,9 @@ func (s *SessionStore) Get(id string) (*state.Session, error) {
 		}
 		return nil, fmt.Errorf("session %q load: %w", id, err)
 	}
+	if summary, err = s.db.open(summary); err != nil {
+		return nil, fmt.Errorf("session %q load summary: %w", id, err)
+	}
 
 	messages, err := s.loadMessages(id)
 	if err != nil {
@@ -153,6 +158,10 @@ func (s *SessionStore) AddMessageWithMetadata(id string, msg provider.Message, m
 	if err != nil {
 		return err
 	}
+	content, err := s.db.seal(msg.Content)
+	if err != nil {
+		return fmt.Errorf("add message: %w", err)
+	}
 
 	tx, err := s.db.SQLDB().BeginTx(ctx, nil)
 	if err != nil {
@@ -165,7 +174,7 @@ func (s *SessionStore) AddMessageWithMetadata(id string, msg provider.Message, m
 	if _, err := tx.ExecContext(ctx,
 		d.Rebind(`INSERT INTO messages (session_id, seq, role, content, tool_calls, tool_call_id, metadata, visibility, created_at)
 		SELECT ?, COALESCE(MAX(seq), 0) + 1, ?, ?, ?, ?, ?, ?, ? FROM messages WHERE session_id = ?`),
-		id, string(msg.Role), msg.Content, toolCallsJSON, toolCallID, metadataJSON, visibility, now, id); err != nil {
+		id, string(msg.Role), content, toolCallsJSON, toolCallID, metadataJSON, visibility, now, id); err != nil {
 		return fmt.Errorf("add message insert: %w", err)
 	}
 
@@ -266,6 +275,10 @@ func (s *SessionStore) SetSummary(id string, summary string, messages []provider
 	ctx := context.Background()
 	d := s.db.Dialect()
 	now := time.Now().UTC().Format(time.RFC3339)
+	sealedSummary, err := s.db.seal(summary)
+	if err != nil {
+		return fmt.Errorf("set summary: %w", err)
+	}
 
 	tx, err := s.db.SQLDB().BeginTx(ctx, nil)
 	if err != nil {
@@ -287,9 +300,13 @@ func (s *SessionStore) SetSummary(id string, summary string, messages []provider
 		}
 		toolCallID := sql.NullString{String: msg.ToolCallID, Valid: msg.ToolCallID != ""}
 		visibility := sql.NullString{String: msg.Visibility, Valid: msg.Visibility != ""}
+		content, err := s.db.seal(msg.Content)
+		if err != nil {
+			return fmt.Errorf("set summary: %w", err)
+		}
 		if _, err := tx.ExecContext(ctx,
 			d.Rebind(`INSERT INTO messages (session_id, seq, role, content, tool_calls, tool_call_id, visibility, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
-			id, i+1, string(msg.Role), msg.Content, toolCallsJSON, toolCallID, visibility, now); err != nil {
+			id, i+1, string(msg.Role), content, toolCallsJSON, toolCallID, visibility, now); err != nil {
 			return fmt.Errorf("set summary insert: %w", err)
 		}
 	}
@@ -297,7 +314,7 @@ func (s *SessionStore) SetSummary(id string, summary string, messages []provider
 	// Update session summary and timestamp.
 	if _, err := tx.ExecContext(ctx,
 		d.Rebind(`UPDATE sessions SET summary = ?, updated_at = ? WHERE id = ?`),
-		summary, now, id); err != nil {
+		sealedSummary, now, id); err != nil {
 		return fmt.Errorf("set summary update: %w", err)
 	}
 	return tx.Commit()
@@ -321,6 +338,87 @@ func (s *SessionStore) List() ([]string, error) {
 	return ids, rows.Err()
 }
 
+// SessionSummary is one row of ListSummaries.
+type SessionSummary struct {
+	ID        string    `json:"id"`
+	EntityID  string    `json:"entity_id,omitempty"`
+	GroupID   string    `json:"group_id,omitempty"`
+	ChannelID string    `json:"channel_id,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Messages  int       `json:"messages"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ListSummaries returns the most recently updated sessions without their
+// messages, newest first. limit <= 0 returns all of them.
+func (s *SessionStore) ListSummaries(ctx context.Context, limit int) ([]SessionSummary, error) {
+	q := `SELECT id, COALESCE(entity_id,''), COALESCE(group_id,''), channel_id, COALESCE(title,''),
+		(SELECT COUNT(*) FROM messages WHERE messages.session_id = sessions.id), created_at, updated_at
+		FROM sessions ORDER BY updated_at DESC, id`
+	var args []any
+	if limit > 0 {
+		q += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(q), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list session summaries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []SessionSummary
+	for rows.Next() {
+		var ss SessionSummary
+		var createdAt, updatedAt string
+		if err := rows.Scan(&ss.ID, &ss.EntityID, &ss.GroupID, &ss.ChannelID, &ss.Title, &ss.Messages, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("list session summaries: %w", err)
+		}
+		ss.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		ss.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		out = append(out, ss)
+	}
+	return out, rows.Err()
+}
+
+// BlobRefs returns every blob id referenced from a stored message or session
+// summary. It is the blob GC's view of live data, so a blob a session still
+// points at survives until the session itself is pruned or cleared.
+// Encrypted values can't be matched in SQL, so they are all decrypted and
+// scanned.
+func (s *SessionStore) BlobRefs(ctx context.Context) (map[string]bool, error) {
+	refs := map[string]bool{}
+	pattern := "%" + blob.Ref("sha256:") + "%"
+	for _, q := range []string{
+		`SELECT content FROM messages WHERE content LIKE ? OR content LIKE ?`,
+		`SELECT summary FROM sessions WHERE summary LIKE ? OR summary LIKE ?`,
+	} {
+		rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(q), pattern, encPrefix+"%")
+		if err != nil {
+			return nil, fmt.Errorf("blob refs: %w", err)
+		}
+		for rows.Next() {
+			var text string
+			if err := rows.Scan(&text); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("blob refs scan: %w", err)
+			}
+			if text, err = s.db.open(text); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("blob refs: %w", err)
+			}
+			for _, id := range blob.Refs(text) {
+				refs[id] = true
+			}
+		}
+		err = rows.Err()
+		_ = rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return refs, nil
+}
+
 // ClearMessages drops the conversation history of a session — messages and
 // the derived summary — atomically. The session row itself stays (entity_id,
 // group_id, active_model, metadata, title, created_at all preserved), and
@@ -391,6 +489,10 @@ func (s *SessionStore) loadMessages(sessionID string) ([]provider.Message, error
 		if err := rows.Scan(&role, &content, &toolCallsJSON, &toolCallID, &visibility); err != nil {
 			return nil, fmt.Errorf("load messages scan: %w", err)
 		}
+		content, err := s.db.open(content)
+		if err != nil {
+			return nil, fmt.Errorf("load messages: %w", err)
+		}
 		msg := provider.Message{
 			Role:       provider.Role(role),
 			Content:    content,
@@ -409,3 +511,93 @@ func (s *SessionStore) loadMessages(sessionID string) ([]provider.Message, error
 	}
 	return messages, rows.Err()
 }
+
+// MessagePage selects a window of a session's messages by seq. AfterSeq
+// pages forward from the start; BeforeSeq pages backward from the end,
+// e.g. BeforeSeq of the oldest message shown to load the ones above it.
+// Both zero is the first page.
+type MessagePage struct {
+	AfterSeq  int64
+	BeforeSeq int64 // takes precedence over AfterSeq
+	Limit     int   // default 50, at most 500
+}
+
+// StoredMessage is a message row: the message with its position and the
+// per-message columns Get leaves out.
+type StoredMessage struct {
+	Seq        int64               `json:"seq"`
+	Role       provider.Role       `json:"role"`
+	Content    string              `json:"content"`
+	ToolCalls  []provider.ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+	Visibility string              `json:"visibility,omitempty"`
+	Metadata   map[string]string   `json:"metadata,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+}
+
+// Messages returns one page of a session's messages, oldest first, without
+// loading the rest of the session. It returns state.ErrSessionNotFound for
+// an unknown session.
+func (s *SessionStore) Messages(ctx context.Context, id string, page MessagePage) ([]StoredMessage, error) {
+	limit := page.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	limit = min(limit, 500)
+	d := s.db.Dialect()
+	var exists int
+	err := s.db.SQLDB().QueryRowContext(ctx, d.Rebind(`SELECT 1 FROM sessions WHERE id = ?`), id).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, state.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("messages: %w", err)
+	}
+
+	const cols = `SELECT seq, role, content, tool_calls, tool_call_id, visibility, metadata, created_at FROM messages`
+	q := cols + ` WHERE session_id = ? AND seq > ? ORDER BY seq LIMIT ?`
+	args := []any{id, page.AfterSeq, limit}
+	if page.BeforeSeq > 0 {
+		q = cols + ` WHERE session_id = ? AND seq < ? ORDER BY seq DESC LIMIT ?`
+		args = []any{id, page.BeforeSeq, limit}
+	}
+	rows, err := s.db.SQLDB().QueryContext(ctx, d.Rebind(q), args...)
+	if err != nil {
+		return nil, fmt.Errorf("messages: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	out := []StoredMessage{}
+	for rows.Next() {
+		var m StoredMessage
+		var role, createdAt string
+		var toolCallsJSON, toolCallID, visibility, metadataJSON sql.NullString
+		if err := rows.Scan(&m.Seq, &role, &m.Content, &toolCallsJSON, &toolCallID, &visibility, &metadataJSON, &createdAt); err != nil {
+			return nil, fmt.Errorf("messages scan: %w", err)
+		}
+		var err error
+		if m.Content, err = s.db.open(m.Content); err != nil {
+			return nil, fmt.Errorf("messages: %w", err)
+		}
+		m.Role = provider.Role(role)
+		m.ToolCallID, m.Visibility = toolCallID.String, visibility.String
+		m.CreatedAt = parseTimeOrZero(createdAt)
+		if toolCallsJSON.Valid {
+			if err := json.Unmarshal([]byte(toolCallsJSON.String), &m.ToolCalls); err != nil {
+				return nil, fmt.Errorf("messages unmarshal tool_calls: %w", err)
+			}
+		}
+		if metadataJSON.Valid {
+			if err := json.Unmarshal([]byte(metadataJSON.String), &m.Metadata); err != nil {
+				return nil, fmt.Errorf("messages unmarshal metadata: %w", err)
+			}
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if page.BeforeSeq > 0 {
+		slices.Reverse(out)
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/opentalon/opentalon/internal/evaluation"
+)
+
+// SessionScoreStore keeps LLM-judge scores for completed sessions. It
+// implements evaluation.ScoreStore.
+type SessionScoreStore struct {
+	db *DB
+}
+
+// NewSessionScoreStore returns a SessionScoreStore backed by db.
+func NewSessionScoreStore(db *DB) *SessionScoreStore {
+	return &SessionScoreStore{db: db}
+}
+
+// RecordScores inserts one judging's scores in a single transaction.
+func (s *SessionScoreStore) RecordScores(ctx context.Context, scores []evaluation.Score) error {
+	tx, err := s.db.SQLDB().BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("session score store: record: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	q := s.db.Dialect().Rebind(`
+		INSERT INTO session_scores
+		  (id, session_id, criterion, score, reason, model_id, variant, judge_model, prompt_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for _, sc := range scores {
+		created := sc.CreatedAt
+		if created.IsZero() {
+			created = time.Now()
+		}
+		if _, err := tx.ExecContext(ctx, q,
+			"scr_"+uuid.New().String(), sc.SessionID, sc.Criterion, sc.Score, sc.Reason,
+			sc.ModelID, sc.Variant, sc.JudgeModel, sc.PromptHash, created.UTC().Format(time.RFC3339)); err != nil {
+			return fmt.Errorf("session score store: record: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("session score store: record: %w", err)
+	}
+	return nil
+}
+
+// SessionScores returns every score recorded for sessionID, oldest judging
+// first.
+func (s *SessionScoreStore) SessionScores(ctx context.Context, sessionID string) ([]evaluation.Score, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT criterion, score, reason, model_id, variant, judge_model, prompt_hash, created_at
+		FROM session_scores WHERE session_id = ?
+		ORDER BY created_at, criterion`), sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("session score store: query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []evaluation.Score
+	for rows.Next() {
+		sc := evaluation.Score{SessionID: sessionID}
+		var created string
+		if err := rows.Scan(&sc.Criterion, &sc.Score, &sc.Reason, &sc.ModelID, &sc.Variant, &sc.JudgeModel, &sc.PromptHash, &created); err != nil {
+			return nil, fmt.Errorf("session score store: scan: %w", err)
+		}
+		sc.CreatedAt, _ = time.Parse(time.RFC3339, created)
+		out = append(out, sc)
+	}
+	return out, rows.Err()
+}
+
+// Summarize averages each criterion per (model, variant, prompt hash) over
+// the scores recorded on or after since.
+func (s *SessionScoreStore) Summarize(ctx context.Context, since time.Time) ([]evaluation.Summary, error) {
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT model_id, variant, prompt_hash, criterion, COUNT(DISTINCT session_id), AVG(score)
+		FROM session_scores WHERE created_at >= ?
+		GROUP BY model_id, variant, prompt_hash, criterion
+		ORDER BY model_id, variant, prompt_hash, criterion`), since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("session score store: summarize: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []evaluation.Summary
+	for rows.Next() {
+		var sm evaluation.Summary
+		if err := rows.Scan(&sm.ModelID, &sm.Variant, &sm.PromptHash, &sm.Criterion, &sm.Sessions, &sm.Average); err != nil {
+			return nil, fmt.Errorf("session score store: scan: %w", err)
+		}
+		out = append(out, sm)
+	}
+	return out, rows.Err()
+}
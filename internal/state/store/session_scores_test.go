@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/evaluation"
+)
+
+func TestSessionScoreStore_RecordAndSummarize(t *testing.T) {
+	s := NewSessionScoreStore(openTestDB(t))
+	ctx := context.Background()
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	record := func(session, model, variant, hash string, at time.Time, help, res float64) {
+		t.Helper()
+		err := s.RecordScores(ctx, []evaluation.Score{
+			{SessionID: session, Criterion: "helpfulness", Score: help, Reason: "r", ModelID: model, Variant: variant, JudgeModel: "judge", PromptHash: hash, CreatedAt: at},
+			{SessionID: session, Criterion: "resolution", Score: res, ModelID: model, Variant: variant, JudgeModel: "judge", PromptHash: hash, CreatedAt: at},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("web:1", "gpt-4o", "", "h1", old, 2, 2)
+	record("web:2", "gpt-4o", "", "h1", recent, 4, 5)
+	record("web:3", "gpt-4o", "", "h1", recent, 5, 3)
+	record("web:4", "claude", "terse", "h2", recent, 3, 3)
+	record("web:5", "claude", "", "h2", recent, 5, 5)
+
+	got, err := s.SessionScores(ctx, "web:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Criterion != "helpfulness" || got[0].Score != 4 || got[0].Reason != "r" || got[0].ModelID != "gpt-4o" || !got[0].CreatedAt.Equal(recent) {
+		t.Fatalf("session scores = %+v", got)
+	}
+
+	sum, err := s.Summarize(ctx, recent.Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []evaluation.Summary{
+		{ModelID: "claude", PromptHash: "h2", Criterion: "helpfulness", Sessions: 1, Average: 5},
+		{ModelID: "claude", PromptHash: "h2", Criterion: "resolution", Sessions: 1, Average: 5},
+		{ModelID: "claude", Variant: "terse", PromptHash: "h2", Criterion: "helpfulness", Sessions: 1, Average: 3},
+		{ModelID: "claude", Variant: "terse", PromptHash: "h2", Criterion: "resolution", Sessions: 1, Average: 3},
+		{ModelID: "gpt-4o", PromptHash: "h1", Criterion: "helpfulness", Sessions: 2, Average: 4.5},
+		{ModelID: "gpt-4o", PromptHash: "h1", Criterion: "resolution", Sessions: 2, Average: 4},
+	}
+	if len(sum) != len(want) {
+		t.Fatalf("summary = %+v", sum)
+	}
+	for i := range want {
+		if sum[i] != want[i] {
+			t.Errorf("summary[%d] = %+v, want %+v", i, sum[i], want[i])
+		}
+	}
+}
@@ -3,8 +3,10 @@ package store
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/opentalon/opentalon/internal/blob"
 	"github.com/opentalon/opentalon/internal/provider"
 	"github.com/opentalon/opentalon/internal/state"
 	"github.com/opentalon/opentalon/internal/state/store/events"
@@ -165,3 +167,61 @@ func TestSessionStore_ClearMessagesIsIdempotent(t *testing.T) {
 		t.Errorf("Messages len = %d, want 0", len(after.Messages))
 	}
 }
+
+func TestSessionStore_BlobRefs(t *testing.T) {
+	db := openTestDB(t)
+	store := NewSessionStore(db, 0, 0)
+	a := "sha256:" + strings.Repeat("a", 64)
+	b := "sha256:" + strings.Repeat("b", 64)
+
+	store.Create("s1", "", "", "")
+	if err := store.AddMessage("s1", provider.Message{Role: provider.RoleTool, Content: "preview\n[stored as " + blob.Ref(a) + "]"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddMessage("s1", provider.Message{Role: provider.RoleUser, Content: "no refs"}); err != nil {
+		t.Fatal(err)
+	}
+	store.Create("s2", "", "", "")
+	if err := store.SetSummary("s2", "earlier output was "+blob.Ref(b), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	refs, err := store.BlobRefs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 || !refs[a] || !refs[b] {
+		t.Errorf("BlobRefs = %v", refs)
+	}
+}
+
+func TestSessionStore_ListSummaries(t *testing.T) {
+	db := openTestDB(t)
+	store := NewSessionStore(db, 0, 0)
+	ctx := context.Background()
+
+	store.Create("old", "alice", "staff", "")
+	store.Create("new", "bob", "", "")
+	for _, c := range []string{"hi", "there"} {
+		if err := store.AddMessage("new", provider.Message{Role: provider.RoleUser, Content: c}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.SQLDB().Exec(`UPDATE sessions SET updated_at = '2020-01-01T00:00:00Z' WHERE id = 'old'`); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.ListSummaries(ctx, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != "new" || got[1].ID != "old" {
+		t.Fatalf("ListSummaries = %+v; want new, old", got)
+	}
+	if got[0].Messages != 2 || got[0].EntityID != "bob" || got[1].GroupID != "staff" || got[1].Messages != 0 {
+		t.Errorf("summaries = %+v", got)
+	}
+	if got, _ := store.ListSummaries(ctx, 1); len(got) != 1 || got[0].ID != "new" {
+		t.Errorf("ListSummaries(limit 1) = %+v", got)
+	}
+}
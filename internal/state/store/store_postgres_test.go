@@ -34,6 +34,8 @@ func pgDB(t *testing.T) *DB {
 		// next Open(); dropping it ensures a fresh migration replay. Keep
 		// this list in sync with internal/state/store/migrations/*.sql.
 		db.SQLDB().Exec(`DROP TABLE IF EXISTS
+			scheduler_runs,
+			scheduler_jobs,
 			session_events,
 			prompt_snapshots,
 			ai_debug_events,
@@ -55,8 +57,8 @@ func TestPostgres_OpenAndMigrations(t *testing.T) {
 	if err := db.SQLDB().QueryRow("SELECT version FROM schema_version LIMIT 1").Scan(&v); err != nil {
 		t.Fatalf("read schema_version: %v", err)
 	}
-	if v != 15 {
-		t.Errorf("schema_version = %d, want 15", v)
+	if v != 22 {
+		t.Errorf("schema_version = %d, want 22", v)
 	}
 }
 
@@ -4,14 +4,18 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/opentalon/opentalon/internal/actor"
 	"github.com/opentalon/opentalon/internal/config"
 	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/internal/state"
 )
 
 func TestOpenAndMigrations(t *testing.T) {
@@ -27,8 +31,8 @@ func TestOpenAndMigrations(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read schema_version: %v", err)
 	}
-	if v != 15 {
-		t.Errorf("schema_version = %d, want 15", v)
+	if v != 23 {
+		t.Errorf("schema_version = %d, want 23", v)
 	}
 
 	// Re-open: idempotent, no error
@@ -41,8 +45,8 @@ func TestOpenAndMigrations(t *testing.T) {
 	if err != nil {
 		t.Fatalf("read schema_version (second open): %v", err)
 	}
-	if v != 15 {
-		t.Errorf("schema_version after re-open = %d, want 15", v)
+	if v != 23 {
+		t.Errorf("schema_version after re-open = %d, want 23", v)
 	}
 }
 
@@ -91,6 +95,40 @@ func TestMemoryStore_AddScopedAndMemoriesForContext(t *testing.T) {
 	}
 }
 
+func TestMemoryStore_Promote(t *testing.T) {
+	mem := NewMemoryStore(openTestDB(t))
+	ctx := context.Background()
+	own, err := mem.AddScoped(ctx, "slack:U1", "deploys go through the canary first", "workflow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if own.ActorID != "slack:U1" {
+		t.Errorf("ActorID = %q", own.ActorID)
+	}
+
+	got, err := mem.Promote(ctx, own.ID)
+	if err != nil {
+		t.Fatalf("Promote: %v", err)
+	}
+	if got.ActorID != "" || got.Content != own.Content || len(got.Tags) != 1 {
+		t.Errorf("promoted = %+v", got)
+	}
+	list, _ := mem.MemoriesForContext(actor.WithActor(ctx, "slack:U2"), "workflow")
+	if len(list) != 1 || list[0].ID != own.ID {
+		t.Errorf("U2 sees %+v, want the promoted memory", list)
+	}
+
+	if _, err := mem.Promote(ctx, own.ID); err == nil || !strings.Contains(err.Error(), "already shared") {
+		t.Errorf("second promote = %v", err)
+	}
+	if _, err := mem.Promote(ctx, "mem_missing"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("missing promote = %v", err)
+	}
+	if m, err := mem.Get(ctx, "mem_missing"); m != nil || err != nil {
+		t.Errorf("Get missing = %+v, %v", m, err)
+	}
+}
+
 func TestSessionStore_PersistAndGet(t *testing.T) {
 	dir := t.TempDir()
 	db, err := Open(config.DBConfig{}, dir)
@@ -195,6 +233,52 @@ func TestSessionStore_MaxMessagesTrim(t *testing.T) {
 	}
 }
 
+func TestSessionStore_MessagesPaging(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	sessStore := NewSessionStore(db, 0, 0)
+	sessStore.Create("s1", "", "", "")
+	for i := 1; i <= 7; i++ {
+		_ = sessStore.AddMessage("s1", provider.Message{Role: provider.RoleUser, Content: fmt.Sprintf("m%d", i)})
+	}
+	_ = sessStore.AddMessageWithMetadata("s1", provider.Message{Role: provider.RoleAssistant, Content: "m8"}, map[string]string{"prompt_type": "tool_confirmation"})
+
+	contents := func(msgs []StoredMessage) string {
+		var parts []string
+		for _, m := range msgs {
+			parts = append(parts, fmt.Sprintf("%d:%s", m.Seq, m.Content))
+		}
+		return strings.Join(parts, " ")
+	}
+	for _, tc := range []struct {
+		page MessagePage
+		want string
+	}{
+		{MessagePage{Limit: 3}, "1:m1 2:m2 3:m3"},
+		{MessagePage{AfterSeq: 3, Limit: 3}, "4:m4 5:m5 6:m6"},
+		{MessagePage{AfterSeq: 6}, "7:m7 8:m8"},
+		{MessagePage{AfterSeq: 8}, ""},
+		{MessagePage{BeforeSeq: 9, Limit: 2}, "7:m7 8:m8"},
+		{MessagePage{BeforeSeq: 3, Limit: 5}, "1:m1 2:m2"},
+	} {
+		got, err := sessStore.Messages(ctx, "s1", tc.page)
+		if err != nil {
+			t.Fatalf("Messages(%+v): %v", tc.page, err)
+		}
+		if contents(got) != tc.want {
+			t.Errorf("Messages(%+v) = %q, want %q", tc.page, contents(got), tc.want)
+		}
+	}
+
+	last, _ := sessStore.Messages(ctx, "s1", MessagePage{BeforeSeq: 9, Limit: 1})
+	if len(last) != 1 || last[0].Metadata["prompt_type"] != "tool_confirmation" || last[0].Role != provider.RoleAssistant || last[0].CreatedAt.IsZero() {
+		t.Errorf("last message = %+v", last)
+	}
+	if _, err := sessStore.Messages(ctx, "nope", MessagePage{}); !errors.Is(err, state.ErrSessionNotFound) {
+		t.Errorf("unknown session: err = %v", err)
+	}
+}
+
 // SetTitle is a one-shot fill: it labels an empty session but must never
 // overwrite an existing title. This protects a title set through another path
 // (e.g. a user rename via the REST API) from being clobbered by the async
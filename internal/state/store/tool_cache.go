@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ToolCacheStore is the persistent tier of the tool result cache. It
+// implements orchestrator.ToolCacheStore.
+type ToolCacheStore struct {
+	db *DB
+}
+
+// NewToolCacheStore returns a ToolCacheStore backed by db.
+func NewToolCacheStore(db *DB) *ToolCacheStore {
+	return &ToolCacheStore{db: db}
+}
+
+// GetToolResult returns the cached result for key unless it has expired
+// by now.
+func (s *ToolCacheStore) GetToolResult(ctx context.Context, key string, now time.Time) (content, structured string, ok bool, err error) {
+	err = s.db.SQLDB().QueryRowContext(ctx, s.db.Dialect().Rebind(`
+		SELECT content, structured FROM tool_cache WHERE cache_key = ? AND expires_at > ?`),
+		key, now.UTC().Format(time.RFC3339)).Scan(&content, &structured)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", "", false, nil
+	}
+	if err != nil {
+		return "", "", false, fmt.Errorf("tool cache store: get: %w", err)
+	}
+	return content, structured, true, nil
+}
+
+// PutToolResult stores a result until expires, replacing any earlier one
+// for key, and drops entries that have already expired.
+func (s *ToolCacheStore) PutToolResult(ctx context.Context, key, content, structured string, expires time.Time) error {
+	db, d := s.db.SQLDB(), s.db.Dialect()
+	if _, err := db.ExecContext(ctx, d.Rebind(`DELETE FROM tool_cache WHERE expires_at <= ?`), time.Now().UTC().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("tool cache store: prune: %w", err)
+	}
+	_, err := db.ExecContext(ctx, d.Rebind(`
+		INSERT INTO tool_cache (cache_key, content, structured, expires_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (cache_key) DO UPDATE SET content = excluded.content, structured = excluded.structured, expires_at = excluded.expires_at`),
+		key, content, structured, expires.UTC().Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("tool cache store: put: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestToolCacheStore(t *testing.T) {
+	s := NewToolCacheStore(openTestDB(t))
+	ctx := context.Background()
+	now := time.Now()
+
+	if _, _, ok, err := s.GetToolResult(ctx, "k", now); err != nil || ok {
+		t.Fatalf("empty cache: ok=%v err=%v", ok, err)
+	}
+	if err := s.PutToolResult(ctx, "k", "sunny", `{"temp":21}`, now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	content, structured, ok, err := s.GetToolResult(ctx, "k", now)
+	if err != nil || !ok || content != "sunny" || structured != `{"temp":21}` {
+		t.Fatalf("get = %q %q %v %v", content, structured, ok, err)
+	}
+	if _, _, ok, _ := s.GetToolResult(ctx, "k", now.Add(2*time.Hour)); ok {
+		t.Error("an expired entry was returned")
+	}
+
+	if err := s.PutToolResult(ctx, "k", "rain", "", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if content, _, _, _ = s.GetToolResult(ctx, "k", now); content != "rain" {
+		t.Errorf("replaced entry = %q", content)
+	}
+
+	if err := s.PutToolResult(ctx, "old", "x", "", now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.PutToolResult(ctx, "new", "y", "", now.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := s.db.SQLDB().QueryRow(`SELECT COUNT(*) FROM tool_cache WHERE cache_key = 'old'`).Scan(&n); err != nil || n != 0 {
+		t.Errorf("expired entry not pruned: n=%d err=%v", n, err)
+	}
+}
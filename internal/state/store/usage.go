@@ -1,12 +1,17 @@
 package store
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/opentalon/opentalon/internal/usagestats"
 )
 
 // UsageRecord captures LLM usage statistics for one orchestrator run.
@@ -21,6 +26,7 @@ type UsageRecord struct {
 	InputTokens     int
 	OutputTokens    int
 	ToolCalls       int
+	Tools           []string // plugin__action of each call; stored comma-joined
 	InputCost       float64
 	OutputCost      float64
 }
@@ -65,19 +71,158 @@ func (s *UsageStore) Record(ctx context.Context, r UsageRecord) error {
 		kind = "chat"
 	}
 	source := sql.NullString{String: r.SystemSource, Valid: r.SystemSource != ""}
+	tools := sql.NullString{String: strings.Join(r.Tools, ","), Valid: len(r.Tools) > 0}
 	_, err := s.db.SQLDB().ExecContext(ctx, s.db.Dialect().Rebind(`
 		INSERT INTO profile_usage
 		  (id, entity_id, group_id, channel_id, session_id, model_id,
 		   interaction_kind, system_source,
-		   input_tokens, output_tokens, tool_calls,
+		   input_tokens, output_tokens, tool_calls, tools,
 		   input_cost, output_cost, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
 		id, r.EntityID, r.GroupID, r.ChannelID, r.SessionID, r.ModelID,
 		kind, source,
-		r.InputTokens, r.OutputTokens, r.ToolCalls,
+		r.InputTokens, r.OutputTokens, r.ToolCalls, tools,
 		r.InputCost, r.OutputCost, now)
 	if err != nil {
 		return fmt.Errorf("usage store: record: %w", err)
 	}
 	return nil
 }
+
+// EntityUsage sums entityID's chat runs recorded on or after since, with
+// its tools by number of calls. It implements usagestats.Store.
+func (s *UsageStore) EntityUsage(ctx context.Context, entityID string, since time.Time) (usagestats.Usage, error) {
+	sinceStr := since.UTC().Format(time.RFC3339)
+	var u usagestats.Usage
+	row := s.db.SQLDB().QueryRowContext(ctx, s.db.Dialect().Rebind(`
+		SELECT COUNT(DISTINCT session_id), COUNT(*),
+		       COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(tool_calls), 0),
+		       COALESCE(SUM(input_cost + output_cost), 0)
+		FROM profile_usage
+		WHERE entity_id = ? AND created_at >= ? AND interaction_kind = 'chat'`),
+		entityID, sinceStr)
+	if err := row.Scan(&u.Sessions, &u.Runs, &u.InputTokens, &u.OutputTokens, &u.ToolCalls, &u.Cost); err != nil {
+		return u, fmt.Errorf("usage store: entity usage: %w", err)
+	}
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT tools FROM profile_usage
+		WHERE entity_id = ? AND created_at >= ? AND interaction_kind = 'chat' AND tools IS NOT NULL`),
+		entityID, sinceStr)
+	if err != nil {
+		return u, fmt.Errorf("usage store: entity tools: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	calls := map[string]int{}
+	for rows.Next() {
+		var tools string
+		if err := rows.Scan(&tools); err != nil {
+			return u, fmt.Errorf("usage store: entity tools: %w", err)
+		}
+		for _, t := range strings.Split(tools, ",") {
+			if t != "" {
+				calls[t]++
+			}
+		}
+	}
+	for t, n := range calls {
+		u.Tools = append(u.Tools, usagestats.ToolCount{Tool: t, Calls: n})
+	}
+	slices.SortFunc(u.Tools, func(a, b usagestats.ToolCount) int {
+		return cmp.Or(b.Calls-a.Calls, strings.Compare(a.Tool, b.Tool))
+	})
+	return u, rows.Err()
+}
+
+// UsageTotal sums the usage rows of one Key in UsageReport.
+type UsageTotal struct {
+	Key          string  `json:"key"`
+	Runs         int     `json:"runs"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	ToolCalls    int     `json:"tool_calls"`
+	Cost         float64 `json:"cost"`
+}
+
+// usageReportColumns are the columns UsageReport can group by.
+var usageReportColumns = map[string]string{
+	"entity":  "entity_id",
+	"group":   "group_id",
+	"channel": "channel_id",
+	"model":   "model_id",
+	"kind":    "interaction_kind",
+}
+
+// UsageReport totals usage recorded on or after since, grouped by "entity",
+// "group", "channel", "model" or "kind", highest token count first.
+func (s *UsageStore) UsageReport(ctx context.Context, since time.Time, by string) ([]UsageTotal, error) {
+	col, ok := usageReportColumns[by]
+	if !ok {
+		return nil, fmt.Errorf("usage store: cannot group by %q (use entity, group, channel, model or kind)", by)
+	}
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT COALESCE(`+col+`, ''), COUNT(*),
+		       COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(tool_calls), 0),
+		       COALESCE(SUM(input_cost + output_cost), 0)
+		FROM profile_usage
+		WHERE created_at >= ?
+		GROUP BY COALESCE(`+col+`, '')
+		ORDER BY SUM(input_tokens + output_tokens) DESC`),
+		since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("usage store: report: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []UsageTotal
+	for rows.Next() {
+		var t UsageTotal
+		if err := rows.Scan(&t.Key, &t.Runs, &t.InputTokens, &t.OutputTokens, &t.ToolCalls, &t.Cost); err != nil {
+			return nil, fmt.Errorf("usage store: report: %w", err)
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// UsagePoint sums the usage rows of one time bucket in UsageSeries.
+type UsagePoint struct {
+	Bucket       string  `json:"bucket"` // UTC hour ("2006-01-02T15") or day ("2006-01-02")
+	Runs         int     `json:"runs"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// usageBucketWidths are the created_at prefix lengths UsageSeries buckets by.
+var usageBucketWidths = map[string]int{"hour": 13, "day": 10}
+
+// UsageSeries totals usage recorded on or after since per "hour" or "day",
+// oldest first. Buckets without usage are omitted.
+func (s *UsageStore) UsageSeries(ctx context.Context, since time.Time, bucket string) ([]UsagePoint, error) {
+	width, ok := usageBucketWidths[bucket]
+	if !ok {
+		return nil, fmt.Errorf("usage store: cannot bucket by %q (use hour or day)", bucket)
+	}
+	key := fmt.Sprintf("SUBSTR(created_at, 1, %d)", width)
+	rows, err := s.db.SQLDB().QueryContext(ctx, s.db.Dialect().Rebind(`
+		SELECT `+key+`, COUNT(*),
+		       COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0),
+		       COALESCE(SUM(input_cost + output_cost), 0)
+		FROM profile_usage
+		WHERE created_at >= ?
+		GROUP BY `+key+`
+		ORDER BY `+key),
+		since.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, fmt.Errorf("usage store: series: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	var out []UsagePoint
+	for rows.Next() {
+		var p UsagePoint
+		if err := rows.Scan(&p.Bucket, &p.Runs, &p.InputTokens, &p.OutputTokens, &p.Cost); err != nil {
+			return nil, fmt.Errorf("usage store: series: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUsageStore_UsageReport(t *testing.T) {
+	db := openTestDB(t)
+	us := NewUsageStore(db)
+	ctx := context.Background()
+	for _, r := range []UsageRecord{
+		{EntityID: "alice", ChannelID: "slack", SessionID: "s1", ModelID: "m1", InputTokens: 100, OutputTokens: 50, ToolCalls: 2, InputCost: 0.1, OutputCost: 0.2},
+		{EntityID: "alice", ChannelID: "slack", SessionID: "s1", ModelID: "m2", InputTokens: 10, OutputTokens: 5},
+		{EntityID: "bob", ChannelID: "web", SessionID: "s2", ModelID: "m1", InputTokens: 1000, OutputTokens: 1},
+	} {
+		if err := us.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := us.UsageReport(ctx, time.Now().Add(-time.Hour), "entity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Key != "bob" || got[1].Key != "alice" {
+		t.Fatalf("report by entity = %+v; want bob then alice", got)
+	}
+	if a := got[1]; a.Runs != 2 || a.InputTokens != 110 || a.OutputTokens != 55 || a.ToolCalls != 2 || a.Cost < 0.29 || a.Cost > 0.31 {
+		t.Errorf("alice = %+v", a)
+	}
+
+	byModel, err := us.UsageReport(ctx, time.Now().Add(-time.Hour), "model")
+	if err != nil || len(byModel) != 2 || byModel[0].Key != "m1" || byModel[0].Runs != 2 {
+		t.Errorf("report by model = %+v, %v", byModel, err)
+	}
+	if later, _ := us.UsageReport(ctx, time.Now().Add(time.Hour), "entity"); len(later) != 0 {
+		t.Errorf("rows before since should be excluded, got %+v", later)
+	}
+	if _, err := us.UsageReport(ctx, time.Time{}, "session_id; DROP TABLE x"); err == nil {
+		t.Error("unknown grouping should fail")
+	}
+}
+
+func TestUsageStore_UsageSeries(t *testing.T) {
+	db := openTestDB(t)
+	us := NewUsageStore(db)
+	ctx := context.Background()
+	for _, r := range []UsageRecord{
+		{EntityID: "alice", SessionID: "s1", ModelID: "m1", InputTokens: 100, OutputTokens: 50, InputCost: 0.1},
+		{EntityID: "bob", SessionID: "s2", ModelID: "m1", InputTokens: 10, OutputTokens: 5},
+	} {
+		if err := us.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for bucket, width := range map[string]int{"hour": 13, "day": 10} {
+		got, err := us.UsageSeries(ctx, time.Now().Add(-time.Hour), bucket)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Both rows were recorded just now, so they share a bucket unless the
+		// test straddles an hour boundary.
+		runs, in := 0, 0
+		for _, p := range got {
+			if len(p.Bucket) != width {
+				t.Errorf("%s bucket %q has the wrong width", bucket, p.Bucket)
+			}
+			runs, in = runs+p.Runs, in+p.InputTokens
+		}
+		if len(got) == 0 || runs != 2 || in != 110 {
+			t.Errorf("series by %s = %+v", bucket, got)
+		}
+	}
+	if _, err := us.UsageSeries(ctx, time.Time{}, "week"); err == nil {
+		t.Error("unknown bucket should fail")
+	}
+}
+
+func TestUsageStore_EntityUsage(t *testing.T) {
+	db := openTestDB(t)
+	us := NewUsageStore(db)
+	ctx := context.Background()
+	for _, r := range []UsageRecord{
+		{EntityID: "alice", ChannelID: "slack", SessionID: "s1", InputTokens: 100, OutputTokens: 50, ToolCalls: 3, InputCost: 0.1,
+			Tools: []string{"jira__search", "gitlab__create_pr", "jira__search"}},
+		{EntityID: "alice", ChannelID: "slack", SessionID: "s2", InputTokens: 10, OutputTokens: 5, ToolCalls: 1, Tools: []string{"gitlab__create_pr"}},
+		{EntityID: "alice", ChannelID: "slack", SessionID: "s2", InputTokens: 20, OutputTokens: 5},
+		{EntityID: "alice", ChannelID: "api", SessionID: "s3", InteractionKind: "system", InputTokens: 5000, ToolCalls: 1, Tools: []string{"crm__sync"}},
+		{EntityID: "bob", ChannelID: "web", SessionID: "s4", InputTokens: 1000, ToolCalls: 1, Tools: []string{"jira__search"}},
+	} {
+		if err := us.Record(ctx, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, err := us.EntityUsage(ctx, "alice", time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Sessions != 2 || u.Runs != 3 || u.InputTokens != 130 || u.OutputTokens != 60 || u.ToolCalls != 4 || u.Cost < 0.09 || u.Cost > 0.11 {
+		t.Errorf("alice = %+v; want only her chat runs", u)
+	}
+	if len(u.Tools) != 2 || u.Tools[0].Tool != "gitlab__create_pr" || u.Tools[0].Calls != 2 || u.Tools[1].Tool != "jira__search" || u.Tools[1].Calls != 2 {
+		t.Errorf("tools = %+v; want ties ordered by name", u.Tools)
+	}
+	if later, _ := us.EntityUsage(ctx, "alice", time.Now().Add(time.Hour)); later.Runs != 0 || len(later.Tools) != 0 {
+		t.Errorf("rows before since should be excluded, got %+v", later)
+	}
+}
@@ -0,0 +1,119 @@
+// Package usagestats provides the built-in stats tool, with which a user
+// asks what they have used: conversations, tokens, cost and the tools
+// called most, as recorded in the usage store. A user only sees their own
+// usage; the entity comes from the verified profile in the request context,
+// never from the LLM's arguments.
+package usagestats
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+const ToolName = "stats"
+
+// defaultWindow is how far back usage looks without since.
+const defaultWindow = 7 * 24 * time.Hour
+
+// topTools is how many of the most-called tools usage reports.
+const topTools = 5
+
+// Usage sums an entity's chat runs over a period. System runs made on the
+// entity's behalf are left out, as they are from its token budget.
+type Usage struct {
+	Sessions     int         `json:"sessions"`
+	Runs         int         `json:"runs"`
+	InputTokens  int         `json:"input_tokens"`
+	OutputTokens int         `json:"output_tokens"`
+	ToolCalls    int         `json:"tool_calls"`
+	Cost         float64     `json:"cost"`
+	Tools        []ToolCount `json:"top_tools,omitempty"` // most-called first
+}
+
+// ToolCount is how often a tool (plugin__action) was called.
+type ToolCount struct {
+	Tool  string `json:"tool"`
+	Calls int    `json:"calls"`
+}
+
+// Store reads recorded usage; *store.UsageStore satisfies it.
+type Store interface {
+	EntityUsage(ctx context.Context, entityID string, since time.Time) (Usage, error)
+}
+
+// Tool is the built-in stats plugin.
+type Tool struct {
+	store         Store
+	allowedGroups []string
+}
+
+// NewTool returns the tool. allowedGroups restricts it to those profile
+// groups; empty leaves it visible to everyone.
+func NewTool(store Store, allowedGroups []string) *Tool {
+	return &Tool{store: store, allowedGroups: allowedGroups}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   "The current user's own usage: conversations, tokens, cost and the tools used most.",
+		AllowedGroups: t.allowedGroups,
+		Actions: []orchestrator.Action{
+			{
+				Name:        "usage",
+				Description: "What the user has used over a period, e.g. when they ask \"how much have I used this week\".",
+				Parameters: []orchestrator.Parameter{
+					{Name: "since", Description: "Go duration to look back, e.g. 24h for today or 720h for a month (default 168h)", Required: false},
+				},
+				ReadOnly: true,
+			},
+		},
+	}
+}
+
+// report is what usage returns.
+type report struct {
+	Period string `json:"period"`
+	Usage
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if call.Action != "usage" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown stats action: %s", call.Action)}
+	}
+	// Usage is recorded per profile entity, so callers without a verified
+	// profile have none to report.
+	p := profile.FromContext(ctx)
+	if p == nil || p.EntityID == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Content: "Usage is not tracked for this conversation."}
+	}
+	window := defaultWindow
+	if s := strings.TrimSpace(call.Args["since"]); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("invalid since %q: want a Go duration such as 168h", s)}
+		}
+		window = d
+	}
+	u, err := t.store.EntityUsage(ctx, p.EntityID, time.Now().Add(-window))
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if u.Runs == 0 {
+		return orchestrator.ToolResult{CallID: call.ID, Content: fmt.Sprintf("No usage recorded in the last %s.", window)}
+	}
+	if len(u.Tools) > topTools {
+		u.Tools = u.Tools[:topTools]
+	}
+	data, err := json.Marshal(report{Period: "last " + window.String(), Usage: u})
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling usage: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
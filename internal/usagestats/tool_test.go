@@ -0,0 +1,80 @@
+package usagestats
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/profile"
+)
+
+// fakeStore returns usage for "alice" only and records what it was asked.
+type fakeStore struct {
+	entity string
+	since  time.Time
+}
+
+func (f *fakeStore) EntityUsage(_ context.Context, entityID string, since time.Time) (Usage, error) {
+	f.entity, f.since = entityID, since
+	if entityID != "alice" {
+		return Usage{}, nil
+	}
+	u := Usage{Sessions: 2, Runs: 5, InputTokens: 1200, OutputTokens: 300, ToolCalls: 7, Cost: 0.04}
+	for i, name := range []string{"a__1", "a__2", "a__3", "a__4", "a__5", "a__6"} {
+		u.Tools = append(u.Tools, ToolCount{Tool: name, Calls: 6 - i})
+	}
+	return u, nil
+}
+
+func usage(t *testing.T, tool *Tool, ctx context.Context, args map[string]string) orchestrator.ToolResult {
+	t.Helper()
+	return tool.Execute(ctx, orchestrator.ToolCall{ID: "c1", Plugin: ToolName, Action: "usage", Args: args})
+}
+
+func TestUsage_OwnEntityOnly(t *testing.T) {
+	store := &fakeStore{}
+	tool := NewTool(store, nil)
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "alice"})
+
+	// An entity in the arguments is ignored: the profile decides whose usage is read.
+	res := usage(t, tool, ctx, map[string]string{"entity_id": "bob"})
+	if res.Error != "" || store.entity != "alice" {
+		t.Fatalf("result %+v for entity %q", res, store.entity)
+	}
+	for _, want := range []string{`"period":"last 168h0m0s"`, `"sessions":2`, `"input_tokens":1200`, `"cost":0.04`, `{"tool":"a__5","calls":2}`} {
+		if !strings.Contains(res.Content, want) {
+			t.Errorf("content %s lacks %s", res.Content, want)
+		}
+	}
+	if strings.Contains(res.Content, "a__6") {
+		t.Errorf("content %s lists more than %d tools", res.Content, topTools)
+	}
+	if d := time.Since(store.since); d < defaultWindow || d > defaultWindow+time.Minute {
+		t.Errorf("default window looked back %s", d)
+	}
+
+	if res := usage(t, tool, ctx, map[string]string{"since": "24h"}); !strings.Contains(res.Content, `"period":"last 24h0m0s"`) {
+		t.Errorf("since=24h: %+v", res)
+	}
+	if res := usage(t, tool, ctx, map[string]string{"since": "a week"}); !strings.Contains(res.Error, "invalid since") {
+		t.Errorf("bad since: %+v", res)
+	}
+}
+
+func TestUsage_NoProfileOrNoUsage(t *testing.T) {
+	store := &fakeStore{}
+	tool := NewTool(store, []string{"staff"})
+	if got := tool.Capability().AllowedGroups; len(got) != 1 || got[0] != "staff" {
+		t.Errorf("allowed groups = %v", got)
+	}
+
+	if res := usage(t, tool, context.Background(), nil); res.Content != "Usage is not tracked for this conversation." || store.entity != "" {
+		t.Errorf("without a profile: %+v (store asked for %q)", res, store.entity)
+	}
+	ctx := profile.WithProfile(context.Background(), &profile.Profile{EntityID: "carol"})
+	if res := usage(t, tool, ctx, nil); res.Content != "No usage recorded in the last 168h0m0s." {
+		t.Errorf("no usage: %+v", res)
+	}
+}
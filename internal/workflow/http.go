@@ -0,0 +1,79 @@
+package workflow
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// runBody is the JSON body of POST /workflows/{name}/run.
+type runBody struct {
+	Inputs map[string]string `json:"inputs"`
+}
+
+// NewHandler returns the HTTP API for the engine:
+//
+//	GET  /workflows              names, descriptions and inputs
+//	POST /workflows/{name}/run   {"inputs": {...}} → the Run
+//
+// Every route requires "Authorization: Bearer <token>". A run that fails at
+// a step is still answered 200 with status "failed"; 400 means it could not
+// start.
+func NewHandler(e *Engine, token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workflows", func(w http.ResponseWriter, r *http.Request) {
+		type info struct {
+			Name        string   `json:"name"`
+			Description string   `json:"description,omitempty"`
+			Inputs      []string `json:"inputs,omitempty"`
+		}
+		out := []info{}
+		for _, wf := range e.Workflows() {
+			out = append(out, info{Name: wf.Name, Description: wf.Description, Inputs: wf.Inputs})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("POST /workflows/{name}/run", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		if _, ok := e.Get(name); !ok {
+			writeError(w, http.StatusNotFound, "unknown workflow "+name)
+			return
+		}
+		var body runBody
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&body); err != nil {
+				writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+				return
+			}
+		}
+		run, err := e.Run(r.Context(), name, body.Inputs)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, run)
+	})
+	return requireToken(token, mux)
+}
+
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
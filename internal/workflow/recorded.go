@@ -0,0 +1,274 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/pkg/toolfqn"
+)
+
+// defaultRecordingLimit is how many recordings are kept when NewRecordings
+// is given no limit; the oldest are dropped first.
+const defaultRecordingLimit = 50
+
+// LastRecording names the most recent recording in Get and Replay.
+const LastRecording = "last"
+
+// Recording is the plugin calls of one past turn, saved so they can be
+// replayed without the LLM.
+type Recording struct {
+	Name       string                      `yaml:"name" json:"name"`
+	Trigger    string                      `yaml:"trigger" json:"trigger"` // the user message that started the turn
+	Steps      []orchestrator.WorkflowStep `yaml:"steps" json:"steps"`
+	Outcome    string                      `yaml:"outcome,omitempty" json:"outcome,omitempty"`
+	RecordedAt time.Time                   `yaml:"recorded_at" json:"recorded_at"`
+}
+
+// Recordings keeps recorded workflows, in a YAML file when it has a path.
+// It is the orchestrator's WorkflowRecorder.
+type Recordings struct {
+	mu    sync.Mutex
+	path  string
+	limit int
+	items []Recording // oldest first
+}
+
+// NewRecordings loads the recordings at path; an empty path keeps them in
+// memory only. limit <= 0 means 50.
+func NewRecordings(path string, limit int) (*Recordings, error) {
+	if limit <= 0 {
+		limit = defaultRecordingLimit
+	}
+	r := &Recordings{path: path, limit: limit}
+	if path == "" {
+		return r, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r, nil
+		}
+		return nil, fmt.Errorf("reading recorded workflows: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &r.items); err != nil {
+		return nil, fmt.Errorf("parsing recorded workflows: %w", err)
+	}
+	return r, nil
+}
+
+// RecordWorkflow saves a turn's plugin calls under a name derived from its
+// trigger. Turns that ran or replayed a workflow are not recorded again.
+func (r *Recordings) RecordWorkflow(_ context.Context, wf orchestrator.Workflow) error {
+	for _, s := range wf.Steps {
+		if s.Plugin == ToolName {
+			return nil
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rec := Recording{
+		Name:       r.uniqueName(slug(wf.Trigger)),
+		Trigger:    wf.Trigger,
+		Steps:      wf.Steps,
+		Outcome:    wf.Outcome,
+		RecordedAt: time.Now().UTC(),
+	}
+	items := append(slices.Clone(r.items), rec)
+	if len(items) > r.limit {
+		items = items[len(items)-r.limit:]
+	}
+	if err := r.write(items); err != nil {
+		return err
+	}
+	r.items = items
+	slog.Info("workflow recorded", "component", "workflow", "name", rec.Name, "steps", len(rec.Steps))
+	return nil
+}
+
+// List returns the recordings, newest first.
+func (r *Recordings) List() []Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := slices.Clone(r.items)
+	slices.Reverse(out)
+	return out
+}
+
+// Get returns the named recording; LastRecording is the newest one.
+func (r *Recordings) Get(name string) (Recording, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == LastRecording && len(r.items) > 0 {
+		return r.items[len(r.items)-1], true
+	}
+	for _, rec := range r.items {
+		if rec.Name == name {
+			return rec, true
+		}
+	}
+	return Recording{}, false
+}
+
+// Delete drops the named recording.
+func (r *Recordings) Delete(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.items, func(rec Recording) bool { return rec.Name == name })
+	if i < 0 {
+		return fmt.Errorf("unknown recording %q", name)
+	}
+	items := slices.Delete(slices.Clone(r.items), i, i+1)
+	if err := r.write(items); err != nil {
+		return err
+	}
+	r.items = items
+	return nil
+}
+
+func (r *Recordings) uniqueName(base string) string {
+	taken := func(name string) bool {
+		return name == LastRecording || slices.ContainsFunc(r.items, func(rec Recording) bool { return rec.Name == name })
+	}
+	name := base
+	for n := 2; taken(name); n++ {
+		name = base + "-" + strconv.Itoa(n)
+	}
+	return name
+}
+
+func (r *Recordings) write(items []Recording) error {
+	if r.path == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0700); err != nil {
+		return fmt.Errorf("creating workflows dir: %w", err)
+	}
+	data, err := yaml.Marshal(items)
+	if err != nil {
+		return fmt.Errorf("marshaling recorded workflows: %w", err)
+	}
+	return os.WriteFile(r.path, data, 0600)
+}
+
+// slug makes a recording name from the first words of a trigger, e.g.
+// "Do the v1.2 release!" → "do-the-v1-2-release".
+func slug(trigger string) string {
+	words := strings.FieldsFunc(strings.ToLower(trigger), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 5 {
+		words = words[:5]
+	}
+	if len(words) == 0 {
+		return "workflow"
+	}
+	return strings.Join(words, "-")
+}
+
+// ReplayStep is one step of a replay with the arguments it will run with.
+type ReplayStep struct {
+	Order  int               `json:"order"`
+	Tool   string            `json:"tool"`
+	Args   map[string]string `json:"args,omitempty"`
+	Recall map[string]string `json:"recorded_args,omitempty"` // recorded values that args replaced
+}
+
+// ReplayPlan applies overrides to the recording's arguments. A key "name"
+// replaces that argument in every step that was recorded with it; "N.name"
+// sets it on step N only (1-based) and may add an argument the step did not
+// have.
+func ReplayPlan(rec Recording, overrides map[string]string) ([]ReplayStep, error) {
+	plan := make([]ReplayStep, len(rec.Steps))
+	for i, s := range rec.Steps {
+		plan[i] = ReplayStep{Order: i + 1, Tool: toolfqn.Join(s.Plugin, s.Action), Args: maps.Clone(s.Args)}
+		if plan[i].Args == nil {
+			plan[i].Args = make(map[string]string)
+		}
+	}
+	set := func(st *ReplayStep, name, value string) {
+		if old, ok := st.Args[name]; ok && old != value {
+			if st.Recall == nil {
+				st.Recall = make(map[string]string)
+			}
+			st.Recall[name] = old
+		}
+		st.Args[name] = value
+	}
+	for key, value := range overrides {
+		if n, name, ok := strings.Cut(key, "."); ok {
+			if i, err := strconv.Atoi(n); err == nil {
+				if i < 1 || i > len(plan) || name == "" {
+					return nil, fmt.Errorf("override %q: expected N.arg with N between 1 and %d", key, len(plan))
+				}
+				set(&plan[i-1], name, value)
+				continue
+			}
+		}
+		matched := false
+		for i := range plan {
+			if _, ok := plan[i].Args[key]; ok {
+				set(&plan[i], key, value)
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no recorded step takes argument %q; use N.%s to add it to step N", key, key)
+		}
+	}
+	return plan, nil
+}
+
+// Replay runs a recording's plugin calls in order with overrides applied
+// (see ReplayPlan), stopping at the first failure. Arguments are passed
+// as recorded; they are not templates.
+func (e *Engine) Replay(ctx context.Context, rec Recording, overrides map[string]string) (*Run, error) {
+	plan, err := ReplayPlan(rec, overrides)
+	if err != nil {
+		return nil, fmt.Errorf("recording %q: %w", rec.Name, err)
+	}
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxDepth {
+		return nil, fmt.Errorf("recording %q: workflows nested more than %d deep", rec.Name, maxDepth)
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	run := &Run{Workflow: rec.Name, Status: StatusSucceeded, Started: time.Now().UTC()}
+	var last string
+	for i, st := range plan {
+		if err := ctx.Err(); err != nil {
+			run.Status, run.Error = StatusFailed, err.Error()
+			break
+		}
+		s := rec.Steps[i]
+		id := strconv.Itoa(st.Order) + "_" + s.Action
+		start := time.Now()
+		out, err := e.runner.RunAction(ctx, s.Plugin, s.Action, st.Args)
+		if err != nil {
+			run.Steps = append(run.Steps, StepRun{ID: id, Status: StatusFailed, Error: err.Error(), Attempts: 1, Duration: time.Since(start)})
+			run.Status, run.Error = StatusFailed, fmt.Sprintf("step %d (%s): %v", st.Order, st.Tool, err)
+			break
+		}
+		run.Steps = append(run.Steps, StepRun{ID: id, Status: StatusSucceeded, Output: out, Attempts: 1, Duration: time.Since(start)})
+		last = out
+	}
+	if run.Status == StatusSucceeded {
+		run.Output = last
+	}
+	run.Finished = time.Now().UTC()
+	slog.Info("workflow replay", "component", "workflow", "recording", rec.Name, "status", run.Status,
+		"steps", len(run.Steps), "duration", run.Finished.Sub(run.Started), "error", run.Error)
+	return run, nil
+}
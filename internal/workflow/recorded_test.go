@@ -0,0 +1,151 @@
+package workflow
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func releaseWorkflow(trigger string) orchestrator.Workflow {
+	return orchestrator.Workflow{Trigger: trigger, Outcome: "released", Steps: []orchestrator.WorkflowStep{
+		{Plugin: "git", Action: "tag", Order: 1, Args: map[string]string{"version": "v1.2", "repo": "api"}},
+		{Plugin: "slack", Action: "post", Order: 2, Args: map[string]string{"text": "api v1.2 released", "version": "v1.2"}},
+	}}
+}
+
+func TestRecordingsPersistAndName(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workflows", "recorded.yaml")
+	recs, err := NewRecordings(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, trigger := range []string{"Do the v1.2 release!", "do the v1.2 release", "Cut the release"} {
+		if err := recs.RecordWorkflow(ctx, releaseWorkflow(trigger)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Turns that ran a workflow are not recorded.
+	wf := releaseWorkflow("again")
+	wf.Steps = append(wf.Steps, orchestrator.WorkflowStep{Plugin: ToolName, Action: "replay"})
+	if err := recs.RecordWorkflow(ctx, wf); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewRecordings(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, r := range reloaded.List() {
+		names = append(names, r.Name)
+	}
+	if got := strings.Join(names, ","); got != "cut-the-release,do-the-v1-2-release-2" {
+		t.Errorf("recordings = %s; want the newest two, newest first", got)
+	}
+	last, ok := reloaded.Get(LastRecording)
+	if !ok || last.Name != "cut-the-release" || len(last.Steps) != 2 || last.Steps[0].Args["version"] != "v1.2" {
+		t.Errorf("last = %+v", last)
+	}
+	if err := reloaded.Delete("cut-the-release"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reloaded.Get("cut-the-release"); ok {
+		t.Error("deleted recording still listed")
+	}
+}
+
+func TestReplayPlan(t *testing.T) {
+	rec := Recording{Name: "release", Steps: releaseWorkflow("").Steps}
+	plan, err := ReplayPlan(rec, map[string]string{"version": "v1.3", "2.text": "api v1.3 released", "1.dry_run": "false"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan[0].Args["version"] != "v1.3" || plan[1].Args["version"] != "v1.3" || plan[0].Args["repo"] != "api" {
+		t.Errorf("version override = %+v", plan)
+	}
+	if plan[1].Args["text"] != "api v1.3 released" || plan[1].Recall["text"] != "api v1.2 released" {
+		t.Errorf("step override = %+v", plan[1])
+	}
+	if plan[0].Args["dry_run"] != "false" {
+		t.Errorf("N.arg should add an argument: %+v", plan[0])
+	}
+	if rec.Steps[0].Args["version"] != "v1.2" {
+		t.Error("the plan changed the recording")
+	}
+	for _, bad := range []map[string]string{{"branch": "main"}, {"3.version": "v1"}, {"0.version": "v1"}} {
+		if _, err := ReplayPlan(rec, bad); err == nil {
+			t.Errorf("overrides %v should be rejected", bad)
+		}
+	}
+}
+
+func TestEngineReplay(t *testing.T) {
+	runner := &fakeRunner{results: map[string]string{"git.tag": "tagged", "slack.post": "posted"}}
+	e := mustEngine(t, runner)
+	rec := Recording{Name: "release", Steps: releaseWorkflow("").Steps}
+
+	run, err := e.Replay(context.Background(), rec, map[string]string{"version": "v1.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusSucceeded || run.Output != "posted" || len(run.Steps) != 2 {
+		t.Errorf("run = %+v", run)
+	}
+	if got := strings.Join(runner.calls, ","); got != "git.tag,slack.post" {
+		t.Errorf("calls = %s", got)
+	}
+	if runner.args["git.tag"]["version"] != "v1.3" {
+		t.Errorf("git.tag args = %v", runner.args["git.tag"])
+	}
+
+	runner.calls = nil
+	runner.fail = map[string]int{"git.tag": 1}
+	run, err = e.Replay(context.Background(), rec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusFailed || len(run.Steps) != 1 || run.Output != "" || len(runner.calls) != 1 {
+		t.Errorf("a failed step should stop the replay: %+v, calls %v", run, runner.calls)
+	}
+}
+
+func TestToolReplay(t *testing.T) {
+	runner := &fakeRunner{results: map[string]string{"git.tag": "tagged", "slack.post": "posted"}}
+	recs, _ := NewRecordings("", 0)
+	if err := recs.RecordWorkflow(context.Background(), releaseWorkflow("release api")); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewTool(mustEngine(t, runner), recs, nil)
+	if c := tool.Capability(); len(c.Actions) != 4 {
+		t.Errorf("actions = %d, want run, list, recordings and replay", len(c.Actions))
+	}
+	exec := func(action string, args map[string]string) orchestrator.ToolResult {
+		return tool.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: action, Args: args})
+	}
+
+	if res := exec("recordings", nil); !strings.Contains(res.Content, `"steps":["git__tag","slack__post"]`) {
+		t.Errorf("recordings = %+v", res)
+	}
+	res := exec("replay", map[string]string{"name": "last", "args": `{"version":"v1.3"}`, "preview": "true"})
+	if res.Error != "" || !strings.Contains(res.Content, `"version":"v1.3"`) || !strings.Contains(res.Content, `"recorded_args":{"version":"v1.2"}`) {
+		t.Errorf("preview = %+v", res)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("preview ran %v", runner.calls)
+	}
+	if res := exec("replay", map[string]string{"name": "release-api"}); res.Error != "" || res.Content != "posted" {
+		t.Errorf("replay = %+v", res)
+	}
+	if res := exec("replay", map[string]string{"name": "nope"}); res.Error == "" {
+		t.Error("unknown recording should fail")
+	}
+
+	off := NewTool(mustEngine(t, runner), nil, nil)
+	if res := off.Execute(context.Background(), orchestrator.ToolCall{Action: "replay", Args: map[string]string{"name": "last"}}); res.Error == "" {
+		t.Error("replay without recordings should fail")
+	}
+}
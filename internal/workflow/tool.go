@@ -0,0 +1,246 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/pkg/toolfqn"
+)
+
+const ToolName = "workflow"
+
+// Tool is the built-in workflow plugin: it lists and runs the configured
+// workflows and replays recorded ones. Scheduler jobs run a workflow with
+// action workflow__run.
+type Tool struct {
+	engine        *Engine
+	recordings    *Recordings // nil = recording is off
+	allowedGroups []string
+}
+
+// NewTool returns the workflow tool; recordings may be nil.
+func NewTool(engine *Engine, recordings *Recordings, allowedGroups []string) *Tool {
+	return &Tool{engine: engine, recordings: recordings, allowedGroups: allowedGroups}
+}
+
+func (t *Tool) Capability() orchestrator.PluginCapability {
+	var names []string
+	for _, w := range t.engine.Workflows() {
+		entry := w.Name
+		if w.Description != "" {
+			entry += " (" + w.Description + ")"
+		}
+		if len(w.Inputs) > 0 {
+			entry += " [inputs: " + strings.Join(w.Inputs, ", ") + "]"
+		}
+		names = append(names, entry)
+	}
+	desc := "Run predefined multi-step workflows."
+	if len(names) > 0 {
+		desc += " Available: " + strings.Join(names, "; ") + "."
+	}
+	actions := []orchestrator.Action{
+		{
+			Name:        "run",
+			Description: "Run a workflow and return its result.",
+			Parameters: []orchestrator.Parameter{
+				{Name: "name", Description: "Workflow name", Required: true},
+				{Name: "inputs", Description: "JSON-encoded object with the workflow's inputs, e.g. inputs={\"service\":\"api\"}", Required: false},
+			},
+		},
+		{
+			Name:        "list",
+			Description: "List the workflows with their steps and inputs.",
+		},
+	}
+	if t.recordings != nil {
+		desc += " Tool sequences from earlier conversations are recorded and can be replayed without re-planning them, e.g. to \"do the same release steps as last time\"."
+		actions = append(actions,
+			orchestrator.Action{
+				Name:        "recordings",
+				Description: "List recorded tool sequences, newest first, with the request that produced each and its steps.",
+			},
+			orchestrator.Action{
+				Name: "replay",
+				Description: "Re-run a recorded tool sequence step by step. Call with preview=true first: it returns the steps and the arguments they would run with. " +
+					"Ask the user about values that should differ from last time (versions, dates, names), then replay with those values in args.",
+				Parameters: []orchestrator.Parameter{
+					{Name: "name", Description: "Recording name, or \"last\" for the most recent one", Required: true},
+					{Name: "args", Description: "JSON-encoded object of argument overrides: \"version\" replaces that argument in every step recorded with it, \"2.version\" in step 2 only", Required: false},
+					{Name: "preview", Description: "\"true\" returns the steps and arguments without running them", Required: false},
+				},
+			},
+		)
+	}
+	return orchestrator.PluginCapability{
+		Name:          ToolName,
+		Description:   desc,
+		AllowedGroups: t.allowedGroups,
+		Actions:       actions,
+	}
+}
+
+func (t *Tool) Execute(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	switch call.Action {
+	case "run":
+		return t.run(ctx, call)
+	case "list":
+		return t.list(call)
+	case "recordings":
+		return t.listRecordings(call)
+	case "replay":
+		return t.replay(ctx, call)
+	default:
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown workflow action: %s", call.Action)}
+	}
+}
+
+func (t *Tool) run(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	name := strings.TrimSpace(call.Args["name"])
+	if name == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "name is required"}
+	}
+	inputs, err := parseObject("inputs", call.Args["inputs"])
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	run, err := t.engine.Run(ctx, name, inputs)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if run.Status != StatusSucceeded {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("workflow %q failed: %s", name, run.Error)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: run.Output}
+}
+
+func (t *Tool) list(call orchestrator.ToolCall) orchestrator.ToolResult {
+	type stepInfo struct {
+		ID   string `json:"id"`
+		Kind string `json:"kind"`
+	}
+	type info struct {
+		Name        string     `json:"name"`
+		Description string     `json:"description,omitempty"`
+		Inputs      []string   `json:"inputs,omitempty"`
+		Steps       []stepInfo `json:"steps"`
+	}
+	var out []info
+	for _, w := range t.engine.Workflows() {
+		i := info{Name: w.Name, Description: w.Description, Inputs: w.Inputs}
+		for _, s := range w.Steps {
+			i.Steps = append(i.Steps, stepInfo{ID: s.ID, Kind: s.kind()})
+		}
+		out = append(out, i)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling workflows: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
+
+func (t *Tool) listRecordings(call orchestrator.ToolCall) orchestrator.ToolResult {
+	if t.recordings == nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "workflow recording is not enabled"}
+	}
+	type info struct {
+		Name       string    `json:"name"`
+		Trigger    string    `json:"trigger"`
+		Steps      []string  `json:"steps"`
+		RecordedAt time.Time `json:"recorded_at"`
+	}
+	out := []info{}
+	for _, rec := range t.recordings.List() {
+		i := info{Name: rec.Name, Trigger: rec.Trigger, RecordedAt: rec.RecordedAt}
+		for _, s := range rec.Steps {
+			i.Steps = append(i.Steps, toolfqn.Join(s.Plugin, s.Action))
+		}
+		out = append(out, i)
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling recordings: %v", err)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+}
+
+func (t *Tool) replay(ctx context.Context, call orchestrator.ToolCall) orchestrator.ToolResult {
+	if t.recordings == nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "workflow recording is not enabled"}
+	}
+	name := strings.TrimSpace(call.Args["name"])
+	if name == "" {
+		return orchestrator.ToolResult{CallID: call.ID, Error: "name is required"}
+	}
+	rec, ok := t.recordings.Get(name)
+	if !ok {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("unknown recording %q; see workflow__recordings", name)}
+	}
+	overrides, err := parseObject("args", call.Args["args"])
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if call.Args["preview"] == "true" {
+		plan, err := ReplayPlan(rec, overrides)
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+		}
+		data, err := json.Marshal(map[string]any{
+			"name":    rec.Name,
+			"trigger": rec.Trigger,
+			"steps":   plan,
+			"next":    "Confirm the arguments with the user, then call workflow__replay with any changed values in args.",
+		})
+		if err != nil {
+			return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("marshaling replay plan: %v", err)}
+		}
+		return orchestrator.ToolResult{CallID: call.ID, Content: string(data)}
+	}
+	run, err := t.engine.Replay(ctx, rec, overrides)
+	if err != nil {
+		return orchestrator.ToolResult{CallID: call.ID, Error: err.Error()}
+	}
+	if run.Status != StatusSucceeded {
+		return orchestrator.ToolResult{CallID: call.ID, Error: fmt.Sprintf("replay of %q failed after %d steps: %s", rec.Name, len(run.Steps), run.Error)}
+	}
+	return orchestrator.ToolResult{CallID: call.ID, Content: run.Output}
+}
+
+// kind names the step type for listings.
+func (s Step) kind() string {
+	switch {
+	case len(s.Parallel) > 0:
+		return "parallel"
+	case s.Prompt != "":
+		return "llm"
+	default:
+		return "tool:" + s.Tool
+	}
+}
+
+// parseObject decodes the JSON object passed as param; non-string values
+// are passed on in their JSON form.
+func parseObject(param, raw string) (map[string]string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var m map[string]any
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, fmt.Errorf("%s must be a JSON object: %w", param, err)
+	}
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		if s, ok := v.(string); ok {
+			out[k] = s
+			continue
+		}
+		b, _ := json.Marshal(v)
+		out[k] = string(b)
+	}
+	return out, nil
+}
@@ -0,0 +1,76 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+)
+
+func testEngine(t *testing.T) *Engine {
+	t.Helper()
+	return mustEngine(t, &fakeRunner{results: map[string]string{"echo.say": "hello"}},
+		Workflow{Name: "greet", Description: "Say hello", Inputs: []string{"who"}, Steps: []Step{
+			{ID: "say", Tool: "echo__say", Args: map[string]string{"text": "{{.Input.who}}"}},
+		}, Output: "{{.Steps.say.Output}}, {{.Input.who}}"},
+	)
+}
+
+func TestToolRunAndList(t *testing.T) {
+	tool := NewTool(testEngine(t), nil, nil)
+	if c := tool.Capability(); !strings.Contains(c.Description, "greet (Say hello) [inputs: who]") {
+		t.Errorf("description = %q", c.Description)
+	}
+	res := tool.Execute(context.Background(), orchestrator.ToolCall{ID: "1", Action: "run", Args: map[string]string{"name": "greet", "inputs": `{"who":"Ana"}`}})
+	if res.Error != "" || res.Content != "hello, Ana" {
+		t.Errorf("run = %+v", res)
+	}
+	res = tool.Execute(context.Background(), orchestrator.ToolCall{ID: "2", Action: "run", Args: map[string]string{"name": "greet", "inputs": "not json"}})
+	if res.Error == "" {
+		t.Error("malformed inputs should fail")
+	}
+	res = tool.Execute(context.Background(), orchestrator.ToolCall{ID: "3", Action: "list"})
+	if !strings.Contains(res.Content, `"kind":"tool:echo__say"`) {
+		t.Errorf("list = %s", res.Content)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(testEngine(t), "secret"))
+	defer srv.Close()
+
+	do := func(method, path, token, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+	if resp := do("GET", "/workflows", "wrong", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("bad token: %d", resp.StatusCode)
+	}
+	resp := do("POST", "/workflows/greet/run", "secret", `{"inputs":{"who":"Bo"}}`)
+	var run Run
+	if err := json.NewDecoder(resp.Body).Decode(&run); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusOK || run.Status != StatusSucceeded || run.Output != "hello, Bo" {
+		t.Errorf("run: %d %+v", resp.StatusCode, run)
+	}
+	if resp := do("POST", "/workflows/greet/run", "secret", `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing input: %d", resp.StatusCode)
+	}
+	if resp := do("POST", "/workflows/nope/run", "secret", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown workflow: %d", resp.StatusCode)
+	}
+}
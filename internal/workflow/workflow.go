@@ -0,0 +1,474 @@
+// Package workflow runs declarative multi-step workflows defined in the
+// config: tool steps and LLM steps in sequence, parallel branches, steps
+// conditioned on earlier results, and per-step retries. Step arguments,
+// prompts, conditions and the workflow output are Go text/templates over
+// the inputs and the results of earlier steps. Workflows are started by the
+// built-in workflow tool (and so by scheduler jobs running workflow__run) or
+// through the HTTP API.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	"github.com/opentalon/opentalon/internal/provider"
+	"github.com/opentalon/opentalon/pkg/toolfqn"
+)
+
+// maxDepth bounds workflows started from inside workflows (a tool step
+// calling workflow__run), so a workflow that runs itself fails instead of
+// recursing forever.
+const maxDepth = 4
+
+// Workflow is a named sequence of steps.
+type Workflow struct {
+	Name        string
+	Description string
+	Inputs      []string // names of the inputs a run must supply
+	Steps       []Step
+	Output      string // template for the run's result; empty = output of the last step that ran
+}
+
+// Step is one unit of work. Exactly one of Tool, Prompt or Parallel is set.
+type Step struct {
+	ID       string
+	Tool     string            // plugin__action
+	Args     map[string]string // tool arguments; each value is a template
+	Prompt   string            // LLM step: template for the user message
+	System   string            // LLM step: optional system prompt template
+	Model    string            // LLM step: model id; empty = default model
+	Parallel []Step            // branches run concurrently; each records its own result
+	// If is a template expression, e.g. `ne .Steps.check.Output ""`; the
+	// step is skipped unless it is true by template rules.
+	If              string
+	Retry           Retry
+	ContinueOnError bool // a failure is recorded but does not stop the run
+}
+
+// Retry retries a failed tool or LLM step.
+type Retry struct {
+	Attempts int           // retries after the first try
+	Backoff  time.Duration // wait before the first retry, doubled for each further one
+}
+
+// Step statuses.
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusSkipped   = "skipped"
+)
+
+// StepResult is what later templates see of a finished step as
+// .Steps.<id>.
+type StepResult struct {
+	Status string
+	Output string
+	JSON   any // Output parsed as JSON; nil when it is not JSON
+	Error  string
+}
+
+// StepRun records one step of a run.
+type StepRun struct {
+	ID       string        `json:"id"`
+	Status   string        `json:"status"`
+	Output   string        `json:"output,omitempty"`
+	Error    string        `json:"error,omitempty"`
+	Attempts int           `json:"attempts,omitempty"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+// Run is the outcome of one workflow run.
+type Run struct {
+	Workflow string    `json:"workflow"`
+	Status   string    `json:"status"`
+	Output   string    `json:"output,omitempty"`
+	Error    string    `json:"error,omitempty"`
+	Steps    []StepRun `json:"steps"`
+	Started  time.Time `json:"started"`
+	Finished time.Time `json:"finished"`
+}
+
+// ActionRunner runs a plugin action; the orchestrator satisfies it.
+type ActionRunner interface {
+	RunAction(ctx context.Context, plugin, action string, args map[string]string) (string, error)
+}
+
+// Engine holds the configured workflows and runs them.
+type Engine struct {
+	runner    ActionRunner
+	llm       orchestrator.LLMClient
+	workflows map[string]Workflow
+}
+
+// NewEngine validates workflows and returns an engine running them. llm may
+// be nil when no workflow has an LLM step.
+func NewEngine(runner ActionRunner, llm orchestrator.LLMClient, workflows []Workflow) (*Engine, error) {
+	e := &Engine{runner: runner, llm: llm, workflows: make(map[string]Workflow, len(workflows))}
+	for _, w := range workflows {
+		if w.Name == "" {
+			return nil, fmt.Errorf("workflow name is required")
+		}
+		if _, dup := e.workflows[w.Name]; dup {
+			return nil, fmt.Errorf("duplicate workflow %q", w.Name)
+		}
+		if err := e.validate(w); err != nil {
+			return nil, fmt.Errorf("workflow %q: %w", w.Name, err)
+		}
+		e.workflows[w.Name] = w
+	}
+	return e, nil
+}
+
+func (e *Engine) validate(w Workflow) error {
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	if _, err := parseTemplate("output", w.Output); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	return e.validateSteps(w.Steps, seen, false)
+}
+
+func (e *Engine) validateSteps(steps []Step, seen map[string]bool, inParallel bool) error {
+	for _, s := range steps {
+		if s.ID == "" {
+			return fmt.Errorf("every step needs an id")
+		}
+		if seen[s.ID] {
+			return fmt.Errorf("duplicate step id %q", s.ID)
+		}
+		seen[s.ID] = true
+		kinds := 0
+		for _, set := range []bool{s.Tool != "", s.Prompt != "", len(s.Parallel) > 0} {
+			if set {
+				kinds++
+			}
+		}
+		if kinds != 1 {
+			return fmt.Errorf("step %q: set exactly one of tool, prompt or parallel", s.ID)
+		}
+		if s.Tool != "" {
+			if _, _, err := toolfqn.Split(s.Tool); err != nil {
+				return fmt.Errorf("step %q: invalid tool %q, expected plugin__action", s.ID, s.Tool)
+			}
+		}
+		if s.Prompt != "" && e.llm == nil {
+			return fmt.Errorf("step %q: LLM steps need a configured model", s.ID)
+		}
+		if len(s.Parallel) > 0 {
+			if inParallel {
+				return fmt.Errorf("step %q: parallel steps cannot be nested", s.ID)
+			}
+			if err := e.validateSteps(s.Parallel, seen, true); err != nil {
+				return err
+			}
+		}
+		if s.Retry.Attempts < 0 {
+			return fmt.Errorf("step %q: retry attempts cannot be negative", s.ID)
+		}
+		texts := map[string]string{"prompt": s.Prompt, "system": s.System}
+		for k, v := range s.Args {
+			texts["arg "+k] = v
+		}
+		for what, text := range texts {
+			if _, err := parseTemplate(what, text); err != nil {
+				return fmt.Errorf("step %q: %w", s.ID, err)
+			}
+		}
+		if s.If != "" {
+			if _, err := parseTemplate("if", condition(s.If)); err != nil {
+				return fmt.Errorf("step %q: %w", s.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Workflows returns the configured workflows sorted by name.
+func (e *Engine) Workflows() []Workflow {
+	out := make([]Workflow, 0, len(e.workflows))
+	for _, w := range e.workflows {
+		out = append(out, w)
+	}
+	slices.SortFunc(out, func(a, b Workflow) int { return strings.Compare(a.Name, b.Name) })
+	return out
+}
+
+// Get returns the named workflow.
+func (e *Engine) Get(name string) (Workflow, bool) {
+	w, ok := e.workflows[name]
+	return w, ok
+}
+
+type depthKey struct{}
+
+// Run runs the named workflow with inputs. A step that fails (after its
+// retries, and without ContinueOnError) ends the run with StatusFailed; the
+// returned error is reserved for runs that could not start.
+func (e *Engine) Run(ctx context.Context, name string, inputs map[string]string) (*Run, error) {
+	w, ok := e.workflows[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown workflow %q", name)
+	}
+	for _, in := range w.Inputs {
+		if _, ok := inputs[in]; !ok {
+			return nil, fmt.Errorf("workflow %q: missing input %q", name, in)
+		}
+	}
+	for k := range inputs {
+		if !slices.Contains(w.Inputs, k) {
+			return nil, fmt.Errorf("workflow %q: unknown input %q (inputs: %s)", name, k, strings.Join(w.Inputs, ", "))
+		}
+	}
+	depth, _ := ctx.Value(depthKey{}).(int)
+	if depth >= maxDepth {
+		return nil, fmt.Errorf("workflow %q: workflows nested more than %d deep", name, maxDepth)
+	}
+	ctx = context.WithValue(ctx, depthKey{}, depth+1)
+
+	r := &runner{engine: e, data: templateData{Input: inputs, Steps: make(map[string]StepResult)}}
+	run := &Run{Workflow: name, Status: StatusSucceeded, Started: time.Now().UTC()}
+	if err := r.runSteps(ctx, w.Steps); err != nil {
+		run.Status, run.Error = StatusFailed, err.Error()
+	}
+	run.Steps = r.steps
+	if run.Status == StatusSucceeded {
+		out, err := r.output(w)
+		if err != nil {
+			run.Status, run.Error = StatusFailed, fmt.Sprintf("output: %v", err)
+		}
+		run.Output = out
+	}
+	run.Finished = time.Now().UTC()
+	slog.Info("workflow run", "component", "workflow", "workflow", name, "status", run.Status,
+		"steps", len(run.Steps), "duration", run.Finished.Sub(run.Started), "error", run.Error)
+	return run, nil
+}
+
+// templateData is what step templates render.
+type templateData struct {
+	Input map[string]string
+	Steps map[string]StepResult
+}
+
+// runner carries the state of one run.
+type runner struct {
+	engine *Engine
+
+	mu    sync.Mutex
+	data  templateData
+	steps []StepRun
+	last  string // output of the last step that ran
+}
+
+func (r *runner) runSteps(ctx context.Context, steps []Step) error {
+	for _, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if len(s.Parallel) > 0 {
+			if err := r.runParallel(ctx, s); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.runStep(ctx, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runParallel runs the group's branches concurrently. Branches see the
+// results of the steps before the group, not of each other.
+func (r *runner) runParallel(ctx context.Context, group Step) error {
+	ok, err := r.condition(group)
+	if err != nil {
+		return r.fail(group, err, 0, 0)
+	}
+	if !ok {
+		r.skip(group.ID)
+		for _, b := range group.Parallel {
+			r.skip(b.ID)
+		}
+		return nil
+	}
+	start := time.Now()
+	errs := make([]error, len(group.Parallel))
+	var wg sync.WaitGroup
+	for i, b := range group.Parallel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = r.runStep(ctx, b)
+		}()
+	}
+	wg.Wait()
+	var failed []string
+	for i, err := range errs {
+		if err != nil {
+			failed = append(failed, group.Parallel[i].ID)
+		}
+	}
+	if len(failed) > 0 {
+		return r.fail(group, fmt.Errorf("branches failed: %s", strings.Join(failed, ", ")), 0, time.Since(start))
+	}
+	r.record(group.ID, StepRun{ID: group.ID, Status: StatusSucceeded, Duration: time.Since(start)}, StepResult{Status: StatusSucceeded})
+	return nil
+}
+
+// runStep runs a tool or LLM step with its retries. The returned error stops
+// the run; a failure under ContinueOnError is recorded and returns nil.
+func (r *runner) runStep(ctx context.Context, s Step) error {
+	ok, err := r.condition(s)
+	if err != nil {
+		return r.fail(s, err, 0, 0)
+	}
+	if !ok {
+		r.skip(s.ID)
+		return nil
+	}
+	start := time.Now()
+	var out string
+	tries := 0
+	for {
+		tries++
+		out, err = r.execute(ctx, s)
+		if err == nil || tries > s.Retry.Attempts {
+			break
+		}
+		wait := s.Retry.Backoff << (tries - 1)
+		slog.Info("workflow step failed; retrying", "component", "workflow", "step", s.ID, "attempt", tries, "in", wait, "error", err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return r.fail(s, err, tries, time.Since(start))
+		case <-timer.C:
+		}
+	}
+	if err != nil {
+		return r.fail(s, err, tries, time.Since(start))
+	}
+	res := StepResult{Status: StatusSucceeded, Output: out}
+	var v any
+	if json.Unmarshal([]byte(out), &v) == nil {
+		res.JSON = v
+	}
+	r.record(s.ID, StepRun{ID: s.ID, Status: StatusSucceeded, Output: out, Attempts: tries, Duration: time.Since(start)}, res)
+	r.mu.Lock()
+	r.last = out
+	r.mu.Unlock()
+	return nil
+}
+
+// execute makes one try of a tool or LLM step.
+func (r *runner) execute(ctx context.Context, s Step) (string, error) {
+	if s.Tool != "" {
+		plugin, action, _ := toolfqn.Split(s.Tool)
+		args := make(map[string]string, len(s.Args))
+		for k, v := range s.Args {
+			rendered, err := r.render("arg "+k, v)
+			if err != nil {
+				return "", err
+			}
+			args[k] = rendered
+		}
+		return r.engine.runner.RunAction(ctx, plugin, action, args)
+	}
+	prompt, err := r.render("prompt", s.Prompt)
+	if err != nil {
+		return "", err
+	}
+	system, err := r.render("system", s.System)
+	if err != nil {
+		return "", err
+	}
+	var msgs []provider.Message
+	if system != "" {
+		msgs = append(msgs, provider.Message{Role: provider.RoleSystem, Content: system})
+	}
+	msgs = append(msgs, provider.Message{Role: provider.RoleUser, Content: prompt})
+	resp, err := r.engine.llm.Complete(ctx, &provider.CompletionRequest{Model: s.Model, Messages: msgs})
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+func (r *runner) condition(s Step) (bool, error) {
+	if s.If == "" {
+		return true, nil
+	}
+	out, err := r.render("if", condition(s.If))
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+func (r *runner) fail(s Step, err error, tries int, d time.Duration) error {
+	r.record(s.ID, StepRun{ID: s.ID, Status: StatusFailed, Error: err.Error(), Attempts: tries, Duration: d},
+		StepResult{Status: StatusFailed, Error: err.Error()})
+	if s.ContinueOnError {
+		return nil
+	}
+	return fmt.Errorf("step %q: %w", s.ID, err)
+}
+
+func (r *runner) skip(id string) {
+	r.record(id, StepRun{ID: id, Status: StatusSkipped}, StepResult{Status: StatusSkipped})
+}
+
+func (r *runner) record(id string, run StepRun, res StepResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, run)
+	r.data.Steps[id] = res
+}
+
+func (r *runner) render(what, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	tmpl, err := parseTemplate(what, text)
+	if err != nil {
+		return "", err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	if err := tmpl.Execute(&b, r.data); err != nil {
+		return "", fmt.Errorf("%s: %w", what, err)
+	}
+	return b.String(), nil
+}
+
+func (r *runner) output(w Workflow) (string, error) {
+	if w.Output == "" {
+		return r.last, nil
+	}
+	return r.render("output", w.Output)
+}
+
+// condition wraps an If expression so it renders to "1" when true.
+func condition(expr string) string {
+	return "{{if " + expr + "}}1{{end}}"
+}
+
+func parseTemplate(what, text string) (*template.Template, error) {
+	tmpl, err := template.New(what).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s template: %w", what, err)
+	}
+	return tmpl, nil
+}
@@ -0,0 +1,178 @@
+package workflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// fakeRunner answers plugin.action from results and records every call.
+type fakeRunner struct {
+	mu      sync.Mutex
+	results map[string]string
+	fail    map[string]int // remaining failures per plugin.action
+	calls   []string
+	args    map[string]map[string]string
+}
+
+func (f *fakeRunner) RunAction(_ context.Context, plugin, action string, args map[string]string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := plugin + "." + action
+	f.calls = append(f.calls, key)
+	if f.args == nil {
+		f.args = make(map[string]map[string]string)
+	}
+	f.args[key] = args
+	if f.fail[key] > 0 {
+		f.fail[key]--
+		return "", errors.New(key + " unavailable")
+	}
+	return f.results[key], nil
+}
+
+type fakeLLM struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (f *fakeLLM) Complete(_ context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	last := req.Messages[len(req.Messages)-1].Content
+	f.prompts = append(f.prompts, last)
+	return &provider.CompletionResponse{Content: "summary of " + last}, nil
+}
+
+func mustEngine(t *testing.T, runner ActionRunner, wfs ...Workflow) *Engine {
+	t.Helper()
+	e, err := NewEngine(runner, &fakeLLM{}, wfs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func TestRunSequenceWithLLMAndConditions(t *testing.T) {
+	runner := &fakeRunner{results: map[string]string{
+		"pd.incidents": `{"open":[{"title":"db down"}]}`,
+		"slack.post":   "posted",
+	}}
+	llm := &fakeLLM{}
+	e, err := NewEngine(runner, llm, []Workflow{{
+		Name:   "digest",
+		Inputs: []string{"service"},
+		Steps: []Step{
+			{ID: "fetch", Tool: "pd__incidents", Args: map[string]string{"service": "{{.Input.service}}"}},
+			{ID: "summary", Prompt: `Incidents: {{range .Steps.fetch.JSON.open}}{{.title}}{{end}}`, If: "len .Steps.fetch.JSON.open"},
+			{ID: "post", Tool: "slack__post", Args: map[string]string{"text": "{{.Steps.summary.Output}}"}},
+			{ID: "page", Tool: "pd__page", If: `eq .Steps.post.Output "failed"`},
+		},
+		Output: "{{.Steps.summary.Output}} ({{.Steps.page.Status}})",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := e.Run(context.Background(), "digest", map[string]string{"service": "api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusSucceeded || run.Output != "summary of Incidents: db down (skipped)" {
+		t.Fatalf("run = %+v", run)
+	}
+	if runner.args["pd.incidents"]["service"] != "api" || runner.args["slack.post"]["text"] != "summary of Incidents: db down" {
+		t.Errorf("args = %v", runner.args)
+	}
+	if len(run.Steps) != 4 || run.Steps[3].Status != StatusSkipped {
+		t.Errorf("steps = %+v", run.Steps)
+	}
+}
+
+func TestRunRetriesAndFailures(t *testing.T) {
+	runner := &fakeRunner{
+		results: map[string]string{"a.x": "A", "b.y": "B"},
+		fail:    map[string]int{"a.x": 2, "b.y": 5},
+	}
+	e := mustEngine(t, runner, Workflow{Name: "w", Steps: []Step{
+		{ID: "a", Tool: "a__x", Retry: Retry{Attempts: 2, Backoff: time.Millisecond}},
+		{ID: "b", Tool: "b__y", ContinueOnError: true},
+		{ID: "c", Tool: "b__y"},
+		{ID: "d", Tool: "a__x"},
+	}})
+	run, err := e.Run(context.Background(), "w", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusFailed || !strings.Contains(run.Error, `step "c"`) {
+		t.Fatalf("run = %+v", run)
+	}
+	if run.Steps[0].Status != StatusSucceeded || run.Steps[0].Attempts != 3 {
+		t.Errorf("retried step = %+v", run.Steps[0])
+	}
+	if run.Steps[1].Status != StatusFailed || len(run.Steps) != 3 {
+		t.Errorf("steps = %+v; the run should stop at c", run.Steps)
+	}
+}
+
+func TestRunParallel(t *testing.T) {
+	runner := &fakeRunner{results: map[string]string{"x.one": "1", "x.two": "2"}, fail: map[string]int{"x.bad": 1}}
+	e := mustEngine(t, runner,
+		Workflow{Name: "ok", Steps: []Step{
+			{ID: "fan", Parallel: []Step{{ID: "one", Tool: "x__one"}, {ID: "two", Tool: "x__two"}}},
+		}, Output: "{{.Steps.one.Output}}+{{.Steps.two.Output}}"},
+		Workflow{Name: "bad", Steps: []Step{
+			{ID: "fan", Parallel: []Step{{ID: "one", Tool: "x__one"}, {ID: "bad", Tool: "x__bad"}}},
+		}},
+	)
+	run, _ := e.Run(context.Background(), "ok", nil)
+	if run.Status != StatusSucceeded || run.Output != "1+2" {
+		t.Errorf("ok run = %+v", run)
+	}
+	run, _ = e.Run(context.Background(), "bad", nil)
+	if run.Status != StatusFailed || !strings.Contains(run.Error, "branches failed: bad") {
+		t.Errorf("bad run = %+v", run)
+	}
+}
+
+func TestRunInputsAndDepth(t *testing.T) {
+	e := mustEngine(t, &fakeRunner{}, Workflow{Name: "w", Inputs: []string{"id"}, Steps: []Step{{ID: "s", Tool: "a__b"}}})
+	ctx := context.Background()
+	if _, err := e.Run(ctx, "w", nil); err == nil {
+		t.Error("missing input should fail")
+	}
+	if _, err := e.Run(ctx, "w", map[string]string{"id": "1", "typo": "x"}); err == nil {
+		t.Error("unknown input should fail")
+	}
+	if _, err := e.Run(ctx, "nope", nil); err == nil {
+		t.Error("unknown workflow should fail")
+	}
+	deep := context.WithValue(ctx, depthKey{}, maxDepth)
+	if _, err := e.Run(deep, "w", map[string]string{"id": "1"}); err == nil {
+		t.Error("runs nested beyond maxDepth should fail")
+	}
+}
+
+func TestNewEngineValidates(t *testing.T) {
+	for name, w := range map[string]Workflow{
+		"no steps":        {Name: "w"},
+		"no id":           {Name: "w", Steps: []Step{{Tool: "a__b"}}},
+		"duplicate id":    {Name: "w", Steps: []Step{{ID: "s", Tool: "a__b"}, {ID: "s", Tool: "a__b"}}},
+		"two kinds":       {Name: "w", Steps: []Step{{ID: "s", Tool: "a__b", Prompt: "hi"}}},
+		"bad tool":        {Name: "w", Steps: []Step{{ID: "s", Tool: "nope"}}},
+		"bad template":    {Name: "w", Steps: []Step{{ID: "s", Tool: "a__b", Args: map[string]string{"x": "{{.Input"}}}},
+		"bad condition":   {Name: "w", Steps: []Step{{ID: "s", Tool: "a__b", If: "eq ("}}},
+		"nested parallel": {Name: "w", Steps: []Step{{ID: "p", Parallel: []Step{{ID: "q", Parallel: []Step{{ID: "r", Tool: "a__b"}}}}}}},
+	} {
+		if _, err := NewEngine(&fakeRunner{}, &fakeLLM{}, []Workflow{w}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := NewEngine(&fakeRunner{}, nil, []Workflow{{Name: "w", Steps: []Step{{ID: "s", Prompt: "hi"}}}}); err == nil {
+		t.Error("an LLM step without an LLM should fail")
+	}
+}
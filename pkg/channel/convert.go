@@ -36,6 +36,13 @@ func inboundToProto(m InboundMessage) *channelpb.InboundMessage {
 		Metadata:       m.Metadata,
 		Timestamp:      timestamppb.New(m.Timestamp),
 	}
+	if m.MessageID != "" {
+		pb.Metadata = make(map[string]string, len(m.Metadata)+1)
+		for k, v := range m.Metadata {
+			pb.Metadata[k] = v
+		}
+		pb.Metadata[MessageIDMetadataKey] = m.MessageID
+	}
 	for _, f := range m.Files {
 		pb.Files = append(pb.Files, fileToProto(f))
 	}
@@ -3,15 +3,35 @@ package channel
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"sync"
 
 	"github.com/opentalon/opentalon/pkg/channel/channelpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
 // grpcServer implements channelpb.ChannelServiceServer by delegating to a Channel.
+//
+// The channel is started once, on the first Start stream, and lives as long
+// as ctx; the host may drop and reopen the stream (a restart, a network
+// blip) without restarting the channel. Each new stream replaces the one
+// before it and picks up where it left off: messages wait in the inbox
+// meanwhile, and one that failed to go out on a broken stream goes out
+// first on the next.
 type grpcServer struct {
 	channelpb.UnimplementedChannelServiceServer
-	ch Channel
+	ch  Channel
+	ctx context.Context // the channel's lifetime; nil = forever
+
+	startOnce sync.Once
+	startErr  error
+	inbox     chan InboundMessage
+	redeliver chan InboundMessage
+
+	mu       sync.Mutex
+	streams  int                // Start streams opened so far
+	attached int                // number of the attached stream; 0 = none
+	detach   context.CancelFunc // ends the attached stream
 }
 
 func (s *grpcServer) Capabilities(_ context.Context, _ *emptypb.Empty) (*channelpb.ChannelCapabilities, error) {
@@ -40,22 +60,70 @@ func (s *grpcServer) Tools(_ context.Context, _ *emptypb.Empty) (*channelpb.Tool
 	return &channelpb.ToolsResponse{Tools: toolsToProto(tp.Tools())}, nil
 }
 
+// start starts the channel the first time it is called.
+func (s *grpcServer) start() error {
+	s.startOnce.Do(func() {
+		if s.ctx == nil {
+			s.ctx = context.Background()
+		}
+		s.inbox = make(chan InboundMessage, InboxSize)
+		s.redeliver = make(chan InboundMessage, 1)
+		s.startErr = s.ch.Start(s.ctx, s.inbox)
+	})
+	return s.startErr
+}
+
 func (s *grpcServer) Start(_ *emptypb.Empty, stream channelpb.ChannelService_StartServer) error {
-	inbox := make(chan InboundMessage, 32)
-	if err := s.ch.Start(stream.Context(), inbox); err != nil {
+	if err := s.start(); err != nil {
 		return fmt.Errorf("start: %w", err)
 	}
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	s.mu.Lock()
+	if s.detach != nil {
+		s.detach() // a reconnect replaces the old stream
+	}
+	s.streams++
+	n := s.streams
+	s.attached, s.detach = n, cancel
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		if s.attached == n {
+			s.attached, s.detach = 0, nil
+		}
+		s.mu.Unlock()
+	}()
+
 	for {
+		var msg InboundMessage
 		select {
-		case msg, ok := <-inbox:
-			if !ok {
+		case msg = <-s.redeliver:
+		default:
+			select {
+			case msg = <-s.redeliver:
+			case m, ok := <-s.inbox:
+				if !ok {
+					return nil
+				}
+				msg = m
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-s.ctx.Done():
 				return nil
 			}
-			if err := stream.Send(inboundToProto(msg)); err != nil {
-				return err
+		}
+		err := ctx.Err()
+		if err == nil {
+			err = stream.Send(inboundToProto(msg))
+		}
+		if err != nil {
+			select {
+			case s.redeliver <- msg:
+			default:
+				slog.Warn("channel: inbound message lost on a broken stream", "message_id", msg.MessageID)
 			}
-		case <-stream.Context().Done():
-			return stream.Context().Err()
+			return err
 		}
 	}
 }
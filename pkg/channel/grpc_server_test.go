@@ -0,0 +1,119 @@
+package channel
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/pkg/channel/channelpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// pushChannel delivers whatever is written to in.
+type pushChannel struct {
+	in     chan InboundMessage
+	starts atomic.Int32
+}
+
+func (c *pushChannel) ID() string                                  { return "push" }
+func (c *pushChannel) Kind() string                                { return "push" }
+func (c *pushChannel) Capabilities() Capabilities                  { return Capabilities{ID: "push"} }
+func (c *pushChannel) Send(context.Context, OutboundMessage) error { return nil }
+func (c *pushChannel) Stop() error                                 { return nil }
+func (c *pushChannel) Start(ctx context.Context, inbox chan<- InboundMessage) error {
+	c.starts.Add(1)
+	go func() {
+		for msg := range c.in {
+			if Deliver(ctx, inbox, msg) != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+func TestParseHandshake(t *testing.T) {
+	hs := Handshake{ID: "echo", Network: "unix", Address: "/tmp/c/channel.sock"}
+	got, err := ParseHandshake(hs.String() + "\n")
+	if err != nil || got != hs {
+		t.Errorf("round trip = %+v, %v", got, err)
+	}
+	for _, bad := range []string{"", "echo|unix", "echo|http|x", "|unix|/tmp/s", "a|unix|/s|extra"} {
+		if _, err := ParseHandshake(bad); err == nil {
+			t.Errorf("ParseHandshake(%q) accepted", bad)
+		}
+	}
+}
+
+func TestDeliverBackpressure(t *testing.T) {
+	inbox := make(chan InboundMessage, 1)
+	if err := Deliver(context.Background(), inbox, InboundMessage{Content: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Deliver(ctx, inbox, InboundMessage{Content: "b"}); err != context.DeadlineExceeded {
+		t.Errorf("a full inbox = %v, want the ctx error", err)
+	}
+}
+
+// TestStartSurvivesReconnect checks a new Start stream resumes delivery
+// without restarting the channel.
+func TestStartSurvivesReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := &pushChannel{in: make(chan InboundMessage)}
+	defer close(ch.in)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := grpc.NewServer()
+	gs := &grpcServer{ch: ch, ctx: ctx}
+	channelpb.RegisterChannelServiceServer(srv, gs)
+	go func() { _ = srv.Serve(ln) }()
+	defer srv.Stop()
+	cc, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = cc.Close() }()
+	client := channelpb.NewChannelServiceClient(cc)
+
+	open := func() (channelpb.ChannelService_StartClient, context.CancelFunc) {
+		sctx, scancel := context.WithCancel(context.Background())
+		stream, err := client.Start(sctx, &emptypb.Empty{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stream, scancel
+	}
+	first, drop := open()
+	ch.in <- InboundMessage{Content: "one", MessageID: "1"}
+	if msg, err := first.Recv(); err != nil || msg.GetContent() != "one" {
+		t.Fatalf("first stream = %v, %v", msg, err)
+	}
+	drop() // the host goes away
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		gs.mu.Lock()
+		attached := gs.attached
+		gs.mu.Unlock()
+		if attached == 0 {
+			break
+		}
+	}
+
+	ch.in <- InboundMessage{Content: "two", MessageID: "2"} // waits in the inbox
+	second, done := open()
+	defer done()
+	if msg, err := second.Recv(); err != nil || msg.GetContent() != "two" {
+		t.Fatalf("second stream = %v, %v", msg, err)
+	}
+	if n := ch.starts.Load(); n != 1 {
+		t.Errorf("channel started %d times, want 1", n)
+	}
+}
@@ -0,0 +1,42 @@
+package channel
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EnvSockDir is the environment variable through which the host, when it
+// launches a channel binary, names the directory the channel must create
+// its SocketFileName socket in. A channel started without it prints a
+// Handshake line instead.
+const EnvSockDir = "OPENTALON_CHANNEL_SOCK_DIR"
+
+// Handshake is the line a channel started outside the host prints on
+// stdout so a launcher can find its socket.
+// Format: "<id>|<network>|<address>\n"
+// Example: "echo|unix|/tmp/opentalon-channel-123/channel.sock"
+type Handshake struct {
+	ID      string // the channel's ID()
+	Network string // "unix" or "tcp"
+	Address string // socket path or host:port
+}
+
+func (h Handshake) String() string {
+	return fmt.Sprintf("%s|%s|%s", h.ID, h.Network, h.Address)
+}
+
+// ParseHandshake parses a handshake line from a channel.
+func ParseHandshake(line string) (Handshake, error) {
+	parts := strings.Split(strings.TrimSpace(line), "|")
+	if len(parts) != 3 {
+		return Handshake{}, fmt.Errorf("invalid handshake %q: expected id|network|address", line)
+	}
+	h := Handshake{ID: parts[0], Network: parts[1], Address: parts[2]}
+	if h.ID == "" || h.Address == "" {
+		return Handshake{}, fmt.Errorf("invalid handshake %q: empty id or address", line)
+	}
+	if h.Network != "unix" && h.Network != "tcp" {
+		return Handshake{}, fmt.Errorf("unsupported network %q (want unix or tcp)", h.Network)
+	}
+	return h, nil
+}
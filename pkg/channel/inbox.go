@@ -0,0 +1,23 @@
+package channel
+
+import "context"
+
+// InboxSize is how many inbound messages Serve buffers between a
+// channel's Start and the host. While the host is slow or reconnecting the
+// buffer fills, and then a send into the inbox blocks; see Deliver.
+const InboxSize = 32
+
+// Deliver puts msg into inbox, waiting while it is full, and gives up
+// with ctx's error when ctx ends first. A full inbox is backpressure: the
+// host is behind, so a channel should stop pulling from its platform
+// (stop acking a queue, stop polling) rather than drop messages or queue
+// them without bound. Platforms that redeliver unacknowledged messages
+// then hold them for the channel.
+func Deliver(ctx context.Context, inbox chan<- InboundMessage, msg InboundMessage) error {
+	select {
+	case inbox <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -11,21 +11,25 @@ import (
 	"google.golang.org/grpc"
 )
 
-// SocketFileName is the Unix socket filename used when OPENTALON_CHANNEL_SOCK_DIR is set.
+// SocketFileName is the Unix socket filename used when EnvSockDir is set.
 // The host (connector) expects this name when connecting to a channel subprocess.
 const SocketFileName = "channel.sock"
 
 // Serve runs the channel as a subprocess server. It creates a Unix listener,
 // registers the gRPC ChannelService, then serves until the context is cancelled.
-// If OPENTALON_CHANNEL_SOCK_DIR is set (when launched by the host), the socket is
-// created there and no handshake is written to stdout, so the process can use
+// If EnvSockDir is set (when launched by the host), the socket is created
+// there and no handshake is written to stdout, so the process can use
 // stdin/stdout for the terminal. Otherwise it creates a temp dir and prints
-// id|unix|path to stdout for the host to connect.
-// Serve blocks until the context is cancelled.
+// a Handshake line to stdout for the launcher to connect.
+//
+// The channel's Start runs once, with ctx, when the host first opens the
+// inbound stream; if the host reconnects, the channel keeps running and
+// delivery resumes on the new stream. Serve blocks until the context is
+// cancelled.
 func Serve(ctx context.Context, ch Channel) error {
 	var sockDir string
 	var cleanup bool
-	if envDir := os.Getenv("OPENTALON_CHANNEL_SOCK_DIR"); envDir != "" {
+	if envDir := os.Getenv(EnvSockDir); envDir != "" {
 		sockDir = envDir
 	} else {
 		var err error
@@ -48,15 +52,15 @@ func Serve(ctx context.Context, ch Channel) error {
 	}
 	defer func() { _ = ln.Close() }()
 
-	if os.Getenv("OPENTALON_CHANNEL_SOCK_DIR") == "" {
-		// Handshake: id|network|address (connector expects 3 parts)
-		if _, err := fmt.Fprintf(os.Stdout, "%s|unix|%s\n", ch.ID(), sockPath); err != nil {
+	if os.Getenv(EnvSockDir) == "" {
+		hs := Handshake{ID: ch.ID(), Network: "unix", Address: sockPath}
+		if _, err := fmt.Fprintln(os.Stdout, hs.String()); err != nil {
 			return fmt.Errorf("write handshake: %w", err)
 		}
 	}
 
 	srv := grpc.NewServer()
-	channelpb.RegisterChannelServiceServer(srv, &grpcServer{ch: ch})
+	channelpb.RegisterChannelServiceServer(srv, &grpcServer{ch: ch, ctx: ctx})
 
 	// Shut down gracefully when context is cancelled.
 	go func() {
@@ -0,0 +1,43 @@
+package channel
+
+import "context"
+
+// TypingMetadataKey marks an outbound frame as a typing indicator ("true"):
+// no content, just "the assistant is working". The core sends one every
+// typingIndicatorInterval while a turn runs, and right away on channels
+// declaring Typing.
+const TypingMetadataKey = "_typing"
+
+// StatusMetadataKey carries a progress line on an outbound frame with no
+// content, e.g. "Running gitlab → analyze_code…". Sent only to channels
+// declaring Status; each frame replaces the previous status of the
+// conversation, and the reply itself ends it. Like TypingMetadataKey it is
+// core-to-channel plumbing a channel shows but never forwards as a message.
+const StatusMetadataKey = "_status"
+
+// IsSignal reports whether msg is a typing or status frame rather than a
+// message to deliver.
+func IsSignal(msg OutboundMessage) bool {
+	if msg.Content != "" || len(msg.Files) > 0 {
+		return false
+	}
+	return msg.Metadata[TypingMetadataKey] == "true" || msg.Metadata[StatusMetadataKey] != ""
+}
+
+// StatusFunc shows a progress line to the user of the current turn.
+type StatusFunc func(ctx context.Context, status string)
+
+type statusKey struct{}
+
+// WithStatus stores the turn's StatusFunc in the context.
+func WithStatus(ctx context.Context, fn StatusFunc) context.Context {
+	return context.WithValue(ctx, statusKey{}, fn)
+}
+
+// ReportStatus shows status to the user of the current turn; a no-op when
+// the channel does not take progress updates.
+func ReportStatus(ctx context.Context, status string) {
+	if fn, _ := ctx.Value(statusKey{}).(StatusFunc); fn != nil {
+		fn(ctx, status)
+	}
+}
@@ -0,0 +1,33 @@
+package channel
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReportStatus(t *testing.T) {
+	ReportStatus(context.Background(), "no reporter: no-op")
+
+	var got []string
+	ctx := WithStatus(context.Background(), func(_ context.Context, s string) { got = append(got, s) })
+	ReportStatus(ctx, "Running x…")
+	if len(got) != 1 || got[0] != "Running x…" {
+		t.Errorf("reported %v", got)
+	}
+}
+
+func TestIsSignal(t *testing.T) {
+	for _, tc := range []struct {
+		msg  OutboundMessage
+		want bool
+	}{
+		{OutboundMessage{Metadata: map[string]string{TypingMetadataKey: "true"}}, true},
+		{OutboundMessage{Metadata: map[string]string{StatusMetadataKey: "Running x…"}}, true},
+		{OutboundMessage{Content: "hi", Metadata: map[string]string{StatusMetadataKey: "Running x…"}}, false},
+		{OutboundMessage{Metadata: map[string]string{"type": "error"}}, false},
+	} {
+		if got := IsSignal(tc.msg); got != tc.want {
+			t.Errorf("IsSignal(%+v) = %v, want %v", tc.msg, got, tc.want)
+		}
+	}
+}
@@ -27,6 +27,12 @@ type InboundMessage struct {
 	Files          []FileAttachment  `yaml:"files,omitempty" json:"files,omitempty"`
 	Metadata       map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
 	Timestamp      time.Time         `yaml:"timestamp" json:"timestamp"`
+	// MessageID is the platform's id for this message (a Telegram
+	// update_id, a Slack event_id, a webhook delivery id). It must be the
+	// same on every redelivery: the core drops a message whose id it has
+	// already seen and derives tool idempotency keys from it. Empty = the
+	// channel cannot tell; such messages are never deduplicated by id.
+	MessageID string `yaml:"message_id,omitempty" json:"message_id,omitempty"`
 }
 
 // OutboundMessage is a message from core to a channel.
@@ -38,11 +44,14 @@ type OutboundMessage struct {
 	Metadata       map[string]string `yaml:"metadata,omitempty" json:"metadata,omitempty"`
 }
 
-// FileAttachment describes a file sent with a message.
+// FileAttachment describes a file sent with a message. Inbound files carry
+// either Data or a URL core downloads (http/https). URL is not yet carried
+// over the gRPC channel protocol; plugins send Data there.
 type FileAttachment struct {
 	Name     string `yaml:"name" json:"name"`
 	MimeType string `yaml:"mime_type" json:"mime_type"`
 	Data     []byte `yaml:"data,omitempty" json:"data,omitempty"`
+	URL      string `yaml:"url,omitempty" json:"url,omitempty"`
 	Size     int64  `yaml:"size" json:"size"`
 }
 
@@ -72,6 +81,23 @@ type Capabilities struct {
 	MaxMessageLength     int64          `yaml:"max_message_length" json:"max_message_length"`
 	ResponseFormat       ResponseFormat `yaml:"response_format" json:"response_format"`
 	ResponseFormatPrompt string         `yaml:"response_format_prompt" json:"response_format_prompt"`
+	// LinkThreads makes a new thread session start linked to the
+	// conversation it was spun off from: the thread sees that conversation's
+	// summary and pinned facts. Only meaningful with Threads. Not yet carried
+	// over the gRPC channel protocol; in-process and YAML channels set it.
+	LinkThreads bool `yaml:"link_threads" json:"link_threads"`
+	// Voice means the channel can play an audio reply (voice note). With a
+	// speech.tts provider configured, replies get a synthesized audio
+	// attachment next to the text. Like LinkThreads, not carried over gRPC.
+	Voice bool `yaml:"voice" json:"voice"`
+	// Typing means the channel shows a typing indicator (TypingMetadataKey
+	// frames): one is sent as soon as a message is picked up instead of
+	// after the first keepalive interval.
+	Typing bool `yaml:"typing" json:"typing"`
+	// Status means the channel can show a progress line that later frames
+	// replace (StatusMetadataKey frames), e.g. the tool a long turn is
+	// running. Like LinkThreads, neither is carried over gRPC yet.
+	Status bool `yaml:"status" json:"status"`
 }
 
 type capabilitiesKey struct{}
@@ -156,6 +182,7 @@ type ToolParam struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Required    bool   `json:"required"`
+	Type        string `json:"type,omitempty"` // see pkg/plugin.ParameterMsg; empty = string
 }
 
 // PluginMode identifies how the core connects to a channel plugin.
@@ -261,6 +288,28 @@ const ControlMetadataKey = "control"
 // token so the handler can scope the session and validate it exists.
 const ControlResumeHello = "resume_hello"
 
+// MessageIDMetadataKey carries InboundMessage.MessageID over the gRPC
+// channel protocol, whose InboundMessage has no field for it. The SDK sets
+// it from MessageID and the core moves it back; plugins built without the
+// SDK may set the metadata key themselves.
+const MessageIDMetadataKey = "_message_id"
+
+// ChatTypeMetadataKey tells the core what kind of conversation an inbound
+// message comes from. Channels set it to ChatTypeGroup for rooms several
+// people write in (a Slack channel, a Telegram group); an absent value means
+// a one-to-one chat. The core's group policy (mention gating, per-sender
+// sessions) only applies to group messages.
+const ChatTypeMetadataKey = "chat_type"
+
+// ChatTypeGroup is the ChatTypeMetadataKey value of a group conversation.
+const ChatTypeGroup = "group"
+
+// MentionedMetadataKey is "true" when a group message addresses the bot: an
+// @mention, a reply to one of its messages, or whatever the platform counts
+// as such. Channels that cannot tell leave it out and the core falls back to
+// looking for the configured mention names in the text.
+const MentionedMetadataKey = "mentioned"
+
 // OwnerEntityMetadataKey carries the conversation-owning entity on outbound
 // metadata. This is the authoritative statement of the contract:
 //
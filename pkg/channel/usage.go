@@ -0,0 +1,33 @@
+package channel
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// UsageTokensMetadataKey and UsageCostMetadataKey carry what a reply cost
+// on its outbound metadata: the turn's LLM tokens (input, output and prompt
+// cache) and, when model prices are configured, the spend in USD, e.g.
+// "0.0412". Channels may show them or ignore them; UsageSummary formats
+// both for display.
+const (
+	UsageTokensMetadataKey = "usage_tokens"
+	UsageCostMetadataKey   = "usage_cost"
+)
+
+// UsageSummary renders the usage keys of meta as "12.3k tokens / $0.04",
+// or "" when the reply carries none.
+func UsageSummary(meta map[string]string) string {
+	tokens, err := strconv.Atoi(meta[UsageTokensMetadataKey])
+	if err != nil || tokens <= 0 {
+		return ""
+	}
+	s := strconv.Itoa(tokens) + " tokens"
+	if tokens >= 1000 {
+		s = strconv.FormatFloat(float64(tokens)/1000, 'f', 1, 64) + "k tokens"
+	}
+	if cost, err := strconv.ParseFloat(meta[UsageCostMetadataKey], 64); err == nil && cost > 0 {
+		s += fmt.Sprintf(" / $%.2f", cost)
+	}
+	return s
+}
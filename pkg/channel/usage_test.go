@@ -0,0 +1,20 @@
+package channel
+
+import "testing"
+
+func TestUsageSummary(t *testing.T) {
+	for _, tc := range []struct {
+		meta map[string]string
+		want string
+	}{
+		{map[string]string{UsageTokensMetadataKey: "12345", UsageCostMetadataKey: "0.0412"}, "12.3k tokens / $0.04"},
+		{map[string]string{UsageTokensMetadataKey: "850"}, "850 tokens"},
+		{map[string]string{UsageTokensMetadataKey: "850", UsageCostMetadataKey: "0"}, "850 tokens"},
+		{map[string]string{UsageCostMetadataKey: "0.5"}, ""},
+		{nil, ""},
+	} {
+		if got := UsageSummary(tc.meta); got != tc.want {
+			t.Errorf("UsageSummary(%v) = %q; want %q", tc.meta, got, tc.want)
+		}
+	}
+}
@@ -58,3 +58,12 @@ const GroupID = "group_id"
 // empty string outside any actor context. Used to record who authored a
 // resource; distinct from GroupID, which scopes access.
 const EntityID = "entity_id"
+
+// IdempotencyKey is a hex key that is identical when the same inbound
+// message is handled twice (a redelivered webhook, a retried bot update)
+// and distinct for every other tool call, including repeated calls to the
+// same action within one turn. Plugins with side effects forward it to the
+// remote API (an Idempotency-Key header) or remember it to refuse a second
+// create. Resolves to the empty string when the turn did not come from a
+// channel message with an id; the plugin then has nothing to dedup on.
+const IdempotencyKey = "idempotency_key"
@@ -14,7 +14,7 @@ func capsToProto(c CapabilitiesMsg) *pluginpb.PluginCapabilities {
 			params[j] = &pluginpb.Parameter{
 				Name:        p.Name,
 				Description: p.Description,
-				Type:        p.Type,
+				Type:        p.WireType(),
 				Required:    p.Required,
 			}
 		}
@@ -55,6 +55,7 @@ func capsToProto(c CapabilitiesMsg) *pluginpb.PluginCapabilities {
 		Glossary:             glossary,
 		KnowledgeArticles:    knowledge,
 		SupportsCallbacks:    c.SupportsCallbacks,
+		SupportsProgress:     c.SupportsProgress,
 	}
 }
 
@@ -188,6 +188,23 @@ func TestCapsToProto_Parameters(t *testing.T) {
 	}
 }
 
+func TestCapsToProto_ParameterTypes(t *testing.T) {
+	pb := capsToProto(CapabilitiesMsg{Actions: []ActionMsg{{
+		Name: "list",
+		Parameters: []ParameterMsg{
+			{Name: "limit", Type: "integer"},
+			{Name: "status", Type: "string", Enum: []string{"open", "closed"}},
+			{Name: "ids", Type: "array", Items: "integer"},
+			{Name: "raw", Type: "array"},
+		},
+	}}})
+	for i, want := range []string{"integer", "enum:open,closed", "array:integer", "array"} {
+		if got := pb.Actions[0].Parameters[i].Type; got != want {
+			t.Errorf("parameter %d type = %q; want %q", i, got, want)
+		}
+	}
+}
+
 // TestResponseToProto_StructuredContent verifies that the structured
 // payload travels alongside the textual content over the gRPC boundary.
 func TestResponseToProto_StructuredContent(t *testing.T) {
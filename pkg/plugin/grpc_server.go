@@ -16,10 +16,8 @@ type grpcServer struct {
 }
 
 func (s *grpcServer) Init(_ context.Context, req *pluginpb.PluginInitRequest) (*emptypb.Empty, error) {
-	if c, ok := s.handler.(Configurable); ok {
-		if err := c.Configure(req.GetConfigJson()); err != nil {
-			return nil, status.Errorf(codes.InvalidArgument, "configure: %v", err)
-		}
+	if err := initHandler(s.handler, req.GetConfigJson()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "configure: %v", err)
 	}
 	return &emptypb.Empty{}, nil
 }
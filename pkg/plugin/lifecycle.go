@@ -0,0 +1,86 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Initializer may be implemented by a Handler to receive its config block
+// from the host, decoded, before any Execute call. config is empty (not
+// nil) when the host sent none. A Handler may implement both Initializer
+// and Configurable; Configure runs first.
+type Initializer interface {
+	Init(config map[string]any) error
+}
+
+// Shutdowner may be implemented by a Handler to release resources when the
+// plugin stops: Serve calls Shutdown on SIGINT or SIGTERM (the host sends
+// SIGINT), ServeContext when its ctx ends. In-flight calls finish first;
+// ctx expires after ShutdownTimeout, when the host's grace period runs out.
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// ShutdownTimeout bounds a plugin's whole shutdown, Shutdown included. It
+// matches how long the host waits before it kills a stopping plugin.
+const ShutdownTimeout = 5 * time.Second
+
+var logger atomic.Pointer[slog.Logger]
+
+// Logger returns the plugin's logger. Once Serve has negotiated protocol
+// version 2 or later, records go to stderr as JSON and the host logs them
+// as its own, at their level and tagged with the plugin's name; with an
+// older core they are plain text, which the host passes through.
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.New(slog.NewTextHandler(os.Stderr, nil))
+}
+
+// setupLogger points Logger at stderr in the format version calls for.
+func setupLogger(version int) {
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // the host filters by its own level
+	if version >= 2 {
+		logger.Store(slog.New(slog.NewJSONHandler(os.Stderr, opts)))
+		return
+	}
+	logger.Store(slog.New(slog.NewTextHandler(os.Stderr, opts)))
+}
+
+// initHandler passes configJSON to whichever of Configurable and
+// Initializer h implements.
+func initHandler(h Handler, configJSON string) error {
+	if c, ok := h.(Configurable); ok {
+		if err := c.Configure(configJSON); err != nil {
+			return err
+		}
+	}
+	i, ok := h.(Initializer)
+	if !ok {
+		return nil
+	}
+	config := map[string]any{}
+	if configJSON != "" {
+		if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+			return fmt.Errorf("decode config: %w", err)
+		}
+	}
+	return i.Init(config)
+}
+
+// shutdownHandler calls h's Shutdown, if it has one.
+func shutdownHandler(ctx context.Context, h Handler) {
+	s, ok := h.(Shutdowner)
+	if !ok {
+		return
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		Logger().Warn("plugin shutdown failed", "error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestNegotiateVersion(t *testing.T) {
+	for _, tt := range []struct {
+		offered string
+		want    int
+	}{
+		{"", HandshakeVersion}, // a core from before negotiation
+		{"1", 1},               // an older core
+		{"1,2", 2},             // this core
+		{" 2, 1 ,3", 2},        // a newer core
+		{ProtocolVersions(), ProtocolVersion},
+	} {
+		if got, err := NegotiateVersion(tt.offered); err != nil || got != tt.want {
+			t.Errorf("NegotiateVersion(%q) = %d, %v; want %d", tt.offered, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"3,4", "x"} {
+		if _, err := NegotiateVersion(bad); err == nil {
+			t.Errorf("NegotiateVersion(%q) accepted", bad)
+		}
+	}
+}
+
+type lifecycleHandler struct {
+	configJSON string
+	config     map[string]any
+	shutdown   chan struct{}
+}
+
+func (h *lifecycleHandler) Capabilities() CapabilitiesMsg { return CapabilitiesMsg{Name: "life"} }
+
+func (h *lifecycleHandler) Execute(req Request) Response { return Response{CallID: req.ID} }
+
+func (h *lifecycleHandler) Configure(configJSON string) error {
+	h.configJSON = configJSON
+	return nil
+}
+
+func (h *lifecycleHandler) Init(config map[string]any) error {
+	h.config = config
+	return nil
+}
+
+func (h *lifecycleHandler) Shutdown(context.Context) error {
+	close(h.shutdown)
+	return nil
+}
+
+func TestInitHandler(t *testing.T) {
+	h := &lifecycleHandler{}
+	if err := initHandler(h, `{"region":"eu","retries":3}`); err != nil {
+		t.Fatal(err)
+	}
+	if h.configJSON == "" || h.config["region"] != "eu" || h.config["retries"] != float64(3) {
+		t.Errorf("configured %q, init %v", h.configJSON, h.config)
+	}
+	if err := initHandler(h, ""); err != nil || h.config == nil || len(h.config) != 0 {
+		t.Errorf("empty config = %v, %v", h.config, err)
+	}
+	if err := initHandler(h, "[1]"); err == nil {
+		t.Error("a non-object config was accepted")
+	}
+}
+
+func TestServeContextShutsDown(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := &lifecycleHandler{shutdown: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- ServeContext(ctx, ln, h) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ServeContext = %v", err)
+		}
+	case <-time.After(ShutdownTimeout):
+		t.Fatal("ServeContext did not return")
+	}
+	select {
+	case <-h.shutdown:
+	default:
+		t.Error("Shutdown was not called")
+	}
+}
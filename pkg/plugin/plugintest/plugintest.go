@@ -0,0 +1,211 @@
+// Package plugintest helps test plugins and the code around them. Handler
+// is a scriptable mock plugin that records its calls; Serve runs any
+// plugin.Handler over the real gRPC protocol on a loopback port, so a test
+// exercises the same wire path the host uses:
+//
+//	h := plugintest.New("weather").Returns("now", "Sunny")
+//	srv := plugintest.Serve(t, h, "")
+//	resp, err := srv.Execute("now", map[string]string{"city": "Paris"})
+package plugintest
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/pkg/plugin"
+	"github.com/opentalon/opentalon/proto/pluginpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// Handler is a mock plugin.Handler. Actions are declared with Action,
+// Returns or Fails; calling any other action fails with "unknown action".
+type Handler struct {
+	Description string
+	Latency     time.Duration // added to every Execute
+
+	mu      sync.Mutex
+	name    string
+	actions []plugin.ActionMsg
+	funcs   map[string]func(plugin.Request) plugin.Response
+	calls   []plugin.Request
+	config  string
+}
+
+// New returns a mock plugin called name with no actions.
+func New(name string) *Handler {
+	return &Handler{name: name, funcs: map[string]func(plugin.Request) plugin.Response{}}
+}
+
+// Action declares an action answered by fn. fn may be nil for an action
+// that returns an empty result.
+func (h *Handler) Action(a plugin.ActionMsg, fn func(plugin.Request) plugin.Response) *Handler {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if a.Description == "" {
+		a.Description = a.Name
+	}
+	h.actions = append(h.actions, a)
+	h.funcs[a.Name] = fn
+	return h
+}
+
+// Returns declares an action that always answers content.
+func (h *Handler) Returns(action, content string) *Handler {
+	return h.Action(plugin.ActionMsg{Name: action}, func(plugin.Request) plugin.Response {
+		return plugin.Response{Content: content}
+	})
+}
+
+// Fails declares an action that always fails with msg.
+func (h *Handler) Fails(action, msg string) *Handler {
+	return h.Action(plugin.ActionMsg{Name: action}, func(plugin.Request) plugin.Response {
+		return plugin.Response{Error: msg}
+	})
+}
+
+// Capabilities implements plugin.Handler.
+func (h *Handler) Capabilities() plugin.CapabilitiesMsg {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	desc := h.Description
+	if desc == "" {
+		desc = "Mock " + h.name + " plugin"
+	}
+	return plugin.CapabilitiesMsg{Name: h.name, Description: desc, Actions: append([]plugin.ActionMsg(nil), h.actions...)}
+}
+
+// Configure implements plugin.Configurable and keeps the config for Config.
+func (h *Handler) Configure(configJSON string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.config = configJSON
+	return nil
+}
+
+// Config returns the config block the host sent in Init.
+func (h *Handler) Config() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.config
+}
+
+// Execute implements plugin.Handler.
+func (h *Handler) Execute(req plugin.Request) plugin.Response {
+	h.mu.Lock()
+	h.calls = append(h.calls, req)
+	fn, ok := h.funcs[req.Action]
+	h.mu.Unlock()
+	if h.Latency > 0 {
+		time.Sleep(h.Latency)
+	}
+	if !ok {
+		return plugin.Response{CallID: req.ID, Error: fmt.Sprintf("unknown action %q", req.Action)}
+	}
+	var resp plugin.Response
+	if fn != nil {
+		resp = fn(req)
+	}
+	resp.CallID = req.ID
+	return resp
+}
+
+// Calls returns every request Execute received so far.
+func (h *Handler) Calls() []plugin.Request {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]plugin.Request(nil), h.calls...)
+}
+
+// CallsTo returns the requests Execute received for action.
+func (h *Handler) CallsTo(action string) []plugin.Request {
+	var out []plugin.Request
+	for _, c := range h.Calls() {
+		if c.Action == action {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Server is a plugin served for a test.
+type Server struct {
+	// Handshake is the line the plugin would print; the host can dial it.
+	Handshake plugin.Handshake
+
+	name   string
+	client pluginpb.PluginServiceClient
+	seq    int
+	mu     sync.Mutex
+}
+
+// Serve runs h on a loopback TCP port until the test ends, and then shuts
+// it down as the host does. Like the host, it first sends configJSON
+// through Init, to a plugin.Configurable or plugin.Initializer handler.
+func Serve(t testing.TB, h plugin.Handler, configJSON string) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("plugintest: listen: %v", err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		_ = plugin.ServeContext(ctx, ln, h)
+		close(served)
+	}()
+	t.Cleanup(func() {
+		stop() // shuts the handler down, as on SIGINT
+		<-served
+	})
+	cc, err := grpc.NewClient(ln.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("plugintest: dial: %v", err)
+	}
+	t.Cleanup(func() { _ = cc.Close() })
+
+	s := &Server{
+		Handshake: plugin.Handshake{Version: plugin.ProtocolVersion, Network: "tcp", Address: ln.Addr().String()},
+		client:    pluginpb.NewPluginServiceClient(cc),
+	}
+	initCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if _, err := s.client.Init(initCtx, &pluginpb.PluginInitRequest{ConfigJson: configJSON}); err != nil {
+		t.Fatalf("plugintest: init: %v", err)
+	}
+	caps, err := s.Capabilities()
+	if err != nil {
+		t.Fatalf("plugintest: capabilities: %v", err)
+	}
+	s.name = caps.GetName()
+	return s
+}
+
+// Capabilities fetches the plugin's capabilities as the host receives them.
+func (s *Server) Capabilities() (*pluginpb.PluginCapabilities, error) {
+	return s.client.Capabilities(context.Background(), &emptypb.Empty{})
+}
+
+// Execute calls action over gRPC. A tool error comes back in
+// Response.Error; err is a transport failure.
+func (s *Server) Execute(action string, args map[string]string) (plugin.Response, error) {
+	s.mu.Lock()
+	s.seq++
+	id := fmt.Sprintf("call-%d", s.seq)
+	s.mu.Unlock()
+	resp, err := s.client.Execute(context.Background(), &pluginpb.ToolCallRequest{Id: id, Plugin: s.name, Action: action, Args: args})
+	if err != nil {
+		return plugin.Response{}, err
+	}
+	return plugin.Response{
+		CallID:            resp.GetCallId(),
+		Content:           resp.GetContent(),
+		StructuredContent: resp.GetStructuredContent(),
+		Error:             resp.GetError(),
+	}, nil
+}
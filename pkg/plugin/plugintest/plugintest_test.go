@@ -0,0 +1,76 @@
+package plugintest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/orchestrator"
+	hostplugin "github.com/opentalon/opentalon/internal/plugin"
+	"github.com/opentalon/opentalon/pkg/plugin"
+)
+
+func weather() *Handler {
+	return New("weather").
+		Action(plugin.ActionMsg{Name: "now", Parameters: []plugin.ParameterMsg{{Name: "city", Required: true}}, ReadOnly: true},
+			func(req plugin.Request) plugin.Response {
+				return plugin.Response{Content: "Sunny in " + req.Args["city"]}
+			}).
+		Fails("alert", "alerts are down")
+}
+
+func TestHandler(t *testing.T) {
+	h := weather()
+	if resp := h.Execute(plugin.Request{ID: "1", Action: "now", Args: map[string]string{"city": "Paris"}}); resp.Content != "Sunny in Paris" || resp.CallID != "1" {
+		t.Errorf("now = %+v", resp)
+	}
+	if resp := h.Execute(plugin.Request{Action: "forecast"}); !strings.Contains(resp.Error, "unknown action") {
+		t.Errorf("unknown action = %+v", resp)
+	}
+	if len(h.Calls()) != 2 || len(h.CallsTo("now")) != 1 {
+		t.Errorf("calls = %+v", h.Calls())
+	}
+	caps := h.Capabilities()
+	if caps.Name != "weather" || len(caps.Actions) != 2 || !caps.Actions[0].ReadOnly {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
+
+func TestServe(t *testing.T) {
+	h := weather()
+	h.Latency = 10 * time.Millisecond
+	srv := Serve(t, h, `{"units":"metric"}`)
+	if h.Config() != `{"units":"metric"}` {
+		t.Errorf("config = %q", h.Config())
+	}
+
+	resp, err := srv.Execute("now", map[string]string{"city": "Rome"})
+	if err != nil || resp.Content != "Sunny in Rome" || resp.CallID == "" {
+		t.Fatalf("now = %+v, %v", resp, err)
+	}
+	if resp, _ := srv.Execute("alert", nil); resp.Error != "alerts are down" {
+		t.Errorf("alert = %+v", resp)
+	}
+	if got := h.CallsTo("now"); len(got) != 1 || got[0].Plugin != "weather" {
+		t.Errorf("calls = %+v", got)
+	}
+}
+
+// TestServeHost checks the host's own plugin client can load a served mock.
+func TestServeHost(t *testing.T) {
+	h := weather()
+	srv := Serve(t, h, "")
+	c, err := hostplugin.DialFromHandshake(srv.Handshake, 5*time.Second, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = c.Close() }()
+	if c.Name() != "weather" || len(c.Capability().Actions) != 2 {
+		t.Errorf("capability = %+v", c.Capability())
+	}
+	res := c.Execute(context.Background(), orchestrator.ToolCall{ID: "c1", Plugin: "weather", Action: "now", Args: map[string]string{"city": "Oslo"}})
+	if res.Content != "Sunny in Oslo" || res.CallID != "c1" {
+		t.Errorf("result = %+v", res)
+	}
+}
@@ -3,14 +3,58 @@ package plugin
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 )
 
 const (
-	// HandshakeVersion is the protocol version in the handshake line.
+	// HandshakeVersion is the base protocol version, the one every core
+	// speaks and the one a plugin announces when the core offers none.
 	HandshakeVersion = 1
+
+	// ProtocolVersion is the newest protocol version this SDK speaks.
+	// Version 2 adds structured log records on stderr (see Logger).
+	ProtocolVersion = 2
+
+	// EnvProtocolVersions is the environment variable in which the core
+	// offers the protocol versions it speaks ("1,2"). The plugin announces
+	// the newest one both sides speak in its handshake line.
+	EnvProtocolVersions = "OPENTALON_PLUGIN_PROTOCOL_VERSIONS"
 )
 
+// ProtocolVersions returns the versions this SDK speaks as the core offers
+// them in EnvProtocolVersions.
+func ProtocolVersions() string {
+	vs := make([]string, 0, ProtocolVersion-HandshakeVersion+1)
+	for v := HandshakeVersion; v <= ProtocolVersion; v++ {
+		vs = append(vs, strconv.Itoa(v))
+	}
+	return strings.Join(vs, ",")
+}
+
+// NegotiateVersion picks the newest version in offered (a core's
+// EnvProtocolVersions value) that this SDK speaks. A core that offers
+// nothing predates negotiation and speaks HandshakeVersion.
+func NegotiateVersion(offered string) (int, error) {
+	if strings.TrimSpace(offered) == "" {
+		return HandshakeVersion, nil
+	}
+	best := 0
+	for _, f := range strings.Split(offered, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil {
+			return 0, fmt.Errorf("invalid protocol version %q in %s", f, EnvProtocolVersions)
+		}
+		if v >= HandshakeVersion && v <= ProtocolVersion {
+			best = max(best, v)
+		}
+	}
+	if best == 0 {
+		return 0, fmt.Errorf("no common protocol version: core offers %s, plugin speaks %s", offered, ProtocolVersions())
+	}
+	return best, nil
+}
+
 // CredentialHeader is a per-MCP-server credential specifying an HTTP header
 // name and value to inject into requests to that server. The plugin merges
 // these with its static configured headers; credential headers take priority.
@@ -61,6 +105,12 @@ type CapabilitiesMsg struct {
 	// don't need that capability leave this false (default) and keep
 	// receiving unary Execute traffic.
 	SupportsCallbacks bool `json:"supports_callbacks,omitempty"`
+
+	// SupportsProgress declares the plugin reports progress while an
+	// action runs (see ReportProgress). The host then dispatches its
+	// actions over ExecuteBidi too, so the handler must implement
+	// StreamingHandler.
+	SupportsProgress bool `json:"supports_progress,omitempty"`
 }
 
 // GlossaryEntryMsg is a single term/definition pair provided by a plugin.
@@ -107,12 +157,30 @@ type ActionMsg struct {
 	ReadOnly bool `json:"read_only,omitempty"`
 }
 
-// ParameterMsg describes one parameter of an action.
+// ParameterMsg describes one parameter of an action. Type is "string"
+// (default), "number", "integer", "boolean" or "array"; the host sends it to
+// the model as the parameter's JSON Schema type and checks the model's value
+// against it before Execute. Args still arrive as strings, in canonical form:
+// plain decimals, "true"/"false", and arrays as JSON.
 type ParameterMsg struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Type        string `json:"type"`
-	Required    bool   `json:"required"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Enum        []string `json:"enum,omitempty"`  // allowed values; Type is then "string"
+	Items       string   `json:"items,omitempty"` // element type of an array; empty = any JSON value
+}
+
+// WireType is Type as the gRPC protocol carries it, with Enum and Items
+// folded in: "enum:a,b,c" or "array:integer".
+func (p ParameterMsg) WireType() string {
+	switch {
+	case len(p.Enum) > 0:
+		return "enum:" + strings.Join(p.Enum, ",")
+	case p.Type == "array" && p.Items != "":
+		return "array:" + p.Items
+	}
+	return p.Type
 }
 
 // Handshake is the first line a plugin binary writes to stdout.
@@ -162,8 +230,8 @@ func ParseHandshake(line string) (Handshake, error) {
 		}
 	}
 
-	if h.Version != HandshakeVersion {
-		return Handshake{}, fmt.Errorf("unsupported handshake version %d (want %d)", h.Version, HandshakeVersion)
+	if h.Version < HandshakeVersion || h.Version > ProtocolVersion {
+		return Handshake{}, fmt.Errorf("unsupported handshake version %d (want %s)", h.Version, ProtocolVersions())
 	}
 	if h.Network != "unix" && h.Network != "tcp" {
 		return Handshake{}, fmt.Errorf("unsupported network %q (want unix or tcp)", h.Network)
@@ -46,7 +46,8 @@ func TestParseHandshakeInvalid(t *testing.T) {
 	bad := []string{
 		"",
 		"garbage",
-		"2|unix|/tmp/x.sock",                  // wrong version
+		"3|unix|/tmp/x.sock",                  // version newer than the SDK
+		"0|unix|/tmp/x.sock",                  // no such version
 		"1|http|localhost:8080",               // unsupported network
 		"1|unix",                              // missing address
 		"1|unix|/tmp/p.sock|bad:addr:format",  // too many colons
@@ -1,10 +1,13 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/opentalon/opentalon/proto/pluginpb"
 	"google.golang.org/grpc"
@@ -52,15 +55,51 @@ type Refreshable interface {
 // and for closing the listener after ServeListener returns.
 // Useful for TCP mode (MCP_GRPC_PORT).
 func ServeListener(ln net.Listener, handler Handler) error {
+	return ServeContext(context.Background(), ln, handler)
+}
+
+// ServeContext is ServeListener that stops when ctx ends: it stops taking
+// calls, lets in-flight ones finish, calls the handler's Shutdown (see
+// Shutdowner) and returns nil, all within ShutdownTimeout.
+func ServeContext(ctx context.Context, ln net.Listener, handler Handler) error {
 	srv := grpc.NewServer()
 	pluginpb.RegisterPluginServiceServer(srv, &grpcServer{handler: handler})
-	return srv.Serve(ln)
+	served := make(chan error, 1)
+	go func() { served <- srv.Serve(ln) }()
+
+	select {
+	case err := <-served:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		srv.Stop()
+	}
+	shutdownHandler(shutdownCtx, handler)
+	return nil
 }
 
 // Serve starts a gRPC server on a Unix socket and serves requests from the
-// host using the given handler. It prints the handshake line to stdout so the
-// host can discover the socket. This function blocks until the server is stopped.
+// host using the given handler. It negotiates the protocol version with the
+// core (see NegotiateVersion) and prints the handshake line to stdout so the
+// host can discover the socket. It blocks until the server fails, or until
+// SIGINT or SIGTERM shuts the plugin down gracefully.
 func Serve(handler Handler) error {
+	version, err := NegotiateVersion(os.Getenv(EnvProtocolVersions))
+	if err != nil {
+		return err
+	}
+	setupLogger(version)
+
 	sockDir, err := os.MkdirTemp("", "opentalon-plugin-*")
 	if err != nil {
 		return fmt.Errorf("create socket dir: %w", err)
@@ -74,13 +113,12 @@ func Serve(handler Handler) error {
 	defer func() { _ = ln.Close() }()
 	defer func() { _ = os.RemoveAll(sockDir) }()
 
-	hs := Handshake{Version: HandshakeVersion, Network: "unix", Address: sockPath, HTTPAddr: httpAddrFromEnv()}
+	hs := Handshake{Version: version, Network: "unix", Address: sockPath, HTTPAddr: httpAddrFromEnv()}
 	if _, err := fmt.Fprintln(os.Stdout, hs.String()); err != nil {
 		return fmt.Errorf("write handshake: %w", err)
 	}
 
-	srv := grpc.NewServer()
-	pluginpb.RegisterPluginServiceServer(srv, &grpcServer{handler: handler})
-
-	return srv.Serve(ln)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return ServeContext(ctx, ln, handler)
 }
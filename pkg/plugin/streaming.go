@@ -29,8 +29,9 @@ type CallResult struct {
 }
 
 // StreamingHandler is the plugin-side interface for actions that need
-// to call back into the host orchestrator mid-execution. Implement it
-// alongside Handler, then set CapabilitiesMsg.SupportsCallbacks = true.
+// to call back into the host orchestrator mid-execution or report
+// progress. Implement it alongside Handler, then set
+// CapabilitiesMsg.SupportsCallbacks (or SupportsProgress) = true.
 // The host will then dispatch this plugin's actions over ExecuteBidi
 // and pass a live HostCaller to ExecuteWithCallbacks.
 //
@@ -107,6 +108,56 @@ func (h *hostCallerStream) RunAction(ctx context.Context, plugin, action string,
 	}
 }
 
+// Progress is one progress report from a running action. Every field is
+// optional: Message is a short status line shown to the user, Partial is
+// the next piece of the result (the host joins the pieces, then appends the
+// final Response.Content, and keeps them if the action fails), and Percent
+// is 1-100, or 0 when unknown.
+type Progress struct {
+	Message string
+	Partial string
+	Percent int
+}
+
+// ProgressReporter is implemented by the HostCaller the SDK passes to
+// ExecuteWithCallbacks. Set CapabilitiesMsg.SupportsProgress so the host
+// listens for the reports.
+type ProgressReporter interface {
+	ReportProgress(ctx context.Context, p Progress) error
+}
+
+// ReportProgress sends p to the host if host supports progress reports and
+// does nothing otherwise, so a handler can report unconditionally.
+func ReportProgress(ctx context.Context, host HostCaller, p Progress) error {
+	if r, ok := host.(ProgressReporter); ok {
+		return r.ReportProgress(ctx, p)
+	}
+	return nil
+}
+
+// ReportProgress sends a ToolProgress frame. It shares mu with RunAction
+// so frames never interleave on the stream.
+func (h *hostCallerStream) ReportProgress(ctx context.Context, p Progress) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := h.stream.Send(&pluginpb.PluginMessage{
+		Payload: &pluginpb.PluginMessage_Progress{
+			Progress: &pluginpb.ToolProgress{
+				Message:        p.Message,
+				PartialContent: p.Partial,
+				Percent:        int32(min(max(p.Percent, 0), 100)),
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("send progress: %w", err)
+	}
+	return nil
+}
+
 // deliverResponse hands an incoming CallbackResponse to the goroutine
 // blocked in RunAction. Called from the stream's receive loop.
 func (h *hostCallerStream) deliverResponse(resp *pluginpb.CallbackResponse) {
@@ -128,8 +179,8 @@ func (h *hostCallerStream) deliverResponse(resp *pluginpb.CallbackResponse) {
 // ExecuteBidi is the server-side implementation of the bidirectional
 // streaming RPC. It is registered when the Handler also implements
 // StreamingHandler and the plugin's Capabilities reports
-// SupportsCallbacks = true. The host calls this in place of unary
-// Execute for matching plugins.
+// SupportsCallbacks or SupportsProgress = true. The host calls this in
+// place of unary Execute for matching plugins.
 //
 // Frame flow:
 //
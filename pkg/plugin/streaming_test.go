@@ -240,3 +240,46 @@ func TestExecuteBidi_HandlerWithoutStreamingImpl(t *testing.T) {
 
 // Compile-time: streamHandler satisfies StreamingHandler.
 var _ StreamingHandler = (*streamHandler)(nil)
+
+type plainHost struct{}
+
+func (plainHost) RunAction(context.Context, string, string, map[string]string) (CallResult, error) {
+	return CallResult{}, nil
+}
+
+// TestReportProgress_Frame: the SDK host sends a ToolProgress frame ahead
+// of the result, and ReportProgress is a no-op on hosts without progress.
+func TestReportProgress_Frame(t *testing.T) {
+	if err := ReportProgress(context.Background(), plainHost{}, Progress{Message: "x"}); err != nil {
+		t.Errorf("plain host: %v", err)
+	}
+
+	h := &streamHandler{
+		caps: CapabilitiesMsg{Name: "p", SupportsProgress: true},
+		body: func(ctx context.Context, req Request, host HostCaller) Response {
+			if err := ReportProgress(ctx, host, Progress{Message: "half way", Percent: 50, Partial: "a"}); err != nil {
+				return Response{Error: err.Error()}
+			}
+			return Response{CallID: req.ID, Content: "b"}
+		},
+	}
+	srv := &grpcServer{handler: h}
+	stream := newFakeStream()
+	stream.in <- &pluginpb.HostMessage{
+		Payload: &pluginpb.HostMessage_Call{Call: &pluginpb.ToolCallRequest{Id: "c1", Plugin: "p", Action: "go"}},
+	}
+	done := make(chan error, 1)
+	go func() { done <- srv.ExecuteBidi(stream) }()
+
+	p := (<-stream.outCh).GetProgress()
+	if p.GetMessage() != "half way" || p.GetPercent() != 50 || p.GetPartialContent() != "a" {
+		t.Errorf("progress frame = %+v", p)
+	}
+	if res := (<-stream.outCh).GetResult(); res.GetContent() != "b" {
+		t.Errorf("result frame = %+v", res)
+	}
+	stream.close()
+	if err := <-done; err != nil && !errors.Is(err, io.EOF) {
+		t.Errorf("ExecuteBidi returned: %v", err)
+	}
+}
@@ -0,0 +1,364 @@
+// Package providertest is a scriptable mock LLM provider for tests. A
+// Provider answers from a queue of scripted replies, records every request
+// it gets and can inject latency and errors. It works in process, as an
+// LLM client, and over HTTP as an OpenAI-compatible endpoint, so an
+// integration test can point a real opentalon config at it:
+//
+//	p := providertest.New(providertest.Text("Hello!"))
+//	srv := httptest.NewServer(p.Handler())
+//	// models.providers.mock: {api: openai-completions, base_url: srv.URL}
+package providertest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+// ErrScriptExhausted is returned once every scripted reply has been used.
+var ErrScriptExhausted = errors.New("providertest: no scripted reply left")
+
+// Reply is one scripted model answer.
+type Reply struct {
+	Content   string
+	ToolCalls []ToolCall // native tool calls
+	Err       error      // returned instead of an answer
+	Status    int        // HTTP status the Handler fails with when Err is set; 0 = 500
+	Latency   time.Duration
+
+	InputTokens, OutputTokens int
+}
+
+// ToolCall is a native tool call in a scripted reply. Name is
+// "plugin__action"; an empty ID is filled in.
+type ToolCall struct {
+	ID   string
+	Name string
+	Args map[string]string
+}
+
+// Text is a reply that answers with s.
+func Text(s string) Reply { return Reply{Content: s} }
+
+// Call is a reply that makes one native tool call.
+func Call(name string, args map[string]string) Reply {
+	return Reply{ToolCalls: []ToolCall{{Name: name, Args: args}}}
+}
+
+// Fail is a reply that fails with err.
+func Fail(err error) Reply { return Reply{Err: err} }
+
+// Request is what the provider was asked, as recorded.
+type Request struct {
+	Model    string
+	Messages []Message
+	Tools    []string // names of the native tools offered
+	Stream   bool
+}
+
+// Message is one message of a recorded request.
+type Message struct {
+	Role       string
+	Content    string
+	ToolCallID string
+}
+
+// Last returns the content of the request's last message.
+func (r Request) Last() string {
+	if len(r.Messages) == 0 {
+		return ""
+	}
+	return r.Messages[len(r.Messages)-1].Content
+}
+
+// Provider is the mock. Set the exported fields before first use.
+type Provider struct {
+	Name    string        // provider id; "" = "mock"
+	Model   string        // model id; "" = "mock-model"
+	Native  bool          // report native tool-calling support
+	Latency time.Duration // added to every call, before the reply's own
+
+	mu       sync.Mutex
+	replies  []Reply
+	requests []Request
+	calls    int
+}
+
+// New returns a provider that answers with replies, in order.
+func New(replies ...Reply) *Provider {
+	return &Provider{replies: replies}
+}
+
+// Script queues more replies.
+func (p *Provider) Script(replies ...Reply) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.replies = append(p.replies, replies...)
+}
+
+// Pending returns how many scripted replies are left.
+func (p *Provider) Pending() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.replies)
+}
+
+// Requests returns every request received so far.
+func (p *Provider) Requests() []Request {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Request(nil), p.requests...)
+}
+
+// ID implements provider.Provider.
+func (p *Provider) ID() string {
+	if p.Name == "" {
+		return "mock"
+	}
+	return p.Name
+}
+
+func (p *Provider) model() string {
+	if p.Model == "" {
+		return "mock-model"
+	}
+	return p.Model
+}
+
+// Models implements provider.Provider.
+func (p *Provider) Models() []provider.ModelInfo {
+	return []provider.ModelInfo{{ID: p.model(), Name: p.model(), ProviderID: p.ID()}}
+}
+
+// SupportsFeature implements provider.Provider: streaming always, native
+// tools when Native is set.
+func (p *Provider) SupportsFeature(f provider.Feature) bool {
+	return f == provider.FeatureStreaming || (f == provider.FeatureTools && p.Native)
+}
+
+// next records req and pops the next reply, waiting out its latency.
+func (p *Provider) next(ctx context.Context, req Request) (Reply, error) {
+	p.mu.Lock()
+	p.requests = append(p.requests, req)
+	if len(p.replies) == 0 {
+		p.mu.Unlock()
+		return Reply{}, ErrScriptExhausted
+	}
+	r := p.replies[0]
+	p.replies = p.replies[1:]
+	p.calls++
+	for i := range r.ToolCalls {
+		if r.ToolCalls[i].ID == "" {
+			r.ToolCalls[i].ID = fmt.Sprintf("call_%d_%d", p.calls, i+1)
+		}
+	}
+	p.mu.Unlock()
+
+	if d := p.Latency + r.Latency; d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return Reply{}, ctx.Err()
+		case <-t.C:
+		}
+	}
+	return r, r.Err
+}
+
+// Complete implements provider.Provider.
+func (p *Provider) Complete(ctx context.Context, req *provider.CompletionRequest) (*provider.CompletionResponse, error) {
+	r, err := p.next(ctx, fromCompletionRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	resp := &provider.CompletionResponse{
+		ID:      fmt.Sprintf("mock-%d", len(p.Requests())),
+		Model:   p.model(),
+		Content: r.Content,
+		Usage:   provider.Usage{InputTokens: r.InputTokens, OutputTokens: r.OutputTokens},
+	}
+	for _, tc := range r.ToolCalls {
+		resp.ToolCalls = append(resp.ToolCalls, provider.ToolCall{ID: tc.ID, Name: tc.Name, Arguments: tc.Args})
+	}
+	return resp, nil
+}
+
+// Stream implements provider.Provider. The reply's content arrives one
+// word per chunk; tool calls are not streamed.
+func (p *Provider) Stream(ctx context.Context, req *provider.CompletionRequest) (provider.ResponseStream, error) {
+	rec := fromCompletionRequest(req)
+	rec.Stream = true
+	r, err := p.next(ctx, rec)
+	if err != nil {
+		return nil, err
+	}
+	return &stream{chunks: words(r.Content), model: p.model(),
+		usage: provider.Usage{InputTokens: r.InputTokens, OutputTokens: r.OutputTokens}}, nil
+}
+
+type stream struct {
+	chunks []string
+	model  string
+	usage  provider.Usage
+	done   bool
+}
+
+func (s *stream) Recv() (provider.StreamChunk, error) {
+	if len(s.chunks) > 0 {
+		c := s.chunks[0]
+		s.chunks = s.chunks[1:]
+		return provider.StreamChunk{Content: c, Model: s.model}, nil
+	}
+	if s.done {
+		return provider.StreamChunk{}, io.EOF
+	}
+	s.done = true
+	return provider.StreamChunk{Done: true, Model: s.model, Usage: s.usage}, nil
+}
+
+func (s *stream) Close() error { return nil }
+
+// words splits s into chunks that concatenate back to s.
+func words(s string) []string {
+	var out []string
+	for s != "" {
+		i := strings.IndexByte(s[1:], ' ')
+		if i < 0 {
+			return append(out, s)
+		}
+		out = append(out, s[:i+1])
+		s = s[i+1:]
+	}
+	return out
+}
+
+func fromCompletionRequest(req *provider.CompletionRequest) Request {
+	r := Request{Model: req.Model, Stream: req.Stream}
+	for _, m := range req.Messages {
+		r.Messages = append(r.Messages, Message{Role: string(m.Role), Content: m.Content, ToolCallID: m.ToolCallID})
+	}
+	for _, t := range req.Tools {
+		r.Tools = append(r.Tools, t.Name)
+	}
+	return r
+}
+
+// Handler serves the provider as an OpenAI-compatible chat completions
+// endpoint (POST /chat/completions, streaming or not).
+func (p *Provider) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /chat/completions", p.serveCompletion)
+	mux.HandleFunc("POST /v1/chat/completions", p.serveCompletion)
+	return mux
+}
+
+type wireRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role       string          `json:"role"`
+		Content    json.RawMessage `json:"content"`
+		ToolCallID string          `json:"tool_call_id"`
+	} `json:"messages"`
+	Tools []struct {
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	} `json:"tools"`
+}
+
+// wireContent reads a message content that is a string or an array of
+// content parts.
+func wireContent(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var parts []struct {
+		Text string `json:"text"`
+	}
+	_ = json.Unmarshal(raw, &parts)
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
+
+func (p *Provider) serveCompletion(w http.ResponseWriter, r *http.Request) {
+	var in wireRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req := Request{Model: in.Model, Stream: in.Stream}
+	for _, m := range in.Messages {
+		req.Messages = append(req.Messages, Message{Role: m.Role, Content: wireContent(m.Content), ToolCallID: m.ToolCallID})
+	}
+	for _, t := range in.Tools {
+		req.Tools = append(req.Tools, t.Function.Name)
+	}
+	reply, err := p.next(r.Context(), req)
+	if err != nil {
+		status := reply.Status
+		if status == 0 {
+			status = http.StatusInternalServerError
+		}
+		writeError(w, status, err)
+		return
+	}
+
+	usage := map[string]int{"prompt_tokens": reply.InputTokens, "completion_tokens": reply.OutputTokens}
+	if in.Stream {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, c := range words(reply.Content) {
+			writeEvent(w, map[string]any{"model": p.model(), "choices": []any{map[string]any{"index": 0, "delta": map[string]any{"content": c}}}})
+		}
+		writeEvent(w, map[string]any{"model": p.model(), "choices": []any{map[string]any{"index": 0, "delta": map[string]any{}, "finish_reason": "stop"}}, "usage": usage})
+		_, _ = io.WriteString(w, "data: [DONE]\n\n")
+		return
+	}
+
+	msg := map[string]any{"role": "assistant", "content": reply.Content}
+	finish := "stop"
+	if len(reply.ToolCalls) > 0 {
+		var calls []any
+		for _, tc := range reply.ToolCalls {
+			args, _ := json.Marshal(tc.Args)
+			calls = append(calls, map[string]any{"id": tc.ID, "type": "function",
+				"function": map[string]any{"name": tc.Name, "arguments": string(args)}})
+		}
+		msg["tool_calls"] = calls
+		finish = "tool_calls"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":      fmt.Sprintf("mock-%d", len(p.Requests())),
+		"model":   p.model(),
+		"choices": []any{map[string]any{"index": 0, "message": msg, "finish_reason": finish}},
+		"usage":   usage,
+	})
+}
+
+func writeEvent(w http.ResponseWriter, v any) {
+	b, _ := json.Marshal(v)
+	_, _ = fmt.Fprintf(w, "data: %s\n\n", b)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]string{"message": err.Error(), "type": "mock_error"}})
+}
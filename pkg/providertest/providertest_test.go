@@ -0,0 +1,131 @@
+package providertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentalon/opentalon/internal/provider"
+)
+
+var _ provider.Provider = (*Provider)(nil)
+
+func ask(content string) *provider.CompletionRequest {
+	return &provider.CompletionRequest{Model: "m", Messages: []provider.Message{{Role: provider.RoleUser, Content: content}}}
+}
+
+func TestProviderScript(t *testing.T) {
+	boom := errors.New("boom")
+	p := New(Text("hi"), Call("weather__now", map[string]string{"city": "Paris"}), Fail(boom))
+	ctx := context.Background()
+
+	resp, err := p.Complete(ctx, ask("hello"))
+	if err != nil || resp.Content != "hi" {
+		t.Fatalf("first = %+v, %v", resp, err)
+	}
+	resp, _ = p.Complete(ctx, ask("weather?"))
+	if len(resp.ToolCalls) != 1 || resp.ToolCalls[0].ID == "" || resp.ToolCalls[0].Arguments["city"] != "Paris" {
+		t.Errorf("tool call = %+v", resp.ToolCalls)
+	}
+	if _, err := p.Complete(ctx, ask("again")); !errors.Is(err, boom) {
+		t.Errorf("scripted error = %v", err)
+	}
+	if _, err := p.Complete(ctx, ask("more")); !errors.Is(err, ErrScriptExhausted) {
+		t.Errorf("exhausted = %v", err)
+	}
+	reqs := p.Requests()
+	if len(reqs) != 4 || reqs[1].Last() != "weather?" || reqs[0].Messages[0].Role != "user" {
+		t.Errorf("requests = %+v", reqs)
+	}
+}
+
+func TestProviderLatency(t *testing.T) {
+	p := New(Reply{Content: "slow", Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := p.Complete(ctx, ask("x")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v", err)
+	}
+
+	p = New(Text("ok"))
+	p.Latency = 30 * time.Millisecond
+	start := time.Now()
+	if _, err := p.Complete(context.Background(), ask("x")); err != nil || time.Since(start) < p.Latency {
+		t.Errorf("latency not applied: %v after %s", err, time.Since(start))
+	}
+}
+
+func TestProviderStream(t *testing.T) {
+	p := New(Reply{Content: "one two three", OutputTokens: 3})
+	s, err := p.Stream(context.Background(), ask("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for {
+		c, err := s.Recv()
+		if err == io.EOF {
+			break
+		}
+		b.WriteString(c.Content)
+		if c.Done && c.Usage.OutputTokens != 3 {
+			t.Errorf("usage = %+v", c.Usage)
+		}
+	}
+	if b.String() != "one two three" || !p.Requests()[0].Stream {
+		t.Errorf("streamed %q", b.String())
+	}
+}
+
+// TestHandler drives the mock through the real OpenAI-compatible client.
+func TestHandler(t *testing.T) {
+	p := New(
+		Reply{Content: "hello there", InputTokens: 5, OutputTokens: 2},
+		Call("weather__now", map[string]string{"city": "Rome"}),
+		Reply{Content: "streamed reply"},
+		Reply{Err: errors.New("bad request"), Status: 400},
+	)
+	srv := httptest.NewServer(p.Handler())
+	defer srv.Close()
+	client := provider.NewOpenAIProvider("mock", srv.URL, "", p.Models())
+	ctx := context.Background()
+
+	resp, err := client.Complete(ctx, ask("hi"))
+	if err != nil || resp.Content != "hello there" || resp.Usage.InputTokens != 5 {
+		t.Fatalf("complete = %+v, %v", resp, err)
+	}
+	req := ask("weather?")
+	req.Tools = []provider.ToolDefinition{{Name: "weather__now", Description: "Weather"}}
+	resp, err = client.Complete(ctx, req)
+	if err != nil || len(resp.ToolCalls) != 1 || resp.ToolCalls[0].Arguments["city"] != "Rome" {
+		t.Fatalf("tool call = %+v, %v", resp, err)
+	}
+	if got := p.Requests()[1]; len(got.Tools) != 1 || got.Tools[0] != "weather__now" || got.Last() != "weather?" {
+		t.Errorf("recorded = %+v", got)
+	}
+
+	s, err := client.Stream(ctx, ask("stream"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	for {
+		c, err := s.Recv()
+		if err != nil || c.Done {
+			break
+		}
+		b.WriteString(c.Content)
+	}
+	_ = s.Close()
+	if b.String() != "streamed reply" {
+		t.Errorf("streamed %q", b.String())
+	}
+
+	if _, err := client.Complete(ctx, ask("fail")); err == nil || !strings.Contains(err.Error(), "bad request") {
+		t.Errorf("error = %v", err)
+	}
+}
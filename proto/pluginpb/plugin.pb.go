@@ -110,14 +110,16 @@ func (*HostMessage_CallbackResponse) isHostMessage_Payload() {}
 
 // PluginMessage is one frame on the plugin → host direction of an
 // ExecuteBidi stream. Frames are zero or more {callback_request}
-// (each requesting a host-side RunAction) followed by exactly one
-// terminal {result}; sending {result} closes the plugin's send half.
+// (each requesting a host-side RunAction) and {progress}, in any
+// order, followed by exactly one terminal {result}; sending {result}
+// closes the plugin's send half.
 type PluginMessage struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
 	// Types that are valid to be assigned to Payload:
 	//
 	//	*PluginMessage_CallbackRequest
 	//	*PluginMessage_Result
+	//	*PluginMessage_Progress
 	Payload       isPluginMessage_Payload `protobuf_oneof:"payload"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
@@ -178,6 +180,15 @@ func (x *PluginMessage) GetResult() *ToolResultResponse {
 	return nil
 }
 
+func (x *PluginMessage) GetProgress() *ToolProgress {
+	if x != nil {
+		if x, ok := x.Payload.(*PluginMessage_Progress); ok {
+			return x.Progress
+		}
+	}
+	return nil
+}
+
 type isPluginMessage_Payload interface {
 	isPluginMessage_Payload()
 }
@@ -190,10 +201,16 @@ type PluginMessage_Result struct {
 	Result *ToolResultResponse `protobuf:"bytes,2,opt,name=result,proto3,oneof"`
 }
 
+type PluginMessage_Progress struct {
+	Progress *ToolProgress `protobuf:"bytes,3,opt,name=progress,proto3,oneof"`
+}
+
 func (*PluginMessage_CallbackRequest) isPluginMessage_Payload() {}
 
 func (*PluginMessage_Result) isPluginMessage_Payload() {}
 
+func (*PluginMessage_Progress) isPluginMessage_Payload() {}
+
 // CallbackRequest asks the host to invoke another tool/action via its
 // normal executeCall path. The host's profile/actor context for the
 // dispatched call is inherited from the surrounding ToolCallRequest's
@@ -623,8 +640,14 @@ type PluginCapabilities struct {
 	// this false (the default) and continue to receive unary Execute
 	// traffic — no behaviour change for existing plugins.
 	SupportsCallbacks bool `protobuf:"varint,7,opt,name=supports_callbacks,json=supportsCallbacks,proto3" json:"supports_callbacks,omitempty"`
-	unknownFields     protoimpl.UnknownFields
-	sizeCache         protoimpl.SizeCache
+	// SupportsProgress declares that this plugin reports progress while an
+	// action runs: ToolProgress frames on ExecuteBidi before the result.
+	// The host then dispatches its actions over ExecuteBidi, as for
+	// supports_callbacks, and shows each frame to the user as a status
+	// update. Plugins that answer quickly leave this false.
+	SupportsProgress bool `protobuf:"varint,8,opt,name=supports_progress,json=supportsProgress,proto3" json:"supports_progress,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
 }
 
 func (x *PluginCapabilities) Reset() {
@@ -706,6 +729,13 @@ func (x *PluginCapabilities) GetSupportsCallbacks() bool {
 	return false
 }
 
+func (x *PluginCapabilities) GetSupportsProgress() bool {
+	if x != nil {
+		return x.SupportsProgress
+	}
+	return false
+}
+
 type GlossaryEntry struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Term          string                 `protobuf:"bytes,1,opt,name=term,proto3" json:"term,omitempty"`
@@ -1029,6 +1059,75 @@ func (x *Parameter) GetRequired() bool {
 	return false
 }
 
+// ToolProgress reports how a running action is doing. A plugin may send
+// any number before its terminal {result}; all fields are optional.
+type ToolProgress struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// message is a short status line for the user ("Indexed 120/400 files").
+	Message string `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// partial_content is the next piece of the result. The host joins the
+	// pieces in order, followed by the result's own content, so a plugin
+	// can stream its output and end with an empty result. If the action
+	// fails or times out, the pieces received so far are kept with the
+	// error.
+	PartialContent string `protobuf:"bytes,2,opt,name=partial_content,json=partialContent,proto3" json:"partial_content,omitempty"`
+	// percent done, 1-100; 0 means unknown.
+	Percent       int32 `protobuf:"varint,3,opt,name=percent,proto3" json:"percent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ToolProgress) Reset() {
+	*x = ToolProgress{}
+	mi := &file_plugin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ToolProgress) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ToolProgress) ProtoMessage() {}
+
+func (x *ToolProgress) ProtoReflect() protoreflect.Message {
+	mi := &file_plugin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ToolProgress.ProtoReflect.Descriptor instead.
+func (*ToolProgress) Descriptor() ([]byte, []int) {
+	return file_plugin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *ToolProgress) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ToolProgress) GetPartialContent() string {
+	if x != nil {
+		return x.PartialContent
+	}
+	return ""
+}
+
+func (x *ToolProgress) GetPercent() int32 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
 var File_plugin_proto protoreflect.FileDescriptor
 
 const file_plugin_proto_rawDesc = "" +
@@ -1037,10 +1136,11 @@ const file_plugin_proto_rawDesc = "" +
 	"\vHostMessage\x12:\n" +
 	"\x04call\x18\x01 \x01(\v2$.opentalon.plugin.v1.ToolCallRequestH\x00R\x04call\x12T\n" +
 	"\x11callback_response\x18\x02 \x01(\v2%.opentalon.plugin.v1.CallbackResponseH\x00R\x10callbackResponseB\t\n" +
-	"\apayload\"\xb0\x01\n" +
+	"\apayload\"\xf1\x01\n" +
 	"\rPluginMessage\x12Q\n" +
 	"\x10callback_request\x18\x01 \x01(\v2$.opentalon.plugin.v1.CallbackRequestH\x00R\x0fcallbackRequest\x12A\n" +
-	"\x06result\x18\x02 \x01(\v2'.opentalon.plugin.v1.ToolResultResponseH\x00R\x06resultB\t\n" +
+	"\x06result\x18\x02 \x01(\v2'.opentalon.plugin.v1.ToolResultResponseH\x00R\x06result\x12?\n" +
+	"\bprogress\x18\x03 \x01(\v2!.opentalon.plugin.v1.ToolProgressH\x00R\bprogressB\t\n" +
 	"\apayload\"\xce\x01\n" +
 	"\x0fCallbackRequest\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
@@ -1077,7 +1177,7 @@ const file_plugin_proto_rawDesc = "" +
 	"\acall_id\x18\x01 \x01(\tR\x06callId\x12\x18\n" +
 	"\acontent\x18\x02 \x01(\tR\acontent\x12\x14\n" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x12-\n" +
-	"\x12structured_content\x18\x04 \x01(\tR\x11structuredContent\"\xfc\x02\n" +
+	"\x12structured_content\x18\x04 \x01(\tR\x11structuredContent\"\xa9\x03\n" +
 	"\x12PluginCapabilities\x12\x12\n" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
 	"\vdescription\x18\x02 \x01(\tR\vdescription\x125\n" +
@@ -1085,7 +1185,8 @@ const file_plugin_proto_rawDesc = "" +
 	"\x16system_prompt_addition\x18\x04 \x01(\tR\x14systemPromptAddition\x12>\n" +
 	"\bglossary\x18\x05 \x03(\v2\".opentalon.plugin.v1.GlossaryEntryR\bglossary\x12T\n" +
 	"\x12knowledge_articles\x18\x06 \x03(\v2%.opentalon.plugin.v1.KnowledgeArticleR\x11knowledgeArticles\x12-\n" +
-	"\x12supports_callbacks\x18\a \x01(\bR\x11supportsCallbacks\"\x8f\x01\n" +
+	"\x12supports_callbacks\x18\a \x01(\bR\x11supportsCallbacks\x12+\n" +
+	"\x11supports_progress\x18\b \x01(\bR\x10supportsProgress\"\x8f\x01\n" +
 	"\rGlossaryEntry\x12\x12\n" +
 	"\x04term\x18\x01 \x01(\tR\x04term\x12\x1e\n" +
 	"\n" +
@@ -1113,7 +1214,11 @@ const file_plugin_proto_rawDesc = "" +
 	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
 	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x12\n" +
 	"\x04type\x18\x03 \x01(\tR\x04type\x12\x1a\n" +
-	"\brequired\x18\x04 \x01(\bR\brequired2\xb3\x03\n" +
+	"\brequired\x18\x04 \x01(\bR\brequired\"k\n" +
+	"\fToolProgress\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12'\n" +
+	"\x0fpartial_content\x18\x02 \x01(\tR\x0epartialContent\x12\x18\n" +
+	"\apercent\x18\x03 \x01(\x05R\apercent2\xb3\x03\n" +
 	"\rPluginService\x12F\n" +
 	"\x04Init\x12&.opentalon.plugin.v1.PluginInitRequest\x1a\x16.google.protobuf.Empty\x12X\n" +
 	"\aExecute\x12$.opentalon.plugin.v1.ToolCallRequest\x1a'.opentalon.plugin.v1.ToolResultResponse\x12O\n" +
@@ -1133,7 +1238,7 @@ func file_plugin_proto_rawDescGZIP() []byte {
 	return file_plugin_proto_rawDescData
 }
 
-var file_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 16)
+var file_plugin_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_plugin_proto_goTypes = []any{
 	(*HostMessage)(nil),        // 0: opentalon.plugin.v1.HostMessage
 	(*PluginMessage)(nil),      // 1: opentalon.plugin.v1.PluginMessage
@@ -1148,39 +1253,41 @@ var file_plugin_proto_goTypes = []any{
 	(*KnowledgeArticle)(nil),   // 10: opentalon.plugin.v1.KnowledgeArticle
 	(*Action)(nil),             // 11: opentalon.plugin.v1.Action
 	(*Parameter)(nil),          // 12: opentalon.plugin.v1.Parameter
-	nil,                        // 13: opentalon.plugin.v1.CallbackRequest.ArgsEntry
-	nil,                        // 14: opentalon.plugin.v1.ToolCallRequest.ArgsEntry
-	nil,                        // 15: opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry
-	(*emptypb.Empty)(nil),      // 16: google.protobuf.Empty
+	(*ToolProgress)(nil),       // 13: opentalon.plugin.v1.ToolProgress
+	nil,                        // 14: opentalon.plugin.v1.CallbackRequest.ArgsEntry
+	nil,                        // 15: opentalon.plugin.v1.ToolCallRequest.ArgsEntry
+	nil,                        // 16: opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry
+	(*emptypb.Empty)(nil),      // 17: google.protobuf.Empty
 }
 var file_plugin_proto_depIdxs = []int32{
 	5,  // 0: opentalon.plugin.v1.HostMessage.call:type_name -> opentalon.plugin.v1.ToolCallRequest
 	3,  // 1: opentalon.plugin.v1.HostMessage.callback_response:type_name -> opentalon.plugin.v1.CallbackResponse
 	2,  // 2: opentalon.plugin.v1.PluginMessage.callback_request:type_name -> opentalon.plugin.v1.CallbackRequest
 	7,  // 3: opentalon.plugin.v1.PluginMessage.result:type_name -> opentalon.plugin.v1.ToolResultResponse
-	13, // 4: opentalon.plugin.v1.CallbackRequest.args:type_name -> opentalon.plugin.v1.CallbackRequest.ArgsEntry
-	14, // 5: opentalon.plugin.v1.ToolCallRequest.args:type_name -> opentalon.plugin.v1.ToolCallRequest.ArgsEntry
-	15, // 6: opentalon.plugin.v1.ToolCallRequest.credential_headers:type_name -> opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry
-	11, // 7: opentalon.plugin.v1.PluginCapabilities.actions:type_name -> opentalon.plugin.v1.Action
-	9,  // 8: opentalon.plugin.v1.PluginCapabilities.glossary:type_name -> opentalon.plugin.v1.GlossaryEntry
-	10, // 9: opentalon.plugin.v1.PluginCapabilities.knowledge_articles:type_name -> opentalon.plugin.v1.KnowledgeArticle
-	12, // 10: opentalon.plugin.v1.Action.parameters:type_name -> opentalon.plugin.v1.Parameter
-	6,  // 11: opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry.value:type_name -> opentalon.plugin.v1.CredentialHeader
-	4,  // 12: opentalon.plugin.v1.PluginService.Init:input_type -> opentalon.plugin.v1.PluginInitRequest
-	5,  // 13: opentalon.plugin.v1.PluginService.Execute:input_type -> opentalon.plugin.v1.ToolCallRequest
-	16, // 14: opentalon.plugin.v1.PluginService.Capabilities:input_type -> google.protobuf.Empty
-	16, // 15: opentalon.plugin.v1.PluginService.RefreshCapabilities:input_type -> google.protobuf.Empty
-	0,  // 16: opentalon.plugin.v1.PluginService.ExecuteBidi:input_type -> opentalon.plugin.v1.HostMessage
-	16, // 17: opentalon.plugin.v1.PluginService.Init:output_type -> google.protobuf.Empty
-	7,  // 18: opentalon.plugin.v1.PluginService.Execute:output_type -> opentalon.plugin.v1.ToolResultResponse
-	8,  // 19: opentalon.plugin.v1.PluginService.Capabilities:output_type -> opentalon.plugin.v1.PluginCapabilities
-	8,  // 20: opentalon.plugin.v1.PluginService.RefreshCapabilities:output_type -> opentalon.plugin.v1.PluginCapabilities
-	1,  // 21: opentalon.plugin.v1.PluginService.ExecuteBidi:output_type -> opentalon.plugin.v1.PluginMessage
-	17, // [17:22] is the sub-list for method output_type
-	12, // [12:17] is the sub-list for method input_type
-	12, // [12:12] is the sub-list for extension type_name
-	12, // [12:12] is the sub-list for extension extendee
-	0,  // [0:12] is the sub-list for field type_name
+	13, // 4: opentalon.plugin.v1.PluginMessage.progress:type_name -> opentalon.plugin.v1.ToolProgress
+	14, // 5: opentalon.plugin.v1.CallbackRequest.args:type_name -> opentalon.plugin.v1.CallbackRequest.ArgsEntry
+	15, // 6: opentalon.plugin.v1.ToolCallRequest.args:type_name -> opentalon.plugin.v1.ToolCallRequest.ArgsEntry
+	16, // 7: opentalon.plugin.v1.ToolCallRequest.credential_headers:type_name -> opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry
+	11, // 8: opentalon.plugin.v1.PluginCapabilities.actions:type_name -> opentalon.plugin.v1.Action
+	9,  // 9: opentalon.plugin.v1.PluginCapabilities.glossary:type_name -> opentalon.plugin.v1.GlossaryEntry
+	10, // 10: opentalon.plugin.v1.PluginCapabilities.knowledge_articles:type_name -> opentalon.plugin.v1.KnowledgeArticle
+	12, // 11: opentalon.plugin.v1.Action.parameters:type_name -> opentalon.plugin.v1.Parameter
+	6,  // 12: opentalon.plugin.v1.ToolCallRequest.CredentialHeadersEntry.value:type_name -> opentalon.plugin.v1.CredentialHeader
+	4,  // 13: opentalon.plugin.v1.PluginService.Init:input_type -> opentalon.plugin.v1.PluginInitRequest
+	5,  // 14: opentalon.plugin.v1.PluginService.Execute:input_type -> opentalon.plugin.v1.ToolCallRequest
+	17, // 15: opentalon.plugin.v1.PluginService.Capabilities:input_type -> google.protobuf.Empty
+	17, // 16: opentalon.plugin.v1.PluginService.RefreshCapabilities:input_type -> google.protobuf.Empty
+	0,  // 17: opentalon.plugin.v1.PluginService.ExecuteBidi:input_type -> opentalon.plugin.v1.HostMessage
+	17, // 18: opentalon.plugin.v1.PluginService.Init:output_type -> google.protobuf.Empty
+	7,  // 19: opentalon.plugin.v1.PluginService.Execute:output_type -> opentalon.plugin.v1.ToolResultResponse
+	8,  // 20: opentalon.plugin.v1.PluginService.Capabilities:output_type -> opentalon.plugin.v1.PluginCapabilities
+	8,  // 21: opentalon.plugin.v1.PluginService.RefreshCapabilities:output_type -> opentalon.plugin.v1.PluginCapabilities
+	1,  // 22: opentalon.plugin.v1.PluginService.ExecuteBidi:output_type -> opentalon.plugin.v1.PluginMessage
+	18, // [18:23] is the sub-list for method output_type
+	13, // [13:18] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_plugin_proto_init() }
@@ -1195,6 +1302,7 @@ func file_plugin_proto_init() {
 	file_plugin_proto_msgTypes[1].OneofWrappers = []any{
 		(*PluginMessage_CallbackRequest)(nil),
 		(*PluginMessage_Result)(nil),
+		(*PluginMessage_Progress)(nil),
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -1202,7 +1310,7 @@ func file_plugin_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_plugin_proto_rawDesc), len(file_plugin_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   16,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   1,
 		},